@@ -0,0 +1,291 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/policy"
+	"github.com/Suhaibinator/SProto/internal/wkt"
+	"go.uber.org/zap"
+)
+
+// demoModule describes an example module version seeded into the demo registry.
+type demoModule struct {
+	Namespace string
+	Name      string
+	Version   string
+	Files     map[string]string
+}
+
+// demoModules are published (in order) when the demo registry starts.
+var demoModules = []demoModule{
+	{
+		Namespace: "examples",
+		Name:      "greeter",
+		Version:   "v1.0.0",
+		Files: map[string]string{
+			"greeter/v1/greeter.proto": `syntax = "proto3";
+
+package examples.greeter.v1;
+
+// Greeter sends greetings.
+service Greeter {
+  rpc SayHello(HelloRequest) returns (HelloReply);
+}
+
+message HelloRequest {
+  string name = 1;
+}
+
+message HelloReply {
+  string message = 1;
+}
+`,
+		},
+	},
+	{
+		Namespace: "examples",
+		Name:      "greeter",
+		Version:   "v1.1.0",
+		Files: map[string]string{
+			"greeter/v1/greeter.proto": `syntax = "proto3";
+
+package examples.greeter.v1;
+
+// Greeter sends greetings.
+service Greeter {
+  rpc SayHello(HelloRequest) returns (HelloReply);
+  rpc SayGoodbye(GoodbyeRequest) returns (GoodbyeReply);
+}
+
+message HelloRequest {
+  string name = 1;
+  string locale = 2;
+}
+
+message HelloReply {
+  string message = 1;
+}
+
+message GoodbyeRequest {
+  string name = 1;
+}
+
+message GoodbyeReply {
+  string message = 1;
+}
+`,
+		},
+	},
+	{
+		Namespace: "examples",
+		Name:      "user",
+		Version:   "v0.1.0",
+		Files: map[string]string{
+			"user/v1/user.proto": `syntax = "proto3";
+
+package examples.user.v1;
+
+message User {
+  string id = 1;
+  string email = 2;
+  string display_name = 3;
+}
+`,
+		},
+	},
+	{
+		Namespace: "acme",
+		Name:      "billing",
+		Version:   "v2.0.0",
+		Files: map[string]string{
+			"billing/v2/invoice.proto": `syntax = "proto3";
+
+package acme.billing.v2;
+
+message Invoice {
+  string id = 1;
+  string customer_id = 2;
+  repeated LineItem items = 3;
+}
+
+message LineItem {
+  string sku = 1;
+  int64 quantity = 2;
+  int64 unit_price_cents = 3;
+}
+`,
+		},
+	},
+}
+
+// runDemo starts a throwaway registry: SQLite and local storage in a temporary directory,
+// authentication disabled, and a few example modules seeded. Everything is removed on exit.
+func runDemo(cfg config.Config) {
+	tempDir, err := os.MkdirTemp("", "sproto-demo-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create demo directory: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Override backend and auth settings; the port and log settings are still taken from the environment.
+	cfg.DbType = "sqlite"
+	cfg.SqlitePath = filepath.Join(tempDir, "sproto.db")
+	cfg.StorageType = "local"
+	cfg.LocalStoragePath = filepath.Join(tempDir, "storage")
+	cfg.SkipMigrations = false
+	cfg.AuthToken = "" // Auth disabled
+	cfg.PublicRead = true
+	cfg.ClamAVAddress = ""
+	cfg.ListenAddress = "" // Always on localhost:<SERVER_PORT>, as printed below
+	cfg.MetricsListenAddress = ""
+
+	srv := initServer(cfg)
+	log, router := srv.Logger(), srv.Handler()
+	defer srv.Close()
+
+	// Sample data is seeded with publish policies suspended; they apply to everything published afterwards
+	policies := policy.GetEngine()
+	policy.SetEngine(nil)
+
+	if err := seedDemoModules(router); err != nil {
+		log.Error("Failed to seed demo modules", zap.Error(err))
+		return // Deferred cleanup still runs
+	}
+	log.Info("Seeded demo modules", zap.Int("versions", len(demoModules)), zap.String("data_dir", tempDir))
+
+	// Built-in google/protobuf and google/api modules, as after `sproto-server seed-wkt`
+	if builtins, err := wkt.Modules(); err != nil {
+		log.Error("Failed to load built-in modules", zap.Error(err))
+	} else if err := seedModules("", builtins); err != nil {
+		log.Error("Failed to seed built-in modules", zap.Error(err))
+	}
+	policy.SetEngine(policies)
+
+	if grpcServer := startGRPCServer(cfg, srv); grpcServer != nil {
+		defer grpcServer.Stop()
+	}
+
+	listenAddr := ":" + cfg.ServerPort
+	httpServer := &http.Server{Addr: listenAddr, Handler: router}
+
+	fmt.Fprintf(os.Stderr, `
+SProto demo registry running on http://localhost:%[1]s (auth disabled, data in %[2]s)
+Try it with the CLI:
+
+  protoreg-cli --registry-url http://localhost:%[1]s list
+  protoreg-cli --registry-url http://localhost:%[1]s list examples/greeter
+  protoreg-cli --registry-url http://localhost:%[1]s fetch examples/greeter v1.1.0 --output ./demo-protos
+  protoreg-cli --registry-url http://localhost:%[1]s --api-token demo publish ./my-protos --module demo/mine --version v0.1.0
+
+Press Ctrl+C to stop; all demo data is deleted on exit.
+
+`, cfg.ServerPort, tempDir)
+
+	// Shut down gracefully on Ctrl+C so the temp directory is removed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Info("Starting demo server", zap.String("address", listenAddr))
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error("Demo server failed", zap.Error(err))
+	}
+}
+
+// seedDemoModules publishes the demo modules through the regular publish endpoint,
+// so seeded data is stored exactly like data published by the CLI.
+func seedDemoModules(router http.Handler) error {
+	for _, m := range demoModules {
+		status, body, err := publishFiles(router, "", m.Namespace, m.Name, m.Version, m.Files)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusCreated {
+			return fmt.Errorf("publishing %s/%s@%s returned %d: %s", m.Namespace, m.Name, m.Version, status, body)
+		}
+	}
+	return nil
+}
+
+// publishFiles zips files and publishes them through the router's publish endpoint,
+// returning the response status and body.
+func publishFiles(router http.Handler, authToken, namespace, name, version string, files map[string]string) (int, string, error) {
+	artifact, err := zipFiles(files)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to build artifact for %s/%s@%s: %w", namespace, name, version, err)
+	}
+	return publishArtifact(router, authToken, namespace, name, version, artifact)
+}
+
+// publishArtifact publishes a zipped artifact through the router's publish endpoint,
+// returning the response status and body.
+func publishArtifact(router http.Handler, authToken, namespace, name, version string, artifact []byte) (int, string, error) {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("artifact", version+".zip")
+	if err != nil {
+		return 0, "", err
+	}
+	if _, err := part.Write(artifact); err != nil {
+		return 0, "", err
+	}
+	if err := mw.Close(); err != nil {
+		return 0, "", err
+	}
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/modules/%s/%s/%s", namespace, name, version), body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+authToken)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr.Code, rr.Body.String(), nil
+}
+
+// zipFiles builds an in-memory zip archive from a map of file paths to contents.
+// Entries are written in sorted order so the same files always produce the same artifact digest.
+func zipFiles(files map[string]string) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, name := range names {
+		content := files[name]
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
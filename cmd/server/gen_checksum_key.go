@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Suhaibinator/SProto/internal/translog"
+)
+
+// runGenChecksumKey prints a new key for signing checksum statements (CHECKSUM_SIGNING_KEY) and the
+// public key clients configure to verify them. It doesn't touch the database or storage.
+func runGenChecksumKey() {
+	privateKey, publicKey, err := translog.GenerateKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate key: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("PROTOREG_CHECKSUM_SIGNING_KEY=%s\n", privateKey)
+	fmt.Printf("# Public key for clients: protoreg-cli configure --registry-public-key %s\n", publicKey)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Suhaibinator/SProto/internal/provenance"
+	"github.com/Suhaibinator/SProto/internal/translog"
+)
+
+// runGenSigningKey prints a new key for signing published versions (VERSION_SIGNING_KEY) and its key ID.
+// It doesn't touch the database or storage.
+func runGenSigningKey() {
+	privateKey, publicKey, err := translog.GenerateKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate key: %v\n", err)
+		os.Exit(1)
+	}
+	pub, err := translog.ParsePublicKey(publicKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate key: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("PROTOREG_VERSION_SIGNING_KEY=%s\n", privateKey)
+	fmt.Printf("# Key ID %s, public key %s (served at /.well-known/sproto/signing-keys;\n", provenance.KeyID(pub), publicKey)
+	fmt.Printf("# add it to PROTOREG_VERSION_SIGNING_RETIRED_KEYS when rotating to another key)\n")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api"
+	"github.com/Suhaibinator/SProto/internal/bufimport"
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// runImportBuf imports the semver-labeled versions of a buf module (see package bufimport), publishing
+// them through the regular publish endpoint in-process, without going through a running server. Versions
+// that already exist are skipped, so it is safe to re-run after an interruption.
+func runImportBuf(cfg config.Config, args []string) {
+	fs := flag.NewFlagSet("import-buf", flag.ExitOnError)
+	module := fs.String("module", "", "Target module (namespace/module_name); defaults to <owner>/<module> of the buf module")
+	bufToken := fs.String("buf-token", os.Getenv("BUF_TOKEN"), "Buf API token for private modules (default: the BUF_TOKEN env var)")
+	dryRun := fs.Bool("dry-run", false, "Only list the versions that would be imported")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: sproto-server import-buf [--module namespace/name] [--buf-token token] [--dry-run] <remote/owner/module>\n")
+		os.Exit(2)
+	}
+	ref, err := bufimport.ParseModuleRef(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *module == "" {
+		*module = ref.Owner + "/" + ref.Module
+	}
+
+	log := initBackends(cfg)
+	defer func() { _ = log.Sync() }()
+
+	router := mux.NewRouter()
+	api.RegisterRoutes(router, cfg.AuthToken)
+	namespace, name, _ := strings.Cut(*module, "/")
+	publisher := &routerPublisher{router: router, authToken: cfg.AuthToken, namespace: namespace, name: name}
+
+	result, err := bufimport.Import(context.Background(), bufimport.NewClient(ref, *bufToken), ref, publisher,
+		bufimport.Options{Module: *module, DryRun: *dryRun, Log: log.With(zap.String("job", "import-buf"))})
+	if result != nil {
+		verb := "Imported"
+		if *dryRun {
+			verb = "Would import"
+		}
+		fmt.Printf("%s %d version(s) of %s into %s: %s\n", verb, len(result.Imported), ref, *module, strings.Join(result.Imported, ", "))
+		if len(result.Existing) > 0 {
+			fmt.Printf("Already in the registry: %s\n", strings.Join(result.Existing, ", "))
+		}
+		if len(result.Skipped) > 0 {
+			fmt.Printf("Skipped labels (not semantic versions): %s\n", strings.Join(result.Skipped, ", "))
+		}
+	}
+	if err != nil {
+		log.Error("Failed to import buf module", zap.String("buf_module", ref.String()), zap.Error(err))
+		os.Exit(1)
+	}
+}
+
+// routerPublisher publishes imported versions through the router's endpoints.
+type routerPublisher struct {
+	router          *mux.Router
+	authToken       string
+	namespace, name string
+}
+
+func (p *routerPublisher) Publish(_ context.Context, version string, artifact []byte) (bool, error) {
+	status, body, err := publishArtifact(p.router, p.authToken, p.namespace, p.name, version, artifact)
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case http.StatusCreated:
+		return true, nil
+	case http.StatusConflict:
+		return false, nil // Imported before
+	default:
+		return false, fmt.Errorf("publish returned %d: %s", status, strings.TrimSpace(body))
+	}
+}
+
+func (p *routerPublisher) AddNote(_ context.Context, version, note string) error {
+	payload, err := json.Marshal(apitypes.AddVersionNoteRequest{Note: note})
+	if err != nil {
+		return err
+	}
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/modules/%s/%s/%s/notes", p.namespace, p.name, version), bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(apitypes.PublisherHeader, "import-buf")
+	if p.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.authToken)
+	}
+	rr := httptest.NewRecorder()
+	p.router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		return fmt.Errorf("adding the note returned %d: %s", rr.Code, strings.TrimSpace(rr.Body.String()))
+	}
+	return nil
+}
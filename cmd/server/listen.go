@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+)
+
+// unixSocketPrefix marks listen addresses that are Unix domain socket paths, e.g. "unix:///run/sproto/api.sock".
+const unixSocketPrefix = "unix://"
+
+// apiListenAddress returns the address of the API listener: LISTEN_ADDRESS, or ":<SERVER_PORT>".
+func apiListenAddress(cfg config.Config) string {
+	if cfg.ListenAddress != "" {
+		return cfg.ListenAddress
+	}
+	return ":" + cfg.ServerPort
+}
+
+// grpcListenAddress returns the address of the gRPC listener: GRPC_LISTEN_ADDRESS, or ":<GRPC_PORT>".
+// Returns "" if gRPC is disabled (neither is set).
+func grpcListenAddress(cfg config.Config) string {
+	if cfg.GRPCListenAddress != "" {
+		return cfg.GRPCListenAddress
+	}
+	if cfg.GRPCPort != "" {
+		return ":" + cfg.GRPCPort
+	}
+	return ""
+}
+
+// listen opens a listener on a TCP address ("host:port", ":port") or a Unix socket ("unix:///path").
+// A socket file left behind by a previous run is removed first; a socket another process is still
+// serving on is an error.
+func listen(address string) (net.Listener, error) {
+	socketPath, ok := strings.CutPrefix(address, unixSocketPrefix)
+	if !ok {
+		return net.Listen("tcp", address)
+	}
+	if socketPath == "" {
+		return nil, fmt.Errorf("invalid listen address %q: missing socket path", address)
+	}
+
+	if info, err := os.Lstat(socketPath); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", socketPath)
+		}
+		if conn, err := net.DialTimeout("unix", socketPath, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %s is in use by another process", socketPath)
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", socketPath, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return net.Listen("unix", socketPath)
+}
@@ -1,46 +1,178 @@
 package main
 
 import (
-	"log"
+	"flag"
+	"fmt"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/Suhaibinator/SProto/internal/api"
 	"github.com/Suhaibinator/SProto/internal/config"
-	"github.com/Suhaibinator/SProto/internal/db"
-	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/pkg/server"
 	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
 )
 
+const usage = `Usage: sproto-server [command]
+
+Commands:
+  serve [--skip-migrations]
+          Start the registry server (default). --skip-migrations doesn't migrate the database, and refuses to
+          start unless it is at this release's schema version (see migrate-db)
+  demo    Start a throwaway registry (SQLite + local storage in a temp dir, auth disabled, seeded example modules)
+  migrate-db [--status]
+          Migrate the database to this release's schema version (--status: only compare the versions)
+  migrate-storage [--dry-run] [--keep-old]
+          Move artifacts stored under older key layouts to the current layout
+  seed-wkt
+          Publish the built-in google/protobuf (well-known types) and google/api modules
+  import-buf [--module namespace/name] [--buf-token token] [--dry-run] <remote/owner/module>
+          Import the semver-labeled versions of a module from a buf registry (e.g. buf.build/acme/petapis)
+  gen-checksum-key
+          Print a new checksum statement signing key (PROTOREG_CHECKSUM_SIGNING_KEY) and its public key
+  gen-signing-key
+          Print a new version signing key (PROTOREG_VERSION_SIGNING_KEY) and its key ID
+`
+
 func main() {
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Initialize Database (Postgres or SQLite)
-	_, err = db.Init(cfg) // Pass the whole config struct
-	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	command, args := "serve", os.Args[1:] // Flags of the default command
+	if len(os.Args) > 1 && (!strings.HasPrefix(os.Args[1], "-") || os.Args[1] == "-h" || os.Args[1] == "--help") {
+		command, args = os.Args[1], os.Args[2:]
+	}
+
+	switch command {
+	case "serve":
+		runServer(cfg, args)
+	case "demo":
+		runDemo(cfg)
+	case "migrate-db":
+		runMigrateDB(cfg, args)
+	case "migrate-storage":
+		runMigrateStorage(cfg, args)
+	case "seed-wkt":
+		runSeedWKT(cfg)
+	case "import-buf":
+		runImportBuf(cfg, args)
+	case "gen-checksum-key":
+		runGenChecksumKey()
+	case "gen-signing-key":
+		runGenSigningKey()
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
+
+// runServer initializes all components from cfg and serves the API until the process exits.
+func runServer(cfg config.Config, args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	skipMigrations := fs.Bool("skip-migrations", cfg.SkipMigrations, "Don't migrate the database; refuse to start unless it is at this release's schema version")
+	_ = fs.Parse(args)
+	cfg.SkipMigrations = *skipMigrations
+
+	srv := initServer(cfg)
+	log := srv.Logger()
+	defer srv.Close()
+
+	// Start gRPC reflection server (optional)
+	if grpcServer := startGRPCServer(cfg, srv); grpcServer != nil {
+		defer grpcServer.Stop()
+	}
+
+	// Start the metrics listener (optional, /metrics is on the API listener otherwise)
+	if cfg.MetricsListenAddress != "" {
+		startMetricsServer(cfg, log)
 	}
 
-	// Initialize Storage (Minio or Local)
-	_, err = storage.InitStorage(cfg) // Use the new unified storage init
+	// Start Server
+	listenAddr := apiListenAddress(cfg)
+	listener, err := listen(listenAddr)
+	if err != nil {
+		log.Fatal("Failed to listen", zap.String("address", listenAddr), zap.Error(err))
+	}
+	log.Info("Starting server", zap.String("address", listenAddr))
+	err = http.Serve(listener, srv.Handler())
 	if err != nil {
-		log.Fatalf("Failed to initialize storage: %v", err) // Updated error message
+		log.Fatal("Failed to start server", zap.Error(err))
 	}
+}
 
-	// Initialize Router
+// startMetricsServer serves /metrics on METRICS_LISTEN_ADDRESS in the background.
+func startMetricsServer(cfg config.Config, log *zap.Logger) {
+	listener, err := listen(cfg.MetricsListenAddress)
+	if err != nil {
+		log.Fatal("Failed to listen for metrics", zap.String("address", cfg.MetricsListenAddress), zap.Error(err))
+	}
 	router := mux.NewRouter()
+	api.RegisterMetricsRoute(router)
+	go func() {
+		log.Info("Starting metrics server", zap.String("address", cfg.MetricsListenAddress))
+		if err := http.Serve(listener, router); err != nil {
+			log.Error("Metrics server failed", zap.Error(err))
+		}
+	}()
+}
 
-	// Register API routes
-	api.RegisterRoutes(router, cfg.AuthToken) // Pass the router and auth token
+// startGRPCServer serves gRPC Server Reflection for the stored schemas in the background, on
+// GRPC_LISTEN_ADDRESS or GRPC_PORT. Returns nil if neither is configured.
+func startGRPCServer(cfg config.Config, srv *server.Server) *grpc.Server {
+	log := srv.Logger()
+	listenAddr := grpcListenAddress(cfg)
+	if listenAddr == "" {
+		return nil
+	}
+	listener, err := listen(listenAddr)
+	if err != nil {
+		log.Fatal("Failed to listen for gRPC", zap.String("address", listenAddr), zap.Error(err))
+	}
 
-	// Start Server
-	listenAddr := ":" + cfg.ServerPort
-	log.Printf("Starting server on %s", listenAddr)
-	err = http.ListenAndServe(listenAddr, router)
+	grpcServer := srv.NewGRPCServer()
+	go func() {
+		log.Info("Starting gRPC reflection server", zap.String("address", listenAddr))
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Error("gRPC server failed", zap.Error(err))
+		}
+	}()
+	return grpcServer
+}
+
+// initServer initializes the registry (see server.New). Initialization failures are fatal.
+func initServer(cfg config.Config) *server.Server {
+	srv, err := server.New(cfg)
+	if err != nil {
+		fatal("Failed to initialize server", err)
+	}
+	return srv
+}
+
+// initBackends initializes logging, database (and the checksum log kept in it) and storage (see
+// server.InitBackends). Initialization failures are fatal.
+func initBackends(cfg config.Config) *zap.Logger {
+	log, err := server.InitBackends(cfg)
 	if err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+		fatal("Failed to initialize server", err)
+	}
+	return log
+}
+
+// fatal logs err and exits; to stderr if the logger isn't initialized yet.
+func fatal(message string, err error) {
+	if log := logging.L(); log.Core().Enabled(zapcore.FatalLevel) {
+		log.Fatal(message, zap.Error(err))
 	}
+	fmt.Fprintf(os.Stderr, "%s: %v\n", message, err)
+	os.Exit(1)
 }
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"go.uber.org/zap"
+)
+
+// runMigrateDB migrates the database to this release's schema version, for operators who start servers with
+// --skip-migrations and run migrations as a separate deployment step. With --status it only compares the
+// versions, exiting with status 1 if the database isn't at this release's. Either way it refuses databases
+// migrated by a newer release.
+func runMigrateDB(cfg config.Config, args []string) {
+	fs := flag.NewFlagSet("migrate-db", flag.ExitOnError)
+	status := fs.Bool("status", false, "Only print the schema versions of the database and of this release")
+	_ = fs.Parse(args)
+
+	log, err := logging.Init(cfg)
+	if err != nil {
+		fatal("Failed to initialize logger", err)
+	}
+	defer func() { _ = log.Sync() }()
+
+	cfg.SkipMigrations = *status
+	if _, err := db.Init(cfg); err != nil {
+		if errors.Is(err, db.ErrSchemaOutdated) || errors.Is(err, db.ErrSchemaTooNew) {
+			fmt.Println(err)
+			_ = log.Sync()
+			os.Exit(1)
+		}
+		fatal("Failed to initialize database", err)
+	}
+	version, err := db.StoredSchemaVersion(db.GetDB())
+	if err != nil {
+		fatal("Failed to read the database schema version", err)
+	}
+	fmt.Printf("Database schema is at version %d (this release: %d)\n", version, db.SchemaVersion)
+	if !*status {
+		log.Info("Database migrated", zap.Int("schema_version", version))
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Suhaibinator/SProto/internal/api"
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"go.uber.org/zap"
+)
+
+// runMigrateStorage copies every artifact stored under an older key layout to its key in the
+// current layout, verifies the digest, updates the version record and (unless --keep-old) deletes
+// the old object. It is safe to re-run: versions are only marked migrated after the copy succeeded.
+// A running server can do the same with the migrate-storage admin job.
+func runMigrateStorage(cfg config.Config, args []string) {
+	fs := flag.NewFlagSet("migrate-storage", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Only print the artifacts that would be migrated")
+	keepOld := fs.Bool("keep-old", false, "Keep objects under their old keys after copying them")
+	_ = fs.Parse(args)
+
+	log := initBackends(cfg)
+	defer func() { _ = log.Sync() }()
+
+	artifacts, err := api.ListLegacyArtifacts(context.Background())
+	if err != nil {
+		log.Fatal("Failed to list artifacts to migrate", zap.Error(err))
+	}
+	log.Info("Found artifacts stored under older key layouts", zap.Int("count", len(artifacts)), zap.Int("target_layout", storage.CurrentKeyLayout))
+
+	migrated, failed := 0, 0
+	for _, a := range artifacts {
+		if *dryRun {
+			fmt.Printf("%s: %s -> %s\n", a.Coordinates(), a.ArtifactStorageKey, a.NewKey())
+			continue
+		}
+
+		fields := []zap.Field{zap.String("coordinates", a.Coordinates()), zap.String("old_key", a.ArtifactStorageKey), zap.String("new_key", a.NewKey())}
+		if err := api.MigrateArtifact(context.Background(), a, *keepOld); err != nil {
+			log.Error("Failed to migrate artifact", append(fields, zap.Error(err))...)
+			failed++
+			continue
+		}
+		log.Info("Migrated artifact", fields...)
+		migrated++
+	}
+
+	if *dryRun {
+		return
+	}
+	log.Info("Storage migration finished", zap.Int("migrated", migrated), zap.Int("failed", failed))
+	if failed > 0 {
+		_ = log.Sync()
+		os.Exit(1)
+	}
+}
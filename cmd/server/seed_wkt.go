@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Suhaibinator/SProto/internal/api"
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/wkt"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// runSeedWKT publishes the built-in google/protobuf (well-known types) and google/api modules at their
+// pinned versions, so modules depending on them resolve out of the box. Versions that already exist are
+// left untouched, so it is safe to run on every deployment.
+func runSeedWKT(cfg config.Config) {
+	log := initBackends(cfg)
+	defer func() { _ = log.Sync() }()
+
+	modules, err := wkt.Modules()
+	if err != nil {
+		log.Fatal("Failed to load built-in modules", zap.Error(err))
+	}
+	if err := seedModules(cfg.AuthToken, modules); err != nil {
+		log.Error("Failed to seed well-known modules", zap.Error(err))
+		os.Exit(1)
+	}
+}
+
+// seedModules publishes modules through the regular publish endpoint, skipping versions that already exist.
+func seedModules(authToken string, modules []wkt.Module) error {
+	router := mux.NewRouter()
+	api.RegisterRoutes(router, authToken)
+
+	for _, m := range modules {
+		coordinates := fmt.Sprintf("%s/%s@%s", m.Namespace, m.Name, m.Version)
+		status, body, err := publishFiles(router, authToken, m.Namespace, m.Name, m.Version, m.Files)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case http.StatusCreated:
+			fmt.Printf("Published %s\n", coordinates)
+		case http.StatusConflict:
+			fmt.Printf("%s already exists, skipping\n", coordinates)
+		default:
+			return fmt.Errorf("publishing %s returned %d: %s", coordinates, status, body)
+		}
+	}
+	return nil
+}
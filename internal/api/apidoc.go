@@ -0,0 +1,480 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// API document: every route is registered together with a routeDoc describing its operation (summary,
+// parameters, request and response types), and the registry's own OpenAPI 3 document, served at
+// /api/v1/openapi.json, is generated from those registrations. Paths, methods and path parameters come
+// from the routes themselves and schemas from the Go types of the bodies, so the document follows the
+// handlers; clients in other languages can be generated from it and contract tests can check against it.
+
+// APIDocumentPath is where the registry serves its own OpenAPI document.
+const APIDocumentPath = "/api/v1/openapi.json"
+
+// routeAuth is the token a route requires.
+type routeAuth int
+
+const (
+	authRead       routeAuth = iota // None for public modules; a read token for private ones or without PUBLIC_READ
+	authAdmin                       // The admin API token
+	authMaintainer                  // The admin token or a maintainer token of the namespace
+	authNone                        // No token is ever checked (health checks, signed CDN URLs)
+)
+
+// routeDoc describes the operation of a route in the API document.
+type routeDoc struct {
+	ID      string // operationId, unique in the document; GET routes answering HEAD get "head..." for HEAD
+	Tag     string
+	Summary string
+	Auth    routeAuth
+	Path    string // Documented path, for route templates OpenAPI can't express (default: the route's)
+
+	Query   []routeParam
+	Headers []routeParam // Request headers
+	Request any          // A value of the JSON request body's type, or nil
+	Upload  string       // Content type of a non-JSON request body, e.g. multipart/form-data
+	Form    []routeParam // Fields of a multipart/form-data body
+
+	OptionalBody bool // The request body may be left out
+
+	Status    int         // Success status; 200 if 0
+	Response  any         // A value of the JSON success body's type, or nil
+	Download  string      // Content type of a non-JSON success body, instead of Response
+	Responses map[int]any // Other success statuses and a value of their JSON body's type (nil for none)
+
+	Errors      []int       // Error statuses, besides those of Auth; the body is an ErrorResponse...
+	ErrorBodies map[int]any // ...unless listed here
+}
+
+// routeParam describes a query parameter, request header or form field.
+type routeParam struct {
+	Name        string
+	Description string
+	Type        string // string (default), integer, boolean, or binary (a file)
+	Required    bool
+}
+
+// pathParamDescriptions describe the path parameters, by name.
+var pathParamDescriptions = map[string]string{
+	"namespace":   "Module namespace",
+	"module_name": "Module name",
+	"version":     "Version, e.g. v1.2.0",
+	"channel":     "Dev channel name, without the dev- prefix",
+	"classifier":  "Secondary artifact classifier, e.g. openapi",
+	"message":     "Fully-qualified message name, e.g. mycompany.user.v1.User",
+	"package":     "Proto package, e.g. mycompany.user.v1",
+	"name":        "Plugin name",
+	"id":          "Operation, webhook subscription or managed token ID",
+	"job":         "Admin job, e.g. gc",
+	"digest":      "Digest of the original upload: sha256:<hex> or <hex>",
+}
+
+// pathVariable matches the variables of route templates and their optional pattern ({channel:dev-[^/]*}).
+var pathVariable = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// --- Registration ---
+
+// documentedRoute is a registered route with its operation.
+type documentedRoute struct {
+	route *mux.Route
+	doc   routeDoc
+}
+
+// apiDocument collects the documented routes of a router and serves their OpenAPI document.
+type apiDocument struct {
+	routes []documentedRoute
+
+	once sync.Once // The document is generated on first request, once every route is registered
+	body []byte
+	etag string
+	err  error
+}
+
+// add documents a registered route.
+func (d *apiDocument) add(route *mux.Route, doc routeDoc) {
+	d.routes = append(d.routes, documentedRoute{route: route, doc: doc})
+}
+
+// serve responds with the OpenAPI document.
+// GET /api/v1/openapi.json
+func (d *apiDocument) serve(w http.ResponseWriter, r *http.Request) {
+	d.once.Do(func() {
+		d.body, d.err = d.generate()
+		sum := sha256.Sum256(d.body)
+		d.etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	})
+	if d.err != nil {
+		logging.FromContext(r.Context()).Error("Error generating the API document", zap.Error(d.err))
+		response.Error(w, http.StatusInternalServerError, "Failed to generate the API document")
+		return
+	}
+	w.Header().Set("ETag", d.etag)
+	setListCacheHeaders(w)
+	if notModified(w, r, d.etag) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(d.body)
+}
+
+// --- Document ---
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema        `json:"schemas"`
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"` // path, query or header
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// bearerAuth is the name of the document's security scheme: every token is sent as a bearer token.
+const bearerAuth = "bearerAuth"
+
+// generate builds the OpenAPI document of the documented routes.
+func (d *apiDocument) generate() ([]byte, error) {
+	doc := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "SProto Registry API",
+			Version:     "v1",
+			Description: "API of the SProto Protobuf registry, generated from its route registrations.",
+		},
+		Paths: map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{
+			Schemas: map[string]*openAPISchema{},
+			SecuritySchemes: map[string]openAPISecurityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", Description: "The admin API token, a read token or a maintainer token"},
+			},
+		},
+	}
+	g := &apiSchemaGenerator{schemas: doc.Components.Schemas, types: map[string]reflect.Type{}}
+	for _, dr := range d.routes {
+		template, err := dr.route.GetPathTemplate()
+		if err != nil {
+			return nil, err
+		}
+		methods, err := dr.route.GetMethods()
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", template, err)
+		}
+		documentedPath := dr.doc.Path
+		if documentedPath == "" {
+			documentedPath = pathVariable.ReplaceAllString(template, "{$1}")
+		}
+		if doc.Paths[documentedPath] == nil {
+			doc.Paths[documentedPath] = map[string]*openAPIOperation{}
+		}
+		for _, method := range methods {
+			doc.Paths[documentedPath][strings.ToLower(method)] = g.operation(dr.doc, documentedPath, method)
+		}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// operation describes the method of a documented route. HEAD is described like GET, without bodies.
+func (g *apiSchemaGenerator) operation(doc routeDoc, documentedPath, method string) *openAPIOperation {
+	head := method == http.MethodHead
+	op := &openAPIOperation{OperationID: doc.ID, Summary: doc.Summary, Responses: map[string]openAPIResponse{}}
+	if head {
+		op.OperationID = "head" + strings.TrimPrefix(doc.ID, "get")
+	}
+	if doc.Tag != "" {
+		op.Tags = []string{doc.Tag}
+	}
+
+	errorCodes := append([]int(nil), doc.Errors...)
+	switch doc.Auth {
+	case authRead:
+		// Anonymous callers are only refused without PUBLIC_READ
+		op.Security = []map[string][]string{{}, {bearerAuth: {}}}
+		errorCodes = append(errorCodes, http.StatusUnauthorized)
+	case authAdmin, authMaintainer:
+		op.Security = []map[string][]string{{bearerAuth: {}}}
+		errorCodes = append(errorCodes, http.StatusUnauthorized, http.StatusForbidden)
+	}
+
+	for _, match := range pathVariable.FindAllStringSubmatch(documentedPath, -1) {
+		op.Parameters = append(op.Parameters, openAPIParameter{Name: match[1], In: "path", Description: pathParamDescriptions[match[1]], Required: true, Schema: &openAPISchema{Type: "string"}})
+	}
+	for _, p := range doc.Query {
+		op.Parameters = append(op.Parameters, openAPIParameter{Name: p.Name, In: "query", Description: p.Description, Required: p.Required, Schema: paramSchema(p)})
+	}
+	for _, p := range doc.Headers {
+		op.Parameters = append(op.Parameters, openAPIParameter{Name: p.Name, In: "header", Description: p.Description, Required: p.Required, Schema: paramSchema(p)})
+	}
+
+	switch {
+	case head:
+	case doc.Request != nil:
+		op.RequestBody = &openAPIRequestBody{Required: !doc.OptionalBody, Content: jsonContent(g.schemaOf(reflect.TypeOf(doc.Request)))}
+	case doc.Upload != "":
+		schema := &openAPISchema{Type: "string", Format: "binary"}
+		if len(doc.Form) > 0 {
+			schema = &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+			for _, field := range doc.Form {
+				schema.Properties[field.Name] = paramSchema(field)
+				if field.Required {
+					schema.Required = append(schema.Required, field.Name)
+				}
+			}
+		}
+		op.RequestBody = &openAPIRequestBody{Required: !doc.OptionalBody, Content: map[string]openAPIMediaType{doc.Upload: {Schema: schema}}}
+	}
+
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := openAPIResponse{Description: http.StatusText(status)}
+	switch {
+	case head:
+	case doc.Download != "":
+		success.Content = map[string]openAPIMediaType{doc.Download: {Schema: &openAPISchema{Type: "string", Format: "binary"}}}
+	case doc.Response != nil:
+		success.Content = jsonContent(g.schemaOf(reflect.TypeOf(doc.Response)))
+	}
+	op.Responses[fmt.Sprint(status)] = success
+	for other, body := range doc.Responses {
+		resp := openAPIResponse{Description: http.StatusText(other)}
+		if body != nil && !head {
+			resp.Content = jsonContent(g.schemaOf(reflect.TypeOf(body)))
+		}
+		op.Responses[fmt.Sprint(other)] = resp
+	}
+	errorSchema := g.schemaOf(reflect.TypeOf(response.ErrorResponse{}))
+	for _, code := range errorCodes {
+		resp := openAPIResponse{Description: http.StatusText(code)}
+		if !head {
+			resp.Content = jsonContent(errorSchema)
+			if body, ok := doc.ErrorBodies[code]; ok {
+				resp.Content = jsonContent(g.schemaOf(reflect.TypeOf(body)))
+			}
+		}
+		op.Responses[fmt.Sprint(code)] = resp
+	}
+	// Any other error, e.g. 500, or 429 when a limit is reached
+	op.Responses["default"] = openAPIResponse{Description: "Error", Content: jsonContent(errorSchema)}
+	if head {
+		op.Responses["default"] = openAPIResponse{Description: "Error"}
+	}
+	return op
+}
+
+// paramSchema is the schema of a query parameter, header or form field.
+func paramSchema(p routeParam) *openAPISchema {
+	switch p.Type {
+	case "", "string":
+		return &openAPISchema{Type: "string"}
+	case "binary":
+		return &openAPISchema{Type: "string", Format: "binary"}
+	default:
+		return &openAPISchema{Type: p.Type}
+	}
+}
+
+func jsonContent(schema *openAPISchema) map[string]openAPIMediaType {
+	return map[string]openAPIMediaType{"application/json": {Schema: schema}}
+}
+
+// --- Schemas ---
+
+// apiSchemaGenerator derives schemas from Go types as encoding/json marshals them. Named struct types are
+// added to the document's components and referenced.
+type apiSchemaGenerator struct {
+	schemas map[string]*openAPISchema
+	types   map[string]reflect.Type // Type of each component, to tell apart same-named types of other packages
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	uuidType          = reflect.TypeOf(uuid.UUID{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (g *apiSchemaGenerator) schemaOf(t reflect.Type) *openAPISchema {
+	switch t {
+	case timeType:
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case uuidType:
+		return &openAPISchema{Type: "string", Format: "uuid"}
+	case rawMessageType:
+		return &openAPISchema{} // Any JSON value
+	}
+	if t.Kind() != reflect.Pointer && (t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType)) {
+		return &openAPISchema{}
+	}
+	if t.Kind() != reflect.Pointer && (t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)) {
+		return &openAPISchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaOf(t.Elem())
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"} // Base64
+		}
+		return &openAPISchema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.componentRef(t)
+	default: // Interfaces: any JSON value
+		return &openAPISchema{}
+	}
+}
+
+// componentRef adds a named struct type to the components (once) and references it.
+func (g *apiSchemaGenerator) componentRef(t reflect.Type) *openAPISchema {
+	name := t.Name()
+	if other, ok := g.types[name]; ok && other != t {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	ref := &openAPISchema{Ref: "#/components/schemas/" + name}
+	if _, ok := g.types[name]; ok {
+		return ref
+	}
+	g.types[name] = t
+	g.schemas[name] = &openAPISchema{} // Placeholder, for recursive types
+	g.schemas[name] = g.structSchema(t)
+	return ref
+}
+
+// structSchema describes the JSON object of a struct type. Fields without omitempty are required.
+func (g *apiSchemaGenerator) structSchema(t reflect.Type) *openAPISchema {
+	schema := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+	g.addFields(schema, t)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (g *apiSchemaGenerator) addFields(schema *openAPISchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			g.addFields(schema, fieldType) // Embedded fields are promoted
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		property := g.schemaOf(field.Type)
+		if strings.Contains(options, "string") && property.Type != "" {
+			property = &openAPISchema{Type: "string"}
+		}
+		omitEmpty := strings.Contains(options, "omitempty")
+		if field.Type.Kind() == reflect.Pointer && !omitEmpty && property.Ref == "" {
+			property.Nullable = true
+		}
+		schema.Properties[name] = property
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
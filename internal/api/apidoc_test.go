@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIDocument(t *testing.T) {
+	router := mux.NewRouter()
+	RegisterRoutes(router, "")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, APIDocumentPath, nil))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	var doc struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if !assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc)) {
+		return
+	}
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	// Every route (and method) is documented, with a unique operationId
+	routes := 0
+	assert.NoError(t, router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if methods, err := route.GetMethods(); err == nil {
+			routes += len(methods)
+		}
+		return nil
+	}))
+	operationIDs := map[string]bool{}
+	for path, operations := range doc.Paths {
+		for method, op := range operations {
+			id, _ := op["operationId"].(string)
+			assert.NotEmpty(t, id, "%s %s", method, path)
+			assert.False(t, operationIDs[id], "duplicate operationId %s", id)
+			operationIDs[id] = true
+		}
+	}
+	assert.Equal(t, routes, len(operationIDs))
+	assert.True(t, operationIDs["headModuleVersion"])
+
+	// Every referenced schema is defined
+	for _, ref := range regexp.MustCompile(`#/components/schemas/([A-Za-z0-9_.]+)`).FindAllStringSubmatch(rr.Body.String(), -1) {
+		assert.Contains(t, doc.Components.Schemas, ref[1])
+	}
+
+	// Path parameters come from the route templates; patterns are left out
+	getVersion := doc.Paths["/api/v1/modules/{namespace}/{module_name}/{version}"]["get"]
+	if !assert.NotNil(t, getVersion) {
+		return
+	}
+	assert.Len(t, getVersion["parameters"], 3)
+	assert.Equal(t, []interface{}{map[string]interface{}{}, map[string]interface{}{"bearerAuth": []interface{}{}}}, getVersion["security"])
+	assert.Contains(t, rr.Body.String(), `"$ref": "#/components/schemas/ModuleVersionResponse"`)
+	assert.Contains(t, doc.Paths, "/api/v1/modules/{namespace}/{module_name}/dev-{channel}")
+
+	publish := doc.Paths["/api/v1/modules/{namespace}/{module_name}/{version}"]["post"]
+	if !assert.NotNil(t, publish) {
+		return
+	}
+	assert.Equal(t, []interface{}{map[string]interface{}{"bearerAuth": []interface{}{}}}, publish["security"])
+	assert.Contains(t, publish["responses"], "201")
+	assert.Contains(t, publish["responses"], "202")
+	assert.Contains(t, publish["responses"], "401")
+
+	// Schemas follow the JSON encoding of the Go types
+	subscription := doc.Components.Schemas["WebhookSubscriptionResponse"]
+	if !assert.NotNil(t, subscription) {
+		return
+	}
+	properties := subscription["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "uuid"}, properties["id"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "date-time"}, properties["created_at"])
+	assert.Equal(t, map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}, properties["event_types"])
+	assert.Contains(t, subscription["required"], "url")
+	assert.NotContains(t, subscription["required"], "description") // omitempty
+
+	// Cached like other metadata
+	req := httptest.NewRequest(http.MethodGet, APIDocumentPath, nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+}
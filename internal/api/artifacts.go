@@ -0,0 +1,382 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Secondary artifacts: outputs derived from a version's protos (descriptor sets, OpenAPI specs, generated
+// docs, ...) attached to the version under a classifier, stored and fetched independently of the primary
+// artifact. Like the version itself they are immutable: attaching different content under an existing
+// classifier is a conflict, attaching the same content again is a no-op (so CI jobs can retry).
+
+// defaultArtifactContentType is stored for secondary artifacts uploaded without a Content-Type.
+const defaultArtifactContentType = "application/octet-stream"
+
+// AttachVersionArtifactHandler attaches a secondary artifact to a published module version.
+// PUT /api/v1/modules/{namespace}/{module_name}/{version}/artifacts/{classifier}
+// The request body is the artifact itself, stored with the request's Content-Type.
+// Requires Authentication.
+func AttachVersionArtifactHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+	classifier := vars["classifier"]
+	coordinates := fmt.Sprintf("%s/%s@%s", namespace, moduleName, version)
+	log := logging.FromContext(r.Context()).With(zap.String("module_version", coordinates), zap.String("classifier", classifier))
+
+	// --- Input Validation ---
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+	if err := validation.ValidateClassifier(classifier); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = defaultArtifactContentType
+	}
+	if _, _, err := mime.ParseMediaType(contentType); err != nil || len(contentType) > 255 {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid Content-Type %q", contentType))
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, requestLimits.MaxUploadBytes)) // Same limit as publishing
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			response.Error(w, http.StatusRequestEntityTooLarge, uploadLimitMessage())
+		} else {
+			log.Warn("Error reading artifact body", zap.Error(err))
+			response.Error(w, http.StatusBadRequest, "Could not read artifact")
+		}
+		return
+	}
+	if len(data) == 0 {
+		response.Error(w, http.StatusBadRequest, "Artifact must not be empty")
+		return
+	}
+	if !checkUploadDigest(w, r, bytes.NewReader(data)) {
+		return // Response already written
+	}
+
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return // Response already written
+	}
+
+	// --- Virus Scan (optional) ---
+	scanStatus, scanResult, ok := scanArtifact(w, r, canonicalFile{bytes.NewReader(data)}, coordinates+" "+classifier)
+	if !ok {
+		return // Response already written
+	}
+
+	sum := sha256.Sum256(data)
+	digestHex := hex.EncodeToString(sum[:])
+
+	// --- Immutability ---
+	var existing models.VersionArtifact
+	err = db.GetDB().Where("module_version_id = ? AND classifier = ?", moduleVersion.ID, classifier).First(&existing).Error
+	switch {
+	case err == nil && existing.Digest == digestHex:
+		log.Info("Artifact already attached with the same content")
+		response.JSON(w, http.StatusOK, versionArtifactResponse(existing))
+		return
+	case err == nil:
+		response.Error(w, http.StatusConflict, fmt.Sprintf("Artifact '%s' is already attached to %s with a different digest", classifier, coordinates))
+		return
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		log.Error("Error checking for existing artifact", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error during artifact check")
+		return
+	}
+
+	// --- Storage ---
+	storageKey, err := uploadVersionArtifact(r, namespace, moduleName, moduleVersion.Version, classifier, contentType, data, digestHex)
+	if err != nil {
+		log.Error("Error uploading artifact to storage", zap.String("key", storageKey), zap.Error(err))
+		switch status := storageErrorStatus(err); status {
+		case http.StatusInsufficientStorage:
+			response.Error(w, status, "Artifact storage quota exceeded")
+		case http.StatusServiceUnavailable:
+			response.Error(w, status, "Artifact storage unavailable")
+		case http.StatusConflict:
+			response.Error(w, status, "Artifact storage key already holds a different object")
+		default:
+			response.Error(w, http.StatusInternalServerError, "Failed to upload artifact to storage")
+		}
+		return
+	}
+
+	versionArtifact := models.VersionArtifact{
+		ModuleVersionID: moduleVersion.ID,
+		Classifier:      classifier,
+		ContentType:     contentType,
+		Digest:          digestHex,
+		Size:            int64(len(data)),
+		StorageKey:      storageKey,
+		ScanStatus:      scanStatus,
+		ScanResult:      scanResult,
+		CreatedAt:       time.Now().UTC(),
+	}
+	if err := db.GetDB().Create(&versionArtifact).Error; err != nil {
+		log.Error("Error saving artifact record", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error saving artifact")
+		return
+	}
+	log.Info("Attached artifact", zap.String("key", storageKey), zap.Int("size", len(data)), zap.String("content_type", contentType))
+
+	response.JSON(w, http.StatusCreated, versionArtifactResponse(versionArtifact))
+}
+
+// uploadVersionArtifact stores the content of a secondary artifact under its digest-addressed key,
+// returning the key.
+func uploadVersionArtifact(r *http.Request, namespace, moduleName, version, classifier, contentType string, data []byte, digestHex string) (string, error) {
+	storageProvider := storage.GetStorageProvider()
+	storageKey := storage.VersionArtifactKey(namespace, moduleName, version, classifier, digestHex)
+	err := storageProvider.UploadFile(r.Context(), storageKey, bytes.NewReader(data), int64(len(data)), contentType)
+	if errors.Is(err, storage.ErrObjectExists) {
+		// Left over from an earlier attempt whose database write failed (the key is digest-addressed)
+		err = reuseExistingArtifact(r, storageProvider, storageKey, digestHex)
+	}
+	return storageKey, err
+}
+
+// ListVersionArtifactsHandler lists the secondary artifacts attached to a module version.
+// GET /api/v1/modules/{namespace}/{module_name}/{version}/artifacts
+func ListVersionArtifactsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return // Response already written
+	}
+
+	var artifacts []models.VersionArtifact
+	if err := requestDB(r).Where("module_version_id = ?", moduleVersion.ID).Order("classifier ASC").Find(&artifacts).Error; err != nil {
+		logging.FromContext(r.Context()).Error("Error listing version artifacts", zap.Stringer("module_version_id", moduleVersion.ID), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve artifacts")
+		return
+	}
+	respData := apitypes.ListVersionArtifactsResponse{
+		Namespace:  namespace,
+		ModuleName: moduleName,
+		Version:    moduleVersion.Version,
+		Artifacts:  make([]apitypes.VersionArtifactResponse, 0, len(artifacts)), // Empty array, not null
+	}
+	for _, a := range artifacts {
+		respData.Artifacts = append(respData.Artifacts, versionArtifactResponse(a))
+	}
+
+	setListCacheHeaders(w) // Artifacts can still be attached
+	response.JSON(w, http.StatusOK, respData)
+}
+
+// FetchVersionArtifactHandler downloads a secondary artifact of a module version.
+// GET|HEAD /api/v1/modules/{namespace}/{module_name}/{version}/artifacts/{classifier}
+// HEAD returns the headers without the body.
+func FetchVersionArtifactHandler(w http.ResponseWriter, r *http.Request) {
+	serveVersionArtifact(w, r, mux.Vars(r)["classifier"], "Artifact not found")
+}
+
+// serveVersionArtifact serves the secondary artifact attached under classifier to the version in the
+// request's path, responding 404 with notFoundMessage if there is none.
+func serveVersionArtifact(w http.ResponseWriter, r *http.Request, classifier, notFoundMessage string) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+	log := logging.FromContext(r.Context()).With(zap.String("module_version", fmt.Sprintf("%s/%s@%s", namespace, moduleName, version)), zap.String("classifier", classifier))
+
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return // Response already written
+	}
+	if sunsetGone(w, r, moduleVersion) {
+		return // 410 already written
+	}
+	writeVersionArtifact(w, r, log, moduleVersion, classifier, notFoundMessage, fmt.Sprintf("%s-%s-%s", moduleName, moduleVersion.Version, classifier))
+}
+
+// writeVersionArtifact streams the secondary artifact attached under classifier to a version, as a download
+// named filename, responding 404 with notFoundMessage if there is none.
+func writeVersionArtifact(w http.ResponseWriter, r *http.Request, log *zap.Logger, moduleVersion *models.ModuleVersion, classifier, notFoundMessage, filename string) {
+	var versionArtifact models.VersionArtifact
+	err := requestDB(r).Where("module_version_id = ? AND classifier = ?", moduleVersion.ID, classifier).First(&versionArtifact).Error
+	if err != nil {
+		status, message := http.StatusNotFound, notFoundMessage
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Error("Error finding version artifact", zap.Error(err))
+			status, message = http.StatusInternalServerError, "Failed to retrieve artifact"
+		}
+		if r.Method == http.MethodHead {
+			w.WriteHeader(status)
+		} else {
+			response.Error(w, status, message)
+		}
+		return
+	}
+
+	// Immutable, like the primary artifact
+	etag := `"` + versionArtifact.Digest + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Artifact-Digest", "sha256:"+versionArtifact.Digest)
+	w.Header().Set("X-Artifact-Size", strconv.FormatInt(versionArtifact.Size, 10))
+	w.Header().Set("X-Scan-Status", versionArtifact.ScanStatus)
+	setImmutableCacheHeaders(w)
+	if notModified(w, r, etag) {
+		return
+	}
+	w.Header().Set("Content-Type", versionArtifact.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(versionArtifact.Size, 10))
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Streaming holds a slot (MAX_CONCURRENT_ARTIFACT_STREAMS) for the whole download
+	limit := streamSlots
+	if !limit.acquire(w, r) {
+		return // 429 already written
+	}
+	defer limit.release()
+
+	stream, err := storage.GetStorageProvider().DownloadFile(r.Context(), versionArtifact.StorageKey)
+	if err != nil {
+		w.Header().Del("Content-Length")
+		switch status := storageErrorStatus(err); status {
+		case http.StatusNotFound:
+			log.Warn("Artifact not found in storage", zap.String("key", versionArtifact.StorageKey), zap.Error(err))
+			response.Error(w, status, "Artifact not found in storage")
+		case http.StatusServiceUnavailable:
+			log.Error("Storage unavailable while downloading artifact", zap.String("key", versionArtifact.StorageKey), zap.Error(err))
+			response.Error(w, status, "Artifact storage unavailable")
+		default:
+			log.Error("Error downloading artifact from storage", zap.String("key", versionArtifact.StorageKey), zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to retrieve artifact from storage")
+		}
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, filename, url.PathEscape(filename)))
+	if _, err := io.Copy(w, stream); err != nil {
+		// The client may have disconnected; headers are already sent
+		log.Warn("Error streaming artifact to client", zap.Error(err))
+	}
+}
+
+// attachedArtifact is a module version with the secondary artifact attached to it under some classifier.
+type attachedArtifact struct {
+	Version            string
+	PublishedAt        time.Time
+	DeprecationMessage string
+	DeprecatedAt       *time.Time
+	Digest             string // SHA256 hex string of the artifact
+	StorageKey         string
+	AttachedAt         time.Time
+	semver             *semver.Version
+}
+
+// versionsWithArtifact lists the semantic versions of a module with a secondary artifact attached under
+// classifier, ascending.
+func versionsWithArtifact(r *http.Request, namespace, name, classifier string) ([]attachedArtifact, error) {
+	var rows []attachedArtifact
+	err := requestDB(r).WithContext(r.Context()).Table("version_artifacts").
+		Select("module_versions.version, module_versions.created_at AS published_at, module_versions.deprecation_message, module_versions.deprecated_at, version_artifacts.digest, version_artifacts.storage_key, version_artifacts.created_at AS attached_at").
+		Joins("JOIN module_versions ON module_versions.id = version_artifacts.module_version_id").
+		Joins("JOIN modules ON modules.id = module_versions.module_id").
+		Where("modules.namespace = ? AND modules.name = ? AND version_artifacts.classifier = ?", namespace, name, classifier).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	versions := rows[:0]
+	for _, row := range rows {
+		if v, err := semver.NewVersion(row.Version); err == nil {
+			row.semver = v
+			versions = append(versions, row)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].semver.LessThan(versions[j].semver) })
+	return versions, nil
+}
+
+// findVersionArtifact finds the secondary artifact attached under classifier to a version.
+func findVersionArtifact(ctx context.Context, database *gorm.DB, moduleVersion *models.ModuleVersion, classifier string) (*models.VersionArtifact, error) {
+	var versionArtifact models.VersionArtifact
+	err := database.WithContext(ctx).Where("module_version_id = ? AND classifier = ?", moduleVersion.ID, classifier).First(&versionArtifact).Error
+	if err != nil {
+		return nil, err
+	}
+	return &versionArtifact, nil
+}
+
+// readVersionArtifact downloads a secondary artifact from storage, for handlers that serve something derived
+// from it. On failure a response has been written and ok is false.
+func readVersionArtifact(w http.ResponseWriter, r *http.Request, log *zap.Logger, versionArtifact *models.VersionArtifact) ([]byte, bool) {
+	stream, err := storage.GetStorageProvider().DownloadFile(r.Context(), versionArtifact.StorageKey)
+	if err == nil {
+		defer stream.Close()
+		var buf bytes.Buffer
+		if _, err = io.Copy(&buf, stream); err == nil {
+			return buf.Bytes(), true
+		}
+	}
+	log.Error("Error downloading artifact from storage", zap.String("key", versionArtifact.StorageKey), zap.Error(err))
+	if status := storageErrorStatus(err); status == http.StatusServiceUnavailable {
+		response.Error(w, status, "Artifact storage unavailable")
+	} else {
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve artifact from storage")
+	}
+	return nil, false
+}
+
+func versionArtifactResponse(a models.VersionArtifact) apitypes.VersionArtifactResponse {
+	return apitypes.VersionArtifactResponse{
+		Classifier:  a.Classifier,
+		ContentType: a.ContentType,
+		Digest:      "sha256:" + a.Digest,
+		Size:        a.Size,
+		ScanStatus:  a.ScanStatus,
+		CreatedAt:   a.CreatedAt,
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for secondary artifacts ---
+
+func TestVersionArtifactHandlers(t *testing.T) {
+	gormDB := newTestDB(t)
+	newTestStorage(t)
+
+	module := models.Module{Namespace: "acme", Name: "billing", Visibility: models.VisibilityPublic}
+	assert.NoError(t, gormDB.Create(&module).Error)
+	assert.NoError(t, gormDB.Create(&models.ModuleVersion{ModuleID: module.ID, Version: "v1.0.0", VersionKey: validation.VersionKey("v1.0.0"), ArtifactDigest: "abc123", ArtifactStorageKey: "k"}).Error)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/artifacts", ListVersionArtifactsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/artifacts/{classifier}", FetchVersionArtifactHandler).Methods("GET", "HEAD")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/artifacts/{classifier}", AttachVersionArtifactHandler).Methods("PUT")
+	do := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/modules/acme/billing/"+path, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), readerKey, reader{}))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	spec := `{"openapi":"3.0.0"}`
+	rr := do("PUT", "v1.0.0/artifacts/openapi", "application/json", spec)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var attached apitypes.VersionArtifactResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &attached))
+	digest := sha256.Sum256([]byte(spec))
+	assert.Equal(t, "sha256:"+hex.EncodeToString(digest[:]), attached.Digest)
+	assert.Equal(t, int64(len(spec)), attached.Size)
+	assert.Equal(t, "application/json", attached.ContentType)
+
+	// Immutable: the same content again is a no-op, different content a conflict
+	assert.Equal(t, http.StatusOK, do("PUT", "v1.0.0/artifacts/openapi", "application/json", spec).Code)
+	assert.Equal(t, http.StatusConflict, do("PUT", "v1.0.0/artifacts/openapi", "application/json", `{}`).Code)
+	assert.Equal(t, http.StatusCreated, do("PUT", "v1.0.0/artifacts/docs", "", "<html></html>").Code)
+
+	rr = do("GET", "v1.0.0/artifacts/openapi", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, spec, rr.Body.String())
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, attached.Digest, rr.Header().Get("X-Artifact-Digest"))
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "immutable")
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "billing-v1.0.0-openapi")
+
+	rr = do("HEAD", "v1.0.0/artifacts/docs", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, defaultArtifactContentType, rr.Header().Get("Content-Type"))
+	assert.Empty(t, rr.Body.String())
+
+	rr = do("GET", "v1.0.0/artifacts", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list apitypes.ListVersionArtifactsResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	if assert.Len(t, list.Artifacts, 2) {
+		assert.Equal(t, "docs", list.Artifacts[0].Classifier)
+		assert.Equal(t, "openapi", list.Artifacts[1].Classifier)
+	}
+
+	assert.Equal(t, http.StatusNotFound, do("GET", "v1.0.0/artifacts/swift", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("PUT", "v9.0.0/artifacts/docs", "", "x").Code)
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "v1.0.0/artifacts/Docs", "", "x").Code)
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "v1.0.0/artifacts/docs2", "", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "v1.0.0/artifacts/docs2", "not a type", "x").Code)
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Brute-force protection: failed authentication attempts are counted per client IP. Every failure is
+// answered after an escalating delay, and a client reaching the failure limit within the window is
+// banned (429) from authenticating for a while. Anonymous reads of public modules are never blocked.
+
+// AuthFailurePolicy configures the brute-force protection.
+type AuthFailurePolicy struct {
+	MaxFailures    int           // Failures within Window before the client is banned; 0 disables bans
+	Window         time.Duration // Failures older than this are forgotten
+	BanDuration    time.Duration // How long a banned client can't authenticate
+	FailureDelay   time.Duration // Delay before answering the first failure, doubled for each further one; 0 disables delays
+	ClientIPHeader string        // Header set by a trusted reverse proxy holding the client IP (last entry wins); RemoteAddr if empty
+}
+
+// DefaultAuthFailurePolicy is used until SetAuthFailurePolicy is called (protection disabled).
+var DefaultAuthFailurePolicy = AuthFailurePolicy{}
+
+// maxAuthFailureDelay caps the escalating delay, so slow responses can't tie up the server.
+const maxAuthFailureDelay = 5 * time.Second
+
+// Global brute-force protection, configured at startup via SetAuthFailurePolicy.
+var authGuard = newAuthFailureGuard(DefaultAuthFailurePolicy)
+
+// SetAuthFailurePolicy configures the brute-force protection (and forgets recorded failures).
+func SetAuthFailurePolicy(p AuthFailurePolicy) {
+	authGuard = newAuthFailureGuard(p)
+}
+
+// authFailureGuard tracks failed attempts per client.
+type authFailureGuard struct {
+	policy  AuthFailurePolicy
+	mu      sync.Mutex
+	clients map[string]*authFailures
+}
+
+type authFailures struct {
+	count       int       // Failures in the current window
+	windowStart time.Time // First failure of the current window
+	bannedUntil time.Time
+}
+
+func newAuthFailureGuard(p AuthFailurePolicy) *authFailureGuard {
+	return &authFailureGuard{policy: p, clients: map[string]*authFailures{}}
+}
+
+// bannedFor returns how long the client remains banned, or 0.
+func (g *authFailureGuard) bannedFor(client string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f, ok := g.clients[client]; ok {
+		if remaining := time.Until(f.bannedUntil); remaining > 0 {
+			return remaining
+		}
+	}
+	return 0
+}
+
+// fail records a failed attempt and returns the delay to apply and whether the client got banned by it.
+func (g *authFailureGuard) fail(client string) (time.Duration, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+	if len(g.clients) > 10000 {
+		g.prune(now) // Keep memory bounded when many addresses fail
+	}
+	f, ok := g.clients[client]
+	if !ok {
+		f = &authFailures{}
+		g.clients[client] = f
+	}
+	if now.Sub(f.windowStart) > g.policy.Window {
+		f.count, f.windowStart = 0, now // Start a new window
+	}
+	f.count++
+
+	var delay time.Duration
+	if g.policy.FailureDelay > 0 {
+		delay = time.Duration(math.Min(float64(g.policy.FailureDelay)*math.Pow(2, float64(f.count-1)), float64(maxAuthFailureDelay)))
+	}
+	if g.policy.MaxFailures > 0 && f.count >= g.policy.MaxFailures {
+		f.bannedUntil = now.Add(g.policy.BanDuration)
+		f.count, f.windowStart = 0, now // Start over once the ban ends
+		return delay, true
+	}
+	return delay, false
+}
+
+// succeed forgets the failures of a client that authenticated successfully.
+func (g *authFailureGuard) succeed(client string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f, ok := g.clients[client]; ok && !time.Now().Before(f.bannedUntil) {
+		delete(g.clients, client)
+	}
+}
+
+// prune drops clients that are neither banned nor within a failure window. Callers hold g.mu.
+func (g *authFailureGuard) prune(now time.Time) {
+	for client, f := range g.clients {
+		if now.After(f.bannedUntil) && now.Sub(f.windowStart) > g.policy.Window {
+			delete(g.clients, client)
+		}
+	}
+}
+
+// clientIP returns the address failures are counted against.
+func clientIP(r *http.Request) string {
+	if header := authGuard.policy.ClientIPHeader; header != "" {
+		if values := strings.Split(r.Header.Get(header), ","); strings.TrimSpace(values[len(values)-1]) != "" {
+			return strings.TrimSpace(values[len(values)-1]) // Added by the trusted proxy
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr // Unix sockets and tests
+	}
+	if host == "" {
+		host = "unknown"
+	}
+	return host
+}
+
+// tokenPrefix returns the start of a presented token for the audit log; enough to recognize a guessing
+// campaign or a mistyped token without revealing a valid one.
+func tokenPrefix(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return token[:4] + "…"
+}
+
+// --- Middleware Helpers ---
+
+// rejectBannedClient responds 429 if the client is banned from authenticating and reports whether it did.
+func rejectBannedClient(w http.ResponseWriter, r *http.Request) bool {
+	remaining := authGuard.bannedFor(clientIP(r))
+	if remaining <= 0 {
+		return false
+	}
+	metrics.AuthBannedRequestsTotal.Inc()
+	logging.Audit(r.Context()).Warn("Rejected authentication attempt from banned client",
+		zap.String("event", "auth.banned_request"), zap.String("client_ip", clientIP(r)))
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	response.Error(w, http.StatusTooManyRequests, "Too many failed authentication attempts, retry later")
+	return true
+}
+
+// rejectAuth records a failed authentication attempt (metrics, audit log, ban), waits for the escalating
+// delay and responds 401 with message.
+func rejectAuth(w http.ResponseWriter, r *http.Request, reason, token, message string) {
+	client := clientIP(r)
+	metrics.AuthFailuresTotal.WithLabelValues(reason).Inc()
+	delay, banned := authGuard.fail(client)
+
+	audit := logging.Audit(r.Context()).With(zap.String("client_ip", client), zap.String("reason", reason))
+	if token != "" {
+		audit = audit.With(zap.String("token_prefix", tokenPrefix(token)))
+	}
+	audit.Warn("Authentication failed", zap.String("event", "auth.failure"))
+	if banned {
+		metrics.AuthBansTotal.Inc()
+		audit.Warn("Client banned after repeated authentication failures",
+			zap.String("event", "auth.ban"), zap.Duration("ban_duration", authGuard.policy.BanDuration))
+	}
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done(): // Client gave up
+		}
+	}
+	response.Error(w, http.StatusUnauthorized, message)
+}
+
+// acceptAuth forgets the failures of a client that authenticated successfully.
+func acceptAuth(r *http.Request) {
+	authGuard.succeed(clientIP(r))
+}
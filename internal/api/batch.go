@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// MaxBatchGetModules is the maximum number of modules per batchGet request.
+const MaxBatchGetModules = 100
+
+// ModuleCoordinates identifies a module and, optionally, one of its versions.
+type ModuleCoordinates struct {
+	Namespace  string `json:"namespace"`
+	ModuleName string `json:"module_name"`
+	Version    string `json:"version,omitempty"` // Empty for the latest version
+}
+
+// BatchGetModulesRequest is the JSON body of POST /api/v1/modules:batchGet.
+type BatchGetModulesRequest struct {
+	Modules []ModuleCoordinates `json:"modules"`
+}
+
+// BatchGetModulesResponse holds one result per requested module, in request order.
+type BatchGetModulesResponse struct {
+	Results []BatchGetModuleResult `json:"results"`
+}
+
+// BatchGetModuleResult is the metadata of a single requested module.
+// Modules or versions that don't exist are reported in Error rather than failing the whole request.
+type BatchGetModuleResult struct {
+	Namespace          string            `json:"namespace"`
+	ModuleName         string            `json:"module_name"`
+	Error              string            `json:"error,omitempty"`
+	CreatedAt          *time.Time        `json:"created_at,omitempty"`
+	UpdatedAt          *time.Time        `json:"updated_at,omitempty"`
+	VersionCount       int               `json:"version_count"`
+	LatestVersion      string            `json:"latest_version,omitempty"`      // Most recently published
+	Version            *BatchVersionInfo `json:"version,omitempty"`             // The requested version, or the latest
+	DeprecatedVersions []string          `json:"deprecated_versions,omitempty"` // Sorted semantically descending
+}
+
+// BatchVersionInfo is the metadata of a module version in a batchGet result.
+type BatchVersionInfo struct {
+	Version            string     `json:"version"`
+	ArtifactDigest     string     `json:"artifact_digest"` // sha256:<hex_digest>
+	ArtifactSize       int64      `json:"artifact_size"`
+	ScanStatus         string     `json:"scan_status"`
+	CreatedAt          time.Time  `json:"created_at"`
+	Deprecated         bool       `json:"deprecated"`
+	DeprecationMessage string     `json:"deprecation_message,omitempty"`
+	SunsetAt           *time.Time `json:"sunset_at,omitempty"`
+	Sunset             bool       `json:"sunset"`
+}
+
+// BatchGetModulesHandler returns the metadata, latest version and deprecation status of several modules
+// in one call, e.g. for dashboards tracking many schemas.
+// POST /api/v1/modules:batchGet
+func BatchGetModulesHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+
+	var req BatchGetModulesRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if len(req.Modules) == 0 {
+		response.Error(w, http.StatusBadRequest, "At least one module is required")
+		return
+	}
+	if len(req.Modules) > MaxBatchGetModules {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("At most %d modules can be requested at once", MaxBatchGetModules))
+		return
+	}
+	pairs := make([][]interface{}, 0, len(req.Modules))
+	for i, c := range req.Modules {
+		if c.Namespace == "" || c.ModuleName == "" {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("modules[%d]: namespace and module_name are required", i))
+			return
+		}
+		if c.Version != "" && !strings.HasPrefix(c.Version, "v") {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("modules[%d]: invalid version format: must start with 'v'", i))
+			return
+		}
+		pairs = append(pairs, []interface{}{c.Namespace, c.ModuleName})
+	}
+
+	// --- Load Modules and Versions (two queries for the whole batch) ---
+	gormDB := db.GetReadDB() // Read-only despite the POST
+	var modules []models.Module
+	if err := gormDB.Where("(namespace, name) IN ?", pairs).Find(&modules).Error; err != nil {
+		log.Error("Error loading modules for batchGet", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve modules")
+		return
+	}
+	rd := readerFromContext(r.Context())
+	byName := make(map[string]*models.Module, len(modules))
+	moduleIDs := make([]uuid.UUID, 0, len(modules))
+	for i := range modules {
+		if !rd.canRead(modules[i].Namespace, modules[i].Name, modules[i].Visibility) {
+			continue // Reported as not found
+		}
+		byName[modules[i].Namespace+"/"+modules[i].Name] = &modules[i]
+		moduleIDs = append(moduleIDs, modules[i].ID)
+	}
+
+	versionsByModule := map[uuid.UUID][]models.ModuleVersion{}
+	if len(moduleIDs) > 0 {
+		var versions []models.ModuleVersion
+		err := gormDB.Select("module_id, version, artifact_digest, artifact_size, scan_status, created_at, deprecated_at, deprecation_message, sunset_at, sunset_enforced_at").
+			Where("module_id IN ?", moduleIDs).
+			Order("created_at DESC").
+			Find(&versions).Error
+		if err != nil {
+			log.Error("Error loading module versions for batchGet", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to retrieve module versions")
+			return
+		}
+		for _, v := range versions {
+			versionsByModule[v.ModuleID] = append(versionsByModule[v.ModuleID], v)
+		}
+	}
+
+	// --- Assemble Results (in request order) ---
+	results := make([]BatchGetModuleResult, 0, len(req.Modules))
+	for _, c := range req.Modules {
+		result := BatchGetModuleResult{Namespace: c.Namespace, ModuleName: c.ModuleName}
+		module, found := byName[c.Namespace+"/"+c.ModuleName]
+		if !found {
+			result.Error = "Module not found"
+			results = append(results, result)
+			continue
+		}
+		result.CreatedAt = &module.CreatedAt
+		result.UpdatedAt = &module.UpdatedAt
+
+		versions := versionsByModule[module.ID] // Newest first
+		result.VersionCount = len(versions)
+		if len(versions) > 0 {
+			result.LatestVersion = versions[0].Version // Same definition as the module list
+		}
+		for i := range versions {
+			v := &versions[i]
+			if v.DeprecatedAt != nil {
+				result.DeprecatedVersions = append(result.DeprecatedVersions, v.Version)
+			}
+			if (c.Version == "" && i == 0) || v.Version == c.Version {
+				result.Version = batchVersionInfo(v)
+			}
+		}
+		sortVersionsDesc(result.DeprecatedVersions)
+		if c.Version != "" && result.Version == nil {
+			result.Error = "Module version not found"
+		}
+		results = append(results, result)
+	}
+
+	setListCacheHeaders(w)
+	response.JSON(w, http.StatusOK, BatchGetModulesResponse{Results: results})
+}
+
+func batchVersionInfo(v *models.ModuleVersion) *BatchVersionInfo {
+	return &BatchVersionInfo{
+		Version:            v.Version,
+		ArtifactDigest:     "sha256:" + v.ArtifactDigest,
+		ArtifactSize:       v.ArtifactSize,
+		ScanStatus:         v.ScanStatus,
+		CreatedAt:          v.CreatedAt,
+		Deprecated:         v.DeprecatedAt != nil,
+		DeprecationMessage: v.DeprecationMessage,
+		SunsetAt:           v.SunsetAt,
+		Sunset:             v.SunsetEnforcedAt != nil,
+	}
+}
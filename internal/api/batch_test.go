@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchGetModulesHandler(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules:batchGet", BatchGetModulesHandler).Methods("POST")
+	batchGet := func(body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/api/v1/modules:batchGet", bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Invalid requests
+	assert.Equal(t, http.StatusBadRequest, batchGet(`{"modules":[]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, batchGet(`{"modules":[{"namespace":"my-org"}]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, batchGet(`{"modules":[{"namespace":"my-org","module_name":"a","version":"1.0.0"}]}`).Code)
+
+	billingID, userID := uuid.New(), uuid.New()
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "modules" WHERE (namespace, name) IN (($1,$2),($3,$4),($5,$6),($7,$8))`)).
+		WithArgs("my-org", "billing", "my-org", "user", "my-org", "user", "my-org", "missing").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "namespace", "name", "created_at", "updated_at"}).
+			AddRow(billingID, "my-org", "billing", created, created).
+			AddRow(userID, "my-org", "user", created, created))
+	deprecatedAt := created.Add(time.Hour)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT module_id, version, artifact_digest, artifact_size, scan_status, created_at, deprecated_at, deprecation_message, sunset_at, sunset_enforced_at FROM "module_versions" WHERE module_id IN ($1,$2) ORDER BY created_at DESC`)).
+		WithArgs(billingID, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"module_id", "version", "artifact_digest", "artifact_size", "scan_status", "created_at", "deprecated_at", "deprecation_message"}).
+			AddRow(billingID, "v2.0.1", "bbb", 200, "clean", created.Add(2*time.Hour), nil, nil).
+			AddRow(billingID, "v2.0.0", "aaa", 100, "clean", created.Add(time.Hour), deprecatedAt, "rounding bug, use v2.0.1").
+			AddRow(userID, "v1.0.0", "ccc", 50, "skipped", created, nil, nil))
+
+	rr := batchGet(`{"modules":[
+		{"namespace":"my-org","module_name":"billing"},
+		{"namespace":"my-org","module_name":"user","version":"v1.0.0"},
+		{"namespace":"my-org","module_name":"user","version":"v9.9.9"},
+		{"namespace":"my-org","module_name":"missing"}
+	]}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp BatchGetModulesResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Results, 4) {
+		billing := resp.Results[0]
+		assert.Empty(t, billing.Error)
+		assert.Equal(t, 2, billing.VersionCount)
+		assert.Equal(t, "v2.0.1", billing.LatestVersion)
+		assert.Equal(t, []string{"v2.0.0"}, billing.DeprecatedVersions)
+		if assert.NotNil(t, billing.Version) {
+			assert.Equal(t, "v2.0.1", billing.Version.Version)
+			assert.Equal(t, "sha256:bbb", billing.Version.ArtifactDigest)
+			assert.False(t, billing.Version.Deprecated)
+		}
+
+		user := resp.Results[1]
+		assert.Empty(t, user.Error)
+		if assert.NotNil(t, user.Version) {
+			assert.Equal(t, "v1.0.0", user.Version.Version)
+		}
+
+		assert.Equal(t, "Module version not found", resp.Results[2].Error)
+		assert.Equal(t, "v1.0.0", resp.Results[2].LatestVersion, "module metadata is still reported")
+		assert.Equal(t, "Module not found", resp.Results[3].Error)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
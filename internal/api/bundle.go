@@ -0,0 +1,155 @@
+package api
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// GetModuleVersionBundleHandler returns a module version flattened together with its dependencies, for tools
+// that can't handle one include path per module.
+// GET /api/v1/modules/{namespace}/{module_name}/{version}/bundle?format=zip|descriptor_set
+// Dependencies resolve like at compile time (newest published version matching each constraint), so unlike
+// the artifact a bundle can change when dependencies publish; it is cached like metadata, with an ETag.
+func GetModuleVersionBundleHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = apitypes.BundleFormatZip
+	}
+	if format != apitypes.BundleFormatZip && format != apitypes.BundleFormatDescriptorSet {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid format %q: must be %s or %s", format, apitypes.BundleFormatZip, apitypes.BundleFormatDescriptorSet))
+		return
+	}
+
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return // Response already written
+	}
+	if sunsetGone(w, r, moduleVersion) {
+		return // 410 already written
+	}
+	recordFetch(r, moduleVersion.ID) // Consumption report
+
+	// Assembling downloads every dependency's artifact, so it holds a stream slot like a download
+	limit := streamSlots
+	if !limit.acquire(w, r) {
+		return // 429 already written
+	}
+	defer limit.release()
+
+	// --- Assembly ---
+	log = log.With(zap.String("module_version", namespace+"/"+moduleName+"@"+version), zap.String("format", format))
+	bundle, deps, ok := readableBundle(w, r, log, namespace, moduleName, version)
+	if !ok {
+		return // Response already written
+	}
+
+	var body []byte
+	var contentType, filename string
+	switch format {
+	case apitypes.BundleFormatDescriptorSet:
+		set, err := bundle.DescriptorSet(r.Context())
+		if err == nil {
+			body, err = proto.MarshalOptions{Deterministic: true}.Marshal(set)
+		}
+		if err != nil {
+			writeBundleError(w, log, err)
+			return
+		}
+		contentType, filename = "application/x-protobuf", fmt.Sprintf("%s-%s.binpb", moduleName, bundle.Version)
+	default:
+		var err error
+		if body, err = bundle.Zip(); err != nil {
+			writeBundleError(w, log, err)
+			return
+		}
+		contentType, filename = "application/zip", fmt.Sprintf("%s-%s-bundle.zip", moduleName, bundle.Version)
+	}
+
+	// The content is deterministic, so its digest identifies it
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+	w.Header().Set("ETag", etag)
+	w.Header().Set(apitypes.BundleDependenciesHeader, strings.Join(deps, ", "))
+	setListCacheHeaders(w)
+	if notModified(w, r, etag) {
+		return
+	}
+
+	log.Info("Serving bundle", zap.Int("dependencies", len(deps)), zap.Int("size", len(body)))
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, filename, url.PathEscape(filename)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		log.Warn("Error writing bundle to client", zap.Error(err))
+	}
+}
+
+// readableBundle assembles a module version with its dependencies (see descriptor.Loader.Bundle), returning
+// it with the "namespace/name@version" of each dependency. Dependencies the caller can't read must not leak
+// through what is built from the bundle, so they are rejected with 403. On failure a response has been
+// written and ok is false.
+func readableBundle(w http.ResponseWriter, r *http.Request, log *zap.Logger, namespace, moduleName, version string) (*descriptor.Bundle, []string, bool) {
+	loader := descriptor.NewLoader(requestDB(r), storage.GetStorageProvider())
+	bundle, err := loader.Bundle(r.Context(), namespace, moduleName, version)
+	if err != nil {
+		writeBundleError(w, log, err)
+		return nil, nil, false
+	}
+
+	deps := make([]string, 0, len(bundle.Dependencies))
+	for _, dep := range bundle.Dependencies {
+		depNamespace, depName, _ := manifest.SplitModule(dep.Module) // Validated by the loader
+		readable, err := moduleReadable(r, depNamespace, depName)
+		if err != nil {
+			log.Error("Error checking dependency visibility", zap.String("dependency", dep.Module), zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to assemble bundle")
+			return nil, nil, false
+		}
+		if !readable {
+			response.Error(w, http.StatusForbidden, fmt.Sprintf("Not allowed to read dependency %s", dep.Module))
+			return nil, nil, false
+		}
+		deps = append(deps, dep.Module+"@"+dep.Version)
+	}
+	return bundle, deps, true
+}
+
+// writeBundleError maps errors assembling or compiling a bundle to a response.
+func writeBundleError(w http.ResponseWriter, log *zap.Logger, err error) {
+	switch {
+	case errors.Is(err, descriptor.ErrNotFound):
+		// The version exists (checked before), so a dependency has no matching published version
+		response.Error(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, descriptor.ErrCompile):
+		response.Error(w, http.StatusUnprocessableEntity, err.Error())
+	case storageErrorStatus(err) == http.StatusServiceUnavailable:
+		log.Error("Storage unavailable while assembling bundle", zap.Error(err))
+		response.Error(w, http.StatusServiceUnavailable, "Artifact storage unavailable")
+	default:
+		log.Error("Error assembling bundle", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to assemble bundle")
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for the bundle endpoint ---
+
+func TestGetModuleVersionBundleHandler(t *testing.T) {
+	gormDB := newTestDB(t)
+	provider := newTestStorage(t)
+
+	publish := func(name, visibility string, files map[string]string) {
+		buf := new(bytes.Buffer)
+		zw := zip.NewWriter(buf)
+		for p, content := range files {
+			w, err := zw.Create(p)
+			assert.NoError(t, err)
+			_, _ = w.Write([]byte(content))
+		}
+		assert.NoError(t, zw.Close())
+		module := models.Module{Namespace: "acme", Name: name, Visibility: visibility}
+		assert.NoError(t, gormDB.Create(&module).Error)
+		key := "acme/" + name + "/v1.0.0.zip"
+		assert.NoError(t, provider.UploadFile(context.Background(), key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "application/zip"))
+		assert.NoError(t, gormDB.Create(&models.ModuleVersion{ModuleID: module.ID, Version: "v1.0.0", VersionKey: validation.VersionKey("v1.0.0"), ArtifactDigest: name, ArtifactStorageKey: key}).Error)
+	}
+	publish("secret", models.VisibilityPrivate, map[string]string{
+		"acme/secret/v1/key.proto": `syntax = "proto3"; package acme.secret.v1; message Key { string id = 1; }`,
+	})
+	publish("billing", models.VisibilityPublic, map[string]string{
+		"sproto.yaml":                   "name: acme/billing\ndependencies:\n  acme/secret: ^1.0.0\n",
+		"acme/billing/v1/billing.proto": `syntax = "proto3"; package acme.billing.v1; import "acme/secret/v1/key.proto"; message Charge { acme.secret.v1.Key key = 1; }`,
+	})
+
+	get := func(query string, rd reader, header http.Header) *httptest.ResponseRecorder {
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/bundle", GetModuleVersionBundleHandler)
+		req := httptest.NewRequest("GET", "/api/v1/modules/acme/billing/v1.0.0/bundle"+query, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req = req.WithContext(context.WithValue(req.Context(), readerKey, rd))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The dependency is private: it must not leak through the public module's bundle
+	rr := get("", reader{}, nil)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "acme/secret")
+
+	rr = get("", reader{admin: true}, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "acme/secret@v1.0.0", rr.Header().Get(apitypes.BundleDependenciesHeader))
+	zipReader, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if assert.NoError(t, err) && assert.Len(t, zipReader.File, 2) {
+		assert.Equal(t, "acme/billing/v1/billing.proto", zipReader.File[0].Name)
+		assert.Equal(t, "acme/secret/v1/key.proto", zipReader.File[1].Name)
+	}
+
+	// Revalidation
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, http.StatusNotModified, get("", reader{admin: true}, http.Header{"If-None-Match": {etag}}).Code)
+
+	rr = get("?format=descriptor_set", reader{admin: true}, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/x-protobuf", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "billing-v1.0.0.binpb")
+	assert.Contains(t, rr.Body.String(), "acme/secret/v1/key.proto")
+
+	assert.Equal(t, http.StatusBadRequest, get("?format=tar", reader{admin: true}, nil).Code)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CachePolicy controls the Cache-Control headers sent to clients and CDNs.
+type CachePolicy struct {
+	// ArtifactMaxAge applies to artifact downloads. Published artifacts are content-addressed and never
+	// change, so they are marked immutable and can be cached for a long time.
+	ArtifactMaxAge time.Duration
+	// ListMaxAge applies to listings and metadata that change when versions are published.
+	// 0 sends "no-cache" (shared caches must revalidate every time).
+	ListMaxAge time.Duration
+}
+
+// DefaultCachePolicy is used until SetCachePolicy is called.
+var DefaultCachePolicy = CachePolicy{
+	ArtifactMaxAge: 365 * 24 * time.Hour,
+	ListMaxAge:     30 * time.Second,
+}
+
+// Global cache policy, configured at startup via SetCachePolicy.
+var cachePolicy = DefaultCachePolicy
+
+// SetCachePolicy configures the Cache-Control headers for artifact and list responses.
+func SetCachePolicy(p CachePolicy) {
+	cachePolicy = p
+}
+
+// setImmutableCacheHeaders marks a response as never changing (versioned artifacts).
+func setImmutableCacheHeaders(w http.ResponseWriter) {
+	if cachePolicy.ArtifactMaxAge <= 0 {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int64(cachePolicy.ArtifactMaxAge.Seconds())))
+}
+
+// setListCacheHeaders marks a response as cacheable for a short time (listings and metadata).
+func setListCacheHeaders(w http.ResponseWriter) {
+	if cachePolicy.ListMaxAge <= 0 {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int64(cachePolicy.ListMaxAge.Seconds())))
+}
+
+// etagMatches reports whether the request's If-None-Match header matches etag (weak comparison).
+func etagMatches(r *http.Request, etag string) bool {
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if etag == "" || ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified writes 304 Not Modified if the request's If-None-Match header matches etag, and reports
+// whether it did (the caller should then stop). The ETag and Cache-Control headers must already be set.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	if !etagMatches(r, etag) {
+		return false
+	}
+	w.Header().Del("Content-Length") // Describes the body we are not sending
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// preconditionsMet evaluates the If-Match and If-None-Match headers of a write to a resource whose current
+// ETag is etag ("" if it doesn't exist yet). Clients send the ETag they read in If-Match, so a write based
+// on a stale read fails (412) instead of overwriting a concurrent change; If-None-Match: * only creates.
+// If-Match uses the strong comparison: weak ETags never match.
+func preconditionsMet(r *http.Request, etag string) bool {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if etag == "" {
+			return false
+		}
+		matched := false
+		for _, candidate := range strings.Split(ifMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == etag || candidate == "*" {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return !etagMatches(r, etag)
+}
+
+// hasPreconditions reports whether a write request carries If-Match or If-None-Match.
+func hasPreconditions(r *http.Request) bool {
+	return r.Header.Get("If-Match") != "" || r.Header.Get("If-None-Match") != ""
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for caching headers ---
+
+func TestFetchModuleVersionArtifactHandler_ImmutableAndRevalidation(t *testing.T) {
+	_, mock := setupMockDB(t)
+	provider := newTestStorage(t)
+
+	content := []byte("fake zip content")
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+	key := "v2/modules/my-org/my-module/v1.0.0/" + digest + ".zip"
+	assert.NoError(t, provider.UploadFile(context.Background(), key, bytes.NewReader(content), int64(len(content)), "application/zip"))
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+			WithArgs("my-org", "my-module", "v1.0.0", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "module_id", "version", "artifact_digest", "artifact_storage_key", "artifact_size"}).
+				AddRow(uuid.New(), uuid.New(), "v1.0.0", digest, key, len(content)))
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/artifact", FetchModuleVersionArtifactHandler).Methods("GET", "HEAD")
+
+	// First download: full body, cacheable forever
+	req, err := http.NewRequest("GET", "/api/v1/modules/my-org/my-module/v1.0.0/artifact", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, content, rr.Body.Bytes())
+	assert.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+	etag := rr.Header().Get("ETag")
+	assert.Equal(t, `"`+digest+`"`, etag)
+
+	// Revalidation with the ETag: 304 without a body
+	req, err = http.NewRequest("GET", "/api/v1/modules/my-org/my-module/v1.0.0/artifact", nil)
+	assert.NoError(t, err)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Empty(t, rr.Header().Get("Content-Length"))
+	assert.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListModulesHandler_ShortCacheTTL(t *testing.T) {
+	_, mock := setupMockDB(t)
+	SetCachePolicy(CachePolicy{ArtifactMaxAge: DefaultCachePolicy.ArtifactMaxAge, ListMaxAge: 0})
+	t.Cleanup(func() { SetCachePolicy(DefaultCachePolicy) })
+
+	mock.ExpectQuery(`WITH LatestVersions AS`).
+		WillReturnRows(sqlmock.NewRows([]string{"namespace", "name", "latest_version"}))
+
+	req, err := http.NewRequest("GET", "/api/v1/modules", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(ListModulesHandler).ServeHTTP(rr, req)
+
+	// --- Assertions ---
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/repo"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Canary rollouts: a version marked as canary with a rollout percentage is handed out by the latest
+// resolution (GET .../latest) to that fraction of consumers only; everyone else keeps getting the newest
+// stable version. Consumers are assigned by a hash of their client ID and the module, so a client gets
+// the same answer on every call and raising the percentage only adds clients. Lifting the canary mark
+// (DELETE) completes the rollout: the version becomes an ordinary one.
+
+// LatestVersionResponse is the version GET .../latest resolved for the caller.
+type LatestVersionResponse struct {
+	Namespace  string `json:"namespace"`
+	ModuleName string `json:"module_name"`
+	Version    string `json:"version"`
+	Canary     bool   `json:"canary"` // The caller is part of the version's canary rollout
+}
+
+// SetCanaryHandler marks a module version as canary with a rollout percentage (PUT) or lifts the mark,
+// completing the rollout (DELETE).
+// PUT|DELETE /api/v1/modules/{namespace}/{module_name}/{version}/canary
+func SetCanaryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+	log := logging.FromContext(r.Context()).With(zap.String("module_version", fmt.Sprintf("%s/%s@%s", namespace, moduleName, version)))
+
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+
+	var percent *int
+	if r.Method == http.MethodPut {
+		var req apitypes.CanaryRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		if req.Percent == nil || *req.Percent < 0 || *req.Percent > 100 {
+			response.Error(w, http.StatusBadRequest, "percent is required and must be between 0 and 100")
+			return
+		}
+		percent = req.Percent
+	}
+
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return // Response already written
+	}
+	if err := primaryRepositories().Versions.SetCanary(r.Context(), moduleVersion.ID, percent); err != nil {
+		log.Error("Error updating canary status", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error updating canary status")
+		return
+	}
+
+	respData := apitypes.CanaryResponse{Namespace: namespace, ModuleName: moduleName, Version: moduleVersion.Version}
+	if percent != nil {
+		respData.Canary, respData.Percent = true, *percent
+		log.Info("Set canary rollout", zap.Int("percent", *percent))
+	} else {
+		log.Info("Completed canary rollout")
+	}
+	response.JSON(w, http.StatusOK, respData)
+}
+
+// ResolveLatestHandler returns the latest version of a module for the calling client: the newest canary
+// newer than the newest stable version whose rollout includes the client, or else the newest stable
+// version (see manifest.Newest). The client is identified by the client_id query parameter, the
+// X-SProto-Client-ID header or the caller's token, in that order.
+// GET /api/v1/modules/{namespace}/{module_name}/latest
+func ResolveLatestHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	log := logging.FromContext(r.Context()).With(zap.String("module", namespace+"/"+moduleName))
+
+	// Modules the caller isn't allowed to read are reported as not found
+	repos := requestRepositories(r)
+	readable, err := moduleReadable(r, namespace, moduleName)
+	var module *models.Module
+	if err == nil && readable {
+		module, err = repos.Modules.Get(r.Context(), namespace, moduleName)
+	}
+	var versions []repo.VersionSummary
+	if err == nil && module != nil {
+		versions, err = repos.Versions.ListByModule(r.Context(), module.ID)
+	}
+	if errors.Is(err, repo.ErrNotFound) || (err == nil && !readable) {
+		response.Error(w, http.StatusNotFound, "Module not found")
+		return
+	}
+	if err != nil {
+		log.Error("Error resolving latest version", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to resolve the latest version")
+		return
+	}
+
+	version, canary := resolveLatest(versions, canaryBucket(namespace+"/"+moduleName, requestClientID(r)))
+	if version == "" {
+		response.Error(w, http.StatusNotFound, "Module has no version available to this client")
+		return
+	}
+	// The answer depends on the client, so shared caches mustn't store it
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Add("Vary", apitypes.ClientIDHeader)
+	w.Header().Add("Vary", "Authorization")
+	response.JSON(w, http.StatusOK, LatestVersionResponse{Namespace: namespace, ModuleName: moduleName, Version: version, Canary: canary})
+}
+
+// requestClientID returns the client ID canary rollouts assign the request by.
+func requestClientID(r *http.Request) string {
+	if id := r.URL.Query().Get("client_id"); id != "" {
+		return id
+	}
+	if id := r.Header.Get(apitypes.ClientIDHeader); id != "" {
+		return id
+	}
+	if consumer := readerFromContext(r.Context()).consumer; consumer != "" {
+		return consumer
+	}
+	return AnonymousConsumer
+}
+
+// canaryBucket deterministically maps a client to a bucket 0-99 of a module's rollouts. Clients in buckets
+// below a canary's percentage get it.
+func canaryBucket(module, clientID string) int {
+	sum := sha256.Sum256([]byte(module + "\x00" + clientID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
+
+// resolveLatest returns the latest version for a client in bucket, and whether it is a canary.
+func resolveLatest(versions []repo.VersionSummary, bucket int) (string, bool) {
+	var stable []string
+	var canaries []repo.VersionSummary
+	for _, v := range versions {
+		if v.CanaryPercent == nil {
+			stable = append(stable, v.Version)
+		} else if _, err := semver.NewVersion(v.Version); err == nil {
+			canaries = append(canaries, v)
+		}
+	}
+	latest := manifest.Newest(stable)
+
+	// The newest canary first; canaries older than the stable version are moot
+	sort.Slice(canaries, func(i, j int) bool { return manifest.IsOlder(canaries[j].Version, canaries[i].Version) })
+	for _, c := range canaries {
+		if latest != "" && !manifest.IsOlder(latest, c.Version) {
+			break
+		}
+		if bucket < *c.CanaryPercent {
+			return c.Version, true
+		}
+	}
+	return latest, false
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/repo"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanaryRollout(t *testing.T) {
+	gormDB := newTestDB(t)
+	module := models.Module{Namespace: "acme", Name: "user", Visibility: models.VisibilityPublic}
+	assert.NoError(t, gormDB.Create(&module).Error)
+	for _, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0-rc.1"} {
+		assert.NoError(t, gormDB.Create(&models.ModuleVersion{ModuleID: module.ID, Version: v, VersionKey: validation.VersionKey(v), ArtifactDigest: "aaa"}).Error)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/modules/{namespace}/{module_name}/latest", ResolveLatestHandler).Methods("GET")
+	router.HandleFunc("/modules/{namespace}/{module_name}/{version}/canary", SetCanaryHandler).Methods("PUT", "DELETE")
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	latest := func(clientID string) LatestVersionResponse {
+		rr := send("GET", "/modules/acme/user/latest?client_id="+clientID, "")
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, "private, no-cache", rr.Header().Get("Cache-Control"))
+		var resp LatestVersionResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+	// Clients inside and outside a 30% rollout
+	var inside, outside string
+	for i := 0; inside == "" || outside == ""; i++ {
+		if id := fmt.Sprintf("client-%d", i); canaryBucket("acme/user", id) < 30 {
+			inside = id
+		} else {
+			outside = id
+		}
+	}
+
+	assert.Equal(t, "v1.1.0", latest(inside).Version)
+	for _, body := range []string{`{}`, `{"percent":101}`, `{"percent":-1}`, `nope`} {
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/modules/acme/user/v2.0.0/canary", body).Code, body)
+	}
+	assert.Equal(t, http.StatusNotFound, send("PUT", "/modules/acme/user/v2.0.0/canary", `{"percent":30}`).Code)
+	assert.NoError(t, gormDB.Create(&models.ModuleVersion{ModuleID: module.ID, Version: "v2.0.0", VersionKey: validation.VersionKey("v2.0.0"), ArtifactDigest: "bbb"}).Error)
+
+	// Published before being marked: everyone sees it until the rollout starts
+	assert.Equal(t, "v2.0.0", latest(outside).Version)
+	rr := send("PUT", "/modules/acme/user/v2.0.0/canary", `{"percent":30}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"namespace":"acme","module_name":"user","version":"v2.0.0","canary":true,"percent":30}`, rr.Body.String())
+	assert.Equal(t, LatestVersionResponse{Namespace: "acme", ModuleName: "user", Version: "v2.0.0", Canary: true}, latest(inside))
+	assert.Equal(t, LatestVersionResponse{Namespace: "acme", ModuleName: "user", Version: "v1.1.0"}, latest(outside))
+
+	// Completing the rollout makes it the stable version
+	rr = send("DELETE", "/modules/acme/user/v2.0.0/canary", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"namespace":"acme","module_name":"user","version":"v2.0.0","canary":false,"percent":0}`, rr.Body.String())
+	assert.Equal(t, LatestVersionResponse{Namespace: "acme", ModuleName: "user", Version: "v2.0.0"}, latest(outside))
+
+	assert.Equal(t, http.StatusNotFound, send("GET", "/modules/acme/nope/latest", "").Code)
+}
+
+func TestResolveLatest(t *testing.T) {
+	percent := func(p int) *int { return &p }
+	versions := []repo.VersionSummary{
+		{Version: "v1.0.0"},
+		{Version: "v1.1.0", CanaryPercent: percent(50)},
+		{Version: "v1.2.0", CanaryPercent: percent(10)},
+		{Version: "v0.9.0", CanaryPercent: percent(100)}, // Older than the stable version
+	}
+	for bucket, want := range map[int]string{0: "v1.2.0", 9: "v1.2.0", 10: "v1.1.0", 49: "v1.1.0", 50: "v1.0.0", 99: "v1.0.0"} {
+		version, canary := resolveLatest(versions, bucket)
+		assert.Equal(t, want, version, bucket)
+		assert.Equal(t, want != "v1.0.0", canary, bucket)
+	}
+	// Only canaries: those outside the rollouts get nothing
+	version, _ := resolveLatest(versions[1:2], 70)
+	assert.Empty(t, version)
+	assert.Equal(t, canaryBucket("acme/user", "client"), canaryBucket("acme/user", "client"))
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Capacity: counting the objects of the bucket and the rows of the database means listing and scanning
+// them, so a background collector gathers them every CAPACITY_COLLECT_INTERVAL into the metrics and a
+// snapshot reported by /readyz. With the capacity of the bucket and database configured, /readyz also
+// warns once their usage crosses CAPACITY_WARN_PERCENT, before disks or quotas fill.
+
+// CapacityPolicy configures the capacity warnings of /readyz.
+type CapacityPolicy struct {
+	StorageBytes  int64 // Capacity of the bucket (quota) or local storage disk; 0 if unknown (no warnings)
+	DatabaseBytes int64 // Capacity of the database disk or plan; 0 if unknown (no warnings)
+	WarnPercent   int   // Warn when usage crosses this percentage of the capacity
+}
+
+// capacityPolicy is the configured policy; see SetCapacityPolicy.
+var capacityPolicy CapacityPolicy
+
+// SetCapacityPolicy configures the capacity warnings of /readyz.
+func SetCapacityPolicy(p CapacityPolicy) {
+	capacityPolicy = p
+}
+
+// StorageCapacity is the content of the storage bucket as of the last collection.
+type StorageCapacity struct {
+	Objects       int64     `json:"objects"`
+	TotalBytes    int64     `json:"total_bytes"`
+	CapacityBytes int64     `json:"capacity_bytes,omitempty"` // Omitted when no capacity is configured
+	PercentUsed   *float64  `json:"percent_used,omitempty"`   // Omitted when no capacity is configured
+	CollectedAt   time.Time `json:"collected_at"`
+}
+
+// DatabaseCapacity is the size of the metadata database as of the last collection.
+type DatabaseCapacity struct {
+	SizeBytes     int64            `json:"size_bytes"`
+	Rows          map[string]int64 `json:"rows"`
+	CapacityBytes int64            `json:"capacity_bytes,omitempty"` // Omitted when no capacity is configured
+	PercentUsed   *float64         `json:"percent_used,omitempty"`   // Omitted when no capacity is configured
+	CollectedAt   time.Time        `json:"collected_at"`
+}
+
+// capacitySnapshot holds the last successful collection of each source (nil until then).
+var capacitySnapshot struct {
+	sync.Mutex
+	storage  *StorageCapacity
+	database *DatabaseCapacity
+}
+
+// collectCapacity gathers the content of the bucket and the size of the database into the metrics and the
+// snapshot. A source that fails keeps its previous values; the errors are returned joined.
+func collectCapacity(ctx context.Context) error {
+	var errs []error
+	if stats, err := storage.Stats(ctx, storage.GetStorageProvider()); err != nil {
+		errs = append(errs, fmt.Errorf("storage: %w", err))
+	} else {
+		now := time.Now().UTC()
+		metrics.StorageObjects.Set(float64(stats.Objects))
+		metrics.StorageBytes.Set(float64(stats.TotalBytes))
+		metrics.CapacityLastCollectTimestamp.WithLabelValues("storage").Set(float64(now.Unix()))
+		capacitySnapshot.Lock()
+		capacitySnapshot.storage = &StorageCapacity{Objects: stats.Objects, TotalBytes: stats.TotalBytes, CollectedAt: now}
+		capacitySnapshot.Unlock()
+	}
+
+	if size, err := db.Size(ctx, db.GetDB()); err != nil {
+		errs = append(errs, fmt.Errorf("database: %w", err))
+	} else {
+		now := time.Now().UTC()
+		metrics.DBSizeBytes.Set(float64(size.Bytes))
+		for table, rows := range size.Rows {
+			metrics.DBTableRows.WithLabelValues(table).Set(float64(rows))
+		}
+		metrics.CapacityLastCollectTimestamp.WithLabelValues("database").Set(float64(now.Unix()))
+		capacitySnapshot.Lock()
+		capacitySnapshot.database = &DatabaseCapacity{SizeBytes: size.Bytes, Rows: size.Rows, CollectedAt: now}
+		capacitySnapshot.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// RunCapacityCollector collects storage and database capacity every interval, until ctx is canceled.
+func RunCapacityCollector(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := collectCapacity(ctx); err != nil && ctx.Err() == nil {
+			logging.L().Warn("Failed to collect capacity", zap.String("job", "capacity"), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Readiness statuses of /readyz.
+const (
+	ReadyStatusOK          = "ok"          // Database and storage reachable
+	ReadyStatusWarning     = "warning"     // Ready, but capacity crossed the warning threshold
+	ReadyStatusUnavailable = "unavailable" // Database or storage unreachable (503)
+)
+
+// ReadyzResponse is the body of /readyz.
+type ReadyzResponse struct {
+	Status   string            `json:"status"`
+	Checks   map[string]string `json:"checks"` // "database" and "storage": "ok" or the error
+	Storage  *StorageCapacity  `json:"storage,omitempty"`
+	Database *DatabaseCapacity `json:"database,omitempty"`
+	Warnings []string          `json:"warnings,omitempty"`
+}
+
+// readyzProbeKey is looked up to check that storage is reachable; it never exists.
+const readyzProbeKey = "readyz/probe"
+
+// ReadyzHandler reports whether the server can serve requests: the database and storage are reachable.
+// It responds 503 if either isn't, and 200 otherwise, with the last collected capacity and, when usage
+// crossed CAPACITY_WARN_PERCENT of a configured capacity, status "warning" and the warnings.
+// GET /readyz
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	resp := ReadyzResponse{Status: ReadyStatusOK, Checks: map[string]string{"database": "ok", "storage": "ok"}}
+	if err := pingDatabase(ctx); err != nil {
+		log.Warn("Readiness check failed", zap.String("check", "database"), zap.Error(err))
+		resp.Checks["database"] = err.Error()
+		resp.Status = ReadyStatusUnavailable
+	}
+	if _, err := storage.GetStorageProvider().FileExists(ctx, readyzProbeKey); err != nil {
+		log.Warn("Readiness check failed", zap.String("check", "storage"), zap.Error(err))
+		resp.Checks["storage"] = err.Error()
+		resp.Status = ReadyStatusUnavailable
+	}
+
+	capacitySnapshot.Lock()
+	if capacitySnapshot.storage != nil {
+		storageCapacity := *capacitySnapshot.storage
+		resp.Storage = &storageCapacity
+	}
+	if capacitySnapshot.database != nil {
+		databaseCapacity := *capacitySnapshot.database
+		resp.Database = &databaseCapacity
+	}
+	capacitySnapshot.Unlock()
+
+	if resp.Storage != nil && capacityPolicy.StorageBytes > 0 {
+		percent := percentOf(resp.Storage.TotalBytes, capacityPolicy.StorageBytes)
+		resp.Storage.CapacityBytes, resp.Storage.PercentUsed = capacityPolicy.StorageBytes, &percent
+		if capacityPolicy.WarnPercent > 0 && percent >= float64(capacityPolicy.WarnPercent) {
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("Storage uses %d of %d bytes (%.1f%%)", resp.Storage.TotalBytes, capacityPolicy.StorageBytes, percent))
+		}
+	}
+	if resp.Database != nil && capacityPolicy.DatabaseBytes > 0 {
+		percent := percentOf(resp.Database.SizeBytes, capacityPolicy.DatabaseBytes)
+		resp.Database.CapacityBytes, resp.Database.PercentUsed = capacityPolicy.DatabaseBytes, &percent
+		if capacityPolicy.WarnPercent > 0 && percent >= float64(capacityPolicy.WarnPercent) {
+			resp.Warnings = append(resp.Warnings, fmt.Sprintf("Database uses %d of %d bytes (%.1f%%)", resp.Database.SizeBytes, capacityPolicy.DatabaseBytes, percent))
+		}
+	}
+
+	status := http.StatusOK
+	if resp.Status == ReadyStatusUnavailable {
+		status = http.StatusServiceUnavailable
+	} else if len(resp.Warnings) > 0 {
+		resp.Status = ReadyStatusWarning
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, status, resp)
+}
+
+// pingDatabase checks that the (primary) database is reachable.
+func pingDatabase(ctx context.Context) error {
+	sqlDB, err := db.GetDB().DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
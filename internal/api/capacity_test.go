@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadyzHandler(t *testing.T) {
+	gormDB := newTestDB(t)
+	provider := newTestStorage(t)
+	t.Cleanup(func() {
+		SetCapacityPolicy(CapacityPolicy{})
+		capacitySnapshot.storage, capacitySnapshot.database = nil, nil
+	})
+
+	readyz := func() (int, ReadyzResponse) {
+		rr := httptest.NewRecorder()
+		ReadyzHandler(rr, httptest.NewRequest("GET", "/readyz", nil))
+		var resp ReadyzResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return rr.Code, resp
+	}
+
+	// Ready before the first collection, without capacity
+	code, resp := readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ReadyStatusOK, resp.Status)
+	assert.Equal(t, map[string]string{"database": "ok", "storage": "ok"}, resp.Checks)
+	assert.Nil(t, resp.Storage)
+
+	// Collected capacity is reported, with warnings past the threshold of a configured capacity
+	assert.NoError(t, provider.UploadFile(context.Background(), "acme/user/v1.0.0.zip", strings.NewReader("0123456789"), 10, "application/zip"))
+	assert.NoError(t, gormDB.Create(&models.Module{Namespace: "acme", Name: "user"}).Error)
+	// A table that can't be counted fails the collection, but not that of the storage
+	assert.NoError(t, gormDB.Migrator().DropTable(&models.ClientUsage{}))
+	assert.Error(t, collectCapacity(context.Background()))
+	assert.NotNil(t, capacitySnapshot.storage)
+	_, err := db.Migrate(gormDB)
+	assert.NoError(t, err)
+	assert.NoError(t, collectCapacity(context.Background()))
+	SetCapacityPolicy(CapacityPolicy{StorageBytes: 12, WarnPercent: 80})
+
+	code, resp = readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ReadyStatusWarning, resp.Status)
+	if assert.NotNil(t, resp.Storage) && assert.NotNil(t, resp.Database) {
+		assert.Equal(t, int64(1), resp.Storage.Objects)
+		assert.Equal(t, int64(10), resp.Storage.TotalBytes)
+		assert.Equal(t, int64(12), resp.Storage.CapacityBytes)
+		assert.Equal(t, int64(1), resp.Database.Rows["modules"])
+		assert.Greater(t, resp.Database.SizeBytes, int64(0))
+		assert.Nil(t, resp.Database.PercentUsed, "no database capacity configured")
+	}
+	assert.Equal(t, []string{"Storage uses 10 of 12 bytes (83.3%)"}, resp.Warnings)
+
+	// Unreachable database
+	sqlDB, err := gormDB.DB()
+	assert.NoError(t, err)
+	assert.NoError(t, sqlDB.Close())
+	code, resp = readyz()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, ReadyStatusUnavailable, resp.Status)
+	assert.NotEqual(t, "ok", resp.Checks["database"])
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/cdn"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// CDNOriginPathPrefix is the path prefix of the signed origin endpoint the CDN pulls artifacts from.
+const CDNOriginPathPrefix = "/cdn/v1/modules"
+
+// Global CDN signer. nil unless CDN signed URL mode is configured.
+var cdnSigner *cdn.Signer
+
+// SetCDNSigner enables CDN signed URL mode (nil disables it).
+func SetCDNSigner(s *cdn.Signer) {
+	cdnSigner = s
+}
+
+// cdnArtifactPath returns the origin path of a module version's artifact, as signed in CDN URLs.
+func cdnArtifactPath(namespace, moduleName, version string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/artifact", CDNOriginPathPrefix, namespace, moduleName, version)
+}
+
+// redirectToCDN responds with a 302 to a short-lived signed CDN URL for the artifact.
+func redirectToCDN(w http.ResponseWriter, r *http.Request, namespace, moduleName, version string) {
+	signedURL := cdnSigner.SignURL(cdnArtifactPath(namespace, moduleName, version), time.Now())
+	// The redirect expires with the signature, so it must not be cached
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, signedURL, http.StatusFound)
+}
+
+// CDNArtifactHandler is the origin endpoint behind the CDN. It serves an artifact only if the
+// request carries a valid, unexpired signature issued by the artifact endpoint.
+// GET|HEAD /cdn/v1/modules/{namespace}/{module_name}/{version}/artifact?expires=...&signature=...
+func CDNArtifactHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	if cdnSigner == nil {
+		response.Error(w, http.StatusNotFound, "CDN signed URLs are not enabled")
+		return
+	}
+
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+
+	if err := cdnSigner.Verify(cdnArtifactPath(namespace, moduleName, version), r.URL.Query(), time.Now()); err != nil {
+		log.Info("Rejected CDN artifact request", zap.String("path", r.URL.Path), zap.Error(err))
+		if errors.Is(err, cdn.ErrExpired) {
+			response.Error(w, http.StatusForbidden, "Signed URL expired")
+		} else {
+			response.Error(w, http.StatusForbidden, "Invalid signature")
+		}
+		return
+	}
+
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return
+	}
+	if sunsetGone(w, r, moduleVersion) {
+		return // Signed URLs issued before the sunset was enforced
+	}
+	serveArtifact(w, r, moduleVersion)
+}
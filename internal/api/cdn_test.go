@@ -0,0 +1,69 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Suhaibinator/SProto/internal/cdn"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for CDN signed URL mode ---
+
+func TestFetchModuleVersionArtifactHandler_CDNRedirectAndOrigin(t *testing.T) {
+	_, mock := setupMockDB(t)
+	provider := newTestStorage(t)
+	signer, err := cdn.NewSigner("https://cdn.example.com", "secret", time.Minute)
+	assert.NoError(t, err)
+	SetCDNSigner(signer)
+	t.Cleanup(func() { SetCDNSigner(nil) })
+
+	content := []byte("fake zip content")
+	key := "v2/modules/my-org/my-module/v1.0.0/abc.zip"
+	assert.NoError(t, provider.UploadFile(context.Background(), key, bytes.NewReader(content), int64(len(content)), "application/zip"))
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+			WithArgs("my-org", "my-module", "v1.0.0", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "module_id", "version", "artifact_digest", "artifact_storage_key"}).
+				AddRow(uuid.New(), uuid.New(), "v1.0.0", "abc", key))
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/artifact", FetchModuleVersionArtifactHandler).Methods("GET", "HEAD")
+	router.HandleFunc(CDNOriginPathPrefix+"/{namespace}/{module_name}/{version}/artifact", CDNArtifactHandler).Methods("GET", "HEAD")
+
+	// The API endpoint redirects to a signed CDN URL
+	req, err := http.NewRequest("GET", "/api/v1/modules/my-org/my-module/v1.0.0/artifact", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	location, err := url.Parse(rr.Header().Get("Location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "cdn.example.com", location.Host)
+
+	// The CDN pulls from the origin endpoint with the signed path and query
+	req, err = http.NewRequest("GET", location.RequestURI(), nil)
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, content, rr.Body.Bytes())
+
+	// Tampered signatures are rejected before touching the database
+	req, err = http.NewRequest("GET", location.Path+"?expires=9999999999&signature=00", nil)
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.JSONEq(t, `{"error":"Invalid signature"}`, rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/changelog"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// ModuleVersionChangelogResponse is the changelog section of a single module version.
+type ModuleVersionChangelogResponse struct {
+	Namespace  string `json:"namespace"`
+	ModuleName string `json:"module_name"`
+	Version    string `json:"version"`
+	Changelog  string `json:"changelog"` // Markdown
+}
+
+// GetModuleVersionChangelogHandler returns the CHANGELOG.md section extracted when the version was published.
+// GET /api/v1/modules/{namespace}/{module_name}/{version}/changelog
+func GetModuleVersionChangelogHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return // Response already written
+	}
+	if moduleVersion.Changelog == "" {
+		response.Error(w, http.StatusNotFound, "No changelog for this version")
+		return
+	}
+
+	// The changelog is fixed at publish time, like the artifact it was extracted from
+	setImmutableCacheHeaders(w)
+	response.JSON(w, http.StatusOK, ModuleVersionChangelogResponse{
+		Namespace:  namespace,
+		ModuleName: moduleName,
+		Version:    moduleVersion.Version,
+		Changelog:  moduleVersion.Changelog,
+	})
+}
+
+// readChangelog extracts the section for version from the uploaded artifact's CHANGELOG.md. The file is
+// rewound afterwards. A missing or unreadable changelog never fails a publish; "" is returned instead.
+func readChangelog(ctx context.Context, file multipart.File, version string) string {
+	artifact, err := io.ReadAll(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("Error reading artifact for changelog extraction", zap.Error(err))
+		return ""
+	}
+	return artifactChangelog(ctx, artifact, version)
+}
+
+// artifactChangelog extracts the section for version from an artifact's CHANGELOG.md, logging (but
+// otherwise ignoring) errors.
+func artifactChangelog(ctx context.Context, artifact []byte, version string) string {
+	section, err := changelog.FromArtifact(artifact, version)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to extract changelog from artifact", zap.String("version", version), zap.Error(err))
+		return ""
+	}
+	return section
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetModuleVersionChangelogHandler(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/changelog", GetModuleVersionChangelogHandler).Methods("GET")
+	get := func(version string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/api/v1/modules/my-org/my-module/"+version+"/changelog", nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	versionRows := func(version, changelog string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "module_id", "version", "artifact_digest", "changelog"}).
+			AddRow(uuid.New(), uuid.New(), version, "abc123", changelog)
+	}
+
+	mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+		WithArgs("my-org", "my-module", "v1.1.0", 1).
+		WillReturnRows(versionRows("v1.1.0", "- Contains hotfix for billing rounding"))
+	rr := get("v1.1.0")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"namespace":"my-org","module_name":"my-module","version":"v1.1.0","changelog":"- Contains hotfix for billing rounding"}`, rr.Body.String())
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "immutable")
+
+	// Published without a CHANGELOG.md section
+	mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+		WithArgs("my-org", "my-module", "v1.0.0", 1).
+		WillReturnRows(versionRows("v1.0.0", ""))
+	rr = get("v1.0.0")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":"No changelog for this version"}`, rr.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, get("1.0.0").Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,327 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/translog"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Checksum log: every published version's digest is appended to an append-only Merkle tree (see package
+// translog). Clients can record the log's root hash and check inclusion proofs for what they fetch, so a
+// registry serving different bytes for a version it already published can be detected.
+
+// Global checksum log settings, configured at startup via SetChecksumLog and SetChecksumSigningKey.
+var (
+	checksumLog        *translog.Log      // nil disables the log (the endpoints return 503)
+	checksumSigningKey ed25519.PrivateKey // nil leaves statements unsigned
+)
+
+// SetChecksumLog configures the checksum log publishes are appended to.
+func SetChecksumLog(l *translog.Log) {
+	checksumLog = l
+}
+
+// SetChecksumSigningKey configures the key signing the checksum statements in fetch and metadata responses.
+func SetChecksumSigningKey(key ed25519.PrivateKey) {
+	checksumSigningKey = key
+}
+
+// Paging of GET /api/v1/checksums.
+const (
+	defaultChecksumPageSize = 100
+	maxChecksumPageSize     = 1000
+)
+
+// recordChecksum appends a newly published version to the checksum log. It runs after the publish is
+// committed and never fails it: versions missing from the log are appended by the Sync at the next startup.
+func recordChecksum(ctx context.Context, namespace, moduleName, version, digestHex string) {
+	if checksumLog == nil {
+		return
+	}
+	log := logging.FromContext(ctx)
+	entry, err := checksumLog.Append(ctx, namespace+"/"+moduleName, version, "sha256:"+digestHex)
+	if err != nil {
+		log.Error("Failed to append version to the checksum log", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", version), zap.Error(err))
+		return
+	}
+	log.Debug("Appended version to the checksum log", zap.Int64("index", entry.Index), zap.String("root_hash", entry.RootHash))
+}
+
+// --- Attestations ---
+
+// Headers carrying a version's checksum statement on artifact responses (see checksumAttestation).
+const (
+	checksumIndexHeader     = "X-Checksum-Log-Index"
+	checksumStatementHeader = "X-Checksum-Statement" // Base64 of the statement text
+	checksumSignatureHeader = "X-Checksum-Signature" // Base64 Ed25519 signature of the statement text
+)
+
+// checksumStatement returns a version's statement and its signature ("" when signing isn't configured),
+// or ok=false if the checksum log is disabled or doesn't have the version (yet).
+func checksumStatement(ctx context.Context, namespace, moduleName, version string) (translog.Statement, string, bool) {
+	if checksumLog == nil {
+		return translog.Statement{}, "", false
+	}
+	entry, err := checksumLog.Find(ctx, namespace+"/"+moduleName, version)
+	if err != nil {
+		if !errors.Is(err, translog.ErrNotFound) {
+			logging.FromContext(ctx).Warn("Error looking up checksum log entry", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", version), zap.Error(err))
+		}
+		return translog.Statement{}, "", false
+	}
+	statement := translog.Statement{
+		Module:   entry.Module,
+		Version:  entry.Version,
+		Digest:   entry.Digest,
+		Index:    entry.Index,
+		TreeSize: entry.Index + 1,
+		RootHash: entry.RootHash,
+	}
+	signature := ""
+	if checksumSigningKey != nil {
+		signature = translog.SignStatement(checksumSigningKey, statement.Marshal())
+	}
+	return statement, signature, true
+}
+
+// checksumAttestation returns a version's attestation for the metadata response, or nil if it has none.
+func checksumAttestation(ctx context.Context, namespace, moduleName, version string) *apitypes.ChecksumAttestation {
+	statement, signature, ok := checksumStatement(ctx, namespace, moduleName, version)
+	if !ok {
+		return nil
+	}
+	proof, err := checksumLog.Prove(ctx, statement.Module, statement.Version, statement.TreeSize)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Error computing checksum inclusion proof", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", version), zap.Error(err))
+		return nil
+	}
+	attestation := &apitypes.ChecksumAttestation{
+		Index:     statement.Index,
+		TreeSize:  statement.TreeSize,
+		RootHash:  statement.RootHash,
+		Proof:     proof.Hashes,
+		Statement: string(statement.Marshal()),
+		Signature: signature,
+	}
+	if attestation.Proof == nil {
+		attestation.Proof = []string{}
+	}
+	return attestation
+}
+
+// setChecksumHeaders adds a version's statement (and signature) to an artifact response.
+func setChecksumHeaders(w http.ResponseWriter, r *http.Request, moduleVersion *models.ModuleVersion) {
+	namespace, moduleName := mux.Vars(r)["namespace"], mux.Vars(r)["module_name"]
+	statement, signature, ok := checksumStatement(r.Context(), namespace, moduleName, moduleVersion.Version)
+	if !ok {
+		return
+	}
+	w.Header().Set(checksumIndexHeader, strconv.FormatInt(statement.Index, 10))
+	w.Header().Set(checksumStatementHeader, base64.StdEncoding.EncodeToString(statement.Marshal()))
+	if signature != "" {
+		w.Header().Set(checksumSignatureHeader, signature)
+	}
+}
+
+// ChecksumEntryResponse is an entry of the checksum log.
+type ChecksumEntryResponse struct {
+	Index int64 `json:"index"`
+	// Omitted for modules the caller can't read; the hashes still let the caller verify the log
+	Module    string    `json:"module,omitempty"`
+	Version   string    `json:"version,omitempty"`
+	Digest    string    `json:"digest,omitempty"` // sha256:<hex_digest>
+	Redacted  bool      `json:"redacted,omitempty"`
+	LeafHash  string    `json:"leaf_hash"` // SHA256(0x00 || "<module> <version> <digest>\n"), hex
+	RootHash  string    `json:"root_hash"` // Root hash of the log up to and including this entry, hex
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChecksumLogResponse defines the response for GET /api/v1/checksums.
+type ChecksumLogResponse struct {
+	TreeSize int64                   `json:"tree_size"`
+	RootHash string                  `json:"root_hash"`
+	Entries  []ChecksumEntryResponse `json:"entries"`
+	// Base64 Ed25519 key verifying statement signatures, if signing is configured. Clients should pin it
+	// out of band rather than trust it from here.
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// ChecksumProofResponse defines the response for GET /api/v1/checksums/{namespace}/{module_name}/{version}.
+type ChecksumProofResponse struct {
+	Entry    ChecksumEntryResponse `json:"entry"`
+	TreeSize int64                 `json:"tree_size"`
+	RootHash string                `json:"root_hash"` // Root hash of the tree of tree_size entries
+	Proof    []string              `json:"proof"`     // Audit path from the leaf up, hex (RFC 6962)
+}
+
+// newChecksumEntryResponse converts a log entry for the response.
+func newChecksumEntryResponse(e models.ChecksumEntry) ChecksumEntryResponse {
+	return ChecksumEntryResponse{
+		Index:     e.Index,
+		Module:    e.Module,
+		Version:   e.Version,
+		Digest:    e.Digest,
+		LeafHash:  e.LeafHash,
+		RootHash:  e.RootHash,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+// ListChecksumsHandler returns the checksum log's current size and root hash with a page of its entries.
+// Entries of modules the caller can't read are redacted to their hashes.
+// GET /api/v1/checksums?start=<index>&limit=<n>
+func ListChecksumsHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	if checksumLog == nil {
+		response.Error(w, http.StatusServiceUnavailable, "Checksum log is not enabled")
+		return
+	}
+
+	start, limit := int64(0), defaultChecksumPageSize
+	if s := r.URL.Query().Get("start"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			response.Error(w, http.StatusBadRequest, "Invalid start: must be a non-negative integer")
+			return
+		}
+		start = n
+	}
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxChecksumPageSize {
+			response.Error(w, http.StatusBadRequest, "Invalid limit: must be between 1 and "+strconv.Itoa(maxChecksumPageSize))
+			return
+		}
+		limit = n
+	}
+
+	treeSize, rootHash, err := checksumLog.Head(r.Context())
+	if err != nil {
+		log.Error("Error reading checksum log head", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to read checksum log")
+		return
+	}
+	entries, err := checksumLog.Entries(r.Context(), start, limit)
+	if err != nil {
+		log.Error("Error reading checksum log entries", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to read checksum log")
+		return
+	}
+	hidden, err := unreadableModules(r)
+	if err != nil {
+		log.Error("Error looking up module visibility", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to read checksum log")
+		return
+	}
+
+	resp := ChecksumLogResponse{TreeSize: treeSize, RootHash: rootHash, Entries: []ChecksumEntryResponse{}}
+	if checksumSigningKey != nil {
+		resp.PublicKey = translog.EncodePublicKey(checksumSigningKey)
+	}
+	for _, e := range entries {
+		if e.Index >= treeSize {
+			break // Appended after the head was read; keep the page consistent with the reported head
+		}
+		entry := newChecksumEntryResponse(e)
+		if hidden[e.Module] {
+			entry.Module, entry.Version, entry.Digest, entry.Redacted = "", "", "", true
+		}
+		resp.Entries = append(resp.Entries, entry)
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// unreadableModules returns the modules ("namespace/name") the caller may not read.
+func unreadableModules(r *http.Request) (map[string]bool, error) {
+	rd := readerFromContext(r.Context())
+	if rd.admin {
+		return nil, nil
+	}
+	var restricted []models.Module
+	err := db.GetReadDB().WithContext(r.Context()).Select("namespace, name, visibility").
+		Where("visibility IN ?", []string{models.VisibilityInternal, models.VisibilityPrivate}).Find(&restricted).Error
+	if err != nil {
+		return nil, err
+	}
+	hidden := map[string]bool{}
+	for _, m := range restricted {
+		if !rd.canRead(m.Namespace, m.Name, m.Visibility) {
+			hidden[m.Namespace+"/"+m.Name] = true
+		}
+	}
+	return hidden, nil
+}
+
+// GetChecksumProofHandler returns a module version's checksum log entry with an inclusion proof, against
+// the current tree or the tree of ?tree_size= entries (e.g. the size of a root hash the client recorded).
+// GET /api/v1/checksums/{namespace}/{module_name}/{version}
+func GetChecksumProofHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	versionStr := vars["version"]
+
+	if checksumLog == nil {
+		response.Error(w, http.StatusServiceUnavailable, "Checksum log is not enabled")
+		return
+	}
+	var treeSize int64
+	if s := r.URL.Query().Get("tree_size"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 1 {
+			response.Error(w, http.StatusBadRequest, "Invalid tree_size: must be a positive integer")
+			return
+		}
+		treeSize = n
+	}
+
+	readable, err := moduleReadable(r, namespace, moduleName)
+	if err != nil {
+		log.Error("Error looking up module visibility", zap.String("namespace", namespace), zap.String("module", moduleName), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module")
+		return
+	}
+	if !readable {
+		response.Error(w, http.StatusNotFound, "Checksum entry not found") // Don't reveal that the module exists
+		return
+	}
+
+	proof, err := checksumLog.Prove(r.Context(), namespace+"/"+moduleName, versionStr, treeSize)
+	switch {
+	case errors.Is(err, translog.ErrNotFound):
+		response.Error(w, http.StatusNotFound, "Checksum entry not found")
+		return
+	case errors.Is(err, translog.ErrTreeSize):
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		log.Error("Error computing checksum inclusion proof", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", versionStr), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to compute inclusion proof")
+		return
+	}
+
+	resp := ChecksumProofResponse{
+		Entry:    newChecksumEntryResponse(proof.Entry),
+		TreeSize: proof.TreeSize,
+		RootHash: proof.RootHash,
+		Proof:    proof.Hashes,
+	}
+	if resp.Proof == nil {
+		resp.Proof = []string{} // A tree of one entry needs no hashes
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
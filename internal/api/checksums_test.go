@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/translog"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for the checksum log endpoints ---
+
+// setupChecksumLog initializes a SQLite database as the global DB with a private and a public module
+// whose versions are in the checksum log.
+func setupChecksumLog(t *testing.T) *translog.Log {
+	gormDB := newTestDB(t)
+
+	assert.NoError(t, gormDB.Create(&models.Module{Namespace: "acme", Name: "billing", Visibility: models.VisibilityPublic}).Error)
+	assert.NoError(t, gormDB.Create(&models.Module{Namespace: "acme", Name: "secret", Visibility: models.VisibilityPrivate}).Error)
+
+	checksums := translog.New(gormDB)
+	SetChecksumLog(checksums)
+	t.Cleanup(func() { SetChecksumLog(nil) })
+	for _, v := range []string{"acme/billing v1.0.0", "acme/secret v1.0.0", "acme/billing v1.1.0"} {
+		module, version, _ := strings.Cut(v, " ")
+		_, err := checksums.Append(context.Background(), module, version, "sha256:"+strings.Repeat("a", 64))
+		assert.NoError(t, err)
+	}
+	return checksums
+}
+
+func TestListChecksumsHandler_RedactsUnreadableModules(t *testing.T) {
+	setupChecksumLog(t)
+
+	// Anonymous caller: the private module's entry only has its hashes
+	req := httptest.NewRequest("GET", "/api/v1/checksums?start=1&limit=5", nil)
+	req = req.WithContext(context.WithValue(req.Context(), readerKey, reader{}))
+	rr := httptest.NewRecorder()
+	ListChecksumsHandler(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp ChecksumLogResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, int64(3), resp.TreeSize)
+	assert.Len(t, resp.Entries, 2)
+	assert.Equal(t, int64(1), resp.Entries[0].Index)
+	assert.True(t, resp.Entries[0].Redacted)
+	assert.Empty(t, resp.Entries[0].Module)
+	assert.NotEmpty(t, resp.Entries[0].LeafHash)
+	assert.Equal(t, "acme/billing", resp.Entries[1].Module)
+	assert.Equal(t, resp.RootHash, resp.Entries[1].RootHash) // The last entry's root is the head
+	assert.NotContains(t, rr.Body.String(), "secret")
+
+	// Bad paging parameters
+	for _, query := range []string{"start=-1", "limit=0", "limit=5000", "start=x"} {
+		rr := httptest.NewRecorder()
+		ListChecksumsHandler(rr, httptest.NewRequest("GET", "/api/v1/checksums?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestGetChecksumProofHandler(t *testing.T) {
+	setupChecksumLog(t)
+	get := func(path string, rd reader) *httptest.ResponseRecorder {
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/checksums/{namespace}/{module_name}/{version}", GetChecksumProofHandler)
+		req := httptest.NewRequest("GET", path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), readerKey, rd))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/api/v1/checksums/acme/billing/v1.0.0", reader{})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp ChecksumProofResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, int64(3), resp.TreeSize)
+	assert.Equal(t, "sha256:"+strings.Repeat("a", 64), resp.Entry.Digest)
+
+	// The proof verifies the logged entry against the root hash
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		assert.NoError(t, err)
+		return b
+	}
+	var proof [][]byte
+	for _, h := range resp.Proof {
+		proof = append(proof, decode(h))
+	}
+	leaf := translog.LeafHash(translog.EntryText("acme/billing", "v1.0.0", resp.Entry.Digest))
+	assert.NoError(t, translog.VerifyInclusion(leaf, resp.Entry.Index, resp.TreeSize, proof, decode(resp.RootHash)))
+
+	// Against an older tree size
+	rr = get("/api/v1/checksums/acme/billing/v1.0.0?tree_size=1", reader{})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/checksums/acme/billing/v1.1.0?tree_size=2", reader{}).Code)
+
+	// Private modules are only visible to callers who can read them
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/checksums/acme/secret/v1.0.0", reader{}).Code)
+	assert.Equal(t, http.StatusOK, get("/api/v1/checksums/acme/secret/v1.0.0", reader{admin: true}).Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/checksums/acme/billing/v9.0.0", reader{}).Code)
+}
+
+func TestChecksumAttestation(t *testing.T) {
+	setupChecksumLog(t)
+	_, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	SetChecksumSigningKey(key)
+	t.Cleanup(func() { SetChecksumSigningKey(nil) })
+
+	// The statement covers the tree right after the version was appended, not the current one
+	attestation := checksumAttestation(context.Background(), "acme", "billing", "v1.0.0")
+	if assert.NotNil(t, attestation) {
+		assert.Equal(t, int64(0), attestation.Index)
+		assert.Equal(t, int64(1), attestation.TreeSize)
+		assert.NoError(t, translog.VerifyStatement(key.Public().(ed25519.PublicKey), []byte(attestation.Statement), attestation.Signature))
+		statement, err := translog.ParseStatement([]byte(attestation.Statement))
+		assert.NoError(t, err)
+		assert.Equal(t, "acme/billing", statement.Module)
+		assert.Equal(t, attestation.RootHash, statement.RootHash)
+	}
+	attestation = checksumAttestation(context.Background(), "acme", "billing", "v1.1.0")
+	if assert.NotNil(t, attestation) {
+		assert.Equal(t, int64(3), attestation.TreeSize)
+		assert.Len(t, attestation.Proof, 1) // The root of the first two entries
+	}
+	assert.Nil(t, checksumAttestation(context.Background(), "acme", "billing", "v9.0.0"))
+
+	// Artifact responses carry the same statement in headers
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/", nil), map[string]string{"namespace": "acme", "module_name": "billing"})
+	rr := httptest.NewRecorder()
+	setChecksumHeaders(rr, req, &models.ModuleVersion{Version: "v1.1.0"})
+	assert.Equal(t, "2", rr.Header().Get(checksumIndexHeader))
+	statement, err := base64.StdEncoding.DecodeString(rr.Header().Get(checksumStatementHeader))
+	assert.NoError(t, err)
+	assert.Equal(t, attestation.Statement, string(statement))
+	assert.Equal(t, attestation.Signature, rr.Header().Get(checksumSignatureHeader))
+
+	// Without a signing key the statement is unsigned
+	SetChecksumSigningKey(nil)
+	attestation = checksumAttestation(context.Background(), "acme", "billing", "v1.1.0")
+	if assert.NotNil(t, attestation) {
+		assert.Empty(t, attestation.Signature)
+	}
+}
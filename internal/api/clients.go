@@ -0,0 +1,268 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Client inventory: every API request is attributed to the consumer its token identifies (as in the
+// consumption reports) and to the client software named by its User-Agent ("protoreg-cli/v1.4.0 (linux/amd64)"
+// is protoreg-cli v1.4.0), so operators can see which teams still run outdated CLIs before making a
+// breaking protocol change.
+
+// ClientRetention is how long clients are kept in the inventory after they were last seen.
+const ClientRetention = 90 * 24 * time.Hour
+
+// CLIClient is the product name protoreg-cli sends in its User-Agent, the default client of the report.
+const CLIClient = "protoreg-cli"
+
+// UnknownClient stands for requests without a User-Agent.
+const UnknownClient = "unknown"
+
+// maxClientField bounds the client and version parsed from a User-Agent (the columns' size).
+const maxClientField = 64
+
+// maxPendingClients bounds the distinct clients recorded between flushes, so a caller sending random
+// User-Agents can't grow the tracker without limit. Requests of further clients aren't recorded.
+const maxPendingClients = 10000
+
+// parseUserAgent returns the client and version of a User-Agent: the name and version of its first product
+// ("protoreg-cli/v1.4.0 (linux/amd64)" is protoreg-cli, v1.4.0). The version is empty if there is none.
+func parseUserAgent(userAgent string) (client, version string) {
+	product, _, _ := strings.Cut(strings.TrimSpace(userAgent), " ")
+	if product == "" {
+		return UnknownClient, ""
+	}
+	client, version, _ = strings.Cut(product, "/")
+	if client == "" {
+		client = UnknownClient
+	}
+	return truncate(client, maxClientField), truncate(version, maxClientField)
+}
+
+// truncate shortens s to at most n bytes.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// ClientInventoryMiddleware records the client of every request for the inventory. It runs after
+// ReadAuthMiddleware, which identifies the consumer; requests it rejects aren't recorded.
+func ClientInventoryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consumer := readerFromContext(r.Context()).consumer
+		if consumer == "" {
+			consumer = AnonymousConsumer // Also when authentication is disabled
+		}
+		client, version := parseUserAgent(r.UserAgent())
+		clientUsage.record(consumer, client, version, time.Now().UTC())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// --- Client Tracking ---
+
+// clientKey identifies the requests of one client version by one consumer.
+type clientKey struct {
+	consumer, client, version string
+}
+
+// clientTracker counts requests in memory; RunClientInventoryFlusher adds them to the database.
+type clientTracker struct {
+	mu      sync.Mutex
+	pending map[clientKey]*models.ClientUsage // Requests since the last flush
+}
+
+var clientUsage = newClientTracker()
+
+func newClientTracker() *clientTracker {
+	return &clientTracker{pending: map[clientKey]*models.ClientUsage{}}
+}
+
+func (t *clientTracker) record(consumer, client, version string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := clientKey{consumer: consumer, client: client, version: version}
+	row, ok := t.pending[key]
+	if !ok {
+		if len(t.pending) >= maxPendingClients {
+			return
+		}
+		row = &models.ClientUsage{Consumer: consumer, Client: client, ClientVersion: version, FirstSeenAt: at}
+		t.pending[key] = row
+	}
+	row.Requests++
+	row.LastSeenAt = at
+}
+
+// flush adds the requests recorded since the last flush to the database.
+func (t *clientTracker) flush(ctx context.Context) error {
+	t.mu.Lock()
+	rows := make([]models.ClientUsage, 0, len(t.pending))
+	for _, row := range t.pending {
+		rows = append(rows, *row)
+	}
+	t.pending = map[clientKey]*models.ClientUsage{}
+	t.mu.Unlock()
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Existing rows keep their first request and add the new count
+	err := db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "consumer"}, {Name: "client"}, {Name: "client_version"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"requests":     gorm.Expr("client_usages.requests + excluded.requests"),
+			"last_seen_at": gorm.Expr("excluded.last_seen_at"),
+		}),
+	}).Create(&rows).Error
+	if err != nil {
+		// Retry with the next flush, merged with the requests recorded meanwhile
+		t.mu.Lock()
+		for _, row := range rows {
+			key := clientKey{consumer: row.Consumer, client: row.Client, version: row.ClientVersion}
+			if newer, ok := t.pending[key]; ok {
+				row.Requests += newer.Requests
+				row.LastSeenAt = newer.LastSeenAt
+			}
+			r := row
+			t.pending[key] = &r
+		}
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// pruneClients deletes the clients not seen for ClientRetention.
+func pruneClients(ctx context.Context, now time.Time) error {
+	return db.GetDB().WithContext(ctx).Where("last_seen_at < ?", now.Add(-ClientRetention)).Delete(&models.ClientUsage{}).Error
+}
+
+// RunClientInventoryFlusher adds recorded requests to the database every interval, and deletes clients not
+// seen for ClientRetention, until ctx is canceled. Requests recorded after the last flush are lost if the
+// server stops.
+func RunClientInventoryFlusher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := clientUsage.flush(ctx); err != nil {
+				logging.L().Warn("Failed to persist client inventory", zap.Error(err))
+			}
+			if err := pruneClients(ctx, time.Now().UTC()); err != nil {
+				logging.L().Warn("Failed to delete expired clients from the inventory", zap.Error(err))
+			}
+		}
+	}
+}
+
+// --- Client Inventory Report ---
+
+// ClientInventoryHandler reports which clients (and versions) each consumer used.
+// GET /api/v1/admin/clients (admin token only)
+// ?since= (a date or RFC3339 timestamp) only reports clients seen since then, ?client= only that client.
+// ?min_version=v1.4.0 flags the versions of ?client= (default protoreg-cli) older than it, and
+// ?outdated=true only reports the consumers still on such a version. Requests show up within a minute.
+func ClientInventoryHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	query := r.URL.Query()
+	checked := CLIClient
+	if value := query.Get("client"); value != "" {
+		checked = value
+	}
+	var since time.Time
+	if value := query.Get("since"); value != "" {
+		parsed, err := apitypes.ParseTokenExpiry(value) // Same date formats
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid since %q: expected a date (2006-01-02) or an RFC3339 timestamp", value))
+			return
+		}
+		since = *parsed
+	}
+	var minVersion *semver.Version
+	if value := query.Get("min_version"); value != "" {
+		parsed, err := semver.NewVersion(value)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid min_version %q: %v", value, err))
+			return
+		}
+		minVersion = parsed
+	}
+	onlyOutdated := query.Get("outdated") == "true"
+	if onlyOutdated && minVersion == nil {
+		response.Error(w, http.StatusBadRequest, "outdated=true requires min_version")
+		return
+	}
+
+	dbQuery := db.GetReadDB().WithContext(r.Context()).Where("last_seen_at >= ?", since)
+	if query.Get("client") != "" {
+		dbQuery = dbQuery.Where("client = ?", checked)
+	}
+	var rows []models.ClientUsage
+	if err := dbQuery.Order("consumer, last_seen_at DESC").Find(&rows).Error; err != nil {
+		log.Error("Error loading client inventory", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve client inventory")
+		return
+	}
+
+	resp := apitypes.ClientInventoryResponse{Client: checked, Clients: []apitypes.ClientInventoryEntry{}, OutdatedConsumers: []string{}}
+	if minVersion != nil {
+		resp.MinVersion = query.Get("min_version")
+	}
+	latest := map[string]bool{} // Consumers whose most recent version of the checked client was seen
+	outdated := map[string]bool{}
+	for _, row := range rows {
+		entry := apitypes.ClientInventoryEntry{Consumer: row.Consumer, Client: row.Client, Version: row.ClientVersion, Requests: row.Requests, FirstSeenAt: row.FirstSeenAt, LastSeenAt: row.LastSeenAt}
+		if minVersion != nil && row.Client == checked {
+			entry.Outdated = olderThan(row.ClientVersion, minVersion)
+			if !latest[row.Consumer] { // Rows are ordered most recently seen first
+				latest[row.Consumer] = true
+				if entry.Outdated {
+					outdated[row.Consumer] = true
+					resp.OutdatedConsumers = append(resp.OutdatedConsumers, row.Consumer)
+				}
+			}
+		}
+		resp.Clients = append(resp.Clients, entry)
+	}
+	if onlyOutdated {
+		kept := []apitypes.ClientInventoryEntry{}
+		for _, entry := range resp.Clients {
+			if outdated[entry.Consumer] {
+				kept = append(kept, entry)
+			}
+		}
+		resp.Clients = kept
+	}
+	sort.Strings(resp.OutdatedConsumers)
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// olderThan reports whether a client version is older than min. Versions that aren't semantic versions
+// (e.g. "dev" builds) are never reported as outdated.
+func olderThan(version string, min *semver.Version) bool {
+	v, err := semver.NewVersion(version)
+	return err == nil && v.LessThan(min)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	for ua, want := range map[string][2]string{
+		"protoreg-cli/v1.4.0 (linux/amd64)": {"protoreg-cli", "v1.4.0"},
+		"Go-http-client/1.1":                {"Go-http-client", "1.1"},
+		"curl":                              {"curl", ""},
+		"":                                  {UnknownClient, ""},
+		"/1.0":                              {UnknownClient, "1.0"},
+	} {
+		client, version := parseUserAgent(ua)
+		assert.Equal(t, want, [2]string{client, version}, ua)
+	}
+	client, _ := parseUserAgent(strings.Repeat("x", 100) + "/1.0")
+	assert.Len(t, client, maxClientField)
+}
+
+func TestClientInventoryHandler(t *testing.T) {
+	newTestDB(t)
+	clientUsage = newClientTracker()
+	SetReadTokens(map[string]ReadToken{"payments-token": {Consumer: "payments-team"}, "search-token": {Consumer: "search-team"}})
+	t.Cleanup(func() { SetReadTokens(nil) })
+
+	// Requests are attributed to the consumer and the client of their User-Agent
+	router := mux.NewRouter()
+	router.Use(ReadAuthMiddleware("admin-token"))
+	router.Use(ClientInventoryMiddleware)
+	router.HandleFunc("/api/v1/modules", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	router.HandleFunc("/api/v1/admin/clients", ClientInventoryHandler)
+	do := func(path, token, userAgent string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("User-Agent", userAgent)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	do("/api/v1/modules", "payments-token", "protoreg-cli/v1.2.0 (linux/amd64)")
+	do("/api/v1/modules", "search-token", "protoreg-cli/v1.2.0 (darwin/arm64)")
+	do("/api/v1/modules", "", "Go-http-client/1.1")
+	do("/api/v1/modules", "bogus-token", "protoreg-cli/v0.1.0") // Rejected: not recorded
+	assert.NoError(t, clientUsage.flush(context.Background()))
+	time.Sleep(10 * time.Millisecond)
+	do("/api/v1/modules", "search-token", "protoreg-cli/v1.5.0 (darwin/arm64)") // Upgraded since
+	do("/api/v1/modules", "search-token", "protoreg-cli/v1.5.0 (darwin/arm64)")
+	do("/api/v1/modules", "payments-token", "protoreg-cli/v1.2.0 (linux/amd64)") // Added to the existing row
+	assert.NoError(t, clientUsage.flush(context.Background()))
+
+	get := func(query string) apitypes.ClientInventoryResponse {
+		rr := do("/api/v1/admin/clients"+query, "admin-token", "")
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp apitypes.ClientInventoryResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+	resp := get("")
+	assert.Equal(t, CLIClient, resp.Client)
+	assert.Empty(t, resp.OutdatedConsumers)
+	var rows []string
+	for _, c := range resp.Clients {
+		rows = append(rows, fmt.Sprintf("%s %s %s %d", c.Consumer, c.Client, c.Version, c.Requests))
+	}
+	assert.Equal(t, []string{
+		"anonymous Go-http-client 1.1 1",
+		"payments-team protoreg-cli v1.2.0 2",
+		"search-team protoreg-cli v1.5.0 2",
+		"search-team protoreg-cli v1.2.0 1",
+	}, rows)
+
+	// Only the most recently seen version of a consumer makes it outdated
+	resp = get("?min_version=v1.4.0")
+	assert.Equal(t, "v1.4.0", resp.MinVersion)
+	assert.Equal(t, []string{"payments-team"}, resp.OutdatedConsumers)
+	assert.True(t, resp.Clients[1].Outdated)
+	assert.False(t, resp.Clients[2].Outdated)
+	assert.True(t, resp.Clients[3].Outdated)
+	resp = get("?min_version=v1.4.0&outdated=true")
+	if assert.Len(t, resp.Clients, 1) {
+		assert.Equal(t, "payments-team", resp.Clients[0].Consumer)
+	}
+	resp = get("?client=Go-http-client&min_version=2.0")
+	if assert.Len(t, resp.Clients, 1) {
+		assert.True(t, resp.Clients[0].Outdated)
+	}
+	assert.Equal(t, []string{"anonymous"}, resp.OutdatedConsumers)
+	assert.Empty(t, get("?since=2999-01-01").Clients)
+
+	for _, query := range []string{"?min_version=latest", "?since=yesterday", "?outdated=true"} {
+		assert.Equal(t, http.StatusBadRequest, do("/api/v1/admin/clients"+query, "admin-token", "").Code, query)
+	}
+
+	// Clients not seen for the retention period are deleted
+	assert.NoError(t, pruneClients(context.Background(), time.Now().UTC().Add(ClientRetention+time.Hour)))
+	assert.Empty(t, get("").Clients)
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Compatibility matrix: for each published version of a module, the ranges of published versions of each
+// declared dependency it accepts, so consumers can pick versions that work together without resolving
+// every combination themselves. The declarations are cached server-side (see descriptor.Loader.Compatibility);
+// the ranges follow new releases of the dependencies, so the matrix is cached like metadata, with an ETag.
+
+// GetModuleCompatibilityHandler returns the compatibility matrix of a module.
+// GET /api/v1/modules/{namespace}/{module_name}/compatibility
+// Dependencies the caller can't read are reported with their constraint but no ranges, like dependencies
+// that were never published, so the matrix doesn't reveal their versions.
+func GetModuleCompatibilityHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	log = log.With(zap.String("module", namespace+"/"+moduleName))
+
+	readable, err := moduleReadable(r, namespace, moduleName)
+	if err != nil {
+		log.Error("Error checking module visibility", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to compute compatibility matrix")
+		return
+	}
+	if !readable {
+		response.Error(w, http.StatusNotFound, "Module not found") // Don't reveal that it exists
+		return
+	}
+
+	loader := descriptor.NewLoader(requestDB(r), storage.GetStorageProvider())
+	matrix, err := loader.Compatibility(r.Context(), namespace, moduleName)
+	switch {
+	case errors.Is(err, descriptor.ErrNotFound):
+		response.Error(w, http.StatusNotFound, "Module not found")
+		return
+	case err != nil && storageErrorStatus(err) == http.StatusServiceUnavailable:
+		log.Error("Storage unavailable while computing compatibility matrix", zap.Error(err))
+		response.Error(w, http.StatusServiceUnavailable, "Artifact storage unavailable")
+		return
+	case err != nil:
+		log.Error("Error computing compatibility matrix", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to compute compatibility matrix")
+		return
+	}
+
+	// --- Hide Unreadable Dependencies ---
+	hidden := map[string]bool{}
+	checked := map[string]bool{}
+	for i := range matrix.Versions {
+		deps := matrix.Versions[i].Dependencies
+		for j := range deps {
+			if !checked[deps[j].Module] {
+				checked[deps[j].Module] = true
+				depNamespace, depName, _ := manifest.SplitModule(deps[j].Module) // Validated by the loader
+				readable, err := moduleReadable(r, depNamespace, depName)
+				if err != nil {
+					log.Error("Error checking dependency visibility", zap.String("dependency", deps[j].Module), zap.Error(err))
+					response.Error(w, http.StatusInternalServerError, "Failed to compute compatibility matrix")
+					return
+				}
+				hidden[deps[j].Module] = !readable
+			}
+			if hidden[deps[j].Module] {
+				deps[j].Ranges = []descriptor.VersionRange{}
+				deps[j].Newest = ""
+			}
+		}
+	}
+
+	body, err := json.Marshal(matrix)
+	if err != nil {
+		log.Error("Error encoding compatibility matrix", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to compute compatibility matrix")
+		return
+	}
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+	w.Header().Set("ETag", etag)
+	setListCacheHeaders(w)
+	if notModified(w, r, etag) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		log.Warn("Error writing compatibility matrix to client", zap.Error(err))
+	}
+}
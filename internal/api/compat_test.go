@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetModuleCompatibilityHandler(t *testing.T) {
+	gormDB := newTestDB(t)
+	newTestStorage(t)
+
+	router := mux.NewRouter()
+	router.Use(ReadAuthMiddleware("admin-token"))
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/compatibility", GetModuleCompatibilityHandler).Methods("GET")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	serve := func(path, token, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	publish := func(namespace, name, version string, files map[string]string) {
+		packed := map[string][]byte{}
+		for file, content := range files {
+			packed[file] = []byte(content)
+		}
+		data, err := artifact.Pack(packed)
+		assert.NoError(t, err)
+		req := newPublishRequest(t, namespace, name, version, "", data)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	for _, v := range []string{"v1.0.0", "v1.1.0", "v2.0.0"} {
+		publish("acme", "user", v, map[string]string{"acme/user/v1/user.proto": `syntax = "proto3"; package acme.user.v1; message User {}`})
+	}
+	publish("acme", "secret", "v1.0.0", map[string]string{"acme/secret/v1/secret.proto": `syntax = "proto3"; package acme.secret.v1; message Secret {}`})
+	publish("acme", "orders", "v1.0.0", map[string]string{
+		"sproto.yaml":                 "name: acme/orders\ndependencies:\n  acme/user: ^1.0.0\n  acme/secret: ^1.0.0\n",
+		"acme/orders/v1/orders.proto": `syntax = "proto3"; package acme.orders.v1; message Order {}`,
+	})
+	assert.NoError(t, gormDB.Model(&models.Module{}).Where("name = ?", "secret").Update("visibility", models.VisibilityPrivate).Error)
+
+	rr := serve("/api/v1/modules/acme/orders/compatibility", "admin-token", "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var matrix descriptor.CompatibilityMatrix
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &matrix))
+	assert.Equal(t, []descriptor.VersionCompatibility{{Version: "v1.0.0", Dependencies: []descriptor.DependencyCompatibility{
+		{Module: "acme/secret", Constraint: "^1.0.0", Ranges: []descriptor.VersionRange{{From: "v1.0.0", To: "v1.0.0"}}, Newest: "v1.0.0"},
+		{Module: "acme/user", Constraint: "^1.0.0", Ranges: []descriptor.VersionRange{{From: "v1.0.0", To: "v1.1.0"}}, Newest: "v1.1.0"},
+	}}}, matrix.Versions)
+
+	// Revalidation
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, http.StatusNotModified, serve("/api/v1/modules/acme/orders/compatibility", "admin-token", etag).Code)
+
+	// Anonymous callers don't see the versions of the private dependency
+	rr = serve("/api/v1/modules/acme/orders/compatibility", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	matrix = descriptor.CompatibilityMatrix{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &matrix))
+	assert.Equal(t, descriptor.DependencyCompatibility{Module: "acme/secret", Constraint: "^1.0.0", Ranges: []descriptor.VersionRange{}}, matrix.Versions[0].Dependencies[0])
+	assert.Equal(t, "v1.1.0", matrix.Versions[0].Dependencies[1].Newest)
+
+	assert.Equal(t, http.StatusNotFound, serve("/api/v1/modules/acme/secret/compatibility", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("/api/v1/modules/acme/nope/compatibility", "admin-token", "").Code)
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Consumption analytics: every download of a module version (artifact or bundle) is attributed to the
+// consumer its token identifies, so schema owners can see who uses which versions before making a
+// breaking change. Read tokens name their consumer in READ_TOKENS ("token#payments-team"); unnamed
+// read tokens are reported by token ID (as in the token report), the admin token as "admin" and
+// requests without a token as "anonymous".
+
+// Consumers that aren't read tokens.
+const (
+	AdminConsumer     = "admin"
+	AnonymousConsumer = "anonymous"
+)
+
+// consumerName returns the consumer a read token identifies: its configured name, or its token ID.
+func consumerName(token string, rt ReadToken) string {
+	if rt.Consumer != "" {
+		return rt.Consumer
+	}
+	return "token:" + tokenFingerprint(token)[:12]
+}
+
+// recordFetch attributes a download of a module version to the caller.
+func recordFetch(r *http.Request, moduleVersionID uuid.UUID) {
+	consumer := readerFromContext(r.Context()).consumer
+	if consumer == "" {
+		consumer = AnonymousConsumer
+	}
+	consumption.record(moduleVersionID, consumer, time.Now().UTC())
+}
+
+// --- Consumption Tracking ---
+
+// consumptionKey identifies the downloads of a module version by one consumer.
+type consumptionKey struct {
+	moduleVersionID uuid.UUID
+	consumer        string
+}
+
+// consumptionTracker counts downloads in memory; RunConsumptionFlusher adds them to the database, so
+// downloads don't each cost a write.
+type consumptionTracker struct {
+	mu      sync.Mutex
+	pending map[consumptionKey]*models.ModuleConsumption // Downloads since the last flush
+}
+
+var consumption = newConsumptionTracker()
+
+func newConsumptionTracker() *consumptionTracker {
+	return &consumptionTracker{pending: map[consumptionKey]*models.ModuleConsumption{}}
+}
+
+func (t *consumptionTracker) record(moduleVersionID uuid.UUID, consumer string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := consumptionKey{moduleVersionID: moduleVersionID, consumer: consumer}
+	row, ok := t.pending[key]
+	if !ok {
+		row = &models.ModuleConsumption{ModuleVersionID: moduleVersionID, Consumer: consumer, FirstFetchedAt: at}
+		t.pending[key] = row
+	}
+	row.FetchCount++
+	row.LastFetchedAt = at
+}
+
+// flush adds the downloads recorded since the last flush to the database.
+func (t *consumptionTracker) flush(ctx context.Context) error {
+	t.mu.Lock()
+	rows := make([]models.ModuleConsumption, 0, len(t.pending))
+	for _, row := range t.pending {
+		rows = append(rows, *row)
+	}
+	t.pending = map[consumptionKey]*models.ModuleConsumption{}
+	t.mu.Unlock()
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Existing rows keep their first download and add the new count
+	err := db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "module_version_id"}, {Name: "consumer"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"fetch_count":     gorm.Expr("module_consumptions.fetch_count + excluded.fetch_count"),
+			"last_fetched_at": gorm.Expr("excluded.last_fetched_at"),
+		}),
+	}).Create(&rows).Error
+	if err != nil {
+		// Retry with the next flush, merged with the downloads recorded meanwhile
+		t.mu.Lock()
+		for _, row := range rows {
+			key := consumptionKey{moduleVersionID: row.ModuleVersionID, consumer: row.Consumer}
+			if newer, ok := t.pending[key]; ok {
+				row.FetchCount += newer.FetchCount
+				row.LastFetchedAt = newer.LastFetchedAt
+			}
+			r := row
+			t.pending[key] = &r
+		}
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// RunConsumptionFlusher adds recorded downloads to the database every interval until ctx is canceled.
+// Downloads recorded after the last flush are lost if the server stops.
+func RunConsumptionFlusher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := consumption.flush(ctx); err != nil {
+				logging.L().Warn("Failed to persist module consumption", zap.Error(err))
+			}
+		}
+	}
+}
+
+// --- Consumption Report ---
+
+// consumptionRow is a row of the report query.
+type consumptionRow struct {
+	Version        string
+	Consumer       string
+	FetchCount     int64
+	FirstFetchedAt time.Time
+	LastFetchedAt  time.Time
+}
+
+// ModuleConsumersHandler reports which consumers downloaded which versions of a module.
+// GET /api/v1/modules/{namespace}/{module_name}/consumers
+// ?version=v1.2.0 only reports downloads of that version, ?since=2026-01-01 (a date or RFC3339 timestamp)
+// only consumers that downloaded a version since then. Downloads show up within a minute.
+func ModuleConsumersHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	gormDB := db.GetReadDB()
+
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		parsed, err := apitypes.ParseTokenExpiry(value) // Same date formats
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid since %q: expected a date (2006-01-02) or an RFC3339 timestamp", value))
+			return
+		}
+		since = *parsed
+	}
+
+	module, ok := findReadableModule(w, r, namespace, moduleName)
+	if !ok {
+		return // Response already written
+	}
+
+	query := gormDB.Table("module_consumptions mc").
+		Select("mv.version, mc.consumer, mc.fetch_count, mc.first_fetched_at, mc.last_fetched_at").
+		Joins("JOIN module_versions mv ON mv.id = mc.module_version_id").
+		Where("mv.module_id = ?", module.ID)
+	if version := r.URL.Query().Get("version"); version != "" {
+		query = query.Where("mv.version = ?", version)
+	}
+	var rows []consumptionRow
+	if err := query.Scan(&rows).Error; err != nil {
+		log.Error("Error loading module consumption", zap.String("namespace", namespace), zap.String("module", moduleName), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module consumers")
+		return
+	}
+
+	respData := apitypes.ModuleConsumersResponse{Namespace: namespace, ModuleName: moduleName, Consumers: aggregateConsumers(rows, since)}
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, http.StatusOK, respData)
+}
+
+// aggregateConsumers groups report rows by consumer, leaving out consumers without downloads since since.
+func aggregateConsumers(rows []consumptionRow, since time.Time) []apitypes.ModuleConsumer {
+	byConsumer := map[string]*apitypes.ModuleConsumer{}
+	for _, row := range rows {
+		c, ok := byConsumer[row.Consumer]
+		if !ok {
+			c = &apitypes.ModuleConsumer{Consumer: row.Consumer, FirstFetchedAt: row.FirstFetchedAt, LastFetchedAt: row.LastFetchedAt}
+			byConsumer[row.Consumer] = c
+		}
+		c.FetchCount += row.FetchCount
+		if row.FirstFetchedAt.Before(c.FirstFetchedAt) {
+			c.FirstFetchedAt = row.FirstFetchedAt
+		}
+		if row.LastFetchedAt.After(c.LastFetchedAt) {
+			c.LastFetchedAt = row.LastFetchedAt
+		}
+		c.Versions = append(c.Versions, apitypes.ConsumerVersionUsage{Version: row.Version, FetchCount: row.FetchCount, LastFetchedAt: row.LastFetchedAt})
+	}
+
+	consumers := make([]apitypes.ModuleConsumer, 0, len(byConsumer)) // Empty array, not null
+	for _, c := range byConsumer {
+		if c.LastFetchedAt.Before(since) {
+			continue
+		}
+		sort.Slice(c.Versions, func(i, j int) bool { return c.Versions[i].LastFetchedAt.After(c.Versions[j].LastFetchedAt) })
+		consumers = append(consumers, *c)
+	}
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].Consumer < consumers[j].Consumer })
+	return consumers
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleConsumersHandler(t *testing.T) {
+	gormDB := newTestDB(t)
+	newTestStorage(t)
+	consumption = newConsumptionTracker()
+	SetReadTokens(map[string]ReadToken{"payments-token": {Consumer: "payments-team"}, "ci-token": {}})
+	t.Cleanup(func() { SetReadTokens(nil) })
+
+	module := models.Module{Namespace: "acme", Name: "billing", Visibility: models.VisibilityInternal}
+	assert.NoError(t, gormDB.Create(&module).Error)
+	v1 := models.ModuleVersion{ModuleID: module.ID, Version: "v1.0.0", VersionKey: validation.VersionKey("v1.0.0"), ArtifactDigest: "abc123", ArtifactStorageKey: "k1"}
+	v2 := models.ModuleVersion{ModuleID: module.ID, Version: "v2.0.0", VersionKey: validation.VersionKey("v2.0.0"), ArtifactDigest: "def456", ArtifactStorageKey: "k2"}
+	assert.NoError(t, gormDB.Create(&v1).Error)
+	assert.NoError(t, gormDB.Create(&v2).Error)
+
+	// Downloads are attributed to the consumer the token identifies; HEAD requests aren't downloads
+	router := mux.NewRouter()
+	router.Use(ReadAuthMiddleware("admin-token"))
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/artifact", FetchModuleVersionArtifactHandler).Methods("GET", "HEAD")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/consumers", ModuleConsumersHandler).Methods("GET")
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/modules/acme/billing/"+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	do("GET", "v1.0.0/artifact", "payments-token")
+	do("GET", "v2.0.0/artifact", "payments-token")
+	do("HEAD", "v2.0.0/artifact", "payments-token")
+	do("GET", "v1.0.0/artifact", "ci-token")
+	assert.NoError(t, consumption.flush(context.Background()))
+	do("GET", "v2.0.0/artifact", "payments-token") // Added to the existing row
+	do("GET", "v1.0.0/artifact", "admin-token")
+	assert.NoError(t, consumption.flush(context.Background()))
+
+	report := func(query, token string) apitypes.ModuleConsumersResponse {
+		rr := do("GET", "consumers"+query, token)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp apitypes.ModuleConsumersResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+	resp := report("", "ci-token")
+	ciID := "token:" + tokenFingerprint("ci-token")[:12]
+	counts := map[string]int64{}
+	for _, c := range resp.Consumers {
+		counts[c.Consumer] = c.FetchCount
+	}
+	assert.Equal(t, map[string]int64{"admin": 1, ciID: 1, "payments-team": 3}, counts)
+	assert.Equal(t, []string{"admin", "payments-team", ciID}, []string{resp.Consumers[0].Consumer, resp.Consumers[1].Consumer, resp.Consumers[2].Consumer})
+	payments := resp.Consumers[1]
+	assert.Len(t, payments.Versions, 2)
+	assert.Equal(t, "v2.0.0", payments.Versions[0].Version) // Newest download first
+	assert.Equal(t, int64(2), payments.Versions[0].FetchCount)
+
+	// Filters
+	resp = report("?version=v2.0.0", "ci-token")
+	assert.Len(t, resp.Consumers, 1)
+	assert.Equal(t, "payments-team", resp.Consumers[0].Consumer)
+	assert.Empty(t, report("?since=2999-01-01", "ci-token").Consumers)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "consumers?since=yesterday", "ci-token").Code)
+
+	// Internal module: anonymous callers don't see it
+	assert.Equal(t, http.StatusNotFound, do("GET", "consumers", "").Code)
+}
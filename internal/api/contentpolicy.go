@@ -0,0 +1,87 @@
+package api
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// Content policy: the content types an artifact may have, the largest file it may contain and the
+// extensions of its files, configured for the server (ARTIFACT_CONTENT_TYPES, ARTIFACT_MAX_FILE_BYTES,
+// ARTIFACT_ALLOWED_EXTENSIONS) and replaced setting by setting by namespace policies. It is checked by
+// artifact.ContentPolicy on every path creating a version or dev channel (uploads, imports through the
+// publish handler, republishes), right after the artifact is canonicalized, before the more expensive checks.
+
+// contentPolicy is the server's content policy; see SetContentPolicy.
+var contentPolicy artifact.ContentPolicy
+
+// SetContentPolicy configures the server's content policy; empty lists and 0 allow anything.
+func SetContentPolicy(contentTypes, extensions []string, maxFileBytes int64) error {
+	p, err := artifact.NewContentPolicy(contentTypes, extensions, maxFileBytes)
+	if err != nil {
+		return err
+	}
+	contentPolicy = p
+	return nil
+}
+
+// namespaceContentPolicy returns the content policy of a namespace: the server's, with the settings of its
+// namespace policy (nil if it has none) in place of the server's.
+func namespaceContentPolicy(nsPolicy *models.NamespacePolicy) artifact.ContentPolicy {
+	if nsPolicy == nil {
+		return contentPolicy
+	}
+	return contentPolicy.Override(artifact.ContentPolicy{
+		ContentTypes: splitLines(nsPolicy.ContentTypes),
+		MaxFileBytes: nsPolicy.MaxFileBytes,
+		Extensions:   splitLines(nsPolicy.AllowedExtensions),
+	})
+}
+
+// checkContentPolicy runs evaluateContentPolicy on an uploaded artifact. The file is rewound afterwards.
+// Returns false if the upload must be rejected; the response has then been written.
+func checkContentPolicy(w http.ResponseWriter, r *http.Request, file multipart.File, nsPolicy *models.NamespacePolicy) bool {
+	data, err := io.ReadAll(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error reading artifact for content policy", zap.Error(err))
+		response.Error(w, http.StatusBadRequest, "Could not read artifact file")
+		return false
+	}
+	return evaluateContentPolicy(w, r, nsPolicy, data)
+}
+
+// evaluateContentPolicy checks an artifact against the content policy of its namespace (see
+// namespaceContentPolicy), rejecting it with 403 and every violation.
+// Returns false if the upload must be rejected; the response has then been written.
+func evaluateContentPolicy(w http.ResponseWriter, r *http.Request, nsPolicy *models.NamespacePolicy, data []byte) bool {
+	found := namespaceContentPolicy(nsPolicy).Check(data)
+	if len(found) == 0 {
+		return true
+	}
+	violations := make([]apitypes.PolicyViolation, 0, len(found))
+	for _, v := range found {
+		violations = append(violations, apitypes.PolicyViolation{Policy: v.Rule, Message: v.Message})
+	}
+	logging.FromContext(r.Context()).Info("Artifact denied by content policy", zap.Any("violations", violations))
+	response.JSON(w, http.StatusForbidden, apitypes.PolicyDeniedResponse{Error: "Artifact denied by content policy", Violations: violations})
+	return false
+}
+
+// splitLines splits a newline-separated list stored in the database; empty is nil.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
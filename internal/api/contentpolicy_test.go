@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContentPolicy(t *testing.T) {
+	newTestDB(t)
+	newTestStorage(t)
+	assert.NoError(t, SetContentPolicy([]string{"application/zip"}, []string{".proto", ".md"}, 0))
+	t.Cleanup(func() { assert.NoError(t, SetContentPolicy(nil, nil, 0)) })
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	router.HandleFunc("/api/v1/admin/namespace-policies/{namespace}", PutNamespacePolicyHandler).Methods("PUT")
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	publish := func(version string, files map[string]string) *httptest.ResponseRecorder {
+		packed := map[string][]byte{}
+		for name, content := range files {
+			packed[name] = []byte(content)
+		}
+		data, err := artifact.Pack(packed)
+		assert.NoError(t, err)
+		return serve(newPublishRequest(t, "acme", "orders", version, "", data))
+	}
+	violations := func(rr *httptest.ResponseRecorder) []string {
+		assert.Equal(t, http.StatusForbidden, rr.Code, rr.Body.String())
+		var resp apitypes.PolicyDeniedResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "Artifact denied by content policy", resp.Error)
+		names := []string{}
+		for _, v := range resp.Violations {
+			names = append(names, v.Policy)
+		}
+		return names
+	}
+	proto := `syntax = "proto3"; package acme.orders.v1; message Order { string id = 1; }`
+
+	// Server policy: zip archives of .proto and .md files (and the manifest)
+	assert.Equal(t, http.StatusCreated, publish("v1.0.0", map[string]string{"acme/orders/v1/orders.proto": proto, "README.md": "# Orders", "sproto.yaml": "name: acme/orders"}).Code)
+	assert.Equal(t, []string{"allowed-extensions"}, violations(publish("v1.1.0", map[string]string{"acme/orders/v1/orders.proto": proto, "build.sh": "#!/bin/sh"})))
+	assert.Equal(t, []string{"content-type"}, violations(serve(newPublishRequest(t, "acme", "orders", "v1.1.0", "", []byte(proto)))))
+
+	// The namespace policy replaces the settings it has
+	req := httptest.NewRequest("PUT", "/api/v1/admin/namespace-policies/acme", strings.NewReader(`{"max_file_bytes":128,"allowed_extensions":["proto","SH"]}`))
+	rr := serve(req)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var saved apitypes.NamespacePolicyResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &saved))
+	assert.Equal(t, []string{".proto", ".sh"}, saved.AllowedExtensions)
+	assert.Equal(t, http.StatusCreated, publish("v1.1.0", map[string]string{"acme/orders/v1/orders.proto": proto, "build.sh": "#!/bin/sh"}).Code)
+	assert.Equal(t, []string{"max-file-size", "allowed-extensions"}, violations(publish("v1.2.0", map[string]string{"acme/orders/v1/orders.proto": proto, "README.md": strings.Repeat("#", 129)})))
+	assert.Equal(t, []string{"content-type"}, violations(serve(newPublishRequest(t, "acme", "orders", "v1.2.0", "", []byte(proto)))))
+
+	// Republishes must pass today's policy too
+	assert.Equal(t, []string{"allowed-extensions"}, violations(serve(httptest.NewRequest("POST", "/api/v1/modules/acme/orders/v1.0.0-final?from=v1.0.0", nil))))
+	assert.Equal(t, http.StatusBadRequest, serve(httptest.NewRequest("PUT", "/api/v1/admin/namespace-policies/acme", strings.NewReader(`{"content_types":["zip"]}`))).Code)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// MaxDeprecationMessageLength is the maximum length of a deprecation message in bytes.
+const MaxDeprecationMessageLength = 1024
+
+// DeprecateModuleVersionHandler marks a module version as deprecated (PUT) or lifts the deprecation (DELETE).
+// Deprecated versions can still be fetched; consumers see the status and message in the version metadata.
+// A PUT with "sunset_at" schedules the end of downloads (see sunset.go); a PUT without it removes the
+// sunset, and DELETE lifts both.
+// PUT|DELETE /api/v1/modules/{namespace}/{module_name}/{version}/deprecation
+func DeprecateModuleVersionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+	log := logging.FromContext(r.Context()).With(zap.String("module_version", fmt.Sprintf("%s/%s@%s", namespace, moduleName, version)))
+
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+
+	var req apitypes.DeprecateModuleVersionRequest
+	if r.Method == http.MethodPut {
+		// The body is optional: deprecating without a message is allowed
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 2*MaxDeprecationMessageLength+1024)).Decode(&req)
+		if err != nil && !errors.Is(err, io.EOF) {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		req.Message = strings.TrimSpace(req.Message)
+		if len(req.Message) > MaxDeprecationMessageLength {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Deprecation message exceeds the maximum length of %d bytes", MaxDeprecationMessageLength))
+			return
+		}
+	}
+	sunsetAt, err := apitypes.ParseTokenExpiry(strings.TrimSpace(req.SunsetAt)) // Same date formats
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid sunset_at %q: expected a date (2006-01-02) or an RFC3339 timestamp", req.SunsetAt))
+		return
+	}
+
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return // Response already written
+	}
+
+	updates := map[string]interface{}{"deprecated_at": nil, "deprecation_message": "", "sunset_at": nil, "sunset_enforced_at": nil}
+	var sunsetEnforcedAt *time.Time
+	if r.Method == http.MethodPut {
+		deprecatedAt := time.Now().UTC()
+		if moduleVersion.DeprecatedAt != nil {
+			deprecatedAt = *moduleVersion.DeprecatedAt // Updating the message keeps the original date
+		}
+		updates = map[string]interface{}{"deprecated_at": deprecatedAt, "deprecation_message": req.Message, "sunset_at": sunsetAt, "sunset_enforced_at": nil}
+		if sunsetAt != nil && moduleVersion.SunsetAt != nil && sunsetAt.Equal(*moduleVersion.SunsetAt) {
+			// An unchanged sunset stays enforced; a moved one is enforced again by the job once reached
+			sunsetEnforcedAt = moduleVersion.SunsetEnforcedAt
+			updates["sunset_enforced_at"] = sunsetEnforcedAt
+		}
+	}
+	if err = db.GetDB().Model(&models.ModuleVersion{}).Where("id = ?", moduleVersion.ID).Updates(updates).Error; err != nil {
+		log.Error("Error updating deprecation status", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error updating deprecation status")
+		return
+	}
+
+	respData := apitypes.DeprecationResponse{Namespace: namespace, ModuleName: moduleName, Version: moduleVersion.Version}
+	if deprecatedAt, ok := updates["deprecated_at"].(time.Time); ok {
+		respData.Deprecated = true
+		respData.Message = req.Message
+		respData.DeprecatedAt = &deprecatedAt
+		respData.SunsetAt = sunsetAt
+		respData.Sunset = sunsetEnforcedAt != nil
+		log.Info("Deprecated module version", zap.String("message", req.Message), zap.Timep("sunset_at", sunsetAt))
+	} else {
+		log.Info("Lifted module version deprecation")
+	}
+	response.JSON(w, http.StatusOK, respData)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecateModuleVersionHandler(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/deprecation", DeprecateModuleVersionHandler).Methods("PUT", "DELETE")
+	send := func(method, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(method, "/api/v1/modules/my-org/billing/v2.0.0/deprecation", bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	expectVersion := func() uuid.UUID {
+		id := uuid.New()
+		mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+			WithArgs("my-org", "billing", "v2.0.0", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "module_id", "version", "artifact_digest"}).AddRow(id, uuid.New(), "v2.0.0", "aaa"))
+		return id
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send("PUT", `not json`).Code)
+
+	// Deprecate with a message
+	id := expectVersion()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "module_versions" SET "deprecated_at"=$1,"deprecation_message"=$2,"sunset_at"=$3,"sunset_enforced_at"=$4 WHERE id = $5`)).
+		WithArgs(sqlmock.AnyArg(), "rounding bug, use v2.0.1", nil, nil, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	rr := send("PUT", `{"message":" rounding bug, use v2.0.1 "}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp apitypes.DeprecationResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.True(t, resp.Deprecated)
+	assert.Equal(t, "rounding bug, use v2.0.1", resp.Message)
+	assert.NotNil(t, resp.DeprecatedAt)
+
+	// Lift the deprecation
+	id = expectVersion()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "module_versions" SET "deprecated_at"=$1,"deprecation_message"=$2,"sunset_at"=$3,"sunset_enforced_at"=$4 WHERE id = $5`)).
+		WithArgs(nil, "", nil, nil, id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	rr = send("DELETE", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"namespace":"my-org","module_name":"billing","version":"v2.0.0","deprecated":false}`, rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
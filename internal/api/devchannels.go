@@ -0,0 +1,456 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/notify"
+	"github.com/Suhaibinator/SProto/internal/repo"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Dev channels: mutable snapshots of a module's work in progress, published under dev-<name> (e.g.
+// dev-alice) by `protoreg-cli dev`, which republishes a proto directory whenever it changes. Services in a
+// dev cluster fetch a channel like a version (GET .../dev-alice/artifact), so they always get the latest
+// WIP schema. Versions start with 'v', so channel names can't be mistaken for them.
+//
+// Channels are deliberately not versions: they aren't listed, resolved, searched or recorded in the
+// checksum log, namespace and publish policies don't apply, and their responses are never cached as
+// immutable (the ETag is the current digest, so revalidation is cheap). Publishing only canonicalizes,
+// scans and checks the import graph, and requires the module to have been published before.
+// With DEV_CHANNEL_TTL set, channels not updated for that long are deleted.
+
+// devChannelTTL is how long a dev channel is kept after its last update; 0 (the default) keeps them.
+var devChannelTTL time.Duration
+
+// SetDevChannelTTL sets how long dev channels are kept after their last update (0 keeps them).
+func SetDevChannelTTL(ttl time.Duration) {
+	devChannelTTL = ttl
+}
+
+func devChannelResponse(namespace, moduleName string, channel *models.DevChannel) apitypes.DevChannelResponse {
+	return apitypes.DevChannelResponse{
+		Namespace:      namespace,
+		ModuleName:     moduleName,
+		Channel:        channel.Channel,
+		ArtifactDigest: "sha256:" + channel.ArtifactDigest,
+		ArtifactSize:   channel.ArtifactSize,
+		ScanStatus:     channel.ScanStatus,
+		Revision:       channel.Revision,
+		Publisher:      channel.Publisher,
+		CreatedAt:      channel.CreatedAt,
+		UpdatedAt:      channel.UpdatedAt,
+	}
+}
+
+// --- Publishing ---
+
+// PublishDevChannelHandler creates or replaces the artifact of a dev channel (multipart "artifact" field,
+// like publishing a version). Responds 201 when the channel is created, 200 when it is updated or the
+// artifact was already the channel's (unchanged).
+// PUT /api/v1/modules/{namespace}/{module_name}/{channel}
+// Requires Authentication.
+func PublishDevChannelHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	channelName := vars["channel"]
+	coordinates := fmt.Sprintf("%s/%s@%s", namespace, moduleName, channelName)
+	log := logging.FromContext(r.Context()).With(zap.String("dev_channel", coordinates))
+
+	// --- Input Validation ---
+	if err := validation.ValidateDevChannel(channelName); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	gormDB := db.GetDB()
+	module, err := primaryRepositories().Modules.Get(r.Context(), namespace, moduleName)
+	if errors.Is(err, repo.ErrNotFound) {
+		// Dev channels hang off an existing module, so a typo can't create a module nobody publishes to
+		response.Error(w, http.StatusNotFound, "Module not found: publish a first version before using dev channels")
+		return
+	} else if err != nil {
+		log.Error("Error finding module", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error during module lookup")
+		return
+	}
+
+	file, _, ok := readArtifactForm(w, r)
+	if !ok {
+		return // Response already written
+	}
+	defer file.Close()
+
+	// --- Canonical Archive, Content Policy, Virus Scan, Digest and Import Graph (as for versions) ---
+	file, _, ok = canonicalizeArtifact(w, r, file)
+	if !ok {
+		return // Response already written
+	}
+	nsPolicy, ok := findNamespacePolicy(w, r, namespace)
+	if !ok {
+		return // Response already written
+	}
+	if !checkContentPolicy(w, r, file, nsPolicy) {
+		return // Response already written
+	}
+	scanStatus, _, ok := scanArtifact(w, r, file, coordinates)
+	if !ok {
+		return // Response already written
+	}
+	digestHex, size, err := digestArtifact(file)
+	if err != nil {
+		log.Error("Error reading artifact file", zap.Error(err))
+		response.Error(w, http.StatusBadRequest, "Could not read artifact file")
+		return
+	}
+	if !checkImportGraph(w, r, file, coordinates) {
+		return // Response already written
+	}
+
+	var channel models.DevChannel
+	err = gormDB.Where("module_id = ? AND channel = ?", module.ID, channelName).First(&channel).Error
+	exists := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error finding dev channel", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error during dev channel lookup")
+		return
+	}
+	if exists && channel.ArtifactDigest == digestHex {
+		// Saved without changes, or a restarted watcher republishing: nothing for consumers to pull
+		resp := devChannelResponse(namespace, moduleName, &channel)
+		resp.Unchanged = true
+		response.JSON(w, http.StatusOK, resp)
+		return
+	}
+
+	// --- Storage ---
+	storageProvider := storage.GetStorageProvider()
+	storageKey := storage.DevChannelKey(namespace, moduleName, channelName, digestHex)
+	err = storageProvider.UploadFile(r.Context(), storageKey, file, size, "application/zip")
+	if errors.Is(err, storage.ErrObjectExists) {
+		// Left over from an earlier attempt whose database write failed
+		err = reuseExistingArtifact(r, storageProvider, storageKey, digestHex)
+	}
+	if err != nil {
+		log.Error("Error uploading dev channel artifact to storage", zap.String("key", storageKey), zap.Error(err))
+		switch status := storageErrorStatus(err); status {
+		case http.StatusInsufficientStorage:
+			response.Error(w, status, "Artifact storage quota exceeded")
+		case http.StatusServiceUnavailable:
+			response.Error(w, status, "Artifact storage unavailable")
+		default:
+			response.Error(w, http.StatusInternalServerError, "Failed to upload artifact to storage")
+		}
+		return
+	}
+
+	// --- Database ---
+	now := time.Now().UTC()
+	previousKey := channel.ArtifactStorageKey
+	status := http.StatusOK
+	if exists {
+		err = gormDB.Model(&channel).Updates(map[string]interface{}{
+			"artifact_digest":      digestHex,
+			"artifact_storage_key": storageKey,
+			"artifact_size":        size,
+			"scan_status":          scanStatus,
+			"revision":             gorm.Expr("revision + 1"),
+			"publisher":            r.Header.Get(apitypes.PublisherHeader),
+			"updated_at":           now,
+		}).Error
+		if err == nil {
+			err = gormDB.First(&channel, "id = ?", channel.ID).Error // The incremented revision
+		}
+	} else {
+		channel = models.DevChannel{
+			ModuleID:           module.ID,
+			Channel:            channelName,
+			ArtifactDigest:     digestHex,
+			ArtifactStorageKey: storageKey,
+			ArtifactSize:       size,
+			ScanStatus:         scanStatus,
+			Revision:           1,
+			Publisher:          r.Header.Get(apitypes.PublisherHeader),
+			CreatedAt:          now,
+			UpdatedAt:          now,
+		}
+		err = gormDB.Create(&channel).Error
+		status = http.StatusCreated
+	}
+	if err != nil {
+		log.Error("Error saving dev channel", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error saving dev channel")
+		return
+	}
+	log.Info("Published dev channel", zap.String("key", storageKey), zap.Int("revision", channel.Revision), zap.Int64("size", size))
+
+	// The previous artifact is no longer served (best-effort: a leftover object is only wasted space)
+	if previousKey != "" && previousKey != storageKey {
+		if err := storageProvider.DeleteFile(r.Context(), previousKey); err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			log.Warn("Failed to delete previous dev channel artifact", zap.String("key", previousKey), zap.Error(err))
+		}
+	}
+
+	// Lets dev clusters pull the new schema as soon as it is published, instead of polling
+	notify.Send(r.Context(), notify.Event{
+		Type:       notify.EventDevChannelUpdated,
+		Namespace:  namespace,
+		ModuleName: moduleName,
+		Message:    fmt.Sprintf("%s was updated (revision %d)", coordinates, channel.Revision),
+		Data: map[string]interface{}{
+			"channel":         channelName,
+			"revision":        channel.Revision,
+			"artifact_digest": "sha256:" + digestHex,
+		},
+	})
+	response.JSON(w, status, devChannelResponse(namespace, moduleName, &channel))
+}
+
+// DeleteDevChannelHandler deletes a dev channel and its artifact.
+// DELETE /api/v1/modules/{namespace}/{module_name}/{channel}
+// Requires Authentication.
+func DeleteDevChannelHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	channelName := vars["channel"]
+	if err := validation.ValidateDevChannel(channelName); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	channel, ok := findDevChannel(w, r, namespace, moduleName, channelName)
+	if !ok {
+		return // Response already written
+	}
+	if err := deleteDevChannel(r.Context(), channel); err != nil {
+		logging.FromContext(r.Context()).Error("Error deleting dev channel", zap.String("channel", channelName), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to delete dev channel")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteDevChannel deletes a dev channel's record, then (best-effort) its artifact.
+func deleteDevChannel(ctx context.Context, channel *models.DevChannel) error {
+	if err := db.GetDB().WithContext(ctx).Delete(&models.DevChannel{}, "id = ?", channel.ID).Error; err != nil {
+		return err
+	}
+	err := storage.GetStorageProvider().DeleteFile(ctx, channel.ArtifactStorageKey)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+		// The record is gone, so the object is no longer served
+		logging.L().Warn("Failed to delete dev channel artifact", zap.String("key", channel.ArtifactStorageKey), zap.Error(err))
+	}
+	return nil
+}
+
+// --- Fetching ---
+
+// findDevChannel looks up a dev channel by namespace, module name and channel name.
+// On failure it writes the error response (404 or 500) and returns false; like versions, channels of
+// modules the caller isn't allowed to read are reported as not found.
+// HEAD requests get the status code only, since their responses carry no body.
+func findDevChannel(w http.ResponseWriter, r *http.Request, namespace, moduleName, channelName string) (*models.DevChannel, bool) {
+	log := logging.FromContext(r.Context())
+	readable, err := moduleReadable(r, namespace, moduleName)
+	if err == nil && !readable {
+		err = gorm.ErrRecordNotFound
+	}
+	var channel models.DevChannel
+	if err == nil {
+		err = requestDB(r).Joins("JOIN modules ON modules.id = dev_channels.module_id").
+			Where("modules.namespace = ? AND modules.name = ? AND dev_channels.channel = ?", namespace, moduleName, channelName).
+			First(&channel).Error
+	}
+	if err != nil {
+		status, message := http.StatusNotFound, "Dev channel not found"
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Error("Error finding dev channel", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("channel", channelName), zap.Error(err))
+			status, message = http.StatusInternalServerError, "Failed to retrieve dev channel"
+		}
+		if r.Method == http.MethodHead {
+			w.WriteHeader(status)
+		} else {
+			response.Error(w, status, message)
+		}
+		return nil, false
+	}
+	return &channel, true
+}
+
+// setDevChannelHeaders sets the artifact metadata headers of a dev channel. Its artifact changes, so
+// responses must be revalidated (cheaply: the ETag is the current digest).
+func setDevChannelHeaders(w http.ResponseWriter, channel *models.DevChannel) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, channel.ArtifactDigest))
+	w.Header().Set("X-Artifact-Digest", "sha256:"+channel.ArtifactDigest)
+	w.Header().Set("X-Artifact-Size", strconv.FormatInt(channel.ArtifactSize, 10))
+	w.Header().Set("X-Scan-Status", channel.ScanStatus)
+	w.Header().Set(apitypes.DevRevisionHeader, strconv.Itoa(channel.Revision))
+	w.Header().Set("Cache-Control", "no-cache")
+}
+
+// GetDevChannelHandler returns the metadata of a dev channel.
+// GET|HEAD /api/v1/modules/{namespace}/{module_name}/{channel}
+func GetDevChannelHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := validation.ValidateDevChannel(vars["channel"]); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	channel, ok := findDevChannel(w, r, vars["namespace"], vars["module_name"], vars["channel"])
+	if !ok {
+		return
+	}
+	setDevChannelHeaders(w, channel)
+	if notModified(w, r, w.Header().Get("ETag")) {
+		return
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	response.JSON(w, http.StatusOK, devChannelResponse(vars["namespace"], vars["module_name"], channel))
+}
+
+// FetchDevChannelArtifactHandler downloads the current artifact of a dev channel. Unlike version artifacts
+// it is always streamed by the registry (never redirected to the CDN) and can't be fetched partially.
+// GET|HEAD /api/v1/modules/{namespace}/{module_name}/{channel}/artifact
+func FetchDevChannelArtifactHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	channelName := vars["channel"]
+	if err := validation.ValidateDevChannel(channelName); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.URL.Query().Has("paths") {
+		response.Error(w, http.StatusBadRequest, "Partial fetches (?paths=) aren't supported for dev channels")
+		return
+	}
+	channel, ok := findDevChannel(w, r, vars["namespace"], vars["module_name"], channelName)
+	if !ok {
+		return
+	}
+	log := logging.FromContext(r.Context()).With(zap.String("key", channel.ArtifactStorageKey))
+	storageProvider := storage.GetStorageProvider()
+
+	// HEAD: report existence and metadata without streaming the artifact
+	if r.Method == http.MethodHead {
+		exists, err := storageProvider.FileExists(r.Context(), channel.ArtifactStorageKey)
+		if err != nil {
+			log.Error("Error checking dev channel artifact in storage", zap.Error(err))
+			w.WriteHeader(storageErrorStatus(err))
+			return
+		}
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		setDevChannelHeaders(w, channel)
+		if !notModified(w, r, w.Header().Get("ETag")) {
+			w.WriteHeader(http.StatusOK)
+		}
+		return
+	}
+
+	// Consumers polling for changes mostly get a 304
+	setDevChannelHeaders(w, channel)
+	if notModified(w, r, w.Header().Get("ETag")) {
+		return
+	}
+
+	// Streaming holds a slot (MAX_CONCURRENT_ARTIFACT_STREAMS) for the whole download
+	limit := streamSlots
+	if !limit.acquire(w, r) {
+		return // 429 already written
+	}
+	defer limit.release()
+
+	artifactStream, err := storageProvider.DownloadFile(r.Context(), channel.ArtifactStorageKey)
+	if err != nil {
+		switch status := storageErrorStatus(err); status {
+		case http.StatusNotFound:
+			// Replaced between the lookup and the download: the client retries and gets the new one
+			log.Warn("Dev channel artifact not found in storage", zap.Error(err))
+			response.Error(w, status, "Artifact not found in storage")
+		case http.StatusServiceUnavailable:
+			log.Error("Storage unavailable while downloading dev channel artifact", zap.Error(err))
+			response.Error(w, status, "Artifact storage unavailable")
+		default:
+			log.Error("Error downloading dev channel artifact from storage", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to retrieve artifact from storage")
+		}
+		return
+	}
+	defer artifactStream.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Length", strconv.FormatInt(channel.ArtifactSize, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, channelName))
+	if _, err := io.Copy(w, artifactStream); err != nil {
+		// The client may have disconnected; headers are already sent
+		log.Warn("Error streaming dev channel artifact to client", zap.Error(err))
+	}
+}
+
+// listDevChannels returns the names of a module's dev channels, sorted.
+func listDevChannels(gormDB *gorm.DB, moduleID uuid.UUID) ([]string, error) {
+	var channels []string
+	err := gormDB.Model(&models.DevChannel{}).Where("module_id = ?", moduleID).Order("channel").Pluck("channel", &channels).Error
+	return channels, err
+}
+
+// --- Expiry ---
+
+// purgeDevChannels deletes the dev channels last updated before cutoff, with their artifacts. Returns how
+// many it deleted.
+func purgeDevChannels(ctx context.Context, cutoff time.Time) (int, error) {
+	var expired []models.DevChannel
+	if err := db.GetDB().WithContext(ctx).Where("updated_at <= ?", cutoff).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+	purged := 0
+	for i := range expired {
+		if err := deleteDevChannel(ctx, &expired[i]); err != nil {
+			return purged, err
+		}
+		purged++
+		logging.L().Info("Deleted expired dev channel", zap.String("job", "dev-channels"), zap.String("channel", expired[i].Channel), zap.Time("updated_at", expired[i].UpdatedAt))
+	}
+	return purged, nil
+}
+
+// RunDevChannelCleanup deletes the dev channels not updated for DEV_CHANNEL_TTL every interval (and once
+// at start) until ctx is canceled. It does nothing if no TTL is set.
+func RunDevChannelCleanup(ctx context.Context, interval time.Duration) {
+	if devChannelTTL <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := purgeDevChannels(ctx, time.Now().UTC().Add(-devChannelTTL)); err != nil {
+			logging.L().Warn("Failed to delete expired dev channels", zap.String("job", "dev-channels"), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for dev channels ---
+
+func TestDevChannelHandlers(t *testing.T) {
+	gormDB := newTestDB(t)
+	provider := newTestStorage(t)
+
+	module := models.Module{Namespace: "acme", Name: "billing", Visibility: models.VisibilityPublic}
+	assert.NoError(t, gormDB.Create(&module).Error)
+	assert.NoError(t, gormDB.Create(&models.ModuleVersion{ModuleID: module.ID, Version: "v1.0.0", VersionKey: validation.VersionKey("v1.0.0"), ArtifactDigest: "abc123", ArtifactStorageKey: "k"}).Error)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}", ListModuleVersionsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{channel:dev-[^/]*}", GetDevChannelHandler).Methods("GET", "HEAD")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{channel:dev-[^/]*}/artifact", FetchDevChannelArtifactHandler).Methods("GET", "HEAD")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{channel:dev-[^/]*}", PublishDevChannelHandler).Methods("PUT")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{channel:dev-[^/]*}", DeleteDevChannelHandler).Methods("DELETE")
+	zipOf := func(content string) []byte {
+		buf := new(bytes.Buffer)
+		zw := zip.NewWriter(buf)
+		w, err := zw.Create("billing/v1/billing.proto")
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+		assert.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+	put := func(module, channel string, artifact []byte) *httptest.ResponseRecorder {
+		req := newPublishRequest(t, "acme", module, channel, "", artifact)
+		req.Method = http.MethodPut
+		req.Header.Set(apitypes.PublisherHeader, "alice")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	do := func(method, path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/modules/acme/"+path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), readerKey, reader{}))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Created, then replaced: the revision goes up and the previous object is deleted
+	rr := put("billing", "dev-alice", zipOf(`syntax = "proto3";`))
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var first apitypes.DevChannelResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &first))
+	assert.Equal(t, 1, first.Revision)
+	assert.Equal(t, "alice", first.Publisher)
+
+	rr = put("billing", "dev-alice", zipOf(`syntax = "proto3"; package billing.v1;`))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var second apitypes.DevChannelResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &second))
+	assert.Equal(t, 2, second.Revision)
+	assert.False(t, second.Unchanged)
+	assert.NotEqual(t, first.ArtifactDigest, second.ArtifactDigest)
+	exists, err := provider.FileExists(context.Background(), storage.DevChannelKey("acme", "billing", "dev-alice", strings.TrimPrefix(first.ArtifactDigest, "sha256:")))
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// The same content again changes nothing
+	rr = put("billing", "dev-alice", zipOf(`syntax = "proto3"; package billing.v1;`))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var unchanged apitypes.DevChannelResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &unchanged))
+	assert.True(t, unchanged.Unchanged)
+	assert.Equal(t, 2, unchanged.Revision)
+
+	// Fetched like a version, but never cached as immutable
+	rr = do("GET", "billing/dev-alice/artifact", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, second.ArtifactDigest, rr.Header().Get("X-Artifact-Digest"))
+	assert.Equal(t, "2", rr.Header().Get(apitypes.DevRevisionHeader))
+	assert.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+	sum := sha256.Sum256(rr.Body.Bytes())
+	assert.Equal(t, second.ArtifactDigest, "sha256:"+hex.EncodeToString(sum[:]))
+	etag := rr.Header().Get("ETag")
+	assert.Equal(t, http.StatusNotModified, do("GET", "billing/dev-alice/artifact", etag).Code)
+	assert.Equal(t, http.StatusOK, do("HEAD", "billing/dev-alice/artifact", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "billing/dev-alice/artifact?paths=billing/", "").Code)
+
+	rr = do("GET", "billing/dev-alice", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var meta apitypes.DevChannelResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &meta))
+	assert.Equal(t, "dev-alice", meta.Channel)
+	assert.Equal(t, 2, meta.Revision)
+
+	// Listed next to the versions, not as one
+	rr = do("GET", "billing", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list ListModuleVersionsResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	assert.Equal(t, []string{"v1.0.0"}, list.Versions)
+	assert.Equal(t, []string{"dev-alice"}, list.DevChannels)
+
+	// Unknown modules, invalid names, unknown channels
+	assert.Equal(t, http.StatusNotFound, put("ledger", "dev-alice", zipOf(`syntax = "proto3";`)).Code)
+	assert.Equal(t, http.StatusBadRequest, put("billing", "dev-Alice", zipOf(`syntax = "proto3";`)).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "billing/dev-bob/artifact", "").Code)
+
+	// Deleted with its artifact
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "billing/dev-alice", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "billing/dev-alice", "").Code)
+	exists, err = provider.FileExists(context.Background(), storage.DevChannelKey("acme", "billing", "dev-alice", strings.TrimPrefix(second.ArtifactDigest, "sha256:")))
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// Expiry (DEV_CHANNEL_TTL)
+	assert.Equal(t, http.StatusCreated, put("billing", "dev-bob", zipOf(`syntax = "proto3";`)).Code)
+	purged, err := purgeDevChannels(context.Background(), time.Now().UTC().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, purged)
+	purged, err = purgeDevChannels(context.Background(), time.Now().UTC().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+}
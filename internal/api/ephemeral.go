@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"go.uber.org/zap"
+)
+
+// Ephemeral namespaces: a namespace policy with an ephemeral TTL marks its namespace as scratch space, for
+// CI preview builds (v0.0.0-pr123.abc) and dev channels. Versions published there are deleted by the
+// cleanup job (and the gc job) once the TTL has passed since their publish, dev channels once it has passed
+// since their last update, and modules left with neither are deleted too, so previews don't pile up in
+// long-term storage. Everything else about the namespace is unchanged: versions are immutable while they
+// exist, and their digests stay in the append-only checksum log after they are gone.
+
+// ephemeralResult counts what expireEphemeral deleted.
+type ephemeralResult struct {
+	Versions    int // Expired versions
+	DevChannels int // Dev channels not updated within the TTL
+	Modules     int // Modules left without versions and dev channels
+}
+
+// expireEphemeral deletes the versions and dev channels of ephemeral namespaces whose TTL has passed at
+// now, and the modules left empty. Stops early when ctx is canceled.
+func expireEphemeral(ctx context.Context, now time.Time) (ephemeralResult, error) {
+	gormDB := db.GetDB().WithContext(ctx)
+	var result ephemeralResult
+	var policies []models.NamespacePolicy
+	if err := gormDB.Where("ephemeral_ttl_seconds > 0").Find(&policies).Error; err != nil {
+		return result, err
+	}
+	for _, nsPolicy := range policies {
+		cutoff := now.Add(-time.Duration(nsPolicy.EphemeralTTLSeconds) * time.Second)
+		var modules []models.Module
+		if err := gormDB.Where("namespace = ?", nsPolicy.Namespace).Find(&modules).Error; err != nil {
+			return result, err
+		}
+		for _, module := range modules {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			if err := expireEphemeralModule(ctx, module, cutoff, &result); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// expireEphemeralModule deletes the versions of a module published before cutoff and its dev channels not
+// updated since, then the module itself if nothing is left.
+func expireEphemeralModule(ctx context.Context, module models.Module, cutoff time.Time, result *ephemeralResult) error {
+	gormDB := db.GetDB().WithContext(ctx)
+	log := logging.L().With(zap.String("job", "ephemeral"), zap.String("module", module.Namespace+"/"+module.Name))
+
+	var versions []models.ModuleVersion
+	if err := gormDB.Where("module_id = ? AND created_at < ?", module.ID, cutoff).Order("created_at").Find(&versions).Error; err != nil {
+		return err
+	}
+	for i := range versions {
+		if err := deleteModuleVersion(ctx, &versions[i]); err != nil {
+			return err
+		}
+		result.Versions++
+		log.Info("Deleted expired ephemeral version", zap.String("version", versions[i].Version))
+	}
+
+	var channels []models.DevChannel
+	if err := gormDB.Where("module_id = ? AND updated_at < ?", module.ID, cutoff).Find(&channels).Error; err != nil {
+		return err
+	}
+	for i := range channels {
+		if err := deleteDevChannel(ctx, &channels[i]); err != nil {
+			return err
+		}
+		result.DevChannels++
+		log.Info("Deleted expired ephemeral dev channel", zap.String("channel", channels[i].Channel))
+	}
+
+	deleted, err := deleteModuleIfEmpty(ctx, module.ID)
+	if err != nil || !deleted {
+		return err
+	}
+	result.Modules++
+	log.Info("Deleted empty ephemeral module")
+	return nil
+}
+
+// RunEphemeralCleanup deletes the expired versions of ephemeral namespaces every interval (and once at
+// start) until ctx is canceled.
+func RunEphemeralCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := expireEphemeral(ctx, time.Now().UTC()); err != nil && ctx.Err() == nil {
+			logging.L().Warn("Failed to delete expired ephemeral versions", zap.String("job", "ephemeral"), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEphemeralNamespaces(t *testing.T) {
+	gormDB := newTestDB(t)
+	provider := newTestStorage(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	router.HandleFunc("/api/v1/admin/namespace-policies/{namespace}", GetNamespacePolicyHandler).Methods("GET")
+	router.HandleFunc("/api/v1/admin/namespace-policies/{namespace}", PutNamespacePolicyHandler).Methods("PUT")
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	publish := func(namespace, name, version string) models.ModuleVersion {
+		data, err := artifact.Pack(map[string][]byte{name + ".proto": []byte(`syntax = "proto3"; package ` + name + `.` + strings.ReplaceAll(version, ".", "_") + `;`)})
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newPublishRequest(t, namespace, name, version, "", data))
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var mv models.ModuleVersion
+		assert.NoError(t, gormDB.Joins("JOIN modules ON modules.id = module_versions.module_id").
+			Where("modules.namespace = ? AND modules.name = ? AND module_versions.version = ?", namespace, name, version).First(&mv).Error)
+		return mv
+	}
+	age := func(mv models.ModuleVersion, d time.Duration) {
+		assert.NoError(t, gormDB.Model(&models.ModuleVersion{}).Where("id = ?", mv.ID).Update("created_at", time.Now().UTC().Add(-d)).Error)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, serve("PUT", "/api/v1/admin/namespace-policies/previews", `{"ephemeral_ttl":"30s"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve("PUT", "/api/v1/admin/namespace-policies/previews", `{"ephemeral_ttl":"3 days"}`).Code)
+	assert.Equal(t, http.StatusOK, serve("PUT", "/api/v1/admin/namespace-policies/previews", `{"ephemeral_ttl":"72h"}`).Code)
+	var got apitypes.NamespacePolicyResponse
+	assert.NoError(t, json.Unmarshal(serve("GET", "/api/v1/admin/namespace-policies/previews", "").Body.Bytes(), &got))
+	assert.Equal(t, "72h0m0s", got.EphemeralTTL)
+
+	expired := publish("previews", "orders", "v0.1.0")
+	fresh := publish("previews", "orders", "v0.2.0")
+	lone := publish("previews", "billing", "v0.1.0")
+	kept := publish("acme", "orders", "v1.0.0") // Not ephemeral
+	age(expired, 73*time.Hour)
+	age(lone, 73*time.Hour)
+	age(kept, 1000*time.Hour)
+	assert.NoError(t, gormDB.Create(&models.VersionNote{ModuleVersionID: expired.ID, Body: "preview of #123"}).Error)
+
+	result, err := expireEphemeral(context.Background(), time.Now().UTC())
+	assert.NoError(t, err)
+	assert.Equal(t, ephemeralResult{Versions: 2, Modules: 1}, result)
+
+	var versions []string
+	assert.NoError(t, gormDB.Model(&models.ModuleVersion{}).Order("version").Pluck("version", &versions).Error)
+	assert.Equal(t, []string{"v0.2.0", "v1.0.0"}, versions)
+	var notes, modules int64
+	assert.NoError(t, gormDB.Model(&models.VersionNote{}).Count(&notes).Error)
+	assert.Zero(t, notes)
+	assert.NoError(t, gormDB.Model(&models.Module{}).Where("namespace = ? AND name = ?", "previews", "billing").Count(&modules).Error)
+	assert.Zero(t, modules, "modules left without versions are deleted")
+	for key, want := range map[string]bool{expired.ArtifactStorageKey: false, fresh.ArtifactStorageKey: true, kept.ArtifactStorageKey: true} {
+		exists, err := provider.FileExists(context.Background(), key)
+		assert.NoError(t, err)
+		assert.Equal(t, want, exists, key)
+	}
+
+	// Nothing else has expired yet
+	result, err = expireEphemeral(context.Background(), time.Now().UTC())
+	assert.NoError(t, err)
+	assert.Equal(t, ephemeralResult{}, result)
+}
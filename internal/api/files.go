@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// File digests: the size and SHA256 of every file of a version's artifact are recorded at publish and listed
+// by the files endpoint, so a client holding an extracted copy of another version only downloads the files
+// that changed (with a partial fetch, ?paths=). Versions published before files were recorded are indexed
+// the first time their files are listed.
+
+// readFileDigests hashes the files of the uploaded artifact. The file is rewound afterwards. Hashing never
+// fails a publish; nil is returned instead, and the files are indexed when they are first listed.
+func readFileDigests(ctx context.Context, file multipart.File) []artifact.FileDigest {
+	data, err := io.ReadAll(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("Error reading artifact for file digests", zap.Error(err))
+		return nil
+	}
+	return artifactFileDigests(ctx, data)
+}
+
+// artifactFileDigests returns the digests of an artifact's files, logging (but otherwise ignoring) errors.
+func artifactFileDigests(ctx context.Context, data []byte) []artifact.FileDigest {
+	digests, err := artifact.FileDigests(data)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to compute the digests of the artifact's files", zap.Error(err))
+		return nil
+	}
+	return digests
+}
+
+// versionFiles returns the records of a module version's file digests, recorded within its publish
+// transaction (or lazily, when listed).
+func versionFiles(moduleVersionID uuid.UUID, digests []artifact.FileDigest) []models.VersionFile {
+	files := make([]models.VersionFile, 0, len(digests))
+	for _, d := range digests {
+		files = append(files, models.VersionFile{ModuleVersionID: moduleVersionID, Path: d.Name, Size: d.Size, Digest: d.SHA256})
+	}
+	return files
+}
+
+// ListVersionFilesHandler lists the files of a module version's artifact with their size and SHA256.
+// GET /api/v1/modules/{namespace}/{module_name}/{version}/files
+// ?paths=billing/,common/types.proto lists only the matching files, like a partial fetch.
+func ListVersionFilesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+	var paths []string
+	if r.URL.Query().Has("paths") {
+		var err error
+		if paths, err = artifact.ParsePaths(r.URL.Query().Get("paths")); err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid paths: %v", err))
+			return
+		}
+	}
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return // Response already written
+	}
+	log := logging.FromContext(r.Context()).With(zap.Stringer("module_version_id", moduleVersion.ID))
+
+	var files []models.VersionFile
+	if err := requestDB(r).Where("module_version_id = ?", moduleVersion.ID).Order("path ASC").Find(&files).Error; err != nil {
+		log.Error("Error listing version files", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve files")
+		return
+	}
+	if len(files) == 0 {
+		// Published before files were recorded (or hashing failed then): index them now
+		var ok bool
+		if files, ok = indexStoredFiles(w, r, moduleVersion); !ok {
+			return // Response already written
+		}
+	}
+
+	respData := apitypes.ListVersionFilesResponse{
+		Namespace:      namespace,
+		ModuleName:     moduleName,
+		Version:        moduleVersion.Version,
+		ArtifactDigest: "sha256:" + moduleVersion.ArtifactDigest,
+		Files:          make([]apitypes.VersionFileResponse, 0, len(files)), // Empty array, not null
+	}
+	for _, f := range files {
+		if paths != nil && !artifact.MatchesPaths(f.Path, paths) {
+			continue
+		}
+		respData.Files = append(respData.Files, apitypes.VersionFileResponse{Path: f.Path, Size: f.Size, Digest: "sha256:" + f.Digest})
+	}
+
+	setImmutableCacheHeaders(w) // The artifact of a version never changes
+	response.JSON(w, http.StatusOK, respData)
+}
+
+// indexStoredFiles computes and records the file digests of a version published before they were recorded.
+// Recording them is best-effort: they are computed again on the next listing if it fails.
+// Returns false if the artifact can't be read; an error response has then been written.
+func indexStoredFiles(w http.ResponseWriter, r *http.Request, moduleVersion *models.ModuleVersion) ([]models.VersionFile, bool) {
+	log := logging.FromContext(r.Context()).With(zap.Stringer("module_version_id", moduleVersion.ID))
+
+	data, err := readStoredArtifact(r, moduleVersion)
+	if err != nil {
+		switch status := storageErrorStatus(err); status {
+		case http.StatusNotFound:
+			log.Warn("Artifact not found in storage", zap.String("key", moduleVersion.ArtifactStorageKey), zap.Error(err))
+			response.Error(w, status, "Artifact not found in storage")
+		case http.StatusServiceUnavailable:
+			log.Error("Storage unavailable while reading artifact", zap.String("key", moduleVersion.ArtifactStorageKey), zap.Error(err))
+			response.Error(w, status, "Artifact storage unavailable")
+		default:
+			log.Error("Error reading artifact from storage", zap.String("key", moduleVersion.ArtifactStorageKey), zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to retrieve artifact from storage")
+		}
+		return nil, false
+	}
+	digests, err := artifact.FileDigests(data)
+	if err != nil {
+		log.Error("Error computing the digests of the artifact's files", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to read the artifact's files")
+		return nil, false
+	}
+	files := versionFiles(moduleVersion.ID, digests)
+	if err := primaryRepositories().Versions.AddFiles(r.Context(), files); err != nil {
+		log.Warn("Failed to record the digests of the artifact's files", zap.Error(err))
+	}
+	return files, true
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListVersionFilesHandler(t *testing.T) {
+	gormDB := newTestDB(t)
+	newTestStorage(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/files", ListVersionFilesHandler).Methods("GET")
+	list := func(version, query string) (*httptest.ResponseRecorder, apitypes.ListVersionFilesResponse) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/modules/acme/user/"+version+"/files"+query, nil))
+		var resp apitypes.ListVersionFilesResponse
+		if rr.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		}
+		return rr, resp
+	}
+	digest := func(content string) string {
+		sum := sha256.Sum256([]byte(content))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	user := `syntax = "proto3"; package user.v1;`
+	types := `syntax = "proto3"; package user.types.v1;`
+	data, err := artifact.Pack(map[string][]byte{"user/v1/user.proto": []byte(user), "user/types/v1/types.proto": []byte(types)})
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, newPublishRequest(t, "acme", "user", "v1.0.0", "", data))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	// Recorded at publish, by path
+	rr, resp := list("v1.0.0", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+	sum := sha256.Sum256(data)
+	assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), resp.ArtifactDigest)
+	assert.Equal(t, []apitypes.VersionFileResponse{
+		{Path: "user/types/v1/types.proto", Size: int64(len(types)), Digest: digest(types)},
+		{Path: "user/v1/user.proto", Size: int64(len(user)), Digest: digest(user)},
+	}, resp.Files)
+	var count int64
+	assert.NoError(t, gormDB.Model(&models.VersionFile{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	// Filtered like a partial fetch
+	rr, resp = list("v1.0.0", "?paths=user/v1/")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, resp.Files, 1)
+	assert.Equal(t, "user/v1/user.proto", resp.Files[0].Path)
+	rr, resp = list("v1.0.0", "?paths=billing/")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, []apitypes.VersionFileResponse{}, resp.Files)
+
+	// Versions published before files were recorded are indexed when first listed
+	assert.NoError(t, gormDB.Where("1 = 1").Delete(&models.VersionFile{}).Error)
+	rr, resp = list("v1.0.0", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, resp.Files, 2)
+	assert.NoError(t, gormDB.Model(&models.VersionFile{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	rr, _ = list("v2.0.0", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr, _ = list("1.0.0", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr, _ = list("v1.0.0", "?paths=../other")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,446 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/gomod"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/repo"
+	"github.com/Suhaibinator/SProto/internal/scan"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Go module proxy: for modules with server-side Go generation enabled (GO_PROXY_MODULES), the registry
+// serves the protoc-gen-go output of every version as a Go module through the GOPROXY protocol under
+// /gomod, so Go services can `go get` generated code with GOPROXY=https://<registry>/gomod,... (see package
+// gomod for the module layout). A version's Go module is generated on first request and attached to it as
+// the secondary artifact "go-module": go.sum pins the hashes of the zip and go.mod, so they must never change,
+// even when newer dependency versions are published later.
+// Paths the proxy doesn't serve are answered with 404, so the go command moves on to the next proxy.
+
+// GoModuleClassifier is the secondary artifact classifier generated Go module zips are attached under.
+const GoModuleClassifier = "go-module"
+
+// GoProxyPathPrefix is where the Go module proxy is served (the GOPROXY URL is the registry's plus this).
+const GoProxyPathPrefix = "/gomod"
+
+// goProxy holds the Go module proxy settings; see SetGoProxy.
+var goProxy struct {
+	prefix  string   // Go module path prefix; "" if the proxy is disabled
+	modules []string // Modules (namespace/name) with Go generation enabled (path.Match patterns)
+}
+
+// GoModuleInfo is the .info document of a Go module version.
+type GoModuleInfo struct {
+	Version string    `json:"Version"`
+	Time    time.Time `json:"Time"`
+}
+
+// SetGoProxy configures the Go module proxy: the module path prefix of generated modules (e.g.
+// buf.example.com/gen/go) and the modules (path.Match patterns of namespace/name) with Go generation
+// enabled. No patterns disable the proxy.
+func SetGoProxy(prefix string, modules []string) error {
+	prefix = strings.TrimSuffix(strings.TrimSpace(prefix), "/")
+	var patterns []string
+	for _, pattern := range modules {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid Go module pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if len(patterns) > 0 {
+		if prefix == "" {
+			return errors.New("a Go module path prefix is required to enable Go generation")
+		}
+		first, _, _ := strings.Cut(prefix, "/")
+		if !strings.Contains(first, ".") || strings.ContainsAny(prefix, " :@!") || strings.ToLower(prefix) != prefix {
+			return fmt.Errorf("invalid Go module path prefix %q: must be a lowercase module path starting with a domain, e.g. buf.example.com/gen/go", prefix)
+		}
+	}
+	goProxy.prefix, goProxy.modules = prefix, patterns
+	return nil
+}
+
+// goGenerationEnabled reports whether Go generation is enabled for a module.
+func goGenerationEnabled(namespace, name string) bool {
+	for _, pattern := range goProxy.modules {
+		if ok, _ := path.Match(pattern, namespace+"/"+name); ok { // Patterns are validated by SetGoProxy
+			return true
+		}
+	}
+	return false
+}
+
+// goModule is a registry module's major version, as a Go module.
+type goModule struct {
+	path      string
+	namespace string
+	name      string
+	major     uint64 // Major version of the path; 0 for the unsuffixed path (versions 0 and 1)
+}
+
+// findGoModule resolves the module path of a proxy request to a module with Go generation enabled that the
+// caller can read. On failure a 404 has been written (or 500) and ok is false.
+func findGoModule(w http.ResponseWriter, r *http.Request) (*goModule, bool) {
+	log := logging.FromContext(r.Context())
+	if len(goProxy.modules) == 0 {
+		response.Error(w, http.StatusNotFound, "Go module proxy is not enabled")
+		return nil, false
+	}
+	modulePath, err := gomod.UnescapePath(mux.Vars(r)["module_path"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	namespace, name, major, err := gomod.ParseModulePath(goProxy.prefix, modulePath)
+	if err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	if !goGenerationEnabled(namespace, name) {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("Go generation is not enabled for %s/%s", namespace, name))
+		return nil, false
+	}
+	readable, err := moduleReadable(r, namespace, name)
+	if err != nil {
+		log.Error("Error checking module visibility", zap.String("namespace", namespace), zap.String("module", name), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module")
+		return nil, false
+	}
+	if !readable {
+		response.Error(w, http.StatusNotFound, "Module not found") // Don't reveal that it exists
+		return nil, false
+	}
+	return &goModule{path: modulePath, namespace: namespace, name: name, major: major}, true
+}
+
+// goModuleVersions returns the versions of a module that are versions of its Go module (see
+// gomod.VersionMajor and gomod.MatchesPath), sorted ascending.
+func goModuleVersions(r *http.Request, m *goModule) ([]*semver.Version, error) {
+	repos := requestRepositories(r)
+	module, err := repos.Modules.Get(r.Context(), m.namespace, m.name)
+	if errors.Is(err, repo.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, err := repos.Versions.ListByModule(r.Context(), module.ID)
+	if err != nil {
+		return nil, err
+	}
+	var versions semver.Collection
+	for _, row := range rows {
+		if major, ok := gomod.VersionMajor(row.Version); ok && gomod.MatchesPath(m.major, major) {
+			versions = append(versions, semver.MustParse(row.Version))
+		}
+	}
+	sort.Sort(versions)
+	return versions, nil
+}
+
+// findGoModuleVersion resolves the version of a proxy request. On failure a response has been written
+// (404, or 410 for sunset versions) and ok is false.
+func findGoModuleVersion(w http.ResponseWriter, r *http.Request, m *goModule) (*models.ModuleVersion, bool) {
+	version, err := gomod.UnescapePath(mux.Vars(r)["version"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	if major, ok := gomod.VersionMajor(version); !ok || !gomod.MatchesPath(m.major, major) {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("%s is not a version of %s", version, m.path))
+		return nil, false
+	}
+	moduleVersion, ok := findModuleVersion(w, r, m.namespace, m.name, version)
+	if !ok {
+		return nil, false // Response already written
+	}
+	if sunsetGone(w, r, moduleVersion) {
+		return nil, false // 410 already written
+	}
+	return moduleVersion, true
+}
+
+// GoModuleListHandler lists the versions of a generated Go module, one per line.
+// GET /gomod/{module_path}/@v/list
+func GoModuleListHandler(w http.ResponseWriter, r *http.Request) {
+	m, ok := findGoModule(w, r)
+	if !ok {
+		return // Response already written
+	}
+	versions, err := goModuleVersions(r, m)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error listing Go module versions", zap.String("module_path", m.path), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module versions")
+		return
+	}
+	var b strings.Builder
+	for _, v := range versions {
+		b.WriteString(v.Original() + "\n")
+	}
+	setListCacheHeaders(w)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+// GoModuleLatestHandler serves the .info document of the latest version of a generated Go module: the
+// highest release, or the highest pre-release if there is none.
+// GET /gomod/{module_path}/@latest
+func GoModuleLatestHandler(w http.ResponseWriter, r *http.Request) {
+	m, ok := findGoModule(w, r)
+	if !ok {
+		return // Response already written
+	}
+	versions, err := goModuleVersions(r, m)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error listing Go module versions", zap.String("module_path", m.path), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module versions")
+		return
+	}
+	if len(versions) == 0 {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("%s has no versions", m.path))
+		return
+	}
+	latest := versions[len(versions)-1]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].Prerelease() == "" {
+			latest = versions[i]
+			break
+		}
+	}
+	moduleVersion, ok := findModuleVersion(w, r, m.namespace, m.name, latest.Original())
+	if !ok {
+		return // Response already written
+	}
+	setListCacheHeaders(w) // Changes when a version is published
+	response.JSON(w, http.StatusOK, GoModuleInfo{Version: moduleVersion.Version, Time: moduleVersion.CreatedAt.UTC()})
+}
+
+// GoModuleInfoHandler serves the .info document of a generated Go module version.
+// GET /gomod/{module_path}/@v/{version}.info
+func GoModuleInfoHandler(w http.ResponseWriter, r *http.Request) {
+	m, ok := findGoModule(w, r)
+	if !ok {
+		return // Response already written
+	}
+	moduleVersion, ok := findGoModuleVersion(w, r, m)
+	if !ok {
+		return // Response already written
+	}
+	setImmutableCacheHeaders(w)
+	response.JSON(w, http.StatusOK, GoModuleInfo{Version: moduleVersion.Version, Time: moduleVersion.CreatedAt.UTC()})
+}
+
+// GoModuleModHandler serves the go.mod file of a generated Go module version, generating the module first
+// if this is its first request.
+// GET /gomod/{module_path}/@v/{version}.mod
+func GoModuleModHandler(w http.ResponseWriter, r *http.Request) {
+	m, ok := findGoModule(w, r)
+	if !ok {
+		return // Response already written
+	}
+	moduleVersion, ok := findGoModuleVersion(w, r, m)
+	if !ok {
+		return // Response already written
+	}
+	moduleZip, ok := goModuleZip(w, r, m, moduleVersion)
+	if !ok {
+		return // Response already written
+	}
+	goMod, err := gomod.ReadGoMod(moduleZip, m.path, moduleVersion.Version)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error reading go.mod of generated Go module", zap.String("module_path", m.path), zap.String("version", moduleVersion.Version), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to read generated go.mod")
+		return
+	}
+	setImmutableCacheHeaders(w)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(goMod)
+}
+
+// GoModuleZipHandler serves the zip of a generated Go module version, generating it first if this is its
+// first request.
+// GET /gomod/{module_path}/@v/{version}.zip
+func GoModuleZipHandler(w http.ResponseWriter, r *http.Request) {
+	m, ok := findGoModule(w, r)
+	if !ok {
+		return // Response already written
+	}
+	moduleVersion, ok := findGoModuleVersion(w, r, m)
+	if !ok {
+		return // Response already written
+	}
+	recordFetch(r, moduleVersion.ID) // Consumption report
+
+	limit := streamSlots
+	if !limit.acquire(w, r) {
+		return // 429 already written
+	}
+	defer limit.release()
+
+	moduleZip, ok := goModuleZip(w, r, m, moduleVersion)
+	if !ok {
+		return // Response already written
+	}
+	setImmutableCacheHeaders(w)
+	w.Header().Set("Content-Type", "application/zip")
+	if _, err := w.Write(moduleZip); err != nil {
+		logging.FromContext(r.Context()).Warn("Error writing Go module zip to client", zap.Error(err))
+	}
+}
+
+// goModuleZip returns the zip of a version's Go module: the one attached to the version, or a newly
+// generated one, attached for the next requests. On failure a response has been written and ok is false.
+func goModuleZip(w http.ResponseWriter, r *http.Request, m *goModule, moduleVersion *models.ModuleVersion) ([]byte, bool) {
+	log := logging.FromContext(r.Context()).With(zap.String("module_path", m.path), zap.String("version", moduleVersion.Version))
+
+	versionArtifact, err := findVersionArtifact(r.Context(), requestDB(r), moduleVersion, GoModuleClassifier)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Not generated yet (or not replicated yet: the primary decides)
+		versionArtifact, err = findVersionArtifact(r.Context(), db.GetDB(), moduleVersion, GoModuleClassifier)
+	}
+	switch {
+	case err == nil:
+		return readVersionArtifact(w, r, log, versionArtifact)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		log.Error("Error finding generated Go module", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve Go module")
+		return nil, false
+	}
+
+	// --- Generation ---
+	moduleZip, ok := generateGoModule(w, r, log, m, moduleVersion)
+	if !ok {
+		return nil, false // Response already written
+	}
+	sum := sha256.Sum256(moduleZip)
+	digestHex := hex.EncodeToString(sum[:])
+	storageKey, err := uploadVersionArtifact(r, m.namespace, m.name, moduleVersion.Version, GoModuleClassifier, "application/zip", moduleZip, digestHex)
+	if err != nil {
+		log.Error("Error uploading generated Go module", zap.String("key", storageKey), zap.Error(err))
+		response.Error(w, storageErrorStatus(err), "Failed to store generated Go module")
+		return nil, false
+	}
+	versionArtifact = &models.VersionArtifact{
+		ModuleVersionID: moduleVersion.ID,
+		Classifier:      GoModuleClassifier,
+		ContentType:     "application/zip",
+		Digest:          digestHex,
+		Size:            int64(len(moduleZip)),
+		StorageKey:      storageKey,
+		ScanStatus:      scan.StatusSkipped, // Generated by the registry, not uploaded
+		CreatedAt:       time.Now().UTC(),
+	}
+	if err := db.GetDB().Create(versionArtifact).Error; err != nil {
+		// Another request may have generated it concurrently: what it stored is what must be served
+		existing, findErr := findVersionArtifact(r.Context(), db.GetDB(), moduleVersion, GoModuleClassifier)
+		if findErr != nil {
+			log.Error("Error saving generated Go module", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to store generated Go module")
+			return nil, false
+		}
+		return readVersionArtifact(w, r, log, existing)
+	}
+	log.Info("Generated Go module", zap.String("key", storageKey), zap.Int("size", len(moduleZip)))
+	return moduleZip, true
+}
+
+// generateGoModule generates the Go module zip of a version from its bundle (see descriptor.Bundle): the
+// module's files become packages of its Go module, its dependencies' files are imported from their own
+// Go modules, which must have Go generation enabled too. Compilation and generation failures are 422.
+func generateGoModule(w http.ResponseWriter, r *http.Request, log *zap.Logger, m *goModule, moduleVersion *models.ModuleVersion) ([]byte, bool) {
+	bundle, _, ok := readableBundle(w, r, log, m.namespace, m.name, moduleVersion.Version)
+	if !ok {
+		return nil, false // Response already written
+	}
+	set, err := bundle.DescriptorSet(r.Context())
+	if err != nil {
+		writeBundleError(w, log, err)
+		return nil, false
+	}
+
+	module := gomod.Module{
+		Path:            m.path,
+		Version:         moduleVersion.Version,
+		ImportPaths:     map[string]string{},
+		ProtobufVersion: gomod.ProtobufVersion(),
+	}
+	for _, f := range bundle.ModuleFiles {
+		if !isWellKnownType(f) {
+			module.Files = append(module.Files, f)
+		}
+	}
+	depPaths := map[string]string{}
+	for _, dep := range bundle.Dependencies {
+		depNamespace, depName, _ := manifest.SplitModule(dep.Module) // Validated by the loader
+		major, ok := gomod.VersionMajor(dep.Version)
+		if !goGenerationEnabled(depNamespace, depName) || !ok {
+			depPaths[dep.Module] = "" // Only an error if its files are imported
+			continue
+		}
+		depPaths[dep.Module] = gomod.ModulePath(goProxy.prefix, depNamespace, depName, major)
+	}
+	required := map[string]bool{}
+	for file, origin := range bundle.Origins {
+		if isWellKnownType(file) {
+			continue // Generated in google.golang.org/protobuf, whatever module carries them
+		}
+		depPath := depPaths[origin]
+		if depPath == "" {
+			response.Error(w, http.StatusUnprocessableEntity, fmt.Sprintf("Dependency %s has no Go module: Go generation must be enabled for it, at a version Go accepts", origin))
+			return nil, false
+		}
+		module.ImportPaths[file] = gomod.PackagePath(depPath, file)
+		if !required[origin] {
+			required[origin] = true
+			for _, dep := range bundle.Dependencies {
+				if dep.Module == origin {
+					module.Requires = append(module.Requires, gomod.Require{Path: depPath, Version: dep.Version})
+				}
+			}
+		}
+	}
+	if len(module.Files) == 0 {
+		response.Error(w, http.StatusUnprocessableEntity, "Module has no files to generate Go code for")
+		return nil, false
+	}
+
+	files, err := gomod.Generate(set, module)
+	if err != nil {
+		log.Info("Go generation failed", zap.Error(err))
+		response.Error(w, http.StatusUnprocessableEntity, err.Error())
+		return nil, false
+	}
+	moduleZip, err := gomod.Zip(m.path, moduleVersion.Version, files)
+	if err != nil {
+		log.Error("Error packing generated Go module", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to generate Go module")
+		return nil, false
+	}
+	return moduleZip, true
+}
+
+// isWellKnownType reports whether a .proto file is one of the well-known types, whose Go packages ship with
+// google.golang.org/protobuf.
+func isWellKnownType(file string) bool {
+	return strings.HasPrefix(file, "google/protobuf/")
+}
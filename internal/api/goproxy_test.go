@@ -0,0 +1,151 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoModuleProxy(t *testing.T) {
+	gormDB := newTestDB(t)
+	provider := newTestStorage(t)
+
+	// Patterns need a prefix that is a module path
+	assert.Error(t, SetGoProxy("", []string{"acme/*"}))
+	assert.Error(t, SetGoProxy("https://buf.example.com", []string{"acme/*"}))
+	assert.Error(t, SetGoProxy("gen/go", []string{"acme/*"}))
+	assert.Error(t, SetGoProxy("buf.example.com/gen/go", []string{"acme/["}))
+	assert.NoError(t, SetGoProxy("buf.example.com/gen/go/", []string{"acme/*"}))
+	t.Cleanup(func() { _ = SetGoProxy("", nil) })
+
+	modules := map[string]*models.Module{}
+	publish := func(namespace, name, version string, files map[string]string) {
+		packed := make(map[string][]byte, len(files))
+		for p, content := range files {
+			packed[p] = []byte(content)
+		}
+		data, err := artifact.Pack(packed)
+		assert.NoError(t, err)
+		module := modules[namespace+"/"+name]
+		if module == nil {
+			module = &models.Module{Namespace: namespace, Name: name, Visibility: models.VisibilityPublic}
+			assert.NoError(t, gormDB.Create(module).Error)
+			modules[namespace+"/"+name] = module
+		}
+		key := namespace + "/" + name + "/" + version + ".zip"
+		assert.NoError(t, provider.UploadFile(context.Background(), key, bytes.NewReader(data), int64(len(data)), "application/zip"))
+		assert.NoError(t, gormDB.Create(&models.ModuleVersion{ModuleID: module.ID, Version: version, VersionKey: validation.VersionKey(version), ArtifactDigest: key, ArtifactStorageKey: key}).Error)
+	}
+	publish("acme", "types", "v1.0.0", map[string]string{
+		"acme/types/v1/id.proto": `syntax = "proto3"; package acme.types.v1; message ID { string value = 1; }`,
+	})
+	billing := map[string]string{
+		"sproto.yaml": "name: acme/billing\ndependencies:\n  acme/types: ^1.0.0\n",
+		"acme/billing/v1/billing.proto": `syntax = "proto3"; package acme.billing.v1; import "acme/types/v1/id.proto";
+import "google/protobuf/timestamp.proto";
+message Charge { acme.types.v1.ID account = 1; google.protobuf.Timestamp at = 2; }`,
+	}
+	publish("acme", "billing", "v1.0.0", billing)
+	publish("acme", "billing", "v1.1.0-rc.1", billing)
+	publish("acme", "billing", "v1.1.0+build.7", billing) // Not a Go version
+	publish("acme", "billing", "v2.0.0", billing)
+	publish("other", "users", "v1.0.0", map[string]string{
+		"other/users/v1/user.proto": `syntax = "proto3"; package other.users.v1; message User { string id = 1; }`,
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc(GoProxyPathPrefix+"/{module_path:.+}/@v/list", GoModuleListHandler)
+	router.HandleFunc(GoProxyPathPrefix+"/{module_path:.+}/@v/{version}.info", GoModuleInfoHandler)
+	router.HandleFunc(GoProxyPathPrefix+"/{module_path:.+}/@v/{version}.mod", GoModuleModHandler)
+	router.HandleFunc(GoProxyPathPrefix+"/{module_path:.+}/@v/{version}.zip", GoModuleZipHandler)
+	router.HandleFunc(GoProxyPathPrefix+"/{module_path:.+}/@latest", GoModuleLatestHandler)
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", GoProxyPathPrefix+"/"+path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), readerKey, reader{}))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Versions 0 and 1 share the unsuffixed module path; build metadata isn't valid in Go versions
+	rr := get("buf.example.com/gen/go/acme/billing/@v/list")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "v1.0.0\nv1.1.0-rc.1\n", rr.Body.String())
+	assert.Equal(t, "v2.0.0\n", get("buf.example.com/gen/go/acme/billing/v2/@v/list").Body.String())
+
+	rr = get("buf.example.com/gen/go/acme/billing/@latest")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var info GoModuleInfo
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &info))
+	assert.Equal(t, "v1.0.0", info.Version)
+	rr = get("buf.example.com/gen/go/acme/billing/@v/v1.1.0-rc.1.info")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &info))
+	assert.Equal(t, "v1.1.0-rc.1", info.Version)
+
+	// The module is generated on first request, requiring its dependency's Go module
+	rr = get("buf.example.com/gen/go/acme/billing/v2/@v/v2.0.0.mod")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "module buf.example.com/gen/go/acme/billing/v2\n")
+	assert.Contains(t, rr.Body.String(), "\tbuf.example.com/gen/go/acme/types v1.0.0\n")
+	assert.Contains(t, rr.Body.String(), "\tgoogle.golang.org/protobuf v")
+	goMod := rr.Body.String()
+
+	rr = get("buf.example.com/gen/go/acme/billing/v2/@v/v2.0.0.zip")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+	zipReader, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if assert.NoError(t, err) && assert.Len(t, zipReader.File, 2) {
+		assert.Equal(t, "buf.example.com/gen/go/acme/billing/v2@v2.0.0/acme/billing/v1/billing.pb.go", zipReader.File[0].Name)
+		assert.Equal(t, "buf.example.com/gen/go/acme/billing/v2@v2.0.0/go.mod", zipReader.File[1].Name)
+	}
+	var attached models.VersionArtifact
+	assert.NoError(t, gormDB.Where("classifier = ?", GoModuleClassifier).First(&attached).Error)
+	assert.Equal(t, int64(rr.Body.Len()), attached.Size)
+
+	// Once generated, the module never changes, even when newer dependency versions are published
+	publish("acme", "types", "v1.1.0", map[string]string{
+		"acme/types/v1/id.proto": `syntax = "proto3"; package acme.types.v1; message ID { string value = 1; string kind = 2; }`,
+	})
+	assert.Equal(t, goMod, get("buf.example.com/gen/go/acme/billing/v2/@v/v2.0.0.mod").Body.String())
+	rr = get("buf.example.com/gen/go/acme/billing/@v/v1.0.0.mod")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "\tbuf.example.com/gen/go/acme/types v1.1.0\n")
+
+	// Anything the proxy doesn't serve is 404, so the go command tries the next proxy
+	for _, path := range []string{
+		"buf.example.com/gen/go/other/users/@v/list",            // Go generation not enabled
+		"example.com/acme/billing/@v/list",                      // Not under the prefix
+		"buf.example.com/gen/go/acme/Billing/@v/list",           // Uppercase letters must be escaped
+		"buf.example.com/gen/go/acme/billing/@v/v2.0.0.info",    // Wrong major version
+		"buf.example.com/gen/go/acme/billing/@v/v1.2.0.info",    // No such version
+		"buf.example.com/gen/go/acme/missing/@latest",           // No such module
+		"buf.example.com/gen/go/acme/billing/v1/@v/v1.0.0.info", // v1 has no suffix
+	} {
+		assert.Equal(t, http.StatusNotFound, get(path).Code, path)
+	}
+
+	// Dependencies must have Go generation enabled too
+	assert.NoError(t, SetGoProxy("buf.example.com/gen/go", []string{"acme/billing"}))
+	rr = get("buf.example.com/gen/go/acme/billing/@v/v1.1.0-rc.1.zip")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "acme/types")
+
+	// The go command authenticates with Basic credentials from .netrc
+	var authorization string
+	handler := basicTokenMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { authorization = r.Header.Get("Authorization") }))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("ci", "read-token")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "Bearer read-token", authorization)
+}
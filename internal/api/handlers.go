@@ -1,16 +1,18 @@
 package api
 
 import (
-	"log"
+	"bytes"
+	"context"
 	"net/http"
-	"os"
 	"sort"
+	"strconv"
 	"strings"
 
 	"errors"
 
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/url"
 
 	"github.com/Masterminds/semver/v3"
@@ -20,11 +22,19 @@ import (
 	"time"
 
 	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/artifact"
 	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
 	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/scan"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
 
+	"github.com/Suhaibinator/SProto/internal/repo"
 	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -38,39 +48,88 @@ type ModuleInfo struct {
 	Namespace     string `json:"namespace"`
 	Name          string `json:"name"`
 	LatestVersion string `json:"latest_version"` // Based on creation time for now
+	Visibility    string `json:"visibility,omitempty"`
+	// Syntaxes and editions declared by the latest version's .proto files (proto2, proto3, edition-2023)
+	Syntaxes []string `json:"syntaxes,omitempty"`
+	// When the module was created or last published to
+	UpdatedAt time.Time `json:"updated_at"`
+	// Downloads of all its versions (see Consumer Reports); only included with ?sort=downloads
+	Downloads *int64 `json:"downloads,omitempty"`
+}
+
+// moduleListFilter parses the filters and sort order of a list request (see ListModulesHandler), or
+// returns the message of a 400 for invalid parameters.
+func moduleListFilter(params url.Values) (repo.ModuleFilter, error) {
+	filter := repo.ModuleFilter{Namespace: params.Get("namespace")}
+	if value := params.Get("updated_after"); value != "" {
+		updatedAfter, err := apitypes.ParseTokenExpiry(value) // Same date formats
+		if err != nil {
+			return filter, fmt.Errorf("Invalid updated_after %q: expected a date (2006-01-02) or an RFC3339 timestamp", value)
+		}
+		filter.UpdatedAfter = updatedAfter
+	}
+	if value := params.Get("has_versions"); value != "" {
+		hasVersions, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("Invalid has_versions %q: expected true or false", value)
+		}
+		filter.HasVersions = &hasVersions
+	}
+	switch order := params.Get("sort"); order {
+	case "", apitypes.ModuleSortName, apitypes.ModuleSortUpdatedAt, apitypes.ModuleSortDownloads:
+		filter.Sort = order
+	default:
+		return filter, fmt.Errorf("Invalid sort %q: expected %s, %s or %s", order, apitypes.ModuleSortName, apitypes.ModuleSortUpdatedAt, apitypes.ModuleSortDownloads)
+	}
+	return filter, nil
 }
 
 // ListModulesHandler handles requests to list all registered modules.
 // GET /api/v1/modules
+// Optional filters: ?namespace= (exact), ?updated_after= (a date or RFC3339 timestamp; modules created or
+// published to since), ?has_versions=true|false and ?syntax= (proto2, proto3, edition-2023; modules whose
+// latest version declares it). ?sort=name (default), updated_at (most recent first) or downloads (most
+// downloaded first, adding each module's download count).
 func ListModulesHandler(w http.ResponseWriter, r *http.Request) {
-	gormDB := db.GetDB() // Get the initialized GORM DB instance
-
-	// Use Raw SQL to execute the query similar to the one defined for sqlc,
-	// as replicating the CTE and window function logic purely with GORM methods can be complex.
-	query := `
-		WITH LatestVersions AS (
-			SELECT
-				module_id,
-				version,
-				ROW_NUMBER() OVER(PARTITION BY module_id ORDER BY created_at DESC) as rn
-			FROM module_versions
-		)
-		SELECT
-			m.namespace,
-			m.name,
-			COALESCE(lv.version, '') AS latest_version
-		FROM modules m
-		LEFT JOIN LatestVersions lv ON m.id = lv.module_id AND lv.rn = 1
-		ORDER BY m.namespace, m.name;
-	`
+	log := logging.FromContext(r.Context())
+	syntax, ok := syntaxFilter(w, r)
+	if !ok {
+		return // 400 already written
+	}
+	filter, err := moduleListFilter(r.URL.Query())
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	var results []ModuleInfo
-	if err := gormDB.Raw(query).Scan(&results).Error; err != nil {
-		log.Printf("Error listing modules: %v", err)
+	rows, err := readRepositories().Modules.List(r.Context(), filter) // Read replica, if configured
+	if err != nil {
+		log.Error("Error listing modules", zap.Error(err))
 		response.Error(w, http.StatusInternalServerError, "Failed to retrieve modules")
 		return
 	}
 
+	// Leave out modules the caller isn't allowed to read, and those not matching the syntax filter
+	rd := readerFromContext(r.Context())
+	var results []ModuleInfo
+	for _, row := range rows {
+		if !rd.canRead(row.Namespace, row.Name, row.Visibility) || (syntax != "" && !hasSyntax(row.LatestSyntaxes, syntax)) {
+			continue
+		}
+		m := ModuleInfo{
+			Namespace:     row.Namespace,
+			Name:          row.Name,
+			LatestVersion: row.LatestVersion,
+			Visibility:    row.Visibility,
+			UpdatedAt:     row.UpdatedAt,
+			Downloads:     row.Downloads,
+		}
+		if row.LatestSyntaxes != "" {
+			m.Syntaxes = splitSyntaxes(row.LatestSyntaxes)
+		}
+		results = append(results, m)
+	}
+
 	// Although the SQL query gets the latest by creation date,
 	// true semantic version sorting might be desired here if versions
 	// could be published out of order. We'll skip that complexity for now.
@@ -81,6 +140,7 @@ func ListModulesHandler(w http.ResponseWriter, r *http.Request) {
 		respData.Modules = []ModuleInfo{}
 	}
 
+	setListCacheHeaders(w)
 	response.JSON(w, http.StatusOK, respData)
 }
 
@@ -90,11 +150,20 @@ type ListModuleVersionsResponse struct {
 	Namespace  string   `json:"namespace"`
 	ModuleName string   `json:"module_name"`
 	Versions   []string `json:"versions"`
+	// CHANGELOG.md sections by version; versions without one are omitted
+	Changelogs map[string]string `json:"changelogs,omitempty"`
+	// Syntaxes and editions declared by each version's .proto files; versions published before they
+	// were detected are omitted
+	Syntaxes map[string][]string `json:"syntaxes,omitempty"`
+	// Dev channels of the module (dev-<name>, see PUT .../{channel}), sorted; they aren't versions
+	DevChannels []string `json:"dev_channels,omitempty"`
 }
 
 // ListModuleVersionsHandler handles requests to list versions for a specific module.
 // GET /api/v1/modules/{namespace}/{module_name}
+// With ?syntax= (proto2, proto3, edition-2023), only versions declaring it are listed.
 func ListModuleVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
 	vars := mux.Vars(r)
 	namespace := vars["namespace"]
 	moduleName := vars["module_name"]
@@ -103,49 +172,72 @@ func ListModuleVersionsHandler(w http.ResponseWriter, r *http.Request) {
 		response.Error(w, http.StatusBadRequest, "Namespace and module name are required")
 		return
 	}
-
-	gormDB := db.GetDB()
-	var module models.Module
+	syntax, ok := syntaxFilter(w, r)
+	if !ok {
+		return // 400 already written
+	}
 
 	// Find the module first
-	err := gormDB.Where("namespace = ? AND name = ?", namespace, moduleName).First(&module).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("Module not found: %s/%s", namespace, moduleName)
-			response.Error(w, http.StatusNotFound, "Module not found")
-		} else {
-			log.Printf("Error finding module %s/%s: %v", namespace, moduleName, err)
-			response.Error(w, http.StatusInternalServerError, "Failed to retrieve module")
-		}
-		return
+	module, ok := findReadableModule(w, r, namespace, moduleName)
+	if !ok {
+		return // Response already written
 	}
 
-	// Find the versions for this module
-	var versions []string
-	err = gormDB.Model(&models.ModuleVersion{}).Where("module_id = ?", module.ID).Order("created_at DESC").Pluck("version", &versions).Error
+	// Find the versions (and their changelogs and syntaxes) for this module
+	rows, err := requestRepositories(r).Versions.ListByModule(r.Context(), module.ID)
 	if err != nil {
-		log.Printf("Error listing versions for module %s/%s (ID: %s): %v", namespace, moduleName, module.ID, err)
+		log.Error("Error listing versions for module", zap.String("namespace", namespace), zap.String("module", moduleName), zap.Stringer("module_id", module.ID), zap.Error(err))
 		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module versions")
 		return
 	}
 
+	var versions []string
+	changelogs := map[string]string{}
+	syntaxes := map[string][]string{}
+	for _, row := range rows {
+		if syntax != "" && !hasSyntax(row.Syntaxes, syntax) {
+			continue
+		}
+		versions = append(versions, row.Version)
+		if row.Changelog != "" {
+			changelogs[row.Version] = row.Changelog
+		}
+		if row.Syntaxes != "" {
+			syntaxes[row.Version] = splitSyntaxes(row.Syntaxes)
+		}
+	}
+
 	// Sort versions semantically descending
 	sortVersionsDesc(versions) // Use the helper function
 
+	// Dev channels are listed alongside, best-effort: they aren't versions
+	devChannels, err := listDevChannels(db.GetReadDB(), module.ID)
+	if err != nil {
+		log.Warn("Error listing dev channels for module", zap.String("namespace", namespace), zap.String("module", moduleName), zap.Error(err))
+	}
+
 	respData := ListModuleVersionsResponse{
 		Namespace:  namespace,
 		ModuleName: moduleName,
 		Versions:   versions,
+		Changelogs: changelogs,
+		Syntaxes:   syntaxes,
+
+		DevChannels: devChannels,
 	}
 	if versions == nil {
 		respData.Versions = []string{} // Ensure empty array, not null
 	}
 
+	setListCacheHeaders(w)
 	response.JSON(w, http.StatusOK, respData)
 }
 
 // FetchModuleVersionArtifactHandler handles requests to download a module version's artifact.
-// GET /api/v1/modules/{namespace}/{module_name}/{version}/artifact
+// GET|HEAD /api/v1/modules/{namespace}/{module_name}/{version}/artifact
+// HEAD checks the artifact exists in storage and returns its headers without streaming it.
+// In CDN mode, GET redirects to a short-lived signed CDN URL instead of streaming the artifact.
+// With ?paths=, only the matching files are returned, re-packed into a zip (see servePartialArtifact).
 func FetchModuleVersionArtifactHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	namespace := vars["namespace"]
@@ -164,38 +256,101 @@ func FetchModuleVersionArtifactHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	// More robust SemVer validation could be added here if needed
 
-	gormDB := db.GetDB()
-	var moduleVersion models.ModuleVersion
+	// Partial fetch (?paths=billing/,common/types.proto): only the matching files
+	var paths []string
+	if r.URL.Query().Has("paths") {
+		var err error
+		if paths, err = artifact.ParsePaths(r.URL.Query().Get("paths")); err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid paths: %v", err))
+			return
+		}
+	}
 
-	// Find the specific module version, joining with modules to filter by namespace/name
-	err := gormDB.Joins("JOIN modules ON modules.id = module_versions.module_id").
-		Where("modules.namespace = ? AND modules.name = ? AND module_versions.version = ?", namespace, moduleName, version).
-		First(&moduleVersion).Error
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return
+	}
+	if sunsetGone(w, r, moduleVersion) {
+		return // 410 already written
+	}
+	if r.Method == http.MethodGet {
+		recordFetch(r, moduleVersion.ID) // Consumption report (HEAD only checks existence)
+	}
 
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("Module version not found: %s/%s@%s", namespace, moduleName, version)
-			response.Error(w, http.StatusNotFound, "Module version not found")
-		} else {
-			log.Printf("Error finding module version %s/%s@%s: %v", namespace, moduleName, version, err)
-			response.Error(w, http.StatusInternalServerError, "Failed to retrieve module version details")
-		}
+	// Partial artifacts are built on demand, so they are served directly rather than through the CDN
+	if paths != nil {
+		servePartialArtifact(w, r, moduleVersion, paths)
 		return
 	}
 
+	// CDN mode: offload delivery to the CDN, which fetches from the signed origin endpoint
+	if cdnSigner != nil && r.Method == http.MethodGet {
+		redirectToCDN(w, r, namespace, moduleName, version)
+		return
+	}
+
+	serveArtifact(w, r, moduleVersion)
+}
+
+// serveArtifact streams a module version's artifact from storage (or, for HEAD, checks it exists).
+// Shared by the API artifact endpoint and the signed CDN origin endpoint.
+func serveArtifact(w http.ResponseWriter, r *http.Request, moduleVersion *models.ModuleVersion) {
+	log := logging.FromContext(r.Context())
+	version := moduleVersion.Version
+
 	// Get the storage provider
 	storageProvider := storage.GetStorageProvider()
 
+	// HEAD: report existence and metadata without streaming the artifact
+	if r.Method == http.MethodHead {
+		exists, err := storageProvider.FileExists(r.Context(), moduleVersion.ArtifactStorageKey)
+		if err != nil {
+			log.Error("Error checking artifact in storage", zap.String("key", moduleVersion.ArtifactStorageKey), zap.Error(err))
+			w.WriteHeader(storageErrorStatus(err))
+			return
+		}
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		setArtifactHeaders(w, moduleVersion)
+		setChecksumHeaders(w, r, moduleVersion)
+		if notModified(w, r, w.Header().Get("ETag")) {
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Revalidation (e.g. by a CDN): the artifact can't have changed if the digest matches
+	if etagMatches(r, artifactETag(moduleVersion)) {
+		setArtifactHeaders(w, moduleVersion)
+		setChecksumHeaders(w, r, moduleVersion)
+		notModified(w, r, artifactETag(moduleVersion))
+		return
+	}
+
+	// Streaming holds a slot (MAX_CONCURRENT_ARTIFACT_STREAMS) for the whole download
+	limit := streamSlots
+	if !limit.acquire(w, r) {
+		return // 429 already written
+	}
+	defer limit.release()
+
 	// Get the artifact stream from the storage provider
 	artifactStream, err := storageProvider.DownloadFile(r.Context(), moduleVersion.ArtifactStorageKey)
 	if err != nil {
-		// Check if it's a 'not found' error specifically if possible (depends on provider impl)
-		// Note: Need to import "os" for os.ErrNotExist
-		if errors.Is(err, os.ErrNotExist) || strings.Contains(strings.ToLower(err.Error()), "not found") || strings.Contains(strings.ToLower(err.Error()), "no such key") {
-			log.Printf("Artifact not found in storage: key=%s, error=%v", moduleVersion.ArtifactStorageKey, err)
-			response.Error(w, http.StatusNotFound, "Artifact not found in storage")
-		} else {
-			log.Printf("Error downloading artifact from storage: key=%s, error=%v", moduleVersion.ArtifactStorageKey, err)
+		// Providers wrap backend-specific errors in the storage sentinel errors
+		switch status := storageErrorStatus(err); status {
+		case http.StatusNotFound:
+			log.Warn("Artifact not found in storage", zap.String("key", moduleVersion.ArtifactStorageKey), zap.Error(err))
+			response.Error(w, status, "Artifact not found in storage")
+		case http.StatusServiceUnavailable:
+			log.Error("Storage unavailable while downloading artifact", zap.String("key", moduleVersion.ArtifactStorageKey), zap.Error(err))
+			response.Error(w, status, "Artifact storage unavailable")
+		default:
+			log.Error("Error downloading artifact from storage", zap.String("key", moduleVersion.ArtifactStorageKey), zap.Error(err))
 			response.Error(w, http.StatusInternalServerError, "Failed to retrieve artifact from storage")
 		}
 		return
@@ -207,39 +362,338 @@ func FetchModuleVersionArtifactHandler(w http.ResponseWriter, r *http.Request) {
 	// Encode filename according to RFC 5987 for broader compatibility
 	encodedFilename := url.PathEscape(fmt.Sprintf("%s.zip", version))
 	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, version+".zip", encodedFilename))
-	if moduleVersion.ArtifactDigest != "" {
-		// Use the stored digest as ETag.
-		w.Header().Set("ETag", fmt.Sprintf(`"%s"`, moduleVersion.ArtifactDigest))
-	}
-	// Content-Length is harder to determine reliably beforehand with the abstraction, removed for now.
-	// If needed later, the StorageProvider interface could be extended with a StatFile method.
+	// ETag, digest, size and scan status, and the signed checksum statement
+	setArtifactHeaders(w, moduleVersion)
+	setChecksumHeaders(w, r, moduleVersion)
 
 	// Stream the artifact content to the response writer
 	_, err = io.Copy(w, artifactStream)
 	if err != nil {
 		// This error might happen if the client disconnects mid-stream
-		log.Printf("Error streaming artifact %s/%s@%s to client: %v", namespace, moduleName, version, err)
+		log.Warn("Error streaming artifact to client", zap.Stringer("module_version_id", moduleVersion.ID), zap.String("version", version), zap.Error(err))
 		// Can't send an error response here as headers/body might be partially written
 		return
 	}
 }
 
-// PublishModuleVersionRequest defines the expected path parameters (implicitly handled by mux).
-// The request body is multipart/form-data with a file field named "artifact".
+// DeleteModuleVersionHandler deletes a module version with its artifacts, and the module if it's left
+// without versions and dev channels. The version's digest stays in the append-only checksum log, so its
+// number shouldn't be reused for different content.
+// DELETE /api/v1/modules/{namespace}/{module_name}/{version}
+// Requires Authentication.
+func DeleteModuleVersionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return // Response already written
+	}
+	log := logging.FromContext(r.Context()).With(zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", version))
+
+	if err := deleteModuleVersion(r.Context(), moduleVersion); err != nil {
+		log.Error("Error deleting module version", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to delete module version")
+		return
+	}
+	// The version is gone either way; a module left behind is only clutter
+	if _, err := deleteModuleIfEmpty(r.Context(), moduleVersion.ModuleID); err != nil {
+		log.Warn("Failed to delete empty module", zap.Error(err))
+	}
+	log.Info("Module version deleted")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteModuleVersion deletes a version with its notes, secondary artifacts, original upload, download
+// counts and search documents, then (best-effort) the objects no other record uses. Versions republished
+// with ?from= share their source's artifact object, and identical original uploads share theirs.
+func deleteModuleVersion(ctx context.Context, version *models.ModuleVersion) error {
+	gormDB := db.GetDB().WithContext(ctx)
+	var secondary []models.VersionArtifact
+	if err := gormDB.Where("module_version_id = ?", version.ID).Find(&secondary).Error; err != nil {
+		return err
+	}
+	var originals []models.OriginalUpload
+	if err := gormDB.Where("module_version_id = ?", version.ID).Find(&originals).Error; err != nil {
+		return err
+	}
+
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&models.VersionNote{}, &models.VersionArtifact{}, &models.VersionFile{}, &models.OriginalUpload{}, &models.ModuleConsumption{}, &models.SearchDocument{}} {
+			if err := tx.Where("module_version_id = ?", version.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.ModuleVersion{}, "id = ?", version.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// The records are gone, so the objects are no longer served; failures only leave them behind
+	deleteUnreferencedObject(ctx, &models.ModuleVersion{}, "artifact_storage_key", version.ArtifactStorageKey)
+	for _, a := range secondary {
+		deleteUnreferencedObject(ctx, &models.VersionArtifact{}, "storage_key", a.StorageKey)
+	}
+	for _, o := range originals {
+		deleteUnreferencedObject(ctx, &models.OriginalUpload{}, "storage_key", o.StorageKey)
+	}
+	return nil
+}
+
+// deleteUnreferencedObject deletes an object from storage unless a record of model still has it in column.
+func deleteUnreferencedObject(ctx context.Context, model any, column, key string) {
+	log := logging.L().With(zap.String("key", key))
+	var references int64
+	if err := db.GetDB().WithContext(ctx).Model(model).Where(column+" = ?", key).Count(&references).Error; err != nil {
+		log.Warn("Failed to check whether an object is still used", zap.Error(err))
+		return
+	}
+	if references > 0 {
+		return
+	}
+	if err := storage.GetStorageProvider().DeleteFile(ctx, key); err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+		log.Warn("Failed to delete object", zap.Error(err))
+	}
+}
+
+// deleteModuleIfEmpty deletes a module with its packages and search documents if it has neither versions
+// nor dev channels left. Reports whether it was deleted.
+func deleteModuleIfEmpty(ctx context.Context, moduleID uuid.UUID) (bool, error) {
+	gormDB := db.GetDB().WithContext(ctx)
+	var remainingVersions, remainingChannels int64
+	if err := gormDB.Model(&models.ModuleVersion{}).Where("module_id = ?", moduleID).Count(&remainingVersions).Error; err != nil {
+		return false, err
+	}
+	if err := gormDB.Model(&models.DevChannel{}).Where("module_id = ?", moduleID).Count(&remainingChannels).Error; err != nil {
+		return false, err
+	}
+	if remainingVersions > 0 || remainingChannels > 0 {
+		return false, nil
+	}
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&models.SearchDocument{}, &models.ProtoPackage{}} {
+			if err := tx.Where("module_id = ?", moduleID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.Module{}, "id = ?", moduleID).Error
+	})
+	return err == nil, err
+}
+
+// GetModuleVersionHandler returns metadata for a single module version, including its notes.
+// GET|HEAD /api/v1/modules/{namespace}/{module_name}/{version}
+// HEAD responds with the same status and X-Artifact-* headers but no body, so clients can cheaply
+// check whether a version exists.
+func GetModuleVersionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return
+	}
+
+	setModuleVersionHeaders(w, moduleVersion)
+	setListCacheHeaders(w) // Metadata such as the scan status may still change
+	if r.Method == http.MethodHead {
+		// Existence check only: skip loading the notes
+		if !notModified(w, r, w.Header().Get("ETag")) {
+			w.WriteHeader(http.StatusOK)
+		}
+		return
+	}
+
+	notes, err := listVersionNotes(moduleVersion.ID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error listing version notes", zap.Stringer("module_version_id", moduleVersion.ID), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve version notes")
+		return
+	}
+	// Adding a note or changing the deprecation status must invalidate cached copies of the metadata
+	if etag := versionMetadataETag(moduleVersion, len(notes)); etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if notModified(w, r, w.Header().Get("ETag")) {
+		return
+	}
+
+	response.JSON(w, http.StatusOK, apitypes.ModuleVersionResponse{
+		Namespace:      namespace,
+		ModuleName:     moduleName,
+		Version:        moduleVersion.Version,
+		ArtifactDigest: "sha256:" + moduleVersion.ArtifactDigest,
+		ArtifactSize:   moduleVersion.ArtifactSize,
+		ScanStatus:     moduleVersion.ScanStatus,
+		CreatedAt:      moduleVersion.CreatedAt,
+
+		Deprecated:         moduleVersion.DeprecatedAt != nil,
+		DeprecationMessage: moduleVersion.DeprecationMessage,
+		SunsetAt:           moduleVersion.SunsetAt,
+		Sunset:             moduleVersion.SunsetEnforcedAt != nil,
+		CanaryPercent:      moduleVersion.CanaryPercent,
+		Notes:              notes,
+
+		NoSchemaChange:     moduleVersion.NoSchemaChangeFrom != "",
+		NoSchemaChangeFrom: moduleVersion.NoSchemaChangeFrom,
+		Syntaxes:           splitSyntaxes(moduleVersion.Syntaxes),
+
+		Checksum:   checksumAttestation(r.Context(), namespace, moduleName, moduleVersion.Version),
+		Signature:  versionSignature(moduleVersion, namespace, moduleName),
+		Provenance: versionProvenance(moduleVersion),
+	})
+}
+
+// storageErrorStatus maps storage provider errors to HTTP status codes:
+// missing objects are 404, an unreachable bucket is 503, a full backend is 507 and anything else 500.
+func storageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrObjectNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrBucketUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, storage.ErrObjectExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// requestDB returns the database handle for a request: the read replica's (if configured) for GET and
+// HEAD requests, the primary's otherwise, so requests that go on to write don't act on stale reads.
+func requestDB(r *http.Request) *gorm.DB {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return db.GetReadDB()
+	}
+	return db.GetDB()
+}
+
+// findReadableModule looks up a module by namespace and name, reporting modules the caller isn't allowed to
+// read as not found. On failure it writes the error response (404 or 500) and returns false.
+func findReadableModule(w http.ResponseWriter, r *http.Request, namespace, moduleName string) (*models.Module, bool) {
+	log := logging.FromContext(r.Context())
+	module, err := requestRepositories(r).Modules.Get(r.Context(), namespace, moduleName)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			log.Info("Module not found", zap.String("namespace", namespace), zap.String("module", moduleName))
+			response.Error(w, http.StatusNotFound, "Module not found")
+		} else {
+			log.Error("Error finding module", zap.String("namespace", namespace), zap.String("module", moduleName), zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to retrieve module")
+		}
+		return nil, false
+	}
+	if !readerFromContext(r.Context()).canRead(namespace, moduleName, module.Visibility) {
+		response.Error(w, http.StatusNotFound, "Module not found") // Don't reveal that it exists
+		return nil, false
+	}
+	return module, true
+}
+
+// findModuleVersion looks up a module version by namespace, module name and version.
+// On failure it writes the error response (404 or 500) and returns false.
+// HEAD requests get the status code only, since their responses carry no body.
+func findModuleVersion(w http.ResponseWriter, r *http.Request, namespace, moduleName, version string) (*models.ModuleVersion, bool) {
+	log := logging.FromContext(r.Context())
+
+	// Modules the caller isn't allowed to read are reported as not found
+	readable, err := moduleReadable(r, namespace, moduleName)
+	if err == nil && !readable {
+		err = repo.ErrNotFound
+	}
+
+	var moduleVersion *models.ModuleVersion
+	if err == nil {
+		moduleVersion, err = requestRepositories(r).Versions.Find(r.Context(), namespace, moduleName, version)
+	}
+
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			log.Info("Module version not found", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", version))
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+			} else {
+				response.Error(w, http.StatusNotFound, "Module version not found")
+			}
+		} else {
+			log.Error("Error finding module version", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", version), zap.Error(err))
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusInternalServerError)
+			} else {
+				response.Error(w, http.StatusInternalServerError, "Failed to retrieve module version details")
+			}
+		}
+		return nil, false
+	}
+	return moduleVersion, true
+}
 
-// PublishModuleVersionResponse defines the successful response structure.
-type PublishModuleVersionResponse struct {
-	Namespace      string    `json:"namespace"`
-	ModuleName     string    `json:"module_name"`
-	Version        string    `json:"version"`
-	ArtifactDigest string    `json:"artifact_digest"` // sha256:<hex_digest>
-	CreatedAt      time.Time `json:"created_at"`
+// setArtifactHeaders sets the module version headers plus Content-Length (when the size is known)
+// and an immutable Cache-Control for responses carrying the artifact itself.
+func setArtifactHeaders(w http.ResponseWriter, moduleVersion *models.ModuleVersion) {
+	setModuleVersionHeaders(w, moduleVersion)
+	setImmutableCacheHeaders(w)
+	if moduleVersion.ArtifactSize > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(moduleVersion.ArtifactSize, 10))
+	}
 }
 
+// artifactETag returns the quoted ETag for a module version (its artifact digest), or "" if unknown.
+func artifactETag(moduleVersion *models.ModuleVersion) string {
+	if moduleVersion.ArtifactDigest == "" {
+		return ""
+	}
+	return fmt.Sprintf(`"%s"`, moduleVersion.ArtifactDigest)
+}
+
+// setModuleVersionHeaders sets the artifact metadata headers shared by the artifact and version endpoints.
+func setModuleVersionHeaders(w http.ResponseWriter, moduleVersion *models.ModuleVersion) {
+	if moduleVersion.ArtifactDigest != "" {
+		// Use the stored digest as ETag.
+		w.Header().Set("ETag", artifactETag(moduleVersion))
+		w.Header().Set("X-Artifact-Digest", "sha256:"+moduleVersion.ArtifactDigest)
+	}
+	if moduleVersion.ArtifactSize > 0 {
+		// Sizes are only known for versions published after the artifact_size column was added
+		w.Header().Set("X-Artifact-Size", strconv.FormatInt(moduleVersion.ArtifactSize, 10))
+	}
+	if moduleVersion.ScanStatus != "" {
+		// Let clients see artifacts that were accepted despite a detection (flag policy)
+		w.Header().Set("X-Scan-Status", moduleVersion.ScanStatus)
+	}
+	setSignatureHeaders(w, moduleVersion)
+}
+
+// PublishModuleVersionRequest defines the expected path parameters (implicitly handled by mux).
+// The request body is multipart/form-data with a file field named "artifact".
+
 // PublishModuleVersionHandler handles requests to publish a new module version.
 // POST /api/v1/modules/{namespace}/{module_name}/{version}
+// With ?validate_only=true the server-side checks run but nothing is persisted.
+// With ?from={version} the new version is created from an existing version's artifact (no body, see
+// republishModuleVersion).
+// With ?async=true the upload is queued and 202 Accepted is returned with an operation to poll (see
+// enqueuePublish).
 // Requires Authentication.
 func PublishModuleVersionHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
 	vars := mux.Vars(r)
 	namespace := vars["namespace"]
 	moduleName := vars["module_name"]
@@ -251,160 +705,498 @@ func PublishModuleVersionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate SemVer format
-	semVer, err := semver.NewVersion(versionStr)
+	// Validate namespace and module name (character set, length, Windows-safe)
+	if err := validation.ValidateNamespace(namespace); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validation.ValidateModuleName(moduleName); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Validate SemVer format, storing it with the 'v' prefix and all three numbers ("1.2" is "v1.2.0")
+	versionStr, err := validation.NormalizeVersion(versionStr)
 	if err != nil {
 		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid semantic version format: %v", err))
 		return
 	}
-	// Re-assign versionStr to ensure it includes the 'v' prefix consistently if the library stripped it
-	versionStr = "v" + semVer.String()
+
+	// Visibility of the module if this publish creates it (ignored for existing modules)
+	visibility := r.URL.Query().Get("visibility")
+	if visibility == "" {
+		visibility = defaultVisibility
+	} else if err := apitypes.ValidateVisibility(visibility); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// --- Republish From an Existing Version ---
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		republishModuleVersion(w, r, namespace, moduleName, versionStr, fromStr)
+		return
+	}
+
+	// --- Asynchronous Publish ---
+	// Processed by a publish worker, which calls this handler again without ?async
+	if r.URL.Query().Get("async") == "true" {
+		enqueuePublish(w, r, namespace, moduleName, versionStr)
+		return
+	}
 
 	// --- File Handling & Digest Calculation ---
-	// Limit upload size (e.g., 32 MB)
-	r.Body = http.MaxBytesReader(w, r.Body, 32<<20) // 32 MB
-	err = r.ParseMultipartForm(32 << 20)
+	file, header, ok := readArtifactForm(w, r)
+	if !ok {
+		return // Response already written
+	}
+	defer file.Close()
+
+	// --- Canonical Archive ---
+	// The zip is re-packed deterministically, so the digest doesn't depend on the client's zip tool.
+	// Everything below (scan, digest, validation, storage) works on the canonical archive; the uploaded
+	// bytes are only kept if ORIGINAL_UPLOAD_RETENTION is set (see retainOriginalUpload).
+	reportStage(r.Context(), StageCanonicalizing)
+	file, original, ok := canonicalizeArtifact(w, r, file)
+	if !ok {
+		return // Response already written
+	}
+
+	// --- Content Policy ---
+	// Content types, file sizes and extensions allowed by the server, or by the namespace policy (nil if the
+	// namespace has none, also checked further below)
+	nsPolicy, ok := findNamespacePolicy(w, r, namespace)
+	if !ok {
+		return // Response already written
+	}
+	if !checkContentPolicy(w, r, file, nsPolicy) {
+		return // Response already written
+	}
+
+	// --- Virus Scan (optional) ---
+	reportStage(r.Context(), StageScanning)
+	scanStatus, scanResult, ok := scanArtifact(w, r, file, fmt.Sprintf("%s/%s@%s", namespace, moduleName, versionStr))
+	if !ok {
+		return // Response already written
+	}
+
+	// --- Digest Calculation ---
+	// The digest is part of the storage key, so it is computed before the upload (the file is rewound afterwards)
+	artifactDigestHex, artifactSize, err := digestArtifact(file)
 	if err != nil {
-		log.Printf("Error parsing multipart form: %v", err)
+		log.Error("Error reading artifact file", zap.Error(err))
+		response.Error(w, http.StatusBadRequest, "Could not read artifact file")
+		return
+	}
+
+	// --- Provenance (promotions only) ---
+	// The registries the version was promoted from, sent by protoreg-cli promote
+	provenanceChain, ok := readProvenance(w, r, artifactDigestHex)
+	if !ok {
+		return // Response already written
+	}
+
+	// --- Import Graph Validation ---
+	// Artifacts declaring dependencies (sproto.yaml) may only import their own files, well-known types
+	// and files of declared dependencies
+	reportStage(r.Context(), StageValidating)
+	if !checkImportGraph(w, r, file, fmt.Sprintf("%s/%s@%s", namespace, moduleName, versionStr)) {
+		return // Response already written
+	}
+
+	// --- Namespace and Publish Policies (optional) ---
+	// Lint, breaking-change, file and version checks configured for the namespace by admins, then the
+	// server's publish policies; every issue found is reported in a single 422
+	if !checkValidation(w, r, file, nsPolicy, namespace, moduleName, versionStr, artifactSize) {
+		return // Response already written
+	}
+
+	// --- Package Ownership ---
+	// The proto packages the version declares, indexed with it; another module's packages are rejected
+	// unless PACKAGE_OWNERSHIP is shared
+	packages, ok := checkPackageOwnership(w, r, file, nsPolicy, namespace, moduleName)
+	if !ok {
+		return // Response already written
+	}
+
+	// --- Validate Only (dry run) ---
+	if r.URL.Query().Get("validate_only") == "true" {
+		validatePublish(w, r, namespace, moduleName, versionStr, artifactDigestHex, artifactSize, scanStatus)
+		return
+	}
+
+	// --- Changelog (optional) ---
+	// The section of CHANGELOG.md for this version, if the artifact has one
+	changelogSection := readChangelog(r.Context(), file, versionStr)
+
+	// --- Schema Change Detection (optional) ---
+	// The previous version, if this one only changes comments or formatting
+	noSchemaChangeFrom := detectNoSchemaChange(r.Context(), file, namespace, moduleName, versionStr)
+
+	// --- Syntax Detection ---
+	// proto2, proto3 or editions, as declared by the .proto files
+	syntaxes := readSyntaxes(r.Context(), file)
+
+	// --- File Digests ---
+	// Size and SHA256 of each file, recorded with the version
+	fileDigests := readFileDigests(r.Context(), file)
+
+	// --- OpenAPI Generation (HTTP-annotated services only) ---
+	// Attached as the "openapi" artifact once the version is committed
+	openAPIDoc := readOpenAPI(r.Context(), file, namespace, moduleName, versionStr)
+
+	// --- Search Entries ---
+	// Files, declarations and comments, indexed once the version is committed
+	searchEntries := readSearchEntries(r.Context(), file)
+
+	// --- Database and Storage Operations (Transaction) ---
+	reportStage(r.Context(), StageStoring)
+	storageProvider := storage.GetStorageProvider() // Get the initialized provider
+
+	var module *models.Module
+	var moduleVersion models.ModuleVersion
+	var storageKey string
+
+	// A failed step returns a publishError with the response, written once the transaction is rolled back
+	err = primaryRepositories().Tx.Transaction(r.Context(), func(tx repo.Repositories) error {
+		// 1. Find or Create Module
+		var err error
+		module, err = tx.Modules.GetOrCreate(r.Context(), namespace, moduleName, visibility)
+		if err != nil {
+			log.Error("Error finding or creating module", zap.String("namespace", namespace), zap.String("module", moduleName), zap.Error(err))
+			return &publishError{http.StatusInternalServerError, "Database error during module lookup/creation", err}
+		}
+
+		// 2. Check for existing version, or one differing only in build metadata or case (Conflict)
+		existing, err := tx.Versions.Conflicting(r.Context(), namespace, moduleName, versionStr)
+		if err != nil {
+			// Unexpected DB error during check
+			log.Error("Error checking for existing version", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", versionStr), zap.Error(err))
+			return &publishError{http.StatusInternalServerError, "Database error during version check", err}
+		}
+		if existing != "" {
+			err = versionConflictError(namespace, moduleName, versionStr, existing)
+			log.Info("Version already exists", zap.Error(err))
+			return &publishError{http.StatusConflict, err.Error(), err}
+		}
+
+		// 3. Upload to Storage Provider (digest-addressed key, see storage.ModuleVersionKey)
+		storageKey = storage.ModuleVersionKey(namespace, moduleName, versionStr, artifactDigestHex)
+		err = storageProvider.UploadFile(r.Context(), storageKey, file, artifactSize, "application/zip")
+		if errors.Is(err, storage.ErrObjectExists) {
+			// Objects are write-once. The key is digest-addressed, so an existing object is normally left over
+			// from an earlier attempt whose database write failed; reuse it if its content really matches.
+			err = reuseExistingArtifact(r, storageProvider, storageKey, artifactDigestHex)
+		}
+		if err != nil {
+			log.Error("Error uploading artifact to storage", zap.String("key", storageKey), zap.Error(err))
+			switch status := storageErrorStatus(err); status {
+			case http.StatusInsufficientStorage:
+				return &publishError{status, "Artifact storage quota exceeded", err}
+			case http.StatusServiceUnavailable:
+				return &publishError{status, "Artifact storage unavailable", err}
+			case http.StatusConflict:
+				return &publishError{status, "Artifact storage key already holds a different object", err}
+			default:
+				return &publishError{http.StatusInternalServerError, "Failed to upload artifact to storage", err}
+			}
+		}
+		log.Info("Successfully uploaded artifact", zap.String("filename", header.Filename), zap.String("key", storageKey), zap.Int64("size", artifactSize))
+
+		// 4. Create ModuleVersion record
+		moduleVersion = models.ModuleVersion{
+			ModuleID:           module.ID,
+			Version:            versionStr,
+			VersionKey:         validation.VersionKey(versionStr),
+			ArtifactDigest:     artifactDigestHex,
+			ArtifactStorageKey: storageKey,
+			ArtifactKeyLayout:  storage.CurrentKeyLayout,
+			ArtifactSize:       artifactSize,
+			ScanStatus:         scanStatus,
+			ScanResult:         scanResult,
+			Changelog:          changelogSection,
+			Syntaxes:           syntaxes,
+			NoSchemaChangeFrom: noSchemaChangeFrom,
+			Provenance:         provenanceChain,
+			// Set explicitly rather than relying on the column default: SQLite's current_timestamp only
+			// has second precision, which makes "latest version" ambiguous for quick successive publishes.
+			CreatedAt: time.Now().UTC(),
+		}
+		signVersion(&moduleVersion, namespace, moduleName)
+		if err := tx.Versions.Create(r.Context(), &moduleVersion); err != nil {
+			log.Error("Error creating module version record", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", versionStr), zap.Error(err))
+			return &publishError{http.StatusInternalServerError, "Database error saving module version", err}
+		}
+
+		// 5. Map the declared proto packages to the module
+		if err := tx.Modules.IndexPackages(r.Context(), module.ID, versionStr, packages); err != nil {
+			log.Error("Error indexing proto packages", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", versionStr), zap.Error(err))
+			return &publishError{http.StatusInternalServerError, "Database error indexing proto packages", err}
+		}
+
+		// 6. Record the digests of the artifact's files
+		if err := tx.Versions.AddFiles(r.Context(), versionFiles(moduleVersion.ID, fileDigests)); err != nil {
+			log.Error("Error recording file digests", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", versionStr), zap.Error(err))
+			return &publishError{http.StatusInternalServerError, "Database error recording file digests", err}
+		}
+
+		// 7. Explicitly update the parent module's updated_at timestamp
+		if err := tx.Modules.Touch(r.Context(), module.ID); err != nil {
+			// Log the error but don't fail the whole operation just for the timestamp update
+			log.Warn("Failed to update module updated_at timestamp", zap.String("namespace", namespace), zap.String("module", moduleName), zap.Error(err))
+		}
+		return nil // 8. Commit Transaction
+	})
+	if err != nil {
+		writePublishError(w, log, err)
+		return
+	}
+
+	// Log the digest for clients to verify fetches against (best-effort, see recordChecksum)
+	recordChecksum(r.Context(), namespace, moduleName, versionStr, artifactDigestHex)
+
+	attachOpenAPI(r, &moduleVersion, namespace, moduleName, openAPIDoc)
+
+	// The newest version is what the module is searched by (best-effort, see indexForSearch)
+	indexForSearch(r.Context(), namespace, moduleName, &moduleVersion, searchEntries)
+
+	// Keep the uploaded bytes for audits if they were re-packed (best-effort, see retainOriginalUpload)
+	originalDigestHex := retainOriginalUpload(r, &moduleVersion, original)
+
+	// Soft quota is advisory: warn (log + webhook) if this publish crossed a threshold, never reject
+	checkModuleQuota(r.Context(), namespace, moduleName, module.ID, artifactSize)
+
+	// --- Success Response ---
+	respData := apitypes.PublishModuleVersionResponse{
+		Namespace:      namespace,
+		ModuleName:     moduleName,
+		Version:        versionStr,
+		ArtifactDigest: "sha256:" + artifactDigestHex, // Add prefix for clarity
+		ScanStatus:     scanStatus,
+		CreatedAt:      moduleVersion.CreatedAt, // Use the timestamp from the created record
+
+		NoSchemaChange:     noSchemaChangeFrom != "",
+		NoSchemaChangeFrom: noSchemaChangeFrom,
+		Syntaxes:           splitSyntaxes(syntaxes),
+
+		Signature:  versionSignature(&moduleVersion, namespace, moduleName),
+		Provenance: versionProvenance(&moduleVersion),
+	}
+	if originalDigestHex != "" {
+		respData.OriginalDigest = "sha256:" + originalDigestHex
+	}
+	response.JSON(w, http.StatusCreated, respData)
+}
+
+// publishError is a failed step of a publish transaction, with the response to write once the transaction
+// is rolled back.
+type publishError struct {
+	status  int
+	message string
+	err     error
+}
+
+func (e *publishError) Error() string { return e.message + ": " + e.err.Error() }
+func (e *publishError) Unwrap() error { return e.err }
+
+// writePublishError writes the response of a failed publish transaction: that of the failed step, or a
+// database error if the commit failed.
+func writePublishError(w http.ResponseWriter, log *zap.Logger, err error) {
+	var stepErr *publishError
+	if errors.As(err, &stepErr) {
+		response.Error(w, stepErr.status, stepErr.message)
+		return
+	}
+	log.Error("Error committing transaction", zap.Error(err))
+	response.Error(w, http.StatusInternalServerError, "Database error during commit")
+}
+
+// versionConflictError describes the conflict of publishing versionStr with an existing version.
+func versionConflictError(namespace, moduleName, versionStr, existing string) error {
+	if existing == versionStr {
+		return fmt.Errorf("version '%s' already exists for module '%s/%s'", versionStr, namespace, moduleName)
+	}
+	return fmt.Errorf("version '%s' conflicts with existing version '%s' of module '%s/%s': versions differing only in build metadata or letter case are the same version",
+		versionStr, existing, namespace, moduleName)
+}
+
+// validatePublish runs the server-side publish checks for a validate-only request
+// (POST .../{version}?validate_only=true) without creating the module, uploading or persisting anything.
+// Checks that fail respond with the same status codes as a real publish (e.g. 409 on conflict).
+func validatePublish(w http.ResponseWriter, r *http.Request, namespace, moduleName, versionStr, digestHex string, size int64, scanStatus string) {
+	log := logging.FromContext(r.Context())
+
+	// Conflict check (read-only: the module is not created if it doesn't exist)
+	existing, err := primaryRepositories().Versions.Conflicting(r.Context(), namespace, moduleName, versionStr)
+	if err != nil {
+		log.Error("Error checking for existing version", zap.String("namespace", namespace), zap.String("module", moduleName), zap.String("version", versionStr), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error during version check")
+		return
+	}
+	if existing != "" {
+		response.Error(w, http.StatusConflict, versionConflictError(namespace, moduleName, versionStr, existing).Error())
+		return
+	}
+
+	response.JSON(w, http.StatusOK, apitypes.ValidatePublishResponse{
+		Valid:          true,
+		Namespace:      namespace,
+		ModuleName:     moduleName,
+		Version:        versionStr,
+		ArtifactDigest: "sha256:" + digestHex,
+		ArtifactSize:   size,
+		ScanStatus:     scanStatus,
+	})
+}
+
+// readArtifactForm reads the "artifact" file of a multipart upload, limited to MAX_UPLOAD_SIZE_BYTES
+// (32 MB by default), and checks it against the ArtifactDigestHeader if the client sent one. On failure it
+// writes the error response (400, 413 or 422) and returns false.
+func readArtifactForm(w http.ResponseWriter, r *http.Request) (multipart.File, *multipart.FileHeader, bool) {
+	log := logging.FromContext(r.Context())
+	r.Body = http.MaxBytesReader(w, r.Body, requestLimits.MaxUploadBytes)
+	err := r.ParseMultipartForm(requestLimits.MaxUploadBytes)
+	if err != nil {
+		log.Warn("Error parsing multipart form", zap.Error(err))
 		if errors.Is(err, http.ErrMissingBoundary) || strings.Contains(err.Error(), "no multipart boundary param") {
 			response.Error(w, http.StatusBadRequest, "Invalid request: Missing or malformed multipart boundary")
 		} else if strings.Contains(err.Error(), "request body too large") {
-			response.Error(w, http.StatusRequestEntityTooLarge, "Artifact file size exceeds limit (32MB)")
+			response.Error(w, http.StatusRequestEntityTooLarge, uploadLimitMessage())
 		} else {
 			response.Error(w, http.StatusBadRequest, "Could not parse multipart form")
 		}
-		return
+		return nil, nil, false
 	}
 
 	file, header, err := r.FormFile("artifact")
 	if err != nil {
-		log.Printf("Error retrieving artifact file from form: %v", err)
+		log.Warn("Error retrieving artifact file from form", zap.Error(err))
 		if errors.Is(err, http.ErrMissingFile) {
 			response.Error(w, http.StatusBadRequest, "Missing 'artifact' file in form data")
 		} else {
 			response.Error(w, http.StatusBadRequest, "Could not retrieve artifact file")
 		}
-		return
+		return nil, nil, false
 	}
-	defer file.Close()
 
-	log.Printf("Received artifact file: %s, Size: %d", header.Filename, header.Size)
+	log.Info("Received artifact file", zap.String("filename", header.Filename), zap.Int64("size", header.Size))
+	if !checkUploadDigest(w, r, file) {
+		file.Close()
+		return nil, nil, false
+	}
+	return file, header, true
+}
 
-	// Calculate SHA256 digest while reading the file for upload
-	hasher := sha256.New()
-	// Use io.TeeReader to write to hasher while reading for upload
-	teeReader := io.TeeReader(file, hasher)
+// canonicalFile is an in-memory artifact that can be used in place of the uploaded multipart file.
+type canonicalFile struct {
+	*bytes.Reader
+}
 
-	// --- Database and Storage Operations (Transaction) ---
-	gormDB := db.GetDB()
-	storageProvider := storage.GetStorageProvider() // Get the initialized provider
-	// cfg, _ := config.LoadConfig() // Config likely not needed directly here anymore
-	// bucketName := cfg.MinioBucket // Bucket name is handled within the provider
+func (canonicalFile) Close() error { return nil }
 
-	var module models.Module
-	var moduleVersion models.ModuleVersion
-	var artifactDigestHex string
-	var storageKey string
+// canonicalizeArtifact re-packs the uploaded zip into its canonical form (see artifact.Canonicalize).
+// Uploads that aren't zip archives at all are passed through unchanged. Also returns the uploaded bytes
+// if the canonical archive differs from them (nil otherwise).
+// Returns false if a response has already been written (publish rejected).
+func canonicalizeArtifact(w http.ResponseWriter, r *http.Request, file multipart.File) (multipart.File, []byte, bool) {
+	log := logging.FromContext(r.Context())
 
-	// Start transaction
-	tx := gormDB.Begin()
-	if tx.Error != nil {
-		log.Printf("Error starting database transaction: %v", tx.Error)
-		response.Error(w, http.StatusInternalServerError, "Database error")
-		return
+	uploaded, err := io.ReadAll(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
 	}
-	// Defer rollback in case of errors
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback() // Rollback on panic
-			panic(r)      // Re-panic
-		} else if err != nil {
-			log.Printf("Rolling back transaction due to error: %v", err)
-			tx.Rollback() // Rollback on explicit error
-		}
-	}()
-
-	// 1. Find or Create Module
-	err = tx.Where(models.Module{Namespace: namespace, Name: moduleName}).
-		Attrs(models.Module{Namespace: namespace, Name: moduleName}). // Set attributes if creating
-		FirstOrCreate(&module).Error
 	if err != nil {
-		log.Printf("Error finding or creating module %s/%s: %v", namespace, moduleName, err)
-		response.Error(w, http.StatusInternalServerError, "Database error during module lookup/creation")
-		return // Triggers deferred rollback
+		log.Error("Error reading artifact file", zap.Error(err))
+		response.Error(w, http.StatusBadRequest, "Could not read artifact file")
+		return nil, nil, false
 	}
 
-	// 2. Check for existing version (Conflict)
-	err = tx.Where("module_id = ? AND version = ?", module.ID, versionStr).First(&models.ModuleVersion{}).Error
-	if err == nil {
-		// Found existing version - Conflict
-		err = fmt.Errorf("version '%s' already exists for module '%s/%s'", versionStr, namespace, moduleName)
-		log.Println(err.Error())
-		response.Error(w, http.StatusConflict, err.Error())
-		return // Triggers deferred rollback
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		// Unexpected DB error during check
-		log.Printf("Error checking for existing version %s/%s@%s: %v", namespace, moduleName, versionStr, err)
-		response.Error(w, http.StatusInternalServerError, "Database error during version check")
-		return // Triggers deferred rollback
+	canonical, err := artifact.Canonicalize(uploaded)
+	switch {
+	case errors.Is(err, artifact.ErrNotZip):
+		log.Warn("Artifact is not a zip archive, storing it as uploaded", zap.Error(err))
+		return file, nil, true
+	case errors.Is(err, artifact.ErrInvalidArchive):
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	case err != nil:
+		log.Error("Error re-packing artifact", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to process artifact")
+		return nil, nil, false
 	}
-	// Reset err as ErrRecordNotFound is expected if version doesn't exist
-	err = nil
+	log.Debug("Re-packed artifact into canonical form", zap.Int("uploaded_size", len(uploaded)), zap.Int("canonical_size", len(canonical)))
+	if bytes.Equal(canonical, uploaded) {
+		return canonicalFile{bytes.NewReader(canonical)}, nil, true
+	}
+	return canonicalFile{bytes.NewReader(canonical)}, uploaded, true
+}
 
-	// 3. Upload to Storage Provider (using the TeeReader)
-	storageKey = fmt.Sprintf("modules/%s/%s/protos.zip", module.ID.String(), versionStr) // Define storage key structure
-	err = storageProvider.UploadFile(r.Context(), storageKey, teeReader, header.Size, "application/zip")
+// digestArtifact computes the SHA256 hex digest and size of the uploaded artifact,
+// then rewinds the file so it can be read again for upload.
+func digestArtifact(file multipart.File) (string, int64, error) {
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
 	if err != nil {
-		log.Printf("Error uploading artifact to storage (Key: %s): %v", storageKey, err)
-		response.Error(w, http.StatusInternalServerError, "Failed to upload artifact to storage")
-		return // Triggers deferred rollback
+		return "", 0, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", 0, fmt.Errorf("failed to rewind artifact: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), size, nil
+}
+
+// scanArtifact runs the configured virus scanner over the uploaded artifact and applies the scan policy.
+// The file is rewound afterwards so it can be read again for upload.
+// Returns the scan status and result to record on the version, and false if a response has
+// already been written (publish rejected).
+func scanArtifact(w http.ResponseWriter, r *http.Request, file multipart.File, coordinates string) (string, string, bool) {
+	log := logging.FromContext(r.Context()).With(zap.String("coordinates", coordinates))
+	scanner := scan.GetScanner()
+	if scanner == nil {
+		return scan.StatusSkipped, "", true
 	}
-	log.Printf("Successfully uploaded %s (Key: %s, Size: %d)", header.Filename, storageKey, header.Size)
 
-	// 4. Get the final digest
-	artifactDigestHex = hex.EncodeToString(hasher.Sum(nil))
+	result, err := scanner.Scan(r.Context(), file)
 
-	// 5. Create ModuleVersion record
-	moduleVersion = models.ModuleVersion{
-		ModuleID:           module.ID,
-		Version:            versionStr,
-		ArtifactDigest:     artifactDigestHex,
-		ArtifactStorageKey: storageKey,
-		// CreatedAt is set by default
+	// Rewind the file regardless of the outcome; it will be read again for upload
+	if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+		log.Error("Error rewinding artifact after scan", zap.Error(seekErr))
+		response.Error(w, http.StatusInternalServerError, "Failed to process artifact")
+		return "", "", false
 	}
-	err = tx.Create(&moduleVersion).Error
+
+	policy := scan.GetPolicy()
 	if err != nil {
-		log.Printf("Error creating module version record %s/%s@%s: %v", namespace, moduleName, versionStr, err)
-		// Attempt to clean up MinIO object if DB insert fails? Maybe too complex.
-		response.Error(w, http.StatusInternalServerError, "Database error saving module version")
-		return // Triggers deferred rollback
+		log.Error("Virus scan failed", zap.Error(err))
+		if policy == scan.PolicyBlock {
+			response.Error(w, http.StatusServiceUnavailable, "Artifact virus scan unavailable")
+			return "", "", false
+		}
+		return scan.StatusError, err.Error(), true
 	}
 
-	// 6. Explicitly update the parent module's updated_at timestamp
-	err = tx.Model(&module).Update("updated_at", time.Now()).Error
-	if err != nil {
-		// Log the error but don't fail the whole operation just for the timestamp update
-		log.Printf("Warning: Failed to update module %s/%s updated_at timestamp: %v", namespace, moduleName, err)
-		err = nil // Reset error so commit doesn't rollback
+	if !result.Clean {
+		log.Warn("Virus scan detected a threat in artifact", zap.String("signature", result.Signature), zap.String("policy", policy))
+		if policy == scan.PolicyBlock {
+			response.Error(w, http.StatusUnprocessableEntity, fmt.Sprintf("Artifact rejected by virus scan: %s", result.Signature))
+			return "", "", false
+		}
+		return scan.StatusInfected, result.Signature, true
 	}
 
-	// 7. Commit Transaction
-	err = tx.Commit().Error
+	return scan.StatusClean, "", true
+}
+
+// reuseExistingArtifact checks that the object already stored under key has the expected digest.
+func reuseExistingArtifact(r *http.Request, storageProvider storage.StorageProvider, key, digestHex string) error {
+	actual, err := storage.ObjectDigest(r.Context(), storageProvider, key)
 	if err != nil {
-		log.Printf("Error committing transaction for %s/%s@%s: %v", namespace, moduleName, versionStr, err)
-		response.Error(w, http.StatusInternalServerError, "Database error during commit")
-		return // Already rolled back by commit error
+		return fmt.Errorf("failed to verify existing artifact object: %w", err)
 	}
-
-	// --- Success Response ---
-	respData := PublishModuleVersionResponse{
-		Namespace:      namespace,
-		ModuleName:     moduleName,
-		Version:        versionStr,
-		ArtifactDigest: "sha256:" + artifactDigestHex, // Add prefix for clarity
-		CreatedAt:      moduleVersion.CreatedAt,       // Use the timestamp from the created record
+	if actual != digestHex {
+		return fmt.Errorf("existing artifact object %s has digest %s, expected %s: %w", key, actual, digestHex, storage.ErrObjectExists)
 	}
-	response.JSON(w, http.StatusCreated, respData)
+	logging.FromContext(r.Context()).Info("Reusing existing artifact object with matching digest", zap.String("key", key))
+	return nil
 }
 
 // Helper function for semantic version sorting
@@ -415,7 +1207,7 @@ func sortVersionsDesc(versions []string) {
 		if err == nil {
 			semvers = append(semvers, v)
 		} else {
-			log.Printf("Warning: Could not parse version '%s' for sorting: %v", vStr, err)
+			logging.L().Warn("Could not parse version for sorting", zap.String("version", vStr), zap.Error(err))
 			// Decide how to handle unparseable versions - maybe keep original string?
 		}
 	}
@@ -1,71 +1,34 @@
 package api
 
 import (
-	// For multipart body
-	"errors" // Ensure fmt is imported
-	// For creating multipart request
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
-	"net/http/httptest" // Re-add httptest
-
-	// Add url import
-	"regexp" // For sqlmock query matching
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
-	"github.com/Suhaibinator/SProto/internal/db" // Import db package
-	// Keep storage import
-	"github.com/google/uuid" // For generating UUIDs in tests
-	"github.com/gorilla/mux" // For setting URL vars
-	// Keep minio import
-	"github.com/stretchr/testify/assert" // Use testify/assert
-
-	// Removed unused imports: net/url
-	"gorm.io/driver/postgres"
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
 )
 
-// --- Test Setup (Mocks, etc.) ---
-
-// --- Test Setup (Mocks, etc.) ---
-
-// setupMockDB initializes sqlmock and returns a mock GORM DB instance and the sqlmock controller.
-func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
-	mockDb, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
-	}
-	// Ensure mockDb is closed at the end of the test
-	t.Cleanup(func() { mockDb.Close() })
-
-	dialector := postgres.New(postgres.Config{
-		Conn:       mockDb,
-		DriverName: "postgres",
-	})
-	gormDB, err := gorm.Open(dialector, &gorm.Config{})
-	if err != nil {
-		t.Fatalf("Failed to open gorm v2 db: %v", err)
-	}
-
-	// Replace the global DB instance with the mock
-	// Prevent panic in db.GetDB() if called before mock is set
-	db.SetDB(&gorm.DB{}) // Set a dummy non-nil DB temporarily
-	// Handle case where original DB might be nil in test environment
-	// var originalDB *gorm.DB // Removed unused variable
-	// Check if db.DB is exported and accessible, or add a helper like db.IsInitialized()
-	// Assuming we can access it or know it's nil initially in tests.
-	// If db.DB is not exported, we might need a different approach or assume it's nil.
-	// Let's proceed assuming we can check or it's nil.
-	// A safer check might involve a TryGetDB that doesn't panic.
-	// For now, let's just set the mock and restore nil in cleanup if needed.
-
-	db.SetDB(gormDB) // Set the mock DB
-
-	// Restore nil after test, as it wasn't initialized
-	t.Cleanup(func() { db.SetDB(nil) })
-
-	return gormDB, mock
-}
-
 // --- Tests for ListModulesHandler ---
 
 func TestListModulesHandler_Success(t *testing.T) {
@@ -79,23 +42,28 @@ func TestListModulesHandler_Success(t *testing.T) {
 			SELECT
 				module_id,
 				version,
+				syntaxes,
 				ROW_NUMBER() OVER(PARTITION BY module_id ORDER BY created_at DESC) as rn
 			FROM module_versions
 		)
 		SELECT
 			m.namespace,
 			m.name,
-			COALESCE(lv.version, '') AS latest_version
+			COALESCE(lv.version, '') AS latest_version,
+			m.visibility,
+			COALESCE(lv.syntaxes, '') AS latest_syntaxes,
+			m.updated_at
 		FROM modules m
 		LEFT JOIN LatestVersions lv ON m.id = lv.module_id AND lv.rn = 1
 		ORDER BY m.namespace, m.name;
 	`)
 
 	// Define expected rows returned by the mock
-	rows := sqlmock.NewRows([]string{"namespace", "name", "latest_version"}).
-		AddRow("my-org", "module-a", "v1.1.0").
-		AddRow("my-org", "module-b", "v0.1.0").
-		AddRow("other-org", "cool-mod", "") // Module with no versions
+	updatedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"namespace", "name", "latest_version", "visibility", "updated_at"}).
+		AddRow("my-org", "module-a", "v1.1.0", "public", updatedAt).
+		AddRow("my-org", "module-b", "v0.1.0", "public", updatedAt).
+		AddRow("other-org", "cool-mod", "", "public", updatedAt) // Module with no versions
 
 	// Expect the query to be executed
 	mock.ExpectQuery(expectedSQL).WillReturnRows(rows)
@@ -113,7 +81,7 @@ func TestListModulesHandler_Success(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 
 	// Assert response body
-	expectedBody := `{"modules":[{"namespace":"my-org","name":"module-a","latest_version":"v1.1.0"},{"namespace":"my-org","name":"module-b","latest_version":"v0.1.0"},{"namespace":"other-org","name":"cool-mod","latest_version":""}]}`
+	expectedBody := `{"modules":[{"namespace":"my-org","name":"module-a","latest_version":"v1.1.0","visibility":"public","updated_at":"2026-01-02T03:04:05Z"},{"namespace":"my-org","name":"module-b","latest_version":"v0.1.0","visibility":"public","updated_at":"2026-01-02T03:04:05Z"},{"namespace":"other-org","name":"cool-mod","latest_version":"","visibility":"public","updated_at":"2026-01-02T03:04:05Z"}]}`
 	assert.JSONEq(t, expectedBody, rr.Body.String())
 
 	// Ensure all expectations were met
@@ -131,13 +99,17 @@ func TestListModulesHandler_DBError(t *testing.T) {
 			SELECT
 				module_id,
 				version,
+				syntaxes,
 				ROW_NUMBER() OVER(PARTITION BY module_id ORDER BY created_at DESC) as rn
 			FROM module_versions
 		)
 		SELECT
 			m.namespace,
 			m.name,
-			COALESCE(lv.version, '') AS latest_version
+			COALESCE(lv.version, '') AS latest_version,
+			m.visibility,
+			COALESCE(lv.syntaxes, '') AS latest_syntaxes,
+			m.updated_at
 		FROM modules m
 		LEFT JOIN LatestVersions lv ON m.id = lv.module_id AND lv.rn = 1
 		ORDER BY m.namespace, m.name;
@@ -169,6 +141,64 @@ func TestListModulesHandler_DBError(t *testing.T) {
 	}
 }
 
+func TestListModulesHandler_FiltersAndSort(t *testing.T) {
+	gormDB := newTestDB(t)
+
+	now := time.Now().UTC()
+	create := func(namespace, name string, updated time.Duration, downloads ...int64) {
+		module := models.Module{Namespace: namespace, Name: name, Visibility: models.VisibilityPublic, UpdatedAt: now.Add(-updated)}
+		assert.NoError(t, gormDB.Create(&module).Error)
+		for i, count := range downloads {
+			version := fmt.Sprintf("v1.%d.0", i)
+			mv := models.ModuleVersion{ModuleID: module.ID, Version: version, VersionKey: validation.VersionKey(version)}
+			assert.NoError(t, gormDB.Create(&mv).Error)
+			if count > 0 {
+				assert.NoError(t, gormDB.Create(&models.ModuleConsumption{ModuleVersionID: mv.ID, Consumer: "anonymous", FetchCount: count, FirstFetchedAt: now, LastFetchedAt: now}).Error)
+			}
+		}
+	}
+	create("acme", "billing", 48*time.Hour, 5, 10)
+	create("acme", "user", time.Hour, 20)
+	create("acme", "empty", 2*time.Hour)
+	create("other", "common", 30*time.Minute, 0)
+
+	list := func(query string) ([]string, ListModulesResponse) {
+		rr := httptest.NewRecorder()
+		ListModulesHandler(rr, httptest.NewRequest("GET", "/api/v1/modules"+query, nil))
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp ListModulesResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		names := []string{}
+		for _, m := range resp.Modules {
+			names = append(names, m.Namespace+"/"+m.Name)
+		}
+		return names, resp
+	}
+
+	names, resp := list("")
+	assert.Equal(t, []string{"acme/billing", "acme/empty", "acme/user", "other/common"}, names)
+	assert.Nil(t, resp.Modules[0].Downloads, "downloads are only added when sorting by them")
+	names, _ = list("?namespace=acme&has_versions=true")
+	assert.Equal(t, []string{"acme/billing", "acme/user"}, names)
+	names, _ = list("?has_versions=false")
+	assert.Equal(t, []string{"acme/empty"}, names)
+	names, _ = list("?updated_after=" + url.QueryEscape(now.Add(-3*time.Hour).Format(time.RFC3339)) + "&sort=updated_at")
+	assert.Equal(t, []string{"other/common", "acme/user", "acme/empty"}, names)
+	names, resp = list("?sort=downloads")
+	assert.Equal(t, []string{"acme/user", "acme/billing", "acme/empty", "other/common"}, names)
+	if assert.NotNil(t, resp.Modules[0].Downloads) && assert.NotNil(t, resp.Modules[1].Downloads) && assert.NotNil(t, resp.Modules[3].Downloads) {
+		assert.Equal(t, int64(20), *resp.Modules[0].Downloads)
+		assert.Equal(t, int64(15), *resp.Modules[1].Downloads)
+		assert.Equal(t, int64(0), *resp.Modules[3].Downloads)
+	}
+
+	for _, query := range []string{"?sort=popularity", "?has_versions=maybe", "?updated_after=yesterday"} {
+		rr := httptest.NewRecorder()
+		ListModulesHandler(rr, httptest.NewRequest("GET", "/api/v1/modules"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
 // --- Tests for ListModuleVersionsHandler ---
 
 func TestListModuleVersionsHandler_Success(t *testing.T) {
@@ -186,11 +216,11 @@ func TestListModuleVersionsHandler_Success(t *testing.T) {
 		WillReturnRows(moduleRows)
 
 	// Mock finding the versions
-	versionRows := sqlmock.NewRows([]string{"version"}).
-		AddRow("v1.0.0").
-		AddRow("v1.1.0").
-		AddRow("v0.9.0") // Unsorted initially
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "version" FROM "module_versions" WHERE module_id = $1 ORDER BY created_at DESC`)).
+	versionRows := sqlmock.NewRows([]string{"version", "changelog"}).
+		AddRow("v1.0.0", "").
+		AddRow("v1.1.0", "- Contains hotfix for billing rounding").
+		AddRow("v0.9.0", nil) // Unsorted initially
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, changelog, syntaxes, canary_percent FROM "module_versions" WHERE module_id = $1 ORDER BY created_at DESC`)).
 		WithArgs(moduleID).
 		WillReturnRows(versionRows)
 
@@ -212,7 +242,8 @@ func TestListModuleVersionsHandler_Success(t *testing.T) {
 	// --- Assertions ---
 	assert.Equal(t, http.StatusOK, rr.Code)
 	// Note: The handler sorts versions semantically descending
-	expectedBody := `{"namespace":"my-org","module_name":"my-module","versions":["v1.1.0","v1.0.0","v0.9.0"]}`
+	// Only versions with a changelog are listed in changelogs
+	expectedBody := `{"namespace":"my-org","module_name":"my-module","versions":["v1.1.0","v1.0.0","v0.9.0"],"changelogs":{"v1.1.0":"- Contains hotfix for billing rounding"}}`
 	assert.JSONEq(t, expectedBody, rr.Body.String())
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -293,7 +324,7 @@ func TestListModuleVersionsHandler_DBErrorFindingVersions(t *testing.T) {
 		WillReturnRows(moduleRows)
 
 	// Mock finding the versions returning an error
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "version" FROM "module_versions" WHERE module_id = $1 ORDER BY created_at DESC`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, changelog, syntaxes, canary_percent FROM "module_versions" WHERE module_id = $1 ORDER BY created_at DESC`)).
 		WithArgs(moduleID).
 		WillReturnError(dbErr)
 
@@ -317,4 +348,319 @@ func TestListModuleVersionsHandler_DBErrorFindingVersions(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-// --- Tests for FetchModuleVersionArtifactHandler ---
+// --- Tests for PublishModuleVersionHandler ---
+
+func TestPublishModuleVersionHandler_ValidateOnlySuccess(t *testing.T) {
+	_, mock := setupMockDB(t)
+	artifact := []byte("fake zip content")
+
+	expectNoNamespacePolicy(mock, "my-org")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "module_versions"."version" FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE (modules.namespace = $1 AND modules.name = $2) AND (module_versions.version = $3 OR module_versions.version_key = $4) LIMIT $5`)).
+		WithArgs("my-org", "my-module", "v1.0.0", "v1.0.0", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler)
+	router.ServeHTTP(rr, newPublishRequest(t, "my-org", "my-module", "v1.0.0", "?validate_only=true", artifact))
+
+	// --- Assertions ---
+	assert.Equal(t, http.StatusOK, rr.Code)
+	sum := sha256.Sum256(artifact)
+	var resp apitypes.ValidatePublishResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.True(t, resp.Valid)
+	assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), resp.ArtifactDigest)
+	assert.Equal(t, int64(len(artifact)), resp.ArtifactSize)
+	assert.Equal(t, "skipped", resp.ScanStatus)
+	// No inserts or storage uploads are expected
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublishModuleVersionHandler_ArtifactDigest(t *testing.T) {
+	artifact := []byte("fake zip content")
+	sum := sha256.Sum256(artifact)
+	publish := func(digest string) *httptest.ResponseRecorder {
+		req := newPublishRequest(t, "my-org", "my-module", "v1.0.0", "?validate_only=true", artifact)
+		req.Header.Set(apitypes.ArtifactDigestHeader, digest)
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler)
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Matching digest (the prefix is optional, hex in any case)
+	_, mock := setupMockDB(t)
+	expectNoNamespacePolicy(mock, "my-org")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "module_versions"."version" FROM "module_versions"`)).
+		WithArgs("my-org", "my-module", "v1.0.0", "v1.0.0", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	assert.Equal(t, http.StatusOK, publish(strings.ToUpper(hex.EncodeToString(sum[:]))).Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Corrupted in transit: rejected before anything else
+	_, mock = setupMockDB(t)
+	other := sha256.Sum256([]byte("original content"))
+	rr := publish("sha256:" + hex.EncodeToString(other[:]))
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Artifact digest mismatch")
+	assert.Contains(t, rr.Body.String(), hex.EncodeToString(sum[:]))
+	assert.Equal(t, http.StatusBadRequest, publish("md5:abc").Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublishModuleVersionHandler_ValidateOnlyConflict(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	expectNoNamespacePolicy(mock, "my-org")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "module_versions"."version" FROM "module_versions"`)).
+		WithArgs("my-org", "my-module", "v1.0.0", "v1.0.0", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("v1.0.0"))
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler)
+	router.ServeHTTP(rr, newPublishRequest(t, "my-org", "my-module", "v1.0.0", "?validate_only=true", []byte("fake zip content")))
+
+	// --- Assertions ---
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.JSONEq(t, `{"error":"version 'v1.0.0' already exists for module 'my-org/my-module'"}`, rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublishModuleVersionHandler_ValidateOnlyCanonicalDigest(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	// The same file zipped with and without compression (and a directory entry)
+	zipWith := func(method uint16, withDir bool) []byte {
+		buf := new(bytes.Buffer)
+		zw := zip.NewWriter(buf)
+		if withDir {
+			_, err := zw.Create("user/")
+			assert.NoError(t, err)
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: "user/user.proto", Method: method, Modified: time.Now()})
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(`syntax = "proto3";`))
+		assert.NoError(t, err)
+		assert.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler)
+
+	var digests []string
+	for _, uploaded := range [][]byte{zipWith(zip.Deflate, false), zipWith(zip.Store, true)} {
+		expectNoNamespacePolicy(mock, "my-org")
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "module_versions"."version" FROM "module_versions"`)).
+			WithArgs("my-org", "my-module", "v1.0.0", "v1.0.0", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newPublishRequest(t, "my-org", "my-module", "v1.0.0", "?validate_only=true", uploaded))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var resp apitypes.ValidatePublishResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		digests = append(digests, resp.ArtifactDigest)
+
+		// The digest is that of the canonical archive, not of the uploaded bytes
+		canonical, err := artifact.Canonicalize(uploaded)
+		assert.NoError(t, err)
+		sum := sha256.Sum256(canonical)
+		assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), resp.ArtifactDigest)
+		assert.Equal(t, int64(len(canonical)), resp.ArtifactSize)
+	}
+	assert.Equal(t, digests[0], digests[1])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublishModuleVersionHandler_RejectsCorruptZip(t *testing.T) {
+	setupMockDB(t)
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "a.proto", Method: zip.Store})
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(`syntax = "proto3";`))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	corrupt := bytes.Replace(buf.Bytes(), []byte("proto3"), []byte("protoX"), 1) // CRC mismatch
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler)
+	router.ServeHTTP(rr, newPublishRequest(t, "my-org", "my-module", "v1.0.0", "?validate_only=true", corrupt))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid artifact archive")
+}
+
+func TestPublishModuleVersionHandler_InvalidModuleName(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler)
+	router.ServeHTTP(rr, newPublishRequest(t, "my-org", "My_Module", "v1.0.0", "", []byte("fake zip content")))
+
+	// --- Assertions ---
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid module name")
+	// Rejected before touching the database
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublishModuleVersionHandler_VersionAliases(t *testing.T) {
+	gormDB := newTestDB(t)
+	newTestStorage(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	data, err := artifact.Pack(map[string][]byte{"user.proto": []byte(`syntax = "proto3"; package user.v1;`)})
+	assert.NoError(t, err)
+	publish := func(version, query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newPublishRequest(t, "acme", "user", version, query, data))
+		return rr
+	}
+
+	assert.Equal(t, http.StatusCreated, publish("1.0.0+build1", "").Code)
+	assert.Equal(t, http.StatusCreated, publish("v1.0.0-RC.1", "").Code)
+	var mv models.ModuleVersion
+	assert.NoError(t, gormDB.Where("version = ?", "v1.0.0+build1").First(&mv).Error)
+	assert.Equal(t, "v1.0.0", mv.VersionKey)
+
+	// Same version, differing only in prefix, build metadata or case
+	for _, version := range []string{"v1.0.0", "1.0.0", "v1.0.0+build2"} {
+		rr := publish(version, "")
+		assert.Equal(t, http.StatusConflict, rr.Code, version)
+		assert.Contains(t, rr.Body.String(), "conflicts with existing version 'v1.0.0+build1'", version)
+	}
+	rr := publish("v1.0.0+build1", "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.JSONEq(t, `{"error":"version 'v1.0.0+build1' already exists for module 'acme/user'"}`, rr.Body.String())
+	assert.Equal(t, http.StatusConflict, publish("v1.0.0-rc.1", "").Code)
+	assert.Equal(t, http.StatusConflict, publish("v1.0.0-rc.1", "?validate_only=true").Code)
+	assert.Equal(t, http.StatusConflict, publish("v1.0.0", "?from=v1.0.0-RC.1").Code)
+
+	// Different versions
+	assert.Equal(t, http.StatusCreated, publish("v1.0.1+build1", "").Code)
+	assert.Equal(t, http.StatusCreated, publish("v1.0.0-rc.2", "").Code)
+	var count int64
+	assert.NoError(t, gormDB.Model(&models.ModuleVersion{}).Count(&count).Error)
+	assert.Equal(t, int64(4), count)
+}
+
+// --- Tests for GetModuleVersionHandler ---
+
+func TestGetModuleVersionHandler_HeadExists(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	rows := sqlmock.NewRows([]string{"id", "module_id", "version", "artifact_digest", "artifact_storage_key", "artifact_size", "scan_status"}).
+		AddRow(uuid.New(), uuid.New(), "v1.0.0", "abc123", "modules/x/v1.0.0/protos.zip", 1234, "clean")
+	mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+		WithArgs("my-org", "my-module", "v1.0.0", 1).
+		WillReturnRows(rows)
+
+	req, err := http.NewRequest("HEAD", "/api/v1/modules/my-org/my-module/v1.0.0", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", GetModuleVersionHandler).Methods("GET", "HEAD")
+	router.ServeHTTP(rr, req)
+
+	// --- Assertions ---
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Equal(t, "sha256:abc123", rr.Header().Get("X-Artifact-Digest"))
+	assert.Equal(t, "1234", rr.Header().Get("X-Artifact-Size"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetModuleVersionHandler_HeadNotFound(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+		WithArgs("my-org", "my-module", "v9.9.9", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	req, err := http.NewRequest("HEAD", "/api/v1/modules/my-org/my-module/v9.9.9", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", GetModuleVersionHandler).Methods("GET", "HEAD")
+	router.ServeHTTP(rr, req)
+
+	// --- Assertions ---
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// --- Tests for DeleteModuleVersionHandler ---
+
+func TestDeleteModuleVersionHandler(t *testing.T) {
+	gormDB := newTestDB(t)
+	provider := newTestStorage(t)
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, "admin-token")
+	serve := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	publish := func(namespace, name, version string) models.ModuleVersion {
+		data, err := artifact.Pack(map[string][]byte{name + ".proto": []byte(`syntax = "proto3"; package ` + name + `.` + strings.ReplaceAll(version, ".", "_") + `;`)})
+		assert.NoError(t, err)
+		req := newPublishRequest(t, namespace, name, version, "", data)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var mv models.ModuleVersion
+		assert.NoError(t, gormDB.Joins("JOIN modules ON modules.id = module_versions.module_id").
+			Where("modules.namespace = ? AND modules.name = ? AND module_versions.version = ?", namespace, name, version).First(&mv).Error)
+		return mv
+	}
+	exists := func(key string) bool {
+		ok, err := provider.FileExists(context.Background(), key)
+		assert.NoError(t, err)
+		return ok
+	}
+	old := publish("acme", "orders", "v0.1.0")
+	current := publish("acme", "orders", "v0.2.0")
+	lone := publish("acme", "billing", "v1.0.0")
+	assert.NoError(t, gormDB.Create(&models.VersionNote{ModuleVersionID: old.ID, Body: "superseded"}).Error)
+	docsKey := storage.VersionArtifactKey("acme", "orders", "v0.1.0", "docs", strings.Repeat("d", 64))
+	assert.NoError(t, provider.UploadFile(context.Background(), docsKey, strings.NewReader("docs"), 4, "text/html"))
+	assert.NoError(t, gormDB.Create(&models.VersionArtifact{ModuleVersionID: old.ID, Classifier: "docs", ContentType: "text/html", Digest: strings.Repeat("d", 64), Size: 4, StorageKey: docsKey}).Error)
+
+	assert.Equal(t, http.StatusUnauthorized, serve("DELETE", "/api/v1/modules/acme/orders/v0.1.0", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("DELETE", "/api/v1/modules/acme/orders/0.1.0", "admin-token").Code)
+	assert.Equal(t, http.StatusNotFound, serve("DELETE", "/api/v1/modules/acme/orders/v9.9.9", "admin-token").Code)
+	// Dev channels are still deleted by their own handler
+	rr := serve("DELETE", "/api/v1/modules/acme/orders/dev-alice", "admin-token")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Dev channel not found")
+
+	assert.Equal(t, http.StatusNoContent, serve("DELETE", "/api/v1/modules/acme/orders/v0.1.0", "admin-token").Code)
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/api/v1/modules/acme/orders/v0.1.0", "").Code)
+	assert.Equal(t, http.StatusOK, serve("GET", "/api/v1/modules/acme/orders/v0.2.0", "").Code)
+	assert.False(t, exists(old.ArtifactStorageKey))
+	assert.True(t, exists(current.ArtifactStorageKey))
+	assert.False(t, exists(docsKey))
+	var notes int64
+	assert.NoError(t, gormDB.Model(&models.VersionNote{}).Count(&notes).Error)
+	assert.Zero(t, notes)
+
+	// The last version takes its module with it
+	assert.Equal(t, http.StatusNoContent, serve("DELETE", "/api/v1/modules/acme/billing/v1.0.0", "admin-token").Code)
+	assert.False(t, exists(lone.ArtifactStorageKey))
+	var modules []string
+	assert.NoError(t, gormDB.Model(&models.Module{}).Order("name").Pluck("name", &modules).Error)
+	assert.Equal(t, []string{"orders"}, modules)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestDB opens a SQLite database in a temporary directory, migrates it like the server does at
+// startup (db.Migrate) and makes it the global database for the rest of the test.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	_, err = db.Migrate(gormDB)
+	require.NoError(t, err)
+	db.SetDB(gormDB)
+	t.Cleanup(func() { db.SetDB(nil) })
+	return gormDB
+}
+
+// newTestStorage makes local storage in a temporary directory the global storage provider for the rest
+// of the test.
+func newTestStorage(t *testing.T) *storage.LocalStorage {
+	t.Helper()
+	provider, err := storage.NewLocalStorage(config.Config{LocalStoragePath: t.TempDir()})
+	require.NoError(t, err)
+	storage.SetStorageProvider(provider)
+	t.Cleanup(func() { storage.SetStorageProvider(nil) })
+	return provider
+}
+
+// setupMockDB initializes sqlmock and returns a mock GORM DB instance and the sqlmock controller.
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	mockDb, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	// Ensure mockDb is closed at the end of the test
+	t.Cleanup(func() { mockDb.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       mockDb,
+		DriverName: "postgres",
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open gorm v2 db: %v", err)
+	}
+
+	// Replace the global DB instance with the mock until the end of the test
+	db.SetDB(gormDB)
+	t.Cleanup(func() { db.SetDB(nil) })
+
+	return gormDB, mock
+}
+
+// newPublishRequest builds a multipart publish request carrying the given artifact bytes.
+func newPublishRequest(t *testing.T, namespace, moduleName, version, query string, artifact []byte) *http.Request {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("artifact", version+".zip")
+	assert.NoError(t, err)
+	_, err = part.Write(artifact)
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+
+	req, err := http.NewRequest("POST", "/api/v1/modules/"+namespace+"/"+moduleName+"/"+version+query, body)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+// expectNoNamespacePolicy expects the lookup of a namespace's policy, finding none.
+func expectNoNamespacePolicy(mock sqlmock.Sqlmock, namespace string) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "namespace_policies" WHERE namespace = $1`)).
+		WithArgs(namespace, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"namespace"}))
+}
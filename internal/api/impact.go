@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"go.uber.org/zap"
+)
+
+// ImpactAnalysisHandler reports which dependent modules a proposed artifact would break.
+// POST /api/v1/impact (multipart form: namespace, module_name, optional version, artifact)
+// The proposal is compared with the module's newest published version; nothing is persisted.
+// Requires Authentication.
+func ImpactAnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+
+	// --- Form Parsing ---
+	r.Body = http.MaxBytesReader(w, r.Body, requestLimits.MaxUploadBytes) // Same limit as publishing
+	if err := r.ParseMultipartForm(requestLimits.MaxUploadBytes); err != nil {
+		log.Warn("Error parsing multipart form", zap.Error(err))
+		if strings.Contains(err.Error(), "request body too large") {
+			response.Error(w, http.StatusRequestEntityTooLarge, uploadLimitMessage())
+		} else {
+			response.Error(w, http.StatusBadRequest, "Could not parse multipart form")
+		}
+		return
+	}
+
+	// --- Input Validation ---
+	namespace := r.FormValue("namespace")
+	moduleName := r.FormValue("module_name")
+	versionStr := r.FormValue("version")
+	if namespace == "" || moduleName == "" {
+		response.Error(w, http.StatusBadRequest, "Namespace and module name are required")
+		return
+	}
+	if err := validation.ValidateNamespace(namespace); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validation.ValidateModuleName(moduleName); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if versionStr != "" {
+		semVer, err := semver.NewVersion(versionStr)
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid semantic version format: %v", err))
+			return
+		}
+		versionStr = "v" + semVer.String()
+	}
+
+	file, _, err := r.FormFile("artifact")
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			response.Error(w, http.StatusBadRequest, "Missing 'artifact' file in form data")
+		} else {
+			response.Error(w, http.StatusBadRequest, "Could not retrieve artifact file")
+		}
+		return
+	}
+	defer file.Close()
+	artifact, err := io.ReadAll(file)
+	if err != nil {
+		log.Error("Error reading artifact file", zap.Error(err))
+		response.Error(w, http.StatusBadRequest, "Could not read artifact file")
+		return
+	}
+
+	// --- Analysis ---
+	log = log.With(zap.String("module", namespace+"/"+moduleName))
+	loader := descriptor.NewLoader(db.GetDB(), storage.GetStorageProvider())
+	report, err := loader.AnalyzeImpact(r.Context(), namespace, moduleName, versionStr, artifact)
+	switch {
+	case errors.Is(err, descriptor.ErrInvalidArtifact):
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, descriptor.ErrNotFound):
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, descriptor.ErrCompile):
+		// The published base version doesn't compile, so there is nothing to compare against
+		response.Error(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		log.Error("Error analyzing impact", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to analyze impact")
+		return
+	}
+
+	log.Info("Impact analysis complete",
+		zap.String("base_version", report.BaseVersion),
+		zap.Int("changes", len(report.Changes)),
+		zap.Int("dependents", len(report.Dependents)),
+		zap.Bool("breaking", report.Breaking))
+	response.JSON(w, http.StatusOK, report)
+}
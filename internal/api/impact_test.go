@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for ImpactAnalysisHandler ---
+
+// newImpactRequest builds a multipart impact analysis request with the given form fields and artifact.
+func newImpactRequest(t *testing.T, fields map[string]string, artifact []byte) *http.Request {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	for k, v := range fields {
+		assert.NoError(t, mw.WriteField(k, v))
+	}
+	if artifact != nil {
+		part, err := mw.CreateFormFile("artifact", "proposal.zip")
+		assert.NoError(t, err)
+		_, err = part.Write(artifact)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mw.Close())
+
+	req, err := http.NewRequest("POST", "/api/v1/impact", body)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestImpactAnalysisHandler_InvalidRequests(t *testing.T) {
+	_, mock := setupMockDB(t)
+	newTestStorage(t)
+
+	tests := []struct {
+		name     string
+		fields   map[string]string
+		artifact []byte
+		wantErr  string
+	}{
+		{"missing module", map[string]string{"namespace": "my-org"}, []byte("zip"), "Namespace and module name are required"},
+		{"invalid version", map[string]string{"namespace": "my-org", "module_name": "my-module", "version": "latest"}, []byte("zip"), "Invalid semantic version format"},
+		{"missing artifact", map[string]string{"namespace": "my-org", "module_name": "my-module"}, nil, "Missing 'artifact' file"},
+		{"not a zip", map[string]string{"namespace": "my-org", "module_name": "my-module"}, []byte("fake zip content"), "invalid artifact"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			ImpactAnalysisHandler(rr, newImpactRequest(t, tt.fields, tt.artifact))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.wantErr)
+		})
+	}
+	// The proposal is rejected before the registry is queried
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// checkImportGraph validates that every import in the artifact resolves, if the artifact declares its
+// dependencies (contains a sproto.yaml). The file is rewound afterwards.
+// Returns false if the publish must be rejected; the response has then been written.
+func checkImportGraph(w http.ResponseWriter, r *http.Request, file multipart.File, coordinates string) bool {
+	log := logging.FromContext(r.Context()).With(zap.String("module_version", coordinates))
+
+	artifact, err := io.ReadAll(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		log.Error("Error reading artifact for import validation", zap.Error(err))
+		response.Error(w, http.StatusBadRequest, "Could not read artifact file")
+		return false
+	}
+
+	// Artifacts without a sproto.yaml (or that aren't zips) declare no dependencies and are not checked
+	if !descriptor.DeclaresDependencies(artifact) {
+		return true
+	}
+
+	loader := descriptor.NewLoader(db.GetDB(), storage.GetStorageProvider())
+	unresolved, _, err := loader.CheckImports(r.Context(), artifact)
+	switch {
+	case errors.Is(err, descriptor.ErrInvalidArtifact):
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return false
+	case errors.Is(err, descriptor.ErrNotFound):
+		// A declared dependency has no matching published version
+		response.Error(w, http.StatusUnprocessableEntity, err.Error())
+		return false
+	case err != nil:
+		log.Error("Error validating artifact imports", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to validate artifact imports")
+		return false
+	}
+
+	if len(unresolved) > 0 {
+		log.Info("Rejecting artifact with unresolved imports", zap.Int("count", len(unresolved)))
+		issues := make([]apitypes.ValidationIssue, 0, len(unresolved))
+		for _, u := range unresolved {
+			issues = append(issues, apitypes.ValidationIssue{File: u.File, Rule: apitypes.RuleImport, Severity: apitypes.SeverityError,
+				Message: fmt.Sprintf("import %q is not in the artifact, a declared dependency or the well-known types", u.Import)})
+		}
+		response.JSON(w, http.StatusUnprocessableEntity, apitypes.ValidationFailedResponse{
+			Error:  fmt.Sprintf("Artifact has %d unresolved import(s); add the missing files or declare the dependency in sproto.yaml", len(unresolved)),
+			Issues: issues,
+		})
+		return false
+	}
+	log.Debug("Artifact imports resolved against declared dependencies")
+	return true
+}
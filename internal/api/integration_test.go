@@ -0,0 +1,342 @@
+//go:build integration
+
+package api
+
+// Integration tests exercising the real handlers against PostgreSQL and MinIO containers.
+// They require a working Docker daemon and are excluded from the default test run:
+//
+//	go test -tags integration -p 1 ./internal/api/...
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const integrationAuthToken = "integration-test-token"
+
+// integrationEnv holds the running containers and the registry under test.
+type integrationEnv struct {
+	server  *httptest.Server
+	storage storage.StorageProvider
+}
+
+// setupIntegrationEnv starts PostgreSQL and MinIO containers, initializes the global DB and
+// storage provider against them, and serves the real router from an httptest server.
+func setupIntegrationEnv(t *testing.T) *integrationEnv {
+	t.Helper()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err, "could not construct docker pool")
+	require.NoError(t, pool.Client.Ping(), "could not connect to docker")
+	pool.MaxWait = 2 * time.Minute
+
+	// --- PostgreSQL ---
+	// Mount the same init scripts used by docker-compose so the schema matches production.
+	sqlDir, err := filepath.Abs(filepath.Join("..", "..", "sql"))
+	require.NoError(t, err)
+
+	postgres, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "17",
+		Env: []string{
+			"POSTGRES_USER=postgres",
+			"POSTGRES_PASSWORD=postgres",
+			"POSTGRES_DB=sproto",
+		},
+		Mounts: []string{sqlDir + ":/docker-entrypoint-initdb.d"},
+	}, func(hc *docker.HostConfig) {
+		hc.AutoRemove = true
+		hc.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	require.NoError(t, err, "could not start postgres")
+	t.Cleanup(func() { _ = pool.Purge(postgres) })
+
+	// --- MinIO ---
+	minio, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "minio/minio",
+		Tag:        "latest",
+		Cmd:        []string{"server", "/data"},
+		Env: []string{
+			"MINIO_ROOT_USER=minioadmin",
+			"MINIO_ROOT_PASSWORD=minioadmin",
+		},
+	}, func(hc *docker.HostConfig) {
+		hc.AutoRemove = true
+		hc.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	require.NoError(t, err, "could not start minio")
+	t.Cleanup(func() { _ = pool.Purge(minio) })
+
+	cfg := config.Config{
+		DbType:         "postgres",
+		DbDsn:          fmt.Sprintf("host=localhost user=postgres password=postgres dbname=sproto port=%s sslmode=disable", postgres.GetPort("5432/tcp")),
+		StorageType:    "minio",
+		MinioEndpoint:  "localhost:" + minio.GetPort("9000/tcp"),
+		MinioAccessKey: "minioadmin",
+		MinioSecretKey: "minioadmin",
+		MinioBucket:    "sproto-integration",
+		AuthToken:      integrationAuthToken,
+	}
+
+	// Wait for both services to accept connections (Init also runs migrations).
+	require.NoError(t, pool.Retry(func() error {
+		_, err := db.Init(cfg)
+		return err
+	}), "postgres did not become ready")
+	t.Cleanup(func() { db.SetDB(nil) })
+
+	var provider storage.StorageProvider
+	require.NoError(t, pool.Retry(func() error {
+		var err error
+		provider, err = storage.InitStorage(cfg)
+		return err
+	}), "minio did not become ready")
+	t.Cleanup(func() { storage.SetStorageProvider(nil) })
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, cfg.AuthToken)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return &integrationEnv{server: server, storage: provider}
+}
+
+// buildTestArtifact creates an in-memory zip containing the given files.
+func buildTestArtifact(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// publish uploads an artifact through the real publish endpoint.
+func (e *integrationEnv) publish(t *testing.T, namespace, name, version string, artifact []byte) *http.Response {
+	t.Helper()
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("artifact", version+".zip")
+	require.NoError(t, err)
+	_, err = part.Write(artifact)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/modules/%s/%s/%s", e.server.URL, namespace, name, version), body)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+integrationAuthToken)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// get performs a GET request against the registry and returns the status and body.
+func (e *integrationEnv) get(t *testing.T, path string) (int, []byte) {
+	t.Helper()
+	resp, err := http.Get(e.server.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, body
+}
+
+// delete performs a DELETE request against the registry, authenticated with token unless it is empty.
+func (e *integrationEnv) delete(t *testing.T, path, token string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest("DELETE", e.server.URL+path, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestIntegration_PublishListFetchDelete(t *testing.T) {
+	env := setupIntegrationEnv(t)
+
+	artifact := buildTestArtifact(t, map[string]string{
+		"user/v1/user.proto": "syntax = \"proto3\";\npackage user.v1;\nmessage User { string id = 1; }\n",
+	})
+
+	// --- Publish ---
+	resp := env.publish(t, "integration", "user", "v1.0.0", artifact)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// Publishing the same version again must conflict
+	resp = env.publish(t, "integration", "user", "v1.0.0", artifact)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = env.publish(t, "integration", "user", "v1.1.0", artifact)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// --- List modules ---
+	status, body := env.get(t, "/api/v1/modules")
+	require.Equal(t, http.StatusOK, status)
+	var modules ListModulesResponse
+	require.NoError(t, json.Unmarshal(body, &modules))
+	require.Len(t, modules.Modules, 1)
+	assert.Equal(t, "integration", modules.Modules[0].Namespace)
+	assert.Equal(t, "user", modules.Modules[0].Name)
+	assert.Equal(t, "v1.1.0", modules.Modules[0].LatestVersion)
+
+	// --- List versions ---
+	status, body = env.get(t, "/api/v1/modules/integration/user")
+	require.Equal(t, http.StatusOK, status)
+	var versions ListModuleVersionsResponse
+	require.NoError(t, json.Unmarshal(body, &versions))
+	assert.Equal(t, []string{"v1.1.0", "v1.0.0"}, versions.Versions)
+
+	// --- Fetch ---
+	status, body = env.get(t, "/api/v1/modules/integration/user/v1.0.0/artifact")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, artifact, body, "fetched artifact must match the published bytes")
+
+	// --- Delete ---
+	var moduleVersion models.ModuleVersion
+	require.NoError(t, db.GetDB().Where("version = ?", "v1.0.0").First(&moduleVersion).Error)
+
+	resp = env.delete(t, "/api/v1/modules/integration/user/v1.0.0", "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = env.delete(t, "/api/v1/modules/integration/user/v1.0.0", integrationAuthToken)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	exists, err := env.storage.FileExists(context.Background(), moduleVersion.ArtifactStorageKey)
+	require.NoError(t, err)
+	assert.False(t, exists, "the deleted version's artifact must be removed from storage")
+
+	status, _ = env.get(t, "/api/v1/modules/integration/user/v1.0.0")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = env.get(t, "/api/v1/modules/integration/user/v1.0.0/artifact")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, body = env.get(t, "/api/v1/modules/integration/user")
+	require.Equal(t, http.StatusOK, status)
+	require.NoError(t, json.Unmarshal(body, &versions))
+	assert.Equal(t, []string{"v1.1.0"}, versions.Versions)
+}
+
+func TestIntegration_Search(t *testing.T) {
+	env := setupIntegrationEnv(t)
+
+	resp := env.publish(t, "integration", "orders", "v1.0.0", buildTestArtifact(t, map[string]string{
+		"sproto.yaml": "name: integration/orders\ndescription: Order placement for the web store\n",
+		"orders/v1/orders.proto": `syntax = "proto3";
+package orders.v1;
+// An order placed by a shopper.
+message Order {
+  // Invoice the order was billed with.
+  string invoice_id = 1;
+}
+// Places orders.
+service OrderService {
+  // Refunds a paid order to the shopper.
+  rpc RefundOrder(Order) returns (Order);
+}
+`,
+	}))
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	search := func(query string) apitypes.SearchResponse {
+		status, body := env.get(t, "/api/v1/search?"+query)
+		require.Equal(t, http.StatusOK, status, string(body))
+		var results apitypes.SearchResponse
+		require.NoError(t, json.Unmarshal(body, &results))
+		return results
+	}
+
+	// Ranked with the tsvector index, snippets highlighted by ts_headline
+	results := search("q=refunding")
+	require.Len(t, results.Results, 1)
+	assert.Equal(t, "method", results.Results[0].Kind)
+	assert.Equal(t, "orders.v1.OrderService.RefundOrder", results.Results[0].Name)
+	assert.Equal(t, "integration/orders", results.Results[0].Module)
+	assert.Equal(t, "v1.0.0", results.Results[0].Version)
+	assert.Contains(t, results.Results[0].Snippet, "<mark>Refunds</mark>")
+
+	results = search("q=web+store&kind=module")
+	require.Len(t, results.Results, 1)
+	assert.Equal(t, "integration/orders", results.Results[0].Name)
+
+	results = search("q=invoice")
+	require.Len(t, results.Results, 1)
+	assert.Equal(t, "orders.v1.Order.invoice_id", results.Results[0].Name)
+	assert.Greater(t, results.Results[0].Rank, 0.0)
+
+	assert.Empty(t, search(`q="order+paid"`).Results)
+}
+
+func TestIntegration_Verify(t *testing.T) {
+	env := setupIntegrationEnv(t)
+
+	resp := env.publish(t, "integration", "ledger", "v1.0.0", buildTestArtifact(t, map[string]string{
+		"ledger/v1/ledger.proto": "syntax = \"proto3\";\npackage ledger.v1;\nmessage Entry { int64 cents = 1; }\n",
+	}))
+	var published apitypes.PublishModuleVersionResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&published))
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	verify := func(digest string) (int, apitypes.VerifyResponse) {
+		payload, err := json.Marshal(apitypes.VerifyRequest{Namespace: "integration", ModuleName: "ledger", Version: "v1.0.0", Digest: digest})
+		require.NoError(t, err)
+		resp, err := http.Post(env.server.URL+"/api/v1/verify", "application/json", bytes.NewReader(payload))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var result apitypes.VerifyResponse
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		}
+		return resp.StatusCode, result
+	}
+
+	// The digest returned by the publish is the recorded one; anything else is a mismatch
+	status, result := verify(published.ArtifactDigest)
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, result.Match)
+	assert.False(t, result.Deprecated)
+	status, result = verify("sha256:" + strings.Repeat("0", 64))
+	require.Equal(t, http.StatusOK, status)
+	assert.False(t, result.Match)
+	assert.Equal(t, published.ArtifactDigest, result.RecordedDigest)
+
+	status, _ = verify("not-a-digest")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
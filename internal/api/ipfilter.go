@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Network restrictions: requests are filtered by client IP (see clientIP, so AUTH_CLIENT_IP_HEADER
+// applies) before any token is looked at. Clients in the denylist are rejected on every route. If an
+// allowlist is configured, the routes needing the admin token (publishing and every other write, admin
+// endpoints) only accept clients in it, so a leaked token is useless outside e.g. the CI runner networks.
+// Requests whose client IP is unknown (Unix sockets without a proxy header) never match a list.
+
+// IPFilterPolicy configures the network restrictions.
+type IPFilterPolicy struct {
+	WriteAllowed []netip.Prefix // Networks allowed to call admin token routes; any if empty
+	Denied       []netip.Prefix // Networks rejected on every route
+}
+
+// Global network restrictions, configured at startup via SetIPFilterPolicy (none by default).
+var ipFilter IPFilterPolicy
+
+// SetIPFilterPolicy configures the network restrictions.
+func SetIPFilterPolicy(p IPFilterPolicy) {
+	ipFilter = p
+}
+
+// ParseCIDRs parses a comma-separated list of networks in CIDR notation ("10.0.0.0/8,2001:db8::/32").
+// A plain address stands for itself.
+func ParseCIDRs(spec string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// ipInNetworks reports whether the client IP is in one of the networks. IPv4-mapped IPv6 addresses
+// ("::ffff:10.0.0.1", as seen on dual-stack listeners) match IPv4 networks.
+func ipInNetworks(client string, networks []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(client)
+	if err != nil {
+		return false // "unknown" (Unix sockets) or a malformed proxy header
+	}
+	addr = addr.Unmap().WithZone("")
+	for _, network := range networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectClientIP logs and counts a request rejected by the list and responds 403.
+func rejectClientIP(w http.ResponseWriter, r *http.Request, list, client string) {
+	metrics.IPFilterRejectionsTotal.WithLabelValues(list).Inc()
+	logging.Audit(r.Context()).Warn("Rejected request by client IP",
+		zap.String("event", "ip.rejected"), zap.String("list", list), zap.String("client_ip", client),
+		zap.String("method", r.Method), zap.String("path", r.URL.Path))
+	response.Error(w, http.StatusForbidden, "Forbidden: Requests from this address are not allowed")
+}
+
+// --- Middleware ---
+
+// DenyIPsMiddleware rejects clients in the denylist with 403, on every route.
+func DenyIPsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(ipFilter.Denied) > 0 {
+			if client := clientIP(r); ipInNetworks(client, ipFilter.Denied) {
+				rejectClientIP(w, r, "denylist", client)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RestrictWriteNetworks rejects clients outside the write allowlist with 403, before authentication.
+// ApplyAuth wraps every admin token route with it, and the routes changing webhook subscriptions (which
+// maintainer tokens may use) are wrapped with it directly.
+func RestrictWriteNetworks(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(ipFilter.WriteAllowed) > 0 {
+			if client := clientIP(r); !ipInNetworks(client, ipFilter.WriteAllowed) {
+				rejectClientIP(w, r, "write_allowlist", client)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,515 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/bufimport"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Admin jobs: maintenance and batch work that admins start on a running server, processed as background
+// operations (see operations.go) instead of a server subcommand or waiting for the next periodic run:
+//
+//	gc               delete expired original uploads, operations finished more than a day ago,
+//	                 (with DEV_CHANNEL_TTL) dev channels not updated for that long and the expired
+//	                 versions of ephemeral namespaces (see ephemeral.go)
+//	sunset           enforce the sunset dates that have passed
+//	migrate-storage  move artifacts stored under older key layouts (like `sproto-server migrate-storage`)
+//	import-buf       import a module from a buf registry (like `sproto-server import-buf`)
+//	tier-storage     move aging artifacts to cold storage (like the storage tiering job, see tiering.go)
+//	reindex-search   rebuild the full-text search index from the newest version of each module (see search.go)
+//	reindex-packages index the proto packages of every version of each module (see packages.go)
+//	sign-versions    sign the versions published without a signature (see signing.go)
+//
+// Each job validates its parameters when it is started and writes its result like an HTTP handler.
+
+// jobDefinition describes an admin job.
+type jobDefinition struct {
+	params  map[string]bool // Accepted parameters: name -> required
+	secrets []string        // Parameters not recorded with the operation
+	// prepare validates the parameters and returns the work to queue, or an error message (400)
+	prepare func(params map[string]string) (operationFunc, string)
+}
+
+// jobs are the admin jobs by kind.
+var jobs = map[string]jobDefinition{
+	models.OperationKindGC:             {prepare: prepareGCJob},
+	models.OperationKindSunset:         {prepare: prepareSunsetJob},
+	models.OperationKindMigrateStorage: {params: map[string]bool{"keep_old": false}, prepare: prepareMigrateStorageJob},
+	models.OperationKindImportBuf: {
+		params:  map[string]bool{"source": true, "module": false, "buf_token": false, "dry_run": false},
+		secrets: []string{"buf_token"},
+		prepare: prepareImportBufJob,
+	},
+	models.OperationKindTierStorage:     {params: map[string]bool{"older_than": false}, prepare: prepareTierStorageJob},
+	models.OperationKindReindexSearch:   {params: map[string]bool{"module": false}, prepare: prepareReindexSearchJob},
+	models.OperationKindReindexPackages: {params: map[string]bool{"module": false}, prepare: prepareReindexPackagesJob},
+	models.OperationKindSignVersions:    {params: map[string]bool{"module": false}, prepare: prepareSignVersionsJob},
+}
+
+// StartJobHandler starts an admin job as a background operation and responds 202 with the operation.
+// POST /api/v1/admin/jobs/{job}
+// Requires Authentication (admin token).
+func StartJobHandler(w http.ResponseWriter, r *http.Request) {
+	kind := mux.Vars(r)["job"]
+	job, ok := jobs[kind]
+	if !ok {
+		names := make([]string, 0, len(jobs))
+		for name := range jobs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("Unknown job %q: expected one of %s", kind, strings.Join(names, ", ")))
+		return
+	}
+
+	var req apitypes.StartJobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	for name := range req.Params {
+		if _, ok := job.params[name]; !ok {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Unknown parameter %q for job %s", name, kind))
+			return
+		}
+	}
+	for name, required := range job.params {
+		if required && req.Params[name] == "" {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Parameter %q is required for job %s", name, kind))
+			return
+		}
+	}
+	run, message := job.prepare(req.Params)
+	if message != "" {
+		response.Error(w, http.StatusBadRequest, message)
+		return
+	}
+
+	// Recorded without secrets, so listing operations doesn't reveal them
+	recorded := make(map[string]string, len(req.Params))
+	for name, value := range req.Params {
+		recorded[name] = value
+	}
+	for _, name := range job.secrets {
+		delete(recorded, name)
+	}
+	op := &models.Operation{Kind: kind}
+	if len(recorded) > 0 {
+		params, _ := json.Marshal(recorded)
+		op.Params = string(params)
+	}
+	if module := req.Params["module"]; module != "" {
+		op.Namespace, op.ModuleName, _ = strings.Cut(module, "/")
+	}
+	respondOperationStarted(w, r, op, startOperation(r.Context(), op, run))
+}
+
+// boolParam parses an optional boolean job parameter.
+func boolParam(params map[string]string, name string) (bool, string) {
+	if params[name] == "" {
+		return false, ""
+	}
+	value, err := strconv.ParseBool(params[name])
+	if err != nil {
+		return false, fmt.Sprintf("Parameter %q must be true or false", name)
+	}
+	return value, ""
+}
+
+// --- gc ---
+
+// GCJobResponse is the result of the gc job.
+type GCJobResponse struct {
+	OriginalUploads int   `json:"original_uploads"`       // Expired original uploads deleted
+	Operations      int64 `json:"operations"`             // Finished operations deleted
+	DevChannels     int   `json:"dev_channels,omitempty"` // Expired dev channels deleted (DEV_CHANNEL_TTL or ephemeral namespaces)
+
+	// Deleted from ephemeral namespaces (see ephemeral.go)
+	EphemeralVersions int `json:"ephemeral_versions,omitempty"`
+	EphemeralModules  int `json:"ephemeral_modules,omitempty"`
+}
+
+func prepareGCJob(map[string]string) (operationFunc, string) {
+	return func(ctx context.Context, w http.ResponseWriter) {
+		now := time.Now().UTC()
+		reportStage(ctx, "original uploads")
+		originals, err := purgeOriginalUploads(ctx, now)
+		if err != nil {
+			logging.FromContext(ctx).Error("Error deleting expired original uploads", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to delete expired original uploads")
+			return
+		}
+		reportStage(ctx, "operations")
+		ops, err := purgeOperations(ctx, now.Add(-operationRetention))
+		if err != nil {
+			logging.FromContext(ctx).Error("Error deleting finished operations", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to delete finished operations")
+			return
+		}
+		channels := 0
+		if devChannelTTL > 0 {
+			reportStage(ctx, "dev channels")
+			if channels, err = purgeDevChannels(ctx, now.Add(-devChannelTTL)); err != nil {
+				logging.FromContext(ctx).Error("Error deleting expired dev channels", zap.Error(err))
+				response.Error(w, http.StatusInternalServerError, "Failed to delete expired dev channels")
+				return
+			}
+		}
+		reportStage(ctx, "ephemeral namespaces")
+		ephemeral, err := expireEphemeral(ctx, now)
+		if err != nil {
+			logging.FromContext(ctx).Error("Error deleting expired ephemeral versions", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to delete expired ephemeral versions")
+			return
+		}
+		response.JSON(w, http.StatusOK, GCJobResponse{OriginalUploads: originals, Operations: ops, DevChannels: channels + ephemeral.DevChannels,
+			EphemeralVersions: ephemeral.Versions, EphemeralModules: ephemeral.Modules})
+	}, ""
+}
+
+// --- sunset ---
+
+// SunsetJobResponse is the result of the sunset job.
+type SunsetJobResponse struct {
+	Enforced int `json:"enforced"` // Versions whose sunset took effect
+}
+
+func prepareSunsetJob(map[string]string) (operationFunc, string) {
+	return func(ctx context.Context, w http.ResponseWriter) {
+		enforced, err := enforceSunsets(ctx, time.Now().UTC())
+		if err != nil {
+			logging.FromContext(ctx).Error("Error enforcing version sunsets", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to enforce version sunsets")
+			return
+		}
+		response.JSON(w, http.StatusOK, SunsetJobResponse{Enforced: enforced})
+	}, ""
+}
+
+// --- migrate-storage ---
+
+// MigrateStorageJobResponse is the result of the migrate-storage job.
+type MigrateStorageJobResponse struct {
+	Error    string               `json:"error,omitempty"` // Set if some artifacts failed to migrate
+	Migrated int                  `json:"migrated"`
+	Failed   []ArtifactJobFailure `json:"failed"`
+}
+
+// ArtifactJobFailure is an artifact a storage job failed to process (it is left as it was).
+type ArtifactJobFailure struct {
+	ModuleVersion string `json:"module_version"` // namespace/name@version
+	Error         string `json:"error"`
+}
+
+func prepareMigrateStorageJob(params map[string]string) (operationFunc, string) {
+	keepOld, message := boolParam(params, "keep_old")
+	if message != "" {
+		return nil, message
+	}
+	return func(ctx context.Context, w http.ResponseWriter) {
+		log := logging.FromContext(ctx)
+		artifacts, err := ListLegacyArtifacts(ctx)
+		if err != nil {
+			log.Error("Error listing artifacts to migrate", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to list artifacts to migrate")
+			return
+		}
+
+		resp := MigrateStorageJobResponse{Failed: []ArtifactJobFailure{}}
+		for i, a := range artifacts {
+			if ctx.Err() != nil {
+				break // Canceled: the artifacts migrated so far stay migrated
+			}
+			reportStage(ctx, fmt.Sprintf("migrating %d/%d", i+1, len(artifacts)))
+			if err := MigrateArtifact(ctx, a, keepOld); err != nil {
+				log.Error("Failed to migrate artifact", zap.String("coordinates", a.Coordinates()), zap.Error(err))
+				resp.Failed = append(resp.Failed, ArtifactJobFailure{ModuleVersion: a.Coordinates(), Error: err.Error()})
+				continue
+			}
+			resp.Migrated++
+		}
+		log.Info("Storage migration finished", zap.Int("migrated", resp.Migrated), zap.Int("failed", len(resp.Failed)))
+
+		switch {
+		case ctx.Err() != nil:
+			resp.Error = fmt.Sprintf("interrupted after migrating %d of %d artifact(s)", resp.Migrated, len(artifacts))
+			response.JSON(w, http.StatusServiceUnavailable, resp)
+		case len(resp.Failed) > 0:
+			resp.Error = fmt.Sprintf("%d artifact(s) failed to migrate", len(resp.Failed))
+			response.JSON(w, http.StatusInternalServerError, resp)
+		default:
+			response.JSON(w, http.StatusOK, resp)
+		}
+	}, ""
+}
+
+// --- tier-storage ---
+
+// TierStorageJobResponse is the result of the tier-storage job.
+type TierStorageJobResponse struct {
+	Error  string               `json:"error,omitempty"` // Set if some artifacts failed to move
+	Tiered int                  `json:"tiered"`
+	Failed []ArtifactJobFailure `json:"failed"`
+}
+
+func prepareTierStorageJob(params map[string]string) (operationFunc, string) {
+	age := storageTieringAge
+	if params["older_than"] != "" {
+		var err error
+		if age, err = time.ParseDuration(params["older_than"]); err != nil || age < 0 {
+			return nil, `Parameter "older_than" must be a duration, e.g. 2160h`
+		}
+	}
+	if age <= 0 {
+		return nil, `Parameter "older_than" is required when STORAGE_TIERING_AGE is not set`
+	}
+	return func(ctx context.Context, w http.ResponseWriter) {
+		result, err := tierArtifacts(ctx, time.Now().UTC().Add(-age))
+		if errors.Is(err, storage.ErrTieringUnsupported) {
+			response.Error(w, http.StatusBadRequest, "No cold storage tier is configured (MINIO_COLD_STORAGE_CLASS or LOCAL_COLD_STORAGE_PATH)")
+			return
+		} else if err != nil {
+			logging.FromContext(ctx).Error("Error moving artifacts to cold storage", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to move artifacts to cold storage")
+			return
+		}
+
+		resp := TierStorageJobResponse{Tiered: result.Tiered, Failed: result.Failed}
+		switch {
+		case ctx.Err() != nil:
+			resp.Error = fmt.Sprintf("interrupted after moving %d of %d artifact(s)", resp.Tiered, result.Total)
+			response.JSON(w, http.StatusServiceUnavailable, resp)
+		case len(resp.Failed) > 0:
+			resp.Error = fmt.Sprintf("%d artifact(s) failed to move", len(resp.Failed))
+			response.JSON(w, http.StatusInternalServerError, resp)
+		default:
+			response.JSON(w, http.StatusOK, resp)
+		}
+	}, ""
+}
+
+// --- reindex-search, reindex-packages ---
+
+// ReindexJobResponse is the result of the reindex-search, reindex-packages and sign-versions jobs.
+type ReindexJobResponse struct {
+	Error   string             `json:"error,omitempty"` // Set if some modules failed to index
+	Indexed int                `json:"indexed"`
+	Failed  []ModuleJobFailure `json:"failed"`
+}
+
+// ModuleJobFailure is a module a job failed to process.
+type ModuleJobFailure struct {
+	Module string `json:"module"` // namespace/name
+	Error  string `json:"error"`
+}
+
+func prepareReindexSearchJob(params map[string]string) (operationFunc, string) {
+	return prepareReindexJob(params, "search", false, indexModule)
+}
+
+func prepareReindexPackagesJob(params map[string]string) (operationFunc, string) {
+	// Ephemeral namespaces aren't part of the package index
+	return prepareReindexJob(params, "package", true, indexModulePackages)
+}
+
+// prepareReindexJob returns a job indexing every module (or the one named by the "module" parameter) with
+// index. what names the index in logs and errors.
+func prepareReindexJob(params map[string]string, what string, skipEphemeral bool, index func(context.Context, models.Module) error) (operationFunc, string) {
+	var namespace, name string
+	if params["module"] != "" {
+		var err error
+		if namespace, name, err = manifest.SplitModule(params["module"]); err != nil {
+			return nil, fmt.Sprintf(`Invalid parameter "module": %v`, err)
+		}
+	}
+	return func(ctx context.Context, w http.ResponseWriter) {
+		log := logging.FromContext(ctx)
+		query := db.GetDB().WithContext(ctx).Order("namespace, name")
+		if namespace != "" {
+			query = query.Where("namespace = ? AND name = ?", namespace, name)
+		}
+		if skipEphemeral {
+			query = query.Where("namespace NOT IN (?)", db.GetDB().Model(&models.NamespacePolicy{}).Select("namespace").Where("ephemeral_ttl_seconds > 0"))
+		}
+		var modules []models.Module
+		if err := query.Find(&modules).Error; err != nil {
+			log.Error("Error listing modules to index", zap.String("index", what), zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to list modules to index")
+			return
+		}
+		if namespace != "" && len(modules) == 0 {
+			response.Error(w, http.StatusNotFound, "Module not found")
+			return
+		}
+
+		resp := ReindexJobResponse{Failed: []ModuleJobFailure{}}
+		for i, module := range modules {
+			if ctx.Err() != nil {
+				break // Canceled: the modules indexed so far stay indexed
+			}
+			fullName := module.Namespace + "/" + module.Name
+			reportStage(ctx, fmt.Sprintf("indexing %d/%d", i+1, len(modules)))
+			if err := index(ctx, module); err != nil {
+				log.Warn("Failed to index module", zap.String("index", what), zap.String("module", fullName), zap.Error(err))
+				resp.Failed = append(resp.Failed, ModuleJobFailure{Module: fullName, Error: err.Error()})
+				continue
+			}
+			resp.Indexed++
+		}
+		log.Info("Reindexing finished", zap.String("index", what), zap.Int("indexed", resp.Indexed), zap.Int("failed", len(resp.Failed)))
+
+		switch {
+		case ctx.Err() != nil:
+			resp.Error = fmt.Sprintf("interrupted after indexing %d of %d module(s)", resp.Indexed, len(modules))
+			response.JSON(w, http.StatusServiceUnavailable, resp)
+		case len(resp.Failed) > 0:
+			resp.Error = fmt.Sprintf("%d module(s) failed to index", len(resp.Failed))
+			response.JSON(w, http.StatusInternalServerError, resp)
+		default:
+			response.JSON(w, http.StatusOK, resp)
+		}
+	}, ""
+}
+
+// --- import-buf ---
+
+// ImportBufJobResponse is the result of the import-buf job.
+type ImportBufJobResponse struct {
+	Error    string   `json:"error,omitempty"` // Set if the import stopped early
+	Module   string   `json:"module"`
+	Imported []string `json:"imported"` // Versions published (or, for a dry run, to publish), oldest first
+	Existing []string `json:"existing"` // Versions that already existed and were left untouched
+	Skipped  []string `json:"skipped"`  // Labels that aren't semantic versions
+}
+
+func prepareImportBufJob(params map[string]string) (operationFunc, string) {
+	ref, err := bufimport.ParseModuleRef(params["source"])
+	if err != nil {
+		return nil, err.Error()
+	}
+	module := params["module"]
+	if module == "" {
+		module = ref.Owner + "/" + ref.Module
+	}
+	namespace, name, err := manifest.SplitModule(module)
+	if err != nil {
+		return nil, fmt.Sprintf("Invalid module: %v", err)
+	}
+	dryRun, message := boolParam(params, "dry_run")
+	if message != "" {
+		return nil, message
+	}
+	client := bufimport.NewClient(ref, params["buf_token"])
+
+	return func(ctx context.Context, w http.ResponseWriter) {
+		log := logging.FromContext(ctx).With(zap.String("job", "import-buf"))
+		publisher := &handlerPublisher{namespace: namespace, name: name, report: func(version string) { reportStage(ctx, "importing "+version) }}
+		result, err := bufimport.Import(ctx, client, ref, publisher, bufimport.Options{Module: module, DryRun: dryRun, Log: log})
+
+		resp := ImportBufJobResponse{Module: module, Imported: []string{}, Existing: []string{}, Skipped: []string{}}
+		if result != nil {
+			resp.Imported = append(resp.Imported, result.Imported...)
+			resp.Existing = append(resp.Existing, result.Existing...)
+			resp.Skipped = append(resp.Skipped, result.Skipped...)
+		}
+		var bufErr *bufimport.Error
+		switch {
+		case errors.As(err, &bufErr):
+			resp.Error = err.Error()
+			response.JSON(w, http.StatusBadGateway, resp)
+		case err != nil:
+			log.Error("Failed to import buf module", zap.String("buf_module", ref.String()), zap.Error(err))
+			resp.Error = err.Error()
+			response.JSON(w, http.StatusInternalServerError, resp)
+		default:
+			response.JSON(w, http.StatusOK, resp)
+		}
+	}, ""
+}
+
+// handlerPublisher publishes imported versions by calling the publish and note handlers directly, with
+// the context (and so the admin identity) of the operation.
+type handlerPublisher struct {
+	namespace, name string
+	report          func(version string)
+}
+
+func (p *handlerPublisher) Publish(ctx context.Context, version string, artifact []byte) (bool, error) {
+	p.report(version)
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("artifact", version+".zip")
+	if err != nil {
+		return false, err
+	}
+	if _, err := part.Write(artifact); err != nil {
+		return false, err
+	}
+	if err := mw.Close(); err != nil {
+		return false, err
+	}
+	status, respBody, err := p.call(ctx, PublishModuleVersionHandler, version, "", body, mw.FormDataContentType())
+	if err != nil {
+		return false, err
+	}
+	switch status {
+	case http.StatusCreated:
+		return true, nil
+	case http.StatusConflict:
+		return false, nil // Imported before
+	default:
+		return false, fmt.Errorf("publish returned %d: %s", status, strings.TrimSpace(respBody))
+	}
+}
+
+func (p *handlerPublisher) AddNote(ctx context.Context, version, note string) error {
+	payload, err := json.Marshal(apitypes.AddVersionNoteRequest{Note: note})
+	if err != nil {
+		return err
+	}
+	status, respBody, err := p.call(ctx, AddVersionNoteHandler, version, "/notes", bytes.NewReader(payload), "application/json")
+	if err != nil {
+		return err
+	}
+	if status != http.StatusCreated {
+		return fmt.Errorf("adding the note returned %d: %s", status, strings.TrimSpace(respBody))
+	}
+	return nil
+}
+
+// call runs handler for a request on the target module version, returning the status and body.
+func (p *handlerPublisher) call(ctx context.Context, handler http.HandlerFunc, version, suffix string, body io.Reader, contentType string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("/api/v1/modules/%s/%s/%s%s", p.namespace, p.name, version, suffix), body)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(apitypes.PublisherHeader, "import-buf")
+	req = mux.SetURLVars(req, map[string]string{"namespace": p.namespace, "module_name": p.name, "version": version})
+	rec := newOperationRecorder()
+	handler(rec, req)
+	if req.MultipartForm != nil {
+		_ = req.MultipartForm.RemoveAll()
+	}
+	return rec.status, rec.body.String(), nil
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationsAndJobs(t *testing.T) {
+	newTestDB(t)
+	newTestStorage(t)
+
+	SetOperationQueue(OperationQueue{Workers: 1, Size: 4})
+	t.Cleanup(func() { SetOperationQueue(OperationQueue{}) })
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/operations", ListOperationsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/operations/{id}", GetOperationHandler).Methods("GET")
+	router.HandleFunc("/api/v1/operations/{id}/cancel", CancelOperationHandler).Methods("POST")
+	router.HandleFunc("/api/v1/admin/jobs/{job}", StartJobHandler).Methods("POST")
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rr
+	}
+	decode := func(rr *httptest.ResponseRecorder) apitypes.OperationResponse {
+		var op apitypes.OperationResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &op), rr.Body.String())
+		return op
+	}
+	wait := func(id string) apitypes.OperationResponse {
+		var op apitypes.OperationResponse
+		assert.Eventually(t, func() bool {
+			op = decode(do("GET", "/api/v1/operations/"+id, ""))
+			return op.Status != "queued" && op.Status != "running"
+		}, 10*time.Second, 10*time.Millisecond)
+		return op
+	}
+
+	// Invalid jobs and parameters are rejected before anything is queued
+	assert.Equal(t, http.StatusNotFound, do("POST", "/api/v1/admin/jobs/nope", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/api/v1/admin/jobs/gc", `{"params": {"nope": "1"}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/api/v1/admin/jobs/migrate-storage", `{"params": {"keep_old": "maybe"}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/api/v1/admin/jobs/import-buf", `{"params": {"module": "acme/user"}}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/api/v1/admin/jobs/gc", `not json`).Code)
+
+	// Queued operations are canceled right away (the workers aren't running yet)
+	rr := do("POST", "/api/v1/admin/jobs/sunset", "")
+	assert.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	queued := decode(rr)
+	assert.Equal(t, "sunset", queued.Kind)
+	rr = do("POST", "/api/v1/operations/"+queued.ID+"/cancel", "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "canceled", decode(rr).Status)
+	assert.Equal(t, http.StatusConflict, do("POST", "/api/v1/operations/"+queued.ID+"/cancel", "").Code)
+
+	// Running operations are interrupted through their context
+	started := make(chan struct{})
+	blocking := &models.Operation{Kind: models.OperationKindGC}
+	assert.NoError(t, startOperation(context.Background(), blocking, func(ctx context.Context, w http.ResponseWriter) {
+		close(started)
+		<-ctx.Done()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"error": "Interrupted"}`))
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go RunOperationWorkers(ctx)
+	<-started
+	assert.Eventually(t, func() bool {
+		return decode(do("GET", "/api/v1/operations/"+blocking.ID.String(), "")).Status == "running"
+	}, 10*time.Second, 10*time.Millisecond)
+	rr = do("POST", "/api/v1/operations/"+blocking.ID.String()+"/cancel", "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	op := wait(blocking.ID.String())
+	assert.Equal(t, "canceled", op.Status)
+	assert.Equal(t, "Canceled: Interrupted", op.Error)
+	// The canceled queued operation was skipped by the worker
+	assert.Equal(t, "canceled", decode(do("GET", "/api/v1/operations/"+queued.ID, "")).Status)
+
+	// Jobs record their result and parameters
+	rr = do("POST", "/api/v1/admin/jobs/gc", "")
+	assert.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	op = wait(decode(rr).ID)
+	assert.Equal(t, "succeeded", op.Status)
+	assert.JSONEq(t, `{"original_uploads": 0, "operations": 0}`, string(op.Result))
+
+	rr = do("POST", "/api/v1/admin/jobs/migrate-storage", `{"params": {"keep_old": "true"}}`)
+	assert.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	op = wait(decode(rr).ID)
+	assert.Equal(t, "succeeded", op.Status)
+	assert.Equal(t, map[string]string{"keep_old": "true"}, op.Params)
+	assert.JSONEq(t, `{"migrated": 0, "failed": []}`, string(op.Result))
+
+	// Listing: newest first, filtered by kind and status
+	var list apitypes.ListOperationsResponse
+	rr = do("GET", "/api/v1/operations", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	assert.Len(t, list.Operations, 4)
+	assert.Equal(t, "migrate-storage", list.Operations[0].Kind)
+
+	assert.NoError(t, json.Unmarshal(do("GET", "/api/v1/operations?kind=gc&status=canceled", "").Body.Bytes(), &list))
+	assert.Len(t, list.Operations, 1)
+	assert.Equal(t, blocking.ID.String(), list.Operations[0].ID)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/api/v1/operations?limit=0", "").Code)
+}
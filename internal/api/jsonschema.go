@@ -0,0 +1,120 @@
+package api
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// JSON Schemas: every top-level message of a module version can be fetched as a JSON Schema document, so
+// services validating JSON payloads shaped by protos can pull schemas straight from the registry. They are
+// generated on request from the compiled descriptors; like bundles, they resolve dependencies to the newest
+// matching versions and are cached like metadata, with an ETag.
+
+// ListMessageSchemasResponse lists the messages of a module version that have a JSON Schema.
+type ListMessageSchemasResponse struct {
+	Namespace  string   `json:"namespace"`
+	ModuleName string   `json:"module_name"`
+	Version    string   `json:"version"`
+	Messages   []string `json:"messages"` // Fully-qualified, sorted
+}
+
+// ListMessageSchemasHandler lists the top-level messages of a module version.
+// GET /api/v1/modules/{namespace}/{module_name}/{version}/jsonschema
+func ListMessageSchemasHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	schema, deps, ok := compileForJSONSchema(w, r, vars["namespace"], vars["module_name"], vars["version"])
+	if !ok {
+		return // Response already written
+	}
+	messages := schema.Messages()
+	if messages == nil {
+		messages = []string{} // Empty array, not null
+	}
+	w.Header().Set(apitypes.BundleDependenciesHeader, strings.Join(deps, ", "))
+	setListCacheHeaders(w)
+	response.JSON(w, http.StatusOK, ListMessageSchemasResponse{
+		Namespace:  schema.Namespace,
+		ModuleName: schema.Name,
+		Version:    schema.Version,
+		Messages:   messages,
+	})
+}
+
+// GetMessageJSONSchemaHandler returns the JSON Schema of a top-level message of a module version.
+// GET /api/v1/modules/{namespace}/{module_name}/{version}/jsonschema/{message}
+// {message} is the fully-qualified message name, e.g. "mycompany.user.v1.User".
+func GetMessageJSONSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	messageName := vars["message"]
+	schema, deps, ok := compileForJSONSchema(w, r, vars["namespace"], vars["module_name"], vars["version"])
+	if !ok {
+		return // Response already written
+	}
+
+	body, err := descriptor.JSONSchema(schema, messageName)
+	if err != nil {
+		if errors.Is(err, descriptor.ErrMessageNotFound) {
+			response.Error(w, http.StatusNotFound, fmt.Sprintf("Message '%s' not found in %s/%s@%s", messageName, schema.Namespace, schema.Name, schema.Version))
+			return
+		}
+		logging.FromContext(r.Context()).Error("Error generating JSON Schema", zap.String("message", messageName), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to generate JSON Schema")
+		return
+	}
+
+	// The content is deterministic, so its digest identifies it
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(body))
+	w.Header().Set("ETag", etag)
+	w.Header().Set(apitypes.BundleDependenciesHeader, strings.Join(deps, ", "))
+	setListCacheHeaders(w)
+	if notModified(w, r, etag) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		logging.FromContext(r.Context()).Warn("Error writing JSON Schema to client", zap.Error(err))
+	}
+}
+
+// compileForJSONSchema compiles a module version against its (readable) dependencies, returning the
+// schema and the dependency versions it was compiled against. On failure a response has been written
+// and ok is false.
+func compileForJSONSchema(w http.ResponseWriter, r *http.Request, namespace, moduleName, version string) (*descriptor.Schema, []string, bool) {
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return nil, nil, false
+	}
+	if _, ok := findModuleVersion(w, r, namespace, moduleName, version); !ok {
+		return nil, nil, false
+	}
+
+	// Compiling downloads every dependency's artifact, so it holds a stream slot like a bundle
+	limit := streamSlots
+	if !limit.acquire(w, r) {
+		return nil, nil, false // 429 already written
+	}
+	defer limit.release()
+
+	log := logging.FromContext(r.Context()).With(zap.String("module_version", namespace+"/"+moduleName+"@"+version))
+	bundle, deps, ok := readableBundle(w, r, log, namespace, moduleName, version)
+	if !ok {
+		return nil, nil, false
+	}
+	schema, err := bundle.Schema(r.Context())
+	if err != nil {
+		writeBundleError(w, log, err)
+		return nil, nil, false
+	}
+	return schema, deps, true
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageJSONSchemaHandlers(t *testing.T) {
+	gormDB := newTestDB(t)
+	provider := newTestStorage(t)
+
+	publish := func(name, visibility string, files map[string][]byte) {
+		data, err := artifact.Pack(files)
+		assert.NoError(t, err)
+		module := models.Module{Namespace: "acme", Name: name, Visibility: visibility}
+		assert.NoError(t, gormDB.Create(&module).Error)
+		key := "acme/" + name + "/v1.0.0.zip"
+		assert.NoError(t, provider.UploadFile(context.Background(), key, bytes.NewReader(data), int64(len(data)), "application/zip"))
+		assert.NoError(t, gormDB.Create(&models.ModuleVersion{ModuleID: module.ID, Version: "v1.0.0", VersionKey: validation.VersionKey("v1.0.0"), ArtifactDigest: name, ArtifactStorageKey: key}).Error)
+	}
+	publish("secret", models.VisibilityPrivate, map[string][]byte{
+		"acme/secret/v1/key.proto": []byte(`syntax = "proto3"; package acme.secret.v1; message Key { string id = 1; }`),
+	})
+	publish("billing", models.VisibilityPublic, map[string][]byte{
+		"sproto.yaml":                   []byte("name: acme/billing\ndependencies:\n  acme/secret: ^1.0.0\n"),
+		"acme/billing/v1/billing.proto": []byte(`syntax = "proto3"; package acme.billing.v1; import "acme/secret/v1/key.proto"; message Charge { acme.secret.v1.Key key = 1; int64 cents = 2; }`),
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/jsonschema", ListMessageSchemasHandler).Methods("GET")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/jsonschema/{message}", GetMessageJSONSchemaHandler).Methods("GET")
+	get := func(path string, rd reader, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/modules/acme/billing/v1.0.0/jsonschema"+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req = req.WithContext(context.WithValue(req.Context(), readerKey, rd))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The dependency is private: its messages must not leak through the schema
+	rr := get("/acme.billing.v1.Charge", reader{}, nil)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = get("", reader{admin: true}, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"namespace":"acme","module_name":"billing","version":"v1.0.0","messages":["acme.billing.v1.Charge"]}`, rr.Body.String())
+
+	rr = get("/acme.billing.v1.Charge", reader{admin: true}, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/schema+json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "acme/secret@v1.0.0", rr.Header().Get(apitypes.BundleDependenciesHeader))
+	var doc map[string]any
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+	assert.Equal(t, "#/$defs/acme.billing.v1.Charge", doc["$ref"])
+	assert.Contains(t, doc["$defs"], "acme.secret.v1.Key")
+
+	// Revalidation
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, http.StatusNotModified, get("/acme.billing.v1.Charge", reader{admin: true}, http.Header{"If-None-Match": {etag}}).Code)
+
+	rr = get("/acme.secret.v1.Key", reader{admin: true}, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":"Message 'acme.secret.v1.Key' not found in acme/billing@v1.0.0"}`, rr.Body.String())
+}
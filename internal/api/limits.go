@@ -0,0 +1,139 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// RequestLimits bounds the expensive endpoints so a burst of large uploads or downloads can't starve
+// the cheap metadata endpoints.
+type RequestLimits struct {
+	// MaxUploadBytes is the largest request body accepted by publish and impact analysis.
+	MaxUploadBytes int64
+	// MaxConcurrentPublishes bounds publishes and impact analyses in progress (0 = unlimited).
+	MaxConcurrentPublishes int
+	// MaxConcurrentArtifactStreams bounds artifact downloads streamed from storage (0 = unlimited).
+	MaxConcurrentArtifactStreams int
+	// QueueTimeout is how long a request waits for a free slot before it is rejected with 429.
+	// 0 rejects immediately when all slots are taken.
+	QueueTimeout time.Duration
+}
+
+// DefaultRequestLimits is used until SetRequestLimits is called.
+var DefaultRequestLimits = RequestLimits{
+	MaxUploadBytes: 32 << 20, // 32 MB
+	QueueTimeout:   10 * time.Second,
+}
+
+// Global limits, configured at startup via SetRequestLimits.
+var (
+	requestLimits = DefaultRequestLimits
+	publishSlots  *concurrencyLimit // nil when unlimited
+	streamSlots   *concurrencyLimit // nil when unlimited
+)
+
+// SetRequestLimits configures the upload size and concurrency limits.
+func SetRequestLimits(l RequestLimits) {
+	if l.MaxUploadBytes <= 0 {
+		l.MaxUploadBytes = DefaultRequestLimits.MaxUploadBytes
+	}
+	requestLimits = l
+	publishSlots = newConcurrencyLimit("publish", l.MaxConcurrentPublishes, l.QueueTimeout)
+	streamSlots = newConcurrencyLimit("artifact_stream", l.MaxConcurrentArtifactStreams, l.QueueTimeout)
+}
+
+// uploadLimitMessage describes the upload size limit for 413 responses.
+func uploadLimitMessage() string {
+	return fmt.Sprintf("Artifact file size exceeds limit (%s)", formatBytes(requestLimits.MaxUploadBytes))
+}
+
+// formatBytes formats a byte count as whole MB (or bytes below 1 MB).
+func formatBytes(n int64) string {
+	if n >= 1<<20 && n%(1<<20) == 0 {
+		return fmt.Sprintf("%dMB", n>>20)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// --- Concurrency Limits ---
+
+// concurrencyLimit is a semaphore with a bounded wait.
+type concurrencyLimit struct {
+	name         string // Metric label
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+func newConcurrencyLimit(name string, max int, queueTimeout time.Duration) *concurrencyLimit {
+	if max <= 0 {
+		return nil
+	}
+	return &concurrencyLimit{name: name, slots: make(chan struct{}, max), queueTimeout: queueTimeout}
+}
+
+// acquire takes a slot, waiting up to the queue timeout. If none becomes free (or the client goes away),
+// it writes a 429 response and returns false. A nil limit always succeeds.
+// Callers must call release once they are done.
+func (l *concurrencyLimit) acquire(w http.ResponseWriter, r *http.Request) bool {
+	if l == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		metrics.LimitInFlight.WithLabelValues(l.name).Inc()
+		return true
+	default:
+	}
+
+	if l.queueTimeout > 0 {
+		metrics.LimitQueued.WithLabelValues(l.name).Inc()
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		select {
+		case l.slots <- struct{}{}:
+			metrics.LimitQueued.WithLabelValues(l.name).Dec()
+			metrics.LimitInFlight.WithLabelValues(l.name).Inc()
+			return true
+		case <-timer.C:
+		case <-r.Context().Done():
+		}
+		metrics.LimitQueued.WithLabelValues(l.name).Dec()
+	}
+
+	metrics.LimitRejectionsTotal.WithLabelValues(l.name).Inc()
+	logging.FromContext(r.Context()).Warn("Concurrency limit reached, rejecting request", zap.String("limit", l.name), zap.Int("max", cap(l.slots)))
+	// Suggest retrying after about one queue timeout; slots are usually held for a similar time
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(l.queueTimeout.Seconds())))))
+	response.Error(w, http.StatusTooManyRequests, fmt.Sprintf("Too many concurrent %s requests, retry later", l.name))
+	return false
+}
+
+// release frees a slot taken by acquire.
+func (l *concurrencyLimit) release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+	metrics.LimitInFlight.WithLabelValues(l.name).Dec()
+}
+
+// LimitPublishes wraps a handler so it only runs while holding a publish slot (MaxConcurrentPublishes).
+// The limit is looked up per request, so SetRequestLimits may be called after the routes are registered.
+func LimitPublishes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := publishSlots
+		if !limit.acquire(w, r) {
+			return // 429 already written
+		}
+		defer limit.release()
+		next.ServeHTTP(w, r)
+	})
+}
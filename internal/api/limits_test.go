@@ -0,0 +1,26 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishModuleVersionHandler_UploadSizeLimit(t *testing.T) {
+	_, mock := setupMockDB(t)
+	SetRequestLimits(RequestLimits{MaxUploadBytes: 1024})
+	t.Cleanup(func() { SetRequestLimits(DefaultRequestLimits) })
+
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler)
+	router.ServeHTTP(rr, newPublishRequest(t, "my-org", "my-module", "v1.0.0", "", bytes.Repeat([]byte("x"), 2048)))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.JSONEq(t, `{"error":"Artifact file size exceeds limit (1024 bytes)"}`, rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,346 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Managed tokens: read and maintainer tokens created, replaced and revoked through the admin API, in
+// addition to the ones configured in READ_TOKENS and MAINTAINER_TOKENS. They grant the same access, but
+// live in the database, so declarative tools (e.g. a Terraform provider) can manage them by ID without a
+// restart. The registry generates the token and returns it only in the create response; only its SHA256
+// is stored, like token uses.
+//
+// ReadAuthMiddleware authenticates them from an in-memory cache: changes apply immediately on the server
+// that made them, and on other replicas at their next refresh (see RunManagedTokenRefresher).
+
+// managedTokenPrefix starts every managed token, so leaked ones are easy to recognize in scans.
+const managedTokenPrefix = "sproto_"
+
+// maxManagedTokenRequestBytes limits the JSON body of managed token requests.
+const maxManagedTokenRequestBytes = 16 * 1024
+
+// managedTokens caches the grants of the managed tokens by fingerprint (see LoadManagedTokens).
+var managedTokens struct {
+	sync.RWMutex
+	byFingerprint map[string]managedGrant
+}
+
+// managedGrant is a cached managed token.
+type managedGrant struct {
+	ID uuid.UUID
+	ReadToken
+}
+
+// --- Admin Endpoints ---
+
+// ListManagedTokensHandler lists the managed tokens.
+// GET /api/v1/admin/managed-tokens
+// Requires Authentication (admin token).
+func ListManagedTokensHandler(w http.ResponseWriter, r *http.Request) {
+	var rows []models.ManagedToken
+	if err := requestDB(r).WithContext(r.Context()).Order("created_at, id").Find(&rows).Error; err != nil {
+		logging.FromContext(r.Context()).Error("Error listing managed tokens", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve managed tokens")
+		return
+	}
+	resp := apitypes.ListManagedTokensResponse{Tokens: make([]apitypes.ManagedTokenResponse, 0, len(rows))}
+	for _, row := range rows {
+		resp.Tokens = append(resp.Tokens, managedTokenResponse(row))
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// CreateManagedTokenHandler generates a read or maintainer token. The response is the only one that
+// contains the token.
+// POST /api/v1/admin/managed-tokens
+// Requires Authentication (admin token).
+func CreateManagedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	var req apitypes.ManagedTokenRequest
+	if !decodeManagedTokenRequest(w, r, &req) {
+		return
+	}
+
+	token, err := generateManagedToken()
+	if err != nil {
+		log.Error("Error generating managed token", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+	row := models.ManagedToken{Fingerprint: tokenFingerprint(token), CreatedAt: time.Now().UTC()}
+	row.UpdatedAt = row.CreatedAt
+	applyManagedTokenRequest(&row, req)
+	if err := db.GetDB().WithContext(r.Context()).Create(&row).Error; err != nil {
+		log.Error("Error creating managed token", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error creating managed token")
+		return
+	}
+	reloadManagedTokens(r)
+	log.Info("Created managed token", zap.Stringer("token_id", row.ID), zap.String("kind", row.Kind), zap.String("consumer", row.Consumer),
+		zap.Strings("patterns", req.Patterns))
+
+	resp := managedTokenResponse(row)
+	resp.Token = token
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/admin/managed-tokens/%s", row.ID))
+	response.JSON(w, http.StatusCreated, resp)
+}
+
+// GetManagedTokenHandler returns a managed token (without the token itself).
+// GET /api/v1/admin/managed-tokens/{id}
+// Requires Authentication (admin token).
+func GetManagedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Managed token not found")
+		return
+	}
+	var row models.ManagedToken
+	err = requestDB(r).WithContext(r.Context()).Where("id = ?", id).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(w, http.StatusNotFound, "Managed token not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error loading managed token", zap.Stringer("token_id", id), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve managed token")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, http.StatusOK, managedTokenResponse(row))
+}
+
+// PutManagedTokenHandler replaces the kind, consumer, patterns, expiry and description of a managed token;
+// the token itself doesn't change. Sending the same request again changes nothing.
+// PUT /api/v1/admin/managed-tokens/{id}
+// Requires Authentication (admin token).
+func PutManagedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Managed token not found")
+		return
+	}
+	log := logging.FromContext(r.Context()).With(zap.Stringer("token_id", id))
+	var req apitypes.ManagedTokenRequest
+	if !decodeManagedTokenRequest(w, r, &req) {
+		return
+	}
+
+	var row models.ManagedToken
+	err = db.GetDB().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).First(&row).Error; err != nil {
+			return err
+		}
+		applyManagedTokenRequest(&row, req)
+		row.UpdatedAt = time.Now().UTC()
+		return tx.Select("kind", "consumer", "patterns", "description", "expires_at", "updated_at").Updates(&row).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(w, http.StatusNotFound, "Managed token not found")
+		return
+	}
+	if err != nil {
+		log.Error("Error updating managed token", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error updating managed token")
+		return
+	}
+	reloadManagedTokens(r)
+	log.Info("Updated managed token", zap.String("kind", row.Kind), zap.String("consumer", row.Consumer), zap.Strings("patterns", req.Patterns))
+	response.JSON(w, http.StatusOK, managedTokenResponse(row))
+}
+
+// DeleteManagedTokenHandler revokes a managed token.
+// DELETE /api/v1/admin/managed-tokens/{id}
+// Requires Authentication (admin token).
+func DeleteManagedTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Managed token not found")
+		return
+	}
+	result := db.GetDB().WithContext(r.Context()).Where("id = ?", id).Delete(&models.ManagedToken{})
+	if result.Error != nil {
+		logging.FromContext(r.Context()).Error("Error deleting managed token", zap.Stringer("token_id", id), zap.Error(result.Error))
+		response.Error(w, http.StatusInternalServerError, "Database error deleting managed token")
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.Error(w, http.StatusNotFound, "Managed token not found")
+		return
+	}
+	reloadManagedTokens(r)
+	logging.FromContext(r.Context()).Info("Revoked managed token", zap.Stringer("token_id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeManagedTokenRequest decodes and validates the body of a managed token request.
+// Returns false if the response has been written.
+func decodeManagedTokenRequest(w http.ResponseWriter, r *http.Request, req *apitypes.ManagedTokenRequest) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxManagedTokenRequestBytes)).Decode(req); err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return false
+	}
+	if err := validateManagedTokenRequest(req); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+// validateManagedTokenRequest checks the kind, the consumer name and the patterns, and drops blank and
+// duplicate patterns.
+func validateManagedTokenRequest(req *apitypes.ManagedTokenRequest) error {
+	addPattern := addModulePattern
+	switch req.Kind {
+	case apitypes.ManagedTokenRead:
+	case apitypes.ManagedTokenMaintainer:
+		addPattern = addNamespacePattern
+	default:
+		return fmt.Errorf("invalid token kind %q: must be %s or %s", req.Kind, apitypes.ManagedTokenRead, apitypes.ManagedTokenMaintainer)
+	}
+	if req.Consumer != "" && !consumerPattern.MatchString(req.Consumer) {
+		return fmt.Errorf("invalid consumer name %q: letters, digits, '.', '_' and '-' only, at most 128 characters", req.Consumer)
+	}
+	patterns := make([]string, 0, len(req.Patterns))
+	seen := map[string]bool{}
+	for _, pattern := range req.Patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || seen[pattern] {
+			continue
+		}
+		if err := addPattern(&ReadToken{}, pattern); err != nil {
+			return err
+		}
+		seen[pattern] = true
+		patterns = append(patterns, pattern)
+	}
+	req.Patterns = patterns
+	return nil
+}
+
+// applyManagedTokenRequest copies a validated request onto a managed token row.
+func applyManagedTokenRequest(row *models.ManagedToken, req apitypes.ManagedTokenRequest) {
+	row.Kind = req.Kind
+	row.Consumer = req.Consumer
+	row.Patterns = strings.Join(req.Patterns, "\n")
+	row.Description = req.Description
+	row.ExpiresAt = nil
+	if req.ExpiresAt != nil {
+		expiresAt := req.ExpiresAt.UTC()
+		row.ExpiresAt = &expiresAt
+	}
+}
+
+// generateManagedToken returns a new random token.
+func generateManagedToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return managedTokenPrefix + hex.EncodeToString(secret), nil
+}
+
+func managedTokenResponse(row models.ManagedToken) apitypes.ManagedTokenResponse {
+	resp := apitypes.ManagedTokenResponse{
+		ID:          row.ID,
+		Kind:        row.Kind,
+		Consumer:    row.Consumer,
+		Patterns:    []string{}, // Empty array, not null
+		Description: row.Description,
+		ExpiresAt:   row.ExpiresAt,
+		Expired:     tokenExpired(row.ExpiresAt),
+		Fingerprint: row.Fingerprint[:12],
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+	}
+	if row.Patterns != "" {
+		resp.Patterns = strings.Split(row.Patterns, "\n")
+	}
+	return resp
+}
+
+// --- Authentication Cache ---
+
+// LoadManagedTokens loads the managed tokens into the cache ReadAuthMiddleware authenticates them from.
+func LoadManagedTokens(ctx context.Context) error {
+	var rows []models.ManagedToken
+	if err := db.GetDB().WithContext(ctx).Find(&rows).Error; err != nil {
+		return err
+	}
+	byFingerprint := make(map[string]managedGrant, len(rows))
+	for _, row := range rows {
+		byFingerprint[row.Fingerprint] = managedGrant{ID: row.ID, ReadToken: managedReadToken(row)}
+	}
+	managedTokens.Lock()
+	managedTokens.byFingerprint = byFingerprint
+	managedTokens.Unlock()
+	return nil
+}
+
+// RunManagedTokenRefresher reloads the managed tokens every interval until ctx is canceled, so tokens
+// created or revoked through another replica are picked up.
+func RunManagedTokenRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := LoadManagedTokens(ctx); err != nil {
+				logging.L().Warn("Failed to reload managed tokens", zap.Error(err))
+			}
+		}
+	}
+}
+
+// reloadManagedTokens applies a change made by a request. The change is saved either way; if the reload
+// fails, it applies at the next refresh.
+func reloadManagedTokens(r *http.Request) {
+	if err := LoadManagedTokens(r.Context()); err != nil {
+		logging.FromContext(r.Context()).Warn("Failed to reload managed tokens", zap.Error(err))
+	}
+}
+
+// managedReadToken returns the grants of a managed token. Its patterns were validated when it was saved.
+func managedReadToken(row models.ManagedToken) ReadToken {
+	rt := ReadToken{Patterns: []string{}, ExpiresAt: row.ExpiresAt, Consumer: row.Consumer}
+	addPattern := addModulePattern
+	if row.Kind == apitypes.ManagedTokenMaintainer {
+		rt.Namespaces, addPattern = []string{}, addNamespacePattern
+	}
+	if row.Patterns != "" {
+		for _, pattern := range strings.Split(row.Patterns, "\n") {
+			_ = addPattern(&rt, pattern)
+		}
+	}
+	return rt
+}
+
+// managedTokenGrants returns the cached managed tokens by fingerprint. The map is replaced, never modified,
+// by reloads.
+func managedTokenGrants() map[string]managedGrant {
+	managedTokens.RLock()
+	defer managedTokens.RUnlock()
+	return managedTokens.byFingerprint
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagedTokens(t *testing.T) {
+	newTestDB(t)
+	t.Cleanup(func() { managedTokens.byFingerprint = nil })
+	SetTokenPolicy(TokenPolicy{StaleAfter: 24 * time.Hour})
+	t.Cleanup(func() { SetTokenPolicy(DefaultTokenPolicy) })
+
+	admin := mux.NewRouter()
+	admin.HandleFunc("/api/v1/admin/managed-tokens", ListManagedTokensHandler).Methods("GET")
+	admin.HandleFunc("/api/v1/admin/managed-tokens", CreateManagedTokenHandler).Methods("POST")
+	admin.HandleFunc("/api/v1/admin/managed-tokens/{id}", GetManagedTokenHandler).Methods("GET")
+	admin.HandleFunc("/api/v1/admin/managed-tokens/{id}", PutManagedTokenHandler).Methods("PUT")
+	admin.HandleFunc("/api/v1/admin/managed-tokens/{id}", DeleteManagedTokenHandler).Methods("DELETE")
+	serveAdmin := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		admin.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	// Managed tokens authenticate like configured ones
+	reads := mux.NewRouter()
+	reads.Use(ReadAuthMiddleware("admin-token"))
+	reads.HandleFunc("/api/v1/namespaces/{namespace}/webhooks", ListWebhookSubscriptionsHandler).Methods("GET")
+	listWebhooks := func(namespace, token string) int {
+		req := httptest.NewRequest("GET", "/api/v1/namespaces/"+namespace+"/webhooks", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		reads.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusBadRequest, serveAdmin("POST", "/api/v1/admin/managed-tokens", `{"kind":"admin"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveAdmin("POST", "/api/v1/admin/managed-tokens", `{"kind":"read","patterns":["payments"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveAdmin("POST", "/api/v1/admin/managed-tokens", `{"kind":"maintainer","patterns":["payments/*"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serveAdmin("POST", "/api/v1/admin/managed-tokens", `{"kind":"read","consumer":"ci team"}`).Code)
+
+	rr := serveAdmin("POST", "/api/v1/admin/managed-tokens", `{"kind":"maintainer","consumer":"payments-team","patterns":["payments"," ","payments"],"description":"Terraform"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created apitypes.ManagedTokenResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "/api/v1/admin/managed-tokens/"+created.ID.String(), rr.Header().Get("Location"))
+	assert.True(t, strings.HasPrefix(created.Token, managedTokenPrefix))
+	assert.Equal(t, tokenFingerprint(created.Token)[:12], created.Fingerprint)
+	assert.Equal(t, []string{"payments"}, created.Patterns)
+	assert.Equal(t, http.StatusOK, listWebhooks("payments", created.Token))
+	assert.Equal(t, http.StatusForbidden, listWebhooks("billing", created.Token))
+	assert.True(t, isReadToken(created.Token)) // Rejected by ApplyAuth on write endpoints
+
+	// The token is only returned once
+	tokenPath := "/api/v1/admin/managed-tokens/" + created.ID.String()
+	rr = serveAdmin("GET", tokenPath, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), created.Token)
+	assert.Contains(t, rr.Body.String(), `"consumer":"payments-team"`)
+	assert.Equal(t, http.StatusNotFound, serveAdmin("GET", "/api/v1/admin/managed-tokens/"+uuid.NewString(), "").Code)
+
+	// Replacing the grants keeps the token; repeating the request changes nothing
+	replacement := `{"kind":"maintainer","patterns":["billing"],"expires_at":"2000-01-01T00:00:00Z"}`
+	for i := 0; i < 2; i++ {
+		rr = serveAdmin("PUT", tokenPath, replacement)
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var updated apitypes.ManagedTokenResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &updated))
+	assert.Equal(t, created.Fingerprint, updated.Fingerprint)
+	assert.Empty(t, updated.Consumer)
+	assert.Empty(t, updated.Token)
+	assert.True(t, updated.Expired)
+	assert.Equal(t, http.StatusUnauthorized, listWebhooks("billing", created.Token)) // Expired
+	assert.Equal(t, http.StatusOK, serveAdmin("PUT", tokenPath, `{"kind":"maintainer","patterns":["billing"]}`).Code)
+	assert.Equal(t, http.StatusOK, listWebhooks("billing", created.Token))
+	assert.Equal(t, http.StatusForbidden, listWebhooks("payments", created.Token))
+
+	rr = serveAdmin("GET", "/api/v1/admin/managed-tokens", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list apitypes.ListManagedTokensResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	if assert.Len(t, list.Tokens, 1) {
+		assert.Equal(t, []string{"billing"}, list.Tokens[0].Patterns)
+	}
+
+	// The token report lists managed tokens with their ID
+	tokenUsage = newTokenUsageTracker()
+	reportRR := httptest.NewRecorder()
+	TokenReportHandler("").ServeHTTP(reportRR, httptest.NewRequest("GET", "/api/v1/admin/tokens", nil))
+	var report apitypes.TokenReportResponse
+	assert.NoError(t, json.Unmarshal(reportRR.Body.Bytes(), &report))
+	if assert.Len(t, report.Tokens, 1) {
+		assert.Equal(t, "maintainer", report.Tokens[0].Kind)
+		assert.Equal(t, "token:"+created.Fingerprint, report.Tokens[0].Consumer)
+		assert.Equal(t, &created.ID, report.Tokens[0].ManagedID)
+	}
+
+	// Revoked tokens are rejected at once
+	assert.Equal(t, http.StatusNoContent, serveAdmin("DELETE", tokenPath, "").Code)
+	assert.Equal(t, http.StatusNotFound, serveAdmin("DELETE", tokenPath, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, listWebhooks("billing", created.Token))
+	assert.False(t, isReadToken(created.Token))
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Maven bridge: generated Java stubs are built by CI (protoc --java_out, compiled and packaged) and attached
+// to versions as the secondary artifact "maven-jar", optionally with the POM declaring their dependencies as
+// "maven-pom". With a group prefix configured (MAVEN_GROUP_PREFIX), the registry serves them as a Maven
+// repository under /maven, so Java services depend on schema updates like on any other library:
+//
+//	<groupId>com.example.protos.acme</groupId>  (prefix + "." + namespace)
+//	<artifactId>billing</artifactId>             (module name)
+//	<version>1.2.0</version>                     (registry version without the "v")
+//
+// Only versions with a jar attached are versions of the Maven artifact. Checksum files (.md5, .sha1,
+// .sha256, .sha512) are computed on request.
+
+// Secondary artifact classifiers of generated Java stubs.
+const (
+	MavenJarClassifier = "maven-jar"
+	MavenPOMClassifier = "maven-pom" // Optional; a POM without dependencies is served otherwise
+)
+
+// MavenPathPrefix is where the Maven repository is served (the repository URL is the registry's plus this).
+const MavenPathPrefix = "/maven"
+
+// mavenGroupPrefix is the groupId prefix of the Maven bridge; "" if it is disabled. See SetMavenGroupPrefix.
+var mavenGroupPrefix string
+
+// groupIDPattern matches Maven groupIds as the bridge accepts them: lowercase, dot-separated.
+var groupIDPattern = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*$`)
+
+// mavenChecksums are the checksum files Maven clients request next to each file.
+var mavenChecksums = map[string]func() hash.Hash{".md5": md5.New, ".sha1": sha1.New, ".sha256": sha256.New, ".sha512": sha512.New}
+
+// MavenMetadata is the maven-metadata.xml document of an artifact.
+type MavenMetadata struct {
+	XMLName    xml.Name        `xml:"metadata"`
+	GroupID    string          `xml:"groupId"`
+	ArtifactID string          `xml:"artifactId"`
+	Versioning MavenVersioning `xml:"versioning"`
+}
+
+// MavenVersioning lists the versions of an artifact in maven-metadata.xml.
+type MavenVersioning struct {
+	Latest      string   `xml:"latest"`
+	Release     string   `xml:"release,omitempty"`
+	Versions    []string `xml:"versions>version"`
+	LastUpdated string   `xml:"lastUpdated"` // yyyyMMddHHmmss, UTC
+}
+
+// mavenPOM is the POM served for versions without a "maven-pom" artifact.
+type mavenPOM struct {
+	XMLName      xml.Name `xml:"project"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	ModelVersion string   `xml:"modelVersion"`
+	GroupID      string   `xml:"groupId"`
+	ArtifactID   string   `xml:"artifactId"`
+	Version      string   `xml:"version"`
+	Packaging    string   `xml:"packaging"`
+}
+
+// SetMavenGroupPrefix configures the Maven bridge: the groupId prefix of bridged modules (e.g.
+// com.example.protos). An empty prefix disables the bridge.
+func SetMavenGroupPrefix(prefix string) error {
+	prefix = strings.TrimSpace(prefix)
+	if prefix != "" && !groupIDPattern.MatchString(prefix) {
+		return fmt.Errorf("invalid Maven group prefix %q: must be a lowercase groupId, e.g. com.example.protos", prefix)
+	}
+	mavenGroupPrefix = prefix
+	return nil
+}
+
+// mavenFile is a file of the Maven repository, resolved to the module it belongs to.
+type mavenFile struct {
+	namespace  string
+	name       string
+	groupID    string
+	artifactID string
+	version    string // Maven version; "" for maven-metadata.xml
+	file       string // File name without the checksum extension
+	checksum   string // Checksum extension (see mavenChecksums); "" for the file itself
+}
+
+// parseMavenPath resolves a path of the Maven repository:
+// <group dirs>/<artifactId>/maven-metadata.xml or <group dirs>/<artifactId>/<version>/<file>, where the
+// group dirs are those of the prefix followed by the namespace's.
+func parseMavenPath(p string) (*mavenFile, error) {
+	parts := strings.Split(p, "/")
+	if slices.Contains(parts, "") {
+		return nil, fmt.Errorf("invalid path %s", p)
+	}
+	prefixDirs := strings.Split(mavenGroupPrefix, ".")
+	if len(parts) < len(prefixDirs)+3 || strings.Join(parts[:len(prefixDirs)], ".") != mavenGroupPrefix {
+		return nil, fmt.Errorf("%s is not an artifact under group %s", p, mavenGroupPrefix)
+	}
+	f := &mavenFile{file: parts[len(parts)-1]}
+	for extension := range mavenChecksums {
+		if base, ok := strings.CutSuffix(f.file, extension); ok {
+			f.file, f.checksum = base, extension
+		}
+	}
+	dirs := parts[len(prefixDirs) : len(parts)-1] // Namespace, artifactId and version
+	if f.file != "maven-metadata.xml" {
+		if len(dirs) < 3 {
+			return nil, fmt.Errorf("%s is not an artifact under group %s", p, mavenGroupPrefix)
+		}
+		f.version, dirs = dirs[len(dirs)-1], dirs[:len(dirs)-1]
+	}
+	f.namespace, f.name = strings.Join(dirs[:len(dirs)-1], "."), dirs[len(dirs)-1]
+	f.groupID, f.artifactID = mavenGroupPrefix+"."+f.namespace, f.name
+	return f, nil
+}
+
+// MavenRepositoryHandler serves the Maven repository of generated Java stubs: the maven-metadata.xml of
+// each artifact, the jar and POM of each version, and their checksums.
+// GET|HEAD /maven/{path}
+func MavenRepositoryHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	if mavenGroupPrefix == "" {
+		response.Error(w, http.StatusNotFound, "Maven bridge is not enabled")
+		return
+	}
+	f, err := parseMavenPath(mux.Vars(r)["path"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, err.Error())
+		return
+	}
+	readable, err := moduleReadable(r, f.namespace, f.name)
+	if err != nil {
+		log.Error("Error checking module visibility", zap.String("namespace", f.namespace), zap.String("module", f.name), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module")
+		return
+	}
+	if !readable {
+		response.Error(w, http.StatusNotFound, "Module not found") // Don't reveal that it exists
+		return
+	}
+
+	// --- maven-metadata.xml ---
+	if f.version == "" {
+		metadata, ok := mavenMetadata(w, r, log, f)
+		if !ok {
+			return // Response already written
+		}
+		setListCacheHeaders(w) // Changes when a jar is attached
+		writeMavenFile(w, f, "application/xml", metadata)
+		return
+	}
+
+	moduleVersion, ok := findModuleVersion(w, r, f.namespace, f.name, "v"+f.version)
+	if !ok {
+		return // Response already written
+	}
+	if sunsetGone(w, r, moduleVersion) {
+		return // 410 already written
+	}
+	filename := f.artifactID + "-" + f.version
+	switch f.file {
+	case filename + ".jar":
+		if f.checksum == "" {
+			recordFetch(r, moduleVersion.ID) // Consumption report
+			writeVersionArtifact(w, r, log, moduleVersion, MavenJarClassifier, "No Maven jar for this version", f.file)
+			return
+		}
+		jar, ok := findMavenJar(w, r, log, moduleVersion)
+		if !ok {
+			return // Response already written
+		}
+		if f.checksum == ".sha256" {
+			setImmutableCacheHeaders(w)
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = w.Write([]byte(jar.Digest)) // Recorded when it was attached
+			return
+		}
+		content, ok := readVersionArtifact(w, r, log, jar)
+		if !ok {
+			return // Response already written
+		}
+		setImmutableCacheHeaders(w)
+		writeMavenFile(w, f, "", content)
+	case filename + ".pom":
+		content, ok := mavenPOMContent(w, r, log, f, moduleVersion)
+		if !ok {
+			return // Response already written
+		}
+		setImmutableCacheHeaders(w)
+		writeMavenFile(w, f, "application/xml", content)
+	default:
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("No file %s in %s:%s:%s", f.file, f.groupID, f.artifactID, f.version))
+	}
+}
+
+// writeMavenFile writes a file of the Maven repository, or its checksum if one was requested.
+func writeMavenFile(w http.ResponseWriter, f *mavenFile, contentType string, content []byte) {
+	if f.checksum != "" {
+		h := mavenChecksums[f.checksum]()
+		h.Write(content)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(hex.EncodeToString(h.Sum(nil))))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(content)
+}
+
+// mavenMetadata builds the maven-metadata.xml of an artifact from the versions with a jar attached. On
+// failure a response has been written and ok is false.
+func mavenMetadata(w http.ResponseWriter, r *http.Request, log *zap.Logger, f *mavenFile) ([]byte, bool) {
+	versions, err := versionsWithArtifact(r, f.namespace, f.name, MavenJarClassifier)
+	if err != nil {
+		log.Error("Error listing Maven versions", zap.String("namespace", f.namespace), zap.String("module", f.name), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module versions")
+		return nil, false
+	}
+	if len(versions) == 0 {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("No versions of %s:%s", f.groupID, f.artifactID))
+		return nil, false
+	}
+	metadata := MavenMetadata{GroupID: f.groupID, ArtifactID: f.artifactID}
+	lastUpdated := versions[0].AttachedAt
+	for _, v := range versions {
+		version := strings.TrimPrefix(v.Version, "v")
+		metadata.Versioning.Versions = append(metadata.Versioning.Versions, version)
+		metadata.Versioning.Latest = version
+		if v.semver.Prerelease() == "" {
+			metadata.Versioning.Release = version
+		}
+		if v.AttachedAt.After(lastUpdated) {
+			lastUpdated = v.AttachedAt
+		}
+	}
+	metadata.Versioning.LastUpdated = lastUpdated.UTC().Format("20060102150405")
+	content, err := xml.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		log.Error("Error encoding Maven metadata", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to encode Maven metadata")
+		return nil, false
+	}
+	return append([]byte(xml.Header), content...), true
+}
+
+// mavenPOMContent returns the POM of a version: the "maven-pom" artifact attached to it, else a POM without
+// dependencies if a jar is attached. On failure a response has been written and ok is false.
+func mavenPOMContent(w http.ResponseWriter, r *http.Request, log *zap.Logger, f *mavenFile, moduleVersion *models.ModuleVersion) ([]byte, bool) {
+	pom, err := findVersionArtifact(r.Context(), requestDB(r), moduleVersion, MavenPOMClassifier)
+	if err == nil {
+		return readVersionArtifact(w, r, log, pom)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error("Error finding Maven POM", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve artifact")
+		return nil, false
+	}
+	if _, ok := findMavenJar(w, r, log, moduleVersion); !ok {
+		return nil, false // Response already written
+	}
+	content, err := xml.MarshalIndent(mavenPOM{
+		Xmlns:        "http://maven.apache.org/POM/4.0.0",
+		ModelVersion: "4.0.0",
+		GroupID:      f.groupID,
+		ArtifactID:   f.artifactID,
+		Version:      f.version,
+		Packaging:    "jar",
+	}, "", "  ")
+	if err != nil {
+		log.Error("Error encoding Maven POM", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to encode Maven POM")
+		return nil, false
+	}
+	return append([]byte(xml.Header), content...), true
+}
+
+// findMavenJar finds the jar attached to a version. On failure a response has been written (404 if there is
+// none) and ok is false.
+func findMavenJar(w http.ResponseWriter, r *http.Request, log *zap.Logger, moduleVersion *models.ModuleVersion) (*models.VersionArtifact, bool) {
+	jar, err := findVersionArtifact(r.Context(), requestDB(r), moduleVersion, MavenJarClassifier)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.Error(w, http.StatusNotFound, "No Maven jar for this version")
+		return nil, false
+	case err != nil:
+		log.Error("Error finding Maven jar", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve artifact")
+		return nil, false
+	}
+	return jar, true
+}
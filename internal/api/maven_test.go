@@ -0,0 +1,170 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageBridges(t *testing.T) {
+	gormDB := newTestDB(t)
+	provider := newTestStorage(t)
+
+	assert.Error(t, SetMavenGroupPrefix("Com.Example"))
+	assert.NoError(t, SetMavenGroupPrefix("com.example.protos"))
+	t.Cleanup(func() { _ = SetMavenGroupPrefix("") })
+	assert.Error(t, SetNPMScope("@Protos"))
+	assert.NoError(t, SetNPMScope("protos"))
+	t.Cleanup(func() { _ = SetNPMScope("") })
+
+	versions := map[string]*models.ModuleVersion{}
+	publish := func(name, visibility string, version string) {
+		var module models.Module
+		assert.NoError(t, gormDB.Where(models.Module{Namespace: "acme", Name: name}).Attrs(models.Module{Visibility: visibility}).FirstOrCreate(&module).Error)
+		moduleVersion := &models.ModuleVersion{ModuleID: module.ID, Version: version, VersionKey: validation.VersionKey(version), ArtifactDigest: "digest", ArtifactStorageKey: "key"}
+		assert.NoError(t, gormDB.Create(moduleVersion).Error)
+		versions[name+"@"+version] = moduleVersion
+	}
+	attach := func(name, version, classifier string, data []byte) string {
+		sum := sha256.Sum256(data)
+		key := "acme/" + name + "/" + version + "/" + classifier
+		assert.NoError(t, provider.UploadFile(context.Background(), key, bytes.NewReader(data), int64(len(data)), "application/octet-stream"))
+		assert.NoError(t, gormDB.Create(&models.VersionArtifact{
+			ModuleVersionID: versions[name+"@"+version].ID, Classifier: classifier, ContentType: "application/octet-stream",
+			Digest: hex.EncodeToString(sum[:]), Size: int64(len(data)), StorageKey: key, CreatedAt: time.Now(),
+		}).Error)
+		return hex.EncodeToString(sum[:])
+	}
+	tarball := func(packageJSON string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "package/package.json", Mode: 0o644, Size: int64(len(packageJSON))}))
+		_, _ = tw.Write([]byte(packageJSON))
+		assert.NoError(t, tw.Close())
+		assert.NoError(t, gz.Close())
+		return buf.Bytes()
+	}
+	for _, version := range []string{"v0.9.0", "v1.0.0", "v1.1.0-rc.1", "v1.1.0+build.7"} {
+		publish("billing", models.VisibilityPublic, version)
+	}
+	publish("secret", models.VisibilityPrivate, "v1.0.0")
+	jar := []byte("PK jar of v1.0.0")
+	jarDigest := attach("billing", "v1.0.0", MavenJarClassifier, jar)
+	attach("billing", "v1.1.0-rc.1", MavenJarClassifier, []byte("PK jar of v1.1.0-rc.1"))
+	pom := []byte("<project><dependencies/></project>")
+	attach("billing", "v1.1.0-rc.1", MavenPOMClassifier, pom)
+	attach("secret", "v1.0.0", MavenJarClassifier, []byte("PK"))
+	npmPackage := tarball(`{"name": "@protos/acme_billing", "version": "1.0.0", "dependencies": {"@bufbuild/protobuf": "^2.0.0"}}`)
+	npmDigest := attach("billing", "v1.0.0", NPMPackageClassifier, npmPackage)
+	attach("billing", "v1.1.0-rc.1", NPMPackageClassifier, tarball(`{"name": "@protos/acme_billing", "version": "1.1.0-rc.1"}`))
+	attach("billing", "v1.1.0+build.7", NPMPackageClassifier, tarball(`{}`)) // npm ignores build metadata
+	now := time.Now()
+	assert.NoError(t, gormDB.Model(versions["billing@v1.0.0"]).Updates(models.ModuleVersion{DeprecatedAt: &now, DeprecationMessage: "Use 1.1.0"}).Error)
+
+	router := mux.NewRouter()
+	router.HandleFunc(MavenPathPrefix+"/{path:.+}", MavenRepositoryHandler)
+	router.HandleFunc(NPMPathPrefix+"/{scope:@[^/]+}/{package}", NPMPackumentHandler)
+	router.HandleFunc(NPMPathPrefix+"/{scope:@[^/]+}/{package}/-/{tarball}", NPMTarballHandler)
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req = req.WithContext(context.WithValue(req.Context(), readerKey, reader{}))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	checksum := func(h hash.Hash, data []byte) string {
+		h.Write(data)
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	// Maven: versions with a jar attached, under the namespace's group
+	rr := get("/maven/com/example/protos/acme/billing/maven-metadata.xml")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var metadata MavenMetadata
+	assert.NoError(t, xml.Unmarshal(rr.Body.Bytes(), &metadata))
+	assert.Equal(t, "com.example.protos.acme", metadata.GroupID)
+	assert.Equal(t, []string{"1.0.0", "1.1.0-rc.1"}, metadata.Versioning.Versions)
+	assert.Equal(t, "1.1.0-rc.1", metadata.Versioning.Latest)
+	assert.Equal(t, "1.0.0", metadata.Versioning.Release)
+	assert.Equal(t, checksum(sha1.New(), rr.Body.Bytes()), get("/maven/com/example/protos/acme/billing/maven-metadata.xml.sha1").Body.String())
+
+	rr = get("/maven/com/example/protos/acme/billing/1.0.0/billing-1.0.0.jar")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, jar, rr.Body.Bytes())
+	assert.Equal(t, checksum(sha1.New(), jar), get("/maven/com/example/protos/acme/billing/1.0.0/billing-1.0.0.jar.sha1").Body.String())
+	assert.Equal(t, checksum(md5.New(), jar), get("/maven/com/example/protos/acme/billing/1.0.0/billing-1.0.0.jar.md5").Body.String())
+	assert.Equal(t, jarDigest, get("/maven/com/example/protos/acme/billing/1.0.0/billing-1.0.0.jar.sha256").Body.String())
+
+	// The attached POM, else one without dependencies
+	assert.Equal(t, pom, get("/maven/com/example/protos/acme/billing/1.1.0-rc.1/billing-1.1.0-rc.1.pom").Body.Bytes())
+	rr = get("/maven/com/example/protos/acme/billing/1.0.0/billing-1.0.0.pom")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "<groupId>com.example.protos.acme</groupId>")
+	assert.Contains(t, rr.Body.String(), "<version>1.0.0</version>")
+
+	for _, path := range []string{
+		"/maven/com/example/protos/acme/billing/0.9.0/billing-0.9.0.pom", // No jar attached
+		"/maven/com/example/protos/acme/billing/1.0.0/billing-1.0.0.war", // Not a file of the repository
+		"/maven/com/example/protos/acme/billing/1.0.0/other-1.0.0.jar",   // Wrong artifactId
+		"/maven/com/example/acme/billing/maven-metadata.xml",             // Not under the group prefix
+		"/maven/com/example/protos/acme/secret/maven-metadata.xml",       // Not readable
+		"/maven/com/example/protos/acme/missing/maven-metadata.xml",      // No such module
+	} {
+		assert.Equal(t, http.StatusNotFound, get(path).Code, path)
+	}
+
+	// npm: the packument lists the versions with a package attached, with their dependencies and deprecation
+	rr = get("/npm/@protos%2facme_billing") // npm escapes the slash of scoped names
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var packument NPMPackument
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &packument))
+	assert.Equal(t, "@protos/acme_billing", packument.Name)
+	assert.Equal(t, map[string]string{"latest": "1.0.0"}, packument.DistTags)
+	assert.Len(t, packument.Versions, 2)
+	release := packument.Versions["1.0.0"]
+	assert.Equal(t, map[string]string{"@bufbuild/protobuf": "^2.0.0"}, release.Dependencies)
+	assert.Equal(t, "Use 1.1.0", release.Deprecated)
+	assert.Equal(t, "http://example.com/npm/@protos/acme_billing/-/acme_billing-1.0.0.tgz", release.Dist.Tarball)
+	digest, _ := hex.DecodeString(npmDigest)
+	assert.Equal(t, "sha256-"+base64.StdEncoding.EncodeToString(digest), release.Dist.Integrity)
+	assert.Empty(t, packument.Versions["1.1.0-rc.1"].Deprecated)
+
+	rr = get("/npm/@protos/acme_billing/-/acme_billing-1.0.0.tgz")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, npmPackage, rr.Body.Bytes())
+
+	for _, path := range []string{
+		"/npm/@other/acme_billing",                           // Other scope
+		"/npm/@protos/billing",                               // No namespace
+		"/npm/@protos/acme_secret",                           // Not readable
+		"/npm/@protos/acme_billing/-/acme_billing-0.9.0.tgz", // No package attached
+		"/npm/@protos/acme_billing/-/billing-1.0.0.tgz",      // Wrong tarball name
+	} {
+		assert.Equal(t, http.StatusNotFound, get(path).Code, path)
+	}
+
+	// Disabled bridges serve nothing
+	assert.NoError(t, SetMavenGroupPrefix(""))
+	assert.NoError(t, SetNPMScope(""))
+	assert.Equal(t, http.StatusNotFound, get("/maven/com/example/protos/acme/billing/maven-metadata.xml").Code)
+	assert.Equal(t, http.StatusNotFound, get("/npm/@protos/acme_billing").Code)
+}
@@ -2,43 +2,172 @@ package api
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	"runtime/debug"
 	"strings"
+	"time"
 
 	"github.com/Suhaibinator/SProto/internal/api/response" // We'll create this package next
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 // contextKey is a custom type used for context keys to avoid collisions.
 type contextKey string
 
-const isAuthenticatedKey contextKey = "isAuthenticated"
+const (
+	isAuthenticatedKey contextKey = "isAuthenticated"
+	requestIDKey       contextKey = "requestID"
+	readerKey          contextKey = "reader" // Read authorization identity (see ReadAuthMiddleware)
+)
+
+// statusRecorder wraps http.ResponseWriter to capture the status code and bytes written.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK // Implicit WriteHeader
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += int64(n)
+	return n, err
+}
+
+// Flush lets streaming handlers flush through the recorder.
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// RequestLoggingMiddleware assigns each request an ID (reusing a client-provided X-Request-ID if present),
+// stores a request-scoped logger carrying per-request fields in the context, and logs the completed request.
+func RequestLoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(apitypes.RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(apitypes.RequestIDHeader, requestID)
+
+		log := logging.L().With(
+			zap.String("request_id", requestID),
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("remote_addr", r.RemoteAddr),
+		)
+
+		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+		ctx = logging.WithLogger(ctx, log)
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK // Handler wrote nothing
+		}
+		log.Info("Request completed",
+			zap.Int("status", recorder.status),
+			zap.Int64("bytes", recorder.bytes),
+			zap.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// RecoveryMiddleware catches panics raised by downstream handlers (including panics while reading the
+// request body), logs them with the stack trace and request ID, and returns a 500 JSON error so clients
+// get a proper response instead of a dropped connection.
+// It must be registered after RequestLoggingMiddleware so the request-scoped logger is available.
+func RecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// http.ErrAbortHandler is the sanctioned way to abort a response; let net/http handle it.
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(rec)
+			}
+
+			logging.FromContext(r.Context()).Error("Recovered from panic in handler",
+				zap.String("panic", fmt.Sprint(rec)),
+				zap.ByteString("stack", debug.Stack()),
+			)
+
+			if recorder.status != 0 {
+				// Headers (and maybe part of the body) are already sent; the best we can do is stop here.
+				return
+			}
+			response.Error(recorder, http.StatusInternalServerError, "Internal server error")
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// RequestIDFromContext returns the request ID assigned by RequestLoggingMiddleware, or "" if none.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
 
 // AuthMiddleware creates a middleware function that checks for a static bearer token.
 func AuthMiddleware(requiredToken string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Check if token is provided and valid
+			// Clients banned after repeated failures can't authenticate for a while
+			if rejectBannedClient(w, r) {
+				return
+			}
+
+			// Check if token is provided and valid (failures are logged to the audit log by rejectAuth)
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				log.Println("AuthMiddleware: Missing Authorization header")
-				response.Error(w, http.StatusUnauthorized, "Unauthorized: Missing Authorization header")
+				rejectAuth(w, r, "missing_header", "", "Unauthorized: Missing Authorization header")
 				return
 			}
 
 			parts := strings.Split(authHeader, " ")
 			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				log.Println("AuthMiddleware: Invalid Authorization header format")
-				response.Error(w, http.StatusUnauthorized, "Unauthorized: Invalid Authorization header format")
+				rejectAuth(w, r, "invalid_format", "", "Unauthorized: Invalid Authorization header format")
 				return
 			}
 
 			token := parts[1]
-			if token != requiredToken {
-				log.Println("AuthMiddleware: Invalid token")
-				response.Error(w, http.StatusUnauthorized, "Unauthorized: Invalid token")
+			valid := tokensEqual(token, requiredToken)
+			if !valid && isReadToken(token) {
+				// A valid identity, just not allowed to write: not counted as an authentication failure
+				response.Error(w, http.StatusForbidden, "Forbidden: Read tokens can't be used for write operations")
+				return
+			}
+			if !valid {
+				rejectAuth(w, r, "invalid_token", token, "Unauthorized: Invalid token")
+				return
+			}
+			if tokenExpired(tokenPolicy.AdminExpiresAt) {
+				rejectAuth(w, r, "expired_token", token, "Unauthorized: Token expired")
 				return
 			}
+			acceptAuth(r)
+			acceptToken(w, token, tokenPolicy.AdminExpiresAt)
 
 			// Token is valid, proceed to the next handler
 			// Optionally, add user info to context if using more complex auth
@@ -49,12 +178,13 @@ func AuthMiddleware(requiredToken string) func(http.Handler) http.Handler {
 }
 
 // ApplyAuth selectively applies the authentication middleware only if the token is not empty.
-// If the token is empty, it allows all requests through for that handler.
+// If the token is empty, it allows all requests through for that handler. The write allowlist
+// (see RestrictWriteNetworks) applies either way, before authentication.
 func ApplyAuth(handler http.Handler, requiredToken string) http.Handler {
 	if requiredToken == "" {
-		log.Println("Warning: Auth token is empty, authentication is disabled for protected routes.")
-		return handler // No auth required if token is not set
+		logging.L().Warn("Auth token is empty, authentication is disabled for protected routes.")
+		return RestrictWriteNetworks(handler) // No auth required if token is not set
 	}
 	authMiddleware := AuthMiddleware(requiredToken)
-	return authMiddleware(handler)
+	return RestrictWriteNetworks(authMiddleware(handler))
 }
@@ -0,0 +1,298 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for RequestLoggingMiddleware ---
+
+func TestRequestLoggingMiddleware_GeneratesRequestID(t *testing.T) {
+	var seenID string
+	handler := RequestLoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenID = RequestIDFromContext(r.Context())
+		assert.NotNil(t, logging.FromContext(r.Context()))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest("GET", "/api/v1/modules", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.NotEmpty(t, seenID)
+	assert.Equal(t, seenID, rr.Header().Get(apitypes.RequestIDHeader))
+}
+
+func TestRequestLoggingMiddleware_PropagatesClientRequestID(t *testing.T) {
+	var seenID string
+	handler := RequestLoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenID = RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	req.Header.Set(apitypes.RequestIDHeader, "ci-run-42")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, "ci-run-42", seenID)
+	assert.Equal(t, "ci-run-42", rr.Header().Get(apitypes.RequestIDHeader))
+}
+
+// --- Tests for RecoveryMiddleware ---
+
+func TestRecoveryMiddleware_ReturnsJSONError(t *testing.T) {
+	handler := RequestLoggingMiddleware(RecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})))
+
+	req := httptest.NewRequest("POST", "/api/v1/modules/my-org/my-module/v1.0.0", nil)
+	rr := httptest.NewRecorder()
+	assert.NotPanics(t, func() { handler.ServeHTTP(rr, req) })
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.JSONEq(t, `{"error":"Internal server error"}`, rr.Body.String())
+	assert.NotEmpty(t, rr.Header().Get(apitypes.RequestIDHeader))
+}
+
+func TestRecoveryMiddleware_AfterHeadersWritten(t *testing.T) {
+	handler := RecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+		panic("boom mid-stream")
+	}))
+
+	req := httptest.NewRequest("GET", "/api/v1/modules/my-org/my-module/v1.0.0/artifact", nil)
+	rr := httptest.NewRecorder()
+	assert.NotPanics(t, func() { handler.ServeHTTP(rr, req) })
+
+	// The original status is kept and no JSON error is appended to the partial body
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "partial", rr.Body.String())
+}
+
+func TestRecoveryMiddleware_RepanicsOnAbortHandler(t *testing.T) {
+	handler := RecoveryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	rr := httptest.NewRecorder()
+	assert.Panics(t, func() { handler.ServeHTTP(rr, req) })
+}
+
+// --- Tests for LimitPublishes ---
+
+func TestLimitPublishes_QueuesThenRejects(t *testing.T) {
+	SetRequestLimits(RequestLimits{MaxConcurrentPublishes: 1, QueueTimeout: 100 * time.Millisecond})
+	t.Cleanup(func() { SetRequestLimits(DefaultRequestLimits) })
+
+	started, unblock := make(chan struct{}, 2), make(chan struct{})
+	handler := LimitPublishes(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-unblock
+		w.WriteHeader(http.StatusCreated)
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/modules/a/b/v1.0.0", nil))
+		return rr
+	}
+
+	// The first request holds the only slot
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- serve() }()
+	<-started
+
+	// A second request waits for the queue timeout, then gets 429
+	rr := serve()
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"Too many concurrent publish requests, retry later"}`, rr.Body.String())
+
+	// A queued request gets the slot once the first one finishes
+	second := make(chan *httptest.ResponseRecorder)
+	go func() { second <- serve() }()
+	time.Sleep(20 * time.Millisecond)
+	unblock <- struct{}{}
+	assert.Equal(t, http.StatusCreated, (<-first).Code)
+	<-started
+	close(unblock)
+	assert.Equal(t, http.StatusCreated, (<-second).Code)
+}
+
+func TestLimitPublishes_Unlimited(t *testing.T) {
+	SetRequestLimits(DefaultRequestLimits)
+	handler := LimitPublishes(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/impact", nil))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+}
+
+// --- Tests for Brute-Force Protection ---
+
+func TestAuthMiddleware_BansAfterRepeatedFailures(t *testing.T) {
+	SetAuthFailurePolicy(AuthFailurePolicy{MaxFailures: 3, Window: time.Minute, BanDuration: time.Minute, FailureDelay: 10 * time.Millisecond})
+	t.Cleanup(func() { SetAuthFailurePolicy(DefaultAuthFailurePolicy) })
+
+	handler := AuthMiddleware("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/modules/a/b/v1.0.0", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	failuresBefore := testutil.ToFloat64(metrics.AuthFailuresTotal.WithLabelValues("invalid_token"))
+	bansBefore := testutil.ToFloat64(metrics.AuthBansTotal)
+
+	// Failures are answered after an escalating delay (10ms, 20ms)
+	start := time.Now()
+	assert.Equal(t, http.StatusUnauthorized, send("198.51.100.7:1111", "guess-1").Code)
+	assert.Equal(t, http.StatusUnauthorized, send("198.51.100.7:2222", "guess-2").Code)
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+
+	// The third failure bans the client, even for the right token
+	assert.Equal(t, http.StatusUnauthorized, send("198.51.100.7:3333", "guess-3").Code)
+	rr := send("198.51.100.7:4444", "secret")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"Too many failed authentication attempts, retry later"}`, rr.Body.String())
+	assert.Equal(t, failuresBefore+3, testutil.ToFloat64(metrics.AuthFailuresTotal.WithLabelValues("invalid_token")))
+	assert.Equal(t, bansBefore+1, testutil.ToFloat64(metrics.AuthBansTotal))
+
+	// Other clients are unaffected, and a success forgets earlier failures
+	assert.Equal(t, http.StatusUnauthorized, send("203.0.113.9:1111", "guess").Code)
+	assert.Equal(t, http.StatusOK, send("203.0.113.9:1111", "secret").Code)
+	assert.Empty(t, authGuard.clients["203.0.113.9"])
+}
+
+func TestAuthMiddleware_RejectsReadTokens(t *testing.T) {
+	SetReadTokens(map[string]ReadToken{"ci-token": {}})
+	t.Cleanup(func() { SetReadTokens(nil) })
+
+	handler := AuthMiddleware("secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest("POST", "/api/v1/modules/a/b/v1.0.0", nil)
+	req.RemoteAddr = "192.0.2.44:1111"
+	req.Header.Set("Authorization", "Bearer ci-token")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Forbidden rather than unauthorized, and not counted towards a ban
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.JSONEq(t, `{"error":"Forbidden: Read tokens can't be used for write operations"}`, rr.Body.String())
+	assert.Empty(t, authGuard.clients["192.0.2.44"])
+}
+
+func TestTokensEqual(t *testing.T) {
+	assert.True(t, tokensEqual("secret", "secret"))
+	assert.False(t, tokensEqual("secret", "secreT"))
+	assert.False(t, tokensEqual("secre", "secret")) // Prefixes and other lengths don't match
+	assert.False(t, tokensEqual("", "secret"))
+
+	// Read tokens are found by comparing every configured token
+	SetReadTokens(map[string]ReadToken{"ci-token": {Patterns: []string{"ci/*"}}, "other-token": {Patterns: []string{"other/*"}}})
+	t.Cleanup(func() { SetReadTokens(nil) })
+	rt, known := lookupReadToken("ci-token")
+	assert.True(t, known)
+	assert.Equal(t, []string{"ci/*"}, rt.Patterns)
+	_, known = lookupReadToken("ci-toke")
+	assert.False(t, known)
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v1/modules", nil)
+	req.RemoteAddr = "10.0.0.5:4321"
+	req.Header.Set("X-Forwarded-For", "203.0.113.1, 198.51.100.2")
+	assert.Equal(t, "10.0.0.5", clientIP(req))
+
+	SetAuthFailurePolicy(AuthFailurePolicy{ClientIPHeader: "X-Forwarded-For"})
+	t.Cleanup(func() { SetAuthFailurePolicy(DefaultAuthFailurePolicy) })
+	assert.Equal(t, "198.51.100.2", clientIP(req)) // Added by the trusted proxy
+	req.Header.Del("X-Forwarded-For")
+	assert.Equal(t, "10.0.0.5", clientIP(req))
+}
+
+// --- Tests for the IP filters ---
+
+func TestParseCIDRs(t *testing.T) {
+	prefixes, err := ParseCIDRs(" 10.20.0.0/16, 192.0.2.7 ,2001:db8::1/32,")
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.20.0.0/16"),
+		netip.MustParsePrefix("192.0.2.7/32"),
+		netip.MustParsePrefix("2001:db8::/32"), // Masked
+	}, prefixes)
+
+	prefixes, err = ParseCIDRs("")
+	assert.NoError(t, err)
+	assert.Empty(t, prefixes)
+
+	_, err = ParseCIDRs("10.0.0.0/33")
+	assert.Error(t, err)
+	_, err = ParseCIDRs("ci-runners")
+	assert.Error(t, err)
+}
+
+func TestIPFilters(t *testing.T) {
+	SetIPFilterPolicy(IPFilterPolicy{
+		WriteAllowed: []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")},
+		Denied:       []netip.Prefix{netip.MustParsePrefix("198.51.100.0/24")},
+	})
+	t.Cleanup(func() { SetIPFilterPolicy(IPFilterPolicy{}) })
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	read := DenyIPsMiddleware(ok)
+	write := DenyIPsMiddleware(ApplyAuth(ok, "secret"))
+	send := func(handler http.Handler, remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/modules/a/b/v1.0.0", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	rejectedBefore := testutil.ToFloat64(metrics.IPFilterRejectionsTotal.WithLabelValues("write_allowlist"))
+
+	// Writes from the allowlist, including IPv4-mapped addresses of dual-stack listeners
+	assert.Equal(t, http.StatusOK, send(write, "10.20.3.4:1111", "secret").Code)
+	assert.Equal(t, http.StatusOK, send(write, "[::ffff:10.20.3.4]:1111", "secret").Code)
+
+	// Writes from elsewhere are rejected even with the right token, before authentication
+	rr := send(write, "203.0.113.50:1111", "secret")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.JSONEq(t, `{"error":"Forbidden: Requests from this address are not allowed"}`, rr.Body.String())
+	assert.Equal(t, http.StatusForbidden, send(write, "203.0.113.50:1111", "guess").Code)
+	assert.Empty(t, authGuard.clients["203.0.113.50"]) // Not counted as an authentication failure
+	assert.Equal(t, rejectedBefore+2, testutil.ToFloat64(metrics.IPFilterRejectionsTotal.WithLabelValues("write_allowlist")))
+
+	// Unknown client addresses (Unix sockets) don't match the allowlist
+	assert.Equal(t, http.StatusForbidden, send(write, "@", "secret").Code)
+
+	// Reads aren't restricted by the allowlist; the denylist applies everywhere
+	assert.Equal(t, http.StatusOK, send(read, "203.0.113.50:1111", "").Code)
+	assert.Equal(t, http.StatusForbidden, send(read, "198.51.100.7:1111", "").Code)
+	assert.Equal(t, http.StatusForbidden, send(write, "198.51.100.7:1111", "secret").Code)
+
+	// Without an admin token the allowlist still applies
+	assert.Equal(t, http.StatusForbidden, send(ApplyAuth(ok, ""), "203.0.113.50:1111", "").Code)
+}
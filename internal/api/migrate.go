@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Storage layout migration: artifacts published before the current key layout (see
+// storage.ModuleVersionKey) are copied to their key in the current layout, verified against their
+// recorded digest, and their version record is pointed at the new key. Used by the migrate-storage
+// subcommand of the server and the migrate-storage admin job.
+
+// LegacyArtifact is a module version whose artifact is stored under an older key layout.
+type LegacyArtifact struct {
+	ID                 uuid.UUID
+	Namespace          string
+	Name               string
+	Version            string
+	ArtifactDigest     string
+	ArtifactStorageKey string
+	ArtifactSize       int64
+}
+
+// Coordinates returns namespace/name@version.
+func (a LegacyArtifact) Coordinates() string {
+	return fmt.Sprintf("%s/%s@%s", a.Namespace, a.Name, a.Version)
+}
+
+// NewKey returns the artifact's key in the current layout.
+func (a LegacyArtifact) NewKey() string {
+	return storage.ModuleVersionKey(a.Namespace, a.Name, a.Version, a.ArtifactDigest)
+}
+
+// ListLegacyArtifacts lists the artifacts stored under an older key layout, by module and age.
+func ListLegacyArtifacts(ctx context.Context) ([]LegacyArtifact, error) {
+	var artifacts []LegacyArtifact
+	err := db.GetDB().WithContext(ctx).Model(&models.ModuleVersion{}).
+		Select("module_versions.id, modules.namespace, modules.name, module_versions.version, module_versions.artifact_digest, module_versions.artifact_storage_key, module_versions.artifact_size").
+		Joins("JOIN modules ON modules.id = module_versions.module_id").
+		Where("module_versions.artifact_key_layout < ?", storage.CurrentKeyLayout).
+		Order("modules.namespace, modules.name, module_versions.created_at").
+		Scan(&artifacts).Error
+	return artifacts, err
+}
+
+// MigrateArtifact copies a single artifact to its key in the current layout, checking its digest on the
+// way, then points the version record at the new key. Unless keepOld, the old object is deleted once no
+// version uses it anymore. Safe to re-run: the version is only updated after the copy succeeded.
+func MigrateArtifact(ctx context.Context, a LegacyArtifact, keepOld bool) error {
+	provider := storage.GetStorageProvider()
+	newKey := a.NewKey()
+
+	reader, err := provider.DownloadFile(ctx, a.ArtifactStorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	defer reader.Close()
+
+	size := a.ArtifactSize
+	if size <= 0 {
+		size = -1 // Unknown (published before sizes were recorded)
+	}
+	hasher := sha256.New()
+	err = provider.UploadFile(ctx, newKey, io.TeeReader(reader, hasher), size, "application/zip")
+	switch {
+	case errors.Is(err, storage.ErrObjectExists):
+		// Copied by an earlier, interrupted run. Objects are write-once, so reuse it only if it is intact.
+		digest, err := storage.ObjectDigest(ctx, provider, newKey)
+		if err != nil {
+			return fmt.Errorf("failed to verify existing object at new key: %w", err)
+		}
+		if digest != a.ArtifactDigest {
+			return fmt.Errorf("new key already holds a different object: recorded %s, stored object has %s", a.ArtifactDigest, digest)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to upload: %w", err)
+	default:
+		if digest := hex.EncodeToString(hasher.Sum(nil)); digest != a.ArtifactDigest {
+			_ = provider.DeleteFile(ctx, newKey) // Only just created by this run
+			return fmt.Errorf("digest mismatch: recorded %s, stored object has %s", a.ArtifactDigest, digest)
+		}
+	}
+
+	gormDB := db.GetDB().WithContext(ctx)
+	err = gormDB.Model(&models.ModuleVersion{}).Where("id = ?", a.ID).Updates(map[string]interface{}{
+		"artifact_storage_key": newKey,
+		"artifact_key_layout":  storage.CurrentKeyLayout,
+		"artifact_tiered_at":   nil, // Written to the hot tier
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update version record: %w", err)
+	}
+
+	if !keepOld {
+		// Versions republished with ?from= share their source's object; it is deleted once the last
+		// of them has been migrated
+		var sharing int64
+		if err := gormDB.Model(&models.ModuleVersion{}).Where("artifact_storage_key = ?", a.ArtifactStorageKey).Count(&sharing).Error; err != nil {
+			logging.L().Warn("Failed to check whether the old artifact object is still in use, keeping it", zap.String("key", a.ArtifactStorageKey), zap.Error(err))
+			return nil
+		}
+		if sharing > 0 {
+			logging.L().Info("Old artifact object is still used by other versions, keeping it", zap.String("key", a.ArtifactStorageKey), zap.Int64("versions", sharing))
+			return nil
+		}
+		if err := provider.DeleteFile(ctx, a.ArtifactStorageKey); err != nil {
+			// The version already points at the new key; the old object is just left behind
+			logging.L().Warn("Failed to delete old artifact object", zap.String("key", a.ArtifactStorageKey), zap.Error(err))
+		}
+	}
+	return nil
+}
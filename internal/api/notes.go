@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// MaxNoteLength is the maximum length of a version note in bytes.
+const MaxNoteLength = 4096
+
+// AddVersionNoteHandler attaches a free-form note to a published module version.
+// POST /api/v1/modules/{namespace}/{module_name}/{version}/notes
+// The author is taken from the X-SProto-Publisher header, if present.
+func AddVersionNoteHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	version := vars["version"]
+	log := logging.FromContext(r.Context()).With(zap.String("module_version", fmt.Sprintf("%s/%s@%s", namespace, moduleName, version)))
+
+	if !strings.HasPrefix(version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+
+	var req apitypes.AddVersionNoteRequest
+	// Leave some room for the JSON envelope and escaping
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 2*MaxNoteLength+1024)).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Note == "" {
+		response.Error(w, http.StatusBadRequest, "Note must not be empty")
+		return
+	}
+	if len(req.Note) > MaxNoteLength {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Note exceeds the maximum length of %d bytes", MaxNoteLength))
+		return
+	}
+
+	moduleVersion, ok := findModuleVersion(w, r, namespace, moduleName, version)
+	if !ok {
+		return // Response already written
+	}
+
+	note := models.VersionNote{
+		ModuleVersionID: moduleVersion.ID,
+		Body:            req.Note,
+		Author:          r.Header.Get(apitypes.PublisherHeader),
+		CreatedAt:       time.Now().UTC(),
+	}
+	if err := db.GetDB().Create(&note).Error; err != nil {
+		log.Error("Error saving version note", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error saving note")
+		return
+	}
+	log.Info("Added version note", zap.String("author", note.Author))
+
+	response.JSON(w, http.StatusCreated, versionNoteResponse(note))
+}
+
+// listVersionNotes returns the notes attached to a module version, oldest first.
+func listVersionNotes(moduleVersionID uuid.UUID) ([]apitypes.VersionNoteResponse, error) {
+	var notes []models.VersionNote
+	err := db.GetReadDB().Where("module_version_id = ?", moduleVersionID).Order("created_at ASC").Find(&notes).Error
+	if err != nil {
+		return nil, err
+	}
+	respNotes := make([]apitypes.VersionNoteResponse, 0, len(notes))
+	for _, n := range notes {
+		respNotes = append(respNotes, versionNoteResponse(n))
+	}
+	return respNotes, nil
+}
+
+func versionNoteResponse(n models.VersionNote) apitypes.VersionNoteResponse {
+	return apitypes.VersionNoteResponse{Note: n.Body, Author: n.Author, CreatedAt: n.CreatedAt}
+}
+
+// versionMetadataETag returns the ETag of the version metadata endpoint. Notes are append-only, so the
+// artifact digest plus the note count and deprecation (and sunset) status identifies the response; versions without
+// notes that aren't deprecated keep the plain digest.
+func versionMetadataETag(moduleVersion *models.ModuleVersion, noteCount int) string {
+	if moduleVersion.ArtifactDigest == "" {
+		return ""
+	}
+	etag := moduleVersion.ArtifactDigest
+	if noteCount > 0 {
+		etag += fmt.Sprintf("-notes.%d", noteCount)
+	}
+	if moduleVersion.DeprecatedAt != nil {
+		// The message can change while the date stays; a short hash of it keeps the ETag compact
+		etag += fmt.Sprintf("-deprecated.%08x", crc32.ChecksumIEEE([]byte(moduleVersion.DeprecationMessage)))
+	}
+	if moduleVersion.SunsetAt != nil {
+		etag += fmt.Sprintf("-sunset.%d", moduleVersion.SunsetAt.Unix())
+		if moduleVersion.SunsetEnforcedAt != nil {
+			etag += ".enforced"
+		}
+	}
+	if moduleVersion.CanaryPercent != nil {
+		etag += fmt.Sprintf("-canary.%d", *moduleVersion.CanaryPercent)
+	}
+	if moduleVersion.SigningKeyID != "" {
+		// Versions published unsigned can be signed later (sign-versions job)
+		etag += "-signed." + moduleVersion.SigningKeyID
+	}
+	return `"` + etag + `"`
+}
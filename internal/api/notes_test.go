@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestGetModuleVersionHandler_WithNotes(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	versionID := uuid.New()
+	mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+		WithArgs("my-org", "my-module", "v1.0.0", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "module_id", "version", "artifact_digest", "artifact_storage_key", "artifact_size", "scan_status"}).
+			AddRow(versionID, uuid.New(), "v1.0.0", "abc123", "modules/x/v1.0.0/protos.zip", 1234, "clean"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "version_notes" WHERE module_version_id = $1 ORDER BY created_at ASC`)).
+		WithArgs(versionID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "module_version_id", "body", "author", "created_at"}).
+			AddRow(uuid.New(), versionID, "contains hotfix for billing rounding", "ci-bot", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+	req, err := http.NewRequest("GET", "/api/v1/modules/my-org/my-module/v1.0.0", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", GetModuleVersionHandler).Methods("GET", "HEAD")
+	router.ServeHTTP(rr, req)
+
+	// --- Assertions ---
+	assert.Equal(t, http.StatusOK, rr.Code)
+	// The note count is part of the ETag so adding a note invalidates cached metadata
+	assert.Equal(t, `"abc123-notes.1"`, rr.Header().Get("ETag"))
+	var resp apitypes.ModuleVersionResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Notes, 1) {
+		assert.Equal(t, "contains hotfix for billing rounding", resp.Notes[0].Note)
+		assert.Equal(t, "ci-bot", resp.Notes[0].Author)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddVersionNoteHandler(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/notes", AddVersionNoteHandler).Methods("POST")
+	addNote := func(version, body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/api/v1/modules/my-org/my-module/"+version+"/notes", bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		req.Header.Set(apitypes.PublisherHeader, "ci-bot")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Invalid requests are rejected before touching the database
+	assert.Equal(t, http.StatusBadRequest, addNote("v1.0.0", `not json`).Code)
+	rr := addNote("v1.0.0", `{"note":"   "}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":"Note must not be empty"}`, rr.Body.String())
+	long, _ := json.Marshal(apitypes.AddVersionNoteRequest{Note: string(bytes.Repeat([]byte("x"), MaxNoteLength+1))})
+	assert.Equal(t, http.StatusBadRequest, addNote("v1.0.0", string(long)).Code)
+
+	// Unknown version
+	mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+		WithArgs("my-org", "my-module", "v9.9.9", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	assert.Equal(t, http.StatusNotFound, addNote("v9.9.9", `{"note":"hello"}`).Code)
+
+	// Success
+	versionID := uuid.New()
+	mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+		WithArgs("my-org", "my-module", "v1.0.0", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "module_id", "version", "artifact_digest"}).
+			AddRow(versionID, uuid.New(), "v1.0.0", "abc123"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "version_notes"`)).
+		WithArgs(sqlmock.AnyArg(), versionID, "contains hotfix for billing rounding", "ci-bot", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+	rr = addNote("v1.0.0", `{"note":"  contains hotfix for billing rounding\n"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var resp apitypes.VersionNoteResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "contains hotfix for billing rounding", resp.Note)
+	assert.Equal(t, "ci-bot", resp.Author)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// npm bridge: generated TypeScript stubs are built by CI (e.g. protoc-gen-es or ts-proto, then `npm pack`)
+// and attached to versions as the secondary artifact "npm-package". With a scope configured (NPM_SCOPE), the
+// registry serves them as an npm registry under /npm, so front ends and Node services install schema
+// updates with npm: module acme/billing is the package @<scope>/acme_billing ("_" separates the namespace
+// from the name, since neither can contain one), its versions are the registry versions without the "v".
+// Only versions with a package attached are versions of the npm package; versions with build metadata are
+// left out, since npm ignores it.
+
+// NPMPackageClassifier is the secondary artifact classifier generated npm package tarballs are attached under.
+const NPMPackageClassifier = "npm-package"
+
+// NPMPathPrefix is where the npm registry is served (the registry URL of the scope is the registry's plus this).
+const NPMPathPrefix = "/npm"
+
+// npmScope is the scope of the npm bridge, with its "@"; "" if it is disabled. See SetNPMScope.
+var npmScope string
+
+// npmScopePattern matches npm scopes.
+var npmScopePattern = regexp.MustCompile(`^@[a-z0-9][a-z0-9._-]*$`)
+
+// npmManifests caches the package.json fields read from package tarballs by digest: tarballs never change,
+// and reading each of them on every packument request would download them all.
+var npmManifests = struct {
+	sync.Mutex
+	byDigest map[string]npmManifest
+}{byDigest: map[string]npmManifest{}}
+
+// npmManifest holds the package.json fields of a tarball that npm needs before downloading it.
+type npmManifest struct {
+	Dependencies     map[string]string `json:"dependencies"`
+	PeerDependencies map[string]string `json:"peerDependencies"`
+}
+
+// NPMPackument is the registry document of an npm package, with the fields npm install reads.
+type NPMPackument struct {
+	Name     string                       `json:"name"`
+	DistTags map[string]string            `json:"dist-tags"`
+	Versions map[string]NPMPackageVersion `json:"versions"`
+	Time     map[string]time.Time         `json:"time"`
+}
+
+// NPMPackageVersion is a version of an npm package in its packument.
+type NPMPackageVersion struct {
+	Name             string            `json:"name"`
+	Version          string            `json:"version"`
+	Dependencies     map[string]string `json:"dependencies,omitempty"`
+	PeerDependencies map[string]string `json:"peerDependencies,omitempty"`
+	Deprecated       string            `json:"deprecated,omitempty"` // Deprecation message of the registry version
+	Dist             NPMDist           `json:"dist"`
+}
+
+// NPMDist locates the tarball of an npm package version.
+type NPMDist struct {
+	Tarball   string `json:"tarball"`
+	Integrity string `json:"integrity"` // Subresource integrity: sha256-<base64>
+}
+
+// SetNPMScope configures the npm bridge: the scope of bridged packages (e.g. @protos; the "@" is optional).
+// An empty scope disables the bridge.
+func SetNPMScope(scope string) error {
+	scope = strings.TrimPrefix(strings.TrimSpace(scope), "@")
+	if scope == "" {
+		npmScope = ""
+		return nil
+	}
+	if !npmScopePattern.MatchString("@" + scope) {
+		return fmt.Errorf("invalid npm scope %q: must be lowercase, e.g. @protos", scope)
+	}
+	npmScope = "@" + scope
+	return nil
+}
+
+// npmPackage is a registry module, as an npm package.
+type npmPackage struct {
+	name      string // Package name without the scope
+	namespace string
+	module    string
+}
+
+// findNPMPackage resolves the package of an npm request to a module the caller can read. On failure a 404
+// has been written (or 500) and ok is false.
+func findNPMPackage(w http.ResponseWriter, r *http.Request) (*npmPackage, bool) {
+	log := logging.FromContext(r.Context())
+	if npmScope == "" {
+		response.Error(w, http.StatusNotFound, "npm bridge is not enabled")
+		return nil, false
+	}
+	vars := mux.Vars(r)
+	if vars["scope"] != npmScope {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("Only packages of scope %s are served", npmScope))
+		return nil, false
+	}
+	namespace, name, ok := strings.Cut(vars["package"], "_")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "_") {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("Invalid package name %s: expected %s/<namespace>_<module>", vars["package"], npmScope))
+		return nil, false
+	}
+	readable, err := moduleReadable(r, namespace, name)
+	if err != nil {
+		log.Error("Error checking module visibility", zap.String("namespace", namespace), zap.String("module", name), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module")
+		return nil, false
+	}
+	if !readable {
+		response.Error(w, http.StatusNotFound, "Module not found") // Don't reveal that it exists
+		return nil, false
+	}
+	return &npmPackage{name: vars["package"], namespace: namespace, module: name}, true
+}
+
+// NPMPackumentHandler serves the packument of an npm package: its versions with their dependencies,
+// deprecation and tarball.
+// GET /npm/{scope}/{package}
+func NPMPackumentHandler(w http.ResponseWriter, r *http.Request) {
+	pkg, ok := findNPMPackage(w, r)
+	if !ok {
+		return // Response already written
+	}
+	log := logging.FromContext(r.Context()).With(zap.String("package", npmScope+"/"+pkg.name))
+	rows, err := versionsWithArtifact(r, pkg.namespace, pkg.module, NPMPackageClassifier)
+	if err != nil {
+		log.Error("Error listing npm package versions", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module versions")
+		return
+	}
+
+	packument := NPMPackument{
+		Name:     npmScope + "/" + pkg.name,
+		DistTags: map[string]string{},
+		Versions: map[string]NPMPackageVersion{},
+		Time:     map[string]time.Time{},
+	}
+	for _, row := range rows {
+		if row.semver.Metadata() != "" {
+			continue
+		}
+		manifest, ok := readNPMManifest(w, r, log, row)
+		if !ok {
+			return // Response already written
+		}
+		version := strings.TrimPrefix(row.Version, "v")
+		digest, _ := hex.DecodeString(row.Digest)
+		entry := NPMPackageVersion{
+			Name:             packument.Name,
+			Version:          version,
+			Dependencies:     manifest.Dependencies,
+			PeerDependencies: manifest.PeerDependencies,
+			Dist: NPMDist{
+				Tarball:   fmt.Sprintf("%s%s/%s/%s/-/%s-%s.tgz", requestBaseURL(r), NPMPathPrefix, npmScope, pkg.name, pkg.name, version),
+				Integrity: "sha256-" + base64.StdEncoding.EncodeToString(digest),
+			},
+		}
+		if row.DeprecatedAt != nil {
+			entry.Deprecated = row.DeprecationMessage
+			if entry.Deprecated == "" {
+				entry.Deprecated = "Deprecated"
+			}
+		}
+		packument.Versions[version] = entry
+		packument.Time[version] = row.PublishedAt.UTC()
+		if release := packument.DistTags["latest"]; release == "" || row.semver.Prerelease() == "" || strings.Contains(release, "-") {
+			packument.DistTags["latest"] = version // Rows are ascending: the highest release, else pre-release
+		}
+		if row.AttachedAt.After(packument.Time["modified"]) {
+			packument.Time["modified"] = row.AttachedAt.UTC()
+		}
+	}
+	if len(packument.Versions) == 0 {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("No versions of %s", packument.Name))
+		return
+	}
+	setListCacheHeaders(w) // Changes when a package is attached
+	response.JSON(w, http.StatusOK, packument)
+}
+
+// NPMTarballHandler downloads the tarball of an npm package version.
+// GET|HEAD /npm/{scope}/{package}/-/{tarball}
+func NPMTarballHandler(w http.ResponseWriter, r *http.Request) {
+	pkg, ok := findNPMPackage(w, r)
+	if !ok {
+		return // Response already written
+	}
+	tarball := mux.Vars(r)["tarball"]
+	version, ok := strings.CutPrefix(strings.TrimSuffix(tarball, ".tgz"), pkg.name+"-")
+	if !ok || !strings.HasSuffix(tarball, ".tgz") {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("Invalid tarball name %s: expected %s-<version>.tgz", tarball, pkg.name))
+		return
+	}
+	moduleVersion, ok := findModuleVersion(w, r, pkg.namespace, pkg.module, "v"+version)
+	if !ok {
+		return // Response already written
+	}
+	if sunsetGone(w, r, moduleVersion) {
+		return // 410 already written
+	}
+	recordFetch(r, moduleVersion.ID) // Consumption report
+	log := logging.FromContext(r.Context()).With(zap.String("package", npmScope+"/"+pkg.name), zap.String("version", version))
+	writeVersionArtifact(w, r, log, moduleVersion, NPMPackageClassifier, "No npm package for this version", tarball)
+}
+
+// readNPMManifest returns the package.json fields of a version's tarball, downloading it unless cached.
+// Tarballs without a readable package.json have no dependencies. On failure a response has been written and
+// ok is false.
+func readNPMManifest(w http.ResponseWriter, r *http.Request, log *zap.Logger, row attachedArtifact) (npmManifest, bool) {
+	npmManifests.Lock()
+	manifest, cached := npmManifests.byDigest[row.Digest]
+	npmManifests.Unlock()
+	if cached {
+		return manifest, true
+	}
+	tarball, ok := readVersionArtifact(w, r, log, &models.VersionArtifact{StorageKey: row.StorageKey})
+	if !ok {
+		return npmManifest{}, false
+	}
+	manifest, err := parseNPMManifest(tarball)
+	if err != nil {
+		log.Warn("Invalid npm package tarball", zap.String("version", row.Version), zap.Error(err))
+	}
+	npmManifests.Lock()
+	npmManifests.byDigest[row.Digest] = manifest
+	npmManifests.Unlock()
+	return manifest, true
+}
+
+// parseNPMManifest reads the package.json of a package tarball: the file at the root of its top directory
+// (package/ for tarballs made by npm pack).
+func parseNPMManifest(tarball []byte) (npmManifest, error) {
+	var manifest npmManifest
+	gz, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		return manifest, fmt.Errorf("invalid gzip: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return manifest, errors.New("no package.json")
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("invalid tar: %w", err)
+		}
+		if _, file, ok := strings.Cut(header.Name, "/"); !ok || file != "package.json" {
+			continue
+		}
+		if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+			return npmManifest{}, fmt.Errorf("invalid package.json: %w", err)
+		}
+		return manifest, nil
+	}
+}
+
+// requestBaseURL returns the scheme and host a request was sent to, for absolute URLs in responses. Behind
+// a TLS-terminating proxy the scheme is taken from X-Forwarded-Proto.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
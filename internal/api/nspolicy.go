@@ -0,0 +1,471 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Namespace policies: admins configure, per namespace, the checks every published version must pass
+// (lint ruleset, breaking-change level, allowed file names and syntaxes, monotonic versions, HTTP rules,
+// and the content policy settings replacing the server's, see contentpolicy.go). Unlike the CEL publish
+// policies (PUBLISH_POLICY_FILE), which are server configuration, they are stored in the database and
+// managed through the admin API, so platform teams can tighten a namespace without a redeploy.
+// A policy can also make its namespace ephemeral: versions expire after a TTL (see ephemeral.go).
+//
+// Policies carry a revision, incremented by every update and served as their ETag. Admins editing a policy
+// send it back in If-Match, so of two concurrent edits the second fails with 412 instead of silently
+// replacing the first.
+
+// maxNamespacePolicyRequestBytes limits the JSON body of namespace policy updates.
+const maxNamespacePolicyRequestBytes = 64 * 1024
+
+// Errors of namespace policy writes, returned from their transactions.
+var (
+	errNoNamespacePolicy      = errors.New("namespace has no policy")
+	errPolicyPrecondition     = errors.New("namespace policy precondition failed")
+	errNamespacePolicyChanged = errors.New("namespace policy changed concurrently")
+)
+
+// --- Admin Endpoints ---
+
+// ListNamespacePoliciesHandler lists the namespaces that have a policy, by namespace.
+// GET /api/v1/admin/namespace-policies
+// Requires Authentication (admin token).
+func ListNamespacePoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	var policies []models.NamespacePolicy
+	if err := requestDB(r).Order("namespace").Find(&policies).Error; err != nil {
+		logging.FromContext(r.Context()).Error("Error listing namespace policies", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve namespace policies")
+		return
+	}
+	resp := apitypes.ListNamespacePoliciesResponse{Policies: make([]apitypes.NamespacePolicyResponse, 0, len(policies))} // Empty array, not null
+	for _, p := range policies {
+		resp.Policies = append(resp.Policies, namespacePolicyResponse(p))
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// GetNamespacePolicyHandler returns the policy of a namespace, or 404 if it has none.
+// GET /api/v1/admin/namespace-policies/{namespace}
+// Requires Authentication (admin token).
+func GetNamespacePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	namespace := mux.Vars(r)["namespace"]
+	var nsPolicy models.NamespacePolicy
+	err := requestDB(r).Where("namespace = ?", namespace).First(&nsPolicy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("Namespace '%s' has no policy", namespace))
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error finding namespace policy", zap.String("namespace", namespace), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve namespace policy")
+		return
+	}
+	w.Header().Set("ETag", namespacePolicyETag(nsPolicy))
+	response.JSON(w, http.StatusOK, namespacePolicyResponse(nsPolicy))
+}
+
+// PutNamespacePolicyHandler creates or replaces the policy of a namespace. The namespace doesn't need to
+// have modules yet, so a policy can be in place before the first publish.
+// PUT /api/v1/admin/namespace-policies/{namespace}
+// If-Match: "<revision>" only replaces that revision of the policy, If-None-Match: * only creates one (412
+// otherwise). Requires Authentication (admin token).
+func PutNamespacePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	namespace := mux.Vars(r)["namespace"]
+	if err := validation.ValidateNamespace(namespace); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	log := logging.FromContext(r.Context()).With(zap.String("namespace", namespace))
+
+	var req apitypes.NamespacePolicyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxNamespacePolicyRequestBytes)).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if err := validateNamespacePolicyRequest(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ephemeralTTL, _ := time.ParseDuration(req.EphemeralTTL) // Validated above; empty is 0
+	nsPolicy := models.NamespacePolicy{
+		Namespace:    namespace,
+		LintRuleset:  req.LintRuleset,
+		CompatLevel:  req.CompatLevel,
+		AllowedFiles: strings.Join(req.AllowedFiles, "\n"),
+		Monotonic:    req.Monotonic,
+		UpdatedAt:    time.Now().UTC(),
+
+		AllowedSyntaxes:     strings.Join(req.AllowedSyntaxes, "\n"),
+		HTTPRules:           req.HTTPRules,
+		EphemeralTTLSeconds: int64(ephemeralTTL / time.Second),
+
+		ContentTypes:      strings.Join(req.ContentTypes, "\n"),
+		MaxFileBytes:      req.MaxFileBytes,
+		AllowedExtensions: strings.Join(req.AllowedExtensions, "\n"),
+	}
+	var current models.NamespacePolicy
+	err := db.GetDB().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("namespace = ?", namespace).First(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if !preconditionsMet(r, "") {
+				return errPolicyPrecondition
+			}
+			nsPolicy.Revision = 1
+			// Created meanwhile if nothing was inserted
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&nsPolicy)
+			if result.Error == nil && result.RowsAffected == 0 {
+				return errNamespacePolicyChanged
+			}
+			return result.Error
+		}
+		if err != nil {
+			return err
+		}
+		if !preconditionsMet(r, namespacePolicyETag(current)) {
+			return errPolicyPrecondition
+		}
+		nsPolicy.Revision = current.Revision + 1
+		// The revision condition catches an update between the read and the write
+		result := tx.Model(&models.NamespacePolicy{}).Where("namespace = ? AND revision = ?", namespace, current.Revision).Select("*").Updates(&nsPolicy)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return errNamespacePolicyChanged
+		}
+		return result.Error
+	})
+	if err != nil {
+		namespacePolicyWriteError(w, r, log, err, namespace, current)
+		return
+	}
+	log.Info("Saved namespace policy", zap.String("lint_ruleset", nsPolicy.LintRuleset), zap.String("compat_level", nsPolicy.CompatLevel),
+		zap.Strings("allowed_files", req.AllowedFiles), zap.Bool("monotonic", nsPolicy.Monotonic), zap.Strings("allowed_syntaxes", req.AllowedSyntaxes),
+		zap.Bool("http_rules", nsPolicy.HTTPRules), zap.Int64("ephemeral_ttl_seconds", nsPolicy.EphemeralTTLSeconds),
+		zap.Strings("content_types", req.ContentTypes), zap.Int64("max_file_bytes", req.MaxFileBytes), zap.Strings("allowed_extensions", req.AllowedExtensions),
+		zap.Int64("revision", nsPolicy.Revision))
+	w.Header().Set("ETag", namespacePolicyETag(nsPolicy))
+	response.JSON(w, http.StatusOK, namespacePolicyResponse(nsPolicy))
+}
+
+// DeleteNamespacePolicyHandler removes the policy of a namespace; publishes are then only subject to the
+// server-wide checks.
+// DELETE /api/v1/admin/namespace-policies/{namespace}
+// If-Match: "<revision>" only deletes that revision of the policy (412 otherwise).
+// Requires Authentication (admin token).
+func DeleteNamespacePolicyHandler(w http.ResponseWriter, r *http.Request) {
+	namespace := mux.Vars(r)["namespace"]
+	log := logging.FromContext(r.Context()).With(zap.String("namespace", namespace))
+	var current models.NamespacePolicy
+	err := db.GetDB().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("namespace = ?", namespace).First(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errNoNamespacePolicy
+		}
+		if err != nil {
+			return err
+		}
+		if !preconditionsMet(r, namespacePolicyETag(current)) {
+			return errPolicyPrecondition
+		}
+		result := tx.Where("namespace = ? AND revision = ?", namespace, current.Revision).Delete(&models.NamespacePolicy{})
+		if result.Error == nil && result.RowsAffected == 0 {
+			return errNamespacePolicyChanged
+		}
+		return result.Error
+	})
+	if err != nil {
+		namespacePolicyWriteError(w, r, log, err, namespace, current)
+		return
+	}
+	log.Info("Deleted namespace policy", zap.Int64("revision", current.Revision))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// namespacePolicyWriteError writes the response for a failed policy write; current is the policy the write
+// was checked against (zero if there was none).
+func namespacePolicyWriteError(w http.ResponseWriter, r *http.Request, log *zap.Logger, err error, namespace string, current models.NamespacePolicy) {
+	switch {
+	case errors.Is(err, errNoNamespacePolicy):
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("Namespace '%s' has no policy", namespace))
+	case errors.Is(err, errPolicyPrecondition) && current.Namespace == "":
+		response.Error(w, http.StatusPreconditionFailed, fmt.Sprintf("Namespace '%s' has no policy", namespace))
+	case errors.Is(err, errPolicyPrecondition):
+		w.Header().Set("ETag", namespacePolicyETag(current))
+		response.Error(w, http.StatusPreconditionFailed, fmt.Sprintf("The policy of namespace '%s' was changed (now at revision %d): get it again and reapply your change", namespace, current.Revision))
+	case errors.Is(err, errNamespacePolicyChanged) && hasPreconditions(r):
+		response.Error(w, http.StatusPreconditionFailed, fmt.Sprintf("The policy of namespace '%s' was changed concurrently: get it again and reapply your change", namespace))
+	case errors.Is(err, errNamespacePolicyChanged):
+		response.Error(w, http.StatusConflict, fmt.Sprintf("The policy of namespace '%s' was changed concurrently, try again", namespace))
+	default:
+		log.Error("Error writing namespace policy", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error updating namespace policy")
+	}
+}
+
+// namespacePolicyETag returns the ETag of a namespace policy: its quoted revision.
+func namespacePolicyETag(p models.NamespacePolicy) string {
+	return fmt.Sprintf(`"%d"`, p.Revision)
+}
+
+// validateNamespacePolicyRequest checks the ruleset, the compatibility level, the file patterns, the
+// syntaxes, the ephemeral TTL and the content policy settings, and drops blank and duplicate patterns,
+// syntaxes, content types and extensions.
+func validateNamespacePolicyRequest(req *apitypes.NamespacePolicyRequest) error {
+	if req.LintRuleset != "" {
+		if err := descriptor.ValidateLintRuleset(req.LintRuleset); err != nil {
+			return err
+		}
+	}
+	if req.CompatLevel != "" && req.CompatLevel != models.CompatWire && req.CompatLevel != models.CompatSource {
+		return fmt.Errorf("invalid compat level %q: must be %s or %s", req.CompatLevel, models.CompatWire, models.CompatSource)
+	}
+	patterns := make([]string, 0, len(req.AllowedFiles))
+	seen := map[string]bool{}
+	for _, pattern := range req.AllowedFiles {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || seen[pattern] {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid allowed file pattern %q: %v", pattern, err)
+		}
+		seen[pattern] = true
+		patterns = append(patterns, pattern)
+	}
+	req.AllowedFiles = patterns
+
+	syntaxes := make([]string, 0, len(req.AllowedSyntaxes))
+	seen = map[string]bool{}
+	for _, syntax := range req.AllowedSyntaxes {
+		syntax = strings.TrimSpace(syntax)
+		if syntax == "" || seen[syntax] {
+			continue
+		}
+		if err := descriptor.ValidateSyntax(syntax); err != nil {
+			return err
+		}
+		seen[syntax] = true
+		syntaxes = append(syntaxes, syntax)
+	}
+	req.AllowedSyntaxes = syntaxes
+
+	if req.EphemeralTTL != "" {
+		ttl, err := time.ParseDuration(req.EphemeralTTL)
+		if err != nil || ttl < time.Minute {
+			return fmt.Errorf("invalid ephemeral TTL %q: must be a duration of at least 1m, e.g. 72h", req.EphemeralTTL)
+		}
+	}
+
+	content, err := artifact.NewContentPolicy(req.ContentTypes, req.AllowedExtensions, req.MaxFileBytes)
+	if err != nil {
+		return err
+	}
+	req.ContentTypes, req.AllowedExtensions = content.ContentTypes, content.Extensions
+	return nil
+}
+
+func namespacePolicyResponse(p models.NamespacePolicy) apitypes.NamespacePolicyResponse {
+	resp := apitypes.NamespacePolicyResponse{
+		Namespace: p.Namespace,
+		NamespacePolicyRequest: apitypes.NamespacePolicyRequest{
+			LintRuleset:  p.LintRuleset,
+			CompatLevel:  p.CompatLevel,
+			AllowedFiles: []string{}, // Empty arrays, not null
+			Monotonic:    p.Monotonic,
+
+			AllowedSyntaxes: []string{},
+			HTTPRules:       p.HTTPRules,
+
+			ContentTypes:      []string{},
+			MaxFileBytes:      p.MaxFileBytes,
+			AllowedExtensions: []string{},
+		},
+		UpdatedAt: p.UpdatedAt,
+		Revision:  p.Revision,
+	}
+	if p.AllowedFiles != "" {
+		resp.AllowedFiles = strings.Split(p.AllowedFiles, "\n")
+	}
+	if p.AllowedSyntaxes != "" {
+		resp.AllowedSyntaxes = strings.Split(p.AllowedSyntaxes, "\n")
+	}
+	if p.ContentTypes != "" {
+		resp.ContentTypes = strings.Split(p.ContentTypes, "\n")
+	}
+	if p.AllowedExtensions != "" {
+		resp.AllowedExtensions = strings.Split(p.AllowedExtensions, "\n")
+	}
+	if p.EphemeralTTLSeconds > 0 {
+		resp.EphemeralTTL = (time.Duration(p.EphemeralTTLSeconds) * time.Second).String()
+	}
+	return resp
+}
+
+// --- Publish Check ---
+
+// findNamespacePolicy loads the policy of a namespace; it is nil if the namespace has none.
+// Returns false if the lookup failed; the response has then been written.
+func findNamespacePolicy(w http.ResponseWriter, r *http.Request, namespace string) (*models.NamespacePolicy, bool) {
+	var nsPolicy models.NamespacePolicy
+	err := db.GetDB().WithContext(r.Context()).Where("namespace = ?", namespace).First(&nsPolicy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, true
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error finding namespace policy", zap.String("namespace", namespace), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to evaluate namespace policy")
+		return nil, false
+	}
+	return &nsPolicy, true
+}
+
+// evaluateNamespacePolicy runs the checks of a namespace policy over an artifact about to be published as
+// namespace/moduleName@version, recording their issues in v. Lint, HTTP rule and compatibility checks need
+// an artifact that compiles; if it doesn't, its compile errors are recorded instead.
+// Returns false if a check couldn't run; the response has then been written.
+func evaluateNamespacePolicy(w http.ResponseWriter, r *http.Request, v *publishChecks, nsPolicy *models.NamespacePolicy, artifact []byte, namespace, moduleName, version string) bool {
+	log := logging.FromContext(r.Context()).With(zap.String("module_version", namespace+"/"+moduleName+"@"+version))
+
+	// --- Allowed Files ---
+	if nsPolicy.AllowedFiles != "" {
+		patterns := strings.Split(nsPolicy.AllowedFiles, "\n")
+		zipReader, err := zip.NewReader(bytes.NewReader(artifact), int64(len(artifact)))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid artifact: %v", err))
+			return false
+		}
+		for _, f := range zipReader.File {
+			name := path.Clean(strings.ReplaceAll(f.Name, `\`, "/"))
+			if f.FileInfo().IsDir() || name == manifest.ManifestFileName || fileAllowed(path.Base(name), patterns) {
+				continue
+			}
+			v.addError("allowed-files", name, 0, fmt.Sprintf("file %s matches none of the allowed patterns (%s)", name, strings.Join(patterns, ", ")))
+		}
+	}
+
+	// --- Syntaxes and Editions ---
+	if nsPolicy.AllowedSyntaxes != "" {
+		allowed := strings.Split(nsPolicy.AllowedSyntaxes, "\n")
+		syntaxes, err := descriptor.ArtifactSyntaxes(artifact)
+		if !v.schemaChecked(w, log, err) {
+			return false
+		}
+		for _, syntax := range syntaxes {
+			if !slices.Contains(allowed, syntax) {
+				v.addError("syntax", "", 0, fmt.Sprintf("files declaring %s are not allowed in namespace %s (allowed: %s)", syntax, namespace, strings.Join(allowed, ", ")))
+			}
+		}
+	}
+
+	// --- Monotonic Versions ---
+	if nsPolicy.Monotonic {
+		var versions []string
+		err := db.GetDB().WithContext(r.Context()).Model(&models.ModuleVersion{}).
+			Joins("JOIN modules ON modules.id = module_versions.module_id").
+			Where("modules.namespace = ? AND modules.name = ?", namespace, moduleName).
+			Pluck("module_versions.version", &versions).Error
+		if err != nil {
+			log.Error("Error listing versions for namespace policy", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to evaluate namespace policy")
+			return false
+		}
+		newest := ""
+		for _, existing := range versions {
+			if newest == "" || manifest.IsOlder(newest, existing) {
+				newest = existing
+			}
+		}
+		if newest != "" && newest != version && !manifest.IsOlder(newest, version) { // Republishing newest is a conflict
+			v.addError("monotonic", "", 0, fmt.Sprintf("version %s is not newer than the newest published version %s", version, newest))
+		}
+	}
+
+	// --- Lint ---
+	loader := descriptor.NewLoader(db.GetDB(), storage.GetStorageProvider())
+	if nsPolicy.LintRuleset != "" && !v.compileFailed {
+		issues, err := loader.LintArtifact(r.Context(), namespace, moduleName, version, artifact, nsPolicy.LintRuleset)
+		if !v.schemaChecked(w, log, err) {
+			return false
+		}
+		for _, issue := range issues {
+			v.addError("lint:"+issue.Rule, issue.File, issue.Line, issue.Message)
+		}
+	}
+
+	// --- HTTP Rules ---
+	if nsPolicy.HTTPRules && !v.compileFailed {
+		issues, err := loader.CheckHTTPRulesArtifact(r.Context(), namespace, moduleName, version, artifact)
+		if !v.schemaChecked(w, log, err) {
+			return false
+		}
+		for _, issue := range issues {
+			v.addError("http:"+issue.Rule, issue.File, issue.Line, issue.Message)
+		}
+	}
+
+	// --- Breaking Changes ---
+	if nsPolicy.CompatLevel != "" && !v.compileFailed {
+		diff, err := loader.DiffAgainstLatest(r.Context(), namespace, moduleName, version, artifact)
+		if errors.Is(err, descriptor.ErrNotFound) {
+			err = nil // First version of the module: nothing to compare against
+		} else if err == nil && !majorVersionBump(diff.BaseVersion, version) {
+			for _, change := range diff.Changes {
+				if nsPolicy.CompatLevel == models.CompatWire && !breaksWire(change.Kind) {
+					continue
+				}
+				v.addError("compat:"+change.Kind, "", 0, fmt.Sprintf("%s (compared with %s; breaking changes need a new major version)", change.Message, diff.BaseVersion))
+			}
+		}
+		if !v.schemaChecked(w, log, err) {
+			return false
+		}
+	}
+	return true
+}
+
+// fileAllowed reports whether a file name matches one of the patterns.
+func fileAllowed(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok { // Patterns are validated when the policy is saved
+			return true
+		}
+	}
+	return false
+}
+
+// breaksWire reports whether a kind of breaking change also breaks the binary encoding. Renamed fields
+// keep their numbers, and removed files may only have moved their definitions.
+func breaksWire(kind string) bool {
+	return kind != descriptor.ChangeFieldRenamed && kind != descriptor.ChangeFileRemoved
+}
+
+// majorVersionBump reports whether version has a higher major version than base.
+func majorVersionBump(base, version string) bool {
+	b, errBase := semver.NewVersion(base)
+	v, errVersion := semver.NewVersion(version)
+	return errBase == nil && errVersion == nil && v.Major() > b.Major()
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/policy"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for namespace policies ---
+
+func TestNamespacePolicies(t *testing.T) {
+	newTestDB(t)
+	newTestStorage(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	router.HandleFunc("/api/v1/admin/namespace-policies", ListNamespacePoliciesHandler).Methods("GET")
+	router.HandleFunc("/api/v1/admin/namespace-policies/{namespace}", GetNamespacePolicyHandler).Methods("GET")
+	router.HandleFunc("/api/v1/admin/namespace-policies/{namespace}", PutNamespacePolicyHandler).Methods("PUT")
+	router.HandleFunc("/api/v1/admin/namespace-policies/{namespace}", DeleteNamespacePolicyHandler).Methods("DELETE")
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	publish := func(version string, files map[string]string) *httptest.ResponseRecorder {
+		packed := map[string][]byte{}
+		for name, content := range files {
+			packed[name] = []byte(content)
+		}
+		data, err := artifact.Pack(packed)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newPublishRequest(t, "acme", "orders", version, "", data))
+		return rr
+	}
+	issues := func(rr *httptest.ResponseRecorder) []apitypes.ValidationIssue {
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+		var resp apitypes.ValidationFailedResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, fmt.Sprintf("Artifact failed validation with %d error(s)", len(resp.Issues)), resp.Error)
+		return resp.Issues
+	}
+	violations := func(rr *httptest.ResponseRecorder) []string {
+		names := []string{}
+		for _, issue := range issues(rr) {
+			names = append(names, issue.Rule)
+		}
+		return names
+	}
+
+	// --- CRUD ---
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/api/v1/admin/namespace-policies/acme", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("PUT", "/api/v1/admin/namespace-policies/acme", `{"lint_ruleset":"strict"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve("PUT", "/api/v1/admin/namespace-policies/acme", `{"compat_level":"binary"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve("PUT", "/api/v1/admin/namespace-policies/acme", `{"allowed_files":["[a-"]}`).Code)
+
+	rr := serve("PUT", "/api/v1/admin/namespace-policies/acme", `{"lint_ruleset":"basic","compat_level":"wire","allowed_files":["*.proto"," ","*.proto","*.md"],"monotonic":true,"http_rules":true}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = serve("GET", "/api/v1/admin/namespace-policies/acme", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var got apitypes.NamespacePolicyResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+	assert.Equal(t, apitypes.NamespacePolicyRequest{LintRuleset: "basic", CompatLevel: "wire", AllowedFiles: []string{"*.proto", "*.md"}, Monotonic: true, AllowedSyntaxes: []string{}, HTTPRules: true,
+		ContentTypes: []string{}, AllowedExtensions: []string{}}, got.NamespacePolicyRequest)
+	var list apitypes.ListNamespacePoliciesResponse
+	assert.NoError(t, json.Unmarshal(serve("GET", "/api/v1/admin/namespace-policies", "").Body.Bytes(), &list))
+	assert.Len(t, list.Policies, 1)
+
+	// --- Optimistic Concurrency ---
+	conditional := func(method, namespace, body, header, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/admin/namespace-policies/"+namespace, strings.NewReader(body))
+		req.Header.Set(header, etag)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	assert.Equal(t, int64(1), got.Revision)
+	assert.Equal(t, `"1"`, rr.Header().Get("ETag"))
+	policyBody := `{"lint_ruleset":"basic","compat_level":"wire","allowed_files":["*.proto","*.md"],"monotonic":true,"http_rules":true}`
+	assert.Equal(t, http.StatusPreconditionFailed, conditional("PUT", "acme", policyBody, "If-None-Match", "*").Code) // Exists
+	rr = conditional("PUT", "acme", policyBody, "If-Match", `"1"`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+
+	// A second edit based on revision 1 fails instead of overwriting the first one
+	rr = conditional("PUT", "acme", `{"lint_ruleset":"minimal"}`, "If-Match", `"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code, rr.Body.String())
+	assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+	assert.Contains(t, rr.Body.String(), "revision 2")
+	assert.Equal(t, http.StatusPreconditionFailed, conditional("PUT", "acme", policyBody, "If-Match", `W/"2"`).Code) // Strong comparison
+	assert.Equal(t, http.StatusPreconditionFailed, conditional("DELETE", "acme", "", "If-Match", `"1"`).Code)
+	assert.NoError(t, json.Unmarshal(serve("GET", "/api/v1/admin/namespace-policies/acme", "").Body.Bytes(), &got))
+	assert.Equal(t, "basic", got.LintRuleset)
+	assert.Equal(t, int64(2), got.Revision)
+
+	// Without a policy, If-Match fails and If-None-Match: * creates
+	assert.Equal(t, http.StatusPreconditionFailed, conditional("PUT", "other", policyBody, "If-Match", "*").Code)
+	assert.Equal(t, http.StatusOK, conditional("PUT", "other", policyBody, "If-None-Match", "*").Code)
+	assert.Equal(t, http.StatusNoContent, conditional("DELETE", "other", "", "If-Match", `"1"`).Code)
+
+	// --- Publish Checks ---
+	v1 := `syntax = "proto3"; package acme.orders.v1; message Order { string id = 1; string note = 2; }`
+	assert.Equal(t, http.StatusCreated, publish("v1.1.0", map[string]string{"acme/orders/v1/orders.proto": v1, "README.md": "# Orders"}).Code)
+
+	// Lint issues, disallowed files and an older version are all reported, with the file and line at fault
+	rr = publish("v1.0.0", map[string]string{"acme/orders/v1/orders.proto": "syntax = \"proto3\";\npackage acme.orders.v1;\n\nmessage Order {\n  string id = 1;\n  string note = 2;\n  string itemName = 3;\n}\n", "build.sh": "#!/bin/sh"})
+	assert.Equal(t, []apitypes.ValidationIssue{
+		{File: "build.sh", Rule: "allowed-files", Severity: apitypes.SeverityError, Message: "file build.sh matches none of the allowed patterns (*.proto, *.md)"},
+		{Rule: "monotonic", Severity: apitypes.SeverityError, Message: "version v1.0.0 is not newer than the newest published version v1.1.0"},
+		{File: "acme/orders/v1/orders.proto", Line: 7, Rule: "lint:FIELD_LOWER_SNAKE_CASE", Severity: apitypes.SeverityError, Message: "field acme.orders.v1.Order.itemName should be lower_snake_case"},
+	}, issues(rr))
+
+	// Wire level: renames are allowed, removals aren't without a major version bump
+	renamed := `syntax = "proto3"; package acme.orders.v1; message Order { string id = 1; string comment = 2; }`
+	assert.Equal(t, http.StatusCreated, publish("v1.2.0", map[string]string{"acme/orders/v1/orders.proto": renamed}).Code)
+	removed := `syntax = "proto3"; package acme.orders.v1; message Order { string id = 1; }`
+	assert.Equal(t, []string{"compat:field_removed"}, violations(publish("v1.3.0", map[string]string{"acme/orders/v1/orders.proto": removed})))
+	assert.Equal(t, http.StatusCreated, publish("v2.0.0", map[string]string{"acme/orders/v1/orders.proto": removed}).Code)
+
+	// Artifacts that don't compile can't be checked: every compile error is reported instead
+	rr = publish("v2.1.0", map[string]string{
+		"acme/orders/v1/orders.proto": "syntax = \"proto3\";\nmessage {",
+		"acme/orders/v1/items.proto":  "syntax = \"proto3\";\npackage acme.orders.v1;\nmessage Item { Missing m = 1; }",
+	})
+	compileIssues := issues(rr)
+	if assert.Len(t, compileIssues, 2) {
+		assert.Equal(t, apitypes.ValidationIssue{File: "acme/orders/v1/items.proto", Line: 3, Column: 16, Rule: apitypes.RuleCompile, Severity: apitypes.SeverityError}, apitypes.ValidationIssue{File: compileIssues[0].File, Line: compileIssues[0].Line, Column: compileIssues[0].Column, Rule: compileIssues[0].Rule, Severity: compileIssues[0].Severity})
+		assert.Equal(t, "acme/orders/v1/orders.proto", compileIssues[1].File)
+		assert.Equal(t, 2, compileIssues[1].Line)
+	}
+
+	// Publish policies are checked with the namespace policy, in the same response
+	engine, err := policy.New([]policy.Policy{{Name: "main-only", Expression: "branch == 'main'", Message: "publish from main"}})
+	assert.NoError(t, err)
+	policy.SetEngine(engine)
+	rr = publish("v1.0.0", map[string]string{"acme/orders/v1/orders.proto": removed})
+	policy.SetEngine(nil)
+	assert.Equal(t, []string{"monotonic", "policy:main-only"}, violations(rr))
+
+	// Without the policy, only the server-wide checks apply
+	assert.Equal(t, http.StatusNoContent, serve("DELETE", "/api/v1/admin/namespace-policies/acme", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("DELETE", "/api/v1/admin/namespace-policies/acme", "").Code)
+	assert.Equal(t, http.StatusCreated, publish("v1.5.0", map[string]string{"acme/orders/v1/orders.proto": v1, "build.sh": "#!/bin/sh"}).Code)
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/scan"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"go.uber.org/zap"
+)
+
+// OpenAPI documents: for modules with services annotated with google.api.http, an OpenAPI 3 document is
+// generated at publish and attached to the version as the secondary artifact "openapi", so API gateways
+// and portals can consume it without compiling the protos themselves.
+
+// OpenAPIClassifier is the secondary artifact classifier generated OpenAPI documents are attached under.
+const OpenAPIClassifier = "openapi"
+
+// GetModuleVersionOpenAPIHandler serves the OpenAPI document generated for a module version.
+// GET|HEAD /api/v1/modules/{namespace}/{module_name}/{version}/openapi.json
+// Same as fetching the "openapi" artifact; 404 if the version has no HTTP-annotated services.
+func GetModuleVersionOpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	serveVersionArtifact(w, r, OpenAPIClassifier, "No OpenAPI document for this version")
+}
+
+// readOpenAPI generates the OpenAPI document of an uploaded artifact (see generateOpenAPI). The file is
+// rewound afterwards.
+func readOpenAPI(ctx context.Context, file multipart.File, namespace, moduleName, version string) []byte {
+	artifact, err := io.ReadAll(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("Error reading artifact for OpenAPI generation", zap.Error(err))
+		return nil
+	}
+	return generateOpenAPI(ctx, artifact, namespace, moduleName, version)
+}
+
+// generateOpenAPI returns the OpenAPI document of an artifact's HTTP-annotated services, or nil.
+// Generation is best-effort and never fails a publish: artifacts without HTTP rules, that don't compile,
+// and errors simply get no document.
+func generateOpenAPI(ctx context.Context, artifact []byte, namespace, moduleName, version string) []byte {
+	log := logging.FromContext(ctx).With(zap.String("module", namespace+"/"+moduleName), zap.String("version", version))
+
+	loader := descriptor.NewLoader(db.GetDB(), storage.GetStorageProvider())
+	doc, err := loader.OpenAPIForArtifact(ctx, namespace, moduleName, version, artifact)
+	switch {
+	case errors.Is(err, descriptor.ErrNoHTTPRules):
+		return nil
+	case errors.Is(err, descriptor.ErrInvalidArtifact):
+		log.Debug("Artifact could not be compiled for OpenAPI generation", zap.Error(err))
+		return nil
+	case err != nil:
+		log.Warn("OpenAPI generation failed", zap.Error(err))
+		return nil
+	}
+	return doc
+}
+
+// attachOpenAPI attaches a generated OpenAPI document to a just-published version. Best-effort like the
+// generation: failures are logged, the version stays published without a document.
+func attachOpenAPI(r *http.Request, moduleVersion *models.ModuleVersion, namespace, moduleName string, doc []byte) {
+	if doc == nil {
+		return
+	}
+	log := logging.FromContext(r.Context()).With(zap.String("module", namespace+"/"+moduleName), zap.String("version", moduleVersion.Version))
+
+	sum := sha256.Sum256(doc)
+	digestHex := hex.EncodeToString(sum[:])
+	storageKey, err := uploadVersionArtifact(r, namespace, moduleName, moduleVersion.Version, OpenAPIClassifier, "application/json", doc, digestHex)
+	if err != nil {
+		log.Warn("Error uploading generated OpenAPI document", zap.String("key", storageKey), zap.Error(err))
+		return
+	}
+	versionArtifact := models.VersionArtifact{
+		ModuleVersionID: moduleVersion.ID,
+		Classifier:      OpenAPIClassifier,
+		ContentType:     "application/json",
+		Digest:          digestHex,
+		Size:            int64(len(doc)),
+		StorageKey:      storageKey,
+		ScanStatus:      scan.StatusSkipped, // Generated by the registry, not uploaded
+		CreatedAt:       time.Now().UTC(),
+	}
+	if err := db.GetDB().Create(&versionArtifact).Error; err != nil {
+		log.Warn("Error saving generated OpenAPI document", zap.Error(err))
+		return
+	}
+	log.Info("Attached generated OpenAPI document", zap.String("key", storageKey), zap.Int("size", len(doc)))
+}
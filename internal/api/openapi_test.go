@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/internal/wkt"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetModuleVersionOpenAPIHandler(t *testing.T) {
+	gormDB := newTestDB(t)
+	provider := newTestStorage(t)
+
+	// google/api (and google/protobuf, which it depends on) as seeded by seed-wkt
+	modules, err := wkt.Modules()
+	assert.NoError(t, err)
+	for _, m := range modules {
+		files := make(map[string][]byte, len(m.Files))
+		for p, content := range m.Files {
+			files[p] = []byte(content)
+		}
+		data, err := artifact.Pack(files)
+		assert.NoError(t, err)
+		key := m.Namespace + "/" + m.Name + "/" + m.Version + ".zip"
+		assert.NoError(t, provider.UploadFile(context.Background(), key, bytes.NewReader(data), int64(len(data)), "application/zip"))
+		module := models.Module{Namespace: m.Namespace, Name: m.Name, Visibility: models.VisibilityPublic}
+		assert.NoError(t, gormDB.Create(&module).Error)
+		assert.NoError(t, gormDB.Create(&models.ModuleVersion{ModuleID: module.ID, Version: m.Version, VersionKey: validation.VersionKey(m.Version), ArtifactDigest: m.Version, ArtifactStorageKey: key}).Error)
+	}
+
+	module := models.Module{Namespace: "acme", Name: "books", Visibility: models.VisibilityPublic}
+	assert.NoError(t, gormDB.Create(&module).Error)
+	annotated := models.ModuleVersion{ModuleID: module.ID, Version: "v1.0.0", VersionKey: validation.VersionKey("v1.0.0"), ArtifactDigest: "a", ArtifactStorageKey: "a"}
+	plain := models.ModuleVersion{ModuleID: module.ID, Version: "v1.1.0", VersionKey: validation.VersionKey("v1.1.0"), ArtifactDigest: "b", ArtifactStorageKey: "b"}
+	assert.NoError(t, gormDB.Create(&annotated).Error)
+	assert.NoError(t, gormDB.Create(&plain).Error)
+
+	req := httptest.NewRequest("POST", "/", nil)
+	annotatedArtifact, err := artifact.Pack(map[string][]byte{
+		"sproto.yaml": []byte("name: acme/books\ndependencies:\n  google/api: ^1.0.0\n"),
+		"acme/books/v1/books.proto": []byte(`syntax = "proto3"; package acme.books.v1; import "google/api/annotations.proto";
+message Book { string name = 1; }
+service Library { rpc GetBook(Book) returns (Book) { option (google.api.http) = { get: "/v1/{name=books/*}" }; } }`),
+	})
+	assert.NoError(t, err)
+	doc := generateOpenAPI(req.Context(), annotatedArtifact, "acme", "books", "v1.0.0")
+	assert.Contains(t, string(doc), `"/v1/{name}"`)
+	attachOpenAPI(req, &annotated, "acme", "books", doc)
+
+	// Modules without HTTP rules get no document
+	plainArtifact, err := artifact.Pack(map[string][]byte{
+		"acme/books/v1/books.proto": []byte(`syntax = "proto3"; package acme.books.v1; message Book { string name = 1; }`),
+	})
+	assert.NoError(t, err)
+	doc = generateOpenAPI(req.Context(), plainArtifact, "acme", "books", "v1.1.0")
+	assert.Nil(t, doc)
+	attachOpenAPI(req, &plain, "acme", "books", doc)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/openapi.json", GetModuleVersionOpenAPIHandler).Methods("GET", "HEAD")
+	get := func(version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/modules/acme/books/"+version+"/openapi.json", nil)
+		req = req.WithContext(context.WithValue(req.Context(), readerKey, reader{}))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("v1.0.0")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "immutable")
+	var served map[string]any
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &served))
+	assert.Equal(t, "3.0.3", served["openapi"])
+
+	rr = get("v1.1.0")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":"No OpenAPI document for this version"}`, rr.Body.String())
+}
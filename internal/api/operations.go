@@ -0,0 +1,533 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Operations: long-running work processed in the background by a pool of workers, such as asynchronous
+// publishes (?async=true) and admin jobs (imports, storage migrations, garbage collection, see jobs.go).
+// Starting one responds 202 Accepted with the operation; clients poll GET /api/v1/operations/{id} for
+// its status and current stage and, once finished, the status and body of the equivalent synchronous
+// request. Operations can be listed, and canceled while queued or running.
+//
+// An operation is queued -> running -> succeeded, failed or canceled. Queued work (e.g. an upload) is
+// kept in memory, so OPERATION_QUEUE_SIZE bounds the memory used; operations still queued or running when
+// the server stops are marked as failed at the next start.
+
+// OperationQueue configures the background workers.
+type OperationQueue struct {
+	Workers int // Operations processed concurrently; 0 disables asynchronous publishes and admin jobs
+	Size    int // Operations waiting for a worker before further requests are rejected with 503
+}
+
+// operationQueue holds the queued operations; nil when background operations are disabled.
+var operationQueue chan operationJob
+
+// operationWorkers is the number of workers draining operationQueue.
+var operationWorkers int
+
+// operationQueueCreatedAt is when operationQueue was created: operations queued before were lost with the
+// previous server's memory.
+var operationQueueCreatedAt time.Time
+
+// operationRetention is how long finished operations can be polled.
+const operationRetention = 24 * time.Hour
+
+// Operation listing limits.
+const (
+	DefaultOperationListLimit = 50
+	MaxOperationListLimit     = 500
+)
+
+// SetOperationQueue enables background operations with the given number of workers and queue size
+// (disabled if queue.Workers is 0). Workers are started by RunOperationWorkers.
+func SetOperationQueue(queue OperationQueue) {
+	if queue.Workers <= 0 {
+		operationQueue, operationWorkers = nil, 0
+		return
+	}
+	operationQueue = make(chan operationJob, max(queue.Size, 0))
+	operationWorkers = queue.Workers
+	operationQueueCreatedAt = time.Now().UTC()
+}
+
+// operationFunc does the work of an operation, writing its outcome like an HTTP handler: a 2xx status
+// means success. ctx is canceled if the operation is canceled.
+type operationFunc func(ctx context.Context, w http.ResponseWriter)
+
+// operationJob is a queued operation.
+type operationJob struct {
+	id  uuid.UUID
+	ctx context.Context // Values (logger, caller) of the request that started the operation
+	run operationFunc
+}
+
+// Errors of startOperation.
+var (
+	errOperationsDisabled = errors.New("background operations are disabled on this registry")
+	errOperationQueueFull = errors.New("operation queue is full")
+)
+
+// running holds the cancel functions of the operations being processed.
+var running = struct {
+	sync.Mutex
+	cancels map[uuid.UUID]context.CancelFunc
+}{cancels: map[uuid.UUID]context.CancelFunc{}}
+
+// startOperation records op as queued and queues run for a worker. The values of ctx (not its
+// cancellation) are passed on to run.
+func startOperation(ctx context.Context, op *models.Operation, run operationFunc) error {
+	if operationQueue == nil {
+		return errOperationsDisabled
+	}
+	op.Status = models.OperationQueued
+	op.CreatedAt = time.Now().UTC()
+	if err := db.GetDB().WithContext(ctx).Create(op).Error; err != nil {
+		return fmt.Errorf("failed to create operation: %w", err)
+	}
+
+	select {
+	case operationQueue <- operationJob{id: op.ID, ctx: context.WithoutCancel(ctx), run: run}:
+		logging.FromContext(ctx).Info("Queued operation", zap.Stringer("operation_id", op.ID), zap.String("kind", op.Kind))
+		return nil
+	default:
+		// Fail the operation right away rather than holding more work in memory
+		finishOperation(context.Background(), op.ID, http.StatusServiceUnavailable, nil, "Operation queue is full", false)
+		return errOperationQueueFull
+	}
+}
+
+// respondOperationStarted writes the response of a request that started an operation (202 Accepted and
+// its polling URL), or the error startOperation returned.
+func respondOperationStarted(w http.ResponseWriter, r *http.Request, op *models.Operation, err error) {
+	switch {
+	case errors.Is(err, errOperationsDisabled):
+		response.Error(w, http.StatusBadRequest, "Background operations are disabled on this registry")
+	case errors.Is(err, errOperationQueueFull):
+		w.Header().Set("Retry-After", "30")
+		response.Error(w, http.StatusServiceUnavailable, "Operation queue is full, retry later")
+	case err != nil:
+		logging.FromContext(r.Context()).Error("Error starting operation", zap.String("kind", op.Kind), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error creating operation")
+	default:
+		w.Header().Set("Location", "/api/v1/operations/"+op.ID.String())
+		response.JSON(w, http.StatusAccepted, newOperationResponse(op))
+	}
+}
+
+type operationStageKey struct{}
+
+// reportStage records the current stage of the operation running with ctx (a no-op outside of
+// operations, e.g. for synchronous publishes).
+func reportStage(ctx context.Context, stage string) {
+	if report, ok := ctx.Value(operationStageKey{}).(func(string)); ok {
+		report(stage)
+	}
+}
+
+// --- API ---
+
+func newOperationResponse(op *models.Operation) apitypes.OperationResponse {
+	resp := apitypes.OperationResponse{
+		ID:         op.ID.String(),
+		Kind:       op.Kind,
+		Namespace:  op.Namespace,
+		ModuleName: op.ModuleName,
+		Version:    op.Version,
+		Status:     op.Status,
+		Stage:      op.Stage,
+		CreatedAt:  op.CreatedAt,
+		StartedAt:  op.StartedAt,
+		FinishedAt: op.FinishedAt,
+		HTTPStatus: op.HTTPStatus,
+		Error:      op.Error,
+	}
+	if op.Params != "" {
+		_ = json.Unmarshal([]byte(op.Params), &resp.Params)
+	}
+	if op.Result != "" && json.Valid([]byte(op.Result)) {
+		resp.Result = json.RawMessage(op.Result)
+	}
+	return resp
+}
+
+// ListOperationsHandler lists operations, newest first. Optional filters: ?kind=, ?status= and ?limit=
+// (default 50, at most 500).
+// GET /api/v1/operations
+// Requires Authentication.
+func ListOperationsHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	limit := DefaultOperationListLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 || n > MaxOperationListLimit {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit: must be between 1 and %d", MaxOperationListLimit))
+			return
+		}
+		limit = n
+	}
+
+	// Not from the read replica: clients poll right after starting operations
+	query := db.GetDB().WithContext(r.Context()).Model(&models.Operation{})
+	if kind := r.URL.Query().Get("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var ops []models.Operation
+	if err := query.Order("created_at DESC").Limit(limit).Find(&ops).Error; err != nil {
+		log.Error("Error listing operations", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve operations")
+		return
+	}
+	resp := apitypes.ListOperationsResponse{Operations: make([]apitypes.OperationResponse, 0, len(ops))}
+	for i := range ops {
+		resp.Operations = append(resp.Operations, newOperationResponse(&ops[i]))
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// GetOperationHandler returns the status of a background operation.
+// GET /api/v1/operations/{id}
+// Requires Authentication.
+func GetOperationHandler(w http.ResponseWriter, r *http.Request) {
+	op, ok := findOperation(w, r)
+	if !ok {
+		return // Response already written
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if op.Status == models.OperationQueued || op.Status == models.OperationRunning {
+		w.Header().Set("Retry-After", "1")
+	}
+	response.JSON(w, http.StatusOK, newOperationResponse(op))
+}
+
+// CancelOperationHandler cancels a queued or running operation. Queued operations are canceled right
+// away; running ones are interrupted and become canceled once their worker has stopped (work already
+// done, such as versions imported so far, is kept). Responds with the operation; 409 if it has finished.
+// POST /api/v1/operations/{id}/cancel
+// Requires Authentication.
+func CancelOperationHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	op, ok := findOperation(w, r)
+	if !ok {
+		return // Response already written
+	}
+	gormDB := db.GetDB().WithContext(r.Context())
+
+	switch op.Status {
+	case models.OperationQueued:
+		// The condition guards against a worker picking it up meanwhile (the worker skips canceled operations)
+		now := time.Now().UTC()
+		result := gormDB.Model(&models.Operation{}).Where("id = ? AND status = ?", op.ID, models.OperationQueued).
+			Updates(map[string]any{"status": models.OperationCanceled, "error": "Canceled before it started", "finished_at": now})
+		if result.Error != nil {
+			log.Error("Error canceling operation", zap.Stringer("operation_id", op.ID), zap.Error(result.Error))
+			response.Error(w, http.StatusInternalServerError, "Failed to cancel operation")
+			return
+		}
+		if result.RowsAffected == 0 {
+			cancelRunning(op.ID) // Started meanwhile
+		}
+	case models.OperationRunning:
+		if !cancelRunning(op.ID) {
+			// Run by another replica, or interrupted by a restart and not yet marked as failed
+			response.Error(w, http.StatusConflict, "Operation is not running on this server")
+			return
+		}
+	default:
+		response.Error(w, http.StatusConflict, fmt.Sprintf("Operation has already %s", op.Status))
+		return
+	}
+	log.Info("Canceled operation", zap.Stringer("operation_id", op.ID), zap.String("kind", op.Kind), zap.String("status", op.Status))
+
+	if err := gormDB.First(op, "id = ?", op.ID).Error; err != nil {
+		log.Warn("Error reloading canceled operation", zap.Stringer("operation_id", op.ID), zap.Error(err))
+	}
+	response.JSON(w, http.StatusOK, newOperationResponse(op))
+}
+
+// findOperation loads the operation named by the {id} path variable, writing a 404 if there is none.
+func findOperation(w http.ResponseWriter, r *http.Request) (*models.Operation, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Operation not found")
+		return nil, false
+	}
+	var op models.Operation
+	if err := db.GetDB().WithContext(r.Context()).First(&op, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(w, http.StatusNotFound, "Operation not found")
+		} else {
+			logging.FromContext(r.Context()).Error("Error finding operation", zap.Stringer("operation_id", id), zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to retrieve operation")
+		}
+		return nil, false
+	}
+	return &op, true
+}
+
+// cancelRunning interrupts an operation running on this server. Returns false if it isn't.
+func cancelRunning(id uuid.UUID) bool {
+	running.Lock()
+	defer running.Unlock()
+	cancel, ok := running.cancels[id]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// --- Workers ---
+
+// RunOperationWorkers processes queued operations until ctx is canceled. Operations left queued or
+// running by a previous run of the server are marked as failed first, and finished operations are
+// deleted once they're older than a day.
+func RunOperationWorkers(ctx context.Context) {
+	queue, workers := operationQueue, operationWorkers
+	if queue == nil {
+		return
+	}
+	if err := failInterruptedOperations(ctx); err != nil {
+		logging.L().Warn("Failed to mark interrupted operations as failed", zap.String("job", "operations"), zap.Error(err))
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-queue:
+					runOperation(ctx, job)
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if _, err := purgeOperations(ctx, time.Now().UTC().Add(-operationRetention)); err != nil {
+			logging.L().Warn("Failed to delete finished operations", zap.String("job", "operations"), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOperation runs a queued operation, unless it was canceled meanwhile, and records its outcome.
+func runOperation(ctx context.Context, job operationJob) {
+	log := logging.FromContext(job.ctx).With(zap.Stringer("operation_id", job.id))
+	gormDB := db.GetDB().WithContext(ctx)
+
+	started := time.Now().UTC()
+	result := gormDB.Model(&models.Operation{}).Where("id = ? AND status = ?", job.id, models.OperationQueued).
+		Updates(map[string]any{"status": models.OperationRunning, "started_at": started})
+	if result.Error != nil {
+		log.Warn("Error marking operation as running", zap.Error(result.Error))
+	} else if result.RowsAffected == 0 {
+		log.Info("Skipping canceled operation")
+		return
+	}
+
+	// Canceled by CancelOperationHandler (or the server shutting down); the values of the starting
+	// request are kept. Stages are recorded as they're reached (best-effort: only progress information).
+	runCtx, cancel := context.WithCancel(logging.WithLogger(job.ctx, log))
+	stop := context.AfterFunc(ctx, cancel)
+	running.Lock()
+	running.cancels[job.id] = cancel
+	running.Unlock()
+	defer func() {
+		running.Lock()
+		delete(running.cancels, job.id)
+		running.Unlock()
+		stop()
+		cancel()
+	}()
+	runCtx = context.WithValue(runCtx, operationStageKey{}, func(stage string) {
+		if len(stage) > 128 {
+			stage = stage[:128]
+		}
+		if err := gormDB.Model(&models.Operation{}).Where("id = ?", job.id).Update("stage", stage).Error; err != nil {
+			log.Warn("Error recording operation stage", zap.String("stage", stage), zap.Error(err))
+		}
+	})
+
+	rec := newOperationRecorder()
+	func() {
+		defer func() {
+			// Like RecoveryMiddleware: a panicking operation fails, not the server
+			if p := recover(); p != nil {
+				log.Error("Panic while running operation", zap.Any("panic", p))
+				rec = newOperationRecorder()
+				response.Error(rec, http.StatusInternalServerError, "Internal server error")
+			}
+		}()
+		job.run(runCtx, rec)
+	}()
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	errMessage := ""
+	if rec.status < 200 || rec.status > 299 {
+		var errResp response.ErrorResponse
+		if json.Unmarshal(rec.body.Bytes(), &errResp) == nil && errResp.Error != "" {
+			errMessage = errResp.Error
+		} else {
+			errMessage = http.StatusText(rec.status)
+		}
+	}
+	// Work that failed because it was interrupted was canceled; work that completed anyway succeeded
+	canceled := runCtx.Err() != nil && errMessage != ""
+	finishOperation(context.WithoutCancel(ctx), job.id, rec.status, rec.body.Bytes(), errMessage, canceled)
+	log.Info("Finished operation", zap.Int("status", rec.status), zap.Bool("canceled", canceled), zap.Duration("duration", time.Since(started)))
+}
+
+// finishOperation records the outcome of an operation: succeeded for a 2xx status, failed (or canceled)
+// otherwise.
+func finishOperation(ctx context.Context, id uuid.UUID, status int, body []byte, errMessage string, canceled bool) {
+	opStatus := models.OperationSucceeded
+	switch {
+	case canceled:
+		opStatus = models.OperationCanceled
+		errMessage = "Canceled: " + errMessage
+	case status < 200 || status > 299:
+		opStatus = models.OperationFailed
+	}
+	err := db.GetDB().WithContext(ctx).Model(&models.Operation{}).Where("id = ?", id).Updates(map[string]any{
+		"status":      opStatus,
+		"stage":       "",
+		"http_status": status,
+		"result":      string(bytes.TrimSpace(body)),
+		"error":       errMessage,
+		"finished_at": time.Now().UTC(),
+	}).Error
+	if err != nil {
+		logging.L().Error("Error recording operation result", zap.Stringer("operation_id", id), zap.Int("status", status), zap.Error(err))
+	}
+}
+
+// failInterruptedOperations marks the operations a previous run of the server didn't finish as failed:
+// their work was only held in memory. Operations created since the queue was set up are left alone.
+func failInterruptedOperations(ctx context.Context) error {
+	result := db.GetDB().WithContext(ctx).Model(&models.Operation{}).
+		Where("status IN ? AND created_at < ?", []string{models.OperationQueued, models.OperationRunning}, operationQueueCreatedAt).
+		Updates(map[string]any{
+			"status":      models.OperationFailed,
+			"stage":       "",
+			"error":       "Interrupted by a server restart, start it again",
+			"finished_at": time.Now().UTC(),
+		})
+	if result.Error == nil && result.RowsAffected > 0 {
+		logging.L().Warn("Marked interrupted operations as failed", zap.String("job", "operations"), zap.Int64("operations", result.RowsAffected))
+	}
+	return result.Error
+}
+
+// purgeOperations deletes the operations that finished before cutoff. Returns how many it deleted.
+func purgeOperations(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := db.GetDB().WithContext(ctx).Where("finished_at IS NOT NULL AND finished_at < ?", cutoff).Delete(&models.Operation{})
+	return result.RowsAffected, result.Error
+}
+
+// operationRecorder captures the outcome an operation writes.
+type operationRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newOperationRecorder() *operationRecorder {
+	return &operationRecorder{header: http.Header{}}
+}
+
+func (rec *operationRecorder) Header() http.Header { return rec.header }
+
+func (rec *operationRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *operationRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(p)
+}
+
+// --- Asynchronous Publish ---
+
+// Publish stages reported while an asynchronous publish runs.
+const (
+	StageCanonicalizing = "canonicalizing"
+	StageScanning       = "scanning"
+	StageValidating     = "validating"
+	StageStoring        = "storing"
+)
+
+// enqueuePublish accepts an asynchronous publish (POST .../{version}?async=true): the body is read and
+// the publish is queued as an operation, which replays the request without ?async. Responds 202 with the
+// operation.
+func enqueuePublish(w http.ResponseWriter, r *http.Request, namespace, moduleName, versionStr string) {
+	if operationQueue == nil {
+		respondOperationStarted(w, r, nil, errOperationsDisabled)
+		return
+	}
+
+	// The upload is read now: the request (and its body) is gone once the 202 has been sent
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, requestLimits.MaxUploadBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			response.Error(w, http.StatusRequestEntityTooLarge, uploadLimitMessage())
+		} else {
+			logging.FromContext(r.Context()).Warn("Error reading asynchronous publish body", zap.Error(err))
+			response.Error(w, http.StatusBadRequest, "Could not read request body")
+		}
+		return
+	}
+
+	query := r.URL.Query()
+	query.Del("async")
+	replay := r.Clone(context.WithoutCancel(r.Context()))
+	replay.URL.RawQuery = query.Encode()
+	replay.RequestURI = ""
+
+	op := &models.Operation{Kind: models.OperationKindPublish, Namespace: namespace, ModuleName: moduleName, Version: versionStr}
+	err = startOperation(r.Context(), op, func(ctx context.Context, w http.ResponseWriter) {
+		req := replay.WithContext(ctx)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		PublishModuleVersionHandler(w, req)
+		if req.MultipartForm != nil {
+			_ = req.MultipartForm.RemoveAll() // Temporary files of large uploads
+		}
+	})
+	respondOperationStarted(w, r, op, err)
+}
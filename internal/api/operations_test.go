@@ -0,0 +1,109 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncPublish(t *testing.T) {
+	gormDB := newTestDB(t)
+	newTestStorage(t)
+
+	// An operation left running by a previous run of the server
+	interrupted := models.Operation{Kind: models.OperationKindPublish, Namespace: "acme", ModuleName: "user", Version: "v0.1.0", Status: models.OperationRunning, CreatedAt: time.Now().UTC().Add(-time.Minute)}
+	assert.NoError(t, gormDB.Create(&interrupted).Error)
+
+	SetOperationQueue(OperationQueue{Workers: 1, Size: 4})
+	t.Cleanup(func() { SetOperationQueue(OperationQueue{}) })
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go RunOperationWorkers(ctx)
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create("acme/user/v1/user.proto")
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(`syntax = "proto3"; package acme.user.v1;`))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	router.HandleFunc("/api/v1/operations/{id}", GetOperationHandler).Methods("GET")
+	getOperation := func(location string) apitypes.OperationResponse {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", location, nil))
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var op apitypes.OperationResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &op))
+		return op
+	}
+	publish := func(version string) apitypes.OperationResponse {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newPublishRequest(t, "acme", "user", version, "?async=true", buf.Bytes()))
+		assert.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+		var accepted apitypes.OperationResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &accepted))
+		assert.Equal(t, "queued", accepted.Status)
+		assert.Equal(t, "/api/v1/operations/"+accepted.ID, rr.Header().Get("Location"))
+
+		var op apitypes.OperationResponse
+		assert.Eventually(t, func() bool {
+			op = getOperation(rr.Header().Get("Location"))
+			return op.Status != "queued" && op.Status != "running"
+		}, 10*time.Second, 10*time.Millisecond)
+		return op
+	}
+
+	// The result is the response of the synchronous publish
+	op := publish("v1.0.0")
+	assert.Equal(t, "succeeded", op.Status)
+	assert.Equal(t, http.StatusCreated, op.HTTPStatus)
+	assert.NotNil(t, op.FinishedAt)
+	var published apitypes.PublishModuleVersionResponse
+	assert.NoError(t, json.Unmarshal(op.Result, &published))
+	assert.Equal(t, "v1.0.0", published.Version)
+	assert.Empty(t, op.Stage)
+	var count int64
+	assert.NoError(t, gormDB.Model(&models.ModuleVersion{}).Where("version = ?", "v1.0.0").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// Failures keep the synchronous status and error
+	op = publish("v1.0.0")
+	assert.Equal(t, "failed", op.Status)
+	assert.Equal(t, http.StatusConflict, op.HTTPStatus)
+	assert.Contains(t, op.Error, "already exists")
+
+	// The interrupted operation was failed before the workers started
+	op = getOperation("/api/v1/operations/" + interrupted.ID.String())
+	assert.Equal(t, "failed", op.Status)
+	assert.Contains(t, op.Error, "server restart")
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/operations/"+uuid.NewString(), nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Finished operations are deleted after the retention period
+	purged, err := purgeOperations(context.Background(), time.Now().UTC().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+
+	// Disabled: asynchronous publishes are rejected
+	cancel()
+	SetOperationQueue(OperationQueue{})
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, newPublishRequest(t, "acme", "user", "v1.1.0", "?async=true", buf.Bytes()))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
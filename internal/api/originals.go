@@ -0,0 +1,260 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Original uploads: the registry stores the canonical form of published zips (see canonicalizeArtifact), so
+// the bytes a publisher actually sent are otherwise lost. With ORIGINAL_UPLOAD_RETENTION set, uploads that
+// differ from their canonical form are also kept, addressable by their digest, until the retention period
+// has passed; admins can retrieve them for audits or disputes. A cleanup job deletes expired ones.
+
+// originalUploadRetention is how long original uploads are kept; 0 (the default) keeps none.
+var originalUploadRetention time.Duration
+
+// SetOriginalUploadRetention sets how long the original bytes of re-packed uploads are kept (0 disables it).
+func SetOriginalUploadRetention(retention time.Duration) {
+	originalUploadRetention = retention
+}
+
+// retainOriginalUpload stores the original bytes a version was published with, if retention is enabled
+// and they differ from the stored artifact (original is nil otherwise). Like attaching the generated
+// OpenAPI document, it is best-effort: the version is already committed. Returns the hex digest of the
+// original, or "" if it wasn't kept.
+func retainOriginalUpload(r *http.Request, moduleVersion *models.ModuleVersion, original []byte) string {
+	if originalUploadRetention <= 0 || original == nil {
+		return ""
+	}
+	log := logging.FromContext(r.Context()).With(zap.Stringer("module_version_id", moduleVersion.ID), zap.String("version", moduleVersion.Version))
+
+	sum := sha256.Sum256(original)
+	digestHex := hex.EncodeToString(sum[:])
+	storageProvider := storage.GetStorageProvider()
+	storageKey := storage.OriginalUploadKey(digestHex)
+	err := storageProvider.UploadFile(r.Context(), storageKey, bytes.NewReader(original), int64(len(original)), "application/zip")
+	if errors.Is(err, storage.ErrObjectExists) {
+		// Another version was uploaded with the same bytes (or an earlier attempt left the object behind)
+		err = reuseExistingArtifact(r, storageProvider, storageKey, digestHex)
+	}
+	if err != nil {
+		log.Warn("Error storing original upload", zap.String("key", storageKey), zap.Error(err))
+		return ""
+	}
+
+	now := time.Now().UTC()
+	originalUpload := models.OriginalUpload{
+		ModuleVersionID: moduleVersion.ID,
+		Digest:          digestHex,
+		Size:            int64(len(original)),
+		StorageKey:      storageKey,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(originalUploadRetention),
+	}
+	if err := db.GetDB().WithContext(r.Context()).Create(&originalUpload).Error; err != nil {
+		// The object is left for the next upload with the same bytes; without a record it is never served
+		log.Warn("Error saving original upload", zap.Error(err))
+		return ""
+	}
+	log.Info("Kept original upload", zap.String("key", storageKey), zap.Int("size", len(original)), zap.Time("expires_at", originalUpload.ExpiresAt))
+	return digestHex
+}
+
+// --- Admin Endpoints ---
+
+// OriginalUploadResponse describes a kept original upload.
+type OriginalUploadResponse struct {
+	Namespace  string    `json:"namespace"`
+	ModuleName string    `json:"module_name"`
+	Version    string    `json:"version"`
+	Digest     string    `json:"digest"` // sha256:<hex_digest> of the uploaded bytes
+	Size       int64     `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// ListOriginalUploadsResponse is the response of ListOriginalUploadsHandler.
+type ListOriginalUploadsResponse struct {
+	Originals []OriginalUploadResponse `json:"originals"`
+}
+
+// originalUploadRow is an original upload joined with its module version.
+type originalUploadRow struct {
+	Namespace string
+	Name      string
+	Version   string
+	Digest    string
+	Size      int64
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// ListOriginalUploadsHandler lists the original uploads that haven't expired, newest first. Optional
+// filters: ?module=namespace/name, ?version=v1.2.0 (with module) and ?digest=sha256:<hex>.
+// GET /api/v1/admin/originals
+// Requires Authentication (admin token).
+func ListOriginalUploadsHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	query := requestDB(r).Table("original_uploads ou").
+		Select("m.namespace, m.name, mv.version, ou.digest, ou.size, ou.created_at, ou.expires_at").
+		Joins("JOIN module_versions mv ON mv.id = ou.module_version_id").
+		Joins("JOIN modules m ON m.id = mv.module_id").
+		Where("ou.expires_at > ?", time.Now().UTC())
+
+	if module := r.URL.Query().Get("module"); module != "" {
+		namespace, name, found := strings.Cut(module, "/")
+		if !found || namespace == "" || name == "" {
+			response.Error(w, http.StatusBadRequest, "Invalid module: expected namespace/module_name")
+			return
+		}
+		query = query.Where("m.namespace = ? AND m.name = ?", namespace, name)
+		if version := r.URL.Query().Get("version"); version != "" {
+			query = query.Where("mv.version = ?", version)
+		}
+	} else if r.URL.Query().Get("version") != "" {
+		response.Error(w, http.StatusBadRequest, "The version filter requires a module")
+		return
+	}
+	if digest := r.URL.Query().Get("digest"); digest != "" {
+		query = query.Where("ou.digest = ?", strings.TrimPrefix(digest, "sha256:"))
+	}
+
+	var rows []originalUploadRow
+	if err := query.Order("ou.created_at DESC").Scan(&rows).Error; err != nil {
+		log.Error("Error listing original uploads", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve original uploads")
+		return
+	}
+	resp := ListOriginalUploadsResponse{Originals: make([]OriginalUploadResponse, 0, len(rows))}
+	for _, row := range rows {
+		resp.Originals = append(resp.Originals, OriginalUploadResponse{
+			Namespace:  row.Namespace,
+			ModuleName: row.Name,
+			Version:    row.Version,
+			Digest:     "sha256:" + row.Digest,
+			Size:       row.Size,
+			CreatedAt:  row.CreatedAt,
+			ExpiresAt:  row.ExpiresAt,
+		})
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// GetOriginalUploadHandler streams the original upload with the given digest ("sha256:<hex>" or "<hex>"),
+// responding 404 once it has expired.
+// GET /api/v1/admin/originals/{digest}
+// Requires Authentication (admin token).
+func GetOriginalUploadHandler(w http.ResponseWriter, r *http.Request) {
+	digestHex := strings.TrimPrefix(mux.Vars(r)["digest"], "sha256:")
+	log := logging.FromContext(r.Context()).With(zap.String("digest", digestHex))
+
+	var originalUpload models.OriginalUpload
+	err := requestDB(r).Where("digest = ? AND expires_at > ?", digestHex, time.Now().UTC()).First(&originalUpload).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.Error(w, http.StatusNotFound, "Original upload not found or expired")
+		} else {
+			log.Error("Error finding original upload", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to retrieve original upload")
+		}
+		return
+	}
+
+	stream, err := storage.GetStorageProvider().DownloadFile(r.Context(), originalUpload.StorageKey)
+	if err != nil {
+		switch status := storageErrorStatus(err); status {
+		case http.StatusNotFound:
+			log.Warn("Original upload not found in storage", zap.String("key", originalUpload.StorageKey), zap.Error(err))
+			response.Error(w, status, "Original upload not found in storage")
+		case http.StatusServiceUnavailable:
+			log.Error("Storage unavailable while downloading original upload", zap.String("key", originalUpload.StorageKey), zap.Error(err))
+			response.Error(w, status, "Artifact storage unavailable")
+		default:
+			log.Error("Error downloading original upload from storage", zap.String("key", originalUpload.StorageKey), zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to retrieve original upload from storage")
+		}
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Length", strconv.FormatInt(originalUpload.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="original-%s.zip"`, digestHex))
+	w.Header().Set("X-Artifact-Digest", "sha256:"+digestHex)
+	w.Header().Set("X-Expires-At", originalUpload.ExpiresAt.UTC().Format(time.RFC3339))
+	if _, err := io.Copy(w, stream); err != nil {
+		// The client may have disconnected; headers are already sent
+		log.Warn("Error streaming original upload to client", zap.Error(err))
+	}
+}
+
+// --- Cleanup Job ---
+
+// purgeOriginalUploads deletes the original uploads that expired before now, and their objects unless
+// another version's unexpired record still uses them. Returns how many records it deleted.
+func purgeOriginalUploads(ctx context.Context, now time.Time) (int, error) {
+	gormDB := db.GetDB().WithContext(ctx)
+	var expired []models.OriginalUpload
+	if err := gormDB.Where("expires_at <= ?", now).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, originalUpload := range expired {
+		if err := gormDB.Where("module_version_id = ?", originalUpload.ModuleVersionID).Delete(&models.OriginalUpload{}).Error; err != nil {
+			return purged, err
+		}
+		purged++
+
+		var remaining int64
+		if err := gormDB.Model(&models.OriginalUpload{}).Where("storage_key = ?", originalUpload.StorageKey).Count(&remaining).Error; err != nil {
+			return purged, err
+		}
+		if remaining > 0 {
+			continue // Shared with a version uploaded with the same bytes
+		}
+		err := storage.GetStorageProvider().DeleteFile(ctx, originalUpload.StorageKey)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			// The record is gone, so the object is no longer served; a later upload with the same bytes reuses it
+			logging.L().Warn("Failed to delete expired original upload", zap.String("job", "original-uploads"), zap.String("key", originalUpload.StorageKey), zap.Error(err))
+			continue
+		}
+		logging.L().Info("Deleted expired original upload", zap.String("job", "original-uploads"), zap.String("key", originalUpload.StorageKey))
+	}
+	return purged, nil
+}
+
+// RunOriginalUploadCleanup deletes expired original uploads every interval (and once at start) until ctx
+// is canceled.
+func RunOriginalUploadCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := purgeOriginalUploads(ctx, time.Now().UTC()); err != nil {
+			logging.L().Warn("Failed to delete expired original uploads", zap.String("job", "original-uploads"), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for original uploads ---
+
+func TestOriginalUploadRetention(t *testing.T) {
+	gormDB := newTestDB(t)
+	provider := newTestStorage(t)
+	SetOriginalUploadRetention(24 * time.Hour)
+	t.Cleanup(func() { SetOriginalUploadRetention(0) })
+
+	// Stored (uncompressed) with a directory entry, so the canonical archive differs
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	_, err := zw.Create("user/")
+	assert.NoError(t, err)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "user/user.proto", Method: zip.Store, Modified: time.Now()})
+	assert.NoError(t, err)
+	_, err = w.Write([]byte(`syntax = "proto3";`))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	uploaded := buf.Bytes()
+	sum := sha256.Sum256(uploaded)
+	originalDigest := "sha256:" + hex.EncodeToString(sum[:])
+	canonical, err := artifact.Canonicalize(uploaded)
+	assert.NoError(t, err)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	router.HandleFunc("/api/v1/admin/originals", ListOriginalUploadsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/admin/originals/{digest}", GetOriginalUploadHandler).Methods("GET")
+	publish := func(version string, data []byte) apitypes.PublishModuleVersionResponse {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newPublishRequest(t, "acme", "user", version, "", data))
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var resp apitypes.PublishModuleVersionResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr
+	}
+
+	// The original is kept next to the canonical archive, and reported on publish
+	assert.Equal(t, originalDigest, publish("v1.0.0", uploaded).OriginalDigest)
+	// The same bytes for another version share the object; canonical uploads keep nothing
+	assert.Equal(t, originalDigest, publish("v1.1.0", uploaded).OriginalDigest)
+	assert.Empty(t, publish("v1.2.0", canonical).OriginalDigest)
+
+	rr := get("/api/v1/admin/originals?module=acme/user&version=v1.0.0")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list ListOriginalUploadsResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	assert.Len(t, list.Originals, 1)
+	assert.Equal(t, originalDigest, list.Originals[0].Digest)
+	assert.Equal(t, int64(len(uploaded)), list.Originals[0].Size)
+	assert.Equal(t, http.StatusBadRequest, get("/api/v1/admin/originals?version=v1.0.0").Code)
+
+	rr = get("/api/v1/admin/originals/" + originalDigest)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, uploaded, rr.Body.Bytes())
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/admin/originals/sha256:"+strings.Repeat("0", 64)).Code)
+
+	// Expired originals are deleted; the object goes with the last version using it
+	key := storage.OriginalUploadKey(hex.EncodeToString(sum[:]))
+	var v1 models.ModuleVersion
+	assert.NoError(t, gormDB.Where("version = ?", "v1.0.0").First(&v1).Error)
+	assert.NoError(t, gormDB.Model(&models.OriginalUpload{}).Where("module_version_id = ?", v1.ID).Update("expires_at", time.Now().UTC().Add(-time.Minute)).Error)
+	purged, err := purgeOriginalUploads(context.Background(), time.Now().UTC())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	exists, err := provider.FileExists(context.Background(), key)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	purged, err = purgeOriginalUploads(context.Background(), time.Now().UTC().Add(48*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	exists, err = provider.FileExists(context.Background(), key)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/admin/originals/"+originalDigest).Code)
+}
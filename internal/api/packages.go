@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Package index: the proto packages declared by each published version (e.g. mycompany.user.v1) are
+// mapped to their module, so tooling can find which module to depend on from an import statement
+// (GET /api/v1/packages/{package}). A package stays mapped to a module once any of its versions declared
+// it. With PACKAGE_OWNERSHIP=unique (the default), a package belongs to the first module publishing it, and
+// publishes of other modules declaring it are rejected (409). Ephemeral namespaces are neither indexed nor
+// checked, so preview builds can republish copies of other modules' packages. Versions published before
+// the index existed are indexed by the reindex-packages admin job.
+
+// Package ownership modes.
+const (
+	PackageOwnershipUnique = "unique" // A package belongs to one module
+	PackageOwnershipShared = "shared" // Several modules may declare a package
+)
+
+// packageOwnership is the configured mode; see SetPackageOwnership.
+var packageOwnership = PackageOwnershipUnique
+
+// SetPackageOwnership sets whether a package may be declared by several modules ("unique" or "shared";
+// empty means unique).
+func SetPackageOwnership(mode string) error {
+	mode = strings.ToLower(mode)
+	if mode == "" {
+		mode = PackageOwnershipUnique
+	}
+	if mode != PackageOwnershipUnique && mode != PackageOwnershipShared {
+		return fmt.Errorf("invalid package ownership %q: must be %s or %s", mode, PackageOwnershipUnique, PackageOwnershipShared)
+	}
+	packageOwnership = mode
+	return nil
+}
+
+// protoPackagePattern matches a proto package name: dot-separated identifiers.
+var protoPackagePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// --- Indexing ---
+
+// checkPackageOwnership runs evaluatePackageOwnership on an uploaded artifact. The file is rewound
+// afterwards.
+func checkPackageOwnership(w http.ResponseWriter, r *http.Request, file multipart.File, nsPolicy *models.NamespacePolicy, namespace, moduleName string) ([]string, bool) {
+	artifact, err := io.ReadAll(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error reading artifact for the package index", zap.Error(err))
+		response.Error(w, http.StatusBadRequest, "Could not read artifact file")
+		return nil, false
+	}
+	return evaluatePackageOwnership(w, r, nsPolicy, artifact, namespace, moduleName)
+}
+
+// evaluatePackageOwnership reads the packages declared by an artifact about to be published to
+// namespace/moduleName and, with PACKAGE_OWNERSHIP=unique, rejects the publish (409) if another module
+// declares one of them. nsPolicy is the namespace's policy (nil if it has none). Returns the packages to
+// index, nil if there is nothing to index (an ephemeral namespace, or files that don't parse, which the
+// import graph check reports), and false if the publish must be rejected; the response has then been
+// written.
+func evaluatePackageOwnership(w http.ResponseWriter, r *http.Request, nsPolicy *models.NamespacePolicy, artifact []byte, namespace, moduleName string) ([]string, bool) {
+	log := logging.FromContext(r.Context())
+	if nsPolicy != nil && nsPolicy.EphemeralTTLSeconds > 0 {
+		return nil, true
+	}
+
+	packages, err := descriptor.ArtifactPackages(artifact)
+	if err != nil {
+		log.Warn("Failed to read the packages of the artifact", zap.Error(err))
+		return nil, true
+	}
+	if packageOwnership != PackageOwnershipUnique || len(packages) == 0 {
+		return packages, true
+	}
+
+	owners, err := packageModules(db.GetDB().WithContext(r.Context()), packages)
+	if err != nil {
+		log.Error("Error checking package ownership", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to check package ownership")
+		return nil, false
+	}
+	var conflicts []string
+	for _, owner := range owners {
+		if owner.Namespace != namespace || owner.Name != moduleName {
+			conflicts = append(conflicts, fmt.Sprintf("package %s is declared by module %s/%s", owner.Package, owner.Namespace, owner.Name))
+		}
+	}
+	if len(conflicts) > 0 {
+		log.Info("Rejected publish declaring packages of other modules", zap.Strings("conflicts", conflicts))
+		response.Error(w, http.StatusConflict, fmt.Sprintf("Package ownership conflict: %s", strings.Join(conflicts, "; ")))
+		return nil, false
+	}
+	return packages, true
+}
+
+// packageOwner is a module declaring a package.
+type packageOwner struct {
+	Package    string
+	Namespace  string
+	Name       string
+	Visibility string
+	Version    string
+}
+
+// packageModules returns the modules declaring the packages, by package then module name.
+func packageModules(gormDB *gorm.DB, packages []string) ([]packageOwner, error) {
+	var owners []packageOwner
+	err := gormDB.Table("proto_packages").
+		Select("proto_packages.package, modules.namespace, modules.name, modules.visibility, proto_packages.version").
+		Joins("JOIN modules ON modules.id = proto_packages.module_id").
+		Where("proto_packages.package IN ?", packages).
+		Order("proto_packages.package, modules.namespace, modules.name").
+		Scan(&owners).Error
+	return owners, err
+}
+
+// --- Package Endpoint ---
+
+// GetPackageHandler returns the modules declaring a proto package.
+// GET /api/v1/packages/{package}
+// Modules the caller can't read are left out; 404 if none is left.
+func GetPackageHandler(w http.ResponseWriter, r *http.Request) {
+	pkg := mux.Vars(r)["package"]
+	if !protoPackagePattern.MatchString(pkg) {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid package name %q: expected dot-separated identifiers, e.g. mycompany.user.v1", pkg))
+		return
+	}
+
+	owners, err := packageModules(requestDB(r).WithContext(r.Context()), []string{pkg})
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error looking up package", zap.String("package", pkg), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to look up package")
+		return
+	}
+	rd := readerFromContext(r.Context())
+	resp := apitypes.PackageResponse{Package: pkg, Modules: []apitypes.PackageModule{}}
+	for _, owner := range owners {
+		if rd.canRead(owner.Namespace, owner.Name, owner.Visibility) {
+			resp.Modules = append(resp.Modules, apitypes.PackageModule{Module: owner.Namespace + "/" + owner.Name, Version: owner.Version})
+		}
+	}
+	if len(resp.Modules) == 0 {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("No module declares package %s", pkg))
+		return
+	}
+	setListCacheHeaders(w) // Changes when modules are published
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// --- Reindexing ---
+
+// indexModulePackages indexes the packages of every version of a module, oldest first, downloading their
+// artifacts. Used by the reindex-packages job. Versions whose files don't parse are skipped.
+func indexModulePackages(ctx context.Context, module models.Module) error {
+	gormDB := db.GetDB().WithContext(ctx)
+	var versions []models.ModuleVersion
+	if err := gormDB.Where("module_id = ?", module.ID).Order("created_at").Find(&versions).Error; err != nil {
+		return err
+	}
+	for _, version := range versions {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		reader, err := storage.GetStorageProvider().DownloadFile(ctx, version.ArtifactStorageKey)
+		if err != nil {
+			return fmt.Errorf("failed to download artifact of %s: %w", version.Version, err)
+		}
+		artifact, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read artifact of %s: %w", version.Version, err)
+		}
+		packages, err := descriptor.ArtifactPackages(artifact)
+		if err != nil {
+			logging.FromContext(ctx).Warn("Skipped version whose packages can't be read", zap.String("module", module.Namespace+"/"+module.Name), zap.String("version", version.Version), zap.Error(err))
+			continue
+		}
+		if err := primaryRepositories().Modules.IndexPackages(ctx, module.ID, version.Version, packages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageIndex(t *testing.T) {
+	gormDB := newTestDB(t)
+	newTestStorage(t)
+	t.Cleanup(func() { assert.NoError(t, SetPackageOwnership("")) })
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/packages/{package}", GetPackageHandler).Methods("GET")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	publish := func(name, version string, files map[string][]byte) *httptest.ResponseRecorder {
+		data, err := artifact.Pack(files)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newPublishRequest(t, "acme", name, version, "", data))
+		return rr
+	}
+	get := func(pkg string, rd reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v1/packages/"+pkg, nil)
+		req = req.WithContext(context.WithValue(req.Context(), readerKey, rd))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	user := map[string][]byte{
+		"user.proto":  []byte(`syntax = "proto3"; package mycompany.user.v1;`),
+		"types.proto": []byte(`syntax = "proto3"; package mycompany.types.v1;`),
+	}
+	assert.Equal(t, http.StatusCreated, publish("user", "v1.0.0", user).Code)
+	assert.Equal(t, http.StatusCreated, publish("user", "v1.1.0", user).Code)
+
+	rr := get("mycompany.user.v1", reader{})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"package":"mycompany.user.v1","modules":[{"module":"acme/user","version":"v1.1.0"}]}`, rr.Body.String())
+	assert.Equal(t, http.StatusNotFound, get("mycompany.billing.v1", reader{}).Code)
+	assert.Equal(t, http.StatusBadRequest, get("mycompany..v1", reader{}).Code)
+
+	// Another module declaring one of the packages is rejected while ownership is unique
+	billing := map[string][]byte{
+		"billing.proto": []byte(`syntax = "proto3"; package mycompany.billing.v1;`),
+		"types.proto":   []byte(`syntax = "proto3"; package mycompany.types.v1;`),
+	}
+	rr = publish("billing", "v1.0.0", billing)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.JSONEq(t, `{"error":"Package ownership conflict: package mycompany.types.v1 is declared by module acme/user"}`, rr.Body.String())
+	assert.Equal(t, http.StatusNotFound, get("mycompany.billing.v1", reader{}).Code)
+
+	// Shared ownership lists every declaring module
+	assert.Error(t, SetPackageOwnership("exclusive"))
+	assert.NoError(t, SetPackageOwnership(PackageOwnershipShared))
+	assert.Equal(t, http.StatusCreated, publish("billing", "v1.0.0", billing).Code)
+	rr = get("mycompany.types.v1", reader{})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"package":"mycompany.types.v1","modules":[{"module":"acme/billing","version":"v1.0.0"},{"module":"acme/user","version":"v1.1.0"}]}`, rr.Body.String())
+
+	// Modules the caller can't read are left out
+	assert.NoError(t, gormDB.Model(&models.Module{}).Where("name = ?", "billing").Update("visibility", models.VisibilityPrivate).Error)
+	assert.NotContains(t, get("mycompany.types.v1", reader{}).Body.String(), "acme/billing")
+	assert.Equal(t, http.StatusNotFound, get("mycompany.billing.v1", reader{}).Code)
+	assert.Equal(t, http.StatusOK, get("mycompany.billing.v1", reader{admin: true}).Code)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// servePartialArtifact serves the files of a module version's artifact matching paths (?paths=), re-packed
+// into a zip server-side, so consumers of a large module only download the slice they need.
+// The slice of an immutable artifact is immutable too (and deterministic), so it is cached like the artifact,
+// with the digest of the partial zip as ETag.
+func servePartialArtifact(w http.ResponseWriter, r *http.Request, moduleVersion *models.ModuleVersion, paths []string) {
+	log := logging.FromContext(r.Context()).With(zap.Stringer("module_version_id", moduleVersion.ID), zap.Strings("paths", paths))
+
+	// Reading and re-packing the artifact holds a stream slot like a download
+	limit := streamSlots
+	if !limit.acquire(w, r) {
+		return // 429 already written
+	}
+	defer limit.release()
+
+	data, err := readStoredArtifact(r, moduleVersion)
+	if err != nil {
+		switch status := storageErrorStatus(err); status {
+		case http.StatusNotFound:
+			log.Warn("Artifact not found in storage", zap.String("key", moduleVersion.ArtifactStorageKey), zap.Error(err))
+			response.Error(w, status, "Artifact not found in storage")
+		case http.StatusServiceUnavailable:
+			log.Error("Storage unavailable while reading artifact", zap.String("key", moduleVersion.ArtifactStorageKey), zap.Error(err))
+			response.Error(w, status, "Artifact storage unavailable")
+		default:
+			log.Error("Error reading artifact from storage", zap.String("key", moduleVersion.ArtifactStorageKey), zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to retrieve artifact from storage")
+		}
+		return
+	}
+
+	partial, count, err := artifact.Subset(data, paths)
+	if errors.Is(err, artifact.ErrNoMatch) {
+		response.Error(w, http.StatusNotFound, "No file of the artifact matches the paths")
+		return
+	}
+	if err != nil {
+		log.Error("Error building partial artifact", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to build partial artifact")
+		return
+	}
+
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(partial))
+	w.Header().Set("ETag", etag)
+	if moduleVersion.ArtifactDigest != "" {
+		w.Header().Set(apitypes.PartialSourceDigestHeader, "sha256:"+moduleVersion.ArtifactDigest)
+	}
+	w.Header().Set(apitypes.PartialFilesHeader, strconv.Itoa(count))
+	if moduleVersion.ScanStatus != "" {
+		w.Header().Set("X-Scan-Status", moduleVersion.ScanStatus)
+	}
+	setImmutableCacheHeaders(w)
+	if notModified(w, r, etag) {
+		return
+	}
+
+	filename := moduleVersion.Version + "-partial.zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, filename, url.PathEscape(filename)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(partial)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	log.Debug("Serving partial artifact", zap.Int("files", count), zap.Int("size", len(partial)), zap.Int("artifact_size", len(data)))
+	if _, err := w.Write(partial); err != nil {
+		log.Warn("Error writing partial artifact to client", zap.Error(err))
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for partial artifact fetches ---
+
+func TestFetchModuleVersionArtifactHandler_PartialPaths(t *testing.T) {
+	_, mock := setupMockDB(t)
+	provider := newTestStorage(t)
+
+	content, err := artifact.Pack(map[string][]byte{
+		"billing/v1/invoice.proto": []byte(`syntax = "proto3";`),
+		"common/types.proto":       []byte(`syntax = "proto3";`),
+		"common/money.proto":       []byte(`syntax = "proto3";`),
+		"orders/v1/order.proto":    []byte(`syntax = "proto3";`),
+	})
+	assert.NoError(t, err)
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+	key := "v2/modules/my-org/my-module/v1.0.0/" + digest + ".zip"
+	assert.NoError(t, provider.UploadFile(context.Background(), key, bytes.NewReader(content), int64(len(content)), "application/zip"))
+
+	for i := 0; i < 3; i++ {
+		mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+			WithArgs("my-org", "my-module", "v1.0.0", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "module_id", "version", "artifact_digest", "artifact_storage_key", "artifact_size"}).
+				AddRow(uuid.New(), uuid.New(), "v1.0.0", digest, key, len(content)))
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/artifact", FetchModuleVersionArtifactHandler).Methods("GET", "HEAD")
+
+	// Only the matching files, identified by their own digest
+	req, err := http.NewRequest("GET", "/api/v1/modules/my-org/my-module/v1.0.0/artifact?paths=billing/,common/types.proto", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	zipReader, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	assert.NoError(t, err)
+	names := []string{}
+	for _, f := range zipReader.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"billing/v1/invoice.proto", "common/types.proto"}, names)
+	partialSum := sha256.Sum256(rr.Body.Bytes())
+	etag := fmt.Sprintf(`"%x"`, partialSum)
+	assert.Equal(t, etag, rr.Header().Get("ETag"))
+	assert.Equal(t, "sha256:"+digest, rr.Header().Get(apitypes.PartialSourceDigestHeader))
+	assert.Equal(t, "2", rr.Header().Get(apitypes.PartialFilesHeader))
+	assert.Empty(t, rr.Header().Get("X-Artifact-Digest")) // Not the published artifact
+	assert.Equal(t, fmt.Sprint(rr.Body.Len()), rr.Header().Get("Content-Length"))
+	assert.Equal(t, "public, max-age=31536000, immutable", rr.Header().Get("Cache-Control"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), `filename="v1.0.0-partial.zip"`)
+
+	// Revalidation with the partial ETag
+	req, err = http.NewRequest("GET", "/api/v1/modules/my-org/my-module/v1.0.0/artifact?paths=billing/,common/types.proto", nil)
+	assert.NoError(t, err)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	// Nothing matches
+	req, err = http.NewRequest("GET", "/api/v1/modules/my-org/my-module/v1.0.0/artifact?paths=missing/", nil)
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":"No file of the artifact matches the paths"}`, rr.Body.String())
+
+	// Invalid paths are rejected before touching the database
+	for _, paths := range []string{"", "/etc/passwd", "../other"} {
+		req, err = http.NewRequest("GET", "/api/v1/modules/my-org/my-module/v1.0.0/artifact?paths="+url.QueryEscape(paths), nil)
+		assert.NoError(t, err)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, paths)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
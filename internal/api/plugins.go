@@ -0,0 +1,279 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Plugin registry: admins register versions of protoc plugins (container images and/or per-platform
+// binaries), and code generation references them by name and version ("registry://go:v1.34") instead of
+// every team pinning its own copy. The registry only stores the references; images and binaries are
+// hosted elsewhere, binaries pinned by their SHA256.
+
+// maxPluginRequestBytes limits the JSON body of plugin registrations.
+const maxPluginRequestBytes = 64 * 1024
+
+var (
+	platformPattern = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9]+$`)
+	sha256Pattern   = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// ListPluginsHandler lists every registered plugin version, by name and then newest version first.
+// GET /api/v1/plugins
+func ListPluginsHandler(w http.ResponseWriter, r *http.Request) {
+	var plugins []models.Plugin
+	if err := db.GetReadDB().Preload("Binaries").Find(&plugins).Error; err != nil {
+		logging.FromContext(r.Context()).Error("Error listing plugins", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve plugins")
+		return
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		if plugins[i].Name != plugins[j].Name {
+			return plugins[i].Name < plugins[j].Name
+		}
+		return manifest.IsOlder(plugins[j].Version, plugins[i].Version)
+	})
+
+	respData := apitypes.ListPluginsResponse{Plugins: make([]apitypes.PluginResponse, 0, len(plugins))} // Empty array, not null
+	for _, p := range plugins {
+		respData.Plugins = append(respData.Plugins, pluginResponse(p))
+	}
+	setListCacheHeaders(w)
+	response.JSON(w, http.StatusOK, respData)
+}
+
+// GetPluginHandler returns a registered plugin version.
+// GET /api/v1/plugins/{name}/{version}
+// {version} is an exact version ("v1.34.2"), a partial one matching the newest release of the line
+// ("v1.34", "v1") or "latest".
+func GetPluginHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if err := validation.ValidatePluginName(name); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	gormDB := db.GetReadDB()
+
+	var versions []string
+	if err := gormDB.Model(&models.Plugin{}).Where("name = ?", name).Pluck("version", &versions).Error; err != nil {
+		logging.FromContext(r.Context()).Error("Error listing plugin versions", zap.String("plugin", name), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error finding plugin")
+		return
+	}
+	version, err := resolvePluginVersion(versions, vars["version"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if version == "" {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("Plugin '%s' has no version matching '%s'", name, vars["version"]))
+		return
+	}
+
+	var plugin models.Plugin
+	if err := gormDB.Preload("Binaries").Where("name = ? AND version = ?", name, version).First(&plugin).Error; err != nil {
+		logging.FromContext(r.Context()).Error("Error finding plugin", zap.String("plugin", name+":"+version), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error finding plugin")
+		return
+	}
+	setListCacheHeaders(w) // Partial versions and "latest" move when new versions are registered
+	response.JSON(w, http.StatusOK, pluginResponse(plugin))
+}
+
+// RegisterPluginHandler registers a plugin version. Versions are immutable: registering an existing one
+// is a conflict (delete it first to replace it).
+// POST /api/v1/plugins/{name}/{version}
+func RegisterPluginHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	if err := validation.ValidatePluginName(name); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	semVer, err := semver.NewVersion(vars["version"])
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid semantic version format: %v", err))
+		return
+	}
+	version := "v" + semVer.String() // Normalized like module versions
+	log := logging.FromContext(r.Context()).With(zap.String("plugin", name+":"+version))
+
+	var req apitypes.RegisterPluginRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPluginRequestBytes)).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if err := validatePluginRequest(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	plugin := models.Plugin{
+		Name:        name,
+		Version:     version,
+		Image:       req.Image,
+		Description: req.Description,
+		CreatedAt:   time.Now().UTC(),
+	}
+	for _, b := range req.Binaries {
+		plugin.Binaries = append(plugin.Binaries, models.PluginBinary{Platform: b.Platform, URL: b.URL, SHA256: b.SHA256})
+	}
+
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Plugin{}).Where("name = ? AND version = ?", name, version).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errPluginExists
+		}
+		return tx.Create(&plugin).Error // Creates the binaries too
+	})
+	if errors.Is(err, errPluginExists) {
+		response.Error(w, http.StatusConflict, fmt.Sprintf("version '%s' already exists for plugin '%s'", version, name))
+		return
+	}
+	if err != nil {
+		log.Error("Error registering plugin", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error registering plugin")
+		return
+	}
+
+	log.Info("Registered plugin", zap.String("image", plugin.Image), zap.Int("binaries", len(plugin.Binaries)))
+	response.JSON(w, http.StatusCreated, pluginResponse(plugin))
+}
+
+// DeletePluginHandler unregisters a plugin version (exact version only).
+// DELETE /api/v1/plugins/{name}/{version}
+func DeletePluginHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+	version := vars["version"]
+	log := logging.FromContext(r.Context()).With(zap.String("plugin", name+":"+version))
+
+	var deleted int64
+	err := db.GetDB().Transaction(func(tx *gorm.DB) error {
+		var plugin models.Plugin
+		if err := tx.Where("name = ? AND version = ?", name, version).First(&plugin).Error; err != nil {
+			return err
+		}
+		// Binaries first: SQLite doesn't enforce the foreign key's ON DELETE CASCADE
+		if err := tx.Where("plugin_id = ?", plugin.ID).Delete(&models.PluginBinary{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&plugin)
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && deleted == 0) {
+		response.Error(w, http.StatusNotFound, fmt.Sprintf("Plugin version '%s:%s' not found", name, version))
+		return
+	}
+	if err != nil {
+		log.Error("Error deleting plugin", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error deleting plugin")
+		return
+	}
+	log.Info("Deleted plugin")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var errPluginExists = errors.New("plugin version exists")
+
+// resolvePluginVersion picks the version a reference means among a plugin's versions: exact versions must
+// exist, partial ones ("v1.34", "v1") match the newest version of the line and "latest" the newest
+// stable version. Returns "" if none matches.
+func resolvePluginVersion(versions []string, ref string) (string, error) {
+	if ref == "latest" {
+		return manifest.Newest(versions), nil
+	}
+	if !strings.HasPrefix(ref, "v") {
+		return "", errors.New("invalid version format: must start with 'v' or be 'latest'")
+	}
+	core, _, _ := strings.Cut(strings.SplitN(ref, "+", 2)[0], "-")
+	if strings.Count(core, ".") == 2 {
+		semVer, err := semver.NewVersion(ref)
+		if err != nil {
+			return "", fmt.Errorf("invalid semantic version format: %v", err)
+		}
+		for _, v := range versions {
+			if v == "v"+semVer.String() {
+				return v, nil
+			}
+		}
+		return "", nil
+	}
+	constraint, err := semver.NewConstraint(strings.TrimPrefix(ref, "v")) // "1.34" matches 1.34.x
+	if err != nil {
+		return "", fmt.Errorf("invalid version: %v", err)
+	}
+	return manifest.NewestMatching(versions, constraint), nil
+}
+
+// validatePluginRequest checks a registration: an image, binaries or both, binaries with a GOOS/GOARCH
+// platform (once each), an HTTP(S) URL and a SHA256 digest.
+func validatePluginRequest(req *apitypes.RegisterPluginRequest) error {
+	req.Image = strings.TrimSpace(req.Image)
+	req.Description = strings.TrimSpace(req.Description)
+	if req.Image == "" && len(req.Binaries) == 0 {
+		return errors.New("a plugin version needs an image, binaries or both")
+	}
+	if strings.ContainsAny(req.Image, " \t\n") {
+		return fmt.Errorf("invalid image reference %q", req.Image)
+	}
+	platforms := map[string]bool{}
+	for i := range req.Binaries {
+		b := &req.Binaries[i]
+		b.SHA256 = strings.ToLower(b.SHA256)
+		if !platformPattern.MatchString(b.Platform) {
+			return fmt.Errorf("invalid binary platform %q: must be GOOS/GOARCH, e.g. linux/amd64", b.Platform)
+		}
+		if platforms[b.Platform] {
+			return fmt.Errorf("more than one binary for platform %q", b.Platform)
+		}
+		platforms[b.Platform] = true
+		if u, err := url.Parse(b.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid URL %q for platform %s: must be http or https", b.URL, b.Platform)
+		}
+		if !sha256Pattern.MatchString(b.SHA256) {
+			return fmt.Errorf("invalid sha256 for platform %s: must be 64 hex characters", b.Platform)
+		}
+	}
+	return nil
+}
+
+func pluginResponse(p models.Plugin) apitypes.PluginResponse {
+	resp := apitypes.PluginResponse{
+		Name:        p.Name,
+		Version:     p.Version,
+		Image:       p.Image,
+		Binaries:    make([]apitypes.PluginBinaryInfo, 0, len(p.Binaries)), // Empty array, not null
+		Description: p.Description,
+		CreatedAt:   p.CreatedAt,
+	}
+	for _, b := range p.Binaries {
+		resp.Binaries = append(resp.Binaries, apitypes.PluginBinaryInfo{Platform: b.Platform, URL: b.URL, SHA256: b.SHA256})
+	}
+	sort.Slice(resp.Binaries, func(i, j int) bool { return resp.Binaries[i].Platform < resp.Binaries[j].Platform })
+	return resp
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPluginHandlers(t *testing.T) {
+	gormDB := newTestDB(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/plugins", ListPluginsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/plugins/{name}/{version}", GetPluginHandler).Methods("GET")
+	router.HandleFunc("/api/v1/plugins/{name}/{version}", RegisterPluginHandler).Methods("POST")
+	router.HandleFunc("/api/v1/plugins/{name}/{version}", DeletePluginHandler).Methods("DELETE")
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/plugins"+path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	digest := strings.Repeat("ab", 32)
+
+	// Versions are normalized; binaries are validated
+	rr := do("POST", "/go/1.34.2", `{"image":"ghcr.io/acme/protoc-gen-go:1.34.2","binaries":[{"platform":"linux/amd64","url":"https://example.com/protoc-gen-go","sha256":"`+strings.ToUpper(digest)+`"}]}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var created apitypes.PluginResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "v1.34.2", created.Version)
+	assert.Equal(t, []apitypes.PluginBinaryInfo{{Platform: "linux/amd64", URL: "https://example.com/protoc-gen-go", SHA256: digest}}, created.Binaries)
+	assert.Equal(t, http.StatusCreated, do("POST", "/go/v1.34.1", `{"image":"ghcr.io/acme/protoc-gen-go:1.34.1"}`).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/go/v1.35.0", `{"image":"ghcr.io/acme/protoc-gen-go:1.35.0"}`).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/grpc-go/v1.5.1", `{"image":"ghcr.io/acme/protoc-gen-go-grpc:1.5.1"}`).Code)
+	assert.Equal(t, http.StatusConflict, do("POST", "/go/v1.34.2", `{"image":"other"}`).Code)
+	for _, body := range []string{
+		`{}`,
+		`not json`,
+		`{"binaries":[{"platform":"linux","url":"https://example.com/x","sha256":"` + digest + `"}]}`,
+		`{"binaries":[{"platform":"linux/amd64","url":"file:///x","sha256":"` + digest + `"}]}`,
+		`{"binaries":[{"platform":"linux/amd64","url":"https://example.com/x","sha256":"abc"}]}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, do("POST", "/go/v2.0.0", body).Code, body)
+	}
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/Go/v2.0.0", `{"image":"x"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/go/latest", `{"image":"x"}`).Code)
+
+	// Listed by name, newest version first
+	rr = do("GET", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list apitypes.ListPluginsResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	var listed []string
+	for _, p := range list.Plugins {
+		listed = append(listed, p.Name+":"+p.Version)
+	}
+	assert.Equal(t, []string{"go:v1.35.0", "go:v1.34.2", "go:v1.34.1", "grpc-go:v1.5.1"}, listed)
+
+	// Exact, partial and latest versions
+	for ref, want := range map[string]string{"v1.34.1": "v1.34.1", "v1.34": "v1.34.2", "v1": "v1.35.0", "latest": "v1.35.0"} {
+		rr = do("GET", "/go/"+ref, "")
+		assert.Equal(t, http.StatusOK, rr.Code, ref)
+		var got apitypes.PluginResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+		assert.Equal(t, want, got.Version, ref)
+	}
+	assert.Equal(t, http.StatusNotFound, do("GET", "/go/v1.36", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/go/v1.34.3", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/ts/latest", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/go/1.34", "").Code)
+
+	// Deleting removes the binaries too
+	assert.Equal(t, http.StatusNoContent, do("DELETE", "/go/v1.34.2", "").Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", "/go/v1.34.2", "").Code)
+	var binaries int64
+	assert.NoError(t, gormDB.Model(&models.PluginBinary{}).Count(&binaries).Error)
+	assert.Zero(t, binaries)
+	rr = do("GET", "/go/v1.34", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"version":"v1.34.1"`)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/policy"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// evaluatePublishPolicy runs the engine's policies over an artifact about to be published as
+// namespace/moduleName@version, recording their violations in v.
+// Returns false if the policies couldn't be evaluated; the response has then been written.
+func evaluatePublishPolicy(w http.ResponseWriter, r *http.Request, v *publishChecks, engine *policy.Engine, artifact []byte, namespace, moduleName, version string, size int64) bool {
+	log := logging.FromContext(r.Context()).With(zap.String("module_version", namespace+"/"+moduleName+"@"+version))
+
+	input := policy.Input{
+		Namespace:  namespace,
+		ModuleName: moduleName,
+		Version:    version,
+		Size:       size,
+		Publisher:  r.Header.Get(apitypes.PublisherHeader),
+		Branch:     r.Header.Get(apitypes.BranchHeader),
+	}
+	// An unreadable artifact has no files or dependencies; policies can still decide on the rest
+	if summary, err := descriptor.SummarizeArtifact(artifact); err == nil {
+		input.Files = summary.Files
+		input.Dependencies = summary.Dependencies
+	}
+
+	// --- Diff Against the Newest Published Version ---
+	loader := descriptor.NewLoader(db.GetDB(), storage.GetStorageProvider())
+	diff, err := loader.DiffAgainstLatest(r.Context(), namespace, moduleName, version, artifact)
+	switch {
+	case errors.Is(err, descriptor.ErrNotFound):
+		// First version of the module: nothing to compare against
+	case errors.Is(err, descriptor.ErrInvalidArtifact), errors.Is(err, descriptor.ErrCompile):
+		log.Warn("Diff unavailable for policy evaluation", zap.Error(err))
+	case err != nil:
+		log.Error("Error computing diff for policy evaluation", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to evaluate publish policies")
+		return false
+	default:
+		input.Diff = diff
+	}
+
+	for _, violation := range engine.Evaluate(r.Context(), input) {
+		v.addError("policy:"+violation.Policy, "", 0, violation.Message)
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Suhaibinator/SProto/internal/policy"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for publish policies ---
+
+func TestPublishModuleVersionHandler_PolicyDenied(t *testing.T) {
+	_, mock := setupMockDB(t)
+	newTestStorage(t)
+
+	engine, err := policy.New([]policy.Policy{{
+		Name:       "main-only",
+		Namespaces: []string{"my-org"},
+		Expression: "branch == 'main'",
+		Message:    "publish from main",
+	}})
+	assert.NoError(t, err)
+	policy.SetEngine(engine)
+	t.Cleanup(func() { policy.SetEngine(nil) })
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler)
+
+	expectNoNamespacePolicy(mock, "my-org")
+	// Denied: the artifact isn't a zip, so no diff is computed (only the namespace policy is looked up)
+	rr := httptest.NewRecorder()
+	req := newPublishRequest(t, "my-org", "my-module", "v1.0.0", "?validate_only=true", []byte("fake zip content"))
+	req.Header.Set(apitypes.BranchHeader, "feature")
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var resp apitypes.ValidationFailedResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, []apitypes.ValidationIssue{{Rule: "policy:main-only", Severity: apitypes.SeverityError, Message: "publish from main"}}, resp.Issues)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	expectNoNamespacePolicy(mock, "my-org")
+	// Allowed: the publish continues to the remaining checks
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "module_versions"."version" FROM "module_versions"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	rr = httptest.NewRecorder()
+	req = newPublishRequest(t, "my-org", "my-module", "v1.0.0", "?validate_only=true", []byte("fake zip content"))
+	req.Header.Set(apitypes.BranchHeader, "main")
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/provenance"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// Promotion: `protoreg-cli promote` copies a version from one registry to another (e.g. staging to prod)
+// and publishes it with the chain of registries it came from in the ProvenanceHeader. The chain is stored
+// with the version and served in its metadata, so consumers can trace it back to where it was originally
+// published, and when. The registry only checks that the chain is well-formed and that the registry the
+// version was promoted from had the same artifact; the CLI verified the source's digest and signature.
+
+// readProvenance returns the promotion chain sent with a publish of an artifact with digestHex, as stored
+// with the version ("" if there is none). On failure it writes a 400 response and returns false.
+func readProvenance(w http.ResponseWriter, r *http.Request, digestHex string) (string, bool) {
+	header := r.Header.Get(apitypes.ProvenanceHeader)
+	if header == "" {
+		return "", true
+	}
+	var chain []provenance.Source
+	if err := json.Unmarshal([]byte(header), &chain); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid "+apitypes.ProvenanceHeader+" header: must be a JSON array of sources")
+		return "", false
+	}
+	if err := provenance.ValidateChain(chain, "sha256:"+digestHex); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid "+apitypes.ProvenanceHeader+" header: "+err.Error())
+		return "", false
+	}
+	data, err := json.Marshal(chain) // Re-encoded, so only the known fields are stored
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error encoding provenance chain", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to record provenance")
+		return "", false
+	}
+	return string(data), true
+}
+
+// versionProvenance returns the promotion chain of a version for API responses, or nil if it was
+// published here.
+func versionProvenance(moduleVersion *models.ModuleVersion) []provenance.Source {
+	if moduleVersion.Provenance == "" {
+		return nil
+	}
+	var chain []provenance.Source
+	if err := json.Unmarshal([]byte(moduleVersion.Provenance), &chain); err != nil {
+		logging.L().Warn("Stored provenance chain is invalid", zap.String("module_version_id", moduleVersion.ID.String()), zap.Error(err))
+		return nil
+	}
+	return chain
+}
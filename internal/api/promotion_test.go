@@ -0,0 +1,78 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/provenance"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromotionProvenance(t *testing.T) {
+	gormDB := newTestDB(t)
+	newTestStorage(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", GetModuleVersionHandler).Methods("GET")
+	data, err := artifact.Pack(map[string][]byte{"user.proto": []byte(`syntax = "proto3"; package user.v1;`)})
+	assert.NoError(t, err)
+	sum := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	publish := func(version string, chain any) *httptest.ResponseRecorder {
+		req := newPublishRequest(t, "acme", "user", version, "", data)
+		if chain != nil {
+			header, err := json.Marshal(chain)
+			assert.NoError(t, err)
+			req.Header.Set(apitypes.ProvenanceHeader, string(header))
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The chain is recorded with the version and served in its metadata
+	published := time.Date(2023, 10, 28, 9, 0, 0, 0, time.UTC)
+	chain := []provenance.Source{
+		{Registry: "https://dev.example.com", Module: "acme/user", Version: "v1.0.0", Digest: digest, PublishedAt: published},
+		{Registry: "https://staging.example.com", Module: "acme/user", Version: "v1.0.0", Digest: digest, PublishedAt: published.Add(time.Hour), Signature: "c2ln", KeyID: "0123456789abcdef"},
+	}
+	rr := publish("v1.0.0", chain)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var resp apitypes.PublishModuleVersionResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, chain, resp.Provenance)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/modules/acme/user/v1.0.0", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var meta apitypes.ModuleVersionResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &meta))
+	assert.Equal(t, chain, meta.Provenance)
+
+	// Versions published here have none
+	rr = publish("v1.1.0", nil)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "provenance")
+
+	// The source must have had the same artifact, and the chain must be well-formed
+	rr = publish("v1.2.0", []provenance.Source{{Registry: "https://staging.example.com", Module: "acme/user", Version: "v1.2.0", Digest: "sha256:0000", PublishedAt: published}})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "does not match acme/user@v1.2.0's at https://staging.example.com")
+	rr = publish("v1.2.0", map[string]string{"registry": "https://staging.example.com"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "must be a JSON array of sources")
+	rr = publish("v1.2.0", []provenance.Source{})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var count int64
+	assert.NoError(t, gormDB.Model(&models.ModuleVersion{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/policy"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// publishChecks collects the issues found by the checks of a publish.
+type publishChecks struct {
+	issues        []apitypes.ValidationIssue
+	compileFailed bool // The artifact's compile errors were recorded: the checks needing its schema are skipped
+}
+
+// addError records an issue rejecting the publish; file and line are optional.
+func (v *publishChecks) addError(rule, file string, line int, message string) {
+	v.issues = append(v.issues, apitypes.ValidationIssue{File: file, Line: line, Rule: rule, Severity: apitypes.SeverityError, Message: message})
+}
+
+// schemaChecked handles the error of a check needing the artifact's schema (lint, HTTP rules, breaking
+// changes, syntaxes): an artifact that doesn't compile gets its compile errors recorded, once for all such
+// checks, and the check has no results. Returns false on other errors; the response has then been written.
+func (v *publishChecks) schemaChecked(w http.ResponseWriter, log *zap.Logger, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, descriptor.ErrInvalidArtifact), errors.Is(err, descriptor.ErrCompile):
+		if v.compileFailed {
+			return true
+		}
+		v.compileFailed = true
+		issues := descriptor.CompileIssues(err)
+		if len(issues) == 0 { // e.g. a dependency with no matching published version
+			v.addError(apitypes.RuleCompile, "", 0, err.Error())
+		}
+		for _, issue := range issues {
+			severity := apitypes.SeverityError
+			if issue.Warning {
+				severity = apitypes.SeverityWarning
+			}
+			v.issues = append(v.issues, apitypes.ValidationIssue{File: issue.File, Line: issue.Line, Column: issue.Column, Rule: apitypes.RuleCompile, Severity: severity, Message: issue.Message})
+		}
+		return true
+	default:
+		log.Error("Error validating artifact", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to validate artifact")
+		return false
+	}
+}
+
+// passed reports whether no errors were found. Otherwise the 422 listing every issue has been written.
+func (v *publishChecks) passed(w http.ResponseWriter, log *zap.Logger) bool {
+	errorCount := 0
+	for _, issue := range v.issues {
+		if issue.Severity == apitypes.SeverityError {
+			errorCount++
+		}
+	}
+	if errorCount == 0 {
+		return true
+	}
+	log.Info("Publish rejected by validation", zap.Int("errors", errorCount), zap.Any("issues", v.issues))
+	response.JSON(w, http.StatusUnprocessableEntity, apitypes.ValidationFailedResponse{
+		Error:  fmt.Sprintf("Artifact failed validation with %d error(s)", errorCount),
+		Issues: v.issues,
+	})
+	return false
+}
+
+// checkValidation runs the namespace policy (nil if the namespace has none) and the publish policies on an
+// uploaded artifact. The file is rewound afterwards.
+// Returns false if the publish must be rejected; the response has then been written.
+func checkValidation(w http.ResponseWriter, r *http.Request, file multipart.File, nsPolicy *models.NamespacePolicy, namespace, moduleName, version string, size int64) bool {
+	if engine := policy.GetEngine(); nsPolicy == nil && (engine == nil || !engine.Applies(namespace)) {
+		return true
+	}
+
+	artifact, err := io.ReadAll(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error reading artifact for validation", zap.Error(err))
+		response.Error(w, http.StatusBadRequest, "Could not read artifact file")
+		return false
+	}
+	return validateArtifact(w, r, nsPolicy, artifact, namespace, moduleName, version, size)
+}
+
+// validateArtifact runs the namespace policy (nil if the namespace has none) and the publish policies over
+// an artifact about to be published as namespace/moduleName@version. Every check runs, so a rejected
+// publish lists all the issues at once.
+// Returns false if the publish must be rejected; the response has then been written.
+func validateArtifact(w http.ResponseWriter, r *http.Request, nsPolicy *models.NamespacePolicy, artifact []byte, namespace, moduleName, version string, size int64) bool {
+	log := logging.FromContext(r.Context()).With(zap.String("module_version", namespace+"/"+moduleName+"@"+version))
+	v := &publishChecks{}
+	if nsPolicy != nil && !evaluateNamespacePolicy(w, r, v, nsPolicy, artifact, namespace, moduleName, version) {
+		return false
+	}
+	if engine := policy.GetEngine(); engine != nil && engine.Applies(namespace) {
+		if !evaluatePublishPolicy(w, r, v, engine, artifact, namespace, moduleName, version, size) {
+			return false
+		}
+	}
+	if !v.passed(w, log) {
+		return false
+	}
+	log.Debug("Artifact passed validation")
+	return true
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/repo"
+)
+
+// Metadata repositories (see the repo package). Unless set with SetRepositories, the handlers use the GORM
+// repositories on the database of the db package (and its read replica, if configured).
+var (
+	primaryRepos *repo.Repositories
+	readRepos    *repo.Repositories
+)
+
+// SetRepositories replaces the metadata repositories of the handlers: primary for writes and for reads
+// that precede writes, read for the other GET and HEAD requests (pass primary twice without a replica).
+func SetRepositories(primary, read repo.Repositories) {
+	primaryRepos, readRepos = &primary, &read
+}
+
+// primaryRepositories returns the repositories for writes.
+func primaryRepositories() repo.Repositories {
+	if primaryRepos != nil {
+		return *primaryRepos
+	}
+	return repo.NewGorm(db.GetDB())
+}
+
+// readRepositories returns the repositories for reads (the read replica's, if configured).
+func readRepositories() repo.Repositories {
+	if readRepos != nil {
+		return *readRepos
+	}
+	return repo.NewGorm(db.GetReadDB())
+}
+
+// requestRepositories returns the repositories for a request, chosen like requestDB.
+func requestRepositories(r *http.Request) repo.Repositories {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return readRepositories()
+	}
+	return primaryRepositories()
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/repo"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListModulesHandler_CustomRepositories(t *testing.T) {
+	repos := newMemoryRepositories(t)
+	user, err := repos.Modules.GetOrCreate(context.Background(), "acme", "user", models.VisibilityPublic)
+	assert.NoError(t, err)
+	assert.NoError(t, repos.Versions.Create(context.Background(), &models.ModuleVersion{ModuleID: user.ID, Version: "v1.0.0", Syntaxes: "proto3"}))
+	_, err = repos.Modules.GetOrCreate(context.Background(), "other", "common", models.VisibilityPublic)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	ListModulesHandler(rr, httptest.NewRequest("GET", "/api/v1/modules?namespace=acme", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp ListModulesResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Modules, 1) {
+		assert.Equal(t, "user", resp.Modules[0].Name)
+		assert.Equal(t, []string{"proto3"}, resp.Modules[0].Syntaxes)
+	}
+}
+
+func TestGetModuleUsageHandler_CustomRepositories(t *testing.T) {
+	// The module lookup goes through the repositories, so an unknown module needs no database
+	newMemoryRepositories(t)
+
+	req := httptest.NewRequest("GET", "/api/v1/modules/acme/missing/usage", nil)
+	req = mux.SetURLVars(req, map[string]string{"namespace": "acme", "module_name": "missing"})
+	rr := httptest.NewRecorder()
+	GetModuleUsageHandler(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), "Module not found")
+}
+
+func TestSetModuleVisibilityHandler_CustomRepositories(t *testing.T) {
+	repos := newMemoryRepositories(t)
+	_, err := repos.Modules.GetOrCreate(context.Background(), "acme", "user", models.VisibilityPublic)
+	assert.NoError(t, err)
+	setVisibility := func(moduleName string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/v1/modules/acme/"+moduleName+"/visibility", strings.NewReader(`{"visibility":"private"}`))
+		req = mux.SetURLVars(req, map[string]string{"namespace": "acme", "module_name": moduleName})
+		rr := httptest.NewRecorder()
+		SetModuleVisibilityHandler(rr, req)
+		return rr
+	}
+
+	rr := setVisibility("user")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	visibility, err := repos.Modules.Visibility(context.Background(), "acme", "user")
+	assert.NoError(t, err)
+	assert.Equal(t, models.VisibilityPrivate, visibility)
+	assert.Equal(t, http.StatusNotFound, setVisibility("missing").Code)
+}
+
+func TestSetCanaryHandler_CustomRepositories(t *testing.T) {
+	repos := newMemoryRepositories(t)
+	module, err := repos.Modules.GetOrCreate(context.Background(), "acme", "user", models.VisibilityPublic)
+	assert.NoError(t, err)
+	assert.NoError(t, repos.Versions.Create(context.Background(), &models.ModuleVersion{ModuleID: module.ID, Version: "v1.1.0"}))
+	setCanary := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/modules/acme/user/v1.1.0/canary", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"namespace": "acme", "module_name": "user", "version": "v1.1.0"})
+		rr := httptest.NewRecorder()
+		SetCanaryHandler(rr, req)
+		return rr
+	}
+	canaryPercent := func() *int {
+		versions, err := repos.Versions.ListByModule(context.Background(), module.ID)
+		assert.NoError(t, err)
+		return versions[0].CanaryPercent
+	}
+
+	rr := setCanary("PUT", `{"percent":10}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	if assert.NotNil(t, canaryPercent()) {
+		assert.Equal(t, 10, *canaryPercent())
+	}
+	assert.Equal(t, http.StatusOK, setCanary("DELETE", "").Code)
+	assert.Nil(t, canaryPercent())
+}
+
+// newMemoryRepositories makes in-memory repositories those of the handlers for the rest of the test.
+func newMemoryRepositories(t *testing.T) repo.Repositories {
+	repos := repo.NewMemory()
+	SetRepositories(repos, repos)
+	t.Cleanup(func() { primaryRepos, readRepos = nil, nil })
+	return repos
+}
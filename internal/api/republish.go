@@ -0,0 +1,184 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/repo"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// republishModuleVersion creates versionStr from an already published version of the same module
+// (POST .../{version}?from={fromVersion}), e.g. to promote a release candidate to the final version.
+// The new version points at the source's stored artifact: nothing is uploaded or copied, so the
+// artifact and its digest are identical byte for byte. The scan outcome is carried over; the content,
+// namespace and publish policies are evaluated for the new version. ?validate_only=true is honoured.
+func republishModuleVersion(w http.ResponseWriter, r *http.Request, namespace, moduleName, versionStr, fromStr string) {
+	log := logging.FromContext(r.Context()).With(zap.String("module_version", fmt.Sprintf("%s/%s@%s", namespace, moduleName, versionStr)))
+
+	fromStr, err := validation.NormalizeVersion(fromStr)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid semantic version format for 'from': %v", err))
+		return
+	}
+	if fromStr == versionStr {
+		response.Error(w, http.StatusBadRequest, "A version can't be republished under its own version")
+		return
+	}
+
+	source, ok := findModuleVersion(w, r, namespace, moduleName, fromStr)
+	if !ok {
+		return // Response already written (404 if the source version doesn't exist)
+	}
+	log = log.With(zap.String("from", fromStr))
+
+	// --- Verify the Source Artifact ---
+	// The promise is a byte-for-byte copy, so make sure the stored object still matches its recorded digest
+	artifact, err := readStoredArtifact(r, source)
+	if err != nil {
+		log.Error("Error reading source artifact", zap.String("key", source.ArtifactStorageKey), zap.Error(err))
+		switch status := storageErrorStatus(err); status {
+		case http.StatusNotFound:
+			response.Error(w, http.StatusInternalServerError, "Source artifact is missing from storage")
+		case http.StatusServiceUnavailable:
+			response.Error(w, status, "Artifact storage unavailable")
+		default:
+			response.Error(w, http.StatusInternalServerError, "Failed to read source artifact")
+		}
+		return
+	}
+	sum := sha256.Sum256(artifact)
+	if digestHex := hex.EncodeToString(sum[:]); digestHex != source.ArtifactDigest {
+		log.Error("Source artifact digest mismatch", zap.String("recorded", source.ArtifactDigest), zap.String("stored", digestHex))
+		response.Error(w, http.StatusInternalServerError, "Source artifact failed its integrity check")
+		return
+	}
+
+	// --- Content Policy ---
+	// The source was accepted under the policies of its time; the new version must pass today's
+	nsPolicy, ok := findNamespacePolicy(w, r, namespace)
+	if !ok {
+		return // Response already written
+	}
+	if !evaluateContentPolicy(w, r, nsPolicy, artifact) {
+		return // Response already written
+	}
+
+	// --- Namespace and Publish Policies (optional) ---
+	if !validateArtifact(w, r, nsPolicy, artifact, namespace, moduleName, versionStr, int64(len(artifact))) {
+		return // Response already written
+	}
+
+	// --- Package Ownership ---
+	packages, ok := evaluatePackageOwnership(w, r, nsPolicy, artifact, namespace, moduleName)
+	if !ok {
+		return // Response already written
+	}
+
+	// --- Validate Only (dry run) ---
+	if r.URL.Query().Get("validate_only") == "true" {
+		validatePublish(w, r, namespace, moduleName, versionStr, source.ArtifactDigest, source.ArtifactSize, source.ScanStatus)
+		return
+	}
+
+	// --- Create the Version (Transaction) ---
+	var moduleVersion models.ModuleVersion
+	err = primaryRepositories().Tx.Transaction(r.Context(), func(tx repo.Repositories) error {
+		// Conflict check (the same version, or one differing only in build metadata or case)
+		existing, err := tx.Versions.Conflicting(r.Context(), namespace, moduleName, versionStr)
+		if err != nil {
+			log.Error("Error checking for existing version", zap.Error(err))
+			return &publishError{http.StatusInternalServerError, "Database error during version check", err}
+		}
+		if existing != "" {
+			err = versionConflictError(namespace, moduleName, versionStr, existing)
+			log.Info("Version already exists", zap.Error(err))
+			return &publishError{http.StatusConflict, err.Error(), err}
+		}
+
+		// Same object, digest and layout as the source: objects are write-once, so sharing the key is safe
+		moduleVersion = models.ModuleVersion{
+			ModuleID:           source.ModuleID,
+			Version:            versionStr,
+			VersionKey:         validation.VersionKey(versionStr),
+			ArtifactDigest:     source.ArtifactDigest,
+			ArtifactStorageKey: source.ArtifactStorageKey,
+			ArtifactKeyLayout:  source.ArtifactKeyLayout,
+			ArtifactSize:       source.ArtifactSize,
+			ArtifactTieredAt:   source.ArtifactTieredAt, // The shared object may already be cold
+			ScanStatus:         source.ScanStatus,
+			ScanResult:         source.ScanResult,
+			Changelog:          artifactChangelog(r.Context(), artifact, versionStr), // The new version's section, not the source's
+			Syntaxes:           artifactSyntaxes(r.Context(), artifact),              // Also known if the source predates detection
+			NoSchemaChangeFrom: compareSchemaWithPrevious(r.Context(), artifact, namespace, moduleName, versionStr),
+			CreatedAt:          time.Now().UTC(),
+		}
+		signVersion(&moduleVersion, namespace, moduleName) // New coordinates, so a new signature
+		if err := tx.Versions.Create(r.Context(), &moduleVersion); err != nil {
+			log.Error("Error creating module version record", zap.Error(err))
+			return &publishError{http.StatusInternalServerError, "Database error saving module version", err}
+		}
+
+		if err := tx.Modules.IndexPackages(r.Context(), source.ModuleID, versionStr, packages); err != nil {
+			log.Error("Error indexing proto packages", zap.Error(err))
+			return &publishError{http.StatusInternalServerError, "Database error indexing proto packages", err}
+		}
+
+		if err := tx.Versions.AddFiles(r.Context(), versionFiles(moduleVersion.ID, artifactFileDigests(r.Context(), artifact))); err != nil {
+			log.Error("Error recording file digests", zap.Error(err))
+			return &publishError{http.StatusInternalServerError, "Database error recording file digests", err}
+		}
+
+		if err := tx.Modules.Touch(r.Context(), source.ModuleID); err != nil {
+			// Don't fail the republish just for the timestamp update
+			log.Warn("Failed to update module updated_at timestamp", zap.Error(err))
+		}
+		return nil
+	})
+	if err != nil {
+		writePublishError(w, log, err)
+		return
+	}
+	log.Info("Republished module version", zap.String("key", source.ArtifactStorageKey))
+	recordChecksum(r.Context(), namespace, moduleName, versionStr, source.ArtifactDigest)
+	// Regenerated rather than copied: the document names the version
+	attachOpenAPI(r, &moduleVersion, namespace, moduleName, generateOpenAPI(r.Context(), artifact, namespace, moduleName, versionStr))
+	indexForSearch(r.Context(), namespace, moduleName, &moduleVersion, extractSearchEntries(r.Context(), artifact))
+
+	// No soft quota check: nothing new was stored
+	response.JSON(w, http.StatusCreated, apitypes.PublishModuleVersionResponse{
+		Namespace:       namespace,
+		ModuleName:      moduleName,
+		Version:         versionStr,
+		ArtifactDigest:  "sha256:" + source.ArtifactDigest,
+		ScanStatus:      source.ScanStatus,
+		CreatedAt:       moduleVersion.CreatedAt,
+		RepublishedFrom: fromStr,
+
+		NoSchemaChange:     moduleVersion.NoSchemaChangeFrom != "",
+		NoSchemaChangeFrom: moduleVersion.NoSchemaChangeFrom,
+		Syntaxes:           splitSyntaxes(moduleVersion.Syntaxes),
+
+		Signature: versionSignature(&moduleVersion, namespace, moduleName),
+	})
+}
+
+// readStoredArtifact reads a module version's artifact from storage into memory.
+func readStoredArtifact(r *http.Request, moduleVersion *models.ModuleVersion) ([]byte, error) {
+	reader, err := storage.GetStorageProvider().DownloadFile(r.Context(), moduleVersion.ArtifactStorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
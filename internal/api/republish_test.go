@@ -0,0 +1,71 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestPublishModuleVersionHandler_RepublishFrom(t *testing.T) {
+	_, mock := setupMockDB(t)
+	provider := newTestStorage(t)
+
+	content := []byte("fake zip content")
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+	key := "v2/modules/my-org/my-module/v1.0.0-rc.1/" + digest + ".zip"
+	assert.NoError(t, provider.UploadFile(context.Background(), key, bytes.NewReader(content), int64(len(content)), "application/zip"))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler)
+	republish := func(version, query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/api/v1/modules/my-org/my-module/"+version+query, nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Invalid or identical source versions are rejected before touching the database
+	assert.Equal(t, http.StatusBadRequest, republish("v1.0.0", "?from=not-a-version").Code)
+	assert.Equal(t, http.StatusBadRequest, republish("v1.0.0", "?from=1.0.0").Code)
+
+	// Missing source version
+	mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+		WithArgs("my-org", "my-module", "v0.9.0", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	rr := republish("v1.0.0", "?from=v0.9.0")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":"Module version not found"}`, rr.Body.String())
+
+	// Validate only: the source's artifact is verified and its metadata carried over, nothing is written
+	mock.ExpectQuery(`SELECT .* FROM "module_versions" JOIN modules ON modules.id = module_versions.module_id WHERE`).
+		WithArgs("my-org", "my-module", "v1.0.0-rc.1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "module_id", "version", "artifact_digest", "artifact_storage_key", "artifact_size", "scan_status"}).
+			AddRow(uuid.New(), uuid.New(), "v1.0.0-rc.1", digest, key, len(content), "clean"))
+	expectNoNamespacePolicy(mock, "my-org")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "module_versions"."version" FROM "module_versions"`)).
+		WithArgs("my-org", "my-module", "v1.0.0", "v1.0.0", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	rr = republish("v1.0.0", "?from=v1.0.0-rc.1&validate_only=true")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp apitypes.ValidatePublishResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "sha256:"+digest, resp.ArtifactDigest)
+	assert.Equal(t, int64(len(content)), resp.ArtifactSize)
+	assert.Equal(t, "clean", resp.ScanStatus)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// Server-side resolution: clients send their root constraints (the dependencies of their sproto.yaml) and
+// get back the complete, pinned set of versions, resolved by manifest.Resolve against the registry's own
+// data. Every client gets the same answer, whatever version of the resolver it ships, and the registry
+// reads the packaged manifests from its declaration cache instead of clients downloading every artifact.
+
+// MaxResolveDependencies is the maximum number of root constraints per resolve request.
+const MaxResolveDependencies = 100
+
+// ResolveConflictResponse explains why no version set satisfies all the constraints (409).
+type ResolveConflictResponse struct {
+	Error    string                  `json:"error"`
+	Conflict *manifest.ConflictError `json:"conflict"`
+}
+
+// ResolveHandler resolves root constraints to a complete, conflict-free set of pinned versions.
+// POST /api/v1/resolve
+// Modules the caller can't read are treated as unpublished.
+func ResolveHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+
+	var req apitypes.ResolveRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if len(req.Dependencies) > MaxResolveDependencies {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("At most %d dependencies can be resolved at once", MaxResolveDependencies))
+		return
+	}
+
+	// --- Validation ---
+	// The root constraints are validated like a sproto.yaml
+	root := &manifest.Manifest{Dependencies: req.Dependencies}
+	for _, module := range root.DependencyNames() {
+		if _, _, err := manifest.SplitModule(module); err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid dependency: %v", err))
+			return
+		}
+		if _, err := root.Constraint(module); err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	lock := &manifest.Lock{LockVersion: manifest.LockFormatVersion}
+	for i, l := range req.Locked {
+		if _, _, err := manifest.SplitModule(l.Module); err != nil || l.Version == "" {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("locked[%d]: module (namespace/name) and version are required", i))
+			return
+		}
+		lock.Dependencies = append(lock.Dependencies, manifest.LockedDependency{Module: l.Module, Version: l.Version, Digest: l.Digest})
+	}
+	opts := manifest.ResolveOptions{Latest: req.Latest}
+	if len(req.Update) > 0 {
+		opts.Update = make(map[string]bool, len(req.Update))
+		for _, module := range req.Update {
+			opts.Update[module] = true
+		}
+	}
+
+	// --- Resolution ---
+	loader := descriptor.NewLoader(db.GetReadDB(), storage.GetStorageProvider()) // Read-only despite the POST
+	source := &readableSource{Source: loader.Source(r.Context()), r: r}
+	resolved, err := manifest.Resolve(root, lock, source, opts)
+	var conflict *manifest.ConflictError
+	switch {
+	case errors.As(err, &conflict):
+		response.JSON(w, http.StatusConflict, ResolveConflictResponse{Error: conflict.Error(), Conflict: conflict})
+		return
+	case errors.Is(err, descriptor.ErrNotFound), errors.Is(err, descriptor.ErrInvalidArtifact), errors.Is(err, manifest.ErrNotConverged):
+		// A locked version that doesn't exist, an unreadable packaged manifest, or a resolution that keeps changing
+		response.Error(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil && storageErrorStatus(err) == http.StatusServiceUnavailable:
+		log.Error("Storage unavailable while resolving dependencies", zap.Error(err))
+		response.Error(w, http.StatusServiceUnavailable, "Artifact storage unavailable")
+		return
+	case err != nil:
+		log.Error("Error resolving dependencies", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to resolve dependencies")
+		return
+	}
+
+	resp := apitypes.ResolveResponse{Dependencies: make([]apitypes.ResolvedDependency, 0, len(resolved.Dependencies))} // Empty array, not null
+	for _, dep := range resolved.Dependencies {                                                                        // Sorted by Resolve
+		resp.Dependencies = append(resp.Dependencies, apitypes.ResolvedDependency{Module: dep.Module, Version: dep.Version, Digest: dep.Digest})
+	}
+	log.Debug("Resolved dependencies", zap.Int("roots", len(req.Dependencies)), zap.Int("resolved", len(resp.Dependencies)))
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// readableSource hides the modules the caller can't read from a resolution, as if they were unpublished.
+type readableSource struct {
+	manifest.Source
+	r *http.Request
+}
+
+func (s *readableSource) Versions(module string) ([]string, error) {
+	if ok, err := s.readable(module); !ok {
+		return nil, err
+	}
+	return s.Source.Versions(module)
+}
+
+func (s *readableSource) Manifest(module, version string) (*manifest.Manifest, string, error) {
+	if ok, err := s.readable(module); !ok {
+		if err == nil {
+			err = fmt.Errorf("%w: %s@%s", descriptor.ErrNotFound, module, version)
+		}
+		return nil, "", err
+	}
+	return s.Source.Manifest(module, version)
+}
+
+func (s *readableSource) readable(module string) (bool, error) {
+	namespace, name, err := manifest.SplitModule(module)
+	if err != nil {
+		return false, err
+	}
+	return moduleReadable(s.r, namespace, name)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveHandler(t *testing.T) {
+	gormDB := newTestDB(t)
+	newTestStorage(t)
+
+	router := mux.NewRouter()
+	router.Use(ReadAuthMiddleware("admin-token"))
+	router.HandleFunc("/api/v1/resolve", ResolveHandler).Methods("POST")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	resolve := func(body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/resolve", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	publish := func(namespace, name, version string, files map[string]string) {
+		packed := map[string][]byte{}
+		for file, content := range files {
+			packed[file] = []byte(content)
+		}
+		data, err := artifact.Pack(packed)
+		assert.NoError(t, err)
+		req := newPublishRequest(t, namespace, name, version, "", data)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	for _, v := range []string{"v0.3.0", "v1.0.0"} {
+		publish("acme", "common", v, map[string]string{"acme/common/types.proto": `syntax = "proto3"; package acme.common; message T {}`})
+	}
+	publish("acme", "app", "v1.0.0", map[string]string{
+		"sproto.yaml":        "dependencies:\n  acme/common: ^0.3.0\n",
+		"acme/app/app.proto": `syntax = "proto3"; package acme.app; message A {}`,
+	})
+	publish("acme", "app", "v2.0.0", map[string]string{
+		"sproto.yaml":        "dependencies:\n  acme/common: ^1.0.0\n",
+		"acme/app/app.proto": `syntax = "proto3"; package acme.app; message A {}`,
+	})
+	publish("acme", "secret", "v1.0.0", map[string]string{"acme/secret/s.proto": `syntax = "proto3"; package acme.secret; message S {}`})
+	assert.NoError(t, gormDB.Model(&models.Module{}).Where("name = ?", "secret").Update("visibility", models.VisibilityPrivate).Error)
+
+	versions := func(rr *httptest.ResponseRecorder) map[string]string {
+		var resp apitypes.ResolveResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		out := map[string]string{}
+		for _, dep := range resp.Dependencies {
+			assert.True(t, strings.HasPrefix(dep.Digest, "sha256:"), dep.Digest)
+			out[dep.Module] = dep.Version
+		}
+		return out
+	}
+
+	// Transitive dependencies are pinned
+	rr := resolve(`{"dependencies": {"acme/app": "^2.0.0"}}`, "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, map[string]string{"acme/app": "v2.0.0", "acme/common": "v1.0.0"}, versions(rr))
+
+	// acme/app@v2.0.0 conflicts with the root's acme/common, so the resolver backtracks to v1.0.0
+	rr = resolve(`{"dependencies": {"acme/app": "*", "acme/common": "^0.3.0"}}`, "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, map[string]string{"acme/app": "v1.0.0", "acme/common": "v0.3.0"}, versions(rr))
+
+	// Locked versions are kept unless updated
+	rr = resolve(`{"dependencies": {"acme/app": "*"}, "locked": [{"module": "acme/app", "version": "v1.0.0"}], "update": ["acme/common"]}`, "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, map[string]string{"acme/app": "v1.0.0", "acme/common": "v0.3.0"}, versions(rr))
+
+	// Conflicts are explained
+	rr = resolve(`{"dependencies": {"acme/app": "^2.0.0", "acme/common": "^0.3.0"}}`, "")
+	assert.Equal(t, http.StatusConflict, rr.Code, rr.Body.String())
+	var conflict ResolveConflictResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &conflict))
+	assert.Equal(t, "acme/common", conflict.Conflict.Module)
+	assert.Equal(t, []manifest.Requirement{{From: "sproto.yaml", Constraint: "^0.3.0"}, {From: "acme/app@v2.0.0", Constraint: "^1.0.0"}}, conflict.Conflict.Requirements)
+	assert.Contains(t, conflict.Error, "no published version of acme/common satisfies")
+
+	// Private modules resolve only for callers that may read them
+	assert.Equal(t, http.StatusConflict, resolve(`{"dependencies": {"acme/secret": "^1.0.0"}}`, "").Code)
+	rr = resolve(`{"dependencies": {"acme/secret": "^1.0.0"}}`, "admin-token")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, map[string]string{"acme/secret": "v1.0.0"}, versions(rr))
+
+	// Nothing to resolve
+	rr = resolve(`{"dependencies": {}}`, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"dependencies": []}`, rr.Body.String())
+
+	for _, invalid := range []string{`not json`, `{"dependencies": {"nope": "^1.0.0"}}`, `{"dependencies": {"acme/app": "^^1"}}`, `{"locked": [{"module": "acme/app"}]}`} {
+		assert.Equal(t, http.StatusBadRequest, resolve(invalid, "").Code, invalid)
+	}
+}
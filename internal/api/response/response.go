@@ -2,8 +2,10 @@ package response
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
+
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"go.uber.org/zap"
 )
 
 // ErrorResponse defines the structure for JSON error responses.
@@ -19,7 +21,7 @@ func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	if data != nil {
 		if err := json.NewEncoder(w).Encode(data); err != nil {
 			// Log the error, but don't try to write another header as it's already sent.
-			log.Printf("Error encoding JSON response: %v", err)
+			logging.L().Error("Error encoding JSON response", zap.Error(err))
 			// Optionally write a plain text error to the body if possible
 			// http.Error(w, "Internal Server Error", http.StatusInternalServerError) // Avoid this after WriteHeader
 		}
@@ -28,6 +30,6 @@ func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
 
 // Error sends a JSON error response.
 func Error(w http.ResponseWriter, statusCode int, message string) {
-	log.Printf("API Error: Status %d, Message: %s", statusCode, message) // Log the error being sent
+	logging.L().Info("API error response", zap.Int("status", statusCode), zap.String("message", message)) // Log the error being sent
 	JSON(w, statusCode, ErrorResponse{Error: message})
 }
@@ -1,37 +1,600 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
 	"github.com/gorilla/mux"
 )
 
-// RegisterRoutes sets up the API routes for the registry server.
+// Query parameters, headers and form fields documented on several routes.
+var (
+	sinceParam     = routeParam{Name: "since", Description: "A date (2006-01-02) or RFC3339 timestamp"}
+	publisherParam = routeParam{Name: apitypes.PublisherHeader, Description: "Who publishes (recorded, and checked by publish policies)"}
+	branchParam    = routeParam{Name: apitypes.BranchHeader, Description: "Branch the publish comes from (checked by publish policies)"}
+	digestParam    = routeParam{Name: apitypes.ArtifactDigestHeader, Description: "sha256:<hex> of the artifact; the upload is rejected (422) if it differs"}
+	ifMatchParam   = routeParam{Name: "If-Match", Description: `"<revision>": only if the policy is still at that revision`}
+	artifactField  = routeParam{Name: "artifact", Description: "Zip of the module's .proto files", Type: "binary", Required: true}
+)
+
+// RegisterRoutes sets up the API routes for the registry server. /metrics is registered by RegisterMetricsRoute.
+// Each route is documented with a routeDoc, from which the API document (/api/v1/openapi.json) is generated.
 func RegisterRoutes(router *mux.Router, authToken string) {
+	// Assign request IDs and request-scoped loggers, and log every request
+	router.Use(RequestLoggingMiddleware)
+	// Turn handler panics into 500 JSON responses (registered after logging so the request ID is available)
+	router.Use(RecoveryMiddleware)
+	// Reject clients in the IP denylist before anything else looks at the request
+	router.Use(DenyIPsMiddleware)
+
 	// Define the base path for API v1
 	apiV1 := router.PathPrefix("/api/v1").Subrouter()
+	// Identify the caller for read authorization (module visibility)
+	apiV1.Use(ReadAuthMiddleware(authToken))
+	// Record the caller's client (User-Agent) for the client inventory
+	apiV1.Use(ClientInventoryMiddleware)
+
+	docs := &apiDocument{}
 
 	// --- Public Routes (No Auth Required) ---
 
+	// API Document: GET /api/v1/openapi.json
+	docs.add(apiV1.HandleFunc("/openapi.json", docs.serve).Methods("GET"), routeDoc{
+		ID: "getAPIDocument", Tag: "meta", Summary: "OpenAPI document of this API",
+		Response: json.RawMessage{},
+	})
+
 	// List All Modules: GET /api/v1/modules
-	apiV1.HandleFunc("/modules", ListModulesHandler).Methods("GET")
+	docs.add(apiV1.HandleFunc("/modules", ListModulesHandler).Methods("GET"), routeDoc{
+		ID: "listModules", Tag: "modules", Summary: "List the registered modules",
+		Query: []routeParam{
+			{Name: "namespace", Description: "Only modules of this namespace"},
+			{Name: "updated_after", Description: "Only modules created or published to since: a date (2006-01-02) or RFC3339 timestamp"},
+			{Name: "has_versions", Description: "Only modules with (true) or without (false) versions", Type: "boolean"},
+			{Name: "syntax", Description: "Only modules whose latest version declares this syntax: proto2, proto3 or edition-2023"},
+			{Name: "sort", Description: "name (default), updated_at (most recent first) or downloads (most downloaded first)"},
+		},
+		Response: ListModulesResponse{}, Errors: []int{400},
+	})
+
+	// Batch Module Metadata: POST /api/v1/modules:batchGet
+	docs.add(apiV1.HandleFunc("/modules:batchGet", BatchGetModulesHandler).Methods("POST"), routeDoc{
+		ID: "batchGetModules", Tag: "modules", Summary: "Get the metadata of several modules",
+		Request: BatchGetModulesRequest{}, Response: BatchGetModulesResponse{}, Errors: []int{400},
+	})
+
+	// Full-Text Search: GET /api/v1/search?q=
+	docs.add(apiV1.HandleFunc("/search", SearchHandler).Methods("GET"), routeDoc{
+		ID: "search", Tag: "modules", Summary: "Search module descriptions, files and declarations",
+		Query: []routeParam{
+			{Name: "q", Description: `Words that must all match; "quoted phrases" must match as a phrase`, Required: true},
+			{Name: "kind", Description: "module, file, message, enum, service, method or field"},
+			{Name: "module", Description: "Only this module: namespace/name"},
+			{Name: "limit", Description: "Maximum results (default 20, at most 100)", Type: "integer"},
+		},
+		Response: apitypes.SearchResponse{}, Errors: []int{400},
+	})
+
+	// Resolve Dependencies: POST /api/v1/resolve
+	docs.add(apiV1.HandleFunc("/resolve", ResolveHandler).Methods("POST"), routeDoc{
+		ID: "resolveDependencies", Tag: "modules", Summary: "Resolve constraints to a conflict-free set of pinned versions",
+		Request: apitypes.ResolveRequest{}, Response: apitypes.ResolveResponse{}, Errors: []int{400, 409, 422, 503},
+		ErrorBodies: map[int]any{409: ResolveConflictResponse{}},
+	})
+
+	// Proto Package Lookup: GET /api/v1/packages/{package}
+	docs.add(apiV1.HandleFunc("/packages/{package}", GetPackageHandler).Methods("GET"), routeDoc{
+		ID: "getPackage", Tag: "modules", Summary: "List the modules declaring a proto package",
+		Response: apitypes.PackageResponse{}, Errors: []int{400, 404},
+	})
 
 	// List Module Versions: GET /api/v1/modules/{namespace}/{module_name}
-	apiV1.HandleFunc("/modules/{namespace}/{module_name}", ListModuleVersionsHandler).Methods("GET")
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}", ListModuleVersionsHandler).Methods("GET"), routeDoc{
+		ID: "listModuleVersions", Tag: "modules", Summary: "List the versions of a module",
+		Query:    []routeParam{{Name: "syntax", Description: "Only versions declaring this syntax: proto2, proto3 or edition-2023"}},
+		Response: ListModuleVersionsResponse{}, Errors: []int{400, 404},
+	})
+
+	// Module Storage Usage: GET /api/v1/modules/{namespace}/{module_name}/usage
+	// Registered before the {version} route, which would otherwise match "usage"
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/usage", GetModuleUsageHandler).Methods("GET"), routeDoc{
+		ID: "getModuleUsage", Tag: "modules", Summary: "Storage consumed by the versions of a module",
+		Response: ModuleUsageResponse{}, Errors: []int{404},
+	})
+
+	// Module Consumers: GET /api/v1/modules/{namespace}/{module_name}/consumers
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/consumers", ModuleConsumersHandler).Methods("GET"), routeDoc{
+		ID: "listModuleConsumers", Tag: "modules", Summary: "Consumers that downloaded the versions of a module",
+		Query:    []routeParam{{Name: "version", Description: "Only downloads of this version"}, sinceParam},
+		Response: apitypes.ModuleConsumersResponse{}, Errors: []int{400, 404},
+	})
+
+	// Module Fetch SLOs: GET /api/v1/modules/{namespace}/{module_name}/slo
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/slo", GetModuleSLOHandler).Methods("GET"), routeDoc{
+		ID: "getModuleSLO", Tag: "modules", Summary: "Availability and latency of a module's downloads",
+		Query: []routeParam{
+			{Name: "windows", Description: "Comma-separated time windows (default 1h,24h,7d,30d)"},
+			{Name: "kind", Description: "Only one kind of download: artifact, bundle or secondary_artifact"},
+		},
+		Response: apitypes.ModuleSLOResponse{}, Errors: []int{400, 404},
+	})
+
+	// Module Compatibility Matrix: GET /api/v1/modules/{namespace}/{module_name}/compatibility
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/compatibility", GetModuleCompatibilityHandler).Methods("GET"), routeDoc{
+		ID: "getModuleCompatibility", Tag: "modules", Summary: "Compatibility matrix of a module's versions with their dependencies",
+		Response: descriptor.CompatibilityMatrix{}, Errors: []int{404, 503},
+	})
+
+	// Watch Module Versions: GET /api/v1/modules/{namespace}/{module_name}/watch
+	// Registered before the {version} route, which would otherwise match "watch"
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/watch", WatchModuleHandler).Methods("GET"), routeDoc{
+		ID: "watchModule", Tag: "modules", Summary: "Stream new versions of a module with their schema changes (server-sent events)",
+		Query:    []routeParam{{Name: "since", Description: "First replay the versions published after this one"}},
+		Headers:  []routeParam{{Name: "Last-Event-ID", Description: "Sent by reconnecting clients, like since"}},
+		Download: "text/event-stream", Errors: []int{404},
+	})
+
+	// Dev Channels: GET|HEAD /api/v1/modules/{namespace}/{module_name}/dev-{name}[/artifact]
+	// Registered before the {version} routes, which would otherwise match dev channels
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{channel:dev-[^/]*}", GetDevChannelHandler).Methods("GET", "HEAD"), routeDoc{
+		ID: "getDevChannel", Tag: "dev-channels", Summary: "Metadata of a dev channel",
+		Path:     "/api/v1/modules/{namespace}/{module_name}/dev-{channel}",
+		Response: apitypes.DevChannelResponse{}, Errors: []int{400, 404},
+	})
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{channel:dev-[^/]*}/artifact", FetchDevChannelArtifactHandler).Methods("GET", "HEAD"), routeDoc{
+		ID: "getDevChannelArtifact", Tag: "dev-channels", Summary: "Download the current artifact of a dev channel",
+		Path:     "/api/v1/modules/{namespace}/{module_name}/dev-{channel}/artifact",
+		Download: "application/zip", Errors: []int{400, 404},
+	})
+
+	// Resolve Latest Version (canary rollouts included): GET /api/v1/modules/{namespace}/{module_name}/latest
+	// Registered before the {version} route, which would otherwise match "latest"
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/latest", ResolveLatestHandler).Methods("GET"), routeDoc{
+		ID: "resolveLatestVersion", Tag: "modules", Summary: "Latest version of a module for the calling client (canary rollouts included)",
+		Query:    []routeParam{{Name: "client_id", Description: "Identifies the client for canary rollouts"}},
+		Headers:  []routeParam{{Name: apitypes.ClientIDHeader, Description: "Identifies the client for canary rollouts, without client_id"}},
+		Response: LatestVersionResponse{}, Errors: []int{404},
+	})
+
+	// Get Module Version Metadata: GET|HEAD /api/v1/modules/{namespace}/{module_name}/{version}
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{version}", GetModuleVersionHandler).Methods("GET", "HEAD"), routeDoc{
+		ID: "getModuleVersion", Tag: "versions", Summary: "Metadata of a module version, including its notes",
+		Response: apitypes.ModuleVersionResponse{}, Errors: []int{400, 404},
+	})
+
+	// Fetch Module Version Artifact: GET|HEAD /api/v1/modules/{namespace}/{module_name}/{version}/artifact
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{version}/artifact", measureFetch(FetchKindArtifact, FetchModuleVersionArtifactHandler)).Methods("GET", "HEAD"), routeDoc{
+		ID: "getModuleVersionArtifact", Tag: "versions", Summary: "Download the artifact of a module version (redirected to the CDN in CDN mode)",
+		Query:    []routeParam{{Name: "paths", Description: "Only these files or directories, re-packed into a zip: comma-separated"}},
+		Download: "application/zip", Responses: map[int]any{http.StatusFound: nil}, Errors: []int{400, 404, 410},
+	})
+
+	// List Secondary Artifacts: GET /api/v1/modules/{namespace}/{module_name}/{version}/artifacts
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{version}/artifacts", ListVersionArtifactsHandler).Methods("GET"), routeDoc{
+		ID: "listVersionArtifacts", Tag: "versions", Summary: "List the secondary artifacts of a module version",
+		Response: apitypes.ListVersionArtifactsResponse{}, Errors: []int{400, 404},
+	})
+
+	// List Artifact Files: GET /api/v1/modules/{namespace}/{module_name}/{version}/files
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{version}/files", ListVersionFilesHandler).Methods("GET"), routeDoc{
+		ID: "listVersionFiles", Tag: "versions", Summary: "List the files of a module version's artifact with their size and SHA256",
+		Query:    []routeParam{{Name: "paths", Description: "Only these files or directories: comma-separated"}},
+		Response: apitypes.ListVersionFilesResponse{}, Errors: []int{400, 404, 503},
+	})
+
+	// Get Generated OpenAPI Document: GET|HEAD /api/v1/modules/{namespace}/{module_name}/{version}/openapi.json
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{version}/openapi.json", GetModuleVersionOpenAPIHandler).Methods("GET", "HEAD"), routeDoc{
+		ID: "getModuleVersionOpenAPI", Tag: "versions", Summary: "OpenAPI document generated for the HTTP-annotated services of a module version",
+		Response: json.RawMessage{}, Errors: []int{400, 404, 410},
+	})
+
+	// Fetch Secondary Artifact: GET|HEAD /api/v1/modules/{namespace}/{module_name}/{version}/artifacts/{classifier}
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{version}/artifacts/{classifier}", measureFetch(FetchKindSecondaryArtifact, FetchVersionArtifactHandler)).Methods("GET", "HEAD"), routeDoc{
+		ID: "getVersionArtifact", Tag: "versions", Summary: "Download a secondary artifact of a module version (served with the content type it was attached with)",
+		Download: "application/octet-stream", Errors: []int{400, 404, 410},
+	})
+
+	// Get Module Version Bundle (with dependencies): GET /api/v1/modules/{namespace}/{module_name}/{version}/bundle
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{version}/bundle", measureFetch(FetchKindBundle, GetModuleVersionBundleHandler)).Methods("GET"), routeDoc{
+		ID: "getModuleVersionBundle", Tag: "versions", Summary: "A module version flattened together with its dependencies",
+		Query:    []routeParam{{Name: "format", Description: "zip (default) or descriptor_set (a binary FileDescriptorSet)"}},
+		Download: "application/zip", Errors: []int{400, 403, 404, 410, 422},
+	})
+
+	// List Message JSON Schemas: GET /api/v1/modules/{namespace}/{module_name}/{version}/jsonschema
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{version}/jsonschema", ListMessageSchemasHandler).Methods("GET"), routeDoc{
+		ID: "listMessageSchemas", Tag: "versions", Summary: "List the top-level messages of a module version",
+		Response: ListMessageSchemasResponse{}, Errors: []int{400, 404, 422},
+	})
+
+	// Get Message JSON Schema: GET /api/v1/modules/{namespace}/{module_name}/{version}/jsonschema/{message}
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{version}/jsonschema/{message}", GetMessageJSONSchemaHandler).Methods("GET"), routeDoc{
+		ID: "getMessageJSONSchema", Tag: "versions", Summary: "JSON Schema of a top-level message of a module version",
+		Download: "application/schema+json", Errors: []int{400, 404, 422},
+	})
+
+	// Get Module Version Changelog: GET /api/v1/modules/{namespace}/{module_name}/{version}/changelog
+	docs.add(apiV1.HandleFunc("/modules/{namespace}/{module_name}/{version}/changelog", GetModuleVersionChangelogHandler).Methods("GET"), routeDoc{
+		ID: "getModuleVersionChangelog", Tag: "versions", Summary: "CHANGELOG.md section of a module version",
+		Response: ModuleVersionChangelogResponse{}, Errors: []int{400, 404},
+	})
+
+	// Verify Digest: POST /api/v1/verify (read-only despite the POST)
+	docs.add(apiV1.HandleFunc("/verify", VerifyHandler).Methods("POST"), routeDoc{
+		ID: "verifyDigest", Tag: "versions", Summary: "Check a digest against the one recorded for a module version",
+		Request: apitypes.VerifyRequest{}, Response: apitypes.VerifyResponse{}, Errors: []int{400, 404},
+	})
+
+	// Checksum Log: GET /api/v1/checksums
+	docs.add(apiV1.HandleFunc("/checksums", ListChecksumsHandler).Methods("GET"), routeDoc{
+		ID: "listChecksums", Tag: "checksums", Summary: "Size and root hash of the checksum log, with a page of its entries",
+		Query: []routeParam{
+			{Name: "start", Description: "Index of the first entry", Type: "integer"},
+			{Name: "limit", Description: "Maximum entries", Type: "integer"},
+		},
+		Response: ChecksumLogResponse{}, Errors: []int{400, 503},
+	})
+
+	// Checksum Inclusion Proof: GET /api/v1/checksums/{namespace}/{module_name}/{version}
+	docs.add(apiV1.HandleFunc("/checksums/{namespace}/{module_name}/{version}", GetChecksumProofHandler).Methods("GET"), routeDoc{
+		ID: "getChecksumProof", Tag: "checksums", Summary: "Checksum log entry of a module version with an inclusion proof",
+		Query:    []routeParam{{Name: "tree_size", Description: "Prove against the tree of this many entries (default: the current tree)", Type: "integer"}},
+		Response: ChecksumProofResponse{}, Errors: []int{400, 404, 503},
+	})
+
+	// List Plugins: GET /api/v1/plugins
+	docs.add(apiV1.HandleFunc("/plugins", ListPluginsHandler).Methods("GET"), routeDoc{
+		ID: "listPlugins", Tag: "plugins", Summary: "List the registered plugin versions",
+		Response: apitypes.ListPluginsResponse{},
+	})
 
-	// Fetch Module Version Artifact: GET /api/v1/modules/{namespace}/{module_name}/{version}/artifact
-	apiV1.HandleFunc("/modules/{namespace}/{module_name}/{version}/artifact", FetchModuleVersionArtifactHandler).Methods("GET")
+	// Get Plugin Version: GET /api/v1/plugins/{name}/{version} ({version} may be partial or "latest")
+	docs.add(apiV1.HandleFunc("/plugins/{name}/{version}", GetPluginHandler).Methods("GET"), routeDoc{
+		ID: "getPlugin", Tag: "plugins", Summary: `Get a plugin version ({version} may be partial, e.g. v1.34, or "latest")`,
+		Response: apitypes.PluginResponse{}, Errors: []int{400, 404},
+	})
+
+	// Namespace Webhook Subscriptions: GET|POST /api/v1/namespaces/{namespace}/webhooks, GET|PUT|DELETE .../webhooks/{id}
+	// Authorized in the handlers: the admin token or a maintainer token of the namespace (MAINTAINER_TOKENS).
+	// Changes are writes, restricted to the write allowlist like the admin token routes
+	docs.add(apiV1.HandleFunc("/namespaces/{namespace}/webhooks", ListWebhookSubscriptionsHandler).Methods("GET"), routeDoc{
+		ID: "listWebhookSubscriptions", Tag: "namespaces", Summary: "List the webhook subscriptions of a namespace", Auth: authMaintainer,
+		Response: apitypes.ListWebhookSubscriptionsResponse{},
+	})
+	docs.add(apiV1.Handle("/namespaces/{namespace}/webhooks", RestrictWriteNetworks(http.HandlerFunc(CreateWebhookSubscriptionHandler))).Methods("POST"), routeDoc{
+		ID: "createWebhookSubscription", Tag: "namespaces", Summary: "Subscribe an endpoint to the events of a namespace", Auth: authMaintainer,
+		Request: apitypes.WebhookSubscriptionRequest{}, Status: http.StatusCreated, Response: apitypes.WebhookSubscriptionResponse{}, Errors: []int{400, 409},
+	})
+	docs.add(apiV1.HandleFunc("/namespaces/{namespace}/webhooks/{id}", GetWebhookSubscriptionHandler).Methods("GET"), routeDoc{
+		ID: "getWebhookSubscription", Tag: "namespaces", Summary: "Get a webhook subscription of a namespace", Auth: authMaintainer,
+		Response: apitypes.WebhookSubscriptionResponse{}, Errors: []int{404},
+	})
+	docs.add(apiV1.Handle("/namespaces/{namespace}/webhooks/{id}", RestrictWriteNetworks(http.HandlerFunc(PutWebhookSubscriptionHandler))).Methods("PUT"), routeDoc{
+		ID: "putWebhookSubscription", Tag: "namespaces", Summary: "Replace a webhook subscription of a namespace", Auth: authMaintainer,
+		Request: apitypes.WebhookSubscriptionRequest{}, Response: apitypes.WebhookSubscriptionResponse{}, Errors: []int{400, 404},
+	})
+	docs.add(apiV1.Handle("/namespaces/{namespace}/webhooks/{id}", RestrictWriteNetworks(http.HandlerFunc(DeleteWebhookSubscriptionHandler))).Methods("DELETE"), routeDoc{
+		ID: "deleteWebhookSubscription", Tag: "namespaces", Summary: "Remove a webhook subscription of a namespace", Auth: authMaintainer,
+		Status: http.StatusNoContent, Errors: []int{404},
+	})
 
 	// --- Protected Routes (Auth Required) ---
 
 	// Publish Module Version: POST /api/v1/modules/{namespace}/{module_name}/{version}
 	// Wrap the handler with the authentication middleware
 	publishHandler := http.HandlerFunc(PublishModuleVersionHandler)
-	apiV1.Handle("/modules/{namespace}/{module_name}/{version}", ApplyAuth(publishHandler, authToken)).Methods("POST")
+	// Authenticated before taking a publish slot, so unauthenticated requests can't exhaust the limit
+	docs.add(apiV1.Handle("/modules/{namespace}/{module_name}/{version}", ApplyAuth(LimitPublishes(publishHandler), authToken)).Methods("POST"), routeDoc{
+		ID: "publishModuleVersion", Tag: "versions", Summary: "Publish a module version", Auth: authAdmin,
+		Query: []routeParam{
+			{Name: "validate_only", Description: "Run the server-side checks without persisting anything (200)", Type: "boolean"},
+			{Name: "from", Description: "Create the version from an existing version's artifact (no body)"},
+			{Name: "async", Description: "Queue the upload and respond 202 with an operation to poll", Type: "boolean"},
+			{Name: "visibility", Description: "Visibility of a new module: public or private"},
+		},
+		Headers: []routeParam{publisherParam, branchParam, digestParam,
+			{Name: apitypes.ProvenanceHeader, Description: "JSON array of the registries the version is promoted from, the original first"}},
+		Upload: "multipart/form-data", Form: []routeParam{artifactField}, OptionalBody: true,
+		Status: http.StatusCreated, Response: apitypes.PublishModuleVersionResponse{},
+		Responses:   map[int]any{http.StatusOK: apitypes.ValidatePublishResponse{}, http.StatusAccepted: apitypes.OperationResponse{}},
+		Errors:      []int{400, 403, 409, 413, 422},
+		ErrorBodies: map[int]any{403: apitypes.PolicyDeniedResponse{}, 422: apitypes.ValidationFailedResponse{}},
+	})
+
+	// Publish / Delete Dev Channel: PUT|DELETE /api/v1/modules/{namespace}/{module_name}/dev-{name}
+	devPublishHandler := http.HandlerFunc(PublishDevChannelHandler)
+	docs.add(apiV1.Handle("/modules/{namespace}/{module_name}/{channel:dev-[^/]*}", ApplyAuth(LimitPublishes(devPublishHandler), authToken)).Methods("PUT"), routeDoc{
+		ID: "publishDevChannel", Tag: "dev-channels", Summary: "Create or replace the artifact of a dev channel", Auth: authAdmin,
+		Path:    "/api/v1/modules/{namespace}/{module_name}/dev-{channel}",
+		Headers: []routeParam{publisherParam},
+		Upload:  "multipart/form-data", Form: []routeParam{artifactField},
+		Status: http.StatusCreated, Response: apitypes.DevChannelResponse{}, Responses: map[int]any{http.StatusOK: apitypes.DevChannelResponse{}},
+		Errors: []int{400, 403, 404, 413, 422}, ErrorBodies: map[int]any{403: apitypes.PolicyDeniedResponse{}, 422: apitypes.ValidationFailedResponse{}},
+	})
+	docs.add(apiV1.Handle("/modules/{namespace}/{module_name}/{channel:dev-[^/]*}", ApplyAuth(http.HandlerFunc(DeleteDevChannelHandler), authToken)).Methods("DELETE"), routeDoc{
+		ID: "deleteDevChannel", Tag: "dev-channels", Summary: "Delete a dev channel and its artifact", Auth: authAdmin,
+		Path:   "/api/v1/modules/{namespace}/{module_name}/dev-{channel}",
+		Status: http.StatusNoContent, Errors: []int{400, 404},
+	})
+
+	// Delete Module Version: DELETE /api/v1/modules/{namespace}/{module_name}/{version}
+	// Registered after the dev channel route, which would otherwise be matched as a version
+	docs.add(apiV1.Handle("/modules/{namespace}/{module_name}/{version}", ApplyAuth(http.HandlerFunc(DeleteModuleVersionHandler), authToken)).Methods("DELETE"), routeDoc{
+		ID: "deleteModuleVersion", Tag: "versions", Summary: "Delete a module version and its artifacts", Auth: authAdmin,
+		Status: http.StatusNoContent, Errors: []int{400, 404},
+	})
+
+	// Operations: GET /api/v1/operations[/{id}], POST /api/v1/operations/{id}/cancel (asynchronous publishes, admin jobs)
+	docs.add(apiV1.Handle("/operations", ApplyAuth(http.HandlerFunc(ListOperationsHandler), authToken)).Methods("GET"), routeDoc{
+		ID: "listOperations", Tag: "operations", Summary: "List background operations, newest first", Auth: authAdmin,
+		Query: []routeParam{
+			{Name: "kind", Description: "Only operations of this kind, e.g. publish or gc"},
+			{Name: "status", Description: "Only operations in this status: queued, running, succeeded, failed or canceled"},
+			{Name: "limit", Description: "Maximum operations (default 50, at most 500)", Type: "integer"},
+		},
+		Response: apitypes.ListOperationsResponse{}, Errors: []int{400},
+	})
+	docs.add(apiV1.Handle("/operations/{id}", ApplyAuth(http.HandlerFunc(GetOperationHandler), authToken)).Methods("GET"), routeDoc{
+		ID: "getOperation", Tag: "operations", Summary: "Status of a background operation", Auth: authAdmin,
+		Response: apitypes.OperationResponse{}, Errors: []int{404},
+	})
+	docs.add(apiV1.Handle("/operations/{id}/cancel", ApplyAuth(http.HandlerFunc(CancelOperationHandler), authToken)).Methods("POST"), routeDoc{
+		ID: "cancelOperation", Tag: "operations", Summary: "Cancel a queued or running operation", Auth: authAdmin,
+		Response: apitypes.OperationResponse{}, Errors: []int{404, 409},
+	})
+
+	// Start Admin Job: POST /api/v1/admin/jobs/{job} (admin token only)
+	docs.add(apiV1.Handle("/admin/jobs/{job}", ApplyAuth(http.HandlerFunc(StartJobHandler), authToken)).Methods("POST"), routeDoc{
+		ID: "startJob", Tag: "admin", Summary: "Start an admin job as a background operation", Auth: authAdmin,
+		Request: apitypes.StartJobRequest{}, OptionalBody: true,
+		Status: http.StatusAccepted, Response: apitypes.OperationResponse{}, Errors: []int{400, 404},
+	})
+
+	// Storage Self-Test: POST /api/v1/admin/storage/selftest (admin token only)
+	docs.add(apiV1.Handle("/admin/storage/selftest", ApplyAuth(http.HandlerFunc(StorageSelfTestHandler), authToken)).Methods("POST"), routeDoc{
+		ID: "storageSelfTest", Tag: "admin", Summary: "Write, read back and delete a probe object through the storage provider", Auth: authAdmin,
+		Query:    []routeParam{{Name: "size", Description: "Size of the probe in bytes (default 1 KiB, at most 16 MiB)", Type: "integer"}},
+		Response: apitypes.StorageSelfTestResponse{}, Errors: []int{400, 503}, ErrorBodies: map[int]any{503: apitypes.StorageSelfTestResponse{}},
+	})
+
+	// Set Module Visibility: PUT /api/v1/modules/{namespace}/{module_name}/visibility
+	docs.add(apiV1.Handle("/modules/{namespace}/{module_name}/visibility", ApplyAuth(http.HandlerFunc(SetModuleVisibilityHandler), authToken)).Methods("PUT"), routeDoc{
+		ID: "setModuleVisibility", Tag: "modules", Summary: "Change who may read a module", Auth: authAdmin,
+		Request: apitypes.SetModuleVisibilityRequest{}, Response: apitypes.ModuleVisibilityResponse{}, Errors: []int{400, 404},
+	})
+
+	// Token Report: GET /api/v1/admin/tokens (admin token only)
+	docs.add(apiV1.Handle("/admin/tokens", ApplyAuth(TokenReportHandler(authToken), authToken)).Methods("GET"), routeDoc{
+		ID: "getTokenReport", Tag: "admin", Summary: "Configured tokens with their expiry and last use", Auth: authAdmin,
+		Query:    []routeParam{{Name: "stale", Description: "Only stale and expired tokens", Type: "boolean"}},
+		Response: apitypes.TokenReportResponse{},
+	})
+
+	// Managed Tokens: GET|POST /api/v1/admin/managed-tokens, GET|PUT|DELETE .../managed-tokens/{id} (admin token only)
+	docs.add(apiV1.Handle("/admin/managed-tokens", ApplyAuth(http.HandlerFunc(ListManagedTokensHandler), authToken)).Methods("GET"), routeDoc{
+		ID: "listManagedTokens", Tag: "admin", Summary: "List the tokens managed through the admin API", Auth: authAdmin,
+		Response: apitypes.ListManagedTokensResponse{},
+	})
+	docs.add(apiV1.Handle("/admin/managed-tokens", ApplyAuth(http.HandlerFunc(CreateManagedTokenHandler), authToken)).Methods("POST"), routeDoc{
+		ID: "createManagedToken", Tag: "admin", Summary: "Generate a read or maintainer token (returned only once)", Auth: authAdmin,
+		Request: apitypes.ManagedTokenRequest{}, Status: http.StatusCreated, Response: apitypes.ManagedTokenResponse{}, Errors: []int{400},
+	})
+	docs.add(apiV1.Handle("/admin/managed-tokens/{id}", ApplyAuth(http.HandlerFunc(GetManagedTokenHandler), authToken)).Methods("GET"), routeDoc{
+		ID: "getManagedToken", Tag: "admin", Summary: "Get a managed token (without the token itself)", Auth: authAdmin,
+		Response: apitypes.ManagedTokenResponse{}, Errors: []int{404},
+	})
+	docs.add(apiV1.Handle("/admin/managed-tokens/{id}", ApplyAuth(http.HandlerFunc(PutManagedTokenHandler), authToken)).Methods("PUT"), routeDoc{
+		ID: "putManagedToken", Tag: "admin", Summary: "Replace the grants, expiry and description of a managed token", Auth: authAdmin,
+		Request: apitypes.ManagedTokenRequest{}, Response: apitypes.ManagedTokenResponse{}, Errors: []int{400, 404},
+	})
+	docs.add(apiV1.Handle("/admin/managed-tokens/{id}", ApplyAuth(http.HandlerFunc(DeleteManagedTokenHandler), authToken)).Methods("DELETE"), routeDoc{
+		ID: "deleteManagedToken", Tag: "admin", Summary: "Revoke a managed token", Auth: authAdmin,
+		Status: http.StatusNoContent, Errors: []int{404},
+	})
+
+	// Client Inventory: GET /api/v1/admin/clients (admin token only)
+	docs.add(apiV1.Handle("/admin/clients", ApplyAuth(http.HandlerFunc(ClientInventoryHandler), authToken)).Methods("GET"), routeDoc{
+		ID: "getClientInventory", Tag: "admin", Summary: "Clients (and versions) each consumer used", Auth: authAdmin,
+		Query: []routeParam{
+			sinceParam,
+			{Name: "client", Description: "Only this client, which min_version applies to (default protoreg-cli)"},
+			{Name: "min_version", Description: "Flag the versions of the client older than this"},
+			{Name: "outdated", Description: "Only consumers still on an outdated version (requires min_version)", Type: "boolean"},
+		},
+		Response: apitypes.ClientInventoryResponse{}, Errors: []int{400},
+	})
+
+	// Original Uploads: GET /api/v1/admin/originals[/{digest}] (admin token only)
+	docs.add(apiV1.Handle("/admin/originals", ApplyAuth(http.HandlerFunc(ListOriginalUploadsHandler), authToken)).Methods("GET"), routeDoc{
+		ID: "listOriginalUploads", Tag: "admin", Summary: "List the original uploads that haven't expired, newest first", Auth: authAdmin,
+		Query: []routeParam{
+			{Name: "module", Description: "Only uploads of this module: namespace/name"},
+			{Name: "version", Description: "Only uploads of this version (requires module)"},
+			{Name: "digest", Description: "Only the upload with this digest: sha256:<hex>"},
+		},
+		Response: ListOriginalUploadsResponse{}, Errors: []int{400},
+	})
+	docs.add(apiV1.Handle("/admin/originals/{digest}", ApplyAuth(http.HandlerFunc(GetOriginalUploadHandler), authToken)).Methods("GET"), routeDoc{
+		ID: "getOriginalUpload", Tag: "admin", Summary: "Download an original upload", Auth: authAdmin,
+		Download: "application/zip", Errors: []int{404},
+	})
+
+	// Namespace Policies: GET /api/v1/admin/namespace-policies, GET|PUT|DELETE /api/v1/admin/namespace-policies/{namespace} (admin token only)
+	docs.add(apiV1.Handle("/admin/namespace-policies", ApplyAuth(http.HandlerFunc(ListNamespacePoliciesHandler), authToken)).Methods("GET"), routeDoc{
+		ID: "listNamespacePolicies", Tag: "namespaces", Summary: "List the namespaces that have a policy", Auth: authAdmin,
+		Response: apitypes.ListNamespacePoliciesResponse{},
+	})
+	docs.add(apiV1.Handle("/admin/namespace-policies/{namespace}", ApplyAuth(http.HandlerFunc(GetNamespacePolicyHandler), authToken)).Methods("GET"), routeDoc{
+		ID: "getNamespacePolicy", Tag: "namespaces", Summary: "Get the policy of a namespace", Auth: authAdmin,
+		Response: apitypes.NamespacePolicyResponse{}, Errors: []int{404},
+	})
+	docs.add(apiV1.Handle("/admin/namespace-policies/{namespace}", ApplyAuth(http.HandlerFunc(PutNamespacePolicyHandler), authToken)).Methods("PUT"), routeDoc{
+		ID: "putNamespacePolicy", Tag: "namespaces", Summary: "Create or replace the policy of a namespace", Auth: authAdmin,
+		Headers: []routeParam{ifMatchParam, {Name: "If-None-Match", Description: "*: only if the namespace has no policy yet"}},
+		Request: apitypes.NamespacePolicyRequest{}, Response: apitypes.NamespacePolicyResponse{}, Errors: []int{400, 409, 412},
+	})
+	docs.add(apiV1.Handle("/admin/namespace-policies/{namespace}", ApplyAuth(http.HandlerFunc(DeleteNamespacePolicyHandler), authToken)).Methods("DELETE"), routeDoc{
+		ID: "deleteNamespacePolicy", Tag: "namespaces", Summary: "Remove the policy of a namespace", Auth: authAdmin,
+		Headers: []routeParam{ifMatchParam},
+		Status:  http.StatusNoContent, Errors: []int{404, 409, 412},
+	})
+
+	// Add Version Note: POST /api/v1/modules/{namespace}/{module_name}/{version}/notes
+	docs.add(apiV1.Handle("/modules/{namespace}/{module_name}/{version}/notes", ApplyAuth(http.HandlerFunc(AddVersionNoteHandler), authToken)).Methods("POST"), routeDoc{
+		ID: "addVersionNote", Tag: "versions", Summary: "Attach a note to a published module version", Auth: authAdmin,
+		Headers: []routeParam{{Name: apitypes.PublisherHeader, Description: "Author of the note"}},
+		Request: apitypes.AddVersionNoteRequest{}, Status: http.StatusCreated, Response: apitypes.VersionNoteResponse{}, Errors: []int{400, 404},
+	})
+
+	// Attach Secondary Artifact: PUT /api/v1/modules/{namespace}/{module_name}/{version}/artifacts/{classifier}
+	attachHandler := http.HandlerFunc(AttachVersionArtifactHandler)
+	docs.add(apiV1.Handle("/modules/{namespace}/{module_name}/{version}/artifacts/{classifier}", ApplyAuth(LimitPublishes(attachHandler), authToken)).Methods("PUT"), routeDoc{
+		ID: "attachVersionArtifact", Tag: "versions", Summary: "Attach a secondary artifact, stored with the request's Content-Type", Auth: authAdmin,
+		Upload: "application/octet-stream",
+		Status: http.StatusCreated, Response: apitypes.VersionArtifactResponse{}, Responses: map[int]any{http.StatusOK: apitypes.VersionArtifactResponse{}},
+		Errors: []int{400, 404, 409, 413},
+	})
+
+	// Deprecate / Undeprecate Module Version: PUT|DELETE /api/v1/modules/{namespace}/{module_name}/{version}/deprecation
+	docs.add(apiV1.Handle("/modules/{namespace}/{module_name}/{version}/deprecation", ApplyAuth(http.HandlerFunc(DeprecateModuleVersionHandler), authToken)).Methods("PUT"), routeDoc{
+		ID: "deprecateModuleVersion", Tag: "versions", Summary: "Deprecate a module version, optionally with a sunset date", Auth: authAdmin,
+		Request: apitypes.DeprecateModuleVersionRequest{}, OptionalBody: true, Response: apitypes.DeprecationResponse{}, Errors: []int{400, 404},
+	})
+	docs.add(apiV1.Handle("/modules/{namespace}/{module_name}/{version}/deprecation", ApplyAuth(http.HandlerFunc(DeprecateModuleVersionHandler), authToken)).Methods("DELETE"), routeDoc{
+		ID: "undeprecateModuleVersion", Tag: "versions", Summary: "Lift the deprecation and sunset of a module version", Auth: authAdmin,
+		Response: apitypes.DeprecationResponse{}, Errors: []int{400, 404},
+	})
+
+	// Set / Complete Canary Rollout: PUT|DELETE /api/v1/modules/{namespace}/{module_name}/{version}/canary
+	docs.add(apiV1.Handle("/modules/{namespace}/{module_name}/{version}/canary", ApplyAuth(http.HandlerFunc(SetCanaryHandler), authToken)).Methods("PUT"), routeDoc{
+		ID: "setCanary", Tag: "versions", Summary: "Mark a module version as canary with a rollout percentage", Auth: authAdmin,
+		Request: apitypes.CanaryRequest{}, Response: apitypes.CanaryResponse{}, Errors: []int{400, 404},
+	})
+	docs.add(apiV1.Handle("/modules/{namespace}/{module_name}/{version}/canary", ApplyAuth(http.HandlerFunc(SetCanaryHandler), authToken)).Methods("DELETE"), routeDoc{
+		ID: "completeCanary", Tag: "versions", Summary: "Lift the canary mark of a module version, completing the rollout", Auth: authAdmin,
+		Response: apitypes.CanaryResponse{}, Errors: []int{400, 404},
+	})
+
+	// Cross-Module Impact Analysis: POST /api/v1/impact
+	impactHandler := http.HandlerFunc(ImpactAnalysisHandler)
+	docs.add(apiV1.Handle("/impact", ApplyAuth(LimitPublishes(impactHandler), authToken)).Methods("POST"), routeDoc{
+		ID: "analyzeImpact", Tag: "modules", Summary: "Report which dependent modules a proposed artifact would break", Auth: authAdmin,
+		Upload: "multipart/form-data",
+		Form: []routeParam{
+			{Name: "namespace", Required: true},
+			{Name: "module_name", Required: true},
+			{Name: "version", Description: "Proposed version"},
+			artifactField,
+		},
+		Response: descriptor.ImpactReport{}, Errors: []int{400, 404, 413, 422},
+	})
+
+	// Register / Delete Plugin Version: POST|DELETE /api/v1/plugins/{name}/{version}
+	docs.add(apiV1.Handle("/plugins/{name}/{version}", ApplyAuth(http.HandlerFunc(RegisterPluginHandler), authToken)).Methods("POST"), routeDoc{
+		ID: "registerPlugin", Tag: "plugins", Summary: "Register a plugin version", Auth: authAdmin,
+		Request: apitypes.RegisterPluginRequest{}, Status: http.StatusCreated, Response: apitypes.PluginResponse{}, Errors: []int{400, 409},
+	})
+	docs.add(apiV1.Handle("/plugins/{name}/{version}", ApplyAuth(http.HandlerFunc(DeletePluginHandler), authToken)).Methods("DELETE"), routeDoc{
+		ID: "deletePlugin", Tag: "plugins", Summary: "Unregister a plugin version (exact version only)", Auth: authAdmin,
+		Status: http.StatusNoContent, Errors: []int{404},
+	})
+
+	// --- CDN Origin (signed URLs, only active in CDN mode) ---
+
+	// Fetch Artifact via Signed URL: GET|HEAD /cdn/v1/modules/{namespace}/{module_name}/{version}/artifact
+	docs.add(router.HandleFunc(CDNOriginPathPrefix+"/{namespace}/{module_name}/{version}/artifact", CDNArtifactHandler).Methods("GET", "HEAD"), routeDoc{
+		ID: "getCDNArtifact", Tag: "versions", Summary: "CDN origin: the artifact of a module version, via a signed URL issued by the artifact endpoint", Auth: authNone,
+		Query: []routeParam{
+			{Name: "expires", Description: "Expiry of the signed URL (Unix time)", Type: "integer", Required: true},
+			{Name: "signature", Description: "Signature of the URL", Required: true},
+		},
+		Download: "application/zip", Errors: []int{403, 404, 410},
+	})
+
+	// --- Go Module Proxy (GOPROXY protocol, only active with GO_PROXY_MODULES) ---
+	goProxyRouter := router.PathPrefix(GoProxyPathPrefix).Subrouter()
+	// The go command authenticates with Basic credentials (.netrc); otherwise read authorization as for /api/v1
+	goProxyRouter.Use(basicTokenMiddleware, ReadAuthMiddleware(authToken), ClientInventoryMiddleware)
+
+	// List Go Module Versions: GET /gomod/{module_path}/@v/list
+	docs.add(goProxyRouter.HandleFunc("/{module_path:.+}/@v/list", GoModuleListHandler).Methods("GET"), routeDoc{
+		ID: "listGoModuleVersions", Tag: "go-proxy", Summary: "Versions of a generated Go module, one per line",
+		Download: "text/plain", Errors: []int{404},
+	})
+	// Go Module Version Info: GET /gomod/{module_path}/@v/{version}.info
+	docs.add(goProxyRouter.HandleFunc("/{module_path:.+}/@v/{version}.info", GoModuleInfoHandler).Methods("GET"), routeDoc{
+		ID: "getGoModuleInfo", Tag: "go-proxy", Summary: "Version and publish time of a generated Go module version",
+		Response: GoModuleInfo{}, Errors: []int{404, 410},
+	})
+	// Go Module go.mod: GET /gomod/{module_path}/@v/{version}.mod
+	docs.add(goProxyRouter.HandleFunc("/{module_path:.+}/@v/{version}.mod", GoModuleModHandler).Methods("GET"), routeDoc{
+		ID: "getGoModuleMod", Tag: "go-proxy", Summary: "go.mod file of a generated Go module version (generated on first request)",
+		Download: "text/plain", Errors: []int{403, 404, 410, 422},
+	})
+	// Go Module Zip: GET /gomod/{module_path}/@v/{version}.zip
+	docs.add(goProxyRouter.HandleFunc("/{module_path:.+}/@v/{version}.zip", GoModuleZipHandler).Methods("GET"), routeDoc{
+		ID: "getGoModuleZip", Tag: "go-proxy", Summary: "Zip of a generated Go module version (generated on first request)",
+		Download: "application/zip", Errors: []int{403, 404, 410, 422},
+	})
+	// Latest Go Module Version: GET /gomod/{module_path}/@latest
+	docs.add(goProxyRouter.HandleFunc("/{module_path:.+}/@latest", GoModuleLatestHandler).Methods("GET"), routeDoc{
+		ID: "getGoModuleLatest", Tag: "go-proxy", Summary: "Latest version of a generated Go module (the highest release, else pre-release)",
+		Response: GoModuleInfo{}, Errors: []int{404},
+	})
+
+	// --- Maven Repository (generated Java stubs, only active with MAVEN_GROUP_PREFIX) ---
+	mavenRouter := router.PathPrefix(MavenPathPrefix).Subrouter()
+	// Maven authenticates with Basic credentials (settings.xml); otherwise read authorization as for /api/v1
+	mavenRouter.Use(basicTokenMiddleware, ReadAuthMiddleware(authToken), ClientInventoryMiddleware)
+
+	// Maven Repository File: GET|HEAD /maven/{path}
+	docs.add(mavenRouter.HandleFunc("/{path:.+}", MavenRepositoryHandler).Methods("GET", "HEAD"), routeDoc{
+		ID: "getMavenFile", Tag: "package-bridges", Summary: "maven-metadata.xml, jar or POM of a bridged module (or their .md5/.sha1/.sha256/.sha512)",
+		Download: "application/octet-stream", Errors: []int{404, 410, 503},
+	})
+
+	// --- npm Registry (generated TypeScript stubs, only active with NPM_SCOPE) ---
+	npmRouter := router.PathPrefix(NPMPathPrefix).Subrouter()
+	npmRouter.Use(basicTokenMiddleware, ReadAuthMiddleware(authToken), ClientInventoryMiddleware)
+
+	// npm Packument: GET /npm/{scope}/{package}
+	docs.add(npmRouter.HandleFunc("/{scope:@[^/]+}/{package}", NPMPackumentHandler).Methods("GET"), routeDoc{
+		ID: "getNPMPackument", Tag: "package-bridges", Summary: "Versions of a bridged npm package with their dependencies and tarballs",
+		Response: NPMPackument{}, Errors: []int{404, 503},
+	})
+	// npm Tarball: GET|HEAD /npm/{scope}/{package}/-/{tarball}
+	docs.add(npmRouter.HandleFunc("/{scope:@[^/]+}/{package}/-/{tarball}", NPMTarballHandler).Methods("GET", "HEAD"), routeDoc{
+		ID: "getNPMTarball", Tag: "package-bridges", Summary: "Tarball of a bridged npm package version",
+		Download: "application/gzip", Errors: []int{404, 410, 503},
+	})
+
+	// --- Version Signing Keys (public, for verifying version signatures) ---
+	docs.add(router.HandleFunc(apitypes.SigningKeysPath, SigningKeysHandler).Methods("GET"), routeDoc{
+		ID: "listSigningKeys", Tag: "versions", Summary: "Public keys version signatures can be verified with", Auth: authNone,
+		Response: apitypes.SigningKeysResponse{},
+	})
 
 	// --- Health Check (Outside API versioning for simplicity) ---
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	docs.add(router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("OK")) // Explicitly ignore error
-	}).Methods("GET")
+	}).Methods("GET"), routeDoc{
+		ID: "health", Tag: "meta", Summary: "Liveness check", Auth: authNone,
+		Download: "text/plain",
+	})
+
+	// Readiness: database and storage reachable, with the last collected capacity
+	docs.add(router.HandleFunc("/readyz", ReadyzHandler).Methods("GET"), routeDoc{
+		ID: "readyz", Tag: "meta", Summary: "Readiness: database and storage reachable, with the last collected capacity", Auth: authNone,
+		Response: ReadyzResponse{}, Errors: []int{503}, ErrorBodies: map[int]any{503: ReadyzResponse{}},
+	})
+}
+
+// RegisterMetricsRoute exposes the Prometheus metrics on /metrics. It is registered separately so the
+// metrics can be served on their own listener (METRICS_LISTEN_ADDRESS).
+func RegisterMetricsRoute(router *mux.Router) {
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
 }
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Schema change detection: at publish, the artifact's compiled descriptors are compared with those of the
+// previous version. If only comments or formatting changed, the version is tagged "no schema change", so
+// consumers can skip regenerating code for it.
+
+// Whether publishes compare schemas, configured at startup via SetSchemaChangeDetection (off by default,
+// as it compiles the artifact and the previous version on every publish).
+var detectSchemaChanges bool
+
+// SetSchemaChangeDetection enables or disables tagging versions without schema changes.
+func SetSchemaChangeDetection(enabled bool) {
+	detectSchemaChanges = enabled
+}
+
+// detectNoSchemaChange returns the previous version of the module if the uploaded artifact has the same
+// compiled schema, or "". The file is rewound afterwards. Detection is best-effort and never fails a
+// publish: artifacts that don't compile, first versions and errors are simply not tagged.
+func detectNoSchemaChange(ctx context.Context, file multipart.File, namespace, moduleName, version string) string {
+	if !detectSchemaChanges {
+		return ""
+	}
+	log := logging.FromContext(ctx).With(zap.String("module", namespace+"/"+moduleName), zap.String("version", version))
+
+	artifact, err := io.ReadAll(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		log.Warn("Error reading artifact for schema change detection", zap.Error(err))
+		return ""
+	}
+	return compareSchemaWithPrevious(ctx, artifact, namespace, moduleName, version)
+}
+
+// compareSchemaWithPrevious is detectNoSchemaChange for an artifact already in memory.
+func compareSchemaWithPrevious(ctx context.Context, artifact []byte, namespace, moduleName, version string) string {
+	if !detectSchemaChanges {
+		return ""
+	}
+	log := logging.FromContext(ctx).With(zap.String("module", namespace+"/"+moduleName), zap.String("version", version))
+
+	loader := descriptor.NewLoader(db.GetDB(), storage.GetStorageProvider())
+	cmp, err := loader.CompareWithPrevious(ctx, namespace, moduleName, version, artifact)
+	switch {
+	case errors.Is(err, descriptor.ErrNotFound):
+		log.Debug("No previous version to compare the schema with", zap.Error(err))
+		return ""
+	case errors.Is(err, descriptor.ErrInvalidArtifact):
+		log.Debug("Artifact schema could not be compiled for comparison", zap.Error(err))
+		return ""
+	case err != nil:
+		log.Warn("Schema change detection failed", zap.Error(err))
+		return ""
+	}
+	if !cmp.Unchanged {
+		return ""
+	}
+	log.Info("Version has no schema change", zap.String("previous_version", cmp.PreviousVersion))
+	return cmp.PreviousVersion
+}
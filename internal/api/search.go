@@ -0,0 +1,432 @@
+package api
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Full-text search: every module is indexed with its sproto.yaml description, along with the files and
+// declarations (messages, enums, services, methods, fields) of its newest version and their leading
+// comments (see descriptor.ArtifactSearchEntries). Documents are replaced at each publish, best-effort:
+// a failure is logged, and the reindex-search admin job rebuilds the index. Queries run against a
+// tsvector column on PostgreSQL and an FTS4 table on SQLite (see db.MigrateSearchIndex); results are
+// ranked with matches in names weighted above matches in comments, and come with a highlighted snippet.
+
+// Search limits.
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	maxSearchQueryLen  = 256
+	// Matches are fetched in batches until enough readable ones are found (modules the caller can't read
+	// are skipped), up to maxSearchBatches batches.
+	searchBatchSize  = 100
+	maxSearchBatches = 10
+)
+
+// searchKinds are the accepted values of ?kind=.
+var searchKinds = map[string]bool{
+	models.SearchKindModule:      true,
+	descriptor.SearchKindFile:    true,
+	descriptor.SearchKindMessage: true,
+	descriptor.SearchKindEnum:    true,
+	descriptor.SearchKindService: true,
+	descriptor.SearchKindMethod:  true,
+	descriptor.SearchKindField:   true,
+}
+
+// --- Indexing ---
+
+// artifactSearchEntries is what is indexed for a module version.
+type artifactSearchEntries struct {
+	description string
+	entries     []descriptor.SearchEntry
+}
+
+// readSearchEntries extracts the search entries of an uploaded artifact (see extractSearchEntries). The
+// file is rewound afterwards.
+func readSearchEntries(ctx context.Context, file multipart.File) *artifactSearchEntries {
+	artifact, err := io.ReadAll(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("Error reading artifact for search indexing", zap.Error(err))
+		return nil
+	}
+	return extractSearchEntries(ctx, artifact)
+}
+
+// extractSearchEntries returns the search entries of an artifact, or nil (logged) if its files don't parse.
+func extractSearchEntries(ctx context.Context, artifact []byte) *artifactSearchEntries {
+	description, entries, err := descriptor.ArtifactSearchEntries(artifact)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to extract search entries from the artifact", zap.Error(err))
+		return nil
+	}
+	return &artifactSearchEntries{description: description, entries: entries}
+}
+
+// indexForSearch replaces the search documents of a module with those of a just-published version.
+// Best-effort: failures are logged, the module keeps its previous documents until the next publish or
+// reindex-search job.
+func indexForSearch(ctx context.Context, namespace, moduleName string, moduleVersion *models.ModuleVersion, extracted *artifactSearchEntries) {
+	if extracted == nil {
+		return
+	}
+	log := logging.FromContext(ctx).With(zap.String("module", namespace+"/"+moduleName), zap.String("version", moduleVersion.Version))
+	indexed, err := replaceSearchDocuments(ctx, db.GetDB(), namespace, moduleName, moduleVersion, extracted)
+	if err != nil {
+		log.Warn("Failed to index module version for search", zap.Error(err))
+		return
+	}
+	if indexed {
+		log.Debug("Indexed module version for search", zap.Int("entries", len(extracted.entries)))
+	}
+}
+
+// replaceSearchDocuments replaces the search documents of a module with those extracted from one of its
+// versions, unless a newer version has been published meanwhile (which is indexed instead). Returns
+// whether the documents were replaced.
+func replaceSearchDocuments(ctx context.Context, gormDB *gorm.DB, namespace, moduleName string, moduleVersion *models.ModuleVersion, extracted *artifactSearchEntries) (bool, error) {
+	docs := make([]models.SearchDocument, 0, len(extracted.entries)+1)
+	docs = append(docs, models.SearchDocument{
+		Kind:  models.SearchKindModule,
+		Name:  namespace + "/" + moduleName,
+		Terms: searchTerms(namespace + "/" + moduleName),
+		Body:  extracted.description,
+	})
+	for _, e := range extracted.entries {
+		docs = append(docs, models.SearchDocument{Kind: e.Kind, Name: e.Name, File: e.File, Terms: searchTerms(e.Name), Body: e.Comment})
+	}
+	for i := range docs {
+		docs[i].ModuleID = moduleVersion.ModuleID
+		docs[i].ModuleVersionID = moduleVersion.ID
+	}
+
+	indexed := false
+	err := gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var newest models.ModuleVersion
+		if err := tx.Select("id").Where("module_id = ?", moduleVersion.ModuleID).Order("created_at DESC").First(&newest).Error; err != nil {
+			return err
+		}
+		if newest.ID != moduleVersion.ID {
+			return nil // Superseded
+		}
+		if err := tx.Where("module_id = ?", moduleVersion.ModuleID).Delete(&models.SearchDocument{}).Error; err != nil {
+			return err
+		}
+		if err := tx.CreateInBatches(docs, 200).Error; err != nil {
+			return err
+		}
+		indexed = true
+		return nil
+	})
+	return indexed, err
+}
+
+// searchTerms returns the words of a name for indexing: each part of the name (split on dots, slashes,
+// underscores and other separators), followed by its camel-case words if it has several, so that
+// "orders.v1.PlaceOrderRequest" is found by "orders", "PlaceOrderRequest" and "order".
+func searchTerms(name string) string {
+	var terms []string
+	parts := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for _, part := range parts {
+		terms = append(terms, part)
+		if words := camelCaseWords(part); len(words) > 1 {
+			terms = append(terms, words...)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// camelCaseWords splits an identifier at lower-to-upper case transitions and before the last capital of
+// an acronym ("HTTPServer" -> "HTTP", "Server").
+func camelCaseWords(s string) []string {
+	runes := []rune(s)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		boundary := (unicode.IsLower(prev) && unicode.IsUpper(cur)) ||
+			(unicode.IsUpper(prev) && unicode.IsUpper(cur) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))
+		if boundary {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(runes[start:]))
+}
+
+// indexModule indexes the newest version of a module, downloading its artifact. Used by the
+// reindex-search job.
+func indexModule(ctx context.Context, module models.Module) error {
+	gormDB := db.GetDB().WithContext(ctx)
+	var newest models.ModuleVersion
+	err := gormDB.Where("module_id = ?", module.ID).Order("created_at DESC").First(&newest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // No versions (yet)
+	} else if err != nil {
+		return err
+	}
+	reader, err := storage.GetStorageProvider().DownloadFile(ctx, newest.ArtifactStorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to download artifact of %s: %w", newest.Version, err)
+	}
+	artifact, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read artifact of %s: %w", newest.Version, err)
+	}
+	description, entries, err := descriptor.ArtifactSearchEntries(artifact)
+	if err != nil {
+		return fmt.Errorf("version %s: %w", newest.Version, err)
+	}
+	_, err = replaceSearchDocuments(ctx, gormDB, module.Namespace, module.Name, &newest, &artifactSearchEntries{description: description, entries: entries})
+	return err
+}
+
+// --- Search Endpoint ---
+
+// searchQuery is a validated search request.
+type searchQuery struct {
+	text      string   // As entered
+	terms     []string // Words and "quoted phrases", for SQLite
+	kind      string
+	namespace string
+	name      string
+}
+
+// parseSearchTerms splits a query into words and phrases (in double quotes), dropping punctuation.
+func parseSearchTerms(q string) []string {
+	var terms []string
+	words := func(s string) string {
+		return strings.Join(strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }), " ")
+	}
+	for i, part := range strings.Split(q, `"`) {
+		if i%2 == 1 { // Inside quotes
+			if phrase := words(part); phrase != "" {
+				terms = append(terms, phrase)
+			}
+			continue
+		}
+		terms = append(terms, strings.Fields(words(part))...)
+	}
+	return terms
+}
+
+// SearchHandler runs a full-text search over module descriptions, files and declarations.
+// GET /api/v1/search?q=...
+// Optional filters: ?kind= (module, file, message, enum, service, method, field), ?module=namespace/name,
+// and ?limit= (default 20, max 100). Words must all match (in any order); "quoted phrases" must match as
+// a phrase. Modules the caller can't read are left out.
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	params := r.URL.Query()
+	query := searchQuery{text: strings.TrimSpace(params.Get("q")), kind: params.Get("kind")}
+	query.terms = parseSearchTerms(query.text)
+	if len(query.terms) == 0 {
+		response.Error(w, http.StatusBadRequest, "Query parameter q must contain at least one word")
+		return
+	}
+	if len(query.text) > maxSearchQueryLen {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Query is too long (max %d characters)", maxSearchQueryLen))
+		return
+	}
+	if query.kind != "" && !searchKinds[query.kind] {
+		response.Error(w, http.StatusBadRequest, "Invalid kind: must be one of module, file, message, enum, service, method or field")
+		return
+	}
+	if module := params.Get("module"); module != "" {
+		var err error
+		if query.namespace, query.name, err = manifest.SplitModule(module); err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid module: %v", err))
+			return
+		}
+	}
+	limit := DefaultSearchLimit
+	if limitStr := params.Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 || n > MaxSearchLimit {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit: must be between 1 and %d", MaxSearchLimit))
+			return
+		}
+		limit = n
+	}
+
+	rd := readerFromContext(r.Context())
+	results := []apitypes.SearchResult{}
+	gormDB := requestDB(r).WithContext(r.Context())
+	for batch := 0; batch < maxSearchBatches && len(results) < limit; batch++ {
+		rows, err := searchDocuments(gormDB, query, batch*searchBatchSize, searchBatchSize)
+		if err != nil {
+			log.Error("Error searching modules", zap.String("query", query.text), zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Search failed")
+			return
+		}
+		for _, row := range rows {
+			if len(results) == limit {
+				break
+			}
+			if !rd.canRead(row.Namespace, row.ModuleName, row.Visibility) {
+				continue
+			}
+			results = append(results, apitypes.SearchResult{
+				Module:  row.Namespace + "/" + row.ModuleName,
+				Version: row.Version,
+				Kind:    row.Kind,
+				Name:    row.Name,
+				File:    row.File,
+				Snippet: row.Snippet,
+				Rank:    row.Rank,
+			})
+		}
+		if len(rows) < searchBatchSize {
+			break // No more matches
+		}
+	}
+	response.JSON(w, http.StatusOK, apitypes.SearchResponse{Query: query.text, Results: results})
+}
+
+// searchRow is a matching document with its module.
+type searchRow struct {
+	Namespace  string
+	ModuleName string
+	Visibility string
+	Version    string
+	Kind       string
+	Name       string
+	File       string
+	Snippet    string
+	Rank       float64
+	MatchInfo  []byte // SQLite only, see sqliteRank
+}
+
+// searchFilters returns the SQL conditions (and arguments) of the kind and module filters.
+func searchFilters(query searchQuery) (string, []any) {
+	var conditions string
+	var args []any
+	if query.kind != "" {
+		conditions += " AND d.kind = ?"
+		args = append(args, query.kind)
+	}
+	if query.namespace != "" {
+		conditions += " AND m.namespace = ? AND m.name = ?"
+		args = append(args, query.namespace, query.name)
+	}
+	return conditions, args
+}
+
+// searchDocuments returns matching documents, best first, skipping offset of them.
+func searchDocuments(gormDB *gorm.DB, query searchQuery, offset, limit int) ([]searchRow, error) {
+	if gormDB.Dialector.Name() == "sqlite" {
+		return searchDocumentsSQLite(gormDB, query, offset, limit)
+	}
+	return searchDocumentsPostgres(gormDB, query, offset, limit)
+}
+
+// searchDocumentsPostgres matches the query (web search syntax) against the tsvector column, ranked with
+// ts_rank (terms are weighted A, the body B).
+func searchDocumentsPostgres(gormDB *gorm.DB, query searchQuery, offset, limit int) ([]searchRow, error) {
+	filters, filterArgs := searchFilters(query)
+	sql := `
+		SELECT m.namespace, m.name AS module_name, m.visibility, mv.version, d.kind, d.name, d.file,
+			ts_headline('english', d.body, q, 'StartSel=` + apitypes.SearchMatchStart + `, StopSel=` + apitypes.SearchMatchEnd + `, MaxWords=24, MinWords=8, MaxFragments=2, FragmentDelimiter=" … "') AS snippet,
+			ts_rank(d.tsv, q) AS rank
+		FROM search_documents d
+		CROSS JOIN websearch_to_tsquery('english', ?) q
+		JOIN modules m ON m.id = d.module_id
+		JOIN module_versions mv ON mv.id = d.module_version_id
+		WHERE d.tsv @@ q` + filters + `
+		ORDER BY rank DESC, d.id
+		LIMIT ? OFFSET ?`
+	args := append([]any{query.text}, filterArgs...)
+	args = append(args, limit, offset)
+	var rows []searchRow
+	if err := gormDB.Raw(sql, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// searchDocumentsSQLite matches the query against the FTS4 table. FTS4 has no ranking function, so all
+// matches are ranked here (see sqliteRank) before the requested range is returned.
+func searchDocumentsSQLite(gormDB *gorm.DB, query searchQuery, offset, limit int) ([]searchRow, error) {
+	// Every word and phrase is quoted, so FTS query operators in the input are matched as plain words
+	quoted := make([]string, len(query.terms))
+	for i, term := range query.terms {
+		quoted[i] = `"` + term + `"`
+	}
+	filters, filterArgs := searchFilters(query)
+	fts := db.SearchFTSTable
+	sql := `
+		SELECT m.namespace, m.name AS module_name, m.visibility, mv.version, d.kind, d.name, d.file,
+			snippet(` + fts + `, '` + apitypes.SearchMatchStart + `', '` + apitypes.SearchMatchEnd + `', ' … ', 1, 24) AS snippet,
+			matchinfo(` + fts + `, 'pcx') AS match_info
+		FROM ` + fts + ` f
+		JOIN search_documents d ON d.id = f.docid
+		JOIN modules m ON m.id = d.module_id
+		JOIN module_versions mv ON mv.id = d.module_version_id
+		WHERE ` + fts + ` MATCH ?` + filters + `
+		ORDER BY d.id`
+	args := append([]any{strings.Join(quoted, " ")}, filterArgs...)
+	var rows []searchRow
+	if err := gormDB.Raw(sql, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].Rank = sqliteRank(rows[i].MatchInfo)
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Rank > rows[j].Rank })
+	if offset >= len(rows) {
+		return nil, nil
+	}
+	return rows[offset:min(offset+limit, len(rows))], nil
+}
+
+// Column weights of the SQLite ranking, matching ts_rank's defaults for weights A (terms) and B (body).
+var sqliteColumnWeights = []float64{1.0, 0.4}
+
+// sqliteRank scores a match from FTS4's matchinfo(..., 'pcx'): for each phrase and column, the hits in
+// the row relative to the hits in all rows (rarer words count more), weighted by column. The blob is an
+// array of native-endian uint32: phrase count, column count, then (hits in row, hits in all rows, rows
+// with hits) per phrase and column.
+func sqliteRank(matchInfo []byte) float64 {
+	if len(matchInfo) < 8 {
+		return 0
+	}
+	value := func(i int) float64 { return float64(binary.NativeEndian.Uint32(matchInfo[4*i:])) }
+	phrases, columns := int(value(0)), int(value(1))
+	if len(matchInfo) < 4*(2+3*phrases*columns) {
+		return 0
+	}
+	var rank float64
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < columns && c < len(sqliteColumnWeights); c++ {
+			base := 2 + 3*(p*columns+c)
+			if hits, allHits := value(base), value(base+1); hits > 0 && allHits > 0 {
+				rank += sqliteColumnWeights[c] * hits / allHits
+			}
+		}
+	}
+	return rank
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	gormDB := newTestDB(t)
+	newTestStorage(t)
+
+	router := mux.NewRouter()
+	router.Use(ReadAuthMiddleware("admin-token"))
+	router.HandleFunc("/api/v1/search", SearchHandler).Methods("GET")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	search := func(query, token string) (int, apitypes.SearchResponse) {
+		req := httptest.NewRequest("GET", "/api/v1/search?"+query, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var resp apitypes.SearchResponse
+		if rr.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		}
+		return rr.Code, resp
+	}
+	names := func(resp apitypes.SearchResponse) []string {
+		var names []string
+		for _, result := range resp.Results {
+			names = append(names, result.Kind+" "+result.Name)
+		}
+		return names
+	}
+	publish := func(namespace, name, version string, files map[string]string) {
+		packed := map[string][]byte{}
+		for file, content := range files {
+			packed[file] = []byte(content)
+		}
+		data, err := artifact.Pack(packed)
+		assert.NoError(t, err)
+		req := newPublishRequest(t, namespace, name, version, "", data)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	publish("acme", "orders", "v1.0.0", map[string]string{
+		"sproto.yaml":                 "name: acme/orders\ndescription: Order placement for the web store\n",
+		"acme/orders/v1/orders.proto": "syntax = \"proto3\";\npackage acme.orders.v1;\n// An order placed by a shopper.\nmessage Order {}\n",
+	})
+	publish("acme", "orders", "v1.1.0", map[string]string{
+		"sproto.yaml": "name: acme/orders\ndescription: Order placement for the web store\n",
+		"acme/orders/v1/orders.proto": `syntax = "proto3";
+package acme.orders.v1;
+// An order placed by a shopper.
+message Order {
+  // Invoice the order was billed with.
+  string invoice_id = 1;
+}
+// Places orders.
+service OrderService {
+  // Refunds a paid order to the shopper.
+  rpc RefundOrder(Order) returns (Order);
+}
+`,
+	})
+	publish("acme", "billing", "v1.0.0", map[string]string{
+		"acme/billing/v1/billing.proto": "syntax = \"proto3\";\npackage acme.billing.v1;\n// A bill sent to a shopper for their orders.\nmessage Invoice {}\n",
+	})
+	assert.NoError(t, gormDB.Model(&models.Module{}).Where("name = ?", "billing").Update("visibility", models.VisibilityPrivate).Error)
+
+	// Names (split into words) and comments of the newest version are matched, best matches first
+	code, resp := search("q=invoice", "admin-token")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "invoice", resp.Query)
+	assert.Equal(t, []string{"field acme.orders.v1.Order.invoice_id", "message acme.billing.v1.Invoice"}, names(resp))
+	assert.Equal(t, "acme/orders", resp.Results[0].Module)
+	assert.Equal(t, "v1.1.0", resp.Results[0].Version)
+	assert.Equal(t, "acme/orders/v1/orders.proto", resp.Results[0].File)
+	assert.Equal(t, "<mark>Invoice</mark> the order was billed with.", resp.Results[0].Snippet)
+	assert.Greater(t, resp.Results[0].Rank, resp.Results[1].Rank) // Matched in the name and the comment
+
+	// Stemming, module descriptions, camel-case words, phrases
+	_, resp = search("q=refunding", "")
+	assert.Equal(t, []string{"method acme.orders.v1.OrderService.RefundOrder"}, names(resp))
+	_, resp = search("q=web+store&kind=module", "")
+	assert.Equal(t, []string{"module acme/orders"}, names(resp))
+	assert.Equal(t, "Order placement for the <mark>web</mark> <mark>store</mark>", resp.Results[0].Snippet)
+	_, resp = search(`q="paid+order"`, "")
+	assert.Equal(t, []string{"method acme.orders.v1.OrderService.RefundOrder"}, names(resp))
+	_, resp = search(`q="order+paid"`, "")
+	assert.Empty(t, resp.Results)
+	assert.NotNil(t, resp.Results)
+
+	// Private modules are left out for callers that can't read them; filters and limits
+	_, resp = search("q=shopper", "")
+	assert.NotContains(t, names(resp), "message acme.billing.v1.Invoice")
+	assert.Len(t, resp.Results, 2)
+	_, resp = search("q=shopper&module=acme/billing", "admin-token")
+	assert.Equal(t, []string{"message acme.billing.v1.Invoice"}, names(resp))
+	_, resp = search("q=shopper&limit=1", "admin-token")
+	assert.Len(t, resp.Results, 1)
+
+	for _, query := range []string{"", "q=", "q=%22%22+-", "q=order&kind=oneof", "q=order&module=acme", "q=order&limit=0", "q=order&limit=101", "q=" + strings.Repeat("a", 257)} {
+		code, _ := search(query, "")
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+
+	// The reindex-search job rebuilds the index from the stored artifacts
+	assert.NoError(t, gormDB.Where("1 = 1").Delete(&models.SearchDocument{}).Error)
+	_, resp = search("q=invoice", "admin-token")
+	assert.Empty(t, resp.Results)
+	_, message := prepareReindexSearchJob(map[string]string{"module": "nope"})
+	assert.Contains(t, message, "module")
+	run, message := prepareReindexSearchJob(nil)
+	assert.Empty(t, message)
+	rr := httptest.NewRecorder()
+	run(context.Background(), rr)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"indexed": 2, "failed": []}`, rr.Body.String())
+	_, resp = search("q=invoice", "admin-token")
+	assert.Len(t, resp.Results, 2)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// Storage self-test: /readyz only checks that storage answers. The self-test goes through what publishing
+// and fetching do, with a probe object, so a missing permission (e.g. s3:DeleteObject) or a slow step can
+// be diagnosed on the running server, with its credentials and network path.
+
+// Probe object sizes of the storage self-test (?size=).
+const (
+	defaultSelfTestSize = 1024
+	maxSelfTestSize     = 16 << 20
+)
+
+// selfTestTimeout bounds a whole self-test; steps still running then fail with a deadline error.
+const selfTestTimeout = 30 * time.Second
+
+// StorageSelfTestHandler writes, stats, reads back and deletes a probe object through the storage
+// provider, and reports each step's outcome and duration.
+// POST /api/v1/admin/storage/selftest (admin token only)
+// ?size= sets the probe's size in bytes (default 1 KiB, at most 16 MiB), e.g. to measure throughput.
+// Responds 200 if every step succeeded and 503 otherwise, with the same body.
+func StorageSelfTestHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	size := int64(defaultSelfTestSize)
+	if value := r.URL.Query().Get("size"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 1 || parsed > maxSelfTestSize {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid size %q: expected 1 to %d bytes", value, maxSelfTestSize))
+			return
+		}
+		size = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), selfTestTimeout)
+	defer cancel()
+	provider := storage.GetStorageProvider()
+	result := storage.SelfTest(ctx, provider, size)
+
+	resp := apitypes.StorageSelfTestResponse{Provider: storage.ProviderName(provider), OK: result.OK(), Key: result.Key, SizeBytes: result.Size}
+	var total time.Duration
+	for _, step := range result.Steps {
+		s := apitypes.StorageSelfTestStep{Step: step.Name, OK: !step.Skipped && step.Err == nil, Skipped: step.Skipped, DurationMs: durationMillis(step.Duration)}
+		if step.Err != nil {
+			s.Error = step.Err.Error()
+			log.Warn("Storage self-test step failed", zap.String("step", step.Name), zap.String("key", result.Key), zap.Error(step.Err))
+		}
+		resp.Steps = append(resp.Steps, s)
+		total += step.Duration
+	}
+	resp.TotalDurationMs = durationMillis(total)
+	log.Info("Ran storage self-test", zap.Bool("ok", resp.OK), zap.Int64("size", size), zap.Float64("duration_ms", resp.TotalDurationMs))
+
+	status := http.StatusOK
+	if !resp.OK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, status, resp)
+}
+
+// durationMillis returns d in milliseconds, to the microsecond.
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
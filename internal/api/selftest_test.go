@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageSelfTestHandler(t *testing.T) {
+	provider := newTestStorage(t)
+
+	selftest := func(query string) (int, apitypes.StorageSelfTestResponse) {
+		rr := httptest.NewRecorder()
+		StorageSelfTestHandler(rr, httptest.NewRequest("POST", "/api/v1/admin/storage/selftest"+query, nil))
+		var resp apitypes.StorageSelfTestResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return rr.Code, resp
+	}
+
+	code, resp := selftest("?size=65536")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, resp.OK)
+	assert.Equal(t, int64(65536), resp.SizeBytes)
+	if assert.Len(t, resp.Steps, 4) {
+		assert.Equal(t, "write", resp.Steps[0].Step)
+		assert.Equal(t, "delete", resp.Steps[3].Step)
+		for _, step := range resp.Steps {
+			assert.True(t, step.OK, step.Step)
+		}
+	}
+	exists, err := provider.FileExists(context.Background(), resp.Key)
+	assert.NoError(t, err)
+	assert.False(t, exists, "the probe is deleted")
+
+	for _, query := range []string{"?size=0", "?size=big", fmt.Sprintf("?size=%d", maxSelfTestSize+1)} {
+		rr := httptest.NewRecorder()
+		StorageSelfTestHandler(rr, httptest.NewRequest("POST", "/api/v1/admin/storage/selftest"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
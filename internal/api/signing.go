@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/provenance"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// Version signatures: with a version signing key configured, each version's coordinates and digest are
+// signed once, at publish (see package provenance), and the signature is stored with the version and served
+// in its metadata and fetch responses. The public keys are published at SigningKeysPath, so a mirror
+// copying artifacts can keep the signatures and prove where they came from.
+
+// Headers carrying a version's signature on artifact and metadata responses.
+const (
+	versionSignatureHeader      = "X-Version-Signature"        // Base64 Ed25519 signature of provenance.Payload
+	versionSignatureKeyIDHeader = "X-Version-Signature-Key-Id" // provenance.KeyID of the signing key
+)
+
+// Global version signing settings, configured at startup via SetVersionSigningKeys.
+var (
+	versionSigningKey   ed25519.PrivateKey  // nil leaves new versions unsigned
+	retiredSigningKeys  []ed25519.PublicKey // Former keys, published so their signatures stay verifiable
+	versionSigningKeyID string              // provenance.KeyID of versionSigningKey
+)
+
+// SetVersionSigningKeys configures the key signing new versions (nil disables signing) and the retired
+// keys published next to it.
+func SetVersionSigningKeys(key ed25519.PrivateKey, retired []ed25519.PublicKey) {
+	versionSigningKey = key
+	retiredSigningKeys = retired
+	versionSigningKeyID = ""
+	if key != nil {
+		versionSigningKeyID = provenance.KeyID(key.Public().(ed25519.PublicKey))
+	}
+}
+
+// signVersion signs a version about to be created, if a signing key is configured.
+func signVersion(moduleVersion *models.ModuleVersion, namespace, moduleName string) {
+	if versionSigningKey == nil {
+		return
+	}
+	moduleVersion.Signature = provenance.Sign(versionSigningKey, namespace+"/"+moduleName, moduleVersion.Version, "sha256:"+moduleVersion.ArtifactDigest)
+	moduleVersion.SigningKeyID = versionSigningKeyID
+}
+
+// versionSignature returns a version's signature for API responses, or nil if it's unsigned.
+func versionSignature(moduleVersion *models.ModuleVersion, namespace, moduleName string) *apitypes.VersionSignature {
+	if moduleVersion.Signature == "" {
+		return nil
+	}
+	return &apitypes.VersionSignature{
+		Algorithm: provenance.Algorithm,
+		KeyID:     moduleVersion.SigningKeyID,
+		Payload:   string(provenance.Payload(namespace+"/"+moduleName, moduleVersion.Version, "sha256:"+moduleVersion.ArtifactDigest)),
+		Signature: moduleVersion.Signature,
+	}
+}
+
+// setSignatureHeaders adds a version's signature, if any, to an artifact or metadata response.
+func setSignatureHeaders(w http.ResponseWriter, moduleVersion *models.ModuleVersion) {
+	if moduleVersion.Signature == "" {
+		return
+	}
+	w.Header().Set(versionSignatureHeader, moduleVersion.Signature)
+	w.Header().Set(versionSignatureKeyIDHeader, moduleVersion.SigningKeyID)
+}
+
+// SigningKeysHandler lists the public keys version signatures can be verified with: the current signing
+// key and the retired ones (VERSION_SIGNING_RETIRED_KEYS).
+// GET /.well-known/sproto/signing-keys
+// Public, even without PUBLIC_READ: the keys reveal nothing about modules.
+func SigningKeysHandler(w http.ResponseWriter, r *http.Request) {
+	resp := apitypes.SigningKeysResponse{Keys: []apitypes.SigningKey{}}
+	if versionSigningKey != nil {
+		pub := versionSigningKey.Public().(ed25519.PublicKey)
+		resp.Keys = append(resp.Keys, apitypes.SigningKey{KeyID: versionSigningKeyID, Algorithm: provenance.Algorithm, PublicKey: base64.StdEncoding.EncodeToString(pub), Current: true})
+	}
+	for _, pub := range retiredSigningKeys {
+		resp.Keys = append(resp.Keys, apitypes.SigningKey{KeyID: provenance.KeyID(pub), Algorithm: provenance.Algorithm, PublicKey: base64.StdEncoding.EncodeToString(pub)})
+	}
+	setListCacheHeaders(w)
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// --- sign-versions ---
+
+// prepareSignVersionsJob returns the sign-versions job: it signs the versions of every module (or the one
+// named by the "module" parameter) that have no signature, e.g. published before signing was configured.
+func prepareSignVersionsJob(params map[string]string) (operationFunc, string) {
+	if versionSigningKey == nil {
+		return nil, "Version signing is not configured (VERSION_SIGNING_KEY)"
+	}
+	return prepareReindexJob(params, "signature", false, signModuleVersions)
+}
+
+// signModuleVersions signs the versions of a module that have no signature.
+func signModuleVersions(ctx context.Context, module models.Module) error {
+	gormDB := db.GetDB().WithContext(ctx)
+	var versions []models.ModuleVersion
+	if err := gormDB.Where("module_id = ? AND (signature IS NULL OR signature = '')", module.ID).Order("created_at").Find(&versions).Error; err != nil {
+		return err
+	}
+	for i := range versions {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		signVersion(&versions[i], module.Namespace, module.Name)
+		err := gormDB.Model(&models.ModuleVersion{}).Where("id = ?", versions[i].ID).
+			Updates(map[string]interface{}{"signature": versions[i].Signature, "signing_key_id": versions[i].SigningKeyID}).Error
+		if err != nil {
+			return fmt.Errorf("failed to sign %s: %w", versions[i].Version, err)
+		}
+	}
+	if len(versions) > 0 {
+		logging.FromContext(ctx).Info("Signed module versions", zap.String("module", module.Namespace+"/"+module.Name), zap.Int("count", len(versions)), zap.String("key_id", versionSigningKeyID))
+	}
+	return nil
+}
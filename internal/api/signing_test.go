@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/provenance"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionSignatures(t *testing.T) {
+	gormDB := newTestDB(t)
+	newTestStorage(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", GetModuleVersionHandler).Methods("GET")
+	router.HandleFunc(apitypes.SigningKeysPath, SigningKeysHandler).Methods("GET")
+	data, err := artifact.Pack(map[string][]byte{"user.proto": []byte(`syntax = "proto3"; package user.v1;`)})
+	assert.NoError(t, err)
+	publish := func(version, query string) apitypes.PublishModuleVersionResponse {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newPublishRequest(t, "acme", "user", version, query, data))
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var resp apitypes.PublishModuleVersionResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr
+	}
+
+	// Without a signing key, versions are unsigned and no keys are published
+	SetVersionSigningKeys(nil, nil)
+	assert.Nil(t, publish("v0.9.0", "").Signature)
+	rr := get(apitypes.SigningKeysPath)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"keys":[]}`, rr.Body.String())
+
+	retiredPub, _, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	pub, key, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	SetVersionSigningKeys(key, []ed25519.PublicKey{retiredPub})
+	t.Cleanup(func() { SetVersionSigningKeys(nil, nil) })
+
+	// Publishes and republishes are signed with the current key
+	resp := publish("v1.0.0", "")
+	if assert.NotNil(t, resp.Signature) {
+		assert.Equal(t, provenance.KeyID(pub), resp.Signature.KeyID)
+		assert.Equal(t, "sproto version signature v1\nacme/user v1.0.0 "+resp.ArtifactDigest+"\n", resp.Signature.Payload)
+		assert.NoError(t, provenance.Verify(pub, "acme/user", "v1.0.0", resp.ArtifactDigest, resp.Signature.Signature))
+	}
+	republished := publish("v1.0.1", "?from=v1.0.0")
+	if assert.NotNil(t, republished.Signature) {
+		assert.NoError(t, provenance.Verify(pub, "acme/user", "v1.0.1", republished.ArtifactDigest, republished.Signature.Signature))
+	}
+
+	// The metadata carries the stored signature, in the body and in headers
+	rr = get("/api/v1/modules/acme/user/v1.0.0")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var meta apitypes.ModuleVersionResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &meta))
+	assert.Equal(t, resp.Signature, meta.Signature)
+	assert.Equal(t, resp.Signature.Signature, rr.Header().Get(versionSignatureHeader))
+	assert.Equal(t, provenance.KeyID(pub), rr.Header().Get(versionSignatureKeyIDHeader))
+	assert.Empty(t, get("/api/v1/modules/acme/user/v0.9.0").Header().Get(versionSignatureHeader))
+
+	// The current key comes first, then the retired ones
+	rr = get(apitypes.SigningKeysPath)
+	var keys apitypes.SigningKeysResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &keys))
+	if assert.Len(t, keys.Keys, 2) {
+		assert.Equal(t, apitypes.SigningKey{KeyID: provenance.KeyID(pub), Algorithm: "ed25519", PublicKey: base64.StdEncoding.EncodeToString(pub), Current: true}, keys.Keys[0])
+		assert.Equal(t, provenance.KeyID(retiredPub), keys.Keys[1].KeyID)
+		assert.False(t, keys.Keys[1].Current)
+	}
+
+	// The sign-versions job signs what was published unsigned and changes its metadata ETag
+	etag := get("/api/v1/modules/acme/user/v0.9.0").Header().Get("ETag")
+	var module models.Module
+	assert.NoError(t, gormDB.Where("namespace = ? AND name = ?", "acme", "user").First(&module).Error)
+	assert.NoError(t, signModuleVersions(context.Background(), module))
+	rr = get("/api/v1/modules/acme/user/v0.9.0")
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &meta))
+	if assert.NotNil(t, meta.Signature) {
+		assert.NoError(t, provenance.Verify(pub, "acme/user", "v0.9.0", meta.ArtifactDigest, meta.Signature.Signature))
+	}
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+	var unchanged models.ModuleVersion
+	assert.NoError(t, gormDB.Where("version = ?", "v1.0.0").First(&unchanged).Error)
+	assert.Equal(t, resp.Signature.Signature, unchanged.Signature)
+
+	// The job can't start without a signing key
+	SetVersionSigningKeys(nil, nil)
+	_, message := prepareSignVersionsJob(nil)
+	assert.Contains(t, message, "VERSION_SIGNING_KEY")
+}
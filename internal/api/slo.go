@@ -0,0 +1,362 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Fetch SLOs: downloads of a module's artifacts are timed and counted per module and hour, so platform teams
+// can report the availability and latency of schema distribution per module. Prometheus gets the same
+// measurements without the module (sproto_artifact_fetch_duration_seconds), keeping its cardinality bounded.
+
+// Kinds of downloads measured for fetch SLOs.
+const (
+	FetchKindArtifact          = "artifact"
+	FetchKindBundle            = "bundle"
+	FetchKindSecondaryArtifact = "secondary_artifact"
+)
+
+// SLORetention is how long hourly fetch statistics are kept, and the longest window of the SLO report.
+const SLORetention = 30 * 24 * time.Hour
+
+// defaultSLOWindows are the windows reported without ?windows=.
+var defaultSLOWindows = []string{"1h", "24h", "7d", "30d"}
+
+// sloLatencyBounds are the upper bounds of the latency buckets of models.ModuleFetchStat, in order.
+var sloLatencyBounds = []time.Duration{
+	50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// fetchStatBuckets returns the latency bucket counters of a row, matching sloLatencyBounds plus the overflow.
+func fetchStatBuckets(s *models.ModuleFetchStat) []*int64 {
+	return []*int64{&s.Le50ms, &s.Le100ms, &s.Le250ms, &s.Le500ms, &s.Le1s, &s.Le2500ms, &s.Le5s, &s.Le10s, &s.LeInf}
+}
+
+// measureFetch times and counts the downloads served by next for the fetch SLOs of the module in the route.
+// HEAD requests and client errors aren't counted (a 404 says nothing about availability), except 429: a
+// download rejected because the registry is saturated counts as an error.
+func measureFetch(kind string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			status := recorder.status
+			if !completed {
+				status = http.StatusInternalServerError // Panicked; RecoveryMiddleware responds 500
+			} else if status == 0 {
+				status = http.StatusOK // Handler wrote nothing
+			}
+			observeFetch(r, kind, status, time.Since(start))
+		}()
+		next(recorder, r)
+		completed = true
+	}
+}
+
+// observeFetch records a download in the Prometheus histogram and, for GET requests, the module's fetch stats.
+func observeFetch(r *http.Request, kind string, status int, elapsed time.Duration) {
+	outcome := "ok"
+	switch {
+	case status >= 500:
+		outcome = "server_error"
+	case status >= 400:
+		outcome = "client_error"
+	}
+	observer := metrics.ArtifactFetchDuration.WithLabelValues(kind, outcome)
+	requestID := RequestIDFromContext(r.Context())
+	if eo, ok := observer.(prometheus.ExemplarObserver); ok && requestID != "" {
+		eo.ObserveWithExemplar(elapsed.Seconds(), prometheus.Labels{"request_id": requestID})
+	} else {
+		observer.Observe(elapsed.Seconds())
+	}
+
+	if r.Method != http.MethodGet || (status >= 400 && status < 500 && status != http.StatusTooManyRequests) {
+		return
+	}
+	vars := mux.Vars(r)
+	module := vars["namespace"] + "/" + vars["module_name"]
+	serverError := status >= 500 || status == http.StatusTooManyRequests
+	fetchStats.record(module, kind, time.Now().UTC(), elapsed, serverError)
+}
+
+// --- Fetch Statistics Tracking ---
+
+// fetchStatKey identifies the downloads of a module of one kind in an hour.
+type fetchStatKey struct {
+	module      string
+	kind        string
+	bucketStart time.Time
+}
+
+// fetchStatTracker counts downloads in memory; RunSLOFlusher adds them to the database, so downloads don't
+// each cost a write.
+type fetchStatTracker struct {
+	mu      sync.Mutex
+	pending map[fetchStatKey]*models.ModuleFetchStat // Downloads since the last flush
+}
+
+var fetchStats = newFetchStatTracker()
+
+func newFetchStatTracker() *fetchStatTracker {
+	return &fetchStatTracker{pending: map[fetchStatKey]*models.ModuleFetchStat{}}
+}
+
+func (t *fetchStatTracker) record(module, kind string, at time.Time, elapsed time.Duration, serverError bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := fetchStatKey{module: module, kind: kind, bucketStart: at.Truncate(time.Hour)}
+	row, ok := t.pending[key]
+	if !ok {
+		row = &models.ModuleFetchStat{Module: module, Kind: kind, BucketStart: key.bucketStart}
+		t.pending[key] = row
+	}
+	row.Requests++
+	if serverError {
+		row.Errors++
+	}
+	row.LatencySum += elapsed.Milliseconds()
+	buckets := fetchStatBuckets(row)
+	bucket := len(sloLatencyBounds) // Overflow
+	for i, bound := range sloLatencyBounds {
+		if elapsed <= bound {
+			bucket = i
+			break
+		}
+	}
+	*buckets[bucket]++
+}
+
+// mergeFetchStat adds the counts of other to row.
+func mergeFetchStat(row, other *models.ModuleFetchStat) {
+	row.Requests += other.Requests
+	row.Errors += other.Errors
+	row.LatencySum += other.LatencySum
+	buckets, otherBuckets := fetchStatBuckets(row), fetchStatBuckets(other)
+	for i := range buckets {
+		*buckets[i] += *otherBuckets[i]
+	}
+}
+
+// flush adds the downloads recorded since the last flush to the database.
+func (t *fetchStatTracker) flush(ctx context.Context) error {
+	t.mu.Lock()
+	rows := make([]models.ModuleFetchStat, 0, len(t.pending))
+	for _, row := range t.pending {
+		rows = append(rows, *row)
+	}
+	t.pending = map[fetchStatKey]*models.ModuleFetchStat{}
+	t.mu.Unlock()
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Existing hours add the new counts
+	updates := map[string]interface{}{}
+	for _, column := range []string{"requests", "errors", "latency_sum_ms", "le_50ms", "le_100ms", "le_250ms", "le_500ms", "le_1s", "le_2500ms", "le_5s", "le_10s", "le_inf"} {
+		updates[column] = gorm.Expr(fmt.Sprintf("module_fetch_stats.%s + excluded.%s", column, column))
+	}
+	err := db.GetDB().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "module"}, {Name: "kind"}, {Name: "bucket_start"}},
+		DoUpdates: clause.Assignments(updates),
+	}).Create(&rows).Error
+	if err != nil {
+		// Retry with the next flush, merged with the downloads recorded meanwhile
+		t.mu.Lock()
+		for _, row := range rows {
+			key := fetchStatKey{module: row.Module, kind: row.Kind, bucketStart: row.BucketStart}
+			r := row
+			if newer, ok := t.pending[key]; ok {
+				mergeFetchStat(&r, newer)
+			}
+			t.pending[key] = &r
+		}
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// pruneFetchStats deletes the hours older than SLORetention.
+func pruneFetchStats(ctx context.Context, now time.Time) error {
+	return db.GetDB().WithContext(ctx).Where("bucket_start < ?", now.Add(-SLORetention).Truncate(time.Hour)).Delete(&models.ModuleFetchStat{}).Error
+}
+
+// RunSLOFlusher adds recorded downloads to the database every interval, and deletes statistics older than
+// SLORetention, until ctx is canceled. Downloads recorded after the last flush are lost if the server stops.
+func RunSLOFlusher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fetchStats.flush(ctx); err != nil {
+				logging.L().Warn("Failed to persist module fetch statistics", zap.Error(err))
+			}
+			if err := pruneFetchStats(ctx, time.Now().UTC()); err != nil {
+				logging.L().Warn("Failed to delete expired module fetch statistics", zap.Error(err))
+			}
+		}
+	}
+}
+
+// --- SLO Report ---
+
+// parseSLOWindow parses a window of whole hours or days ("6h", "7d"), at most SLORetention.
+func parseSLOWindow(value string) (time.Duration, error) {
+	unit := time.Hour
+	number, ok := strings.CutSuffix(value, "h")
+	if !ok {
+		if number, ok = strings.CutSuffix(value, "d"); !ok {
+			return 0, fmt.Errorf("invalid window %q: expected hours (24h) or days (7d)", value)
+		}
+		unit = 24 * time.Hour
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid window %q: expected hours (24h) or days (7d)", value)
+	}
+	window := time.Duration(n) * unit
+	if window > SLORetention {
+		return 0, fmt.Errorf("invalid window %q: statistics are kept for %d days", value, int(SLORetention/(24*time.Hour)))
+	}
+	return window, nil
+}
+
+// GetModuleSLOHandler reports the availability and latency of a module's downloads over time windows.
+// GET /api/v1/modules/{namespace}/{module_name}/slo
+// ?windows=1h,24h,7d,30d (the default) selects the windows, ?kind=artifact|bundle|secondary_artifact only
+// counts one kind of download. Downloads show up within a minute.
+func GetModuleSLOHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	gormDB := db.GetReadDB()
+
+	windowNames := defaultSLOWindows
+	if value := r.URL.Query().Get("windows"); value != "" {
+		windowNames = strings.Split(value, ",")
+	}
+	windows := make([]time.Duration, len(windowNames))
+	for i, name := range windowNames {
+		window, err := parseSLOWindow(strings.TrimSpace(name))
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		windowNames[i], windows[i] = strings.TrimSpace(name), window
+	}
+	kind := r.URL.Query().Get("kind")
+	switch kind {
+	case "", FetchKindArtifact, FetchKindBundle, FetchKindSecondaryArtifact:
+	default:
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid kind %q: expected %s, %s or %s", kind, FetchKindArtifact, FetchKindBundle, FetchKindSecondaryArtifact))
+		return
+	}
+
+	if _, ok := findReadableModule(w, r, namespace, moduleName); !ok {
+		return // Response already written
+	}
+
+	now := time.Now().UTC()
+	oldest := now
+	for _, window := range windows {
+		if since := sloWindowStart(now, window); since.Before(oldest) {
+			oldest = since
+		}
+	}
+	query := gormDB.Where("module = ? AND bucket_start >= ?", namespace+"/"+moduleName, oldest)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	var rows []models.ModuleFetchStat
+	if err := query.Find(&rows).Error; err != nil {
+		log.Error("Error loading module fetch statistics", zap.String("namespace", namespace), zap.String("module", moduleName), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module fetch statistics")
+		return
+	}
+
+	respData := apitypes.ModuleSLOResponse{Namespace: namespace, ModuleName: moduleName, Kind: kind, Windows: make([]apitypes.SLOWindow, len(windows))}
+	for i, window := range windows {
+		respData.Windows[i] = summarizeSLOWindow(windowNames[i], sloWindowStart(now, window), rows)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, http.StatusOK, respData)
+}
+
+// sloWindowStart returns the start of the oldest hour of a window ending now: windows count whole hours,
+// the current one included, so a window covers at least its duration.
+func sloWindowStart(now time.Time, window time.Duration) time.Time {
+	return now.Add(-window).Truncate(time.Hour)
+}
+
+// summarizeSLOWindow adds up the hours of rows starting at or after since.
+func summarizeSLOWindow(name string, since time.Time, rows []models.ModuleFetchStat) apitypes.SLOWindow {
+	var total models.ModuleFetchStat
+	for i := range rows {
+		if !rows[i].BucketStart.Before(since) {
+			mergeFetchStat(&total, &rows[i])
+		}
+	}
+	summary := apitypes.SLOWindow{Window: name, Since: since, Requests: total.Requests, Errors: total.Errors}
+	if total.Requests == 0 {
+		return summary
+	}
+	availability := float64(total.Requests-total.Errors) / float64(total.Requests)
+	summary.Availability = &availability
+	buckets := fetchStatBuckets(&total)
+	counts := make([]int64, len(buckets))
+	for i, b := range buckets {
+		counts[i] = *b
+	}
+	summary.LatencyMs = &apitypes.SLOLatency{
+		P50:  latencyQuantile(0.50, counts),
+		P95:  latencyQuantile(0.95, counts),
+		P99:  latencyQuantile(0.99, counts),
+		Mean: math.Round(float64(total.LatencySum)/float64(total.Requests)*10) / 10,
+	}
+	return summary
+}
+
+// latencyQuantile estimates a quantile from the latency buckets' counts, in milliseconds, by linear
+// interpolation within the bucket like Prometheus' histogram_quantile. Quantiles in the overflow bucket
+// are reported as the highest bound.
+func latencyQuantile(q float64, counts []int64) float64 {
+	var total int64
+	for _, c := range counts {
+		total += c
+	}
+	rank := q * float64(total)
+	var cumulative int64
+	lower := 0.0
+	for i, bound := range sloLatencyBounds {
+		upper := float64(bound.Milliseconds())
+		if counts[i] > 0 && float64(cumulative+counts[i]) >= rank {
+			return math.Round(lower + (upper-lower)*(rank-float64(cumulative))/float64(counts[i]))
+		}
+		cumulative += counts[i]
+		lower = upper
+	}
+	return lower
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModuleSLOHandler(t *testing.T) {
+	gormDB := newTestDB(t)
+	fetchStats = newFetchStatTracker()
+	SetReadTokens(map[string]ReadToken{"ci-token": {}})
+	t.Cleanup(func() { SetReadTokens(nil) })
+
+	module := models.Module{Namespace: "acme", Name: "billing", Visibility: models.VisibilityInternal}
+	assert.NoError(t, gormDB.Create(&module).Error)
+
+	// Downloads answer with the status in ?status=; client errors other than 429 and HEAD requests aren't counted
+	router := mux.NewRouter()
+	router.Use(ReadAuthMiddleware("admin-token"))
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/artifact", measureFetch(FetchKindArtifact, func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(status)
+	})).Methods("GET", "HEAD")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/slo", GetModuleSLOHandler).Methods("GET")
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/modules/acme/billing/"+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	do("GET", "v1.0.0/artifact?status=200", "")
+	do("GET", "v1.0.0/artifact?status=500", "")
+	do("GET", "v1.0.0/artifact?status=404", "")
+	do("HEAD", "v1.0.0/artifact?status=200", "")
+	assert.NoError(t, fetchStats.flush(context.Background()))
+	do("GET", "v1.0.0/artifact?status=304", "") // Added to the existing row
+	do("GET", "v1.0.0/artifact?status=429", "")
+	assert.NoError(t, fetchStats.flush(context.Background()))
+
+	// An older hour, within 7d but not 1h
+	old := models.ModuleFetchStat{Module: "acme/billing", Kind: FetchKindBundle, BucketStart: time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Hour), Requests: 6, LatencySum: 6000, Le1s: 6}
+	assert.NoError(t, gormDB.Create(&old).Error)
+
+	report := func(query string) apitypes.ModuleSLOResponse {
+		rr := do("GET", "slo"+query, "ci-token")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+		var resp apitypes.ModuleSLOResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+	resp := report("")
+	assert.Equal(t, []string{"1h", "24h", "7d", "30d"}, []string{resp.Windows[0].Window, resp.Windows[1].Window, resp.Windows[2].Window, resp.Windows[3].Window})
+	hour := resp.Windows[0]
+	assert.Equal(t, int64(4), hour.Requests)
+	assert.Equal(t, int64(2), hour.Errors)
+	assert.InDelta(t, 0.5, *hour.Availability, 1e-9)
+	assert.NotNil(t, hour.LatencyMs)
+	week := resp.Windows[2]
+	assert.Equal(t, int64(10), week.Requests)
+	assert.InDelta(t, 0.8, *week.Availability, 1e-9)
+	assert.Equal(t, float64(992), week.LatencyMs.P99) // Interpolated within 500ms-1s
+
+	// Filters
+	resp = report("?kind=bundle&windows=24h,3d")
+	assert.Equal(t, "bundle", resp.Kind)
+	assert.Zero(t, resp.Windows[0].Requests)
+	assert.Nil(t, resp.Windows[0].Availability)
+	assert.Nil(t, resp.Windows[0].LatencyMs)
+	assert.Equal(t, int64(6), resp.Windows[1].Requests)
+	for _, query := range []string{"?windows=1w", "?windows=0h", "?windows=31d", "?kind=zip"} {
+		assert.Equal(t, http.StatusBadRequest, do("GET", "slo"+query, "ci-token").Code, query)
+	}
+
+	// Internal module: anonymous callers don't see it
+	assert.Equal(t, http.StatusNotFound, do("GET", "slo", "").Code)
+
+	// Hours older than the retention period are deleted
+	expired := models.ModuleFetchStat{Module: "acme/billing", Kind: FetchKindArtifact, BucketStart: time.Now().UTC().Add(-SLORetention - 2*time.Hour).Truncate(time.Hour), Requests: 1}
+	assert.NoError(t, gormDB.Create(&expired).Error)
+	assert.NoError(t, pruneFetchStats(context.Background(), time.Now().UTC()))
+	var remaining int64
+	assert.NoError(t, gormDB.Model(&models.ModuleFetchStat{}).Count(&remaining).Error)
+	assert.Equal(t, int64(2), remaining)
+}
+
+func TestLatencyQuantile(t *testing.T) {
+	//                 50  100 250 500 1s 2.5s 5s 10s +Inf
+	counts := []int64{50, 40, 0, 0, 8, 0, 0, 0, 2}
+	assert.Equal(t, float64(50), latencyQuantile(0.50, counts))
+	assert.Equal(t, float64(75), latencyQuantile(0.70, counts)) // Interpolated within 50-100ms
+	assert.Equal(t, float64(813), latencyQuantile(0.95, counts))
+	assert.Equal(t, float64(10000), latencyQuantile(0.99, counts)) // Overflow: the highest bound
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/notify"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Sunset enforcement: a deprecated version can be given a sunset date (PUT .../{version}/deprecation with
+// "sunset_at"). Until then, downloads carry a Sunset header (RFC 8594); once the sunset job has seen the
+// date pass, downloads of the version respond 410 Gone, or are still served with a logged warning if
+// SUNSET_ENFORCEMENT is "warn". Metadata, listings and notes stay available either way.
+
+// Sunset enforcement modes.
+const (
+	SunsetBlock = "block" // Respond 410 Gone
+	SunsetWarn  = "warn"  // Serve the download and log a warning
+)
+
+// sunsetEnforcement is the configured mode; see SetSunsetEnforcement.
+var sunsetEnforcement = SunsetBlock
+
+// SetSunsetEnforcement sets what happens to downloads of sunset versions ("block" or "warn"; empty means block).
+func SetSunsetEnforcement(mode string) error {
+	mode = strings.ToLower(mode)
+	if mode == "" {
+		mode = SunsetBlock
+	}
+	if mode != SunsetBlock && mode != SunsetWarn {
+		return fmt.Errorf("invalid sunset enforcement %q: must be %s or %s", mode, SunsetBlock, SunsetWarn)
+	}
+	sunsetEnforcement = mode
+	return nil
+}
+
+// sunsetGone sets the Sunset header of a download and, if the version's sunset is enforced in block
+// mode, writes the 410 response and returns true.
+func sunsetGone(w http.ResponseWriter, r *http.Request, moduleVersion *models.ModuleVersion) bool {
+	if moduleVersion.SunsetAt == nil {
+		return false
+	}
+	w.Header().Set("Sunset", moduleVersion.SunsetAt.UTC().Format(http.TimeFormat))
+	if moduleVersion.SunsetEnforcedAt == nil {
+		return false // Not reached yet, or the job hasn't run since
+	}
+
+	log := logging.FromContext(r.Context()).With(zap.Stringer("module_version_id", moduleVersion.ID), zap.String("version", moduleVersion.Version))
+	if sunsetEnforcement == SunsetWarn {
+		log.Warn("Serving a version past its sunset date", zap.Time("sunset_at", *moduleVersion.SunsetAt))
+		return false
+	}
+	log.Info("Refused download of a sunset version", zap.Time("sunset_at", *moduleVersion.SunsetAt))
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusGone)
+		return true
+	}
+	message := fmt.Sprintf("Version %s was sunset on %s", moduleVersion.Version, moduleVersion.SunsetAt.UTC().Format(time.RFC3339))
+	if moduleVersion.DeprecationMessage != "" {
+		message += ": " + moduleVersion.DeprecationMessage
+	}
+	response.Error(w, http.StatusGone, message)
+	return true
+}
+
+// --- Sunset Job ---
+
+// sunsetCandidate is a version whose sunset date has passed but isn't enforced yet.
+type sunsetCandidate struct {
+	ID                 uuid.UUID
+	Namespace          string
+	Name               string
+	Version            string
+	SunsetAt           time.Time
+	DeprecationMessage string
+}
+
+// enforceSunsets starts enforcing the sunset of deprecated versions whose sunset date is before now,
+// notifying the webhook for each, and returns how many it enforced.
+func enforceSunsets(ctx context.Context, now time.Time) (int, error) {
+	gormDB := db.GetDB().WithContext(ctx)
+	var candidates []sunsetCandidate
+	err := gormDB.Table("module_versions mv").
+		Select("mv.id, m.namespace, m.name, mv.version, mv.sunset_at, mv.deprecation_message").
+		Joins("JOIN modules m ON m.id = mv.module_id").
+		Where("mv.deprecated_at IS NOT NULL AND mv.sunset_at <= ? AND mv.sunset_enforced_at IS NULL", now).
+		Scan(&candidates).Error
+	if err != nil {
+		return 0, err
+	}
+
+	enforced := 0
+	for _, c := range candidates {
+		// The condition guards against the deprecation being lifted or moved meanwhile
+		result := gormDB.Model(&models.ModuleVersion{}).
+			Where("id = ? AND deprecated_at IS NOT NULL AND sunset_at <= ? AND sunset_enforced_at IS NULL", c.ID, now).
+			Update("sunset_enforced_at", now)
+		if result.Error != nil {
+			return enforced, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		enforced++
+		logging.L().Info("Enforcing version sunset", zap.String("job", "sunset"),
+			zap.String("module_version", fmt.Sprintf("%s/%s@%s", c.Namespace, c.Name, c.Version)), zap.Time("sunset_at", c.SunsetAt))
+		notify.Send(ctx, notify.Event{
+			Type:       notify.EventVersionSunset,
+			Namespace:  c.Namespace,
+			ModuleName: c.Name,
+			Message:    fmt.Sprintf("%s/%s@%s reached its sunset date", c.Namespace, c.Name, c.Version),
+			Data: map[string]interface{}{
+				"version":             c.Version,
+				"sunset_at":           c.SunsetAt.UTC(),
+				"deprecation_message": c.DeprecationMessage,
+				"enforcement":         sunsetEnforcement,
+			},
+		})
+	}
+	return enforced, nil
+}
+
+// RunSunsetEnforcer enforces sunset dates every interval (and once at start) until ctx is canceled.
+// Sunsets therefore take effect up to one interval after their date.
+func RunSunsetEnforcer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := enforceSunsets(ctx, time.Now().UTC()); err != nil {
+			logging.L().Warn("Failed to enforce version sunsets", zap.String("job", "sunset"), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
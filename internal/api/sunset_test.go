@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/notify"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for Sunset Enforcement ---
+
+// eventRecorder collects the events sent to the webhook.
+type eventRecorder struct{ events []notify.Event }
+
+func (e *eventRecorder) Notify(_ context.Context, event notify.Event) {
+	e.events = append(e.events, event)
+}
+
+func TestSunsetEnforcement(t *testing.T) {
+	gormDB := newTestDB(t)
+	provider := newTestStorage(t)
+	assert.NoError(t, provider.UploadFile(context.Background(), "k1", strings.NewReader("zip"), 3, "application/zip"))
+	recorder := &eventRecorder{}
+	notify.SetNotifier(recorder)
+	t.Cleanup(func() { notify.SetNotifier(nil) })
+	t.Cleanup(func() { _ = SetSunsetEnforcement(SunsetBlock) })
+
+	module := models.Module{Namespace: "acme", Name: "billing"}
+	assert.NoError(t, gormDB.Create(&module).Error)
+	v1 := models.ModuleVersion{ModuleID: module.ID, Version: "v1.0.0", VersionKey: validation.VersionKey("v1.0.0"), ArtifactDigest: "abc123", ArtifactStorageKey: "k1"}
+	assert.NoError(t, gormDB.Create(&v1).Error)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/artifact", FetchModuleVersionArtifactHandler).Methods("GET", "HEAD")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}/deprecation", DeprecateModuleVersionHandler).Methods("PUT", "DELETE")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", GetModuleVersionHandler).Methods("GET")
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/modules/acme/billing/v1.0.0"+path, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusBadRequest, do("PUT", "/deprecation", `{"sunset_at":"next week"}`).Code)
+
+	// Before the sunset date, downloads succeed and announce it
+	sunsetAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	rr := do("PUT", "/deprecation", `{"message":"use v2","sunset_at":"`+sunsetAt.Format(time.RFC3339)+`"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var deprecation apitypes.DeprecationResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deprecation))
+	assert.True(t, sunsetAt.Equal(*deprecation.SunsetAt))
+	assert.False(t, deprecation.Sunset)
+	rr = do("GET", "/artifact", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, sunsetAt.Format(http.TimeFormat), rr.Header().Get("Sunset"))
+
+	// The job enforces sunsets once their date has passed, once
+	n, err := enforceSunsets(context.Background(), time.Now().UTC())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	n, err = enforceSunsets(context.Background(), sunsetAt.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = enforceSunsets(context.Background(), sunsetAt.Add(2*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Len(t, recorder.events, 1)
+	assert.Equal(t, notify.EventVersionSunset, recorder.events[0].Type)
+	assert.Equal(t, "v1.0.0", recorder.events[0].Data["version"])
+
+	// Block mode: 410 Gone, metadata stays available
+	rr = do("GET", "/artifact", "")
+	assert.Equal(t, http.StatusGone, rr.Code)
+	assert.Contains(t, rr.Body.String(), "use v2")
+	assert.Equal(t, http.StatusGone, do("HEAD", "/artifact", "").Code)
+	rr = do("GET", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var metadata apitypes.ModuleVersionResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &metadata))
+	assert.True(t, metadata.Sunset)
+	assert.True(t, sunsetAt.Equal(*metadata.SunsetAt))
+
+	// Warn mode: served anyway
+	assert.NoError(t, SetSunsetEnforcement(SunsetWarn))
+	assert.Equal(t, http.StatusOK, do("GET", "/artifact", "").Code)
+	assert.NoError(t, SetSunsetEnforcement(SunsetBlock))
+	assert.Error(t, SetSunsetEnforcement("delete"))
+
+	// Updating the message keeps the enforced sunset; moving the date lifts it until the job runs again
+	rr = do("PUT", "/deprecation", `{"message":"use v2.1","sunset_at":"`+sunsetAt.Format(time.RFC3339)+`"}`)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deprecation))
+	assert.True(t, deprecation.Sunset)
+	assert.Equal(t, http.StatusGone, do("GET", "/artifact", "").Code)
+	later := sunsetAt.Add(24 * time.Hour)
+	rr = do("PUT", "/deprecation", `{"message":"use v2.1","sunset_at":"`+later.Format(time.RFC3339)+`"}`)
+	deprecation = apitypes.DeprecationResponse{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deprecation))
+	assert.False(t, deprecation.Sunset)
+	assert.Equal(t, http.StatusOK, do("GET", "/artifact", "").Code)
+
+	// Lifting the deprecation removes the sunset
+	assert.Equal(t, http.StatusOK, do("DELETE", "/deprecation", "").Code)
+	n, err = enforceSunsets(context.Background(), later.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	rr = do("GET", "/artifact", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Sunset"))
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"go.uber.org/zap"
+)
+
+// Syntax metadata: the syntaxes and editions (proto2, proto3, edition-2023) declared by a version's .proto
+// files are detected at publish, stored on the version (comma-separated) and returned with its metadata.
+// Listings can be filtered by them (?syntax=), and namespace policies can restrict them.
+
+// readSyntaxes detects the syntaxes declared by the uploaded artifact's .proto files. The file is rewound
+// afterwards. Detection never fails a publish; "" (unknown) is returned instead.
+func readSyntaxes(ctx context.Context, file multipart.File) string {
+	artifact, err := io.ReadAll(file)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("Error reading artifact for syntax detection", zap.Error(err))
+		return ""
+	}
+	return artifactSyntaxes(ctx, artifact)
+}
+
+// artifactSyntaxes returns the comma-separated syntax labels of an artifact's .proto files, logging (but
+// otherwise ignoring) errors.
+func artifactSyntaxes(ctx context.Context, artifact []byte) string {
+	syntaxes, err := descriptor.ArtifactSyntaxes(artifact)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to detect the syntax of the artifact's files", zap.Error(err))
+		return ""
+	}
+	return strings.Join(syntaxes, ",")
+}
+
+// splitSyntaxes returns the labels of a stored syntax list; an empty array (not null) if unknown.
+func splitSyntaxes(syntaxes string) []string {
+	if syntaxes == "" {
+		return []string{}
+	}
+	return strings.Split(syntaxes, ",")
+}
+
+// hasSyntax reports whether a stored syntax list contains the label.
+func hasSyntax(syntaxes, syntax string) bool {
+	for _, s := range strings.Split(syntaxes, ",") {
+		if s == syntax {
+			return true
+		}
+	}
+	return false
+}
+
+// syntaxFilter returns the ?syntax= filter of a listing ("" if absent).
+// Returns false if it is invalid; a 400 response has then been written.
+func syntaxFilter(w http.ResponseWriter, r *http.Request) (string, bool) {
+	syntax := r.URL.Query().Get("syntax")
+	if syntax == "" {
+		return "", true
+	}
+	if err := descriptor.ValidateSyntax(syntax); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return syntax, true
+}
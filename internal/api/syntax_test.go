@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for syntax metadata ---
+
+func TestSyntaxMetadata(t *testing.T) {
+	newTestDB(t)
+	newTestStorage(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules", ListModulesHandler).Methods("GET")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}", ListModuleVersionsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", GetModuleVersionHandler).Methods("GET")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	router.HandleFunc("/api/v1/admin/namespace-policies/{namespace}", PutNamespacePolicyHandler).Methods("PUT")
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+	publish := func(namespace, name, version string, files map[string]string) *httptest.ResponseRecorder {
+		packed := map[string][]byte{}
+		for file, content := range files {
+			packed[file] = []byte(content)
+		}
+		data, err := artifact.Pack(packed)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newPublishRequest(t, namespace, name, version, "", data))
+		return rr
+	}
+
+	// Detected at publish and returned with the publish response and the version metadata
+	rr := publish("acme", "orders", "v1.0.0", map[string]string{
+		"acme/orders/v1/orders.proto": `syntax = "proto3"; package acme.orders.v1; message Order {}`,
+		"acme/orders/v1/legacy.proto": `syntax = "proto2"; package acme.orders.v1; message Legacy {}`,
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var published apitypes.PublishModuleVersionResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &published))
+	assert.Equal(t, []string{"proto2", "proto3"}, published.Syntaxes)
+	assert.Equal(t, http.StatusCreated, publish("acme", "orders", "v2.0.0", map[string]string{
+		"acme/orders/v2/orders.proto": `edition = "2023"; package acme.orders.v2; message Order {}`,
+	}).Code)
+	assert.Equal(t, http.StatusCreated, publish("acme", "billing", "v1.0.0", map[string]string{
+		"acme/billing/v1/billing.proto": `syntax = "proto3"; package acme.billing.v1; message Invoice {}`,
+	}).Code)
+
+	var meta apitypes.ModuleVersionResponse
+	assert.NoError(t, json.Unmarshal(serve("GET", "/api/v1/modules/acme/orders/v2.0.0", "").Body.Bytes(), &meta))
+	assert.Equal(t, []string{"edition-2023"}, meta.Syntaxes)
+
+	// Version listings report and filter by syntax
+	var versions ListModuleVersionsResponse
+	assert.NoError(t, json.Unmarshal(serve("GET", "/api/v1/modules/acme/orders", "").Body.Bytes(), &versions))
+	assert.Equal(t, []string{"v2.0.0", "v1.0.0"}, versions.Versions)
+	assert.Equal(t, map[string][]string{"v1.0.0": {"proto2", "proto3"}, "v2.0.0": {"edition-2023"}}, versions.Syntaxes)
+	assert.NoError(t, json.Unmarshal(serve("GET", "/api/v1/modules/acme/orders?syntax=proto2", "").Body.Bytes(), &versions))
+	assert.Equal(t, []string{"v1.0.0"}, versions.Versions)
+
+	// Module listings filter by the latest version's syntax
+	var modules ListModulesResponse
+	assert.NoError(t, json.Unmarshal(serve("GET", "/api/v1/modules?syntax=proto3", "").Body.Bytes(), &modules))
+	assert.Len(t, modules.Modules, 1)
+	assert.Equal(t, "billing", modules.Modules[0].Name)
+	assert.Equal(t, []string{"proto3"}, modules.Modules[0].Syntaxes)
+	assert.NoError(t, json.Unmarshal(serve("GET", "/api/v1/modules?syntax=edition-2023", "").Body.Bytes(), &modules))
+	assert.Len(t, modules.Modules, 1)
+	assert.Equal(t, "orders", modules.Modules[0].Name)
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/api/v1/modules?syntax=proto4", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("GET", "/api/v1/modules/acme/orders?syntax=2023", "").Code)
+
+	// Namespace policies restrict the syntaxes that may be published
+	assert.Equal(t, http.StatusBadRequest, serve("PUT", "/api/v1/admin/namespace-policies/acme", `{"allowed_syntaxes":["proto4"]}`).Code)
+	rr = serve("PUT", "/api/v1/admin/namespace-policies/acme", `{"allowed_syntaxes":["proto3"," ","edition-2023","proto3"]}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var nsPolicy apitypes.NamespacePolicyResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &nsPolicy))
+	assert.Equal(t, []string{"proto3", "edition-2023"}, nsPolicy.AllowedSyntaxes)
+
+	rr = publish("acme", "orders", "v2.1.0", map[string]string{
+		"acme/orders/v2/orders.proto": `edition = "2023"; package acme.orders.v2; message Order {}`,
+		"acme/orders/v2/legacy.proto": `package acme.orders.v2; message Legacy {}`, // No declaration: proto2
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	var denied apitypes.ValidationFailedResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &denied))
+	assert.Equal(t, []apitypes.ValidationIssue{{Rule: "syntax", Severity: apitypes.SeverityError, Message: "files declaring proto2 are not allowed in namespace acme (allowed: proto3, edition-2023)"}}, denied.Issues)
+	assert.Equal(t, http.StatusCreated, publish("acme", "orders", "v2.2.0", map[string]string{
+		"acme/orders/v2/orders.proto": `edition = "2023"; package acme.orders.v2; message Order {}`,
+	}).Code)
+}
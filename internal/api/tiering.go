@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Storage tiering: artifacts of versions published more than STORAGE_TIERING_AGE ago are rarely fetched
+// but kept forever, so a background job (and the tier-storage admin job) moves them to the cold tier of
+// the storage provider (a cheaper S3 storage class, or the local cold directory). Fetches of cold
+// artifacts are unchanged: the providers read them transparently. module_versions.artifact_tiered_at
+// records which versions were moved, so each artifact is only transitioned once.
+
+// storageTieringAge is the configured age; see SetStorageTieringAge.
+var storageTieringAge time.Duration
+
+// SetStorageTieringAge sets the age after which artifacts are moved to cold storage (0 disables tiering).
+func SetStorageTieringAge(age time.Duration) {
+	storageTieringAge = age
+}
+
+// tieringCandidate is a hot artifact of a version published before the tiering cutoff.
+type tieringCandidate struct {
+	Namespace          string
+	Name               string
+	Version            string
+	ArtifactStorageKey string
+}
+
+// tieringResult is the outcome of a tiering run.
+type tieringResult struct {
+	Tiered int                  // Artifacts moved to cold storage
+	Failed []ArtifactJobFailure // Artifacts that couldn't be moved (they stay hot and are retried next run)
+	Total  int                  // Artifacts considered
+}
+
+// tierArtifacts moves the artifacts of versions published before cutoff to cold storage, reporting the
+// progress as the stage of the running operation (if any). Stops early when ctx is canceled. Returns an
+// error wrapping storage.ErrTieringUnsupported if the provider has no cold tier.
+func tierArtifacts(ctx context.Context, cutoff time.Time) (tieringResult, error) {
+	log := logging.FromContext(ctx).With(zap.String("job", "storage-tiering"))
+	gormDB := db.GetDB().WithContext(ctx)
+	var candidates []tieringCandidate
+	err := gormDB.Table("module_versions mv").
+		Select("m.namespace, m.name, mv.version, mv.artifact_storage_key").
+		Joins("JOIN modules m ON m.id = mv.module_id").
+		Where("mv.artifact_tiered_at IS NULL AND mv.created_at < ?", cutoff).
+		Order("mv.created_at").
+		Scan(&candidates).Error
+	if err != nil {
+		return tieringResult{}, err
+	}
+
+	// Versions republished with ?from= share their source's object: it is moved once, for all of them
+	keys := make([]string, 0, len(candidates))
+	byKey := make(map[string]tieringCandidate, len(candidates))
+	for _, c := range candidates {
+		if _, ok := byKey[c.ArtifactStorageKey]; !ok {
+			keys = append(keys, c.ArtifactStorageKey)
+			byKey[c.ArtifactStorageKey] = c
+		}
+	}
+
+	result := tieringResult{Failed: []ArtifactJobFailure{}, Total: len(keys)}
+	provider := storage.GetStorageProvider()
+	for i, key := range keys {
+		if ctx.Err() != nil {
+			break // Canceled: the artifacts moved so far stay cold
+		}
+		c := byKey[key]
+		coordinates := fmt.Sprintf("%s/%s@%s", c.Namespace, c.Name, c.Version)
+		reportStage(ctx, fmt.Sprintf("tiering %d/%d", i+1, len(keys)))
+		if err := storage.TransitionFile(ctx, provider, key); err != nil {
+			if errors.Is(err, storage.ErrTieringUnsupported) {
+				return result, err
+			}
+			log.Warn("Failed to move artifact to cold storage", zap.String("module_version", coordinates), zap.String("key", key), zap.Error(err))
+			result.Failed = append(result.Failed, ArtifactJobFailure{ModuleVersion: coordinates, Error: err.Error()})
+			continue
+		}
+		err := gormDB.Model(&models.ModuleVersion{}).Where("artifact_storage_key = ? AND artifact_tiered_at IS NULL", key).
+			Update("artifact_tiered_at", time.Now().UTC()).Error
+		if err != nil {
+			// The object is cold either way; it is transitioned again (a no-op) next run
+			log.Warn("Failed to record cold artifact", zap.String("module_version", coordinates), zap.Error(err))
+		}
+		result.Tiered++
+		log.Info("Moved artifact to cold storage", zap.String("module_version", coordinates), zap.String("key", key))
+	}
+	return result, nil
+}
+
+// RunStorageTiering moves aging artifacts to cold storage every interval, until ctx is canceled.
+func RunStorageTiering(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if storageTieringAge > 0 {
+			result, err := tierArtifacts(ctx, time.Now().UTC().Add(-storageTieringAge))
+			if err != nil {
+				logging.L().Warn("Failed to move artifacts to cold storage", zap.String("job", "storage-tiering"), zap.Error(err))
+			} else if result.Total > 0 {
+				logging.L().Info("Storage tiering finished", zap.String("job", "storage-tiering"), zap.Int("tiered", result.Tiered), zap.Int("failed", len(result.Failed)))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageTiering(t *testing.T) {
+	gormDB := newTestDB(t)
+	coldPath := t.TempDir()
+	provider, err := storage.NewLocalStorage(config.Config{LocalStoragePath: t.TempDir(), LocalColdStoragePath: coldPath})
+	assert.NoError(t, err)
+	storage.SetStorageProvider(provider)
+	t.Cleanup(func() { storage.SetStorageProvider(nil) })
+
+	module := models.Module{Namespace: "acme", Name: "user"}
+	assert.NoError(t, gormDB.Create(&module).Error)
+	old := time.Now().UTC().Add(-100 * 24 * time.Hour)
+	create := func(version, key string, createdAt time.Time, upload bool) {
+		if upload {
+			assert.NoError(t, provider.UploadFile(context.Background(), key, strings.NewReader(version), int64(len(version)), "application/zip"))
+		}
+		assert.NoError(t, gormDB.Create(&models.ModuleVersion{ModuleID: module.ID, Version: version, VersionKey: validation.VersionKey(version), ArtifactDigest: version, ArtifactStorageKey: key, CreatedAt: createdAt}).Error)
+	}
+	create("v1.0.0", "k1", old, true)
+	create("v1.0.1", "k1", old.Add(time.Hour), false) // Republished with ?from=v1.0.0
+	create("v0.9.0", "missing", old, false)
+	create("v2.0.0", "k2", time.Now().UTC(), true)
+
+	result, err := tierArtifacts(context.Background(), time.Now().UTC().Add(-30*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Tiered)
+	assert.Equal(t, 2, result.Total)
+	if assert.Len(t, result.Failed, 1) {
+		assert.Equal(t, "acme/user@v0.9.0", result.Failed[0].ModuleVersion)
+	}
+
+	var versions []models.ModuleVersion
+	assert.NoError(t, gormDB.Order("version").Find(&versions).Error)
+	tiered := map[string]bool{}
+	for _, v := range versions {
+		tiered[v.Version] = v.ArtifactTieredAt != nil
+	}
+	assert.Equal(t, map[string]bool{"v0.9.0": false, "v1.0.0": true, "v1.0.1": true, "v2.0.0": false}, tiered)
+	_, err = os.Stat(filepath.Join(coldPath, "k1"))
+	assert.NoError(t, err)
+
+	// Cold artifacts are read transparently, and are only moved once
+	reader, err := provider.DownloadFile(context.Background(), "k1")
+	if assert.NoError(t, err) {
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		assert.Equal(t, "v1.0.0", string(data))
+	}
+	result, err = tierArtifacts(context.Background(), time.Now().UTC().Add(-30*24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Tiered)
+	assert.Equal(t, 1, result.Total)
+
+	// The admin job needs an age, from its parameter or STORAGE_TIERING_AGE
+	_, message := prepareTierStorageJob(nil)
+	assert.Contains(t, message, "older_than")
+	_, message = prepareTierStorageJob(map[string]string{"older_than": "90 days"})
+	assert.Contains(t, message, "duration")
+	SetStorageTieringAge(90 * 24 * time.Hour)
+	t.Cleanup(func() { SetStorageTieringAge(0) })
+	_, message = prepareTierStorageJob(nil)
+	assert.Empty(t, message)
+}
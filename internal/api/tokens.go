@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// Token lifecycle: the admin and read tokens can carry an expiry date after which they are rejected,
+// and the last use of every token is recorded so unused (stale) tokens can be found and rotated out.
+
+// TokenPolicy configures the lifecycle of the admin token and the stale token report.
+type TokenPolicy struct {
+	AdminExpiresAt *time.Time    // Expiry of the admin token (AUTH_TOKEN); nil if it doesn't expire
+	StaleAfter     time.Duration // Tokens unused for this long are reported as stale
+}
+
+// DefaultTokenPolicy is used until SetTokenPolicy is called.
+var DefaultTokenPolicy = TokenPolicy{StaleAfter: 90 * 24 * time.Hour}
+
+// Global token policy, configured at startup via SetTokenPolicy.
+var tokenPolicy = DefaultTokenPolicy
+
+// SetTokenPolicy configures the admin token expiry and the stale token threshold.
+func SetTokenPolicy(p TokenPolicy) {
+	tokenPolicy = p
+}
+
+// tokenFingerprint identifies a token in the database and reports without revealing it.
+func tokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokensEqual compares a presented token with a configured one in constant time, so response timing
+// reveals neither the content nor the length of the secret (both are hashed first).
+func tokensEqual(presented, configured string) bool {
+	a, b := sha256.Sum256([]byte(presented)), sha256.Sum256([]byte(configured))
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1
+}
+
+// tokenExpired reports whether a token with the given expiry is no longer valid.
+func tokenExpired(expiresAt *time.Time) bool {
+	return expiresAt != nil && !time.Now().Before(*expiresAt)
+}
+
+// acceptToken records a successful use of token and advertises its expiry to the client.
+func acceptToken(w http.ResponseWriter, token string, expiresAt *time.Time) {
+	tokenUsage.record(tokenFingerprint(token), time.Now().UTC())
+	if expiresAt != nil {
+		w.Header().Set(apitypes.TokenExpiryHeader, expiresAt.Format(time.RFC3339))
+	}
+}
+
+// --- Last-Used Tracking ---
+
+// tokenUsageTracker keeps the last use of each token in memory; RunTokenUsageFlusher persists it.
+// Writing to the database on every request would be wasteful for a value only needed by reports.
+type tokenUsageTracker struct {
+	mu       sync.Mutex
+	lastUsed map[string]time.Time // Fingerprint -> last use
+	dirty    map[string]bool      // Fingerprints not yet persisted
+}
+
+var tokenUsage = newTokenUsageTracker()
+
+func newTokenUsageTracker() *tokenUsageTracker {
+	return &tokenUsageTracker{lastUsed: map[string]time.Time{}, dirty: map[string]bool{}}
+}
+
+func (t *tokenUsageTracker) record(fingerprint string, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastUsed[fingerprint] = at
+	t.dirty[fingerprint] = true
+}
+
+// flush persists the uses recorded since the last flush.
+func (t *tokenUsageTracker) flush(ctx context.Context) error {
+	t.mu.Lock()
+	rows := make([]models.TokenUsage, 0, len(t.dirty))
+	for fingerprint := range t.dirty {
+		rows = append(rows, models.TokenUsage{Fingerprint: fingerprint, LastUsedAt: t.lastUsed[fingerprint]})
+	}
+	t.dirty = map[string]bool{}
+	t.mu.Unlock()
+	if len(rows) == 0 {
+		return nil
+	}
+
+	err := primaryRepositories().Tokens.RecordUses(ctx, rows)
+	if err != nil {
+		// Retry with the next flush (unless a newer use was recorded meanwhile, which is dirty anyway)
+		t.mu.Lock()
+		for _, row := range rows {
+			t.dirty[row.Fingerprint] = true
+		}
+		t.mu.Unlock()
+	}
+	return err
+}
+
+// lastUse returns the last use of each fingerprint, from memory or, for uses before the last restart,
+// the database.
+func (t *tokenUsageTracker) lastUse(ctx context.Context, fingerprints []string) (map[string]time.Time, error) {
+	rows, err := primaryRepositories().Tokens.LastUses(ctx, fingerprints)
+	if err != nil {
+		return nil, err
+	}
+	uses := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		uses[row.Fingerprint] = row.LastUsedAt
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, fingerprint := range fingerprints {
+		if at, ok := t.lastUsed[fingerprint]; ok && at.After(uses[fingerprint]) {
+			uses[fingerprint] = at
+		}
+	}
+	return uses, nil
+}
+
+// RunTokenUsageFlusher persists recorded token uses every interval until ctx is canceled.
+// Uses recorded after the last flush are lost if the server stops.
+func RunTokenUsageFlusher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := tokenUsage.flush(ctx); err != nil {
+				logging.L().Warn("Failed to persist token usage", zap.Error(err))
+			}
+		}
+	}
+}
+
+// --- Token Report ---
+
+// TokenReportHandler reports the configured tokens with their expiry and last use, so stale or
+// expired tokens can be rotated out. ?stale=true only lists stale and expired tokens.
+// GET /api/v1/admin/tokens
+func TokenReportHandler(adminToken string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := logging.FromContext(r.Context())
+
+		// Admin token first, then the read and maintainer tokens ordered by ID
+		var entries []apitypes.TokenReportEntry
+		if adminToken != "" {
+			entries = append(entries, apitypes.TokenReportEntry{ID: tokenFingerprint(adminToken), Kind: "admin", Consumer: AdminConsumer, ExpiresAt: tokenPolicy.AdminExpiresAt})
+		}
+		managed := managedTokenGrants()
+		readEntries := make([]apitypes.TokenReportEntry, 0, len(readTokens)+len(managed))
+		for token, rt := range readTokens {
+			readEntries = append(readEntries, readTokenReportEntry(tokenFingerprint(token), rt))
+		}
+		for fingerprint, grant := range managed {
+			entry, id := readTokenReportEntry(fingerprint, grant.ReadToken), grant.ID
+			entry.ManagedID = &id
+			readEntries = append(readEntries, entry)
+		}
+		sort.Slice(readEntries, func(i, j int) bool { return readEntries[i].ID < readEntries[j].ID })
+		entries = append(entries, readEntries...)
+		fingerprints := make([]string, 0, len(entries))
+		for _, e := range entries {
+			fingerprints = append(fingerprints, e.ID) // Full fingerprint until shortened below
+		}
+
+		uses := map[string]time.Time{}
+		if len(fingerprints) > 0 {
+			var err error
+			if uses, err = tokenUsage.lastUse(r.Context(), fingerprints); err != nil {
+				log.Error("Error loading token usage", zap.Error(err))
+				response.Error(w, http.StatusInternalServerError, "Failed to retrieve token usage")
+				return
+			}
+		}
+
+		onlyStale := r.URL.Query().Get("stale") == "true"
+		now := time.Now()
+		report := make([]apitypes.TokenReportEntry, 0, len(entries))
+		for _, entry := range entries {
+			if at, ok := uses[entry.ID]; ok {
+				entry.LastUsedAt = &at
+			}
+			entry.ID = entry.ID[:12]
+			entry.Expired = tokenExpired(entry.ExpiresAt)
+			entry.Stale = entry.LastUsedAt == nil || now.Sub(*entry.LastUsedAt) > tokenPolicy.StaleAfter
+			if onlyStale && !entry.Stale && !entry.Expired {
+				continue
+			}
+			report = append(report, entry)
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		response.JSON(w, http.StatusOK, apitypes.TokenReportResponse{StaleAfter: tokenPolicy.StaleAfter.String(), Tokens: report})
+	}
+}
+
+// readTokenReportEntry returns the report entry of a read or maintainer token, by fingerprint.
+func readTokenReportEntry(fingerprint string, rt ReadToken) apitypes.TokenReportEntry {
+	entry := apitypes.TokenReportEntry{ID: fingerprint, Kind: "read", Consumer: rt.Consumer, Patterns: rt.Patterns, ExpiresAt: rt.ExpiresAt}
+	if entry.Consumer == "" {
+		entry.Consumer = "token:" + fingerprint[:12] // As consumerName
+	}
+	if rt.Namespaces != nil {
+		entry.Kind, entry.Patterns = "maintainer", rt.Namespaces
+	}
+	return entry
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for Token Lifecycle ---
+
+func TestTokenExpiry(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(24 * time.Hour)
+	SetReadTokens(map[string]ReadToken{"old-token": {ExpiresAt: &past}, "ci-token": {ExpiresAt: &future}})
+	SetTokenPolicy(TokenPolicy{AdminExpiresAt: &past, StaleAfter: time.Hour})
+	t.Cleanup(func() {
+		SetReadTokens(nil)
+		SetTokenPolicy(DefaultTokenPolicy)
+	})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	send := func(handler http.Handler, token string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/api/v1/modules", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Expired admin token is rejected for reads and writes
+	rr := send(ReadAuthMiddleware("admin-token")(ok), "admin-token")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.JSONEq(t, `{"error":"Unauthorized: Token expired"}`, rr.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, send(AuthMiddleware("admin-token")(ok), "admin-token").Code)
+
+	// Expired read token
+	assert.Equal(t, http.StatusUnauthorized, send(ReadAuthMiddleware("admin-token")(ok), "old-token").Code)
+
+	// Valid read token: accepted, expiry advertised and use recorded
+	rr = send(ReadAuthMiddleware("admin-token")(ok), "ci-token")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, future.UTC().Format(time.RFC3339), rr.Header().Get(apitypes.TokenExpiryHeader))
+	tokenUsage.mu.Lock()
+	_, recorded := tokenUsage.lastUsed[tokenFingerprint("ci-token")]
+	tokenUsage.mu.Unlock()
+	assert.True(t, recorded)
+}
+
+func TestTokenReportHandler(t *testing.T) {
+	_, mock := setupMockDB(t)
+	SetReadTokens(map[string]ReadToken{"ci-token": {}, "payments-token": {Patterns: []string{"payments/*"}}})
+	SetTokenPolicy(TokenPolicy{StaleAfter: 24 * time.Hour})
+	tokenUsage = newTokenUsageTracker() // Forget uses recorded by other tests
+	t.Cleanup(func() {
+		SetReadTokens(nil)
+		SetTokenPolicy(DefaultTokenPolicy)
+	})
+
+	// The admin token was used recently (persisted); ci-token long ago; payments-token never
+	admin, ci := tokenFingerprint("admin-token"), tokenFingerprint("ci-token")
+	recent, old := time.Now().Add(-time.Hour), time.Now().Add(-30*24*time.Hour)
+	mock.ExpectQuery(`SELECT \* FROM "token_usages" WHERE fingerprint IN`).
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "last_used_at"}).AddRow(admin, recent).AddRow(ci, old))
+
+	req, err := http.NewRequest("GET", "/api/v1/admin/tokens?stale=true", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	TokenReportHandler("admin-token").ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp apitypes.TokenReportResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "24h0m0s", resp.StaleAfter)
+	ids := map[string]apitypes.TokenReportEntry{}
+	for _, e := range resp.Tokens {
+		assert.True(t, e.Stale)
+		ids[e.ID] = e
+	}
+	assert.Len(t, ids, 2) // The admin token isn't stale
+	assert.Equal(t, "read", ids[ci[:12]].Kind)
+	assert.NotNil(t, ids[ci[:12]].LastUsedAt)
+	assert.Nil(t, ids[tokenFingerprint("payments-token")[:12]].LastUsedAt)
+	assert.NotContains(t, rr.Body.String(), "ci-token") // Tokens are never revealed
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// uploadDigestPattern matches the value of ArtifactDigestHeader; the "sha256:" prefix is optional.
+var uploadDigestPattern = regexp.MustCompile(`^(?:sha256:)?([0-9a-fA-F]{64})$`)
+
+// checkUploadDigest compares the uploaded bytes with the ArtifactDigestHeader of the request, if any, and
+// rewinds content afterwards. Returns false if a response has already been written (upload rejected).
+func checkUploadDigest(w http.ResponseWriter, r *http.Request, content io.ReadSeeker) bool {
+	expected := strings.TrimSpace(r.Header.Get(apitypes.ArtifactDigestHeader))
+	if expected == "" {
+		return true
+	}
+	m := uploadDigestPattern.FindStringSubmatch(expected)
+	if m == nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid %s %q: expected sha256:<hex>", apitypes.ArtifactDigestHeader, expected))
+		return false
+	}
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, content)
+	if err == nil {
+		_, err = content.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error reading artifact file", zap.Error(err))
+		response.Error(w, http.StatusBadRequest, "Could not read artifact file")
+		return false
+	}
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if !strings.EqualFold(m[1], actual) {
+		logging.FromContext(r.Context()).Warn("Uploaded artifact doesn't match its digest", zap.String("expected", strings.ToLower(m[1])), zap.String("actual", actual), zap.Int64("size", size))
+		response.Error(w, http.StatusUnprocessableEntity, fmt.Sprintf("Artifact digest mismatch: %s is sha256:%s, but the %d bytes received have sha256:%s; the upload was corrupted in transit", apitypes.ArtifactDigestHeader, strings.ToLower(m[1]), size, actual))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/notify"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ModuleQuota configures the per-module soft storage quota.
+// The quota is advisory: crossing it emits warnings (log + webhook) but publishes are never rejected.
+type ModuleQuota struct {
+	SoftBytes   int64 // 0 disables quota warnings
+	WarnPercent int   // Warn when usage crosses this percentage of SoftBytes
+}
+
+// Global soft quota settings, configured at startup via SetModuleQuota.
+var moduleQuota ModuleQuota
+
+// SetModuleQuota configures the per-module soft quota used for usage reporting and warnings.
+func SetModuleQuota(q ModuleQuota) {
+	moduleQuota = q
+}
+
+// ModuleUsageResponse defines the response for the module storage usage endpoint.
+type ModuleUsageResponse struct {
+	Namespace      string   `json:"namespace"`
+	ModuleName     string   `json:"module_name"`
+	VersionCount   int64    `json:"version_count"`
+	TotalBytes     int64    `json:"total_bytes"`
+	SoftQuotaBytes int64    `json:"soft_quota_bytes,omitempty"` // Omitted when no quota is configured
+	PercentUsed    *float64 `json:"percent_used,omitempty"`     // Omitted when no quota is configured
+}
+
+// moduleUsage holds the aggregated storage consumption of a module.
+type moduleUsage struct {
+	VersionCount int64
+	TotalBytes   int64
+}
+
+// GetModuleUsageHandler reports the storage consumed by all versions of a module.
+// GET /api/v1/modules/{namespace}/{module_name}/usage
+func GetModuleUsageHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+
+	module, ok := findReadableModule(w, r, namespace, moduleName)
+	if !ok {
+		return // Response already written
+	}
+
+	usage, err := getModuleUsage(db.GetReadDB(), module.ID)
+	if err != nil {
+		log.Error("Error computing module usage", zap.String("namespace", namespace), zap.String("module", moduleName), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to compute module usage")
+		return
+	}
+
+	respData := ModuleUsageResponse{
+		Namespace:    namespace,
+		ModuleName:   moduleName,
+		VersionCount: usage.VersionCount,
+		TotalBytes:   usage.TotalBytes,
+	}
+	if moduleQuota.SoftBytes > 0 {
+		percent := percentOf(usage.TotalBytes, moduleQuota.SoftBytes)
+		respData.SoftQuotaBytes = moduleQuota.SoftBytes
+		respData.PercentUsed = &percent
+	}
+	setListCacheHeaders(w)
+	response.JSON(w, http.StatusOK, respData)
+}
+
+// getModuleUsage sums the artifact sizes and counts the versions of a module.
+func getModuleUsage(gormDB *gorm.DB, moduleID uuid.UUID) (moduleUsage, error) {
+	var usage moduleUsage
+	err := gormDB.Model(&models.ModuleVersion{}).
+		Select("COUNT(*) AS version_count, COALESCE(SUM(artifact_size), 0) AS total_bytes").
+		Where("module_id = ?", moduleID).
+		Scan(&usage).Error
+	return usage, err
+}
+
+// checkModuleQuota compares a module's usage after a publish of addedBytes against the soft quota
+// and emits a warning when the publish crossed the warning threshold or the quota itself.
+// Warnings are only emitted on crossing, so a module sitting above a threshold doesn't warn on every publish.
+func checkModuleQuota(ctx context.Context, namespace, moduleName string, moduleID uuid.UUID, addedBytes int64) {
+	if moduleQuota.SoftBytes <= 0 {
+		return
+	}
+	log := logging.FromContext(ctx)
+
+	usage, err := getModuleUsage(db.GetDB(), moduleID)
+	if err != nil {
+		log.Warn("Failed to compute module usage for quota check", zap.String("namespace", namespace), zap.String("module", moduleName), zap.Error(err))
+		return
+	}
+
+	before := usage.TotalBytes - addedBytes
+	warnAt := moduleQuota.SoftBytes * int64(moduleQuota.WarnPercent) / 100
+
+	var eventType string
+	switch {
+	case before < moduleQuota.SoftBytes && usage.TotalBytes >= moduleQuota.SoftBytes:
+		eventType = notify.EventQuotaExceeded
+	case moduleQuota.WarnPercent > 0 && before < warnAt && usage.TotalBytes >= warnAt:
+		eventType = notify.EventQuotaWarning
+	default:
+		return
+	}
+
+	percent := percentOf(usage.TotalBytes, moduleQuota.SoftBytes)
+	message := fmt.Sprintf("Module %s/%s uses %d of %d bytes (%.1f%%) of its soft storage quota; consider removing unused versions",
+		namespace, moduleName, usage.TotalBytes, moduleQuota.SoftBytes, percent)
+	log.Warn(message,
+		zap.String("event", eventType),
+		zap.String("namespace", namespace),
+		zap.String("module", moduleName),
+		zap.Int64("total_bytes", usage.TotalBytes),
+		zap.Int64("soft_quota_bytes", moduleQuota.SoftBytes),
+		zap.Int64("version_count", usage.VersionCount),
+	)
+	notify.Send(ctx, notify.Event{
+		Type:       eventType,
+		Namespace:  namespace,
+		ModuleName: moduleName,
+		Message:    message,
+		Data: map[string]interface{}{
+			"total_bytes":      usage.TotalBytes,
+			"soft_quota_bytes": moduleQuota.SoftBytes,
+			"percent_used":     percent,
+			"version_count":    usage.VersionCount,
+		},
+	})
+}
+
+// percentOf returns used as a percentage of total, rounded to one decimal.
+func percentOf(used, total int64) float64 {
+	return float64(used*1000/total) / 10
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// --- Tests for GetModuleUsageHandler ---
+
+func TestGetModuleUsageHandler_WithQuota(t *testing.T) {
+	_, mock := setupMockDB(t)
+	SetModuleQuota(ModuleQuota{SoftBytes: 2000, WarnPercent: 80})
+	t.Cleanup(func() { SetModuleQuota(ModuleQuota{}) })
+	moduleID := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "modules" WHERE namespace = $1 AND name = $2 ORDER BY "modules"."id" LIMIT $3`)).
+		WithArgs("my-org", "my-module", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "namespace", "name"}).AddRow(moduleID, "my-org", "my-module"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) AS version_count, COALESCE(SUM(artifact_size), 0) AS total_bytes FROM "module_versions" WHERE module_id = $1`)).
+		WithArgs(moduleID).
+		WillReturnRows(sqlmock.NewRows([]string{"version_count", "total_bytes"}).AddRow(3, 1700))
+
+	req, err := http.NewRequest("GET", "/api/v1/modules/my-org/my-module/usage", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/usage", GetModuleUsageHandler)
+	router.ServeHTTP(rr, req)
+
+	// --- Assertions ---
+	assert.Equal(t, http.StatusOK, rr.Code)
+	expectedBody := `{"namespace":"my-org","module_name":"my-module","version_count":3,"total_bytes":1700,"soft_quota_bytes":2000,"percent_used":85}`
+	assert.JSONEq(t, expectedBody, rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetModuleUsageHandler_ModuleNotFound(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "modules" WHERE namespace = $1 AND name = $2 ORDER BY "modules"."id" LIMIT $3`)).
+		WithArgs("my-org", "missing", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	req, err := http.NewRequest("GET", "/api/v1/modules/my-org/missing/usage", nil)
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/usage", GetModuleUsageHandler)
+	router.ServeHTTP(rr, req)
+
+	// --- Assertions ---
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":"Module not found"}`, rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
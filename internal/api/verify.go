@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/repo"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"go.uber.org/zap"
+)
+
+// Digest verification: consumers holding an artifact digest (e.g. from sproto.lock or a build cache)
+// can ask the registry whether it is the digest recorded for a version, and whether that version has
+// been deprecated or withdrawn (sunset), without downloading the artifact.
+
+// digestPattern matches an artifact digest, with or without the "sha256:" prefix.
+var digestPattern = regexp.MustCompile(`^(sha256:)?[0-9a-f]{64}$`)
+
+// VerifyHandler checks a digest against the artifact digest recorded for a module version.
+// POST /api/v1/verify
+func VerifyHandler(w http.ResponseWriter, r *http.Request) {
+	var req apitypes.VerifyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if req.Namespace == "" || req.ModuleName == "" || req.Version == "" {
+		response.Error(w, http.StatusBadRequest, "namespace, module_name and version are required")
+		return
+	}
+	if !strings.HasPrefix(req.Version, "v") {
+		response.Error(w, http.StatusBadRequest, "Invalid version format: must start with 'v'")
+		return
+	}
+	digest := strings.ToLower(strings.TrimSpace(req.Digest))
+	if !digestPattern.MatchString(digest) {
+		response.Error(w, http.StatusBadRequest, "Invalid digest: expected sha256:<64 hex characters>")
+		return
+	}
+	digest = "sha256:" + strings.TrimPrefix(digest, "sha256:")
+
+	// --- Look Up the Version (read-only despite the POST) ---
+	log := logging.FromContext(r.Context()).With(zap.String("module", req.Namespace+"/"+req.ModuleName), zap.String("version", req.Version))
+	repos := readRepositories()
+	visibility, err := repos.Modules.Visibility(r.Context(), req.Namespace, req.ModuleName)
+	if err == nil && !readerFromContext(r.Context()).canRead(req.Namespace, req.ModuleName, visibility) {
+		err = repo.ErrNotFound // Unreadable modules are reported as not found, like everywhere else
+	}
+	var moduleVersion *models.ModuleVersion
+	if err == nil {
+		moduleVersion, err = repos.Versions.Find(r.Context(), req.Namespace, req.ModuleName, req.Version)
+	}
+	if errors.Is(err, repo.ErrNotFound) {
+		response.Error(w, http.StatusNotFound, "Module version not found")
+		return
+	} else if err != nil {
+		log.Error("Error finding module version to verify", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve module version details")
+		return
+	}
+
+	recorded := "sha256:" + moduleVersion.ArtifactDigest
+	resp := apitypes.VerifyResponse{
+		Namespace:          req.Namespace,
+		ModuleName:         req.ModuleName,
+		Version:            moduleVersion.Version,
+		Digest:             digest,
+		Match:              digest == recorded,
+		RecordedDigest:     recorded,
+		ScanStatus:         moduleVersion.ScanStatus,
+		Deprecated:         moduleVersion.DeprecatedAt != nil,
+		DeprecationMessage: moduleVersion.DeprecationMessage,
+		SunsetAt:           moduleVersion.SunsetAt,
+		Sunset:             moduleVersion.SunsetEnforcedAt != nil,
+	}
+	if !resp.Match {
+		// Worth noticing: a tampered cache, a stale lockfile or a registry that changed under its consumers
+		log.Warn("Digest verification mismatch", zap.String("digest", digest), zap.String("recorded_digest", recorded))
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+// --- Tests for the verify endpoint ---
+
+func TestVerifyHandler(t *testing.T) {
+	gormDB := newTestDB(t)
+
+	public := models.Module{Namespace: "acme", Name: "billing", Visibility: models.VisibilityPublic}
+	private := models.Module{Namespace: "acme", Name: "secret", Visibility: models.VisibilityPrivate}
+	assert.NoError(t, gormDB.Create(&public).Error)
+	assert.NoError(t, gormDB.Create(&private).Error)
+	digest := strings.Repeat("ab", 32)
+	deprecatedAt, sunsetAt := time.Now().Add(-48*time.Hour), time.Now().Add(-time.Hour)
+	assert.NoError(t, gormDB.Create(&[]models.ModuleVersion{
+		{ModuleID: public.ID, Version: "v1.0.0", VersionKey: validation.VersionKey("v1.0.0"), ArtifactDigest: digest, ArtifactStorageKey: "k1", ScanStatus: "clean",
+			DeprecatedAt: &deprecatedAt, DeprecationMessage: "Use v2", SunsetAt: &sunsetAt, SunsetEnforcedAt: &sunsetAt},
+		{ModuleID: public.ID, Version: "v2.0.0", VersionKey: validation.VersionKey("v2.0.0"), ArtifactDigest: digest, ArtifactStorageKey: "k2", ScanStatus: "clean"},
+		{ModuleID: private.ID, Version: "v1.0.0", VersionKey: validation.VersionKey("v1.0.0"), ArtifactDigest: digest, ArtifactStorageKey: "k3", ScanStatus: "clean"},
+	}).Error)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/verify", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), readerKey, reader{})) // Anonymous
+		rr := httptest.NewRecorder()
+		VerifyHandler(rr, req)
+		return rr
+	}
+	verify := func(body string) apitypes.VerifyResponse {
+		rr := send(body)
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var resp apitypes.VerifyResponse
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		return resp
+	}
+
+	// Matching digest, with or without the prefix and in any case
+	resp := verify(`{"namespace":"acme","module_name":"billing","version":"v2.0.0","digest":"sha256:` + digest + `"}`)
+	assert.True(t, resp.Match)
+	assert.Equal(t, "sha256:"+digest, resp.RecordedDigest)
+	assert.Equal(t, "clean", resp.ScanStatus)
+	assert.False(t, resp.Deprecated)
+	assert.False(t, resp.Sunset)
+	assert.True(t, verify(`{"namespace":"acme","module_name":"billing","version":"v2.0.0","digest":"`+strings.ToUpper(digest)+`"}`).Match)
+
+	// Mismatch is a successful answer
+	resp = verify(`{"namespace":"acme","module_name":"billing","version":"v2.0.0","digest":"` + strings.Repeat("cd", 32) + `"}`)
+	assert.False(t, resp.Match)
+	assert.Equal(t, "sha256:"+strings.Repeat("cd", 32), resp.Digest)
+
+	// Deprecated and withdrawn versions are reported, not refused
+	resp = verify(`{"namespace":"acme","module_name":"billing","version":"v1.0.0","digest":"` + digest + `"}`)
+	assert.True(t, resp.Match)
+	assert.True(t, resp.Deprecated)
+	assert.Equal(t, "Use v2", resp.DeprecationMessage)
+	assert.True(t, resp.Sunset)
+	assert.NotNil(t, resp.SunsetAt)
+
+	// Unknown versions and modules the caller can't read are not found
+	assert.Equal(t, http.StatusNotFound, send(`{"namespace":"acme","module_name":"billing","version":"v9.0.0","digest":"`+digest+`"}`).Code)
+	assert.Equal(t, http.StatusNotFound, send(`{"namespace":"acme","module_name":"secret","version":"v1.0.0","digest":"`+digest+`"}`).Code)
+
+	// Bad requests
+	for _, body := range []string{
+		`{`,
+		`{"namespace":"acme","module_name":"billing","digest":"` + digest + `"}`,
+		`{"namespace":"acme","module_name":"billing","version":"1.0.0","digest":"` + digest + `"}`,
+		`{"namespace":"acme","module_name":"billing","version":"v1.0.0"}`,
+		`{"namespace":"acme","module_name":"billing","version":"v1.0.0","digest":"sha256:abc"}`,
+		`{"namespace":"acme","module_name":"billing","version":"v1.0.0","digest":"md5:` + digest + `"}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, send(body).Code, body)
+	}
+}
@@ -0,0 +1,392 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/repo"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Read authorization: every module has a visibility (models.VisibilityPublic/Internal/Private).
+// Public modules can be read by anyone. Internal modules need a valid token (the admin token or
+// any read token). Private modules need the admin token or a read token granted the module.
+// Modules a caller can't read are reported as not found, so their existence isn't revealed.
+//
+// Registries run in one of two modes (PUBLIC_READ):
+//   - Public (the default, for open-source style registries): anonymous callers can read public modules.
+//   - Private: every read needs a token. Anonymous requests to any /api/v1 endpoint are rejected with
+//     401 by ReadAuthMiddleware, so endpoints added later are covered without further changes.
+// In both modes, writes need the admin token; read tokens are rejected on write endpoints with 403.
+
+// Global read authorization settings, configured at startup via SetReadTokens, SetDefaultVisibility
+// and SetPublicRead.
+var (
+	readTokens        map[string]ReadToken
+	defaultVisibility = models.VisibilityPublic
+	publicRead        = true
+)
+
+// ReadToken is a read-only token configured in READ_TOKENS, or a maintainer token (MAINTAINER_TOKENS), or
+// the grants of a managed token.
+type ReadToken struct {
+	Patterns  []string   // Private modules the token may read (path.Match patterns)
+	ExpiresAt *time.Time // nil if the token doesn't expire
+	Consumer  string     // Team or service the token identifies in consumption reports; "" if unnamed
+	// Namespaces a maintainer token maintains (path.Match patterns); nil for read tokens
+	Namespaces []string
+}
+
+// consumerPattern restricts consumer names (READ_TOKENS "token#consumer") to a log- and URL-friendly set.
+var consumerPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// SetReadTokens configures the read tokens (see ParseReadTokens).
+func SetReadTokens(tokens map[string]ReadToken) {
+	readTokens = tokens
+}
+
+// SetPublicRead configures whether anonymous callers can read public modules. If false, every
+// read needs the admin token or a read token (has no effect if authentication is disabled).
+func SetPublicRead(enabled bool) {
+	publicRead = enabled
+}
+
+// isReadToken reports whether token is a configured or managed read token (expired or not).
+func isReadToken(token string) bool {
+	_, known := lookupReadToken(token)
+	return known
+}
+
+// lookupReadToken returns the grants of a read or maintainer token: configured (READ_TOKENS,
+// MAINTAINER_TOKENS) or managed through the admin API (see managedtokens.go).
+func lookupReadToken(token string) (ReadToken, bool) {
+	// Every configured token is compared, in constant time, rather than looked up by the raw secret
+	var found ReadToken
+	known := false
+	for configured, rt := range readTokens {
+		if tokensEqual(token, configured) {
+			found, known = rt, true
+		}
+	}
+	if known {
+		return found, true
+	}
+	managedTokens.RLock()
+	defer managedTokens.RUnlock()
+	grant, known := managedTokens.byFingerprint[tokenFingerprint(token)]
+	return grant.ReadToken, known
+}
+
+// SetDefaultVisibility configures the visibility of modules created by a publish without ?visibility=.
+func SetDefaultVisibility(visibility string) error {
+	if err := apitypes.ValidateVisibility(visibility); err != nil {
+		return err
+	}
+	defaultVisibility = visibility
+	return nil
+}
+
+// ParseReadTokens parses READ_TOKENS: a comma-separated list of tokens, each optionally followed by
+// "#" and the name of the consumer it identifies (see ModuleConsumersHandler), by "@" and an expiry
+// date (see ParseTokenExpiry), and by "=" and "|"-separated module patterns (path.Match syntax)
+// naming the private modules it may read.
+// For example "ci-token#ci@2027-01-01,payments-token#payments-team=payments/*|billing/ledger".
+func ParseReadTokens(spec string) (map[string]ReadToken, error) {
+	return parseTokens(spec, "read", addModulePattern)
+}
+
+// ParseMaintainerTokens parses MAINTAINER_TOKENS, in the format of READ_TOKENS except that the patterns
+// name namespaces, e.g. "payments-token#payments-team=payments|payments-*". A maintainer token reads the
+// modules of its namespaces (private ones included) like a read token, and manages their webhook
+// subscriptions (see webhooks.go); it can't publish.
+func ParseMaintainerTokens(spec string) (map[string]ReadToken, error) {
+	return parseTokens(spec, "maintainer", addNamespacePattern)
+}
+
+// addModulePattern grants a read token the private modules matching pattern (namespace/name).
+func addModulePattern(rt *ReadToken, pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil || strings.Count(pattern, "/") != 1 {
+		return fmt.Errorf("invalid module pattern %q: expected namespace/name, e.g. payments/*", pattern)
+	}
+	rt.Patterns = append(rt.Patterns, pattern)
+	return nil
+}
+
+// addNamespacePattern makes a maintainer token maintain, and read, the namespaces matching pattern.
+func addNamespacePattern(rt *ReadToken, pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil || strings.Contains(pattern, "/") {
+		return fmt.Errorf("invalid namespace pattern %q: expected a namespace, e.g. payments or payments-*", pattern)
+	}
+	rt.Namespaces = append(rt.Namespaces, pattern)
+	rt.Patterns = append(rt.Patterns, pattern+"/*")
+	return nil
+}
+
+// parseTokens parses a comma-separated token list: "token[#consumer][@expiry][=pattern|pattern]",
+// passing each pattern to addPattern. kind names the tokens in errors.
+func parseTokens(spec, kind string, addPattern func(rt *ReadToken, pattern string) error) (map[string]ReadToken, error) {
+	tokens := map[string]ReadToken{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		token, patternList, _ := strings.Cut(entry, "=")
+		token, expiry, _ := strings.Cut(strings.TrimSpace(token), "@")
+		token, consumer, named := strings.Cut(token, "#")
+		if token == "" {
+			return nil, fmt.Errorf("%s token entry %q has no token", kind, entry)
+		}
+		if named && !consumerPattern.MatchString(consumer) {
+			return nil, fmt.Errorf("invalid consumer name %q: letters, digits, '.', '_' and '-' only, at most 128 characters", consumer)
+		}
+		expiresAt, err := apitypes.ParseTokenExpiry(expiry)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := tokens[token]; dup {
+			return nil, fmt.Errorf("duplicate %s token", kind)
+		}
+		rt := ReadToken{Patterns: []string{}, ExpiresAt: expiresAt, Consumer: consumer}
+		for _, pattern := range strings.Split(patternList, "|") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			if err := addPattern(&rt, pattern); err != nil {
+				return nil, err
+			}
+		}
+		tokens[token] = rt
+	}
+	return tokens, nil
+}
+
+// --- Reader Identity ---
+
+// reader is the identity of the caller for read authorization.
+type reader struct {
+	admin     bool     // Admin token (or auth disabled): reads everything
+	token     bool     // Presented a valid read token
+	patterns  []string // Private modules the read token was granted
+	consumer  string   // Who the token identifies for consumption reports (see consumerName); "" if anonymous
+	maintains []string // Namespaces a maintainer token maintains (path.Match patterns)
+}
+
+// canMaintain reports whether the reader may manage the settings namespace maintainers control (its
+// webhook subscriptions): the admin token, or a maintainer token of the namespace.
+func (rd reader) canMaintain(namespace string) bool {
+	if rd.admin {
+		return true
+	}
+	for _, pattern := range rd.maintains {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// canRead reports whether the reader may read a module with the given visibility.
+func (rd reader) canRead(namespace, name, visibility string) bool {
+	switch {
+	case rd.admin || visibility == models.VisibilityPublic || visibility == "": // "" for rows from before visibilities
+		return true
+	case visibility == models.VisibilityInternal:
+		return rd.token
+	}
+	for _, pattern := range rd.patterns {
+		if ok, _ := path.Match(pattern, namespace+"/"+name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readerFromContext returns the reader identified by ReadAuthMiddleware. Requests that didn't pass
+// through the middleware (internal callers, tests) are treated as admin.
+func readerFromContext(ctx context.Context) reader {
+	rd, ok := ctx.Value(readerKey).(reader)
+	if !ok {
+		return reader{admin: true}
+	}
+	return rd
+}
+
+// basicTokenMiddleware lets package managers that only send Basic credentials (the go command from .netrc,
+// Maven from settings.xml) authenticate for ReadAuthMiddleware: the password is taken as the token.
+func basicTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if scheme, credentials, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "basic") {
+			if decoded, err := base64.StdEncoding.DecodeString(credentials); err == nil {
+				if _, token, ok := strings.Cut(string(decoded), ":"); ok {
+					r.Header.Set("Authorization", "Bearer "+token)
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ReadAuthMiddleware identifies the caller for read authorization. Requests without a token are
+// anonymous (public modules only, or rejected with 401 if public read is disabled); an unknown or
+// expired token is rejected with 401. If adminToken is empty, authentication is disabled and every
+// caller can read everything.
+func ReadAuthMiddleware(adminToken string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rd := reader{admin: adminToken == ""}
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" && !rd.admin && !publicRead {
+				// Not counted as an authentication failure: nothing was presented
+				w.Header().Set("WWW-Authenticate", `Bearer realm="protoreg"`)
+				response.Error(w, http.StatusUnauthorized, "Unauthorized: This registry requires a token to read")
+				return
+			}
+			if authHeader != "" && !rd.admin {
+				if rejectBannedClient(w, r) {
+					return
+				}
+				scheme, token, ok := strings.Cut(authHeader, " ")
+				if !ok || strings.ToLower(scheme) != "bearer" {
+					rejectAuth(w, r, "invalid_format", "", "Unauthorized: Invalid Authorization header format")
+					return
+				}
+				var expiresAt *time.Time
+				if tokensEqual(token, adminToken) {
+					rd.admin, expiresAt = true, tokenPolicy.AdminExpiresAt
+					rd.consumer = AdminConsumer
+				} else if readToken, known := lookupReadToken(token); known {
+					rd.token, rd.patterns, expiresAt = true, readToken.Patterns, readToken.ExpiresAt
+					rd.consumer, rd.maintains = consumerName(token, readToken), readToken.Namespaces
+				} else {
+					rejectAuth(w, r, "invalid_token", token, "Unauthorized: Invalid token")
+					return
+				}
+				if tokenExpired(expiresAt) {
+					rejectAuth(w, r, "expired_token", token, "Unauthorized: Token expired")
+					return
+				}
+				acceptAuth(r)
+				acceptToken(w, token, expiresAt)
+			}
+			if rd.admin || rd.token {
+				// Responses may contain non-public modules; keep them out of shared caches
+				w = &privateCacheWriter{ResponseWriter: w}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), readerKey, rd)))
+		})
+	}
+}
+
+// privateCacheWriter wraps http.ResponseWriter to turn "Cache-Control: public" into "private" for
+// authenticated requests, so CDNs and proxies never serve them to other callers.
+type privateCacheWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (p *privateCacheWriter) WriteHeader(code int) {
+	if !p.wroteHeader {
+		p.wroteHeader = true
+		if cc := p.Header().Get("Cache-Control"); strings.HasPrefix(cc, "public") {
+			p.Header().Set("Cache-Control", "private"+strings.TrimPrefix(cc, "public"))
+		}
+	}
+	p.ResponseWriter.WriteHeader(code)
+}
+
+func (p *privateCacheWriter) Write(b []byte) (int, error) {
+	if !p.wroteHeader {
+		p.WriteHeader(http.StatusOK) // Implicit WriteHeader
+	}
+	return p.ResponseWriter.Write(b)
+}
+
+// Flush lets streaming handlers flush through the wrapper.
+func (p *privateCacheWriter) Flush() {
+	if f, ok := p.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// moduleReadable reports whether the caller may read the module. Unknown modules are reported as
+// readable, so callers produce their usual "not found" response.
+func moduleReadable(r *http.Request, namespace, name string) (bool, error) {
+	rd := readerFromContext(r.Context())
+	if rd.admin {
+		return true, nil // No need to look the module up
+	}
+	visibility, err := requestRepositories(r).Modules.Visibility(r.Context(), namespace, name)
+	if err != nil {
+		return false, err
+	}
+	return rd.canRead(namespace, name, visibility), nil
+}
+
+// IsPublicModule reports whether a module is public (or doesn't exist), for unauthenticated callers
+// outside the HTTP API such as gRPC reflection. Always false if public read is disabled.
+func IsPublicModule(ctx context.Context, namespace, name string) (bool, error) {
+	if !publicRead {
+		return false, nil
+	}
+	visibility, err := readRepositories().Modules.Visibility(ctx, namespace, name)
+	if err != nil {
+		return false, err
+	}
+	return visibility == models.VisibilityPublic || visibility == "", nil
+}
+
+// --- Visibility Endpoint ---
+
+// SetModuleVisibilityHandler changes who may read a module.
+// PUT /api/v1/modules/{namespace}/{module_name}/visibility
+func SetModuleVisibilityHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	log := logging.FromContext(r.Context()).With(zap.String("module", namespace+"/"+moduleName))
+
+	var req apitypes.SetModuleVisibilityRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if err := apitypes.ValidateVisibility(req.Visibility); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	repos := primaryRepositories()
+	module, err := repos.Modules.Get(r.Context(), namespace, moduleName)
+	if err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			response.Error(w, http.StatusNotFound, "Module not found")
+		} else {
+			log.Error("Error finding module", zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to retrieve module")
+		}
+		return
+	}
+	if err := repos.Modules.SetVisibility(r.Context(), module.ID, req.Visibility); err != nil {
+		log.Error("Error updating module visibility", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error updating visibility")
+		return
+	}
+	log.Info("Changed module visibility", zap.String("visibility", req.Visibility))
+
+	response.JSON(w, http.StatusOK, apitypes.ModuleVisibilityResponse{Namespace: namespace, ModuleName: moduleName, Visibility: req.Visibility})
+}
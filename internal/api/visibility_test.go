@@ -0,0 +1,197 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// --- Tests for Module Visibility ---
+
+func TestParseReadTokens(t *testing.T) {
+	tokens, err := ParseReadTokens(" ci-token@2027-01-01 , payments-token#payments-team=payments/*|billing/ledger ")
+	assert.NoError(t, err)
+	expiry := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, map[string]ReadToken{
+		"ci-token":       {Patterns: []string{}, ExpiresAt: &expiry},
+		"payments-token": {Patterns: []string{"payments/*", "billing/ledger"}, Consumer: "payments-team"},
+	}, tokens)
+
+	for _, spec := range []string{"=payments/*", "a,a", "tok=payments", "tok=[/x", "tok@tomorrow", "tok#", "tok#team/a", "#team"} {
+		_, err := ParseReadTokens(spec)
+		assert.Error(t, err, spec)
+	}
+
+	anonymous := reader{}
+	ci := reader{token: true}
+	payments := reader{token: true, patterns: tokens["payments-token"].Patterns}
+	assert.True(t, anonymous.canRead("acme", "user", "public"))
+	assert.True(t, anonymous.canRead("acme", "user", "")) // Rows from before visibilities
+	assert.False(t, anonymous.canRead("acme", "user", "internal"))
+	assert.True(t, ci.canRead("acme", "user", "internal"))
+	assert.False(t, ci.canRead("payments", "core", "private"))
+	assert.True(t, payments.canRead("payments", "core", "private"))
+	assert.False(t, payments.canRead("billing", "invoices", "private"))
+	assert.True(t, reader{admin: true}.canRead("billing", "invoices", "private"))
+}
+
+func TestParseMaintainerTokens(t *testing.T) {
+	tokens, err := ParseMaintainerTokens("pay-token#payments-team=payments|payments-*")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]ReadToken{
+		"pay-token": {Patterns: []string{"payments/*", "payments-*/*"}, Consumer: "payments-team", Namespaces: []string{"payments", "payments-*"}},
+	}, tokens)
+	for _, spec := range []string{"tok=payments/core", "tok=[x", "a=x,a=y"} {
+		_, err := ParseMaintainerTokens(spec)
+		assert.Error(t, err, spec)
+	}
+
+	maintainer := reader{token: true, patterns: tokens["pay-token"].Patterns, maintains: tokens["pay-token"].Namespaces}
+	assert.True(t, maintainer.canMaintain("payments"))
+	assert.True(t, maintainer.canMaintain("payments-eu"))
+	assert.False(t, maintainer.canMaintain("billing"))
+	assert.True(t, maintainer.canRead("payments-eu", "ledger", "private"))
+	assert.False(t, reader{token: true}.canMaintain("payments"))
+	assert.True(t, reader{admin: true}.canMaintain("billing"))
+}
+
+func TestReadAuthMiddleware(t *testing.T) {
+	_, mock := setupMockDB(t)
+	SetReadTokens(map[string]ReadToken{"ci-token": {}, "payments-token": {Patterns: []string{"payments/*"}}})
+	t.Cleanup(func() { SetReadTokens(nil) })
+
+	router := mux.NewRouter()
+	router.Use(ReadAuthMiddleware("admin-token"))
+	router.HandleFunc("/modules/{namespace}/{module_name}", func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		ok, err := moduleReadable(r, vars["namespace"], vars["module_name"])
+		assert.NoError(t, err)
+		setListCacheHeaders(w)
+		assert.NoError(t, json.NewEncoder(w).Encode(ok))
+	})
+	send := func(token string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/modules/payments/core", nil)
+		assert.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	expectVisibility := func() {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "visibility" FROM "modules" WHERE namespace = $1 AND name = $2`)).
+			WithArgs("payments", "core").
+			WillReturnRows(sqlmock.NewRows([]string{"visibility"}).AddRow("private"))
+	}
+
+	// Anonymous: private module isn't readable, response stays publicly cacheable
+	expectVisibility()
+	rr := send("")
+	assert.Equal(t, "false\n", rr.Body.String())
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "public")
+
+	// Read token without a grant for the module
+	expectVisibility()
+	assert.Equal(t, "false\n", send("ci-token").Body.String())
+
+	// Read token granted the module: readable, and kept out of shared caches
+	expectVisibility()
+	rr = send("payments-token")
+	assert.Equal(t, "true\n", rr.Body.String())
+	assert.Regexp(t, "^private", rr.Header().Get("Cache-Control"))
+
+	// Admin token reads everything without a lookup
+	assert.Equal(t, "true\n", send("admin-token").Body.String())
+
+	// Unknown token
+	assert.Equal(t, http.StatusUnauthorized, send("bogus").Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadAuthMiddleware_PublicReadDisabled(t *testing.T) {
+	_, mock := setupMockDB(t)
+	SetReadTokens(map[string]ReadToken{"ci-token": {}})
+	SetPublicRead(false)
+	t.Cleanup(func() {
+		SetReadTokens(nil)
+		SetPublicRead(true)
+	})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	send := func(handler http.Handler, token string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/api/v1/search?q=order", nil)
+		assert.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Anonymous reads are rejected, whatever the endpoint
+	rr := send(ReadAuthMiddleware("admin-token")(ok), "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, `Bearer realm="protoreg"`, rr.Header().Get("WWW-Authenticate"))
+
+	// Read and admin tokens can read
+	assert.Equal(t, http.StatusOK, send(ReadAuthMiddleware("admin-token")(ok), "ci-token").Code)
+	assert.Equal(t, http.StatusOK, send(ReadAuthMiddleware("admin-token")(ok), "admin-token").Code)
+
+	// Without an admin token authentication is disabled altogether
+	assert.Equal(t, http.StatusOK, send(ReadAuthMiddleware("")(ok), "").Code)
+
+	// Nothing is public to callers outside the HTTP API (no lookup needed)
+	public, err := IsPublicModule(context.Background(), "acme", "orders")
+	assert.NoError(t, err)
+	assert.False(t, public)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetModuleVisibilityHandler(t *testing.T) {
+	_, mock := setupMockDB(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/visibility", SetModuleVisibilityHandler).Methods("PUT")
+	send := func(body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("PUT", "/api/v1/modules/payments/core/visibility", bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send(`{"visibility":"secret"}`).Code)
+
+	// Unknown module
+	mock.ExpectQuery(`SELECT \* FROM "modules" WHERE namespace = \$1 AND name = \$2`).
+		WithArgs("payments", "core", 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	assert.Equal(t, http.StatusNotFound, send(`{"visibility":"private"}`).Code)
+
+	id := uuid.New()
+	mock.ExpectQuery(`SELECT \* FROM "modules" WHERE namespace = \$1 AND name = \$2`).
+		WithArgs("payments", "core", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "namespace", "name", "visibility"}).AddRow(id, "payments", "core", "public"))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "modules" SET "visibility"=\$1,"updated_at"=\$2 WHERE "id" = \$3`).
+		WithArgs("private", sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	rr := send(`{"visibility":"private"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"namespace":"payments","module_name":"core","visibility":"private"}`, rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,328 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/repo"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Schema watch: editor integrations (IDE plugins, language servers) keep a server-sent events stream open
+// per module and get an event for every new version, with the elements that differ from the previous
+// version (see descriptor.SchemaDiff), so they can refresh completions without re-downloading anything
+// until a schema changed. RunVersionWatcher polls the database for the watched modules only, so versions
+// published through any replica are announced.
+
+// Watch stream settings.
+const (
+	watchKeepalive  = 30 * time.Second // Comment sent on idle streams, so proxies don't close them
+	watchBacklog    = 50               // Most versions replayed on (re)connection
+	watchBufferSize = 16               // Events queued per stream; a client that falls further behind is disconnected
+)
+
+// --- Watched Modules ---
+
+// watchedModule is a module with open watch streams.
+type watchedModule struct {
+	namespace, name string
+	announced       map[string]bool // Versions already published or announced
+	subscribers     map[chan apitypes.VersionEvent]struct{}
+}
+
+// versionWatcher announces new versions of the watched modules to their streams.
+type versionWatcher struct {
+	mu      sync.Mutex
+	modules map[uuid.UUID]*watchedModule
+}
+
+var versionWatch = &versionWatcher{modules: make(map[uuid.UUID]*watchedModule)}
+
+// subscribe opens a stream of the versions of a module published from now on. The channel is closed
+// if the stream falls too far behind.
+func (v *versionWatcher) subscribe(ctx context.Context, module models.Module) (chan apitypes.VersionEvent, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	m, ok := v.modules[module.ID]
+	if !ok {
+		// The versions published so far aren't announced. Loaded under the lock, so a poll can't
+		// announce versions of the module before they're known.
+		var versions []string
+		err := db.GetReadDB().WithContext(ctx).Model(&models.ModuleVersion{}).
+			Where("module_id = ?", module.ID).Pluck("version", &versions).Error
+		if err != nil {
+			return nil, err
+		}
+		m = &watchedModule{namespace: module.Namespace, name: module.Name, announced: make(map[string]bool, len(versions)), subscribers: make(map[chan apitypes.VersionEvent]struct{})}
+		for _, version := range versions {
+			m.announced[version] = true
+		}
+		v.modules[module.ID] = m
+	}
+	ch := make(chan apitypes.VersionEvent, watchBufferSize)
+	m.subscribers[ch] = struct{}{}
+	return ch, nil
+}
+
+// unsubscribe closes a stream; the module is no longer polled once it has none.
+func (v *versionWatcher) unsubscribe(moduleID uuid.UUID, ch chan apitypes.VersionEvent) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	m, ok := v.modules[moduleID]
+	if !ok {
+		return
+	}
+	delete(m.subscribers, ch)
+	if len(m.subscribers) == 0 {
+		delete(v.modules, moduleID)
+	}
+}
+
+// watchedVersion is a version of a watched module, as polled.
+type watchedVersion struct {
+	ModuleID  uuid.UUID
+	Version   string
+	CreatedAt time.Time
+}
+
+// poll announces the versions of the watched modules published since the last poll, oldest first.
+func (v *versionWatcher) poll(ctx context.Context) error {
+	v.mu.Lock()
+	watched := make(map[uuid.UUID]*watchedModule, len(v.modules))
+	ids := make([]uuid.UUID, 0, len(v.modules))
+	for id, m := range v.modules {
+		watched[id] = m
+		ids = append(ids, id)
+	}
+	v.mu.Unlock()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var rows []watchedVersion
+	err := db.GetReadDB().WithContext(ctx).Model(&models.ModuleVersion{}).
+		Select("module_id, version, created_at").Where("module_id IN ?", ids).Order("created_at").Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	published := make(map[uuid.UUID]map[string]bool, len(ids))
+	var fresh []watchedVersion
+	v.mu.Lock()
+	for _, row := range rows {
+		if published[row.ModuleID] == nil {
+			published[row.ModuleID] = make(map[string]bool)
+		}
+		published[row.ModuleID][row.Version] = true
+		if !watched[row.ModuleID].announced[row.Version] {
+			fresh = append(fresh, row)
+		}
+	}
+	events := make([]apitypes.VersionEvent, len(fresh))
+	for i, row := range fresh {
+		m := watched[row.ModuleID]
+		events[i] = apitypes.VersionEvent{Namespace: m.namespace, ModuleName: m.name, Version: row.Version, CreatedAt: row.CreatedAt}
+	}
+	v.mu.Unlock()
+
+	// Diffed without the lock: compiling schemas can take a while
+	loader := descriptor.NewLoader(db.GetReadDB(), storage.GetStorageProvider())
+	for i, e := range events {
+		events[i] = newVersionEvent(ctx, loader, e.Namespace, e.ModuleName, e.Version, e.CreatedAt)
+	}
+
+	// Modules watched again since the snapshot have their own announced versions, and are left to the next poll
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for i, row := range fresh {
+		m := watched[row.ModuleID]
+		if v.modules[row.ModuleID] != m || m.announced[row.Version] {
+			continue
+		}
+		m.announced[row.Version] = true
+		for ch := range m.subscribers {
+			select {
+			case ch <- events[i]:
+			default:
+				close(ch) // The client reconnects with Last-Event-ID and gets what it missed
+				delete(m.subscribers, ch)
+			}
+		}
+	}
+	// Versions deleted since (e.g. ephemeral namespaces) are announced again if they're published again
+	for id, m := range watched {
+		if v.modules[id] != m {
+			continue
+		}
+		for version := range m.announced {
+			if !published[id][version] {
+				delete(m.announced, version)
+			}
+		}
+	}
+	return nil
+}
+
+// RunVersionWatcher announces the new versions of the watched modules every interval until ctx is canceled.
+func RunVersionWatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := versionWatch.poll(ctx); err != nil {
+				logging.L().Warn("Failed to poll watched modules for new versions", zap.Error(err))
+			}
+		}
+	}
+}
+
+// newVersionEvent describes a version and diffs it with the previous one. A diff that fails (e.g. storage
+// unavailable) is reported in the event rather than holding it back.
+func newVersionEvent(ctx context.Context, loader *descriptor.Loader, namespace, name, version string, createdAt time.Time) apitypes.VersionEvent {
+	event := apitypes.VersionEvent{
+		Namespace: namespace, ModuleName: name, Version: version, CreatedAt: createdAt, Changes: []apitypes.ElementDiff{},
+		DescriptorSetURL: fmt.Sprintf("/api/v1/modules/%s/%s/%s/bundle?format=%s", namespace, name, version, apitypes.BundleFormatDescriptorSet),
+	}
+	previous, changes, err := loader.DiffWithPrevious(ctx, namespace, name, version)
+	switch {
+	case errors.Is(err, descriptor.ErrCompile):
+		event.DiffError = "The schema of this version or the previous one does not compile"
+	case err != nil:
+		logging.L().Warn("Failed to diff new version", zap.String("module_version", namespace+"/"+name+"@"+version), zap.Error(err))
+		event.DiffError = "Failed to diff with the previous version"
+	default:
+		event.PreviousVersion = previous
+		for _, change := range changes {
+			event.Changes = append(event.Changes, apitypes.ElementDiff(change))
+		}
+	}
+	return event
+}
+
+// writeVersionEvent writes a version event in the SSE format, with the version as its ID.
+func writeVersionEvent(w io.Writer, event apitypes.VersionEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.Version, apitypes.WatchEventVersion, data)
+	return err
+}
+
+// --- Watch Endpoint ---
+
+// WatchModuleHandler streams the new versions of a module as server-sent events until the client disconnects.
+// GET /api/v1/modules/{namespace}/{module_name}/watch
+// ?since=<version> (or the Last-Event-ID header sent by reconnecting clients) first replays the versions
+// published after that one; an unknown version replays nothing.
+func WatchModuleHandler(w http.ResponseWriter, r *http.Request) {
+	log := logging.FromContext(r.Context())
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	moduleName := vars["module_name"]
+	log = log.With(zap.String("module", namespace+"/"+moduleName))
+
+	module, ok := findReadableModule(w, r, namespace, moduleName)
+	if !ok {
+		return // Response already written
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		response.Error(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	// Subscribed before the replay, so nothing published in between is missed
+	ch, err := versionWatch.subscribe(r.Context(), *module)
+	if err != nil {
+		log.Error("Error subscribing to module versions", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to watch module")
+		return
+	}
+	defer versionWatch.unsubscribe(module.ID, ch)
+
+	since := r.URL.Query().Get("since")
+	if since == "" {
+		since = r.Header.Get("Last-Event-ID")
+	}
+	var replay []watchedVersion
+	if since != "" {
+		after, err := requestRepositories(r).Versions.Find(r.Context(), namespace, moduleName, since)
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+		case err != nil:
+			log.Error("Error finding module version", zap.String("version", since), zap.Error(err))
+			response.Error(w, http.StatusInternalServerError, "Failed to watch module")
+			return
+		default:
+			err := requestDB(r).Model(&models.ModuleVersion{}).Select("module_id, version, created_at").
+				Where("module_id = ? AND created_at > ?", module.ID, after.CreatedAt).
+				Order("created_at DESC").Limit(watchBacklog).Scan(&replay).Error
+			if err != nil {
+				log.Error("Error listing module versions", zap.Error(err))
+				response.Error(w, http.StatusInternalServerError, "Failed to watch module")
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Don't let nginx buffer the stream
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	sent := make(map[string]bool)
+	loader := descriptor.NewLoader(requestDB(r), storage.GetStorageProvider())
+	for i := len(replay) - 1; i >= 0; i-- {
+		event := newVersionEvent(r.Context(), loader, namespace, moduleName, replay[i].Version, replay[i].CreatedAt)
+		if err := writeVersionEvent(w, event); err != nil {
+			return
+		}
+		sent[event.Version] = true
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(watchKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				log.Info("Closing watch stream that fell behind")
+				return
+			}
+			if sent[event.Version] {
+				continue // Already replayed
+			}
+			if err := writeVersionEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepalive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchModuleHandler(t *testing.T) {
+	newTestDB(t)
+	newTestStorage(t)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/watch", WatchModuleHandler).Methods("GET")
+	router.HandleFunc("/api/v1/modules/{namespace}/{module_name}/{version}", PublishModuleVersionHandler).Methods("POST")
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+	publish := func(version, content string) {
+		data, err := artifact.Pack(map[string][]byte{"user/v1/user.proto": []byte(content)})
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, newPublishRequest(t, "acme", "user", version, "", data))
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	}
+	watch := func(ctx context.Context, query string, header http.Header) (*http.Response, *bufio.Reader) {
+		req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/v1/modules/acme/user/watch"+query, nil)
+		assert.NoError(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := srv.Client().Do(req)
+		assert.NoError(t, err)
+		return resp, bufio.NewReader(resp.Body)
+	}
+	next := func(events *bufio.Reader) (string, apitypes.VersionEvent) {
+		var id string
+		var event apitypes.VersionEvent
+		for {
+			line, err := events.ReadString('\n')
+			assert.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				return id, event
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				assert.Equal(t, apitypes.WatchEventVersion, strings.TrimPrefix(line, "event: "))
+			case strings.HasPrefix(line, "data: "):
+				assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+			}
+		}
+	}
+
+	publish("v1.0.0", `syntax = "proto3"; package user.v1; message User { string id = 1; }`)
+	publish("v1.1.0", `syntax = "proto3"; package user.v1; message User { string id = 1; string email = 2; }`)
+
+	// Versions published after since are replayed, with their changes
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resp, events := watch(ctx, "?since=v1.0.0", nil)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	id, event := next(events)
+	assert.Equal(t, "v1.1.0", id)
+	assert.Equal(t, "v1.0.0", event.PreviousVersion)
+	assert.Equal(t, []apitypes.ElementDiff{
+		{Change: apitypes.DiffAdded, Kind: descriptor.ElementField, Element: "user.v1.User.email", File: "user/v1/user.proto", After: "string = 2"},
+	}, event.Changes)
+	assert.Equal(t, "/api/v1/modules/acme/user/v1.1.0/bundle?format=descriptor_set", event.DescriptorSetURL)
+
+	// New versions are pushed by the watcher's next poll, to every stream of the module
+	other, otherEvents := watch(ctx, "", http.Header{"Last-Event-ID": {"v1.1.0"}})
+	defer other.Body.Close()
+	assert.Equal(t, http.StatusOK, other.StatusCode)
+	publish("v1.2.0", `syntax = "proto3"; package user.v1; message User { string email = 2; }`)
+	assert.NoError(t, versionWatch.poll(context.Background()))
+	for _, r := range []*bufio.Reader{events, otherEvents} {
+		id, event = next(r)
+		assert.Equal(t, "v1.2.0", id)
+		assert.Equal(t, "v1.1.0", event.PreviousVersion)
+		assert.Equal(t, []apitypes.ElementDiff{
+			{Change: apitypes.DiffRemoved, Kind: descriptor.ElementField, Element: "user.v1.User.id", File: "user/v1/user.proto", Before: "string = 1"},
+		}, event.Changes)
+	}
+
+	// Streams are unsubscribed when clients disconnect
+	cancel()
+	assert.Eventually(t, func() bool {
+		versionWatch.mu.Lock()
+		defer versionWatch.mu.Unlock()
+		return len(versionWatch.modules) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/modules/acme/billing/watch", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
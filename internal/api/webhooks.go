@@ -0,0 +1,337 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api/response"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/integrity"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/notify"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Webhook subscriptions: besides the registry-wide webhook (WEBHOOK_URL), each namespace can route its
+// events to its team's own endpoints, optionally filtered by event type. Subscriptions are managed by the
+// namespace's maintainers (MAINTAINER_TOKENS) or the admin, so teams control their notifications without
+// asking the registry operators.
+
+// maxWebhookSubscriptions bounds the subscriptions of a namespace.
+const maxWebhookSubscriptions = 20
+
+// maxWebhookRequestBytes limits the JSON body of subscription requests.
+const maxWebhookRequestBytes = 16 * 1024
+
+// WebhookEventTypes are the event types subscriptions can filter on.
+var WebhookEventTypes = []string{
+	notify.EventQuotaWarning,
+	notify.EventQuotaExceeded,
+	notify.EventVersionSunset,
+	notify.EventDevChannelUpdated,
+	integrity.EventDigestMismatch,
+}
+
+// requireMaintainer checks that the caller may manage the subscriptions of a namespace: the admin token
+// or a maintainer token of the namespace. Returns false if the response has been written.
+func requireMaintainer(w http.ResponseWriter, r *http.Request, namespace string) bool {
+	rd := readerFromContext(r.Context())
+	if rd.canMaintain(namespace) {
+		return true
+	}
+	if !rd.token {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="protoreg"`)
+		response.Error(w, http.StatusUnauthorized, "Unauthorized: Managing webhook subscriptions requires the admin token or a maintainer token of the namespace")
+		return false
+	}
+	response.Error(w, http.StatusForbidden, fmt.Sprintf("Forbidden: This token doesn't maintain namespace '%s'", namespace))
+	return false
+}
+
+// ListWebhookSubscriptionsHandler lists the webhook subscriptions of a namespace.
+// GET /api/v1/namespaces/{namespace}/webhooks
+// Requires the admin token or a maintainer token of the namespace.
+func ListWebhookSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	namespace := mux.Vars(r)["namespace"]
+	if !requireMaintainer(w, r, namespace) {
+		return
+	}
+	var subscriptions []models.WebhookSubscription
+	if err := requestDB(r).WithContext(r.Context()).Where("namespace = ?", namespace).Order("created_at, id").Find(&subscriptions).Error; err != nil {
+		logging.FromContext(r.Context()).Error("Error listing webhook subscriptions", zap.String("namespace", namespace), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve webhook subscriptions")
+		return
+	}
+	resp := apitypes.ListWebhookSubscriptionsResponse{Subscriptions: make([]apitypes.WebhookSubscriptionResponse, 0, len(subscriptions)), EventTypes: WebhookEventTypes}
+	for _, s := range subscriptions {
+		resp.Subscriptions = append(resp.Subscriptions, webhookSubscriptionResponse(s))
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, http.StatusOK, resp)
+}
+
+// CreateWebhookSubscriptionHandler subscribes an endpoint to the events of a namespace. The namespace
+// doesn't need to have modules yet.
+// POST /api/v1/namespaces/{namespace}/webhooks
+// Requires the admin token or a maintainer token of the namespace.
+func CreateWebhookSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	namespace := mux.Vars(r)["namespace"]
+	if err := validation.ValidateNamespace(namespace); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !requireMaintainer(w, r, namespace) {
+		return
+	}
+	log := logging.FromContext(r.Context()).With(zap.String("namespace", namespace))
+
+	var req apitypes.WebhookSubscriptionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookRequestBytes)).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if err := validateWebhookSubscriptionRequest(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	createdBy := readerFromContext(r.Context()).consumer
+	if createdBy == "" {
+		createdBy = AdminConsumer // Authentication disabled
+	}
+	subscription := models.WebhookSubscription{
+		Namespace:   namespace,
+		URL:         req.URL,
+		Secret:      req.Secret,
+		EventTypes:  strings.Join(req.EventTypes, "\n"),
+		Description: req.Description,
+		CreatedBy:   createdBy,
+		CreatedAt:   time.Now().UTC(),
+	}
+	errTooMany := errors.New("too many webhook subscriptions")
+	err := db.GetDB().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.WebhookSubscription{}).Where("namespace = ?", namespace).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= maxWebhookSubscriptions {
+			return errTooMany
+		}
+		return tx.Create(&subscription).Error
+	})
+	if errors.Is(err, errTooMany) {
+		response.Error(w, http.StatusConflict, fmt.Sprintf("Namespace '%s' already has %d webhook subscriptions; delete one first", namespace, maxWebhookSubscriptions))
+		return
+	}
+	if err != nil {
+		log.Error("Error creating webhook subscription", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error creating webhook subscription")
+		return
+	}
+	log.Info("Created webhook subscription", zap.Stringer("subscription_id", subscription.ID), zap.String("url", subscription.URL),
+		zap.Strings("event_types", req.EventTypes), zap.String("created_by", createdBy))
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/namespaces/%s/webhooks/%s", namespace, subscription.ID))
+	response.JSON(w, http.StatusCreated, webhookSubscriptionResponse(subscription))
+}
+
+// GetWebhookSubscriptionHandler returns a webhook subscription of a namespace, e.g. to import it into a
+// declarative configuration.
+// GET /api/v1/namespaces/{namespace}/webhooks/{id}
+// Requires the admin token or a maintainer token of the namespace.
+func GetWebhookSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	if !requireMaintainer(w, r, namespace) {
+		return
+	}
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Webhook subscription not found")
+		return
+	}
+	var subscription models.WebhookSubscription
+	err = requestDB(r).WithContext(r.Context()).Where("id = ? AND namespace = ?", id, namespace).First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(w, http.StatusNotFound, "Webhook subscription not found")
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error loading webhook subscription", zap.Stringer("subscription_id", id), zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Failed to retrieve webhook subscription")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, http.StatusOK, webhookSubscriptionResponse(subscription))
+}
+
+// PutWebhookSubscriptionHandler replaces the URL, secret, event types and description of a webhook
+// subscription of a namespace. Sending the same request again changes nothing.
+// PUT /api/v1/namespaces/{namespace}/webhooks/{id}
+// Requires the admin token or a maintainer token of the namespace.
+func PutWebhookSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	if !requireMaintainer(w, r, namespace) {
+		return
+	}
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Webhook subscription not found")
+		return
+	}
+	log := logging.FromContext(r.Context()).With(zap.String("namespace", namespace), zap.Stringer("subscription_id", id))
+
+	var req apitypes.WebhookSubscriptionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookRequestBytes)).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if err := validateWebhookSubscriptionRequest(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var subscription models.WebhookSubscription
+	err = db.GetDB().WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND namespace = ?", id, namespace).First(&subscription).Error; err != nil {
+			return err
+		}
+		subscription.URL = req.URL
+		subscription.Secret = req.Secret
+		subscription.EventTypes = strings.Join(req.EventTypes, "\n")
+		subscription.Description = req.Description
+		return tx.Select("url", "secret", "event_types", "description").Updates(&subscription).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.Error(w, http.StatusNotFound, "Webhook subscription not found")
+		return
+	}
+	if err != nil {
+		log.Error("Error updating webhook subscription", zap.Error(err))
+		response.Error(w, http.StatusInternalServerError, "Database error updating webhook subscription")
+		return
+	}
+	log.Info("Updated webhook subscription", zap.String("url", subscription.URL), zap.Strings("event_types", req.EventTypes))
+	response.JSON(w, http.StatusOK, webhookSubscriptionResponse(subscription))
+}
+
+// DeleteWebhookSubscriptionHandler removes a webhook subscription of a namespace.
+// DELETE /api/v1/namespaces/{namespace}/webhooks/{id}
+// Requires the admin token or a maintainer token of the namespace.
+func DeleteWebhookSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace := vars["namespace"]
+	if !requireMaintainer(w, r, namespace) {
+		return
+	}
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		response.Error(w, http.StatusNotFound, "Webhook subscription not found")
+		return
+	}
+	// The namespace condition keeps maintainers to their own namespaces' subscriptions
+	result := db.GetDB().WithContext(r.Context()).Where("id = ? AND namespace = ?", id, namespace).Delete(&models.WebhookSubscription{})
+	if result.Error != nil {
+		logging.FromContext(r.Context()).Error("Error deleting webhook subscription", zap.Stringer("subscription_id", id), zap.Error(result.Error))
+		response.Error(w, http.StatusInternalServerError, "Database error deleting webhook subscription")
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.Error(w, http.StatusNotFound, "Webhook subscription not found")
+		return
+	}
+	logging.FromContext(r.Context()).Info("Deleted webhook subscription", zap.String("namespace", namespace), zap.Stringer("subscription_id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateWebhookSubscriptionRequest checks the URL and the event types, and drops blank and duplicate
+// event types. Hostnames resolving to private addresses are refused when delivering (see notify).
+func validateWebhookSubscriptionRequest(req *apitypes.WebhookSubscriptionRequest) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q: expected an http or https URL", req.URL)
+	}
+	if !notify.AllowedTargetHost(u.Hostname()) {
+		return fmt.Errorf("invalid webhook URL %q: loopback, link-local and private addresses are not allowed", req.URL)
+	}
+	if len(req.Secret) > 255 {
+		return errors.New("webhook secret is too long: at most 255 characters")
+	}
+	eventTypes := make([]string, 0, len(req.EventTypes))
+	seen := map[string]bool{}
+	for _, pattern := range req.EventTypes {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || seen[pattern] {
+			continue
+		}
+		if !matchesEventType(pattern) {
+			return fmt.Errorf("invalid event type %q: matches none of %s", pattern, strings.Join(WebhookEventTypes, ", "))
+		}
+		seen[pattern] = true
+		eventTypes = append(eventTypes, pattern)
+	}
+	req.EventTypes = eventTypes
+	return nil
+}
+
+// matchesEventType reports whether an event type pattern is valid and matches a known event type.
+func matchesEventType(pattern string) bool {
+	for _, eventType := range WebhookEventTypes {
+		if ok, err := path.Match(pattern, eventType); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func webhookSubscriptionResponse(s models.WebhookSubscription) apitypes.WebhookSubscriptionResponse {
+	resp := apitypes.WebhookSubscriptionResponse{
+		ID:          s.ID,
+		Namespace:   s.Namespace,
+		URL:         s.URL,
+		EventTypes:  []string{}, // Empty array, not null
+		Description: s.Description,
+		HasSecret:   s.Secret != "",
+		CreatedBy:   s.CreatedBy,
+		CreatedAt:   s.CreatedAt,
+	}
+	if s.EventTypes != "" {
+		resp.EventTypes = strings.Split(s.EventTypes, "\n")
+	}
+	return resp
+}
+
+// FindWebhookSubscriptions returns the webhook subscriptions of a namespace for notify.Send (see
+// notify.SetSubscriptionFinder).
+func FindWebhookSubscriptions(ctx context.Context, namespace string) ([]notify.Subscription, error) {
+	gormDB := db.GetDB()
+	if gormDB == nil {
+		return nil, nil
+	}
+	var rows []models.WebhookSubscription
+	if err := gormDB.WithContext(ctx).Where("namespace = ?", namespace).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	subscriptions := make([]notify.Subscription, 0, len(rows))
+	for _, row := range rows {
+		s := notify.Subscription{URL: row.URL, Secret: row.Secret}
+		if row.EventTypes != "" {
+			s.EventTypes = strings.Split(row.EventTypes, "\n")
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, nil
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/notify"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSubscriptions(t *testing.T) {
+	newTestDB(t)
+	tokens, err := ParseMaintainerTokens("pay-token#payments-team=payments")
+	assert.NoError(t, err)
+	tokens["ci-token"] = ReadToken{}
+	SetReadTokens(tokens)
+	t.Cleanup(func() { SetReadTokens(nil) })
+
+	router := mux.NewRouter()
+	router.Use(ReadAuthMiddleware("admin-token"))
+	router.HandleFunc("/api/v1/namespaces/{namespace}/webhooks", ListWebhookSubscriptionsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/namespaces/{namespace}/webhooks", CreateWebhookSubscriptionHandler).Methods("POST")
+	router.HandleFunc("/api/v1/namespaces/{namespace}/webhooks/{id}", GetWebhookSubscriptionHandler).Methods("GET")
+	router.HandleFunc("/api/v1/namespaces/{namespace}/webhooks/{id}", PutWebhookSubscriptionHandler).Methods("PUT")
+	router.HandleFunc("/api/v1/namespaces/{namespace}/webhooks/{id}", DeleteWebhookSubscriptionHandler).Methods("DELETE")
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Only the admin and the namespace's maintainers manage its subscriptions
+	body := `{"url":"https://hooks.example.com/payments","secret":"s3cret","event_types":["module_version.*"," ","module_version.*"],"description":"Sunsets"}`
+	assert.Equal(t, http.StatusUnauthorized, serve("POST", "/api/v1/namespaces/payments/webhooks", "", body).Code)
+	assert.Equal(t, http.StatusForbidden, serve("POST", "/api/v1/namespaces/payments/webhooks", "ci-token", body).Code)
+	assert.Equal(t, http.StatusForbidden, serve("POST", "/api/v1/namespaces/billing/webhooks", "pay-token", body).Code)
+	assert.Equal(t, http.StatusBadRequest, serve("POST", "/api/v1/namespaces/payments/webhooks", "pay-token", `{"url":"ftp://example.com"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve("POST", "/api/v1/namespaces/payments/webhooks", "pay-token", `{"url":"https://example.com","event_types":["module.published"]}`).Code)
+	for _, target := range []string{"http://127.0.0.1:8080/hook", "http://169.254.169.254/latest/meta-data", "https://[::1]/hook", "http://10.1.2.3/hook"} {
+		rr := serve("POST", "/api/v1/namespaces/payments/webhooks", "pay-token", `{"url":"`+target+`"}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+		assert.Contains(t, rr.Body.String(), "private addresses are not allowed")
+	}
+
+	rr := serve("POST", "/api/v1/namespaces/payments/webhooks", "pay-token", body)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created apitypes.WebhookSubscriptionResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "/api/v1/namespaces/payments/webhooks/"+created.ID.String(), rr.Header().Get("Location"))
+	assert.Equal(t, []string{"module_version.*"}, created.EventTypes)
+	assert.True(t, created.HasSecret)
+	assert.Equal(t, "payments-team", created.CreatedBy)
+	assert.NotContains(t, rr.Body.String(), "s3cret")
+	assert.Equal(t, http.StatusCreated, serve("POST", "/api/v1/namespaces/billing/webhooks", "admin-token", `{"url":"http://billing.internal/hook"}`).Code)
+
+	rr = serve("GET", "/api/v1/namespaces/payments/webhooks", "pay-token", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list apitypes.ListWebhookSubscriptionsResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	assert.Len(t, list.Subscriptions, 1)
+	assert.Contains(t, list.EventTypes, notify.EventVersionSunset)
+	assert.Equal(t, http.StatusForbidden, serve("GET", "/api/v1/namespaces/billing/webhooks", "pay-token", "").Code)
+
+	// Events of the namespace are routed to its subscriptions that want them
+	subscriptions, err := FindWebhookSubscriptions(context.Background(), "payments")
+	assert.NoError(t, err)
+	assert.Equal(t, []notify.Subscription{{URL: "https://hooks.example.com/payments", Secret: "s3cret", EventTypes: []string{"module_version.*"}}}, subscriptions)
+	assert.True(t, subscriptions[0].Wants(notify.EventVersionSunset))
+	assert.False(t, subscriptions[0].Wants(notify.EventQuotaWarning))
+
+	// A subscription can be read back by ID and replaced as a whole, e.g. by a declarative tool
+	subscriptionPath := "/api/v1/namespaces/payments/webhooks/" + created.ID.String()
+	rr = serve("GET", subscriptionPath, "pay-token", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var fetched apitypes.WebhookSubscriptionResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &fetched))
+	assert.Equal(t, created.ID, fetched.ID)
+	assert.Equal(t, created.EventTypes, fetched.EventTypes)
+	assert.True(t, fetched.HasSecret)
+	assert.Equal(t, http.StatusNotFound, serve("GET", "/api/v1/namespaces/payments/webhooks/not-a-uuid", "pay-token", "").Code)
+	replacement := `{"url":"https://hooks.example.com/v2","event_types":["module.quota_*"]}`
+	assert.Equal(t, http.StatusForbidden, serve("PUT", "/api/v1/namespaces/billing/webhooks/"+created.ID.String(), "pay-token", replacement).Code)
+	assert.Equal(t, http.StatusBadRequest, serve("PUT", subscriptionPath, "pay-token", `{"url":"mailto:team@example.com"}`).Code)
+	for i := 0; i < 2; i++ { // Idempotent
+		rr = serve("PUT", subscriptionPath, "pay-token", replacement)
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var updated apitypes.WebhookSubscriptionResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &updated))
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, "https://hooks.example.com/v2", updated.URL)
+	assert.Equal(t, []string{"module.quota_*"}, updated.EventTypes)
+	assert.False(t, updated.HasSecret) // Omitted, so removed
+	assert.Empty(t, updated.Description)
+	assert.Equal(t, "payments-team", updated.CreatedBy)
+	subscriptions, err = FindWebhookSubscriptions(context.Background(), "payments")
+	assert.NoError(t, err)
+	assert.Equal(t, []notify.Subscription{{URL: "https://hooks.example.com/v2", EventTypes: []string{"module.quota_*"}}}, subscriptions)
+
+	// Maintainers can't delete other namespaces' subscriptions, even by ID
+	assert.Equal(t, http.StatusNotFound, serve("DELETE", "/api/v1/namespaces/payments/webhooks/"+uuid.NewString(), "pay-token", "").Code)
+	assert.Equal(t, http.StatusForbidden, serve("DELETE", "/api/v1/namespaces/billing/webhooks/"+created.ID.String(), "pay-token", "").Code)
+	assert.Equal(t, http.StatusNoContent, serve("DELETE", "/api/v1/namespaces/payments/webhooks/"+created.ID.String(), "pay-token", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("DELETE", "/api/v1/namespaces/payments/webhooks/"+created.ID.String(), "pay-token", "").Code)
+}
+
+func TestWebhookSubscriptionWritesRestrictedToAllowlist(t *testing.T) {
+	gormDB := newTestDB(t)
+	tokens, err := ParseMaintainerTokens("pay-token#payments-team=payments")
+	assert.NoError(t, err)
+	SetReadTokens(tokens)
+	t.Cleanup(func() { SetReadTokens(nil) })
+	SetIPFilterPolicy(IPFilterPolicy{WriteAllowed: []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")}})
+	t.Cleanup(func() { SetIPFilterPolicy(IPFilterPolicy{}) })
+
+	router := mux.NewRouter()
+	RegisterRoutes(router, "admin-token")
+	serve := func(method, path, remoteAddr, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	body := `{"url":"https://hooks.example.com/payments"}`
+	subscriptionPath := "/api/v1/namespaces/payments/webhooks/" + uuid.NewString()
+
+	// Maintainer tokens change subscriptions from the allowlist only, like the admin token
+	for _, token := range []string{"pay-token", "admin-token"} {
+		rr := serve("POST", "/api/v1/namespaces/payments/webhooks", "203.0.113.50:1111", token, body)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.JSONEq(t, `{"error":"Forbidden: Requests from this address are not allowed"}`, rr.Body.String())
+		assert.Equal(t, http.StatusForbidden, serve("PUT", subscriptionPath, "203.0.113.50:1111", token, body).Code)
+		assert.Equal(t, http.StatusForbidden, serve("DELETE", subscriptionPath, "203.0.113.50:1111", token, "").Code)
+	}
+	var count int64
+	assert.NoError(t, gormDB.Model(&models.WebhookSubscription{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	assert.Equal(t, http.StatusCreated, serve("POST", "/api/v1/namespaces/payments/webhooks", "10.20.3.4:1111", "pay-token", body).Code)
+	assert.Equal(t, http.StatusNotFound, serve("PUT", subscriptionPath, "10.20.3.4:1111", "pay-token", body).Code)
+	assert.Equal(t, http.StatusNotFound, serve("DELETE", subscriptionPath, "10.20.3.4:1111", "pay-token", "").Code)
+
+	// Reads aren't restricted
+	assert.Equal(t, http.StatusOK, serve("GET", "/api/v1/namespaces/payments/webhooks", "203.0.113.50:1111", "pay-token", "").Code)
+}
@@ -0,0 +1,135 @@
+// Package artifact re-packs module artifacts (zip archives) into a canonical form.
+//
+// Two clients zipping the same files rarely produce the same bytes: entry order, timestamps,
+// permissions, directory entries, extra fields and the compression level all vary between zip tools.
+// The registry digests and stores the canonical archive instead, so the digest only depends on the
+// file names and contents. The canonical form is:
+//
+//   - one entry per file, sorted by name (forward slashes); directory entries are dropped
+//   - every entry deflated at CompressionLevel, modified at ModTime, with mode 0644
+//   - no archive or entry comments, no extra fields besides the timestamp written by archive/zip
+//
+// Canonicalizing a canonical archive returns it unchanged.
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CompressionLevel is the deflate level of every entry in a canonical archive.
+const CompressionLevel = flate.BestCompression
+
+// ModTime is the modification time of every entry in a canonical archive (the earliest time the
+// MS-DOS format can represent).
+var ModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// MaxUncompressedSize caps the total size of the files in an archive, so a small upload can't expand
+// into an unbounded amount of work (zip bomb).
+const MaxUncompressedSize = 1 << 30 // 1 GB
+
+// ErrNotZip is returned for data that is not a zip archive at all.
+var ErrNotZip = errors.New("artifact is not a zip archive")
+
+// ErrInvalidArchive is returned for zip archives whose entries can't be re-packed (corrupt data,
+// duplicate file names, too large).
+var ErrInvalidArchive = errors.New("invalid artifact archive")
+
+// Canonicalize returns the canonical form of the zip archive data.
+func Canonicalize(data []byte) ([]byte, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotZip, err)
+	}
+
+	// Collect the files under their normalized names
+	files := make(map[string]*zip.File, len(zipReader.File))
+	names := make([]string, 0, len(zipReader.File))
+	for _, f := range zipReader.File {
+		if f.FileInfo().IsDir() {
+			continue // Implied by the file names
+		}
+		name := strings.TrimPrefix(strings.ReplaceAll(f.Name, `\`, "/"), "./")
+		if _, dup := files[name]; dup {
+			return nil, fmt.Errorf("%w: duplicate file %q", ErrInvalidArchive, name)
+		}
+		files[name] = f
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := new(bytes.Buffer)
+	zipWriter := newWriter(buf)
+	var total int64
+	for _, name := range names {
+		w, err := createEntry(zipWriter, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to canonical archive: %w", name, err)
+		}
+		rc, err := files[name].Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to open %s: %v", ErrInvalidArchive, name, err)
+		}
+		// Read one byte past the remaining budget to detect archives that exceed it
+		n, err := io.Copy(w, io.LimitReader(rc, MaxUncompressedSize-total+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read %s: %v", ErrInvalidArchive, name, err)
+		}
+		if total += n; total > MaxUncompressedSize {
+			return nil, fmt.Errorf("%w: uncompressed size exceeds %d bytes", ErrInvalidArchive, int64(MaxUncompressedSize))
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish canonical archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Pack writes files (keyed by slash-separated path) into an archive in canonical form.
+func Pack(files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := new(bytes.Buffer)
+	zipWriter := newWriter(buf)
+	for _, name := range names {
+		w, err := createEntry(zipWriter, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to archive: %w", name, err)
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, fmt.Errorf("failed to write %s to archive: %w", name, err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// newWriter returns a zip writer deflating at CompressionLevel.
+func newWriter(out io.Writer) *zip.Writer {
+	zipWriter := zip.NewWriter(out)
+	zipWriter.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, CompressionLevel)
+	})
+	return zipWriter
+}
+
+// createEntry adds a file entry with the canonical method, modification time and mode.
+func createEntry(zipWriter *zip.Writer, name string) (io.Writer, error) {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: ModTime}
+	header.SetMode(0644)
+	return zipWriter.CreateHeader(header)
+}
@@ -0,0 +1,103 @@
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEntry is a zip entry written by buildZip.
+type testEntry struct {
+	name    string
+	content string
+	method  uint16
+	mod     time.Time
+}
+
+// buildZip writes entries in the given order with the given metadata, as differing zip tools would.
+func buildZip(t *testing.T, level int, comment string, entries ...testEntry) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, level)
+	})
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: e.method, Modified: e.mod}
+		w, err := zw.CreateHeader(header)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, e.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.SetComment(comment))
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestCanonicalize_IndependentOfZipTool(t *testing.T) {
+	now := time.Now()
+	a := buildZip(t, flate.BestSpeed, "",
+		testEntry{name: "user/v1/user.proto", content: "message User {}", method: zip.Deflate, mod: now},
+		testEntry{name: "README.md", content: "# user", method: zip.Deflate, mod: now},
+	)
+	b := buildZip(t, flate.NoCompression, "built by another tool",
+		testEntry{name: "user/", method: zip.Store, mod: now.Add(-time.Hour)},
+		testEntry{name: "./README.md", content: "# user", method: zip.Store, mod: now.Add(-time.Hour)},
+		testEntry{name: `user\v1\user.proto`, content: "message User {}", method: zip.Store},
+	)
+	require.NotEqual(t, a, b)
+
+	canonicalA, err := Canonicalize(a)
+	require.NoError(t, err)
+	canonicalB, err := Canonicalize(b)
+	require.NoError(t, err)
+	assert.Equal(t, canonicalA, canonicalB)
+
+	// Canonical archives are left unchanged
+	again, err := Canonicalize(canonicalA)
+	require.NoError(t, err)
+	assert.Equal(t, canonicalA, again)
+
+	// Sorted file entries with fixed metadata
+	zr, err := zip.NewReader(bytes.NewReader(canonicalA), int64(len(canonicalA)))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 2)
+	assert.Equal(t, "README.md", zr.File[0].Name)
+	assert.Equal(t, "user/v1/user.proto", zr.File[1].Name)
+	for _, f := range zr.File {
+		assert.Equal(t, zip.Deflate, f.Method)
+		assert.True(t, f.Modified.Equal(ModTime), "modified %s", f.Modified)
+		assert.Equal(t, "-rw-r--r--", f.Mode().String())
+	}
+	rc, err := zr.File[1].Open()
+	require.NoError(t, err)
+	content, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "message User {}", string(content))
+}
+
+func TestCanonicalize_Errors(t *testing.T) {
+	_, err := Canonicalize([]byte("fake zip content"))
+	assert.ErrorIs(t, err, ErrNotZip)
+
+	dup := buildZip(t, flate.DefaultCompression, "",
+		testEntry{name: "a.proto", content: "one", method: zip.Deflate},
+		testEntry{name: "./a.proto", content: "two", method: zip.Deflate},
+	)
+	_, err = Canonicalize(dup)
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+
+	// Corrupt entry data (CRC mismatch)
+	corrupt := buildZip(t, flate.DefaultCompression, "", testEntry{name: "a.proto", content: "syntax = \"proto3\";", method: zip.Store})
+	i := bytes.Index(corrupt, []byte("proto3"))
+	require.Positive(t, i)
+	corrupt[i] = 'X'
+	_, err = Canonicalize(corrupt)
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+}
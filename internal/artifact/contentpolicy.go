@@ -0,0 +1,129 @@
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+)
+
+// ContentTypeZip is the content type of zip archives.
+const ContentTypeZip = "application/zip"
+
+// Rules of content policy violations.
+const (
+	RuleContentType = "content-type"
+	RuleFileSize    = "max-file-size"
+	RuleExtension   = "allowed-extensions"
+)
+
+// ContentPolicy restricts what an uploaded artifact may be and contain. Every publish path (uploads,
+// imports, republishes, dev channels) checks artifacts against the same policy: the server's, with the
+// settings of the namespace policy, if any, in place of the server's.
+type ContentPolicy struct {
+	// ContentTypes the artifact may have, as detected from its content (application/zip for zip archives,
+	// e.g. text/plain otherwise); empty allows any
+	ContentTypes []string
+	// MaxFileBytes is the largest uncompressed file an archive may contain; 0 allows any size
+	MaxFileBytes int64
+	// Extensions the files of an archive may have (".proto"); empty allows any. The manifest (sproto.yaml)
+	// is always allowed.
+	Extensions []string
+}
+
+// ContentViolation is a way an artifact breaks a content policy.
+type ContentViolation struct {
+	Rule    string // RuleContentType, RuleFileSize or RuleExtension
+	Message string
+}
+
+// NewContentPolicy validates and normalizes the settings of a content policy: content types are lowercase
+// media types without parameters, extensions lowercase with a leading dot. Blank and duplicate entries are
+// dropped.
+func NewContentPolicy(contentTypes, extensions []string, maxFileBytes int64) (ContentPolicy, error) {
+	if maxFileBytes < 0 {
+		return ContentPolicy{}, fmt.Errorf("invalid maximum file size %d: must not be negative", maxFileBytes)
+	}
+	p := ContentPolicy{MaxFileBytes: maxFileBytes}
+	for _, contentType := range contentTypes {
+		contentType = strings.TrimSpace(contentType)
+		if contentType == "" {
+			continue
+		}
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !strings.Contains(mediaType, "/") {
+			return ContentPolicy{}, fmt.Errorf("invalid content type %q: must be a media type such as %s", contentType, ContentTypeZip)
+		}
+		if !slices.Contains(p.ContentTypes, mediaType) {
+			p.ContentTypes = append(p.ContentTypes, mediaType)
+		}
+	}
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if ext == "." || strings.ContainsAny(ext[1:], `./\* `) {
+			return ContentPolicy{}, fmt.Errorf("invalid file extension %q: must be a single extension such as .proto", ext)
+		}
+		if !slices.Contains(p.Extensions, ext) {
+			p.Extensions = append(p.Extensions, ext)
+		}
+	}
+	return p, nil
+}
+
+// Override returns the policy with the settings of o that are set in place of its own.
+func (p ContentPolicy) Override(o ContentPolicy) ContentPolicy {
+	if len(o.ContentTypes) > 0 {
+		p.ContentTypes = o.ContentTypes
+	}
+	if o.MaxFileBytes > 0 {
+		p.MaxFileBytes = o.MaxFileBytes
+	}
+	if len(o.Extensions) > 0 {
+		p.Extensions = o.Extensions
+	}
+	return p
+}
+
+// Check returns the ways the artifact data breaks the policy, in file order. The file size and extension
+// rules only apply to zip archives.
+func (p ContentPolicy) Check(data []byte) []ContentViolation {
+	var violations []ContentViolation
+	zipReader, zipErr := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if len(p.ContentTypes) > 0 {
+		contentType := ContentTypeZip
+		if zipErr != nil {
+			contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+		}
+		if !slices.Contains(p.ContentTypes, contentType) {
+			violations = append(violations, ContentViolation{Rule: RuleContentType, Message: fmt.Sprintf("artifact content type %s is not allowed (allowed: %s)", contentType, strings.Join(p.ContentTypes, ", "))})
+		}
+	}
+	if zipErr != nil {
+		return violations
+	}
+	for _, f := range zipReader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name := strings.TrimPrefix(strings.ReplaceAll(f.Name, `\`, "/"), "./")
+		if p.MaxFileBytes > 0 && f.UncompressedSize64 > uint64(p.MaxFileBytes) {
+			violations = append(violations, ContentViolation{Rule: RuleFileSize, Message: fmt.Sprintf("file %s is %d bytes, larger than the limit of %d bytes", name, f.UncompressedSize64, p.MaxFileBytes)})
+		}
+		if len(p.Extensions) > 0 && name != manifest.ManifestFileName && !slices.Contains(p.Extensions, strings.ToLower(path.Ext(name))) {
+			violations = append(violations, ContentViolation{Rule: RuleExtension, Message: fmt.Sprintf("file %s has none of the allowed extensions (%s)", name, strings.Join(p.Extensions, ", "))})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,52 @@
+package artifact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewContentPolicy(t *testing.T) {
+	p, err := NewContentPolicy([]string{" Application/Zip ", "", "application/zip; charset=binary"}, []string{"proto", ".PROTO", " .md"}, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, ContentPolicy{ContentTypes: []string{"application/zip"}, MaxFileBytes: 1024, Extensions: []string{".proto", ".md"}}, p)
+
+	_, err = NewContentPolicy([]string{"zip"}, nil, 0)
+	assert.ErrorContains(t, err, "invalid content type")
+	_, err = NewContentPolicy(nil, []string{"*.proto"}, 0)
+	assert.ErrorContains(t, err, "invalid file extension")
+	_, err = NewContentPolicy(nil, []string{"tar.gz"}, 0)
+	assert.ErrorContains(t, err, "invalid file extension")
+	_, err = NewContentPolicy(nil, nil, -1)
+	assert.Error(t, err)
+
+	// Set namespace settings replace the server's
+	server := ContentPolicy{ContentTypes: []string{"application/zip"}, MaxFileBytes: 1024}
+	assert.Equal(t, ContentPolicy{ContentTypes: []string{"application/zip"}, MaxFileBytes: 64, Extensions: []string{".proto"}},
+		server.Override(ContentPolicy{MaxFileBytes: 64, Extensions: []string{".proto"}}))
+}
+
+func TestContentPolicyCheck(t *testing.T) {
+	archive, err := Pack(map[string][]byte{
+		"sproto.yaml":        []byte("name: acme/user"),
+		"user/v1/user.proto": []byte("syntax = \"proto3\";"),
+		"docs/diagram.PNG":   make([]byte, 2048),
+		"LICENSE":            []byte("MIT"),
+	})
+	require.NoError(t, err)
+
+	assert.Empty(t, ContentPolicy{}.Check(archive))
+
+	violations := ContentPolicy{ContentTypes: []string{ContentTypeZip}, MaxFileBytes: 1024, Extensions: []string{".proto"}}.Check(archive)
+	assert.Equal(t, []ContentViolation{
+		{Rule: RuleExtension, Message: "file LICENSE has none of the allowed extensions (.proto)"},
+		{Rule: RuleFileSize, Message: "file docs/diagram.PNG is 2048 bytes, larger than the limit of 1024 bytes"},
+		{Rule: RuleExtension, Message: "file docs/diagram.PNG has none of the allowed extensions (.proto)"},
+	}, violations)
+
+	// Uploads that aren't archives only have a content type
+	violations = ContentPolicy{ContentTypes: []string{ContentTypeZip}, Extensions: []string{".proto"}}.Check([]byte("syntax = \"proto3\";"))
+	assert.Equal(t, []ContentViolation{{Rule: RuleContentType, Message: "artifact content type text/plain is not allowed (allowed: application/zip)"}}, violations)
+	assert.Empty(t, ContentPolicy{ContentTypes: []string{"text/plain"}}.Check([]byte("syntax = \"proto3\";")))
+}
@@ -0,0 +1,52 @@
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// FileDigest is the SHA256 of one file of an archive.
+type FileDigest struct {
+	Name   string // Slash-separated path relative to the archive root
+	Size   int64  // Uncompressed size in bytes
+	SHA256 string // Hex SHA256 of the file's content
+}
+
+// FileDigests returns the SHA256 of every file of the archive data, by name. Clients holding an extracted
+// copy of another version compare them with their files to only download the ones that changed.
+func FileDigests(data []byte) ([]FileDigest, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotZip, err)
+	}
+
+	digests := make([]FileDigest, 0, len(zipReader.File))
+	var total int64
+	for _, f := range zipReader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to open %s: %v", ErrInvalidArchive, f.Name, err)
+		}
+		hash := sha256.New()
+		// Read one byte past the remaining budget to detect archives that exceed it
+		n, err := io.Copy(hash, io.LimitReader(rc, MaxUncompressedSize-total+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read %s: %v", ErrInvalidArchive, f.Name, err)
+		}
+		if total += n; total > MaxUncompressedSize {
+			return nil, fmt.Errorf("%w: uncompressed size exceeds %d bytes", ErrInvalidArchive, int64(MaxUncompressedSize))
+		}
+		digests = append(digests, FileDigest{Name: f.Name, Size: n, SHA256: hex.EncodeToString(hash.Sum(nil))})
+	}
+	sort.Slice(digests, func(i, j int) bool { return digests[i].Name < digests[j].Name })
+	return digests, nil
+}
@@ -0,0 +1,34 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileDigests(t *testing.T) {
+	canonical, err := Pack(map[string][]byte{
+		"common/types.proto": []byte("types"),
+		"README.md":          []byte("readme"),
+		"billing/v1.proto":   []byte(""),
+	})
+	require.NoError(t, err)
+
+	digests, err := FileDigests(canonical)
+	require.NoError(t, err)
+	sum := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+	assert.Equal(t, []FileDigest{
+		{Name: "README.md", Size: 6, SHA256: sum("readme")},
+		{Name: "billing/v1.proto", Size: 0, SHA256: sum("")},
+		{Name: "common/types.proto", Size: 5, SHA256: sum("types")},
+	}, digests)
+
+	_, err = FileDigests([]byte("not a zip"))
+	assert.ErrorIs(t, err, ErrNotZip)
+}
@@ -0,0 +1,78 @@
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrNoMatch is returned by Subset when no file of the archive matches the paths.
+var ErrNoMatch = errors.New("no file matches the paths")
+
+// ParsePaths parses a comma-separated path filter ("billing/,common/types.proto"). Each path is a file
+// or, with a trailing slash or when files exist below it, a directory, relative to the archive root.
+// Empty filters, absolute paths and paths leaving the root are rejected.
+func ParsePaths(s string) ([]string, error) {
+	var paths []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if strings.HasPrefix(p, "/") || strings.Contains(p, `\`) {
+			return nil, fmt.Errorf("invalid path %q: must be relative, with forward slashes", p)
+		}
+		cleaned := path.Clean(p)
+		if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			return nil, fmt.Errorf("invalid path %q: must be inside the module", p)
+		}
+		paths = append(paths, cleaned)
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("no paths given")
+	}
+	return paths, nil
+}
+
+// MatchesPaths reports whether the file name is one of paths (as returned by ParsePaths) or below one of them.
+func MatchesPaths(name string, paths []string) bool {
+	for _, p := range paths {
+		if name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Subset returns an archive holding only the files of the canonical archive data that match paths, and
+// their number. Entries are copied without recompressing, so the subset of a canonical archive is
+// canonical too (and deterministic: the same paths always give the same bytes).
+func Subset(data []byte, paths []string) ([]byte, int, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrNotZip, err)
+	}
+
+	buf := new(bytes.Buffer)
+	zipWriter := newWriter(buf)
+	count := 0
+	for _, f := range zipReader.File {
+		if f.FileInfo().IsDir() || !MatchesPaths(f.Name, paths) {
+			continue
+		}
+		if err := zipWriter.Copy(f); err != nil {
+			return nil, 0, fmt.Errorf("failed to copy %s to archive: %w", f.Name, err)
+		}
+		count++
+	}
+	if count == 0 {
+		return nil, 0, ErrNoMatch
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, 0, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return buf.Bytes(), count, nil
+}
@@ -0,0 +1,64 @@
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePaths(t *testing.T) {
+	paths, err := ParsePaths(" billing/ ,common/types.proto,,./docs")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"billing", "common/types.proto", "docs"}, paths)
+
+	for _, invalid := range []string{"", " , ", "/etc/passwd", "../secret", "a/../../b", ".", `billing\v1`} {
+		_, err := ParsePaths(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSubset(t *testing.T) {
+	canonical, err := Pack(map[string][]byte{
+		"billing/v1/invoice.proto": []byte("invoice"),
+		"billing/v1/payment.proto": []byte("payment"),
+		"billingx/other.proto":     []byte("other"),
+		"common/types.proto":       []byte("types"),
+		"common/money.proto":       []byte("money"),
+		"README.md":                []byte("readme"),
+	})
+	require.NoError(t, err)
+
+	paths, err := ParsePaths("billing/,common/types.proto")
+	require.NoError(t, err)
+	subset, count, err := Subset(canonical, paths)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	zipReader, err := zip.NewReader(bytes.NewReader(subset), int64(len(subset)))
+	require.NoError(t, err)
+	names := []string{}
+	for _, f := range zipReader.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"billing/v1/invoice.proto", "billing/v1/payment.proto", "common/types.proto"}, names)
+
+	// The subset is canonical: packing the same files gives the same bytes
+	packed, err := Pack(map[string][]byte{
+		"billing/v1/invoice.proto": []byte("invoice"),
+		"billing/v1/payment.proto": []byte("payment"),
+		"common/types.proto":       []byte("types"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, packed, subset)
+	canonicalSubset, err := Canonicalize(subset)
+	require.NoError(t, err)
+	assert.Equal(t, subset, canonicalSubset)
+
+	_, _, err = Subset(canonical, []string{"missing"})
+	assert.ErrorIs(t, err, ErrNoMatch)
+	_, _, err = Subset([]byte("not a zip"), paths)
+	assert.ErrorIs(t, err, ErrNotZip)
+}
@@ -0,0 +1,347 @@
+// Package bufimport imports the versions of a module hosted on a buf registry (buf.build or a private
+// BSR) into SProto. Each semver label of the buf module becomes a module version with the files of the
+// labeled commit, published oldest first so the registry's version order matches the buf module's.
+//
+// The registry is read through its Connect API (buf.registry.module.v1, JSON over HTTP POST). Both
+// `protoreg-cli import-buf` and `sproto-server import-buf` use it; they differ in how versions are
+// published (see Publisher).
+package bufimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"go.uber.org/zap"
+)
+
+// ModuleRef identifies a module on a buf registry, e.g. buf.build/acme/petapis.
+type ModuleRef struct {
+	Remote string // Registry host, e.g. "buf.build"
+	Owner  string // User or organization
+	Module string
+}
+
+// ParseModuleRef parses <remote>/<owner>/<module>.
+func ParseModuleRef(value string) (ModuleRef, error) {
+	parts := strings.Split(strings.TrimPrefix(value, "https://"), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return ModuleRef{}, fmt.Errorf("invalid buf module %q: expected <remote>/<owner>/<module>, e.g. buf.build/acme/petapis", value)
+	}
+	return ModuleRef{Remote: parts[0], Owner: parts[1], Module: parts[2]}, nil
+}
+
+func (r ModuleRef) String() string {
+	return r.Remote + "/" + r.Owner + "/" + r.Module
+}
+
+// --- Registry Client ---
+
+// Error is an error response of the buf registry.
+type Error struct {
+	StatusCode int    // HTTP status
+	Code       string // Connect error code, e.g. "not_found", "unauthenticated"
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("buf registry returned status %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("buf registry returned %s: %s", e.Code, e.Message)
+}
+
+// Client calls the Connect API of a buf registry.
+type Client struct {
+	BaseURL    string // E.g. "https://buf.build"
+	Token      string // Buf API token (BUF_TOKEN); required for private modules only
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the registry hosting ref.
+func NewClient(ref ModuleRef, token string) *Client {
+	return &Client{BaseURL: "https://" + ref.Remote, Token: token, HTTPClient: &http.Client{Timeout: 2 * time.Minute}}
+}
+
+// call invokes a unary Connect procedure with a JSON request and decodes the JSON response into out.
+func (c *Client) call(ctx context.Context, procedure string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.BaseURL, "/")+"/"+procedure, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connect-Protocol-Version", "1")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Connect errors are {"code": "...", "message": "..."}
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return apiErr
+	}
+	return json.Unmarshal(data, out)
+}
+
+// resourceRef is a buf.registry.module.v1.ResourceRef selecting a module, or a label/commit of it.
+type resourceRef struct {
+	Name resourceRefName `json:"name"`
+}
+
+type resourceRefName struct {
+	Owner  string `json:"owner"`
+	Module string `json:"module"`
+	Ref    string `json:"ref,omitempty"` // Label name or commit ID
+}
+
+// Label is a label (tag) of a buf module pointing at a commit.
+type Label struct {
+	Name       string    `json:"name"`
+	CommitID   string    `json:"commitId"`
+	CreateTime time.Time `json:"createTime"`
+}
+
+// ListLabels returns the (unarchived) labels of a module.
+func (c *Client) ListLabels(ctx context.Context, ref ModuleRef) ([]Label, error) {
+	type listLabelsRequest struct {
+		PageSize    int         `json:"pageSize"`
+		PageToken   string      `json:"pageToken,omitempty"`
+		ResourceRef resourceRef `json:"resourceRef"`
+	}
+	type listLabelsResponse struct {
+		NextPageToken string  `json:"nextPageToken"`
+		Labels        []Label `json:"labels"`
+	}
+
+	var labels []Label
+	pageToken := ""
+	for {
+		req := listLabelsRequest{PageSize: 250, PageToken: pageToken, ResourceRef: resourceRef{Name: resourceRefName{Owner: ref.Owner, Module: ref.Module}}}
+		var resp listLabelsResponse
+		if err := c.call(ctx, "buf.registry.module.v1.LabelService/ListLabels", req, &resp); err != nil {
+			return nil, fmt.Errorf("failed to list labels of %s: %w", ref, err)
+		}
+		labels = append(labels, resp.Labels...)
+		if resp.NextPageToken == "" {
+			return labels, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// Commit is the commit a label points at.
+type Commit struct {
+	ID         string    `json:"id"`
+	CreateTime time.Time `json:"createTime"`
+	Digest     struct {
+		Type  string `json:"type"` // E.g. "DIGEST_TYPE_B5"
+		Value string `json:"value"`
+	} `json:"digest"`
+	SourceControlURL string `json:"sourceControlUrl"`
+}
+
+// File is a file of a buf module commit (.proto files, documentation and license).
+type File struct {
+	Path    string `json:"path"`
+	Content []byte `json:"content"` // Base64 in JSON
+}
+
+// Content is a downloaded module commit.
+type Content struct {
+	Commit Commit `json:"commit"`
+	Files  []File `json:"files"`
+}
+
+// Download returns the files of the commit a label (or commit ID) of a module points at.
+func (c *Client) Download(ctx context.Context, ref ModuleRef, label string) (*Content, error) {
+	type downloadValue struct {
+		ResourceRef resourceRef `json:"resourceRef"`
+	}
+	type downloadRequest struct {
+		Values []downloadValue `json:"values"`
+	}
+	type downloadResponse struct {
+		Contents []Content `json:"contents"`
+	}
+
+	req := downloadRequest{Values: []downloadValue{{ResourceRef: resourceRef{Name: resourceRefName{Owner: ref.Owner, Module: ref.Module, Ref: label}}}}}
+	var resp downloadResponse
+	if err := c.call(ctx, "buf.registry.module.v1.DownloadService/Download", req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to download %s:%s: %w", ref, label, err)
+	}
+	if len(resp.Contents) != 1 {
+		return nil, fmt.Errorf("failed to download %s:%s: expected 1 content, got %d", ref, label, len(resp.Contents))
+	}
+	return &resp.Contents[0], nil
+}
+
+// --- Conversion ---
+
+// Version is a label that is imported as an SProto version.
+type Version struct {
+	Version string // Normalized ("v" + semver)
+	Label   Label
+}
+
+// Versions selects the labels that are semantic versions, sorted oldest (lowest) first. Other labels
+// (e.g. "main") are returned as skipped. If several labels normalize to the same version ("1.2.0" and
+// "v1.2.0"), the one spelled like the version ("v1.2.0") is kept.
+func Versions(labels []Label) (versions []Version, skipped []string) {
+	byVersion := map[string]Label{}
+	parsed := map[string]*semver.Version{}
+	for _, l := range labels {
+		v, err := semver.NewVersion(l.Name)
+		if err != nil {
+			skipped = append(skipped, l.Name)
+			continue
+		}
+		version := "v" + v.String()
+		if existing, ok := byVersion[version]; ok {
+			if existing.Name == version || l.Name != version {
+				skipped = append(skipped, l.Name)
+				continue
+			}
+			skipped = append(skipped, existing.Name)
+		}
+		byVersion[version] = l
+		parsed[version] = v
+	}
+	for version, l := range byVersion {
+		versions = append(versions, Version{Version: version, Label: l})
+	}
+	sort.Slice(versions, func(i, j int) bool { return parsed[versions[i].Version].LessThan(parsed[versions[j].Version]) })
+	sort.Strings(skipped)
+	return versions, skipped
+}
+
+// Artifact packs the files of a downloaded commit into a module artifact, with a sproto.yaml naming the
+// module and version. The buf module's dependencies are not carried over: buf.yaml and buf.lock aren't
+// part of the downloaded files, and its dependencies may not exist in SProto.
+func Artifact(content *Content, module, version string) ([]byte, error) {
+	files := make(map[string][]byte, len(content.Files)+1)
+	for _, f := range content.Files {
+		if f.Path == manifest.ManifestFileName {
+			continue // Replaced below
+		}
+		files[f.Path] = f.Content
+	}
+	files[manifest.ManifestFileName] = []byte(fmt.Sprintf("name: %s\nversion: %s\n", module, version))
+	return artifact.Pack(files)
+}
+
+// Note describes where an imported version came from, attached to the version as a note.
+func Note(ref ModuleRef, label string, commit Commit) string {
+	note := fmt.Sprintf("Imported from %s:%s (commit %s", ref, label, commit.ID)
+	if !commit.CreateTime.IsZero() {
+		note += ", created " + commit.CreateTime.UTC().Format(time.RFC3339)
+	}
+	note += ")"
+	if commit.Digest.Value != "" {
+		note += fmt.Sprintf("\nbuf digest: %s:%s", strings.ToLower(strings.TrimPrefix(commit.Digest.Type, "DIGEST_TYPE_")), commit.Digest.Value)
+	}
+	if commit.SourceControlURL != "" {
+		note += "\nSource: " + commit.SourceControlURL
+	}
+	return note
+}
+
+// --- Import ---
+
+// Publisher publishes imported versions to SProto.
+type Publisher interface {
+	// Publish publishes a version of the target module. It returns false (and no error) if the version
+	// already exists, so an interrupted import can be re-run.
+	Publish(ctx context.Context, version string, artifact []byte) (bool, error)
+	// AddNote attaches a note to a published version.
+	AddNote(ctx context.Context, version, note string) error
+}
+
+// Options configure an import.
+type Options struct {
+	Module string // Target SProto module (namespace/name)
+	DryRun bool   // Only report the versions that would be imported
+	Log    *zap.Logger
+}
+
+// Result reports what an import did.
+type Result struct {
+	Imported []string // Versions published (or, for a dry run, to publish), oldest first
+	Existing []string // Versions that already existed and were left untouched
+	Skipped  []string // Labels that aren't semantic versions
+}
+
+// Import publishes the semver-labeled versions of a buf module to SProto, oldest first. It stops at the
+// first version that fails, so versions are never published out of order; re-running it continues
+// there, since existing versions are skipped.
+func Import(ctx context.Context, client *Client, ref ModuleRef, publisher Publisher, opts Options) (*Result, error) {
+	log := opts.Log
+	if log == nil {
+		log = zap.NewNop()
+	}
+	if _, _, err := manifest.SplitModule(opts.Module); err != nil {
+		return nil, err
+	}
+
+	labels, err := client.ListLabels(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	versions, skipped := Versions(labels)
+	result := &Result{Skipped: skipped}
+	log.Info("Listed buf module versions", zap.String("buf_module", ref.String()), zap.Int("versions", len(versions)), zap.Strings("skipped_labels", skipped))
+
+	for _, v := range versions {
+		if opts.DryRun {
+			result.Imported = append(result.Imported, v.Version)
+			continue
+		}
+		content, err := client.Download(ctx, ref, v.Label.Name)
+		if err != nil {
+			return result, err
+		}
+		zipData, err := Artifact(content, opts.Module, v.Version)
+		if err != nil {
+			return result, fmt.Errorf("failed to pack %s:%s: %w", ref, v.Label.Name, err)
+		}
+		created, err := publisher.Publish(ctx, v.Version, zipData)
+		if err != nil {
+			return result, fmt.Errorf("failed to publish %s@%s: %w", opts.Module, v.Version, err)
+		}
+		if !created {
+			log.Info("Version already exists, skipping", zap.String("version", v.Version))
+			result.Existing = append(result.Existing, v.Version)
+			continue
+		}
+		result.Imported = append(result.Imported, v.Version)
+		log.Info("Imported version", zap.String("version", v.Version), zap.String("label", v.Label.Name), zap.String("commit", content.Commit.ID))
+		if err := publisher.AddNote(ctx, v.Version, Note(ref, v.Label.Name, content.Commit)); err != nil {
+			// The version is published; only its provenance note is missing
+			log.Warn("Failed to attach import note", zap.String("version", v.Version), zap.Error(err))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,149 @@
+package bufimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModuleRef(t *testing.T) {
+	ref, err := ParseModuleRef("buf.build/acme/petapis")
+	require.NoError(t, err)
+	assert.Equal(t, ModuleRef{Remote: "buf.build", Owner: "acme", Module: "petapis"}, ref)
+	assert.Equal(t, "buf.build/acme/petapis", ref.String())
+
+	for _, value := range []string{"", "acme/petapis", "buf.build/acme/", "buf.build/acme/petapis/v1"} {
+		_, err := ParseModuleRef(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	versions, skipped := Versions([]Label{
+		{Name: "v1.10.0"}, {Name: "main"}, {Name: "1.2.0"}, {Name: "v1.2.0"}, {Name: "v1.9.0"}, {Name: "v2.0.0-beta.1"},
+	})
+	names := []string{}
+	for _, v := range versions {
+		names = append(names, v.Version+"="+v.Label.Name)
+	}
+	assert.Equal(t, []string{"v1.2.0=v1.2.0", "v1.9.0=v1.9.0", "v1.10.0=v1.10.0", "v2.0.0-beta.1=v2.0.0-beta.1"}, names)
+	assert.Equal(t, []string{"1.2.0", "main"}, skipped)
+}
+
+// fakeRegistry serves the buf.registry.module.v1 endpoints used by Import.
+func fakeRegistry(t *testing.T, token string) *httptest.Server {
+	files := map[string]string{"v1.0.0": "syntax = \"proto3\";\npackage pet.v1;\n", "v1.1.0": "syntax = \"proto3\";\npackage pet.v1;\nmessage Pet {}\n"}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":"unauthenticated","message":"you are not authenticated"}`))
+			return
+		}
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch r.URL.Path {
+		case "/buf.registry.module.v1.LabelService/ListLabels":
+			if req["pageToken"] == nil {
+				_, _ = w.Write([]byte(`{"nextPageToken":"p2","labels":[{"name":"v1.1.0","commitId":"c2"},{"name":"main","commitId":"c2"}]}`))
+			} else {
+				_, _ = w.Write([]byte(`{"labels":[{"name":"v1.0.0","commitId":"c1"}]}`))
+			}
+		case "/buf.registry.module.v1.DownloadService/Download":
+			ref := req["values"].([]interface{})[0].(map[string]interface{})["resourceRef"].(map[string]interface{})["name"].(map[string]interface{})
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"contents": []interface{}{map[string]interface{}{
+				"commit": map[string]interface{}{"id": "commit-" + ref["ref"].(string), "createTime": "2024-05-01T10:00:00Z", "digest": map[string]string{"type": "DIGEST_TYPE_B5", "value": "abcd"}},
+				"files":  []File{{Path: "pet/v1/pet.proto", Content: []byte(files[ref["ref"].(string)])}, {Path: "README.md", Content: []byte("# Pets\n")}},
+			}}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// recordingPublisher publishes into a map; versions in it already exist.
+type recordingPublisher struct {
+	artifacts map[string][]byte
+	notes     map[string]string
+	order     []string
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, version string, artifact []byte) (bool, error) {
+	if _, ok := p.artifacts[version]; ok {
+		return false, nil
+	}
+	p.artifacts[version] = artifact
+	p.order = append(p.order, version)
+	return true, nil
+}
+
+func (p *recordingPublisher) AddNote(_ context.Context, version, note string) error {
+	p.notes[version] = note
+	return nil
+}
+
+func TestImport(t *testing.T) {
+	srv := fakeRegistry(t, "buf-token")
+	defer srv.Close()
+	ref := ModuleRef{Remote: "buf.build", Owner: "acme", Module: "petapis"}
+	client := &Client{BaseURL: srv.URL, Token: "buf-token", HTTPClient: srv.Client()}
+	publisher := &recordingPublisher{artifacts: map[string][]byte{}, notes: map[string]string{}}
+
+	// Dry run: nothing is downloaded or published
+	result, err := Import(context.Background(), client, ref, publisher, Options{Module: "acme/petapis", DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, result.Imported)
+	assert.Empty(t, publisher.order)
+
+	// Oldest first, with sproto.yaml and a provenance note
+	result, err = Import(context.Background(), client, ref, publisher, Options{Module: "acme/petapis"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, publisher.order)
+	assert.Equal(t, []string{"main"}, result.Skipped)
+	zr, err := zip.NewReader(bytes.NewReader(publisher.artifacts["v1.1.0"]), int64(len(publisher.artifacts["v1.1.0"])))
+	require.NoError(t, err)
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(data)
+	}
+	assert.Equal(t, "name: acme/petapis\nversion: v1.1.0\n", contents["sproto.yaml"])
+	assert.Contains(t, contents["pet/v1/pet.proto"], "message Pet")
+	assert.Equal(t, "# Pets\n", contents["README.md"])
+	assert.Equal(t, "Imported from buf.build/acme/petapis:v1.1.0 (commit commit-v1.1.0, created 2024-05-01T10:00:00Z)\nbuf digest: b5:abcd", publisher.notes["v1.1.0"])
+
+	// Re-running skips existing versions
+	result, err = Import(context.Background(), client, ref, publisher, Options{Module: "acme/petapis"})
+	require.NoError(t, err)
+	assert.Empty(t, result.Imported)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, result.Existing)
+
+	// Registry errors are reported with their status
+	client.Token = "wrong"
+	_, err = Import(context.Background(), client, ref, publisher, Options{Module: "acme/petapis"})
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.Equal(t, "unauthenticated", apiErr.Code)
+
+	_, err = Import(context.Background(), client, ref, publisher, Options{Module: "petapis"})
+	assert.Error(t, err)
+}
+
+func TestNote(t *testing.T) {
+	commit := Commit{ID: "c1", CreateTime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), SourceControlURL: "https://github.com/acme/petapis/commit/123"}
+	assert.Equal(t, "Imported from buf.build/acme/petapis:v1.0.0 (commit c1, created 2024-05-01T10:00:00Z)\nSource: https://github.com/acme/petapis/commit/123",
+		Note(ModuleRef{Remote: "buf.build", Owner: "acme", Module: "petapis"}, "v1.0.0", commit))
+}
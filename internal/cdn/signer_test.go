@@ -0,0 +1,42 @@
+package cdn
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner_RoundTrip(t *testing.T) {
+	signer, err := NewSigner("https://cdn.example.com/", "secret", 5*time.Minute)
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	path := "/cdn/v1/modules/my-org/my-module/v1.0.0/artifact"
+
+	signed, err := url.Parse(signer.SignURL(path, now))
+	require.NoError(t, err)
+	assert.Equal(t, "cdn.example.com", signed.Host)
+	assert.Equal(t, path, signed.Path)
+	assert.Equal(t, "1700000300", signed.Query().Get(ExpiresParam))
+
+	assert.NoError(t, signer.Verify(path, signed.Query(), now))
+	assert.ErrorIs(t, signer.Verify(path, signed.Query(), now.Add(6*time.Minute)), ErrExpired)
+	assert.ErrorIs(t, signer.Verify("/cdn/v1/modules/my-org/other/v1.0.0/artifact", signed.Query(), now), ErrInvalidSignature)
+	assert.ErrorIs(t, signer.Verify(path, url.Values{}, now), ErrMissingSignature)
+
+	// Extending the expiry invalidates the signature
+	tampered := signed.Query()
+	tampered.Set(ExpiresParam, "1800000000")
+	assert.ErrorIs(t, signer.Verify(path, tampered, now), ErrInvalidSignature)
+}
+
+func TestNewSigner_InvalidConfig(t *testing.T) {
+	_, err := NewSigner("cdn.example.com", "secret", time.Minute)
+	assert.Error(t, err)
+	_, err = NewSigner("https://cdn.example.com", "", time.Minute)
+	assert.Error(t, err)
+	_, err = NewSigner("https://cdn.example.com", "secret", 0)
+	assert.Error(t, err)
+}
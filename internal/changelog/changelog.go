@@ -0,0 +1,144 @@
+// Package changelog extracts the section for a given version from a CHANGELOG.md packaged in a
+// module artifact.
+//
+// Sections are recognised by Markdown ATX headings (levels 1-3) whose first word is the version,
+// optionally prefixed with "v" and wrapped in brackets or a link, as in "Keep a Changelog":
+//
+//	## [1.2.0] - 2024-05-01
+//	## v1.2.0
+//	### 1.2.0 (2024-05-01)
+//
+// A section ends at the next heading of the same or a higher level.
+package changelog
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// FileName is the changelog file looked up at the root of an artifact (matched case-insensitively).
+const FileName = "CHANGELOG.md"
+
+// MaxSectionLength caps the stored section. Longer sections are truncated.
+const MaxSectionLength = 64 << 10 // 64 KB
+
+// maxFileSize is the largest changelog that is read from an artifact.
+const maxFileSize = 4 << 20 // 4 MB
+
+// FromArtifact returns the section for version from the CHANGELOG.md at the root of artifact (a zip),
+// or "" if the artifact has no changelog or it has no section for version.
+func FromArtifact(artifact []byte, version string) (string, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(artifact), int64(len(artifact)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	for _, f := range zipReader.File {
+		name := path.Clean(strings.ReplaceAll(f.Name, `\`, "/"))
+		if f.FileInfo().IsDir() || !strings.EqualFold(name, FileName) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", fmt.Errorf("failed to open %s in artifact: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, maxFileSize))
+		rc.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s in artifact: %w", f.Name, err)
+		}
+		return Section(string(content), version), nil
+	}
+	return "", nil
+}
+
+// Section returns the body of the section for version in a Markdown changelog (without its heading,
+// trimmed), or "" if there is none.
+func Section(markdown, version string) string {
+	want := strings.TrimPrefix(version, "v")
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+
+	start, level := -1, 0
+	inFence := false
+	for i, line := range lines {
+		if isFence(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		l, text := heading(line)
+		if l == 0 {
+			continue
+		}
+		if start >= 0 {
+			if l <= level {
+				return finish(lines[start:i])
+			}
+			continue
+		}
+		if l <= 3 && headingVersion(text) == want {
+			start, level = i+1, l
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	return finish(lines[start:])
+}
+
+// finish joins and trims the lines of a section and enforces MaxSectionLength.
+func finish(lines []string) string {
+	section := strings.TrimSpace(strings.Join(lines, "\n"))
+	if len(section) > MaxSectionLength {
+		cut := MaxSectionLength
+		for cut > 0 && !isRuneStart(section[cut]) {
+			cut-- // Don't split a UTF-8 sequence
+		}
+		section = section[:cut] + "\n\n…"
+	}
+	return section
+}
+
+func isRuneStart(b byte) bool { return b&0xC0 != 0x80 }
+
+// isFence reports whether line opens or closes a fenced code block (whose contents aren't headings).
+func isFence(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return len(line)-len(trimmed) < 4 && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"))
+}
+
+// heading returns the level and text of an ATX heading ("## text"), or level 0 if line isn't one.
+func heading(line string) (int, string) {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) >= 4 {
+		return 0, "" // Indented code block
+	}
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, ""
+	}
+	rest := trimmed[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "" // "#tag" is not a heading
+	}
+	return level, strings.TrimSpace(strings.TrimRight(strings.TrimSpace(rest), "#"))
+}
+
+// headingVersion returns the version a heading's text starts with, without a "v" prefix, brackets or
+// link target, e.g. "1.2.0" for "[v1.2.0](https://...) - 2024-05-01".
+func headingVersion(text string) string {
+	text = strings.TrimPrefix(text, "[")
+	end := strings.IndexAny(text, " \t]()")
+	if end >= 0 {
+		text = text[:end]
+	}
+	return strings.TrimPrefix(text, "v")
+}
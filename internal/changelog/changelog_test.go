@@ -0,0 +1,81 @@
+package changelog
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const keepAChangelog = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+
+- Work in progress
+
+## [1.2.0] - 2024-05-01
+
+### Added
+
+- ` + "`Invoice.rounding_mode`" + `
+
+` + "```" + `
+## 1.1.0 inside a code block is not a heading
+` + "```" + `
+
+## [v1.1.0](https://example.com/compare/v1.0.0...v1.1.0) - 2024-04-01
+
+- Contains hotfix for billing rounding
+
+## 1.0.0
+Initial release
+`
+
+func TestSection(t *testing.T) {
+	assert.Equal(t, "### Added\n\n- `Invoice.rounding_mode`\n\n```\n## 1.1.0 inside a code block is not a heading\n```", Section(keepAChangelog, "v1.2.0"),
+		"subsections belong to the section, fenced code is not a heading")
+	assert.Equal(t, "- Contains hotfix for billing rounding", Section(keepAChangelog, "v1.1.0"))
+	assert.Equal(t, "Initial release", Section(keepAChangelog, "1.0.0"), "last section runs to the end")
+	assert.Empty(t, Section(keepAChangelog, "v0.9.0"))
+	assert.Empty(t, Section(keepAChangelog, "v1.2"), "versions must match exactly")
+	assert.Empty(t, Section("## v2.0.0\r\n", "v2.0.0"), "empty section")
+	assert.Equal(t, "Windows line endings", Section("## v2.0.0\r\nWindows line endings\r\n## v1.0.0\r\n", "v2.0.0"))
+}
+
+func TestSection_Truncated(t *testing.T) {
+	section := Section("## 1.0.0\n"+strings.Repeat("é", MaxSectionLength), "v1.0.0")
+	assert.True(t, strings.HasSuffix(section, "\n\n…"))
+	assert.LessOrEqual(t, len(section), MaxSectionLength+len("\n\n…"))
+	assert.True(t, strings.ToValidUTF8(section, "") == section, "must not split a UTF-8 sequence")
+}
+
+func TestFromArtifact(t *testing.T) {
+	artifact := func(files map[string]string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for name, content := range files {
+			w, err := zw.Create(name)
+			require.NoError(t, err)
+			_, err = w.Write([]byte(content))
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+
+	section, err := FromArtifact(artifact(map[string]string{"billing/v1/billing.proto": "", "changelog.md": keepAChangelog}), "v1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "- Contains hotfix for billing rounding", section, "file name is matched case-insensitively")
+
+	section, err = FromArtifact(artifact(map[string]string{"docs/CHANGELOG.md": keepAChangelog}), "v1.1.0")
+	require.NoError(t, err)
+	assert.Empty(t, section, "only the root changelog is used")
+
+	_, err = FromArtifact([]byte("not a zip"), "v1.0.0")
+	assert.Error(t, err)
+}
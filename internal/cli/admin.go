@@ -0,0 +1,455 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	adminPolicyLint         string
+	adminPolicyCompat       string
+	adminPolicyAllowedFiles []string
+	adminPolicyMonotonic    bool
+	adminPolicySyntaxes     []string
+	adminPolicyEphemeralTTL string
+	adminPolicyHTTPRules    bool
+	adminPolicyContentTypes []string
+	adminPolicyMaxFileBytes int64
+	adminPolicyExtensions   []string
+	adminPolicyIfRevision   int64
+
+	adminRunParams []string
+	adminRunWait   bool
+
+	adminSelfTestSize int64
+)
+
+// adminCmd groups the registry administration commands
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Administer the registry",
+	Long:  `Commands for registry administrators. They require the admin API token.`,
+}
+
+// adminRunCmd represents the admin run command
+var adminRunCmd = &cobra.Command{
+	Use:   "run <job>",
+	Short: "Start an admin job on the registry",
+	Long: `Starts a maintenance or batch job as a background operation of the registry and prints its
+operation ID (see 'protoreg-cli operations'). With --wait, waits for it to finish and prints
+its result. Jobs:
+  gc               delete expired original uploads, dev channels and ephemeral versions, and
+                   operations finished more than a day ago
+  sunset           enforce the sunset dates that have passed
+  migrate-storage  move artifacts stored under older key layouts (param: keep_old)
+  import-buf       import a module from a buf registry (params: source, module, buf_token, dry_run)
+  tier-storage     move artifacts of old versions to cold storage (param: older_than, e.g. 2160h)
+  reindex-search   rebuild the search index from each module's newest version (param: module)
+  reindex-packages index the proto packages of every version of each module (param: module)
+  sign-versions    sign the versions published without a registry signature (param: module)
+
+Examples:
+  protoreg-cli admin run gc --wait
+  protoreg-cli admin run migrate-storage --param keep_old=true
+  protoreg-cli admin run import-buf --param source=buf.build/acme/petapis --param module=acme/pet`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		req := apitypes.StartJobRequest{Params: map[string]string{}}
+		for _, param := range adminRunParams {
+			name, value, ok := strings.Cut(param, "=")
+			if !ok || name == "" {
+				return exitErrorf(ExitUsage, "invalid --param %q: expected name=value", param)
+			}
+			req.Params[name] = value
+		}
+		payload, err := json.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		jobURL := fmt.Sprintf("%s/api/v1/admin/jobs/%s", strings.TrimSuffix(registryURL, "/"), url.PathEscape(args[0]))
+		bodyBytes, err := adminRequest(http.MethodPost, jobURL, apiToken, payload, http.StatusAccepted)
+		if err != nil {
+			return err
+		}
+		var op apitypes.OperationResponse
+		if err := json.Unmarshal(bodyBytes, &op); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		fmt.Printf("Started %s job (operation %s)\n", op.Kind, op.ID)
+		if !adminRunWait {
+			return nil
+		}
+		return waitAndPrintOperation(registryURL, apiToken, op)
+	},
+}
+
+// adminSelfTestCmd represents the admin storage-selftest command
+var adminSelfTestCmd = &cobra.Command{
+	Use:   "storage-selftest",
+	Short: "Check the registry's artifact storage with a probe object",
+	Long: `Makes the registry write a probe object to its artifact storage (MinIO/S3 or a local
+directory), check that it exists, read it back and delete it, and prints the outcome and
+duration of each step. Use it to diagnose missing bucket permissions or slow storage on the
+running server. Exits with 6 if a step failed.
+
+Examples:
+  protoreg-cli admin storage-selftest
+  protoreg-cli admin storage-selftest --size 8388608   # 8 MiB probe, to gauge throughput`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		targetURL := fmt.Sprintf("%s/api/v1/admin/storage/selftest", strings.TrimSuffix(registryURL, "/"))
+		if adminSelfTestSize > 0 {
+			targetURL += fmt.Sprintf("?size=%d", adminSelfTestSize)
+		}
+		req, err := http.NewRequest(http.MethodPost, targetURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		var result apitypes.StorageSelfTestResponse
+		if (resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable) || json.Unmarshal(bodyBytes, &result) != nil || len(result.Steps) == 0 {
+			return registryError(resp.StatusCode, bodyBytes) // Not a self-test result, e.g. 401
+		}
+
+		fmt.Printf("Storage self-test (%s, %d byte probe %s)\n", orNone(result.Provider), result.SizeBytes, result.Key)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "STEP\tRESULT\tDURATION\tERROR")
+		for _, step := range result.Steps {
+			outcome := "ok"
+			switch {
+			case step.Skipped:
+				outcome = "skipped"
+			case !step.OK:
+				outcome = "FAILED"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%.1fms\t%s\n", step.Step, outcome, step.DurationMs, step.Error)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if !result.OK {
+			return exitErrorf(ExitNetwork, "storage self-test failed")
+		}
+		fmt.Printf("All steps passed in %.1fms\n", result.TotalDurationMs)
+		return nil
+	},
+}
+
+// adminPolicyCmd groups the commands for namespace policies
+var adminPolicyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Manage the publish policies of namespaces",
+	Long: `Commands for namespace policies: checks every version published in a namespace must pass,
+in addition to the registry's publish policies:
+  - lint: the files follow a lint ruleset (minimal, basic or standard)
+  - compat: no breaking changes (wire or source level) without a new major version
+  - allowed files: the artifact only contains files matching the patterns
+  - allowed syntaxes: the .proto files only declare the given syntaxes or editions
+  - monotonic: new versions are newer than every published version
+  - HTTP rules: google.api.http annotations are valid for grpc-gateway, without duplicate routes
+A policy can also make the namespace ephemeral: its versions are deleted a while after they
+were published (--ephemeral-ttl), e.g. for CI preview builds.`,
+}
+
+// adminPolicyListCmd represents the admin policy list command
+var adminPolicyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the namespaces that have a policy",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		bodyBytes, err := adminRequest(http.MethodGet, strings.TrimSuffix(registryURL, "/")+"/api/v1/admin/namespace-policies", apiToken, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var list apitypes.ListNamespacePoliciesResponse
+		if err := json.Unmarshal(bodyBytes, &list); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if len(list.Policies) == 0 {
+			fmt.Println("No namespace policies")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAMESPACE\tLINT\tCOMPAT\tALLOWED FILES\tALLOWED SYNTAXES\tMONOTONIC\tHTTP RULES\tEPHEMERAL TTL")
+		for _, p := range list.Policies {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%t\t%t\t%s\n", p.Namespace, orDash(p.LintRuleset), orDash(p.CompatLevel), orDash(strings.Join(p.AllowedFiles, ",")),
+				orDash(strings.Join(p.AllowedSyntaxes, ",")), p.Monotonic, p.HTTPRules, orDash(p.EphemeralTTL))
+		}
+		return tw.Flush()
+	},
+}
+
+// adminPolicyGetCmd represents the admin policy get command
+var adminPolicyGetCmd = &cobra.Command{
+	Use:   "get <namespace>",
+	Short: "Show the policy of a namespace",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		policyURL, err := namespacePolicyURL(registryURL, args[0])
+		if err != nil {
+			return err
+		}
+		bodyBytes, err := adminRequest(http.MethodGet, policyURL, apiToken, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var p apitypes.NamespacePolicyResponse
+		if err := json.Unmarshal(bodyBytes, &p); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		printNamespacePolicy(p)
+		return nil
+	},
+}
+
+// adminPolicySetCmd represents the admin policy set command
+var adminPolicySetCmd = &cobra.Command{
+	Use:   "set <namespace>",
+	Short: "Set the policy of a namespace",
+	Long: `Creates or replaces the policy of a namespace. The whole policy is replaced: checks whose
+flag is omitted are disabled. The namespace doesn't need to have modules yet.
+
+With --if-revision (the revision "admin policy get" shows), the policy is only replaced if
+nobody changed it since; otherwise the registry refuses it (exit code 5), so concurrent
+edits don't overwrite each other. --if-revision 0 only creates a policy.
+
+--content-type, --max-file-bytes and --allow-extension replace the server's content policy
+(PROTOREG_ARTIFACT_CONTENT_TYPES, ...) for the namespace; omitted ones keep the server's.
+
+Examples:
+  protoreg-cli admin policy set mycompany --lint standard --compat wire --monotonic
+  protoreg-cli admin policy set mycompany --allow-file '*.proto' --allow-file README.md
+  protoreg-cli admin policy set mycompany --allow-syntax proto3 --allow-syntax edition-2023
+  protoreg-cli admin policy set mycompany --http-rules
+  protoreg-cli admin policy set previews --ephemeral-ttl 72h
+  protoreg-cli admin policy set mycompany --allow-extension .proto --allow-extension .md --max-file-bytes 1048576
+  protoreg-cli admin policy set mycompany --lint standard --if-revision 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		policyURL, err := namespacePolicyURL(registryURL, args[0])
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(apitypes.NamespacePolicyRequest{
+			LintRuleset:  adminPolicyLint,
+			CompatLevel:  adminPolicyCompat,
+			AllowedFiles: adminPolicyAllowedFiles,
+			Monotonic:    adminPolicyMonotonic,
+
+			AllowedSyntaxes: adminPolicySyntaxes,
+			HTTPRules:       adminPolicyHTTPRules,
+			EphemeralTTL:    adminPolicyEphemeralTTL,
+
+			ContentTypes:      adminPolicyContentTypes,
+			MaxFileBytes:      adminPolicyMaxFileBytes,
+			AllowedExtensions: adminPolicyExtensions,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		bodyBytes, err := adminRequestWithHeader(http.MethodPut, policyURL, apiToken, payload, policyPrecondition(cmd), http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var p apitypes.NamespacePolicyResponse
+		if err := json.Unmarshal(bodyBytes, &p); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		fmt.Printf("Saved the policy of namespace %s\n", p.Namespace)
+		printNamespacePolicy(p)
+		return nil
+	},
+}
+
+// adminPolicyDeleteCmd represents the admin policy delete command
+var adminPolicyDeleteCmd = &cobra.Command{
+	Use:   "delete <namespace>",
+	Short: "Remove the policy of a namespace",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		policyURL, err := namespacePolicyURL(registryURL, args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := adminRequestWithHeader(http.MethodDelete, policyURL, apiToken, nil, policyPrecondition(cmd), http.StatusNoContent); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted the policy of namespace %s\n", args[0])
+		return nil
+	},
+}
+
+// requireAdmin returns the registry URL and API token admin commands need.
+func requireAdmin() (string, string, error) {
+	registryURL, err := requireRegistryURL()
+	if err != nil {
+		return "", "", err
+	}
+	apiToken, err := requireAPIToken()
+	if err != nil {
+		return "", "", err
+	}
+	return registryURL, apiToken, nil
+}
+
+// adminRequest sends an authenticated request with an optional JSON payload and returns the response
+// body, or the registry's error if the status isn't wantStatus.
+func adminRequest(method, targetURL, apiToken string, payload []byte, wantStatus int) ([]byte, error) {
+	return adminRequestWithHeader(method, targetURL, apiToken, payload, nil, wantStatus)
+}
+
+// adminRequestWithHeader is adminRequest with additional request headers (e.g. If-Match).
+func adminRequestWithHeader(method, targetURL, apiToken string, payload []byte, header http.Header, wantStatus int) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, targetURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+apiToken)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	GetLogger().Debug("Sending admin request", zap.String("method", method), zap.String("url", targetURL))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != wantStatus {
+		return nil, registryError(resp.StatusCode, bodyBytes)
+	}
+	return bodyBytes, nil
+}
+
+// namespacePolicyURL returns the URL of a namespace's policy. Invalid namespaces are reported as validation errors.
+func namespacePolicyURL(registryURL, namespace string) (string, error) {
+	if err := validation.ValidateNamespace(namespace); err != nil {
+		return "", withExitCode(ExitValidation, err)
+	}
+	return fmt.Sprintf("%s/api/v1/admin/namespace-policies/%s", strings.TrimSuffix(registryURL, "/"), url.PathEscape(namespace)), nil
+}
+
+// policyPrecondition returns the precondition header of a policy write: If-Match for the revision of
+// --if-revision, If-None-Match: * for --if-revision 0 (the policy must not exist yet), or none.
+func policyPrecondition(cmd *cobra.Command) http.Header {
+	if !cmd.Flags().Changed("if-revision") {
+		return nil
+	}
+	if adminPolicyIfRevision == 0 {
+		return http.Header{"If-None-Match": {"*"}}
+	}
+	return http.Header{"If-Match": {fmt.Sprintf(`"%d"`, adminPolicyIfRevision)}}
+}
+
+func printNamespacePolicy(p apitypes.NamespacePolicyResponse) {
+	fmt.Printf("  Lint ruleset:  %s\n", orNone(p.LintRuleset))
+	fmt.Printf("  Compat level:  %s\n", orNone(p.CompatLevel))
+	fmt.Printf("  Allowed files: %s\n", orNone(strings.Join(p.AllowedFiles, ", ")))
+	fmt.Printf("  Syntaxes:      %s\n", orNone(strings.Join(p.AllowedSyntaxes, ", ")))
+	fmt.Printf("  Monotonic:     %t\n", p.Monotonic)
+	fmt.Printf("  HTTP rules:    %t\n", p.HTTPRules)
+	fmt.Printf("  Ephemeral TTL: %s\n", orNone(p.EphemeralTTL))
+	fmt.Printf("  Content types: %s\n", orNone(strings.Join(p.ContentTypes, ", ")))
+	if p.MaxFileBytes > 0 {
+		fmt.Printf("  Max file size: %d bytes\n", p.MaxFileBytes)
+	} else {
+		fmt.Printf("  Max file size: (none)\n")
+	}
+	fmt.Printf("  Extensions:    %s\n", orNone(strings.Join(p.AllowedExtensions, ", ")))
+	fmt.Printf("  Updated:       %s\n", p.UpdatedAt.Local().Format(time.RFC3339))
+	fmt.Printf("  Revision:      %d\n", p.Revision)
+}
+
+// orNone returns s, or "(none)" if s is empty.
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
+
+func init() {
+	rootCmd.AddCommand(adminCmd)
+	adminCmd.AddCommand(adminPolicyCmd)
+	adminCmd.AddCommand(adminRunCmd)
+	adminCmd.AddCommand(adminSelfTestCmd)
+	adminPolicyCmd.AddCommand(adminPolicyListCmd)
+	adminPolicyCmd.AddCommand(adminPolicyGetCmd)
+	adminPolicyCmd.AddCommand(adminPolicySetCmd)
+	adminPolicyCmd.AddCommand(adminPolicyDeleteCmd)
+
+	adminRunCmd.Flags().StringArrayVar(&adminRunParams, "param", nil, "Job parameter as name=value (repeatable)")
+	adminRunCmd.Flags().BoolVar(&adminRunWait, "wait", false, "Wait for the job to finish and print its result")
+
+	adminSelfTestCmd.Flags().Int64Var(&adminSelfTestSize, "size", 0, "Size of the probe object in bytes, at most 16 MiB (default 1 KiB)")
+
+	adminPolicySetCmd.Flags().StringVar(&adminPolicyLint, "lint", "", "Lint ruleset: minimal, basic or standard (default: no linting)")
+	adminPolicySetCmd.Flags().StringVar(&adminPolicyCompat, "compat", "", "Reject breaking changes without a major version bump: wire or source (default: allowed)")
+	adminPolicySetCmd.Flags().StringArrayVar(&adminPolicyAllowedFiles, "allow-file", nil, "Glob pattern of the file names artifacts may contain, e.g. '*.proto' (repeatable; default: any file)")
+	adminPolicySetCmd.Flags().StringArrayVar(&adminPolicySyntaxes, "allow-syntax", nil, "Syntax or edition the .proto files may declare: proto2, proto3 or edition-<year> (repeatable; default: any)")
+	adminPolicySetCmd.Flags().BoolVar(&adminPolicyMonotonic, "monotonic", false, "Require new versions to be newer than every published version")
+	adminPolicySetCmd.Flags().BoolVar(&adminPolicyHTTPRules, "http-rules", false, "Require valid google.api.http annotations without duplicate routes")
+	adminPolicySetCmd.Flags().Int64Var(&adminPolicyIfRevision, "if-revision", 0, "Only replace the policy if it is still at this revision; 0 only creates one")
+	adminPolicyDeleteCmd.Flags().Int64Var(&adminPolicyIfRevision, "if-revision", 0, "Only delete the policy if it is still at this revision")
+	adminPolicySetCmd.Flags().StringArrayVar(&adminPolicyContentTypes, "content-type", nil, "Content type artifacts may have, e.g. application/zip (repeatable; default: the server's)")
+	adminPolicySetCmd.Flags().Int64Var(&adminPolicyMaxFileBytes, "max-file-bytes", 0, "Largest file an artifact may contain, in bytes (default: the server's)")
+	adminPolicySetCmd.Flags().StringArrayVar(&adminPolicyExtensions, "allow-extension", nil, "Extension of the files artifacts may contain, e.g. .proto (repeatable; default: the server's)")
+	adminPolicySetCmd.Flags().StringVar(&adminPolicyEphemeralTTL, "ephemeral-ttl", "", "Make the namespace ephemeral: delete versions this long after they were published, e.g. 72h (default: keep them)")
+}
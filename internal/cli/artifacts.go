@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	artifactsContentType string
+	artifactsOutput      string
+)
+
+// artifactsCmd groups the commands for secondary artifacts
+var artifactsCmd = &cobra.Command{
+	Use:   "artifacts",
+	Short: "Attach and fetch secondary artifacts of module versions",
+	Long: `Commands for secondary artifacts: outputs derived from a version's protos (descriptor
+sets, OpenAPI specs, generated docs, ...) attached to the version under a classifier such
+as "descriptors", "openapi" or "docs", and fetched independently of its protos.
+
+Attached artifacts can't be replaced; attaching the same content again is a no-op.`,
+}
+
+// artifactsAttachCmd represents the artifacts attach command
+var artifactsAttachCmd = &cobra.Command{
+	Use:   "attach <namespace/module_name> <version> <classifier> <file|->",
+	Short: "Attach a secondary artifact to a published module version",
+	Long: `Uploads a file (or standard input, "-") and attaches it to a published module version under
+a classifier (lowercase letters, digits, '-' and '.'). The content type is taken from
+--content-type, or guessed from the file extension. Requires an API token.
+
+Examples:
+  protoreg-cli artifacts attach mycompany/user v1.2.0 openapi ./gen/user.openapi.json
+  buf build -o - | protoreg-cli artifacts attach mycompany/user v1.2.0 descriptors - --content-type application/x-protobuf`,
+	Args: cobra.ExactArgs(4),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		apiToken, err := requireAPIToken()
+		if err != nil {
+			return err
+		}
+		artifactURL, err := versionArtifactURL(registryURL, args[0], args[1], args[2])
+		if err != nil {
+			return err
+		}
+
+		var data []byte
+		if args[3] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[3])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read artifact: %w", err)
+		}
+		contentType := artifactsContentType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(args[3])) // "" for stdin; the registry then stores application/octet-stream
+		}
+
+		req, err := http.NewRequest(http.MethodPut, artifactURL, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+		req.Header.Set(apitypes.ArtifactDigestHeader, uploadDigest(data))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		log.Info("Attaching artifact", zap.String("url", artifactURL), zap.Int("size", len(data)), zap.String("content_type", contentType))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+			return registryError(resp.StatusCode, bodyBytes)
+		}
+
+		var attached apitypes.VersionArtifactResponse
+		if err := json.Unmarshal(bodyBytes, &attached); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if resp.StatusCode == http.StatusOK {
+			fmt.Printf("Artifact %s of %s@%s is already attached with the same content\n", attached.Classifier, qualifyModule(args[0]), args[1])
+		} else {
+			fmt.Printf("Attached %s to %s@%s\n", attached.Classifier, qualifyModule(args[0]), args[1])
+		}
+		fmt.Printf("  Digest: %s\n", attached.Digest)
+		fmt.Printf("  Size: %d bytes (%s)\n", attached.Size, attached.ContentType)
+		return nil
+	},
+}
+
+// artifactsGetCmd represents the artifacts get command
+var artifactsGetCmd = &cobra.Command{
+	Use:   "get <namespace/module_name> <version> <classifier>",
+	Short: "Download a secondary artifact of a module version",
+	Long: `Downloads the artifact attached to a module version under a classifier into --output
+("-" writes it to standard output).
+
+Examples:
+  protoreg-cli artifacts get mycompany/user v1.2.0 openapi --output user.openapi.json
+  protoreg-cli artifacts get mycompany/user v1.2.0 descriptors -o - | protoc --decode_raw`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		artifactURL, err := versionArtifactURL(registryURL, args[0], args[1], args[2])
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequest(http.MethodGet, artifactURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		setReadToken(req)
+		log.Info("Fetching artifact", zap.String("url", artifactURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			return fmt.Errorf("%s@%s %s: %w", qualifyModule(args[0]), args[1], args[2], registryError(resp.StatusCode, bodyBytes))
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read artifact: %w", err)
+		}
+
+		if artifactsOutput == "-" {
+			_, err := os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(artifactsOutput, data, 0644); err != nil {
+			return fmt.Errorf("failed to write artifact to %s: %w", artifactsOutput, err)
+		}
+		fmt.Printf("Wrote %s of %s@%s (%d bytes) to %s\n", args[2], qualifyModule(args[0]), args[1], len(data), artifactsOutput)
+		return nil
+	},
+}
+
+// artifactsListCmd represents the artifacts list command
+var artifactsListCmd = &cobra.Command{
+	Use:   "list <namespace/module_name> <version>",
+	Short: "List the secondary artifacts of a module version",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		versionURL, err := versionArtifactURL(registryURL, args[0], args[1], "")
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequest(http.MethodGet, versionURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		setReadToken(req)
+		log.Info("Listing artifacts", zap.String("url", versionURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return registryError(resp.StatusCode, bodyBytes)
+		}
+
+		var list apitypes.ListVersionArtifactsResponse
+		if err := json.Unmarshal(bodyBytes, &list); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if len(list.Artifacts) == 0 {
+			fmt.Printf("No artifacts attached to %s/%s@%s\n", list.Namespace, list.ModuleName, list.Version)
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CLASSIFIER\tCONTENT TYPE\tSIZE\tDIGEST")
+		for _, a := range list.Artifacts {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", a.Classifier, a.ContentType, a.Size, a.Digest)
+		}
+		return tw.Flush()
+	},
+}
+
+// versionArtifactURL returns the URL of a secondary artifact of a module version, or of the version's
+// artifact list if classifier is empty. Invalid arguments are reported as validation errors.
+func versionArtifactURL(registryURL, moduleArg, version, classifier string) (string, error) {
+	moduleFullName := qualifyModule(moduleArg) // The namespace may be omitted if a default namespace is configured
+	parts := strings.SplitN(moduleFullName, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", exitErrorf(ExitValidation, "invalid module name format %q: expected 'namespace/module_name'", moduleFullName)
+	}
+	if !strings.HasPrefix(version, "v") {
+		return "", exitErrorf(ExitValidation, "invalid version format %q: must start with 'v'", version)
+	}
+	artifactsURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s/artifacts", strings.TrimSuffix(registryURL, "/"),
+		url.PathEscape(parts[0]), url.PathEscape(parts[1]), url.PathEscape(version))
+	if classifier == "" {
+		return artifactsURL, nil
+	}
+	if err := validation.ValidateClassifier(classifier); err != nil {
+		return "", withExitCode(ExitValidation, err)
+	}
+	return artifactsURL + "/" + url.PathEscape(classifier), nil
+}
+
+func init() {
+	rootCmd.AddCommand(artifactsCmd)
+	artifactsCmd.AddCommand(artifactsAttachCmd)
+	artifactsCmd.AddCommand(artifactsGetCmd)
+	artifactsCmd.AddCommand(artifactsListCmd)
+
+	artifactsAttachCmd.Flags().StringVar(&artifactsContentType, "content-type", "", "Content type of the artifact (default: guessed from the file extension)")
+	artifactsGetCmd.Flags().StringVarP(&artifactsOutput, "output", "o", "", "File to write the artifact to, or - for standard output (required)")
+	_ = artifactsGetCmd.MarkFlagRequired("output")
+}
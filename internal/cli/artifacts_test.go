@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionArtifactURL(t *testing.T) {
+	u, err := versionArtifactURL("https://registry.example.com/", "acme/user", "v1.2.0", "openapi")
+	require.NoError(t, err)
+	assert.Equal(t, "https://registry.example.com/api/v1/modules/acme/user/v1.2.0/artifacts/openapi", u)
+
+	u, err = versionArtifactURL("https://registry.example.com", "acme/user", "v1.2.0", "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://registry.example.com/api/v1/modules/acme/user/v1.2.0/artifacts", u)
+
+	for _, args := range [][3]string{{"user", "v1.2.0", "docs"}, {"acme/user", "1.2.0", "docs"}, {"acme/user", "v1.2.0", "Docs/html"}} {
+		_, err := versionArtifactURL("https://registry.example.com", args[0], args[1], args[2])
+		assert.Equal(t, ExitValidation, exitCode(err), "%v", args)
+	}
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	bundleOutput string
+	bundleFormat string
+)
+
+// Bundle formats accepted by --format, mapped to the registry's ?format= values.
+var bundleFormats = map[string]string{
+	"zip":            apitypes.BundleFormatZip,
+	"descriptor-set": apitypes.BundleFormatDescriptorSet,
+}
+
+// bundleCmd represents the bundle command
+var bundleCmd = &cobra.Command{
+	Use:   "bundle [namespace/module_name|directory] [version]",
+	Short: "Download a module version together with its dependencies as a single file",
+	Long: `Downloads a module version flattened together with its dependencies, for tools that
+can't handle one include path per module. The registry resolves the dependencies like at
+publish time (newest published version matching each constraint in sproto.yaml).
+
+Formats (--format):
+  zip             The .proto files of the module and all its dependencies in one zip, a
+                  single import root (the well-known types are not included; protoc ships them)
+  descriptor-set  A binary FileDescriptorSet of the compiled module, self-contained
+                  (imports and well-known types included, like protoc --include_imports)
+
+The module argument works like for 'fetch': a module name, or a module directory whose
+sproto.yaml provides the name and (unless given) the version.
+
+Examples:
+  protoreg-cli bundle mycompany/billing v1.2.0 --output billing-bundle.zip
+  protoreg-cli bundle mycompany/billing v1.2.0 --format descriptor-set --output billing.binpb`,
+	Args: cobra.MaximumNArgs(2), // Module name (or directory) and version
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		format, ok := bundleFormats[bundleFormat]
+		if !ok {
+			return exitErrorf(ExitUsage, "invalid --format %q: must be zip or descriptor-set", bundleFormat)
+		}
+
+		moduleArg, version := ".", ""
+		if len(args) > 0 {
+			moduleArg = args[0]
+		}
+		if len(args) > 1 {
+			version = args[1]
+		}
+		namespace, moduleName, version, err := resolveModuleArg(moduleArg, version)
+		if err != nil {
+			return exitErrorf(ExitValidation, "invalid module: %w", err)
+		}
+		if version == "" {
+			return exitErrorf(ExitUsage, "version is required (as an argument or in sproto.yaml)")
+		}
+		if !strings.HasPrefix(version, "v") {
+			return exitErrorf(ExitValidation, "invalid version format %q: must start with 'v'", version)
+		}
+
+		data, dependencies, err := downloadBundle(&http.Client{}, registryURL, namespace, moduleName, version, format, log)
+		if err != nil {
+			return fmt.Errorf("failed to download bundle: %w", err)
+		}
+		if err := os.WriteFile(bundleOutput, data, 0644); err != nil {
+			return fmt.Errorf("failed to write bundle to %s: %w", bundleOutput, err)
+		}
+
+		fmt.Printf("Wrote %s bundle of %s/%s@%s (%d bytes) to %s\n", bundleFormat, namespace, moduleName, version, len(data), bundleOutput)
+		if dependencies != "" {
+			fmt.Printf("  Dependencies: %s\n", dependencies)
+		}
+		return nil
+	},
+}
+
+// downloadBundle downloads a module version's bundle in format, returning it with the dependency
+// versions it was assembled from (as reported by the registry).
+func downloadBundle(client *http.Client, registryURL, namespace, moduleName, version, format string, log *zap.Logger) ([]byte, string, error) {
+	targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s/bundle?format=%s", strings.TrimSuffix(registryURL, "/"),
+		url.PathEscape(namespace), url.PathEscape(moduleName), url.PathEscape(version), url.QueryEscape(format))
+	log.Info("Fetching bundle", zap.String("url", targetURL))
+
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	setReadToken(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, "", fmt.Errorf("%s/%s@%s: %w", namespace, moduleName, version, registryError(resp.StatusCode, bodyBytes))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read bundle: %w", err)
+	}
+	return data, resp.Header.Get(apitypes.BundleDependenciesHeader), nil
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+
+	bundleCmd.Flags().StringVarP(&bundleOutput, "output", "o", "", "File to write the bundle to (required)")
+	_ = bundleCmd.MarkFlagRequired("output")
+	bundleCmd.Flags().StringVar(&bundleFormat, "format", "zip", "Bundle format: zip (flattened .proto files) or descriptor-set (binary FileDescriptorSet)")
+}
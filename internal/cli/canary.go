@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	canaryPercent  int
+	canaryComplete bool
+)
+
+// canaryCmd represents the canary command
+var canaryCmd = &cobra.Command{
+	Use:   "canary <namespace/module_name> <version>",
+	Short: "Roll a module version out to a share of consumers",
+	Long: `Marks a published module version as canary with a rollout percentage. Clients
+resolving the module's latest version (GET /api/v1/modules/{namespace}/{module_name}/latest)
+get the canary only if they fall in that share of consumers; the others keep getting
+the newest stable version. Consumers are assigned by a hash of their client ID, so
+each keeps getting the same answer and raising the percentage only adds clients.
+
+Running it again changes the percentage. Use --complete to lift the canary mark,
+making the version available to everyone. Requires an API token.
+
+Examples:
+  protoreg-cli canary mycompany/billing v2.0.0 --percent 10
+  protoreg-cli canary mycompany/billing v2.0.0 --percent 50
+  protoreg-cli canary mycompany/billing v2.0.0 --complete`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		apiToken, err := requireAPIToken()
+		if err != nil {
+			return err
+		}
+
+		moduleFullName := qualifyModule(args[0]) // The namespace may be omitted if a default namespace is configured
+		version := args[1]
+		parts := strings.SplitN(moduleFullName, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return exitErrorf(ExitValidation, "invalid module name format %q: expected 'namespace/module_name'", moduleFullName)
+		}
+		if !strings.HasPrefix(version, "v") {
+			return exitErrorf(ExitValidation, "invalid version format %q: must start with 'v'", version)
+		}
+		if canaryComplete == cmd.Flags().Changed("percent") {
+			return exitErrorf(ExitUsage, "exactly one of --percent and --complete is required")
+		}
+		if canaryPercent < 0 || canaryPercent > 100 {
+			return exitErrorf(ExitUsage, "invalid --percent %d: must be between 0 and 100", canaryPercent)
+		}
+
+		targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s/canary", strings.TrimSuffix(registryURL, "/"),
+			url.PathEscape(parts[0]), url.PathEscape(parts[1]), url.PathEscape(version))
+
+		method := http.MethodPut
+		var body io.Reader
+		if canaryComplete {
+			method = http.MethodDelete
+		} else {
+			payload, err := json.Marshal(apitypes.CanaryRequest{Percent: &canaryPercent})
+			if err != nil {
+				return fmt.Errorf("failed to encode request: %w", err)
+			}
+			body = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequest(method, targetURL, body)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		log.Debug("Updating canary status", zap.String("method", method), zap.String("url", targetURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return registryError(resp.StatusCode, bodyBytes)
+		}
+
+		var status apitypes.CanaryResponse
+		if err := json.Unmarshal(bodyBytes, &status); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if !status.Canary {
+			fmt.Printf("%s@%s is rolled out to all consumers\n", moduleFullName, status.Version)
+			return nil
+		}
+		fmt.Printf("%s@%s is a canary for %d%% of consumers\n", moduleFullName, status.Version, status.Percent)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(canaryCmd)
+	canaryCmd.Flags().IntVar(&canaryPercent, "percent", 0, "Share of consumers (0-100) resolving latest to the version")
+	canaryCmd.Flags().BoolVar(&canaryComplete, "complete", false, "Lift the canary mark, rolling the version out to everyone")
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+)
+
+var (
+	adminClientsClient     string
+	adminClientsMinVersion string
+	adminClientsSince      string
+	adminClientsOutdated   bool
+)
+
+// adminClientsCmd represents the admin clients command
+var adminClientsCmd = &cobra.Command{
+	Use:   "clients",
+	Short: "Report which clients and versions each team uses against the registry",
+	Long: `Lists, for each consumer (the team or service a token identifies, "admin" or
+"anonymous"), the clients that sent requests to the registry, as named by their
+User-Agent (e.g. protoreg-cli v1.4.0), with request counts and when they were last
+seen. Requests show up within a minute; clients not seen for 90 days are dropped.
+
+With --min-version, versions of the checked client (--client, default protoreg-cli)
+older than it are flagged, and the consumers whose most recently seen version is
+older are listed, to find who must upgrade before a breaking protocol change.
+
+Requires the admin API token.
+
+Examples:
+  protoreg-cli admin clients
+  protoreg-cli admin clients --min-version v1.4.0 --outdated
+  protoreg-cli admin clients --client Go-http-client --since 2026-01-01`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		query := url.Values{}
+		if adminClientsClient != "" {
+			query.Set("client", adminClientsClient)
+		}
+		if adminClientsMinVersion != "" {
+			query.Set("min_version", adminClientsMinVersion)
+		}
+		if adminClientsSince != "" {
+			query.Set("since", adminClientsSince)
+		}
+		if adminClientsOutdated {
+			query.Set("outdated", "true")
+		}
+		targetURL := strings.TrimSuffix(registryURL, "/") + "/api/v1/admin/clients"
+		if len(query) > 0 {
+			targetURL += "?" + query.Encode()
+		}
+		bodyBytes, err := adminRequest(http.MethodGet, targetURL, apiToken, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var inventory apitypes.ClientInventoryResponse
+		if err := json.Unmarshal(bodyBytes, &inventory); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CONSUMER\tCLIENT\tVERSION\tREQUESTS\tLAST SEEN")
+		for _, c := range inventory.Clients {
+			version := orNone(c.Version)
+			if c.Outdated {
+				version += " (outdated)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", c.Consumer, c.Client, version, c.Requests, c.LastSeenAt.Local().Format("2006-01-02 15:04"))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		if inventory.MinVersion != "" {
+			if len(inventory.OutdatedConsumers) == 0 {
+				fmt.Printf("\nEvery consumer runs %s %s or newer\n", inventory.Client, inventory.MinVersion)
+			} else {
+				fmt.Printf("\n%d consumer(s) still run %s older than %s: %s\n", len(inventory.OutdatedConsumers), inventory.Client, inventory.MinVersion, strings.Join(inventory.OutdatedConsumers, ", "))
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	adminCmd.AddCommand(adminClientsCmd)
+	adminClientsCmd.Flags().StringVar(&adminClientsClient, "client", "", "Only report this client, and check it against --min-version (default: all clients; protoreg-cli is checked)")
+	adminClientsCmd.Flags().StringVar(&adminClientsMinVersion, "min-version", "", "Flag versions of the checked client older than this one, e.g. v1.4.0")
+	adminClientsCmd.Flags().StringVar(&adminClientsSince, "since", "", "Only report clients seen since this date (2006-01-02) or RFC3339 timestamp")
+	adminClientsCmd.Flags().BoolVar(&adminClientsOutdated, "outdated", false, "Only report consumers whose most recent version is older than --min-version")
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var compatVersion string
+
+// compatCmd represents the compat command
+var compatCmd = &cobra.Command{
+	Use:   "compat [namespace/module_name|directory]",
+	Short: "Show which dependency versions each version of a module is compatible with",
+	Long: `Prints the compatibility matrix of a module: for each published version, the dependencies
+declared in its sproto.yaml, their constraints, and the ranges of published dependency
+versions satisfying them. The newest compatible version is the one 'deps install' and the
+registry resolve to. A dependency without compatible versions ("-") can't be installed.
+
+The module argument works like for 'list': a module name, or a module directory whose
+sproto.yaml provides the name (default ".").
+
+Examples:
+  protoreg-cli compat mycompany/orders
+  protoreg-cli compat mycompany/orders --version v1.2.0`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		moduleArg := "."
+		if len(args) > 0 {
+			moduleArg = args[0]
+		}
+		namespace, moduleName, _, err := resolveModuleArg(moduleArg, "")
+		if err != nil {
+			return exitErrorf(ExitValidation, "invalid module: %w", err)
+		}
+
+		targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/compatibility", strings.TrimSuffix(registryURL, "/"), url.PathEscape(namespace), url.PathEscape(moduleName))
+		req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		setReadToken(req)
+		log.Info("Requesting compatibility matrix", zap.String("url", targetURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s/%s: %w", namespace, moduleName, registryError(resp.StatusCode, bodyBytes))
+		}
+
+		var matrix descriptor.CompatibilityMatrix
+		if err := json.Unmarshal(bodyBytes, &matrix); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tDEPENDENCY\tCONSTRAINT\tCOMPATIBLE\tNEWEST")
+		found := false
+		for _, v := range matrix.Versions {
+			if compatVersion != "" && v.Version != compatVersion {
+				continue
+			}
+			found = true
+			switch {
+			case v.Error != "":
+				fmt.Fprintf(tw, "%s\t(unreadable: %s)\t\t\t\n", v.Version, v.Error)
+			case len(v.Dependencies) == 0:
+				fmt.Fprintf(tw, "%s\t(no dependencies)\t\t\t\n", v.Version)
+			}
+			for _, dep := range v.Dependencies {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", v.Version, dep.Module, orDash(dep.Constraint), formatRanges(dep.Ranges), orDash(dep.Newest))
+			}
+		}
+		if compatVersion != "" && !found {
+			return exitErrorf(ExitNotFound, "%s/%s has no published version %s", namespace, moduleName, compatVersion)
+		}
+		return tw.Flush()
+	},
+}
+
+// formatRanges renders version ranges as "v1.0.0 - v1.2.0, v2.0.0", or "-" if there are none.
+func formatRanges(ranges []descriptor.VersionRange) string {
+	if len(ranges) == 0 {
+		return "-"
+	}
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r.From == r.To {
+			parts = append(parts, r.From)
+		} else {
+			parts = append(parts, r.From+" - "+r.To)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	rootCmd.AddCommand(compatCmd)
+	compatCmd.Flags().StringVar(&compatVersion, "version", "", "Only show this version of the module")
+}
@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/Suhaibinator/SProto/internal/translog"
+	"github.com/Suhaibinator/SProto/internal/validation"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -14,33 +16,68 @@ import (
 var (
 	configureRegistryURL string
 	configureApiToken    string
+	configureReadToken   string
+
+	configureDefaultNamespace string
+
+	configureRegistryPublicKey string
+
+	configureProfile string
 )
 
 // configureCmd represents the configure command
 var configureCmd = &cobra.Command{
 	Use:   "configure",
-	Short: "Configure registry URL and API token",
-	Long: `Saves the SProto registry server URL and API token to the configuration file.
+	Short: "Configure registry URL, API and read tokens, default namespace and registry public key",
+	Long: `Saves the SProto registry server URL, API token, read token, default namespace and registry public key
+to the configuration file.
 Configuration is stored in ~/.config/protoreg/config.yaml by default.
 
+With a default namespace, module names can be given without it (e.g. "billing" for
+"mycompany/billing"); it can also be set with PROTOREG_DEFAULT_NAMESPACE.
+
+With a registry public key (printed by 'sproto-server gen-checksum-key'; also
+PROTOREG_REGISTRY_PUBLIC_KEY), fetch and deps verify every artifact they download against
+the registry's signed checksum statement and refuse artifacts that don't match.
+
+With a read token (also PROTOREG_READ_TOKEN), commands that only read from the registry
+(fetch, list, search, deps, ...) authenticate with it instead of the API token, so machines
+that only consume modules, or registries with anonymous read disabled, don't need the
+publish token.
+
+With --profile, the registry URL, tokens and public key are saved as a named profile instead
+(profiles.<name> in the configuration file), for commands that work with several registries,
+such as 'protoreg-cli promote'. Other commands use the top-level settings, the "default" profile.
+
 Precedence order for configuration values:
-1. Command-line flags (--registry-url, --api-token)
-2. Environment variables (PROTOREG_REGISTRY_URL, PROTOREG_API_TOKEN)
+1. Command-line flags (--registry-url, --api-token, --read-token)
+2. Environment variables (PROTOREG_REGISTRY_URL, PROTOREG_API_TOKEN, PROTOREG_READ_TOKEN)
 3. Configuration file (~/.config/protoreg/config.yaml)
 4. Default values
 
 This command updates the configuration file directly.`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		log := GetLogger()
 
 		// Check if at least one flag was provided
 		urlFlagSet := cmd.Flags().Changed("registry-url")
 		tokenFlagSet := cmd.Flags().Changed("api-token")
+		readTokenFlagSet := cmd.Flags().Changed("read-token")
+		namespaceFlagSet := cmd.Flags().Changed("default-namespace")
+		publicKeyFlagSet := cmd.Flags().Changed("registry-public-key")
 
-		if !urlFlagSet && !tokenFlagSet {
-			log.Error("At least one flag (--registry-url or --api-token) must be provided")
+		if !urlFlagSet && !tokenFlagSet && !readTokenFlagSet && !namespaceFlagSet && !publicKeyFlagSet {
 			_ = cmd.Usage() // Show usage information
-			os.Exit(1)
+			return exitErrorf(ExitUsage, "at least one flag (--registry-url, --api-token, --read-token, --default-namespace or --registry-public-key) must be provided")
+		}
+
+		if configureProfile != "" {
+			if err := validateProfileName(configureProfile); err != nil {
+				return withExitCode(ExitUsage, err)
+			}
+			if namespaceFlagSet && configureProfile != defaultProfile {
+				return exitErrorf(ExitUsage, "--default-namespace can't be set for a profile; it applies to every registry")
+			}
 		}
 
 		// Determine config file path
@@ -50,7 +87,7 @@ This command updates the configuration file directly.`,
 		} else {
 			home, err := homedir.Dir()
 			if err != nil {
-				log.Fatal("Failed to get home directory", zap.Error(err))
+				return fmt.Errorf("failed to get home directory: %w", err)
 			}
 			configFilePath = filepath.Join(home, ".config", "protoreg", "config.yaml")
 		}
@@ -58,18 +95,40 @@ This command updates the configuration file directly.`,
 
 		// Ensure config directory exists
 		if err := os.MkdirAll(configDir, 0750); err != nil { // Use 0750 for permissions
-			log.Fatal("Failed to create config directory", zap.String("path", configDir), zap.Error(err))
+			return fmt.Errorf("failed to create config directory %s: %w", configDir, err)
 		}
 
 		// Update viper settings based on flags
 		if urlFlagSet {
-			viper.Set("registry_url", configureRegistryURL)
-			log.Info("Setting registry_url in config", zap.String("value", configureRegistryURL))
+			viper.Set(profileKey(configureProfile, "registry_url"), configureRegistryURL)
+			log.Info("Setting registry_url in config", zap.String("value", configureRegistryURL), zap.String("profile", configureProfile))
 		}
 		if tokenFlagSet {
-			viper.Set("api_token", configureApiToken)
+			viper.Set(profileKey(configureProfile, "api_token"), configureApiToken)
 			log.Info("Setting api_token in config") // Don't log the token itself
 		}
+		if readTokenFlagSet {
+			viper.Set(profileKey(configureProfile, "read_token"), configureReadToken) // "" removes it
+			log.Info("Setting read_token in config")
+		}
+		if namespaceFlagSet {
+			if configureDefaultNamespace != "" {
+				if err := validation.ValidateNamespace(configureDefaultNamespace); err != nil {
+					return exitErrorf(ExitValidation, "invalid default namespace: %w", err)
+				}
+			}
+			viper.Set("default_namespace", configureDefaultNamespace) // "" removes the default
+			log.Info("Setting default_namespace in config", zap.String("value", configureDefaultNamespace))
+		}
+		if publicKeyFlagSet {
+			if configureRegistryPublicKey != "" {
+				if _, err := translog.ParsePublicKey(configureRegistryPublicKey); err != nil {
+					return exitErrorf(ExitValidation, "invalid registry public key: %w", err)
+				}
+			}
+			viper.Set(profileKey(configureProfile, "registry_public_key"), configureRegistryPublicKey) // "" turns verification off
+			log.Info("Setting registry_public_key in config", zap.String("value", configureRegistryPublicKey))
+		}
 
 		// Write the config file
 		log.Info("Writing configuration", zap.String("path", configFilePath))
@@ -79,14 +138,15 @@ This command updates the configuration file directly.`,
 			if os.IsNotExist(err) {
 				err = viper.SafeWriteConfigAs(configFilePath) // Attempt safe write first
 				if err != nil {
-					log.Fatal("Failed to write new config file", zap.String("path", configFilePath), zap.Error(err))
+					return fmt.Errorf("failed to write new config file %s: %w", configFilePath, err)
 				}
 			} else {
-				log.Fatal("Failed to write config file", zap.String("path", configFilePath), zap.Error(err))
+				return fmt.Errorf("failed to write config file %s: %w", configFilePath, err)
 			}
 		}
 
 		fmt.Printf("Configuration successfully saved to %s\n", configFilePath)
+		return nil
 	},
 }
 
@@ -96,6 +156,10 @@ func init() {
 	// Flags specific to the configure command
 	configureCmd.Flags().StringVar(&configureRegistryURL, "registry-url", "", "Registry server URL to save")
 	configureCmd.Flags().StringVar(&configureApiToken, "api-token", "", "API token to save")
+	configureCmd.Flags().StringVar(&configureReadToken, "read-token", "", "Read-only token to save, used by commands that only read (empty to unset)")
+	configureCmd.Flags().StringVar(&configureDefaultNamespace, "default-namespace", "", "Namespace assumed for module names given without one (empty to unset)")
+	configureCmd.Flags().StringVar(&configureRegistryPublicKey, "registry-public-key", "", "Registry's checksum statement public key; downloads are verified against it (empty to unset)")
+	configureCmd.Flags().StringVar(&configureProfile, "profile", "", "Save the registry URL, tokens and public key as this named profile (e.g. staging) instead of the defaults")
 
 	// We don't mark them as required here because the Run function checks if at least one is set.
 }
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A publish can't be undone (versions are immutable), so publish shows what it is about to do and asks
+// before uploading. --yes and CI environments skip the question; other non-interactive runs must pass
+// --yes, so a script pointed at the wrong registry fails instead of publishing.
+
+// errPublishCancelled is returned when the user declines the confirmation prompt.
+var errPublishCancelled = errors.New("publish cancelled")
+
+// publishSummary describes a publish for the confirmation prompt.
+type publishSummary struct {
+	Module   string // namespace/name
+	Version  string
+	Registry string
+	Files    int    // Files in the artifact; 0 for a republish
+	Size     int    // Artifact size in bytes; 0 for a republish
+	Digest   string // sha256:<hex>; "" for a republish
+	From     string // Source version of a republish (--from)
+}
+
+// print writes the summary, one field per line.
+func (s publishSummary) print(w io.Writer) {
+	fmt.Fprintf(w, "About to publish %s@%s\n", s.Module, s.Version)
+	fmt.Fprintf(w, "  Registry: %s\n", s.Registry)
+	if s.From != "" {
+		fmt.Fprintf(w, "  From:     %s (its artifact is reused)\n", s.From)
+		return
+	}
+	fmt.Fprintf(w, "  Files:    %d\n", s.Files)
+	fmt.Fprintf(w, "  Size:     %d bytes\n", s.Size)
+	fmt.Fprintf(w, "  Digest:   %s\n", s.Digest)
+}
+
+// confirmPublish prints the summary and asks for confirmation on in/out, unless assumeYes is set.
+// interactive reports whether in is a terminal; without one nothing can be confirmed, so the publish
+// is refused with a usage error.
+func confirmPublish(s publishSummary, assumeYes, interactive bool, in io.Reader, out io.Writer) error {
+	if assumeYes {
+		return nil
+	}
+	if !interactive {
+		return exitErrorf(ExitUsage, "refusing to publish %s@%s to %s without confirmation: stdin is not a terminal; pass --yes (or set CI=true) to publish non-interactively",
+			s.Module, s.Version, s.Registry)
+	}
+	s.print(out)
+	fmt.Fprint(out, "Publish? [y/N] ")
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errPublishCancelled
+}
+
+// ciMode reports whether the CLI runs in a CI environment, detected by the CI variable that CI
+// systems (GitHub Actions, GitLab CI, CircleCI, ...) set.
+func ciMode() bool {
+	switch strings.ToLower(os.Getenv("CI")) {
+	case "", "0", "false", "no":
+		return false
+	}
+	return true
+}
+
+// isTerminal reports whether f is an interactive terminal (a character device, not a pipe or file).
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// zipFileNames returns the sorted names of the files (not directories) in an artifact.
+func zipFileNames(zipData []byte) ([]string, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, f := range zipReader.File {
+		if !f.FileInfo().IsDir() {
+			files = append(files, f.Name)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmPublish(t *testing.T) {
+	summary := publishSummary{Module: "acme/user", Version: "v1.2.0", Registry: "https://registry.example.com", Files: 3, Size: 812, Digest: "sha256:7ca2"}
+
+	// --yes / CI: no question asked
+	out := new(bytes.Buffer)
+	require.NoError(t, confirmPublish(summary, true, false, strings.NewReader(""), out))
+	assert.Empty(t, out.String())
+
+	// No terminal to ask on: refused
+	err := confirmPublish(summary, false, false, strings.NewReader("y\n"), out)
+	require.Error(t, err)
+	assert.Equal(t, ExitUsage, exitCode(err))
+	assert.Contains(t, err.Error(), "--yes")
+
+	for answer, confirmed := range map[string]bool{"y\n": true, "YES\n": true, " yes ": true, "n\n": false, "\n": false, "": false} {
+		out.Reset()
+		err := confirmPublish(summary, false, true, strings.NewReader(answer), out)
+		if confirmed {
+			assert.NoError(t, err, "answer %q", answer)
+		} else {
+			assert.ErrorIs(t, err, errPublishCancelled, "answer %q", answer)
+		}
+		assert.Contains(t, out.String(), "About to publish acme/user@v1.2.0")
+		assert.Contains(t, out.String(), "Registry: https://registry.example.com")
+		assert.Contains(t, out.String(), "Files:    3")
+		assert.Contains(t, out.String(), "Digest:   sha256:7ca2")
+		assert.True(t, strings.HasSuffix(out.String(), "Publish? [y/N] "))
+	}
+
+	// Republish: no artifact to describe
+	out.Reset()
+	summary = publishSummary{Module: "acme/user", Version: "v1.2.0", Registry: "https://registry.example.com", From: "v1.2.0-rc.1"}
+	require.NoError(t, confirmPublish(summary, false, true, strings.NewReader("y\n"), out))
+	assert.Contains(t, out.String(), "From:     v1.2.0-rc.1")
+	assert.NotContains(t, out.String(), "Files:")
+}
+
+func TestCIMode(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "false": false, "0": false, "true": true, "1": true, "woodpecker": true} {
+		t.Setenv("CI", value)
+		assert.Equal(t, want, ciMode(), "CI=%q", value)
+	}
+}
+
+func TestZipFileNames(t *testing.T) {
+	files, err := zipFileNames(buildZip(t, "sproto.yaml", "acme/", "acme/b.proto", "acme/a.proto"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme/a.proto", "acme/b.proto", "sproto.yaml"}, files)
+
+	_, err = zipFileNames([]byte("not a zip"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	consumersVersion string
+	consumersSince   string
+)
+
+// consumersCmd represents the consumers command
+var consumersCmd = &cobra.Command{
+	Use:   "consumers [namespace/module_name|directory]",
+	Short: "Report who downloads which versions of a module",
+	Long: `Lists the consumers that downloaded versions of a module (artifacts or bundles), so they
+can be notified before a breaking change. Consumers are the teams or services named for
+their read token on the registry, "token:<id>" for unnamed read tokens, "admin" and
+"anonymous". Downloads show up in the report within a minute.
+
+The module argument works like for 'list': a module name, or a module directory whose
+sproto.yaml provides the name (default ".").
+
+Examples:
+  protoreg-cli consumers mycompany/billing
+  protoreg-cli consumers mycompany/billing --version v1.2.0 --since 2026-01-01`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		moduleArg := "."
+		if len(args) > 0 {
+			moduleArg = args[0]
+		}
+		namespace, moduleName, _, err := resolveModuleArg(moduleArg, "")
+		if err != nil {
+			return exitErrorf(ExitValidation, "invalid module: %w", err)
+		}
+
+		query := url.Values{}
+		if consumersVersion != "" {
+			query.Set("version", consumersVersion)
+		}
+		if consumersSince != "" {
+			query.Set("since", consumersSince)
+		}
+		targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/consumers", strings.TrimSuffix(registryURL, "/"), url.PathEscape(namespace), url.PathEscape(moduleName))
+		if len(query) > 0 {
+			targetURL += "?" + query.Encode()
+		}
+		req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		setReadToken(req)
+		log.Info("Requesting consumption report", zap.String("url", targetURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s/%s: %w", namespace, moduleName, registryError(resp.StatusCode, bodyBytes))
+		}
+
+		var report apitypes.ModuleConsumersResponse
+		if err := json.Unmarshal(bodyBytes, &report); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if len(report.Consumers) == 0 {
+			fmt.Printf("No recorded downloads of %s/%s\n", report.Namespace, report.ModuleName)
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CONSUMER\tDOWNLOADS\tLAST DOWNLOAD\tVERSIONS")
+		for _, c := range report.Consumers {
+			versions := make([]string, 0, len(c.Versions))
+			for _, v := range c.Versions {
+				versions = append(versions, fmt.Sprintf("%s (%d)", v.Version, v.FetchCount))
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", c.Consumer, c.FetchCount, c.LastFetchedAt.Local().Format(time.RFC3339), strings.Join(versions, ", "))
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(consumersCmd)
+	consumersCmd.Flags().StringVar(&consumersVersion, "version", "", "Only report downloads of this version")
+	consumersCmd.Flags().StringVar(&consumersSince, "since", "", "Only report consumers with downloads since this date (2006-01-02) or RFC3339 timestamp")
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	deprecateMessage string
+	deprecateSunset  string
+	deprecateUndo    bool
+)
+
+// deprecateCmd represents the deprecate command
+var deprecateCmd = &cobra.Command{
+	Use:   "deprecate <namespace/module_name> <version>",
+	Short: "Mark a module version as deprecated",
+	Long: `Marks a published module version as deprecated, optionally with a message telling
+consumers why and what to use instead. Deprecated versions can still be fetched;
+the status is shown by 'protoreg-cli info' and returned by the API.
+
+With --sunset, downloads of the version stop after the given date (the registry
+responds 410 Gone, or only logs a warning if it is configured to). Until then,
+downloads carry a Sunset header.
+
+Running it again updates the message and sunset date (omitting --sunset removes it).
+Use --undo to lift the deprecation. Requires an API token.
+
+Examples:
+  protoreg-cli deprecate mycompany/billing v2.0.0 --message "rounding bug, use v2.0.1"
+  protoreg-cli deprecate mycompany/billing v1.0.0 --message "use v2" --sunset 2026-12-31
+  protoreg-cli deprecate mycompany/billing v2.0.0 --undo`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		apiToken, err := requireAPIToken()
+		if err != nil {
+			return err
+		}
+
+		moduleFullName := qualifyModule(args[0]) // The namespace may be omitted if a default namespace is configured
+		version := args[1]
+		parts := strings.SplitN(moduleFullName, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return exitErrorf(ExitValidation, "invalid module name format %q: expected 'namespace/module_name'", moduleFullName)
+		}
+		if !strings.HasPrefix(version, "v") {
+			return exitErrorf(ExitValidation, "invalid version format %q: must start with 'v'", version)
+		}
+		if deprecateUndo && (cmd.Flags().Changed("message") || cmd.Flags().Changed("sunset")) {
+			return exitErrorf(ExitUsage, "--message and --sunset can't be combined with --undo")
+		}
+		if _, err := apitypes.ParseTokenExpiry(deprecateSunset); err != nil {
+			return exitErrorf(ExitUsage, "invalid --sunset %q: expected a date (2006-01-02) or an RFC3339 timestamp", deprecateSunset)
+		}
+
+		targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s/deprecation", strings.TrimSuffix(registryURL, "/"),
+			url.PathEscape(parts[0]), url.PathEscape(parts[1]), url.PathEscape(version))
+
+		method := http.MethodPut
+		var body io.Reader
+		if deprecateUndo {
+			method = http.MethodDelete
+		} else {
+			payload, err := json.Marshal(apitypes.DeprecateModuleVersionRequest{Message: deprecateMessage, SunsetAt: deprecateSunset})
+			if err != nil {
+				return fmt.Errorf("failed to encode request: %w", err)
+			}
+			body = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequest(method, targetURL, body)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		log.Debug("Updating deprecation status", zap.String("method", method), zap.String("url", targetURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return registryError(resp.StatusCode, bodyBytes)
+		}
+
+		var status apitypes.DeprecationResponse
+		if err := json.Unmarshal(bodyBytes, &status); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if !status.Deprecated {
+			fmt.Printf("%s@%s is no longer deprecated\n", moduleFullName, status.Version)
+			return nil
+		}
+		fmt.Printf("%s@%s is deprecated", moduleFullName, status.Version)
+		if status.Message != "" {
+			fmt.Printf(": %s", status.Message)
+		}
+		fmt.Println()
+		if status.SunsetAt != nil {
+			if status.Sunset {
+				fmt.Printf("Sunset since %s (enforced)\n", status.SunsetAt.Local().Format(time.RFC3339))
+			} else {
+				fmt.Printf("Downloads stop after %s\n", status.SunsetAt.Local().Format(time.RFC3339))
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deprecateCmd)
+	deprecateCmd.Flags().StringVarP(&deprecateMessage, "message", "m", "", "Why the version is deprecated / what to use instead")
+	deprecateCmd.Flags().StringVar(&deprecateSunset, "sunset", "", "Stop serving downloads after this date (2006-01-02) or RFC3339 timestamp")
+	deprecateCmd.Flags().BoolVar(&deprecateUndo, "undo", false, "Lift the deprecation")
+}
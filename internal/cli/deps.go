@@ -0,0 +1,377 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	depsDir          string
+	depsUpdateLatest bool
+
+	depsInstallOutput string
+	depsInstallLayout string
+)
+
+// defaultDepsOutputDir is where deps install extracts to, relative to --dir.
+const defaultDepsOutputDir = "sproto_deps"
+
+// depsCmd groups the dependency management commands
+var depsCmd = &cobra.Command{
+	Use:   "deps",
+	Short: "Manage module dependencies declared in sproto.yaml",
+	Long: `Commands for resolving and locking the dependencies declared in sproto.yaml.
+Resolved versions and artifact digests are pinned in sproto.lock.`,
+}
+
+// depsUpdateCmd represents the deps update command
+var depsUpdateCmd = &cobra.Command{
+	Use:   "update [namespace/module_name]",
+	Short: "Update sproto.lock to the newest versions allowed by sproto.yaml",
+	Long: `Resolves the dependencies in sproto.yaml (including dependencies of dependencies, read from
+the sproto.yaml packaged in each artifact) and rewrites sproto.lock.
+
+Without arguments every dependency moves to the newest version satisfying all constraints.
+With a module argument only that module is bumped; other dependencies keep their locked
+versions unless a new constraint forces them to change.
+
+--latest ignores the constraints for the updated module(s) and picks the newest published version.
+
+The registry resolves the dependencies (POST /api/v1/resolve), so every client gets the same
+result; with registries that don't support it, they are resolved locally.
+
+Examples:
+  protoreg-cli deps update
+  protoreg-cli deps update mycompany/user
+  protoreg-cli deps update mycompany/user --latest`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+
+		m, err := manifest.LoadManifest(filepath.Join(depsDir, manifest.ManifestFileName))
+		if err != nil {
+			return fmt.Errorf("failed to read manifest: %w", err)
+		}
+		lockPath := filepath.Join(depsDir, manifest.LockFileName)
+		lock, err := manifest.LoadLock(lockPath)
+		if errors.Is(err, os.ErrNotExist) {
+			lock = &manifest.Lock{}
+		} else if err != nil {
+			return fmt.Errorf("failed to read lockfile: %w", err)
+		}
+
+		opts := manifest.ResolveOptions{Latest: depsUpdateLatest}
+		if len(args) == 1 {
+			if _, _, err := manifest.SplitModule(args[0]); err != nil {
+				return exitErrorf(ExitValidation, "invalid module name: %w", err)
+			}
+			if _, ok := m.Dependencies[args[0]]; !ok && lock.Find(args[0]) == nil {
+				return exitErrorf(ExitUsage, "%s is not a dependency", args[0])
+			}
+			opts.Update = map[string]bool{args[0]: true}
+		}
+
+		client := &http.Client{}
+		updated, err := resolveOnRegistry(client, registryURL, m, lock, opts, log)
+		if errors.Is(err, errResolveUnsupported) {
+			log.Warn("The registry doesn't resolve dependencies; resolving locally")
+			source := &registrySource{client: client, registryURL: registryURL, log: log}
+			updated, err = manifest.Resolve(m, lock, source, opts)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to resolve dependencies: %w", err)
+		}
+
+		if err := updated.Save(lockPath); err != nil {
+			return fmt.Errorf("failed to write lockfile: %w", err)
+		}
+		printLockChanges(lock, updated)
+		return nil
+	},
+}
+
+// depsInstallCmd represents the deps install command
+var depsInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Download the dependency versions pinned in sproto.lock",
+	Long: `Downloads every dependency pinned in sproto.lock, checks each artifact against the locked
+digest and extracts it into the output directory (default: sproto_deps next to sproto.lock).
+The install is all or nothing: if any artifact doesn't match its digest, nothing is extracted,
+and if an extraction fails, what the install already extracted is removed again.
+
+--layout nested (the default) extracts each artifact to <output>/<namespace>/<name>/<version>/.
+--layout flat extracts only the .proto files of all dependencies directly into <output>, so it can be
+used as a single include path (protoc -I <output>); files of different dependencies with the same
+path but different content are reported as a conflict before anything is written.
+
+Run 'protoreg-cli deps update' first to create or refresh sproto.lock.
+
+Examples:
+  protoreg-cli deps install
+  protoreg-cli deps install --dir ./protos --output ./include --layout flat`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		if err := validateLayout(depsInstallLayout); err != nil {
+			return exitErrorf(ExitUsage, "invalid --layout: %w", err)
+		}
+		outputDir := depsInstallOutput
+		if outputDir == "" {
+			outputDir = filepath.Join(depsDir, defaultDepsOutputDir)
+		}
+
+		lock, err := manifest.LoadLock(filepath.Join(depsDir, manifest.LockFileName))
+		if errors.Is(err, os.ErrNotExist) {
+			return exitErrorf(ExitUsage, "no sproto.lock found in %s; run 'protoreg-cli deps update' first", depsDir)
+		} else if err != nil {
+			return fmt.Errorf("failed to read lockfile: %w", err)
+		}
+		if len(lock.Dependencies) == 0 {
+			fmt.Println("No dependencies to install.")
+			return nil
+		}
+
+		// --- Download and verify everything before writing ---
+		// Every artifact must match the digest pinned in sproto.lock; mismatches are collected so they are
+		// all reported, and nothing is extracted if there is any
+		client := &http.Client{}
+		artifacts := make([][]byte, len(lock.Dependencies))
+		owners := make(map[string]string)    // Lowercased path -> module, for flat layout conflicts
+		checksums := make(map[string]uint32) // Lowercased path -> CRC-32 of its content
+		var mismatches []string
+		for i, dep := range lock.Dependencies {
+			namespace, name, err := manifest.SplitModule(dep.Module)
+			if err != nil {
+				return exitErrorf(ExitValidation, "invalid module in lockfile: %w", err)
+			}
+			if dep.Digest == "" {
+				return exitErrorf(ExitValidation, "%s@%s has no digest in sproto.lock; run 'protoreg-cli deps update' to pin it", dep.Module, dep.Version)
+			}
+			zipData, err := downloadArtifact(client, registryURL, namespace, name, dep.Version, log)
+			if err != nil {
+				return fmt.Errorf("failed to fetch artifact of %s: %w", dep.Module, err)
+			}
+			sum := sha256.Sum256(zipData)
+			if digest := "sha256:" + hex.EncodeToString(sum[:]); digest != dep.Digest {
+				log.Warn("Artifact digest does not match sproto.lock", zap.String("module", dep.Module), zap.String("version", dep.Version), zap.String("locked", dep.Digest), zap.String("downloaded", digest))
+				mismatches = append(mismatches, fmt.Sprintf("%s@%s (locked %s, got %s)", dep.Module, dep.Version, dep.Digest, digest))
+				continue
+			}
+			if depsInstallLayout == layoutFlat {
+				sums, err := zipFileChecksums(zipData, layoutFilter(layoutFlat))
+				if err != nil {
+					return exitErrorf(ExitValidation, "invalid artifact of %s: %w", dep.Module, err)
+				}
+				for p, crc := range sums {
+					if other, ok := owners[p]; ok && checksums[p] != crc {
+						return exitErrorf(ExitUsage, "dependencies %s and %s contain different files with the same path %s; use --layout nested", other, dep.Module, p)
+					}
+					owners[p], checksums[p] = dep.Module, crc
+				}
+			}
+			artifacts[i] = zipData
+		}
+		if len(mismatches) > 0 {
+			return exitErrorf(ExitValidation, "artifact digests do not match sproto.lock, nothing was installed: %s", strings.Join(mismatches, "; "))
+		}
+
+		// --- Extract ---
+		// The install is all or nothing: if an extraction fails, what the install created so far (version
+		// directories, or files in the flat layout) is removed again
+		rollback := &extractionRollback{root: outputDir}
+		defer func() {
+			if err != nil {
+				rollback.undo()
+			}
+		}()
+		for i, dep := range lock.Dependencies {
+			namespace, name, _ := manifest.SplitModule(dep.Module)
+			target := extractionPath(outputDir, depsInstallLayout, namespace, name, dep.Version)
+			if err := rollback.track(artifacts[i], target, depsInstallLayout); err != nil {
+				return exitErrorf(ExitValidation, "invalid artifact of %s: %w", dep.Module, err)
+			}
+			count, err := extractZipFiltered(artifacts[i], target, layoutFilter(depsInstallLayout), log)
+			if err != nil {
+				return fmt.Errorf("failed to extract artifact of %s to %s: %w", dep.Module, target, err)
+			}
+			fmt.Printf("  %s %s (%d files)\n", dep.Module, dep.Version, count)
+		}
+		fmt.Printf("Installed %d dependencies to %s (%s layout).\n", len(lock.Dependencies), outputDir, depsInstallLayout)
+		return nil
+	},
+}
+
+// errResolveUnsupported means the registry predates server-side resolution.
+var errResolveUnsupported = errors.New("registry does not support resolving dependencies")
+
+// resolveOnRegistry resolves the dependencies of m with the registry's resolver (POST /api/v1/resolve).
+// Conflicts are reported with the registry's explanation (exit code ExitConflict).
+func resolveOnRegistry(client *http.Client, registryURL string, m *manifest.Manifest, lock *manifest.Lock, opts manifest.ResolveOptions, log *zap.Logger) (*manifest.Lock, error) {
+	body := apitypes.ResolveRequest{Dependencies: m.Dependencies, Latest: opts.Latest}
+	for _, dep := range lock.Dependencies {
+		body.Locked = append(body.Locked, apitypes.ResolvedDependency{Module: dep.Module, Version: dep.Version})
+	}
+	for module := range opts.Update {
+		body.Update = append(body.Update, module)
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	targetURL := strings.TrimSuffix(registryURL, "/") + "/api/v1/resolve"
+	req, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setReadToken(req)
+	log.Info("Resolving dependencies on the registry", zap.String("url", targetURL))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return nil, errResolveUnsupported
+	default:
+		return nil, registryError(resp.StatusCode, bodyBytes)
+	}
+
+	var resolved apitypes.ResolveResponse
+	if err := json.Unmarshal(bodyBytes, &resolved); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	result := &manifest.Lock{LockVersion: manifest.LockFormatVersion}
+	for _, dep := range resolved.Dependencies {
+		result.Dependencies = append(result.Dependencies, manifest.LockedDependency{Module: dep.Module, Version: dep.Version, Digest: dep.Digest})
+	}
+	return result, nil
+}
+
+// registrySource resolves dependencies against the registry API.
+type registrySource struct {
+	client      *http.Client
+	registryURL string
+	log         *zap.Logger
+}
+
+// Versions lists the published versions of a module.
+func (s *registrySource) Versions(module string) ([]string, error) {
+	namespace, name, err := manifest.SplitModule(module)
+	if err != nil {
+		return nil, err
+	}
+	return getModuleVersions(s.client, s.registryURL, namespace, name, s.log)
+}
+
+// Manifest downloads a module version's artifact and returns its packaged sproto.yaml and digest.
+func (s *registrySource) Manifest(module, version string) (*manifest.Manifest, string, error) {
+	namespace, name, err := manifest.SplitModule(module)
+	if err != nil {
+		return nil, "", err
+	}
+	zipData, err := downloadArtifact(s.client, s.registryURL, namespace, name, version, s.log)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(zipData)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+
+	m, err := readPackagedManifest(zipData)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s@%s: %w", module, version, err)
+	}
+	return m, digest, nil
+}
+
+// readPackagedManifest returns the sproto.yaml at the root of an artifact, or nil if there is none.
+func readPackagedManifest(zipData []byte) (*manifest.Manifest, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive reader: %w", err)
+	}
+	for _, f := range zipReader.File {
+		if f.Name != manifest.ManifestFileName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		return manifest.ParseManifest(data)
+	}
+	return nil, nil
+}
+
+// printLockChanges prints the differences between the old and new lockfile.
+func printLockChanges(before, after *manifest.Lock) {
+	changes := 0
+	for _, dep := range after.Dependencies {
+		switch old := before.Find(dep.Module); {
+		case old == nil:
+			fmt.Printf("  + %s %s\n", dep.Module, dep.Version)
+			changes++
+		case old.Version != dep.Version:
+			fmt.Printf("  ~ %s %s -> %s\n", dep.Module, old.Version, dep.Version)
+			changes++
+		}
+	}
+	for _, dep := range before.Dependencies {
+		if after.Find(dep.Module) == nil {
+			fmt.Printf("  - %s %s\n", dep.Module, dep.Version)
+			changes++
+		}
+	}
+	if changes == 0 {
+		fmt.Printf("%s is up to date.\n", manifest.LockFileName)
+	} else {
+		fmt.Printf("Updated %s (%d changes).\n", manifest.LockFileName, changes)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(depsCmd)
+	depsCmd.AddCommand(depsUpdateCmd)
+	depsCmd.AddCommand(depsInstallCmd)
+
+	depsCmd.PersistentFlags().StringVarP(&depsDir, "dir", "d", ".", "Directory containing sproto.yaml and sproto.lock")
+	depsUpdateCmd.Flags().BoolVar(&depsUpdateLatest, "latest", false, "Ignore sproto.yaml constraints and pick the newest published version")
+	depsInstallCmd.Flags().StringVarP(&depsInstallOutput, "output", "o", "", "Directory to extract the dependencies into (default: sproto_deps in --dir)")
+	depsInstallCmd.Flags().StringVar(&depsInstallLayout, "layout", layoutNested, "Output layout: nested (<output>/<namespace>/<name>/<version>/) or flat (.proto files directly in <output>, an include path)")
+}
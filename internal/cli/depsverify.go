@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var depsVerifyStrict bool
+
+// depsVerifyCmd represents the deps verify command
+var depsVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the versions pinned in sproto.lock against the registry without downloading",
+	Long: `Asks the registry (POST /api/v1/verify) whether each digest pinned in sproto.lock is the
+digest it recorded for that version, and whether the version has been deprecated or withdrawn
+(past its sunset date, so downloads are refused). Nothing is downloaded, which makes it a cheap
+supply-chain check for CI.
+
+Each dependency is printed with its status:
+
+  ok          the digest matches
+  deprecated  the digest matches, but the version is deprecated (the message is shown)
+  withdrawn   the digest matches, but the version is past its sunset and can't be downloaded
+  MISMATCH    the registry recorded another digest for the version
+  not found   the version doesn't exist (or isn't visible to your token)
+  no digest   sproto.lock has no digest for the version
+
+The command exits 1 if any dependency is not ok, deprecated excepted; with --strict deprecated
+versions fail too.
+
+Examples:
+  protoreg-cli deps verify
+  protoreg-cli deps verify --dir ./protos --strict`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+
+		lock, err := manifest.LoadLock(filepath.Join(depsDir, manifest.LockFileName))
+		if errors.Is(err, os.ErrNotExist) {
+			return exitErrorf(ExitUsage, "%s not found in %s; run 'protoreg-cli deps update' first", manifest.LockFileName, depsDir)
+		} else if err != nil {
+			return fmt.Errorf("failed to read lockfile: %w", err)
+		}
+		if len(lock.Dependencies) == 0 {
+			fmt.Println("No dependencies are locked.")
+			return nil
+		}
+
+		client := &http.Client{}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "MODULE\tVERSION\tSTATUS\tDETAILS")
+		failed := false
+		for _, dep := range lock.Dependencies {
+			status, details, err := verifyLockedDependency(client, registryURL, dep, log)
+			if err != nil {
+				_ = tw.Flush()
+				return fmt.Errorf("%s@%s: %w", dep.Module, dep.Version, err)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", dep.Module, dep.Version, status, orDash(details))
+			failed = failed || (status != "ok" && (status != "deprecated" || depsVerifyStrict))
+		}
+		_ = tw.Flush()
+
+		if failed {
+			return exitStatus(1)
+		}
+		return nil
+	},
+}
+
+// errVerifyUnsupported means the registry predates the verify endpoint.
+var errVerifyUnsupported = errors.New("registry does not support digest verification (POST /api/v1/verify); upgrade it or use 'deps install', which checks the downloaded artifacts")
+
+// verifyLockedDependency checks a locked dependency with the registry and returns its status and
+// details for the deps verify table.
+func verifyLockedDependency(client *http.Client, registryURL string, dep manifest.LockedDependency, log *zap.Logger) (string, string, error) {
+	if dep.Digest == "" {
+		return "no digest", "run 'protoreg-cli deps update'", nil
+	}
+	namespace, name, err := manifest.SplitModule(dep.Module)
+	if err != nil {
+		return "", "", withExitCode(ExitValidation, err)
+	}
+	payload, err := json.Marshal(apitypes.VerifyRequest{Namespace: namespace, ModuleName: name, Version: dep.Version, Digest: dep.Digest})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	targetURL := strings.TrimSuffix(registryURL, "/") + "/api/v1/verify"
+	req, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setReadToken(req)
+	log.Debug("Verifying locked digest", zap.String("module", dep.Module), zap.String("version", dep.Version))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// The registry answers unknown versions with a JSON error; older registries have no such route
+		var errResp apiErrorResponse
+		if json.Unmarshal(bodyBytes, &errResp) == nil && errResp.Error != "" {
+			return "not found", "", nil
+		}
+		return "", "", errVerifyUnsupported
+	case http.StatusMethodNotAllowed:
+		return "", "", errVerifyUnsupported
+	default:
+		return "", "", registryError(resp.StatusCode, bodyBytes)
+	}
+
+	var result apitypes.VerifyResponse
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return "", "", fmt.Errorf("failed to parse API response: %w", err)
+	}
+	switch {
+	case !result.Match:
+		return "MISMATCH", fmt.Sprintf("locked %s, registry recorded %s", result.Digest, result.RecordedDigest), nil
+	case result.Sunset:
+		return "withdrawn", result.DeprecationMessage, nil
+	case result.Deprecated:
+		return "deprecated", result.DeprecationMessage, nil
+	}
+	return "ok", "", nil
+}
+
+func init() {
+	depsCmd.AddCommand(depsVerifyCmd)
+	depsVerifyCmd.Flags().BoolVar(&depsVerifyStrict, "strict", false, "Also fail if a locked version is deprecated")
+}
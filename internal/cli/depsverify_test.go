@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestVerifyLockedDependency(t *testing.T) {
+	recorded := "sha256:" + strings.Repeat("a", 64)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req apitypes.VerifyRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		resp := apitypes.VerifyResponse{Digest: req.Digest, Match: req.Digest == recorded, RecordedDigest: recorded}
+		switch req.ModuleName {
+		case "old":
+			resp.Deprecated, resp.DeprecationMessage = true, "Use acme/new"
+		case "gone":
+			resp.Deprecated, resp.Sunset = true, true
+		case "missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"Module version not found"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	check := func(module, digest string) (string, string) {
+		status, details, err := verifyLockedDependency(srv.Client(), srv.URL, manifest.LockedDependency{Module: module, Version: "v1.0.0", Digest: digest}, zap.NewNop())
+		require.NoError(t, err)
+		return status, details
+	}
+	status, _ := check("acme/user", recorded)
+	assert.Equal(t, "ok", status)
+	status, details := check("acme/user", "sha256:"+strings.Repeat("b", 64))
+	assert.Equal(t, "MISMATCH", status)
+	assert.Contains(t, details, "registry recorded "+recorded)
+	status, details = check("acme/old", recorded)
+	assert.Equal(t, "deprecated", status)
+	assert.Equal(t, "Use acme/new", details)
+	status, _ = check("acme/gone", recorded)
+	assert.Equal(t, "withdrawn", status)
+	status, _ = check("acme/missing", recorded)
+	assert.Equal(t, "not found", status)
+	status, _ = check("acme/user", "")
+	assert.Equal(t, "no digest", status)
+
+	// Registries without the endpoint answer with the router's plain text 404
+	old := httptest.NewServer(http.NotFoundHandler())
+	defer old.Close()
+	_, _, err := verifyLockedDependency(old.Client(), old.URL, manifest.LockedDependency{Module: "acme/user", Version: "v1.0.0", Digest: recorded}, zap.NewNop())
+	assert.True(t, errors.Is(err, errVerifyUnsupported))
+}
@@ -0,0 +1,318 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"os/user"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	devModuleName string
+	devChannel    string
+	devInterval   time.Duration
+	devOnce       bool
+	devDelete     bool
+)
+
+// devCmd represents the dev command
+var devCmd = &cobra.Command{
+	Use:   "dev [directory]",
+	Short: "Watch a proto directory and republish it to a dev channel on every change",
+	Long: `Publishes the directory (default: the current one) to a dev channel of the module, then
+watches it and republishes whenever a file changes, until interrupted (Ctrl+C).
+
+A dev channel is a mutable, work-in-progress "version" named dev-<name> (by default dev-<your
+user name>). Unlike versions, each publish replaces its artifact, so services in a dev cluster
+can fetch the latest WIP schema without a version being cut:
+
+  protoreg-cli fetch mycompany/user dev-alice --output ./protos
+  GET /api/v1/modules/mycompany/user/dev-alice/artifact
+
+The module must already exist in the registry (publish a first version). The registry
+re-packs, scans and checks the import graph of each upload like for a version, but namespace
+and publish policies don't apply. A rejected upload is reported and the watch goes on; the
+next change is published again.
+
+The directory is checked every --interval; a change is published once the files have stayed
+the same for one interval, so saving several files at once results in a single publish.
+
+--module defaults to the name in the directory's sproto.yaml; the namespace can be omitted
+if a default namespace is configured ('protoreg-cli configure'). Authentication via API
+token is required.
+
+Examples:
+  protoreg-cli dev ./protos --module mycompany/user
+  protoreg-cli dev ./protos --channel dev-checkout-team --interval 2s
+  protoreg-cli dev ./protos --once       # publish the current state and exit
+  protoreg-cli dev --module mycompany/user --delete`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		apiToken, err := requireAPIToken()
+		if err != nil {
+			return err
+		}
+		if devInterval <= 0 {
+			return exitErrorf(ExitUsage, "--interval must be positive")
+		}
+
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return exitErrorf(ExitUsage, "input path %s is not a directory", dir)
+		}
+		m, err := loadModuleManifest(dir)
+		if err != nil {
+			return fmt.Errorf("failed to read manifest: %w", err)
+		}
+		module, _ := applyManifestDefaults(m, devModuleName, "", log)
+		if module == "" {
+			return exitErrorf(ExitUsage, "--module flag is required (or a name in sproto.yaml)")
+		}
+		namespace, moduleName, found := strings.Cut(module, "/")
+		if !found || namespace == "" || moduleName == "" {
+			return exitErrorf(ExitValidation, "invalid module name format %q: expected 'namespace/module_name'", module)
+		}
+
+		username := currentUsername()
+		channel := devChannel
+		if channel == "" {
+			channel = defaultDevChannel(username)
+		}
+		if err := validation.ValidateDevChannel(channel); err != nil {
+			return exitErrorf(ExitValidation, "invalid --channel: %w", err)
+		}
+		targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s", strings.TrimSuffix(registryURL, "/"),
+			url.PathEscape(namespace), url.PathEscape(moduleName), url.PathEscape(channel))
+		client := &http.Client{}
+
+		if devDelete {
+			if err := deleteDevChannel(client, targetURL, apiToken); err != nil {
+				return fmt.Errorf("failed to delete %s/%s@%s: %w", namespace, moduleName, channel, err)
+			}
+			fmt.Printf("Deleted %s/%s@%s\n", namespace, moduleName, channel)
+			return nil
+		}
+
+		w := &devWatcher{
+			dir: dir,
+			publish: func(zipData []byte) (*apitypes.DevChannelResponse, error) {
+				return publishDevChannel(client, targetURL, zipData, apiToken, username)
+			},
+			log: log,
+		}
+		if devOnce {
+			zipData, digestHex, err := devSnapshot(dir, log)
+			if err != nil {
+				return err
+			}
+			return w.publishSnapshot(zipData, digestHex)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		fmt.Printf("Watching %s, publishing to %s/%s@%s (Ctrl+C to stop)\n", dir, namespace, moduleName, channel)
+		w.run(ctx, devInterval)
+		return nil
+	},
+}
+
+// devWatcher publishes a directory to a dev channel whenever its content changes.
+type devWatcher struct {
+	dir     string
+	publish func(zipData []byte) (*apitypes.DevChannelResponse, error)
+	log     *zap.Logger
+
+	published string // Digest of the last snapshot published (or rejected by the registry)
+	pending   string // Digest of the last snapshot seen, published once it is seen twice in a row
+}
+
+// run checks the directory every interval until ctx is canceled. The first snapshot is published right away.
+func (w *devWatcher) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	first := true
+	for {
+		w.check(first)
+		first = false
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check takes a snapshot of the directory and publishes it if it changed and has settled (or now is set).
+// Errors are reported and the watch goes on.
+func (w *devWatcher) check(now bool) {
+	zipData, digestHex, err := devSnapshot(w.dir, w.log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err) // E.g. a file deleted mid-walk; the next check retries
+		return
+	}
+	if digestHex == w.published {
+		w.pending = digestHex
+		return // Nothing changed since the last publish
+	}
+	settled := digestHex == w.pending
+	w.pending = digestHex
+	if !settled && !now {
+		return // Still being edited: publish once it stops changing
+	}
+	if err := w.publishSnapshot(zipData, digestHex); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
+
+// publishSnapshot publishes a snapshot and prints the outcome. Snapshots the registry rejects are
+// remembered like published ones, so they aren't sent again until the files change; network and server
+// errors are retried on the next check.
+func (w *devWatcher) publishSnapshot(zipData []byte, digestHex string) error {
+	result, err := w.publish(zipData)
+	if err != nil {
+		if code := exitCode(err); code != ExitNetwork && code != ExitFailure {
+			w.published = digestHex
+		}
+		return err
+	}
+	w.published = digestHex
+	if result.Unchanged {
+		fmt.Printf("%s %s/%s@%s is up to date (revision %d)\n", time.Now().Format("15:04:05"), result.Namespace, result.ModuleName, result.Channel, result.Revision)
+		return nil
+	}
+	fmt.Printf("%s Published %s/%s@%s (revision %d, %s)\n", time.Now().Format("15:04:05"), result.Namespace, result.ModuleName, result.Channel, result.Revision, result.ArtifactDigest)
+	return nil
+}
+
+// devSnapshot zips the directory in the registry's canonical form and returns it with its hex digest.
+// The canonical form has fixed timestamps, so the digest only changes when the files do.
+func devSnapshot(dir string, log *zap.Logger) ([]byte, string, error) {
+	zipBuffer, err := zipDirectory(dir, log)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed during directory walk/zip creation: %w", err)
+	}
+	canonical, err := artifact.Canonicalize(zipBuffer.Bytes())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to re-pack artifact: %w", err)
+	}
+	sum := sha256.Sum256(canonical)
+	return canonical, hex.EncodeToString(sum[:]), nil
+}
+
+// publishDevChannel uploads an artifact to a dev channel (PUT, same multipart form as publishing).
+func publishDevChannel(client *http.Client, targetURL string, zipData []byte, apiToken, publisher string) (*apitypes.DevChannelResponse, error) {
+	req, err := newArtifactUploadRequest(targetURL, "dev", zipData, apiToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Method = http.MethodPut // Channels are replaced, versions created
+	if publisher != "" {
+		req.Header.Set(apitypes.PublisherHeader, publisher)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		printErrorDetails(bodyBytes)
+		return nil, fmt.Errorf("dev publish failed: %w", registryError(resp.StatusCode, bodyBytes))
+	}
+	var result apitypes.DevChannelResponse
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	return &result, nil
+}
+
+// deleteDevChannel deletes a dev channel.
+func deleteDevChannel(client *http.Client, targetURL, apiToken string) error {
+	req, err := http.NewRequest(http.MethodDelete, targetURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return registryError(resp.StatusCode, bodyBytes)
+	}
+	return nil
+}
+
+// currentUsername returns the user name of the current user ($USER or the OS account), or "" if unknown.
+func currentUsername() string {
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// defaultDevChannel returns "dev-<username>", with the user name lowercased and characters channel names
+// don't allow replaced by '-' (e.g. "CORP\Alice.Smith" becomes "dev-corp-alice.smith").
+func defaultDevChannel(username string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(username) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	name := strings.Trim(b.String(), "-.")
+	for strings.Contains(name, "..") {
+		name = strings.ReplaceAll(name, "..", ".")
+	}
+	if name == "" {
+		name = "user"
+	}
+	if len(name) > validation.MaxDevChannelLength-len(validation.DevChannelPrefix) {
+		name = strings.TrimRight(name[:validation.MaxDevChannelLength-len(validation.DevChannelPrefix)], "-.")
+	}
+	return validation.DevChannelPrefix + name
+}
+
+func init() {
+	rootCmd.AddCommand(devCmd)
+	devCmd.Flags().StringVarP(&devModuleName, "module", "m", "", "Full module name (namespace/name) (default: the name in sproto.yaml)")
+	devCmd.Flags().StringVar(&devChannel, "channel", "", "Dev channel to publish to, dev-<name> (default: dev-<your user name>)")
+	devCmd.Flags().DurationVar(&devInterval, "interval", time.Second, "How often the directory is checked for changes")
+	devCmd.Flags().BoolVar(&devOnce, "once", false, "Publish the current state of the directory and exit")
+	devCmd.Flags().BoolVar(&devDelete, "delete", false, "Delete the dev channel and exit")
+}
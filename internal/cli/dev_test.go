@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDefaultDevChannel(t *testing.T) {
+	assert.Equal(t, "dev-alice", defaultDevChannel("alice"))
+	assert.Equal(t, "dev-corp-alice.smith", defaultDevChannel(`CORP\Alice.Smith`))
+	assert.Equal(t, "dev-a.b", defaultDevChannel("a..b_"))
+	assert.Equal(t, "dev-user", defaultDevChannel(""))
+	assert.Len(t, defaultDevChannel(strings.Repeat("a", 100)), validation.MaxDevChannelLength)
+	assert.NoError(t, validation.ValidateDevChannel(defaultDevChannel(strings.Repeat("a", 100))))
+}
+
+func TestPublishDevChannel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/api/v1/modules/acme/user/dev-alice", r.URL.Path)
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		assert.Equal(t, "alice", r.Header.Get(apitypes.PublisherHeader))
+		assert.Equal(t, uploadDigest([]byte("zip")), r.Header.Get(apitypes.ArtifactDigestHeader))
+		file, _, err := r.FormFile("artifact")
+		if assert.NoError(t, err) {
+			data, _ := io.ReadAll(file)
+			assert.Equal(t, "zip", string(data))
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"namespace":"acme","module_name":"user","channel":"dev-alice","revision":1}`))
+	}))
+	defer srv.Close()
+
+	result, err := publishDevChannel(srv.Client(), srv.URL+"/api/v1/modules/acme/user/dev-alice", []byte("zip"), "tok", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Revision)
+
+	rejected := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"error":"Import graph violation"}`))
+	}))
+	defer rejected.Close()
+	_, err = publishDevChannel(rejected.Client(), rejected.URL, []byte("zip"), "tok", "")
+	require.Error(t, err)
+	assert.Equal(t, ExitValidation, exitCode(err))
+}
+
+func TestDevWatcher(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "user.proto"), []byte(content), 0o644))
+	}
+	write(`syntax = "proto3";`)
+
+	publishes := 0
+	w := &devWatcher{dir: dir, log: zap.NewNop(), publish: func([]byte) (*apitypes.DevChannelResponse, error) {
+		publishes++
+		return &apitypes.DevChannelResponse{Revision: publishes}, nil
+	}}
+
+	w.check(true) // The first snapshot is published right away
+	assert.Equal(t, 1, publishes)
+	w.check(false)
+	assert.Equal(t, 1, publishes, "unchanged files aren't published again")
+
+	// A change is published once it has settled for a check
+	write(`syntax = "proto3"; package user.v1;`)
+	w.check(false)
+	assert.Equal(t, 1, publishes)
+	w.check(false)
+	assert.Equal(t, 2, publishes)
+
+	// Snapshots rejected by the registry aren't sent again until the files change
+	w.publish = func([]byte) (*apitypes.DevChannelResponse, error) {
+		publishes++
+		return nil, registryError(http.StatusUnprocessableEntity, []byte(`{"error":"invalid"}`))
+	}
+	write(`syntax = "proto3"; package user.v2;`)
+	w.check(false)
+	w.check(false)
+	w.check(false)
+	assert.Equal(t, 3, publishes)
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// existsCmd represents the exists command
+var existsCmd = &cobra.Command{
+	Use:   "exists <namespace/module_name> <version>",
+	Short: "Check whether a module version has been published",
+	Long: `Checks whether a specific module version exists in the registry using a
+HEAD request (no artifact is downloaded).
+
+Exit codes:
+  0  the version exists
+  1  the version does not exist
+  2  the check failed (invalid arguments, registry unreachable, server error)
+
+Example (only publish if the version is new):
+  protoreg-cli exists mycompany/user v1.0.0 || protoreg-cli publish ./protos --module mycompany/user --version v1.0.0 --yes`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exists, err := checkExists(qualifyModule(args[0]), args[1], GetLogger()) // The namespace may be omitted if a default namespace is configured
+		if err != nil {
+			return withExitCode(2, err) // exists reports every failure with 2, see above
+		}
+		if !exists {
+			return exitStatus(1)
+		}
+		return nil
+	},
+}
+
+// checkExists checks whether a module version exists and prints the result.
+func checkExists(moduleFullName, version string, log *zap.Logger) (bool, error) {
+	registryURL, err := requireRegistryURL()
+	if err != nil {
+		return false, err
+	}
+
+	parts := strings.SplitN(moduleFullName, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return false, fmt.Errorf("invalid module name format %q: expected 'namespace/module_name'", moduleFullName)
+	}
+	if !strings.HasPrefix(version, "v") {
+		return false, fmt.Errorf("invalid version format %q: must start with 'v'", version)
+	}
+
+	targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s", strings.TrimSuffix(registryURL, "/"),
+		url.PathEscape(parts[0]), url.PathEscape(parts[1]), url.PathEscape(version))
+	log.Debug("Checking module version", zap.String("url", targetURL))
+
+	req, err := http.NewRequest("HEAD", targetURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	setReadToken(req)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute request: %w", err)
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		fmt.Printf("%s@%s exists", moduleFullName, version)
+		if digest := resp.Header.Get(apitypes.ArtifactDigestHeader); digest != "" {
+			fmt.Printf(" (%s)", digest)
+		}
+		fmt.Println()
+		return true, nil
+	case http.StatusNotFound:
+		fmt.Printf("%s@%s does not exist\n", moduleFullName, version)
+		return false, nil
+	}
+	return false, fmt.Errorf("unexpected response from registry: status %d", resp.StatusCode)
+}
+
+func init() {
+	rootCmd.AddCommand(existsCmd)
+}
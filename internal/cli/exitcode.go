@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+)
+
+// Exit codes of protoreg-cli. They are part of the CLI's interface: scripts branch on them, so existing
+// codes must not change meaning. Commands reporting a result through their exit code (exists, impact,
+// outdated --exit-code, selftest) use 1 for the negative result.
+const (
+	ExitOK         = 0 // Success
+	ExitFailure    = 1 // Unclassified failure (e.g. a server error), or a command's negative result
+	ExitUsage      = 2 // Invalid flags, arguments or configuration (e.g. no registry URL)
+	ExitAuth       = 3 // Missing or rejected API token, or insufficient permissions (401, 403)
+	ExitNotFound   = 4 // Module, version, file or token doesn't exist (404)
+	ExitConflict   = 5 // Conflicts with the registry's state, e.g. publishing an existing version (409, 412)
+	ExitNetwork    = 6 // Registry unreachable, timed out or temporarily unavailable (502, 503, 504)
+	ExitValidation = 7 // Input rejected as invalid, locally or by the registry (400, 413, 422)
+)
+
+// exitError attaches an exit code to an error that exitCode couldn't classify from its type. Without an
+// error, it only sets the exit code of a command reporting its result through it (see exitStatus).
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode returns err carrying an explicit exit code.
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitErrorf formats an error carrying an explicit exit code.
+func exitErrorf(code int, format string, args ...any) error {
+	return withExitCode(code, fmt.Errorf(format, args...))
+}
+
+// exitStatus returns an error that makes the CLI exit with code without reporting anything, for
+// commands whose exit code is their result (e.g. exists for a missing version).
+func exitStatus(code int) error {
+	return &exitError{code: code}
+}
+
+// statusExitCode maps an HTTP error status of the registry to an exit code.
+func statusExitCode(statusCode int) int {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ExitAuth
+	case http.StatusNotFound, http.StatusGone:
+		return ExitNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ExitConflict
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return ExitValidation
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ExitNetwork
+	}
+	return ExitFailure
+}
+
+// exitCode maps an error to the exit code of the failure it describes:
+// an explicit code (withExitCode), errModuleNotFound, network errors, or ExitFailure.
+func exitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	if errors.Is(err, errModuleNotFound) {
+		return ExitNotFound
+	}
+	var netErr net.Error // Includes *url.Error, returned by http.Client for failed requests
+	if errors.As(err, &netErr) {
+		return ExitNetwork
+	}
+	return ExitFailure
+}
+
+// reportError prints the error a command failed with, unless it only sets the exit code (exitStatus).
+func reportError(err error) {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) && exitErr.err == nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "Error:", err)
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusExitCode(t *testing.T) {
+	cases := map[int]int{
+		http.StatusUnauthorized:          ExitAuth,
+		http.StatusForbidden:             ExitAuth,
+		http.StatusNotFound:              ExitNotFound,
+		http.StatusConflict:              ExitConflict,
+		http.StatusPreconditionFailed:    ExitConflict,
+		http.StatusBadRequest:            ExitValidation,
+		http.StatusRequestEntityTooLarge: ExitValidation,
+		http.StatusUnprocessableEntity:   ExitValidation,
+		http.StatusBadGateway:            ExitNetwork,
+		http.StatusServiceUnavailable:    ExitNetwork,
+		http.StatusGatewayTimeout:        ExitNetwork,
+		http.StatusInternalServerError:   ExitFailure,
+		http.StatusTeapot:                ExitFailure,
+	}
+	for status, want := range cases {
+		assert.Equal(t, want, statusExitCode(status), "status %d", status)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	// Explicit codes survive wrapping
+	err := fmt.Errorf("failed to fetch: %w", withExitCode(ExitConflict, errors.New("exists")))
+	assert.Equal(t, ExitConflict, exitCode(err))
+	assert.EqualError(t, err, "failed to fetch: exists")
+
+	assert.Equal(t, ExitNotFound, exitCode(fmt.Errorf("acme/x: %w", errModuleNotFound)))
+
+	// Failed requests
+	urlErr := &url.Error{Op: "Get", URL: "http://localhost:1", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+	assert.Equal(t, ExitNetwork, exitCode(fmt.Errorf("failed to execute request: %w", urlErr)))
+
+	assert.Equal(t, ExitFailure, exitCode(errors.New("something else")))
+}
+
+func TestExitStatus(t *testing.T) {
+	// Result codes carry no error to report
+	err := exitStatus(1)
+	assert.Equal(t, 1, exitCode(err))
+	assert.Nil(t, errors.Unwrap(err))
+}
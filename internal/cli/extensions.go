@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// CLI extensions (kubectl-style plugins): "protoreg-cli foo args..." runs the executable protoreg-foo found
+// on PATH with args, unless foo is a built-in command. The extension gets the effective registry settings
+// (flags, environment and config file resolved as for built-in commands) in its environment, so teams can
+// add subcommands, such as wrappers around their code generation, without forking the CLI. Not to be
+// confused with protoc plugins (the plugins command).
+
+// extensionPrefix is the prefix of the executables run as extensions.
+const extensionPrefix = "protoreg-"
+
+// Environment variables set for extensions, besides those of the CLI's own environment.
+const (
+	ExtensionEnvRegistryURL      = "PROTOREG_REGISTRY_URL"
+	ExtensionEnvAPIToken         = "PROTOREG_API_TOKEN"
+	ExtensionEnvReadToken        = "PROTOREG_READ_TOKEN"
+	ExtensionEnvDefaultNamespace = "PROTOREG_DEFAULT_NAMESPACE"
+	ExtensionEnvCLI              = "PROTOREG_CLI" // Path of protoreg-cli, for extensions calling back into it
+)
+
+// findExtension returns the executable of the extension named by a command line (the arguments after the
+// program name), and the arguments to pass to it. Leading global flags (--registry-url, --config, ...) are
+// applied first. Returns false if the command line names a built-in command or no extension exists.
+func findExtension(args []string) (string, []string, bool) {
+	// The global flags share their values with the root command's, so they apply as if cobra parsed them
+	flags := pflag.NewFlagSet(rootCmd.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(rootCmd.PersistentFlags())
+	flags.SetInterspersed(false)
+	flags.Usage = func() {}
+	flags.SetOutput(discardWriter{})
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		return "", nil, false
+	}
+	name := flags.Arg(0)
+	if name == "" || name == "cli" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\`) || isBuiltinCommand(name) {
+		return "", nil, false
+	}
+	path, err := exec.LookPath(extensionPrefix + name)
+	if err != nil {
+		return "", nil, false
+	}
+	return path, flags.Args()[1:], true
+}
+
+// isBuiltinCommand reports whether name is a command of the CLI (or an alias of one).
+func isBuiltinCommand(name string) bool {
+	rootCmd.InitDefaultHelpCmd()
+	rootCmd.InitDefaultCompletionCmd()
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// runExtension runs an extension with the registry settings in its environment, and returns its exit code.
+func runExtension(path string, args []string) int {
+	initConfig()
+	cmd := exec.Command(path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), extensionEnv()...)
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &exitErr) && exitErr.ExitCode() >= 0:
+		return exitErr.ExitCode()
+	default:
+		reportError(fmt.Errorf("failed to run extension %s: %w", path, err))
+		return ExitFailure
+	}
+}
+
+// extensionEnv returns the effective registry settings as environment variables for an extension.
+func extensionEnv() []string {
+	settings := []struct{ name, value string }{
+		{ExtensionEnvRegistryURL, viper.GetString("registry_url")},
+		{ExtensionEnvAPIToken, viper.GetString("api_token")},
+		{ExtensionEnvReadToken, viper.GetString("read_token")},
+		{ExtensionEnvDefaultNamespace, viper.GetString("default_namespace")},
+	}
+	if self, err := os.Executable(); err == nil {
+		settings = append(settings, struct{ name, value string }{ExtensionEnvCLI, self})
+	}
+	var env []string
+	for _, s := range settings {
+		if s.value != "" {
+			env = append(env, s.name+"="+s.value)
+		}
+	}
+	return env
+}
+
+// discardWriter swallows the flag parser's output; parse errors leave the command line to cobra.
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+// listExtensions returns the extensions on PATH, by name, with their executable. Built-in commands shadow
+// extensions of the same name, and earlier PATH entries shadow later ones.
+func listExtensions() map[string]string {
+	extensions := map[string]string{}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), extensionPrefix)
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name)) // protoreg-foo.exe
+			}
+			if !ok || name == "" || name == "cli" || entry.IsDir() || isBuiltinCommand(name) { // protoreg-cli itself
+				continue
+			}
+			if _, seen := extensions[name]; seen {
+				continue
+			}
+			if path, err := exec.LookPath(filepath.Join(dir, entry.Name())); err == nil { // Executable
+				extensions[name] = path
+			}
+		}
+	}
+	return extensions
+}
+
+// extensionsCmd represents the extensions command
+var extensionsCmd = &cobra.Command{
+	Use:   "extensions",
+	Short: "List the CLI extensions found on PATH",
+	Long: `Lists the CLI extensions: executables named protoreg-<name> on PATH, run as
+'protoreg-cli <name> [args...]'. Extensions get the registry settings in their
+environment (PROTOREG_REGISTRY_URL, PROTOREG_API_TOKEN, PROTOREG_READ_TOKEN,
+PROTOREG_DEFAULT_NAMESPACE) as resolved from flags, environment and config file,
+and the path of protoreg-cli in PROTOREG_CLI. Built-in commands can't be overridden.
+
+Examples:
+  protoreg-cli extensions
+  protoreg-cli --registry-url https://registry.internal codegen --lang go   # runs protoreg-codegen`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		extensions := listExtensions()
+		if len(extensions) == 0 {
+			fmt.Println("No extensions found on PATH")
+			return nil
+		}
+		names := make([]string, 0, len(extensions))
+		for name := range extensions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s\t%s\n", name, extensions[name])
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(extensionsCmd)
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeExtension creates an executable shell script protoreg-<name> in dir.
+func writeExtension(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, extensionPrefix+name)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755))
+	return path
+}
+
+func TestFindExtension(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("extensions are shell scripts in this test")
+	}
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	hello := writeExtension(t, dir, "hello", "exit 0\n")
+	writeExtension(t, dir, "fetch", "exit 0\n") // Shadowed by the built-in command
+	require.NoError(t, os.WriteFile(filepath.Join(dir, extensionPrefix+"notes"), []byte("not executable"), 0644))
+	t.Cleanup(func() { registryURL = "" })
+
+	path, args, ok := findExtension([]string{"hello", "--lang", "go", "x"})
+	assert.True(t, ok)
+	assert.Equal(t, hello, path)
+	assert.Equal(t, []string{"--lang", "go", "x"}, args)
+
+	// Leading global flags are applied, later ones passed on
+	path, args, ok = findExtension([]string{"--registry-url", "http://registry.test", "hello", "--registry-url", "other"})
+	assert.True(t, ok)
+	assert.Equal(t, hello, path)
+	assert.Equal(t, []string{"--registry-url", "other"}, args)
+	assert.Equal(t, "http://registry.test", registryURL)
+
+	for _, cmdline := range [][]string{{}, {"fetch"}, {"help"}, {"nope"}, {"notes"}, {"--unknown", "hello"}, {"../hello"}} {
+		_, _, ok := findExtension(cmdline)
+		assert.False(t, ok, strings.Join(cmdline, " "))
+	}
+	assert.Equal(t, map[string]string{"hello": hello}, listExtensions())
+}
+
+func TestRunExtension(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("extensions are shell scripts in this test")
+	}
+	dir := t.TempDir()
+	out := filepath.Join(dir, "env")
+	path := writeExtension(t, dir, "env", `echo "$PROTOREG_REGISTRY_URL $PROTOREG_API_TOKEN $PROTOREG_READ_TOKEN $*" > "`+out+`"; exit 3`+"\n")
+	t.Setenv("PROTOREG_API_TOKEN", "from-env")
+	t.Setenv("PROTOREG_READ_TOKEN", "")
+	viper.Set("registry_url", "http://registry.test")
+	t.Cleanup(func() { viper.Set("registry_url", "") })
+
+	assert.Equal(t, 3, runExtension(path, []string{"a", "b"}), "the extension's exit code is passed on")
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "http://registry.test from-env  a b\n", string(content))
+	assert.Equal(t, ExitFailure, runExtension(filepath.Join(dir, "missing"), nil))
+}
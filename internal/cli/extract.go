@@ -0,0 +1,295 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// windowsReservedDeviceNames can't be used as file or directory names on Windows,
+// even with an extension ("nul.proto" is as unusable as "nul").
+var windowsReservedDeviceNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// windowsInvalidChars are characters not allowed in Windows file names (besides path separators and control characters).
+const windowsInvalidChars = `<>:"|?*`
+
+// sanitizeZipEntryName validates a zip entry name and returns it as a relative, slash-separated path.
+// The same rules are applied on every OS so an artifact that extracts on Linux also extracts on Windows:
+// backslashes are treated as separators, and absolute paths, drive letters, '..' components,
+// Windows-invalid characters, trailing dots/spaces and reserved device names are rejected.
+func sanitizeZipEntryName(name string) (string, error) {
+	normalized := strings.ReplaceAll(name, `\`, "/")
+	if normalized == "" {
+		return "", fmt.Errorf("empty file name")
+	}
+	if strings.HasPrefix(normalized, "/") {
+		return "", fmt.Errorf("absolute path %q", name)
+	}
+	if len(normalized) >= 2 && normalized[1] == ':' {
+		return "", fmt.Errorf("path with drive letter %q", name)
+	}
+
+	var parts []string
+	for _, part := range strings.Split(normalized, "/") {
+		switch part {
+		case "", ".":
+			continue // Collapse "a//b" and "./a"
+		case "..":
+			return "", fmt.Errorf("path %q escapes the target directory", name)
+		}
+		for _, r := range part {
+			if r < 0x20 || strings.ContainsRune(windowsInvalidChars, r) {
+				return "", fmt.Errorf("path %q contains invalid character %q", name, r)
+			}
+		}
+		if strings.HasSuffix(part, ".") || strings.HasSuffix(part, " ") {
+			return "", fmt.Errorf("path %q has a component ending in '.' or ' '", name)
+		}
+		base, _, _ := strings.Cut(part, ".")
+		if windowsReservedDeviceNames[strings.ToUpper(strings.TrimRight(base, " "))] {
+			return "", fmt.Errorf("path %q uses reserved Windows device name %q", name, base)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("empty file name %q", name)
+	}
+	return path.Join(parts...), nil
+}
+
+// --- Layouts ---
+
+// Layouts artifacts are extracted in (--layout of fetch and deps install).
+const (
+	// layoutNested extracts every file of an artifact to <output>/<namespace>/<name>/<version>/.
+	layoutNested = "nested"
+	// layoutFlat extracts only the .proto files, directly into <output>, so the output directory is an
+	// import root (protoc -I <output>). Other files, such as each module's sproto.yaml, would collide.
+	layoutFlat = "flat"
+)
+
+// validateLayout checks a --layout value.
+func validateLayout(layout string) error {
+	if layout != layoutNested && layout != layoutFlat {
+		return fmt.Errorf("invalid layout %q: must be %q or %q", layout, layoutFlat, layoutNested)
+	}
+	return nil
+}
+
+// extractionPath returns the directory a module version's artifact is extracted into.
+func extractionPath(outputDir, layout, namespace, moduleName, version string) string {
+	if layout == layoutFlat {
+		return outputDir
+	}
+	return filepath.Join(outputDir, namespace, moduleName, version)
+}
+
+// layoutFilter returns which (sanitized) entry names are extracted in a layout; nil means all.
+func layoutFilter(layout string) func(name string) bool {
+	if layout == layoutFlat {
+		return func(name string) bool { return path.Ext(name) == ".proto" }
+	}
+	return nil
+}
+
+// zipFileChecksums returns the CRC-32 of each file in a zip archive accepted by keep (nil: all), keyed by
+// lowercased sanitized path. Used to detect files of different artifacts that would overwrite each other.
+func zipFileChecksums(zipData []byte, keep func(name string) bool) (map[string]uint32, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive reader: %w", err)
+	}
+	sums := make(map[string]uint32)
+	for _, f := range zipReader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name, err := sanitizeZipEntryName(f.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file path in zip archive: %w", err)
+		}
+		if keep == nil || keep(name) {
+			sums[strings.ToLower(name)] = f.CRC32
+		}
+	}
+	return sums, nil
+}
+
+// --- Extraction ---
+
+// extractZip extracts a zip archive into destDir and returns the number of files written.
+// All entries are validated before anything is written, so a bad archive leaves destDir untouched.
+func extractZip(zipData []byte, destDir string, log *zap.Logger) (int, error) {
+	return extractZipFiltered(zipData, destDir, nil, log)
+}
+
+// extractZipFiltered is extractZip for only the entries whose sanitized name is accepted by keep (nil: all).
+// Directory entries are skipped when filtering; directories are created as needed for the files.
+func extractZipFiltered(zipData []byte, destDir string, keep func(name string) bool, log *zap.Logger) (int, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open zip archive reader: %w", err)
+	}
+
+	// --- Validate all entries first ---
+	targets := make([]string, len(zipReader.File)) // "" for entries not extracted
+	seen := make(map[string]string)                // Lowercased path -> original entry, to catch case-only collisions
+	for i, f := range zipReader.File {
+		name, err := sanitizeZipEntryName(f.Name)
+		if err != nil {
+			return 0, fmt.Errorf("invalid file path in zip archive: %w", err)
+		}
+		if keep != nil && (f.FileInfo().IsDir() || !keep(name)) {
+			continue
+		}
+		// Directory entries may legitimately repeat paths implied by files; only files must be unique
+		if !f.FileInfo().IsDir() {
+			key := strings.ToLower(name)
+			if other, ok := seen[key]; ok {
+				return 0, fmt.Errorf("zip entries %q and %q would overwrite each other on case-insensitive filesystems", other, f.Name)
+			}
+			seen[key] = f.Name
+		}
+		targets[i] = name
+	}
+
+	// --- Extract ---
+	baseDir := filepath.Clean(destDir)
+	if err := os.MkdirAll(longPath(baseDir), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create extraction directory %s: %w", baseDir, err)
+	}
+
+	extracted := 0
+	for i, f := range zipReader.File {
+		if targets[i] == "" {
+			continue
+		}
+		fpath := filepath.Join(baseDir, filepath.FromSlash(targets[i]))
+
+		// Defense in depth: the sanitized name must still resolve inside the target directory
+		if rel, err := filepath.Rel(baseDir, fpath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			return extracted, fmt.Errorf("invalid file path in zip archive (potential traversal attack): %q", f.Name)
+		}
+
+		log.Debug("Extracting file", zap.String("path", fpath))
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(longPath(fpath), 0755); err != nil {
+				return extracted, fmt.Errorf("failed to create directory %s: %w", fpath, err)
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(longPath(filepath.Dir(fpath)), 0755); err != nil {
+			return extracted, fmt.Errorf("failed to create directory for %s: %w", fpath, err)
+		}
+		if err := extractZipFile(f, fpath); err != nil {
+			return extracted, err
+		}
+		extracted++
+	}
+	return extracted, nil
+}
+
+// removePartialExtraction returns a function to defer around a nested-layout extraction into destDir: if
+// destDir didn't exist yet and *err is set when it runs, destDir is removed, so a failed extraction doesn't
+// leave a version directory that looks complete. Flat extractions share their directory and are kept.
+func removePartialExtraction(destDir, layout string, err *error) func() {
+	if layout != layoutNested {
+		return func() {}
+	}
+	if _, statErr := os.Stat(longPath(destDir)); !os.IsNotExist(statErr) {
+		return func() {}
+	}
+	return func() {
+		if *err != nil {
+			_ = os.RemoveAll(longPath(destDir))
+		}
+	}
+}
+
+// extractZipFile writes a single zip entry to fpath.
+func extractZipFile(f *zip.File, fpath string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s in zip archive: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	// Archives created on Windows often carry no Unix permissions
+	perm := f.Mode().Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+	outFile, err := os.OpenFile(longPath(fpath), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file %s: %w", fpath, err)
+	}
+
+	_, err = io.Copy(outFile, rc)
+	closeErr := outFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to copy file contents to %s: %w", fpath, err)
+	}
+	return closeErr
+}
+
+// extractionRollback records what the extractions of an install create, so a failed install can remove
+// them all again: new version directories in the nested layout, and new files in the flat layout (whose
+// directory is shared with earlier installs). Files that already existed are overwritten in place.
+type extractionRollback struct {
+	root    string   // Output directory of the install; it and the paths outside it are never removed
+	created []string // In creation order
+}
+
+// track records what extracting zipData into destDir (in layout) will create. Call it before extracting.
+func (rb *extractionRollback) track(zipData []byte, destDir, layout string) error {
+	if layout == layoutNested {
+		if _, err := os.Stat(longPath(destDir)); os.IsNotExist(err) {
+			rb.created = append(rb.created, destDir)
+			return nil
+		}
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive reader: %w", err)
+	}
+	keep := layoutFilter(layout)
+	for _, f := range zipReader.File {
+		name, err := sanitizeZipEntryName(f.Name)
+		if err != nil || f.FileInfo().IsDir() || (keep != nil && !keep(name)) {
+			continue // Invalid entries fail the extraction before anything is written
+		}
+		fpath := filepath.Join(destDir, filepath.FromSlash(name))
+		if _, err := os.Stat(longPath(fpath)); os.IsNotExist(err) {
+			rb.created = append(rb.created, fpath)
+		}
+	}
+	return nil
+}
+
+// undo removes everything recorded, newest first, and then the directories that were left empty.
+func (rb *extractionRollback) undo() {
+	root := filepath.Clean(rb.root)
+	for i := len(rb.created) - 1; i >= 0; i-- {
+		_ = os.RemoveAll(longPath(rb.created[i]))
+		// Prune the parents the extraction created (os.Remove fails on non-empty directories)
+		for dir := filepath.Dir(rb.created[i]); dir != root && strings.HasPrefix(dir, root+string(os.PathSeparator)); dir = filepath.Dir(dir) {
+			if os.Remove(longPath(dir)) != nil {
+				break
+			}
+		}
+	}
+	rb.created = nil
+}
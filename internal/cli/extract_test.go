@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// buildZip creates an in-memory zip with the given entry names (entries ending in '/' are directories).
+func buildZip(t *testing.T, names ...string) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		if name[len(name)-1] != '/' {
+			_, err = w.Write([]byte("syntax = \"proto3\";\n"))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestSanitizeZipEntryName(t *testing.T) {
+	valid := map[string]string{
+		"user/v1/user.proto":   "user/v1/user.proto",
+		`user\v1\user.proto`:   "user/v1/user.proto", // Windows separators
+		"./user//user.proto":   "user/user.proto",
+		"dir/":                 "dir",
+		"console/config.proto": "console/config.proto", // Only exact device names are reserved
+	}
+	for name, expected := range valid {
+		got, err := sanitizeZipEntryName(name)
+		if assert.NoError(t, err, name) {
+			assert.Equal(t, expected, got, name)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"/etc/passwd",
+		`\\server\share\a.proto`,
+		"C:/Windows/a.proto",
+		"c:a.proto",
+		"../a.proto",
+		`protos\..\..\a.proto`,
+		"a/b:c.proto",
+		"a/b?.proto",
+		"a/b\x01.proto",
+		"a/trailing./b.proto",
+		"a/trailing /b.proto",
+		"aux/a.proto",
+		"a/CON",
+		"a/nul.proto",
+		"a/com1.txt",
+	}
+	for _, name := range invalid {
+		_, err := sanitizeZipEntryName(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestExtractZip(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "out")
+	data := buildZip(t, "user/", `user\v1\user.proto`, "common/types.proto")
+
+	count, err := extractZip(data, dest, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.FileExists(t, filepath.Join(dest, "user", "v1", "user.proto"))
+	assert.FileExists(t, filepath.Join(dest, "common", "types.proto"))
+}
+
+func TestExtractZip_RejectsBeforeWriting(t *testing.T) {
+	tests := map[string][]string{
+		"traversal":      {"ok.proto", "../escape.proto"},
+		"device name":    {"ok.proto", "nul.proto"},
+		"case collision": {"User.proto", "user.proto"},
+	}
+	for name, entries := range tests {
+		t.Run(name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "out")
+			_, err := extractZip(buildZip(t, entries...), dest, zap.NewNop())
+			assert.Error(t, err)
+			// Nothing is written when any entry is invalid
+			_, statErr := os.Stat(dest)
+			assert.True(t, os.IsNotExist(statErr))
+		})
+	}
+}
+
+func TestExtractZip_FlatLayout(t *testing.T) {
+	out := t.TempDir()
+	data := buildZip(t, "sproto.yaml", "acme/", "acme/user/v1/user.proto", "README.md")
+
+	dest := extractionPath(out, layoutFlat, "acme", "user", "v1.0.0")
+	assert.Equal(t, out, dest)
+	count, err := extractZipFiltered(data, dest, layoutFilter(layoutFlat), zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	// Only .proto files, at their import path
+	assert.FileExists(t, filepath.Join(out, "acme", "user", "v1", "user.proto"))
+	assert.NoFileExists(t, filepath.Join(out, "sproto.yaml"))
+	assert.NoFileExists(t, filepath.Join(out, "README.md"))
+
+	// The nested layout keeps everything under the module version's directory
+	assert.Equal(t, filepath.Join(out, "acme", "user", "v1.0.0"), extractionPath(out, layoutNested, "acme", "user", "v1.0.0"))
+	assert.Nil(t, layoutFilter(layoutNested))
+	assert.Error(t, validateLayout("tree"))
+}
+
+func TestZipFileChecksums(t *testing.T) {
+	sums, err := zipFileChecksums(buildZip(t, "sproto.yaml", "acme/", `Acme\User.proto`), layoutFilter(layoutFlat))
+	require.NoError(t, err)
+	require.Len(t, sums, 1)
+	assert.Contains(t, sums, "acme/user.proto")
+
+	_, err = zipFileChecksums(buildZip(t, "../escape.proto"), nil)
+	assert.Error(t, err)
+}
+
+func TestRemovePartialExtraction(t *testing.T) {
+	out := t.TempDir()
+	extract := func(dest, layout string, fail bool) (err error) {
+		defer removePartialExtraction(dest, layout, &err)()
+		require.NoError(t, os.MkdirAll(dest, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dest, "a.proto"), nil, 0644))
+		if fail {
+			return assert.AnError
+		}
+		return nil
+	}
+
+	// A version directory created by a failed extraction is removed
+	dest := extractionPath(out, layoutNested, "acme", "user", "v1.0.0")
+	require.Error(t, extract(dest, layoutNested, true))
+	assert.NoDirExists(t, dest)
+
+	// Successful extractions, directories that existed before and flat layouts are kept
+	require.NoError(t, extract(dest, layoutNested, false))
+	assert.DirExists(t, dest)
+	require.Error(t, extract(dest, layoutNested, true))
+	assert.DirExists(t, dest)
+	flat := filepath.Join(out, "include")
+	require.Error(t, extract(flat, layoutFlat, true))
+	assert.DirExists(t, flat)
+}
+
+func TestExtractionRollback(t *testing.T) {
+	for _, layout := range []string{layoutNested, layoutFlat} {
+		t.Run(layout, func(t *testing.T) {
+			out := t.TempDir()
+			// Left by an earlier install: kept, even when overwritten
+			earlier := filepath.Join(extractionPath(out, layout, "acme", "user", "v1.0.0"), "acme", "user", "v1", "user.proto")
+			require.NoError(t, os.MkdirAll(filepath.Dir(earlier), 0755))
+			require.NoError(t, os.WriteFile(earlier, nil, 0644))
+
+			rollback := &extractionRollback{root: out}
+			for _, dep := range []struct{ name, version, file string }{
+				{"user", "v1.0.0", "acme/user/v1/user.proto"},
+				{"orders", "v2.0.0", "acme/orders/v2/orders.proto"},
+			} {
+				data := buildZip(t, "sproto.yaml", dep.file)
+				dest := extractionPath(out, layout, "acme", dep.name, dep.version)
+				require.NoError(t, rollback.track(data, dest, layout))
+				_, err := extractZipFiltered(data, dest, layoutFilter(layout), zap.NewNop())
+				require.NoError(t, err)
+			}
+			assert.FileExists(t, filepath.Join(extractionPath(out, layout, "acme", "orders", "v2.0.0"), "acme", "orders", "v2", "orders.proto"))
+
+			rollback.undo()
+			assert.FileExists(t, earlier)
+			entries, err := os.ReadDir(filepath.Join(out, "acme"))
+			require.NoError(t, err)
+			require.Len(t, entries, 1, "the directories of the second module are removed")
+			assert.Equal(t, "user", entries[0].Name())
+			assert.DirExists(t, out)
+		})
+	}
+}
@@ -1,26 +1,33 @@
 package cli
 
 import (
-	"archive/zip"
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strings"
 
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
-var fetchOutputDir string
+var (
+	fetchOutputDir string
+	fetchLayout    string
+	fetchPaths     string
+	fetchRewrites  []string
+	fetchSince     string
+)
 
 // fetchCmd represents the fetch command
 var fetchCmd = &cobra.Command{
-	Use:   "fetch <namespace/module_name> <version>",
+	Use:   "fetch [namespace/module_name|directory] [version]",
 	Short: "Fetch and extract a module version artifact",
 	Long: `Downloads the artifact (zip file) for a specific module version from the registry
 and extracts its contents into a specified output directory.
@@ -28,135 +35,259 @@ and extracts its contents into a specified output directory.
 The extracted files will be placed under the directory structure:
 <output_dir>/<namespace>/<module_name>/<version>/...
 
-Example:
-  protoreg-cli fetch mycompany/user v1.0.0 --output ./protos`,
-	Args: cobra.ExactArgs(2), // Requires module name and version
-	Run: func(cmd *cobra.Command, args []string) {
-		log := GetLogger()
-		registryURL := viper.GetString("registry_url")
-		if registryURL == "" {
-			log.Fatal("Registry URL is not configured. Use --registry-url flag, PROTOREG_REGISTRY_URL env var, or 'protoreg-cli configure'.")
-		}
-		if fetchOutputDir == "" {
-			log.Fatal("--output flag is required")
-		}
+With --layout flat, only the .proto files are extracted, directly into <output_dir>, so it can be
+used as an include path: import "mycompany/user/v1/user.proto" resolves with protoc -I <output_dir>.
 
-		moduleFullName := args[0]
-		version := args[1]
+With --paths, only the files under the given directories or matching the given files are fetched:
+the registry builds a smaller zip from the artifact, so consumers of a large module download only
+the slice they need. If a registry public key is configured, the slice can't be checked against the
+signed checksum statement, so the whole artifact is downloaded, verified and filtered locally instead.
 
-		parts := strings.SplitN(moduleFullName, "/", 2)
-		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-			log.Fatal("Invalid module name format. Expected 'namespace/module_name'.", zap.String("module", moduleFullName))
-		}
-		namespace := parts[0]
-		moduleName := parts[1]
+With --rewrite-imports from=to (repeatable), the import statements of the extracted .proto files and
+the paths the files are extracted to are rewritten: paths starting with the directory from start with to
+instead. A '*' component matches any directory and is carried over to the first '*' of to. An empty
+from prefixes every path, an empty to strips the prefix; the longest matching from wins. Imports of other
+modules' files are rewritten too, so fetch dependencies with the same mappings.
 
-		// Validate version format (basic check)
-		if !strings.HasPrefix(version, "v") {
-			log.Fatal("Invalid version format: must start with 'v'", zap.String("version", version))
-		}
-		// More robust SemVer validation could be added here
+With --since, an extracted copy of another version is updated like rsync: the registry's SHA256 of
+each file is compared with the files on disk, and only the files that changed are downloaded. In the
+flat layout, the copy is updated in place and the files removed from the module are deleted (unless
+modified locally); in the nested layout, unchanged files are copied from the other version's directory.
 
-		client := &http.Client{}
+Instead of a version, a dev channel (dev-<name>, published by 'protoreg-cli dev') can be fetched to get
+its latest work in progress. Dev channels are mutable, so the registry signs no checksum statement for
+them; their artifact is only checked against the digest the registry reports.
 
-		// Construct URL
-		encodedNamespace := url.PathEscape(namespace)
-		encodedModuleName := url.PathEscape(moduleName)
-		encodedVersion := url.PathEscape(version) // Version might contain special chars in pre-release/build metadata
-		targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s/artifact", strings.TrimSuffix(registryURL, "/"), encodedNamespace, encodedModuleName, encodedVersion)
-		log.Info("Fetching artifact", zap.String("url", targetURL))
+Instead of a module name, a module directory can be given: the module name and (unless
+given) the version are then read from its sproto.yaml. Without arguments, the current
+directory's sproto.yaml is used. The namespace can be omitted if a default namespace is
+configured ('protoreg-cli configure').
 
-		req, err := http.NewRequest("GET", targetURL, nil)
+Examples:
+  protoreg-cli fetch mycompany/user v1.0.0 --output ./protos
+  protoreg-cli fetch mycompany/user v1.0.0 --output ./include --layout flat
+  protoreg-cli fetch mycompany/billing v2.3.0 --output ./protos --paths billing/,common/types.proto
+  protoreg-cli fetch mycompany/user v1.0.0 --output ./protos --layout flat --rewrite-imports 'mycompany/*/v1=mycompany/*'
+  protoreg-cli fetch mycompany/user v1.0.0 --output ./protos --layout flat --rewrite-imports =vendor
+  protoreg-cli fetch mycompany/user v1.3.0 --output ./include --layout flat --since v1.2.0
+  protoreg-cli fetch mycompany/user dev-alice --output ./protos    # latest WIP of a dev channel
+  protoreg-cli fetch --output ./protos     # name and version from ./sproto.yaml`,
+	Args: cobra.MaximumNArgs(2), // Module name (or directory) and version
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
 		if err != nil {
-			log.Fatal("Failed to create request", zap.Error(err))
+			return err
+		}
+		if fetchOutputDir == "" {
+			return exitErrorf(ExitUsage, "--output flag is required")
+		}
+		if err := validateLayout(fetchLayout); err != nil {
+			return exitErrorf(ExitUsage, "invalid --layout: %w", err)
+		}
+		var paths []string
+		if cmd.Flags().Changed("paths") {
+			if paths, err = artifact.ParsePaths(fetchPaths); err != nil {
+				return exitErrorf(ExitUsage, "invalid --paths: %w", err)
+			}
+		}
+		rewrites, err := parseImportRewrites(fetchRewrites)
+		if err != nil {
+			return exitErrorf(ExitUsage, "invalid --rewrite-imports: %w", err)
 		}
 
-		resp, err := client.Do(req)
+		// Module name or directory (default: the current directory), and version
+		moduleArg, version := ".", ""
+		if len(args) > 0 {
+			moduleArg = args[0]
+		}
+		if len(args) > 1 {
+			version = args[1]
+		}
+		namespace, moduleName, version, err := resolveModuleArg(moduleArg, version)
 		if err != nil {
-			log.Fatal("Failed to execute request", zap.Error(err))
+			return exitErrorf(ExitValidation, "invalid module: %w", err)
+		}
+		if version == "" {
+			return exitErrorf(ExitUsage, "version is required (as an argument or in sproto.yaml)")
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			bodyBytes, _ := io.ReadAll(resp.Body) // Read body for error reporting
-			handleApiError(resp.StatusCode, bodyBytes, log)
-			os.Exit(1)
+		// Validate version format (basic check); dev channels (dev-<name>, see 'protoreg-cli dev') are fetched like versions
+		if !strings.HasPrefix(version, "v") && !validation.IsDevChannel(version) {
+			return exitErrorf(ExitValidation, "invalid version format %q: must start with 'v' (or be a dev channel, dev-<name>)", version)
+		}
+		// More robust SemVer validation could be added here
+
+		if fetchSince != "" {
+			// Files are listed by version, so dev channels can't be synced; rewritten paths can't be compared
+			if !strings.HasPrefix(version, "v") || !strings.HasPrefix(fetchSince, "v") {
+				return exitErrorf(ExitUsage, "--since requires versions starting with 'v'")
+			}
+			if len(rewrites) > 0 {
+				return exitErrorf(ExitUsage, "--since can't be combined with --rewrite-imports")
+			}
+			return fetchChangedFiles(registryURL, namespace, moduleName, version, paths, log)
 		}
 
-		// Read the entire zip file into memory (for simplicity with archive/zip)
-		// For very large files, streaming extraction might be better, but more complex.
-		zipData, err := io.ReadAll(resp.Body)
+		var zipData []byte
+		if paths != nil && viper.GetString("registry_public_key") == "" && !validation.IsDevChannel(version) {
+			zipData, err = downloadPartialArtifact(&http.Client{}, registryURL, namespace, moduleName, version, paths, log)
+		} else {
+			// Without --paths, or to verify the whole artifact before filtering it locally
+			zipData, err = downloadArtifact(&http.Client{}, registryURL, namespace, moduleName, version, log)
+		}
 		if err != nil {
-			log.Fatal("Failed to read artifact zip data", zap.Error(err))
+			return fmt.Errorf("failed to fetch artifact: %w", err)
+		}
+		keep := layoutFilter(fetchLayout)
+		if paths != nil {
+			layoutKeep := keep
+			keep = func(name string) bool {
+				return artifact.MatchesPaths(name, paths) && (layoutKeep == nil || layoutKeep(name))
+			}
+		}
+		if len(rewrites) > 0 {
+			// Rewritten after filtering, so --paths and the layout apply to the published paths
+			if zipData, err = rewriteArtifact(zipData, keep, rewrites); err != nil {
+				return exitErrorf(ExitValidation, "failed to rewrite imports: %w", err)
+			}
+			keep = nil
 		}
 
 		// --- Extraction Logic ---
-		extractionBasePath := filepath.Join(fetchOutputDir, namespace, moduleName, version)
-		log.Info("Extracting artifact", zap.String("path", extractionBasePath))
+		extractionBasePath := extractionPath(fetchOutputDir, fetchLayout, namespace, moduleName, version)
+		log.Info("Extracting artifact", zap.String("path", extractionBasePath), zap.String("layout", fetchLayout))
+		defer removePartialExtraction(extractionBasePath, fetchLayout, &err)()
 
-		zipReader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+		extractedCount, err := extractZipFiltered(zipData, extractionBasePath, keep, log)
 		if err != nil {
-			log.Fatal("Failed to open zip archive reader", zap.Error(err))
+			return fmt.Errorf("failed to extract artifact to %s: %w", extractionBasePath, err)
+		}
+		if extractedCount == 0 && paths != nil {
+			return exitErrorf(ExitValidation, "no file of %s/%s@%s matches --paths %s", namespace, moduleName, version, fetchPaths)
 		}
 
-		// Ensure base directory exists
-		if err := os.MkdirAll(extractionBasePath, 0755); err != nil {
-			log.Fatal("Failed to create extraction directory", zap.String("path", extractionBasePath), zap.Error(err))
+		log.Info("Artifact extracted successfully", zap.Int("files_extracted", extractedCount), zap.String("output_dir", extractionBasePath))
+		fmt.Printf("Successfully fetched and extracted %d files to %s\n", extractedCount, extractionBasePath)
+		return nil
+	},
+}
+
+// fetchChangedFiles updates the extracted copy of fetchSince to version (fetch --since), downloading only
+// the files that changed.
+func fetchChangedFiles(registryURL, namespace, moduleName, version string, paths []string, log *zap.Logger) (err error) {
+	keep := layoutFilter(fetchLayout)
+	if paths != nil {
+		layoutKeep := keep
+		keep = func(name string) bool {
+			return artifact.MatchesPaths(name, paths) && (layoutKeep == nil || layoutKeep(name))
 		}
+	}
+	destDir := extractionPath(fetchOutputDir, fetchLayout, namespace, moduleName, version)
+	sinceDir := extractionPath(fetchOutputDir, fetchLayout, namespace, moduleName, fetchSince)
+	log.Info("Syncing artifact", zap.String("path", destDir), zap.String("since", fetchSince), zap.String("layout", fetchLayout))
+	defer removePartialExtraction(destDir, fetchLayout, &err)()
 
-		extractedCount := 0
-		for _, f := range zipReader.File {
-			fpath := filepath.Join(extractionBasePath, f.Name)
+	result, err := syncArtifact(&http.Client{}, registryURL, namespace, moduleName, version, fetchSince, destDir, sinceDir, keep, log)
+	if err != nil {
+		return err
+	}
+	if result.Downloaded+result.Unchanged == 0 && paths != nil {
+		return exitErrorf(ExitValidation, "no file of %s/%s@%s matches --paths %s", namespace, moduleName, version, fetchPaths)
+	}
+	fmt.Printf("Synced %s/%s@%s to %s: %d files downloaded, %d unchanged, %d removed\n", namespace, moduleName, version, destDir,
+		result.Downloaded, result.Unchanged, result.Removed)
+	return nil
+}
 
-			// Basic path traversal check
-			if !strings.HasPrefix(fpath, filepath.Clean(extractionBasePath)+string(os.PathSeparator)) {
-				log.Fatal("Invalid file path in zip archive (potential traversal attack)", zap.String("path", f.Name))
-			}
+// downloadArtifact downloads a module version's artifact (zip) into memory and, if a registry public key
+// is configured, verifies it against the registry's signed checksum statement (see verifyChecksum).
+func downloadArtifact(client *http.Client, registryURL, namespace, moduleName, version string, log *zap.Logger) ([]byte, error) {
+	// Construct URL
+	encodedNamespace := url.PathEscape(namespace)
+	encodedModuleName := url.PathEscape(moduleName)
+	encodedVersion := url.PathEscape(version) // Version might contain special chars in pre-release/build metadata
+	targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s/artifact", strings.TrimSuffix(registryURL, "/"), encodedNamespace, encodedModuleName, encodedVersion)
+	log.Info("Fetching artifact", zap.String("url", targetURL))
 
-			log.Debug("Extracting file", zap.String("path", fpath))
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setReadToken(req)
 
-			if f.FileInfo().IsDir() {
-				// Create directory
-				if err := os.MkdirAll(fpath, os.ModePerm); err != nil { // Use ModePerm for simplicity, could use f.Mode()
-					log.Fatal("Failed to create directory from zip", zap.String("path", fpath), zap.Error(err))
-				}
-				continue
-			}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
 
-			// Create containing directory if needed
-			if err := os.MkdirAll(filepath.Dir(fpath), os.ModePerm); err != nil {
-				log.Fatal("Failed to create directory for file", zap.String("path", fpath), zap.Error(err))
-			}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body) // Read body for error reporting
+		return nil, fmt.Errorf("%s/%s@%s: %w", namespace, moduleName, version, registryError(resp.StatusCode, bodyBytes))
+	}
 
-			// Open the file within the zip archive
-			rc, err := f.Open()
-			if err != nil {
-				log.Fatal("Failed to open file in zip archive", zap.String("name", f.Name), zap.Error(err))
-			}
+	// Read the entire zip file into memory (for simplicity with archive/zip)
+	// For very large files, streaming extraction might be better, but more complex.
+	zipData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact zip data: %w", err)
+	}
 
-			// Create the destination file
-			outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			if err != nil {
-				rc.Close()
-				log.Fatal("Failed to create destination file", zap.String("path", fpath), zap.Error(err))
+	// Dev channels change, so there is no signed checksum statement; the digest header catches corrupted transfers
+	if validation.IsDevChannel(version) {
+		if digest := resp.Header.Get(apitypes.ArtifactDigestHeader); digest != "" {
+			if sum := sha256.Sum256(zipData); "sha256:"+hex.EncodeToString(sum[:]) != digest {
+				return nil, withExitCode(ExitValidation, fmt.Errorf("artifact digest sha256:%x does not match the registry's %s", sum, digest))
 			}
+		}
+		return zipData, nil
+	}
 
-			// Copy contents
-			_, err = io.Copy(outFile, rc)
+	// With a registry public key configured, refuse artifacts that don't match the signed checksum statement
+	if err := verifyChecksum(client, registryURL, namespace, moduleName, version, zipData, log); err != nil {
+		return nil, fmt.Errorf("checksum verification failed: %w", err)
+	}
+	return zipData, nil
+}
 
-			// Close files
-			rc.Close()
-			outFile.Close() // Close immediately after copy
+// downloadPartialArtifact downloads the files of a module version's artifact matching paths, as a zip built
+// by the registry (?paths=). It isn't the published artifact, so it can't be checked against the signed
+// checksum statement; its digest is checked against the ETag to catch corrupted transfers.
+func downloadPartialArtifact(client *http.Client, registryURL, namespace, moduleName, version string, paths []string, log *zap.Logger) ([]byte, error) {
+	targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s/artifact?paths=%s", strings.TrimSuffix(registryURL, "/"),
+		url.PathEscape(namespace), url.PathEscape(moduleName), url.PathEscape(version), url.QueryEscape(strings.Join(paths, ",")))
+	log.Info("Fetching partial artifact", zap.String("url", targetURL))
 
-			if err != nil {
-				log.Fatal("Failed to copy file contents", zap.String("path", fpath), zap.Error(err))
-			}
-			extractedCount++
-		}
+	req, err := http.NewRequest("GET", targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setReadToken(req)
 
-		log.Info("Artifact extracted successfully", zap.Int("files_extracted", extractedCount), zap.String("output_dir", extractionBasePath))
-		fmt.Printf("Successfully fetched and extracted %d files to %s\n", extractedCount, extractionBasePath)
-	},
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body) // Read body for error reporting
+		return nil, fmt.Errorf("%s/%s@%s: %w", namespace, moduleName, version, registryError(resp.StatusCode, bodyBytes))
+	}
+	zipData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact zip data: %w", err)
+	}
+
+	if etag := strings.Trim(resp.Header.Get("ETag"), `"`); etag != "" {
+		if sum := sha256.Sum256(zipData); hex.EncodeToString(sum[:]) != etag {
+			return nil, withExitCode(ExitValidation, fmt.Errorf("partial artifact digest sha256:%x does not match the registry's sha256:%s", sum, etag))
+		}
+	}
+	log.Info("Fetched partial artifact", zap.String("files", resp.Header.Get(apitypes.PartialFilesHeader)),
+		zap.Int("size", len(zipData)), zap.String("source_digest", resp.Header.Get(apitypes.PartialSourceDigestHeader)))
+	return zipData, nil
 }
 
 func init() {
@@ -165,4 +296,8 @@ func init() {
 	// Required flag for output directory
 	fetchCmd.Flags().StringVarP(&fetchOutputDir, "output", "o", "", "Base directory to extract proto files into (required)")
 	_ = fetchCmd.MarkFlagRequired("output")
+	fetchCmd.Flags().StringVar(&fetchPaths, "paths", "", "Only fetch these files or directories, comma-separated (e.g. billing/,common/types.proto)")
+	fetchCmd.Flags().StringArrayVar(&fetchRewrites, "rewrite-imports", nil, "Rewrite import paths (and the extracted files' paths) starting with from to start with to, as from=to (repeatable)")
+	fetchCmd.Flags().StringVar(&fetchSince, "since", "", "Update the extracted copy of this version, only downloading the files that changed")
+	fetchCmd.Flags().StringVar(&fetchLayout, "layout", layoutNested, "Output layout: nested (<output>/<namespace>/<name>/<version>/) or flat (.proto files directly in <output>, an include path)")
 }
@@ -0,0 +1,399 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+var (
+	filesSince string
+	filesPaths string
+)
+
+// Status of a file between two versions
+const (
+	fileAdded   = "added"
+	fileChanged = "changed"
+	fileRemoved = "removed"
+)
+
+// fileChange is a file that differs between two versions.
+type fileChange struct {
+	Status string
+	File   apitypes.VersionFileResponse // The new file; the old one if removed
+}
+
+// filesCmd represents the files command
+var filesCmd = &cobra.Command{
+	Use:   "files <namespace/module_name> <version>",
+	Short: "List the files of a module version with their SHA256",
+	Long: `Lists the files of a module version's artifact with their size and SHA256, as recorded by
+the registry when the version was published.
+
+With --since, only the files added, changed or removed since another version are listed:
+what 'protoreg-cli fetch --since' downloads to update an extracted copy of that version.
+
+Examples:
+  protoreg-cli files mycompany/user v1.2.0
+  protoreg-cli files mycompany/billing v2.3.0 --paths billing/
+  protoreg-cli files mycompany/user v1.3.0 --since v1.2.0`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		namespace, moduleName, err := splitModuleArg(args[0])
+		if err != nil {
+			return err
+		}
+		var paths []string
+		if cmd.Flags().Changed("paths") {
+			if paths, err = artifact.ParsePaths(filesPaths); err != nil {
+				return exitErrorf(ExitUsage, "invalid --paths: %w", err)
+			}
+		}
+		for _, v := range []string{args[1], filesSince} {
+			if v != "" && !strings.HasPrefix(v, "v") {
+				return exitErrorf(ExitValidation, "invalid version format %q: must start with 'v'", v)
+			}
+		}
+
+		client := &http.Client{}
+		list, err := fetchVersionFiles(client, registryURL, namespace, moduleName, args[1], paths, log)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if filesSince == "" {
+			if len(list.Files) == 0 {
+				fmt.Printf("No files in %s/%s@%s\n", namespace, moduleName, list.Version)
+				return nil
+			}
+			fmt.Fprintln(tw, "PATH\tSIZE\tDIGEST")
+			for _, f := range list.Files {
+				fmt.Fprintf(tw, "%s\t%d\t%s\n", f.Path, f.Size, f.Digest)
+			}
+			return tw.Flush()
+		}
+
+		since, err := fetchVersionFiles(client, registryURL, namespace, moduleName, filesSince, paths, log)
+		if err != nil {
+			return err
+		}
+		changes := diffVersionFiles(since.Files, list.Files)
+		if len(changes) == 0 {
+			fmt.Printf("No file changed between %s and %s\n", since.Version, list.Version)
+			return nil
+		}
+		fmt.Fprintln(tw, "STATUS\tPATH\tSIZE\tDIGEST")
+		for _, c := range changes {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", c.Status, c.File.Path, c.File.Size, c.File.Digest)
+		}
+		return tw.Flush()
+	},
+}
+
+// splitModuleArg returns the namespace and name of a module argument (the namespace may be omitted if a
+// default namespace is configured). Invalid names are reported as validation errors.
+func splitModuleArg(moduleArg string) (string, string, error) {
+	moduleFullName := qualifyModule(moduleArg)
+	parts := strings.SplitN(moduleFullName, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", exitErrorf(ExitValidation, "invalid module name format %q: expected 'namespace/module_name'", moduleFullName)
+	}
+	return parts[0], parts[1], nil
+}
+
+// fetchVersionFiles lists the files of a module version's artifact, only those matching paths if not nil.
+func fetchVersionFiles(client *http.Client, registryURL, namespace, moduleName, version string, paths []string, log *zap.Logger) (*apitypes.ListVersionFilesResponse, error) {
+	targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s/files", strings.TrimSuffix(registryURL, "/"),
+		url.PathEscape(namespace), url.PathEscape(moduleName), url.PathEscape(version))
+	if paths != nil {
+		targetURL += "?paths=" + url.QueryEscape(strings.Join(paths, ","))
+	}
+	log.Info("Listing artifact files", zap.String("url", targetURL))
+
+	req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setReadToken(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s/%s@%s: %w", namespace, moduleName, version, registryError(resp.StatusCode, bodyBytes))
+	}
+
+	var list apitypes.ListVersionFilesResponse
+	if err := json.Unmarshal(bodyBytes, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	return &list, nil
+}
+
+// diffVersionFiles returns the files added, changed or removed between two file lists (sorted by path, as
+// listed by the registry), by path.
+func diffVersionFiles(from, to []apitypes.VersionFileResponse) []fileChange {
+	var changes []fileChange
+	i, j := 0, 0
+	for i < len(from) || j < len(to) {
+		switch {
+		case j == len(to) || (i < len(from) && from[i].Path < to[j].Path):
+			changes = append(changes, fileChange{Status: fileRemoved, File: from[i]})
+			i++
+		case i == len(from) || to[j].Path < from[i].Path:
+			changes = append(changes, fileChange{Status: fileAdded, File: to[j]})
+			j++
+		default:
+			if from[i].Digest != to[j].Digest {
+				changes = append(changes, fileChange{Status: fileChanged, File: to[j]})
+			}
+			i++
+			j++
+		}
+	}
+	return changes
+}
+
+// localFileDigest returns the sha256:<hex> digest of a file, or "" if it doesn't exist.
+func localFileDigest(fpath string) (string, error) {
+	f, err := os.Open(longPath(fpath))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", fpath, err)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// copyLocalFile copies src to dst, creating dst's directory.
+func copyLocalFile(src, dst string) error {
+	data, err := os.ReadFile(longPath(src))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(longPath(filepath.Dir(dst)), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", dst, err)
+	}
+	return os.WriteFile(longPath(dst), data, 0644)
+}
+
+// syncResult counts what syncArtifact did.
+type syncResult struct {
+	Downloaded int // Files downloaded from the registry
+	Unchanged  int // Files already on disk, or copied from the extracted copy of the other version
+	Removed    int // Files of the other version no longer in the version (same directory only)
+}
+
+// syncArtifact extracts the files of a module version accepted by keep (nil: all) into destDir, downloading
+// only the ones whose content isn't already on disk, like rsync: files are compared by SHA256 with those in
+// destDir, then with those of sinceVersion's extracted copy in sinceDir. If both are the same directory
+// (the flat layout), the files sinceVersion had but the version doesn't are removed, unless modified locally.
+func syncArtifact(client *http.Client, registryURL, namespace, moduleName, version, sinceVersion, destDir, sinceDir string, keep func(name string) bool, log *zap.Logger) (syncResult, error) {
+	var result syncResult
+	list, err := fetchVersionFiles(client, registryURL, namespace, moduleName, version, nil, log)
+	if err != nil {
+		return result, err
+	}
+	wanted, err := keptFiles(list.Files, keep)
+	if err != nil {
+		return result, err
+	}
+
+	var missing []apitypes.VersionFileResponse
+	for _, f := range wanted {
+		dst := filepath.Join(destDir, filepath.FromSlash(f.Path))
+		ok, err := hasLocalFile(dst, f)
+		if err != nil {
+			return result, err
+		}
+		if ok {
+			result.Unchanged++
+			continue
+		}
+		if sinceDir != destDir {
+			src := filepath.Join(sinceDir, filepath.FromSlash(f.Path))
+			if ok, err = hasLocalFile(src, f); err != nil {
+				return result, err
+			}
+			if ok {
+				if err := copyLocalFile(src, dst); err != nil {
+					return result, fmt.Errorf("failed to copy %s: %w", src, err)
+				}
+				result.Unchanged++
+				continue
+			}
+		}
+		missing = append(missing, f)
+	}
+	if result.Downloaded, err = downloadFiles(client, registryURL, namespace, moduleName, version, missing, len(wanted), destDir, log); err != nil {
+		return result, err
+	}
+
+	if sinceDir != destDir {
+		return result, nil
+	}
+	since, err := fetchVersionFiles(client, registryURL, namespace, moduleName, sinceVersion, nil, log)
+	if err != nil {
+		return result, err
+	}
+	previous, err := keptFiles(since.Files, keep)
+	if err != nil {
+		return result, err
+	}
+	for _, c := range diffVersionFiles(previous, wanted) {
+		if c.Status != fileRemoved {
+			continue
+		}
+		fpath := filepath.Join(destDir, filepath.FromSlash(c.File.Path))
+		digest, err := localFileDigest(fpath)
+		if err != nil {
+			return result, err
+		}
+		if digest != c.File.Digest {
+			if digest != "" {
+				log.Warn("Keeping locally modified file removed from the module", zap.String("path", fpath))
+			}
+			continue
+		}
+		if err := os.Remove(longPath(fpath)); err != nil {
+			return result, fmt.Errorf("failed to remove %s: %w", fpath, err)
+		}
+		result.Removed++
+	}
+	return result, nil
+}
+
+// hasLocalFile reports whether the file at fpath has the content of a listed file.
+func hasLocalFile(fpath string, f apitypes.VersionFileResponse) (bool, error) {
+	digest, err := localFileDigest(fpath)
+	return digest == f.Digest, err
+}
+
+// downloadFiles downloads files of a module version (out of the total it has, after filtering) and extracts
+// them into destDir. Few files are downloaded as a partial artifact, most as the whole artifact, which is
+// also what is verified against the signed checksum statement if a registry public key is configured.
+// Every file is checked against its listed SHA256 before anything is written.
+func downloadFiles(client *http.Client, registryURL, namespace, moduleName, version string, files []apitypes.VersionFileResponse, total int, destDir string, log *zap.Logger) (int, error) {
+	if len(files) == 0 {
+		return 0, nil
+	}
+	paths := make([]string, 0, len(files))
+	digests := make(map[string]string, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path)
+		digests[f.Path] = f.Digest
+	}
+
+	var zipData []byte
+	var err error
+	if viper.GetString("registry_public_key") == "" && len(files) <= total/2 {
+		zipData, err = downloadPartialArtifact(client, registryURL, namespace, moduleName, version, paths, log)
+	} else {
+		zipData, err = downloadArtifact(client, registryURL, namespace, moduleName, version, log)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch artifact: %w", err)
+	}
+	if err := checkZipFileDigests(zipData, digests); err != nil {
+		return 0, withExitCode(ExitValidation, fmt.Errorf("%s/%s@%s: %w", namespace, moduleName, version, err))
+	}
+	count, err := extractZipFiltered(zipData, destDir, func(name string) bool { _, ok := digests[name]; return ok }, log)
+	if err != nil {
+		return count, fmt.Errorf("failed to extract artifact to %s: %w", destDir, err)
+	}
+	return count, nil
+}
+
+// checkZipFileDigests checks that the zip has every file of digests (by sanitized path), with that SHA256.
+func checkZipFileDigests(zipData []byte, digests map[string]string) error {
+	zipReader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive reader: %w", err)
+	}
+	found := 0
+	for _, f := range zipReader.File {
+		name, err := sanitizeZipEntryName(f.Name)
+		if err != nil {
+			return fmt.Errorf("invalid file path in zip archive: %w", err)
+		}
+		want, ok := digests[name]
+		if !ok || f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s in zip archive: %w", f.Name, err)
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read %s in zip archive: %w", f.Name, err)
+		}
+		if got := "sha256:" + hex.EncodeToString(hash.Sum(nil)); got != want {
+			return fmt.Errorf("file %s has digest %s, the registry listed %s", name, got, want)
+		}
+		found++
+	}
+	if found != len(digests) {
+		return fmt.Errorf("the artifact is missing %d of the listed files", len(digests)-found)
+	}
+	return nil
+}
+
+// keptFiles returns the files accepted by keep (nil: all), with their sanitized paths.
+func keptFiles(files []apitypes.VersionFileResponse, keep func(name string) bool) ([]apitypes.VersionFileResponse, error) {
+	kept := make([]apitypes.VersionFileResponse, 0, len(files))
+	for _, f := range files {
+		name, err := sanitizeZipEntryName(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file path in artifact: %w", err)
+		}
+		if keep == nil || keep(name) {
+			f.Path = name
+			kept = append(kept, f)
+		}
+	}
+	return kept, nil
+}
+
+func init() {
+	rootCmd.AddCommand(filesCmd)
+
+	filesCmd.Flags().StringVar(&filesSince, "since", "", "Only list the files added, changed or removed since this version")
+	filesCmd.Flags().StringVar(&filesPaths, "paths", "", "Only list these files or directories, comma-separated (e.g. billing/,common/types.proto)")
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDiffVersionFiles(t *testing.T) {
+	file := func(path, digest string) apitypes.VersionFileResponse {
+		return apitypes.VersionFileResponse{Path: path, Digest: digest}
+	}
+	from := []apitypes.VersionFileResponse{file("a.proto", "1"), file("b.proto", "1"), file("d.proto", "1")}
+	to := []apitypes.VersionFileResponse{file("a.proto", "1"), file("b.proto", "2"), file("c.proto", "1")}
+	assert.Equal(t, []fileChange{
+		{Status: fileChanged, File: file("b.proto", "2")},
+		{Status: fileAdded, File: file("c.proto", "1")},
+		{Status: fileRemoved, File: file("d.proto", "1")},
+	}, diffVersionFiles(from, to))
+	assert.Empty(t, diffVersionFiles(to, to))
+}
+
+// filesRegistry serves the file listings and (partial) artifacts of module versions' contents.
+func filesRegistry(t *testing.T, versions map[string]map[string]string, partials *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(r.URL.Path, "/") // /api/v1/modules/{namespace}/{module_name}/{version}/{files|artifact}
+		files, ok := versions[parts[6]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"Module version not found"}`))
+			return
+		}
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		pack := func(names []string) []byte {
+			buf := new(bytes.Buffer)
+			zw := zip.NewWriter(buf)
+			for _, name := range names {
+				fw, err := zw.Create(name)
+				require.NoError(t, err)
+				_, err = fw.Write([]byte(files[name]))
+				require.NoError(t, err)
+			}
+			require.NoError(t, zw.Close())
+			return buf.Bytes()
+		}
+
+		if parts[7] == "files" {
+			sum := sha256.Sum256(pack(names))
+			list := apitypes.ListVersionFilesResponse{Version: parts[6], ArtifactDigest: "sha256:" + hex.EncodeToString(sum[:])}
+			for _, name := range names {
+				sum := sha256.Sum256([]byte(files[name]))
+				list.Files = append(list.Files, apitypes.VersionFileResponse{Path: name, Size: int64(len(files[name])), Digest: "sha256:" + hex.EncodeToString(sum[:])})
+			}
+			_ = json.NewEncoder(w).Encode(list)
+			return
+		}
+		if r.URL.Query().Has("paths") {
+			*partials = append(*partials, r.URL.Query().Get("paths"))
+			names = strings.Split(r.URL.Query().Get("paths"), ",")
+		}
+		data := pack(names)
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sha256.Sum256(data)))
+		_, _ = w.Write(data)
+	}))
+}
+
+func TestSyncArtifact(t *testing.T) {
+	versions := map[string]map[string]string{
+		"v1.0.0": {"a.proto": "a1", "b.proto": "b1", "c.proto": "c1", "d.proto": "d1", "old.proto": "old", "edited.proto": "edited"},
+		"v1.1.0": {"a.proto": "a1", "b.proto": "b2", "c.proto": "c1", "d.proto": "d1", "e.proto": "e2"},
+	}
+	var partials []string
+	srv := filesRegistry(t, versions, &partials)
+	defer srv.Close()
+	write := func(dir string, files map[string]string) {
+		for name, content := range files {
+			require.NoError(t, os.MkdirAll(dir, 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+		}
+	}
+	assertFiles := func(dir string, files map[string]string) {
+		for name, content := range files {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			require.NoError(t, err)
+			assert.Equal(t, content, string(data), name)
+		}
+	}
+
+	// Same directory: only the changed files are downloaded, and the removed ones deleted unless edited
+	dir := t.TempDir()
+	write(dir, versions["v1.0.0"])
+	write(dir, map[string]string{"edited.proto": "edited locally"})
+	result, err := syncArtifact(srv.Client(), srv.URL, "acme", "user", "v1.1.0", "v1.0.0", dir, dir, nil, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, syncResult{Downloaded: 2, Unchanged: 3, Removed: 1}, result)
+	assert.Equal(t, []string{"b.proto,e.proto"}, partials)
+	assertFiles(dir, versions["v1.1.0"])
+	assert.NoFileExists(t, filepath.Join(dir, "old.proto"))
+	assert.FileExists(t, filepath.Join(dir, "edited.proto"))
+
+	// Separate directories: unchanged files are copied from the other version's
+	partials = nil
+	out := t.TempDir()
+	sinceDir, destDir := filepath.Join(out, "v1.0.0"), filepath.Join(out, "v1.1.0")
+	write(sinceDir, versions["v1.0.0"])
+	result, err = syncArtifact(srv.Client(), srv.URL, "acme", "user", "v1.1.0", "v1.0.0", destDir, sinceDir, nil, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, syncResult{Downloaded: 2, Unchanged: 3}, result)
+	assert.Equal(t, []string{"b.proto,e.proto"}, partials)
+	assertFiles(destDir, versions["v1.1.0"])
+	assert.FileExists(t, filepath.Join(sinceDir, "old.proto"))
+
+	// Without a copy of the other version, most files are missing: the whole artifact is downloaded
+	partials = nil
+	result, err = syncArtifact(srv.Client(), srv.URL, "acme", "user", "v1.1.0", "v1.0.0", filepath.Join(out, "fresh"), filepath.Join(out, "none"), nil, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, syncResult{Downloaded: 5}, result)
+	assert.Empty(t, partials)
+
+	_, err = syncArtifact(srv.Client(), srv.URL, "acme", "user", "v9.0.0", "v1.0.0", dir, dir, nil, zap.NewNop())
+	assert.ErrorContains(t, err, "acme/user@v9.0.0")
+}
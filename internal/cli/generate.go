@@ -0,0 +1,316 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+var (
+	generatePlugin           string
+	generateOpts             []string
+	generateOut              string
+	generateContainerRuntime string
+)
+
+// registryPluginScheme prefixes --plugin references to plugins registered in the registry.
+const registryPluginScheme = "registry://"
+
+// generateCmd represents the generate command
+var generateCmd = &cobra.Command{
+	Use:   "generate [namespace/module_name|directory] [version]",
+	Short: "Generate code for a module version with a protoc plugin",
+	Long: `Runs a protoc plugin on a published module version and writes the generated files to --out,
+without a local protoc: the registry compiles the module with its dependencies (the
+descriptor-set bundle) and the plugin generates code for the module's own files.
+
+--plugin is either a plugin registered in the registry, registry://<name>[:<version>]
+(the version may be exact, partial like v1.34 for the newest 1.34.x, or omitted for the
+newest), or a local plugin executable (a path or a name on PATH). Registered plugins run
+from their binary for this platform, downloaded once and checked against its digest, or
+else from their container image (--container-runtime, docker by default).
+
+The module argument works like for 'fetch'. Source comments aren't available to plugins:
+the registry's descriptor sets carry no source info.
+
+Examples:
+  protoreg-cli generate mycompany/billing v1.2.0 --plugin registry://go:v1.34 --opt paths=source_relative --out gen/go
+  protoreg-cli generate mycompany/billing v1.2.0 --plugin ./bin/protoc-gen-validate --out gen`,
+	Args: cobra.MaximumNArgs(2), // Module name (or directory) and version
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		moduleArg, version := ".", ""
+		if len(args) > 0 {
+			moduleArg = args[0]
+		}
+		if len(args) > 1 {
+			version = args[1]
+		}
+		namespace, moduleName, version, err := resolveModuleArg(moduleArg, version)
+		if err != nil {
+			return exitErrorf(ExitValidation, "invalid module: %w", err)
+		}
+		if version == "" {
+			return exitErrorf(ExitUsage, "version is required (as an argument or in sproto.yaml)")
+		}
+		if !strings.HasPrefix(version, "v") {
+			return exitErrorf(ExitValidation, "invalid version format %q: must start with 'v'", version)
+		}
+		client := &http.Client{}
+
+		// --- Plugin ---
+		var pluginCmd []string
+		pluginName, pluginVersion, fromRegistry, err := parsePluginRef(generatePlugin)
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+		if fromRegistry {
+			plugin, err := fetchPlugin(client, registryURL, pluginName, pluginVersion, log)
+			if err != nil {
+				return err
+			}
+			if pluginCmd, err = registryPluginCommand(client, plugin, log); err != nil {
+				return err
+			}
+			fmt.Printf("Using plugin %s:%s\n", plugin.Name, plugin.Version)
+		} else {
+			executable, err := exec.LookPath(generatePlugin)
+			if err != nil {
+				return exitErrorf(ExitUsage, "plugin %q not found: %w", generatePlugin, err)
+			}
+			pluginCmd = []string{executable}
+		}
+
+		// --- Code Generator Request ---
+		// The module's own files are generated; the descriptor set provides them with their imports
+		zipData, err := downloadArtifact(client, registryURL, namespace, moduleName, version, log)
+		if err != nil {
+			return fmt.Errorf("failed to download artifact: %w", err)
+		}
+		summary, err := descriptor.SummarizeArtifact(zipData)
+		if err != nil {
+			return fmt.Errorf("failed to read artifact: %w", err)
+		}
+		setData, _, err := downloadBundle(client, registryURL, namespace, moduleName, version, apitypes.BundleFormatDescriptorSet, log)
+		if err != nil {
+			return fmt.Errorf("failed to download descriptor set: %w", err)
+		}
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(setData, &set); err != nil {
+			return fmt.Errorf("failed to parse descriptor set: %w", err)
+		}
+		request := &pluginpb.CodeGeneratorRequest{
+			FileToGenerate: summary.Files,
+			ProtoFile:      set.GetFile(), // Dependencies before dependents, as plugins expect
+		}
+		if len(generateOpts) > 0 {
+			request.Parameter = proto.String(strings.Join(generateOpts, ","))
+		}
+
+		// --- Run and Write ---
+		response, err := runPlugin(cmd.Context(), pluginCmd, request)
+		if err != nil {
+			return err
+		}
+		if response.Error != nil {
+			return fmt.Errorf("plugin failed: %s", response.GetError())
+		}
+		written, err := writeGeneratedFiles(generateOut, response)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Generated %d file(s) for %s/%s@%s in %s\n", len(written), namespace, moduleName, version, generateOut)
+		for _, name := range written {
+			log.Debug("Wrote generated file", zap.String("file", name))
+		}
+		return nil
+	},
+}
+
+// parsePluginRef parses a --plugin value: registry://<name>[:<version>] (the version defaults to
+// "latest"), or anything else as a local executable.
+func parsePluginRef(ref string) (name, version string, fromRegistry bool, err error) {
+	if ref == "" {
+		return "", "", false, fmt.Errorf("--plugin is required")
+	}
+	rest, ok := strings.CutPrefix(ref, registryPluginScheme)
+	if !ok {
+		return "", "", false, nil
+	}
+	name, version, ok = strings.Cut(rest, ":")
+	if !ok {
+		version = "latest"
+	}
+	if name == "" || version == "" {
+		return "", "", false, fmt.Errorf("invalid plugin reference %q: expected %s<name>[:<version>]", ref, registryPluginScheme)
+	}
+	return name, version, true, nil
+}
+
+// registryPluginCommand returns the command running a registered plugin: its binary for this platform
+// (see cachedPluginBinary), or its container image.
+func registryPluginCommand(client *http.Client, plugin *apitypes.PluginResponse, log *zap.Logger) ([]string, error) {
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	for _, b := range plugin.Binaries {
+		if b.Platform == platform {
+			executable, err := cachedPluginBinary(client, plugin, b, log)
+			if err != nil {
+				return nil, err
+			}
+			return []string{executable}, nil
+		}
+	}
+	if plugin.Image == "" {
+		return nil, exitErrorf(ExitNotFound, "plugin %s:%s has no binary for %s and no container image", plugin.Name, plugin.Version, platform)
+	}
+	if _, err := exec.LookPath(generateContainerRuntime); err != nil {
+		return nil, exitErrorf(ExitUsage, "plugin %s:%s runs as a container image, but %q was not found: %w", plugin.Name, plugin.Version, generateContainerRuntime, err)
+	}
+	return []string{generateContainerRuntime, "run", "--rm", "-i", plugin.Image}, nil
+}
+
+// cachedPluginBinary returns the path of a plugin binary in the user cache directory, downloading it
+// first if it isn't there (or doesn't match its digest anymore).
+func cachedPluginBinary(client *http.Client, plugin *apitypes.PluginResponse, binary apitypes.PluginBinaryInfo, log *zap.Logger) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	dir := filepath.Join(cacheDir, "protoreg", "plugins", plugin.Name, plugin.Version, strings.ReplaceAll(binary.Platform, "/", "-"))
+	executable := filepath.Join(dir, "protoc-gen-"+plugin.Name)
+	if runtime.GOOS == "windows" {
+		executable += ".exe"
+	}
+	if data, err := os.ReadFile(executable); err == nil && sha256Hex(data) == binary.SHA256 {
+		return executable, nil // Cached
+	}
+
+	log.Info("Downloading plugin binary", zap.String("url", binary.URL))
+	resp, err := client.Get(binary.URL)
+	if err != nil {
+		return "", withExitCode(ExitNetwork, fmt.Errorf("failed to download plugin binary: %w", err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", withExitCode(ExitNetwork, fmt.Errorf("failed to download plugin binary from %s: HTTP %d", binary.URL, resp.StatusCode))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", withExitCode(ExitNetwork, fmt.Errorf("failed to download plugin binary: %w", err))
+	}
+	if digest := sha256Hex(data); digest != binary.SHA256 {
+		return "", exitErrorf(ExitValidation, "plugin binary %s has digest %s, but %s:%s is registered with %s", binary.URL, digest, plugin.Name, plugin.Version, binary.SHA256)
+	}
+
+	// Written to a temporary file and renamed, so an interrupted download never looks cached
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create plugin cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to cache plugin binary: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after the rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to cache plugin binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to cache plugin binary: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return "", fmt.Errorf("failed to cache plugin binary: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), executable); err != nil {
+		return "", fmt.Errorf("failed to cache plugin binary: %w", err)
+	}
+	return executable, nil
+}
+
+// runPlugin runs a plugin command with the request on its standard input, and parses its response.
+func runPlugin(ctx context.Context, command []string, request *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
+	input, err := proto.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode code generator request: %w", err)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, command[0], command[1:]...)
+	c.Stdin = bytes.NewReader(input)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w\n%s", command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	var response pluginpb.CodeGeneratorResponse
+	if err := proto.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("plugin %s returned an invalid response: %w", command[0], err)
+	}
+	return &response, nil
+}
+
+// writeGeneratedFiles writes the files of a plugin response under outDir, returning their names. Names
+// must be relative and stay inside outDir; insertion points (edits to files of another plugin's output)
+// aren't supported.
+func writeGeneratedFiles(outDir string, response *pluginpb.CodeGeneratorResponse) ([]string, error) {
+	written := make([]string, 0, len(response.GetFile()))
+	for _, f := range response.GetFile() {
+		name := f.GetName()
+		if f.GetInsertionPoint() != "" {
+			return written, fmt.Errorf("generated file %s uses insertion point %q, which isn't supported", name, f.GetInsertionPoint())
+		}
+		clean := path.Clean(name)
+		if name == "" || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+			return written, fmt.Errorf("plugin returned an invalid file name %q", name)
+		}
+		target := filepath.Join(outDir, filepath.FromSlash(clean))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, fmt.Errorf("failed to create directory for %s: %w", name, err)
+		}
+		if err := os.WriteFile(target, []byte(f.GetContent()), 0644); err != nil {
+			return written, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		written = append(written, clean)
+	}
+	return written, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+
+	generateCmd.Flags().StringVar(&generatePlugin, "plugin", "", "Plugin to run: registry://<name>[:<version>] or a local plugin executable (required)")
+	_ = generateCmd.MarkFlagRequired("plugin")
+	generateCmd.Flags().StringArrayVar(&generateOpts, "opt", nil, "Plugin option, e.g. paths=source_relative (repeatable)")
+	generateCmd.Flags().StringVarP(&generateOut, "out", "o", "", "Directory to write the generated files to (required)")
+	_ = generateCmd.MarkFlagRequired("out")
+	generateCmd.Flags().StringVar(&generateContainerRuntime, "container-runtime", "docker", "Container runtime for plugins registered as images (docker, podman, ...)")
+}
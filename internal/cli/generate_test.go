@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestParsePluginRef(t *testing.T) {
+	name, version, fromRegistry, err := parsePluginRef("registry://go:v1.34")
+	require.NoError(t, err)
+	assert.True(t, fromRegistry)
+	assert.Equal(t, "go", name)
+	assert.Equal(t, "v1.34", version)
+
+	name, version, fromRegistry, err = parsePluginRef("registry://grpc-go")
+	require.NoError(t, err)
+	assert.True(t, fromRegistry)
+	assert.Equal(t, "grpc-go", name)
+	assert.Equal(t, "latest", version)
+
+	_, _, fromRegistry, err = parsePluginRef("./bin/protoc-gen-go")
+	require.NoError(t, err)
+	assert.False(t, fromRegistry)
+
+	for _, ref := range []string{"", "registry://", "registry://go:", "registry://:v1"} {
+		_, _, _, err := parsePluginRef(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestParsePluginBinary(t *testing.T) {
+	binary, err := parsePluginBinary("linux/amd64=https://example.com/dl?file=a#b#abc123")
+	require.NoError(t, err)
+	assert.Equal(t, apitypes.PluginBinaryInfo{Platform: "linux/amd64", URL: "https://example.com/dl?file=a#b", SHA256: "abc123"}, binary)
+
+	_, err = parsePluginBinary("https://example.com/protoc-gen-go#abc123")
+	assert.Error(t, err)
+	_, err = parsePluginBinary("linux/amd64=https://example.com/protoc-gen-go")
+	assert.Error(t, err)
+}
+
+func TestWriteGeneratedFiles(t *testing.T) {
+	out := t.TempDir()
+	written, err := writeGeneratedFiles(out, &pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{
+		{Name: proto.String("acme/books/v1/books.pb.go"), Content: proto.String("package booksv1\n")},
+		{Name: proto.String("./README.md"), Content: proto.String("generated\n")},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme/books/v1/books.pb.go", "README.md"}, written)
+	data, err := os.ReadFile(filepath.Join(out, "acme", "books", "v1", "books.pb.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package booksv1\n", string(data))
+
+	// Names escaping the output directory, and insertion points, are rejected
+	for _, f := range []*pluginpb.CodeGeneratorResponse_File{
+		{Name: proto.String("../escape.go")},
+		{Name: proto.String("a/../../escape.go")},
+		{Name: proto.String("/etc/passwd")},
+		{Name: proto.String("")},
+		{Name: proto.String("books.pb.go"), InsertionPoint: proto.String("imports")},
+	} {
+		_, err := writeGeneratedFiles(out, &pluginpb.CodeGeneratorResponse{File: []*pluginpb.CodeGeneratorResponse_File{f}})
+		assert.Error(t, err, f.GetName())
+	}
+	_, err = os.Stat(filepath.Join(filepath.Dir(out), "escape.go"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCachedPluginBinary(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir) // os.UserCacheDir on Linux
+	t.Setenv("HOME", cacheDir)           // ... and on macOS
+	t.Setenv("LocalAppData", cacheDir)   // ... and on Windows
+
+	content := []byte("#!/bin/sh\n")
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		_, _ = w.Write(content)
+	}))
+	defer srv.Close()
+
+	plugin := &apitypes.PluginResponse{Name: "go", Version: "v1.34.2"}
+	binary := apitypes.PluginBinaryInfo{Platform: runtime.GOOS + "/" + runtime.GOARCH, URL: srv.URL + "/protoc-gen-go", SHA256: sha256Hex(content)}
+	executable, err := cachedPluginBinary(srv.Client(), plugin, binary, zap.NewNop())
+	require.NoError(t, err)
+	data, err := os.ReadFile(executable)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	// Downloaded once
+	again, err := cachedPluginBinary(srv.Client(), plugin, binary, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, executable, again)
+	assert.Equal(t, 1, downloads)
+
+	// A binary that doesn't match the registered digest is refused
+	binary.SHA256 = sha256Hex([]byte("something else"))
+	plugin.Version = "v1.35.0"
+	_, err = cachedPluginBinary(srv.Client(), plugin, binary, zap.NewNop())
+	assert.Error(t, err)
+	assert.Equal(t, ExitValidation, exitCode(err))
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	impactModuleName string
+	impactVersion    string
+)
+
+// impactCmd represents the impact command
+var impactCmd = &cobra.Command{
+	Use:   "impact <directory|->",
+	Short: "Report which dependent modules a proposed change would break",
+	Long: `Zips the specified directory (or reads a zip from stdin with "-") and asks the
+registry to compare it with the newest published version of the module. The
+registry lists the breaking changes and, for every module depending on this one,
+whether it would break: it no longer compiles ("broken"), or it uses a type with
+a breaking change ("affected").
+
+With --version, dependents whose sproto.yaml constraint excludes that version
+are reported as "excluded" (they won't pick the change up).
+
+Nothing is published. Authentication via API token is required.
+
+Exit codes:
+  0  no dependent is broken or affected
+  1  at least one dependent is broken or affected
+  2  the analysis failed (invalid arguments, registry unreachable, server error)
+
+Example:
+  protoreg-cli impact ./protos --module mycompany/user --version v1.3.0`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, err := requestImpact(args[0], GetLogger())
+		if err != nil {
+			return withExitCode(2, err) // impact reports every failure with 2, see above
+		}
+		printImpactReport(report)
+		if report.Breaking {
+			return exitStatus(1)
+		}
+		return nil
+	},
+}
+
+// requestImpact builds the artifact of dir ("-": a zip from stdin) and asks the registry for its impact report.
+func requestImpact(dir string, log *zap.Logger) (*descriptor.ImpactReport, error) {
+	registryURL, err := requireRegistryURL()
+	if err != nil {
+		return nil, err
+	}
+	apiToken, err := requireAPIToken()
+	if err != nil {
+		return nil, err
+	}
+
+	impactModuleName = qualifyModule(impactModuleName)
+	parts := strings.SplitN(impactModuleName, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("--module is required in the format 'namespace/module_name' (got %q)", impactModuleName)
+	}
+	versionStr := ""
+	if impactVersion != "" {
+		semVer, err := semver.NewVersion(impactVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid semantic version format for --version flag %q: %w", impactVersion, err)
+		}
+		versionStr = "v" + semVer.String()
+	}
+
+	// --- Build Artifact ---
+	var zipBuffer *bytes.Buffer
+	if dir == "-" {
+		zipBuffer, err = readZipFromStdin(os.Stdin)
+	} else {
+		zipBuffer, err = zipDirectory(dir, log)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to build artifact: %w", err)
+	}
+
+	// --- Request Analysis ---
+	targetURL := strings.TrimSuffix(registryURL, "/") + "/api/v1/impact"
+	req, err := newImpactRequest(targetURL, parts[0], parts[1], versionStr, zipBuffer.Bytes(), apiToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, registryError(resp.StatusCode, respBodyBytes)
+	}
+
+	var report descriptor.ImpactReport
+	if err := json.Unmarshal(respBodyBytes, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse impact report: %w", err)
+	}
+	return &report, nil
+}
+
+// newImpactRequest builds the multipart POST request carrying the proposed artifact.
+func newImpactRequest(targetURL, namespace, moduleName, versionStr string, zipData []byte, apiToken string) (*http.Request, error) {
+	body := &bytes.Buffer{}
+	multipartWriter := multipart.NewWriter(body)
+	for field, value := range map[string]string{"namespace": namespace, "module_name": moduleName, "version": versionStr} {
+		if err := multipartWriter.WriteField(field, value); err != nil {
+			return nil, fmt.Errorf("failed to write form field %s: %w", field, err)
+		}
+	}
+	part, err := multipartWriter.CreateFormFile("artifact", "proposal.zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file part: %w", err)
+	}
+	if _, err := part.Write(zipData); err != nil {
+		return nil, fmt.Errorf("failed to write zip data to multipart form: %w", err)
+	}
+	if err := multipartWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", targetURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiToken)
+	req.Header.Set("Content-Type", multipartWriter.FormDataContentType())
+	return req, nil
+}
+
+// printImpactReport prints the breaking changes and the status of each dependent.
+func printImpactReport(report *descriptor.ImpactReport) {
+	fmt.Printf("Impact of proposed %s/%s", report.Namespace, report.ModuleName)
+	if report.ProposedVersion != "" {
+		fmt.Printf("@%s", report.ProposedVersion)
+	}
+	fmt.Printf(" (compared with %s)\n", report.BaseVersion)
+
+	fmt.Printf("Breaking changes (%d):\n", len(report.Changes))
+	for _, c := range report.Changes {
+		fmt.Printf("  %s\n", c.Message)
+	}
+
+	fmt.Printf("Dependents (%d):\n", len(report.Dependents))
+	for _, d := range report.Dependents {
+		fmt.Printf("  %s/%s@%s (%s): %s\n", d.Namespace, d.ModuleName, d.Version, d.Constraint, d.Status)
+		if d.Error != "" {
+			fmt.Printf("    %s\n", d.Error)
+		}
+		for _, c := range d.Changes {
+			fmt.Printf("    %s\n", c.Message)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(impactCmd)
+
+	impactCmd.Flags().StringVarP(&impactModuleName, "module", "m", "", "Full module name (namespace/name) (required)")
+	impactCmd.Flags().StringVarP(&impactVersion, "version", "v", "", "Version the change would be published as (optional)")
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/bufimport"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	importBufModule string
+	importBufToken  string
+	importBufDryRun bool
+)
+
+// importBufCmd represents the import-buf command
+var importBufCmd = &cobra.Command{
+	Use:   "import-buf <remote/owner/module>",
+	Short: "Import the versions of a module from a buf registry",
+	Long: `Imports a module from a buf registry (buf.build or a self-hosted BSR): every label that is a
+semantic version (v1.2.0 or 1.2.0) becomes a version of the SProto module, with the files of
+the labeled commit and a generated sproto.yaml. Versions are published oldest first, so the
+registry orders them like the buf module, and each gets a note recording the buf commit it
+came from. Other labels (e.g. "main") are skipped.
+
+Versions that already exist are left untouched, so an interrupted import can simply be run
+again. Dependencies of the buf module are not imported; import them separately.
+
+The target module defaults to <owner>/<module>. Private buf modules need a buf API token
+(--buf-token or the BUF_TOKEN env var). Requires an API token for the registry.
+
+Examples:
+  protoreg-cli import-buf buf.build/acme/petapis --dry-run
+  protoreg-cli import-buf buf.build/acme/petapis --module mycompany/petapis`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		apiToken := ""
+		if !importBufDryRun {
+			if apiToken, err = requireAPIToken(); err != nil {
+				return err
+			}
+		}
+		ref, err := bufimport.ParseModuleRef(args[0])
+		if err != nil {
+			return withExitCode(ExitUsage, err)
+		}
+
+		target := importBufModule
+		if target == "" {
+			target = ref.Owner + "/" + ref.Module
+		}
+		target = qualifyModule(target)
+		parts := strings.SplitN(target, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return exitErrorf(ExitValidation, "invalid module name format %q: expected 'namespace/module_name'", target)
+		}
+		bufToken := importBufToken
+		if bufToken == "" {
+			bufToken = os.Getenv("BUF_TOKEN")
+		}
+
+		publisher := &httpPublisher{client: http.DefaultClient, registryURL: registryURL, namespace: parts[0], moduleName: parts[1], apiToken: apiToken, author: "import-buf", log: log}
+		result, err := bufimport.Import(cmd.Context(), bufimport.NewClient(ref, bufToken), ref, publisher, bufimport.Options{Module: target, DryRun: importBufDryRun, Log: log})
+		if result != nil {
+			skipped := make([]string, 0, len(result.Skipped))
+			for _, label := range result.Skipped {
+				skipped = append(skipped, label+" (not a semantic version)")
+			}
+			printImportResult(ref.String(), target, result.Imported, result.Existing, skipped, importBufDryRun)
+		}
+		if err != nil {
+			var bufErr *bufimport.Error
+			if errors.As(err, &bufErr) {
+				return withExitCode(statusExitCode(bufErr.StatusCode), err)
+			}
+			return err
+		}
+		return nil
+	},
+}
+
+// httpPublisher publishes imported versions through the registry's API.
+type httpPublisher struct {
+	client                *http.Client
+	registryURL           string
+	namespace, moduleName string
+	apiToken              string
+	author                string // Author of the provenance notes
+	log                   *zap.Logger
+}
+
+func (p *httpPublisher) versionURL(version string) string {
+	return fmt.Sprintf("%s/api/v1/modules/%s/%s/%s", strings.TrimSuffix(p.registryURL, "/"), url.PathEscape(p.namespace), url.PathEscape(p.moduleName), url.PathEscape(version))
+}
+
+func (p *httpPublisher) Publish(ctx context.Context, version string, artifact []byte) (bool, error) {
+	req, err := newArtifactUploadRequest(p.versionURL(version), version, artifact, p.apiToken)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("failed to read response body: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return true, nil
+	case http.StatusConflict:
+		return false, nil // Imported before
+	default:
+		printErrorDetails(bodyBytes)
+		return false, registryError(resp.StatusCode, bodyBytes)
+	}
+}
+
+func (p *httpPublisher) AddNote(ctx context.Context, version, note string) error {
+	return addVersionNote(p.client, p.versionURL(version)+"/notes", note, p.author, p.apiToken, p.log)
+}
+
+// printImportResult prints what an import from source did (or, for a dry run, would do). Skipped
+// entries name the label or tag and why it was skipped.
+func printImportResult(source, target string, imported, existing, skipped []string, dryRun bool) {
+	verb := "Imported"
+	if dryRun {
+		verb = "Would import"
+	}
+	if len(imported) == 0 {
+		fmt.Printf("No new versions of %s to import into %s\n", source, target)
+	} else {
+		fmt.Printf("%s %d version(s) of %s into %s: %s\n", verb, len(imported), source, target, strings.Join(imported, ", "))
+	}
+	if len(existing) > 0 {
+		fmt.Printf("Already in the registry: %s\n", strings.Join(existing, ", "))
+	}
+	if len(skipped) > 0 {
+		fmt.Printf("Skipped: %s\n", strings.Join(skipped, ", "))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(importBufCmd)
+	importBufCmd.Flags().StringVar(&importBufModule, "module", "", "Target module (namespace/module_name); defaults to <owner>/<module> of the buf module")
+	importBufCmd.Flags().StringVar(&importBufToken, "buf-token", "", "Buf API token for private modules (default: the BUF_TOKEN env var)")
+	importBufCmd.Flags().BoolVar(&importBufDryRun, "dry-run", false, "Only list the versions that would be imported")
+}
@@ -0,0 +1,319 @@
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	importGitModule     string
+	importGitTagPattern string
+	importGitProtoDir   string
+	importGitDryRun     bool
+)
+
+// importGitCmd represents the import-git command
+var importGitCmd = &cobra.Command{
+	Use:   "import-git [repo-dir]",
+	Short: "Import the tagged versions of a module from a git repository",
+	Long: `Imports the history of a module from a git repository (default: the current directory): every
+tag matching --tags whose name ends in a semantic version (v1.2.0, 1.2.0, or api/v1.2.0 for
+prefixed tags) becomes a version of the module, with the contents of --proto-dir at that tag.
+Versions are published oldest first, so the registry orders them like the tags, and each gets
+a note recording the tag and commit it came from.
+
+A sproto.yaml in the proto directory is kept, with its name and version set to the imported
+module and version (its dependencies are kept as they are); one is generated otherwise. Tags
+whose tree has no proto directory are skipped.
+
+Versions that already exist are left untouched, so an interrupted import can simply be run
+again, and so can an import after new tags were pushed. Requires git and an API token.
+
+Examples:
+  protoreg-cli import-git --module mycompany/orders --proto-dir proto --dry-run
+  protoreg-cli import-git ../orders-api --module mycompany/orders --tags 'orders/v*' --proto-dir proto`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		apiToken := ""
+		if !importGitDryRun {
+			if apiToken, err = requireAPIToken(); err != nil {
+				return err
+			}
+		}
+		if _, err := exec.LookPath("git"); err != nil {
+			return withExitCode(ExitUsage, errors.New("import-git requires git on the PATH"))
+		}
+		repoDir := "."
+		if len(args) == 1 {
+			repoDir = args[0]
+		}
+
+		target := qualifyModule(importGitModule)
+		namespace, moduleName, err := manifest.SplitModule(target)
+		if err != nil {
+			return withExitCode(ExitValidation, err)
+		}
+		protoDir := path.Clean(filepath.ToSlash(importGitProtoDir))
+		if path.IsAbs(protoDir) || protoDir == ".." || strings.HasPrefix(protoDir, "../") {
+			return exitErrorf(ExitUsage, "--proto-dir must be a directory inside the repository, got %q", importGitProtoDir)
+		}
+
+		repo := &gitRepo{dir: repoDir}
+		publisher := &httpPublisher{client: http.DefaultClient, registryURL: registryURL, namespace: namespace, moduleName: moduleName, apiToken: apiToken, author: "import-git", log: log}
+		result, err := importGitTags(cmd.Context(), repo, publisher, gitImportOptions{Module: target, Tags: importGitTagPattern, ProtoDir: protoDir, DryRun: importGitDryRun, Log: log})
+		if result != nil {
+			printImportResult(repo.describe(), target, result.imported, result.existing, result.skipped, importGitDryRun)
+		}
+		return err
+	},
+}
+
+// --- Git Access ---
+
+// gitRepo runs git commands in a local repository.
+type gitRepo struct {
+	dir string
+}
+
+// run runs git with args in the repository and returns its standard output.
+func (g *gitRepo) run(ctx context.Context, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, "git", append([]string{"-C", g.dir}, args...)...)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("git %s failed: %w\n%s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// describe names the repository in output and notes: its origin URL if it has one, else its directory.
+func (g *gitRepo) describe() string {
+	if out, err := g.run(context.Background(), "remote", "get-url", "origin"); err == nil && len(bytes.TrimSpace(out)) > 0 {
+		return string(bytes.TrimSpace(out))
+	}
+	if abs, err := filepath.Abs(g.dir); err == nil {
+		return abs
+	}
+	return g.dir
+}
+
+// tags lists the tags matching a glob pattern (as in git tag --list).
+func (g *gitRepo) tags(ctx context.Context, pattern string) ([]string, error) {
+	out, err := g.run(ctx, "tag", "--list", pattern)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// commit returns the commit a tag points to and its commit date (ISO 8601).
+func (g *gitRepo) commit(ctx context.Context, tag string) (sha, date string, err error) {
+	out, err := g.run(ctx, "log", "-1", "--format=%H %cI", tag+"^{commit}", "--")
+	if err != nil {
+		return "", "", err
+	}
+	sha, date, _ = strings.Cut(strings.TrimSpace(string(out)), " ")
+	return sha, date, nil
+}
+
+// files returns the regular files under dir at a tag, keyed by their path relative to dir. It returns
+// nil if dir doesn't exist at the tag.
+func (g *gitRepo) files(ctx context.Context, tag, dir string) (map[string][]byte, error) {
+	treeish := tag + "^{tree}"
+	if dir != "." {
+		treeish = tag + ":" + dir
+	}
+	if _, err := g.run(ctx, "cat-file", "-e", treeish); err != nil {
+		return nil, nil // No such directory at this tag
+	}
+	out, err := g.run(ctx, "archive", "--format=tar", treeish)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(bytes.NewReader(out))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the archive of %s: %w", tag, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue // Directories, symlinks and the pax header of the commit id
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s at %s: %w", header.Name, tag, err)
+		}
+		files[header.Name] = data
+	}
+	return files, nil
+}
+
+// --- Import ---
+
+// gitImportOptions configures importGitTags.
+type gitImportOptions struct {
+	Module   string // Target module (namespace/name)
+	Tags     string // Glob pattern of the tags to import
+	ProtoDir string // Slash-separated directory of the module within the repository ("." for the root)
+	DryRun   bool   // Only report what would be imported
+	Log      *zap.Logger
+}
+
+// gitImportResult lists what importGitTags did with each version or tag.
+type gitImportResult struct {
+	imported []string // Versions published
+	existing []string // Versions that were already in the registry
+	skipped  []string // Tags not imported, with the reason
+}
+
+// gitTagVersion is a tag that names a version.
+type gitTagVersion struct {
+	tag     string
+	version string // Normalized, e.g. "v1.2.0"
+	parsed  *semver.Version
+}
+
+// tagVersions selects the tags whose name (after the last "/", so "api/v1.2.0" works) is a semantic
+// version, sorted oldest (lowest) first. Other tags are returned as skipped, with the reason. If several
+// tags name the same version ("1.2.0" and "v1.2.0"), the first in tag order is kept.
+func tagVersions(tags []string) (versions []gitTagVersion, skipped []string) {
+	sort.Strings(tags)
+	seen := map[string]string{}
+	for _, tag := range tags {
+		name := tag[strings.LastIndex(tag, "/")+1:]
+		v, err := semver.NewVersion(name)
+		if err != nil {
+			skipped = append(skipped, tag+" (not a semantic version)")
+			continue
+		}
+		version := "v" + v.String()
+		if other, ok := seen[version]; ok {
+			skipped = append(skipped, fmt.Sprintf("%s (same version as %s)", tag, other))
+			continue
+		}
+		seen[version] = tag
+		versions = append(versions, gitTagVersion{tag: tag, version: version, parsed: v})
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].parsed.LessThan(versions[j].parsed) })
+	return versions, skipped
+}
+
+// gitArtifact packs the files of the proto directory at a tag into a module artifact, with its
+// sproto.yaml naming the module and version.
+func gitArtifact(files map[string][]byte, module, version string) ([]byte, error) {
+	m := &manifest.Manifest{}
+	if data, ok := files[manifest.ManifestFileName]; ok {
+		parsed, err := manifest.ParseManifest(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", manifest.ManifestFileName, err)
+		}
+		m = parsed
+	}
+	m.Name, m.Version = module, version
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	packed := make(map[string][]byte, len(files)+1)
+	for name, content := range files {
+		packed[name] = content
+	}
+	packed[manifest.ManifestFileName] = data
+	return artifact.Pack(packed)
+}
+
+// importGitTags publishes the versions named by the repository's tags, oldest first, and attaches a
+// provenance note to each. It stops at the first failure; versions that already exist are skipped.
+func importGitTags(ctx context.Context, repo *gitRepo, publisher *httpPublisher, opts gitImportOptions) (*gitImportResult, error) {
+	log := opts.Log
+	if log == nil {
+		log = zap.NewNop()
+	}
+	tags, err := repo.tags(ctx, opts.Tags)
+	if err != nil {
+		return nil, err
+	}
+	versions, skipped := tagVersions(tags)
+	result := &gitImportResult{skipped: skipped}
+	source := repo.describe()
+	log.Info("Listed git tags", zap.String("repository", source), zap.Int("versions", len(versions)), zap.Strings("skipped_tags", skipped))
+
+	for _, v := range versions {
+		files, err := repo.files(ctx, v.tag, opts.ProtoDir)
+		if err != nil {
+			return result, err
+		}
+		if len(files) == 0 {
+			result.skipped = append(result.skipped, fmt.Sprintf("%s (no files in %s)", v.tag, opts.ProtoDir))
+			continue
+		}
+		if opts.DryRun {
+			result.imported = append(result.imported, v.version)
+			continue
+		}
+		sha, date, err := repo.commit(ctx, v.tag)
+		if err != nil {
+			return result, err
+		}
+		zipData, err := gitArtifact(files, opts.Module, v.version)
+		if err != nil {
+			return result, fmt.Errorf("failed to pack tag %s: %w", v.tag, err)
+		}
+		created, err := publisher.Publish(ctx, v.version, zipData)
+		if err != nil {
+			return result, fmt.Errorf("failed to publish %s@%s: %w", opts.Module, v.version, err)
+		}
+		if !created {
+			log.Info("Version already exists, skipping", zap.String("version", v.version))
+			result.existing = append(result.existing, v.version)
+			continue
+		}
+		result.imported = append(result.imported, v.version)
+		log.Info("Imported version", zap.String("version", v.version), zap.String("tag", v.tag), zap.String("commit", sha))
+		note := fmt.Sprintf("Imported from git tag %s of %s (commit %s, committed %s)", v.tag, source, sha, date)
+		if opts.ProtoDir != "." {
+			note += "\nDirectory: " + opts.ProtoDir
+		}
+		if err := publisher.AddNote(ctx, v.version, note); err != nil {
+			// The version is published; only its provenance note is missing
+			log.Warn("Failed to attach import note", zap.String("version", v.version), zap.Error(err))
+		}
+	}
+	return result, nil
+}
+
+func init() {
+	rootCmd.AddCommand(importGitCmd)
+	importGitCmd.Flags().StringVar(&importGitModule, "module", "", "Target module (namespace/module_name)")
+	importGitCmd.Flags().StringVar(&importGitTagPattern, "tags", "v*", "Glob pattern of the tags to import (e.g. 'orders/v*')")
+	importGitCmd.Flags().StringVar(&importGitProtoDir, "proto-dir", ".", "Directory of the module within the repository")
+	importGitCmd.Flags().BoolVar(&importGitDryRun, "dry-run", false, "Only list the versions that would be imported")
+	_ = importGitCmd.MarkFlagRequired("module")
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTagVersions(t *testing.T) {
+	versions, skipped := tagVersions([]string{"v1.10.0", "latest", "api/v1.2.0", "v1.9.0", "1.9.0", "v2.0.0-rc.1"})
+	tags := []string{}
+	for _, v := range versions {
+		tags = append(tags, v.version+"="+v.tag)
+	}
+	assert.Equal(t, []string{"v1.2.0=api/v1.2.0", "v1.9.0=1.9.0", "v1.10.0=v1.10.0", "v2.0.0-rc.1=v2.0.0-rc.1"}, tags)
+	assert.Equal(t, []string{"latest (not a semantic version)", "v1.9.0 (same version as 1.9.0)"}, skipped)
+}
+
+// gitTestRepo creates a repository with a commit per tag; files maps tag -> path -> content.
+func gitTestRepo(t *testing.T, tags []string, files map[string]map[string]string) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		c := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)...)
+		out, err := c.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	for _, tag := range tags {
+		for name, content := range files[tag] {
+			path := filepath.Join(dir, filepath.FromSlash(name))
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		}
+		git("add", "-A")
+		git("commit", "-q", "--allow-empty", "-m", "release "+tag)
+		git("tag", tag)
+	}
+	return dir
+}
+
+func TestImportGitTags(t *testing.T) {
+	dir := gitTestRepo(t, []string{"v1.0.0", "docs-1", "v1.1.0", "v2.0.0"}, map[string]map[string]string{
+		"v1.0.0": {"README.md": "# Orders\n"},
+		"v1.1.0": {"proto/orders/v1/orders.proto": "syntax = \"proto3\";\n", "proto/sproto.yaml": "name: old/orders\ndependencies:\n  acme/user: ^1.0.0\n"},
+		"v2.0.0": {"proto/orders/v1/orders.proto": "syntax = \"proto3\";\nmessage Order {}\n"},
+	})
+
+	var mu sync.Mutex
+	artifacts := map[string][]byte{}
+	notes := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		version := strings.TrimPrefix(r.URL.Path, "/api/v1/modules/acme/orders/")
+		if strings.HasSuffix(version, "/notes") {
+			var req struct{ Note string }
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			notes[strings.TrimSuffix(version, "/notes")] = req.Note
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if _, ok := artifacts[version]; ok {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error":"version already exists"}`))
+			return
+		}
+		file, _, err := r.FormFile("artifact")
+		require.NoError(t, err)
+		artifacts[version], _ = io.ReadAll(file)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	repo := &gitRepo{dir: dir}
+	publisher := &httpPublisher{client: srv.Client(), registryURL: srv.URL, namespace: "acme", moduleName: "orders", apiToken: "t", author: "import-git", log: zap.NewNop()}
+	opts := gitImportOptions{Module: "acme/orders", Tags: "v*", ProtoDir: "proto"}
+
+	// Dry run: nothing is published; v1.0.0 has no proto directory
+	opts.DryRun = true
+	result, err := importGitTags(context.Background(), repo, publisher, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.1.0", "v2.0.0"}, result.imported)
+	assert.Equal(t, []string{"v1.0.0 (no files in proto)"}, result.skipped)
+	assert.Empty(t, artifacts)
+
+	opts.DryRun = false
+	result, err = importGitTags(context.Background(), repo, publisher, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.1.0", "v2.0.0"}, result.imported)
+
+	// The manifest keeps its dependencies but names the imported module and version
+	zr, err := zip.NewReader(bytes.NewReader(artifacts["v1.1.0"]), int64(len(artifacts["v1.1.0"])))
+	require.NoError(t, err)
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(data)
+	}
+	assert.Equal(t, "name: acme/orders\nversion: v1.1.0\ndependencies:\n    acme/user: ^1.0.0\n", contents["sproto.yaml"])
+	assert.Equal(t, "syntax = \"proto3\";\n", contents["orders/v1/orders.proto"])
+	assert.Len(t, contents, 2)
+	assert.Regexp(t, `^Imported from git tag v2\.0\.0 of .+ \(commit [0-9a-f]{40}, committed \d{4}-.+\)\nDirectory: proto$`, notes["v2.0.0"])
+
+	// Re-running skips existing versions
+	result, err = importGitTags(context.Background(), repo, publisher, opts)
+	require.NoError(t, err)
+	assert.Empty(t, result.imported)
+	assert.Equal(t, []string{"v1.1.0", "v2.0.0"}, result.existing)
+}
@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	infoAddNote string
+	infoAuthor  string
+)
+
+// infoCmd represents the info command
+var infoCmd = &cobra.Command{
+	Use:   "info <namespace/module_name> <version>",
+	Short: "Show metadata and notes of a module version",
+	Long: `Shows the metadata of a published module version (digest, size, scan status,
+publish time, deprecation) together with the notes attached to it.
+
+Notes are free-form, append-only release notes that can be attached after a
+version was published. Use --add-note to attach one (requires an API token);
+--author records who wrote it.
+
+Examples:
+  protoreg-cli info mycompany/user v1.2.3
+  protoreg-cli info mycompany/billing v2.0.1 --add-note "contains hotfix for billing rounding" --author alice`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+
+		moduleFullName := qualifyModule(args[0]) // The namespace may be omitted if a default namespace is configured
+		version := args[1]
+		parts := strings.SplitN(moduleFullName, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return exitErrorf(ExitValidation, "invalid module name format %q: expected 'namespace/module_name'", moduleFullName)
+		}
+		if !strings.HasPrefix(version, "v") {
+			return exitErrorf(ExitValidation, "invalid version format %q: must start with 'v'", version)
+		}
+
+		versionURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s", strings.TrimSuffix(registryURL, "/"),
+			url.PathEscape(parts[0]), url.PathEscape(parts[1]), url.PathEscape(version))
+		client := &http.Client{}
+
+		if cmd.Flags().Changed("add-note") {
+			apiToken, err := requireAPIToken() // Adding notes requires the API token
+			if err != nil {
+				return err
+			}
+			if err := addVersionNote(client, versionURL+"/notes", infoAddNote, infoAuthor, apiToken, log); err != nil {
+				return err
+			}
+		}
+
+		return showVersionInfo(client, versionURL, moduleFullName, log)
+	},
+}
+
+// addVersionNote attaches a note to a module version.
+func addVersionNote(client *http.Client, notesURL, note, author, apiToken string, log *zap.Logger) error {
+	body, err := json.Marshal(apitypes.AddVersionNoteRequest{Note: note})
+	if err != nil {
+		return fmt.Errorf("failed to encode note: %w", err)
+	}
+	req, err := http.NewRequest("POST", notesURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiToken)
+	if author != "" {
+		req.Header.Set(apitypes.PublisherHeader, author)
+	}
+	log.Debug("Adding version note", zap.String("url", notesURL))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return registryError(resp.StatusCode, bodyBytes)
+	}
+	log.Info("Note added")
+	return nil
+}
+
+// showVersionInfo prints the metadata and notes of a module version.
+func showVersionInfo(client *http.Client, versionURL, moduleFullName string, log *zap.Logger) error {
+	log.Debug("Requesting module version metadata", zap.String("url", versionURL))
+	req, err := http.NewRequest("GET", versionURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	setReadToken(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return registryError(resp.StatusCode, bodyBytes)
+	}
+
+	var meta apitypes.ModuleVersionResponse
+	if err := json.Unmarshal(bodyBytes, &meta); err != nil {
+		return fmt.Errorf("failed to parse API response: %w", err)
+	}
+
+	fmt.Printf("%s@%s\n", moduleFullName, meta.Version)
+	fmt.Printf("  Digest:      %s\n", meta.ArtifactDigest)
+	if meta.ArtifactSize > 0 {
+		fmt.Printf("  Size:        %d bytes\n", meta.ArtifactSize)
+	} else {
+		fmt.Printf("  Size:        unknown\n")
+	}
+	fmt.Printf("  Scan status: %s\n", meta.ScanStatus)
+	if len(meta.Syntaxes) > 0 {
+		fmt.Printf("  Syntax:      %s\n", strings.Join(meta.Syntaxes, ", "))
+	}
+	fmt.Printf("  Published:   %s\n", meta.CreatedAt.Local().Format(time.RFC3339))
+	if meta.Checksum != nil {
+		signed := "unsigned"
+		if meta.Checksum.Signature != "" {
+			signed = "signed"
+		}
+		fmt.Printf("  Log entry:   %d (%s)\n", meta.Checksum.Index, signed)
+	}
+	for i, source := range meta.Provenance {
+		label := "Promoted:   "
+		if i == 0 {
+			label = "Origin:     "
+		}
+		fmt.Printf("  %s %s (%s@%s, published %s)\n", label, source.Registry, source.Module, source.Version, source.PublishedAt.Local().Format(time.RFC3339))
+	}
+	if meta.NoSchemaChange {
+		fmt.Printf("  Schema:      unchanged from %s (comments/formatting only)\n", meta.NoSchemaChangeFrom)
+	}
+	if meta.Deprecated {
+		if meta.DeprecationMessage != "" {
+			fmt.Printf("  Deprecated:  %s\n", meta.DeprecationMessage)
+		} else {
+			fmt.Printf("  Deprecated:  yes\n")
+		}
+	}
+	if meta.SunsetAt != nil {
+		state := "scheduled"
+		if meta.Sunset {
+			state = "enforced"
+		}
+		fmt.Printf("  Sunset:      %s (%s)\n", meta.SunsetAt.Local().Format(time.RFC3339), state)
+	}
+
+	if len(meta.Notes) == 0 {
+		fmt.Println("  Notes:       none")
+		return nil
+	}
+	fmt.Println("  Notes:")
+	for _, n := range meta.Notes {
+		header := n.CreatedAt.Local().Format(time.RFC3339)
+		if n.Author != "" {
+			header += " by " + n.Author
+		}
+		fmt.Printf("    [%s]\n", header)
+		for _, line := range strings.Split(n.Note, "\n") {
+			fmt.Printf("      %s\n", line)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+	infoCmd.Flags().StringVar(&infoAddNote, "add-note", "", "Attach a note to the version before showing it (requires an API token)")
+	infoCmd.Flags().StringVar(&infoAuthor, "author", "", "Author recorded with --add-note (sent as the X-SProto-Publisher header)")
+}
@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var (
+	initDir     string
+	initVersion string
+	initDeps    []string
+)
+
+// Files created by init besides sproto.yaml and the example .proto.
+const (
+	lintConfigFileName = "buf.yaml"
+	changelogFileName  = "CHANGELOG.md"
+)
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init <namespace/module_name>",
+	Short: "Scaffold a new module directory",
+	Long: `Creates a module directory with the recommended layout, ready to publish:
+
+  sproto.yaml                          module name, version and dependencies
+  buf.yaml                             lint and breaking-change rules for buf
+  CHANGELOG.md                         its section for each version is stored at publish
+  <namespace>/<name>/v1/<name>.proto   example file in package <namespace>.<name>.v1
+
+The directory defaults to the module name and may already exist, but files in it are never
+overwritten. Dependencies are given as module=constraint and can be changed in sproto.yaml later.
+
+Examples:
+  protoreg-cli init mycompany/billing
+  protoreg-cli init mycompany/billing --dir ./protos --dep mycompany/user=^1.2.0`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		namespace, name, err := manifest.SplitModule(qualifyModule(args[0]))
+		if err != nil {
+			return exitErrorf(ExitValidation, "invalid module name format (expected 'namespace/module_name'): %w", err)
+		}
+		// Same naming rules as the registry, so the module can be published as scaffolded
+		if err := validation.ValidateNamespace(namespace); err != nil {
+			return exitErrorf(ExitValidation, "invalid namespace: %w", err)
+		}
+		if err := validation.ValidateModuleName(name); err != nil {
+			return exitErrorf(ExitValidation, "invalid module name: %w", err)
+		}
+		semVer, err := semver.NewVersion(initVersion)
+		if err != nil {
+			return exitErrorf(ExitValidation, "invalid semantic version format for --version flag %q: %w", initVersion, err)
+		}
+		deps, err := parseInitDeps(initDeps)
+		if err != nil {
+			return exitErrorf(ExitValidation, "invalid --dep: %w", err)
+		}
+
+		dir := initDir
+		if dir == "" {
+			dir = name
+		}
+		created, err := scaffoldModule(dir, namespace, name, "v"+semVer.String(), deps)
+		if err != nil {
+			return fmt.Errorf("failed to scaffold module: %w", err)
+		}
+
+		fmt.Printf("Created module %s/%s in %s:\n", namespace, name, dir)
+		for _, f := range created {
+			fmt.Printf("  %s\n", f)
+		}
+		fmt.Println("\nNext steps:")
+		if len(deps) > 0 {
+			fmt.Printf("  protoreg-cli deps update --dir %s\n", dir)
+		}
+		fmt.Printf("  protoreg-cli publish %s --dry-run\n", dir) // Module and version come from sproto.yaml
+		return nil
+	},
+}
+
+// parseInitDeps parses module=constraint dependency flags.
+func parseInitDeps(values []string) (map[string]string, error) {
+	deps := make(map[string]string, len(values))
+	for _, v := range values {
+		module, constraint, _ := strings.Cut(v, "=")
+		module, constraint = strings.TrimSpace(module), strings.TrimSpace(constraint)
+		if _, _, err := manifest.SplitModule(module); err != nil {
+			return nil, err
+		}
+		deps[module] = constraint
+	}
+	// Validate the constraints the same way publish and deps update will
+	if _, err := manifest.ParseManifest(renderManifest("", "", deps)); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// scaffoldModule writes the files of a new module into dir and returns their paths relative to dir.
+// Nothing is written if any of the files already exists.
+func scaffoldModule(dir, namespace, name, version string, deps map[string]string) ([]string, error) {
+	pkg := protoPackage(namespace, name)
+	protoPath := path.Join(strings.ReplaceAll(pkg, ".", "/"), protoIdentifier(name)+".proto")
+	files := map[string][]byte{
+		manifest.ManifestFileName: renderManifest(namespace+"/"+name, version, deps),
+		lintConfigFileName:        []byte(lintConfigTemplate),
+		changelogFileName:         []byte(fmt.Sprintf(changelogTemplate, strings.TrimPrefix(version, "v"))),
+		protoPath:                 []byte(fmt.Sprintf(protoTemplate, namespace, name, pkg, messageName(name))),
+	}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(p))); err == nil {
+			return nil, fmt.Errorf("%s already exists in %s", p, dir)
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	for _, p := range paths {
+		target := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, files[p], 0644); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// renderManifest returns sproto.yaml content. Empty fields are left out.
+func renderManifest(module, version string, deps map[string]string) []byte {
+	var b strings.Builder
+	b.WriteString("# Module metadata and dependencies, see `protoreg-cli deps --help`.\n")
+	if module != "" {
+		fmt.Fprintf(&b, "name: %s\n", module)
+	}
+	if version != "" {
+		fmt.Fprintf(&b, "version: %s\n", version)
+	}
+	if len(deps) == 0 {
+		b.WriteString("dependencies: {}\n# dependencies:\n#   mycompany/user: ^1.2.0\n")
+		return []byte(b.String())
+	}
+	modules := make([]string, 0, len(deps))
+	for m := range deps {
+		modules = append(modules, m)
+	}
+	sort.Strings(modules)
+	b.WriteString("dependencies:\n")
+	for _, m := range modules {
+		fmt.Fprintf(&b, "  %s: %q\n", m, deps[m])
+	}
+	return []byte(b.String())
+}
+
+// protoPackage returns the protobuf package of a module's first API version, e.g. "mycompany.billing.v1".
+func protoPackage(namespace, name string) string {
+	return protoIdentifier(namespace) + "." + protoIdentifier(name) + ".v1"
+}
+
+// protoIdentifier turns a registry name segment (lowercase letters, digits, '.' and '-') into a
+// protobuf identifier.
+func protoIdentifier(segment string) string {
+	id := strings.NewReplacer(".", "_", "-", "_").Replace(segment)
+	if id[0] >= '0' && id[0] <= '9' {
+		id = "_" + id
+	}
+	return id
+}
+
+// messageName returns the example message's name, e.g. "UserProfile" for "user-profile".
+func messageName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '-' || r == '.' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	msg := b.String()
+	if msg[0] >= '0' && msg[0] <= '9' {
+		msg = "M" + msg
+	}
+	return msg
+}
+
+const lintConfigTemplate = `# Lint and breaking-change rules for buf (https://buf.build/docs/configuration/v2/buf-yaml).
+# Run "buf lint" and "buf breaking" from this directory.
+version: v2
+lint:
+  use:
+    - STANDARD
+breaking:
+  use:
+    - FILE
+`
+
+const changelogTemplate = `# Changelog
+
+The section for each version is stored with it when it is published
+(see "protoreg-cli info" and the changelog API).
+
+## [%s]
+
+### Added
+
+- Initial version
+`
+
+const protoTemplate = `// Example file of %s/%s. Keep one package per directory, with a version suffix
+// (v1, v2, ...) so breaking changes can ship as a new package alongside the old one.
+syntax = "proto3";
+
+package %s;
+
+// Replace with the module's messages, enums and services.
+message %s {
+  // Unique identifier.
+  string id = 1;
+}
+`
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().StringVar(&initDir, "dir", "", "Directory to create the module in (default: the module name)")
+	initCmd.Flags().StringVar(&initVersion, "version", "v0.1.0", "Initial version written to sproto.yaml and CHANGELOG.md")
+	initCmd.Flags().StringArrayVar(&initDeps, "dep", nil, "Dependency as module=constraint, e.g. mycompany/user=^1.2.0 (repeatable)")
+}
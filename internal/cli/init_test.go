@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/changelog"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/bufbuild/protocompile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScaffoldModule(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "user-profile")
+	deps, err := parseInitDeps([]string{"mycompany/user=^1.2.0", "mycompany/common"})
+	require.NoError(t, err)
+
+	created, err := scaffoldModule(dir, "my-company", "user-profile", "v0.1.0", deps)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CHANGELOG.md", "buf.yaml", "my_company/user_profile/v1/user_profile.proto", "sproto.yaml"}, created)
+
+	// The manifest is valid and complete
+	m, err := manifest.LoadManifest(filepath.Join(dir, manifest.ManifestFileName))
+	require.NoError(t, err)
+	assert.Equal(t, "my-company/user-profile", m.Name)
+	assert.Equal(t, "v0.1.0", m.Version)
+	assert.Equal(t, map[string]string{"mycompany/user": "^1.2.0", "mycompany/common": ""}, m.Dependencies)
+
+	// The example file compiles, in the package matching its directory
+	compiler := protocompile.Compiler{Resolver: &protocompile.SourceResolver{ImportPaths: []string{dir}}}
+	files, err := compiler.Compile(context.Background(), "my_company/user_profile/v1/user_profile.proto")
+	require.NoError(t, err)
+	assert.Equal(t, "my_company.user_profile.v1", string(files[0].Package()))
+	assert.NotNil(t, files[0].Messages().ByName("UserProfile"))
+
+	// The changelog has a section for the initial version
+	content, err := os.ReadFile(filepath.Join(dir, "CHANGELOG.md"))
+	require.NoError(t, err)
+	assert.Contains(t, changelog.Section(string(content), "v0.1.0"), "Initial version")
+
+	// Existing files are never overwritten
+	_, err = scaffoldModule(dir, "my-company", "user-profile", "v0.2.0", nil)
+	assert.ErrorContains(t, err, "already exists")
+	m, err = manifest.LoadManifest(filepath.Join(dir, manifest.ManifestFileName))
+	require.NoError(t, err)
+	assert.Equal(t, "v0.1.0", m.Version)
+}
+
+func TestParseInitDeps_Invalid(t *testing.T) {
+	_, err := parseInitDeps([]string{"not-a-module=^1.0.0"})
+	assert.Error(t, err)
+	_, err = parseInitDeps([]string{"mycompany/user=not a constraint"})
+	assert.Error(t, err)
+}
+
+func TestProtoNames(t *testing.T) {
+	assert.Equal(t, "acme.billing.v1", protoPackage("acme", "billing"))
+	assert.Equal(t, "acme_io._3d.v1", protoPackage("acme.io", "3d"))
+	assert.Equal(t, "UserProfileV2", messageName("user-profile.v2"))
+	assert.Equal(t, "M3d", messageName("3d"))
+}
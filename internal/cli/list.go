@@ -2,20 +2,31 @@ package cli
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
+var (
+	listSyntax       string
+	listNamespace    string
+	listUpdatedAfter string
+	listSort         string
+	listHasVersions  bool
+)
+
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use:   "list [namespace/module_name]",
@@ -23,159 +34,280 @@ var listCmd = &cobra.Command{
 	Long: `Lists all available modules in the registry or lists the available versions
 for a specific module.
 
+A module directory can be given instead of a module name; the name is then read from
+its sproto.yaml. The namespace can be omitted if a default namespace is configured
+('protoreg-cli configure').
+
+With --syntax, only modules whose latest version (or only versions) whose .proto files declare
+the syntax or edition are listed: proto2, proto3 or edition-<year> (e.g. edition-2023).
+
+The module list can be filtered by --namespace, --updated-after (modules created or published
+to since a date or RFC3339 timestamp) and --has-versions, and sorted by name (default),
+updated_at (most recent first) or downloads (most downloaded first) with --sort. The registry
+applies them, so only the matching modules are transferred.
+
 Examples:
   protoreg-cli list                  # List all modules
-  protoreg-cli list mycompany/user   # List versions for mycompany/user`,
+  protoreg-cli list mycompany/user   # List versions for mycompany/user
+  protoreg-cli list .                # List versions for the module in ./sproto.yaml
+  protoreg-cli list --syntax edition-2023
+  protoreg-cli list --namespace mycompany --has-versions --sort updated_at
+  protoreg-cli list --updated-after 2026-01-01 --sort downloads`,
 	Args: cobra.MaximumNArgs(1), // 0 or 1 argument
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		log := GetLogger()
-		registryURL := viper.GetString("registry_url")
-		if registryURL == "" {
-			log.Fatal("Registry URL is not configured. Use --registry-url flag, PROTOREG_REGISTRY_URL env var, or 'protoreg-cli configure'.")
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
 		}
 
 		client := &http.Client{} // Use default HTTP client
+		query := url.Values{}
+		if listSyntax != "" {
+			if err := descriptor.ValidateSyntax(listSyntax); err != nil {
+				return exitErrorf(ExitUsage, "invalid --syntax: %w", err)
+			}
+			query.Set("syntax", listSyntax)
+		}
+		moduleQuery, err := moduleListFilters(cmd)
+		if err != nil {
+			return err
+		}
 
 		if len(args) == 0 {
-			// List all modules
-			listAllModules(client, registryURL, log)
-		} else {
-			// List versions for a specific module
-			moduleFullName := args[0]
-			parts := strings.SplitN(moduleFullName, "/", 2)
-			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-				log.Fatal("Invalid module name format. Expected 'namespace/module_name'.", zap.String("module", moduleFullName))
+			for key := range moduleQuery {
+				query[key] = moduleQuery[key]
 			}
-			namespace := parts[0]
-			moduleName := parts[1]
-			listModuleVersions(client, registryURL, namespace, moduleName, log)
+			// List all modules
+			return listAllModules(client, registryURL, query, log)
 		}
+		// List versions for a specific module
+		if len(moduleQuery) > 0 {
+			return exitErrorf(ExitUsage, "--namespace, --updated-after, --has-versions and --sort only apply to the module list")
+		}
+		namespace, moduleName, _, err := resolveModuleArg(args[0], "")
+		if err != nil {
+			return withExitCode(ExitValidation, fmt.Errorf("invalid module: %w", err))
+		}
+		return listModuleVersions(client, registryURL, namespace, moduleName, query, log)
 	},
 }
 
+// moduleListFilters returns the query parameters of the module list filter and sort flags that were set.
+func moduleListFilters(cmd *cobra.Command) (url.Values, error) {
+	query := url.Values{}
+	if listNamespace != "" {
+		query.Set("namespace", listNamespace)
+	}
+	if listUpdatedAfter != "" {
+		query.Set("updated_after", listUpdatedAfter)
+	}
+	if cmd.Flags().Changed("has-versions") {
+		query.Set("has_versions", strconv.FormatBool(listHasVersions))
+	}
+	if listSort != "" {
+		switch listSort {
+		case apitypes.ModuleSortName, apitypes.ModuleSortUpdatedAt, apitypes.ModuleSortDownloads:
+		default:
+			return nil, exitErrorf(ExitUsage, "invalid --sort %q: expected %s, %s or %s", listSort, apitypes.ModuleSortName, apitypes.ModuleSortUpdatedAt, apitypes.ModuleSortDownloads)
+		}
+		query.Set("sort", listSort)
+	}
+	return query, nil
+}
+
 // Response structures matching the server API
 type listModulesApiResponse struct {
 	Modules []struct {
-		Namespace     string `json:"namespace"`
-		Name          string `json:"name"`
-		LatestVersion string `json:"latest_version"`
+		Namespace     string   `json:"namespace"`
+		Name          string   `json:"name"`
+		LatestVersion string   `json:"latest_version"`
+		Syntaxes      []string `json:"syntaxes"`
+		Downloads     *int64   `json:"downloads"`
 	} `json:"modules"`
 }
 
 type listModuleVersionsApiResponse struct {
-	Namespace  string   `json:"namespace"`
-	ModuleName string   `json:"module_name"`
-	Versions   []string `json:"versions"`
+	Namespace  string              `json:"namespace"`
+	ModuleName string              `json:"module_name"`
+	Versions   []string            `json:"versions"`
+	Syntaxes   map[string][]string `json:"syntaxes"`
 }
 
 type apiErrorResponse struct {
 	Error string `json:"error"`
 }
 
-func listAllModules(client *http.Client, registryURL string, log *zap.Logger) {
+func listAllModules(client *http.Client, registryURL string, query url.Values, log *zap.Logger) error {
 	targetURL := fmt.Sprintf("%s/api/v1/modules", strings.TrimSuffix(registryURL, "/"))
+	if len(query) > 0 {
+		targetURL += "?" + query.Encode()
+	}
 	log.Debug("Requesting module list", zap.String("url", targetURL))
 
 	req, err := http.NewRequest("GET", targetURL, nil)
 	if err != nil {
-		log.Fatal("Failed to create request", zap.Error(err))
+		return fmt.Errorf("failed to create request: %w", err)
 	}
+	setReadToken(req)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Fatal("Failed to execute request", zap.Error(err))
+		return fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatal("Failed to read response body", zap.Error(err))
+		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		handleApiError(resp.StatusCode, bodyBytes, log)
-		os.Exit(1)
+		return registryError(resp.StatusCode, bodyBytes)
 	}
 
 	var apiResp listModulesApiResponse
 	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
-		log.Fatal("Failed to parse API response", zap.Error(err), zap.ByteString("body", bodyBytes))
+		return fmt.Errorf("failed to parse API response: %w", err)
 	}
 
 	if len(apiResp.Modules) == 0 {
+		if query.Has("syntax") {
+			fmt.Printf("No modules found whose latest version declares %s.\n", query.Get("syntax"))
+			return nil
+		}
+		if len(query) > 0 {
+			fmt.Println("No modules found matching the filters.")
+			return nil
+		}
 		fmt.Println("No modules found in the registry.")
-		return
+		return nil
 	}
 
 	fmt.Println("Available Modules:")
 	for _, mod := range apiResp.Modules {
-		if mod.LatestVersion != "" {
-			fmt.Printf("  %s/%s (latest: %s)\n", mod.Namespace, mod.Name, mod.LatestVersion)
+		downloads := ""
+		if mod.Downloads != nil {
+			downloads = fmt.Sprintf(", %d downloads", *mod.Downloads)
+		}
+		if mod.LatestVersion != "" && len(mod.Syntaxes) > 0 {
+			fmt.Printf("  %s/%s (latest: %s, %s%s)\n", mod.Namespace, mod.Name, mod.LatestVersion, strings.Join(mod.Syntaxes, ", "), downloads)
+		} else if mod.LatestVersion != "" {
+			fmt.Printf("  %s/%s (latest: %s%s)\n", mod.Namespace, mod.Name, mod.LatestVersion, downloads)
 		} else {
-			fmt.Printf("  %s/%s (no versions published)\n", mod.Namespace, mod.Name)
+			fmt.Printf("  %s/%s (no versions published%s)\n", mod.Namespace, mod.Name, downloads)
 		}
 	}
+	return nil
 }
 
-func listModuleVersions(client *http.Client, registryURL, namespace, moduleName string, log *zap.Logger) {
+func listModuleVersions(client *http.Client, registryURL, namespace, moduleName string, query url.Values, log *zap.Logger) error {
+	apiResp, err := queryModuleVersions(client, registryURL, namespace, moduleName, query, log)
+	if err != nil {
+		return fmt.Errorf("failed to list module versions: %w", err)
+	}
+	versions := apiResp.Versions
+
+	if len(versions) == 0 {
+		if query.Has("syntax") {
+			fmt.Printf("No versions of module %s/%s declare %s.\n", namespace, moduleName, query.Get("syntax"))
+			return nil
+		}
+		fmt.Printf("No versions found for module %s/%s.\n", namespace, moduleName)
+		return nil
+	}
+
+	// Sort versions semantically descending (best effort)
+	sortVersionsDescCli(versions)
+
+	fmt.Printf("Versions for %s/%s:\n", namespace, moduleName)
+	for _, v := range versions {
+		if syntaxes := apiResp.Syntaxes[v]; len(syntaxes) > 0 {
+			fmt.Printf("  %s (%s)\n", v, strings.Join(syntaxes, ", "))
+		} else {
+			fmt.Printf("  %s\n", v)
+		}
+	}
+	return nil
+}
+
+// errModuleNotFound is returned by getModuleVersions when the registry doesn't know the module.
+var errModuleNotFound = errors.New("module not found")
+
+// getModuleVersions requests the published versions of a module.
+// A 404 is returned as errModuleNotFound, other API errors as registryError.
+func getModuleVersions(client *http.Client, registryURL, namespace, moduleName string, log *zap.Logger) ([]string, error) {
+	apiResp, err := queryModuleVersions(client, registryURL, namespace, moduleName, nil, log)
+	if err != nil {
+		return nil, err
+	}
+	return apiResp.Versions, nil
+}
+
+// queryModuleVersions requests the version listing of a module, with optional query parameters (?syntax=).
+// Errors are reported as by getModuleVersions.
+func queryModuleVersions(client *http.Client, registryURL, namespace, moduleName string, query url.Values, log *zap.Logger) (*listModuleVersionsApiResponse, error) {
 	// URL encode path segments
 	encodedNamespace := url.PathEscape(namespace)
 	encodedModuleName := url.PathEscape(moduleName)
 	targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s", strings.TrimSuffix(registryURL, "/"), encodedNamespace, encodedModuleName)
+	if len(query) > 0 {
+		targetURL += "?" + query.Encode()
+	}
 	log.Debug("Requesting module versions", zap.String("url", targetURL))
 
 	req, err := http.NewRequest("GET", targetURL, nil)
 	if err != nil {
-		log.Fatal("Failed to create request", zap.Error(err))
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	setReadToken(req)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Fatal("Failed to execute request", zap.Error(err))
+		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatal("Failed to read response body", zap.Error(err))
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		handleApiError(resp.StatusCode, bodyBytes, log)
-		os.Exit(1)
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%s/%s: %w", namespace, moduleName, errModuleNotFound)
+		}
+		return nil, registryError(resp.StatusCode, bodyBytes)
 	}
 
 	var apiResp listModuleVersionsApiResponse
 	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
-		log.Fatal("Failed to parse API response", zap.Error(err), zap.ByteString("body", bodyBytes))
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
 	}
+	return &apiResp, nil
+}
 
-	if len(apiResp.Versions) == 0 {
-		fmt.Printf("No versions found for module %s/%s.\n", namespace, moduleName)
-		return
+// setReadToken authenticates a read request with the configured read token, or else the API token,
+// so that internal and private modules are visible (and registries with anonymous read disabled can
+// be used). Anonymous requests only see public modules.
+func setReadToken(req *http.Request) {
+	token := viper.GetString("read_token")
+	if token == "" {
+		token = viper.GetString("api_token")
 	}
-
-	// Sort versions semantically descending (best effort)
-	sortVersionsDescCli(apiResp.Versions)
-
-	fmt.Printf("Versions for %s/%s:\n", namespace, moduleName)
-	for _, v := range apiResp.Versions {
-		fmt.Printf("  %s\n", v)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
 	}
 }
 
-// handleApiError attempts to parse and log an API error response.
-func handleApiError(statusCode int, body []byte, log *zap.Logger) {
+// registryError returns the error of an API error response, carrying the exit code of its status.
+func registryError(statusCode int, body []byte) error {
 	var errResp apiErrorResponse
-	logFields := []zap.Field{zap.Int("status_code", statusCode)}
 	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error != "" {
-		logFields = append(logFields, zap.String("api_error", errResp.Error))
-		log.Error("API request failed", logFields...)
-	} else {
-		// Log raw body if JSON parsing fails or error field is empty
-		logFields = append(logFields, zap.ByteString("response_body", body))
-		log.Error("API request failed", logFields...)
+		return exitErrorf(statusExitCode(statusCode), "registry returned status %d: %s", statusCode, errResp.Error)
 	}
+	// Include the raw body if JSON parsing fails or the error field is empty
+	return exitErrorf(statusExitCode(statusCode), "registry returned status %d: %s", statusCode, strings.TrimSpace(string(body)))
 }
 
 // sortVersionsDescCli sorts a slice of version strings semantically descending.
@@ -216,4 +348,10 @@ func sortVersionsDescCli(versions []string) {
 
 func init() {
 	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringVar(&listSyntax, "syntax", "", "Only list modules or versions declaring this syntax or edition: proto2, proto3 or edition-<year>")
+	listCmd.Flags().StringVar(&listNamespace, "namespace", "", "Only list modules of this namespace")
+	listCmd.Flags().StringVar(&listUpdatedAfter, "updated-after", "", "Only list modules created or published to since this date (2006-01-02) or RFC3339 timestamp")
+	listCmd.Flags().BoolVar(&listHasVersions, "has-versions", false, "Only list modules with published versions (--has-versions=false: only those without)")
+	listCmd.Flags().StringVar(&listSort, "sort", "", "Sort the module list by name (default), updated_at or downloads")
 }
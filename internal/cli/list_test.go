@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGetModuleVersions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/modules/acme/user":
+			_, _ = w.Write([]byte(`{"namespace":"acme","module_name":"user","versions":["v1.0.0","v1.1.0"]}`))
+		case "/api/v1/modules/acme/private":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":"insufficient permissions"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"module not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	versions, err := getModuleVersions(srv.Client(), srv.URL, "acme", "user", zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, versions)
+
+	_, err = getModuleVersions(srv.Client(), srv.URL, "acme", "missing", zap.NewNop())
+	assert.True(t, errors.Is(err, errModuleNotFound))
+	assert.Equal(t, ExitNotFound, exitCode(err))
+
+	_, err = getModuleVersions(srv.Client(), srv.URL, "acme", "private", zap.NewNop())
+	assert.EqualError(t, err, "registry returned status 403: insufficient permissions")
+	assert.Equal(t, ExitAuth, exitCode(err))
+}
+
+func TestRegistryError(t *testing.T) {
+	err := registryError(http.StatusConflict, []byte(`{"error":"version already exists"}`))
+	assert.EqualError(t, err, "registry returned status 409: version already exists")
+	assert.Equal(t, ExitConflict, exitCode(err))
+
+	// Bodies that aren't JSON errors are included as they are
+	err = registryError(http.StatusBadGateway, []byte("<html>bad gateway</html>\n"))
+	assert.EqualError(t, err, "registry returned status 502: <html>bad gateway</html>")
+	assert.Equal(t, ExitNetwork, exitCode(err))
+}
+
+func TestListAllModulesFilters(t *testing.T) {
+	var gotQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		_, _ = w.Write([]byte(`{"modules":[{"namespace":"acme","name":"user","latest_version":"v1.0.0","downloads":3}]}`))
+	}))
+	defer srv.Close()
+
+	listNamespace, listSort = "acme", "downloads"
+	t.Cleanup(func() { listNamespace, listSort = "", "" })
+	require.NoError(t, listCmd.Flags().Set("has-versions", "true"))
+	t.Cleanup(func() {
+		_ = listCmd.Flags().Set("has-versions", "false")
+		listCmd.Flags().Lookup("has-versions").Changed = false
+	})
+
+	query, err := moduleListFilters(listCmd)
+	require.NoError(t, err)
+	require.NoError(t, listAllModules(srv.Client(), srv.URL, query, zap.NewNop()))
+	assert.Equal(t, url.Values{"namespace": {"acme"}, "has_versions": {"true"}, "sort": {"downloads"}}, gotQuery)
+
+	listSort = "popularity"
+	_, err = moduleListFilters(listCmd)
+	assert.Equal(t, ExitUsage, exitCode(err))
+}
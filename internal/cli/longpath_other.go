@@ -0,0 +1,8 @@
+//go:build !windows
+
+package cli
+
+// longPath returns p unchanged; only Windows needs extended-length paths.
+func longPath(p string) string {
+	return p
+}
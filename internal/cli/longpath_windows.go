@@ -0,0 +1,27 @@
+//go:build windows
+
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxShortPath is kept below MAX_PATH (260) to leave room for the file name added by callers.
+const maxShortPath = 240
+
+// longPath converts long paths to extended-length form (\\?\C:\... or \\?\UNC\server\share\...)
+// so they can be created on Windows without enabling long path support system-wide.
+func longPath(p string) string {
+	if len(p) < maxShortPath || strings.HasPrefix(p, `\\?\`) {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + strings.TrimPrefix(abs, `\\`)
+	}
+	return `\\?\` + abs
+}
@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+)
+
+var (
+	adminTokenKind        string
+	adminTokenConsumer    string
+	adminTokenPatterns    []string
+	adminTokenExpires     string
+	adminTokenDescription string
+)
+
+// adminTokenCmd groups the commands for managed tokens
+var adminTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage read and maintainer tokens through the registry API",
+	Long: `Creates, updates and revokes read and maintainer tokens without restarting the registry.
+They grant the same access as the tokens configured in PROTOREG_READ_TOKENS and
+PROTOREG_MAINTAINER_TOKENS:
+  - read: reads internal modules, and the private modules matching --pattern (namespace/name)
+  - maintainer: reads and manages the webhooks of the namespaces matching --pattern
+The registry generates the token and shows it once, when it is created.
+
+Requires the admin API token.`,
+}
+
+// adminTokenListCmd represents the admin token list command
+var adminTokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the managed tokens",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		bodyBytes, err := adminRequest(http.MethodGet, managedTokensURL(registryURL), apiToken, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var list apitypes.ListManagedTokensResponse
+		if err := json.Unmarshal(bodyBytes, &list); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if len(list.Tokens) == 0 {
+			fmt.Println("No managed tokens")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tKIND\tCONSUMER\tPATTERNS\tEXPIRES\tFINGERPRINT\tDESCRIPTION")
+		for _, t := range list.Tokens {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Kind, orDash(t.Consumer), orDash(strings.Join(t.Patterns, ",")),
+				tokenExpiry(t), t.Fingerprint, orDash(t.Description))
+		}
+		return tw.Flush()
+	},
+}
+
+// adminTokenGetCmd represents the admin token get command
+var adminTokenGetCmd = &cobra.Command{
+	Use:   "get <id>",
+	Short: "Show a managed token",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		bodyBytes, err := adminRequest(http.MethodGet, managedTokensURL(registryURL)+"/"+url.PathEscape(args[0]), apiToken, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var t apitypes.ManagedTokenResponse
+		if err := json.Unmarshal(bodyBytes, &t); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		printManagedToken(t)
+		return nil
+	},
+}
+
+// adminTokenCreateCmd represents the admin token create command
+var adminTokenCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Generate a read or maintainer token",
+	Long: `Generates a token. It is printed once; store it right away, the registry only keeps
+its SHA256.
+
+Examples:
+  protoreg-cli admin token create --kind read --consumer ci --expires 2027-01-01
+  protoreg-cli admin token create --kind read --consumer payments-team --pattern 'payments/*' --pattern billing/ledger
+  protoreg-cli admin token create --kind maintainer --consumer payments-team --pattern payments`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		payload, err := managedTokenPayload()
+		if err != nil {
+			return err
+		}
+		bodyBytes, err := adminRequest(http.MethodPost, managedTokensURL(registryURL), apiToken, payload, http.StatusCreated)
+		if err != nil {
+			return err
+		}
+		var t apitypes.ManagedTokenResponse
+		if err := json.Unmarshal(bodyBytes, &t); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Created %s token %s; it won't be shown again:\n", t.Kind, t.ID)
+		fmt.Println(t.Token)
+		return nil
+	},
+}
+
+// adminTokenUpdateCmd represents the admin token update command
+var adminTokenUpdateCmd = &cobra.Command{
+	Use:   "update <id>",
+	Short: "Replace the grants, expiry and description of a managed token",
+	Long: `Replaces the kind, consumer, patterns, expiry and description of a managed token; the
+token itself doesn't change. Everything is replaced: omitted flags clear their setting.
+
+Examples:
+  protoreg-cli admin token update 6f1c... --kind maintainer --consumer payments-team --pattern payments --pattern 'payments-*'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		payload, err := managedTokenPayload()
+		if err != nil {
+			return err
+		}
+		bodyBytes, err := adminRequest(http.MethodPut, managedTokensURL(registryURL)+"/"+url.PathEscape(args[0]), apiToken, payload, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var t apitypes.ManagedTokenResponse
+		if err := json.Unmarshal(bodyBytes, &t); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		fmt.Printf("Updated managed token %s\n", t.ID)
+		printManagedToken(t)
+		return nil
+	},
+}
+
+// adminTokenRevokeCmd represents the admin token revoke command
+var adminTokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revoke a managed token",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		if _, err := adminRequest(http.MethodDelete, managedTokensURL(registryURL)+"/"+url.PathEscape(args[0]), apiToken, nil, http.StatusNoContent); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked managed token %s\n", args[0])
+		return nil
+	},
+}
+
+// managedTokensURL returns the URL of the managed tokens collection.
+func managedTokensURL(registryURL string) string {
+	return strings.TrimSuffix(registryURL, "/") + "/api/v1/admin/managed-tokens"
+}
+
+// managedTokenPayload encodes the request of the token flags. Invalid expiries are reported as usage errors.
+func managedTokenPayload() ([]byte, error) {
+	expiresAt, err := apitypes.ParseTokenExpiry(adminTokenExpires)
+	if err != nil {
+		return nil, withExitCode(ExitUsage, err)
+	}
+	payload, err := json.Marshal(apitypes.ManagedTokenRequest{
+		Kind:        adminTokenKind,
+		Consumer:    adminTokenConsumer,
+		Patterns:    adminTokenPatterns,
+		ExpiresAt:   expiresAt,
+		Description: adminTokenDescription,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	return payload, nil
+}
+
+// tokenExpiry formats the expiry of a managed token for tables.
+func tokenExpiry(t apitypes.ManagedTokenResponse) string {
+	switch {
+	case t.ExpiresAt == nil:
+		return "never"
+	case t.Expired:
+		return "expired"
+	}
+	return t.ExpiresAt.Local().Format(time.RFC3339)
+}
+
+func printManagedToken(t apitypes.ManagedTokenResponse) {
+	fmt.Printf("  ID:          %s\n", t.ID)
+	fmt.Printf("  Kind:        %s\n", t.Kind)
+	fmt.Printf("  Consumer:    %s\n", orNone(t.Consumer))
+	fmt.Printf("  Patterns:    %s\n", orNone(strings.Join(t.Patterns, ", ")))
+	fmt.Printf("  Expires:     %s\n", tokenExpiry(t))
+	fmt.Printf("  Fingerprint: %s\n", t.Fingerprint)
+	fmt.Printf("  Description: %s\n", orNone(t.Description))
+	fmt.Printf("  Updated:     %s\n", t.UpdatedAt.Local().Format(time.RFC3339))
+}
+
+func init() {
+	adminCmd.AddCommand(adminTokenCmd)
+	adminTokenCmd.AddCommand(adminTokenListCmd)
+	adminTokenCmd.AddCommand(adminTokenGetCmd)
+	adminTokenCmd.AddCommand(adminTokenCreateCmd)
+	adminTokenCmd.AddCommand(adminTokenUpdateCmd)
+	adminTokenCmd.AddCommand(adminTokenRevokeCmd)
+
+	for _, cmd := range []*cobra.Command{adminTokenCreateCmd, adminTokenUpdateCmd} {
+		cmd.Flags().StringVar(&adminTokenKind, "kind", apitypes.ManagedTokenRead, "Token kind: read or maintainer")
+		cmd.Flags().StringVar(&adminTokenConsumer, "consumer", "", "Team or service the token identifies in consumption reports")
+		cmd.Flags().StringArrayVar(&adminTokenPatterns, "pattern", nil, "Private modules a read token reads (payments/*), or namespaces a maintainer token maintains (payments-*) (repeatable)")
+		cmd.Flags().StringVar(&adminTokenExpires, "expires", "", "Expiry date (2027-01-01) or RFC3339 timestamp; never if omitted")
+		cmd.Flags().StringVar(&adminTokenDescription, "description", "", "What the token is for")
+	}
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// Module names given on the command line may omit the namespace if a default namespace is configured
+// (default_namespace in the config file, PROTOREG_DEFAULT_NAMESPACE), and publish, fetch and list can
+// take the module name and version from a module directory's sproto.yaml instead of flags or arguments.
+
+// qualifyModule prefixes a module name without a namespace with the default namespace, if one is configured.
+func qualifyModule(name string) string {
+	if name == "" || strings.Contains(name, "/") {
+		return name
+	}
+	if namespace := viper.GetString("default_namespace"); namespace != "" {
+		return namespace + "/" + name
+	}
+	return name
+}
+
+// loadModuleManifest reads the sproto.yaml in dir. Returns nil (and no error) if there is none.
+func loadModuleManifest(dir string) (*manifest.Manifest, error) {
+	m, err := manifest.LoadManifest(filepath.Join(dir, manifest.ManifestFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return m, err
+}
+
+// applyManifestDefaults fills in the module name and version missing from the flags with those in m
+// (which may be nil). Flags take precedence; overriding a different value in m is logged, as it usually
+// means the manifest is out of date.
+func applyManifestDefaults(m *manifest.Manifest, module, version string, log *zap.Logger) (string, string) {
+	module = qualifyModule(module)
+	if m == nil {
+		return module, version
+	}
+	if module == "" {
+		module = m.Name
+	} else if m.Name != "" && module != m.Name {
+		log.Warn("--module differs from the name in sproto.yaml; using --module", zap.String("module", module), zap.String("manifest_name", m.Name))
+	}
+	if version == "" {
+		version = m.Version
+	} else if m.Version != "" && !sameVersion(version, m.Version) {
+		log.Warn("--version differs from the version in sproto.yaml; using --version", zap.String("version", version), zap.String("manifest_version", m.Version))
+	}
+	return module, version
+}
+
+// sameVersion reports whether two version strings denote the same semantic version ("1.0.0" and "v1.0.0" do).
+func sameVersion(a, b string) bool {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return va.Equal(vb)
+}
+
+// resolveModuleArg resolves a module argument of fetch or list: a module name (the namespace may be
+// omitted, see qualifyModule) or a module directory containing a sproto.yaml, such as ".". version is
+// returned unchanged if set, and defaults to the manifest's version for directories.
+func resolveModuleArg(arg, version string) (namespace, name, resolvedVersion string, err error) {
+	module := qualifyModule(arg)
+	if info, statErr := os.Stat(arg); statErr == nil && info.IsDir() {
+		m, err := loadModuleManifest(arg)
+		if err != nil {
+			return "", "", "", err
+		}
+		if m == nil || m.Name == "" {
+			return "", "", "", fmt.Errorf("%s has no %s with a module name", arg, manifest.ManifestFileName)
+		}
+		module = m.Name
+		if version == "" {
+			version = m.Version
+		}
+	}
+	namespace, name, err = manifest.SplitModule(module)
+	if err != nil {
+		return "", "", "", fmt.Errorf("%w (or a module directory containing %s)", err, manifest.ManifestFileName)
+	}
+	return namespace, name, version, nil
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestQualifyModule(t *testing.T) {
+	viper.Set("default_namespace", "")
+	t.Cleanup(func() { viper.Set("default_namespace", "") })
+	assert.Equal(t, "billing", qualifyModule("billing"))
+
+	viper.Set("default_namespace", "acme")
+	assert.Equal(t, "acme/billing", qualifyModule("billing"))
+	assert.Equal(t, "other/billing", qualifyModule("other/billing"))
+	assert.Equal(t, "", qualifyModule(""))
+}
+
+func TestResolveModuleArg(t *testing.T) {
+	viper.Set("default_namespace", "")
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sproto.yaml"), []byte("name: acme/billing\nversion: v1.2.0\n"), 0644))
+
+	// A module directory supplies the name and, unless given, the version
+	ns, name, version, err := resolveModuleArg(dir, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme", "billing", "v1.2.0"}, []string{ns, name, version})
+	_, _, version, err = resolveModuleArg(dir, "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", version)
+
+	// Module names are used as is
+	ns, name, version, err = resolveModuleArg("acme/user", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme", "user", ""}, []string{ns, name, version})
+
+	// A directory without a manifest, or a name without a namespace, is an error
+	_, _, _, err = resolveModuleArg(t.TempDir(), "")
+	assert.ErrorContains(t, err, "sproto.yaml")
+	_, _, _, err = resolveModuleArg("billing-without-namespace", "")
+	assert.Error(t, err)
+}
+
+func TestApplyManifestDefaults(t *testing.T) {
+	viper.Set("default_namespace", "")
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sproto.yaml"), []byte("name: acme/billing\nversion: v1.2.0\n"), 0644))
+	m, err := loadModuleManifest(dir)
+	require.NoError(t, err)
+
+	module, version := applyManifestDefaults(m, "", "", zap.NewNop())
+	assert.Equal(t, "acme/billing", module)
+	assert.Equal(t, "v1.2.0", version)
+
+	// Flags take precedence
+	module, version = applyManifestDefaults(m, "acme/other", "1.3.0", zap.NewNop())
+	assert.Equal(t, "acme/other", module)
+	assert.Equal(t, "1.3.0", version)
+
+	// No manifest
+	m, err = loadModuleManifest(t.TempDir())
+	require.NoError(t, err)
+	assert.Nil(t, m)
+	module, version = applyManifestDefaults(m, "acme/billing", "v1.0.0", zap.NewNop())
+	assert.Equal(t, "acme/billing", module)
+	assert.Equal(t, "v1.0.0", version)
+}
@@ -0,0 +1,289 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	operationsListKind   string
+	operationsListStatus string
+	operationsListLimit  int
+)
+
+// operationPollInterval is how often a background operation is polled while waiting for it.
+var operationPollInterval = time.Second
+
+// operationsCmd groups the commands for background operations
+var operationsCmd = &cobra.Command{
+	Use:   "operations",
+	Short: "Inspect, wait for and cancel background operations",
+	Long: `Commands for the registry's background operations: asynchronous publishes (publish --async)
+and admin jobs (admin run). Operations are kept for a day after they finished.`,
+}
+
+// operationsListCmd represents the operations list command
+var operationsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List background operations, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		query := url.Values{}
+		if operationsListKind != "" {
+			query.Set("kind", operationsListKind)
+		}
+		if operationsListStatus != "" {
+			query.Set("status", operationsListStatus)
+		}
+		if operationsListLimit > 0 {
+			query.Set("limit", strconv.Itoa(operationsListLimit))
+		}
+		targetURL := strings.TrimSuffix(registryURL, "/") + "/api/v1/operations"
+		if len(query) > 0 {
+			targetURL += "?" + query.Encode()
+		}
+		bodyBytes, err := adminRequest(http.MethodGet, targetURL, apiToken, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var list apitypes.ListOperationsResponse
+		if err := json.Unmarshal(bodyBytes, &list); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if len(list.Operations) == 0 {
+			fmt.Println("No operations")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tKIND\tTARGET\tSTATUS\tSTAGE\tCREATED")
+		for _, op := range list.Operations {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", op.ID, op.Kind, orDash(operationTarget(op)), op.Status, orDash(op.Stage), op.CreatedAt.Local().Format(time.RFC3339))
+		}
+		return tw.Flush()
+	},
+}
+
+// operationsGetCmd represents the operations get command
+var operationsGetCmd = &cobra.Command{
+	Use:   "get <id>",
+	Short: "Show the status and result of an operation",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		bodyBytes, err := adminRequest(http.MethodGet, operationURL(registryURL, args[0]), apiToken, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var op apitypes.OperationResponse
+		if err := json.Unmarshal(bodyBytes, &op); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		printOperation(op)
+		return nil
+	},
+}
+
+// operationsCancelCmd represents the operations cancel command
+var operationsCancelCmd = &cobra.Command{
+	Use:   "cancel <id>",
+	Short: "Cancel a queued or running operation",
+	Long: `Cancels an operation. Queued operations never start; running ones are interrupted and
+become canceled once they stopped. Work a running operation already completed (e.g. the
+artifacts a storage migration already moved) is kept.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		bodyBytes, err := adminRequest(http.MethodPost, operationURL(registryURL, args[0])+"/cancel", apiToken, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var op apitypes.OperationResponse
+		if err := json.Unmarshal(bodyBytes, &op); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if op.Status == "canceled" {
+			fmt.Printf("Canceled operation %s\n", op.ID)
+		} else {
+			fmt.Printf("Canceling operation %s (it stops at its next checkpoint)\n", op.ID)
+		}
+		return nil
+	},
+}
+
+// operationsWaitCmd represents the operations wait command
+var operationsWaitCmd = &cobra.Command{
+	Use:   "wait <id>",
+	Short: "Wait for an operation to finish and print its result",
+	Long: `Polls an operation until it has finished, printing each stage as it is reached, then prints
+its result. Exits with the exit code of the operation's status if it failed or was canceled.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		registryURL, apiToken, err := requireAdmin()
+		if err != nil {
+			return err
+		}
+		bodyBytes, err := adminRequest(http.MethodGet, operationURL(registryURL, args[0]), apiToken, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var op apitypes.OperationResponse
+		if err := json.Unmarshal(bodyBytes, &op); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		return waitAndPrintOperation(registryURL, apiToken, op)
+	},
+}
+
+// operationURL returns the URL of an operation.
+func operationURL(registryURL, id string) string {
+	return fmt.Sprintf("%s/api/v1/operations/%s", strings.TrimSuffix(registryURL, "/"), url.PathEscape(id))
+}
+
+// pollOperation polls op until it has finished, printing each stage as it is reached, and returns it.
+func pollOperation(client *http.Client, registryURL, apiToken string, op apitypes.OperationResponse, log *zap.Logger) (apitypes.OperationResponse, error) {
+	targetURL := operationURL(registryURL, op.ID)
+	lastStage := ""
+	for op.Status == "queued" || op.Status == "running" {
+		time.Sleep(operationPollInterval)
+		req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+		if err != nil {
+			return op, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+		resp, err := client.Do(req)
+		if err != nil {
+			return op, fmt.Errorf("failed to poll operation %s: %w", op.ID, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return op, fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return op, fmt.Errorf("failed to poll operation %s: %w", op.ID, registryError(resp.StatusCode, body))
+		}
+		op = apitypes.OperationResponse{}
+		if err := json.Unmarshal(body, &op); err != nil {
+			return op, fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if op.Stage != "" && op.Stage != lastStage {
+			fmt.Printf("  %s...\n", op.Stage)
+			lastStage = op.Stage
+		}
+		log.Debug("Polled operation", zap.String("operation", op.ID), zap.String("status", op.Status), zap.String("stage", op.Stage))
+	}
+	return op, nil
+}
+
+// operationResult returns the status code and body a finished operation responded with: the response
+// the synchronous equivalent would have sent.
+func operationResult(op apitypes.OperationResponse) (int, []byte) {
+	result := []byte(op.Result)
+	if len(result) == 0 && op.Error != "" {
+		// E.g. interrupted by a restart of the registry: report the error like a response body
+		result, _ = json.Marshal(map[string]string{"error": op.Error})
+	}
+	if op.HTTPStatus == 0 {
+		op.HTTPStatus = http.StatusInternalServerError
+	}
+	return op.HTTPStatus, result
+}
+
+// waitAndPrintOperation waits for op to finish and prints it, returning an error carrying the exit code
+// of its status if it didn't succeed.
+func waitAndPrintOperation(registryURL, apiToken string, op apitypes.OperationResponse) error {
+	op, err := pollOperation(http.DefaultClient, registryURL, apiToken, op, GetLogger())
+	if err != nil {
+		return err
+	}
+	printOperation(op)
+	if op.Status != "succeeded" {
+		status, result := operationResult(op)
+		return fmt.Errorf("operation %s: %w", op.Status, registryError(status, result))
+	}
+	return nil
+}
+
+// operationTarget returns the module (version) an operation applies to, if any.
+func operationTarget(op apitypes.OperationResponse) string {
+	if op.Namespace == "" {
+		return ""
+	}
+	target := op.Namespace + "/" + op.ModuleName
+	if op.Version != "" {
+		target += "@" + op.Version
+	}
+	return target
+}
+
+func printOperation(op apitypes.OperationResponse) {
+	fmt.Printf("Operation %s\n", op.ID)
+	fmt.Printf("  Kind:      %s\n", op.Kind)
+	if target := operationTarget(op); target != "" {
+		fmt.Printf("  Target:    %s\n", target)
+	}
+	names := make([]string, 0, len(op.Params))
+	for name := range op.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  Param:     %s=%s\n", name, op.Params[name])
+	}
+	fmt.Printf("  Status:    %s\n", op.Status)
+	if op.Stage != "" {
+		fmt.Printf("  Stage:     %s\n", op.Stage)
+	}
+	fmt.Printf("  Created:   %s\n", op.CreatedAt.Local().Format(time.RFC3339))
+	if op.StartedAt != nil {
+		fmt.Printf("  Started:   %s\n", op.StartedAt.Local().Format(time.RFC3339))
+	}
+	if op.FinishedAt != nil {
+		fmt.Printf("  Finished:  %s\n", op.FinishedAt.Local().Format(time.RFC3339))
+	}
+	if op.Error != "" {
+		fmt.Printf("  Error:     %s\n", op.Error)
+	}
+	if len(op.Result) > 0 {
+		var indented bytes.Buffer
+		if json.Indent(&indented, op.Result, "  ", "  ") == nil {
+			fmt.Printf("  Result (HTTP %d):\n  %s\n", op.HTTPStatus, indented.String())
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(operationsCmd)
+	operationsCmd.AddCommand(operationsListCmd)
+	operationsCmd.AddCommand(operationsGetCmd)
+	operationsCmd.AddCommand(operationsCancelCmd)
+	operationsCmd.AddCommand(operationsWaitCmd)
+
+	operationsListCmd.Flags().StringVar(&operationsListKind, "kind", "", "Only list operations of this kind: publish, gc, sunset, migrate-storage, import-buf, tier-storage, reindex-search, reindex-packages or sign-versions")
+	operationsListCmd.Flags().StringVar(&operationsListStatus, "status", "", "Only list operations with this status: queued, running, succeeded, failed or canceled")
+	operationsListCmd.Flags().IntVar(&operationsListLimit, "limit", 0, "Maximum number of operations to list (default: the registry's default, 50)")
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	outdatedDir      string
+	outdatedExitCode bool
+)
+
+// outdatedCmd represents the outdated command
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "Show dependencies with newer versions available",
+	Long: `Reads sproto.yaml and sproto.lock, asks the registry for the published versions of
+each dependency and prints those that have newer versions:
+
+  CURRENT  the version pinned in sproto.lock ("-" if not locked)
+  WANTED   the newest version satisfying the constraint in sproto.yaml
+  LATEST   the newest published version, ignoring constraints
+
+With --exit-code the command exits 1 if any dependency is behind its WANTED version
+(sproto.lock pins an older version than sproto.yaml allows), which is useful for CI gating.
+
+Example:
+  protoreg-cli outdated --dir ./protos --exit-code`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+
+		m, err := manifest.LoadManifest(filepath.Join(outdatedDir, manifest.ManifestFileName))
+		if err != nil {
+			return fmt.Errorf("failed to read manifest: %w", err)
+		}
+		lock, err := manifest.LoadLock(filepath.Join(outdatedDir, manifest.LockFileName))
+		if errors.Is(err, os.ErrNotExist) {
+			lock = &manifest.Lock{} // Nothing locked yet: every dependency shows as outdated
+		} else if err != nil {
+			return fmt.Errorf("failed to read lockfile: %w", err)
+		}
+
+		rows, err := collectOutdated(&http.Client{}, registryURL, m, lock, log)
+		if err != nil {
+			return fmt.Errorf("failed to check dependencies: %w", err)
+		}
+
+		if len(rows) == 0 {
+			fmt.Println("All dependencies are up to date.")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "MODULE\tCONSTRAINT\tCURRENT\tWANTED\tLATEST")
+		behind := false
+		for _, r := range rows {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Module, orDash(r.Constraint), orDash(r.Current), orDash(r.Wanted), orDash(r.Latest))
+			behind = behind || r.behindWanted()
+		}
+		_ = tw.Flush()
+
+		if outdatedExitCode && behind {
+			return exitStatus(1)
+		}
+		return nil
+	},
+}
+
+// outdatedRow is one line of the outdated table.
+type outdatedRow struct {
+	Module     string
+	Constraint string
+	Current    string // Locked version, "" if not locked
+	Wanted     string // Newest version matching the constraint, "" if none
+	Latest     string // Newest published version, "" if none
+}
+
+// behindWanted reports whether the lock can be updated within the manifest constraint.
+func (r outdatedRow) behindWanted() bool {
+	if r.Wanted == "" {
+		return false
+	}
+	return r.Current == "" || manifest.IsOlder(r.Current, r.Wanted)
+}
+
+// collectOutdated queries the registry for each dependency in the manifest and returns the ones
+// that are not locked or have a newer version (within or outside the constraint).
+func collectOutdated(client *http.Client, registryURL string, m *manifest.Manifest, lock *manifest.Lock, log *zap.Logger) ([]outdatedRow, error) {
+	var rows []outdatedRow
+	for _, module := range m.DependencyNames() {
+		namespace, name, _ := manifest.SplitModule(module) // Validated when the manifest was loaded
+		constraint, _ := m.Constraint(module)
+
+		versions, err := getModuleVersions(client, registryURL, namespace, name, log)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", module, err)
+		}
+
+		row := outdatedRow{
+			Module:     module,
+			Constraint: m.Dependencies[module],
+			Wanted:     manifest.NewestMatching(versions, constraint),
+			Latest:     manifest.Newest(versions),
+		}
+		if locked := lock.Find(module); locked != nil {
+			row.Current = locked.Version
+		}
+
+		if row.Current == "" || row.behindWanted() || manifest.IsOlder(row.Current, row.Latest) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// orDash returns s, or "-" if s is empty (for table output).
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+
+	outdatedCmd.Flags().StringVarP(&outdatedDir, "dir", "d", ".", "Directory containing sproto.yaml and sproto.lock")
+	outdatedCmd.Flags().BoolVar(&outdatedExitCode, "exit-code", false, "Exit with status 1 if any dependency can be updated within its constraint")
+}
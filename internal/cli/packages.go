@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// packageCmd represents the package command
+var packageCmd = &cobra.Command{
+	Use:   "package <proto_package>",
+	Short: "Find the module declaring a proto package",
+	Long: `Looks up which module declares a proto package (the "package" statement of its
+.proto files), e.g. to find the module to depend on for an import. Prints the module
+and the newest version declaring the package, one line per module.
+
+Examples:
+  protoreg-cli package mycompany.user.v1`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+
+		targetURL := fmt.Sprintf("%s/api/v1/packages/%s", strings.TrimSuffix(registryURL, "/"), url.PathEscape(args[0]))
+		req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		setReadToken(req)
+		log.Debug("Looking up package", zap.String("url", targetURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return registryError(resp.StatusCode, bodyBytes)
+		}
+
+		var pkg apitypes.PackageResponse
+		if err := json.Unmarshal(bodyBytes, &pkg); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		for _, m := range pkg.Modules {
+			fmt.Printf("%s@%s\n", m.Module, m.Version)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(packageCmd)
+}
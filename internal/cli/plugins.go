@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	pluginsImage       string
+	pluginsBinaries    []string
+	pluginsDescription string
+)
+
+// pluginsCmd groups the commands for the plugin registry
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "List and register protoc plugins in the registry",
+	Long: `Commands for the registry's protoc plugins: versions of code generators (protoc-gen-go,
+protoc-gen-go-grpc, ...) registered once by an admin, as a container image and/or
+per-platform binaries, and used by 'generate --plugin registry://<name>:<version>'.`,
+}
+
+// pluginsListCmd represents the plugins list command
+var pluginsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the registered plugin versions",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		targetURL := strings.TrimSuffix(registryURL, "/") + "/api/v1/plugins"
+		log.Info("Listing plugins", zap.String("url", targetURL))
+
+		req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		setReadToken(req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return registryError(resp.StatusCode, bodyBytes)
+		}
+
+		var list apitypes.ListPluginsResponse
+		if err := json.Unmarshal(bodyBytes, &list); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if len(list.Plugins) == 0 {
+			fmt.Println("No plugins registered")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tVERSION\tIMAGE\tPLATFORMS")
+		for _, p := range list.Plugins {
+			platforms := make([]string, 0, len(p.Binaries))
+			for _, b := range p.Binaries {
+				platforms = append(platforms, b.Platform)
+			}
+			image := p.Image
+			if image == "" {
+				image = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Name, p.Version, image, strings.Join(platforms, ","))
+		}
+		return tw.Flush()
+	},
+}
+
+// pluginsRegisterCmd represents the plugins register command
+var pluginsRegisterCmd = &cobra.Command{
+	Use:   "register <name> <version>",
+	Short: "Register a plugin version",
+	Long: `Registers a version of a protoc plugin: a container image running the plugin (reading the
+CodeGeneratorRequest on standard input), binaries for one or more platforms, or both.
+Binaries are given as <os>/<arch>=<url>#<sha256>; 'generate' downloads the one for its
+platform and checks the digest. Versions can't be changed once registered. Requires the
+admin token.
+
+Examples:
+  protoreg-cli plugins register go v1.34.2 --image ghcr.io/mycompany/protoc-gen-go:1.34.2
+  protoreg-cli plugins register go v1.34.2 \
+    --binary linux/amd64=https://downloads.example.com/protoc-gen-go-1.34.2-linux-amd64#<sha256> \
+    --binary darwin/arm64=https://downloads.example.com/protoc-gen-go-1.34.2-darwin-arm64#<sha256>`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		apiToken, err := requireAPIToken()
+		if err != nil {
+			return err
+		}
+		pluginURL, err := pluginVersionURL(registryURL, args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		body := apitypes.RegisterPluginRequest{Image: pluginsImage, Description: pluginsDescription}
+		for _, spec := range pluginsBinaries {
+			binary, err := parsePluginBinary(spec)
+			if err != nil {
+				return withExitCode(ExitUsage, err)
+			}
+			body.Binaries = append(body.Binaries, binary)
+		}
+		if body.Image == "" && len(body.Binaries) == 0 {
+			return exitErrorf(ExitUsage, "--image or --binary is required")
+		}
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, pluginURL, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+		req.Header.Set("Content-Type", "application/json")
+		log.Info("Registering plugin", zap.String("url", pluginURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusCreated {
+			return registryError(resp.StatusCode, bodyBytes)
+		}
+
+		var registered apitypes.PluginResponse
+		if err := json.Unmarshal(bodyBytes, &registered); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		fmt.Printf("Registered plugin %s:%s\n", registered.Name, registered.Version)
+		if registered.Image != "" {
+			fmt.Printf("  Image: %s\n", registered.Image)
+		}
+		for _, b := range registered.Binaries {
+			fmt.Printf("  Binary (%s): %s\n", b.Platform, b.URL)
+		}
+		return nil
+	},
+}
+
+// pluginsDeleteCmd represents the plugins delete command
+var pluginsDeleteCmd = &cobra.Command{
+	Use:   "delete <name> <version>",
+	Short: "Unregister a plugin version",
+	Long: `Removes a plugin version from the registry (the exact version, e.g. v1.34.2). Generation
+referencing it, or a partial version it was the newest of, picks another version from then
+on. Requires the admin token.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		apiToken, err := requireAPIToken()
+		if err != nil {
+			return err
+		}
+		pluginURL, err := pluginVersionURL(registryURL, args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequest(http.MethodDelete, pluginURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+		log.Info("Deleting plugin", zap.String("url", pluginURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			return registryError(resp.StatusCode, bodyBytes)
+		}
+		fmt.Printf("Deleted plugin %s:%s\n", args[0], args[1])
+		return nil
+	},
+}
+
+// fetchPlugin looks up a registered plugin version (exact, partial or "latest").
+func fetchPlugin(client *http.Client, registryURL, name, version string, log *zap.Logger) (*apitypes.PluginResponse, error) {
+	pluginURL, err := pluginVersionURL(registryURL, name, version)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Resolving plugin", zap.String("url", pluginURL))
+
+	req, err := http.NewRequest(http.MethodGet, pluginURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	setReadToken(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("plugin %s:%s: %w", name, version, registryError(resp.StatusCode, bodyBytes))
+	}
+	var plugin apitypes.PluginResponse
+	if err := json.Unmarshal(bodyBytes, &plugin); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	return &plugin, nil
+}
+
+// pluginVersionURL returns the URL of a plugin version. Invalid arguments are reported as validation errors.
+func pluginVersionURL(registryURL, name, version string) (string, error) {
+	if err := validation.ValidatePluginName(name); err != nil {
+		return "", withExitCode(ExitValidation, err)
+	}
+	if version != "latest" && !strings.HasPrefix(version, "v") {
+		return "", exitErrorf(ExitValidation, "invalid version format %q: must start with 'v' or be 'latest'", version)
+	}
+	return fmt.Sprintf("%s/api/v1/plugins/%s/%s", strings.TrimSuffix(registryURL, "/"), url.PathEscape(name), url.PathEscape(version)), nil
+}
+
+// parsePluginBinary parses a --binary value: <os>/<arch>=<url>#<sha256>.
+func parsePluginBinary(spec string) (apitypes.PluginBinaryInfo, error) {
+	platform, rest, ok := strings.Cut(spec, "=")
+	if !ok {
+		return apitypes.PluginBinaryInfo{}, fmt.Errorf("invalid --binary %q: expected <os>/<arch>=<url>#<sha256>", spec)
+	}
+	i := strings.LastIndex(rest, "#")
+	if i < 0 {
+		return apitypes.PluginBinaryInfo{}, fmt.Errorf("invalid --binary %q: missing #<sha256> after the URL", spec)
+	}
+	return apitypes.PluginBinaryInfo{Platform: platform, URL: rest[:i], SHA256: rest[i+1:]}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(pluginsCmd)
+	pluginsCmd.AddCommand(pluginsListCmd)
+	pluginsCmd.AddCommand(pluginsRegisterCmd)
+	pluginsCmd.AddCommand(pluginsDeleteCmd)
+
+	pluginsRegisterCmd.Flags().StringVar(&pluginsImage, "image", "", "Container image running the plugin")
+	pluginsRegisterCmd.Flags().StringArrayVar(&pluginsBinaries, "binary", nil, "Plugin binary for a platform, as <os>/<arch>=<url>#<sha256> (repeatable)")
+	pluginsRegisterCmd.Flags().StringVar(&pluginsDescription, "description", "", "Description of the plugin version")
+}
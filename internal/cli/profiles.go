@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Profiles: named sets of registry settings in the config file (profiles.<name>.registry_url, api_token,
+// read_token and registry_public_key), for commands working with several registries at once, such as
+// promote. They are saved with 'protoreg-cli configure --profile <name>'. The "default" profile is the
+// top-level configuration every other command uses (flags, env vars and the config file).
+
+// defaultProfile names the top-level configuration.
+const defaultProfile = "default"
+
+// profileNamePattern restricts profile names to what can be a config file key.
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// registryProfile is the settings of a profile.
+type registryProfile struct {
+	name              string
+	registryURL       string
+	apiToken          string
+	readToken         string // Defaults to apiToken
+	registryPublicKey string // Checksum statements are verified if set
+}
+
+// validateProfileName checks that name can name a profile.
+func validateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use lowercase letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// profileKey returns the config key of a setting of a profile ("" for the default profile).
+func profileKey(profile, key string) string {
+	if profile == "" || profile == defaultProfile {
+		return key
+	}
+	return "profiles." + profile + "." + key
+}
+
+// loadProfile returns the settings of a profile. Profiles other than the default one must exist in the
+// config file and have a registry URL.
+func loadProfile(name string) (*registryProfile, error) {
+	if err := validateProfileName(name); err != nil {
+		return nil, withExitCode(ExitUsage, err)
+	}
+	if name != defaultProfile && !viper.IsSet("profiles."+name) {
+		return nil, exitErrorf(ExitUsage, "profile %q is not configured; use 'protoreg-cli configure --profile %s --registry-url <url>'", name, name)
+	}
+	p := &registryProfile{
+		name:              name,
+		registryURL:       strings.TrimSuffix(viper.GetString(profileKey(name, "registry_url")), "/"),
+		apiToken:          viper.GetString(profileKey(name, "api_token")),
+		readToken:         viper.GetString(profileKey(name, "read_token")),
+		registryPublicKey: viper.GetString(profileKey(name, "registry_public_key")),
+	}
+	if p.registryURL == "" {
+		return nil, exitErrorf(ExitUsage, "profile %q has no registry URL; use 'protoreg-cli configure --profile %s --registry-url <url>'", name, name)
+	}
+	return p, nil
+}
+
+// setReadToken authenticates a read request to the profile's registry, like the package-level setReadToken.
+func (p *registryProfile) setReadToken(req *http.Request) {
+	token := p.readToken
+	if token == "" {
+		token = p.apiToken
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+func (p *registryProfile) String() string {
+	return fmt.Sprintf("%s (%s)", p.name, p.registryURL)
+}
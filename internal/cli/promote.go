@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/provenance"
+	"github.com/Suhaibinator/SProto/internal/translog"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	promoteFrom          string
+	promoteTo            string
+	promoteAllowUnsigned bool
+)
+
+// promoteCmd represents the promote command
+var promoteCmd = &cobra.Command{
+	Use:   "promote <namespace/module_name> <version> --from <profile> --to <profile>",
+	Short: "Copy a module version from one registry to another, recording where it came from",
+	Long: `Promotes a published version from one registry to another, e.g. from staging to prod: the
+artifact is downloaded from the --from profile's registry, verified, and published unchanged to
+the --to profile's registry. The target records where the version came from (the source registry,
+its signature and when the version was published there) as provenance, shown by 'protoreg-cli info';
+versions promoted several times keep the whole chain, back to the registry where they were first
+published.
+
+Before anything is published, the artifact must match the digest the source registry lists for the
+version and the version signature it made (VERSION_SIGNING_KEY), checked against the source's
+published keys. Sources that don't sign versions are refused unless --allow-unsigned is given. With
+a registry public key in the source profile, the signed checksum statement is checked too.
+
+Profiles are saved with 'protoreg-cli configure --profile <name>'; "default" is the top-level
+configuration. The source is read with its read token (or API token); publishing needs the target's
+API token. Promoting a version the target already has with the same digest does nothing.
+
+Examples:
+  protoreg-cli configure --profile staging --registry-url https://staging.registry.example.com --read-token ...
+  protoreg-cli configure --profile prod --registry-url https://registry.example.com --api-token ...
+  protoreg-cli promote mycompany/user v1.2.0 --from staging --to prod`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		namespace, moduleName, err := splitModuleArg(args[0])
+		if err != nil {
+			return err
+		}
+		version := args[1]
+		if !strings.HasPrefix(version, "v") {
+			return exitErrorf(ExitValidation, "invalid version format %q: must start with 'v'", version)
+		}
+		from, err := loadProfile(promoteFrom)
+		if err != nil {
+			return err
+		}
+		to, err := loadProfile(promoteTo)
+		if err != nil {
+			return err
+		}
+		if from.registryURL == to.registryURL {
+			return exitErrorf(ExitUsage, "profiles %s and %s are the same registry", from.name, to.name)
+		}
+		if to.apiToken == "" {
+			return exitErrorf(ExitAuth, "profile %s has no API token to publish with; use 'protoreg-cli configure --profile %s --api-token <token>'", to.name, to.name)
+		}
+
+		result, err := promoteVersion(&http.Client{}, from, to, namespace, moduleName, version, promoteAllowUnsigned, log)
+		if err != nil {
+			return err
+		}
+		module := namespace + "/" + moduleName
+		if !result.created {
+			fmt.Printf("%s@%s is already in %s with digest %s; nothing to do.\n", module, version, to, result.digest)
+			return nil
+		}
+		origin := result.chain[0]
+		fmt.Printf("Promoted %s@%s from %s to %s (%s)\n", module, version, from, to, result.digest)
+		fmt.Printf("Originally published at %s on %s\n", origin.Registry, origin.PublishedAt.UTC().Format(time.RFC3339))
+		return nil
+	},
+}
+
+// promotion is the outcome of promoteVersion.
+type promotion struct {
+	digest  string              // sha256:<hex> of the artifact
+	chain   []provenance.Source // Recorded by the target, the original source first
+	created bool                // False if the target already had the version with the same digest
+}
+
+// promoteVersion copies a version from one registry to another, after verifying the downloaded artifact
+// against the source's listed digest, version signature and (with a public key) checksum statement.
+func promoteVersion(client *http.Client, from, to *registryProfile, namespace, moduleName, version string, allowUnsigned bool, log *zap.Logger) (*promotion, error) {
+	module := namespace + "/" + moduleName
+	versionPath := fmt.Sprintf("/api/v1/modules/%s/%s/%s", url.PathEscape(namespace), url.PathEscape(moduleName), url.PathEscape(version))
+
+	// --- Fetch and verify the source version ---
+	var meta apitypes.ModuleVersionResponse
+	if err := getProfileJSON(client, from, versionPath, &meta); err != nil {
+		return nil, fmt.Errorf("%s@%s in %s: %w", module, version, from, err)
+	}
+	zipData, err := getProfileBytes(client, from, versionPath+"/artifact")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s@%s from %s: %w", module, version, from, err)
+	}
+	sum := sha256.Sum256(zipData)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if digest != meta.ArtifactDigest {
+		return nil, exitErrorf(ExitValidation, "artifact downloaded from %s has digest %s, but the registry lists %s for %s@%s", from, digest, meta.ArtifactDigest, module, version)
+	}
+	if from.registryPublicKey != "" {
+		key, err := translog.ParsePublicKey(from.registryPublicKey)
+		if err != nil {
+			return nil, exitErrorf(ExitUsage, "invalid registry public key of profile %s: %w", from.name, err)
+		}
+		if err := verifyAttestation(key, module, meta.Version, zipData, meta.Checksum); err != nil {
+			return nil, withExitCode(ExitValidation, err)
+		}
+	}
+	source := provenance.Source{Registry: from.registryURL, Module: module, Version: meta.Version, Digest: digest, PublishedAt: meta.CreatedAt}
+	if meta.Signature != nil {
+		if err := verifyVersionSignature(client, from, module, meta.Version, digest, meta.Signature); err != nil {
+			return nil, err
+		}
+		source.Signature, source.KeyID = meta.Signature.Signature, meta.Signature.KeyID
+	} else if !allowUnsigned {
+		return nil, exitErrorf(ExitValidation, "%s@%s is not signed by %s (is VERSION_SIGNING_KEY configured there?); use --allow-unsigned to promote it anyway", module, version, from)
+	}
+	log.Info("Verified source version", zap.String("module", module), zap.String("version", meta.Version), zap.String("digest", digest), zap.Bool("signed", meta.Signature != nil))
+	chain := provenance.Chain(meta.Provenance, source)
+
+	// --- Publish to the target ---
+	header, err := json.Marshal(chain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode provenance: %w", err)
+	}
+	req, err := newArtifactUploadRequest(to.registryURL+versionPath, meta.Version, zipData, to.apiToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set(apitypes.ProvenanceHeader, string(header))
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return &promotion{digest: digest, chain: chain, created: true}, nil
+	case http.StatusConflict:
+		// Promoted before? Only if the target has the same artifact
+		var existing apitypes.ModuleVersionResponse
+		if err := getProfileJSON(client, to, versionPath, &existing); err != nil {
+			return nil, fmt.Errorf("%s@%s in %s: %w", module, version, to, err)
+		}
+		if existing.ArtifactDigest != digest {
+			return nil, exitErrorf(ExitConflict, "%s@%s already exists in %s with another artifact (%s, promoting %s)", module, version, to, existing.ArtifactDigest, digest)
+		}
+		return &promotion{digest: digest, chain: existing.Provenance}, nil
+	default:
+		printErrorDetails(bodyBytes)
+		return nil, fmt.Errorf("failed to publish to %s: %w", to, registryError(resp.StatusCode, bodyBytes))
+	}
+}
+
+// verifyVersionSignature checks a version signature made by a profile's registry against the keys it
+// publishes (retired keys included).
+func verifyVersionSignature(client *http.Client, p *registryProfile, module, version, digest string, signature *apitypes.VersionSignature) error {
+	var keys apitypes.SigningKeysResponse
+	if err := getProfileJSON(client, p, apitypes.SigningKeysPath, &keys); err != nil {
+		return fmt.Errorf("failed to fetch the signing keys of %s: %w", p, err)
+	}
+	for _, k := range keys.Keys {
+		if k.KeyID != signature.KeyID {
+			continue
+		}
+		pub, err := base64.StdEncoding.DecodeString(k.PublicKey)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			return exitErrorf(ExitValidation, "signing key %s of %s is invalid", k.KeyID, p)
+		}
+		if err := provenance.Verify(ed25519.PublicKey(pub), module, version, digest, signature.Signature); err != nil {
+			return exitErrorf(ExitValidation, "signature of %s@%s does not verify with key %s of %s: %w", module, version, k.KeyID, p, err)
+		}
+		return nil
+	}
+	return exitErrorf(ExitValidation, "%s@%s is signed with key %s, which %s doesn't publish", module, version, signature.KeyID, p)
+}
+
+// getProfileJSON reads a JSON response from a profile's registry into v.
+func getProfileJSON(client *http.Client, p *registryProfile, path string, v any) error {
+	body, err := getProfileBytes(client, p, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse API response: %w", err)
+	}
+	return nil
+}
+
+// getProfileBytes reads a response from a profile's registry, authenticated with its read token.
+func getProfileBytes(client *http.Client, p *registryProfile, path string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, p.registryURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	p.setReadToken(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, registryError(resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+func init() {
+	rootCmd.AddCommand(promoteCmd)
+
+	promoteCmd.Flags().StringVar(&promoteFrom, "from", "", "Profile of the registry to promote from (required)")
+	promoteCmd.Flags().StringVar(&promoteTo, "to", "", "Profile of the registry to promote to (required)")
+	promoteCmd.Flags().BoolVar(&promoteAllowUnsigned, "allow-unsigned", false, "Promote versions the source registry didn't sign")
+	_ = promoteCmd.MarkFlagRequired("from")
+	_ = promoteCmd.MarkFlagRequired("to")
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/provenance"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPromoteVersion(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	artifact := []byte("zip bytes")
+	sum := sha256.Sum256(artifact)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	publishedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	origin := provenance.Source{Registry: "https://dev.example.com", Module: "acme/user", Version: "v1.0.0", Digest: digest, PublishedAt: publishedAt.Add(-time.Hour)}
+
+	meta := apitypes.ModuleVersionResponse{
+		Version: "v1.0.0", ArtifactDigest: digest, CreatedAt: publishedAt,
+		Signature:  &apitypes.VersionSignature{Algorithm: "ed25519", KeyID: provenance.KeyID(pub), Signature: provenance.Sign(key, "acme/user", "v1.0.0", digest)},
+		Provenance: []provenance.Source{origin},
+	}
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer staging-read", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/v1/modules/acme/user/v1.0.0":
+			_ = json.NewEncoder(w).Encode(meta)
+		case "/api/v1/modules/acme/user/v1.0.0/artifact":
+			_, _ = w.Write(artifact)
+		case apitypes.SigningKeysPath:
+			_ = json.NewEncoder(w).Encode(apitypes.SigningKeysResponse{Keys: []apitypes.SigningKey{
+				{KeyID: provenance.KeyID(pub), Algorithm: "ed25519", PublicKey: base64.StdEncoding.EncodeToString(pub), Current: true},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer source.Close()
+
+	var published *apitypes.ModuleVersionResponse
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/modules/acme/user/v1.0.0", r.URL.Path)
+		if r.Method == http.MethodGet {
+			_ = json.NewEncoder(w).Encode(published)
+			return
+		}
+		assert.Equal(t, "Bearer prod-token", r.Header.Get("Authorization"))
+		if published != nil {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		file, _, err := r.FormFile("artifact")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, artifact, data)
+		var chain []provenance.Source
+		require.NoError(t, json.Unmarshal([]byte(r.Header.Get(apitypes.ProvenanceHeader)), &chain))
+		published = &apitypes.ModuleVersionResponse{Version: "v1.0.0", ArtifactDigest: digest, Provenance: chain}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer target.Close()
+
+	from := &registryProfile{name: "staging", registryURL: source.URL, readToken: "staging-read"}
+	to := &registryProfile{name: "prod", registryURL: target.URL, apiToken: "prod-token"}
+	promote := func(allowUnsigned bool) (*promotion, error) {
+		return promoteVersion(http.DefaultClient, from, to, "acme", "user", "v1.0.0", allowUnsigned, zap.NewNop())
+	}
+
+	// The chain grows by the source, which signed the version
+	result, err := promote(false)
+	require.NoError(t, err)
+	assert.True(t, result.created)
+	require.Len(t, result.chain, 2)
+	assert.Equal(t, origin, result.chain[0])
+	assert.Equal(t, source.URL, result.chain[1].Registry)
+	assert.Equal(t, publishedAt, result.chain[1].PublishedAt.UTC())
+	assert.Equal(t, meta.Signature.Signature, result.chain[1].Signature)
+	assert.Equal(t, result.chain, published.Provenance)
+
+	// Promoting again is a no-op, but not over another artifact
+	result, err = promote(false)
+	require.NoError(t, err)
+	assert.False(t, result.created)
+	published.ArtifactDigest = "sha256:other"
+	_, err = promote(false)
+	assert.Equal(t, ExitConflict, exitCode(err))
+	published = nil
+
+	// Signatures that don't verify, and unsigned versions without --allow-unsigned, are refused
+	meta.Signature.Signature = provenance.Sign(key, "acme/user", "v1.0.1", digest)
+	_, err = promote(true)
+	assert.Equal(t, ExitValidation, exitCode(err))
+	meta.Signature = nil
+	_, err = promote(false)
+	assert.Equal(t, ExitValidation, exitCode(err))
+	assert.ErrorContains(t, err, "--allow-unsigned")
+	result, err = promote(true)
+	require.NoError(t, err)
+	assert.Empty(t, result.chain[1].Signature)
+	published = nil
+
+	// So are artifacts that don't match the listed digest
+	meta.ArtifactDigest = "sha256:" + hex.EncodeToString(make([]byte, 32))
+	_, err = promote(true)
+	assert.Equal(t, ExitValidation, exitCode(err))
+	assert.ErrorContains(t, err, "has digest "+digest)
+	assert.Nil(t, published)
+}
@@ -13,217 +13,250 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
-	"github.com/Suhaibinator/SProto/internal/api"
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
 var (
-	publishModuleName string
-	publishVersion    string
+	publishModuleName       string
+	publishVersion          string
+	publishDryRun           bool
+	publishValidateOnServer bool
+	publishPublisher        string
+	publishBranch           string
+	publishFrom             string
+	publishVisibility       string
+	publishAsync            bool
+
+	publishYes bool
 )
 
 // publishCmd represents the publish command
 var publishCmd = &cobra.Command{
-	Use:   "publish <directory>",
+	Use:   "publish <directory|-> | --from <version>",
 	Short: "Publish a new module version artifact",
 	Long: `Zips the contents of the specified directory (containing .proto files),
 calculates its SHA256 digest, and uploads it to the registry as a new module version.
+The zip is packed in the registry's canonical form (sorted entries, fixed timestamps),
+so the same files always produce the same digest.
 
-Requires --module and --version flags.
+Pass "-" instead of a directory to read a ready-made zip archive from stdin.
+
+With --from and no directory, the new version is created from the artifact of an
+already published version of the module (e.g. to promote a release candidate);
+nothing is uploaded and the digest stays the same.
+
+--visibility sets who may read the module when the publish creates it; use
+'protoreg-cli visibility' to change it later.
+
+With --async the registry accepts the upload right away and runs its checks (scan,
+lint, policies) in the background; the command polls the publish operation, prints
+each stage as it is reached and reports the outcome like a synchronous publish. Use it
+when heavy validation would outlast proxy timeouts.
+
+Before publishing, a summary (module, version, file count, size, digest and registry) is
+shown and confirmation is asked for. --yes skips the question, as does running in CI (the
+CI environment variable is set); other runs without a terminal must pass --yes.
+
+--module and --version default to the name and version in the directory's sproto.yaml
+(the current directory's with "-" or --from); flags take precedence. The namespace can be
+omitted from --module if a default namespace is configured ('protoreg-cli configure').
 Authentication via API token is required.
 
-Example:
-  protoreg-cli publish ./path/to/protos --module mycompany/user --version v1.0.0`,
-	Args: cobra.ExactArgs(1), // Requires directory path
-	Run: func(cmd *cobra.Command, args []string) {
+Examples:
+  protoreg-cli publish ./path/to/protos --module mycompany/user --version v1.0.0
+  protoreg-cli publish ./path/to/protos     # module and version from ./path/to/protos/sproto.yaml
+  git archive --format=zip HEAD:protos | protoreg-cli publish - --module mycompany/user --version v1.0.0 --yes
+  protoreg-cli publish --module mycompany/user --version v1.0.0 --from v1.0.0-rc.2`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if publishFrom != "" {
+			return cobra.NoArgs(cmd, args) // The artifact comes from the --from version
+		}
+		return cobra.ExactArgs(1)(cmd, args) // Requires directory path or "-"
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
 		log := GetLogger()
-		registryURL := viper.GetString("registry_url")
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
 		apiToken := viper.GetString("api_token") // Get token from viper (flag > env > config)
+		if !publishDryRun || publishValidateOnServer || publishFrom != "" {
+			if apiToken, err = requireAPIToken(); err != nil { // Only a local dry run works without it
+				return err
+			}
+		}
 
-		if registryURL == "" {
-			log.Fatal("Registry URL is not configured.")
+		// --- Module and Version ---
+		// Missing flags are taken from the sproto.yaml in the directory (the current directory for stdin or --from)
+		manifestDir := "."
+		if len(args) == 1 && args[0] != "-" {
+			manifestDir = args[0]
 		}
-		if apiToken == "" {
-			log.Fatal("API token is required for publishing. Use --api-token flag, PROTOREG_API_TOKEN env var, or 'protoreg-cli configure'.")
+		m, err := loadModuleManifest(manifestDir)
+		if err != nil {
+			return fmt.Errorf("failed to read manifest: %w", err)
 		}
+		publishModuleName, publishVersion = applyManifestDefaults(m, publishModuleName, publishVersion, log)
 		if publishModuleName == "" {
-			log.Fatal("--module flag is required")
+			return exitErrorf(ExitUsage, "--module flag is required (or a name in sproto.yaml)")
 		}
 		if publishVersion == "" {
-			log.Fatal("--version flag is required")
-		}
-
-		protoDir := args[0]
-
-		// --- Validate Inputs ---
-		dirInfo, err := os.Stat(protoDir)
-		if err != nil {
-			if os.IsNotExist(err) {
-				log.Fatal("Input directory does not exist", zap.String("path", protoDir))
-			}
-			log.Fatal("Failed to stat input directory", zap.String("path", protoDir), zap.Error(err))
-		}
-		if !dirInfo.IsDir() {
-			log.Fatal("Input path is not a directory", zap.String("path", protoDir))
+			return exitErrorf(ExitUsage, "--version flag is required (or a version in sproto.yaml)")
 		}
 
 		parts := strings.SplitN(publishModuleName, "/", 2)
 		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-			log.Fatal("Invalid module name format. Expected 'namespace/module_name'.", zap.String("module", publishModuleName))
+			return exitErrorf(ExitValidation, "invalid module name format %q: expected 'namespace/module_name'", publishModuleName)
 		}
 		namespace := parts[0]
 		moduleName := parts[1]
+		// Same naming rules as the registry, so invalid names fail before anything is zipped or uploaded
+		if err := validation.ValidateNamespace(namespace); err != nil {
+			return exitErrorf(ExitValidation, "invalid namespace: %w", err)
+		}
+		if err := validation.ValidateModuleName(moduleName); err != nil {
+			return exitErrorf(ExitValidation, "invalid module name: %w", err)
+		}
 
 		semVer, err := semver.NewVersion(publishVersion)
 		if err != nil {
-			log.Fatal("Invalid semantic version format for --version flag", zap.String("version", publishVersion), zap.Error(err))
+			return exitErrorf(ExitValidation, "invalid semantic version format for --version flag %q: %w", publishVersion, err)
 		}
 		// Ensure 'v' prefix
 		versionStr := "v" + semVer.String()
 
-		// --- Zip Directory & Calculate Hash ---
-		log.Info("Zipping directory contents", zap.String("directory", protoDir))
-		zipBuffer := new(bytes.Buffer)
-		hasher := sha256.New()
-		// Create a multiwriter to write to both the zip buffer and the hasher
-		multiWriter := io.MultiWriter(zipBuffer, hasher)
-		zipWriter := zip.NewWriter(multiWriter)
-
-		err = filepath.Walk(protoDir, func(filePath string, info os.FileInfo, err error) error {
-			if err != nil {
-				return fmt.Errorf("error accessing path %q: %w", filePath, err)
-			}
-
-			// Skip the root directory itself
-			if filePath == protoDir {
-				return nil
-			}
+		// --- Republish From an Existing Version ---
+		if publishFrom != "" {
+			return republishFromVersion(registryURL, namespace, moduleName, versionStr, apiToken, log)
+		}
 
-			// Create a relative path for the file header
-			relPath, err := filepath.Rel(protoDir, filePath)
+		// --- Build Artifact ---
+		protoDir := args[0]
+		var zipBuffer *bytes.Buffer
+		if protoDir == "-" {
+			// Read a ready-made zip from stdin, e.g. `git archive --format=zip ... | protoreg-cli publish - ...`
+			log.Info("Reading artifact zip from stdin")
+			zipBuffer, err = readZipFromStdin(os.Stdin)
 			if err != nil {
-				return fmt.Errorf("failed to get relative path for %q: %w", filePath, err)
+				return fmt.Errorf("failed to read artifact from stdin: %w", err)
 			}
-			// Use forward slashes for zip header names
-			headerName := filepath.ToSlash(relPath)
-
-			// Get header from file info
-			header, err := zip.FileInfoHeader(info)
+		} else {
+			// --- Validate Inputs ---
+			dirInfo, err := os.Stat(protoDir)
 			if err != nil {
-				return fmt.Errorf("failed to create zip header for %q: %w", filePath, err)
-			}
-			header.Name = headerName
-			header.Method = zip.Deflate // Use compression
-
-			// If it's a directory, add the trailing slash
-			if info.IsDir() {
-				header.Name += "/"
-				// No need to write content for directories
-				_, err = zipWriter.CreateHeader(header)
-				if err != nil {
-					return fmt.Errorf("failed to write zip directory header for %q: %w", headerName, err)
+				if os.IsNotExist(err) {
+					return exitErrorf(ExitUsage, "input directory %s does not exist", protoDir)
 				}
-				log.Debug("Added directory to zip", zap.String("path", headerName))
-				return nil // Don't try to open/copy directory content
+				return fmt.Errorf("failed to stat input directory %s: %w", protoDir, err)
 			}
-
-			// It's a file, create the header
-			writer, err := zipWriter.CreateHeader(header)
-			if err != nil {
-				return fmt.Errorf("failed to write zip file header for %q: %w", headerName, err)
-			}
-
-			// Open the original file
-			file, err := os.Open(filePath)
-			if err != nil {
-				return fmt.Errorf("failed to open file %q: %w", filePath, err)
+			if !dirInfo.IsDir() {
+				return exitErrorf(ExitUsage, "input path %s is not a directory", protoDir)
 			}
-			defer file.Close()
 
-			// Copy the file content into the zip writer
-			_, err = io.Copy(writer, file)
+			log.Info("Zipping directory contents", zap.String("directory", protoDir))
+			zipBuffer, err = zipDirectory(protoDir, log)
 			if err != nil {
-				return fmt.Errorf("failed to copy file content for %q: %w", headerName, err)
+				return fmt.Errorf("failed during directory walk/zip creation: %w", err)
 			}
-			log.Debug("Added file to zip", zap.String("path", headerName))
-			return nil
-		})
-
-		if err != nil {
-			log.Fatal("Failed during directory walk/zip creation", zap.Error(err))
 		}
 
-		// Close the zip writer *before* getting the hash
-		err = zipWriter.Close()
+		// Re-pack into the registry's canonical form, so the digest printed here is the one the registry records
+		canonical, err := artifact.Canonicalize(zipBuffer.Bytes())
 		if err != nil {
-			log.Fatal("Failed to close zip writer", zap.Error(err))
+			return fmt.Errorf("failed to re-pack artifact: %w", err)
 		}
+		zipBuffer = bytes.NewBuffer(canonical)
 
 		// Get the final hash
-		artifactDigestHex := hex.EncodeToString(hasher.Sum(nil))
-		log.Info("Artifact zipped and digest calculated", zap.String("sha256", artifactDigestHex))
+		digest := sha256.Sum256(zipBuffer.Bytes())
+		artifactDigestHex := hex.EncodeToString(digest[:])
+		log.Info("Artifact prepared and digest calculated", zap.String("sha256", artifactDigestHex), zap.Int("size", zipBuffer.Len()))
 
-		// --- Prepare HTTP Request ---
-		// Use the zipBuffer containing the zipped data
-		body := &bytes.Buffer{}
-		multipartWriter := multipart.NewWriter(body)
+		// Construct URL
+		encodedNamespace := url.PathEscape(namespace)
+		encodedModuleName := url.PathEscape(moduleName)
+		encodedVersion := url.PathEscape(versionStr)
+		targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s", strings.TrimSuffix(registryURL, "/"), encodedNamespace, encodedModuleName, encodedVersion)
 
-		// Create form file field
-		part, err := multipartWriter.CreateFormFile("artifact", fmt.Sprintf("%s.zip", versionStr))
-		if err != nil {
-			log.Fatal("Failed to create form file part", zap.Error(err))
+		// --- Dry Run ---
+		if publishDryRun {
+			printDryRunSummary(zipBuffer.Bytes(), namespace, moduleName, versionStr, artifactDigestHex, targetURL)
+			if publishValidateOnServer {
+				return validateOnServer(targetURL, versionStr, zipBuffer.Bytes(), apiToken, log)
+			}
+			return nil
 		}
 
-		// Write zip data to the form file field
-		_, err = io.Copy(part, zipBuffer) // Copy from the zipBuffer
+		// --- Confirm ---
+		files, err := zipFileNames(zipBuffer.Bytes())
 		if err != nil {
-			log.Fatal("Failed to write zip data to multipart form", zap.Error(err))
+			return fmt.Errorf("failed to read artifact: %w", err)
 		}
-
-		// Close multipart writer to finalize boundary
-		err = multipartWriter.Close()
-		if err != nil {
-			log.Fatal("Failed to close multipart writer", zap.Error(err))
+		summary := publishSummary{
+			Module:   namespace + "/" + moduleName,
+			Version:  versionStr,
+			Registry: registryURL,
+			Files:    len(files),
+			Size:     zipBuffer.Len(),
+			Digest:   "sha256:" + artifactDigestHex,
+		}
+		if err := confirmPublish(summary, publishYes || ciMode(), isTerminal(os.Stdin), os.Stdin, os.Stderr); err != nil {
+			return err
 		}
 
-		// Construct URL
-		encodedNamespace := url.PathEscape(namespace)
-		encodedModuleName := url.PathEscape(moduleName)
-		encodedVersion := url.PathEscape(versionStr)
-		targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s", strings.TrimSuffix(registryURL, "/"), encodedNamespace, encodedModuleName, encodedVersion)
+		// --- Prepare HTTP Request ---
+		query := url.Values{}
+		if publishVisibility != "" {
+			query.Set("visibility", publishVisibility) // Only applies if this creates the module
+		}
+		if publishAsync {
+			query.Set("async", "true") // 202 with an operation to poll
+		}
+		if len(query) > 0 {
+			targetURL += "?" + query.Encode()
+		}
 		log.Info("Publishing artifact", zap.String("url", targetURL))
-
-		req, err := http.NewRequest("POST", targetURL, body)
+		req, err := newArtifactUploadRequest(targetURL, versionStr, zipBuffer.Bytes(), apiToken)
 		if err != nil {
-			log.Fatal("Failed to create request", zap.Error(err))
+			return fmt.Errorf("failed to create request: %w", err)
 		}
 
-		// Set headers
-		req.Header.Set("Authorization", "Bearer "+apiToken)
-		req.Header.Set("Content-Type", multipartWriter.FormDataContentType())
-
 		// --- Execute Request ---
 		client := &http.Client{}
 		resp, err := client.Do(req)
 		if err != nil {
-			log.Fatal("Failed to execute request", zap.Error(err))
+			return fmt.Errorf("failed to execute request: %w", err)
 		}
 		defer resp.Body.Close()
 
 		respBodyBytes, err := io.ReadAll(resp.Body)
 		if err != nil {
-			log.Fatal("Failed to read response body", zap.Error(err))
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		// --- Asynchronous Publish ---
+		// Wait for the operation; its result is the response the synchronous publish would have sent
+		statusCode := resp.StatusCode
+		if publishAsync && statusCode == http.StatusAccepted {
+			if statusCode, respBodyBytes, err = waitForOperation(client, registryURL, respBodyBytes, apiToken, log); err != nil {
+				return err
+			}
 		}
 
 		// --- Handle Response ---
-		if resp.StatusCode == http.StatusCreated {
-			var successResp api.PublishModuleVersionResponse // Use struct from api package if accessible, otherwise redefine
+		if statusCode == http.StatusCreated {
+			var successResp apitypes.PublishModuleVersionResponse // Use struct from api package if accessible, otherwise redefine
 			if err := json.Unmarshal(respBodyBytes, &successResp); err != nil {
 				log.Error("Published successfully, but failed to parse success response", zap.Error(err), zap.ByteString("body", respBodyBytes))
 				fmt.Printf("Successfully published %s/%s@%s (Digest: sha256:%s)\n", namespace, moduleName, versionStr, artifactDigestHex)
@@ -231,23 +264,363 @@ Example:
 				fmt.Printf("Successfully published %s/%s@%s\n", successResp.Namespace, successResp.ModuleName, successResp.Version)
 				fmt.Printf("  Digest: %s\n", successResp.ArtifactDigest)
 				fmt.Printf("  Created At: %s\n", successResp.CreatedAt.Format(time.RFC3339))
+				if len(successResp.Syntaxes) > 0 {
+					fmt.Printf("  Syntax: %s\n", strings.Join(successResp.Syntaxes, ", "))
+				}
+				if successResp.NoSchemaChange {
+					fmt.Printf("  No schema change from %s (comments/formatting only)\n", successResp.NoSchemaChangeFrom)
+				}
 			}
 		} else {
-			log.Error("Publish request failed", zap.Int("status_code", resp.StatusCode))
-			handleApiError(resp.StatusCode, respBodyBytes, log) // Use the helper
-			os.Exit(1)
+			printErrorDetails(respBodyBytes)
+			return fmt.Errorf("publish request failed: %w", registryError(statusCode, respBodyBytes))
 		}
+		return nil
 	},
 }
 
+// waitForOperation waits for the operation of an asynchronous publish (the 202 response body accepted)
+// to finish. Returns the status code and body the synchronous publish would have responded with.
+func waitForOperation(client *http.Client, registryURL string, accepted []byte, apiToken string, log *zap.Logger) (int, []byte, error) {
+	var op apitypes.OperationResponse
+	if err := json.Unmarshal(accepted, &op); err != nil || op.ID == "" {
+		return 0, nil, fmt.Errorf("failed to parse publish operation: %v", err)
+	}
+	fmt.Printf("Publish queued (operation %s)\n", op.ID)
+	op, err := pollOperation(client, registryURL, apiToken, op, log)
+	if err != nil {
+		return 0, nil, err
+	}
+	status, result := operationResult(op)
+	return status, result, nil
+}
+
+// republishFromVersion asks the registry to create versionStr from the artifact of the --from version
+// (POST .../{version}?from=...). With --dry-run, the registry only runs its checks (validate_only).
+func republishFromVersion(registryURL, namespace, moduleName, versionStr, apiToken string, log *zap.Logger) error {
+	fromVer, err := semver.NewVersion(publishFrom)
+	if err != nil {
+		return exitErrorf(ExitValidation, "invalid semantic version format for --from flag %q: %w", publishFrom, err)
+	}
+	fromStr := "v" + fromVer.String()
+
+	if !publishDryRun {
+		summary := publishSummary{Module: namespace + "/" + moduleName, Version: versionStr, Registry: registryURL, From: fromStr}
+		if err := confirmPublish(summary, publishYes || ciMode(), isTerminal(os.Stdin), os.Stdin, os.Stderr); err != nil {
+			return err
+		}
+	}
+
+	query := url.Values{"from": {fromStr}}
+	if publishDryRun {
+		query.Set("validate_only", "true")
+	}
+	targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s?%s", strings.TrimSuffix(registryURL, "/"),
+		url.PathEscape(namespace), url.PathEscape(moduleName), url.PathEscape(versionStr), query.Encode())
+	log.Info("Republishing artifact", zap.String("url", targetURL))
+
+	req, err := http.NewRequest("POST", targetURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiToken)
+	if publishPublisher != "" {
+		req.Header.Set(apitypes.PublisherHeader, publishPublisher)
+	}
+	if publishBranch != "" {
+		req.Header.Set(apitypes.BranchHeader, publishBranch)
+	}
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	switch {
+	case publishDryRun && resp.StatusCode == http.StatusOK:
+		var validated apitypes.ValidatePublishResponse
+		_ = json.Unmarshal(respBodyBytes, &validated)
+		fmt.Printf("Dry run: %s/%s@%s would be republished from %s\n", namespace, moduleName, versionStr, fromStr)
+		fmt.Printf("  Digest: %s\n", validated.ArtifactDigest)
+	case !publishDryRun && resp.StatusCode == http.StatusCreated:
+		var successResp apitypes.PublishModuleVersionResponse
+		if err := json.Unmarshal(respBodyBytes, &successResp); err != nil {
+			log.Error("Republished successfully, but failed to parse success response", zap.Error(err), zap.ByteString("body", respBodyBytes))
+			fmt.Printf("Successfully republished %s/%s@%s from %s\n", namespace, moduleName, versionStr, fromStr)
+			return nil
+		}
+		fmt.Printf("Successfully republished %s/%s@%s from %s\n", successResp.Namespace, successResp.ModuleName, successResp.Version, successResp.RepublishedFrom)
+		fmt.Printf("  Digest: %s\n", successResp.ArtifactDigest)
+		fmt.Printf("  Created At: %s\n", successResp.CreatedAt.Format(time.RFC3339))
+		if successResp.NoSchemaChange {
+			fmt.Printf("  No schema change from %s (comments/formatting only)\n", successResp.NoSchemaChangeFrom)
+		}
+	default:
+		printErrorDetails(respBodyBytes)
+		return fmt.Errorf("republish request failed: %w", registryError(resp.StatusCode, respBodyBytes))
+	}
+	return nil
+}
+
+// newArtifactUploadRequest builds the multipart POST request used to upload an artifact.
+func newArtifactUploadRequest(targetURL, versionStr string, zipData []byte, apiToken string) (*http.Request, error) {
+	body := &bytes.Buffer{}
+	multipartWriter := multipart.NewWriter(body)
+
+	// Create form file field
+	part, err := multipartWriter.CreateFormFile("artifact", fmt.Sprintf("%s.zip", versionStr))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file part: %w", err)
+	}
+
+	// Write zip data to the form file field
+	if _, err := part.Write(zipData); err != nil {
+		return nil, fmt.Errorf("failed to write zip data to multipart form: %w", err)
+	}
+
+	// Close multipart writer to finalize boundary
+	if err := multipartWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", targetURL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set headers
+	req.Header.Set("Authorization", "Bearer "+apiToken)
+	req.Header.Set("Content-Type", multipartWriter.FormDataContentType())
+	// The registry rejects the upload if what it receives doesn't match
+	req.Header.Set(apitypes.ArtifactDigestHeader, uploadDigest(zipData))
+	// Context for the registry's publish policies
+	if publishPublisher != "" {
+		req.Header.Set(apitypes.PublisherHeader, publishPublisher)
+	}
+	if publishBranch != "" {
+		req.Header.Set(apitypes.BranchHeader, publishBranch)
+	}
+	return req, nil
+}
+
+// uploadDigest returns the value of the apitypes.ArtifactDigestHeader for an upload.
+func uploadDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// printDryRunSummary prints what a publish would upload without contacting the registry.
+func printDryRunSummary(zipData []byte, namespace, moduleName, versionStr, digestHex, targetURL string) {
+	fmt.Printf("Dry run: %s/%s@%s would be published\n", namespace, moduleName, versionStr)
+	fmt.Printf("  Target URL: %s\n", targetURL)
+	fmt.Printf("  Digest: sha256:%s\n", digestHex)
+	fmt.Printf("  Size: %d bytes\n", len(zipData))
+
+	files, err := zipFileNames(zipData)
+	if err != nil {
+		fmt.Printf("  Files: (could not read archive: %v)\n", err)
+		return
+	}
+	fmt.Printf("  Files (%d):\n", len(files))
+	for _, name := range files {
+		fmt.Printf("    %s\n", name)
+	}
+}
+
+// validateOnServer sends the artifact with ?validate_only=true so the registry runs its publish checks
+// (conflict, virus scan, ...) without persisting anything. Returns an error if validation fails.
+func validateOnServer(targetURL, versionStr string, zipData []byte, apiToken string, log *zap.Logger) error {
+	req, err := newArtifactUploadRequest(targetURL+"?validate_only=true", versionStr, zipData, apiToken)
+	if err != nil {
+		return fmt.Errorf("failed to create validation request: %w", err)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute validation request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Println("Server validation: FAILED")
+		printErrorDetails(respBodyBytes)
+		return registryError(resp.StatusCode, respBodyBytes)
+	}
+
+	var validateResp apitypes.ValidatePublishResponse
+	if err := json.Unmarshal(respBodyBytes, &validateResp); err != nil {
+		return fmt.Errorf("failed to parse validation response: %w", err)
+	}
+	fmt.Println("Server validation: OK")
+	fmt.Printf("  Server Digest: %s\n", validateResp.ArtifactDigest)
+	fmt.Printf("  Scan Status: %s\n", validateResp.ScanStatus)
+	return nil
+}
+
+// zipDirectory zips the contents of dir (paths relative to dir, forward slashes) into a buffer. Symlinks
+// to files are stored as regular files.
+func zipDirectory(protoDir string, log *zap.Logger) (*bytes.Buffer, error) {
+	zipBuffer := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(zipBuffer)
+
+	err := filepath.Walk(protoDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %q: %w", filePath, err)
+		}
+
+		// Skip the root directory itself
+		if filePath == protoDir {
+			return nil
+		}
+
+		// Create a relative path for the file header
+		relPath, err := filepath.Rel(protoDir, filePath)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for %q: %w", filePath, err)
+		}
+		// Use forward slashes for zip header names
+		headerName := filepath.ToSlash(relPath)
+
+		// Symlinks are stored as the files they point to; links to directories aren't followed
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Stat(filePath)
+			if err != nil {
+				return fmt.Errorf("failed to resolve symlink %q: %w", filePath, err)
+			}
+			if target.IsDir() {
+				log.Warn("Skipping symlink to a directory", zap.String("path", headerName))
+				return nil
+			}
+			info = target
+		}
+
+		// Get header from file info
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return fmt.Errorf("failed to create zip header for %q: %w", filePath, err)
+		}
+		header.Name = headerName
+		header.Method = zip.Deflate // Use compression
+
+		// If it's a directory, add the trailing slash
+		if info.IsDir() {
+			header.Name += "/"
+			// No need to write content for directories
+			_, err = zipWriter.CreateHeader(header)
+			if err != nil {
+				return fmt.Errorf("failed to write zip directory header for %q: %w", headerName, err)
+			}
+			log.Debug("Added directory to zip", zap.String("path", headerName))
+			return nil // Don't try to open/copy directory content
+		}
+
+		// It's a file, create the header
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("failed to write zip file header for %q: %w", headerName, err)
+		}
+
+		// Open the original file
+		file, err := os.Open(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open file %q: %w", filePath, err)
+		}
+		defer file.Close()
+
+		// Copy the file content into the zip writer
+		_, err = io.Copy(writer, file)
+		if err != nil {
+			return fmt.Errorf("failed to copy file content for %q: %w", headerName, err)
+		}
+		log.Debug("Added file to zip", zap.String("path", headerName))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Close the zip writer to flush the central directory
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close zip writer: %w", err)
+	}
+	return zipBuffer, nil
+}
+
+// readZipFromStdin reads a complete zip archive from r and checks that it is a readable zip.
+func readZipFromStdin(r io.Reader) (*bytes.Buffer, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no data received on stdin")
+	}
+	if _, err := zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+		return nil, fmt.Errorf("stdin does not contain a valid zip archive: %w", err)
+	}
+	return bytes.NewBuffer(data), nil
+}
+
+// printErrorDetails prints the validation issues or policy violations carried by a publish error response, if any.
+func printErrorDetails(body []byte) {
+	var failed apitypes.ValidationFailedResponse
+	if err := json.Unmarshal(body, &failed); err == nil && len(failed.Issues) > 0 {
+		fmt.Println("Validation issues:")
+		printValidationIssues(os.Stdout, failed.Issues)
+	}
+	var denied apitypes.PolicyDeniedResponse
+	if err := json.Unmarshal(body, &denied); err == nil && len(denied.Violations) > 0 {
+		fmt.Println("Policy violations:")
+		for _, v := range denied.Violations {
+			fmt.Printf("  %s: %s\n", v.Policy, v.Message)
+		}
+	}
+}
+
+// printValidationIssues prints the issues of a failed validation as a table, one row per issue. Lines are
+// line:column when the column is known.
+func printValidationIssues(out io.Writer, issues []apitypes.ValidationIssue) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  FILE\tLINE\tRULE\tSEVERITY\tMESSAGE")
+	for _, issue := range issues {
+		line := "-"
+		if issue.Line > 0 {
+			line = strconv.Itoa(issue.Line)
+			if issue.Column > 0 {
+				line += ":" + strconv.Itoa(issue.Column)
+			}
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", orDash(issue.File), line, issue.Rule, issue.Severity, issue.Message)
+	}
+	_ = tw.Flush()
+}
+
 func init() {
 	rootCmd.AddCommand(publishCmd)
 
-	// Required flags for publish command
-	publishCmd.Flags().StringVarP(&publishModuleName, "module", "m", "", "Full module name (namespace/name) (required)")
-	publishCmd.Flags().StringVarP(&publishVersion, "version", "v", "", "Semantic version for the artifact (e.g., v1.2.3) (required)")
-	_ = publishCmd.MarkFlagRequired("module")
-	_ = publishCmd.MarkFlagRequired("version")
+	// Module and version, required unless set in sproto.yaml (checked in Run)
+	publishCmd.Flags().StringVarP(&publishModuleName, "module", "m", "", "Full module name (namespace/name) (default: the name in sproto.yaml)")
+	publishCmd.Flags().StringVarP(&publishVersion, "version", "v", "", "Semantic version for the artifact (e.g., v1.2.3) (default: the version in sproto.yaml)")
+
+	publishCmd.Flags().BoolVar(&publishDryRun, "dry-run", false, "Validate and print the digest, file list and target URL without uploading")
+	publishCmd.Flags().BoolVar(&publishValidateOnServer, "validate-on-server", false, "With --dry-run, also run the registry's publish checks (nothing is persisted)")
+	publishCmd.Flags().StringVar(&publishPublisher, "publisher", "", "Publisher identity reported to the registry's publish policies")
+	publishCmd.Flags().StringVar(&publishBranch, "branch", "", "Source branch reported to the registry's publish policies")
+	publishCmd.Flags().StringVar(&publishVisibility, "visibility", "", "Visibility of the module if this publish creates it: public, internal or private (default: the registry's default)")
+	publishCmd.Flags().StringVar(&publishFrom, "from", "", "Create the version from this published version's artifact instead of uploading (no directory argument)")
+	publishCmd.Flags().BoolVar(&publishAsync, "async", false, "Let the registry process the publish in the background and poll until it has finished")
+	publishCmd.Flags().BoolVarP(&publishYes, "yes", "y", false, "Publish without asking for confirmation (implied when the CI environment variable is set)")
 
 	// Inherits --registry-url and --api-token from root persistent flags
 }
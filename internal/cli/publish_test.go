@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPrintValidationIssues(t *testing.T) {
+	var out bytes.Buffer
+	printValidationIssues(&out, []apitypes.ValidationIssue{
+		{File: "acme/orders/v1/orders.proto", Line: 2, Column: 9, Rule: apitypes.RuleCompile, Severity: apitypes.SeverityError, Message: "syntax error: unexpected '{'"},
+		{File: "acme/orders/v1/orders.proto", Line: 7, Rule: "lint:FIELD_LOWER_SNAKE_CASE", Severity: apitypes.SeverityError, Message: "field acme.orders.v1.Order.itemName should be lower_snake_case"},
+		{Rule: "policy:main-only", Severity: apitypes.SeverityError, Message: "publish from main"},
+	})
+	assert.Equal(t, ""+
+		"  FILE                         LINE  RULE                         SEVERITY  MESSAGE\n"+
+		"  acme/orders/v1/orders.proto  2:9   compile                      error     syntax error: unexpected '{'\n"+
+		"  acme/orders/v1/orders.proto  7     lint:FIELD_LOWER_SNAKE_CASE  error     field acme.orders.v1.Order.itemName should be lower_snake_case\n"+
+		"  -                            -     policy:main-only             error     publish from main\n",
+		out.String())
+}
+
+func TestReadZipFromStdin(t *testing.T) {
+	var valid bytes.Buffer
+	zw := zip.NewWriter(&valid)
+	w, err := zw.Create("acme/v1/a.proto")
+	require.NoError(t, err)
+	_, err = io.WriteString(w, `syntax = "proto3";`)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr string
+	}{
+		{name: "zip", input: valid.Bytes()},
+		{name: "empty", input: nil, wantErr: "no data received on stdin"},
+		{name: "not a zip", input: []byte("syntax = \"proto3\";\n"), wantErr: "stdin does not contain a valid zip archive"},
+		{name: "truncated zip", input: valid.Bytes()[:valid.Len()/2], wantErr: "stdin does not contain a valid zip archive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := readZipFromStdin(bytes.NewReader(tt.input))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.input, buf.Bytes())
+		})
+	}
+}
+
+func TestZipDirectory(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, dir string)
+		want  map[string]string // Entry name -> content; directories end with '/'
+	}{
+		{
+			name: "nested directories and non-proto files",
+			setup: func(t *testing.T, dir string) {
+				writeTestFile(t, dir, "sproto.yaml", "name: acme/orders\n")
+				writeTestFile(t, dir, "acme/orders/v1/orders.proto", `syntax = "proto3";`)
+				writeTestFile(t, dir, "acme/orders/v1/README.md", "# Orders\n")
+				require.NoError(t, os.MkdirAll(filepath.Join(dir, "acme", "empty"), 0o755))
+			},
+			want: map[string]string{
+				"sproto.yaml":                 "name: acme/orders\n",
+				"acme/":                       "",
+				"acme/empty/":                 "",
+				"acme/orders/":                "",
+				"acme/orders/v1/":             "",
+				"acme/orders/v1/README.md":    "# Orders\n",
+				"acme/orders/v1/orders.proto": `syntax = "proto3";`,
+			},
+		},
+		{
+			name: "symlinks",
+			setup: func(t *testing.T, dir string) {
+				writeTestFile(t, dir, "acme/v1/a.proto", `syntax = "proto3";`)
+				symlinkOrSkip(t, filepath.Join("acme", "v1", "a.proto"), filepath.Join(dir, "link.proto"))
+				symlinkOrSkip(t, "acme", filepath.Join(dir, "linked-dir"))
+			},
+			want: map[string]string{
+				"acme/":           "",
+				"acme/v1/":        "",
+				"acme/v1/a.proto": `syntax = "proto3";`,
+				"link.proto":      `syntax = "proto3";`, // Stored as the file it points to
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.setup(t, dir)
+
+			buf, err := zipDirectory(dir, zap.NewNop())
+			require.NoError(t, err)
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			require.NoError(t, err)
+			got := map[string]string{}
+			for _, f := range zr.File {
+				assert.False(t, path.IsAbs(f.Name) || filepath.IsAbs(f.Name), "entry %q must be relative", f.Name)
+				assert.NotContains(t, f.Name, `\`, "entry %q must use forward slashes", f.Name)
+				assert.Zero(t, f.Mode()&os.ModeSymlink, "entry %q must not be a symlink", f.Name)
+				rc, err := f.Open()
+				require.NoError(t, err)
+				content, err := io.ReadAll(rc)
+				rc.Close()
+				require.NoError(t, err)
+				got[f.Name] = string(content)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// writeTestFile writes content to the slash-separated path rel under dir, creating its directories.
+func writeTestFile(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	p := filepath.Join(dir, filepath.FromSlash(rel))
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0o644))
+}
+
+// symlinkOrSkip creates a symlink, skipping the test where the platform doesn't allow it (e.g. Windows
+// without developer mode).
+func symlinkOrSkip(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+)
+
+// --- Import Rewriting ---
+
+// importRewrite maps a path prefix to another (fetch --rewrite-imports from=to). Prefixes are whole path
+// components; a "*" component matches any single component and is carried over to the first "*" of to
+// that isn't used yet.
+type importRewrite struct {
+	from []string // Components; empty matches every path
+	to   []string
+}
+
+// parseImportRewrites parses from=to mappings. Both sides are relative, slash-separated paths; either may
+// be empty (from: prefix every path, to: strip the prefix). When several mappings match a path, the one
+// with the longest from wins.
+func parseImportRewrites(specs []string) ([]importRewrite, error) {
+	rewrites := make([]importRewrite, 0, len(specs))
+	for _, spec := range specs {
+		from, to, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mapping %q: expected from=to", spec)
+		}
+		fromParts, err := rewriteComponents(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mapping %q: %w", spec, err)
+		}
+		toParts, err := rewriteComponents(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mapping %q: %w", spec, err)
+		}
+		if countWildcards(toParts) > countWildcards(fromParts) {
+			return nil, fmt.Errorf("invalid mapping %q: more '*' after '=' than before", spec)
+		}
+		rewrites = append(rewrites, importRewrite{from: fromParts, to: toParts})
+	}
+	sort.SliceStable(rewrites, func(i, j int) bool { return len(rewrites[i].from) > len(rewrites[j].from) })
+	return rewrites, nil
+}
+
+// rewriteComponents splits one side of a mapping into its path components.
+func rewriteComponents(p string) ([]string, error) {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil, nil
+	}
+	if strings.Contains(p, `\`) {
+		return nil, fmt.Errorf("%q must use forward slashes", p)
+	}
+	parts := strings.Split(p, "/")
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." {
+			return nil, fmt.Errorf("%q must be a relative path without '.' or '..' components", p)
+		}
+		if part != "*" && strings.Contains(part, "*") {
+			return nil, fmt.Errorf("%q: '*' must be a whole path component", p)
+		}
+	}
+	return parts, nil
+}
+
+// countWildcards returns the number of "*" components.
+func countWildcards(parts []string) int {
+	n := 0
+	for _, part := range parts {
+		if part == "*" {
+			n++
+		}
+	}
+	return n
+}
+
+// rewritePath applies the first matching mapping to a slash-separated path; paths no mapping matches are
+// returned unchanged.
+func rewritePath(name string, rewrites []importRewrite) string {
+	parts := strings.Split(name, "/")
+	for _, rw := range rewrites {
+		if len(rw.from) >= len(parts) { // The file name itself is never rewritten
+			continue
+		}
+		var captured []string
+		matched := true
+		for i, part := range rw.from {
+			if part == "*" {
+				captured = append(captured, parts[i])
+			} else if part != parts[i] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		rewritten := make([]string, 0, len(rw.to)+len(parts)-len(rw.from))
+		for _, part := range rw.to {
+			if part == "*" {
+				part, captured = captured[0], captured[1:]
+			}
+			rewritten = append(rewritten, part)
+		}
+		return path.Join(append(rewritten, parts[len(rw.from):]...)...)
+	}
+	return name
+}
+
+// importStatementPattern matches the path of an import statement at the start of a line.
+var importStatementPattern = regexp.MustCompile(`(?m)^(\s*import\s+(?:(?:public|weak)\s+)?")([^"]+)(")`)
+
+// rewriteImports applies the mappings to the import statements of a .proto file.
+func rewriteImports(content []byte, rewrites []importRewrite) []byte {
+	return importStatementPattern.ReplaceAllFunc(content, func(stmt []byte) []byte {
+		m := importStatementPattern.FindSubmatch(stmt)
+		return []byte(string(m[1]) + rewritePath(string(m[2]), rewrites) + string(m[3]))
+	})
+}
+
+// rewriteArtifact returns an archive of the files of zipData accepted by keep (nil: all), moved by the
+// mappings, with the imports of their .proto files rewritten the same way. Moving the files too keeps the
+// result compilable: an import rewritten to vendor/mycompany/user/v1/user.proto finds the file there.
+func rewriteArtifact(zipData []byte, keep func(name string) bool, rewrites []importRewrite) ([]byte, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive reader: %w", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zipReader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name, err := sanitizeZipEntryName(f.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file path in zip archive: %w", err)
+		}
+		if keep != nil && !keep(name) {
+			continue
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if path.Ext(name) == ".proto" {
+			content = rewriteImports(content, rewrites)
+		}
+		target := rewritePath(name, rewrites)
+		if _, ok := files[target]; ok {
+			return nil, fmt.Errorf("rewriting moves several files to %s", target)
+		}
+		files[target] = content
+	}
+	return artifact.Pack(files)
+}
+
+// readZipFile reads a zip entry into memory.
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in zip archive: %w", f.Name, err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s in zip archive: %w", f.Name, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseImportRewrites(t *testing.T) {
+	rewrites, err := parseImportRewrites([]string{"=vendor", "mycompany/*/v1/=mycompany/*", "mycompany/user=users"})
+	require.NoError(t, err)
+	// Longest prefix first
+	assert.Equal(t, []string{"mycompany", "*", "v1"}, rewrites[0].from)
+	assert.Equal(t, []string{"mycompany", "user"}, rewrites[1].from)
+	assert.Empty(t, rewrites[2].from)
+
+	for _, spec := range []string{"vendor", "../a=b", "a=/b/../c", `a\b=c`, "a*=b", "a=*", "a/*=*/*"} {
+		_, err := parseImportRewrites([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestRewritePath(t *testing.T) {
+	rewrites, err := parseImportRewrites([]string{"mycompany/*/v1=mycompany/*", "google=", "=vendor"})
+	require.NoError(t, err)
+	cases := map[string]string{
+		"mycompany/user/v1/user.proto":  "mycompany/user/user.proto",
+		"mycompany/user/v2/user.proto":  "vendor/mycompany/user/v2/user.proto",
+		"google/type/money.proto":       "type/money.proto",
+		"user.proto":                    "vendor/user.proto",
+		"mycompany/user/v1":             "vendor/mycompany/user/v1", // The file name itself is never rewritten
+		"mycompanyx/user/v1/user.proto": "vendor/mycompanyx/user/v1/user.proto",
+	}
+	for name, expected := range cases {
+		assert.Equal(t, expected, rewritePath(name, rewrites), name)
+	}
+	assert.Equal(t, "a/b.proto", rewritePath("a/b.proto", nil))
+}
+
+func TestRewriteArtifact(t *testing.T) {
+	zipData, err := artifact.Pack(map[string][]byte{
+		"mycompany/user/v1/user.proto": []byte("syntax = \"proto3\";\nimport \"mycompany/types/v1/types.proto\";\n  import public \"google/protobuf/empty.proto\";\n// import \"mycompany/user/v1/old.proto\";\n"),
+		"sproto.yaml":                  []byte("name: user\n"),
+	})
+	require.NoError(t, err)
+	rewrites, err := parseImportRewrites([]string{"mycompany/*/v1=vendor/mycompany/*"})
+	require.NoError(t, err)
+
+	rewritten, err := rewriteArtifact(zipData, layoutFilter(layoutFlat), rewrites)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	count, err := extractZip(rewritten, dir, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "the layout filter applies to the published paths")
+
+	content, err := os.ReadFile(filepath.Join(dir, "vendor", "mycompany", "user", "user.proto"))
+	require.NoError(t, err)
+	assert.Equal(t, "syntax = \"proto3\";\nimport \"vendor/mycompany/types/types.proto\";\n  import public \"google/protobuf/empty.proto\";\n// import \"mycompany/user/v1/old.proto\";\n", string(content))
+
+	// Mappings merging files are rejected
+	rewrites, err = parseImportRewrites([]string{"a=c", "b=c"})
+	require.NoError(t, err)
+	zipData, err = artifact.Pack(map[string][]byte{"a/x.proto": nil, "b/x.proto": nil})
+	require.NoError(t, err)
+	_, err = rewriteArtifact(zipData, nil, rewrites)
+	assert.ErrorContains(t, err, "several files to c/x.proto")
+}
@@ -17,6 +17,7 @@ var (
 	cfgFile     string // Path to config file (passed via flag)
 	registryURL string
 	apiToken    string
+	readToken   string
 	logLevel    string // Flag for log level
 	logger      *zap.Logger
 )
@@ -27,9 +28,15 @@ var rootCmd = &cobra.Command{
 	Short: "CLI client for the SProto Protobuf Registry",
 	Long: `protoreg-cli is a command-line tool to interact with the SProto registry,
 allowing you to publish and fetch Protobuf module artifacts.`,
+	// Commands report their failure by returning an error; Execute prints it and picks the exit code
+	SilenceErrors: true,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		// Initialize logger based on the log level flag
 		initLogger(logLevel)
+		// Warn when the registry reports that the API token is about to expire
+		installTokenExpiryWarning()
+		// Identify the CLI and its version to the registry (client inventory)
+		installUserAgent()
 	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
@@ -38,22 +45,77 @@ allowing you to publish and fetch Protobuf module artifacts.`,
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// Commands return their errors rather than exiting, so their deferred cleanups run; the process
+// exits here, with the code of the error (see exitcode.go). Unknown commands naming an extension run it
+// (see extensions.go).
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	if path, args, ok := findExtension(os.Args[1:]); ok {
+		os.Exit(runExtension(path, args))
+	}
+	trackCommandStart(rootCmd)
+	err := rootCmd.Execute()
+	if err == nil {
+		return
+	}
+	reportError(err)
+	if !commandStarted {
+		// Cobra rejected the command line (unknown command or flag, wrong number of arguments)
+		os.Exit(ExitUsage)
+	}
+	os.Exit(exitCode(err))
+}
+
+// commandStarted is set once cobra has validated the command line and runs a command.
+var commandStarted bool
+
+// trackCommandStart wraps the RunE of cmd and its subcommands to set commandStarted, so Execute can tell
+// cobra's command line errors (checked up to just before RunE, e.g. required flags) from the commands' own.
+func trackCommandStart(cmd *cobra.Command) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			commandStarted = true
+			cmd.SilenceUsage = true // The command line was fine; usage would only bury the error
+			return run(cmd, args)
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		trackCommandStart(sub)
+	}
+}
+
+// requireRegistryURL returns the configured registry URL, or a usage error if there is none.
+func requireRegistryURL() (string, error) {
+	registryURL := viper.GetString("registry_url")
+	if registryURL == "" {
+		return "", exitErrorf(ExitUsage, "registry URL is not configured; use the --registry-url flag, the PROTOREG_REGISTRY_URL env var, or 'protoreg-cli configure'")
+	}
+	return registryURL, nil
+}
+
+// requireAPIToken returns the configured API token, or an auth error if there is none.
+func requireAPIToken() (string, error) {
+	apiToken := viper.GetString("api_token")
+	if apiToken == "" {
+		return "", exitErrorf(ExitAuth, "API token is required; use the --api-token flag, the PROTOREG_API_TOKEN env var, or 'protoreg-cli configure'")
+	}
+	return apiToken, nil
 }
 
 func init() {
 	cobra.OnInitialize(initConfig) // Called after flags are parsed
+	rootCmd.Version = cliVersion() // protoreg-cli --version
 
 	// Persistent flags available to all subcommands
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/protoreg/config.yaml)")
 	rootCmd.PersistentFlags().StringVar(&registryURL, "registry-url", "", "Registry server URL (overrides config/env)")
 	rootCmd.PersistentFlags().StringVar(&apiToken, "api-token", "", "API token for authentication (overrides config/env)")
+	rootCmd.PersistentFlags().StringVar(&readToken, "read-token", "", "Read-only token for downloads and queries; defaults to the API token (overrides config/env)")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Set logging level (debug, info, warn, error)")
 
 	// Bind persistent flags to Viper
 	_ = viper.BindPFlag("registry_url", rootCmd.PersistentFlags().Lookup("registry-url"))
 	_ = viper.BindPFlag("api_token", rootCmd.PersistentFlags().Lookup("api-token"))
+	_ = viper.BindPFlag("read_token", rootCmd.PersistentFlags().Lookup("read-token"))
 	// Note: We don't bind cfgFile or logLevel to viper directly, they control viper/logger setup.
 }
 
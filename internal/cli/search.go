@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	searchKind   string
+	searchModule string
+	searchLimit  int
+)
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search <query>...",
+	Short: "Search modules, files, messages, services and their comments",
+	Long: `Runs a full-text search over the registry: module names and descriptions (sproto.yaml
+"description"), and the files, messages, enums, services, methods and fields of each
+module's newest version, with their leading comments. Matches in names rank above
+matches in comments. Matching words are shown between asterisks in the excerpts.
+
+All words must match, in any order and in any form ("refunding" finds "Refunds");
+put words in double quotes to match them as a phrase.
+
+Examples:
+  protoreg-cli search invoice
+  protoreg-cli search '"paid order"' --kind method
+  protoreg-cli search customer address --module mycompany/users --limit 50`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+
+		params := url.Values{"q": {strings.Join(args, " ")}}
+		if searchKind != "" {
+			params.Set("kind", searchKind)
+		}
+		if searchModule != "" {
+			params.Set("module", searchModule)
+		}
+		if searchLimit > 0 {
+			params.Set("limit", strconv.Itoa(searchLimit))
+		}
+		targetURL := fmt.Sprintf("%s/api/v1/search?%s", strings.TrimSuffix(registryURL, "/"), params.Encode())
+		req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		setReadToken(req)
+		log.Info("Searching registry", zap.String("url", targetURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return registryError(resp.StatusCode, bodyBytes)
+		}
+
+		var results apitypes.SearchResponse
+		if err := json.Unmarshal(bodyBytes, &results); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if len(results.Results) == 0 {
+			fmt.Printf("No results for %q\n", results.Query)
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "KIND\tNAME\tMODULE\tEXCERPT")
+		for _, r := range results.Results {
+			fmt.Fprintf(tw, "%s\t%s\t%s@%s\t%s\n", r.Kind, r.Name, r.Module, r.Version, orDash(formatSnippet(r.Snippet)))
+		}
+		return tw.Flush()
+	},
+}
+
+// formatSnippet renders a search snippet on one line, with matches between asterisks.
+func formatSnippet(snippet string) string {
+	snippet = strings.NewReplacer(apitypes.SearchMatchStart, "*", apitypes.SearchMatchEnd, "*").Replace(snippet)
+	return strings.Join(strings.Fields(snippet), " ")
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+	searchCmd.Flags().StringVar(&searchKind, "kind", "", "Only show results of this kind: module, file, message, enum, service, method or field")
+	searchCmd.Flags().StringVar(&searchModule, "module", "", "Only search this module (namespace/name)")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 0, "Maximum number of results (default 20, max 100)")
+}
@@ -0,0 +1,433 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var selftestNamespace string
+
+// Modules published by the self-test: selftestApp depends on (and imports) selftestBase,
+// so the registry's dependency checks and the client's resolver are exercised too.
+const (
+	selftestBase    = "base"
+	selftestApp     = "app"
+	selftestVersion = "v1.0.0"
+)
+
+// selftestCmd represents the selftest command
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Run a publish/list/resolve/fetch/verify round trip against the registry",
+	Long: `Publishes two small modules to a throwaway namespace and checks that the
+registry serves them back correctly:
+
+  publish   both modules are accepted (the second depends on and imports the first)
+  list      they appear in the module list and in their version lists
+  resolve   the second module's sproto.yaml resolves to the first, with the published digest
+  fetch     both artifacts download and their version metadata is served
+  verify    the downloaded artifacts are byte-identical to what was uploaded
+  cleanup   the published versions are deleted again, even if a step failed
+
+Use it as a smoke test after deployments. The namespace defaults to
+"selftest-<random>". Authentication via API token is required.
+
+Exits 0 if every step passed and 1 otherwise.
+
+Example:
+  protoreg-cli selftest --registry-url https://registry.example.com`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		apiToken, err := requireAPIToken()
+		if err != nil {
+			return err
+		}
+
+		namespace := selftestNamespace
+		if namespace == "" {
+			suffix := make([]byte, 4)
+			if _, err := rand.Read(suffix); err != nil {
+				return fmt.Errorf("failed to generate namespace: %w", err)
+			}
+			namespace = "selftest-" + hex.EncodeToString(suffix)
+		}
+
+		st, err := newSelftest(&http.Client{Timeout: 30 * time.Second}, registryURL, apiToken, namespace, log)
+		if err != nil {
+			return fmt.Errorf("failed to prepare self-test artifacts: %w", err)
+		}
+		fmt.Printf("Running self-test against %s in namespace %s\n", registryURL, namespace)
+		if !st.run() {
+			fmt.Println("Self-test FAILED")
+			return exitStatus(1)
+		}
+		fmt.Println("Self-test passed")
+		return nil
+	},
+}
+
+// selftest holds the state of one self-test run.
+type selftest struct {
+	client      *http.Client
+	registryURL string
+	apiToken    string
+	namespace   string
+	log         *zap.Logger
+	files       map[string]map[string]string // Module name -> path -> content
+	artifacts   map[string][]byte            // Module name -> uploaded zip
+	fetched     map[string][]byte            // Module name -> downloaded zip
+	published   []string                     // Modules published by this run, in order (deleted by cleanup)
+}
+
+func newSelftest(client *http.Client, registryURL, apiToken, namespace string, log *zap.Logger) (*selftest, error) {
+	st := &selftest{
+		client:      client,
+		registryURL: strings.TrimSuffix(registryURL, "/"),
+		apiToken:    apiToken,
+		namespace:   namespace,
+		log:         log,
+		artifacts:   make(map[string][]byte),
+		fetched:     make(map[string][]byte),
+	}
+
+	// Proto packages can't contain '-', the namespace can
+	pkg := strings.NewReplacer("-", "_", ".", "_").Replace(namespace)
+	basePath := fmt.Sprintf("%s/%s/v1/%s.proto", namespace, selftestBase, selftestBase)
+	st.files = map[string]map[string]string{
+		selftestBase: {
+			basePath: fmt.Sprintf("syntax = \"proto3\";\npackage %s.base.v1;\n\nmessage Ping {\n  string id = 1;\n}\n", pkg),
+		},
+		selftestApp: {
+			manifest.ManifestFileName: fmt.Sprintf("name: %s/%s\ndependencies:\n  %s/%s: ^1.0.0\n", namespace, selftestApp, namespace, selftestBase),
+			fmt.Sprintf("%s/%s/v1/%s.proto", namespace, selftestApp, selftestApp): fmt.Sprintf(
+				"syntax = \"proto3\";\npackage %s.app.v1;\n\nimport \"%s\";\n\nmessage Pong {\n  %s.base.v1.Ping ping = 1;\n}\n", pkg, basePath, pkg),
+		},
+	}
+	for module, contents := range st.files {
+		zipData, err := zipFiles(contents)
+		if err != nil {
+			return nil, err
+		}
+		st.artifacts[module] = zipData
+	}
+	return st, nil
+}
+
+// run executes the steps in order, stopping at the first failure (later steps depend on earlier ones),
+// then deletes what was published. Returns true if every step, including the cleanup, passed.
+func (st *selftest) run() bool {
+	steps := []struct {
+		name string
+		fn   func() error
+	}{
+		{"publish", st.publish},
+		{"list", st.list},
+		{"resolve", st.resolve},
+		{"fetch", st.fetch},
+		{"verify", st.verify},
+	}
+	passed := true
+	for _, step := range steps {
+		if !st.step(step.name, step.fn) {
+			passed = false
+			break
+		}
+	}
+	// Also after a failure, so failed runs don't leave modules behind
+	return st.step("cleanup", st.cleanup) && passed
+}
+
+// step runs one step and prints its outcome. Returns true if it passed.
+func (st *selftest) step(name string, fn func() error) bool {
+	start := time.Now()
+	err := fn()
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		fmt.Printf("  FAIL  %-8s %8s  %v\n", name, elapsed, err)
+		return false
+	}
+	fmt.Printf("  PASS  %-8s %8s\n", name, elapsed)
+	return true
+}
+
+// --- Steps ---
+
+// publish uploads the base module, then the app module depending on it.
+func (st *selftest) publish() error {
+	for _, module := range []string{selftestBase, selftestApp} {
+		zipData := st.artifacts[module]
+		req, err := newArtifactUploadRequest(st.versionURL(module), selftestVersion, zipData, st.apiToken)
+		if err != nil {
+			return err
+		}
+		body, status, err := st.do(req)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusCreated {
+			return st.apiError(status, body, "publish %s", st.module(module))
+		}
+		st.published = append(st.published, module)
+		var published apitypes.PublishModuleVersionResponse
+		if err := json.Unmarshal(body, &published); err != nil {
+			return fmt.Errorf("failed to parse publish response for %s: %w", st.module(module), err)
+		}
+		if want := digestOf(zipData); published.ArtifactDigest != want {
+			return fmt.Errorf("%s: registry recorded digest %s, uploaded %s", st.module(module), published.ArtifactDigest, want)
+		}
+	}
+	return nil
+}
+
+// list checks both modules appear in the module list and their version lists.
+func (st *selftest) list() error {
+	req, err := http.NewRequest("GET", st.registryURL+"/api/v1/modules", nil)
+	if err != nil {
+		return err
+	}
+	body, status, err := st.do(req)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return st.apiError(status, body, "list modules")
+	}
+	var modules listModulesApiResponse
+	if err := json.Unmarshal(body, &modules); err != nil {
+		return fmt.Errorf("failed to parse module list: %w", err)
+	}
+	latest := make(map[string]string)
+	for _, m := range modules.Modules {
+		if m.Namespace == st.namespace {
+			latest[m.Name] = m.LatestVersion
+		}
+	}
+
+	for _, module := range []string{selftestBase, selftestApp} {
+		if v, ok := latest[module]; !ok {
+			return fmt.Errorf("%s missing from the module list", st.module(module))
+		} else if v != selftestVersion {
+			return fmt.Errorf("%s listed with latest version %q, want %s", st.module(module), v, selftestVersion)
+		}
+		versions, err := getModuleVersions(st.client, st.registryURL, st.namespace, module, st.log)
+		if err != nil {
+			return err
+		}
+		if len(versions) != 1 || versions[0] != selftestVersion {
+			return fmt.Errorf("%s has versions %v, want [%s]", st.module(module), versions, selftestVersion)
+		}
+	}
+	return nil
+}
+
+// resolve resolves the app module's sproto.yaml against the registry, as 'protoreg-cli deps' does.
+func (st *selftest) resolve() error {
+	root, err := readPackagedManifest(st.artifacts[selftestApp])
+	if err != nil {
+		return err
+	}
+	source := &registrySource{client: st.client, registryURL: st.registryURL, log: st.log}
+	lock, err := manifest.Resolve(root, nil, source, manifest.ResolveOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to resolve dependencies: %w", err)
+	}
+	locked := lock.Find(st.module(selftestBase))
+	if locked == nil || len(lock.Dependencies) != 1 {
+		return fmt.Errorf("expected exactly %s in the lock, got %+v", st.module(selftestBase), lock.Dependencies)
+	}
+	if locked.Version != selftestVersion {
+		return fmt.Errorf("%s resolved to %s, want %s", locked.Module, locked.Version, selftestVersion)
+	}
+	if want := digestOf(st.artifacts[selftestBase]); locked.Digest != want {
+		return fmt.Errorf("%s resolved with digest %s, uploaded %s", locked.Module, locked.Digest, want)
+	}
+	return nil
+}
+
+// fetch downloads both artifacts and checks their version metadata.
+func (st *selftest) fetch() error {
+	for _, module := range []string{selftestBase, selftestApp} {
+		req, err := http.NewRequest("GET", st.versionURL(module), nil)
+		if err != nil {
+			return err
+		}
+		body, status, err := st.do(req)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusOK {
+			return st.apiError(status, body, "get %s@%s", st.module(module), selftestVersion)
+		}
+		var meta apitypes.ModuleVersionResponse
+		if err := json.Unmarshal(body, &meta); err != nil {
+			return fmt.Errorf("failed to parse version metadata for %s: %w", st.module(module), err)
+		}
+		if want := digestOf(st.artifacts[module]); meta.ArtifactDigest != want {
+			return fmt.Errorf("%s@%s metadata has digest %s, uploaded %s", st.module(module), selftestVersion, meta.ArtifactDigest, want)
+		}
+
+		zipData, err := downloadArtifact(st.client, st.registryURL, st.namespace, module, selftestVersion, st.log)
+		if err != nil {
+			return err
+		}
+		st.fetched[module] = zipData
+	}
+	return nil
+}
+
+// verify checks the downloaded artifacts are identical to the uploaded ones and extract to the published files.
+func (st *selftest) verify() error {
+	for _, module := range []string{selftestBase, selftestApp} {
+		uploaded, fetched := st.artifacts[module], st.fetched[module]
+		if got, want := digestOf(fetched), digestOf(uploaded); got != want {
+			return fmt.Errorf("%s: downloaded artifact has digest %s, uploaded %s", st.module(module), got, want)
+		}
+		if err := st.verifyExtracted(module, fetched); err != nil {
+			return fmt.Errorf("%s: %w", st.module(module), err)
+		}
+	}
+	return nil
+}
+
+// verifyExtracted extracts an artifact the way 'protoreg-cli fetch' does and compares the files.
+func (st *selftest) verifyExtracted(module string, zipData []byte) error {
+	dir, err := os.MkdirTemp("", "protoreg-selftest-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := extractZip(zipData, dir, st.log); err != nil {
+		return err
+	}
+	for p, want := range st.files[module] {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(p)))
+		if err != nil {
+			return fmt.Errorf("extracted artifact is missing %s: %w", p, err)
+		}
+		if string(got) != want {
+			return fmt.Errorf("extracted %s differs from the published file", p)
+		}
+	}
+	return nil
+}
+
+// cleanup deletes the versions publish created, dependents first. The registry deletes each module along
+// with its last version, so nothing of the run remains.
+func (st *selftest) cleanup() error {
+	for i := len(st.published) - 1; i >= 0; i-- {
+		module := st.published[i]
+		req, err := http.NewRequest("DELETE", st.versionURL(module), nil)
+		if err != nil {
+			return err
+		}
+		body, status, err := st.do(req)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusNoContent && status != http.StatusNotFound { // Already gone is fine
+			return st.apiError(status, body, "delete %s@%s", st.module(module), selftestVersion)
+		}
+	}
+	st.published = nil
+	return nil
+}
+
+// --- Helpers ---
+
+func (st *selftest) module(name string) string {
+	return st.namespace + "/" + name
+}
+
+func (st *selftest) versionURL(module string) string {
+	return fmt.Sprintf("%s/api/v1/modules/%s/%s/%s", st.registryURL, url.PathEscape(st.namespace), url.PathEscape(module), url.PathEscape(selftestVersion))
+}
+
+// do executes a request and reads the whole response body.
+func (st *selftest) do(req *http.Request) ([]byte, int, error) {
+	if req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+st.apiToken) // Reads too, in case the namespace isn't public
+	}
+	resp, err := st.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, resp.StatusCode, nil
+}
+
+// apiError describes a failed API call, including the registry's error message if there is one.
+func (st *selftest) apiError(status int, body []byte, format string, args ...any) error {
+	var errResp apiErrorResponse
+	msg := fmt.Sprintf(format, args...)
+	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error != "" {
+		return fmt.Errorf("%s: registry returned status %d: %s", msg, status, errResp.Error)
+	}
+	return fmt.Errorf("%s: registry returned status %d", msg, status)
+}
+
+// zipFiles builds a zip archive from path -> content in canonical form, i.e. exactly the artifact the
+// registry stores, so uploaded and stored digests can be compared.
+func zipFiles(files map[string]string) ([]byte, error) {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+	for _, p := range paths {
+		w, err := zipWriter.Create(p)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(w, files[p]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return artifact.Canonicalize(buf.Bytes())
+}
+
+// digestOf returns the artifact digest in the registry's format (sha256:<hex>).
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+
+	selftestCmd.Flags().StringVar(&selftestNamespace, "namespace", "", "Namespace to publish into (default \"selftest-<random>\"); must not exist yet")
+}
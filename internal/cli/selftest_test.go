@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeSelftestRegistry serves the endpoints used by the self-test from memory.
+type fakeSelftestRegistry struct {
+	mu        sync.Mutex
+	artifacts map[string][]byte // namespace/module -> artifact of v1.0.0
+	deletes   []string          // Modules deleted, in order
+	reject    string            // Module whose publish is rejected with 422
+	corrupt   bool              // Serve artifacts with a byte flipped
+}
+
+func (f *fakeSelftestRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer selftest-token" && r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path == "/api/v1/modules" {
+		var modules []map[string]string
+		for module := range f.artifacts {
+			namespace, name, _ := strings.Cut(module, "/")
+			modules = append(modules, map[string]string{"namespace": namespace, "name": name, "latest_version": selftestVersion})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"modules": modules})
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/modules/"), "/")
+	if len(parts) < 2 {
+		http.NotFound(w, r)
+		return
+	}
+	module := parts[0] + "/" + parts[1]
+	data, exists := f.artifacts[module]
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodGet && exists:
+		_ = json.NewEncoder(w).Encode(listModuleVersionsApiResponse{Versions: []string{selftestVersion}})
+	case len(parts) == 3 && r.Method == http.MethodPost:
+		if parts[1] == f.reject {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = io.WriteString(w, `{"error":"Dependency validation failed"}`)
+			return
+		}
+		file, _, err := r.FormFile("artifact")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		f.artifacts[module] = data
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(apitypes.PublishModuleVersionResponse{Version: parts[2], ArtifactDigest: digestOf(data)})
+	case len(parts) == 3 && r.Method == http.MethodGet && exists:
+		_ = json.NewEncoder(w).Encode(apitypes.ModuleVersionResponse{Version: parts[2], ArtifactDigest: digestOf(data)})
+	case len(parts) == 3 && r.Method == http.MethodDelete && exists:
+		delete(f.artifacts, module)
+		f.deletes = append(f.deletes, parts[1])
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 4 && parts[3] == "artifact" && exists:
+		if f.corrupt {
+			data = append([]byte{data[0] ^ 0xff}, data[1:]...)
+		}
+		_, _ = w.Write(data)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":"Module version not found"}`)
+	}
+}
+
+func TestSelftest(t *testing.T) {
+	tests := []struct {
+		name        string
+		reject      string
+		corrupt     bool
+		wantPassed  bool
+		wantDeletes []string
+	}{
+		{name: "passes", wantPassed: true, wantDeletes: []string{selftestApp, selftestBase}},
+		{name: "failed step", corrupt: true, wantDeletes: []string{selftestApp, selftestBase}},
+		{name: "failed publish", reject: selftestApp, wantDeletes: []string{selftestBase}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := &fakeSelftestRegistry{artifacts: map[string][]byte{}, reject: tt.reject, corrupt: tt.corrupt}
+			server := httptest.NewServer(registry)
+			defer server.Close()
+
+			st, err := newSelftest(server.Client(), server.URL+"/", "selftest-token", "selftest-0a1b", zap.NewNop())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPassed, st.run())
+
+			// Whatever was published is deleted again, dependents first
+			assert.Empty(t, registry.artifacts)
+			assert.Equal(t, tt.wantDeletes, registry.deletes)
+		})
+	}
+}
+
+func TestSelftestCleanupFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A registry without the delete endpoint
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer server.Close()
+
+	st, err := newSelftest(server.Client(), server.URL, "selftest-token", "selftest-0a1b", zap.NewNop())
+	require.NoError(t, err)
+	st.published = []string{selftestBase}
+	err = st.cleanup()
+	assert.ErrorContains(t, err, "delete selftest-0a1b/base@v1.0.0: registry returned status 405")
+	assert.Equal(t, []string{selftestBase}, st.published, "modules that weren't deleted are still reported")
+}
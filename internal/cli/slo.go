@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	sloWindows []string
+	sloKind    string
+)
+
+// sloCmd represents the slo command
+var sloCmd = &cobra.Command{
+	Use:   "slo [namespace/module_name|directory]",
+	Short: "Report the availability and latency of a module's downloads",
+	Long: `Reports, per time window, how many downloads of a module (artifacts, bundles and
+secondary artifacts) the registry served, how many failed with a server error, the
+resulting availability and estimated latency percentiles. Downloads show up in the
+report within a minute; statistics are kept for 30 days.
+
+The module argument works like for 'list': a module name, or a module directory whose
+sproto.yaml provides the name (default ".").
+
+Examples:
+  protoreg-cli slo mycompany/billing
+  protoreg-cli slo mycompany/billing --window 24h --window 30d --kind artifact`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		moduleArg := "."
+		if len(args) > 0 {
+			moduleArg = args[0]
+		}
+		namespace, moduleName, _, err := resolveModuleArg(moduleArg, "")
+		if err != nil {
+			return exitErrorf(ExitValidation, "invalid module: %w", err)
+		}
+
+		query := url.Values{}
+		if len(sloWindows) > 0 {
+			query.Set("windows", strings.Join(sloWindows, ","))
+		}
+		if sloKind != "" {
+			query.Set("kind", sloKind)
+		}
+		targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/slo", strings.TrimSuffix(registryURL, "/"), url.PathEscape(namespace), url.PathEscape(moduleName))
+		if len(query) > 0 {
+			targetURL += "?" + query.Encode()
+		}
+		req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		setReadToken(req)
+		log.Info("Requesting SLO report", zap.String("url", targetURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s/%s: %w", namespace, moduleName, registryError(resp.StatusCode, bodyBytes))
+		}
+
+		var report apitypes.ModuleSLOResponse
+		if err := json.Unmarshal(bodyBytes, &report); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "WINDOW\tREQUESTS\tERRORS\tAVAILABILITY\tP50\tP95\tP99")
+		for _, w := range report.Windows {
+			if w.Availability == nil {
+				fmt.Fprintf(tw, "%s\t0\t0\t-\t-\t-\t-\n", w.Window)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%.3f%%\t%.0fms\t%.0fms\t%.0fms\n", w.Window, w.Requests, w.Errors, *w.Availability*100,
+				w.LatencyMs.P50, w.LatencyMs.P95, w.LatencyMs.P99)
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sloCmd)
+	sloCmd.Flags().StringArrayVar(&sloWindows, "window", nil, "Window to report, in hours (24h) or days (7d); repeatable (default 1h, 24h, 7d and 30d)")
+	sloCmd.Flags().StringVar(&sloKind, "kind", "", "Only count downloads of this kind (artifact, bundle, secondary_artifact)")
+}
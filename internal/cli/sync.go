@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	syncDir    string
+	syncOutput string
+	syncLayout string
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Bring a local proto tree up to date with sproto.lock, downloading only what changed",
+	Long: `Makes the output directory hold the dependency versions pinned in sproto.lock, like
+'protoreg-cli deps install', but only downloads the files that are missing or differ: the SHA256 of
+each file, as listed by the registry, is compared with the files already on disk. Once a tree is
+in sync, running it again only lists the files, so repeated local setups are near-instant.
+
+The file lists are checked against the digests pinned in sproto.lock, and every downloaded file
+against its listed SHA256, before anything is written. Files that are no longer pinned (e.g. of an
+older version) are left in place; in the nested layout each version has its own directory.
+
+--output defaults to sproto_deps next to sproto.lock; --layout works as for 'deps install'.
+
+Examples:
+  protoreg-cli sync --output ./protos
+  protoreg-cli sync --dir ./api --output ./include --layout flat`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		if err := validateLayout(syncLayout); err != nil {
+			return exitErrorf(ExitUsage, "invalid --layout: %w", err)
+		}
+		outputDir := syncOutput
+		if outputDir == "" {
+			outputDir = filepath.Join(syncDir, defaultDepsOutputDir)
+		}
+
+		lock, err := manifest.LoadLock(filepath.Join(syncDir, manifest.LockFileName))
+		if errors.Is(err, os.ErrNotExist) {
+			return exitErrorf(ExitUsage, "no sproto.lock found in %s; run 'protoreg-cli deps update' first", syncDir)
+		} else if err != nil {
+			return fmt.Errorf("failed to read lockfile: %w", err)
+		}
+		if len(lock.Dependencies) == 0 {
+			fmt.Println("No dependencies to sync.")
+			return nil
+		}
+
+		client := &http.Client{}
+		plans, err := planSync(client, registryURL, lock, outputDir, syncLayout, log)
+		if err != nil {
+			return err
+		}
+		downloaded, unchanged := 0, 0
+		for _, p := range plans {
+			count, err := downloadFiles(client, registryURL, p.namespace, p.name, p.dep.Version, p.missing, p.total, p.target, log)
+			if err != nil {
+				return fmt.Errorf("failed to sync %s: %w", p.dep.Module, err)
+			}
+			fmt.Printf("  %s %s (%d downloaded, %d unchanged)\n", p.dep.Module, p.dep.Version, count, p.total-len(p.missing))
+			downloaded += count
+			unchanged += p.total - len(p.missing)
+		}
+		fmt.Printf("Synced %d dependencies to %s (%s layout): %d files downloaded, %d unchanged.\n", len(plans), outputDir, syncLayout, downloaded, unchanged)
+		return nil
+	},
+}
+
+// syncPlan is what sync downloads for a locked dependency.
+type syncPlan struct {
+	dep             manifest.LockedDependency
+	namespace, name string
+	target          string                         // Directory the dependency is extracted into
+	total           int                            // Files of the dependency in the layout
+	missing         []apitypes.VersionFileResponse // Files not on disk with the listed content
+}
+
+// planSync lists the files of every locked dependency and compares them with the files on disk. The lists
+// must match the locked digests; mismatches are collected so they are all reported, and nothing is planned
+// if there is any. In the flat layout, dependencies with different files at the same path are a conflict.
+func planSync(client *http.Client, registryURL string, lock *manifest.Lock, outputDir, layout string, log *zap.Logger) ([]syncPlan, error) {
+	plans := make([]syncPlan, 0, len(lock.Dependencies))
+	owners := make(map[string]string)  // Lowercased path -> module, for flat layout conflicts
+	digests := make(map[string]string) // Lowercased path -> SHA256 of its content
+	var mismatches []string
+	for _, dep := range lock.Dependencies {
+		namespace, name, err := manifest.SplitModule(dep.Module)
+		if err != nil {
+			return nil, exitErrorf(ExitValidation, "invalid module in lockfile: %w", err)
+		}
+		if dep.Digest == "" {
+			return nil, exitErrorf(ExitValidation, "%s@%s has no digest in sproto.lock; run 'protoreg-cli deps update' to pin it", dep.Module, dep.Version)
+		}
+		list, err := fetchVersionFiles(client, registryURL, namespace, name, dep.Version, nil, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the files of %s: %w", dep.Module, err)
+		}
+		if list.ArtifactDigest != dep.Digest {
+			log.Warn("Artifact digest does not match sproto.lock", zap.String("module", dep.Module), zap.String("version", dep.Version), zap.String("locked", dep.Digest), zap.String("listed", list.ArtifactDigest))
+			mismatches = append(mismatches, fmt.Sprintf("%s@%s (locked %s, registry has %s)", dep.Module, dep.Version, dep.Digest, list.ArtifactDigest))
+			continue
+		}
+		files, err := keptFiles(list.Files, layoutFilter(layout))
+		if err != nil {
+			return nil, exitErrorf(ExitValidation, "invalid artifact of %s: %w", dep.Module, err)
+		}
+		if layout == layoutFlat {
+			for _, f := range files {
+				p := strings.ToLower(f.Path)
+				if other, ok := owners[p]; ok && digests[p] != f.Digest {
+					return nil, exitErrorf(ExitUsage, "dependencies %s and %s contain different files with the same path %s; use --layout nested", other, dep.Module, f.Path)
+				}
+				owners[p], digests[p] = dep.Module, f.Digest
+			}
+		}
+
+		plan := syncPlan{dep: dep, namespace: namespace, name: name, target: extractionPath(outputDir, layout, namespace, name, dep.Version), total: len(files)}
+		for _, f := range files {
+			ok, err := hasLocalFile(filepath.Join(plan.target, filepath.FromSlash(f.Path)), f)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				plan.missing = append(plan.missing, f)
+			}
+		}
+		plans = append(plans, plan)
+	}
+	if len(mismatches) > 0 {
+		return nil, exitErrorf(ExitValidation, "artifact digests do not match sproto.lock, nothing was synced: %s", strings.Join(mismatches, "; "))
+	}
+	return plans, nil
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().StringVarP(&syncDir, "dir", "d", ".", "Directory containing sproto.lock")
+	syncCmd.Flags().StringVarP(&syncOutput, "output", "o", "", "Directory to sync the dependencies into (default: sproto_deps in --dir)")
+	syncCmd.Flags().StringVar(&syncLayout, "layout", layoutNested, "Output layout: nested (<output>/<namespace>/<name>/<version>/) or flat (.proto files directly in <output>, an include path)")
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPlanSync(t *testing.T) {
+	versions := map[string]map[string]string{
+		"v1.0.0": {"common/types.proto": "types", "common/README.md": "readme"},
+		"v2.0.0": {"user/v1/user.proto": "user", "user/v1/prefs.proto": "prefs", "user/v1/roles.proto": "roles", "common/types.proto": "other types"},
+	}
+	var partials []string
+	srv := filesRegistry(t, versions, &partials)
+	defer srv.Close()
+	digest := func(version string) string {
+		list, err := fetchVersionFiles(srv.Client(), srv.URL, "acme", "x", version, nil, zap.NewNop())
+		require.NoError(t, err)
+		return list.ArtifactDigest
+	}
+	lock := &manifest.Lock{Dependencies: []manifest.LockedDependency{
+		{Module: "acme/common", Version: "v1.0.0", Digest: digest("v1.0.0")},
+		{Module: "acme/user", Version: "v2.0.0", Digest: digest("v2.0.0")},
+	}}
+	sync := func(out, layout string) []syncPlan {
+		plans, err := planSync(srv.Client(), srv.URL, lock, out, layout, zap.NewNop())
+		require.NoError(t, err)
+		for _, p := range plans {
+			_, err := downloadFiles(srv.Client(), srv.URL, p.namespace, p.name, p.dep.Version, p.missing, p.total, p.target, zap.NewNop())
+			require.NoError(t, err)
+		}
+		return plans
+	}
+
+	// Everything is missing at first, nothing once in sync
+	out := t.TempDir()
+	plans := sync(out, layoutNested)
+	require.Len(t, plans, 2)
+	assert.Len(t, plans[0].missing, 2)
+	assert.Len(t, plans[1].missing, 4)
+	assert.Empty(t, partials) // Whole artifacts
+	data, err := os.ReadFile(filepath.Join(out, "acme", "user", "v2.0.0", "user", "v1", "user.proto"))
+	require.NoError(t, err)
+	assert.Equal(t, "user", string(data))
+	for _, p := range sync(out, layoutNested) {
+		assert.Empty(t, p.missing, p.dep.Module)
+	}
+
+	// Only the file edited locally is downloaded again
+	edited := filepath.Join(out, "acme", "user", "v2.0.0", "user", "v1", "prefs.proto")
+	require.NoError(t, os.WriteFile(edited, []byte("edited"), 0644))
+	plans = sync(out, layoutNested)
+	assert.Empty(t, plans[0].missing)
+	require.Len(t, plans[1].missing, 1)
+	assert.Equal(t, "user/v1/prefs.proto", plans[1].missing[0].Path)
+	assert.Equal(t, []string{"user/v1/prefs.proto"}, partials)
+	data, err = os.ReadFile(edited)
+	require.NoError(t, err)
+	assert.Equal(t, "prefs", string(data))
+
+	// The flat layout only syncs .proto files, and rejects different files at the same path
+	_, err = planSync(srv.Client(), srv.URL, lock, t.TempDir(), layoutFlat, zap.NewNop())
+	assert.ErrorContains(t, err, "dependencies acme/common and acme/user contain different files with the same path common/types.proto")
+	assert.Equal(t, ExitUsage, exitCode(err))
+	flat := &manifest.Lock{Dependencies: lock.Dependencies[:1]}
+	plans, err = planSync(srv.Client(), srv.URL, flat, t.TempDir(), layoutFlat, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, 1, plans[0].total)
+
+	// Lists that don't match the locked digests are all reported, and nothing is planned
+	mismatched := &manifest.Lock{Dependencies: []manifest.LockedDependency{
+		{Module: "acme/common", Version: "v1.0.0", Digest: "sha256:0000"},
+		{Module: "acme/user", Version: "v2.0.0", Digest: "sha256:1111"},
+	}}
+	_, err = planSync(srv.Client(), srv.URL, mismatched, out, layoutNested, zap.NewNop())
+	assert.ErrorContains(t, err, "acme/common@v1.0.0 (locked sha256:0000")
+	assert.ErrorContains(t, err, "acme/user@v2.0.0 (locked sha256:1111")
+	assert.Equal(t, ExitValidation, exitCode(err))
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// tokenExpiryWarning is how long before the API token expires the CLI starts warning about it.
+const tokenExpiryWarning = 14 * 24 * time.Hour
+
+// tokenExpiryTransport warns (once per run) when the registry reports that the API token expires soon.
+// It wraps http.DefaultTransport, so it sees the responses of every command.
+type tokenExpiryTransport struct {
+	base http.RoundTripper
+	once sync.Once
+}
+
+func (t *tokenExpiryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if header := resp.Header.Get(apitypes.TokenExpiryHeader); header != "" {
+		expiresAt, parseErr := time.Parse(time.RFC3339, header)
+		if parseErr == nil && time.Until(expiresAt) < tokenExpiryWarning {
+			t.once.Do(func() {
+				GetLogger().Warn("The API token expires soon; ask the registry administrator for a new one",
+					zap.Time("expires_at", expiresAt.Local()), zap.Duration("remaining", time.Until(expiresAt).Round(time.Minute)))
+			})
+		}
+	}
+	return resp, nil
+}
+
+// installTokenExpiryWarning makes every HTTP client using the default transport warn about expiring tokens.
+func installTokenExpiryWarning() {
+	if _, installed := http.DefaultTransport.(*tokenExpiryTransport); !installed {
+		http.DefaultTransport = &tokenExpiryTransport{base: http.DefaultTransport}
+	}
+}
+
+var tokensStale bool
+
+// tokensCmd represents the tokens command
+var tokensCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Report the registry's API tokens with their expiry and last use",
+	Long: `Lists the admin and read tokens configured on the registry with their expiry date
+and when they were last used, so stale or expired tokens can be rotated out.
+Tokens are identified by the start of their SHA256; the tokens themselves are never shown.
+
+Requires the admin API token.
+
+Examples:
+  protoreg-cli tokens
+  protoreg-cli tokens --stale`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		apiToken, err := requireAPIToken()
+		if err != nil {
+			return err
+		}
+
+		targetURL := strings.TrimSuffix(registryURL, "/") + "/api/v1/admin/tokens"
+		if tokensStale {
+			targetURL += "?stale=true"
+		}
+		req, err := http.NewRequest("GET", targetURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+		log.Debug("Requesting token report", zap.String("url", targetURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return registryError(resp.StatusCode, bodyBytes)
+		}
+
+		var report apitypes.TokenReportResponse
+		if err := json.Unmarshal(bodyBytes, &report); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if len(report.Tokens) == 0 {
+			fmt.Println("No tokens to report")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tKIND\tCONSUMER\tEXPIRES\tLAST USED\tSTATUS")
+		for _, t := range report.Tokens {
+			expires, lastUsed := "never", "never"
+			if t.ExpiresAt != nil {
+				expires = t.ExpiresAt.Local().Format(time.RFC3339)
+			}
+			if t.LastUsedAt != nil {
+				lastUsed = t.LastUsedAt.Local().Format(time.RFC3339)
+			}
+			var status []string
+			if t.Expired {
+				status = append(status, "expired")
+			}
+			if t.Stale {
+				status = append(status, "stale")
+			}
+			if len(status) == 0 {
+				status = append(status, "ok")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Kind, t.Consumer, expires, lastUsed, strings.Join(status, ","))
+		}
+		tw.Flush()
+		fmt.Printf("(stale: unused for more than %s)\n", report.StaleAfter)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tokensCmd)
+	tokensCmd.Flags().BoolVar(&tokensStale, "stale", false, "Only list stale and expired tokens")
+}
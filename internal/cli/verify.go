@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/translog"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// Checksum verification: with a registry public key configured (registry_public_key), every downloaded
+// artifact is checked against the checksum statement the registry signed when the version was logged.
+// The key is configured out of band, so neither a compromised registry host without the signing key
+// nor anything between it and the client can serve other bytes for a version unnoticed.
+
+// verifyChecksum checks a downloaded artifact against the registry's signed checksum statement, fetched
+// from the version's metadata. It does nothing unless a registry public key is configured.
+func verifyChecksum(client *http.Client, registryURL, namespace, moduleName, version string, zipData []byte, log *zap.Logger) error {
+	encodedKey := viper.GetString("registry_public_key")
+	if encodedKey == "" {
+		return nil
+	}
+	key, err := translog.ParsePublicKey(encodedKey)
+	if err != nil {
+		return withExitCode(ExitUsage, fmt.Errorf("invalid registry public key: %w", err))
+	}
+
+	versionURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/%s", strings.TrimSuffix(registryURL, "/"), url.PathEscape(namespace), url.PathEscape(moduleName), url.PathEscape(version))
+	req, err := http.NewRequest("GET", versionURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	setReadToken(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch checksum statement: %w", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read checksum statement: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("metadata of %s/%s@%s: %w", namespace, moduleName, version, registryError(resp.StatusCode, bodyBytes))
+	}
+	var meta apitypes.ModuleVersionResponse
+	if err := json.Unmarshal(bodyBytes, &meta); err != nil {
+		return fmt.Errorf("failed to parse module version metadata: %w", err)
+	}
+
+	if err := verifyAttestation(key, namespace+"/"+moduleName, version, zipData, meta.Checksum); err != nil {
+		return withExitCode(ExitValidation, err) // The artifact can't be trusted
+	}
+	log.Info("Verified artifact against the signed checksum statement", zap.String("module", namespace+"/"+moduleName), zap.String("version", version), zap.Int64("log_index", meta.Checksum.Index))
+	return nil
+}
+
+// verifyAttestation checks that attestation is a statement signed with key that module@version has
+// the digest of zipData, and that its inclusion proof leads to the statement's root hash. Only the
+// signed statement is trusted; the attestation's other fields are ignored.
+func verifyAttestation(key ed25519.PublicKey, module, version string, zipData []byte, attestation *apitypes.ChecksumAttestation) error {
+	if attestation == nil {
+		return fmt.Errorf("registry returned no checksum statement for %s@%s (is its checksum log enabled?)", module, version)
+	}
+	if attestation.Signature == "" {
+		return fmt.Errorf("checksum statement for %s@%s is not signed (is CHECKSUM_SIGNING_KEY configured on the registry?)", module, version)
+	}
+	if err := translog.VerifyStatement(key, []byte(attestation.Statement), attestation.Signature); err != nil {
+		return fmt.Errorf("checksum statement for %s@%s: %w (is the configured registry public key correct?)", module, version, err)
+	}
+	statement, err := translog.ParseStatement([]byte(attestation.Statement))
+	if err != nil {
+		return err
+	}
+	if statement.Module != module || statement.Version != version {
+		return fmt.Errorf("checksum statement is for %s@%s, not %s@%s", statement.Module, statement.Version, module, version)
+	}
+	sum := sha256.Sum256(zipData)
+	if digest := "sha256:" + hex.EncodeToString(sum[:]); statement.Digest != digest {
+		return fmt.Errorf("downloaded artifact of %s@%s has digest %s, but the registry logged %s", module, version, digest, statement.Digest)
+	}
+
+	root, err := hex.DecodeString(statement.RootHash)
+	if err != nil {
+		return fmt.Errorf("checksum statement has an invalid root hash: %w", err)
+	}
+	proof := make([][]byte, len(attestation.Proof))
+	for i, h := range attestation.Proof {
+		if proof[i], err = hex.DecodeString(h); err != nil {
+			return fmt.Errorf("%w: invalid hash %q", translog.ErrInvalidProof, h)
+		}
+	}
+	leaf := translog.LeafHash(translog.EntryText(module, version, statement.Digest))
+	if err := translog.VerifyInclusion(leaf, statement.Index, statement.TreeSize, proof, root); err != nil {
+		return fmt.Errorf("checksum log inclusion of %s@%s: %w", module, version, err)
+	}
+	return nil
+}
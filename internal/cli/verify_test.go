@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/translog"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAttestation returns the attestation a registry would serve for acme/billing@v1.0.0 with the given
+// artifact, logged as entry 1 of 3.
+func testAttestation(t *testing.T, key ed25519.PrivateKey, zipData []byte) *apitypes.ChecksumAttestation {
+	sum := sha256.Sum256(zipData)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	leaves := [][]byte{
+		translog.LeafHash(translog.EntryText("acme/user", "v1.0.0", "sha256:00")),
+		translog.LeafHash(translog.EntryText("acme/billing", "v1.0.0", digest)),
+		translog.LeafHash(translog.EntryText("acme/user", "v1.1.0", "sha256:11")),
+	}
+	path, err := translog.InclusionProof(leaves, 1)
+	require.NoError(t, err)
+	statement := translog.Statement{Module: "acme/billing", Version: "v1.0.0", Digest: digest, Index: 1, TreeSize: 3, RootHash: hex.EncodeToString(translog.RootHash(leaves))}
+	attestation := &apitypes.ChecksumAttestation{
+		Index:     statement.Index,
+		TreeSize:  statement.TreeSize,
+		RootHash:  statement.RootHash,
+		Statement: string(statement.Marshal()),
+		Signature: translog.SignStatement(key, statement.Marshal()),
+	}
+	for _, h := range path {
+		attestation.Proof = append(attestation.Proof, hex.EncodeToString(h))
+	}
+	return attestation
+}
+
+func TestVerifyAttestation(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	zipData := []byte("artifact")
+
+	assert.NoError(t, verifyAttestation(pub, "acme/billing", "v1.0.0", zipData, testAttestation(t, key, zipData)))
+
+	// Other bytes than the registry logged
+	assert.ErrorContains(t, verifyAttestation(pub, "acme/billing", "v1.0.0", []byte("tampered"), testAttestation(t, key, zipData)), "digest")
+
+	// A statement for another version, or signed with another key
+	assert.ErrorContains(t, verifyAttestation(pub, "acme/billing", "v1.1.0", zipData, testAttestation(t, key, zipData)), "not acme/billing@v1.1.0")
+	_, otherKey, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, verifyAttestation(pub, "acme/billing", "v1.0.0", zipData, testAttestation(t, otherKey, zipData)), translog.ErrInvalidSignature)
+
+	// A proof that doesn't lead to the signed root
+	attestation := testAttestation(t, key, zipData)
+	attestation.Proof[0] = fmt.Sprintf("%064x", 0)
+	assert.ErrorIs(t, verifyAttestation(pub, "acme/billing", "v1.0.0", zipData, attestation), translog.ErrInvalidProof)
+
+	// Unsigned or missing statements are refused
+	attestation = testAttestation(t, key, zipData)
+	attestation.Signature = ""
+	assert.ErrorContains(t, verifyAttestation(pub, "acme/billing", "v1.0.0", zipData, attestation), "not signed")
+	assert.ErrorContains(t, verifyAttestation(pub, "acme/billing", "v1.0.0", zipData, nil), "no checksum statement")
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Version is the version of protoreg-cli, set at build time:
+//
+//	go build -ldflags "-X github.com/Suhaibinator/SProto/internal/cli.Version=v1.4.0" ./cmd/cli
+//
+// When unset, the module version of a `go install ...@version` build is used (see cliVersion).
+var Version = ""
+
+// cliVersion returns the version of protoreg-cli, or "dev" for builds from a source checkout.
+func cliVersion() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// userAgent identifies protoreg-cli to the registry, which reports the versions in use by each team in its
+// client inventory: "protoreg-cli/v1.4.0 (linux/amd64)".
+func userAgent() string {
+	return fmt.Sprintf("protoreg-cli/%s (%s/%s)", cliVersion(), runtime.GOOS, runtime.GOARCH)
+}
+
+// userAgentTransport sets the User-Agent of requests that don't have one. It wraps http.DefaultTransport,
+// so it applies to the requests of every command.
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context()) // A RoundTripper must not modify the request
+		req.Header.Set("User-Agent", userAgent())
+	}
+	return t.base.RoundTrip(req)
+}
+
+// installUserAgent makes every HTTP client using the default transport identify protoreg-cli.
+func installUserAgent() {
+	if _, installed := http.DefaultTransport.(*userAgentTransport); !installed {
+		http.DefaultTransport = &userAgentTransport{base: http.DefaultTransport}
+	}
+}
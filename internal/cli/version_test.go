@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserAgentTransport(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.UserAgent())
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: &userAgentTransport{base: http.DefaultTransport}}
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	req, err := http.NewRequest("GET", server.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("User-Agent", "custom/1.0")
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	if assert.Len(t, got, 2) {
+		assert.True(t, strings.HasPrefix(got[0], "protoreg-cli/"+cliVersion()+" ("), got[0])
+		assert.Equal(t, "custom/1.0", got[1]) // Requests setting their own keep it
+	}
+
+	Version = "v1.4.0"
+	defer func() { Version = "" }()
+	assert.Equal(t, "v1.4.0", cliVersion())
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// visibilityCmd represents the visibility command
+var visibilityCmd = &cobra.Command{
+	Use:   "visibility <namespace/module_name> <public|internal|private>",
+	Short: "Change who may read a module",
+	Long: `Changes the visibility of a module:
+
+  public    anyone can list and fetch it
+  internal  any valid token (the admin token or a read token) is required
+  private   only the admin token and read tokens granted the module
+
+Modules a caller may not read are reported as not found. Requires an API token.
+
+Examples:
+  protoreg-cli visibility payments/ledger private
+  protoreg-cli visibility mycompany/user public`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		apiToken, err := requireAPIToken()
+		if err != nil {
+			return err
+		}
+
+		moduleFullName := qualifyModule(args[0]) // The namespace may be omitted if a default namespace is configured
+		parts := strings.SplitN(moduleFullName, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return exitErrorf(ExitValidation, "invalid module name format %q: expected 'namespace/module_name'", moduleFullName)
+		}
+		if err := apitypes.ValidateVisibility(args[1]); err != nil {
+			return exitErrorf(ExitValidation, "invalid visibility: %w", err)
+		}
+
+		targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/visibility", strings.TrimSuffix(registryURL, "/"),
+			url.PathEscape(parts[0]), url.PathEscape(parts[1]))
+		payload, err := json.Marshal(apitypes.SetModuleVisibilityRequest{Visibility: args[1]})
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		req, err := http.NewRequest(http.MethodPut, targetURL, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+apiToken)
+		req.Header.Set("Content-Type", "application/json")
+		log.Debug("Updating module visibility", zap.String("url", targetURL))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to execute request: %w", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return registryError(resp.StatusCode, bodyBytes)
+		}
+
+		var status apitypes.ModuleVisibilityResponse
+		if err := json.Unmarshal(bodyBytes, &status); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		fmt.Printf("%s is now %s\n", moduleFullName, status.Visibility)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(visibilityCmd)
+}
@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	watchSince string
+	watchJSON  bool
+)
+
+// watchReconnectDelay is how long watch waits before reconnecting a stream that ended.
+const watchReconnectDelay = 5 * time.Second
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch <namespace/module_name>",
+	Short: "Print new versions of a module with their schema changes as they are published",
+	Long: `Keeps a stream open to the registry and prints every new version of a module as it is
+published, with the messages, fields, enums and methods added, removed or changed since the
+previous version. This is what editor integrations use to refresh completions.
+
+With --since, the versions published after that one are printed first. Dropped connections are
+resumed from the last version printed. With --json, each version is printed as one line of JSON
+(the registry's event), for scripts and editor plugins. Runs until interrupted.
+
+Examples:
+  protoreg-cli watch mycompany/user
+  protoreg-cli watch mycompany/user --since v1.2.0 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		log := GetLogger()
+		registryURL, err := requireRegistryURL()
+		if err != nil {
+			return err
+		}
+		namespace, moduleName, err := splitModuleArg(args[0])
+		if err != nil {
+			return err
+		}
+		if watchSince != "" && !strings.HasPrefix(watchSince, "v") {
+			return exitErrorf(ExitValidation, "invalid version format %q: must start with 'v'", watchSince)
+		}
+
+		client := &http.Client{} // No timeout: the stream stays open
+		lastID := watchSince
+		for {
+			err := watchModule(client, registryURL, namespace, moduleName, lastID, log, func(id string, event apitypes.VersionEvent) error {
+				lastID = id
+				if watchJSON {
+					data, err := json.Marshal(event)
+					if err != nil {
+						return err
+					}
+					fmt.Println(string(data))
+					return nil
+				}
+				printVersionEvent(event)
+				return nil
+			})
+			var statusErr *streamStatusError
+			if errors.As(err, &statusErr) {
+				return statusErr.err // The registry refused the stream; reconnecting won't help
+			}
+			log.Warn("Watch stream ended, reconnecting", zap.Error(err), zap.Duration("delay", watchReconnectDelay))
+			time.Sleep(watchReconnectDelay)
+		}
+	},
+}
+
+// streamStatusError is a watch request the registry answered with an error status.
+type streamStatusError struct{ err error }
+
+func (e *streamStatusError) Error() string { return e.err.Error() }
+
+// watchModule opens a watch stream of a module, resumed after the version lastID if set, and calls handle
+// for every version event until the stream ends.
+func watchModule(client *http.Client, registryURL, namespace, moduleName, lastID string, log *zap.Logger, handle func(string, apitypes.VersionEvent) error) error {
+	targetURL := fmt.Sprintf("%s/api/v1/modules/%s/%s/watch", strings.TrimSuffix(registryURL, "/"), url.PathEscape(namespace), url.PathEscape(moduleName))
+	log.Info("Watching module", zap.String("url", targetURL), zap.String("last_event_id", lastID))
+
+	req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	setReadToken(req)
+	req.Header.Set("Accept", "text/event-stream")
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return &streamStatusError{fmt.Errorf("%s/%s: %w", namespace, moduleName, registryError(resp.StatusCode, bodyBytes))}
+	}
+	return readWatchStream(resp.Body, handle)
+}
+
+// readWatchStream parses server-sent events and calls handle for every version event, with its ID. Other
+// events and comments (keepalives) are skipped. Returns io.EOF when the stream ends.
+func readWatchStream(r io.Reader, handle func(string, apitypes.VersionEvent) error) error {
+	reader := bufio.NewReader(r)
+	var id, eventType string
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err // A partial event is dropped; it's replayed on reconnection
+		}
+		line = strings.TrimRight(line, "\r\n")
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch {
+		case line == "":
+			if eventType == apitypes.WatchEventVersion && len(data) > 0 {
+				var event apitypes.VersionEvent
+				if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event); err != nil {
+					return fmt.Errorf("failed to parse event: %w", err)
+				}
+				if err := handle(id, event); err != nil {
+					return err
+				}
+			}
+			eventType, data = "", nil // The ID carries over, as in the SSE spec
+		case field == "": // Comment
+		case field == "id":
+			id = value
+		case field == "event":
+			eventType = value
+		case field == "data":
+			data = append(data, value)
+		}
+	}
+}
+
+// printVersionEvent prints a new version and its changes, one per line.
+func printVersionEvent(event apitypes.VersionEvent) {
+	since := ""
+	if event.PreviousVersion != "" {
+		since = " since " + event.PreviousVersion
+	}
+	fmt.Printf("%s/%s %s published %s: %d change(s)%s\n", event.Namespace, event.ModuleName, event.Version,
+		event.CreatedAt.Local().Format(time.RFC3339), len(event.Changes), since)
+	if event.DiffError != "" {
+		fmt.Printf("  (%s)\n", event.DiffError)
+	}
+	for _, c := range event.Changes {
+		line := fmt.Sprintf("  ~ %s %s", c.Kind, c.Element)
+		switch c.Change {
+		case descriptor.DiffAdded:
+			line = fmt.Sprintf("  + %s %s %s", c.Kind, c.Element, c.After)
+		case descriptor.DiffRemoved:
+			line = fmt.Sprintf("  - %s %s %s", c.Kind, c.Element, c.Before)
+		case descriptor.DiffChanged:
+			if c.Before != "" || c.After != "" {
+				line += fmt.Sprintf(" %s -> %s", c.Before, c.After)
+			}
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringVar(&watchSince, "since", "", "Print the versions published after this one first")
+	watchCmd.Flags().BoolVar(&watchJSON, "json", false, "Print each version as a line of JSON")
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWatchStream(t *testing.T) {
+	stream := ": keepalive\n\n" +
+		"id: v1.1.0\nevent: version\ndata: {\"namespace\":\"acme\",\"module_name\":\"user\",\"version\":\"v1.1.0\",\n" +
+		"data: \"changes\":[{\"change\":\"added\",\"kind\":\"field\",\"element\":\"user.v1.User.email\",\"file\":\"user/v1/user.proto\",\"after\":\"string = 2\"}]}\n\n" +
+		"event: other\ndata: {}\n\n" +
+		"id: v1.2.0\r\nevent: version\r\ndata: {\"version\":\"v1.2.0\",\"changes\":[]}\r\n\r\n" +
+		"id: v1.3.0\nevent: version\ndata: {\"vers" // Cut off
+
+	var ids []string
+	var events []apitypes.VersionEvent
+	err := readWatchStream(strings.NewReader(stream), func(id string, event apitypes.VersionEvent) error {
+		ids = append(ids, id)
+		events = append(events, event)
+		return nil
+	})
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, []string{"v1.1.0", "v1.2.0"}, ids)
+	require.Len(t, events, 2)
+	assert.Equal(t, "acme", events[0].Namespace)
+	require.Len(t, events[0].Changes, 1)
+	assert.Equal(t, "user.v1.User.email", events[0].Changes[0].Element)
+	assert.Equal(t, "v1.2.0", events[1].Version)
+
+	err = readWatchStream(strings.NewReader("event: version\ndata: {\n\n"), func(string, apitypes.VersionEvent) error { return nil })
+	assert.ErrorContains(t, err, "failed to parse event")
+}
@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	webhooksAddURL         string
+	webhooksAddSecret      string
+	webhooksAddEvents      []string
+	webhooksAddDescription string
+)
+
+// webhooksCmd groups the namespace webhook subscription commands
+var webhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "Manage the webhook subscriptions of a namespace",
+	Long: `Subscribes your team's endpoints to the registry events of a namespace (quota warnings,
+version sunsets, dev channel updates, digest mismatches), optionally filtered by event
+type. The registry posts each event as JSON, signed with the subscription's secret
+(X-SProto-Signature) if it has one.
+
+Requires the admin API token or a maintainer token of the namespace (configured on the
+registry in PROTOREG_MAINTAINER_TOKENS), as --api-token or --read-token.`,
+}
+
+// webhooksListCmd represents the webhooks list command
+var webhooksListCmd = &cobra.Command{
+	Use:   "list <namespace>",
+	Short: "List the webhook subscriptions of a namespace",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targetURL, token, err := webhooksRequestTarget(args[0])
+		if err != nil {
+			return err
+		}
+		bodyBytes, err := adminRequest(http.MethodGet, targetURL, token, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var list apitypes.ListWebhookSubscriptionsResponse
+		if err := json.Unmarshal(bodyBytes, &list); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		if len(list.Subscriptions) == 0 {
+			fmt.Printf("Namespace %s has no webhook subscriptions\n", args[0])
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tURL\tEVENTS\tSIGNED\tCREATED BY\tDESCRIPTION")
+		for _, s := range list.Subscriptions {
+			events := strings.Join(s.EventTypes, ",")
+			if events == "" {
+				events = "(all)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\t%s\n", s.ID, s.URL, events, s.HasSecret, s.CreatedBy, orDash(s.Description))
+		}
+		return tw.Flush()
+	},
+}
+
+// webhooksAddCmd represents the webhooks add command
+var webhooksAddCmd = &cobra.Command{
+	Use:   "add <namespace>",
+	Short: "Subscribe an endpoint to the events of a namespace",
+	Long: `Subscribes an endpoint to the events of a namespace. --event (repeatable) limits the
+subscription to event types or patterns such as 'module_version.*'; without it, every
+event of the namespace is sent.
+
+Examples:
+  protoreg-cli webhooks add payments --url https://hooks.example.com/registry --secret "$HOOK_SECRET"
+  protoreg-cli webhooks add payments --url https://chat.example.com/hook --event module_version.sunset --event 'module.quota_*'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targetURL, token, err := webhooksRequestTarget(args[0])
+		if err != nil {
+			return err
+		}
+		if webhooksAddURL == "" {
+			return exitErrorf(ExitUsage, "--url is required")
+		}
+		payload, err := json.Marshal(apitypes.WebhookSubscriptionRequest{URL: webhooksAddURL, Secret: webhooksAddSecret, EventTypes: webhooksAddEvents, Description: webhooksAddDescription})
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		bodyBytes, err := adminRequest(http.MethodPost, targetURL, token, payload, http.StatusCreated)
+		if err != nil {
+			return err
+		}
+		var s apitypes.WebhookSubscriptionResponse
+		if err := json.Unmarshal(bodyBytes, &s); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		fmt.Printf("Subscribed %s to the events of namespace %s (ID %s)\n", s.URL, s.Namespace, s.ID)
+		return nil
+	},
+}
+
+// webhooksGetCmd represents the webhooks get command
+var webhooksGetCmd = &cobra.Command{
+	Use:   "get <namespace> <id>",
+	Short: "Show a webhook subscription of a namespace",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targetURL, token, err := webhooksRequestTarget(args[0])
+		if err != nil {
+			return err
+		}
+		bodyBytes, err := adminRequest(http.MethodGet, targetURL+"/"+url.PathEscape(args[1]), token, nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var s apitypes.WebhookSubscriptionResponse
+		if err := json.Unmarshal(bodyBytes, &s); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		printWebhookSubscription(s)
+		return nil
+	},
+}
+
+// webhooksUpdateCmd represents the webhooks update command
+var webhooksUpdateCmd = &cobra.Command{
+	Use:   "update <namespace> <id>",
+	Short: "Replace a webhook subscription of a namespace",
+	Long: `Replaces the URL, secret, event types and description of a webhook subscription, keeping
+its ID. Everything is replaced: without --secret the requests are no longer signed, and
+without --event every event of the namespace is sent.
+
+Examples:
+  protoreg-cli webhooks update payments 6f1c... --url https://hooks.example.com/v2 --secret "$HOOK_SECRET"`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targetURL, token, err := webhooksRequestTarget(args[0])
+		if err != nil {
+			return err
+		}
+		if webhooksAddURL == "" {
+			return exitErrorf(ExitUsage, "--url is required")
+		}
+		payload, err := json.Marshal(apitypes.WebhookSubscriptionRequest{URL: webhooksAddURL, Secret: webhooksAddSecret, EventTypes: webhooksAddEvents, Description: webhooksAddDescription})
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		bodyBytes, err := adminRequest(http.MethodPut, targetURL+"/"+url.PathEscape(args[1]), token, payload, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var s apitypes.WebhookSubscriptionResponse
+		if err := json.Unmarshal(bodyBytes, &s); err != nil {
+			return fmt.Errorf("failed to parse API response: %w", err)
+		}
+		fmt.Printf("Updated webhook subscription %s of namespace %s\n", s.ID, s.Namespace)
+		printWebhookSubscription(s)
+		return nil
+	},
+}
+
+// webhooksDeleteCmd represents the webhooks delete command
+var webhooksDeleteCmd = &cobra.Command{
+	Use:   "delete <namespace> <id>",
+	Short: "Remove a webhook subscription of a namespace",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		targetURL, token, err := webhooksRequestTarget(args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := adminRequest(http.MethodDelete, targetURL+"/"+url.PathEscape(args[1]), token, nil, http.StatusNoContent); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted webhook subscription %s of namespace %s\n", args[1], args[0])
+		return nil
+	},
+}
+
+// webhooksRequestTarget returns the subscriptions URL of a namespace and the token managing them: the API
+// token if configured, otherwise the read token (a maintainer token is usually configured as one).
+func webhooksRequestTarget(namespace string) (string, string, error) {
+	registryURL, err := requireRegistryURL()
+	if err != nil {
+		return "", "", err
+	}
+	if err := validation.ValidateNamespace(namespace); err != nil {
+		return "", "", withExitCode(ExitValidation, err)
+	}
+	token := viper.GetString("api_token")
+	if token == "" {
+		token = viper.GetString("read_token")
+	}
+	if token == "" {
+		return "", "", exitErrorf(ExitAuth, "a token is required: the admin API token or a maintainer token of the namespace (--api-token or --read-token)")
+	}
+	return fmt.Sprintf("%s/api/v1/namespaces/%s/webhooks", strings.TrimSuffix(registryURL, "/"), url.PathEscape(namespace)), token, nil
+}
+
+func printWebhookSubscription(s apitypes.WebhookSubscriptionResponse) {
+	fmt.Printf("  ID:          %s\n", s.ID)
+	fmt.Printf("  URL:         %s\n", s.URL)
+	fmt.Printf("  Events:      %s\n", orNone(strings.Join(s.EventTypes, ", ")))
+	fmt.Printf("  Signed:      %t\n", s.HasSecret)
+	fmt.Printf("  Description: %s\n", orNone(s.Description))
+	fmt.Printf("  Created by:  %s\n", s.CreatedBy)
+	fmt.Printf("  Created:     %s\n", s.CreatedAt.Local().Format(time.RFC3339))
+}
+
+func init() {
+	rootCmd.AddCommand(webhooksCmd)
+	webhooksCmd.AddCommand(webhooksListCmd)
+	webhooksCmd.AddCommand(webhooksAddCmd)
+	webhooksCmd.AddCommand(webhooksGetCmd)
+	webhooksCmd.AddCommand(webhooksUpdateCmd)
+	webhooksCmd.AddCommand(webhooksDeleteCmd)
+
+	for _, cmd := range []*cobra.Command{webhooksAddCmd, webhooksUpdateCmd} {
+		cmd.Flags().StringVar(&webhooksAddURL, "url", "", "Endpoint receiving the events (http or https)")
+		cmd.Flags().StringVar(&webhooksAddSecret, "secret", "", "Secret signing each request (X-SProto-Signature: sha256=<hmac>)")
+		cmd.Flags().StringArrayVar(&webhooksAddEvents, "event", nil, "Event type or pattern to send, e.g. module_version.sunset or 'module.quota_*' (repeatable; default: all)")
+		cmd.Flags().StringVar(&webhooksAddDescription, "description", "", "What the subscription is for")
+	}
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -8,11 +10,21 @@ import (
 type Config struct {
 	// Server specific configuration
 	ServerPort string `mapstructure:"SERVER_PORT"`
+	LogLevel   string `mapstructure:"LOG_LEVEL"`  // "debug", "info", "warn" or "error"
+	LogFormat  string `mapstructure:"LOG_FORMAT"` // "json" or "console"
+
+	// Listen addresses: "host:port" or "unix:///path/to.sock"
+	ListenAddress        string `mapstructure:"LISTEN_ADDRESS"`         // API listener; ":<SERVER_PORT>" when empty
+	MetricsListenAddress string `mapstructure:"METRICS_LISTEN_ADDRESS"` // Separate /metrics listener; /metrics is served by the API listener when empty
 
 	// Database configuration
 	DbType     string `mapstructure:"DB_TYPE"`     // "postgres" or "sqlite"
 	DbDsn      string `mapstructure:"DB_DSN"`      // Data Source Name for Postgres
+	DbReadDsn  string `mapstructure:"DB_READ_DSN"` // Optional read replica DSN for GET endpoints (Postgres only)
 	SqlitePath string `mapstructure:"SQLITE_PATH"` // Path for SQLite database file
+	// Don't migrate the database at startup (operators run `sproto-server migrate-db` separately); the server
+	// then refuses to start until the database is at its schema version
+	SkipMigrations bool `mapstructure:"SKIP_MIGRATIONS"`
 
 	// Storage configuration
 	StorageType      string `mapstructure:"STORAGE_TYPE"`       // "minio" or "local"
@@ -25,9 +37,138 @@ type Config struct {
 	MinioBucket    string `mapstructure:"MINIO_BUCKET"`
 	MinioUseSSL    bool   `mapstructure:"MINIO_USE_SSL"`
 
+	// MinIO multipart uploads: artifacts larger than the part size are uploaded in parts, several at a time
+	MinioPartSizeBytes     int64 `mapstructure:"MINIO_PART_SIZE_BYTES"`    // At least 5 MiB (the S3 minimum)
+	MinioUploadConcurrency int   `mapstructure:"MINIO_UPLOAD_CONCURRENCY"` // Parts uploaded in parallel per artifact
+
+	// Storage tiering: new objects are written with the (hot) storage class, and artifacts of versions older
+	// than the tiering age are moved to the cold tier by a background job. Reads of cold artifacts are transparent.
+	MinioStorageClass      string        `mapstructure:"MINIO_STORAGE_CLASS"`      // e.g. STANDARD; empty uses the bucket's default
+	MinioColdStorageClass  string        `mapstructure:"MINIO_COLD_STORAGE_CLASS"` // e.g. STANDARD_IA or GLACIER_IR (instant retrieval only)
+	LocalColdStoragePath   string        `mapstructure:"LOCAL_COLD_STORAGE_PATH"`  // e.g. a directory on a cheaper disk
+	StorageTieringAge      time.Duration `mapstructure:"STORAGE_TIERING_AGE"`      // 0 disables tiering
+	StorageTieringInterval time.Duration `mapstructure:"STORAGE_TIERING_INTERVAL"`
+
 	// Authentication
 	AuthToken string `mapstructure:"AUTH_TOKEN"` // Static bearer token for publish operations
 
+	// Read authorization for internal/private modules (see api.ParseReadTokens)
+	ReadTokens              string `mapstructure:"READ_TOKENS"`               // "token[#consumer][@expiry][=pattern|pattern],...", e.g. "ci-token,payments-token#payments=payments/*"
+	MaintainerTokens        string `mapstructure:"MAINTAINER_TOKENS"`         // As READ_TOKENS with namespace patterns, e.g. "pay-token#payments=payments" (see api.ParseMaintainerTokens)
+	DefaultModuleVisibility string `mapstructure:"DEFAULT_MODULE_VISIBILITY"` // Visibility of modules created by a publish without ?visibility=
+	PublicRead              bool   `mapstructure:"PUBLIC_READ"`               // false for a private registry: every read needs a token
+
+	// Token lifecycle (see api.SetTokenPolicy)
+	AuthTokenExpiresAt string        `mapstructure:"AUTH_TOKEN_EXPIRES_AT"` // "2006-01-02" or RFC3339; empty if the admin token doesn't expire
+	StaleTokenAfter    time.Duration `mapstructure:"STALE_TOKEN_AFTER"`     // Tokens unused for this long are reported as stale
+
+	// Brute-force protection for authentication (see api.SetAuthFailurePolicy)
+	AuthMaxFailures    int           `mapstructure:"AUTH_MAX_FAILURES"`     // Failures per client IP within the window before a ban; 0 disables bans
+	AuthFailureWindow  time.Duration `mapstructure:"AUTH_FAILURE_WINDOW"`   // Window in which failures are counted
+	AuthBanDuration    time.Duration `mapstructure:"AUTH_BAN_DURATION"`     // How long a banned client can't authenticate
+	AuthFailureDelay   time.Duration `mapstructure:"AUTH_FAILURE_DELAY"`    // Delay of the first failure response, doubled per failure (max 5s); 0 disables
+	AuthClientIPHeader string        `mapstructure:"AUTH_CLIENT_IP_HEADER"` // e.g. "X-Forwarded-For" behind a trusted proxy; RemoteAddr when empty
+
+	// Network restrictions by client IP (see api.SetIPFilterPolicy)
+	WriteAllowedCIDRs string `mapstructure:"WRITE_ALLOWED_CIDRS"` // e.g. "10.20.0.0/16,192.0.2.7"; admin token routes accept any address when empty
+	DeniedCIDRs       string `mapstructure:"DENIED_CIDRS"`        // Rejected on every route
+
+	// Virus scanning (optional, disabled when ClamAVAddress is empty)
+	ClamAVAddress string        `mapstructure:"CLAMAV_ADDRESS"` // clamd address, e.g. "tcp://clamav:3310" or "unix:///run/clamd.sock"
+	ClamAVTimeout time.Duration `mapstructure:"CLAMAV_TIMEOUT"` // Timeout for a single scan
+	ClamAVPolicy  string        `mapstructure:"CLAMAV_POLICY"`  // "block" or "flag"
+
+	// Webhook notifications (optional, disabled when WebhookURL is empty)
+	WebhookURL     string        `mapstructure:"WEBHOOK_URL"`     // Events are POSTed here as JSON
+	WebhookSecret  string        `mapstructure:"WEBHOOK_SECRET"`  // If set, requests are signed (X-SProto-Signature: sha256=<hmac>)
+	WebhookTimeout time.Duration `mapstructure:"WEBHOOK_TIMEOUT"` // Timeout for a single delivery
+	// Lets namespace webhook subscriptions reach loopback, link-local and private addresses (refused by default)
+	WebhookAllowPrivateTargets bool `mapstructure:"WEBHOOK_ALLOW_PRIVATE_TARGETS"`
+
+	// Per-module soft storage quota (warnings only, publishes are never rejected)
+	ModuleSoftQuotaBytes   int64 `mapstructure:"MODULE_SOFT_QUOTA_BYTES"`   // 0 disables quota warnings
+	ModuleQuotaWarnPercent int   `mapstructure:"MODULE_QUOTA_WARN_PERCENT"` // Warn when usage crosses this percentage of the quota
+
+	// HTTP caching (Cache-Control) for CDN-fronted deployments
+	ArtifactCacheMaxAge time.Duration `mapstructure:"ARTIFACT_CACHE_MAX_AGE"` // Artifacts are immutable; 0 sends no-cache
+	ListCacheMaxAge     time.Duration `mapstructure:"LIST_CACHE_MAX_AGE"`     // Listings and metadata; 0 sends no-cache
+
+	// gRPC Server Reflection for stored schemas (disabled when both are empty)
+	GRPCPort          string `mapstructure:"GRPC_PORT"`
+	GRPCListenAddress string `mapstructure:"GRPC_LISTEN_ADDRESS"` // Takes precedence over GRPCPort; may be a Unix socket
+
+	// CDN signed URL mode (disabled when CDNBaseURL is empty)
+	CDNBaseURL    string        `mapstructure:"CDN_BASE_URL"`    // Artifact downloads redirect to signed URLs on this domain
+	CDNSigningKey string        `mapstructure:"CDN_SIGNING_KEY"` // HMAC-SHA256 key shared with the CDN (if it validates signatures)
+	CDNURLTTL     time.Duration `mapstructure:"CDN_URL_TTL"`     // Validity of issued signed URLs
+
+	// Background artifact integrity verification
+	IntegrityCheckInterval   time.Duration `mapstructure:"INTEGRITY_CHECK_INTERVAL"`    // 0 disables the job
+	IntegrityCheckSampleSize int           `mapstructure:"INTEGRITY_CHECK_SAMPLE_SIZE"` // Artifacts re-verified per run
+	IntegrityCheckPause      time.Duration `mapstructure:"INTEGRITY_CHECK_PAUSE"`       // Delay between artifacts within a run
+
+	// Request limits (keep metadata endpoints responsive during bursts of uploads/downloads)
+	MaxUploadSizeBytes           int64         `mapstructure:"MAX_UPLOAD_SIZE_BYTES"`           // Largest publish/impact request body
+	MaxConcurrentPublishes       int           `mapstructure:"MAX_CONCURRENT_PUBLISHES"`        // 0 = unlimited
+	MaxConcurrentArtifactStreams int           `mapstructure:"MAX_CONCURRENT_ARTIFACT_STREAMS"` // 0 = unlimited
+	LimitQueueTimeout            time.Duration `mapstructure:"LIMIT_QUEUE_TIMEOUT"`             // Wait for a free slot before responding 429
+
+	// Background operations (asynchronous publishes, admin jobs): workers and the operations waiting for them
+	OperationWorkers   int `mapstructure:"OPERATION_WORKERS"`    // 0 disables asynchronous publishes and admin jobs
+	OperationQueueSize int `mapstructure:"OPERATION_QUEUE_SIZE"` // Held in memory (with their uploads); more are rejected with 503
+
+	// Artifact content policy: what uploads may be and contain (namespace policies can replace each setting)
+	ArtifactContentTypes      string `mapstructure:"ARTIFACT_CONTENT_TYPES"`      // Comma-separated, detected from the content (e.g. "application/zip"); empty allows any
+	ArtifactMaxFileBytes      int64  `mapstructure:"ARTIFACT_MAX_FILE_BYTES"`     // Largest uncompressed file in an archive; 0 allows any size
+	ArtifactAllowedExtensions string `mapstructure:"ARTIFACT_ALLOWED_EXTENSIONS"` // Comma-separated, e.g. ".proto,.md"; empty allows any
+
+	// Publish policies (CEL expressions, disabled when PolicyFile is empty)
+	PolicyFile string `mapstructure:"POLICY_FILE"` // YAML file listing the policies
+
+	// Proto package index: whether a package may be declared by several modules
+	PackageOwnership string `mapstructure:"PACKAGE_OWNERSHIP"` // "unique" (rejects publishes claiming another module's package) or "shared"
+
+	// Tag versions whose compiled schema is identical to the previous version's ("no schema change")
+	DetectSchemaChanges bool `mapstructure:"DETECT_SCHEMA_CHANGES"`
+
+	// Go module proxy (/gomod) serving generated Go code, disabled when GoProxyModules is empty
+	GoProxyModulePrefix string `mapstructure:"GO_PROXY_MODULE_PREFIX"` // Module path prefix of generated modules, e.g. "buf.example.com/gen/go"
+	GoProxyModules      string `mapstructure:"GO_PROXY_MODULES"`       // Comma-separated namespace/name patterns with Go generation enabled, e.g. "acme/*"
+
+	// Maven (/maven) and npm (/npm) bridges serving generated stubs attached to versions, each disabled when empty
+	MavenGroupPrefix string `mapstructure:"MAVEN_GROUP_PREFIX"` // groupId prefix of bridged modules, e.g. "com.example.protos"
+	NPMScope         string `mapstructure:"NPM_SCOPE"`          // Scope of bridged packages, e.g. "@protos"
+
+	// Sunset dates of deprecated versions, enforced by a background job
+	SunsetEnforcement   string        `mapstructure:"SUNSET_ENFORCEMENT"`    // "block" (410 Gone) or "warn" (serve and log)
+	SunsetCheckInterval time.Duration `mapstructure:"SUNSET_CHECK_INTERVAL"` // 0 disables the job
+
+	// Original bytes of uploads that were re-packed into canonical form, kept for audits
+	OriginalUploadRetention       time.Duration `mapstructure:"ORIGINAL_UPLOAD_RETENTION"`        // 0 keeps none
+	OriginalUploadCleanupInterval time.Duration `mapstructure:"ORIGINAL_UPLOAD_CLEANUP_INTERVAL"` // Deletion of expired originals
+
+	// Dev channels (protoreg-cli dev): deleted once not updated for DEV_CHANNEL_TTL; 0 keeps them
+	DevChannelTTL time.Duration `mapstructure:"DEV_CHANNEL_TTL"`
+
+	// Deletion of expired versions in ephemeral namespaces (namespace policies with an ephemeral TTL)
+	EphemeralCleanupInterval time.Duration `mapstructure:"EPHEMERAL_CLEANUP_INTERVAL"` // 0 disables the job
+
+	// Storage and database capacity, collected in the background for /metrics and /readyz
+	CapacityCollectInterval time.Duration `mapstructure:"CAPACITY_COLLECT_INTERVAL"` // 0 disables the collector
+	StorageCapacityBytes    int64         `mapstructure:"STORAGE_CAPACITY_BYTES"`    // Bucket quota or disk size; 0 if unknown
+	DBCapacityBytes         int64         `mapstructure:"DB_CAPACITY_BYTES"`         // Database disk or plan size; 0 if unknown
+	CapacityWarnPercent     int           `mapstructure:"CAPACITY_WARN_PERCENT"`     // /readyz warns when usage crosses this percentage
+
+	// Ed25519 key (base64 seed, see `sproto-server gen-checksum-key`) signing checksum log statements
+	// in fetch and metadata responses; statements are unsigned when empty
+	ChecksumSigningKey string `mapstructure:"CHECKSUM_SIGNING_KEY"`
+
+	// Ed25519 key (base64 seed, see `sproto-server gen-signing-key`) signing each version's coordinates and
+	// digest at publish; versions are unsigned when empty. Retired keys (base64 public keys, comma-separated)
+	// stay published at /.well-known/sproto/signing-keys so signatures they made can still be verified
+	VersionSigningKey         string `mapstructure:"VERSION_SIGNING_KEY"`
+	VersionSigningRetiredKeys string `mapstructure:"VERSION_SIGNING_RETIRED_KEYS"`
+
 	// CLI specific configuration (can also be loaded by CLI)
 	RegistryURL string `mapstructure:"REGISTRY_URL"` // URL for the CLI to connect to
 }
@@ -36,9 +177,15 @@ type Config struct {
 func LoadConfig() (config Config, err error) {
 	// Set default values
 	viper.SetDefault("SERVER_PORT", "8080")
+	viper.SetDefault("LISTEN_ADDRESS", "") // ":<SERVER_PORT>" when empty
+	viper.SetDefault("METRICS_LISTEN_ADDRESS", "")
+	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_FORMAT", "json")  // JSON for log aggregation; use "console" for local development
 	viper.SetDefault("DB_TYPE", "postgres") // Default to postgres
 	viper.SetDefault("DB_DSN", "host=localhost user=postgres password=postgres dbname=sproto port=5432 sslmode=disable")
+	viper.SetDefault("DB_READ_DSN", "")                        // No read replica by default
 	viper.SetDefault("SQLITE_PATH", "sproto.db")               // Default SQLite path
+	viper.SetDefault("SKIP_MIGRATIONS", false)                 // Migrate at startup by default
 	viper.SetDefault("STORAGE_TYPE", "minio")                  // Default to minio
 	viper.SetDefault("LOCAL_STORAGE_PATH", "./sproto-storage") // Default local storage path
 	viper.SetDefault("MINIO_ENDPOINT", "localhost:9000")
@@ -46,7 +193,75 @@ func LoadConfig() (config Config, err error) {
 	viper.SetDefault("MINIO_SECRET_KEY", "minioadmin")
 	viper.SetDefault("MINIO_BUCKET", "sproto-artifacts")
 	viper.SetDefault("MINIO_USE_SSL", false)
+	viper.SetDefault("MINIO_PART_SIZE_BYTES", 16<<20) // 16 MiB
+	viper.SetDefault("MINIO_UPLOAD_CONCURRENCY", 4)
+	viper.SetDefault("MINIO_STORAGE_CLASS", "")      // Bucket default
+	viper.SetDefault("MINIO_COLD_STORAGE_CLASS", "") // No cold tier
+	viper.SetDefault("LOCAL_COLD_STORAGE_PATH", "")  // No cold tier
+	viper.SetDefault("STORAGE_TIERING_AGE", "0s")    // Tiering disabled by default
+	viper.SetDefault("STORAGE_TIERING_INTERVAL", "24h")
 	viper.SetDefault("AUTH_TOKEN", "supersecrettoken") // CHANGE THIS IN PRODUCTION
+	viper.SetDefault("CLAMAV_ADDRESS", "")             // Scanning disabled by default
+	viper.SetDefault("CLAMAV_TIMEOUT", "30s")
+	viper.SetDefault("CLAMAV_POLICY", "block")
+	viper.SetDefault("WEBHOOK_URL", "") // Notifications disabled by default
+	viper.SetDefault("WEBHOOK_SECRET", "")
+	viper.SetDefault("WEBHOOK_TIMEOUT", "5s")
+	viper.SetDefault("WEBHOOK_ALLOW_PRIVATE_TARGETS", false)
+	viper.SetDefault("MODULE_SOFT_QUOTA_BYTES", 0) // Quota warnings disabled by default
+	viper.SetDefault("MODULE_QUOTA_WARN_PERCENT", 80)
+	viper.SetDefault("ARTIFACT_CACHE_MAX_AGE", "8760h") // One year
+	viper.SetDefault("LIST_CACHE_MAX_AGE", "30s")
+	viper.SetDefault("GRPC_PORT", "")    // gRPC reflection disabled by default
+	viper.SetDefault("CDN_BASE_URL", "") // CDN mode disabled by default
+	viper.SetDefault("CDN_SIGNING_KEY", "")
+	viper.SetDefault("CDN_URL_TTL", "5m")
+	viper.SetDefault("GRPC_LISTEN_ADDRESS", "") // GRPC_PORT is used when empty
+	viper.SetDefault("INTEGRITY_CHECK_INTERVAL", "6h")
+	viper.SetDefault("INTEGRITY_CHECK_SAMPLE_SIZE", 20)
+	viper.SetDefault("INTEGRITY_CHECK_PAUSE", "1s")
+	viper.SetDefault("MAX_UPLOAD_SIZE_BYTES", 32<<20)      // 32 MB
+	viper.SetDefault("MAX_CONCURRENT_PUBLISHES", 0)        // Unlimited by default
+	viper.SetDefault("MAX_CONCURRENT_ARTIFACT_STREAMS", 0) // Unlimited by default
+	viper.SetDefault("LIMIT_QUEUE_TIMEOUT", "10s")
+	viper.SetDefault("OPERATION_WORKERS", 2)
+	viper.SetDefault("OPERATION_QUEUE_SIZE", 16)
+	viper.SetDefault("ARTIFACT_CONTENT_TYPES", "") // Any artifact content by default
+	viper.SetDefault("ARTIFACT_MAX_FILE_BYTES", 0)
+	viper.SetDefault("ARTIFACT_ALLOWED_EXTENSIONS", "")
+	viper.SetDefault("POLICY_FILE", "") // Publish policies disabled by default
+	viper.SetDefault("READ_TOKENS", "") // Only the admin token can read internal/private modules by default
+	viper.SetDefault("MAINTAINER_TOKENS", "")
+	viper.SetDefault("DEFAULT_MODULE_VISIBILITY", "public")
+	viper.SetDefault("PUBLIC_READ", true)          // Anonymous callers can read public modules by default
+	viper.SetDefault("AUTH_TOKEN_EXPIRES_AT", "")  // The admin token doesn't expire by default
+	viper.SetDefault("STALE_TOKEN_AFTER", "2160h") // 90 days
+	viper.SetDefault("AUTH_MAX_FAILURES", 10)
+	viper.SetDefault("AUTH_FAILURE_WINDOW", "15m")
+	viper.SetDefault("AUTH_BAN_DURATION", "15m")
+	viper.SetDefault("AUTH_FAILURE_DELAY", "100ms")
+	viper.SetDefault("AUTH_CLIENT_IP_HEADER", "") // Use the connection's address by default
+	viper.SetDefault("WRITE_ALLOWED_CIDRS", "")   // No network restrictions by default
+	viper.SetDefault("DENIED_CIDRS", "")
+	viper.SetDefault("DETECT_SCHEMA_CHANGES", false)
+	viper.SetDefault("GO_PROXY_MODULE_PREFIX", "")
+	viper.SetDefault("GO_PROXY_MODULES", "") // Go module proxy disabled by default
+	viper.SetDefault("MAVEN_GROUP_PREFIX", "")
+	viper.SetDefault("NPM_SCOPE", "") // Package bridges disabled by default
+	viper.SetDefault("PACKAGE_OWNERSHIP", "unique")
+	viper.SetDefault("CHECKSUM_SIGNING_KEY", "") // Statements unsigned by default
+	viper.SetDefault("VERSION_SIGNING_KEY", "")  // Versions unsigned by default
+	viper.SetDefault("VERSION_SIGNING_RETIRED_KEYS", "")
+	viper.SetDefault("SUNSET_ENFORCEMENT", "block")
+	viper.SetDefault("SUNSET_CHECK_INTERVAL", "1h")
+	viper.SetDefault("ORIGINAL_UPLOAD_RETENTION", "0s") // Original uploads aren't kept by default
+	viper.SetDefault("ORIGINAL_UPLOAD_CLEANUP_INTERVAL", "1h")
+	viper.SetDefault("DEV_CHANNEL_TTL", "0s") // Dev channels are kept until deleted by default
+	viper.SetDefault("EPHEMERAL_CLEANUP_INTERVAL", "1h")
+	viper.SetDefault("CAPACITY_COLLECT_INTERVAL", "5m")
+	viper.SetDefault("STORAGE_CAPACITY_BYTES", 0) // Capacity warnings disabled by default
+	viper.SetDefault("DB_CAPACITY_BYTES", 0)
+	viper.SetDefault("CAPACITY_WARN_PERCENT", 80)
 	viper.SetDefault("REGISTRY_URL", "http://localhost:8080")
 
 	// Tell viper to look for environment variables with a specific prefix
@@ -1,12 +1,14 @@
 package db
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/logging"
 	"github.com/Suhaibinator/SProto/internal/models"
+	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -16,12 +18,20 @@ import (
 // DB is the global database connection instance
 var DB *gorm.DB
 
-// Init initializes the database connection and runs migrations based on config.
+// closeReplica stops the health checks of the read replica set up by Init and closes its connections.
+var closeReplica func() error
+
+// migratedModels are the models whose tables are created by AutoMigrate (and whose rows are counted by Size).
+var migratedModels = []any{&models.Module{}, &models.ModuleVersion{}, &models.VersionNote{}, &models.VersionArtifact{}, &models.VersionFile{}, &models.DevChannel{}, &models.OriginalUpload{}, &models.NamespacePolicy{}, &models.WebhookSubscription{}, &models.ManagedToken{}, &models.TokenUsage{}, &models.ChecksumEntry{}, &models.Plugin{}, &models.PluginBinary{}, &models.ModuleConsumption{}, &models.Operation{}, &models.SearchDocument{}, &models.ProtoPackage{}, &models.ModuleFetchStat{}, &models.ClientUsage{}, &models.SchemaMigration{}}
+
+// Init initializes the database connection and runs migrations based on config. It fails if the schema
+// version of the database doesn't match this release's and can't be migrated to it (see CheckSchemaVersion).
 func Init(cfg config.Config) (*gorm.DB, error) { // Updated signature
 	var err error
 	var dialector gorm.Dialector // Use interface for flexibility
 	dbType := strings.ToLower(cfg.DbType)
-	log.Printf("Initializing database connection: type=%s", dbType)
+	log := logging.L().With(zap.String("db_type", dbType))
+	log.Info("Initializing database connection")
 
 	switch dbType {
 	case "postgres":
@@ -30,58 +40,105 @@ func Init(cfg config.Config) (*gorm.DB, error) { // Updated signature
 		}
 		dialector = postgres.Open(cfg.DbDsn)
 		// Avoid logging potentially sensitive DSN in production logs
-		log.Printf("Using PostgreSQL DSN (details omitted for security)")
+		log.Info("Using PostgreSQL DSN (details omitted for security)")
 	case "sqlite":
+		if cfg.DbReadDsn != "" {
+			return nil, fmt.Errorf("DB_READ_DSN is only supported for the postgres database type")
+		}
 		if cfg.SqlitePath == "" {
 			return nil, fmt.Errorf("SQLITE_PATH must be set for sqlite database type")
 		}
 		// Ensure the directory for the SQLite file exists (optional but good practice)
 		// dir := filepath.Dir(cfg.SqlitePath)
 		// if err := os.MkdirAll(dir, 0755); err != nil {
-		// 	 log.Printf("Failed to create directory for SQLite database: %v", err)
+		// 	 log.Error("Failed to create directory for SQLite database", zap.Error(err))
 		// 	 return nil, fmt.Errorf("failed to create directory for SQLite DB: %w", err)
 		// }
 		dialector = sqlite.Open(cfg.SqlitePath)
-		log.Printf("Using SQLite database file: %s", cfg.SqlitePath)
+		log.Info("Using SQLite database file", zap.String("path", cfg.SqlitePath))
 	default:
 		return nil, fmt.Errorf("invalid DB_TYPE: %s. Must be 'postgres' or 'sqlite'", cfg.DbType)
 	}
 
 	DB, err = gorm.Open(dialector, &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info), // Log SQL queries
+		Logger: newZapGormLogger(logger.Info), // Log SQL queries (at debug level) through zap
 	})
 
 	if err != nil {
-		log.Printf("Failed to connect to database (%s): %v", dbType, err)
+		log.Error("Failed to connect to database", zap.Error(err))
 		return nil, fmt.Errorf("failed to connect database (%s): %w", dbType, err)
 	}
 
-	log.Printf("Database connection established (%s).", dbType)
+	log.Info("Database connection established")
+	if err := DB.Use(&queryMetrics{provider: dbType}); err != nil { // Latency metrics
+		return nil, fmt.Errorf("failed to register query metrics: %w", err)
+	}
 
-	// Run migrations
-	log.Println("Running database migrations...")
-	err = DB.AutoMigrate(&models.Module{}, &models.ModuleVersion{})
+	// Schema version check, then migrations (see Migrate), unless operators run them separately
+	stored, err := CheckSchemaVersion(DB, !cfg.SkipMigrations)
 	if err != nil {
-		log.Printf("Failed to migrate database (%s): %v", dbType, err)
-		return nil, fmt.Errorf("failed to migrate database (%s): %w", dbType, err)
+		log.Error("Incompatible database schema", zap.Int("database_schema_version", stored), zap.Int("expected_schema_version", SchemaVersion), zap.Error(err))
+		return nil, err
+	}
+	if cfg.SkipMigrations {
+		log.Info("Skipping database migrations", zap.Int("schema_version", stored))
+	} else {
+		log.Info("Running database migrations...", zap.Int("from_schema_version", stored), zap.Int("to_schema_version", SchemaVersion))
+		collisions, err := Migrate(DB)
+		if err != nil {
+			log.Error("Failed to migrate database", zap.Error(err))
+			return nil, fmt.Errorf("failed to migrate database (%s): %w", dbType, err)
+		}
+		for _, c := range collisions {
+			// Each stays fetchable by its exact version; new versions colliding with them are rejected
+			log.Warn("Module has versions differing only in build metadata or letter case; the unique version key index is not created until only one of each remains",
+				zap.String("module", c.Module), zap.String("version_key", c.Key), zap.Strings("versions", c.Versions))
+		}
+		log.Info("Database migrations completed.")
+	}
+
+	// Read replica for GET endpoints (see GetReadDB)
+	ReadDB, closeReplica = nil, nil
+	if cfg.DbReadDsn != "" {
+		if ReadDB, err = initReadReplica(cfg.DbReadDsn, DB); err != nil {
+			log.Error("Failed to set up read replica", zap.Error(err))
+			return nil, err
+		}
 	}
-	log.Println("Database migrations completed.")
 
 	// Optional: Enable uuid-ossp extension if not already enabled - ONLY FOR POSTGRES
 	// You might need to run this manually or ensure the DB user has permissions
 	// result := DB.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp";`)
 	// if result.Error != nil {
-	//  log.Printf("Warning: Failed to ensure uuid-ossp extension exists: %v", result.Error)
+	//  log.Warn("Failed to ensure uuid-ossp extension exists", zap.Error(result.Error))
 	// }
 
 	return DB, nil
 }
 
+// Close stops the read replica's health checks and closes the connections of the primary and the read
+// replica opened by Init. The handles stay set but fail until Init is called again.
+func Close() error {
+	var errs []error
+	if closeReplica != nil {
+		errs = append(errs, closeReplica())
+		closeReplica = nil
+	}
+	if DB != nil {
+		sqlDB, err := DB.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
 // GetDB returns the initialized database instance.
 // Panics if Init has not been called successfully.
 func GetDB() *gorm.DB {
 	if DB == nil {
-		log.Fatal("Database has not been initialized. Call db.Init first.")
+		logging.L().Fatal("Database has not been initialized. Call db.Init first.")
 	}
 	return DB
 }
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is the duration above which queries are logged at warn level.
+const slowQueryThreshold = 200 * time.Millisecond
+
+// zapGormLogger adapts GORM's logger interface to zap so SQL logs share the server's
+// structured format and carry request fields when a request context is passed to GORM.
+type zapGormLogger struct {
+	level logger.LogLevel
+}
+
+// newZapGormLogger creates a GORM logger writing to zap at the given GORM log level.
+func newZapGormLogger(level logger.LogLevel) logger.Interface {
+	return &zapGormLogger{level: level}
+}
+
+// LogMode returns a copy of the logger with the given level.
+func (l *zapGormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &zapGormLogger{level: level}
+}
+
+func (l *zapGormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		logging.FromContext(ctx).Info(fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *zapGormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		logging.FromContext(ctx).Warn(fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *zapGormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		logging.FromContext(ctx).Error(fmt.Sprintf(msg, args...))
+	}
+}
+
+// Trace logs an executed SQL statement. Queries are logged at debug level,
+// slow queries at warn and failed queries (other than "record not found") at error.
+func (l *zapGormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	log := logging.FromContext(ctx)
+	sql, rows := fc()
+	fields := []zap.Field{
+		zap.String("sql", sql),
+		zap.Int64("rows", rows),
+		zap.Duration("elapsed", elapsed),
+	}
+
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		log.Error("SQL query failed", append(fields, zap.Error(err))...)
+	case elapsed > slowQueryThreshold && l.level >= logger.Warn:
+		log.Warn("Slow SQL query", fields...)
+	case l.level >= logger.Info:
+		log.Debug("SQL query", fields...)
+	}
+}
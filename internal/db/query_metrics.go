@@ -0,0 +1,74 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"gorm.io/gorm"
+)
+
+// Names of the metrics callbacks, and the statement instance setting holding a statement's start time.
+const (
+	queryTimerCallback    = "sproto:query_timer"
+	queryObserverCallback = "sproto:query_observer"
+	queryStartKey         = "sproto:query_start"
+)
+
+// queryMetrics is a GORM plugin recording the latency and outcome of every statement in
+// metrics.DBQueryDuration, so slow publishes can be attributed to the database (or not).
+type queryMetrics struct {
+	provider string // Provider label: "postgres" or "sqlite"
+}
+
+// Name implements gorm.Plugin.
+func (m *queryMetrics) Name() string {
+	return "sproto:query_metrics"
+}
+
+// Initialize implements gorm.Plugin. Each callback chain (query family) is timed from before its first
+// callback to after its last one.
+func (m *queryMetrics) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register(queryTimerCallback, startQueryTimer),
+		cb.Create().After("*").Register(queryObserverCallback, m.observer("create")),
+		cb.Query().Before("*").Register(queryTimerCallback, startQueryTimer),
+		cb.Query().After("*").Register(queryObserverCallback, m.observer("query")),
+		cb.Update().Before("*").Register(queryTimerCallback, startQueryTimer),
+		cb.Update().After("*").Register(queryObserverCallback, m.observer("update")),
+		cb.Delete().Before("*").Register(queryTimerCallback, startQueryTimer),
+		cb.Delete().After("*").Register(queryObserverCallback, m.observer("delete")),
+		cb.Row().Before("*").Register(queryTimerCallback, startQueryTimer),
+		cb.Row().After("*").Register(queryObserverCallback, m.observer("row")),
+		cb.Raw().Before("*").Register(queryTimerCallback, startQueryTimer),
+		cb.Raw().After("*").Register(queryObserverCallback, m.observer("raw")),
+	)
+}
+
+// startQueryTimer records the start time of a statement.
+func startQueryTimer(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+// observer returns the callback observing the duration of a statement of the given family.
+func (m *queryMetrics) observer(family string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		start, isTime := value.(time.Time)
+		if !ok || !isTime {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "none" // Raw SQL
+		}
+		outcome := "ok"
+		if errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			outcome = "not_found"
+		} else if db.Error != nil {
+			outcome = "error"
+		}
+		metrics.DBQueryDuration.WithLabelValues(m.provider, family, table, outcome).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// queryCount returns the number of statements observed with the given labels.
+func queryCount(t *testing.T, family, table, outcome string) uint64 {
+	var m dto.Metric
+	require.NoError(t, metrics.DBQueryDuration.WithLabelValues("sqlite", family, table, outcome).(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestQueryMetrics(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "metrics.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormDB.Use(&queryMetrics{provider: "sqlite"}))
+	require.NoError(t, gormDB.AutoMigrate(&marker{}))
+
+	metrics.DBQueryDuration.Reset()
+	require.NoError(t, gormDB.Create(&marker{Name: "a"}).Error)
+	var m marker
+	require.NoError(t, gormDB.First(&m, "name = ?", "a").Error)
+	assert.ErrorIs(t, gormDB.First(&m, "name = ?", "missing").Error, gorm.ErrRecordNotFound)
+	require.NoError(t, gormDB.Exec("UPDATE markers SET name = ?", "b").Error)
+
+	assert.Equal(t, uint64(1), queryCount(t, "create", "markers", "ok"))
+	assert.Equal(t, uint64(1), queryCount(t, "query", "markers", "ok"))
+	assert.Equal(t, uint64(1), queryCount(t, "query", "markers", "not_found"))
+	assert.Equal(t, uint64(1), queryCount(t, "raw", "none", "ok"))
+}
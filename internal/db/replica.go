@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Read replica (DB_READ_DSN): GET endpoints read through ReadDB, so heavy read traffic (e.g. CI fetching
+// dependencies) doesn't compete with publish transactions on the primary. Writes and transactions through
+// ReadDB still go to the primary, and reads fall back to it while the replica fails its health check.
+
+// How often the replica is pinged, and how long a ping may take.
+const (
+	replicaCheckInterval = 10 * time.Second
+	replicaCheckTimeout  = 2 * time.Second
+)
+
+// ReadDB is the database handle for read-only requests; nil when no read replica is configured.
+var ReadDB *gorm.DB
+
+// replicaPolicy is the dbresolver policy choosing between the replica and the primary (in that order):
+// the replica while its last health check succeeded, the primary otherwise.
+type replicaPolicy struct {
+	healthy atomic.Bool
+}
+
+// Resolve implements dbresolver.Policy.
+func (p *replicaPolicy) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	if p.healthy.Load() {
+		return connPools[0]
+	}
+	return connPools[1]
+}
+
+// initReadReplica connects to the read replica and returns the read handle. The replica doesn't have to
+// be reachable at startup: reads use the primary until it is.
+func initReadReplica(readDsn string, primary *gorm.DB) (*gorm.DB, error) {
+	log := logging.L()
+	primarySQL, err := primary.DB()
+	if err != nil {
+		return nil, err
+	}
+	replica, err := gorm.Open(postgres.Open(readDsn), &gorm.Config{Logger: newZapGormLogger(logger.Info), DisableAutomaticPing: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open read replica: %w", err)
+	}
+	replicaSQL, err := replica.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &replicaPolicy{}
+	readDB, err := newReadDB(primarySQL, replicaSQL, func(conn gorm.ConnPool) gorm.Dialector {
+		return postgres.New(postgres.Config{Conn: conn})
+	}, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Initial check; later changes are logged by checkReplica
+	ctx, cancel := context.WithTimeout(context.Background(), replicaCheckTimeout)
+	err = replicaSQL.PingContext(ctx)
+	cancel()
+	policy.healthy.Store(err == nil)
+	if err != nil {
+		log.Warn("Read replica is unreachable; reading from the primary until it is", zap.Error(err))
+	} else {
+		log.Info("Read replica connection established")
+	}
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	go monitorReplica(monitorCtx, replicaSQL, policy)
+	closeReplica = func() error {
+		stopMonitor()
+		return replicaSQL.Close()
+	}
+	return readDB, nil
+}
+
+// newReadDB returns a handle on the primary's connection pool whose reads go to replica or, per policy,
+// the primary. wrap opens a dialector on an existing connection pool.
+func newReadDB(primary, replica *sql.DB, wrap func(gorm.ConnPool) gorm.Dialector, policy dbresolver.Policy) (*gorm.DB, error) {
+	readDB, err := gorm.Open(wrap(primary), &gorm.Config{
+		Logger:               newZapGormLogger(logger.Info),
+		DisableAutomaticPing: true, // Also skips pinging the replica when it is registered below
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open read handle: %w", err)
+	}
+	err = readDB.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: []gorm.Dialector{wrap(replica), wrap(primary)}, // Order expected by replicaPolicy
+		Policy:   policy,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register read replica: %w", err)
+	}
+	if err := readDB.Use(&queryMetrics{provider: readDB.Dialector.Name()}); err != nil {
+		return nil, fmt.Errorf("failed to register query metrics: %w", err)
+	}
+	return readDB, nil
+}
+
+// checkReplica pings the replica and records the result in policy, logging changes.
+func checkReplica(ctx context.Context, replica *sql.DB, policy *replicaPolicy) {
+	ctx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+	defer cancel()
+	err := replica.PingContext(ctx)
+	if wasHealthy := policy.healthy.Swap(err == nil); wasHealthy && err != nil {
+		logging.L().Warn("Read replica is unreachable; falling back to the primary", zap.Error(err))
+	} else if !wasHealthy && err == nil {
+		logging.L().Info("Read replica is reachable again; reading from it")
+	}
+}
+
+// monitorReplica re-checks the replica every replicaCheckInterval until ctx is canceled.
+func monitorReplica(ctx context.Context, replica *sql.DB, policy *replicaPolicy) {
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkReplica(ctx, replica, policy)
+		}
+	}
+}
+
+// GetReadDB returns the handle for read-only requests: the read replica's if configured, the primary's otherwise.
+func GetReadDB() *gorm.DB {
+	if ReadDB != nil {
+		return ReadDB
+	}
+	return GetDB()
+}
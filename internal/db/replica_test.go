@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type marker struct {
+	ID   uint
+	Name string
+}
+
+// openMarkerDB opens a SQLite database holding a single marker row named name.
+func openMarkerDB(t *testing.T, name string) *sql.DB {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), name+".db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormDB.AutoMigrate(&marker{}))
+	require.NoError(t, gormDB.Create(&marker{Name: name}).Error)
+	sqlDB, err := gormDB.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	return sqlDB
+}
+
+func TestReadDB_FallsBackToPrimary(t *testing.T) {
+	primary, replica := openMarkerDB(t, "primary"), openMarkerDB(t, "replica")
+	policy := &replicaPolicy{}
+	readDB, err := newReadDB(primary, replica, func(conn gorm.ConnPool) gorm.Dialector {
+		return &sqlite.Dialector{Conn: conn}
+	}, policy)
+	require.NoError(t, err)
+	read := func() string {
+		var m marker
+		require.NoError(t, readDB.First(&m).Error)
+		return m.Name
+	}
+
+	// Reads go to the replica while it is healthy
+	checkReplica(context.Background(), replica, policy)
+	assert.Equal(t, "replica", read())
+
+	// Writes always go to the primary
+	require.NoError(t, readDB.Create(&marker{Name: "written"}).Error)
+	var count int64
+	require.NoError(t, primary.QueryRow("SELECT COUNT(*) FROM markers").Scan(&count))
+	assert.Equal(t, int64(2), count)
+
+	// An unreachable replica sends reads to the primary
+	replica.Close()
+	checkReplica(context.Background(), replica, policy)
+	assert.Equal(t, "primary", read())
+}
+
+func TestMonitorReplica_StopsWithContext(t *testing.T) {
+	replica := openMarkerDB(t, "replica")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		monitorReplica(ctx, replica, &replicaPolicy{})
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitorReplica didn't return after its context was canceled")
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Schema versions: each release expects its database schema at SchemaVersion, and migrating records it in
+// schema_migrations. At startup the recorded version is compared with the binary's, so a server never runs
+// against a schema it doesn't know: an older one is migrated (or refused, when operators skip migrations to
+// run them separately), a newer one is always refused (it was migrated by a later release, e.g. before a
+// rollback). Databases from before schema versions were recorded are at version 0.
+
+// SchemaVersion is the database schema version this binary expects. Bump it with every change to the
+// migrations (a new model or column, an index, a data migration).
+const SchemaVersion = 1
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer release.
+var ErrSchemaTooNew = errors.New("database schema is newer than this release supports")
+
+// ErrSchemaOutdated is returned when the database needs migrating but migrations are skipped.
+var ErrSchemaOutdated = errors.New("database schema is older than this release expects")
+
+// StoredSchemaVersion returns the schema version recorded in the database: 0 if none was recorded.
+func StoredSchemaVersion(gormDB *gorm.DB) (int, error) {
+	if !gormDB.Migrator().HasTable(&models.SchemaMigration{}) {
+		return 0, nil
+	}
+	var version *int
+	if err := gormDB.Model(&models.SchemaMigration{}).Select("MAX(version)").Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("failed to read the database schema version: %w", err)
+	}
+	if version == nil {
+		return 0, nil
+	}
+	return *version, nil
+}
+
+// CheckSchemaVersion compares the database's schema version with SchemaVersion and returns it. It fails
+// with ErrSchemaTooNew if the database was migrated by a newer release and, unless migrate is set (the
+// caller migrates next), with ErrSchemaOutdated if the database still needs migrating.
+func CheckSchemaVersion(gormDB *gorm.DB, migrate bool) (int, error) {
+	stored, err := StoredSchemaVersion(gormDB)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case stored > SchemaVersion:
+		return stored, fmt.Errorf("%w: the database is at schema version %d, this release at %d. It was migrated by a newer release: run that release (or a later one), or restore a backup taken before the upgrade", ErrSchemaTooNew, stored, SchemaVersion)
+	case stored < SchemaVersion && !migrate:
+		return stored, fmt.Errorf("%w: the database is at schema version %d, this release at %d. Migrate it first with `sproto-server migrate-db`, or start without --skip-migrations (PROTOREG_SKIP_MIGRATIONS)", ErrSchemaOutdated, stored, SchemaVersion)
+	}
+	return stored, nil
+}
+
+// Migrate creates and updates the tables, indexes and data of the schema, then records SchemaVersion. It is
+// safe to run again. Versions whose keys collide are returned, as by MigrateVersionKeys.
+func Migrate(gormDB *gorm.DB) ([]VersionKeyCollision, error) {
+	if err := gormDB.AutoMigrate(migratedModels...); err != nil {
+		return nil, err
+	}
+	if err := MigrateSearchIndex(gormDB); err != nil { // Full-text index, not managed by AutoMigrate
+		return nil, err
+	}
+	collisions, err := MigrateVersionKeys(gormDB)
+	if err != nil {
+		return nil, err
+	}
+	migration := models.SchemaMigration{Version: SchemaVersion, MigratedAt: time.Now().UTC()}
+	if err := gormDB.Clauses(clause.OnConflict{DoNothing: true}).Create(&migration).Error; err != nil {
+		return nil, fmt.Errorf("failed to record the database schema version: %w", err)
+	}
+	return collisions, nil
+}
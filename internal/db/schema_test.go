@@ -0,0 +1,80 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "schema.db")), &gorm.Config{})
+	require.NoError(t, err)
+
+	// A new (or pre-versioning) database needs migrating
+	stored, err := CheckSchemaVersion(gormDB, false)
+	assert.ErrorIs(t, err, ErrSchemaOutdated)
+	assert.Zero(t, stored)
+	_, err = CheckSchemaVersion(gormDB, true)
+	require.NoError(t, err)
+
+	_, err = Migrate(gormDB)
+	require.NoError(t, err)
+	_, err = Migrate(gormDB) // Safe to run again
+	require.NoError(t, err)
+	stored, err = CheckSchemaVersion(gormDB, false)
+	require.NoError(t, err)
+	assert.Equal(t, SchemaVersion, stored)
+
+	// Migrated by a newer release: refused, even when migrating
+	require.NoError(t, gormDB.Create(&models.SchemaMigration{Version: SchemaVersion + 1, MigratedAt: time.Now()}).Error)
+	for _, migrate := range []bool{false, true} {
+		stored, err = CheckSchemaVersion(gormDB, migrate)
+		assert.ErrorIs(t, err, ErrSchemaTooNew)
+		assert.Equal(t, SchemaVersion+1, stored)
+	}
+}
+
+// schemaFingerprints records the fingerprint of the migrated schema (see migratedSchemaFingerprint) of each
+// SchemaVersion. A change to the migrations changes the fingerprint: bump SchemaVersion and record the new
+// one here, so existing databases are migrated (or refused) rather than used with a schema they don't have.
+var schemaFingerprints = map[int]string{
+	1: "d3bcc4dba0efc486e9abd9aed20a98f1b68924de1641591dc5474cf12841942f",
+}
+
+func TestSchemaVersionMatchesMigrations(t *testing.T) {
+	fingerprint := migratedSchemaFingerprint(t)
+	recorded, ok := schemaFingerprints[SchemaVersion]
+	require.True(t, ok, "no fingerprint recorded for SchemaVersion %d: record %q", SchemaVersion, fingerprint)
+	assert.Equal(t, recorded, fingerprint, "the migrated schema changed without a SchemaVersion bump: bump SchemaVersion and record the new fingerprint in schemaFingerprints")
+}
+
+// migratedSchemaFingerprint migrates an empty SQLite database and hashes the definitions of its tables,
+// indexes and triggers: the models' columns, keys and indexes, and the search index.
+func migratedSchemaFingerprint(t *testing.T) string {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "fingerprint.db")), &gorm.Config{})
+	require.NoError(t, err)
+	_, err = Migrate(gormDB)
+	require.NoError(t, err)
+
+	var definitions []struct {
+		Type string
+		Name string
+		SQL  string `gorm:"column:sql"`
+	}
+	require.NoError(t, gormDB.Raw("SELECT type, name, sql FROM sqlite_master WHERE sql IS NOT NULL ORDER BY type, name").Scan(&definitions).Error)
+	require.NotEmpty(t, definitions)
+	hash := sha256.New()
+	for _, d := range definitions {
+		fmt.Fprintf(hash, "%s %s\n%s\n", d.Type, d.Name, d.SQL)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Full-text search index over models.SearchDocument. The index is dialect-specific, so it is created with
+// raw SQL after AutoMigrate:
+//   - PostgreSQL: a generated tsvector column (terms weighted above the body) with a GIN index, queried
+//     with websearch_to_tsquery / ts_rank / ts_headline.
+//   - SQLite: an FTS4 table (the mattn driver isn't built with FTS5) with the porter stemmer, kept in sync
+//     with search_documents by triggers, using the document ID as its docid.
+
+// SearchFTSTable is the SQLite FTS4 table indexing search_documents (columns terms and body).
+const SearchFTSTable = "search_documents_fts"
+
+var searchIndexStatements = map[string][]string{
+	"postgres": {
+		`ALTER TABLE search_documents ADD COLUMN IF NOT EXISTS tsv tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', coalesce(terms, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(body, '')), 'B')
+		) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_search_documents_tsv ON search_documents USING GIN (tsv)`,
+	},
+	"sqlite": {
+		`CREATE VIRTUAL TABLE IF NOT EXISTS ` + SearchFTSTable + ` USING fts4(terms, body, tokenize=porter)`,
+		`CREATE TRIGGER IF NOT EXISTS search_documents_fts_insert AFTER INSERT ON search_documents BEGIN
+			INSERT INTO ` + SearchFTSTable + `(docid, terms, body) VALUES (new.id, new.terms, new.body);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_documents_fts_update AFTER UPDATE ON search_documents BEGIN
+			UPDATE ` + SearchFTSTable + ` SET terms = new.terms, body = new.body WHERE docid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_documents_fts_delete AFTER DELETE ON search_documents BEGIN
+			DELETE FROM ` + SearchFTSTable + ` WHERE docid = old.id;
+		END`,
+	},
+}
+
+// MigrateSearchIndex creates the full-text index of the search_documents table (which must exist) if it
+// doesn't exist yet. Called by Migrate after AutoMigrate.
+func MigrateSearchIndex(gormDB *gorm.DB) error {
+	dialect := gormDB.Dialector.Name()
+	statements, ok := searchIndexStatements[dialect]
+	if !ok {
+		return fmt.Errorf("full-text search is not supported on %s", dialect)
+	}
+	for _, stmt := range statements {
+		if err := gormDB.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create the search index: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMigrateSearchIndex_SQLite(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "search.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormDB.AutoMigrate(&models.SearchDocument{}))
+	require.NoError(t, MigrateSearchIndex(gormDB))
+	require.NoError(t, MigrateSearchIndex(gormDB), "migrating twice is a no-op")
+
+	matches := func(query string) []int64 {
+		var ids []int64
+		require.NoError(t, gormDB.Raw(`SELECT docid FROM `+SearchFTSTable+` WHERE `+SearchFTSTable+` MATCH ? ORDER BY docid`, query).Scan(&ids).Error)
+		return ids
+	}
+
+	moduleID := uuid.New()
+	docs := []models.SearchDocument{
+		{ModuleID: moduleID, ModuleVersionID: uuid.New(), Kind: "message", Name: "orders.v1.Order", Terms: "orders v1 Order", Body: "An order placed by a customer"},
+		{ModuleID: moduleID, ModuleVersionID: uuid.New(), Kind: "message", Name: "orders.v1.Refund", Terms: "orders v1 Refund", Body: "Money returned to customers"},
+	}
+	require.NoError(t, gormDB.Create(&docs).Error)
+
+	// Inserts are indexed, with stemming (customers matches customer)
+	assert.Equal(t, []int64{docs[0].ID, docs[1].ID}, matches("customer"))
+	assert.Equal(t, []int64{docs[1].ID}, matches("refunds"))
+
+	// Updates and deletes are reflected
+	require.NoError(t, gormDB.Model(&docs[0]).Update("body", "A purchase").Error)
+	assert.Equal(t, []int64{docs[1].ID}, matches("customer"))
+	require.NoError(t, gormDB.Delete(&docs[1]).Error)
+	assert.Empty(t, matches("customer"))
+	assert.Equal(t, []int64{docs[0].ID}, matches("purchase"))
+}
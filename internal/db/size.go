@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SizeStats is how much the metadata database stores.
+type SizeStats struct {
+	Bytes int64            // On-disk size of the database (PostgreSQL: the current database; SQLite: the file)
+	Rows  map[string]int64 // Row count of each table created by Init
+}
+
+// sizeQueries return the on-disk size of the database in bytes, by dialect.
+var sizeQueries = map[string]string{
+	"postgres": `SELECT pg_database_size(current_database())`,
+	"sqlite":   `SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()`,
+}
+
+// Size returns the size of the database and the row count of its tables. The rows are counted exactly,
+// so it is meant for periodic collection (see api.RunCapacityCollector), not for request paths.
+func Size(ctx context.Context, gormDB *gorm.DB) (SizeStats, error) {
+	gormDB = gormDB.WithContext(ctx)
+	dialect := gormDB.Dialector.Name()
+	query, ok := sizeQueries[dialect]
+	if !ok {
+		return SizeStats{}, fmt.Errorf("database size is not supported on %s", dialect)
+	}
+	stats := SizeStats{Rows: make(map[string]int64, len(migratedModels))}
+	if err := gormDB.Raw(query).Scan(&stats.Bytes).Error; err != nil {
+		return SizeStats{}, fmt.Errorf("failed to query database size: %w", err)
+	}
+	for _, model := range migratedModels {
+		stmt := &gorm.Statement{DB: gormDB}
+		if err := stmt.Parse(model); err != nil {
+			return SizeStats{}, err
+		}
+		var count int64
+		if err := gormDB.Table(stmt.Schema.Table).Count(&count).Error; err != nil {
+			return SizeStats{}, fmt.Errorf("failed to count rows of %s: %w", stmt.Schema.Table, err)
+		}
+		stats.Rows[stmt.Schema.Table] = count
+	}
+	return stats, nil
+}
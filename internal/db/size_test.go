@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSize_SQLite(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "size.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormDB.AutoMigrate(migratedModels...))
+	require.NoError(t, gormDB.Create(&[]models.Module{{Namespace: "acme", Name: "user"}, {Namespace: "acme", Name: "billing"}}).Error)
+
+	stats, err := Size(context.Background(), gormDB)
+	require.NoError(t, err)
+	assert.Greater(t, stats.Bytes, int64(0))
+	assert.Equal(t, int64(2), stats.Rows["modules"])
+	assert.Equal(t, int64(0), stats.Rows["module_versions"])
+	assert.Len(t, stats.Rows, len(migratedModels))
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Version keys: module_versions.version_key holds validation.VersionKey(version), the version without build
+// metadata and lowercased, so near-duplicates like v1.0.0 and v1.0.0+build1 are the same version. Publishing
+// checks it, and a unique index on (module_id, version_key) backs the check once no existing versions
+// collide. Versions published before keys were introduced may collide; they are reported, not changed.
+
+// VersionKeyIndex is the unique index on module_versions (module_id, version_key).
+const VersionKeyIndex = "uq_module_version_key"
+
+// VersionKeyCollision is a set of published versions of a module sharing a version key.
+type VersionKeyCollision struct {
+	Module   string   // namespace/name
+	Key      string   // The shared key
+	Versions []string // The versions, sorted
+}
+
+// MigrateVersionKeys fills in the version keys of versions that have none, then creates the unique index
+// on them unless existing versions collide, in which case it returns the collisions (and no error) so they
+// can be reported. Called by Migrate after AutoMigrate; migrating again once the collisions are resolved
+// creates the index.
+func MigrateVersionKeys(gormDB *gorm.DB) ([]VersionKeyCollision, error) {
+	var missing []models.ModuleVersion
+	if err := gormDB.Select("id", "version").Where("version_key = ''").Find(&missing).Error; err != nil {
+		return nil, fmt.Errorf("failed to list versions without a version key: %w", err)
+	}
+	for _, mv := range missing {
+		if err := gormDB.Model(&models.ModuleVersion{}).Where("id = ?", mv.ID).Update("version_key", validation.VersionKey(mv.Version)).Error; err != nil {
+			return nil, fmt.Errorf("failed to set the version key of %s: %w", mv.Version, err)
+		}
+	}
+
+	collisions, err := FindVersionKeyCollisions(gormDB)
+	if err != nil {
+		return nil, err
+	}
+	if len(collisions) > 0 {
+		return collisions, nil
+	}
+	if err := gormDB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS ` + VersionKeyIndex + ` ON module_versions (module_id, version_key)`).Error; err != nil {
+		return nil, fmt.Errorf("failed to create the version key index: %w", err)
+	}
+	return nil, nil
+}
+
+// FindVersionKeyCollisions lists the versions of each module that share a version key, by module and key.
+func FindVersionKeyCollisions(gormDB *gorm.DB) ([]VersionKeyCollision, error) {
+	var groups []struct {
+		ModuleID   uuid.UUID
+		VersionKey string
+	}
+	err := gormDB.Model(&models.ModuleVersion{}).Select("module_id, version_key").
+		Group("module_id, version_key").Having("COUNT(*) > 1").Scan(&groups).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to look for colliding versions: %w", err)
+	}
+
+	collisions := make([]VersionKeyCollision, 0, len(groups))
+	for _, g := range groups {
+		var module models.Module
+		if err := gormDB.Select("namespace", "name").Where("id = ?", g.ModuleID).First(&module).Error; err != nil {
+			return nil, fmt.Errorf("failed to look up the module of colliding versions: %w", err)
+		}
+		var versions []string
+		if err := gormDB.Model(&models.ModuleVersion{}).Where("module_id = ? AND version_key = ?", g.ModuleID, g.VersionKey).Order("version").Pluck("version", &versions).Error; err != nil {
+			return nil, fmt.Errorf("failed to list colliding versions: %w", err)
+		}
+		collisions = append(collisions, VersionKeyCollision{Module: module.Namespace + "/" + module.Name, Key: g.VersionKey, Versions: versions})
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		if collisions[i].Module != collisions[j].Module {
+			return collisions[i].Module < collisions[j].Module
+		}
+		return collisions[i].Key < collisions[j].Key
+	})
+	return collisions, nil
+}
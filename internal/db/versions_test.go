@@ -0,0 +1,48 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMigrateVersionKeys(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "versions.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormDB.AutoMigrate(&models.Module{}, &models.ModuleVersion{}))
+
+	user := models.Module{Namespace: "acme", Name: "user"}
+	orders := models.Module{Namespace: "acme", Name: "orders"}
+	require.NoError(t, gormDB.Create(&user).Error)
+	require.NoError(t, gormDB.Create(&orders).Error)
+	// Published before version keys existed: no keys, and two aliases of v1.0.0
+	for _, mv := range []models.ModuleVersion{
+		{ModuleID: user.ID, Version: "v1.0.0"},
+		{ModuleID: user.ID, Version: "v1.0.0+build1"},
+		{ModuleID: user.ID, Version: "v1.1.0-RC.1"},
+		{ModuleID: orders.ID, Version: "v1.0.0"},
+	} {
+		require.NoError(t, gormDB.Create(&mv).Error)
+	}
+
+	collisions, err := MigrateVersionKeys(gormDB)
+	require.NoError(t, err)
+	assert.Equal(t, []VersionKeyCollision{{Module: "acme/user", Key: "v1.0.0", Versions: []string{"v1.0.0", "v1.0.0+build1"}}}, collisions)
+	var keys []string
+	require.NoError(t, gormDB.Model(&models.ModuleVersion{}).Order("version_key").Pluck("version_key", &keys).Error)
+	assert.Equal(t, []string{"v1.0.0", "v1.0.0", "v1.0.0", "v1.1.0-rc.1"}, keys)
+	assert.False(t, gormDB.Migrator().HasIndex(&models.ModuleVersion{}, VersionKeyIndex), "no index while versions collide")
+
+	// Once the collision is resolved, migrating again creates the index
+	require.NoError(t, gormDB.Where("version = ?", "v1.0.0+build1").Delete(&models.ModuleVersion{}).Error)
+	collisions, err = MigrateVersionKeys(gormDB)
+	require.NoError(t, err)
+	assert.Empty(t, collisions)
+	assert.True(t, gormDB.Migrator().HasIndex(&models.ModuleVersion{}, VersionKeyIndex))
+	assert.Error(t, gormDB.Create(&models.ModuleVersion{ModuleID: user.ID, Version: "v1.0.0+build2", VersionKey: "v1.0.0"}).Error)
+}
@@ -0,0 +1,165 @@
+package descriptor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Bundles serve tools that can't work with one include path per module: the module's files and those of
+// its dependencies are flattened into a single import root (a zip), or compiled into a single
+// self-contained FileDescriptorSet (protoc --include_imports --descriptor_set_out).
+
+// Bundle is a module version together with the sources of its dependencies.
+type Bundle struct {
+	Namespace string
+	Name      string
+	Version   string
+	// Files holds the module's .proto files plus those of its dependencies (transitively), keyed by
+	// slash-separated path. The well-known types are not included: they ship with protoc.
+	Files map[string][]byte
+	// ModuleFiles lists the paths of the files contained in the module's artifact, sorted.
+	ModuleFiles []string
+	// Dependencies are the dependency versions the bundle was assembled from, sorted by module.
+	Dependencies []ResolvedDependency
+	// Origins maps the paths of the dependencies' files to the module (namespace/name) each was taken from.
+	Origins map[string]string
+}
+
+// ResolvedDependency is the version a dependency resolved to when a bundle was assembled.
+type ResolvedDependency struct {
+	Module  string `json:"module"` // namespace/name
+	Version string `json:"version"`
+}
+
+// Bundle assembles a module version with its dependencies, each resolved to the newest published version
+// matching its constraint (the same versions Load compiles against). An empty version selects the newest
+// published version. A file present in several modules is taken from the module itself, then from the
+// dependency reached first.
+func (l *Loader) Bundle(ctx context.Context, namespace, name, version string) (*Bundle, error) {
+	mv, err := l.findVersion(ctx, namespace, name, version)
+	if err != nil {
+		return nil, err
+	}
+	own, err := l.readProtoFiles(ctx, mv)
+	if err != nil {
+		return nil, err
+	}
+	if len(own.files) == 0 {
+		return nil, fmt.Errorf("%s/%s@%s contains no .proto files", namespace, name, mv.Version)
+	}
+
+	bundle := &Bundle{Namespace: namespace, Name: name, Version: mv.Version, Files: make(map[string][]byte, len(own.files)), Origins: map[string]string{}}
+	for p, content := range own.files {
+		bundle.Files[p] = content
+		bundle.ModuleFiles = append(bundle.ModuleFiles, p)
+	}
+	sort.Strings(bundle.ModuleFiles)
+
+	visited := map[string]bool{namespace + "/" + name: true}
+	if err := l.addBundleDependencies(ctx, own.manifest, bundle, visited); err != nil {
+		return nil, err
+	}
+	sort.Slice(bundle.Dependencies, func(i, j int) bool { return bundle.Dependencies[i].Module < bundle.Dependencies[j].Module })
+	return bundle, nil
+}
+
+// addBundleDependencies adds the files of m's dependencies (transitively) to bundle, like addDependencySources.
+func (l *Loader) addBundleDependencies(ctx context.Context, m *manifest.Manifest, bundle *Bundle, visited map[string]bool) error {
+	if m == nil {
+		return nil
+	}
+	for _, dep := range m.DependencyNames() {
+		if visited[dep] {
+			continue
+		}
+		visited[dep] = true
+
+		mv, err := l.dependencyVersion(ctx, m, dep)
+		if err != nil {
+			return err
+		}
+		depFiles, err := l.readProtoFiles(ctx, mv)
+		if err != nil {
+			return err
+		}
+		bundle.Dependencies = append(bundle.Dependencies, ResolvedDependency{Module: dep, Version: mv.Version})
+		for p, content := range depFiles.files {
+			if _, exists := bundle.Files[p]; !exists {
+				bundle.Files[p] = content
+				bundle.Origins[p] = dep
+			}
+		}
+		if err := l.addBundleDependencies(ctx, depFiles.manifest, bundle, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Zip returns the bundle's files as an archive in canonical form (see package artifact), so the same
+// module and dependency versions always produce the same bytes.
+func (b *Bundle) Zip() ([]byte, error) {
+	return artifact.Pack(b.Files)
+}
+
+// Schema compiles the module's files against the bundled sources, like Load but with the dependency
+// versions the bundle was assembled from.
+func (b *Bundle) Schema(ctx context.Context) (*Schema, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(toStringMap(b.Files)),
+		}),
+	}
+	compiled, err := compiler.Compile(ctx, b.ModuleFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s/%s@%s: %w", ErrCompile, b.Namespace, b.Name, b.Version, err)
+	}
+	files := new(protoregistry.Files)
+	for _, fd := range compiled {
+		if err := registerWithImports(files, fd); err != nil {
+			return nil, fmt.Errorf("failed to register descriptors of %s/%s@%s: %w", b.Namespace, b.Name, b.Version, err)
+		}
+	}
+	return &Schema{Namespace: b.Namespace, Name: b.Name, Version: b.Version, Files: files, ModuleFiles: b.ModuleFiles}, nil
+}
+
+// DescriptorSet compiles the module's files and returns them with everything they import, well-known
+// types included, in dependency order (every file follows the files it imports).
+func (b *Bundle) DescriptorSet(ctx context.Context) (*descriptorpb.FileDescriptorSet, error) {
+	schema, err := b.Schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	added := make(map[string]bool)
+	for _, p := range schema.ModuleFiles {
+		fd, err := schema.Files.FindFileByPath(p)
+		if err != nil {
+			return nil, err
+		}
+		appendWithImports(set, fd, added)
+	}
+	return set, nil
+}
+
+// appendWithImports appends fd to set after everything it imports, skipping files already added.
+func appendWithImports(set *descriptorpb.FileDescriptorSet, fd protoreflect.FileDescriptor, added map[string]bool) {
+	if added[fd.Path()] {
+		return
+	}
+	added[fd.Path()] = true
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		appendWithImports(set, imports.Get(i).FileDescriptor, added)
+	}
+	set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+}
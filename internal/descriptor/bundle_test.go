@@ -0,0 +1,68 @@
+package descriptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_Bundle(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("acme", "types", "v1.0.0", map[string]string{
+		"acme/types/v1/id.proto": `syntax = "proto3"; package acme.types.v1; message ID { string value = 1; }`,
+	})
+	reg.publish("acme", "common", "v1.0.0", map[string]string{
+		"sproto.yaml": "name: acme/common\ndependencies:\n  acme/types: ^1.0.0\n",
+		"acme/common/v1/money.proto": `syntax = "proto3"; package acme.common.v1; import "acme/types/v1/id.proto";
+message Money { int64 cents = 1; acme.types.v1.ID account = 2; }`,
+	})
+	reg.publish("acme", "common", "v1.1.0", map[string]string{
+		"sproto.yaml": "name: acme/common\ndependencies:\n  acme/types: ^1.0.0\n",
+		"acme/common/v1/money.proto": `syntax = "proto3"; package acme.common.v1; import "acme/types/v1/id.proto";
+message Money { int64 cents = 1; acme.types.v1.ID account = 2; string currency = 3; }`,
+	})
+	reg.publish("acme", "billing", "v1.0.0", map[string]string{
+		"sproto.yaml": "name: acme/billing\ndependencies:\n  acme/common: ^1.0.0\n",
+		"acme/billing/v1/billing.proto": `syntax = "proto3"; package acme.billing.v1;
+import "acme/common/v1/money.proto";
+import "google/protobuf/timestamp.proto";
+message Charge { acme.common.v1.Money amount = 1; google.protobuf.Timestamp at = 2; }`,
+	})
+
+	loader := NewLoader(reg.db, reg.storage)
+	ctx := context.Background()
+	bundle, err := loader.Bundle(ctx, "acme", "billing", "v1.0.0")
+	require.NoError(t, err)
+
+	// Transitive dependencies, at the newest matching versions
+	assert.Equal(t, []ResolvedDependency{{Module: "acme/common", Version: "v1.1.0"}, {Module: "acme/types", Version: "v1.0.0"}}, bundle.Dependencies)
+	assert.Equal(t, []string{"acme/billing/v1/billing.proto"}, bundle.ModuleFiles)
+	assert.Len(t, bundle.Files, 3)
+	assert.Contains(t, string(bundle.Files["acme/common/v1/money.proto"]), "currency")
+	assert.Equal(t, map[string]string{"acme/common/v1/money.proto": "acme/common", "acme/types/v1/id.proto": "acme/types"}, bundle.Origins)
+
+	// A single import root; the well-known types ship with protoc
+	zipData, err := bundle.Zip()
+	require.NoError(t, err)
+	summary, err := SummarizeArtifact(zipData)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme/billing/v1/billing.proto", "acme/common/v1/money.proto", "acme/types/v1/id.proto"}, summary.Files)
+	canonical, err := artifact.Canonicalize(zipData)
+	require.NoError(t, err)
+	assert.Equal(t, canonical, zipData, "bundle zips are canonical")
+
+	// Self-contained, imports first
+	set, err := bundle.DescriptorSet(ctx)
+	require.NoError(t, err)
+	var paths []string
+	for _, f := range set.File {
+		paths = append(paths, f.GetName())
+	}
+	assert.Equal(t, []string{"acme/types/v1/id.proto", "acme/common/v1/money.proto", "google/protobuf/timestamp.proto", "acme/billing/v1/billing.proto"}, paths)
+
+	_, err = loader.Bundle(ctx, "acme", "billing", "v9.0.0")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
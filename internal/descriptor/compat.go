@@ -0,0 +1,187 @@
+package descriptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/models"
+)
+
+// maxCachedDeclarations bounds the dependency declarations kept by the compatibility matrix.
+const maxCachedDeclarations = 4096
+
+// VersionRange is a run of consecutive published versions of a dependency, inclusive at both ends.
+type VersionRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DependencyCompatibility describes which published versions of a dependency a module version accepts.
+type DependencyCompatibility struct {
+	Module     string         `json:"module"`           // namespace/name
+	Constraint string         `json:"constraint"`       // As declared in the packaged sproto.yaml
+	Ranges     []VersionRange `json:"ranges"`           // Runs of published versions satisfying the constraint, oldest first
+	Newest     string         `json:"newest,omitempty"` // Newest satisfying version, the one installs and compiles resolve to
+}
+
+// VersionCompatibility lists the dependencies of one published version of a module.
+type VersionCompatibility struct {
+	Version      string                    `json:"version"`
+	Dependencies []DependencyCompatibility `json:"dependencies"`
+	Error        string                    `json:"error,omitempty"` // Why the dependencies couldn't be read
+}
+
+// CompatibilityMatrix lists, for each published version of a module, the published versions of each of its
+// dependencies it is compatible with.
+type CompatibilityMatrix struct {
+	Namespace  string                 `json:"namespace"`
+	ModuleName string                 `json:"module_name"`
+	Versions   []VersionCompatibility `json:"versions"` // Newest first
+}
+
+// Compatibility computes the compatibility matrix of a module: the dependency constraints declared in the
+// packaged sproto.yaml of each of its published versions, matched against the versions of the dependencies
+// published now. A dependency without any matching version has no ranges, which means that version of the
+// module can't currently be installed. Returns ErrNotFound if the module has no published versions.
+//
+// Reading every artifact is the expensive part, so the declarations are cached by version and artifact
+// digest across loaders (artifacts are immutable); the published versions of the dependencies are looked up
+// on every call, so new releases show up immediately.
+func (l *Loader) Compatibility(ctx context.Context, namespace, name string) (*CompatibilityMatrix, error) {
+	var moduleVersions []models.ModuleVersion
+	err := l.db.WithContext(ctx).Joins("JOIN modules ON modules.id = module_versions.module_id").
+		Where("modules.namespace = ? AND modules.name = ?", namespace, name).
+		Find(&moduleVersions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions of %s/%s: %w", namespace, name, err)
+	}
+	if len(moduleVersions) == 0 {
+		return nil, fmt.Errorf("%w: %s/%s has no published versions", ErrNotFound, namespace, name)
+	}
+	sort.Slice(moduleVersions, func(i, j int) bool {
+		return manifest.IsOlder(moduleVersions[j].Version, moduleVersions[i].Version)
+	})
+
+	matrix := &CompatibilityMatrix{Namespace: namespace, ModuleName: name, Versions: make([]VersionCompatibility, 0, len(moduleVersions))}
+	published := map[string][]*semver.Version{} // Dependency -> published versions, oldest first
+	for i := range moduleVersions {
+		mv := &moduleVersions[i]
+		entry := VersionCompatibility{Version: mv.Version, Dependencies: []DependencyCompatibility{}}
+		declared, err := l.declaredDependencies(ctx, mv)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidArtifact) {
+				return nil, err // Storage errors fail the whole matrix rather than being cached as part of it
+			}
+			entry.Error = err.Error()
+			matrix.Versions = append(matrix.Versions, entry)
+			continue
+		}
+
+		for _, dep := range declared.DependencyNames() {
+			versions, ok := published[dep]
+			if !ok {
+				depNamespace, depName, _ := manifest.SplitModule(dep) // Validated when the manifest was parsed
+				names, err := l.versions(ctx, depNamespace, depName)
+				if err != nil {
+					return nil, err
+				}
+				versions = sortedVersions(names)
+				published[dep] = versions
+			}
+			constraint, _ := declared.Constraint(dep) // Validated when the manifest was parsed
+			entry.Dependencies = append(entry.Dependencies, matchRanges(dep, declared.Dependencies[dep], constraint, versions))
+		}
+		matrix.Versions = append(matrix.Versions, entry)
+	}
+	return matrix, nil
+}
+
+// matchRanges collapses the published versions (oldest first) satisfying a constraint into ranges.
+func matchRanges(dep, raw string, constraint *semver.Constraints, versions []*semver.Version) DependencyCompatibility {
+	compat := DependencyCompatibility{Module: dep, Constraint: raw, Ranges: []VersionRange{}}
+	inRange := false
+	for _, v := range versions {
+		if !constraint.Check(v) {
+			inRange = false
+			continue
+		}
+		version := "v" + v.String()
+		if inRange {
+			compat.Ranges[len(compat.Ranges)-1].To = version
+		} else {
+			compat.Ranges = append(compat.Ranges, VersionRange{From: version, To: version})
+			inRange = true
+		}
+		compat.Newest = version
+	}
+	return compat
+}
+
+// sortedVersions parses versions, skipping any that aren't valid semver, sorted oldest first.
+func sortedVersions(versions []string) []*semver.Version {
+	parsed := make([]*semver.Version, 0, len(versions))
+	for _, v := range versions {
+		if sv, err := semver.NewVersion(v); err == nil {
+			parsed = append(parsed, sv)
+		}
+	}
+	sort.Sort(semver.Collection(parsed))
+	return parsed
+}
+
+// --- Declaration Cache ---
+
+// declarationCache holds the packaged manifests of module versions, keyed by version ID and artifact digest.
+type declarationCache struct {
+	mu        sync.Mutex
+	manifests map[string]*manifest.Manifest
+}
+
+var declarations = &declarationCache{manifests: make(map[string]*manifest.Manifest)}
+
+// declaredDependencies returns the packaged manifest of a module version (an empty one if it has none).
+// Artifacts that can't be read as a zip or carry an invalid sproto.yaml are reported as ErrInvalidArtifact.
+func (l *Loader) declaredDependencies(ctx context.Context, mv *models.ModuleVersion) (*manifest.Manifest, error) {
+	key := mv.ID.String() + ":" + mv.ArtifactDigest
+	declarations.mu.Lock()
+	cached, ok := declarations.manifests[key]
+	declarations.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	reader, err := l.storage.DownloadFile(ctx, mv.ArtifactStorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artifact %s: %w", mv.ArtifactStorageKey, err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact %s: %w", mv.ArtifactStorageKey, err)
+	}
+	contents, err := parseArtifact(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	declared := contents.manifest
+	if declared == nil {
+		declared = &manifest.Manifest{}
+	}
+
+	declarations.mu.Lock()
+	if len(declarations.manifests) >= maxCachedDeclarations {
+		for k := range declarations.manifests { // Evict an arbitrary entry; rereading is cheap enough
+			delete(declarations.manifests, k)
+			break
+		}
+	}
+	declarations.manifests[key] = declared
+	declarations.mu.Unlock()
+	return declared, nil
+}
@@ -0,0 +1,75 @@
+package descriptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompatibility(t *testing.T) {
+	reg := newTestRegistry(t)
+	for _, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0", "v2.0.0", "v2.1.0-rc.1"} {
+		reg.publish("acme", "user", v, map[string]string{"acme/user/v1/user.proto": userProtoV1})
+	}
+	reg.publish("acme", "orders", "v1.0.0", map[string]string{
+		"sproto.yaml":                 "name: acme/orders\ndependencies:\n  acme/user: ^1.0.0\n",
+		"acme/orders/v1/orders.proto": `syntax = "proto3"; package acme.orders.v1;`,
+	})
+	reg.publish("acme", "orders", "v1.1.0", map[string]string{
+		"sproto.yaml":                 "name: acme/orders\ndependencies:\n  acme/user: \">= 1.0.0, != 1.1.0\"\n  acme/geo: ^3.0.0\n",
+		"acme/orders/v1/orders.proto": `syntax = "proto3"; package acme.orders.v1;`,
+	})
+	// No sproto.yaml: no dependencies
+	reg.publish("acme", "orders", "v0.9.0", map[string]string{
+		"acme/orders/v1/orders.proto": `syntax = "proto3"; package acme.orders.v1;`,
+	})
+
+	loader := NewLoader(reg.db, reg.storage)
+	matrix, err := loader.Compatibility(context.Background(), "acme", "orders")
+	require.NoError(t, err)
+	require.Len(t, matrix.Versions, 3)
+	assert.Equal(t, []string{"v1.1.0", "v1.0.0", "v0.9.0"},
+		[]string{matrix.Versions[0].Version, matrix.Versions[1].Version, matrix.Versions[2].Version})
+
+	// Dependencies by name; the != splits the published versions into two ranges, and the prerelease
+	// isn't admitted. acme/geo has no published version at all.
+	latest := matrix.Versions[0].Dependencies
+	require.Len(t, latest, 2)
+	assert.Equal(t, DependencyCompatibility{Module: "acme/geo", Constraint: "^3.0.0", Ranges: []VersionRange{}}, latest[0])
+	assert.Equal(t, DependencyCompatibility{
+		Module:     "acme/user",
+		Constraint: ">= 1.0.0, != 1.1.0",
+		Ranges:     []VersionRange{{From: "v1.0.0", To: "v1.0.0"}, {From: "v1.2.0", To: "v2.0.0"}},
+		Newest:     "v2.0.0",
+	}, latest[1])
+
+	assert.Equal(t, []DependencyCompatibility{{
+		Module:     "acme/user",
+		Constraint: "^1.0.0",
+		Ranges:     []VersionRange{{From: "v1.0.0", To: "v1.2.0"}},
+		Newest:     "v1.2.0",
+	}}, matrix.Versions[1].Dependencies)
+	assert.Empty(t, matrix.Versions[2].Dependencies)
+
+	// New releases of a dependency show up, even though the declarations are cached
+	reg.publish("acme", "user", "v1.3.0", map[string]string{"acme/user/v1/user.proto": userProtoV1})
+	matrix, err = loader.Compatibility(context.Background(), "acme", "orders")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.3.0", matrix.Versions[1].Dependencies[0].Newest)
+
+	_, err = loader.Compatibility(context.Background(), "acme", "nope")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCompatibility_InvalidArtifact(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("acme", "broken", "v1.0.0", map[string]string{"sproto.yaml": "dependencies:\n  not-a-module: ^1.0.0\n"})
+
+	matrix, err := NewLoader(reg.db, reg.storage).Compatibility(context.Background(), "acme", "broken")
+	require.NoError(t, err)
+	require.Len(t, matrix.Versions, 1)
+	assert.Contains(t, matrix.Versions[0].Error, "invalid module name")
+	assert.Empty(t, matrix.Versions[0].Dependencies)
+}
@@ -0,0 +1,115 @@
+package descriptor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/bufbuild/protocompile/reporter"
+)
+
+// CompileIssue is an error or warning reported while parsing or compiling a module's sources.
+type CompileIssue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`   // 1-based; 0 if unknown
+	Column  int    `json:"column,omitempty"` // 1-based; 0 if unknown
+	Message string `json:"message"`
+	Warning bool   `json:"warning,omitempty"` // e.g. an unused import; never fails a compilation on its own
+}
+
+// String formats the issue as compilers do: file:line:col: message.
+func (i CompileIssue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s", i.File, i.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", i.File, i.Line, i.Column, i.Message)
+}
+
+// CompileError is the error of a failed compilation (or parse), with everything the compiler reported
+// rather than only the first error. Errors wrapping ErrCompile wrap it when the sources were at fault.
+type CompileError struct {
+	Issues []CompileIssue // Errors and warnings, by file and position
+}
+
+func (e *CompileError) Error() string {
+	var errs []CompileIssue
+	for _, issue := range e.Issues {
+		if !issue.Warning {
+			errs = append(errs, issue)
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return "invalid source"
+	case 1:
+		return errs[0].String()
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", errs[0], len(errs)-1)
+	}
+}
+
+// CompileIssues returns the issues carried by err: those of a CompileError, or the position of a single
+// parse error. Returns nil if err carries none (e.g. a dependency wasn't found).
+func CompileIssues(err error) []CompileIssue {
+	var compileErr *CompileError
+	if errors.As(err, &compileErr) {
+		return compileErr.Issues
+	}
+	var posErr reporter.ErrorWithPos
+	if errors.As(err, &posErr) {
+		return []CompileIssue{newCompileIssue(posErr, false)}
+	}
+	return nil
+}
+
+// issueCollector collects the errors and warnings of a compilation, letting it continue past the first
+// error so all of them are reported.
+type issueCollector struct {
+	issues []CompileIssue
+}
+
+func (c *issueCollector) reporter() reporter.Reporter {
+	return reporter.NewReporter(
+		func(err reporter.ErrorWithPos) error {
+			c.issues = append(c.issues, newCompileIssue(err, false))
+			return nil // Keep going
+		},
+		func(err reporter.ErrorWithPos) {
+			c.issues = append(c.issues, newCompileIssue(err, true))
+		},
+	)
+}
+
+// err returns the CompileError of a compilation that failed with err (nil if it didn't), or err itself if
+// no issue was collected (e.g. the context was canceled).
+func (c *issueCollector) err(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, issue := range c.issues {
+		if !issue.Warning {
+			// Files are compiled concurrently, so issues are sorted for a stable report
+			sort.SliceStable(c.issues, func(i, j int) bool {
+				a, b := c.issues[i], c.issues[j]
+				if a.File != b.File {
+					return a.File < b.File
+				}
+				if a.Line != b.Line {
+					return a.Line < b.Line
+				}
+				return a.Column < b.Column
+			})
+			return &CompileError{Issues: c.issues}
+		}
+	}
+	return err
+}
+
+func newCompileIssue(err reporter.ErrorWithPos, warning bool) CompileIssue {
+	pos := err.GetPosition()
+	message := err.Error()
+	if underlying := err.Unwrap(); underlying != nil {
+		message = underlying.Error()
+	}
+	return CompileIssue{File: pos.Filename, Line: pos.Line, Column: pos.Col, Message: message, Warning: warning}
+}
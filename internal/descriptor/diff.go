@@ -0,0 +1,265 @@
+package descriptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Kinds of breaking changes between two versions of a module.
+const (
+	ChangeFileRemoved             = "file_removed"
+	ChangeMessageRemoved          = "message_removed"
+	ChangeFieldRemoved            = "field_removed"
+	ChangeFieldRenamed            = "field_renamed"
+	ChangeFieldTypeChanged        = "field_type_changed"
+	ChangeFieldCardinalityChanged = "field_cardinality_changed"
+	ChangeEnumRemoved             = "enum_removed"
+	ChangeEnumValueRemoved        = "enum_value_removed"
+	ChangeServiceRemoved          = "service_removed"
+	ChangeMethodRemoved           = "method_removed"
+	ChangeMethodSignatureChanged  = "method_signature_changed"
+)
+
+// Change is a breaking change to an element of a module.
+type Change struct {
+	Kind string `json:"kind"`
+	// Element is the fully-qualified name of the removed or changed element (the path for removed files).
+	Element string `json:"element"`
+	// Type is the message, enum or service whose users are affected by the change (empty for removed files).
+	Type string `json:"type,omitempty"`
+	// File is the path of the file that defined the element in the base version.
+	File    string `json:"file"`
+	Message string `json:"message"`
+}
+
+// BreakingChanges lists the changes in proposed that can break users of base's files: removed files, types,
+// fields, enum values, services and methods, and fields or methods whose wire types changed.
+// Elements are matched by fully-qualified name (fields and enum values by number), so types that move to
+// another file of the module are not reported as removed; their file is.
+func BreakingChanges(base, proposed *Schema) []Change {
+	proposedFiles := make(map[string]bool, len(proposed.ModuleFiles))
+	for _, p := range proposed.ModuleFiles {
+		proposedFiles[p] = true
+	}
+
+	var changes []Change
+	for _, p := range base.ModuleFiles {
+		fd, err := base.Files.FindFileByPath(p)
+		if err != nil {
+			continue
+		}
+		if !proposedFiles[p] {
+			changes = append(changes, Change{Kind: ChangeFileRemoved, Element: p, File: p, Message: fmt.Sprintf("file %s removed", p)})
+		}
+		d := differ{proposed: proposed, file: p}
+		d.messages(fd.Messages())
+		d.enums(fd.Enums())
+		d.services(fd.Services())
+		changes = append(changes, d.changes...)
+	}
+	return changes
+}
+
+// AddedRequiredFields lists the required fields (proto2 "required" or editions LEGACY_REQUIRED) in proposed's
+// files that base doesn't have, including those of new messages. base may be nil (first version).
+func AddedRequiredFields(base, proposed *Schema) []string {
+	var added []string
+	var walk func(messages protoreflect.MessageDescriptors)
+	walk = func(messages protoreflect.MessageDescriptors) {
+		for i := 0; i < messages.Len(); i++ {
+			msg := messages.Get(i)
+			var baseMsg protoreflect.MessageDescriptor
+			if base != nil {
+				if found, err := base.Files.FindDescriptorByName(msg.FullName()); err == nil {
+					baseMsg, _ = found.(protoreflect.MessageDescriptor)
+				}
+			}
+			fields := msg.Fields()
+			for j := 0; j < fields.Len(); j++ {
+				field := fields.Get(j)
+				if field.Cardinality() != protoreflect.Required {
+					continue
+				}
+				if baseMsg != nil {
+					if old := baseMsg.Fields().ByNumber(field.Number()); old != nil && old.Cardinality() == protoreflect.Required {
+						continue
+					}
+				}
+				added = append(added, string(field.FullName()))
+			}
+			walk(msg.Messages())
+		}
+	}
+	for _, p := range proposed.ModuleFiles {
+		if fd, err := proposed.Files.FindFileByPath(p); err == nil {
+			walk(fd.Messages())
+		}
+	}
+	return added
+}
+
+// DiffSummary describes how an artifact differs from the newest published version of its module.
+type DiffSummary struct {
+	BaseVersion         string
+	Changes             []Change
+	AddedRequiredFields []string
+}
+
+// DiffAgainstLatest compares an artifact (a zip) with the newest published version of the module.
+// Returns ErrNotFound if the module has no published versions and ErrInvalidArtifact if the artifact
+// can't be read or compiled.
+func (l *Loader) DiffAgainstLatest(ctx context.Context, namespace, name, version string, artifact []byte) (*DiffSummary, error) {
+	contents, err := parseArtifact(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	base, proposed, err := l.compareWithLatest(ctx, namespace, name, version, contents)
+	if err != nil {
+		return nil, err
+	}
+	return &DiffSummary{
+		BaseVersion:         base.Version,
+		Changes:             BreakingChanges(base, proposed),
+		AddedRequiredFields: AddedRequiredFields(base, proposed),
+	}, nil
+}
+
+// compareWithLatest compiles the newest published version of a module and a proposed replacement.
+// version labels the proposal in error messages and may be empty.
+func (l *Loader) compareWithLatest(ctx context.Context, namespace, name, version string, contents *artifactContents) (base, proposed *Schema, err error) {
+	base, err = l.Load(ctx, namespace, name, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if version == "" {
+		version = "proposed"
+	}
+	proposed, err = l.compileContents(ctx, namespace, name, version, contents, nil)
+	if errors.Is(err, ErrCompile) || errors.Is(err, ErrNotFound) {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return base, proposed, nil
+}
+
+// differ collects the changes to the elements of one base file.
+type differ struct {
+	proposed *Schema
+	file     string
+	changes  []Change
+}
+
+func (d *differ) add(kind string, element, typ protoreflect.FullName, format string, args ...any) {
+	d.changes = append(d.changes, Change{Kind: kind, Element: string(element), Type: string(typ), File: d.file, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *differ) messages(messages protoreflect.MessageDescriptors) {
+	for i := 0; i < messages.Len(); i++ {
+		msg := messages.Get(i)
+		if msg.IsMapEntry() {
+			continue // Compared as part of the map field
+		}
+		found, err := d.proposed.Files.FindDescriptorByName(msg.FullName())
+		newMsg, ok := found.(protoreflect.MessageDescriptor)
+		if err != nil || !ok {
+			d.add(ChangeMessageRemoved, msg.FullName(), msg.FullName(), "message %s removed", msg.FullName())
+			continue
+		}
+		d.fields(msg, newMsg)
+		d.messages(msg.Messages())
+		d.enums(msg.Enums())
+	}
+}
+
+func (d *differ) fields(msg, newMsg protoreflect.MessageDescriptor) {
+	fields := msg.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		newField := newMsg.Fields().ByNumber(field.Number())
+		switch {
+		case newField == nil:
+			d.add(ChangeFieldRemoved, field.FullName(), msg.FullName(), "field %d %q removed from %s", field.Number(), field.Name(), msg.FullName())
+		case fieldType(field) != fieldType(newField):
+			d.add(ChangeFieldTypeChanged, field.FullName(), msg.FullName(), "field %d %q of %s changed type from %s to %s", field.Number(), field.Name(), msg.FullName(), fieldType(field), fieldType(newField))
+		case field.Cardinality() != newField.Cardinality():
+			d.add(ChangeFieldCardinalityChanged, field.FullName(), msg.FullName(), "field %d %q of %s changed from %s to %s", field.Number(), field.Name(), msg.FullName(), field.Cardinality(), newField.Cardinality())
+		case field.Name() != newField.Name():
+			// Wire compatible, but breaks JSON encoding and generated code
+			d.add(ChangeFieldRenamed, field.FullName(), msg.FullName(), "field %d of %s renamed from %q to %q", field.Number(), msg.FullName(), field.Name(), newField.Name())
+		}
+	}
+}
+
+// fieldType describes a field's type, including the element types of maps and the referenced message or enum.
+func fieldType(field protoreflect.FieldDescriptor) string {
+	if field.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", fieldType(field.MapKey()), fieldType(field.MapValue()))
+	}
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return string(field.Message().FullName())
+	case protoreflect.EnumKind:
+		return string(field.Enum().FullName())
+	default:
+		return field.Kind().String()
+	}
+}
+
+func (d *differ) enums(enums protoreflect.EnumDescriptors) {
+	for i := 0; i < enums.Len(); i++ {
+		enum := enums.Get(i)
+		found, err := d.proposed.Files.FindDescriptorByName(enum.FullName())
+		newEnum, ok := found.(protoreflect.EnumDescriptor)
+		if err != nil || !ok {
+			d.add(ChangeEnumRemoved, enum.FullName(), enum.FullName(), "enum %s removed", enum.FullName())
+			continue
+		}
+		values := enum.Values()
+		for j := 0; j < values.Len(); j++ {
+			value := values.Get(j)
+			if newEnum.Values().ByNumber(value.Number()) == nil {
+				d.add(ChangeEnumValueRemoved, value.FullName(), enum.FullName(), "value %d %q removed from enum %s", value.Number(), value.Name(), enum.FullName())
+			}
+		}
+	}
+}
+
+func (d *differ) services(services protoreflect.ServiceDescriptors) {
+	for i := 0; i < services.Len(); i++ {
+		service := services.Get(i)
+		found, err := d.proposed.Files.FindDescriptorByName(service.FullName())
+		newService, ok := found.(protoreflect.ServiceDescriptor)
+		if err != nil || !ok {
+			d.add(ChangeServiceRemoved, service.FullName(), service.FullName(), "service %s removed", service.FullName())
+			continue
+		}
+		methods := service.Methods()
+		for j := 0; j < methods.Len(); j++ {
+			method := methods.Get(j)
+			newMethod := newService.Methods().ByName(method.Name())
+			switch {
+			case newMethod == nil:
+				d.add(ChangeMethodRemoved, method.FullName(), service.FullName(), "method %s removed", method.FullName())
+			case methodSignature(method) != methodSignature(newMethod):
+				d.add(ChangeMethodSignatureChanged, method.FullName(), service.FullName(), "method %s changed from %s to %s", method.FullName(), methodSignature(method), methodSignature(newMethod))
+			}
+		}
+	}
+}
+
+// methodSignature describes a method's request and response types, including streaming.
+func methodSignature(method protoreflect.MethodDescriptor) string {
+	input, output := string(method.Input().FullName()), string(method.Output().FullName())
+	if method.IsStreamingClient() {
+		input = "stream " + input
+	}
+	if method.IsStreamingServer() {
+		output = "stream " + output
+	}
+	return fmt.Sprintf("(%s) returns (%s)", input, output)
+}
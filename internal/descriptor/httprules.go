@@ -0,0 +1,297 @@
+package descriptor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// HTTP rule validation: the google.api.http annotations of a module's services must form a configuration
+// grpc-gateway (or Envoy transcoding) accepts: valid path templates whose variables name scalar request
+// fields, body and response_body naming existing fields, and no route bound twice within the module. The
+// issues are reported like lint issues, so namespace policies can enforce them at publish time instead of
+// a gateway failing at generation time or startup.
+
+// HTTP rule issue kinds (LintIssue.Rule).
+const (
+	HTTPRuleNoPattern      = "HTTP_RULE_PATTERN"         // No method and path
+	HTTPRulePathTemplate   = "HTTP_RULE_PATH_TEMPLATE"   // Path template that doesn't parse
+	HTTPRulePathVariable   = "HTTP_RULE_PATH_VARIABLE"   // Path variable not naming a scalar request field
+	HTTPRuleBody           = "HTTP_RULE_BODY"            // body not "*" or a request field, or set on GET
+	HTTPRuleResponseBody   = "HTTP_RULE_RESPONSE_BODY"   // response_body not naming a response field
+	HTTPRuleNestedBindings = "HTTP_RULE_NESTED_BINDINGS" // additional_bindings within additional_bindings
+	HTTPRuleDuplicateRoute = "HTTP_RULE_DUPLICATE_ROUTE" // Method and path already bound in the module
+)
+
+// CheckHTTPRulesArtifact compiles an artifact (a zip) for namespace/name@version and checks its HTTP rules.
+// Artifacts without google.api.http annotations have no issues and aren't compiled. Returns
+// ErrInvalidArtifact if the artifact can't be read or compiled.
+func (l *Loader) CheckHTTPRulesArtifact(ctx context.Context, namespace, name, version string, artifact []byte) ([]LintIssue, error) {
+	contents, err := parseArtifact(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	annotated := false
+	for _, content := range contents.files {
+		if bytes.Contains(content, []byte(httpRuleExtension)) {
+			annotated = true
+			break
+		}
+	}
+	if !annotated {
+		return nil, nil
+	}
+
+	schema, err := l.compileContents(ctx, namespace, name, version, contents, nil)
+	if errors.Is(err, ErrCompile) || errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return CheckHTTPRules(schema)
+}
+
+// CheckHTTPRules checks the google.api.http annotations of the services in the schema's own files. Issues
+// are reported in file order, then in declaration order.
+func CheckHTTPRules(s *Schema) ([]LintIssue, error) {
+	extension, ok := httpRuleExtensionType(s)
+	if !ok {
+		return nil, nil // google/api/annotations.proto isn't imported
+	}
+	c := &httpRuleChecker{routes: map[string]string{}}
+	for _, p := range s.ModuleFiles {
+		fd, err := s.Files.FindFileByPath(p)
+		if err != nil {
+			continue
+		}
+		c.filePath = fd.Path()
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				m := methods.Get(j)
+				rule, err := methodHTTPRule(extension, m)
+				if err != nil {
+					return nil, err
+				}
+				if rule != nil {
+					c.rule(m, rule, false)
+				}
+			}
+		}
+	}
+	return c.issues, nil
+}
+
+// httpRuleChecker collects the issues of the HTTP rules of a module.
+type httpRuleChecker struct {
+	filePath string            // Path of the file being checked
+	routes   map[string]string // Route (see parseHTTPTemplate) -> method binding it first
+	issues   []LintIssue
+}
+
+func (c *httpRuleChecker) add(rule string, m protoreflect.MethodDescriptor, format string, args ...any) {
+	c.issues = append(c.issues, LintIssue{Rule: rule, File: c.filePath, Element: string(m.FullName()), Line: sourceLine(m), Message: fmt.Sprintf(format, args...)})
+}
+
+// rule checks a google.api.HttpRule of method m; additional is true for its additional_bindings.
+func (c *httpRuleChecker) rule(m protoreflect.MethodDescriptor, rule protoreflect.Message, additional bool) {
+	fields := rule.Descriptor().Fields()
+	var method, template string
+	if pattern := rule.WhichOneof(rule.Descriptor().Oneofs().ByName("pattern")); pattern != nil {
+		if pattern.Name() == "custom" {
+			custom := rule.Get(pattern).Message()
+			method = custom.Get(custom.Descriptor().Fields().ByName("kind")).String()
+			template = custom.Get(custom.Descriptor().Fields().ByName("path")).String()
+		} else {
+			method = strings.ToUpper(string(pattern.Name()))
+			template = rule.Get(pattern).String()
+		}
+	}
+
+	if method == "" {
+		c.add(HTTPRuleNoPattern, m, "HTTP rule of %s has no method and path", m.FullName())
+	} else if route, variables, err := parseHTTPTemplate(template); err != nil {
+		c.add(HTTPRulePathTemplate, m, "HTTP rule of %s has an invalid path template %q: %v", m.FullName(), template, err)
+	} else {
+		c.variables(m, template, variables)
+		route = strings.ToUpper(method) + " " + route
+		if other, ok := c.routes[route]; ok {
+			c.add(HTTPRuleDuplicateRoute, m, "%s %s of %s is already bound by %s", method, template, m.FullName(), other)
+		} else {
+			c.routes[route] = string(m.FullName())
+		}
+	}
+
+	if body := rule.Get(fields.ByName("body")).String(); body != "" {
+		if method == "GET" {
+			c.add(HTTPRuleBody, m, "HTTP rule of %s maps a body to a GET request", m.FullName())
+		} else if body != "*" && m.Input().Fields().ByName(protoreflect.Name(body)) == nil {
+			c.add(HTTPRuleBody, m, "HTTP body %q of %s is not a field of %s", body, m.FullName(), m.Input().FullName())
+		}
+	}
+	if responseBody := rule.Get(fields.ByName("response_body")).String(); responseBody != "" && m.Output().Fields().ByName(protoreflect.Name(responseBody)) == nil {
+		c.add(HTTPRuleResponseBody, m, "HTTP response_body %q of %s is not a field of %s", responseBody, m.FullName(), m.Output().FullName())
+	}
+
+	bindings := rule.Get(fields.ByName("additional_bindings")).List()
+	if additional && bindings.Len() > 0 {
+		c.add(HTTPRuleNestedBindings, m, "additional binding of %s has additional_bindings of its own", m.FullName())
+		return
+	}
+	for i := 0; i < bindings.Len(); i++ {
+		c.rule(m, bindings.Get(i).Message(), true)
+	}
+}
+
+// variables checks that the path variables of a template name distinct scalar fields of the request.
+func (c *httpRuleChecker) variables(m protoreflect.MethodDescriptor, template string, variables []string) {
+	seen := map[string]bool{}
+	for _, v := range variables {
+		fd := fieldByPath(m.Input(), v)
+		switch {
+		case seen[v]:
+			c.add(HTTPRulePathVariable, m, "path template %q of %s binds %s twice", template, m.FullName(), v)
+		case fd == nil:
+			c.add(HTTPRulePathVariable, m, "path variable %s of %s is not a field of %s", v, m.FullName(), m.Input().FullName())
+		case fd.IsList() || fd.IsMap():
+			c.add(HTTPRulePathVariable, m, "path variable %s of %s is a repeated field", v, m.FullName())
+		case fd.Message() != nil && !strings.HasPrefix(string(fd.Message().FullName()), "google.protobuf."):
+			c.add(HTTPRulePathVariable, m, "path variable %s of %s is a message field", v, m.FullName())
+		}
+		seen[v] = true
+	}
+}
+
+// parseHTTPTemplate parses a google.api.http path template:
+//
+//	Template = "/" Segments [ Verb ] ;
+//	Segments = Segment { "/" Segment } ;
+//	Segment  = "*" | "**" | LITERAL | Variable ;
+//	Variable = "{" FieldPath [ "=" Segments ] "}" ;
+//	FieldPath = IDENT { "." IDENT } ;
+//	Verb     = ":" LITERAL ;
+//
+// It returns the route the template matches, with variables replaced by their segments (default "*"), so
+// "/v1/{name=shelves/*}" and "/v1/shelves/{shelf}" give the same route, and the variables' field paths.
+func parseHTTPTemplate(template string) (string, []string, error) {
+	if !strings.HasPrefix(template, "/") {
+		return "", nil, errors.New("must start with '/'")
+	}
+	p := &templateParser{rest: template[1:]}
+	segments, err := p.segments(false)
+	if err != nil {
+		return "", nil, err
+	}
+	route := "/" + strings.Join(segments, "/")
+	if strings.HasPrefix(p.rest, ":") {
+		verb := p.rest[1:]
+		if !validTemplateLiteral(verb) {
+			return "", nil, fmt.Errorf("invalid verb %q", verb)
+		}
+		route += ":" + verb
+		p.rest = ""
+	}
+	if p.rest != "" {
+		return "", nil, fmt.Errorf("unexpected %q", p.rest)
+	}
+	return route, p.variables, nil
+}
+
+// identPattern matches an identifier of a variable's field path.
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// templateParser parses a path template from left to right.
+type templateParser struct {
+	rest      string // Not yet parsed
+	variables []string
+}
+
+// segments parses Segments; inVariable is true within a variable's "=" pattern.
+func (p *templateParser) segments(inVariable bool) ([]string, error) {
+	var segments []string
+	for {
+		segment, err := p.segment(inVariable)
+		if err != nil {
+			return nil, err
+		}
+		if len(segments) > 0 && strings.HasSuffix(segments[len(segments)-1], "**") {
+			return nil, errors.New("'**' must be the last segment")
+		}
+		segments = append(segments, segment)
+		if !strings.HasPrefix(p.rest, "/") {
+			return segments, nil
+		}
+		p.rest = p.rest[1:]
+	}
+}
+
+// segment parses a Segment, returning it as matched by the route.
+func (p *templateParser) segment(inVariable bool) (string, error) {
+	if strings.HasPrefix(p.rest, "{") {
+		if inVariable {
+			return "", errors.New("variables can't be nested")
+		}
+		end := strings.IndexByte(p.rest, '}')
+		if end < 0 {
+			return "", errors.New("unclosed '{'")
+		}
+		variable := p.rest[1:end]
+		p.rest = p.rest[end+1:]
+		fieldPath, pattern, hasPattern := strings.Cut(variable, "=")
+		for _, ident := range strings.Split(fieldPath, ".") {
+			if !identPattern.MatchString(ident) {
+				return "", fmt.Errorf("invalid variable %q", fieldPath)
+			}
+		}
+		p.variables = append(p.variables, fieldPath)
+		if !hasPattern {
+			return "*", nil
+		}
+		inner := &templateParser{rest: pattern}
+		segments, err := inner.segments(true)
+		if err == nil && inner.rest != "" {
+			err = fmt.Errorf("unexpected %q", inner.rest)
+		}
+		if err != nil {
+			return "", fmt.Errorf("variable %s: %w", fieldPath, err)
+		}
+		return strings.Join(segments, "/"), nil
+	}
+
+	end := strings.IndexAny(p.rest, "/:{}")
+	if end < 0 {
+		end = len(p.rest)
+	}
+	segment := p.rest[:end]
+	p.rest = p.rest[end:]
+	if segment == "*" || segment == "**" {
+		return segment, nil
+	}
+	if !validTemplateLiteral(segment) {
+		if segment == "" {
+			return "", errors.New("empty segment")
+		}
+		return "", fmt.Errorf("invalid segment %q", segment)
+	}
+	return segment, nil
+}
+
+// validTemplateLiteral reports whether s is a non-empty literal of URL path characters.
+func validTemplateLiteral(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-._~%!$&'()+,;=@", r)) {
+			return false
+		}
+	}
+	return true
+}
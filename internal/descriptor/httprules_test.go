@@ -0,0 +1,94 @@
+package descriptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/wkt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHTTPTemplate(t *testing.T) {
+	valid := map[string]struct {
+		route     string
+		variables []string
+	}{
+		"/v1/books":                           {"/v1/books", nil},
+		"/v1/{name=shelves/*/books/*}":        {"/v1/shelves/*/books/*", []string{"name"}},
+		"/v1/shelves/{shelf}/books/{book.id}": {"/v1/shelves/*/books/*", []string{"shelf", "book.id"}},
+		"/v1/{name=shelves/*}:archive":        {"/v1/shelves/*:archive", []string{"name"}},
+		"/v1/files/{path=**}":                 {"/v1/files/**", []string{"path"}},
+	}
+	for template, expected := range valid {
+		route, variables, err := parseHTTPTemplate(template)
+		if assert.NoError(t, err, template) {
+			assert.Equal(t, expected.route, route, template)
+			assert.Equal(t, expected.variables, variables, template)
+		}
+	}
+
+	for _, template := range []string{"", "v1/books", "/v1//books", "/v1/books/", "/v1/{name", "/v1/{name=a/{b}}", "/v1/{1name}", "/v1/**/books", "/v1/books:", "/v1/bo oks", "/v1/{name}}"} {
+		_, _, err := parseHTTPTemplate(template)
+		assert.Error(t, err, template)
+	}
+}
+
+func TestLoader_CheckHTTPRulesArtifact(t *testing.T) {
+	reg := newTestRegistry(t)
+	modules, err := wkt.Modules()
+	require.NoError(t, err)
+	for _, m := range modules {
+		reg.publish(m.Namespace, m.Name, m.Version, m.Files)
+	}
+	loader := NewLoader(reg.db, reg.storage)
+	ctx := context.Background()
+
+	artifact := zipBytes(t, map[string]string{
+		"sproto.yaml": "name: acme/books\ndependencies:\n  google/api: ^1.0.0\n",
+		"acme/books/v1/books.proto": `syntax = "proto3"; package acme.books.v1;
+import "google/api/annotations.proto";
+message Book { string name = 1; repeated string tags = 2; }
+message GetBookRequest { string name = 1; Book book = 2; repeated string ids = 3; }
+service Library {
+  rpc GetBook(GetBookRequest) returns (Book) {
+    option (google.api.http) = { get: "/v1/{name=shelves/*/books/*}" additional_bindings { get: "/v1/books/{name}" } };
+  }
+  rpc FindBook(GetBookRequest) returns (Book) {
+    option (google.api.http) = { get: "/v1/shelves/{book.name}/books/{name}" body: "*" response_body: "title" };
+  }
+  rpc ListBooks(GetBookRequest) returns (Book) {
+    option (google.api.http) = { post: "/v1/{book}/{ids}/{missing}" body: "nope" };
+  }
+  rpc Broken(GetBookRequest) returns (Book) {
+    option (google.api.http) = { patch: "/v1/{name" additional_bindings { custom: { kind: "HEAD" path: "/v1/books/{name}" } additional_bindings { get: "/x" } } };
+  }
+}`,
+	})
+	issues, err := loader.CheckHTTPRulesArtifact(ctx, "acme", "books", "v1.0.0", artifact)
+	require.NoError(t, err)
+	var rules []string
+	for _, issue := range issues {
+		assert.Equal(t, "acme/books/v1/books.proto", issue.File)
+		rules = append(rules, issue.Rule+" "+issue.Element)
+	}
+	assert.Equal(t, []string{
+		"HTTP_RULE_DUPLICATE_ROUTE acme.books.v1.Library.FindBook",
+		"HTTP_RULE_BODY acme.books.v1.Library.FindBook",
+		"HTTP_RULE_RESPONSE_BODY acme.books.v1.Library.FindBook",
+		"HTTP_RULE_PATH_VARIABLE acme.books.v1.Library.ListBooks",
+		"HTTP_RULE_PATH_VARIABLE acme.books.v1.Library.ListBooks",
+		"HTTP_RULE_PATH_VARIABLE acme.books.v1.Library.ListBooks",
+		"HTTP_RULE_BODY acme.books.v1.Library.ListBooks",
+		"HTTP_RULE_PATH_TEMPLATE acme.books.v1.Library.Broken",
+		"HTTP_RULE_NESTED_BINDINGS acme.books.v1.Library.Broken",
+	}, rules)
+	assert.Contains(t, issues[0].Message, "already bound by acme.books.v1.Library.GetBook")
+
+	// Artifacts without annotations aren't compiled
+	issues, err = loader.CheckHTTPRulesArtifact(ctx, "acme", "plain", "v1.0.0", zipBytes(t, map[string]string{"plain.proto": `syntax = "proto3"; import "missing.proto";`}))
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+	_, err = loader.CheckHTTPRulesArtifact(ctx, "acme", "broken", "v1.0.0", zipBytes(t, map[string]string{"broken.proto": `syntax = "proto3"; import "google/api/annotations.proto"; service S { rpc Get(M) returns (M) { option (google.api.http) = { get: "/m" }; } }`}))
+	assert.ErrorIs(t, err, ErrInvalidArtifact)
+}
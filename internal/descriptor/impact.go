@@ -0,0 +1,246 @@
+package descriptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Impact of a proposed module version on a dependent module.
+const (
+	// ImpactBroken means the dependent no longer compiles against the proposed version.
+	ImpactBroken = "broken"
+	// ImpactAffected means the dependent compiles but uses types with breaking changes.
+	ImpactAffected = "affected"
+	// ImpactUnaffected means none of the breaking changes touch types the dependent uses.
+	ImpactUnaffected = "unaffected"
+	// ImpactExcluded means the dependent's constraint does not admit the proposed version.
+	ImpactExcluded = "excluded"
+	// ImpactUnknown means the dependent could not be analyzed (e.g. another of its dependencies is missing).
+	ImpactUnknown = "unknown"
+)
+
+// DependentImpact describes how a proposed module version affects one dependent module.
+type DependentImpact struct {
+	Namespace  string   `json:"namespace"`
+	ModuleName string   `json:"module_name"`
+	Version    string   `json:"version"`    // Newest published version of the dependent, the one analyzed
+	Constraint string   `json:"constraint"` // The dependent's constraint on the analyzed module
+	Status     string   `json:"status"`
+	Error      string   `json:"error,omitempty"`   // Compile error (broken) or analysis error (unknown)
+	Changes    []Change `json:"changes,omitempty"` // Breaking changes to types the dependent uses (affected)
+}
+
+// ImpactReport is the result of analyzing a proposed version of a module against its dependents.
+type ImpactReport struct {
+	Namespace       string            `json:"namespace"`
+	ModuleName      string            `json:"module_name"`
+	BaseVersion     string            `json:"base_version"`               // Newest published version, compared against
+	ProposedVersion string            `json:"proposed_version,omitempty"` // Version the proposal would be published as, if known
+	Changes         []Change          `json:"changes"`
+	Dependents      []DependentImpact `json:"dependents"`
+	// Breaking is true if any dependent is broken or affected.
+	Breaking bool `json:"breaking"`
+}
+
+// AnalyzeImpact compares a proposed artifact (a zip) for a module with the module's newest published version
+// and reports which dependent modules would break. Dependents are the modules whose newest published version
+// declares the module in its packaged sproto.yaml. Each is recompiled against the proposed artifact and
+// checked for uses of types with breaking changes, including types reached through the module's own types.
+// If proposedVersion is set, dependents whose constraint excludes it are reported as excluded.
+// Returns ErrNotFound if the module has no published versions and ErrInvalidArtifact if the proposal can't
+// be read or compiled.
+func (l *Loader) AnalyzeImpact(ctx context.Context, namespace, name, proposedVersion string, artifact []byte) (*ImpactReport, error) {
+	var proposedSemver *semver.Version
+	if proposedVersion != "" {
+		v, err := semver.NewVersion(proposedVersion)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid proposed version %q: %w", ErrInvalidArtifact, proposedVersion, err)
+		}
+		proposedSemver = v
+	}
+
+	contents, err := parseArtifact(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	base, proposed, err := l.compareWithLatest(ctx, namespace, name, proposedVersion, contents)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImpactReport{
+		Namespace:       namespace,
+		ModuleName:      name,
+		BaseVersion:     base.Version,
+		ProposedVersion: proposedVersion,
+		Changes:         BreakingChanges(base, proposed),
+		Dependents:      []DependentImpact{},
+	}
+	if report.Changes == nil {
+		report.Changes = []Change{}
+	}
+
+	// --- Find Dependents ---
+	target := namespace + "/" + name
+	var modules []models.Module
+	if err := l.db.WithContext(ctx).Order("namespace, name").Find(&modules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list modules: %w", err)
+	}
+	overrides := map[string]*artifactContents{target: contents}
+	for _, m := range modules {
+		if m.Namespace == namespace && m.Name == name {
+			continue
+		}
+		mv, err := l.findVersion(ctx, m.Namespace, m.Name, "")
+		if errors.Is(err, ErrNotFound) {
+			continue // No versions
+		}
+		if err != nil {
+			return nil, err
+		}
+		depContents, err := l.readProtoFiles(ctx, mv)
+		if err != nil {
+			// One unreadable artifact shouldn't prevent the analysis; it can't declare anything we can see
+			continue
+		}
+		if depContents.manifest == nil {
+			continue
+		}
+		constraintStr, ok := depContents.manifest.Dependencies[target]
+		if !ok {
+			continue
+		}
+
+		impact := DependentImpact{Namespace: m.Namespace, ModuleName: m.Name, Version: mv.Version, Constraint: constraintStr}
+		if proposedSemver != nil {
+			if constraint, err := depContents.manifest.Constraint(target); err == nil && !constraint.Check(proposedSemver) {
+				impact.Status = ImpactExcluded
+				report.Dependents = append(report.Dependents, impact)
+				continue
+			}
+		}
+
+		// --- Recompile Against the Proposal ---
+		schema, err := l.compileContents(ctx, m.Namespace, m.Name, mv.Version, depContents, overrides)
+		switch {
+		case errors.Is(err, ErrCompile):
+			impact.Status = ImpactBroken
+			impact.Error = err.Error()
+		case err != nil:
+			impact.Status = ImpactUnknown
+			impact.Error = err.Error()
+		default:
+			impact.Changes = affectingChanges(schema, base, report.Changes)
+			impact.Status = ImpactUnaffected
+			if len(impact.Changes) > 0 {
+				impact.Status = ImpactAffected
+			}
+		}
+		if impact.Status == ImpactBroken || impact.Status == ImpactAffected {
+			report.Breaking = true
+		}
+		report.Dependents = append(report.Dependents, impact)
+	}
+	return report, nil
+}
+
+// affectingChanges returns the changes that touch what the dependent uses: types referenced from its own files
+// (closed over the fields of base's types) and files it imports.
+func affectingChanges(dependent, base *Schema, changes []Change) []Change {
+	used := make(map[protoreflect.FullName]bool)
+	imported := make(map[string]bool)
+	var queue []protoreflect.FullName
+	use := func(name protoreflect.FullName) {
+		if !used[name] {
+			used[name] = true
+			queue = append(queue, name)
+		}
+	}
+
+	for _, p := range dependent.ModuleFiles {
+		fd, err := dependent.Files.FindFileByPath(p)
+		if err != nil {
+			continue
+		}
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			imported[imports.Get(i).Path()] = true
+		}
+		collectFileReferences(fd, use)
+	}
+
+	// Types used by the dependent are affected by changes to the types they contain
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		found, err := base.Files.FindDescriptorByName(name)
+		if err != nil {
+			continue
+		}
+		if msg, ok := found.(protoreflect.MessageDescriptor); ok {
+			collectFieldReferences(msg.Fields(), use)
+		}
+	}
+
+	var affecting []Change
+	for _, c := range changes {
+		if used[protoreflect.FullName(c.Type)] || (c.Kind == ChangeFileRemoved && imported[c.File]) {
+			affecting = append(affecting, c)
+		}
+	}
+	return affecting
+}
+
+// collectFileReferences reports the messages, enums and services referenced by a file's definitions.
+func collectFileReferences(fd protoreflect.FileDescriptor, use func(protoreflect.FullName)) {
+	collectMessageReferences(fd.Messages(), use)
+	collectFieldReferences(fd.Extensions(), use)
+	services := fd.Services()
+	for i := 0; i < services.Len(); i++ {
+		methods := services.Get(i).Methods()
+		for j := 0; j < methods.Len(); j++ {
+			use(methods.Get(j).Input().FullName())
+			use(methods.Get(j).Output().FullName())
+		}
+	}
+}
+
+func collectMessageReferences(messages protoreflect.MessageDescriptors, use func(protoreflect.FullName)) {
+	for i := 0; i < messages.Len(); i++ {
+		msg := messages.Get(i)
+		collectFieldReferences(msg.Fields(), use)
+		collectFieldReferences(msg.Extensions(), use)
+		collectMessageReferences(msg.Messages(), use)
+	}
+}
+
+// fieldList is implemented by both protoreflect.FieldDescriptors and protoreflect.ExtensionDescriptors.
+type fieldList interface {
+	Len() int
+	Get(i int) protoreflect.FieldDescriptor
+}
+
+// collectFieldReferences reports the types of message and enum fields (including map values) and the
+// messages extended by extension fields.
+func collectFieldReferences(fields fieldList, use func(protoreflect.FullName)) {
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.IsExtension() {
+			use(field.ContainingMessage().FullName())
+		}
+		if field.IsMap() {
+			field = field.MapValue()
+		}
+		if msg := field.Message(); msg != nil {
+			use(msg.FullName())
+		}
+		if enum := field.Enum(); enum != nil {
+			use(enum.FullName())
+		}
+	}
+}
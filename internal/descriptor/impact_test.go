@@ -0,0 +1,217 @@
+package descriptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const userProtoV1 = `syntax = "proto3";
+package acme.user.v1;
+message Address { string city = 1; string zip = 2; }
+message User { string id = 1; string email = 2; Address address = 3; }
+enum Role { ROLE_UNSPECIFIED = 0; ROLE_ADMIN = 1; }
+message Audit { string actor = 1; }
+service UserService { rpc GetUser(User) returns (User); }
+`
+
+// impactRegistry publishes acme/user and three dependents:
+// acme/orders embeds User (and, through it, Address), acme/audit only uses Audit and acme/admin uses Role.
+func impactRegistry(t *testing.T) *testRegistry {
+	reg := newTestRegistry(t)
+	reg.publish("acme", "user", "v1.0.0", map[string]string{"acme/user/v1/user.proto": userProtoV1})
+	reg.publish("acme", "orders", "v1.0.0", map[string]string{
+		"sproto.yaml": "name: acme/orders\ndependencies:\n  acme/user: ^1.0.0\n",
+		"acme/orders/v1/orders.proto": `syntax = "proto3";
+package acme.orders.v1;
+import "acme/user/v1/user.proto";
+message Order { acme.user.v1.User buyer = 1; }
+`,
+	})
+	reg.publish("acme", "audit", "v1.0.0", map[string]string{
+		"sproto.yaml": "name: acme/audit\ndependencies:\n  acme/user: \">= 1.0.0\"\n",
+		"acme/audit/v1/audit.proto": `syntax = "proto3";
+package acme.audit.v1;
+import "acme/user/v1/user.proto";
+message Entry { acme.user.v1.Audit audit = 1; }
+`,
+	})
+	reg.publish("acme", "admin", "v1.0.0", map[string]string{
+		"sproto.yaml": "name: acme/admin\ndependencies:\n  acme/user: ^1.0.0\n",
+		"acme/admin/v1/admin.proto": `syntax = "proto3";
+package acme.admin.v1;
+import "acme/user/v1/user.proto";
+message Grant { acme.user.v1.Role role = 1; }
+`,
+	})
+	// Doesn't depend on acme/user
+	reg.publish("acme", "geo", "v1.0.0", map[string]string{
+		"acme/geo/v1/point.proto": `syntax = "proto3"; package acme.geo.v1; message Point {}`,
+	})
+	return reg
+}
+
+func dependentsByName(report *ImpactReport) map[string]DependentImpact {
+	byName := make(map[string]DependentImpact)
+	for _, d := range report.Dependents {
+		byName[d.Namespace+"/"+d.ModuleName] = d
+	}
+	return byName
+}
+
+func TestAnalyzeImpact_FieldRemovedInNestedType(t *testing.T) {
+	reg := impactRegistry(t)
+	loader := NewLoader(reg.db, reg.storage)
+
+	// Remove Address.zip: only orders uses Address (through User)
+	proposal := zipBytes(t, map[string]string{"acme/user/v1/user.proto": `syntax = "proto3";
+package acme.user.v1;
+message Address { string city = 1; reserved 2; }
+message User { string id = 1; string email = 2; Address address = 3; }
+enum Role { ROLE_UNSPECIFIED = 0; ROLE_ADMIN = 1; }
+message Audit { string actor = 1; }
+service UserService { rpc GetUser(User) returns (User); }
+`})
+	report, err := loader.AnalyzeImpact(context.Background(), "acme", "user", "", proposal)
+	require.NoError(t, err)
+
+	assert.Equal(t, "v1.0.0", report.BaseVersion)
+	require.Len(t, report.Changes, 1)
+	assert.Equal(t, Change{
+		Kind: ChangeFieldRemoved, Element: "acme.user.v1.Address.zip", Type: "acme.user.v1.Address",
+		File: "acme/user/v1/user.proto", Message: `field 2 "zip" removed from acme.user.v1.Address`,
+	}, report.Changes[0])
+	assert.True(t, report.Breaking)
+
+	deps := dependentsByName(report)
+	require.Len(t, deps, 3) // acme/geo is not a dependent
+	assert.Equal(t, ImpactAffected, deps["acme/orders"].Status)
+	assert.Equal(t, report.Changes, deps["acme/orders"].Changes)
+	assert.Equal(t, ImpactUnaffected, deps["acme/audit"].Status)
+	assert.Equal(t, ImpactUnaffected, deps["acme/admin"].Status)
+}
+
+func TestAnalyzeImpact_RemovedTypeBreaksCompilation(t *testing.T) {
+	reg := impactRegistry(t)
+	loader := NewLoader(reg.db, reg.storage)
+
+	// Remove the Role enum and change the type of User.email
+	proposal := zipBytes(t, map[string]string{"acme/user/v1/user.proto": `syntax = "proto3";
+package acme.user.v1;
+message Address { string city = 1; string zip = 2; }
+message User { string id = 1; bytes email = 2; Address address = 3; }
+message Audit { string actor = 1; }
+service UserService { rpc GetUser(User) returns (User); }
+`})
+	report, err := loader.AnalyzeImpact(context.Background(), "acme", "user", "v2.0.0", proposal)
+	require.NoError(t, err)
+
+	kinds := make(map[string]string)
+	for _, c := range report.Changes {
+		kinds[c.Element] = c.Kind
+	}
+	assert.Equal(t, map[string]string{
+		"acme.user.v1.User.email": ChangeFieldTypeChanged,
+		"acme.user.v1.Role":       ChangeEnumRemoved,
+	}, kinds)
+
+	deps := dependentsByName(report)
+	// v2.0.0 is outside ^1.0.0, so orders and admin won't pick it up; audit accepts any version >= 1.0.0
+	assert.Equal(t, ImpactExcluded, deps["acme/orders"].Status)
+	assert.Equal(t, ImpactExcluded, deps["acme/admin"].Status)
+	assert.Equal(t, ImpactUnaffected, deps["acme/audit"].Status)
+	assert.False(t, report.Breaking)
+
+	// Analyzed as a v1 release, admin no longer compiles
+	report, err = loader.AnalyzeImpact(context.Background(), "acme", "user", "v1.1.0", proposal)
+	require.NoError(t, err)
+	deps = dependentsByName(report)
+	assert.Equal(t, ImpactBroken, deps["acme/admin"].Status)
+	assert.Contains(t, deps["acme/admin"].Error, "acme.user.v1.Role")
+	assert.Equal(t, ImpactAffected, deps["acme/orders"].Status)
+	assert.True(t, report.Breaking)
+}
+
+func TestAnalyzeImpact_Errors(t *testing.T) {
+	reg := impactRegistry(t)
+	loader := NewLoader(reg.db, reg.storage)
+	ctx := context.Background()
+
+	_, err := loader.AnalyzeImpact(ctx, "acme", "missing", "", zipBytes(t, map[string]string{"a.proto": `syntax = "proto3";`}))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = loader.AnalyzeImpact(ctx, "acme", "user", "", []byte("not a zip"))
+	assert.ErrorIs(t, err, ErrInvalidArtifact)
+
+	_, err = loader.AnalyzeImpact(ctx, "acme", "user", "", zipBytes(t, map[string]string{"a.proto": `syntax = "proto3"; message {`}))
+	assert.ErrorIs(t, err, ErrInvalidArtifact)
+}
+
+func TestBreakingChanges(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("acme", "svc", "v1.0.0", map[string]string{
+		"a.proto": `syntax = "proto3";
+package acme;
+message Req { repeated string ids = 1; string name = 2; map<string, int32> counts = 3; }
+message Resp {}
+enum State { STATE_UNSPECIFIED = 0; STATE_ON = 1; }
+service Svc { rpc Get(Req) returns (Resp); rpc Watch(Req) returns (stream Resp); rpc Drop(Req) returns (Resp); }
+`,
+		"b.proto": `syntax = "proto3"; package acme; message Moved {}`,
+	})
+	reg.publish("acme", "svc", "v2.0.0", map[string]string{
+		"a.proto": `syntax = "proto3";
+package acme;
+message Req { string ids = 1; string title = 2; map<string, int64> counts = 3; }
+message Resp {}
+message Moved {}
+enum State { STATE_UNSPECIFIED = 0; }
+service Svc { rpc Get(Req) returns (Resp); rpc Watch(Req) returns (Resp); }
+`,
+	})
+	loader := NewLoader(reg.db, reg.storage)
+	base, err := loader.Load(context.Background(), "acme", "svc", "v1.0.0")
+	require.NoError(t, err)
+	proposed, err := loader.Load(context.Background(), "acme", "svc", "v2.0.0")
+	require.NoError(t, err)
+
+	var got []string
+	for _, c := range BreakingChanges(base, proposed) {
+		got = append(got, c.Kind+" "+c.Element)
+	}
+	assert.Equal(t, []string{
+		"field_cardinality_changed acme.Req.ids",
+		"field_renamed acme.Req.name",
+		"field_type_changed acme.Req.counts",
+		"enum_value_removed acme.STATE_ON",
+		"method_signature_changed acme.Svc.Watch",
+		"method_removed acme.Svc.Drop",
+		"file_removed b.proto", // Moved only changed files, it wasn't removed
+	}, got)
+}
+
+func TestDiffAgainstLatest_AddedRequiredFields(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("acme", "legacy", "v1.0.0", map[string]string{
+		"legacy.proto": `syntax = "proto2"; package legacy; message Item { required string id = 1; optional string note = 2; }`,
+	})
+	loader := NewLoader(reg.db, reg.storage)
+
+	diff, err := loader.DiffAgainstLatest(context.Background(), "acme", "legacy", "v1.1.0", zipBytes(t, map[string]string{
+		"legacy.proto": `syntax = "proto2";
+package legacy;
+message Item { required string id = 1; required string note = 2; }
+message Tag { required string name = 1; }
+`,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", diff.BaseVersion)
+	assert.Equal(t, []string{"legacy.Item.note", "legacy.Tag.name"}, diff.AddedRequiredFields)
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, ChangeFieldCardinalityChanged, diff.Changes[0].Kind)
+
+	_, err = loader.DiffAgainstLatest(context.Background(), "acme", "new", "v1.0.0", zipBytes(t, map[string]string{"a.proto": `syntax = "proto3";`}))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,115 @@
+package descriptor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/parser/fastscan"
+)
+
+// ErrInvalidArtifact is returned when an artifact's sources or manifest can't be read.
+var ErrInvalidArtifact = errors.New("invalid artifact")
+
+// UnresolvedImport is an import statement that resolves neither to a file in the artifact, a well-known
+// type, nor a file in a declared dependency.
+type UnresolvedImport struct {
+	File   string `json:"file"`
+	Import string `json:"import"`
+}
+
+// standardImports resolves the files bundled with protoc (google/protobuf/*.proto), which are always available.
+var standardImports = protocompile.WithStandardImports(protocompile.CompositeResolver{})
+
+// DeclaresDependencies reports whether an artifact (a zip) contains a sproto.yaml at its root.
+// Only such artifacts have their import graph checked.
+func DeclaresDependencies(artifact []byte) bool {
+	contents, err := parseArtifact(artifact)
+	return err == nil && contents.manifest != nil
+}
+
+// ArtifactSummary lists the .proto files of an artifact and the dependencies declared in its sproto.yaml.
+type ArtifactSummary struct {
+	Files        []string          // Sorted, slash-separated paths
+	Dependencies map[string]string // Module name -> constraint; empty without a sproto.yaml
+}
+
+// SummarizeArtifact reads the file list and declared dependencies of an artifact (a zip).
+func SummarizeArtifact(artifact []byte) (*ArtifactSummary, error) {
+	contents, err := parseArtifact(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	summary := &ArtifactSummary{Files: make([]string, 0, len(contents.files)), Dependencies: map[string]string{}}
+	for p := range contents.files {
+		summary.Files = append(summary.Files, p)
+	}
+	sort.Strings(summary.Files)
+	if contents.manifest != nil {
+		for dep, constraint := range contents.manifest.Dependencies {
+			summary.Dependencies[dep] = constraint
+		}
+	}
+	return summary, nil
+}
+
+// CheckImports validates the import graph of an artifact (a zip) about to be published.
+// Artifacts without a sproto.yaml declare no dependencies and are not checked (declared is false).
+// Otherwise every import must resolve to a file in the artifact, a well-known type, or a file in the newest
+// published version of a direct dependency matching its constraint.
+// Unreadable sources, invalid manifests and dependencies without a matching version are reported as
+// ErrInvalidArtifact or ErrNotFound errors.
+func (l *Loader) CheckImports(ctx context.Context, artifact []byte) (unresolved []UnresolvedImport, declared bool, err error) {
+	contents, err := parseArtifact(artifact)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	if contents.manifest == nil {
+		return nil, false, nil
+	}
+
+	// Files available from the direct dependencies
+	available := make(map[string]bool)
+	for _, dep := range contents.manifest.DependencyNames() {
+		if _, _, err := manifest.SplitModule(dep); err != nil {
+			return nil, true, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+		}
+		if _, err := contents.manifest.Constraint(dep); err != nil {
+			return nil, true, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+		}
+		depContents, err := l.dependencyContents(ctx, contents.manifest, dep)
+		if err != nil {
+			return nil, true, err
+		}
+		for p := range depContents.files {
+			available[p] = true
+		}
+	}
+
+	paths := make([]string, 0, len(contents.files))
+	for p := range contents.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		result, err := fastscan.Scan(p, bytes.NewReader(contents.files[p]))
+		if err != nil {
+			return nil, true, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+		}
+		for _, imp := range result.Imports {
+			if _, own := contents.files[imp.Path]; own || available[imp.Path] {
+				continue
+			}
+			if _, err := standardImports.FindFileByPath(imp.Path); err == nil {
+				continue
+			}
+			unresolved = append(unresolved, UnresolvedImport{File: p, Import: imp.Path})
+		}
+	}
+	return unresolved, true, nil
+}
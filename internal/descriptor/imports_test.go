@@ -0,0 +1,60 @@
+package descriptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckImports(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("acme", "common", "v1.0.0", map[string]string{
+		"acme/common/v1/money.proto": `syntax = "proto3"; package acme.common.v1; message Money {}`,
+	})
+	reg.publish("acme", "geo", "v1.0.0", map[string]string{
+		"acme/geo/v1/point.proto": `syntax = "proto3"; package acme.geo.v1; message Point {}`,
+	})
+	loader := NewLoader(reg.db, reg.storage)
+
+	artifact := zipBytes(t, map[string]string{
+		"sproto.yaml": "name: acme/billing\ndependencies:\n  acme/common: ^1.0.0\n",
+		"acme/billing/v1/billing.proto": `syntax = "proto3";
+import "acme/billing/v1/types.proto";     // Own file
+import "acme/common/v1/money.proto";      // Declared dependency
+import "google/protobuf/timestamp.proto"; // Well-known type
+import "acme/geo/v1/point.proto";         // Published, but not declared
+import "missing/v1/missing.proto";        // Doesn't exist anywhere
+`,
+		"acme/billing/v1/types.proto": `syntax = "proto3";`,
+	})
+	assert.True(t, DeclaresDependencies(artifact))
+
+	unresolved, declared, err := loader.CheckImports(context.Background(), artifact)
+	require.NoError(t, err)
+	assert.True(t, declared)
+	assert.Equal(t, []UnresolvedImport{
+		{File: "acme/billing/v1/billing.proto", Import: "acme/geo/v1/point.proto"},
+		{File: "acme/billing/v1/billing.proto", Import: "missing/v1/missing.proto"},
+	}, unresolved)
+}
+
+func TestCheckImports_UnsatisfiableDependencyAndNoManifest(t *testing.T) {
+	reg := newTestRegistry(t)
+	loader := NewLoader(reg.db, reg.storage)
+
+	_, _, err := loader.CheckImports(context.Background(), zipBytes(t, map[string]string{
+		"sproto.yaml": "name: acme/billing\ndependencies:\n  acme/common: ^1.0.0\n",
+		"a.proto":     `syntax = "proto3";`,
+	}))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Without a sproto.yaml nothing is declared and nothing is checked
+	noManifest := zipBytes(t, map[string]string{"a.proto": `syntax = "proto3"; import "missing.proto";`})
+	assert.False(t, DeclaresDependencies(noManifest))
+	unresolved, declared, err := loader.CheckImports(context.Background(), noManifest)
+	require.NoError(t, err)
+	assert.False(t, declared)
+	assert.Empty(t, unresolved)
+}
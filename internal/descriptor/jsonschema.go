@@ -0,0 +1,236 @@
+package descriptor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// JSON Schema generation: a top-level message of a module is described as a JSON Schema (draft 2020-12)
+// validating the JSON payloads protobuf's JSON parsers (protojson, JsonFormat) accept for it. Field names
+// may be the JSON (lowerCamelCase) or the original proto name, 64-bit integers numbers or strings, enums
+// names or numbers, and unknown fields are rejected. Null values, which parsers read as the field's
+// default, are not accepted, and oneofs are not enforced (at most one member set).
+
+// JSONSchemaDialect is the JSON Schema dialect of generated documents.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// ErrMessageNotFound is returned when a module defines no top-level message with the requested name.
+var ErrMessageNotFound = errors.New("message not found")
+
+// Messages returns the fully-qualified names of the top-level messages defined in the module's own files.
+func (s *Schema) Messages() []string {
+	var names []string
+	for _, p := range s.ModuleFiles {
+		fd, err := s.Files.FindFileByPath(p)
+		if err != nil {
+			continue
+		}
+		messages := fd.Messages()
+		for i := 0; i < messages.Len(); i++ {
+			names = append(names, string(messages.Get(i).FullName()))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// JSONSchema generates the JSON Schema document of a top-level message defined in the schema's own files
+// (fully-qualified name, e.g. "acme.books.v1.Book"). Every message and enum it uses is included under
+// $defs, keyed by full name. Returns ErrMessageNotFound for other names.
+func JSONSchema(s *Schema, messageName string) ([]byte, error) {
+	var md protoreflect.MessageDescriptor
+	for _, name := range s.Messages() {
+		if name == messageName {
+			d, err := s.Files.FindDescriptorByName(protoreflect.FullName(name))
+			if err != nil {
+				return nil, err
+			}
+			md = d.(protoreflect.MessageDescriptor)
+			break
+		}
+	}
+	if md == nil {
+		return nil, fmt.Errorf("%w: %s/%s@%s defines no top-level message %q", ErrMessageNotFound, s.Namespace, s.Name, s.Version, messageName)
+	}
+
+	g := &jsonSchemaGenerator{defs: map[string]*jsonSchema{}}
+	root := g.messageSchema(md)
+	root.Schema = JSONSchemaDialect
+	root.Title = messageName
+	root.Defs = g.defs
+	return json.MarshalIndent(root, "", "  ")
+}
+
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 any                    `json:"type,omitempty"` // A type name or a list of them
+	Format               string                 `json:"format,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	ContentEncoding      string                 `json:"contentEncoding,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	PropertyNames        *jsonSchema            `json:"propertyNames,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"` // *jsonSchema or bool
+	Required             []string               `json:"required,omitempty"`
+	Defs                 map[string]*jsonSchema `json:"$defs,omitempty"`
+}
+
+type jsonSchemaGenerator struct {
+	defs map[string]*jsonSchema // Messages and enums, by full name
+}
+
+// messageSchema returns the schema of a message: inline for well-known types with a special JSON form,
+// otherwise a reference to its $defs entry (added, with everything it uses, on first use).
+func (g *jsonSchemaGenerator) messageSchema(md protoreflect.MessageDescriptor) *jsonSchema {
+	if schema := wellKnownJSONSchema(md.FullName()); schema != nil {
+		return schema
+	}
+	name := string(md.FullName())
+	if _, exists := g.defs[name]; !exists {
+		schema := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}, AdditionalProperties: false}
+		g.defs[name] = schema // Before the properties, for recursive messages
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			fieldSchema := g.fieldSchema(fd)
+			schema.Properties[fd.JSONName()] = fieldSchema
+			schema.Properties[string(fd.Name())] = fieldSchema // Parsers accept both names
+		}
+	}
+	return &jsonSchema{Ref: "#/$defs/" + name}
+}
+
+// fieldSchema returns the schema of a field, including repeated and map fields.
+func (g *jsonSchemaGenerator) fieldSchema(fd protoreflect.FieldDescriptor) *jsonSchema {
+	switch {
+	case fd.IsMap():
+		return &jsonSchema{Type: "object", PropertyNames: mapKeySchema(fd.MapKey()), AdditionalProperties: g.singularSchema(fd.MapValue())}
+	case fd.IsList():
+		return &jsonSchema{Type: "array", Items: g.singularSchema(fd)}
+	default:
+		return g.singularSchema(fd)
+	}
+}
+
+func (g *jsonSchemaGenerator) singularSchema(fd protoreflect.FieldDescriptor) *jsonSchema {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.messageSchema(fd.Message())
+	case protoreflect.EnumKind:
+		return g.enumSchema(fd.Enum())
+	default:
+		return scalarJSONSchema(fd.Kind())
+	}
+}
+
+// enumSchema returns a reference to an enum's $defs entry: a value name, or any number (proto3 enums
+// are open).
+func (g *jsonSchemaGenerator) enumSchema(ed protoreflect.EnumDescriptor) *jsonSchema {
+	if ed.FullName() == "google.protobuf.NullValue" {
+		return &jsonSchema{Type: "null"}
+	}
+	name := string(ed.FullName())
+	if _, exists := g.defs[name]; !exists {
+		names := &jsonSchema{Type: "string"}
+		values := ed.Values()
+		for i := 0; i < values.Len(); i++ {
+			names.Enum = append(names.Enum, string(values.Get(i).Name()))
+		}
+		g.defs[name] = &jsonSchema{AnyOf: []*jsonSchema{names, integerSchema(math.MinInt32, math.MaxInt32)}}
+	}
+	return &jsonSchema{Ref: "#/$defs/" + name}
+}
+
+// scalarJSONSchema returns the schema of a scalar field kind.
+func scalarJSONSchema(kind protoreflect.Kind) *jsonSchema {
+	switch kind {
+	case protoreflect.BoolKind:
+		return &jsonSchema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return integerSchema(math.MinInt32, math.MaxInt32)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return integerSchema(0, math.MaxUint32)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &jsonSchema{Type: []string{"integer", "string"}, Pattern: `^-?[0-9]+$`} // Strings in canonical JSON
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &jsonSchema{Type: []string{"integer", "string"}, Pattern: `^[0-9]+$`, Minimum: floatPtr(0)}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return &jsonSchema{Type: []string{"number", "string"}} // Strings for "NaN", "Infinity" and "-Infinity"
+	case protoreflect.BytesKind:
+		return &jsonSchema{Type: "string", ContentEncoding: "base64"}
+	default:
+		return &jsonSchema{Type: "string"}
+	}
+}
+
+// mapKeySchema constrains the (string) keys of a map field's JSON object by the map's key type.
+func mapKeySchema(fd protoreflect.FieldDescriptor) *jsonSchema {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return &jsonSchema{Enum: []any{"true", "false"}}
+	case protoreflect.StringKind:
+		return nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &jsonSchema{Pattern: `^[0-9]+$`}
+	default:
+		return &jsonSchema{Pattern: `^-?[0-9]+$`}
+	}
+}
+
+// wellKnownJSONSchema returns the schema of well-known types with a special JSON form, or nil.
+func wellKnownJSONSchema(name protoreflect.FullName) *jsonSchema {
+	switch name {
+	case "google.protobuf.Timestamp":
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	case "google.protobuf.Duration":
+		return &jsonSchema{Type: "string", Pattern: `^-?[0-9]+(\.[0-9]{1,9})?s$`}
+	case "google.protobuf.FieldMask":
+		return &jsonSchema{Type: "string"} // Comma-separated lowerCamelCase paths
+	case "google.protobuf.Struct":
+		return &jsonSchema{Type: "object"}
+	case "google.protobuf.Value":
+		return &jsonSchema{} // Any JSON value
+	case "google.protobuf.ListValue":
+		return &jsonSchema{Type: "array"}
+	case "google.protobuf.Any":
+		return &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{"@type": {Type: "string"}}, Required: []string{"@type"}}
+	case "google.protobuf.Empty":
+		return &jsonSchema{Type: "object", AdditionalProperties: false}
+	case "google.protobuf.BoolValue":
+		return scalarJSONSchema(protoreflect.BoolKind)
+	case "google.protobuf.Int32Value":
+		return scalarJSONSchema(protoreflect.Int32Kind)
+	case "google.protobuf.UInt32Value":
+		return scalarJSONSchema(protoreflect.Uint32Kind)
+	case "google.protobuf.Int64Value":
+		return scalarJSONSchema(protoreflect.Int64Kind)
+	case "google.protobuf.UInt64Value":
+		return scalarJSONSchema(protoreflect.Uint64Kind)
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return scalarJSONSchema(protoreflect.DoubleKind)
+	case "google.protobuf.StringValue":
+		return scalarJSONSchema(protoreflect.StringKind)
+	case "google.protobuf.BytesValue":
+		return scalarJSONSchema(protoreflect.BytesKind)
+	}
+	return nil
+}
+
+func integerSchema(minimum, maximum float64) *jsonSchema {
+	return &jsonSchema{Type: "integer", Minimum: floatPtr(minimum), Maximum: floatPtr(maximum)}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
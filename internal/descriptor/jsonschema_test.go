@@ -0,0 +1,86 @@
+package descriptor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSchema(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("acme", "common", "v1.0.0", map[string]string{
+		"acme/common/v1/money.proto": `syntax = "proto3"; package acme.common.v1; message Money { int64 cents = 1; string currency_code = 2; }`,
+	})
+	reg.publish("acme", "billing", "v1.0.0", map[string]string{
+		"sproto.yaml": "name: acme/billing\ndependencies:\n  acme/common: ^1.0.0\n",
+		"acme/billing/v1/billing.proto": `syntax = "proto3"; package acme.billing.v1;
+import "acme/common/v1/money.proto";
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+enum State { STATE_UNSPECIFIED = 0; STATE_PAID = 1; }
+message Invoice {
+  message Line { string sku = 1; uint32 quantity = 2; }
+  acme.common.v1.Money total = 1;
+  State state = 2;
+  repeated Line lines = 3;
+  map<int32, string> notes = 4;
+  google.protobuf.Timestamp issued_at = 5;
+  google.protobuf.StringValue memo = 6;
+  bytes signature = 7;
+  Invoice replaces = 8;
+}
+message Empty {}`,
+	})
+
+	loader := NewLoader(reg.db, reg.storage)
+	schema, err := loader.Load(context.Background(), "acme", "billing", "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme.billing.v1.Empty", "acme.billing.v1.Invoice"}, schema.Messages())
+
+	data, err := JSONSchema(schema, "acme.billing.v1.Invoice")
+	require.NoError(t, err)
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, JSONSchemaDialect, doc["$schema"])
+	assert.Equal(t, "acme.billing.v1.Invoice", doc["title"])
+	assert.Equal(t, "#/$defs/acme.billing.v1.Invoice", doc["$ref"])
+
+	defs := doc["$defs"].(map[string]any)
+	assert.ElementsMatch(t, []string{"acme.billing.v1.Invoice", "acme.billing.v1.Invoice.Line", "acme.billing.v1.State", "acme.common.v1.Money"}, keys(defs))
+
+	invoice := defs["acme.billing.v1.Invoice"].(map[string]any)
+	assert.Equal(t, false, invoice["additionalProperties"])
+	props := invoice["properties"].(map[string]any)
+	// Both the JSON and the proto name are accepted
+	assert.Equal(t, props["issuedAt"], props["issued_at"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, props["issuedAt"])
+	assert.Equal(t, map[string]any{"type": "string"}, props["memo"])
+	assert.Equal(t, map[string]any{"type": "string", "contentEncoding": "base64"}, props["signature"])
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/acme.billing.v1.Invoice"}, props["replaces"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/acme.billing.v1.Invoice.Line"}}, props["lines"])
+	assert.Equal(t, map[string]any{"type": "object", "propertyNames": map[string]any{"pattern": "^-?[0-9]+$"}, "additionalProperties": map[string]any{"type": "string"}}, props["notes"])
+
+	money := defs["acme.common.v1.Money"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": []any{"integer", "string"}, "pattern": "^-?[0-9]+$"}, money["cents"])
+	line := defs["acme.billing.v1.Invoice.Line"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "integer", "minimum": float64(0), "maximum": float64(4294967295)}, line["quantity"])
+	state := defs["acme.billing.v1.State"].(map[string]any)["anyOf"].([]any)
+	assert.Equal(t, []any{"STATE_UNSPECIFIED", "STATE_PAID"}, state[0].(map[string]any)["enum"])
+
+	// Only top-level messages of the module itself
+	_, err = JSONSchema(schema, "acme.billing.v1.Invoice.Line")
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+	_, err = JSONSchema(schema, "acme.common.v1.Money")
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+}
+
+func keys(m map[string]any) []string {
+	var ks []string
+	for k := range m {
+		ks = append(ks, k)
+	}
+	return ks
+}
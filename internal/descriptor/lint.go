@@ -0,0 +1,224 @@
+package descriptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Lint rulesets, from least to most strict. Each ruleset includes the rules of the previous one.
+const (
+	// LintMinimal checks that files declare a package matching their directory.
+	LintMinimal = "minimal"
+	// LintBasic adds the naming conventions of the protobuf style guide.
+	LintBasic = "basic"
+	// LintStandard adds versioned packages, prefixed enum values with an _UNSPECIFIED zero value, and
+	// service names ending in "Service".
+	LintStandard = "standard"
+)
+
+// lintLevels orders the rulesets.
+var lintLevels = map[string]int{LintMinimal: 1, LintBasic: 2, LintStandard: 3}
+
+// ValidateLintRuleset checks that ruleset names a lint ruleset.
+func ValidateLintRuleset(ruleset string) error {
+	if _, ok := lintLevels[ruleset]; !ok {
+		return fmt.Errorf("invalid lint ruleset %q: must be %s, %s or %s", ruleset, LintMinimal, LintBasic, LintStandard)
+	}
+	return nil
+}
+
+// LintIssue is a violation of a lint rule.
+type LintIssue struct {
+	Rule    string `json:"rule"`           // e.g. "FIELD_LOWER_SNAKE_CASE"
+	File    string `json:"file"`           // Path of the file defining the element
+	Element string `json:"element"`        // Fully-qualified name of the element (the path for file-level rules)
+	Line    int    `json:"line,omitempty"` // Line declaring the element (the package statement for file-level rules); 0 if unknown
+	Message string `json:"message"`
+}
+
+var (
+	pascalCase       = regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`)
+	lowerSnakeCase   = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	upperSnakeCase   = regexp.MustCompile(`^[A-Z][A-Z0-9]*(_[A-Z0-9]+)*$`)
+	versionComponent = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]*)?$`)
+)
+
+// Lint checks the module's own files (not its dependencies) against a ruleset. Issues are reported in
+// file order, then in declaration order.
+func Lint(schema *Schema, ruleset string) []LintIssue {
+	l := linter{level: lintLevels[ruleset]}
+	for _, p := range schema.ModuleFiles {
+		fd, err := schema.Files.FindFileByPath(p)
+		if err != nil {
+			continue
+		}
+		l.file(fd)
+	}
+	return l.issues
+}
+
+// LintArtifact compiles an artifact (a zip) for namespace/name@version and lints it. Returns
+// ErrInvalidArtifact if the artifact can't be read or compiled.
+func (l *Loader) LintArtifact(ctx context.Context, namespace, name, version string, artifact []byte, ruleset string) ([]LintIssue, error) {
+	if err := ValidateLintRuleset(ruleset); err != nil {
+		return nil, err
+	}
+	contents, err := parseArtifact(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	schema, err := l.compileContents(ctx, namespace, name, version, contents, nil)
+	if errors.Is(err, ErrCompile) || errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Lint(schema, ruleset), nil
+}
+
+// linter collects the issues of the rules up to level.
+type linter struct {
+	level    int
+	filePath string // Path of the file being linted
+	issues   []LintIssue
+}
+
+func (l *linter) add(rule string, d protoreflect.Descriptor, element, format string, args ...any) {
+	l.issues = append(l.issues, LintIssue{Rule: rule, File: l.filePath, Element: element, Line: sourceLine(d), Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) file(fd protoreflect.FileDescriptor) {
+	l.filePath = fd.Path()
+	pkg := string(fd.Package())
+
+	// Package declaration
+	if pkg == "" {
+		l.add("PACKAGE_DEFINED", fd, fd.Path(), "file %s does not declare a package", fd.Path())
+	} else if dir := path.Dir(fd.Path()); dir != strings.ReplaceAll(pkg, ".", "/") {
+		l.add("PACKAGE_DIRECTORY_MATCH", fd, fd.Path(), "file %s of package %s should be in directory %s", fd.Path(), pkg, strings.ReplaceAll(pkg, ".", "/"))
+	}
+	if pkg != "" && l.level >= lintLevels[LintStandard] {
+		if parts := strings.Split(pkg, "."); !versionComponent.MatchString(parts[len(parts)-1]) {
+			l.add("PACKAGE_VERSION_SUFFIX", fd, fd.Path(), "package %s should end in a version such as .v1 or .v1beta1", pkg)
+		}
+	}
+	if l.level < lintLevels[LintBasic] {
+		return
+	}
+
+	// Naming conventions
+	l.messages(fd.Messages())
+	l.enums(fd.Enums())
+	services := fd.Services()
+	for i := 0; i < services.Len(); i++ {
+		service := services.Get(i)
+		if !pascalCase.MatchString(string(service.Name())) {
+			l.add("SERVICE_PASCAL_CASE", service, string(service.FullName()), "service %s should be PascalCase", service.FullName())
+		}
+		if l.level >= lintLevels[LintStandard] && !strings.HasSuffix(string(service.Name()), "Service") {
+			l.add("SERVICE_SUFFIX", service, string(service.FullName()), "service %s should end in Service", service.FullName())
+		}
+		methods := service.Methods()
+		for j := 0; j < methods.Len(); j++ {
+			if method := methods.Get(j); !pascalCase.MatchString(string(method.Name())) {
+				l.add("RPC_PASCAL_CASE", method, string(method.FullName()), "method %s should be PascalCase", method.FullName())
+			}
+		}
+	}
+}
+
+func (l *linter) messages(messages protoreflect.MessageDescriptors) {
+	for i := 0; i < messages.Len(); i++ {
+		msg := messages.Get(i)
+		if msg.IsMapEntry() {
+			continue // Generated for map fields
+		}
+		if !pascalCase.MatchString(string(msg.Name())) {
+			l.add("MESSAGE_PASCAL_CASE", msg, string(msg.FullName()), "message %s should be PascalCase", msg.FullName())
+		}
+		fields := msg.Fields()
+		for j := 0; j < fields.Len(); j++ {
+			if field := fields.Get(j); !lowerSnakeCase.MatchString(string(field.Name())) {
+				l.add("FIELD_LOWER_SNAKE_CASE", field, string(field.FullName()), "field %s should be lower_snake_case", field.FullName())
+			}
+		}
+		oneofs := msg.Oneofs()
+		for j := 0; j < oneofs.Len(); j++ {
+			if oneof := oneofs.Get(j); !oneof.IsSynthetic() && !lowerSnakeCase.MatchString(string(oneof.Name())) {
+				l.add("ONEOF_LOWER_SNAKE_CASE", oneof, string(oneof.FullName()), "oneof %s should be lower_snake_case", oneof.FullName())
+			}
+		}
+		l.messages(msg.Messages())
+		l.enums(msg.Enums())
+	}
+}
+
+func (l *linter) enums(enums protoreflect.EnumDescriptors) {
+	for i := 0; i < enums.Len(); i++ {
+		enum := enums.Get(i)
+		if !pascalCase.MatchString(string(enum.Name())) {
+			l.add("ENUM_PASCAL_CASE", enum, string(enum.FullName()), "enum %s should be PascalCase", enum.FullName())
+		}
+		prefix := upperSnake(string(enum.Name())) + "_"
+		values := enum.Values()
+		for j := 0; j < values.Len(); j++ {
+			value := values.Get(j)
+			name := string(value.Name())
+			// Enum values are scoped to the enum's parent, so their full name skips the enum
+			element := string(enum.FullName()) + "." + name
+			if !upperSnakeCase.MatchString(name) {
+				l.add("ENUM_VALUE_UPPER_SNAKE_CASE", value, element, "enum value %s should be UPPER_SNAKE_CASE", element)
+			}
+			if l.level < lintLevels[LintStandard] {
+				continue
+			}
+			if !strings.HasPrefix(name, prefix) {
+				l.add("ENUM_VALUE_PREFIX", value, element, "enum value %s should be prefixed with %s", element, prefix)
+			}
+			if value.Number() == 0 && name != prefix+"UNSPECIFIED" {
+				l.add("ENUM_ZERO_VALUE_SUFFIX", value, element, "zero value of enum %s should be %sUNSPECIFIED", enum.FullName(), prefix)
+			}
+		}
+	}
+}
+
+// sourceLine returns the line (1-based) declaring d, or 0 if its file has no source info. Files are located
+// by their package statement.
+func sourceLine(d protoreflect.Descriptor) int {
+	locations := d.ParentFile().SourceLocations()
+	var loc protoreflect.SourceLocation
+	if _, ok := d.(protoreflect.FileDescriptor); ok {
+		loc = locations.ByPath(protoreflect.SourcePath{2}) // FileDescriptorProto.package
+	} else {
+		loc = locations.ByDescriptor(d)
+	}
+	if loc.Path == nil {
+		return 0
+	}
+	return loc.StartLine + 1
+}
+
+// upperSnake converts a PascalCase name to UPPER_SNAKE_CASE ("HTTPMethod" -> "HTTP_METHOD").
+func upperSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package descriptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_LintArtifact(t *testing.T) {
+	reg := newTestRegistry(t)
+	loader := NewLoader(reg.db, reg.storage)
+	ctx := context.Background()
+
+	artifact := zipBytes(t, map[string]string{
+		"acme/orders/orders.proto": `syntax = "proto3"; package acme.orders;
+enum HTTPMethod { GET = 0; HTTP_METHOD_POST = 1; }
+message order_item { string itemName = 1; oneof Choice { string a = 2; string b = 3; } map<string, string> labels = 4; }
+service Orders { rpc get_order(order_item) returns (order_item); }`,
+		"misplaced.proto": `syntax = "proto3"; package acme.billing.v1;
+enum Status { STATUS_UNSPECIFIED = 0; STATUS_PAID = 1; }
+message Invoice { string id = 1; }
+service BillingService { rpc GetInvoice(Invoice) returns (Invoice); }`,
+	})
+	rules := func(issues []LintIssue) []string {
+		names := []string{}
+		for _, issue := range issues {
+			names = append(names, issue.Rule+" "+issue.Element)
+		}
+		return names
+	}
+
+	issues, err := loader.LintArtifact(ctx, "acme", "orders", "v1.0.0", artifact, LintMinimal)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"PACKAGE_DIRECTORY_MATCH misplaced.proto"}, rules(issues))
+	assert.Equal(t, "file misplaced.proto of package acme.billing.v1 should be in directory acme/billing/v1", issues[0].Message)
+
+	issues, err = loader.LintArtifact(ctx, "acme", "orders", "v1.0.0", artifact, LintBasic)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"MESSAGE_PASCAL_CASE acme.orders.order_item",
+		"FIELD_LOWER_SNAKE_CASE acme.orders.order_item.itemName",
+		"ONEOF_LOWER_SNAKE_CASE acme.orders.order_item.Choice",
+		"RPC_PASCAL_CASE acme.orders.Orders.get_order",
+		"PACKAGE_DIRECTORY_MATCH misplaced.proto",
+	}, rules(issues))
+	assert.Equal(t, "acme/orders/orders.proto", issues[0].File)
+
+	issues, err = loader.LintArtifact(ctx, "acme", "orders", "v1.0.0", artifact, LintStandard)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"PACKAGE_VERSION_SUFFIX acme/orders/orders.proto",
+		"MESSAGE_PASCAL_CASE acme.orders.order_item",
+		"FIELD_LOWER_SNAKE_CASE acme.orders.order_item.itemName",
+		"ONEOF_LOWER_SNAKE_CASE acme.orders.order_item.Choice",
+		"ENUM_VALUE_PREFIX acme.orders.HTTPMethod.GET",
+		"ENUM_ZERO_VALUE_SUFFIX acme.orders.HTTPMethod.GET",
+		"SERVICE_SUFFIX acme.orders.Orders",
+		"RPC_PASCAL_CASE acme.orders.Orders.get_order",
+		"PACKAGE_DIRECTORY_MATCH misplaced.proto",
+	}, rules(issues))
+	// Issues are located by the line declaring the element (the package statement for file-level rules)
+	lines := []int{}
+	for _, issue := range issues {
+		lines = append(lines, issue.Line)
+	}
+	assert.Equal(t, []int{1, 3, 3, 3, 2, 2, 4, 4, 1}, lines)
+
+	// Invalid rulesets and artifacts
+	_, err = loader.LintArtifact(ctx, "acme", "orders", "v1.0.0", artifact, "strict")
+	assert.EqualError(t, err, `invalid lint ruleset "strict": must be minimal, basic or standard`)
+	_, err = loader.LintArtifact(ctx, "acme", "orders", "v1.0.0", zipBytes(t, map[string]string{"a.proto": "syntax = \"proto3\"; message {"}), LintBasic)
+	assert.True(t, errors.Is(err, ErrInvalidArtifact), err)
+
+	// Every compile error is reported, by file and position
+	_, err = loader.LintArtifact(ctx, "acme", "orders", "v1.0.0", zipBytes(t, map[string]string{
+		"b.proto": "syntax = \"proto3\";\nmessage B { Missing m = 1; Unknown u = 2; }",
+		"a.proto": "syntax = \"proto3\";\nmessage {",
+	}), LintBasic)
+	assert.True(t, errors.Is(err, ErrInvalidArtifact), err)
+	compileIssues := CompileIssues(err)
+	require.Len(t, compileIssues, 3)
+	located := []string{}
+	for _, issue := range compileIssues {
+		located = append(located, fmt.Sprintf("%s:%d:%d", issue.File, issue.Line, issue.Column))
+	}
+	assert.Equal(t, []string{"a.proto:2:9", "b.proto:2:13", "b.proto:2:28"}, located)
+	assert.Contains(t, err.Error(), "a.proto:2:9: ")
+	assert.Contains(t, err.Error(), "(and 2 more errors)")
+}
+
+func TestUpperSnake(t *testing.T) {
+	for name, want := range map[string]string{"Status": "STATUS", "HTTPMethod": "HTTP_METHOD", "OrderV2State": "ORDER_V2_STATE", "userID": "USER_ID"} {
+		assert.Equal(t, want, upperSnake(name), name)
+	}
+}
@@ -0,0 +1,346 @@
+package descriptor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when the requested module or version does not exist.
+var ErrNotFound = errors.New("module version not found")
+
+// ErrCompile is returned when a module's sources fail to compile.
+var ErrCompile = errors.New("failed to compile")
+
+// maxCachedSchemas bounds the number of compiled module versions kept in memory.
+const maxCachedSchemas = 64
+
+// Schema is the compiled form of a module version.
+type Schema struct {
+	Namespace string
+	Name      string
+	Version   string
+	// Files holds the module's own files plus everything they import
+	// (well-known types and files from dependency modules).
+	Files *protoregistry.Files
+	// ModuleFiles lists the paths of the files contained in the module's artifact.
+	ModuleFiles []string
+}
+
+// Services returns the fully-qualified names of the services defined in the module's own files.
+func (s *Schema) Services() []string {
+	var names []string
+	for _, p := range s.ModuleFiles {
+		fd, err := s.Files.FindFileByPath(p)
+		if err != nil {
+			continue
+		}
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			names = append(names, string(services.Get(i).FullName()))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Loader compiles module version artifacts into descriptors.
+// Imports are resolved from the artifact itself, the protobuf well-known types, and the artifacts of
+// the dependencies declared in the packaged sproto.yaml (newest published version matching each constraint).
+// Compiled schemas are cached by module version and artifact digest.
+type Loader struct {
+	db      *gorm.DB
+	storage storage.StorageProvider
+
+	mu    sync.Mutex
+	cache map[string]*Schema
+}
+
+// NewLoader creates a loader reading metadata from db and artifacts from provider.
+func NewLoader(db *gorm.DB, provider storage.StorageProvider) *Loader {
+	return &Loader{db: db, storage: provider, cache: make(map[string]*Schema)}
+}
+
+// Load compiles the given module version. An empty version selects the newest published version.
+func (l *Loader) Load(ctx context.Context, namespace, name, version string) (*Schema, error) {
+	mv, err := l.findVersion(ctx, namespace, name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := fmt.Sprintf("%s/%s@%s:%s", namespace, name, mv.Version, mv.ArtifactDigest)
+	l.mu.Lock()
+	cached, ok := l.cache[cacheKey]
+	l.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	schema, err := l.compile(ctx, namespace, name, mv)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	if len(l.cache) >= maxCachedSchemas {
+		for k := range l.cache { // Evict an arbitrary entry; recompiling is cheap enough
+			delete(l.cache, k)
+			break
+		}
+	}
+	l.cache[cacheKey] = schema
+	l.mu.Unlock()
+	return schema, nil
+}
+
+// findVersion looks up a module version, or the newest version if version is empty.
+func (l *Loader) findVersion(ctx context.Context, namespace, name, version string) (*models.ModuleVersion, error) {
+	if version == "" {
+		versions, err := l.versions(ctx, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		version = manifest.Newest(versions)
+		if version == "" {
+			return nil, fmt.Errorf("%w: %s/%s has no published versions", ErrNotFound, namespace, name)
+		}
+	}
+
+	var mv models.ModuleVersion
+	err := l.db.WithContext(ctx).Joins("JOIN modules ON modules.id = module_versions.module_id").
+		Where("modules.namespace = ? AND modules.name = ? AND module_versions.version = ?", namespace, name, version).
+		First(&mv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s/%s@%s", ErrNotFound, namespace, name, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s/%s@%s: %w", namespace, name, version, err)
+	}
+	return &mv, nil
+}
+
+// versions lists the published versions of a module.
+func (l *Loader) versions(ctx context.Context, namespace, name string) ([]string, error) {
+	var versions []string
+	err := l.db.WithContext(ctx).Model(&models.ModuleVersion{}).
+		Joins("JOIN modules ON modules.id = module_versions.module_id").
+		Where("modules.namespace = ? AND modules.name = ?", namespace, name).
+		Pluck("module_versions.version", &versions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions of %s/%s: %w", namespace, name, err)
+	}
+	return versions, nil
+}
+
+// compile gathers the sources of the module and its dependencies and compiles the module's files.
+func (l *Loader) compile(ctx context.Context, namespace, name string, mv *models.ModuleVersion) (*Schema, error) {
+	own, err := l.readProtoFiles(ctx, mv)
+	if err != nil {
+		return nil, err
+	}
+	return l.compileContents(ctx, namespace, name, mv.Version, own, nil)
+}
+
+// compileContents compiles the files of an artifact against its dependencies.
+// overrides replaces the published sources of dependency modules (keyed "namespace/name"), which lets
+// callers compile a module against an unpublished version of one of its dependencies.
+func (l *Loader) compileContents(ctx context.Context, namespace, name, version string, own *artifactContents, overrides map[string]*artifactContents) (*Schema, error) {
+	if len(own.files) == 0 {
+		return nil, fmt.Errorf("%s/%s@%s contains no .proto files", namespace, name, version)
+	}
+
+	// Files from the module take precedence over files from dependencies with the same path
+	sources := make(map[string][]byte, len(own.files))
+	for p, content := range own.files {
+		sources[p] = content
+	}
+	visited := map[string]bool{namespace + "/" + name: true}
+	if err := l.addDependencySources(ctx, own.manifest, sources, visited, overrides); err != nil {
+		return nil, err
+	}
+
+	moduleFiles := make([]string, 0, len(own.files))
+	for p := range own.files {
+		moduleFiles = append(moduleFiles, p)
+	}
+	sort.Strings(moduleFiles)
+
+	// Every error is collected rather than only the first, and source info locates lint issues
+	collector := &issueCollector{}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(toStringMap(sources)),
+		}),
+		Reporter:       collector.reporter(),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	compiled, err := compiler.Compile(ctx, moduleFiles...)
+	if err := collector.err(err); err != nil {
+		return nil, fmt.Errorf("%w %s/%s@%s: %w", ErrCompile, namespace, name, version, err)
+	}
+
+	files := new(protoregistry.Files)
+	for _, fd := range compiled {
+		if err := registerWithImports(files, fd); err != nil {
+			return nil, fmt.Errorf("failed to register descriptors of %s/%s@%s: %w", namespace, name, version, err)
+		}
+	}
+	return &Schema{Namespace: namespace, Name: name, Version: version, Files: files, ModuleFiles: moduleFiles}, nil
+}
+
+// addDependencySources adds the .proto files of m's dependencies (transitively) to sources.
+// Dependencies present in overrides are taken from there instead of the registry.
+func (l *Loader) addDependencySources(ctx context.Context, m *manifest.Manifest, sources map[string][]byte, visited map[string]bool, overrides map[string]*artifactContents) error {
+	if m == nil {
+		return nil
+	}
+	for _, dep := range m.DependencyNames() {
+		if visited[dep] {
+			continue
+		}
+		visited[dep] = true
+
+		depFiles, ok := overrides[dep]
+		if !ok {
+			var err error
+			if depFiles, err = l.dependencyContents(ctx, m, dep); err != nil {
+				return err
+			}
+		}
+		for p, content := range depFiles.files {
+			if _, exists := sources[p]; !exists {
+				sources[p] = content
+			}
+		}
+		if err := l.addDependencySources(ctx, depFiles.manifest, sources, visited, overrides); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependencyContents reads the newest published version of dep matching its constraint in m.
+func (l *Loader) dependencyContents(ctx context.Context, m *manifest.Manifest, dep string) (*artifactContents, error) {
+	mv, err := l.dependencyVersion(ctx, m, dep)
+	if err != nil {
+		return nil, err
+	}
+	return l.readProtoFiles(ctx, mv)
+}
+
+// dependencyVersion looks up the newest published version of dep matching its constraint in m.
+func (l *Loader) dependencyVersion(ctx context.Context, m *manifest.Manifest, dep string) (*models.ModuleVersion, error) {
+	depNamespace, depName, err := manifest.SplitModule(dep)
+	if err != nil {
+		return nil, err
+	}
+	constraint, err := m.Constraint(dep)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := l.versions(ctx, depNamespace, depName)
+	if err != nil {
+		return nil, err
+	}
+	version := manifest.NewestMatching(versions, constraint)
+	if version == "" {
+		return nil, fmt.Errorf("%w: no published version of dependency %s satisfies %q", ErrNotFound, dep, m.Dependencies[dep])
+	}
+	return l.findVersion(ctx, depNamespace, depName, version)
+}
+
+// artifactContents holds the .proto files and packaged manifest of an artifact.
+type artifactContents struct {
+	files    map[string][]byte
+	manifest *manifest.Manifest // nil if the artifact has no sproto.yaml
+}
+
+// readProtoFiles downloads an artifact and returns its .proto files keyed by slash-separated path.
+func (l *Loader) readProtoFiles(ctx context.Context, mv *models.ModuleVersion) (*artifactContents, error) {
+	reader, err := l.storage.DownloadFile(ctx, mv.ArtifactStorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download artifact %s: %w", mv.ArtifactStorageKey, err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact %s: %w", mv.ArtifactStorageKey, err)
+	}
+
+	contents, err := parseArtifact(data)
+	if err != nil {
+		return nil, fmt.Errorf("artifact %s: %w", mv.ArtifactStorageKey, err)
+	}
+	return contents, nil
+}
+
+// parseArtifact extracts the .proto files and packaged sproto.yaml from an artifact zip.
+func parseArtifact(data []byte) (*artifactContents, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	contents := &artifactContents{files: make(map[string][]byte)}
+	for _, f := range zipReader.File {
+		name := path.Clean(strings.ReplaceAll(f.Name, `\`, "/"))
+		isManifest := name == manifest.ManifestFileName
+		if f.FileInfo().IsDir() || (!isManifest && !strings.HasSuffix(name, ".proto")) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s in artifact: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s in artifact: %w", f.Name, err)
+		}
+		if isManifest {
+			if contents.manifest, err = manifest.ParseManifest(content); err != nil {
+				return nil, fmt.Errorf("invalid %s in artifact: %w", manifest.ManifestFileName, err)
+			}
+			continue
+		}
+		contents.files[name] = content
+	}
+	return contents, nil
+}
+
+// registerWithImports registers fd and, first, everything it imports.
+func registerWithImports(files *protoregistry.Files, fd protoreflect.FileDescriptor) error {
+	if _, err := files.FindFileByPath(fd.Path()); err == nil {
+		return nil // Already registered
+	}
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := registerWithImports(files, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	return files.RegisterFile(fd)
+}
+
+// toStringMap converts file contents for protocompile's map accessor.
+func toStringMap(sources map[string][]byte) map[string]string {
+	m := make(map[string]string, len(sources))
+	for p, content := range sources {
+		m[p] = string(content)
+	}
+	return m
+}
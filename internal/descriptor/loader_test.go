@@ -0,0 +1,121 @@
+package descriptor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testRegistry is a SQLite + local storage registry that modules can be published into.
+type testRegistry struct {
+	t       *testing.T
+	db      *gorm.DB
+	storage storage.StorageProvider
+	modules map[string]models.Module
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+	dir := t.TempDir()
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(dir, "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormDB.AutoMigrate(&models.Module{}, &models.ModuleVersion{}))
+	provider, err := storage.NewLocalStorage(config.Config{LocalStoragePath: filepath.Join(dir, "artifacts")})
+	require.NoError(t, err)
+	return &testRegistry{t: t, db: gormDB, storage: provider, modules: make(map[string]models.Module)}
+}
+
+// zipBytes builds an in-memory zip of files.
+func zipBytes(t *testing.T, files map[string]string) []byte {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for p, content := range files {
+		w, err := zw.Create(p)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// publish stores a zip of files as namespace/name@version.
+func (r *testRegistry) publish(namespace, name, version string, files map[string]string) {
+	buf := bytes.NewBuffer(zipBytes(r.t, files))
+
+	module, ok := r.modules[namespace+"/"+name]
+	if !ok {
+		module = models.Module{Namespace: namespace, Name: name}
+		require.NoError(r.t, r.db.Create(&module).Error)
+		r.modules[namespace+"/"+name] = module
+	}
+	key := namespace + "/" + name + "/" + version + ".zip"
+	require.NoError(r.t, r.storage.UploadFile(context.Background(), key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "application/zip"))
+	require.NoError(r.t, r.db.Create(&models.ModuleVersion{
+		ModuleID: module.ID, Version: version, ArtifactDigest: version, ArtifactStorageKey: key, CreatedAt: time.Now(),
+	}).Error)
+}
+
+func TestLoader_ResolvesDependenciesAndWellKnownTypes(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("acme", "common", "v1.0.0", map[string]string{
+		"acme/common/v1/money.proto": `syntax = "proto3"; package acme.common.v1; message Money { int64 cents = 1; }`,
+	})
+	reg.publish("acme", "common", "v2.0.0", map[string]string{
+		"acme/common/v2/money.proto": `syntax = "proto3"; package acme.common.v2; message Money { int64 cents = 1; }`,
+	})
+	reg.publish("acme", "billing", "v1.0.0", map[string]string{
+		"sproto.yaml": "name: acme/billing\ndependencies:\n  acme/common: ^1.0.0\n",
+		"acme/billing/v1/billing.proto": `syntax = "proto3";
+package acme.billing.v1;
+import "acme/common/v1/money.proto";
+import "google/protobuf/timestamp.proto";
+service Billing { rpc Charge(ChargeRequest) returns (ChargeResponse); }
+message ChargeRequest { acme.common.v1.Money amount = 1; google.protobuf.Timestamp at = 2; }
+message ChargeResponse {}
+`,
+	})
+
+	loader := NewLoader(reg.db, reg.storage)
+	schema, err := loader.Load(context.Background(), "acme", "billing", "") // Newest version
+	require.NoError(t, err)
+
+	assert.Equal(t, "v1.0.0", schema.Version)
+	assert.Equal(t, []string{"acme/billing/v1/billing.proto"}, schema.ModuleFiles)
+	assert.Equal(t, []string{"acme.billing.v1.Billing"}, schema.Services())
+	_, err = schema.Files.FindDescriptorByName("acme.common.v1.Money")
+	assert.NoError(t, err)
+	_, err = schema.Files.FindFileByPath("google/protobuf/timestamp.proto")
+	assert.NoError(t, err)
+
+	// Served from the cache the second time
+	again, err := loader.Load(context.Background(), "acme", "billing", "v1.0.0")
+	require.NoError(t, err)
+	assert.Same(t, schema, again)
+}
+
+func TestLoader_NotFoundAndCompileErrors(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("acme", "broken", "v1.0.0", map[string]string{
+		"broken.proto": `syntax = "proto3"; import "missing.proto"; message A { Missing m = 1; }`,
+	})
+	loader := NewLoader(reg.db, reg.storage)
+
+	_, err := loader.Load(context.Background(), "acme", "nope", "v1.0.0")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = loader.Load(context.Background(), "acme", "broken", "v1.0.0")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.Contains(t, err.Error(), "failed to compile")
+}
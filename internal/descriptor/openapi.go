@@ -0,0 +1,537 @@
+package descriptor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// OpenAPI generation: services annotated with google.api.http (the HTTP/JSON transcoding rules used by
+// grpc-gateway, Envoy and Google Cloud Endpoints) are described as an OpenAPI 3 document, with request
+// and response schemas following the proto3 JSON mapping.
+
+// ErrNoHTTPRules is returned when a module has no methods annotated with google.api.http.
+var ErrNoHTTPRules = errors.New("no methods annotated with google.api.http")
+
+// httpRuleExtension is the full name of the google.api.http method option.
+const httpRuleExtension = "google.api.http"
+
+// OpenAPIForArtifact compiles an artifact (a zip) for version against its dependencies and generates the
+// OpenAPI document of its HTTP-annotated services. Returns ErrNoHTTPRules if the artifact has none (checked
+// before compiling) and ErrInvalidArtifact if the artifact can't be read or compiled.
+func (l *Loader) OpenAPIForArtifact(ctx context.Context, namespace, name, version string, artifact []byte) ([]byte, error) {
+	contents, err := parseArtifact(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	// Cheap pre-check, so modules without HTTP rules (most of them) are never compiled
+	annotated := false
+	for _, content := range contents.files {
+		if bytes.Contains(content, []byte(httpRuleExtension)) {
+			annotated = true
+			break
+		}
+	}
+	if !annotated {
+		return nil, ErrNoHTTPRules
+	}
+
+	schema, err := l.compileContents(ctx, namespace, name, version, contents, nil)
+	if errors.Is(err, ErrCompile) || errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return OpenAPI(schema)
+}
+
+// OpenAPI generates the OpenAPI 3 document (JSON) of the HTTP-annotated services defined in the schema's
+// own files. Returns ErrNoHTTPRules if there are none.
+func OpenAPI(s *Schema) ([]byte, error) {
+	extension, ok := httpRuleExtensionType(s)
+	if !ok {
+		return nil, ErrNoHTTPRules // google/api/annotations.proto isn't imported
+	}
+	g := &openAPIGenerator{
+		extension:    extension,
+		doc:          newOpenAPIDocument(s),
+		operationIDs: map[string]int{},
+	}
+
+	for _, p := range s.ModuleFiles {
+		fd, err := s.Files.FindFileByPath(p)
+		if err != nil {
+			continue
+		}
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				if err := g.addMethod(methods.Get(j)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if len(g.doc.Paths) == 0 {
+		return nil, ErrNoHTTPRules
+	}
+	return json.MarshalIndent(g.doc, "", "  ")
+}
+
+// --- Document ---
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Tags        []string                   `json:"tags"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"` // path or query
+	Required bool           `json:"required,omitempty"`
+	Schema   *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties any                       `json:"additionalProperties,omitempty"` // *openAPISchema or true
+}
+
+func newOpenAPIDocument(s *Schema) *openAPIDocument {
+	return &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       s.Namespace + "/" + s.Name,
+			Version:     s.Version,
+			Description: fmt.Sprintf("Generated from the google.api.http annotations of %s/%s@%s.", s.Namespace, s.Name, s.Version),
+		},
+		Paths:      map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{Schemas: map[string]*openAPISchema{}},
+	}
+}
+
+// --- Generation ---
+
+type openAPIGenerator struct {
+	extension    protoreflect.ExtensionType
+	doc          *openAPIDocument
+	operationIDs map[string]int // Uses of each operationId, to keep them unique
+}
+
+// httpBinding is one HTTP mapping of a method (the rule itself or one of its additional_bindings).
+type httpBinding struct {
+	method       string // Lowercase, as used for OpenAPI path items
+	path         string // google.api.http path template
+	body         string // "", "*" or a request field name
+	responseBody string // "" or a response field name
+}
+
+// addMethod adds the operations of a method's HTTP rule (if any) to the document.
+func (g *openAPIGenerator) addMethod(m protoreflect.MethodDescriptor) error {
+	rule, err := methodHTTPRule(g.extension, m)
+	if err != nil || rule == nil {
+		return err
+	}
+	deprecated := false
+	if opts, ok := m.Options().(*descriptorpb.MethodOptions); ok {
+		deprecated = opts.GetDeprecated()
+	}
+
+	baseID := string(m.Parent().Name()) + "_" + string(m.Name())
+	for _, binding := range httpBindings(rule) {
+		path, pathParams := openAPIPath(binding.path)
+		if g.doc.Paths[path] == nil {
+			g.doc.Paths[path] = map[string]*openAPIOperation{}
+		}
+		if _, exists := g.doc.Paths[path][binding.method]; exists {
+			continue // Already mapped by another method; the first one wins
+		}
+
+		operationID := baseID
+		if n := g.operationIDs[baseID]; n > 0 {
+			operationID = baseID + "_" + strconv.Itoa(n)
+		}
+		g.operationIDs[baseID]++
+
+		op := &openAPIOperation{
+			OperationID: operationID,
+			Tags:        []string{string(m.Parent().FullName())},
+			Deprecated:  deprecated,
+			Responses: map[string]openAPIResponse{
+				"200": {
+					Description: "A successful response.",
+					Content:     jsonContent(g.responseSchema(m.Output(), binding.responseBody)),
+				},
+				"default": {
+					Description: "An unexpected error response.",
+					Content:     jsonContent(statusSchema()),
+				},
+			},
+		}
+		op.Parameters, op.RequestBody = g.requestParameters(m.Input(), binding.body, pathParams)
+		g.doc.Paths[path][binding.method] = op
+	}
+	return nil
+}
+
+// httpRuleExtensionType returns the google.api.http extension as compiled with the schema; false if the
+// schema doesn't import google/api/annotations.proto.
+func httpRuleExtensionType(s *Schema) (protoreflect.ExtensionType, bool) {
+	d, err := s.Files.FindDescriptorByName(httpRuleExtension)
+	if err != nil {
+		return nil, false
+	}
+	xd, ok := d.(protoreflect.ExtensionDescriptor)
+	if !ok {
+		return nil, false
+	}
+	return dynamicpb.NewExtensionType(xd), true
+}
+
+// methodHTTPRule returns the google.api.http option of a method, or nil if it has none.
+// The options are re-parsed with the extension known, as it comes from the module's sources.
+func methodHTTPRule(extension protoreflect.ExtensionType, m protoreflect.MethodDescriptor) (protoreflect.Message, error) {
+	raw, err := proto.Marshal(m.Options())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read options of %s: %w", m.FullName(), err)
+	}
+	types := new(protoregistry.Types)
+	if err := types.RegisterExtension(extension); err != nil {
+		return nil, err
+	}
+	opts := &descriptorpb.MethodOptions{}
+	if err := (proto.UnmarshalOptions{Resolver: types}).Unmarshal(raw, opts); err != nil {
+		return nil, fmt.Errorf("failed to read options of %s: %w", m.FullName(), err)
+	}
+	// Matched by name: the extension's descriptor comes from the module's compiled sources, not from the
+	// generated descriptorpb types, so proto.HasExtension doesn't recognize it
+	var rule protoreflect.Message
+	opts.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.IsExtension() && fd.FullName() == httpRuleExtension {
+			rule = v.Message()
+			return false
+		}
+		return true
+	})
+	return rule, nil
+}
+
+// httpBindings flattens a google.api.HttpRule and its additional_bindings.
+func httpBindings(rule protoreflect.Message) []httpBinding {
+	fields := rule.Descriptor().Fields()
+	var bindings []httpBinding
+	if pattern := rule.WhichOneof(rule.Descriptor().Oneofs().ByName("pattern")); pattern != nil {
+		b := httpBinding{
+			body:         rule.Get(fields.ByName("body")).String(),
+			responseBody: rule.Get(fields.ByName("response_body")).String(),
+		}
+		if pattern.Name() == "custom" {
+			custom := rule.Get(pattern).Message()
+			b.method = strings.ToLower(custom.Get(custom.Descriptor().Fields().ByName("kind")).String())
+			b.path = custom.Get(custom.Descriptor().Fields().ByName("path")).String()
+		} else {
+			b.method = string(pattern.Name()) // get, put, post, delete or patch
+			b.path = rule.Get(pattern).String()
+		}
+		if b.method != "" && strings.HasPrefix(b.path, "/") {
+			bindings = append(bindings, b)
+		}
+	}
+	additional := rule.Get(fields.ByName("additional_bindings")).List()
+	for i := 0; i < additional.Len(); i++ {
+		bindings = append(bindings, httpBindings(additional.Get(i).Message())...) // Nested bindings aren't allowed, but harmless
+	}
+	return bindings
+}
+
+// openAPIPath converts a google.api.http path template to an OpenAPI path, returning the variables'
+// field paths in order: "/v1/{name=shelves/*}/books" becomes "/v1/{name}/books".
+func openAPIPath(template string) (string, []string) {
+	var path strings.Builder
+	var params []string
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		variable, _, _ := strings.Cut(template[start+1:start+end], "=")
+		path.WriteString(template[:start])
+		path.WriteString("{" + variable + "}")
+		params = append(params, variable)
+		template = template[start+end+1:]
+	}
+	path.WriteString(template)
+	return path.String(), params
+}
+
+// requestParameters describes how a request message is mapped: path variables, the body (body is "*",
+// a field name, or "" for none) and query parameters for the remaining top-level fields.
+func (g *openAPIGenerator) requestParameters(input protoreflect.MessageDescriptor, body string, pathParams []string) ([]openAPIParameter, *openAPIRequestBody) {
+	var params []openAPIParameter
+	bound := map[string]bool{} // Top-level fields mapped by the path or body
+	for _, p := range pathParams {
+		schema := &openAPISchema{Type: "string"}
+		if fd := fieldByPath(input, p); fd != nil && fd.Message() == nil {
+			schema = g.scalarSchema(fd)
+		}
+		params = append(params, openAPIParameter{Name: p, In: "path", Required: true, Schema: schema})
+		top, _, _ := strings.Cut(p, ".")
+		bound[top] = true
+	}
+
+	var requestBody *openAPIRequestBody
+	switch body {
+	case "":
+	case "*":
+		// Every field not bound by the path, so no query parameters
+		schema := g.messageSchema(input)
+		if len(bound) > 0 {
+			schema = &openAPISchema{Type: "object", Properties: g.properties(input, bound)}
+		}
+		return params, &openAPIRequestBody{Required: true, Content: jsonContent(schema)}
+	default:
+		if fd := input.Fields().ByName(protoreflect.Name(body)); fd != nil {
+			requestBody = &openAPIRequestBody{Required: true, Content: jsonContent(g.fieldSchema(fd))}
+			bound[body] = true
+		}
+	}
+
+	fields := input.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if bound[string(fd.Name())] || fd.IsMap() {
+			continue
+		}
+		if fd.Message() != nil && !queryableMessage(fd.Message().FullName()) {
+			continue // Only scalars (and messages with a scalar JSON form) can be query parameters
+		}
+		params = append(params, openAPIParameter{Name: fd.JSONName(), In: "query", Schema: g.fieldSchema(fd)})
+	}
+	return params, requestBody
+}
+
+// responseSchema is the schema of the response message, or of its responseBody field if set.
+func (g *openAPIGenerator) responseSchema(output protoreflect.MessageDescriptor, responseBody string) *openAPISchema {
+	if responseBody != "" {
+		if fd := output.Fields().ByName(protoreflect.Name(responseBody)); fd != nil {
+			return g.fieldSchema(fd)
+		}
+	}
+	return g.messageSchema(output)
+}
+
+// --- Schemas (proto3 JSON mapping) ---
+
+// messageSchema returns the schema of a message: inline for well-known types with a special JSON form,
+// otherwise a reference to a component schema (added, with everything it uses, on first use).
+func (g *openAPIGenerator) messageSchema(md protoreflect.MessageDescriptor) *openAPISchema {
+	if schema := wellKnownSchema(md.FullName()); schema != nil {
+		return schema
+	}
+	name := string(md.FullName())
+	if _, exists := g.doc.Components.Schemas[name]; !exists {
+		schema := &openAPISchema{Type: "object"}
+		g.doc.Components.Schemas[name] = schema // Before the properties, for recursive messages
+		schema.Properties = g.properties(md, nil)
+	}
+	return &openAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+// properties returns the JSON properties of a message's fields, except those named in exclude.
+func (g *openAPIGenerator) properties(md protoreflect.MessageDescriptor, exclude map[string]bool) map[string]*openAPISchema {
+	properties := map[string]*openAPISchema{}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !exclude[string(fd.Name())] {
+			properties[fd.JSONName()] = g.fieldSchema(fd)
+		}
+	}
+	return properties
+}
+
+// fieldSchema returns the schema of a field, including repeated and map fields.
+func (g *openAPIGenerator) fieldSchema(fd protoreflect.FieldDescriptor) *openAPISchema {
+	switch {
+	case fd.IsMap():
+		return &openAPISchema{Type: "object", AdditionalProperties: g.singularSchema(fd.MapValue())}
+	case fd.IsList():
+		return &openAPISchema{Type: "array", Items: g.singularSchema(fd)}
+	default:
+		return g.singularSchema(fd)
+	}
+}
+
+func (g *openAPIGenerator) singularSchema(fd protoreflect.FieldDescriptor) *openAPISchema {
+	if fd.Message() != nil {
+		return g.messageSchema(fd.Message())
+	}
+	return g.scalarSchema(fd)
+}
+
+// scalarSchema returns the schema of a scalar or enum field (ignoring cardinality).
+func (g *openAPIGenerator) scalarSchema(fd protoreflect.FieldDescriptor) *openAPISchema {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		return g.enumSchema(fd.Enum())
+	case protoreflect.BoolKind:
+		return &openAPISchema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &openAPISchema{Type: "integer", Format: "int64"} // Doesn't fit an int32
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &openAPISchema{Type: "string", Format: "int64"} // 64-bit integers are JSON strings
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &openAPISchema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case protoreflect.BytesKind:
+		return &openAPISchema{Type: "string", Format: "byte"} // Base64
+	default:
+		return &openAPISchema{Type: "string"}
+	}
+}
+
+// enumSchema returns a reference to an enum's component schema (its value names).
+func (g *openAPIGenerator) enumSchema(ed protoreflect.EnumDescriptor) *openAPISchema {
+	name := string(ed.FullName())
+	if _, exists := g.doc.Components.Schemas[name]; !exists {
+		schema := &openAPISchema{Type: "string"}
+		values := ed.Values()
+		for i := 0; i < values.Len(); i++ {
+			schema.Enum = append(schema.Enum, string(values.Get(i).Name()))
+		}
+		g.doc.Components.Schemas[name] = schema
+	}
+	return &openAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+// wellKnownSchema returns the schema of well-known types with a special JSON form, or nil.
+func wellKnownSchema(name protoreflect.FullName) *openAPISchema {
+	switch name {
+	case "google.protobuf.Timestamp":
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case "google.protobuf.Duration", "google.protobuf.FieldMask", "google.protobuf.StringValue":
+		return &openAPISchema{Type: "string"}
+	case "google.protobuf.Struct":
+		return &openAPISchema{Type: "object", AdditionalProperties: true}
+	case "google.protobuf.Value":
+		return &openAPISchema{} // Any JSON value
+	case "google.protobuf.ListValue":
+		return &openAPISchema{Type: "array", Items: &openAPISchema{}}
+	case "google.protobuf.Any":
+		return &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{"@type": {Type: "string"}}, AdditionalProperties: true}
+	case "google.protobuf.Empty":
+		return &openAPISchema{Type: "object"}
+	case "google.protobuf.BoolValue":
+		return &openAPISchema{Type: "boolean"}
+	case "google.protobuf.Int32Value":
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case "google.protobuf.UInt32Value":
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case "google.protobuf.Int64Value":
+		return &openAPISchema{Type: "string", Format: "int64"}
+	case "google.protobuf.UInt64Value":
+		return &openAPISchema{Type: "string", Format: "uint64"}
+	case "google.protobuf.FloatValue":
+		return &openAPISchema{Type: "number", Format: "float"}
+	case "google.protobuf.DoubleValue":
+		return &openAPISchema{Type: "number", Format: "double"}
+	case "google.protobuf.BytesValue":
+		return &openAPISchema{Type: "string", Format: "byte"}
+	}
+	return nil
+}
+
+// queryableMessage reports whether a message type has a scalar JSON form usable as a query parameter.
+func queryableMessage(name protoreflect.FullName) bool {
+	schema := wellKnownSchema(name)
+	return schema != nil && schema.Type != "" && schema.Type != "object" && schema.Type != "array"
+}
+
+// statusSchema is the schema of google.rpc.Status, the error body of HTTP/JSON transcoding.
+func statusSchema() *openAPISchema {
+	return &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{
+		"code":    {Type: "integer", Format: "int32"},
+		"message": {Type: "string"},
+		"details": {Type: "array", Items: wellKnownSchema("google.protobuf.Any")},
+	}}
+}
+
+func jsonContent(schema *openAPISchema) map[string]openAPIMediaType {
+	return map[string]openAPIMediaType{"application/json": {Schema: schema}}
+}
+
+// fieldByPath resolves a dot-separated field path ("book.name") in a message, or returns nil.
+func fieldByPath(md protoreflect.MessageDescriptor, path string) protoreflect.FieldDescriptor {
+	var fd protoreflect.FieldDescriptor
+	for _, name := range strings.Split(path, ".") {
+		if md == nil {
+			return nil
+		}
+		if fd = md.Fields().ByName(protoreflect.Name(name)); fd == nil {
+			return nil
+		}
+		md = fd.Message()
+	}
+	return fd
+}
@@ -0,0 +1,124 @@
+package descriptor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/wkt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader_OpenAPIForArtifact(t *testing.T) {
+	reg := newTestRegistry(t)
+	modules, err := wkt.Modules()
+	require.NoError(t, err)
+	for _, m := range modules {
+		reg.publish(m.Namespace, m.Name, m.Version, m.Files)
+	}
+	loader := NewLoader(reg.db, reg.storage)
+	ctx := context.Background()
+
+	artifact := zipBytes(t, map[string]string{
+		"sproto.yaml": "name: acme/books\ndependencies:\n  google/api: ^1.0.0\n",
+		"acme/books/v1/books.proto": `syntax = "proto3"; package acme.books.v1;
+import "google/api/annotations.proto";
+import "google/protobuf/timestamp.proto";
+enum Genre { GENRE_UNSPECIFIED = 0; GENRE_FICTION = 1; }
+message Book { string name = 1; int64 page_count = 2; Genre genre = 3; google.protobuf.Timestamp published_at = 4; map<string, string> labels = 5; repeated Book sequels = 6; }
+message GetBookRequest { string name = 1; bool full_view = 2; }
+message CreateBookRequest { string parent = 1; Book book = 2; }
+message UpdateBookRequest { string name = 1; Book book = 2; string etag = 3; }
+service Library {
+  rpc GetBook(GetBookRequest) returns (Book) {
+    option (google.api.http) = { get: "/v1/{name=shelves/*/books/*}" additional_bindings { get: "/v1/books/{name}" } };
+  }
+  rpc CreateBook(CreateBookRequest) returns (Book) {
+    option (google.api.http) = { post: "/v1/{parent=shelves/*}/books" body: "book" };
+  }
+  rpc UpdateBook(UpdateBookRequest) returns (Book) {
+    option deprecated = true;
+    option (google.api.http) = { custom: { kind: "PATCH" path: "/v1/{name=shelves/*/books/*}:update" } body: "*" };
+  }
+  rpc Internal(GetBookRequest) returns (Book);
+}`,
+	})
+	data, err := loader.OpenAPIForArtifact(ctx, "acme", "books", "v1.0.0", artifact)
+	require.NoError(t, err)
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Title   string `json:"title"`
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, "acme/books", doc.Info.Title)
+	assert.Equal(t, "v1.0.0", doc.Info.Version)
+
+	// Path templates lose their patterns; unannotated methods are left out
+	assert.Len(t, doc.Paths, 4)
+	get := doc.Paths["/v1/{name}"]["get"]
+	require.NotNil(t, get)
+	assert.Equal(t, "Library_GetBook", get["operationId"])
+	assert.Equal(t, []any{"acme.books.v1.Library"}, get["tags"])
+	assert.Equal(t, []any{
+		map[string]any{"name": "name", "in": "path", "required": true, "schema": map[string]any{"type": "string"}},
+		map[string]any{"name": "fullView", "in": "query", "schema": map[string]any{"type": "boolean"}},
+	}, get["parameters"])
+	assert.Equal(t, "Library_GetBook_1", doc.Paths["/v1/books/{name}"]["get"]["operationId"])
+
+	// A body field: the remaining fields are query parameters, none here
+	create := doc.Paths["/v1/{parent}/books"]["post"]
+	require.NotNil(t, create)
+	assert.Len(t, create["parameters"], 1)
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/acme.books.v1.Book"},
+		create["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"])
+
+	// A custom method with the whole request as body, minus path variables
+	update := doc.Paths["/v1/{name}:update"]["patch"]
+	require.NotNil(t, update)
+	assert.Equal(t, true, update["deprecated"])
+	body := update["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"book": map[string]any{"$ref": "#/components/schemas/acme.books.v1.Book"},
+		"etag": map[string]any{"type": "string"},
+	}, body["properties"])
+
+	// Schemas follow the proto3 JSON mapping
+	book := doc.Components.Schemas["acme.books.v1.Book"]
+	require.NotNil(t, book)
+	assert.Equal(t, map[string]any{
+		"name":        map[string]any{"type": "string"},
+		"pageCount":   map[string]any{"type": "string", "format": "int64"},
+		"genre":       map[string]any{"$ref": "#/components/schemas/acme.books.v1.Genre"},
+		"publishedAt": map[string]any{"type": "string", "format": "date-time"},
+		"labels":      map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "string"}},
+		"sequels":     map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/acme.books.v1.Book"}},
+	}, book["properties"])
+	assert.Equal(t, []any{"GENRE_UNSPECIFIED", "GENRE_FICTION"}, doc.Components.Schemas["acme.books.v1.Genre"]["enum"])
+
+	// Deterministic
+	again, err := loader.OpenAPIForArtifact(ctx, "acme", "books", "v1.0.0", artifact)
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+
+	// Modules without HTTP rules aren't compiled
+	_, err = loader.OpenAPIForArtifact(ctx, "acme", "plain", "v1.0.0", zipBytes(t, map[string]string{
+		"acme/plain/v1/plain.proto": `syntax = "proto3"; package acme.plain.v1; service S { rpc M(M) returns (M); } message M {}`,
+	}))
+	assert.ErrorIs(t, err, ErrNoHTTPRules)
+
+	_, err = loader.OpenAPIForArtifact(ctx, "acme", "broken", "v1.0.0", zipBytes(t, map[string]string{
+		"acme/broken/v1/broken.proto": `syntax = "proto3"; import "google/api/annotations.proto";
+service S { rpc M(M) returns (M) { option (google.api.http) = { get: "/m" }; } } message M { Missing m = 1; }`,
+	}))
+	assert.ErrorIs(t, err, ErrInvalidArtifact)
+}
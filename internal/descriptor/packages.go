@@ -0,0 +1,39 @@
+package descriptor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/bufbuild/protocompile/ast"
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
+)
+
+// ArtifactPackages returns the distinct proto packages declared by the .proto files of an artifact (a
+// zip), sorted; files without a package statement declare none. Like ArtifactSyntaxes, only the
+// declarations are read, so the files needn't compile, but they must parse.
+func ArtifactPackages(artifact []byte) ([]string, error) {
+	contents, err := parseArtifact(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	seen := map[string]bool{}
+	for p, content := range contents.files {
+		file, err := parser.Parse(p, bytes.NewReader(content), reporter.NewHandler(nil))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+		}
+		for _, decl := range file.Decls {
+			if pkg, ok := decl.(*ast.PackageNode); ok {
+				seen[string(pkg.Name.AsIdentifier())] = true
+			}
+		}
+	}
+	packages := make([]string, 0, len(seen))
+	for pkg := range seen {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+	return packages, nil
+}
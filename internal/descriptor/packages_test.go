@@ -0,0 +1,28 @@
+package descriptor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactPackages(t *testing.T) {
+	packages, err := ArtifactPackages(zipBytes(t, map[string]string{
+		"user/v1/user.proto":    `syntax = "proto3"; package mycompany.user.v1; message User {}`,
+		"user/v1/service.proto": `syntax = "proto3"; package mycompany.user.v1;`,
+		"common/types.proto":    `syntax = "proto3"; package mycompany . common;`,
+		"legacy.proto":          `message Legacy {}`, // No package
+		"README.md":             "package not.a.proto;",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mycompany.common", "mycompany.user.v1"}, packages)
+
+	packages, err = ArtifactPackages(zipBytes(t, map[string]string{"README.md": "no protos"}))
+	require.NoError(t, err)
+	assert.Empty(t, packages)
+
+	_, err = ArtifactPackages(zipBytes(t, map[string]string{"a.proto": `syntax = "proto3"; package a.v1; message {`}))
+	assert.True(t, errors.Is(err, ErrInvalidArtifact), err)
+}
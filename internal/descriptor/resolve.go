@@ -0,0 +1,46 @@
+package descriptor
+
+import (
+	"context"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+)
+
+// Source returns a manifest.Source reading the registry's database and storage directly, for resolving
+// dependencies server-side (see manifest.Resolve). Packaged manifests come from the declaration cache
+// shared with Compatibility, so resolving again is cheap.
+func (l *Loader) Source(ctx context.Context) manifest.Source {
+	return &loaderSource{ctx: ctx, loader: l}
+}
+
+// loaderSource adapts a Loader to manifest.Source.
+type loaderSource struct {
+	ctx    context.Context
+	loader *Loader
+}
+
+// Versions lists the published versions of a module (none if it doesn't exist).
+func (s *loaderSource) Versions(module string) ([]string, error) {
+	namespace, name, err := manifest.SplitModule(module)
+	if err != nil {
+		return nil, err
+	}
+	return s.loader.versions(s.ctx, namespace, name)
+}
+
+// Manifest returns the sproto.yaml packaged in a module version and its artifact digest.
+func (s *loaderSource) Manifest(module, version string) (*manifest.Manifest, string, error) {
+	namespace, name, err := manifest.SplitModule(module)
+	if err != nil {
+		return nil, "", err
+	}
+	mv, err := s.loader.findVersion(s.ctx, namespace, name, version)
+	if err != nil {
+		return nil, "", err
+	}
+	declared, err := s.loader.declaredDependencies(s.ctx, mv)
+	if err != nil {
+		return nil, "", err
+	}
+	return declared, "sha256:" + mv.ArtifactDigest, nil
+}
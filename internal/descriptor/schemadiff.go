@@ -0,0 +1,181 @@
+package descriptor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Kinds of schema elements compared by SchemaDiff.
+const (
+	ElementFile      = "file"
+	ElementMessage   = "message"
+	ElementField     = "field"
+	ElementEnum      = "enum"
+	ElementEnumValue = "enum_value"
+	ElementService   = "service"
+	ElementMethod    = "method"
+)
+
+// How an element differs between two versions.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// ElementDiff is an element of a module's own files that was added, removed or changed between two versions.
+type ElementDiff struct {
+	Change  string `json:"change"`  // added, removed or changed
+	Kind    string `json:"kind"`    // file, message, field, enum, enum_value, service or method
+	Element string `json:"element"` // Fully-qualified name (the path for files)
+	File    string `json:"file"`    // File defining the element (the base version's if removed)
+	// Signatures of fields ("repeated string = 3"), enum values ("= 2") and methods ("(Req) returns (Resp)")
+	Before string `json:"before,omitempty"` // In the base version, if removed or changed
+	After  string `json:"after,omitempty"`  // In the new version, if added or changed
+}
+
+// SchemaDiff lists every element of the module's own files that differs between base and proposed, for
+// tools that refresh what they know of a schema (e.g. editor completions); BreakingChanges only lists what
+// breaks users. Elements are matched by fully-qualified name, so a renamed element is removed and added.
+// Messages, enums and services whose members changed aren't reported as changed themselves; an element
+// that moved to another file of the module is. A nil base has no elements. Sorted by element.
+func SchemaDiff(base, proposed *Schema) []ElementDiff {
+	before, after := schemaElements(base), schemaElements(proposed)
+	var diffs []ElementDiff
+	for key, old := range before {
+		cur, ok := after[key]
+		switch {
+		case !ok:
+			diffs = append(diffs, ElementDiff{Change: DiffRemoved, Kind: old.kind, Element: old.name, File: old.file, Before: old.signature})
+		case old.signature != cur.signature || old.file != cur.file:
+			diffs = append(diffs, ElementDiff{Change: DiffChanged, Kind: cur.kind, Element: cur.name, File: cur.file, Before: old.signature, After: cur.signature})
+		}
+	}
+	for key, cur := range after {
+		if _, ok := before[key]; !ok {
+			diffs = append(diffs, ElementDiff{Change: DiffAdded, Kind: cur.kind, Element: cur.name, File: cur.file, After: cur.signature})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Element != diffs[j].Element {
+			return diffs[i].Element < diffs[j].Element
+		}
+		return diffs[i].Kind < diffs[j].Kind
+	})
+	return diffs
+}
+
+// DiffVersions compiles two published versions of a module and diffs their schemas (see SchemaDiff).
+func (l *Loader) DiffVersions(ctx context.Context, namespace, name, from, to string) ([]ElementDiff, error) {
+	base, err := l.Load(ctx, namespace, name, from)
+	if err != nil {
+		return nil, err
+	}
+	proposed, err := l.Load(ctx, namespace, name, to)
+	if err != nil {
+		return nil, err
+	}
+	return SchemaDiff(base, proposed), nil
+}
+
+// DiffWithPrevious diffs a published version of a module with the newest published version older than it,
+// which is returned too. If there is none, previous is empty and every element of version is added.
+func (l *Loader) DiffWithPrevious(ctx context.Context, namespace, name, version string) (previous string, diffs []ElementDiff, err error) {
+	versions, err := l.versions(ctx, namespace, name)
+	if err != nil {
+		return "", nil, err
+	}
+	proposed, err := l.Load(ctx, namespace, name, version)
+	if err != nil {
+		return "", nil, err
+	}
+	var base *Schema
+	if previous = manifest.Previous(versions, version); previous != "" {
+		if base, err = l.Load(ctx, namespace, name, previous); err != nil {
+			return "", nil, err
+		}
+	}
+	return previous, SchemaDiff(base, proposed), nil
+}
+
+// schemaElement is an element of a schema's own files, as compared by SchemaDiff.
+type schemaElement struct {
+	kind, name, file, signature string
+}
+
+// schemaElements indexes the elements of a schema's own files by kind and name.
+func schemaElements(s *Schema) map[string]schemaElement {
+	elements := make(map[string]schemaElement)
+	if s == nil {
+		return elements
+	}
+	for _, p := range s.ModuleFiles {
+		fd, err := s.Files.FindFileByPath(p)
+		if err != nil {
+			continue
+		}
+		add := func(kind string, name protoreflect.FullName, signature string) {
+			elements[kind+":"+string(name)] = schemaElement{kind: kind, name: string(name), file: p, signature: signature}
+		}
+		add(ElementFile, protoreflect.FullName(p), "")
+
+		var enums func(protoreflect.EnumDescriptors)
+		enums = func(list protoreflect.EnumDescriptors) {
+			for i := 0; i < list.Len(); i++ {
+				enum := list.Get(i)
+				add(ElementEnum, enum.FullName(), "")
+				values := enum.Values()
+				for j := 0; j < values.Len(); j++ {
+					add(ElementEnumValue, values.Get(j).FullName(), fmt.Sprintf("= %d", values.Get(j).Number()))
+				}
+			}
+		}
+		var messages func(protoreflect.MessageDescriptors)
+		messages = func(list protoreflect.MessageDescriptors) {
+			for i := 0; i < list.Len(); i++ {
+				msg := list.Get(i)
+				if msg.IsMapEntry() {
+					continue // Part of the map field's signature
+				}
+				add(ElementMessage, msg.FullName(), "")
+				fields := msg.Fields()
+				for j := 0; j < fields.Len(); j++ {
+					add(ElementField, fields.Get(j).FullName(), fieldSignature(fields.Get(j)))
+				}
+				messages(msg.Messages())
+				enums(msg.Enums())
+			}
+		}
+		messages(fd.Messages())
+		enums(fd.Enums())
+		services := fd.Services()
+		for i := 0; i < services.Len(); i++ {
+			service := services.Get(i)
+			add(ElementService, service.FullName(), "")
+			methods := service.Methods()
+			for j := 0; j < methods.Len(); j++ {
+				add(ElementMethod, methods.Get(j).FullName(), methodSignature(methods.Get(j)))
+			}
+		}
+	}
+	return elements
+}
+
+// fieldSignature describes a field's label, type and number, e.g. "repeated string = 3".
+func fieldSignature(field protoreflect.FieldDescriptor) string {
+	label := ""
+	switch {
+	case field.IsMap():
+	case field.Cardinality() == protoreflect.Repeated:
+		label = "repeated "
+	case field.Cardinality() == protoreflect.Required:
+		label = "required "
+	case field.HasOptionalKeyword():
+		label = "optional "
+	}
+	return fmt.Sprintf("%s%s = %d", label, fieldType(field), field.Number())
+}
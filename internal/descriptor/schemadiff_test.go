@@ -0,0 +1,66 @@
+package descriptor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffVersions(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("acme", "billing", "v1.0.0", map[string]string{
+		"billing/v1/billing.proto": `syntax = "proto3";
+package billing.v1;
+message Invoice { string id = 1; int64 amount_cents = 2; Status status = 3; }
+enum Status { STATUS_UNSPECIFIED = 0; STATUS_PAID = 1; }
+service Billing { rpc GetInvoice(Invoice) returns (Invoice); }
+`,
+		"billing/v1/legacy.proto": `syntax = "proto3"; package billing.v1; message Legacy {}`,
+	})
+	reg.publish("acme", "billing", "v1.1.0", map[string]string{
+		"billing/v1/billing.proto": `syntax = "proto3";
+package billing.v1;
+message Invoice { string id = 1; repeated int64 amount_cents = 2; Status status = 3; optional string currency = 4; map<string, string> labels = 5; }
+enum Status { STATUS_UNSPECIFIED = 0; STATUS_PAID = 1; STATUS_VOID = 2; }
+service Billing { rpc GetInvoice(Invoice) returns (stream Invoice); }
+`,
+	})
+	loader := NewLoader(reg.db, reg.storage)
+
+	diffs, err := loader.DiffVersions(context.Background(), "acme", "billing", "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, []ElementDiff{
+		{Change: DiffChanged, Kind: ElementMethod, Element: "billing.v1.Billing.GetInvoice", File: "billing/v1/billing.proto",
+			Before: "(billing.v1.Invoice) returns (billing.v1.Invoice)", After: "(billing.v1.Invoice) returns (stream billing.v1.Invoice)"},
+		{Change: DiffChanged, Kind: ElementField, Element: "billing.v1.Invoice.amount_cents", File: "billing/v1/billing.proto", Before: "int64 = 2", After: "repeated int64 = 2"},
+		{Change: DiffAdded, Kind: ElementField, Element: "billing.v1.Invoice.currency", File: "billing/v1/billing.proto", After: "optional string = 4"},
+		{Change: DiffAdded, Kind: ElementField, Element: "billing.v1.Invoice.labels", File: "billing/v1/billing.proto", After: "map<string, string> = 5"},
+		{Change: DiffRemoved, Kind: ElementMessage, Element: "billing.v1.Legacy", File: "billing/v1/legacy.proto"},
+		{Change: DiffAdded, Kind: ElementEnumValue, Element: "billing.v1.STATUS_VOID", File: "billing/v1/billing.proto", After: "= 2"},
+		{Change: DiffRemoved, Kind: ElementFile, Element: "billing/v1/legacy.proto", File: "billing/v1/legacy.proto"},
+	}, diffs)
+
+	// Same version: nothing differs
+	diffs, err = loader.DiffVersions(context.Background(), "acme", "billing", "v1.1.0", "v1.1.0")
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	_, err = loader.DiffVersions(context.Background(), "acme", "billing", "v1.0.0", "v9.0.0")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	// Against the previous version; everything is added in the first one
+	previous, diffs, err := loader.DiffWithPrevious(context.Background(), "acme", "billing", "v1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", previous)
+	assert.Len(t, diffs, 7)
+	previous, diffs, err = loader.DiffWithPrevious(context.Background(), "acme", "billing", "v1.0.0")
+	require.NoError(t, err)
+	assert.Empty(t, previous)
+	assert.Contains(t, diffs, ElementDiff{Change: DiffAdded, Kind: ElementMessage, Element: "billing.v1.Legacy", File: "billing/v1/legacy.proto"})
+	for _, d := range diffs {
+		assert.Equal(t, DiffAdded, d.Change, d.Element)
+	}
+}
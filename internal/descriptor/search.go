@@ -0,0 +1,195 @@
+package descriptor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bufbuild/protocompile/ast"
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
+)
+
+// Search entries are what the registry's full-text search indexes for a module version: one entry per
+// file and per declaration (message, enum, service, method, field), carrying the leading comment that
+// documents it. They're read from the parsed sources rather than compiled descriptors, so comments are
+// available and the files needn't compile against their dependencies.
+
+// Kinds of search entries.
+const (
+	SearchKindFile    = "file"
+	SearchKindMessage = "message"
+	SearchKindEnum    = "enum"
+	SearchKindService = "service"
+	SearchKindMethod  = "method"
+	SearchKindField   = "field"
+)
+
+// maxSearchCommentLength caps the comment text indexed per entry (license headers and generated docs can
+// be huge, and only the start of a comment is shown in results anyway).
+const maxSearchCommentLength = 4096
+
+// SearchEntry is a searchable element of a module version.
+type SearchEntry struct {
+	Kind    string // One of the SearchKind* constants
+	Name    string // Fully-qualified name of the declaration (e.g. orders.v1.Order.id), or the path of a file
+	File    string // Path of the file declaring it
+	Comment string // Leading comment, without comment markers; for files, the comment on the package statement
+}
+
+// ArtifactSearchEntries returns the search entries of an artifact (a zip), sorted by file then in source
+// order, along with the module description from its packaged sproto.yaml (empty if there's none). The
+// .proto files must parse.
+func ArtifactSearchEntries(artifact []byte) (description string, entries []SearchEntry, err error) {
+	contents, err := parseArtifact(artifact)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	if contents.manifest != nil {
+		description = strings.TrimSpace(contents.manifest.Description)
+	}
+
+	paths := make([]string, 0, len(contents.files))
+	for p := range contents.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		file, err := parser.Parse(p, bytes.NewReader(contents.files[p]), reporter.NewHandler(nil))
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+		}
+		w := &searchWalker{file: file, path: p}
+		w.walkFile()
+		entries = append(entries, w.entries...)
+	}
+	return description, entries, nil
+}
+
+// searchWalker collects the search entries of one parsed file.
+type searchWalker struct {
+	file    *ast.FileNode
+	path    string
+	pkg     string
+	entries []SearchEntry
+}
+
+func (w *searchWalker) add(kind, name string, node ast.Node) {
+	w.entries = append(w.entries, SearchEntry{Kind: kind, Name: name, File: w.path, Comment: w.comment(node)})
+}
+
+// qualify prefixes a declaration name with its scope (the package, or the enclosing message or service).
+func qualify(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func (w *searchWalker) walkFile() {
+	var pkgNode ast.Node
+	for _, decl := range w.file.Decls {
+		if pkg, ok := decl.(*ast.PackageNode); ok {
+			w.pkg = string(pkg.Name.AsIdentifier())
+			pkgNode = pkg
+			break
+		}
+	}
+	fileEntry := SearchEntry{Kind: SearchKindFile, Name: w.path, File: w.path}
+	if pkgNode != nil {
+		fileEntry.Comment = w.comment(pkgNode)
+	}
+	w.entries = append(w.entries, fileEntry)
+
+	for _, decl := range w.file.Decls {
+		switch decl := decl.(type) {
+		case *ast.MessageNode:
+			w.walkMessage(w.pkg, decl.Name.Val, decl, decl.Decls)
+		case *ast.EnumNode:
+			w.add(SearchKindEnum, qualify(w.pkg, decl.Name.Val), decl)
+		case *ast.ServiceNode:
+			name := qualify(w.pkg, decl.Name.Val)
+			w.add(SearchKindService, name, decl)
+			for _, elem := range decl.Decls {
+				if rpc, ok := elem.(*ast.RPCNode); ok {
+					w.add(SearchKindMethod, qualify(name, rpc.Name.Val), rpc)
+				}
+			}
+		case *ast.ExtendNode:
+			w.walkExtend(w.pkg, decl)
+		}
+	}
+}
+
+// walkMessage adds a message (or group) and its fields and nested declarations.
+func (w *searchWalker) walkMessage(scope, msgName string, node ast.Node, decls []ast.MessageElement) {
+	name := qualify(scope, msgName)
+	w.add(SearchKindMessage, name, node)
+	for _, decl := range decls {
+		switch decl := decl.(type) {
+		case *ast.FieldNode:
+			w.add(SearchKindField, qualify(name, decl.Name.Val), decl)
+		case *ast.MapFieldNode:
+			w.add(SearchKindField, qualify(name, decl.Name.Val), decl)
+		case *ast.GroupNode:
+			w.walkMessage(name, decl.Name.Val, decl, decl.Decls)
+		case *ast.OneofNode:
+			for _, elem := range decl.Decls {
+				switch field := elem.(type) {
+				case *ast.FieldNode:
+					w.add(SearchKindField, qualify(name, field.Name.Val), field)
+				case *ast.GroupNode:
+					w.walkMessage(name, field.Name.Val, field, field.Decls)
+				}
+			}
+		case *ast.MessageNode:
+			w.walkMessage(name, decl.Name.Val, decl, decl.Decls)
+		case *ast.EnumNode:
+			w.add(SearchKindEnum, qualify(name, decl.Name.Val), decl)
+		case *ast.ExtendNode:
+			w.walkExtend(name, decl)
+		}
+	}
+}
+
+// walkExtend adds the extension fields declared by an extend block (scoped like nested declarations).
+func (w *searchWalker) walkExtend(scope string, ext *ast.ExtendNode) {
+	for _, decl := range ext.Decls {
+		switch field := decl.(type) {
+		case *ast.FieldNode:
+			w.add(SearchKindField, qualify(scope, field.Name.Val), field)
+		case *ast.GroupNode:
+			w.walkMessage(scope, field.Name.Val, field, field.Decls)
+		}
+	}
+}
+
+// comment returns the leading comments of a node as plain text: comment markers are removed, and the
+// comments are joined with newlines.
+func (w *searchWalker) comment(node ast.Node) string {
+	comments := w.file.NodeInfo(node).LeadingComments()
+	var lines []string
+	for i := 0; i < comments.Len(); i++ {
+		raw := comments.Index(i).RawText()
+		if text, ok := strings.CutPrefix(raw, "//"); ok {
+			lines = append(lines, strings.TrimSpace(text))
+			continue
+		}
+		raw = strings.TrimSuffix(strings.TrimPrefix(raw, "/*"), "*/")
+		for _, line := range strings.Split(raw, "\n") {
+			line = strings.TrimSpace(line)
+			line = strings.TrimSpace(strings.TrimLeft(line, "*")) // Javadoc-style " * " margins
+			lines = append(lines, line)
+		}
+	}
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	if len(text) > maxSearchCommentLength {
+		text = text[:maxSearchCommentLength]
+		for !utf8.ValidString(text) { // Don't cut a multi-byte character in half
+			text = text[:len(text)-1]
+		}
+	}
+	return text
+}
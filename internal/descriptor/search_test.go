@@ -0,0 +1,76 @@
+package descriptor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactSearchEntries(t *testing.T) {
+	description, entries, err := ArtifactSearchEntries(zipBytes(t, map[string]string{
+		"sproto.yaml": "name: acme/orders\nversion: v1.0.0\ndescription: \"  Order placement APIs \"\n",
+		"orders/v1/orders.proto": `syntax = "proto3";
+
+// Orders of the acme store.
+package orders.v1;
+
+// An order placed by a customer.
+// Orders are immutable once paid.
+message Order {
+  // Unique order identifier.
+  string id = 1;
+  map<string, string> labels = 2;
+  oneof payment {
+    /* Card token,
+     * never the card number. */
+    string card = 3;
+  }
+  // Line of an order.
+  message Line { int64 quantity = 1; }
+}
+
+/** Lifecycle states. */
+enum State { STATE_UNSPECIFIED = 0; }
+
+// Manages orders.
+service OrderService {
+  // Places an order.
+  rpc PlaceOrder(Order) returns (Order);
+}
+`,
+		"common/common.proto": `syntax = "proto2"; message Empty {}`,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "Order placement APIs", description)
+
+	assert.Equal(t, []SearchEntry{
+		{Kind: SearchKindFile, Name: "common/common.proto", File: "common/common.proto"},
+		{Kind: SearchKindMessage, Name: "Empty", File: "common/common.proto"},
+		{Kind: SearchKindFile, Name: "orders/v1/orders.proto", File: "orders/v1/orders.proto", Comment: "Orders of the acme store."},
+		{Kind: SearchKindMessage, Name: "orders.v1.Order", File: "orders/v1/orders.proto", Comment: "An order placed by a customer.\nOrders are immutable once paid."},
+		{Kind: SearchKindField, Name: "orders.v1.Order.id", File: "orders/v1/orders.proto", Comment: "Unique order identifier."},
+		{Kind: SearchKindField, Name: "orders.v1.Order.labels", File: "orders/v1/orders.proto"},
+		{Kind: SearchKindField, Name: "orders.v1.Order.card", File: "orders/v1/orders.proto", Comment: "Card token,\nnever the card number."},
+		{Kind: SearchKindMessage, Name: "orders.v1.Order.Line", File: "orders/v1/orders.proto", Comment: "Line of an order."},
+		{Kind: SearchKindField, Name: "orders.v1.Order.Line.quantity", File: "orders/v1/orders.proto"},
+		{Kind: SearchKindEnum, Name: "orders.v1.State", File: "orders/v1/orders.proto", Comment: "Lifecycle states."},
+		{Kind: SearchKindService, Name: "orders.v1.OrderService", File: "orders/v1/orders.proto", Comment: "Manages orders."},
+		{Kind: SearchKindMethod, Name: "orders.v1.OrderService.PlaceOrder", File: "orders/v1/orders.proto", Comment: "Places an order."},
+	}, entries)
+
+	// Long comments are truncated
+	_, entries, err = ArtifactSearchEntries(zipBytes(t, map[string]string{
+		"a.proto": "// " + strings.Repeat("é", maxSearchCommentLength) + "\nmessage A {}",
+	}))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.LessOrEqual(t, len(entries[1].Comment), maxSearchCommentLength)
+	assert.NotEmpty(t, entries[1].Comment)
+
+	// Files that don't parse
+	_, _, err = ArtifactSearchEntries(zipBytes(t, map[string]string{"a.proto": `message {`}))
+	assert.True(t, errors.Is(err, ErrInvalidArtifact), err)
+}
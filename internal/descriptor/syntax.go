@@ -0,0 +1,73 @@
+package descriptor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
+)
+
+// Syntax labels: the syntax or edition a .proto file declares. Files declaring an edition are labelled
+// with EditionPrefix and the edition, e.g. "edition-2023".
+const (
+	SyntaxProto2  = "proto2" // Also files without a syntax declaration
+	SyntaxProto3  = "proto3"
+	EditionPrefix = "edition-"
+)
+
+// ValidateSyntax checks that s is a syntax label: proto2, proto3 or edition-<year>. Editions aren't
+// restricted to those the registry can compile, so policies can name editions before they're supported.
+func ValidateSyntax(s string) error {
+	if s == SyntaxProto2 || s == SyntaxProto3 {
+		return nil
+	}
+	if edition, ok := strings.CutPrefix(s, EditionPrefix); ok && len(edition) == 4 && strings.Trim(edition, "0123456789") == "" {
+		return nil
+	}
+	return fmt.Errorf("invalid syntax %q: must be %s, %s or %s<year> (e.g. %s2023)", s, SyntaxProto2, SyntaxProto3, EditionPrefix, EditionPrefix)
+}
+
+// ArtifactSyntaxes returns the distinct syntax labels declared by the .proto files of an artifact (a zip),
+// sorted; empty if it has no .proto files. Only the declarations are read, so the files needn't compile,
+// but they must parse.
+func ArtifactSyntaxes(artifact []byte) ([]string, error) {
+	contents, err := parseArtifact(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	paths := make([]string, 0, len(contents.files))
+	for p := range contents.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	seen := map[string]bool{}
+	collector := &issueCollector{}
+	var parseErr error
+	for _, p := range paths {
+		file, err := parser.Parse(p, bytes.NewReader(contents.files[p]), reporter.NewHandler(collector.reporter()))
+		if err != nil {
+			parseErr = err // Parse the other files too, so every error is reported
+			continue
+		}
+		switch {
+		case file.Edition != nil:
+			seen[EditionPrefix+file.Edition.Edition.AsString()] = true
+		case file.Syntax != nil && file.Syntax.Syntax.AsString() == SyntaxProto3:
+			seen[SyntaxProto3] = true
+		default:
+			seen[SyntaxProto2] = true
+		}
+	}
+	if err := collector.err(parseErr); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	syntaxes := make([]string, 0, len(seen))
+	for s := range seen {
+		syntaxes = append(syntaxes, s)
+	}
+	sort.Strings(syntaxes)
+	return syntaxes, nil
+}
@@ -0,0 +1,42 @@
+package descriptor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactSyntaxes(t *testing.T) {
+	syntaxes, err := ArtifactSyntaxes(zipBytes(t, map[string]string{
+		"a/v1/a.proto":   `syntax = "proto3"; package a.v1; message A {}`,
+		"b/v1/b.proto":   `syntax = 'proto3'; package b.v1;`,
+		"legacy.proto":   `package legacy; message L { optional string s = 1; }`, // No declaration: proto2
+		"old/old.proto":  `syntax = "proto2"; package old;`,
+		"new/new.proto":  `edition = "2023"; package new;`,
+		"README.md":      "not a proto file",
+		"docs/notes.txt": "syntax = \"proto9\";",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"edition-2023", "proto2", "proto3"}, syntaxes)
+
+	syntaxes, err = ArtifactSyntaxes(zipBytes(t, map[string]string{"README.md": "no protos"}))
+	require.NoError(t, err)
+	assert.Empty(t, syntaxes)
+
+	// Files that don't parse, and archives that aren't zips
+	_, err = ArtifactSyntaxes(zipBytes(t, map[string]string{"a.proto": `syntax = "proto3"; message {`}))
+	assert.True(t, errors.Is(err, ErrInvalidArtifact), err)
+	_, err = ArtifactSyntaxes([]byte("not a zip"))
+	assert.True(t, errors.Is(err, ErrInvalidArtifact), err)
+}
+
+func TestValidateSyntax(t *testing.T) {
+	for _, valid := range []string{"proto2", "proto3", "edition-2023", "edition-2024"} {
+		assert.NoError(t, ValidateSyntax(valid), valid)
+	}
+	for _, invalid := range []string{"", "proto4", "2023", "edition-", "edition-23", "edition-abcd", "Proto3"} {
+		assert.Error(t, ValidateSyntax(invalid), invalid)
+	}
+}
@@ -0,0 +1,77 @@
+package descriptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+)
+
+// SameSchema reports whether two compiled versions of a module define the same schema: the same files
+// with identical descriptors. Source information (comments, whitespace, line numbers) is ignored, so
+// versions that only differ in documentation or formatting are the same schema. Options (e.g.
+// go_package) and imports are part of the descriptors and do count.
+func SameSchema(base, proposed *Schema) bool {
+	if len(base.ModuleFiles) != len(proposed.ModuleFiles) {
+		return false
+	}
+	for i, p := range base.ModuleFiles {
+		if proposed.ModuleFiles[i] != p { // Both sorted
+			return false
+		}
+		baseFile, err := base.Files.FindFileByPath(p)
+		if err != nil {
+			return false
+		}
+		proposedFile, err := proposed.Files.FindFileByPath(p)
+		if err != nil {
+			return false
+		}
+		baseProto, proposedProto := protodesc.ToFileDescriptorProto(baseFile), protodesc.ToFileDescriptorProto(proposedFile)
+		baseProto.SourceCodeInfo, proposedProto.SourceCodeInfo = nil, nil
+		if !proto.Equal(baseProto, proposedProto) {
+			return false
+		}
+	}
+	return true
+}
+
+// SchemaComparison is the result of comparing an artifact with the previous version of its module.
+type SchemaComparison struct {
+	PreviousVersion string // Newest published version older than the compared one
+	Unchanged       bool   // The artifact's schema is the same as PreviousVersion's (see SameSchema)
+}
+
+// CompareWithPrevious compiles an artifact (a zip) for version and compares its schema with the newest
+// published version older than version. Returns ErrNotFound if there is no older version and
+// ErrInvalidArtifact if the artifact can't be read or compiled.
+func (l *Loader) CompareWithPrevious(ctx context.Context, namespace, name, version string, artifact []byte) (*SchemaComparison, error) {
+	contents, err := parseArtifact(artifact)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	versions, err := l.versions(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	previous := manifest.Previous(versions, version)
+	if previous == "" {
+		return nil, fmt.Errorf("%w: %s/%s has no version older than %s", ErrNotFound, namespace, name, version)
+	}
+
+	base, err := l.Load(ctx, namespace, name, previous)
+	if err != nil {
+		return nil, err
+	}
+	proposed, err := l.compileContents(ctx, namespace, name, version, contents, nil)
+	if errors.Is(err, ErrCompile) || errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArtifact, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaComparison{PreviousVersion: previous, Unchanged: SameSchema(base, proposed)}, nil
+}
@@ -0,0 +1,82 @@
+package descriptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const billingV1 = `syntax = "proto3";
+package billing.v1;
+
+// An invoice.
+message Invoice {
+  string id = 1;
+  int64 amount_cents = 2;
+}
+`
+
+func TestCompareWithPrevious(t *testing.T) {
+	reg := newTestRegistry(t)
+	reg.publish("acme", "billing", "v1.0.0", map[string]string{"billing/v1/billing.proto": billingV1})
+	reg.publish("acme", "billing", "v2.0.0", map[string]string{"billing/v1/billing.proto": `syntax = "proto3"; package billing.v1; message Invoice { string id = 1; }`})
+	loader := NewLoader(reg.db, reg.storage)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		version   string
+		files     map[string]string
+		unchanged bool
+	}{
+		{"comments and whitespace only", "v1.0.1", map[string]string{"billing/v1/billing.proto": `syntax = "proto3";
+
+package billing.v1;
+
+// An invoice, amounts in cents.
+message Invoice {
+    string id = 1; // Unique
+    int64  amount_cents = 2;
+}
+`}, true},
+		{"field added", "v1.1.0", map[string]string{"billing/v1/billing.proto": `syntax = "proto3";
+package billing.v1;
+message Invoice { string id = 1; int64 amount_cents = 2; string currency = 3; }
+`}, false},
+		{"field renamed", "v1.0.1", map[string]string{"billing/v1/billing.proto": `syntax = "proto3";
+package billing.v1;
+message Invoice { string id = 1; int64 amount = 2; }
+`}, false},
+		{"option changed", "v1.0.1", map[string]string{"billing/v1/billing.proto": `syntax = "proto3";
+package billing.v1;
+option go_package = "example.com/billing/v1";
+message Invoice { string id = 1; int64 amount_cents = 2; }
+`}, false},
+		{"file added", "v1.0.1", map[string]string{
+			"billing/v1/billing.proto": billingV1,
+			"billing/v1/extra.proto":   `syntax = "proto3"; package billing.v1; message Extra {}`,
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmp, err := loader.CompareWithPrevious(ctx, "acme", "billing", tt.version, zipBytes(t, tt.files))
+			require.NoError(t, err)
+			assert.Equal(t, "v1.0.0", cmp.PreviousVersion, "compared with the newest older version, not the newest one")
+			assert.Equal(t, tt.unchanged, cmp.Unchanged)
+		})
+	}
+
+	// v2.0.1 is compared with v2.0.0
+	cmp, err := loader.CompareWithPrevious(ctx, "acme", "billing", "v2.0.1", zipBytes(t, map[string]string{"billing/v1/billing.proto": billingV1}))
+	require.NoError(t, err)
+	assert.Equal(t, "v2.0.0", cmp.PreviousVersion)
+	assert.False(t, cmp.Unchanged)
+
+	// No older version, invalid artifact
+	_, err = loader.CompareWithPrevious(ctx, "acme", "billing", "v0.9.0", zipBytes(t, map[string]string{"billing/v1/billing.proto": billingV1}))
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = loader.CompareWithPrevious(ctx, "acme", "billing", "v1.0.1", zipBytes(t, map[string]string{"billing/v1/billing.proto": "syntax = proto3"}))
+	assert.ErrorIs(t, err, ErrInvalidArtifact)
+}
@@ -0,0 +1,188 @@
+package gomod
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/artifact"
+	gengo "google.golang.org/protobuf/cmd/protoc-gen-go/internal_gengo"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ProtobufModule is the Go module generated code depends on.
+const ProtobufModule = "google.golang.org/protobuf"
+
+// DefaultProtobufVersion is required by generated code if the version of ProtobufModule linked into the
+// registry can't be determined.
+const DefaultProtobufVersion = "v1.36.5"
+
+// GoVersion is the go directive of generated go.mod files.
+const GoVersion = "1.21"
+
+// Require is a module requirement of a generated go.mod.
+type Require struct {
+	Path    string
+	Version string
+}
+
+// Module is a Go module to generate from a module version's compiled descriptors.
+type Module struct {
+	Path    string // Go module path (see ModulePath)
+	Version string
+	// Files are the .proto files to generate code for: those of the registry module itself.
+	Files []string
+	// ImportPaths maps the files of dependency modules to the Go packages generated for them in their own
+	// modules (see PackagePath). Files not listed here or in Files keep the go_package they declare, like
+	// the well-known types.
+	ImportPaths map[string]string
+	// Requires lists the modules the generated code imports besides ProtobufModule.
+	Requires []Require
+	// ProtobufVersion is the version of ProtobufModule to require; it must not be older than the
+	// generator's (the version linked into the registry).
+	ProtobufVersion string
+}
+
+// versionDirectory matches the last element of package directories such as user/v1.
+var versionDirectory = regexp.MustCompile(`^v\d+([a-z]+\d+)?$`)
+
+// PackagePath returns the Go import path of the package generated for a .proto file in the module at
+// modulePath: the module path plus the file's directory.
+func PackagePath(modulePath, protoFile string) string {
+	if dir := path.Dir(protoFile); dir != "." {
+		return modulePath + "/" + dir
+	}
+	return modulePath
+}
+
+// packageName returns the Go package name of a package directory: its last element, prefixed with the
+// one before if that is a version (acme/user/v1 is userv1), so versioned packages don't all end up named v1.
+func packageName(importPath string) string {
+	base := path.Base(importPath)
+	if parent := path.Base(path.Dir(importPath)); versionDirectory.MatchString(base) && parent != "." && parent != "/" {
+		base = parent + base
+	}
+	return strings.NewReplacer("-", "_", ".", "_").Replace(base)
+}
+
+// Generate runs protoc-gen-go on a module version's descriptors (every file after those it imports, as
+// descriptor.Bundle.DescriptorSet returns them) and returns the module's files, keyed by path relative to
+// the module root: go.mod and one .pb.go file per .proto file. The go_package options of the module's and
+// its dependencies' files are overridden, so generated packages always live in their registry module.
+func Generate(set *descriptorpb.FileDescriptorSet, m Module) (map[string][]byte, error) {
+	params := []string{"module=" + m.Path}
+	for _, f := range set.GetFile() {
+		name := f.GetName()
+		importPath := m.ImportPaths[name]
+		if importPath == "" && slices.Contains(m.Files, name) {
+			importPath = PackagePath(m.Path, name)
+		}
+		if importPath == "" {
+			continue
+		}
+		mapping := "M" + name + "=" + importPath
+		if f.GetOptions().GetGoPackage() == "" {
+			mapping += ";" + packageName(importPath)
+		}
+		params = append(params, mapping)
+	}
+	request := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: m.Files,
+		Parameter:      proto.String(strings.Join(params, ",")),
+		ProtoFile:      set.GetFile(),
+	}
+	gen, err := protogen.Options{}.New(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare Go generation of %s@%s: %w", m.Path, m.Version, err)
+	}
+	for _, f := range gen.Files {
+		if f.Generate {
+			gengo.GenerateFile(gen, f)
+		}
+	}
+	resp := gen.Response()
+	if resp.Error != nil {
+		return nil, fmt.Errorf("failed to generate Go code for %s@%s: %s", m.Path, m.Version, resp.GetError())
+	}
+
+	files := map[string][]byte{"go.mod": GoMod(m)}
+	for _, f := range resp.GetFile() {
+		files[f.GetName()] = []byte(f.GetContent())
+	}
+	return files, nil
+}
+
+// ProtobufVersion returns the version of ProtobufModule linked into the registry: the version of the
+// generator, and so the oldest runtime generated code supports.
+func ProtobufVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path != ProtobufModule {
+				continue
+			}
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				return dep.Replace.Version
+			}
+			if dep.Version != "" && dep.Version != "(devel)" {
+				return dep.Version
+			}
+		}
+	}
+	return DefaultProtobufVersion
+}
+
+// GoMod returns the go.mod file of a generated module.
+func GoMod(m Module) []byte {
+	requires := append([]Require{{Path: ProtobufModule, Version: m.ProtobufVersion}}, m.Requires...)
+	sort.Slice(requires, func(i, j int) bool { return requires[i].Path < requires[j].Path })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "module %s\n\ngo %s\n\nrequire (\n", m.Path, GoVersion)
+	for _, r := range requires {
+		fmt.Fprintf(&b, "\t%s %s\n", r.Path, r.Version)
+	}
+	b.WriteString(")\n")
+	return []byte(b.String())
+}
+
+// Zip packs a generated module's files into a module zip as the go command downloads it: every file under
+// "<module path>@<version>/". The archive is in canonical form (see package artifact), so generating the
+// same module twice produces the same bytes.
+func Zip(modulePath, version string, files map[string][]byte) ([]byte, error) {
+	prefixed := make(map[string][]byte, len(files))
+	for name, content := range files {
+		prefixed[modulePath+"@"+version+"/"+name] = content
+	}
+	return artifact.Pack(prefixed)
+}
+
+// ReadGoMod returns the go.mod file of a module zip (see Zip).
+func ReadGoMod(moduleZip []byte, modulePath, version string) ([]byte, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(moduleZip), int64(len(moduleZip)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid module zip: %w", err)
+	}
+	name := modulePath + "@" + version + "/go.mod"
+	for _, f := range zipReader.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("module zip contains no %s", name)
+}
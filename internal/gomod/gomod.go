@@ -0,0 +1,107 @@
+// Package gomod turns module versions into Go modules of generated code, for the registry's Go module
+// proxy (the GOPROXY protocol): each registry module major version is a Go module under a configured
+// prefix, e.g. buf.example.com/gen/go/acme/user and buf.example.com/gen/go/acme/user/v2, holding the
+// protoc-gen-go output of its .proto files. Dependencies are Go modules of their own, required by the
+// generated go.mod at the versions the module was compiled against.
+package gomod
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// ModulePath returns the Go module path of a registry module's major version: prefix/namespace/name, with
+// a /vN suffix from major version 2 on (versions 0 and 1 share the unsuffixed path, as in Go).
+func ModulePath(prefix, namespace, name string, major uint64) string {
+	modulePath := strings.TrimSuffix(prefix, "/") + "/" + namespace + "/" + name
+	if major >= 2 {
+		modulePath += "/v" + strconv.FormatUint(major, 10)
+	}
+	return modulePath
+}
+
+// ParseModulePath splits a Go module path under prefix (see ModulePath) into the registry module and the
+// major version of its path (0 for the unsuffixed path).
+func ParseModulePath(prefix, modulePath string) (namespace, name string, major uint64, err error) {
+	rest, ok := strings.CutPrefix(modulePath, strings.TrimSuffix(prefix, "/")+"/")
+	if !ok {
+		return "", "", 0, fmt.Errorf("module path %s is not under %s", modulePath, prefix)
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) == 3 {
+		major, ok = pathMajor(parts[2])
+		if !ok {
+			return "", "", 0, fmt.Errorf("invalid module path %s: %s is not a major version suffix (v2, v3, ...)", modulePath, parts[2])
+		}
+		parts = parts[:2]
+	}
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", 0, fmt.Errorf("invalid module path %s: expected %s/<namespace>/<module>[/vN]", modulePath, prefix)
+	}
+	return parts[0], parts[1], major, nil
+}
+
+// pathMajor parses a major version suffix (v2 and up).
+func pathMajor(suffix string) (uint64, bool) {
+	digits, ok := strings.CutPrefix(suffix, "v")
+	if !ok || digits == "" || digits[0] == '0' {
+		return 0, false
+	}
+	major, err := strconv.ParseUint(digits, 10, 64)
+	return major, err == nil && major >= 2
+}
+
+// VersionMajor returns the major version of a registry version, and whether the version can be a Go module
+// version at all: Go only accepts canonical semantic versions (vMAJOR.MINOR.PATCH, optionally with a
+// pre-release) without build metadata.
+func VersionMajor(version string) (uint64, bool) {
+	v, err := semver.StrictNewVersion(strings.TrimPrefix(version, "v"))
+	if err != nil || !strings.HasPrefix(version, "v") || v.Metadata() != "" {
+		return 0, false
+	}
+	return v.Major(), true
+}
+
+// MatchesPath reports whether a version with major version versionMajor belongs to the module path of
+// major version pathMajor (see ParseModulePath).
+func MatchesPath(pathMajor, versionMajor uint64) bool {
+	if pathMajor == 0 {
+		return versionMajor <= 1
+	}
+	return versionMajor == pathMajor
+}
+
+// UnescapePath decodes a module path or version as escaped in GOPROXY URLs: each uppercase letter is
+// written as "!" followed by its lowercase form (so paths stay unambiguous on case-insensitive file
+// systems), and escaped paths have no uppercase letters.
+func UnescapePath(escaped string) (string, error) {
+	var b strings.Builder
+	bang := false
+	for _, r := range escaped {
+		switch {
+		case r >= utf8.RuneSelf:
+			return "", fmt.Errorf("invalid escaped path %q: non-ASCII character", escaped)
+		case bang:
+			if r < 'a' || r > 'z' {
+				return "", fmt.Errorf("invalid escaped path %q: \"!\" must be followed by a lowercase letter", escaped)
+			}
+			b.WriteRune(r - 'a' + 'A')
+			bang = false
+		case r == '!':
+			bang = true
+		case r >= 'A' && r <= 'Z':
+			return "", fmt.Errorf("invalid escaped path %q: uppercase letters must be escaped", escaped)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if bang {
+		return "", errors.New("invalid escaped path " + strconv.Quote(escaped) + ": trailing \"!\"")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,136 @@
+package gomod
+
+import (
+	"context"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestModulePath(t *testing.T) {
+	assert.Equal(t, "buf.example.com/gen/go/acme/user", ModulePath("buf.example.com/gen/go/", "acme", "user", 1))
+	assert.Equal(t, "buf.example.com/gen/go/acme/user/v2", ModulePath("buf.example.com/gen/go", "acme", "user", 2))
+
+	namespace, name, major, err := ParseModulePath("buf.example.com/gen/go", "buf.example.com/gen/go/acme/user/v3")
+	require.NoError(t, err)
+	assert.Equal(t, []any{"acme", "user", uint64(3)}, []any{namespace, name, major})
+	_, _, major, err = ParseModulePath("buf.example.com/gen/go", "buf.example.com/gen/go/acme/user")
+	require.NoError(t, err)
+	assert.Zero(t, major)
+
+	for _, invalid := range []string{"other.com/acme/user", "buf.example.com/gen/go/acme", "buf.example.com/gen/go/acme/user/v1", "buf.example.com/gen/go/acme/user/v02", "buf.example.com/gen/go/acme/user/v2/x"} {
+		_, _, _, err := ParseModulePath("buf.example.com/gen/go", invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestVersionMajor(t *testing.T) {
+	for version, want := range map[string]uint64{"v0.3.0": 0, "v1.2.3": 1, "v2.0.0-rc.1": 2} {
+		major, ok := VersionMajor(version)
+		assert.True(t, ok, version)
+		assert.Equal(t, want, major, version)
+	}
+	for _, invalid := range []string{"1.2.3", "v1.2", "v1.2.3+build.5", "v01.2.3"} {
+		_, ok := VersionMajor(invalid)
+		assert.False(t, ok, invalid)
+	}
+	assert.True(t, MatchesPath(0, 1))
+	assert.True(t, MatchesPath(0, 0))
+	assert.False(t, MatchesPath(0, 2))
+	assert.True(t, MatchesPath(2, 2))
+	assert.False(t, MatchesPath(3, 2))
+}
+
+func TestUnescapePath(t *testing.T) {
+	unescaped, err := UnescapePath("buf.example.com/!acme/user")
+	require.NoError(t, err)
+	assert.Equal(t, "buf.example.com/Acme/user", unescaped)
+	for _, invalid := range []string{"buf.example.com/Acme", "a!", "a!1"} {
+		_, err := UnescapePath(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	sources := map[string]string{
+		"acme/types/v1/id.proto": `syntax = "proto3"; package acme.types.v1;
+option go_package = "github.com/acme/types/typespb";
+message ID { string value = 1; }`,
+		"acme/billing/v1/billing.proto": `syntax = "proto3"; package acme.billing.v1;
+import "acme/types/v1/id.proto";
+import "google/protobuf/timestamp.proto";
+message Charge { acme.types.v1.ID account = 1; google.protobuf.Timestamp at = 2; }`,
+	}
+	compiler := protocompile.Compiler{Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+		Accessor: protocompile.SourceAccessorFromMap(sources),
+	})}
+	compiled, err := compiler.Compile(context.Background(), "acme/billing/v1/billing.proto")
+	require.NoError(t, err)
+	set := &descriptorpb.FileDescriptorSet{}
+	appendFile(set, compiled[0], map[string]bool{})
+
+	m := Module{
+		Path:            "buf.example.com/gen/go/acme/billing/v2",
+		Version:         "v2.1.0",
+		Files:           []string{"acme/billing/v1/billing.proto"},
+		ImportPaths:     map[string]string{"acme/types/v1/id.proto": PackagePath("buf.example.com/gen/go/acme/types", "acme/types/v1/id.proto")},
+		Requires:        []Require{{Path: "buf.example.com/gen/go/acme/types", Version: "v1.0.0"}},
+		ProtobufVersion: "v1.36.5",
+	}
+	files, err := Generate(set, m)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, `module buf.example.com/gen/go/acme/billing/v2
+
+go 1.21
+
+require (
+	buf.example.com/gen/go/acme/types v1.0.0
+	google.golang.org/protobuf v1.36.5
+)
+`, string(files["go.mod"]))
+
+	// Packages are named after their directory; dependencies and the well-known types are imported
+	code := files["acme/billing/v1/billing.pb.go"]
+	require.NotNil(t, code)
+	parsed, err := parser.ParseFile(token.NewFileSet(), "billing.pb.go", code, parser.ImportsOnly)
+	require.NoError(t, err)
+	assert.Equal(t, "billingv1", parsed.Name.Name)
+	var imports []string
+	for _, spec := range parsed.Imports {
+		imports = append(imports, spec.Path.Value)
+	}
+	assert.Contains(t, imports, `"buf.example.com/gen/go/acme/types/acme/types/v1"`)
+	assert.Contains(t, imports, `"google.golang.org/protobuf/types/known/timestamppb"`)
+
+	// Module zips hold everything under module@version; their go.mod is what .mod serves
+	moduleZip, err := Zip(m.Path, m.Version, files)
+	require.NoError(t, err)
+	again, err := Zip(m.Path, m.Version, files)
+	require.NoError(t, err)
+	assert.Equal(t, moduleZip, again)
+	goMod, err := ReadGoMod(moduleZip, m.Path, m.Version)
+	require.NoError(t, err)
+	assert.Equal(t, files["go.mod"], goMod)
+	_, err = ReadGoMod(moduleZip, m.Path, "v2.0.0")
+	assert.Error(t, err)
+}
+
+// appendFile appends fd to set after everything it imports.
+func appendFile(set *descriptorpb.FileDescriptorSet, fd protoreflect.FileDescriptor, added map[string]bool) {
+	if added[fd.Path()] {
+		return
+	}
+	added[fd.Path()] = true
+	for i := 0; i < fd.Imports().Len(); i++ {
+		appendFile(set, fd.Imports().Get(i).FileDescriptor, added)
+	}
+	set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+}
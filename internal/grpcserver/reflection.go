@@ -0,0 +1,160 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	v1reflectiongrpc "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	v1alphareflectiongrpc "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ModuleMetadataKey is the gRPC metadata key selecting the module served by a reflection stream,
+// as "namespace/name@version" (or "namespace/name" for the newest version).
+const ModuleMetadataKey = "sproto-module"
+
+// schemaLoader compiles module versions (implemented by *descriptor.Loader).
+type schemaLoader interface {
+	Load(ctx context.Context, namespace, name, version string) (*descriptor.Schema, error)
+}
+
+// PublicOnly wraps loader so that modules for which isPublic reports false are treated as not found.
+// Reflection is unauthenticated, so it must only serve public modules.
+func PublicOnly(loader schemaLoader, isPublic func(ctx context.Context, namespace, name string) (bool, error)) schemaLoader {
+	return publicLoader{loader: loader, isPublic: isPublic}
+}
+
+type publicLoader struct {
+	loader   schemaLoader
+	isPublic func(ctx context.Context, namespace, name string) (bool, error)
+}
+
+func (p publicLoader) Load(ctx context.Context, namespace, name, version string) (*descriptor.Schema, error) {
+	public, err := p.isPublic(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if !public {
+		return nil, descriptor.ErrNotFound
+	}
+	return p.loader.Load(ctx, namespace, name, version)
+}
+
+// NewServer creates a gRPC server exposing the Server Reflection service (v1 and v1alpha)
+// for the schemas stored in the registry.
+func NewServer(loader schemaLoader) *grpc.Server {
+	server := grpc.NewServer()
+	r := &registryReflection{loader: loader}
+	v1reflectiongrpc.RegisterServerReflectionServer(server, r)
+	v1alphareflectiongrpc.RegisterServerReflectionServer(server, &registryReflectionV1Alpha{r})
+	return server
+}
+
+// registryReflection serves reflection requests from the module selected by the stream's metadata.
+// Each stream is delegated to a standard reflection server built over the module's descriptors.
+type registryReflection struct {
+	v1reflectiongrpc.UnimplementedServerReflectionServer
+	loader schemaLoader
+}
+
+// ServerReflectionInfo implements the v1 reflection service.
+func (r *registryReflection) ServerReflectionInfo(stream v1reflectiongrpc.ServerReflection_ServerReflectionInfoServer) error {
+	opts, err := r.optionsFor(stream.Context())
+	if err != nil {
+		return err
+	}
+	return reflection.NewServerV1(opts).ServerReflectionInfo(stream)
+}
+
+// registryReflectionV1Alpha serves the deprecated v1alpha reflection service, still used by older clients.
+type registryReflectionV1Alpha struct {
+	*registryReflection
+}
+
+// ServerReflectionInfo implements the v1alpha reflection service.
+func (r *registryReflectionV1Alpha) ServerReflectionInfo(stream v1alphareflectiongrpc.ServerReflection_ServerReflectionInfoServer) error {
+	opts, err := r.optionsFor(stream.Context())
+	if err != nil {
+		return err
+	}
+	return reflection.NewServer(opts).ServerReflectionInfo(stream)
+}
+
+// optionsFor loads the module named in the stream metadata and returns reflection options serving it.
+func (r *registryReflection) optionsFor(ctx context.Context) (reflection.ServerOptions, error) {
+	log := logging.L()
+	values := metadata.ValueFromIncomingContext(ctx, ModuleMetadataKey)
+	if len(values) == 0 {
+		return reflection.ServerOptions{}, status.Errorf(codes.InvalidArgument,
+			"missing %q metadata: set it to namespace/name@version (e.g. grpcurl -H '%s: acme/billing@v1.0.0')", ModuleMetadataKey, ModuleMetadataKey)
+	}
+
+	module, version, _ := strings.Cut(values[0], "@")
+	namespace, name, err := manifest.SplitModule(module)
+	if err != nil {
+		return reflection.ServerOptions{}, status.Errorf(codes.InvalidArgument, "invalid %q metadata: %v", ModuleMetadataKey, err)
+	}
+
+	schema, err := r.loader.Load(ctx, namespace, name, version)
+	if err != nil {
+		if errors.Is(err, descriptor.ErrNotFound) {
+			return reflection.ServerOptions{}, status.Error(codes.NotFound, err.Error())
+		}
+		log.Warn("Failed to load module descriptors for reflection", zap.String("module", values[0]), zap.Error(err))
+		return reflection.ServerOptions{}, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return reflection.ServerOptions{
+		Services:           moduleServices(schema.Services()),
+		DescriptorResolver: schema.Files,
+		ExtensionResolver:  extensionTypes(schema.Files),
+	}, nil
+}
+
+// moduleServices advertises the module's services in the reflection ListServices response.
+type moduleServices []string
+
+// GetServiceInfo implements reflection.ServiceInfoProvider.
+func (s moduleServices) GetServiceInfo() map[string]grpc.ServiceInfo {
+	info := make(map[string]grpc.ServiceInfo, len(s))
+	for _, name := range s {
+		info[name] = grpc.ServiceInfo{}
+	}
+	return info
+}
+
+// extensionTypes builds an extension registry for every extension declared in files.
+func extensionTypes(files *protoregistry.Files) *protoregistry.Types {
+	types := new(protoregistry.Types)
+	var register func(xds protoreflect.ExtensionDescriptors)
+	var registerMessages func(mds protoreflect.MessageDescriptors)
+	register = func(xds protoreflect.ExtensionDescriptors) {
+		for i := 0; i < xds.Len(); i++ {
+			_ = types.RegisterExtension(dynamicpb.NewExtensionType(xds.Get(i))) // Duplicates are impossible within a valid registry
+		}
+	}
+	registerMessages = func(mds protoreflect.MessageDescriptors) {
+		for i := 0; i < mds.Len(); i++ {
+			register(mds.Get(i).Extensions())
+			registerMessages(mds.Get(i).Messages())
+		}
+	}
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		register(fd.Extensions())
+		registerMessages(fd.Messages())
+		return true
+	})
+	return types
+}
@@ -0,0 +1,113 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/bufbuild/protocompile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	v1reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// fakeLoader compiles a fixed set of sources for "acme/greeter@v1.0.0".
+type fakeLoader struct {
+	t *testing.T
+}
+
+func (f fakeLoader) Load(ctx context.Context, namespace, name, version string) (*descriptor.Schema, error) {
+	if namespace != "acme" || name != "greeter" || (version != "" && version != "v1.0.0") {
+		return nil, descriptor.ErrNotFound
+	}
+	compiler := protocompile.Compiler{Resolver: &protocompile.SourceResolver{
+		Accessor: protocompile.SourceAccessorFromMap(map[string]string{
+			"greeter.proto": `syntax = "proto3"; package acme.greeter.v1;
+service Greeter { rpc SayHello(HelloRequest) returns (HelloReply); }
+message HelloRequest { string name = 1; }
+message HelloReply { string message = 1; }`,
+		}),
+	}}
+	compiled, err := compiler.Compile(ctx, "greeter.proto")
+	require.NoError(f.t, err)
+	files := new(protoregistry.Files)
+	require.NoError(f.t, files.RegisterFile(compiled[0]))
+	return &descriptor.Schema{Namespace: namespace, Name: name, Version: "v1.0.0", Files: files, ModuleFiles: []string{"greeter.proto"}}, nil
+}
+
+// dialReflection starts the reflection server in memory and returns a v1 reflection client.
+func dialReflection(t *testing.T) v1reflectionpb.ServerReflectionClient {
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(fakeLoader{t: t})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return v1reflectionpb.NewServerReflectionClient(conn)
+}
+
+// reflect sends a single reflection request with the given module metadata.
+func reflect(t *testing.T, client v1reflectionpb.ServerReflectionClient, module string, req *v1reflectionpb.ServerReflectionRequest) (*v1reflectionpb.ServerReflectionResponse, error) {
+	ctx := context.Background()
+	if module != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, ModuleMetadataKey, module)
+	}
+	stream, err := client.ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(req))
+	return stream.Recv()
+}
+
+func TestReflection_ListServicesAndFileContainingSymbol(t *testing.T) {
+	client := dialReflection(t)
+
+	resp, err := reflect(t, client, "acme/greeter@v1.0.0", &v1reflectionpb.ServerReflectionRequest{
+		MessageRequest: &v1reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	require.NoError(t, err)
+	services := resp.GetListServicesResponse().GetService()
+	require.Len(t, services, 1)
+	assert.Equal(t, "acme.greeter.v1.Greeter", services[0].GetName())
+
+	resp, err = reflect(t, client, "acme/greeter", &v1reflectionpb.ServerReflectionRequest{
+		MessageRequest: &v1reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: "acme.greeter.v1.HelloRequest"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, resp.GetFileDescriptorResponse().GetFileDescriptorProto(), 1)
+}
+
+func TestReflection_ModuleSelectionErrors(t *testing.T) {
+	client := dialReflection(t)
+	listServices := &v1reflectionpb.ServerReflectionRequest{MessageRequest: &v1reflectionpb.ServerReflectionRequest_ListServices{}}
+
+	_, err := reflect(t, client, "", listServices)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = reflect(t, client, "acme/greeter@v9.9.9", listServices)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestPublicOnly(t *testing.T) {
+	loader := PublicOnly(fakeLoader{t: t}, func(ctx context.Context, namespace, name string) (bool, error) {
+		return namespace != "acme", nil
+	})
+	_, err := loader.Load(context.Background(), "acme", "greeter", "v1.0.0")
+	assert.ErrorIs(t, err, descriptor.ErrNotFound)
+
+	public := PublicOnly(fakeLoader{t: t}, func(ctx context.Context, namespace, name string) (bool, error) { return true, nil })
+	schema, err := public.Load(context.Background(), "acme", "greeter", "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", schema.Version)
+}
@@ -0,0 +1,155 @@
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/notify"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// EventDigestMismatch is sent to the webhook when a stored artifact no longer matches its recorded digest.
+const EventDigestMismatch = "artifact.digest_mismatch"
+
+// Check results, used as the "result" metric label.
+const (
+	ResultOK       = "ok"
+	ResultMismatch = "mismatch"
+	ResultError    = "error"
+)
+
+// Verifier periodically re-downloads a random sample of stored artifacts and compares
+// their SHA256 with the digest recorded at publish time.
+type Verifier struct {
+	db         *gorm.DB
+	storage    storage.StorageProvider
+	interval   time.Duration // Time between runs
+	sampleSize int           // Artifacts checked per run
+	pause      time.Duration // Delay between artifacts, to keep the job low priority
+}
+
+// NewVerifier creates a verifier. Call Run to start it.
+func NewVerifier(db *gorm.DB, provider storage.StorageProvider, interval time.Duration, sampleSize int, pause time.Duration) *Verifier {
+	return &Verifier{db: db, storage: provider, interval: interval, sampleSize: sampleSize, pause: pause}
+}
+
+// sampledVersion is the subset of a module version needed to verify it.
+type sampledVersion struct {
+	ID                 uuid.UUID
+	Namespace          string
+	Name               string
+	Version            string
+	ArtifactDigest     string
+	ArtifactStorageKey string
+}
+
+// RunSummary reports the outcome of one verification run.
+type RunSummary struct {
+	Checked    int
+	Mismatches int
+	Errors     int
+}
+
+// Run verifies a sample every interval until ctx is cancelled.
+// The first run happens one interval after start, so restarts don't hammer storage.
+func (v *Verifier) Run(ctx context.Context) {
+	log := logging.L().With(zap.String("job", "integrity"))
+	log.Info("Artifact integrity verification enabled", zap.Duration("interval", v.interval), zap.Int("sample_size", v.sampleSize))
+
+	ticker := time.NewTicker(v.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			summary, err := v.VerifyOnce(logging.WithLogger(ctx, log))
+			if err != nil {
+				log.Error("Artifact integrity verification run failed", zap.Error(err))
+				continue
+			}
+			log.Info("Artifact integrity verification run completed",
+				zap.Int("checked", summary.Checked), zap.Int("mismatches", summary.Mismatches), zap.Int("errors", summary.Errors))
+		}
+	}
+}
+
+// VerifyOnce checks a random sample of artifacts and records the results in metrics.
+// Mismatches are logged at error level and sent as webhook events.
+func (v *Verifier) VerifyOnce(ctx context.Context) (RunSummary, error) {
+	log := logging.FromContext(ctx)
+	var summary RunSummary
+
+	var sample []sampledVersion
+	// RANDOM() is supported by both Postgres and SQLite
+	err := v.db.WithContext(ctx).Model(&models.ModuleVersion{}).
+		Select("module_versions.id, modules.namespace, modules.name, module_versions.version, module_versions.artifact_digest, module_versions.artifact_storage_key").
+		Joins("JOIN modules ON modules.id = module_versions.module_id").
+		Order("RANDOM()").
+		Limit(v.sampleSize).
+		Scan(&sample).Error
+	if err != nil {
+		return summary, fmt.Errorf("failed to sample module versions: %w", err)
+	}
+
+	for i, mv := range sample {
+		if i > 0 && v.pause > 0 {
+			select {
+			case <-ctx.Done():
+				return summary, ctx.Err()
+			case <-time.After(v.pause):
+			}
+		}
+
+		summary.Checked++
+		coordinates := fmt.Sprintf("%s/%s@%s", mv.Namespace, mv.Name, mv.Version)
+		actual, err := storage.ObjectDigest(ctx, v.storage, mv.ArtifactStorageKey)
+		switch {
+		case err != nil:
+			summary.Errors++
+			metrics.IntegrityChecksTotal.WithLabelValues(ResultError).Inc()
+			log.Warn("Failed to re-verify artifact", zap.String("module_version", coordinates), zap.String("key", mv.ArtifactStorageKey), zap.Error(err))
+		case actual != mv.ArtifactDigest:
+			summary.Mismatches++
+			metrics.IntegrityChecksTotal.WithLabelValues(ResultMismatch).Inc()
+			v.reportMismatch(ctx, mv, coordinates, actual)
+		default:
+			metrics.IntegrityChecksTotal.WithLabelValues(ResultOK).Inc()
+		}
+	}
+
+	metrics.IntegrityLastRunMismatches.Set(float64(summary.Mismatches))
+	metrics.IntegrityLastRunTimestamp.SetToCurrentTime()
+	return summary, nil
+}
+
+// reportMismatch logs and notifies about an artifact whose content changed after publish.
+func (v *Verifier) reportMismatch(ctx context.Context, mv sampledVersion, coordinates, actual string) {
+	message := fmt.Sprintf("Stored artifact for %s does not match its recorded digest", coordinates)
+	logging.FromContext(ctx).Error(message,
+		zap.String("event", EventDigestMismatch),
+		zap.Stringer("module_version_id", mv.ID),
+		zap.String("key", mv.ArtifactStorageKey),
+		zap.String("expected_digest", "sha256:"+mv.ArtifactDigest),
+		zap.String("actual_digest", "sha256:"+actual),
+	)
+	notify.Send(ctx, notify.Event{
+		Type:       EventDigestMismatch,
+		Namespace:  mv.Namespace,
+		ModuleName: mv.Name,
+		Message:    message,
+		Data: map[string]interface{}{
+			"version":         mv.Version,
+			"storage_key":     mv.ArtifactStorageKey,
+			"expected_digest": "sha256:" + mv.ArtifactDigest,
+			"actual_digest":   "sha256:" + actual,
+		},
+	})
+}
@@ -0,0 +1,72 @@
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupVerifier creates a SQLite database and local storage holding one intact and one tampered artifact.
+func setupVerifier(t *testing.T) *Verifier {
+	dir := t.TempDir()
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(dir, "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormDB.AutoMigrate(&models.Module{}, &models.ModuleVersion{}))
+
+	provider, err := storage.NewLocalStorage(config.Config{LocalStoragePath: filepath.Join(dir, "artifacts")})
+	require.NoError(t, err)
+
+	module := models.Module{Namespace: "my-org", Name: "my-module"}
+	require.NoError(t, gormDB.Create(&module).Error)
+
+	store := func(version string, stored, published []byte) {
+		key := "v2/modules/my-org/my-module/" + version + ".zip"
+		require.NoError(t, provider.UploadFile(context.Background(), key, bytes.NewReader(stored), int64(len(stored)), "application/zip"))
+		sum := sha256.Sum256(published)
+		require.NoError(t, gormDB.Create(&models.ModuleVersion{
+			ModuleID:           module.ID,
+			Version:            version,
+			ArtifactDigest:     hex.EncodeToString(sum[:]),
+			ArtifactStorageKey: key,
+		}).Error)
+	}
+	store("v1.0.0", []byte("intact"), []byte("intact"))
+	store("v1.1.0", []byte("tampered"), []byte("original"))
+
+	return NewVerifier(gormDB, provider, 0, 10, 0)
+}
+
+func TestVerifyOnce_DetectsMismatch(t *testing.T) {
+	v := setupVerifier(t)
+	mismatchesBefore := testutil.ToFloat64(metrics.IntegrityChecksTotal.WithLabelValues(ResultMismatch))
+
+	summary, err := v.VerifyOnce(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, RunSummary{Checked: 2, Mismatches: 1}, summary)
+	assert.Equal(t, mismatchesBefore+1, testutil.ToFloat64(metrics.IntegrityChecksTotal.WithLabelValues(ResultMismatch)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.IntegrityLastRunMismatches))
+}
+
+func TestVerifyOnce_MissingObjectIsError(t *testing.T) {
+	v := setupVerifier(t)
+	require.NoError(t, v.storage.DeleteFile(context.Background(), "v2/modules/my-org/my-module/v1.0.0.zip"))
+
+	summary, err := v.VerifyOnce(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, RunSummary{Checked: 2, Mismatches: 1, Errors: 1}, summary)
+}
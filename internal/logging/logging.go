@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// loggerContextKey is the context key under which a request-scoped logger is stored.
+type loggerContextKey struct{}
+
+// Init builds the server logger from config and installs it as the global zap logger.
+// LOG_LEVEL selects the minimum level (debug, info, warn, error) and
+// LOG_FORMAT selects the encoding ("json" for log aggregation, "console" for humans).
+func Init(cfg config.Config) (*zap.Logger, error) {
+	var zapLevel zapcore.Level
+	switch strings.ToLower(cfg.LogLevel) {
+	case "debug":
+		zapLevel = zapcore.DebugLevel
+	case "", "info":
+		zapLevel = zapcore.InfoLevel
+	case "warn", "warning":
+		zapLevel = zapcore.WarnLevel
+	case "error":
+		zapLevel = zapcore.ErrorLevel
+	default:
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %s. Must be 'debug', 'info', 'warn' or 'error'", cfg.LogLevel)
+	}
+
+	encoding := strings.ToLower(cfg.LogFormat)
+	if encoding == "" {
+		encoding = "json"
+	}
+	if encoding != "json" && encoding != "console" {
+		return nil, fmt.Errorf("invalid LOG_FORMAT: %s. Must be 'json' or 'console'", cfg.LogFormat)
+	}
+
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.MillisDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+	if encoding == "console" {
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder // INFO, WARN, ... like the CLI
+		encoderConfig.EncodeDuration = zapcore.StringDurationEncoder
+	}
+
+	zapConfig := zap.Config{
+		Level:            zap.NewAtomicLevelAt(zapLevel),
+		Development:      false,
+		Encoding:         encoding,
+		EncoderConfig:    encoderConfig,
+		OutputPaths:      []string{"stderr"},
+		ErrorOutputPaths: []string{"stderr"},
+	}
+
+	logger, err := zapConfig.Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build logger: %w", err)
+	}
+
+	zap.ReplaceGlobals(logger)
+	return logger, nil
+}
+
+// L returns the global server logger.
+// Before Init is called this is a no-op logger, which keeps tests quiet.
+func L() *zap.Logger {
+	return zap.L()
+}
+
+// WithLogger returns a copy of ctx carrying the given request-scoped logger.
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger stored in ctx (with fields such as the request ID),
+// falling back to the global logger if none is present.
+func FromContext(ctx context.Context) *zap.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerContextKey{}).(*zap.Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return L()
+}
+
+// Audit returns the audit logger for security-relevant events (e.g. failed authentication), derived from
+// the request-scoped logger in ctx. Its entries carry "logger":"audit" so they can be routed separately.
+func Audit(ctx context.Context) *zap.Logger {
+	return FromContext(ctx).Named("audit")
+}
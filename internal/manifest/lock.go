@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LockFormatVersion is the current sproto.lock format version.
+const LockFormatVersion = 1
+
+// Lock is the content of a sproto.lock file: the exact versions (and artifact digests)
+// the dependencies in sproto.yaml were resolved to.
+type Lock struct {
+	LockVersion  int                `yaml:"lock_version"`
+	Dependencies []LockedDependency `yaml:"dependencies"`
+}
+
+// LockedDependency pins a single module version.
+type LockedDependency struct {
+	Module  string `yaml:"module"`  // Full module name (namespace/name)
+	Version string `yaml:"version"` // Resolved version
+	Digest  string `yaml:"digest"`  // Artifact digest (sha256:<hex>)
+}
+
+// LoadLock reads a sproto.lock file.
+func LoadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var l Lock
+	if err := yaml.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if l.LockVersion > LockFormatVersion {
+		return nil, fmt.Errorf("%s has lock_version %d, this client supports up to %d; upgrade protoreg-cli", path, l.LockVersion, LockFormatVersion)
+	}
+	return &l, nil
+}
+
+// Find returns the locked entry for a module, or nil if it isn't locked.
+func (l *Lock) Find(module string) *LockedDependency {
+	for i := range l.Dependencies {
+		if l.Dependencies[i].Module == module {
+			return &l.Dependencies[i]
+		}
+	}
+	return nil
+}
+
+// lockHeader is written at the top of every generated lockfile.
+const lockHeader = "# Generated by protoreg-cli. Do not edit by hand.\n"
+
+// Save writes the lock to path atomically: the content is written to a temporary file in the
+// same directory and renamed over the target, so readers never see a partially written lockfile.
+func (l *Lock) Save(path string) error {
+	if l.LockVersion == 0 {
+		l.LockVersion = LockFormatVersion
+	}
+	var buf bytes.Buffer
+	buf.WriteString(lockHeader)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(l); err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary lockfile: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // No-op after a successful rename
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary lockfile: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary lockfile: %w", err)
+	}
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		return fmt.Errorf("failed to set lockfile permissions: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
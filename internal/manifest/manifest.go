@@ -0,0 +1,100 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// File names used in a module directory.
+const (
+	ManifestFileName = "sproto.yaml" // Module metadata and dependency constraints (hand-written)
+	LockFileName     = "sproto.lock" // Resolved dependency versions and digests (generated)
+)
+
+// Manifest is the content of a sproto.yaml file.
+//
+//	name: mycompany/orders
+//	version: v1.2.0
+//	description: Order placement and tracking APIs
+//	dependencies:
+//	  mycompany/user: ^1.2.0
+//	  mycompany/common: ">= 0.3.0, < 1.0.0"
+type Manifest struct {
+	Name         string            `yaml:"name,omitempty"`         // Full module name (namespace/name) of the module in this directory
+	Version      string            `yaml:"version,omitempty"`      // Version of the module in this directory
+	Description  string            `yaml:"description,omitempty"`  // What the module is for (indexed for search)
+	Dependencies map[string]string `yaml:"dependencies,omitempty"` // Full module name -> semver constraint ("" or "*" for any version)
+}
+
+// LoadManifest reads and validates a sproto.yaml file.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseManifest parses and validates sproto.yaml content.
+func ParseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if m.Name != "" {
+		if _, _, err := SplitModule(m.Name); err != nil {
+			return nil, err
+		}
+	}
+	for module := range m.Dependencies {
+		if _, _, err := SplitModule(module); err != nil {
+			return nil, fmt.Errorf("dependency %w", err)
+		}
+		if _, err := m.Constraint(module); err != nil {
+			return nil, err
+		}
+	}
+	return &m, nil
+}
+
+// Constraint returns the parsed version constraint for a dependency.
+// An empty constraint or "*" matches any (non-prerelease) version.
+func (m *Manifest) Constraint(module string) (*semver.Constraints, error) {
+	raw := strings.TrimSpace(m.Dependencies[module])
+	if raw == "" {
+		raw = "*"
+	}
+	c, err := semver.NewConstraint(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %q for %s: %w", raw, module, err)
+	}
+	return c, nil
+}
+
+// DependencyNames returns the manifest's dependency module names, sorted.
+func (m *Manifest) DependencyNames() []string {
+	names := make([]string, 0, len(m.Dependencies))
+	for name := range m.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SplitModule splits a full module name ("namespace/name") into its parts.
+func SplitModule(fullName string) (string, string, error) {
+	parts := strings.SplitN(fullName, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid module name %q: expected 'namespace/module_name'", fullName)
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,54 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseManifest(t *testing.T) {
+	m, err := ParseManifest([]byte(`
+name: mycompany/orders
+version: v1.2.0
+dependencies:
+  mycompany/user: ^1.2.0
+  mycompany/common: ">= 0.3.0, < 1.0.0"
+  mycompany/any: ""
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "mycompany/orders", m.Name)
+	assert.Equal(t, []string{"mycompany/any", "mycompany/common", "mycompany/user"}, m.DependencyNames())
+
+	_, err = ParseManifest([]byte("dependencies:\n  not-a-module: ^1.0.0\n"))
+	assert.Error(t, err)
+	_, err = ParseManifest([]byte("dependencies:\n  a/b: not-a-constraint\n"))
+	assert.Error(t, err)
+}
+
+func TestVersionSelection(t *testing.T) {
+	versions := []string{"v1.0.0", "v1.2.0", "v1.3.0-rc.1", "v2.0.0", "v2.1.0-beta", "garbage"}
+	m := &Manifest{Dependencies: map[string]string{"a/b": "^1.0.0", "a/c": "", "a/d": "^3.0.0"}}
+
+	c, err := m.Constraint("a/b")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.2.0", NewestMatching(versions, c), "prereleases are excluded by default")
+
+	c, err = m.Constraint("a/c")
+	require.NoError(t, err)
+	assert.Equal(t, "v2.0.0", NewestMatching(versions, c))
+
+	c, err = m.Constraint("a/d")
+	require.NoError(t, err)
+	assert.Equal(t, "", NewestMatching(versions, c))
+
+	assert.Equal(t, "v2.0.0", Newest(versions))
+	assert.Equal(t, "v0.1.0-alpha", Newest([]string{"v0.1.0-alpha"}))
+	assert.True(t, IsOlder("v1.2.0", "v1.10.0"))
+	assert.False(t, IsOlder("v1.2.0", "garbage"))
+
+	assert.Equal(t, "v1.3.0-rc.1", Previous(versions, "v1.3.0"))
+	assert.Equal(t, "v2.0.0", Previous(versions, "v2.0.1"))
+	assert.Equal(t, "", Previous(versions, "v1.0.0"))
+	assert.Equal(t, "", Previous(versions, "garbage"))
+}
@@ -0,0 +1,299 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// maxResolveIterations bounds the resolution loop in case dependency manifests keep changing the selection
+// (or backtracking keeps ruling out versions).
+const maxResolveIterations = 200
+
+// ErrNotConverged is returned by Resolve when the selection keeps changing.
+var ErrNotConverged = errors.New("dependency resolution did not converge")
+
+// Source provides the registry data needed to resolve dependencies.
+type Source interface {
+	// Versions returns all published versions of a module.
+	Versions(module string) ([]string, error)
+	// Manifest returns the sproto.yaml packaged in a module version's artifact (nil if it has none)
+	// and the artifact digest (sha256:<hex>).
+	Manifest(module, version string) (*Manifest, string, error)
+}
+
+// ResolveOptions controls which locked versions may change.
+type ResolveOptions struct {
+	// Update lists the modules allowed to move to newer versions. If nil, every module may move.
+	// Other modules keep their locked version as long as it still satisfies all constraints.
+	Update map[string]bool
+	// Latest selects the newest published version for updated modules, ignoring constraints.
+	Latest bool
+}
+
+// requirement is a constraint on a module together with the module that imposed it.
+type requirement struct {
+	from       string // "sproto.yaml" for the root manifest, otherwise module@version
+	raw        string
+	constraint *semver.Constraints
+}
+
+// Requirement is a constraint on a module as reported in a ConflictError.
+type Requirement struct {
+	From       string `json:"from"`       // "sproto.yaml" for the root manifest, otherwise module@version
+	Constraint string `json:"constraint"` // As declared; "" for any version
+}
+
+// ConflictError is returned by Resolve when no published version of a module satisfies all the constraints
+// on it, even after backtracking.
+type ConflictError struct {
+	Module       string        `json:"module"`
+	Requirements []Requirement `json:"requirements"`
+	// Backtracked lists the dependency versions (module@version) ruled out while looking for a solution.
+	Backtracked []string `json:"backtracked,omitempty"`
+}
+
+func (e *ConflictError) Error() string {
+	parts := make([]string, 0, len(e.Requirements))
+	for _, r := range e.Requirements {
+		raw := r.Constraint
+		if raw == "" {
+			raw = "*"
+		}
+		parts = append(parts, fmt.Sprintf("%s (%s)", raw, r.From))
+	}
+	msg := fmt.Sprintf("no published version of %s satisfies %s", e.Module, strings.Join(parts, ", "))
+	if len(e.Backtracked) > 0 {
+		msg += fmt.Sprintf("; also tried without %s", strings.Join(e.Backtracked, ", "))
+	}
+	return msg
+}
+
+// Resolve computes the full (transitive) set of dependency versions for the root manifest.
+// Each module gets the newest version satisfying the constraints of every module that depends on it,
+// unless it is locked and not being updated. Dependencies of dependencies are read from the
+// sproto.yaml packaged in their artifacts.
+//
+// If no version of a module satisfies all constraints, a version of a dependency that imposed one of
+// them is ruled out and resolution starts over (backtracking), so an older dependency with looser
+// constraints can still lead to a solution. If only the root manifest's constraints are left to blame,
+// the first conflict found is returned as a *ConflictError.
+func Resolve(root *Manifest, lock *Lock, src Source, opts ResolveOptions) (*Lock, error) {
+	if lock == nil {
+		lock = &Lock{}
+	}
+	versionsCache := make(map[string][]string)
+	type manifestEntry struct {
+		manifest *Manifest
+		digest   string
+	}
+	manifestCache := make(map[string]manifestEntry)
+
+	getVersions := func(module string) ([]string, error) {
+		if v, ok := versionsCache[module]; ok {
+			return v, nil
+		}
+		v, err := src.Versions(module)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions of %s: %w", module, err)
+		}
+		versionsCache[module] = v
+		return v, nil
+	}
+	getManifest := func(module, version string) (manifestEntry, error) {
+		key := module + "@" + version
+		if e, ok := manifestCache[key]; ok {
+			return e, nil
+		}
+		m, digest, err := src.Manifest(module, version)
+		if err != nil {
+			return manifestEntry{}, fmt.Errorf("failed to read manifest of %s: %w", key, err)
+		}
+		e := manifestEntry{manifest: m, digest: digest}
+		manifestCache[key] = e
+		return e, nil
+	}
+
+	selected := make(map[string]string)
+	excluded := make(map[string]bool) // module@version ruled out by backtracking
+	var backtracked []string
+	var conflict *ConflictError // First conflict found, reported if backtracking doesn't help
+	for iteration := 0; ; iteration++ {
+		if iteration >= maxResolveIterations {
+			if conflict != nil {
+				conflict.Backtracked = backtracked
+				return nil, conflict
+			}
+			return nil, fmt.Errorf("%w after %d iterations", ErrNotConverged, maxResolveIterations)
+		}
+
+		// --- Collect requirements reachable from the root with the current selection ---
+		requirements := make(map[string][]requirement)
+		queue := []string{}
+		addDeps := func(m *Manifest, from string) error {
+			for _, dep := range m.DependencyNames() {
+				c, err := m.Constraint(dep)
+				if err != nil {
+					return fmt.Errorf("%s: %w", from, err)
+				}
+				if _, seen := requirements[dep]; !seen {
+					queue = append(queue, dep)
+				}
+				requirements[dep] = append(requirements[dep], requirement{from: from, raw: m.Dependencies[dep], constraint: c})
+			}
+			return nil
+		}
+		if err := addDeps(root, ManifestFileName); err != nil {
+			return nil, err
+		}
+		for len(queue) > 0 {
+			module := queue[0]
+			queue = queue[1:]
+			version, ok := selected[module]
+			if !ok {
+				continue // Not selected yet; its dependencies are added once it is
+			}
+			entry, err := getManifest(module, version)
+			if err != nil {
+				return nil, err
+			}
+			if entry.manifest != nil {
+				if err := addDeps(entry.manifest, module+"@"+version); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// --- Select a version for every required module ---
+		// Modules are visited in name order, so conflicts (and backtracking) are deterministic
+		modules := make([]string, 0, len(requirements))
+		for module := range requirements {
+			modules = append(modules, module)
+		}
+		sort.Strings(modules)
+		next := make(map[string]string, len(requirements))
+		var unsatisfied string
+		for _, module := range modules {
+			reqs := requirements[module]
+			published, err := getVersions(module)
+			if err != nil {
+				return nil, err
+			}
+			versions := make([]string, 0, len(published))
+			for _, v := range published {
+				if !excluded[module+"@"+v] {
+					versions = append(versions, v)
+				}
+			}
+			updatable := opts.Update == nil || opts.Update[module]
+
+			if locked := lock.Find(module); locked != nil && !updatable && !excluded[module+"@"+locked.Version] && satisfiesAll(locked.Version, reqs) {
+				next[module] = locked.Version
+				continue
+			}
+			if updatable && opts.Latest {
+				if v := Newest(versions); v != "" {
+					next[module] = v
+					continue
+				}
+			}
+			v := newestSatisfyingAll(versions, reqs)
+			if v == "" {
+				unsatisfied = module
+				break
+			}
+			next[module] = v
+		}
+
+		// --- Backtrack ---
+		// Rule out the version of the first dependency that constrained the unsatisfied module and try again
+		if unsatisfied != "" {
+			reqs := requirements[unsatisfied]
+			if conflict == nil {
+				conflict = newConflictError(unsatisfied, reqs)
+			}
+			culprit := ""
+			for _, r := range reqs {
+				if r.from != ManifestFileName && !excluded[r.from] {
+					culprit = r.from
+					break
+				}
+			}
+			if culprit == "" {
+				conflict.Backtracked = backtracked
+				return nil, conflict
+			}
+			excluded[culprit] = true
+			backtracked = append(backtracked, culprit)
+			culpritModule, _, _ := strings.Cut(culprit, "@")
+			delete(selected, culpritModule) // Reselected (without its dependencies) in the next iteration
+			continue
+		}
+
+		if sameSelection(selected, next) {
+			break
+		}
+		selected = next
+	}
+
+	// --- Build the lock ---
+	result := &Lock{LockVersion: LockFormatVersion}
+	for module, version := range selected {
+		entry, err := getManifest(module, version)
+		if err != nil {
+			return nil, err
+		}
+		result.Dependencies = append(result.Dependencies, LockedDependency{Module: module, Version: version, Digest: entry.digest})
+	}
+	sort.Slice(result.Dependencies, func(i, j int) bool { return result.Dependencies[i].Module < result.Dependencies[j].Module })
+	return result, nil
+}
+
+// satisfiesAll reports whether version satisfies every requirement.
+func satisfiesAll(version string, reqs []requirement) bool {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	for _, r := range reqs {
+		if !r.constraint.Check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// newestSatisfyingAll returns the newest version satisfying every requirement, or "".
+func newestSatisfyingAll(versions []string, reqs []requirement) string {
+	for _, v := range parseVersions(versions) {
+		if satisfiesAll(v.Original(), reqs) {
+			return "v" + v.String()
+		}
+	}
+	return ""
+}
+
+// newConflictError describes the requirements no version of module satisfies.
+func newConflictError(module string, reqs []requirement) *ConflictError {
+	e := &ConflictError{Module: module, Requirements: make([]Requirement, 0, len(reqs))}
+	for _, r := range reqs {
+		e.Requirements = append(e.Requirements, Requirement{From: r.from, Constraint: r.raw})
+	}
+	return e
+}
+
+// sameSelection reports whether two module -> version selections are identical.
+func sameSelection(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource serves versions and packaged manifests from memory.
+type fakeSource struct {
+	versions  map[string][]string
+	manifests map[string]*Manifest // module@version -> manifest
+}
+
+func (f *fakeSource) Versions(module string) ([]string, error) {
+	return f.versions[module], nil
+}
+
+func (f *fakeSource) Manifest(module, version string) (*Manifest, string, error) {
+	return f.manifests[module+"@"+version], "sha256:" + module + "@" + version, nil
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		versions: map[string][]string{
+			"a/app":    {"v1.0.0", "v1.1.0", "v2.0.0"},
+			"a/common": {"v0.1.0", "v0.2.0", "v0.3.0", "v1.0.0"},
+		},
+		manifests: map[string]*Manifest{
+			// v1.1.0 of a/app needs a newer a/common than v1.0.0 does
+			"a/app@v1.0.0": {Dependencies: map[string]string{"a/common": "^0.1.0"}},
+			"a/app@v1.1.0": {Dependencies: map[string]string{"a/common": ">= 0.2.0, < 1.0.0"}},
+			"a/app@v2.0.0": {Dependencies: map[string]string{"a/common": "^1.0.0"}},
+		},
+	}
+}
+
+func lockedVersions(l *Lock) map[string]string {
+	out := map[string]string{}
+	for _, d := range l.Dependencies {
+		out[d.Module] = d.Version
+	}
+	return out
+}
+
+func TestResolve_Transitive(t *testing.T) {
+	root := &Manifest{Dependencies: map[string]string{"a/app": "^1.0.0"}}
+
+	lock, err := Resolve(root, nil, newFakeSource(), ResolveOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a/app": "v1.1.0", "a/common": "v0.3.0"}, lockedVersions(lock))
+	assert.Equal(t, "sha256:a/app@v1.1.0", lock.Find("a/app").Digest)
+}
+
+func TestResolve_SingleModuleAndLatest(t *testing.T) {
+	root := &Manifest{Dependencies: map[string]string{"a/app": "^1.0.0", "a/common": "*"}}
+	existing := &Lock{Dependencies: []LockedDependency{
+		{Module: "a/app", Version: "v1.0.0"},
+		{Module: "a/common", Version: "v0.1.0"},
+	}}
+
+	// Updating only a/common keeps a/app locked; ^0.1.0 (from a/app@v1.0.0) caps a/common
+	lock, err := Resolve(root, existing, newFakeSource(), ResolveOptions{Update: map[string]bool{"a/common": true}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a/app": "v1.0.0", "a/common": "v0.1.0"}, lockedVersions(lock))
+
+	// Updating a/app moves it within ^1.0.0, and the locked a/common is forced forward by the new constraint
+	lock, err = Resolve(root, existing, newFakeSource(), ResolveOptions{Update: map[string]bool{"a/app": true}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a/app": "v1.1.0", "a/common": "v0.3.0"}, lockedVersions(lock))
+
+	// --latest ignores the ^1.0.0 constraint for a/app
+	lock, err = Resolve(root, existing, newFakeSource(), ResolveOptions{Update: map[string]bool{"a/app": true}, Latest: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a/app": "v2.0.0", "a/common": "v1.0.0"}, lockedVersions(lock))
+}
+
+func TestResolve_Conflict(t *testing.T) {
+	root := &Manifest{Dependencies: map[string]string{"a/app": "^2.0.0", "a/common": "^0.3.0"}}
+	_, err := Resolve(root, nil, newFakeSource(), ResolveOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no published version of a/common satisfies")
+
+	// The conflict is structured; ruling out a/app@v2.0.0 leaves no version of a/app for ^2.0.0
+	var conflict *ConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "a/common", conflict.Module)
+	assert.Equal(t, []Requirement{{From: "sproto.yaml", Constraint: "^0.3.0"}, {From: "a/app@v2.0.0", Constraint: "^1.0.0"}}, conflict.Requirements)
+	assert.Equal(t, []string{"a/app@v2.0.0"}, conflict.Backtracked)
+}
+
+func TestResolve_Backtracking(t *testing.T) {
+	// The newest a/app needs a/common ^1.0.0, which the root rules out; a/app@v1.1.0 works with a/common v0.3.0
+	root := &Manifest{Dependencies: map[string]string{"a/app": "*", "a/common": "^0.3.0"}}
+	lock, err := Resolve(root, nil, newFakeSource(), ResolveOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a/app": "v1.1.0", "a/common": "v0.3.0"}, lockedVersions(lock))
+
+	// A locked version that causes a conflict is moved too
+	existing := &Lock{Dependencies: []LockedDependency{{Module: "a/app", Version: "v2.0.0"}, {Module: "a/common", Version: "v0.3.0"}}}
+	lock, err = Resolve(root, existing, newFakeSource(), ResolveOptions{Update: map[string]bool{"a/common": true}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a/app": "v1.1.0", "a/common": "v0.3.0"}, lockedVersions(lock))
+}
+
+func TestLockSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFileName)
+	lock := &Lock{Dependencies: []LockedDependency{{Module: "a/app", Version: "v1.0.0", Digest: "sha256:abc"}}}
+	require.NoError(t, lock.Save(path))
+
+	loaded, err := LoadLock(path)
+	require.NoError(t, err)
+	assert.Equal(t, LockFormatVersion, loaded.LockVersion)
+	assert.Equal(t, lock.Dependencies, loaded.Dependencies)
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,68 @@
+package manifest
+
+import (
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// parseVersions parses version strings, skipping any that aren't valid semver, sorted newest first.
+func parseVersions(versions []string) []*semver.Version {
+	parsed := make([]*semver.Version, 0, len(versions))
+	for _, v := range versions {
+		if sv, err := semver.NewVersion(v); err == nil {
+			parsed = append(parsed, sv)
+		}
+	}
+	sort.Sort(sort.Reverse(semver.Collection(parsed)))
+	return parsed
+}
+
+// NewestMatching returns the newest version satisfying the constraint, or "" if none does.
+func NewestMatching(versions []string, constraint *semver.Constraints) string {
+	for _, v := range parseVersions(versions) {
+		if constraint.Check(v) {
+			return "v" + v.String()
+		}
+	}
+	return ""
+}
+
+// Newest returns the newest stable version, falling back to the newest prerelease
+// if there are no stable versions. Returns "" if versions is empty.
+func Newest(versions []string) string {
+	parsed := parseVersions(versions)
+	for _, v := range parsed {
+		if v.Prerelease() == "" {
+			return "v" + v.String()
+		}
+	}
+	if len(parsed) > 0 {
+		return "v" + parsed[0].String()
+	}
+	return ""
+}
+
+// IsOlder reports whether version a is older than version b. Unparseable versions are never older.
+func IsOlder(a, b string) bool {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return va.LessThan(vb)
+}
+
+// Previous returns the newest version older than version (prereleases included), or "" if there is none.
+func Previous(versions []string, version string) string {
+	current, err := semver.NewVersion(version)
+	if err != nil {
+		return ""
+	}
+	for _, v := range parseVersions(versions) {
+		if v.LessThan(current) {
+			return "v" + v.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds all SProto metrics. A dedicated registry (instead of the prometheus default)
+// keeps the exported set explicit and lets tests inspect metrics in isolation.
+var Registry = prometheus.NewRegistry()
+
+// --- Integrity Verification ---
+
+var (
+	// IntegrityChecksTotal counts artifact re-verifications by result: ok, mismatch or error.
+	IntegrityChecksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sproto_integrity_checks_total",
+		Help: "Number of stored artifacts re-verified against their recorded digest, by result (ok, mismatch, error).",
+	}, []string{"result"})
+
+	// IntegrityLastRunTimestamp is the Unix time the last verification run completed.
+	IntegrityLastRunTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sproto_integrity_last_run_timestamp_seconds",
+		Help: "Unix time the last artifact integrity verification run completed.",
+	})
+
+	// IntegrityLastRunMismatches is the number of mismatches found by the last run (alert on > 0).
+	IntegrityLastRunMismatches = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sproto_integrity_last_run_mismatches",
+		Help: "Number of artifacts whose digest did not match in the last integrity verification run.",
+	})
+)
+
+// --- Request Limits ---
+
+var (
+	// LimitInFlight is the number of requests holding a slot of a concurrency limit (publish, artifact_stream).
+	LimitInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sproto_limit_in_flight",
+		Help: "Requests currently holding a slot of a concurrency limit, by limit (publish, artifact_stream).",
+	}, []string{"limit"})
+
+	// LimitQueued is the number of requests waiting for a slot.
+	LimitQueued = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sproto_limit_queued",
+		Help: "Requests currently waiting for a slot of a concurrency limit, by limit.",
+	}, []string{"limit"})
+
+	// LimitRejectionsTotal counts requests rejected with 429 because no slot became free in time.
+	LimitRejectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sproto_limit_rejections_total",
+		Help: "Requests rejected (429) because a concurrency limit was saturated, by limit.",
+	}, []string{"limit"})
+)
+
+// --- Authentication ---
+
+var (
+	// AuthFailuresTotal counts rejected authentication attempts by reason
+	// (missing_header, invalid_format, invalid_token, expired_token).
+	AuthFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sproto_auth_failures_total",
+		Help: "Failed authentication attempts, by reason (missing_header, invalid_format, invalid_token, expired_token).",
+	}, []string{"reason"})
+
+	// AuthBansTotal counts clients temporarily banned after too many failed attempts.
+	AuthBansTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sproto_auth_bans_total",
+		Help: "Clients temporarily banned after too many failed authentication attempts.",
+	})
+
+	// AuthBannedRequestsTotal counts authentication attempts rejected (429) because the client is banned.
+	AuthBannedRequestsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "sproto_auth_banned_requests_total",
+		Help: "Authentication attempts rejected (429) because the client was temporarily banned.",
+	})
+
+	// IPFilterRejectionsTotal counts requests rejected (403) by client IP, by list (denylist, write_allowlist).
+	IPFilterRejectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sproto_ip_filter_rejections_total",
+		Help: "Requests rejected (403) because of the client IP, by list (denylist: DENIED_CIDRS, write_allowlist: WRITE_ALLOWED_CIDRS).",
+	}, []string{"list"})
+)
+
+// --- Backend Latency ---
+
+// latencyBuckets span 1ms to ~30s: index lookups up to multi-hundred-MB artifact uploads.
+var latencyBuckets = prometheus.ExponentialBuckets(0.001, 2, 16)
+
+var (
+	// StorageOperationDuration observes storage provider calls by provider (minio, local), operation
+	// (upload, download, delete, exists, transition, stats) and outcome (ok, not_found, exists, error). Downloads are timed
+	// until the object stream is open, not until the caller has read it.
+	StorageOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sproto_storage_operation_duration_seconds",
+		Help:    "Duration of artifact storage operations, by provider, operation (upload, download, delete, exists) and outcome (ok, not_found, exists, error).",
+		Buckets: latencyBuckets,
+	}, []string{"provider", "operation", "outcome"})
+
+	// DBQueryDuration observes GORM statements by provider (postgres, sqlite), query family (create, query,
+	// update, delete, row, raw), table and outcome (ok, not_found, error).
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sproto_db_query_duration_seconds",
+		Help:    "Duration of database statements, by provider, query family (create, query, update, delete, row, raw), table and outcome (ok, not_found, error).",
+		Buckets: latencyBuckets,
+	}, []string{"provider", "family", "table", "outcome"})
+
+	// ArtifactFetchDuration observes downloads of module versions by kind (artifact, bundle,
+	// secondary_artifact) and outcome (ok, client_error, server_error), until the response is written.
+	// Observations carry the request ID as an exemplar, linking a slow bucket to the request's log lines.
+	ArtifactFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sproto_artifact_fetch_duration_seconds",
+		Help:    "Duration of module version downloads, by kind (artifact, bundle, secondary_artifact) and outcome (ok, client_error, server_error).",
+		Buckets: latencyBuckets,
+	}, []string{"kind", "outcome"})
+)
+
+// --- Capacity ---
+
+var (
+	// StorageObjects is the number of objects in the storage bucket (or local storage directories).
+	StorageObjects = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sproto_storage_objects",
+		Help: "Objects in the storage bucket (or local storage directories, both tiers), as of the last capacity collection.",
+	})
+
+	// StorageBytes is the total size of the stored objects.
+	StorageBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sproto_storage_bytes",
+		Help: "Total size of the objects in the storage bucket (or local storage directories), as of the last capacity collection.",
+	})
+
+	// DBSizeBytes is the on-disk size of the metadata database.
+	DBSizeBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sproto_db_size_bytes",
+		Help: "On-disk size of the metadata database, as of the last capacity collection.",
+	})
+
+	// DBTableRows is the row count of each table of the metadata database.
+	DBTableRows = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sproto_db_table_rows",
+		Help: "Rows of each metadata database table, as of the last capacity collection.",
+	}, []string{"table"})
+
+	// CapacityLastCollectTimestamp is the Unix time capacity was last collected, by source (storage, database).
+	CapacityLastCollectTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sproto_capacity_last_collect_timestamp_seconds",
+		Help: "Unix time storage and database capacity were last collected successfully, by source (storage, database).",
+	}, []string{"source"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		IntegrityChecksTotal,
+		IntegrityLastRunTimestamp,
+		IntegrityLastRunMismatches,
+		LimitInFlight,
+		LimitQueued,
+		LimitRejectionsTotal,
+		AuthFailuresTotal,
+		AuthBansTotal,
+		AuthBannedRequestsTotal,
+		IPFilterRejectionsTotal,
+		StorageOperationDuration,
+		DBQueryDuration,
+		ArtifactFetchDuration,
+		StorageObjects,
+		StorageBytes,
+		DBSizeBytes,
+		DBTableRows,
+		CapacityLastCollectTimestamp,
+	)
+}
+
+// Handler returns the HTTP handler serving the metrics in Prometheus text format, or in OpenMetrics
+// format (which includes exemplars) to scrapers asking for it.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
@@ -4,28 +4,435 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	// Re-add for clarity and potential future use with hooks/methods
+	"gorm.io/gorm"
 )
 
 // Module represents a logical grouping of related .proto files.
 type Module struct {
-	ID        uuid.UUID       `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	ID        uuid.UUID       `gorm:"type:uuid;primary_key"` // Generated in BeforeCreate (DB default uuid_generate_v4() is Postgres-only)
 	Namespace string          `gorm:"type:varchar(255);not null;uniqueIndex:idx_module_namespace_name"`
 	Name      string          `gorm:"type:varchar(255);not null;uniqueIndex:idx_module_namespace_name"`
 	CreatedAt time.Time       `gorm:"not null;default:current_timestamp"`
 	UpdatedAt time.Time       `gorm:"not null;default:current_timestamp"`
 	Versions  []ModuleVersion `gorm:"foreignKey:ModuleID"` // Has many relationship
+
+	// Who may read the module: VisibilityPublic, VisibilityInternal or VisibilityPrivate
+	Visibility string `gorm:"type:varchar(16);not null;default:'public'"`
 }
 
+// Module visibility levels, enforced on read endpoints.
+const (
+	VisibilityPublic   = "public"   // Anyone, no token required
+	VisibilityInternal = "internal" // Any valid token
+	VisibilityPrivate  = "private"  // Only the admin token and read tokens granted the module
+)
+
 // ModuleVersion represents a specific version of a module.
 type ModuleVersion struct {
-	ID                 uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	ModuleID           uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_module_version"`         // Foreign key
-	Version            string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_module_version"` // SemVer string
-	ArtifactDigest     string    `gorm:"type:varchar(64);not null"`                                 // SHA256 hex string
-	ArtifactStorageKey string    `gorm:"type:text;not null"`                                        // Key in MinIO
-	CreatedAt          time.Time `gorm:"not null;default:current_timestamp"`
+	ID                 uuid.UUID  `gorm:"type:uuid;primary_key"`
+	ModuleID           uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_module_version"`         // Foreign key
+	Version            string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_module_version"` // SemVer string
+	VersionKey         string     `gorm:"type:varchar(100);not null;default:''"`                     // validation.VersionKey(Version), unique per module (see db.MigrateVersionKeys)
+	ArtifactDigest     string     `gorm:"type:varchar(64);not null"`                                 // SHA256 hex string
+	ArtifactStorageKey string     `gorm:"type:text;not null"`                                        // Key in the storage backend
+	ArtifactKeyLayout  int        `gorm:"not null;default:1"`                                        // Storage key layout version (see storage.KeyLayoutV1/V2)
+	ArtifactSize       int64      `gorm:"not null;default:0"`                                        // Artifact size in bytes (0 if unknown)
+	ArtifactTieredAt   *time.Time // When the tiering job moved the artifact to cold storage; nil while hot
+	ScanStatus         string     `gorm:"type:varchar(20);not null;default:'skipped'"` // Virus scan outcome: skipped, clean, infected, error
+	ScanResult         string     `gorm:"type:text"`                                   // Detected signature or scanner error, if any
+	Changelog          string     `gorm:"type:text"`                                   // Section of the artifact's CHANGELOG.md for this version, if any
+	Syntaxes           string     `gorm:"type:varchar(255)"`                           // Syntax labels of the .proto files (proto2, proto3, edition-2023), sorted, comma-separated; empty if unknown
+	CreatedAt          time.Time  `gorm:"not null;default:current_timestamp"`
 	// Module             Module    `gorm:"foreignKey:ModuleID"` // Belongs to relationship (optional, can use ModuleID directly)
+
+	// Deprecation (PUT/DELETE .../{version}/deprecation)
+	DeprecatedAt       *time.Time // When the version was deprecated; nil if it isn't
+	DeprecationMessage string     `gorm:"type:text"` // Why it was deprecated / what to use instead
+	SunsetAt           *time.Time // When fetches of the deprecated version stop being served; nil if never
+	SunsetEnforcedAt   *time.Time // When the sunset job enforced SunsetAt; nil until then
+
+	// The previous version this one has the same compiled schema as (only comments or formatting changed),
+	// detected at publish if DETECT_SCHEMA_CHANGES is enabled; empty if the schema changed or wasn't compared
+	NoSchemaChangeFrom string `gorm:"type:varchar(100)"`
+
+	// Canary rollout (PUT/DELETE .../{version}/canary): the percentage of consumers (0-100) the latest
+	// resolution hands this version to instead of the newest stable one; nil if the version isn't a canary
+	CanaryPercent *int
+
+	// Registry signature of the version's coordinates and digest (see package provenance), made at publish
+	// with the version signing key (VERSION_SIGNING_KEY); empty if no key was configured then
+	Signature    string `gorm:"type:varchar(128)"` // Base64 Ed25519 signature of provenance.Payload
+	SigningKeyID string `gorm:"type:varchar(32)"`  // provenance.KeyID of the key that made it
+
+	// Registries the version was promoted from (protoreg-cli promote), as a JSON array of provenance.Source,
+	// the original first; empty if it was published here
+	Provenance string `gorm:"type:text"`
+}
+
+// VersionNote is a free-form note attached to a module version after it was published
+// (e.g. "contains hotfix for billing rounding"). Notes are append-only.
+type VersionNote struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key"`
+	ModuleVersionID uuid.UUID `gorm:"type:uuid;not null;index:idx_version_notes_module_version_id"` // Foreign key
+	Body            string    `gorm:"type:text;not null"`
+	Author          string    `gorm:"type:varchar(255)"` // Self-reported by the client (X-SProto-Publisher), may be empty
+	CreatedAt       time.Time `gorm:"not null;default:current_timestamp"`
+}
+
+// VersionArtifact is a secondary artifact attached to a module version under a classifier (e.g.
+// "descriptors", "openapi", "docs"): an output derived from the version's protos, stored next to them.
+// Like the primary artifact, it can't be replaced once attached.
+type VersionArtifact struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key"`
+	ModuleVersionID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_version_artifact_classifier"` // Foreign key
+	Classifier      string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_version_artifact_classifier"`
+	ContentType     string    `gorm:"type:varchar(255);not null"`
+	Digest          string    `gorm:"type:varchar(64);not null"` // SHA256 hex string
+	Size            int64     `gorm:"not null"`
+	StorageKey      string    `gorm:"type:text;not null"`                          // Key in the storage backend
+	ScanStatus      string    `gorm:"type:varchar(20);not null;default:'skipped'"` // Virus scan outcome, as for the primary artifact
+	ScanResult      string    `gorm:"type:text"`
+	CreatedAt       time.Time `gorm:"not null;default:current_timestamp"`
+}
+
+// VersionFile is a file of a module version's artifact with its SHA256, recorded at publish, so clients
+// holding an extracted copy of another version only need to download the files that changed.
+type VersionFile struct {
+	ModuleVersionID uuid.UUID `gorm:"type:uuid;primaryKey"` // Foreign key
+	Path            string    `gorm:"type:varchar(1024);primaryKey"`
+	Size            int64     `gorm:"not null"`                  // Uncompressed size in bytes
+	Digest          string    `gorm:"type:varchar(64);not null"` // SHA256 hex string
+}
+
+// DevChannel is a mutable snapshot of a module's work in progress, published under a dev-<name> channel
+// (e.g. dev-alice) by `protoreg-cli dev` so services in a dev cluster can pull it like a version. Unlike a
+// version, each publish replaces its artifact; it isn't listed, resolved, searched or logged as a version.
+type DevChannel struct {
+	ID                 uuid.UUID `gorm:"type:uuid;primary_key"`
+	ModuleID           uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_dev_channel"` // Foreign key
+	Channel            string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_dev_channel"`
+	ArtifactDigest     string    `gorm:"type:varchar(64);not null"`                   // SHA256 hex string
+	ArtifactStorageKey string    `gorm:"type:text;not null"`                          // Key in the storage backend (see storage.DevChannelKey)
+	ArtifactSize       int64     `gorm:"not null"`                                    // Artifact size in bytes
+	ScanStatus         string    `gorm:"type:varchar(20);not null;default:'skipped'"` // Virus scan outcome, as for versions
+	Revision           int       `gorm:"not null;default:1"`                          // Incremented each time the artifact changes
+	Publisher          string    `gorm:"type:varchar(255)"`                           // Self-reported by the client (X-SProto-Publisher), may be empty
+	CreatedAt          time.Time `gorm:"not null"`
+	UpdatedAt          time.Time `gorm:"not null;index"` // When the artifact last changed (expiry, see DEV_CHANNEL_TTL)
+}
+
+// OriginalUpload is the zip a version was published with, kept when it differs from the canonical
+// archive the registry stores (see package artifact) so it can be produced in audits or disputes. It is
+// deleted, with its object unless another version shares it, once ExpiresAt has passed.
+type OriginalUpload struct {
+	ModuleVersionID uuid.UUID `gorm:"type:uuid;primaryKey"`            // One per version
+	Digest          string    `gorm:"type:varchar(64);not null;index"` // SHA256 hex string of the uploaded bytes
+	Size            int64     `gorm:"not null"`
+	StorageKey      string    `gorm:"type:text;not null"` // Key in the storage backend (digest-addressed, see storage.OriginalUploadKey)
+	CreatedAt       time.Time `gorm:"not null"`
+	ExpiresAt       time.Time `gorm:"not null;index"`
+}
+
+// NamespacePolicy is the publish configuration of a namespace, managed by admins: checks every version
+// published in the namespace must pass, in addition to the server-wide publish policies.
+type NamespacePolicy struct {
+	Namespace       string    `gorm:"type:varchar(255);primaryKey"`
+	LintRuleset     string    `gorm:"type:varchar(16);not null;default:''"` // descriptor.LintMinimal, LintBasic or LintStandard; empty to skip linting
+	CompatLevel     string    `gorm:"type:varchar(16);not null;default:''"` // CompatWire or CompatSource; empty to allow breaking changes
+	AllowedFiles    string    `gorm:"type:text;not null;default:''"`        // Newline-separated glob patterns of file names; empty allows any file
+	Monotonic       bool      `gorm:"not null;default:false"`               // New versions must be newer than every published version
+	AllowedSyntaxes string    `gorm:"type:text;not null;default:''"`        // Newline-separated syntax labels the .proto files may declare (proto2, proto3, edition-2023); empty allows any
+	UpdatedAt       time.Time `gorm:"not null"`
+
+	// Ephemeral namespaces (CI previews, scratch modules): versions are deleted this many seconds after they
+	// were published, dev channels as long after their last update; 0 keeps them
+	EphemeralTTLSeconds int64 `gorm:"column:ephemeral_ttl_seconds;not null;default:0"`
+
+	// The google.api.http annotations must be valid for grpc-gateway, without routes bound twice in a module
+	HTTPRules bool `gorm:"column:http_rules;not null;default:false"`
+
+	// Content policy settings replacing the server's (see artifact.ContentPolicy): newline-separated content
+	// types and file extensions, and the largest file in bytes; empty (0) keeps the server's setting
+	ContentTypes      string `gorm:"type:text;not null;default:''"`
+	MaxFileBytes      int64  `gorm:"not null;default:0"`
+	AllowedExtensions string `gorm:"type:text;not null;default:''"`
+
+	// Incremented by every update; the policy's ETag, so concurrent edits can't overwrite each other unnoticed
+	Revision int64 `gorm:"not null;default:0"`
+}
+
+// WebhookSubscription sends the webhook events of a namespace to an endpoint of its team, in addition to the
+// registry-wide WEBHOOK_URL. Subscriptions are managed by the namespace's maintainers.
+type WebhookSubscription struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key"`
+	Namespace   string    `gorm:"type:varchar(255);not null;index"`
+	URL         string    `gorm:"type:text;not null"`
+	Secret      string    `gorm:"type:varchar(255);not null;default:''"` // HMAC key signing the requests; empty sends them unsigned
+	EventTypes  string    `gorm:"type:text;not null;default:''"`         // Newline-separated event type patterns (path.Match); empty for all events
+	Description string    `gorm:"type:text;not null;default:''"`
+	CreatedBy   string    `gorm:"type:varchar(128);not null;default:''"` // Consumer of the token that created it
+	CreatedAt   time.Time `gorm:"not null"`
+}
+
+// Compatibility levels of namespace policies.
+const (
+	CompatWire   = "wire"   // No changes that break the binary encoding (renames are allowed)
+	CompatSource = "source" // No changes that break generated code either
+)
+
+// ManagedToken is a read or maintainer token created through the admin API rather than configured in
+// READ_TOKENS or MAINTAINER_TOKENS, so tokens can be issued and revoked without a restart (e.g. by a
+// Terraform provider). Only the SHA256 of the token is stored; the token is returned once, when created.
+type ManagedToken struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key"`
+	Kind        string     `gorm:"type:varchar(16);not null"`             // "read" or "maintainer"
+	Fingerprint string     `gorm:"type:varchar(64);not null;uniqueIndex"` // Hex SHA256 of the token
+	Consumer    string     `gorm:"type:varchar(128);not null;default:''"` // Name in consumption reports; "" if unnamed
+	Patterns    string     `gorm:"type:text;not null;default:''"`         // Newline-separated module (read) or namespace (maintainer) patterns
+	Description string     `gorm:"type:text;not null;default:''"`
+	ExpiresAt   *time.Time // nil if the token doesn't expire
+	CreatedAt   time.Time  `gorm:"not null"`
+	UpdatedAt   time.Time  `gorm:"not null"`
+}
+
+// TokenUsage records when an API token was last used. Tokens are identified by the SHA256 of the
+// token, so the table never holds the secrets themselves.
+type TokenUsage struct {
+	Fingerprint string    `gorm:"type:varchar(64);primary_key"` // Hex SHA256 of the token
+	LastUsedAt  time.Time `gorm:"not null"`
+}
+
+// ModuleConsumption aggregates the downloads of a module version by one consumer: the team or service a
+// token identifies, "admin" or "anonymous". Counts are accumulated in memory and added periodically.
+type ModuleConsumption struct {
+	ModuleVersionID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Consumer        string    `gorm:"type:varchar(128);primaryKey"`
+	FetchCount      int64     `gorm:"not null;default:0"`
+	FirstFetchedAt  time.Time `gorm:"not null"`
+	LastFetchedAt   time.Time `gorm:"not null"`
+}
+
+// ModuleFetchStat aggregates the downloads of a module of one kind (artifact, bundle, secondary_artifact) in
+// an hour, for its SLO report: requests, server errors and a latency histogram. Counts are accumulated in
+// memory and added periodically; hours older than the retention period are deleted.
+type ModuleFetchStat struct {
+	Module      string    `gorm:"type:varchar(512);primaryKey"` // Full module name (namespace/name)
+	Kind        string    `gorm:"type:varchar(32);primaryKey"`
+	BucketStart time.Time `gorm:"primaryKey;index"` // Start of the hour (UTC)
+	Requests    int64     `gorm:"not null;default:0"`
+	Errors      int64     `gorm:"not null;default:0"` // 5xx responses
+	LatencySum  int64     `gorm:"column:latency_sum_ms;not null;default:0"`
+
+	// Requests by latency, each bucket counting those slower than the previous bound (not cumulative)
+	Le50ms   int64 `gorm:"column:le_50ms;not null;default:0"`
+	Le100ms  int64 `gorm:"column:le_100ms;not null;default:0"`
+	Le250ms  int64 `gorm:"column:le_250ms;not null;default:0"`
+	Le500ms  int64 `gorm:"column:le_500ms;not null;default:0"`
+	Le1s     int64 `gorm:"column:le_1s;not null;default:0"`
+	Le2500ms int64 `gorm:"column:le_2500ms;not null;default:0"`
+	Le5s     int64 `gorm:"column:le_5s;not null;default:0"`
+	Le10s    int64 `gorm:"column:le_10s;not null;default:0"`
+	LeInf    int64 `gorm:"column:le_inf;not null;default:0"` // Slower than 10s
+}
+
+// ClientUsage aggregates the requests of one client software version (parsed from the User-Agent, e.g.
+// protoreg-cli v1.4.0) by one consumer, for the client inventory. Counts are accumulated in memory and added
+// periodically; clients not seen for the retention period are deleted.
+type ClientUsage struct {
+	Consumer      string    `gorm:"type:varchar(128);primaryKey"` // As in ModuleConsumption
+	Client        string    `gorm:"type:varchar(64);primaryKey"`  // Product name, e.g. protoreg-cli
+	ClientVersion string    `gorm:"type:varchar(64);primaryKey"`  // Product version, e.g. v1.4.0; empty if none
+	Requests      int64     `gorm:"not null;default:0"`
+	FirstSeenAt   time.Time `gorm:"not null"`
+	LastSeenAt    time.Time `gorm:"not null;index"`
+}
+
+// SchemaMigration records that the database was migrated to a schema version (see db.SchemaVersion); the
+// highest recorded version is the database's. Rows are never updated or deleted.
+type SchemaMigration struct {
+	Version    int       `gorm:"primaryKey;autoIncrement:false"`
+	MigratedAt time.Time `gorm:"not null"`
+}
+
+// ChecksumEntry is an entry of the checksum log, the append-only Merkle tree of published module versions
+// and their digests (see package translog). Entries are never updated or deleted.
+type ChecksumEntry struct {
+	Index     int64     `gorm:"column:entry_index;primaryKey;autoIncrement:false"`                  // Position in the log, from 0
+	Module    string    `gorm:"type:varchar(512);not null;uniqueIndex:idx_checksum_module_version"` // Full module name (namespace/name)
+	Version   string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_checksum_module_version"`
+	Digest    string    `gorm:"type:varchar(71);not null"` // sha256:<hex>
+	LeafHash  string    `gorm:"type:varchar(64);not null"` // Hex hash of the entry text
+	RootHash  string    `gorm:"type:varchar(64);not null"` // Hex root hash of the log up to and including this entry
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// Plugin is a version of a protoc plugin registered by an admin, so code generation can reference it by
+// name ("registry://go:v1.34") instead of every team pinning its own copy. A version is distributed as a
+// container image, as per-platform binaries, or both.
+type Plugin struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key"`
+	Name        string         `gorm:"type:varchar(128);not null;uniqueIndex:idx_plugin_name_version"` // e.g. "go", "grpc-go"
+	Version     string         `gorm:"type:varchar(100);not null;uniqueIndex:idx_plugin_name_version"` // SemVer string
+	Image       string         `gorm:"type:text"`                                                      // Container image reference; empty if only binaries
+	Description string         `gorm:"type:text"`
+	CreatedAt   time.Time      `gorm:"not null;default:current_timestamp"`
+	Binaries    []PluginBinary `gorm:"foreignKey:PluginID"` // Has many relationship
+}
+
+// PluginBinary is a downloadable executable of a plugin version for one platform.
+type PluginBinary struct {
+	ID       uuid.UUID `gorm:"type:uuid;primary_key"`
+	PluginID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_plugin_binary_platform"`        // Foreign key
+	Platform string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_plugin_binary_platform"` // GOOS/GOARCH, e.g. "linux/amd64"
+	URL      string    `gorm:"type:text;not null"`
+	SHA256   string    `gorm:"column:sha256;type:varchar(64);not null"` // Hex digest of the executable
+}
+
+// Operation is long-running work processed in the background, such as an asynchronous publish
+// (?async=true) or an admin job. Clients poll its status until it has finished.
+type Operation struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key"`
+	Kind       string    `gorm:"type:varchar(32);not null;index"`       // OperationKindPublish, OperationKindGC, ...
+	Namespace  string    `gorm:"type:varchar(255);not null;default:''"` // Module the operation is about, if any
+	ModuleName string    `gorm:"type:varchar(255);not null;default:''"`
+	Version    string    `gorm:"type:varchar(100);not null;default:''"`
+	Params     string    `gorm:"type:text;not null;default:''"`         // JSON object of the job's parameters (secrets omitted)
+	Status     string    `gorm:"type:varchar(16);not null;index"`       // OperationQueued, OperationRunning, ...
+	Stage      string    `gorm:"type:varchar(128);not null;default:''"` // Step being run, while running
+	HTTPStatus int       `gorm:"column:http_status;not null;default:0"` // Status of the synchronous equivalent, once finished
+	Result     string    `gorm:"type:text;not null;default:''"`         // JSON response body, once finished
+	Error      string    `gorm:"type:text;not null;default:''"`         // Error message if the operation failed
+	CreatedAt  time.Time `gorm:"not null;index"`
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+// Operation kinds and statuses.
+const (
+	OperationKindPublish         = "publish"          // Asynchronous publish
+	OperationKindGC              = "gc"               // Deletion of expired original uploads and old operations
+	OperationKindSunset          = "sunset"           // Enforcement of the sunset dates that have passed
+	OperationKindMigrateStorage  = "migrate-storage"  // Move of artifacts stored under older key layouts
+	OperationKindImportBuf       = "import-buf"       // Import of a module from a buf registry
+	OperationKindTierStorage     = "tier-storage"     // Move of aging artifacts to cold storage
+	OperationKindReindexSearch   = "reindex-search"   // Rebuild of the full-text search index
+	OperationKindReindexPackages = "reindex-packages" // Indexing of the proto packages of every version
+	OperationKindSignVersions    = "sign-versions"    // Signing of the versions published without a signature
+
+	OperationQueued    = "queued"
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+	OperationCanceled  = "canceled"
+)
+
+// SearchDocument is an entry of the full-text search index: the module itself (with its sproto.yaml
+// description), or a file or declaration of its newest version with its leading comment. The documents of
+// a module are replaced whenever a newer version is published. The text index over Terms and Body is
+// maintained by the database (see db.MigrateSearchIndex), not by GORM.
+type SearchDocument struct {
+	ID              int64     `gorm:"primaryKey"`                    // Integer so SQLite's FTS table can reference it as its docid
+	ModuleID        uuid.UUID `gorm:"type:uuid;not null;index"`      // Foreign key
+	ModuleVersionID uuid.UUID `gorm:"type:uuid;not null"`            // Version the document was extracted from
+	Kind            string    `gorm:"type:varchar(16);not null"`     // "module", or a descriptor.SearchKind*
+	Name            string    `gorm:"type:text;not null"`            // Module name, file path or fully-qualified declaration name
+	File            string    `gorm:"type:text;not null;default:''"` // File declaring it; empty for the module
+	Terms           string    `gorm:"type:text;not null"`            // Words of the name (split on dots, underscores and camel case)
+	Body            string    `gorm:"type:text;not null;default:''"` // Description or leading comment
+}
+
+// SearchKindModule is the kind of the document describing a module as a whole.
+const SearchKindModule = "module"
+
+// ProtoPackage maps a proto package declared by a version of a module to that module, so tooling can find
+// which module to depend on from an import statement. Rows are added at publish and kept while the module
+// exists. With PACKAGE_OWNERSHIP=unique, a package belongs to one module.
+type ProtoPackage struct {
+	ID       int64     `gorm:"primaryKey"`
+	Package  string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_proto_package_module"` // E.g. mycompany.user.v1
+	ModuleID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_proto_package_module;index"`   // Foreign key
+	Version  string    `gorm:"type:varchar(100);not null"`                                      // Most recently published version declaring the package
+}
+
+// BeforeCreate GORM hook for Module to generate the primary key in Go.
+// This keeps ID generation portable across Postgres and SQLite.
+func (m *Module) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate GORM hook for ModuleVersion to generate the primary key in Go.
+func (mv *ModuleVersion) BeforeCreate(tx *gorm.DB) error {
+	if mv.ID == uuid.Nil {
+		mv.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate GORM hook for VersionNote to generate the primary key in Go.
+func (n *VersionNote) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate GORM hook for VersionArtifact to generate the primary key in Go.
+func (a *VersionArtifact) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate GORM hook for WebhookSubscription to generate the primary key in Go.
+func (s *WebhookSubscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate GORM hook for ManagedToken to generate the primary key in Go.
+func (t *ManagedToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate GORM hook for DevChannel to generate the primary key in Go.
+func (c *DevChannel) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate GORM hook for Plugin to generate the primary key in Go.
+func (p *Plugin) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate GORM hook for PluginBinary to generate the primary key in Go.
+func (b *PluginBinary) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate GORM hook for Operation to generate the primary key in Go.
+func (o *Operation) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
 }
 
 // BeforeSave GORM hook for ModuleVersion to update the parent Module's UpdatedAt timestamp.
@@ -0,0 +1,202 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"go.uber.org/zap"
+)
+
+// Event types sent to the webhook.
+const (
+	EventQuotaWarning  = "module.quota_warning"  // Module usage crossed the soft-quota warning threshold
+	EventQuotaExceeded = "module.quota_exceeded" // Module usage crossed the soft quota
+	EventVersionSunset = "module_version.sunset" // A deprecated version reached its sunset date
+
+	EventDevChannelUpdated = "module.dev_channel_updated" // A dev channel's artifact changed (protoreg-cli dev)
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the request body ("sha256=<hex>") when a webhook secret is configured.
+const SignatureHeader = "X-SProto-Signature"
+
+// Event is the JSON payload posted to the webhook.
+type Event struct {
+	Type       string                 `json:"type"`
+	Namespace  string                 `json:"namespace,omitempty"`
+	ModuleName string                 `json:"module_name,omitempty"`
+	Message    string                 `json:"message"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	Timestamp  time.Time              `json:"timestamp"`
+}
+
+// Notifier delivers events to an external system.
+type Notifier interface {
+	// Notify delivers the event. Implementations must not block the caller for long.
+	Notify(ctx context.Context, event Event)
+}
+
+// WebhookNotifier posts events as JSON to a URL, in the background.
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to url. If secret is non-empty, each request
+// is signed with HMAC-SHA256 in the X-SProto-Signature header.
+func NewWebhookNotifier(url, secret string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{url: url, secret: secret, client: &http.Client{Timeout: timeout}}
+}
+
+// Notify posts the event asynchronously; delivery failures are logged, not returned.
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	log := logging.FromContext(ctx).With(zap.String("event", event.Type))
+	go func() {
+		if err := n.send(event); err != nil {
+			log.Warn("Failed to deliver webhook", zap.Error(err))
+		}
+	}()
+}
+
+// send performs the HTTP request for a single event.
+func (n *WebhookNotifier) send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// --- Namespace Subscriptions ---
+
+// Subscription is a webhook endpoint receiving the events of one namespace, registered by its maintainers.
+type Subscription struct {
+	URL        string
+	Secret     string   // Signs the requests like WEBHOOK_SECRET; empty sends them unsigned
+	EventTypes []string // Event types delivered (path.Match patterns, e.g. "module_version.*"); empty for all
+}
+
+// Wants reports whether the subscription receives events of the given type.
+func (s Subscription) Wants(eventType string) bool {
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, pattern := range s.EventTypes {
+		if ok, _ := path.Match(pattern, eventType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscriptionFinder returns the subscriptions of a namespace.
+type SubscriptionFinder func(ctx context.Context, namespace string) ([]Subscription, error)
+
+// Global subscription lookup, configured at startup via SetSubscriptionFinder. nil delivers events to the
+// global webhook only.
+var (
+	findSubscriptions   SubscriptionFinder
+	subscriptionTimeout = 5 * time.Second
+)
+
+// SetSubscriptionFinder configures where the namespace subscriptions of events are looked up.
+func SetSubscriptionFinder(find SubscriptionFinder) {
+	findSubscriptions = find
+}
+
+// notifySubscribers delivers an event to the subscriptions of its namespace that want it, in the
+// background. Lookup and delivery failures are logged, not returned.
+func notifySubscribers(ctx context.Context, event Event) {
+	find := findSubscriptions
+	if find == nil || event.Namespace == "" {
+		return
+	}
+	log := logging.FromContext(ctx).With(zap.String("event", event.Type), zap.String("namespace", event.Namespace))
+	ctx = context.WithoutCancel(ctx) // Events are often sent right before the request ends
+	go func() {
+		subscriptions, err := find(ctx, event.Namespace)
+		if err != nil {
+			log.Warn("Failed to look up webhook subscriptions", zap.Error(err))
+			return
+		}
+		for _, s := range subscriptions {
+			if !s.Wants(event.Type) {
+				continue
+			}
+			n := &WebhookNotifier{url: s.URL, secret: s.Secret, client: subscriptionClient(subscriptionTimeout)}
+			if err := n.send(event); err != nil {
+				log.Warn("Failed to deliver webhook to namespace subscription", zap.String("url", s.URL), zap.Error(err))
+			}
+		}
+	}()
+}
+
+// Global notifier instance. nil when no webhook is configured.
+var notifier Notifier
+
+// InitNotifier configures the webhook notifier from config.
+// Notifications are optional: if WEBHOOK_URL is empty, no notifier is configured and nil is returned.
+// Namespace subscriptions (see SetSubscriptionFinder) are delivered with the same timeout either way, and
+// to public addresses only unless WEBHOOK_ALLOW_PRIVATE_TARGETS is set.
+func InitNotifier(cfg config.Config) Notifier {
+	allowPrivateTargets = cfg.WebhookAllowPrivateTargets
+	if cfg.WebhookTimeout > 0 {
+		subscriptionTimeout = cfg.WebhookTimeout
+	}
+	if cfg.WebhookURL == "" {
+		notifier = nil
+		return nil
+	}
+	notifier = NewWebhookNotifier(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookTimeout)
+	logging.L().Info("Webhook notifications enabled", zap.String("url", cfg.WebhookURL))
+	return notifier
+}
+
+// Send delivers an event through the configured notifier, if any, and to the subscriptions of its namespace.
+func Send(ctx context.Context, event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	if notifier != nil {
+		notifier.Notify(ctx, event)
+	}
+	notifySubscribers(ctx, event)
+}
+
+// SetNotifier is a test helper function to replace the global notifier.
+// !! Use only in tests !!
+func SetNotifier(n Notifier) {
+	notifier = n
+}
@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotifier_SendSignsPayload(t *testing.T) {
+	var gotBody []byte
+	var gotSignature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSignature = r.Header.Get(SignatureHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(server.URL, "s3cret", time.Second)
+	err := n.send(Event{Type: EventQuotaWarning, Namespace: "my-org", ModuleName: "my-module", Message: "almost full"})
+	require.NoError(t, err)
+
+	var event Event
+	require.NoError(t, json.Unmarshal(gotBody, &event))
+	assert.Equal(t, EventQuotaWarning, event.Type)
+	assert.Equal(t, "my-module", event.ModuleName)
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(gotBody)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), gotSignature)
+}
+
+func TestWebhookNotifier_SendErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(SignatureHeader)) // No secret configured
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	n := NewWebhookNotifier(server.URL, "", time.Second)
+	err := n.send(Event{Type: EventQuotaExceeded})
+	assert.ErrorContains(t, err, "status 500")
+}
+
+func TestSendDeliversToNamespaceSubscriptions(t *testing.T) {
+	received := make(chan *http.Request, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	SetSubscriptionFinder(func(ctx context.Context, namespace string) ([]Subscription, error) {
+		if namespace != "payments" {
+			return nil, nil
+		}
+		return []Subscription{
+			{URL: server.URL + "/all"},
+			{URL: server.URL + "/sunsets", Secret: "s3cret", EventTypes: []string{"module_version.*"}},
+		}, nil
+	})
+	t.Cleanup(func() { SetSubscriptionFinder(nil) })
+	allowPrivateTargets = true // The test server listens on loopback
+	t.Cleanup(func() { allowPrivateTargets = false })
+
+	Send(context.Background(), Event{Type: EventVersionSunset, Namespace: "payments", ModuleName: "ledger"})
+	paths := map[string]bool{}
+	for range 2 {
+		select {
+		case r := <-received:
+			paths[r.URL.Path] = true
+			if r.URL.Path == "/sunsets" {
+				assert.NotEmpty(t, r.Header.Get(SignatureHeader))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("webhook not delivered")
+		}
+	}
+	assert.Equal(t, map[string]bool{"/all": true, "/sunsets": true}, paths)
+
+	// Filtered by type and namespace
+	Send(context.Background(), Event{Type: EventQuotaWarning, Namespace: "payments"})
+	Send(context.Background(), Event{Type: EventVersionSunset, Namespace: "billing"})
+	select {
+	case r := <-received:
+		assert.Equal(t, "/all", r.URL.Path)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not delivered")
+	}
+	select {
+	case r := <-received:
+		t.Fatalf("unexpected delivery to %s", r.URL.Path)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSubscriptionWants(t *testing.T) {
+	assert.True(t, Subscription{}.Wants(EventQuotaWarning))
+	s := Subscription{EventTypes: []string{"module.quota_*", EventVersionSunset}}
+	assert.True(t, s.Wants(EventQuotaExceeded))
+	assert.True(t, s.Wants(EventVersionSunset))
+	assert.False(t, s.Wants(EventDevChannelUpdated))
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"time"
+)
+
+// ErrForbiddenTarget is returned when a namespace subscription's URL resolves to an address it may not
+// reach: loopback, link-local, private or unspecified, e.g. the cloud metadata service or the registry's
+// own database. Allowed with WEBHOOK_ALLOW_PRIVATE_TARGETS.
+var ErrForbiddenTarget = errors.New("webhook target address is not allowed")
+
+// Whether subscriptions may reach private targets (WEBHOOK_ALLOW_PRIVATE_TARGETS), and how their hosts
+// are resolved (replaced in tests).
+var (
+	allowPrivateTargets bool
+	lookupHost          = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	}
+)
+
+// forbiddenTarget reports whether a subscription may not reach addr.
+func forbiddenTarget(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsPrivate() || addr.IsUnspecified()
+}
+
+// AllowedTargetHost reports whether a subscription URL's host may be saved: false for a literal
+// forbidden address, so obvious mistakes are rejected early. Hostnames are checked at delivery.
+func AllowedTargetHost(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	return allowPrivateTargets || err != nil || !forbiddenTarget(addr)
+}
+
+// dialPublic connects to addr like a net.Dialer, but refuses hosts resolving to a forbidden address. The
+// addresses are checked when connecting rather than when the subscription is saved, so a host can't be
+// made to resolve to a private address afterwards; redirects are dialed (and checked) the same way.
+func dialPublic(timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := lookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("no addresses found for %s", host)
+		}
+		for _, ip := range ips {
+			if forbiddenTarget(ip) {
+				return nil, fmt.Errorf("%w: %s resolves to %s", ErrForbiddenTarget, host, ip)
+			}
+		}
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.Unmap().String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// subscriptionClient returns the HTTP client delivering to namespace subscriptions, which (unlike the
+// operator's WEBHOOK_URL) are registered by maintainers and so only reach public addresses by default.
+func subscriptionClient(timeout time.Duration) *http.Client {
+	if allowPrivateTargets {
+		return &http.Client{Timeout: timeout}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would make the connection to the target, unchecked
+	transport.DialContext = dialPublic(timeout)
+	transport.DisableKeepAlives = true // Built per delivery, so idle connections would never be reused
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
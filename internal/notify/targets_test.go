@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForbiddenTarget(t *testing.T) {
+	for addr, forbidden := range map[string]bool{
+		"127.0.0.1":        true,
+		"169.254.169.254":  true, // Cloud metadata service
+		"10.0.0.7":         true,
+		"172.16.4.2":       true,
+		"192.168.1.1":      true,
+		"0.0.0.0":          true,
+		"::1":              true,
+		"fe80::1":          true,
+		"fd00::1":          true,
+		"::ffff:127.0.0.1": true,
+		"93.184.216.34":    false,
+		"2606:4700::1111":  false,
+	} {
+		assert.Equal(t, forbidden, forbiddenTarget(netip.MustParseAddr(addr)), addr)
+	}
+
+	assert.False(t, AllowedTargetHost("169.254.169.254"))
+	assert.False(t, AllowedTargetHost("::1"))
+	assert.True(t, AllowedTargetHost("93.184.216.34"))
+	assert.True(t, AllowedTargetHost("hooks.example.com")) // Checked when delivering
+}
+
+func TestSubscriptionDeliveryRefusesPrivateTargets(t *testing.T) {
+	var delivered atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	resolve := lookupHost
+	lookupHost = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		if host == "hooks.internal.example" {
+			return []netip.Addr{netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("10.0.0.7")}, nil
+		}
+		return []netip.Addr{netip.MustParseAddr(host)}, nil
+	}
+	t.Cleanup(func() { lookupHost = resolve })
+	deliver := func(url string) error {
+		n := &WebhookNotifier{url: url, client: subscriptionClient(time.Second)}
+		return n.send(Event{Type: EventQuotaWarning, Namespace: "payments"})
+	}
+
+	// Loopback, the metadata service and hostnames resolving to a private address are refused
+	for _, url := range []string{server.URL, "http://169.254.169.254/latest/meta-data", "http://hooks.internal.example/hook"} {
+		assert.ErrorIs(t, deliver(url), ErrForbiddenTarget, url)
+	}
+	assert.Zero(t, delivered.Load())
+
+	// Unless the operator allows private targets
+	allowPrivateTargets = true
+	t.Cleanup(func() { allowPrivateTargets = false })
+	assert.NoError(t, deliver(server.URL))
+	assert.Equal(t, int32(1), delivered.Load())
+}
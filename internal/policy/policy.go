@@ -0,0 +1,247 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/google/cel-go/cel"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is a publish rule: a CEL expression that must evaluate to true for a publish to be allowed.
+type Policy struct {
+	Name string `yaml:"name"`
+	// Namespaces restricts the policy to namespaces matching these patterns (path.Match syntax, e.g. "team-*").
+	// An empty list applies the policy to every namespace.
+	Namespaces []string `yaml:"namespaces"`
+	Expression string   `yaml:"expression"`
+	// Message is the reason reported when the policy denies a publish. Defaults to the expression.
+	Message string `yaml:"message"`
+}
+
+// File is the content of the policy file (PROTOREG_POLICY_FILE).
+type File struct {
+	Policies []Policy `yaml:"policies"`
+}
+
+// Input is what policies can see about a publish.
+type Input struct {
+	Namespace    string
+	ModuleName   string
+	Version      string // Normalized, with the "v" prefix
+	Files        []string
+	Size         int64
+	Dependencies map[string]string // Declared in the artifact's sproto.yaml
+	Publisher    string            // Self-reported by the client (X-SProto-Publisher)
+	Branch       string            // Self-reported by the client (X-SProto-Branch)
+	// Diff compares the artifact with the newest published version; nil for the first version or if it
+	// couldn't be computed (e.g. the artifact doesn't compile).
+	Diff *descriptor.DiffSummary
+}
+
+// Violation is a policy that denied a publish.
+type Violation struct {
+	Policy  string `json:"policy"`
+	Message string `json:"message"`
+}
+
+// Engine evaluates the configured policies.
+type Engine struct {
+	rules []rule
+}
+
+// rule is a policy with its compiled expression.
+type rule struct {
+	Policy
+	program cel.Program
+}
+
+// Global engine instance; nil when no policy file is configured.
+var engine *Engine
+
+// newEnv declares the variables available to policy expressions.
+func newEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("module", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("version", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("files", cel.ListType(cel.StringType)),
+		cel.Variable("size", cel.IntType),
+		cel.Variable("dependencies", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("publisher", cel.StringType),
+		cel.Variable("branch", cel.StringType),
+		cel.Variable("diff", cel.MapType(cel.StringType, cel.DynType)),
+	)
+}
+
+// New compiles policies. Every expression must type-check to a bool.
+func New(policies []Policy) (*Engine, error) {
+	env, err := newEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CEL environment: %w", err)
+	}
+	e := &Engine{}
+	for i, p := range policies {
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("policy-%d", i+1)
+		}
+		for _, pattern := range p.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("policy %s: invalid namespace pattern %q: %w", p.Name, pattern, err)
+			}
+		}
+		ast, issues := env.Compile(p.Expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("policy %s: %w", p.Name, issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return nil, fmt.Errorf("policy %s: expression must evaluate to a bool, got %s", p.Name, ast.OutputType())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", p.Name, err)
+		}
+		if p.Message == "" {
+			p.Message = p.Expression
+		}
+		e.rules = append(e.rules, rule{Policy: p, program: program})
+	}
+	return e, nil
+}
+
+// Load reads and compiles a policy file.
+func Load(filePath string) (*Engine, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", filePath, err)
+	}
+	return New(f.Policies)
+}
+
+// Applies reports whether any policy applies to the namespace.
+func (e *Engine) Applies(namespace string) bool {
+	for _, r := range e.rules {
+		if r.appliesTo(namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r rule) appliesTo(namespace string) bool {
+	if len(r.Namespaces) == 0 {
+		return true
+	}
+	for _, pattern := range r.Namespaces {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate runs the policies applying to the input's namespace and returns those that deny the publish.
+// Policies that fail to evaluate deny the publish (fail closed).
+func (e *Engine) Evaluate(ctx context.Context, in Input) []Violation {
+	activation := in.activation()
+	var violations []Violation
+	for _, r := range e.rules {
+		if !r.appliesTo(in.Namespace) {
+			continue
+		}
+		out, _, err := r.program.ContextEval(ctx, activation)
+		if err != nil {
+			violations = append(violations, Violation{Policy: r.Name, Message: fmt.Sprintf("policy evaluation failed: %v", err)})
+			continue
+		}
+		if allowed, ok := out.Value().(bool); !ok || !allowed {
+			violations = append(violations, Violation{Policy: r.Name, Message: r.Message})
+		}
+	}
+	return violations
+}
+
+// activation maps the input to the variables declared in newEnv.
+func (in Input) activation() map[string]any {
+	version := map[string]any{"raw": in.Version, "major": int64(0), "minor": int64(0), "patch": int64(0), "prerelease": ""}
+	if v, err := semver.NewVersion(in.Version); err == nil {
+		version["major"], version["minor"], version["patch"] = int64(v.Major()), int64(v.Minor()), int64(v.Patch())
+		version["prerelease"] = v.Prerelease()
+	}
+
+	diff := map[string]any{
+		"available": false, "base_version": "", "base_major": int64(0),
+		"breaking_changes": int64(0), "changes": []map[string]string{}, "added_required_fields": []string{},
+	}
+	if in.Diff != nil {
+		changes := make([]map[string]string, 0, len(in.Diff.Changes))
+		for _, c := range in.Diff.Changes {
+			changes = append(changes, map[string]string{"kind": c.Kind, "element": c.Element, "type": c.Type, "file": c.File, "message": c.Message})
+		}
+		diff["available"] = true
+		diff["base_version"] = in.Diff.BaseVersion
+		if v, err := semver.NewVersion(in.Diff.BaseVersion); err == nil {
+			diff["base_major"] = int64(v.Major())
+		}
+		diff["breaking_changes"] = int64(len(in.Diff.Changes))
+		diff["changes"] = changes
+		if in.Diff.AddedRequiredFields != nil {
+			diff["added_required_fields"] = in.Diff.AddedRequiredFields
+		}
+	}
+
+	files := in.Files
+	if files == nil {
+		files = []string{}
+	}
+	dependencies := in.Dependencies
+	if dependencies == nil {
+		dependencies = map[string]string{}
+	}
+	return map[string]any{
+		"module":       map[string]string{"namespace": in.Namespace, "name": in.ModuleName},
+		"version":      version,
+		"files":        files,
+		"size":         in.Size,
+		"dependencies": dependencies,
+		"publisher":    in.Publisher,
+		"branch":       in.Branch,
+		"diff":         diff,
+	}
+}
+
+// InitEngine loads the policy file configured in PROTOREG_POLICY_FILE.
+// Policies are optional: without a policy file no engine is configured and nil is returned.
+func InitEngine(cfg config.Config) (*Engine, error) {
+	if cfg.PolicyFile == "" {
+		engine = nil
+		return nil, nil
+	}
+	e, err := Load(cfg.PolicyFile)
+	if err != nil {
+		return nil, err
+	}
+	engine = e
+	logging.L().Info("Publish policies enabled", zap.String("file", cfg.PolicyFile), zap.Int("policies", len(e.rules)))
+	return engine, nil
+}
+
+// GetEngine returns the configured policy engine, or nil if publish policies are disabled.
+func GetEngine() *Engine {
+	return engine
+}
+
+// SetEngine replaces the global policy engine; nil disables publish policies.
+func SetEngine(e *Engine) {
+	engine = e
+}
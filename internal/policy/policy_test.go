@@ -0,0 +1,101 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_RejectsInvalidPolicies(t *testing.T) {
+	_, err := New([]Policy{{Name: "syntax", Expression: "version.major >"}})
+	assert.ErrorContains(t, err, "policy syntax")
+
+	_, err = New([]Policy{{Name: "not-bool", Expression: "size + 1"}})
+	assert.ErrorContains(t, err, "must evaluate to a bool")
+
+	_, err = New([]Policy{{Name: "unknown-variable", Expression: "author == 'me'"}})
+	assert.ErrorContains(t, err, "undeclared reference")
+
+	_, err = New([]Policy{{Name: "pattern", Namespaces: []string{"["}, Expression: "true"}})
+	assert.ErrorContains(t, err, "invalid namespace pattern")
+}
+
+func TestEvaluate(t *testing.T) {
+	engine, err := New([]Policy{
+		{
+			Name:       "breaking-needs-major",
+			Expression: "!diff.available || diff.breaking_changes == 0 || version.major > diff.base_major",
+			Message:    "breaking changes require a major version bump",
+		},
+		{
+			Name:       "no-new-required-fields",
+			Expression: "size(diff.added_required_fields) == 0",
+		},
+		{
+			Name:       "release-branch",
+			Namespaces: []string{"payments", "team-*"},
+			Expression: "version.prerelease != '' || branch == 'main'",
+			Message:    "releases must be published from main",
+		},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	assert.True(t, engine.Applies("other"))
+
+	// First version: no diff, no branch rule for this namespace
+	assert.Empty(t, engine.Evaluate(ctx, Input{Namespace: "other", ModuleName: "m", Version: "v1.0.0"}))
+
+	breaking := &descriptor.DiffSummary{
+		BaseVersion: "v1.4.0",
+		Changes:     []descriptor.Change{{Kind: descriptor.ChangeFieldRemoved, Element: "a.B.c"}},
+	}
+	violations := engine.Evaluate(ctx, Input{Namespace: "team-a", ModuleName: "m", Version: "v1.5.0", Branch: "feature", Diff: breaking})
+	assert.Equal(t, []Violation{
+		{Policy: "breaking-needs-major", Message: "breaking changes require a major version bump"},
+		{Policy: "release-branch", Message: "releases must be published from main"},
+	}, violations)
+
+	assert.Empty(t, engine.Evaluate(ctx, Input{Namespace: "team-a", ModuleName: "m", Version: "v2.0.0", Branch: "main", Diff: breaking}))
+	assert.Empty(t, engine.Evaluate(ctx, Input{Namespace: "payments", ModuleName: "m", Version: "v2.0.0-rc.1", Diff: breaking}))
+
+	violations = engine.Evaluate(ctx, Input{Namespace: "other", ModuleName: "m", Version: "v1.1.0",
+		Diff: &descriptor.DiffSummary{BaseVersion: "v1.0.0", AddedRequiredFields: []string{"a.B.d"}}})
+	assert.Equal(t, []Violation{{Policy: "no-new-required-fields", Message: "size(diff.added_required_fields) == 0"}}, violations)
+}
+
+func TestEvaluate_FailsClosed(t *testing.T) {
+	engine, err := New([]Policy{{Name: "common-pinned", Expression: `dependencies["acme/common"].startsWith("^1.")`}})
+	require.NoError(t, err)
+
+	// The key is missing, so evaluation fails and the publish is denied
+	violations := engine.Evaluate(context.Background(), Input{Namespace: "acme", ModuleName: "m", Version: "v1.0.0"})
+	require.Len(t, violations, 1)
+	assert.Equal(t, "common-pinned", violations[0].Policy)
+	assert.Contains(t, violations[0].Message, "policy evaluation failed")
+
+	assert.Empty(t, engine.Evaluate(context.Background(), Input{Namespace: "acme", ModuleName: "m", Version: "v1.0.0",
+		Dependencies: map[string]string{"acme/common": "^1.2.0"}}))
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`policies:
+  - name: protos-only-under-namespace
+    expression: files.all(f, f.startsWith(module.namespace + "/"))
+    message: all files must live under the namespace directory
+`), 0o644))
+
+	engine, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, engine.Evaluate(context.Background(), Input{Namespace: "acme", Files: []string{"acme/a.proto"}}))
+	assert.Len(t, engine.Evaluate(context.Background(), Input{Namespace: "acme", Files: []string{"other/a.proto"}}), 1)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,114 @@
+// Package provenance signs and verifies the registry's version signatures: when a version signing key is
+// configured, the registry signs each version's coordinates and artifact digest once, at publish, and
+// serves the signature with the version. Unlike checksum statements (see package translog), a signature
+// doesn't depend on the checksum log, so it can be copied along with the artifact, e.g. by a mirror, and
+// checked later against the registry's published keys. Versions promoted from another registry (e.g.
+// staging to prod) also record the chain of registries they came from (see Source).
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Algorithm names the signature scheme in API responses.
+const Algorithm = "ed25519"
+
+// payloadHeader is the first line of a signed payload, identifying its format.
+const payloadHeader = "sproto version signature v1"
+
+// ErrInvalidSignature is returned by Verify when the signature doesn't match the version.
+var ErrInvalidSignature = errors.New("invalid version signature")
+
+// Payload returns the signed text form of a version's coordinates and digest:
+//
+//	sproto version signature v1
+//	acme/billing v1.2.0 sha256:7ca2895a...
+func Payload(module, version, digest string) []byte {
+	return []byte(fmt.Sprintf("%s\n%s %s %s\n", payloadHeader, module, version, digest))
+}
+
+// KeyID identifies a signing key by its public key: the first 8 bytes of its SHA256, in hex. Signatures
+// carry the ID of the key that made them, so they can be verified after the registry's key was rotated.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Sign returns the base64 Ed25519 signature of module@version having digest (sha256:<hex>).
+func Sign(key ed25519.PrivateKey, module, version, digest string) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, Payload(module, version, digest)))
+}
+
+// Verify checks a base64 signature of module@version having digest.
+func Verify(key ed25519.PublicKey, module, version, digest, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || !ed25519.Verify(key, Payload(module, version, digest), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// --- Promotion Chains ---
+
+// MaxChainLength bounds the registries a promoted version can record having passed through.
+const MaxChainLength = 16
+
+// Source is a registry a version was promoted from (e.g. staging, when promoted to prod), as recorded by
+// the registry it was promoted to. Signature and KeyID are the source registry's version signature, if
+// it had one, so the hop can be checked later against that registry's published keys.
+type Source struct {
+	Registry    string    `json:"registry"` // Base URL of the source registry
+	Module      string    `json:"module"`   // namespace/name there
+	Version     string    `json:"version"`
+	Digest      string    `json:"digest"`       // sha256:<hex> of the artifact there
+	PublishedAt time.Time `json:"published_at"` // When the version was published there
+	Signature   string    `json:"signature,omitempty"`
+	KeyID       string    `json:"key_id,omitempty"`
+}
+
+// Chain extends the chain of the registries a version went through with the registry it's being
+// promoted from, last. The first source is where the version was originally published.
+func Chain(previous []Source, from Source) []Source {
+	return append(append(make([]Source, 0, len(previous)+1), previous...), from)
+}
+
+// ValidateChain checks a promotion chain for a version with digest (sha256:<hex>): the sources must be
+// well-formed, and the registry the version was promoted from last must have had the same artifact.
+func ValidateChain(chain []Source, digest string) error {
+	if len(chain) == 0 {
+		return errors.New("provenance chain is empty")
+	}
+	if len(chain) > MaxChainLength {
+		return fmt.Errorf("provenance chain has more than %d sources", MaxChainLength)
+	}
+	for i, s := range chain {
+		u, err := url.Parse(s.Registry)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("source %d: registry must be an http(s) URL, got %q", i+1, s.Registry)
+		}
+		if namespace, name, ok := strings.Cut(s.Module, "/"); !ok || namespace == "" || name == "" {
+			return fmt.Errorf("source %d: module must be namespace/name, got %q", i+1, s.Module)
+		}
+		if s.Version == "" {
+			return fmt.Errorf("source %d: version is required", i+1)
+		}
+		if !strings.HasPrefix(s.Digest, "sha256:") {
+			return fmt.Errorf("source %d: digest must be sha256:<hex>, got %q", i+1, s.Digest)
+		}
+		if s.PublishedAt.IsZero() {
+			return fmt.Errorf("source %d: published_at is required", i+1)
+		}
+	}
+	if last := chain[len(chain)-1]; last.Digest != digest {
+		return fmt.Errorf("the artifact's digest %s does not match %s@%s's at %s (%s)", digest, last.Module, last.Version, last.Registry, last.Digest)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package provenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/translog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	assert.Equal(t, "sproto version signature v1\nacme/billing v1.2.0 sha256:abcd\n", string(Payload("acme/billing", "v1.2.0", "sha256:abcd")))
+
+	privateKey, publicKey, err := translog.GenerateKey()
+	require.NoError(t, err)
+	key, err := translog.ParsePrivateKey(privateKey)
+	require.NoError(t, err)
+	pub, err := translog.ParsePublicKey(publicKey)
+	require.NoError(t, err)
+	assert.Len(t, KeyID(pub), 16)
+
+	signature := Sign(key, "acme/billing", "v1.2.0", "sha256:abcd")
+	assert.NoError(t, Verify(pub, "acme/billing", "v1.2.0", "sha256:abcd", signature))
+
+	// Other coordinates, another digest, a garbled signature or another key don't verify
+	assert.ErrorIs(t, Verify(pub, "acme/billing", "v1.2.1", "sha256:abcd", signature), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(pub, "acme/payments", "v1.2.0", "sha256:abcd", signature), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(pub, "acme/billing", "v1.2.0", "sha256:ffff", signature), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(pub, "acme/billing", "v1.2.0", "sha256:abcd", "not base64!"), ErrInvalidSignature)
+	_, otherPublicKey, err := translog.GenerateKey()
+	require.NoError(t, err)
+	other, err := translog.ParsePublicKey(otherPublicKey)
+	require.NoError(t, err)
+	assert.ErrorIs(t, Verify(other, "acme/billing", "v1.2.0", "sha256:abcd", signature), ErrInvalidSignature)
+	assert.NotEqual(t, KeyID(pub), KeyID(other))
+}
+
+func TestValidateChain(t *testing.T) {
+	published := time.Date(2023, 10, 28, 9, 0, 0, 0, time.UTC)
+	staging := Source{Registry: "https://staging.example.com", Module: "acme/billing", Version: "v1.2.0", Digest: "sha256:abcd", PublishedAt: published}
+	chain := Chain(nil, staging)
+	assert.NoError(t, ValidateChain(chain, "sha256:abcd"))
+
+	// Promoted again: the chain keeps where the version was first published
+	qa := Source{Registry: "http://qa.internal:8080", Module: "acme/billing", Version: "v1.2.0", Digest: "sha256:abcd", PublishedAt: published.Add(time.Hour)}
+	longer := Chain(chain, qa)
+	assert.Equal(t, []Source{staging, qa}, longer)
+	assert.Len(t, chain, 1)
+	assert.NoError(t, ValidateChain(longer, "sha256:abcd"))
+
+	assert.ErrorContains(t, ValidateChain(chain, "sha256:ffff"), "does not match acme/billing@v1.2.0's at https://staging.example.com")
+	assert.ErrorContains(t, ValidateChain(nil, "sha256:abcd"), "empty")
+	invalid := func(edit func(*Source)) error {
+		s := staging
+		edit(&s)
+		return ValidateChain([]Source{s}, "sha256:abcd")
+	}
+	assert.ErrorContains(t, invalid(func(s *Source) { s.Registry = "staging" }), "registry must be an http(s) URL")
+	assert.ErrorContains(t, invalid(func(s *Source) { s.Module = "billing" }), "module must be namespace/name")
+	assert.ErrorContains(t, invalid(func(s *Source) { s.Version = "" }), "version is required")
+	assert.ErrorContains(t, invalid(func(s *Source) { s.Digest = "abcd" }), "digest must be sha256:<hex>")
+	assert.ErrorContains(t, invalid(func(s *Source) { s.PublishedAt = time.Time{} }), "published_at is required")
+	tooLong := make([]Source, MaxChainLength+1)
+	for i := range tooLong {
+		tooLong[i] = staging
+	}
+	assert.ErrorContains(t, ValidateChain(tooLong, "sha256:abcd"), "more than 16 sources")
+}
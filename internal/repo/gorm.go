@@ -0,0 +1,243 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NewGorm returns the repositories on a GORM database (PostgreSQL or SQLite, see db.Init).
+func NewGorm(gormDB *gorm.DB) Repositories {
+	return Repositories{
+		Modules:  gormModules{gormDB},
+		Versions: gormVersions{gormDB},
+		Tokens:   gormTokens{gormDB},
+		Tx:       gormTx{gormDB},
+	}
+}
+
+type gormTx struct{ db *gorm.DB }
+
+func (g gormTx) Transaction(ctx context.Context, fn func(tx Repositories) error) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewGorm(tx))
+	})
+}
+
+// notFound wraps gorm.ErrRecordNotFound with ErrNotFound, keeping the original error in the chain.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	return err
+}
+
+type gormModules struct{ db *gorm.DB }
+
+func (g gormModules) List(ctx context.Context, filter ModuleFilter) ([]ModuleSummary, error) {
+	query, args, err := moduleListQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	var rows []ModuleSummary
+	if err := g.db.WithContext(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// moduleListQuery builds the module list query for filter.
+func moduleListQuery(filter ModuleFilter) (string, []any, error) {
+	var conditions []string
+	var args []any
+	if filter.Namespace != "" {
+		conditions = append(conditions, "m.namespace = ?")
+		args = append(args, filter.Namespace)
+	}
+	if filter.UpdatedAfter != nil {
+		conditions = append(conditions, "m.updated_at > ?")
+		args = append(args, *filter.UpdatedAfter)
+	}
+	if filter.HasVersions != nil {
+		if *filter.HasVersions {
+			conditions = append(conditions, "lv.version IS NOT NULL")
+		} else {
+			conditions = append(conditions, "lv.version IS NULL")
+		}
+	}
+
+	downloads, orderBy := "", "m.namespace, m.name"
+	switch filter.Sort {
+	case "", ModuleSortName:
+	case ModuleSortUpdatedAt:
+		orderBy = "m.updated_at DESC, m.namespace, m.name"
+	case ModuleSortDownloads:
+		// Summed from the consumer reports, which count every download
+		downloads = `,
+			COALESCE(d.downloads, 0) AS downloads
+		FROM modules m
+		LEFT JOIN (
+			SELECT mv.module_id, SUM(mc.fetch_count) AS downloads
+			FROM module_consumptions mc
+			JOIN module_versions mv ON mv.id = mc.module_version_id
+			GROUP BY mv.module_id
+		) d ON m.id = d.module_id`
+		orderBy = "downloads DESC, m.namespace, m.name"
+	default:
+		return "", nil, fmt.Errorf("unknown module sort order %q", filter.Sort)
+	}
+	if downloads == "" {
+		downloads = `
+		FROM modules m`
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = `
+		WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	// Use Raw SQL to execute the query similar to the one defined for sqlc,
+	// as replicating the CTE and window function logic purely with GORM methods can be complex.
+	query := `
+		WITH LatestVersions AS (
+			SELECT
+				module_id,
+				version,
+				syntaxes,
+				ROW_NUMBER() OVER(PARTITION BY module_id ORDER BY created_at DESC) as rn
+			FROM module_versions
+		)
+		SELECT
+			m.namespace,
+			m.name,
+			COALESCE(lv.version, '') AS latest_version,
+			m.visibility,
+			COALESCE(lv.syntaxes, '') AS latest_syntaxes,
+			m.updated_at` + downloads + `
+		LEFT JOIN LatestVersions lv ON m.id = lv.module_id AND lv.rn = 1` + where + `
+		ORDER BY ` + orderBy + `;
+	`
+	return query, args, nil
+}
+
+func (g gormModules) Get(ctx context.Context, namespace, name string) (*models.Module, error) {
+	var module models.Module
+	if err := g.db.WithContext(ctx).Where("namespace = ? AND name = ?", namespace, name).First(&module).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &module, nil
+}
+
+func (g gormModules) Visibility(ctx context.Context, namespace, name string) (string, error) {
+	var visibilities []string
+	err := g.db.WithContext(ctx).Model(&models.Module{}).Where("namespace = ? AND name = ?", namespace, name).Pluck("visibility", &visibilities).Error
+	if err != nil || len(visibilities) == 0 {
+		return "", err
+	}
+	return visibilities[0], nil
+}
+
+func (g gormModules) GetOrCreate(ctx context.Context, namespace, name, visibility string) (*models.Module, error) {
+	var module models.Module
+	err := g.db.WithContext(ctx).Where(models.Module{Namespace: namespace, Name: name}).
+		Attrs(models.Module{Namespace: namespace, Name: name, Visibility: visibility}). // Set attributes if creating
+		FirstOrCreate(&module).Error
+	if err != nil {
+		return nil, err
+	}
+	return &module, nil
+}
+
+func (g gormModules) SetVisibility(ctx context.Context, moduleID uuid.UUID, visibility string) error {
+	return g.db.WithContext(ctx).Model(&models.Module{ID: moduleID}).Update("visibility", visibility).Error
+}
+
+func (g gormModules) Touch(ctx context.Context, moduleID uuid.UUID) error {
+	return g.db.WithContext(ctx).Model(&models.Module{}).Where("id = ?", moduleID).Update("updated_at", time.Now()).Error
+}
+
+func (g gormModules) IndexPackages(ctx context.Context, moduleID uuid.UUID, version string, packages []string) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	rows := make([]models.ProtoPackage, 0, len(packages))
+	for _, pkg := range packages {
+		rows = append(rows, models.ProtoPackage{Package: pkg, ModuleID: moduleID, Version: version})
+	}
+	return g.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "package"}, {Name: "module_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"version"}),
+	}).Create(&rows).Error
+}
+
+type gormVersions struct{ db *gorm.DB }
+
+func (g gormVersions) Find(ctx context.Context, namespace, name, version string) (*models.ModuleVersion, error) {
+	var moduleVersion models.ModuleVersion
+	// Joined with modules to filter by namespace/name
+	err := g.db.WithContext(ctx).Joins("JOIN modules ON modules.id = module_versions.module_id").
+		Where("modules.namespace = ? AND modules.name = ? AND module_versions.version = ?", namespace, name, version).
+		First(&moduleVersion).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &moduleVersion, nil
+}
+
+func (g gormVersions) ListByModule(ctx context.Context, moduleID uuid.UUID) ([]VersionSummary, error) {
+	var rows []VersionSummary
+	err := g.db.WithContext(ctx).Model(&models.ModuleVersion{}).Select("version, changelog, syntaxes, canary_percent").
+		Where("module_id = ?", moduleID).Order("created_at DESC").Find(&rows).Error
+	return rows, err
+}
+
+func (g gormVersions) Conflicting(ctx context.Context, namespace, name, version string) (string, error) {
+	var existing []string
+	err := g.db.WithContext(ctx).Model(&models.ModuleVersion{}).
+		Joins("JOIN modules ON modules.id = module_versions.module_id").
+		Where("modules.namespace = ? AND modules.name = ?", namespace, name).
+		Where("module_versions.version = ? OR module_versions.version_key = ?", version, validation.VersionKey(version)).
+		Limit(1).Pluck("module_versions.version", &existing).Error
+	if err != nil || len(existing) == 0 {
+		return "", err
+	}
+	return existing[0], nil
+}
+
+func (g gormVersions) Create(ctx context.Context, version *models.ModuleVersion) error {
+	return g.db.WithContext(ctx).Create(version).Error
+}
+
+func (g gormVersions) SetCanary(ctx context.Context, versionID uuid.UUID, percent *int) error {
+	return g.db.WithContext(ctx).Model(&models.ModuleVersion{}).Where("id = ?", versionID).Update("canary_percent", percent).Error
+}
+
+func (g gormVersions) AddFiles(ctx context.Context, files []models.VersionFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+	return g.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&files, 500).Error
+}
+
+type gormTokens struct{ db *gorm.DB }
+
+func (g gormTokens) RecordUses(ctx context.Context, uses []models.TokenUsage) error {
+	return g.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "fingerprint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_used_at"}),
+	}).Create(&uses).Error
+}
+
+func (g gormTokens) LastUses(ctx context.Context, fingerprints []string) ([]models.TokenUsage, error) {
+	var rows []models.TokenUsage
+	err := g.db.WithContext(ctx).Where("fingerprint IN ?", fingerprints).Find(&rows).Error
+	return rows, err
+}
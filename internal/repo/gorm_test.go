@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestGormRepositories(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "repo.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormDB.AutoMigrate(&models.Module{}, &models.ModuleVersion{}, &models.ModuleConsumption{}, &models.TokenUsage{}))
+	repos := NewGorm(gormDB)
+	ctx := context.Background()
+
+	module := models.Module{Namespace: "acme", Name: "user", Visibility: models.VisibilityInternal}
+	require.NoError(t, gormDB.Create(&module).Error)
+	require.NoError(t, gormDB.Create(&models.Module{Namespace: "acme", Name: "empty", Visibility: models.VisibilityPublic}).Error)
+	require.NoError(t, gormDB.Create(&models.ModuleVersion{ModuleID: module.ID, Version: "v1.0.0", Syntaxes: "proto3", CreatedAt: time.Now().Add(-time.Hour)}).Error)
+	require.NoError(t, gormDB.Create(&models.ModuleVersion{ModuleID: module.ID, Version: "v1.1.0", Changelog: "Added email"}).Error)
+
+	// Modules
+	got, err := repos.Modules.Get(ctx, "acme", "user")
+	require.NoError(t, err)
+	assert.Equal(t, module.ID, got.ID)
+	_, err = repos.Modules.Get(ctx, "acme", "nope")
+	assert.ErrorIs(t, err, ErrNotFound)
+	visibility, err := repos.Modules.Visibility(ctx, "acme", "user")
+	require.NoError(t, err)
+	assert.Equal(t, models.VisibilityInternal, visibility)
+	visibility, err = repos.Modules.Visibility(ctx, "acme", "nope")
+	require.NoError(t, err)
+	assert.Empty(t, visibility)
+
+	hasVersions := true
+	summaries, err := repos.Modules.List(ctx, ModuleFilter{HasVersions: &hasVersions})
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, "v1.1.0", summaries[0].LatestVersion)
+	assert.Nil(t, summaries[0].Downloads)
+	summaries, err = repos.Modules.List(ctx, ModuleFilter{Sort: ModuleSortDownloads})
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, int64(0), *summaries[0].Downloads)
+	_, err = repos.Modules.List(ctx, ModuleFilter{Sort: "popularity"})
+	assert.Error(t, err)
+
+	// Versions
+	version, err := repos.Versions.Find(ctx, "acme", "user", "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "proto3", version.Syntaxes)
+	_, err = repos.Versions.Find(ctx, "acme", "user", "v9.0.0")
+	assert.ErrorIs(t, err, ErrNotFound)
+	versions, err := repos.Versions.ListByModule(ctx, module.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []VersionSummary{{Version: "v1.1.0", Changelog: "Added email"}, {Version: "v1.0.0", Syntaxes: "proto3"}}, versions)
+
+	// Token uses are replaced per fingerprint
+	first, second := time.Now().UTC().Add(-time.Hour).Truncate(time.Second), time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, repos.Tokens.RecordUses(ctx, []models.TokenUsage{{Fingerprint: "a", LastUsedAt: first}, {Fingerprint: "b", LastUsedAt: first}}))
+	require.NoError(t, repos.Tokens.RecordUses(ctx, []models.TokenUsage{{Fingerprint: "a", LastUsedAt: second}}))
+	uses, err := repos.Tokens.LastUses(ctx, []string{"a", "c"})
+	require.NoError(t, err)
+	require.Len(t, uses, 1)
+	assert.True(t, second.Equal(uses[0].LastUsedAt))
+}
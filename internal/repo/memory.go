@@ -0,0 +1,302 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/google/uuid"
+)
+
+// NewMemory returns repositories keeping their records in memory, for tests of the handlers that don't
+// need a database. They record no downloads, so modules sorted by ModuleSortDownloads have none.
+func NewMemory() Repositories {
+	m := &memoryStore{
+		modules:  map[uuid.UUID]models.Module{},
+		versions: map[uuid.UUID]models.ModuleVersion{},
+		packages: map[string]models.ProtoPackage{},
+		files:    map[string]models.VersionFile{},
+		tokens:   map[string]models.TokenUsage{},
+	}
+	return m.repositories()
+}
+
+// memoryStore holds the records of the memory repositories. Transactions run one at a time and are rolled
+// back by restoring a copy of the records; writes outside a transaction aren't isolated from it.
+type memoryStore struct {
+	mu       sync.RWMutex
+	txMu     sync.Mutex
+	modules  map[uuid.UUID]models.Module
+	versions map[uuid.UUID]models.ModuleVersion
+	packages map[string]models.ProtoPackage // By package and module ID
+	files    map[string]models.VersionFile  // By version ID and path
+	tokens   map[string]models.TokenUsage   // By fingerprint
+}
+
+func (m *memoryStore) repositories() Repositories {
+	return Repositories{
+		Modules:  memoryModules{m},
+		Versions: memoryVersions{m},
+		Tokens:   memoryTokens{m},
+		Tx:       m,
+	}
+}
+
+func (m *memoryStore) Transaction(ctx context.Context, fn func(tx Repositories) error) (err error) {
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	m.mu.RLock()
+	modules, versions, packages, files, tokens := maps.Clone(m.modules), maps.Clone(m.versions), maps.Clone(m.packages), maps.Clone(m.files), maps.Clone(m.tokens)
+	m.mu.RUnlock()
+	rollback := func() {
+		m.mu.Lock()
+		m.modules, m.versions, m.packages, m.files, m.tokens = modules, versions, packages, files, tokens
+		m.mu.Unlock()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			rollback()
+			panic(r)
+		}
+		if err != nil {
+			rollback()
+		}
+	}()
+	return fn(m.repositories())
+}
+
+// module returns the module at namespace/name. Requires m.mu.
+func (m *memoryStore) module(namespace, name string) (models.Module, bool) {
+	for _, module := range m.modules {
+		if module.Namespace == namespace && module.Name == name {
+			return module, true
+		}
+	}
+	return models.Module{}, false
+}
+
+// moduleVersions returns the versions of a module, most recently published first. Requires m.mu.
+func (m *memoryStore) moduleVersions(moduleID uuid.UUID) []models.ModuleVersion {
+	var versions []models.ModuleVersion
+	for _, version := range m.versions {
+		if version.ModuleID == moduleID {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].CreatedAt.After(versions[j].CreatedAt) })
+	return versions
+}
+
+type memoryModules struct{ m *memoryStore }
+
+func (r memoryModules) List(ctx context.Context, filter ModuleFilter) ([]ModuleSummary, error) {
+	switch filter.Sort {
+	case "", ModuleSortName, ModuleSortUpdatedAt, ModuleSortDownloads:
+	default:
+		return nil, fmt.Errorf("unknown module sort order %q", filter.Sort)
+	}
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+
+	var rows []ModuleSummary
+	for _, module := range r.m.modules {
+		if filter.Namespace != "" && module.Namespace != filter.Namespace {
+			continue
+		}
+		if filter.UpdatedAfter != nil && !module.UpdatedAt.After(*filter.UpdatedAfter) {
+			continue
+		}
+		row := ModuleSummary{Namespace: module.Namespace, Name: module.Name, Visibility: module.Visibility, UpdatedAt: module.UpdatedAt}
+		if versions := r.m.moduleVersions(module.ID); len(versions) > 0 {
+			row.LatestVersion, row.LatestSyntaxes = versions[0].Version, versions[0].Syntaxes
+		}
+		if filter.HasVersions != nil && *filter.HasVersions != (row.LatestVersion != "") {
+			continue
+		}
+		if filter.Sort == ModuleSortDownloads {
+			row.Downloads = new(int64)
+		}
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if filter.Sort == ModuleSortUpdatedAt && !rows[i].UpdatedAt.Equal(rows[j].UpdatedAt) {
+			return rows[i].UpdatedAt.After(rows[j].UpdatedAt)
+		}
+		if rows[i].Namespace != rows[j].Namespace {
+			return rows[i].Namespace < rows[j].Namespace
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return rows, nil
+}
+
+func (r memoryModules) Get(ctx context.Context, namespace, name string) (*models.Module, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	module, ok := r.m.module(namespace, name)
+	if !ok {
+		return nil, fmt.Errorf("%w: module %s/%s", ErrNotFound, namespace, name)
+	}
+	return &module, nil
+}
+
+func (r memoryModules) Visibility(ctx context.Context, namespace, name string) (string, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	module, _ := r.m.module(namespace, name)
+	return module.Visibility, nil
+}
+
+func (r memoryModules) GetOrCreate(ctx context.Context, namespace, name, visibility string) (*models.Module, error) {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	module, ok := r.m.module(namespace, name)
+	if !ok {
+		now := time.Now()
+		module = models.Module{ID: uuid.New(), Namespace: namespace, Name: name, Visibility: visibility, CreatedAt: now, UpdatedAt: now}
+		r.m.modules[module.ID] = module
+	}
+	return &module, nil
+}
+
+func (r memoryModules) SetVisibility(ctx context.Context, moduleID uuid.UUID, visibility string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if module, ok := r.m.modules[moduleID]; ok {
+		module.Visibility, module.UpdatedAt = visibility, time.Now()
+		r.m.modules[moduleID] = module
+	}
+	return nil
+}
+
+func (r memoryModules) Touch(ctx context.Context, moduleID uuid.UUID) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if module, ok := r.m.modules[moduleID]; ok {
+		module.UpdatedAt = time.Now()
+		r.m.modules[moduleID] = module
+	}
+	return nil
+}
+
+func (r memoryModules) IndexPackages(ctx context.Context, moduleID uuid.UUID, version string, packages []string) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	for _, pkg := range packages {
+		r.m.packages[pkg+"\x00"+moduleID.String()] = models.ProtoPackage{Package: pkg, ModuleID: moduleID, Version: version}
+	}
+	return nil
+}
+
+type memoryVersions struct{ m *memoryStore }
+
+func (r memoryVersions) Find(ctx context.Context, namespace, name, version string) (*models.ModuleVersion, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	if module, ok := r.m.module(namespace, name); ok {
+		for _, v := range r.m.versions {
+			if v.ModuleID == module.ID && v.Version == version {
+				return &v, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: version %s/%s@%s", ErrNotFound, namespace, name, version)
+}
+
+func (r memoryVersions) ListByModule(ctx context.Context, moduleID uuid.UUID) ([]VersionSummary, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	var rows []VersionSummary
+	for _, v := range r.m.moduleVersions(moduleID) {
+		rows = append(rows, VersionSummary{Version: v.Version, Changelog: v.Changelog, Syntaxes: v.Syntaxes, CanaryPercent: v.CanaryPercent})
+	}
+	return rows, nil
+}
+
+func (r memoryVersions) Conflicting(ctx context.Context, namespace, name, version string) (string, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	module, ok := r.m.module(namespace, name)
+	if !ok {
+		return "", nil
+	}
+	key := validation.VersionKey(version)
+	for _, v := range r.m.versions {
+		if v.ModuleID == module.ID && (v.Version == version || v.VersionKey == key) {
+			return v.Version, nil
+		}
+	}
+	return "", nil
+}
+
+func (r memoryVersions) Create(ctx context.Context, version *models.ModuleVersion) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if _, ok := r.m.modules[version.ModuleID]; !ok {
+		return fmt.Errorf("repo: module %s of version %s doesn't exist", version.ModuleID, version.Version)
+	}
+	for _, v := range r.m.versions {
+		if v.ModuleID == version.ModuleID && v.Version == version.Version {
+			return fmt.Errorf("repo: version %s already exists", version.Version)
+		}
+	}
+	if version.ID == uuid.Nil {
+		version.ID = uuid.New()
+	}
+	if version.CreatedAt.IsZero() {
+		version.CreatedAt = time.Now()
+	}
+	r.m.versions[version.ID] = *version
+	return nil
+}
+
+func (r memoryVersions) SetCanary(ctx context.Context, versionID uuid.UUID, percent *int) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	if v, ok := r.m.versions[versionID]; ok {
+		v.CanaryPercent = percent
+		r.m.versions[versionID] = v
+	}
+	return nil
+}
+
+func (r memoryVersions) AddFiles(ctx context.Context, files []models.VersionFile) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	for _, f := range files {
+		key := f.ModuleVersionID.String() + "\x00" + f.Path
+		if _, ok := r.m.files[key]; !ok {
+			r.m.files[key] = f
+		}
+	}
+	return nil
+}
+
+type memoryTokens struct{ m *memoryStore }
+
+func (r memoryTokens) RecordUses(ctx context.Context, uses []models.TokenUsage) error {
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	for _, use := range uses {
+		r.m.tokens[use.Fingerprint] = use
+	}
+	return nil
+}
+
+func (r memoryTokens) LastUses(ctx context.Context, fingerprints []string) ([]models.TokenUsage, error) {
+	r.m.mu.RLock()
+	defer r.m.mu.RUnlock()
+	var rows []models.TokenUsage
+	for _, fingerprint := range fingerprints {
+		if use, ok := r.m.tokens[fingerprint]; ok {
+			rows = append(rows, use)
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,89 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/internal/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestRepositoryWrites runs the same writes against the GORM and memory repositories.
+func TestRepositoryWrites(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "repo.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormDB.AutoMigrate(&models.Module{}, &models.ModuleVersion{}, &models.ProtoPackage{}, &models.VersionFile{}))
+
+	for name, repos := range map[string]Repositories{"gorm": NewGorm(gormDB), "memory": NewMemory()} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// Modules are created once, with the visibility of the first call
+			module, err := repos.Modules.GetOrCreate(ctx, "acme", "user", models.VisibilityInternal)
+			require.NoError(t, err)
+			again, err := repos.Modules.GetOrCreate(ctx, "acme", "user", models.VisibilityPublic)
+			require.NoError(t, err)
+			assert.Equal(t, module.ID, again.ID)
+			assert.Equal(t, models.VisibilityInternal, again.Visibility)
+			require.NoError(t, repos.Modules.SetVisibility(ctx, module.ID, models.VisibilityPrivate))
+			visibility, err := repos.Modules.Visibility(ctx, "acme", "user")
+			require.NoError(t, err)
+			assert.Equal(t, models.VisibilityPrivate, visibility)
+			require.NoError(t, repos.Modules.Touch(ctx, module.ID))
+
+			// Versions
+			version := models.ModuleVersion{ModuleID: module.ID, Version: "v1.0.0", VersionKey: validation.VersionKey("v1.0.0"), ArtifactDigest: "abc", ArtifactStorageKey: "k"}
+			require.NoError(t, repos.Versions.Create(ctx, &version))
+			assert.NotZero(t, version.ID)
+			for candidate, want := range map[string]string{"v1.0.0": "v1.0.0", "v1.0.0+build.1": "v1.0.0", "v1.1.0": ""} {
+				existing, err := repos.Versions.Conflicting(ctx, "acme", "user", candidate)
+				require.NoError(t, err)
+				assert.Equal(t, want, existing, candidate)
+			}
+			existing, err := repos.Versions.Conflicting(ctx, "acme", "nope", "v1.0.0")
+			require.NoError(t, err)
+			assert.Empty(t, existing)
+
+			percent := 20
+			require.NoError(t, repos.Versions.SetCanary(ctx, version.ID, &percent))
+			versions, err := repos.Versions.ListByModule(ctx, module.ID)
+			require.NoError(t, err)
+			require.Len(t, versions, 1)
+			assert.Equal(t, &percent, versions[0].CanaryPercent)
+			require.NoError(t, repos.Versions.SetCanary(ctx, version.ID, nil))
+			versions, err = repos.Versions.ListByModule(ctx, module.ID)
+			require.NoError(t, err)
+			assert.Nil(t, versions[0].CanaryPercent)
+
+			// Indexing again keeps what's indexed
+			files := []models.VersionFile{{ModuleVersionID: version.ID, Path: "user.proto", Size: 3, Digest: "def"}}
+			for i := 0; i < 2; i++ {
+				require.NoError(t, repos.Modules.IndexPackages(ctx, module.ID, "v1.0.0", []string{"acme.user.v1"}))
+				require.NoError(t, repos.Versions.AddFiles(ctx, files))
+			}
+
+			// Transactions
+			require.NoError(t, repos.Tx.Transaction(ctx, func(tx Repositories) error {
+				_, err := tx.Modules.GetOrCreate(ctx, "acme", "committed", models.VisibilityPublic)
+				return err
+			}))
+			_, err = repos.Modules.Get(ctx, "acme", "committed")
+			assert.NoError(t, err)
+			failed := errors.New("failed")
+			err = repos.Tx.Transaction(ctx, func(tx Repositories) error {
+				_, err := tx.Modules.GetOrCreate(ctx, "acme", "rolled-back", models.VisibilityPublic)
+				require.NoError(t, err)
+				return failed
+			})
+			assert.ErrorIs(t, err, failed)
+			_, err = repos.Modules.Get(ctx, "acme", "rolled-back")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package repo defines the metadata stores used by the API handlers: modules, module versions and token
+// usage. Handlers depend on these interfaces rather than on SQL, so another backend (for example one using
+// CockroachDB specifics) only needs to implement them. NewGorm implements them on the PostgreSQL and SQLite
+// databases of the db package, NewMemory in memory for tests.
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned (wrapped) when the requested record doesn't exist. Use errors.Is to check it.
+var ErrNotFound = errors.New("repo: record not found")
+
+// Sort orders of ModuleRepo.List.
+const (
+	ModuleSortName      = apitypes.ModuleSortName      // By namespace and name (default)
+	ModuleSortUpdatedAt = apitypes.ModuleSortUpdatedAt // Most recently published first
+	ModuleSortDownloads = apitypes.ModuleSortDownloads // Most downloaded first
+)
+
+// ModuleFilter selects and orders the modules returned by ModuleRepo.List. Zero fields don't filter.
+type ModuleFilter struct {
+	Namespace    string     // Only modules of this namespace
+	UpdatedAfter *time.Time // Only modules created or published to after this time
+	HasVersions  *bool      // Only modules with (true) or without (false) versions
+	Sort         string     // ModuleSortName (default), ModuleSortUpdatedAt or ModuleSortDownloads
+}
+
+// ModuleSummary is a module with its latest version, as listed by ModuleRepo.List.
+type ModuleSummary struct {
+	Namespace      string
+	Name           string
+	LatestVersion  string // Latest version by publish time; "" if the module has none
+	LatestSyntaxes string // Syntaxes of the latest version, comma-separated (see models.ModuleVersion.Syntaxes)
+	Visibility     string
+	UpdatedAt      time.Time
+	Downloads      *int64 // Downloads of all versions; only set when sorting by ModuleSortDownloads
+}
+
+// ModuleRepo stores modules.
+type ModuleRepo interface {
+	// List returns the modules matching filter, in its order.
+	List(ctx context.Context, filter ModuleFilter) ([]ModuleSummary, error)
+	// Get returns a module by namespace and name, or an error wrapping ErrNotFound.
+	Get(ctx context.Context, namespace, name string) (*models.Module, error)
+	// Visibility returns the visibility of a module, or "" if it doesn't exist.
+	Visibility(ctx context.Context, namespace, name string) (string, error)
+	// GetOrCreate returns a module by namespace and name, creating it with visibility if it doesn't exist.
+	GetOrCreate(ctx context.Context, namespace, name, visibility string) (*models.Module, error)
+	// SetVisibility changes the visibility of a module.
+	SetVisibility(ctx context.Context, moduleID uuid.UUID, visibility string) error
+	// Touch sets the update time of a module to now, after a version was published to it.
+	Touch(ctx context.Context, moduleID uuid.UUID) error
+	// IndexPackages maps proto packages to the module declaring them in version, replacing the version
+	// recorded for packages the module already declared.
+	IndexPackages(ctx context.Context, moduleID uuid.UUID, version string, packages []string) error
+}
+
+// VersionSummary is a module version as listed by VersionRepo.ListByModule.
+type VersionSummary struct {
+	Version   string
+	Changelog string
+	Syntaxes  string
+	// Rollout percentage if the version is a canary (see models.ModuleVersion.CanaryPercent)
+	CanaryPercent *int
+}
+
+// VersionRepo stores module versions.
+type VersionRepo interface {
+	// Find returns a version by module coordinates and exact version, or an error wrapping ErrNotFound.
+	Find(ctx context.Context, namespace, name, version string) (*models.ModuleVersion, error)
+	// ListByModule returns the versions of a module, most recently published first.
+	ListByModule(ctx context.Context, moduleID uuid.UUID) ([]VersionSummary, error)
+	// Conflicting returns the version of a module that version conflicts with: the same version, or one
+	// differing only in build metadata or letter case (see validation.VersionKey). "" if there's none.
+	Conflicting(ctx context.Context, namespace, name, version string) (string, error)
+	// Create stores a new version, generating its ID if unset.
+	Create(ctx context.Context, version *models.ModuleVersion) error
+	// SetCanary sets the rollout percentage of a canary version, or completes its rollout if percent is nil.
+	SetCanary(ctx context.Context, versionID uuid.UUID, percent *int) error
+	// AddFiles records the files of versions, keeping those already recorded.
+	AddFiles(ctx context.Context, files []models.VersionFile) error
+}
+
+// TokenRepo stores the last use of tokens, by fingerprint.
+type TokenRepo interface {
+	// RecordUses stores the last uses, replacing those stored for the same fingerprints.
+	RecordUses(ctx context.Context, uses []models.TokenUsage) error
+	// LastUses returns the stored last uses of the given fingerprints (those never used are left out).
+	LastUses(ctx context.Context, fingerprints []string) ([]models.TokenUsage, error)
+}
+
+// Transactor runs writes spanning several stores atomically.
+type Transactor interface {
+	// Transaction calls fn with repositories writing in one transaction, committed if fn returns nil and
+	// rolled back if it returns an error or panics.
+	Transaction(ctx context.Context, fn func(tx Repositories) error) error
+}
+
+// Repositories groups the stores of one backend.
+type Repositories struct {
+	Modules  ModuleRepo
+	Versions VersionRepo
+	Tokens   TokenRepo
+	Tx       Transactor
+}
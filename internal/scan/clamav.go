@@ -0,0 +1,131 @@
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the maximum size of a single INSTREAM chunk sent to clamd.
+// clamd rejects chunks larger than its StreamMaxLength, so keep this modest.
+const clamdChunkSize = 64 * 1024
+
+// ClamAVScanner implements the Scanner interface by streaming data to a clamd daemon
+// using the INSTREAM command.
+type ClamAVScanner struct {
+	network string // "tcp" or "unix"
+	address string // host:port or socket path
+	timeout time.Duration
+}
+
+// NewClamAVScanner creates a scanner for the given clamd address.
+// The address may be "tcp://host:port", "unix:///path/to/clamd.sock", or a bare "host:port".
+func NewClamAVScanner(address string, timeout time.Duration) (*ClamAVScanner, error) {
+	network, addr, err := parseClamdAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second // Default timeout for a full scan round trip
+	}
+	return &ClamAVScanner{
+		network: network,
+		address: addr,
+		timeout: timeout,
+	}, nil
+}
+
+// parseClamdAddress splits a clamd address into network and address parts.
+func parseClamdAddress(address string) (string, string, error) {
+	switch {
+	case address == "":
+		return "", "", fmt.Errorf("clamd address cannot be empty")
+	case strings.HasPrefix(address, "tcp://"):
+		return "tcp", strings.TrimPrefix(address, "tcp://"), nil
+	case strings.HasPrefix(address, "unix://"):
+		return "unix", strings.TrimPrefix(address, "unix://"), nil
+	case strings.Contains(address, "://"):
+		return "", "", fmt.Errorf("unsupported clamd address scheme: %s", address)
+	default:
+		return "tcp", address, nil
+	}
+}
+
+// Scan streams the reader's content to clamd and interprets the verdict.
+func (c *ClamAVScanner) Scan(ctx context.Context, reader io.Reader) (Result, error) {
+	dialer := &net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to connect to clamd at %s: %w", c.address, err)
+	}
+	defer conn.Close()
+
+	// Bound the whole exchange by the configured timeout (or the context deadline if sooner)
+	deadline := time.Now().Add(c.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+
+	// 'z' prefix means the command and the reply are NUL terminated
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Result{}, fmt.Errorf("failed to send INSTREAM command to clamd: %w", err)
+	}
+
+	buf := make([]byte, clamdChunkSize)
+	sizeHeader := make([]byte, 4)
+	for {
+		n, readErr := reader.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(sizeHeader, uint32(n))
+			if _, err := conn.Write(sizeHeader); err != nil {
+				return Result{}, fmt.Errorf("failed to send chunk size to clamd: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return Result{}, fmt.Errorf("failed to send chunk to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return Result{}, fmt.Errorf("failed to read data for scanning: %w", readErr)
+		}
+	}
+
+	// A zero-length chunk terminates the stream
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return Result{}, fmt.Errorf("failed to terminate clamd stream: %w", err)
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply interprets a clamd INSTREAM reply such as
+// "stream: OK" or "stream: Eicar-Test-Signature FOUND".
+func parseClamdReply(reply []byte) (Result, error) {
+	text := strings.TrimSpace(string(bytes.TrimRight(reply, "\x00")))
+	text = strings.TrimPrefix(text, "stream:")
+	text = strings.TrimSpace(text)
+
+	switch {
+	case text == "OK":
+		return Result{Clean: true}, nil
+	case strings.HasSuffix(text, " FOUND"):
+		return Result{Clean: false, Signature: strings.TrimSuffix(text, " FOUND")}, nil
+	case strings.HasSuffix(text, " ERROR"):
+		return Result{}, fmt.Errorf("clamd reported an error: %s", strings.TrimSuffix(text, " ERROR"))
+	default:
+		return Result{}, fmt.Errorf("unexpected clamd reply: %q", text)
+	}
+}
@@ -0,0 +1,116 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startFakeClamd starts a TCP listener that speaks enough of the clamd INSTREAM protocol
+// to receive a stream and reply with the result of verdict(streamedData).
+func startFakeClamd(t *testing.T, verdict func(data []byte) string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start fake clamd: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				cmd, err := reader.ReadString(0)
+				if err != nil || cmd != "zINSTREAM\x00" {
+					return
+				}
+				var data []byte
+				sizeBuf := make([]byte, 4)
+				for {
+					if _, err := io.ReadFull(reader, sizeBuf); err != nil {
+						return
+					}
+					size := binary.BigEndian.Uint32(sizeBuf)
+					if size == 0 {
+						break
+					}
+					chunk := make([]byte, size)
+					if _, err := io.ReadFull(reader, chunk); err != nil {
+						return
+					}
+					data = append(data, chunk...)
+				}
+				_, _ = conn.Write([]byte(verdict(data) + "\x00"))
+			}(conn)
+		}
+	}()
+
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestClamAVScanner_Clean(t *testing.T) {
+	addr := startFakeClamd(t, func(data []byte) string { return "stream: OK" })
+
+	s, err := NewClamAVScanner(addr, 5*time.Second)
+	assert.NoError(t, err)
+
+	result, err := s.Scan(context.Background(), strings.NewReader("harmless proto content"))
+	assert.NoError(t, err)
+	assert.True(t, result.Clean)
+	assert.Empty(t, result.Signature)
+}
+
+func TestClamAVScanner_Infected(t *testing.T) {
+	addr := startFakeClamd(t, func(data []byte) string {
+		if strings.Contains(string(data), "EICAR") {
+			return "stream: Eicar-Test-Signature FOUND"
+		}
+		return "stream: OK"
+	})
+
+	s, err := NewClamAVScanner(addr, 5*time.Second)
+	assert.NoError(t, err)
+
+	// Large enough to span multiple INSTREAM chunks
+	payload := strings.Repeat("x", clamdChunkSize*2) + "EICAR"
+	result, err := s.Scan(context.Background(), strings.NewReader(payload))
+	assert.NoError(t, err)
+	assert.False(t, result.Clean)
+	assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+}
+
+func TestClamAVScanner_ErrorReply(t *testing.T) {
+	addr := startFakeClamd(t, func(data []byte) string { return "INSTREAM size limit exceeded. ERROR" })
+
+	s, err := NewClamAVScanner(addr, 5*time.Second)
+	assert.NoError(t, err)
+
+	_, err = s.Scan(context.Background(), strings.NewReader("data"))
+	assert.Error(t, err)
+}
+
+func TestParseClamdAddress(t *testing.T) {
+	network, addr, err := parseClamdAddress("unix:///run/clamd.sock")
+	assert.NoError(t, err)
+	assert.Equal(t, "unix", network)
+	assert.Equal(t, "/run/clamd.sock", addr)
+
+	network, addr, err = parseClamdAddress("clamav:3310")
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "clamav:3310", addr)
+
+	_, _, err = parseClamdAddress("http://clamav:3310")
+	assert.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"go.uber.org/zap"
+)
+
+// Scan status values recorded on module versions.
+const (
+	StatusSkipped  = "skipped"  // No scanner configured
+	StatusClean    = "clean"    // Scanner found nothing
+	StatusInfected = "infected" // Scanner detected a signature (only stored with the "flag" policy)
+	StatusError    = "error"    // Scanner failed (only stored with the "flag" policy)
+)
+
+// Policy values controlling what happens when a scan detects something or fails.
+const (
+	PolicyBlock = "block" // Reject the publish
+	PolicyFlag  = "flag"  // Accept the publish but record the result on the version
+)
+
+// Result holds the outcome of a single scan.
+type Result struct {
+	Clean     bool   // True if no threat was detected
+	Signature string // Name of the detected signature, if any
+}
+
+// Scanner defines the interface for scanning artifact content before it is accepted.
+type Scanner interface {
+	// Scan reads the full content of reader and reports whether it is clean.
+	// An error is returned if the scan could not be completed.
+	Scan(ctx context.Context, reader io.Reader) (Result, error)
+}
+
+// Global scanner instance and policy. scanner is nil when scanning is disabled.
+var (
+	scanner Scanner
+	policy  = PolicyBlock
+)
+
+// InitScanner initializes the virus scanner based on config.
+// Scanning is optional: if CLAMAV_ADDRESS is empty, no scanner is configured and nil is returned.
+func InitScanner(cfg config.Config) (Scanner, error) {
+	p := strings.ToLower(cfg.ClamAVPolicy)
+	if p == "" {
+		p = PolicyBlock
+	}
+	if p != PolicyBlock && p != PolicyFlag {
+		return nil, fmt.Errorf("invalid CLAMAV_POLICY: %s. Must be 'block' or 'flag'", cfg.ClamAVPolicy)
+	}
+	policy = p
+
+	if cfg.ClamAVAddress == "" {
+		logging.L().Info("ClamAV address not configured, artifact virus scanning is disabled.")
+		scanner = nil
+		return nil, nil
+	}
+
+	s, err := NewClamAVScanner(cfg.ClamAVAddress, cfg.ClamAVTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize ClamAV scanner: %w", err)
+	}
+	scanner = s
+
+	logging.L().Info("ClamAV scanning enabled", zap.String("address", cfg.ClamAVAddress), zap.String("policy", policy))
+	return scanner, nil
+}
+
+// GetScanner returns the configured scanner, or nil if scanning is disabled.
+func GetScanner() Scanner {
+	return scanner
+}
+
+// GetPolicy returns the configured detection policy ("block" or "flag").
+func GetPolicy() string {
+	return policy
+}
+
+// SetScanner is a test helper function to replace the global scanner and policy.
+// !! Use only in tests !!
+func SetScanner(s Scanner, p string) {
+	scanner = s
+	policy = p
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"syscall"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Sentinel errors returned (wrapped) by all storage providers, so callers can react to
+// storage failures without knowing which backend is configured. Use errors.Is to check them.
+var (
+	// ErrObjectNotFound is returned when the requested object does not exist.
+	ErrObjectNotFound = errors.New("storage: object not found")
+	// ErrBucketUnavailable is returned when the bucket (or base directory) is missing or the backend cannot be reached.
+	ErrBucketUnavailable = errors.New("storage: bucket unavailable")
+	// ErrQuotaExceeded is returned when the backend refuses a write because it is out of space or over quota.
+	ErrQuotaExceeded = errors.New("storage: quota exceeded")
+	// ErrObjectExists is returned when an upload targets a key that already holds an object.
+	// Objects are write-once: providers never overwrite them.
+	ErrObjectExists = errors.New("storage: object already exists")
+)
+
+// classify wraps err with the given sentinel while keeping the original error in the chain.
+func classify(sentinel, err error) error {
+	return fmt.Errorf("%w: %w", sentinel, err)
+}
+
+// mapMinioError maps MinIO/S3 error responses to the storage sentinel errors.
+// Errors that don't correspond to a sentinel are returned unchanged.
+func mapMinioError(err error) error {
+	if err == nil {
+		return nil
+	}
+	errResponse := minio.ToErrorResponse(err)
+	switch errResponse.Code {
+	case "NoSuchKey", "NoSuchVersion":
+		return classify(ErrObjectNotFound, err)
+	case "NoSuchBucket", "XMinioServerNotInitialized", "ServiceUnavailable":
+		return classify(ErrBucketUnavailable, err)
+	case "XMinioAdminBucketQuotaExceeded", "XMinioStorageFull", "QuotaExceeded":
+		return classify(ErrQuotaExceeded, err)
+	case "PreconditionFailed", "ConditionalRequestConflict":
+		// If-None-Match: * on PUT (see MinioStorage.UploadFile)
+		return classify(ErrObjectExists, err)
+	}
+	switch errResponse.StatusCode {
+	case http.StatusServiceUnavailable:
+		return classify(ErrBucketUnavailable, err)
+	case http.StatusInsufficientStorage:
+		return classify(ErrQuotaExceeded, err)
+	case http.StatusPreconditionFailed:
+		return classify(ErrObjectExists, err)
+	}
+	return err
+}
+
+// mapLocalError maps filesystem errors to the storage sentinel errors.
+// Errors that don't correspond to a sentinel are returned unchanged.
+func mapLocalError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, os.ErrExist):
+		return classify(ErrObjectExists, err)
+	case errors.Is(err, os.ErrNotExist):
+		return classify(ErrObjectNotFound, err)
+	case errors.Is(err, os.ErrPermission), errors.Is(err, syscall.EROFS):
+		// The storage directory can't be written to (wrong permissions, read-only mount)
+		return classify(ErrBucketUnavailable, err)
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT):
+		return classify(ErrQuotaExceeded, err)
+	}
+	return err
+}
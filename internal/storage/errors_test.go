@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapMinioError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{"missing key", minio.ErrorResponse{Code: "NoSuchKey", StatusCode: http.StatusNotFound}, ErrObjectNotFound},
+		{"missing bucket", minio.ErrorResponse{Code: "NoSuchBucket", StatusCode: http.StatusNotFound}, ErrBucketUnavailable},
+		{"service unavailable", minio.ErrorResponse{Code: "SomethingElse", StatusCode: http.StatusServiceUnavailable}, ErrBucketUnavailable},
+		{"bucket quota", minio.ErrorResponse{Code: "XMinioAdminBucketQuotaExceeded", StatusCode: http.StatusBadRequest}, ErrQuotaExceeded},
+		{"disk full", minio.ErrorResponse{Code: "XMinioStorageFull", StatusCode: http.StatusInsufficientStorage}, ErrQuotaExceeded},
+		{"key taken", minio.ErrorResponse{Code: "PreconditionFailed", StatusCode: http.StatusPreconditionFailed}, ErrObjectExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapped := mapMinioError(tt.err)
+			assert.ErrorIs(t, mapped, tt.expected)
+			// The original error stays in the chain for logging/inspection
+			var errResponse minio.ErrorResponse
+			assert.True(t, errors.As(mapped, &errResponse))
+		})
+	}
+
+	// Unrelated errors are passed through unchanged
+	other := errors.New("boom")
+	assert.Equal(t, other, mapMinioError(other))
+	assert.NoError(t, mapMinioError(nil))
+}
+
+func TestLocalStorage_DownloadMissingObject(t *testing.T) {
+	local, err := NewLocalStorage(config.Config{LocalStoragePath: t.TempDir()})
+	require.NoError(t, err)
+
+	_, err = local.DownloadFile(context.Background(), "modules/missing/protos.zip")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	exists, err := local.FileExists(context.Background(), "modules/missing/protos.zip")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestLocalStorage_UploadIsWriteOnce(t *testing.T) {
+	dir := t.TempDir()
+	local, err := NewLocalStorage(config.Config{LocalStoragePath: dir})
+	require.NoError(t, err)
+	ctx := context.Background()
+	key := "v2/modules/acme/user/v1.0.0/abc.zip"
+
+	require.NoError(t, local.UploadFile(ctx, key, strings.NewReader("original"), 8, "application/zip"))
+	err = local.UploadFile(ctx, key, strings.NewReader("replacement"), 11, "application/zip")
+	assert.ErrorIs(t, err, ErrObjectExists)
+
+	// The stored object is untouched and no temporary files are left behind
+	reader, err := local.DownloadFile(ctx, key)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(content))
+	entries, err := os.ReadDir(filepath.Join(dir, "v2/modules/acme/user/v1.0.0"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	digest, err := ObjectDigest(ctx, local, key)
+	require.NoError(t, err)
+	assert.Equal(t, "0682c5f2076f099c34cfdd15a9e063849ed437a49677e6fcc5b4198c76575be5", digest) // sha256("original")
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/metrics"
+)
+
+// instrumentedProvider records the latency and outcome of every call to the wrapped provider in
+// metrics.StorageOperationDuration, so slow publishes can be attributed to storage (or not).
+type instrumentedProvider struct {
+	StorageProvider
+	name string // Provider label: "minio" or "local"
+}
+
+// observe records an operation that started at start and returned err.
+func (p *instrumentedProvider) observe(operation string, start time.Time, err error) {
+	metrics.StorageOperationDuration.WithLabelValues(p.name, operation, operationOutcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// operationOutcome returns the outcome label of a storage error.
+func operationOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrObjectNotFound):
+		return "not_found"
+	case errors.Is(err, ErrObjectExists):
+		return "exists"
+	case errors.Is(err, ErrTieringUnsupported), errors.Is(err, ErrStatsUnsupported):
+		return "unsupported"
+	default:
+		return "error"
+	}
+}
+
+func (p *instrumentedProvider) UploadFile(ctx context.Context, objectName string, reader io.Reader, size int64, contentType string) error {
+	start := time.Now()
+	err := p.StorageProvider.UploadFile(ctx, objectName, reader, size, contentType)
+	p.observe("upload", start, err)
+	return err
+}
+
+func (p *instrumentedProvider) DownloadFile(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	start := time.Now()
+	reader, err := p.StorageProvider.DownloadFile(ctx, objectName)
+	p.observe("download", start, err)
+	return reader, err
+}
+
+func (p *instrumentedProvider) DeleteFile(ctx context.Context, objectName string) error {
+	start := time.Now()
+	err := p.StorageProvider.DeleteFile(ctx, objectName)
+	p.observe("delete", start, err)
+	return err
+}
+
+func (p *instrumentedProvider) TransitionFile(ctx context.Context, objectName string) error {
+	start := time.Now()
+	err := TransitionFile(ctx, p.StorageProvider, objectName)
+	p.observe("transition", start, err)
+	return err
+}
+
+func (p *instrumentedProvider) FileExists(ctx context.Context, objectName string) (bool, error) {
+	start := time.Now()
+	exists, err := p.StorageProvider.FileExists(ctx, objectName)
+	p.observe("exists", start, err)
+	return exists, err
+}
+
+func (p *instrumentedProvider) Stats(ctx context.Context) (BucketStats, error) {
+	start := time.Now()
+	stats, err := Stats(ctx, p.StorageProvider)
+	p.observe("stats", start, err)
+	return stats, err
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// operationCount returns the number of local storage operations observed with the given labels.
+func operationCount(t *testing.T, operation, outcome string) uint64 {
+	var m dto.Metric
+	require.NoError(t, metrics.StorageOperationDuration.WithLabelValues("local", operation, outcome).(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestInstrumentedProvider(t *testing.T) {
+	local, err := NewLocalStorage(config.Config{LocalStoragePath: t.TempDir()})
+	require.NoError(t, err)
+	p := &instrumentedProvider{StorageProvider: local, name: "local"}
+	ctx := context.Background()
+
+	metrics.StorageOperationDuration.Reset()
+	require.NoError(t, p.UploadFile(ctx, "a.zip", strings.NewReader("data"), 4, "application/zip"))
+	assert.ErrorIs(t, p.UploadFile(ctx, "a.zip", strings.NewReader("data"), 4, "application/zip"), ErrObjectExists)
+	_, err = p.DownloadFile(ctx, "missing.zip")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+	exists, err := p.FileExists(ctx, "a.zip")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.Equal(t, uint64(1), operationCount(t, "upload", "ok"))
+	assert.Equal(t, uint64(1), operationCount(t, "upload", "exists"))
+	assert.Equal(t, uint64(1), operationCount(t, "download", "not_found"))
+	assert.Equal(t, uint64(1), operationCount(t, "exists", "ok"))
+}
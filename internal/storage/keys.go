@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"fmt"
+	"path"
+)
+
+// Storage key layouts. The layout used for each object is recorded on its module version
+// (artifact_key_layout), so objects stored under older layouts stay readable and can be
+// migrated in place with `sproto-server migrate-storage`.
+const (
+	// KeyLayoutV1 is the original layout: modules/<module_uuid>/<version>/protos.zip
+	KeyLayoutV1 = 1
+	// KeyLayoutV2 is human-readable and digest-addressed: v2/modules/<namespace>/<name>/<version>/<sha256>.zip
+	KeyLayoutV2 = 2
+
+	// CurrentKeyLayout is the layout used for newly published artifacts.
+	CurrentKeyLayout = KeyLayoutV2
+)
+
+// ModuleVersionKey returns the storage key for a module version artifact in the current layout.
+// digestHex is the hex-encoded SHA256 digest of the artifact.
+func ModuleVersionKey(namespace, moduleName, version, digestHex string) string {
+	return path.Join("v2", "modules", namespace, moduleName, version, fmt.Sprintf("%s.zip", digestHex))
+}
+
+// OriginalUploadKey returns the storage key for the original bytes of an artifact that was re-packed into
+// canonical form on publish: v2/originals/<sha256>.zip. Keys only depend on the content, so versions
+// published with identical uploads share the object.
+func OriginalUploadKey(digestHex string) string {
+	return path.Join("v2", "originals", fmt.Sprintf("%s.zip", digestHex))
+}
+
+// DevChannelKey returns the storage key for the artifact of a module's dev channel (e.g. "dev-alice"):
+// v2/dev/<namespace>/<name>/<channel>/<sha256>.zip. Dev channels are mutable, so each artifact they had
+// gets its own digest-addressed object; the previous one is deleted when it is replaced.
+func DevChannelKey(namespace, moduleName, channel, digestHex string) string {
+	return path.Join("v2", "dev", namespace, moduleName, channel, fmt.Sprintf("%s.zip", digestHex))
+}
+
+// VersionArtifactKey returns the storage key for a secondary artifact of a module version, attached under
+// classifier: v2/modules/<namespace>/<name>/<version>/artifacts/<classifier>/<sha256>. Secondary artifacts
+// can have any format, so the key has no extension.
+func VersionArtifactKey(namespace, moduleName, version, classifier, digestHex string) string {
+	return path.Join("v2", "modules", namespace, moduleName, version, "artifacts", classifier, digestHex)
+}
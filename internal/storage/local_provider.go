@@ -4,16 +4,20 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 
 	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"go.uber.org/zap"
 )
 
 // LocalStorage implements the StorageProvider interface using the local filesystem.
 type LocalStorage struct {
 	basePath string
+	// Directory of the cold tier (LOCAL_COLD_STORAGE_PATH), e.g. on a cheaper disk; empty disables tiering.
+	// Objects live in one of the two directories under the same relative path, and are read from either.
+	coldPath string
 }
 
 // NewLocalStorage creates and initializes a new LocalStorage provider.
@@ -27,20 +31,45 @@ func NewLocalStorage(cfg config.Config) (*LocalStorage, error) {
 	// Ensure the base directory exists
 	err := os.MkdirAll(basePath, 0755) // rwxr-xr-x permissions
 	if err != nil {
-		log.Printf("Failed to create local storage directory '%s': %v", basePath, err)
+		logging.L().Error("Failed to create local storage directory", zap.String("path", basePath), zap.Error(err))
 		return nil, fmt.Errorf("failed to create local storage directory: %w", err)
 	}
 
-	log.Printf("Local storage initialized at path: %s", basePath)
+	coldPath := cfg.LocalColdStoragePath
+	if coldPath != "" {
+		if filepath.Clean(coldPath) == filepath.Clean(basePath) {
+			return nil, fmt.Errorf("local cold storage path must differ from the local storage path")
+		}
+		if err := os.MkdirAll(coldPath, 0755); err != nil {
+			logging.L().Error("Failed to create local cold storage directory", zap.String("path", coldPath), zap.Error(err))
+			return nil, fmt.Errorf("failed to create local cold storage directory: %w", err)
+		}
+	}
+
+	logging.L().Info("Local storage initialized", zap.String("path", basePath), zap.String("cold_path", coldPath))
 
 	return &LocalStorage{
 		basePath: basePath,
+		coldPath: coldPath,
 	}, nil
 }
 
 // getFullPath resolves the absolute path for a given object name within the storage base path.
 // It also ensures the necessary subdirectories are created.
 func (l *LocalStorage) getFullPath(objectName string) (string, error) {
+	return resolvePath(l.basePath, objectName)
+}
+
+// getColdPath is getFullPath for the cold tier. Returns "" if there is none.
+func (l *LocalStorage) getColdPath(objectName string) (string, error) {
+	if l.coldPath == "" {
+		return "", nil
+	}
+	return resolvePath(l.coldPath, objectName)
+}
+
+// resolvePath resolves objectName within basePath, creating its directory.
+func resolvePath(basePath, objectName string) (string, error) {
 	// Clean the objectName to prevent path traversal issues (e.g., "../..")
 	// Note: filepath.Join also helps clean paths.
 	cleanObjectName := filepath.Clean(objectName)
@@ -52,19 +81,22 @@ func (l *LocalStorage) getFullPath(objectName string) (string, error) {
 		return "", fmt.Errorf("object name cannot be an absolute path: %s", objectName)
 	}
 
-	fullPath := filepath.Join(l.basePath, cleanObjectName)
+	fullPath := filepath.Join(basePath, cleanObjectName)
 
 	// Ensure the directory for the file exists
 	dir := filepath.Dir(fullPath)
 	err := os.MkdirAll(dir, 0755)
 	if err != nil {
-		return "", fmt.Errorf("failed to create directory structure for %s: %w", fullPath, err)
+		return "", fmt.Errorf("failed to create directory structure for %s: %w", fullPath, mapLocalError(err))
 	}
 
 	return fullPath, nil
 }
 
-// UploadFile saves data to the local filesystem.
+// UploadFile saves data to the local filesystem without overwriting an existing file.
+// The data is written to a temporary file next to the destination, which is then hard-linked into
+// place: linking fails if the destination exists, so concurrent uploads can't replace each other and
+// readers never see a partially written file.
 func (l *LocalStorage) UploadFile(ctx context.Context, objectName string, reader io.Reader, size int64, contentType string) error {
 	// Note: size and contentType are ignored in this basic local implementation,
 	// but kept for interface compatibility.
@@ -72,22 +104,35 @@ func (l *LocalStorage) UploadFile(ctx context.Context, objectName string, reader
 	if err != nil {
 		return err
 	}
+	if _, err := os.Stat(fullPath); err == nil {
+		return fmt.Errorf("failed to create local file %s: %w", fullPath, ErrObjectExists)
+	}
+	if coldPath, err := l.getColdPath(objectName); err != nil {
+		return err
+	} else if coldPath != "" {
+		if _, err := os.Stat(coldPath); err == nil {
+			return fmt.Errorf("failed to create local file %s: %w", fullPath, ErrObjectExists)
+		}
+	}
 
-	// Create the destination file
-	file, err := os.Create(fullPath)
+	// Write to a temporary file in the same directory (hard links can't cross filesystems)
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
 	if err != nil {
-		return fmt.Errorf("failed to create local file %s: %w", fullPath, err)
+		return fmt.Errorf("failed to create local file %s: %w", fullPath, mapLocalError(err))
 	}
-	defer file.Close() // Ensure file is closed
+	defer os.Remove(tmp.Name()) // Only the link remains once it succeeds
 
-	// Copy data from reader to file
-	_, err = io.Copy(file, reader)
+	_, err = io.Copy(tmp, reader)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		// Attempt to remove partially written file on error
-		_ = os.Remove(fullPath)
-		return fmt.Errorf("failed to write data to local file %s: %w", fullPath, err)
+		return fmt.Errorf("failed to write data to local file %s: %w", fullPath, mapLocalError(err))
 	}
 
+	if err := os.Link(tmp.Name(), fullPath); err != nil {
+		return fmt.Errorf("failed to create local file %s: %w", fullPath, mapLocalError(err))
+	}
 	return nil
 }
 
@@ -102,7 +147,15 @@ func (l *LocalStorage) DownloadFile(ctx context.Context, objectName string) (io.
 
 	// Check if file exists before opening (getFullPath only ensures directory)
 	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("object %s not found locally: %w", objectName, os.ErrNotExist)
+		// Moved to the cold tier?
+		coldPath, coldErr := l.getColdPath(objectName)
+		if coldErr != nil || coldPath == "" {
+			return nil, fmt.Errorf("object %s not found locally: %w", objectName, mapLocalError(err))
+		}
+		if _, err := os.Stat(coldPath); err != nil {
+			return nil, fmt.Errorf("object %s not found locally: %w", objectName, mapLocalError(err))
+		}
+		fullPath = coldPath
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to stat local file %s: %w", fullPath, err)
 	}
@@ -111,7 +164,7 @@ func (l *LocalStorage) DownloadFile(ctx context.Context, objectName string) (io.
 	if err != nil {
 		// This check might be redundant given the Stat check above, but handles race conditions or permission issues.
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("object %s not found locally: %w", objectName, err)
+			return nil, fmt.Errorf("object %s not found locally: %w", objectName, mapLocalError(err))
 		}
 		return nil, fmt.Errorf("failed to open local file %s: %w", fullPath, err)
 	}
@@ -128,13 +181,17 @@ func (l *LocalStorage) DeleteFile(ctx context.Context, objectName string) error
 		return err
 	}
 
-	err = os.Remove(fullPath)
-	if err != nil {
+	paths := []string{fullPath}
+	if coldPath, err := l.getColdPath(objectName); err != nil {
+		return err
+	} else if coldPath != "" {
+		paths = append(paths, coldPath)
+	}
+	for _, path := range paths {
 		// If the file doesn't exist, treat it as success (idempotent delete)
-		if os.IsNotExist(err) {
-			return nil
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove local file %s: %w", path, err)
 		}
-		return fmt.Errorf("failed to remove local file %s: %w", fullPath, err)
 	}
 
 	// Optional: Clean up empty parent directories? (Could be complex/risky)
@@ -155,8 +212,70 @@ func (l *LocalStorage) FileExists(ctx context.Context, objectName string) (bool,
 		return true, nil // File exists
 	}
 	if os.IsNotExist(err) {
+		coldPath, err := l.getColdPath(objectName)
+		if err != nil || coldPath == "" {
+			return false, err // File does not exist
+		}
+		if _, err := os.Stat(coldPath); err == nil {
+			return true, nil // Moved to the cold tier
+		} else if !os.IsNotExist(err) {
+			return false, fmt.Errorf("failed to stat local file %s: %w", coldPath, err)
+		}
 		return false, nil // File does not exist
 	}
 	// Some other error occurred
 	return false, fmt.Errorf("failed to stat local file %s: %w", fullPath, err)
 }
+
+// TransitionFile moves a file to the cold storage directory. It is copied (the directories may be on
+// different disks) and synced before the hot copy is removed, so the object is readable throughout.
+func (l *LocalStorage) TransitionFile(ctx context.Context, objectName string) error {
+	coldPath, err := l.getColdPath(objectName)
+	if err != nil {
+		return err
+	}
+	if coldPath == "" {
+		return ErrTieringUnsupported
+	}
+	fullPath, err := l.getFullPath(objectName)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(fullPath)
+	if os.IsNotExist(err) {
+		if _, coldErr := os.Stat(coldPath); coldErr == nil {
+			return nil // Already cold
+		}
+		return fmt.Errorf("object %s not found locally: %w", objectName, mapLocalError(err))
+	} else if err != nil {
+		return fmt.Errorf("failed to open local file %s: %w", fullPath, mapLocalError(err))
+	}
+	defer src.Close()
+
+	// Same approach as UploadFile: a temporary file linked into place
+	tmp, err := os.CreateTemp(filepath.Dir(coldPath), ".transition-*")
+	if err != nil {
+		return fmt.Errorf("failed to create local file %s: %w", coldPath, mapLocalError(err))
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, src)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write data to local file %s: %w", coldPath, mapLocalError(err))
+	}
+	if err := os.Link(tmp.Name(), coldPath); err != nil && !os.IsExist(err) {
+		// An existing cold copy was left by an interrupted transition: objects are write-once, so it's the same
+		return fmt.Errorf("failed to create local file %s: %w", coldPath, mapLocalError(err))
+	}
+
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove local file %s: %w", fullPath, err)
+	}
+	return nil
+}
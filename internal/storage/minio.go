@@ -3,11 +3,12 @@ package storage
 import (
 	"context"
 	"fmt"
-	"log" // Using standard log for simplicity
 
 	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/logging"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.uber.org/zap"
 )
 
 // MinioClient is the global MinIO client instance
@@ -24,30 +25,30 @@ func InitMinio(cfg config.Config) (*minio.Client, error) {
 		Secure: cfg.MinioUseSSL,
 	})
 	if err != nil {
-		log.Printf("Failed to initialize MinIO client: %v", err)
+		logging.L().Error("Failed to initialize MinIO client", zap.Error(err))
 		return nil, fmt.Errorf("failed to initialize MinIO client: %w", err)
 	}
 
-	log.Printf("MinIO client initialized for endpoint: %s", cfg.MinioEndpoint)
+	logging.L().Info("MinIO client initialized", zap.String("endpoint", cfg.MinioEndpoint))
 
 	// Check if the bucket already exists.
 	exists, err := MinioClient.BucketExists(ctx, cfg.MinioBucket)
 	if err != nil {
-		log.Printf("Failed to check if MinIO bucket '%s' exists: %v", cfg.MinioBucket, err)
+		logging.L().Error("Failed to check if MinIO bucket exists", zap.String("bucket", cfg.MinioBucket), zap.Error(err))
 		return nil, fmt.Errorf("failed to check MinIO bucket existence: %w", err)
 	}
 
 	if !exists {
 		// Create the bucket if it does not exist.
-		log.Printf("MinIO bucket '%s' does not exist. Creating...", cfg.MinioBucket)
+		logging.L().Info("MinIO bucket does not exist. Creating...", zap.String("bucket", cfg.MinioBucket))
 		err = MinioClient.MakeBucket(ctx, cfg.MinioBucket, minio.MakeBucketOptions{}) // Use default region
 		if err != nil {
-			log.Printf("Failed to create MinIO bucket '%s': %v", cfg.MinioBucket, err)
+			logging.L().Error("Failed to create MinIO bucket", zap.String("bucket", cfg.MinioBucket), zap.Error(err))
 			return nil, fmt.Errorf("failed to create MinIO bucket: %w", err)
 		}
-		log.Printf("Successfully created MinIO bucket '%s'", cfg.MinioBucket)
+		logging.L().Info("Successfully created MinIO bucket", zap.String("bucket", cfg.MinioBucket))
 	} else {
-		log.Printf("MinIO bucket '%s' already exists.", cfg.MinioBucket)
+		logging.L().Info("MinIO bucket already exists", zap.String("bucket", cfg.MinioBucket))
 	}
 
 	return MinioClient, nil
@@ -57,7 +58,7 @@ func InitMinio(cfg config.Config) (*minio.Client, error) {
 // Panics if InitMinio has not been called successfully.
 func GetMinioClient() *minio.Client {
 	if MinioClient == nil {
-		log.Fatal("MinIO client has not been initialized. Call storage.InitMinio first.")
+		logging.L().Fatal("MinIO client has not been initialized. Call storage.InitMinio first.")
 	}
 	return MinioClient
 }
@@ -2,87 +2,149 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 
 	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/logging"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"go.uber.org/zap"
+)
+
+// Bounds of the multipart part size imposed by S3 (and MinIO).
+const (
+	minMultipartPartSize = 5 << 20 // 5 MiB
+	maxMultipartPartSize = 5 << 30 // 5 GiB
 )
 
 // MinioStorage implements the StorageProvider interface using MinIO.
 type MinioStorage struct {
 	client *minio.Client
 	bucket string
+
+	// Multipart uploads (MINIO_PART_SIZE_BYTES, MINIO_UPLOAD_CONCURRENCY): objects larger than partSize are
+	// uploaded in parts of partSize, up to concurrency at a time.
+	partSize    uint64
+	concurrency uint
+
+	// Storage classes (MINIO_STORAGE_CLASS, MINIO_COLD_STORAGE_CLASS) of new objects and of objects moved to
+	// the cold tier; empty uses the bucket's default, and no cold class disables tiering
+	storageClass     string
+	coldStorageClass string
 }
 
 // NewMinioStorage creates and initializes a new MinioStorage provider.
 func NewMinioStorage(cfg config.Config) (*MinioStorage, error) {
 	ctx := context.Background()
 
+	if cfg.MinioPartSizeBytes < minMultipartPartSize || cfg.MinioPartSizeBytes > maxMultipartPartSize {
+		return nil, fmt.Errorf("invalid MINIO_PART_SIZE_BYTES %d: must be between %d (5 MiB) and %d (5 GiB)", cfg.MinioPartSizeBytes, minMultipartPartSize, maxMultipartPartSize)
+	}
+	if cfg.MinioUploadConcurrency < 1 {
+		return nil, fmt.Errorf("invalid MINIO_UPLOAD_CONCURRENCY %d: must be at least 1", cfg.MinioUploadConcurrency)
+	}
+	if err := validateStorageClass("MINIO_STORAGE_CLASS", cfg.MinioStorageClass); err != nil {
+		return nil, err
+	}
+	if err := validateStorageClass("MINIO_COLD_STORAGE_CLASS", cfg.MinioColdStorageClass); err != nil {
+		return nil, err
+	}
+
 	// Initialize minio client object.
 	minioClient, err := minio.New(cfg.MinioEndpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(cfg.MinioAccessKey, cfg.MinioSecretKey, ""),
 		Secure: cfg.MinioUseSSL,
 	})
 	if err != nil {
-		log.Printf("Failed to initialize MinIO client: %v", err)
+		logging.L().Error("Failed to initialize MinIO client", zap.Error(err))
 		return nil, fmt.Errorf("failed to initialize MinIO client: %w", err)
 	}
 
-	log.Printf("MinIO client initialized for endpoint: %s", cfg.MinioEndpoint)
+	logging.L().Info("MinIO client initialized", zap.String("endpoint", cfg.MinioEndpoint))
 
 	// Check if the bucket already exists.
 	exists, err := minioClient.BucketExists(ctx, cfg.MinioBucket)
 	if err != nil {
-		log.Printf("Failed to check if MinIO bucket '%s' exists: %v", cfg.MinioBucket, err)
+		logging.L().Error("Failed to check if MinIO bucket exists", zap.String("bucket", cfg.MinioBucket), zap.Error(err))
 		return nil, fmt.Errorf("failed to check MinIO bucket existence: %w", err)
 	}
 
 	if !exists {
 		// Create the bucket if it does not exist.
-		log.Printf("MinIO bucket '%s' does not exist. Creating...", cfg.MinioBucket)
+		logging.L().Info("MinIO bucket does not exist. Creating...", zap.String("bucket", cfg.MinioBucket))
 		err = minioClient.MakeBucket(ctx, cfg.MinioBucket, minio.MakeBucketOptions{}) // Use default region
 		if err != nil {
-			log.Printf("Failed to create MinIO bucket '%s': %v", cfg.MinioBucket, err)
+			logging.L().Error("Failed to create MinIO bucket", zap.String("bucket", cfg.MinioBucket), zap.Error(err))
 			return nil, fmt.Errorf("failed to create MinIO bucket: %w", err)
 		}
-		log.Printf("Successfully created MinIO bucket '%s'", cfg.MinioBucket)
+		logging.L().Info("Successfully created MinIO bucket", zap.String("bucket", cfg.MinioBucket))
 	} else {
-		log.Printf("MinIO bucket '%s' already exists.", cfg.MinioBucket)
+		logging.L().Info("MinIO bucket already exists", zap.String("bucket", cfg.MinioBucket))
 	}
 
 	return &MinioStorage{
-		client: minioClient,
-		bucket: cfg.MinioBucket,
+		client:      minioClient,
+		bucket:      cfg.MinioBucket,
+		partSize:    uint64(cfg.MinioPartSizeBytes),
+		concurrency: uint(cfg.MinioUploadConcurrency),
+
+		storageClass:     cfg.MinioStorageClass,
+		coldStorageClass: cfg.MinioColdStorageClass,
 	}, nil
 }
 
-// UploadFile uploads data to MinIO.
+// UploadFile uploads data to MinIO without overwriting an existing object.
+// The existence check avoids sending the body for keys that are obviously taken; the If-None-Match: *
+// precondition makes the server reject the write if the key was created in the meantime.
 func (m *MinioStorage) UploadFile(ctx context.Context, objectName string, reader io.Reader, size int64, contentType string) error {
+	exists, err := m.FileExists(ctx, objectName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("failed to upload object %s to minio: %w", objectName, ErrObjectExists)
+	}
+
+	opts := m.putOptions(reader, contentType)
+	opts.SetMatchETagExcept("*") // If-None-Match: *, checked when the (multipart) upload completes
+	_, err = m.client.PutObject(ctx, m.bucket, objectName, reader, size, opts)
+	if err != nil {
+		return fmt.Errorf("failed to upload object %s to minio: %w", objectName, mapMinioError(err))
+	}
+	return nil
+}
+
+// putOptions returns the options uploading reader. Objects up to the part size are sent with a single
+// PutObject; larger ones as a multipart upload with up to m.concurrency parts in flight. Parts are read
+// in parallel from seekable readers (such as the spooled publish upload); other readers (such as a
+// storage migration's download stream) are buffered part by part, using up to concurrency*partSize memory.
+func (m *MinioStorage) putOptions(reader io.Reader, contentType string) minio.PutObjectOptions {
 	opts := minio.PutObjectOptions{
 		ContentType: contentType,
+		PartSize:    m.partSize,
+		NumThreads:  m.concurrency,
 		// Consider adding UserMetadata if needed
+		StorageClass: m.storageClass,
 	}
-	_, err := m.client.PutObject(ctx, m.bucket, objectName, reader, size, opts)
-	if err != nil {
-		return fmt.Errorf("failed to upload object %s to minio: %w", objectName, err)
+	if _, seekable := reader.(io.ReaderAt); !seekable && m.concurrency > 1 {
+		opts.ConcurrentStreamParts = true
 	}
-	return nil
+	return opts
 }
 
 // DownloadFile retrieves a file from MinIO.
 func (m *MinioStorage) DownloadFile(ctx context.Context, objectName string) (io.ReadCloser, error) {
 	object, err := m.client.GetObject(ctx, m.bucket, objectName, minio.GetObjectOptions{})
 	if err != nil {
-		// Check if the error is 'object not found'
-		errResponse := minio.ToErrorResponse(err)
-		if errResponse.Code == "NoSuchKey" {
-			// Consider returning a more specific error type like os.ErrNotExist
-			return nil, fmt.Errorf("object %s not found in minio: %w", objectName, err)
-		}
-		return nil, fmt.Errorf("failed to get object %s from minio: %w", objectName, err)
+		return nil, fmt.Errorf("failed to get object %s from minio: %w", objectName, mapMinioError(err))
+	}
+	// GetObject is lazy and only reports errors (such as a missing key) on first read.
+	// Stat eagerly so callers can distinguish "not found" before streaming starts.
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, fmt.Errorf("failed to stat object %s in minio: %w", objectName, mapMinioError(err))
 	}
 	// The caller is responsible for closing the object reader.
 	return object, nil
@@ -93,7 +155,7 @@ func (m *MinioStorage) DeleteFile(ctx context.Context, objectName string) error
 	opts := minio.RemoveObjectOptions{}
 	err := m.client.RemoveObject(ctx, m.bucket, objectName, opts)
 	if err != nil {
-		return fmt.Errorf("failed to remove object %s from minio: %w", objectName, err)
+		return fmt.Errorf("failed to remove object %s from minio: %w", objectName, mapMinioError(err))
 	}
 	return nil
 }
@@ -102,8 +164,8 @@ func (m *MinioStorage) DeleteFile(ctx context.Context, objectName string) error
 func (m *MinioStorage) FileExists(ctx context.Context, objectName string) (bool, error) {
 	_, err := m.client.StatObject(ctx, m.bucket, objectName, minio.StatObjectOptions{})
 	if err != nil {
-		errResponse := minio.ToErrorResponse(err)
-		if errResponse.Code == "NoSuchKey" {
+		err = mapMinioError(err)
+		if errors.Is(err, ErrObjectNotFound) {
 			return false, nil // Object does not exist
 		}
 		// Some other error occurred
@@ -111,3 +173,32 @@ func (m *MinioStorage) FileExists(ctx context.Context, objectName string) (bool,
 	}
 	return true, nil // Object exists
 }
+
+// TransitionFile moves an object to the cold storage class by copying it onto itself with the new class
+// (S3 has no other way to change the class of an object). The content type and metadata are kept, and
+// the copy only applies if the object is unchanged since it was inspected.
+func (m *MinioStorage) TransitionFile(ctx context.Context, objectName string) error {
+	if m.coldStorageClass == "" {
+		return ErrTieringUnsupported
+	}
+	info, err := m.client.StatObject(ctx, m.bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to stat object %s in minio: %w", objectName, mapMinioError(err))
+	}
+	if info.StorageClass == m.coldStorageClass {
+		return nil // Already cold
+	}
+
+	// Metadata is replaced as a whole, so the existing values are carried over
+	metadata := map[string]string{"Content-Type": info.ContentType, "X-Amz-Storage-Class": m.coldStorageClass}
+	for k, v := range info.UserMetadata {
+		metadata["X-Amz-Meta-"+k] = v
+	}
+	dst := minio.CopyDestOptions{Bucket: m.bucket, Object: objectName, UserMetadata: metadata, ReplaceMetadata: true}
+	src := minio.CopySrcOptions{Bucket: m.bucket, Object: objectName, MatchETag: info.ETag}
+	// ComposeObject copies objects over 5 GiB (the limit of a single copy) in parts
+	if _, err := m.client.ComposeObject(ctx, dst, src); err != nil {
+		return fmt.Errorf("failed to move object %s to storage class %s: %w", objectName, m.coldStorageClass, mapMinioError(err))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMinioStorage_ValidatesMultipartSettings(t *testing.T) {
+	cfg := config.Config{MinioEndpoint: "localhost:9000", MinioBucket: "b", MinioPartSizeBytes: 1 << 20, MinioUploadConcurrency: 4}
+	_, err := NewMinioStorage(cfg)
+	assert.ErrorContains(t, err, "MINIO_PART_SIZE_BYTES")
+
+	cfg.MinioPartSizeBytes, cfg.MinioUploadConcurrency = 16<<20, 0
+	_, err = NewMinioStorage(cfg)
+	assert.ErrorContains(t, err, "MINIO_UPLOAD_CONCURRENCY")
+}
+
+func TestMinioStorage_PutOptions(t *testing.T) {
+	m := &MinioStorage{partSize: 16 << 20, concurrency: 4}
+
+	// Seekable readers are read in parallel by offset; streams are buffered part by part
+	opts := m.putOptions(bytes.NewReader(nil), "application/zip")
+	assert.Equal(t, uint64(16<<20), opts.PartSize)
+	assert.Equal(t, uint(4), opts.NumThreads)
+	assert.Equal(t, "application/zip", opts.ContentType)
+	assert.False(t, opts.ConcurrentStreamParts)
+	assert.True(t, m.putOptions(io.TeeReader(strings.NewReader(""), io.Discard), "application/zip").ConcurrentStreamParts)
+
+	// Sequential uploads don't need the buffers
+	m.concurrency = 1
+	assert.False(t, m.putOptions(io.TeeReader(strings.NewReader(""), io.Discard), "application/zip").ConcurrentStreamParts)
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Self-test steps, in the order they run.
+const (
+	SelfTestWrite  = "write"
+	SelfTestStat   = "stat"
+	SelfTestRead   = "read"
+	SelfTestDelete = "delete"
+)
+
+// SelfTestStep is the outcome of one step of a self-test. Skipped steps (after a failed write) have no
+// duration and no error.
+type SelfTestStep struct {
+	Name     string
+	Duration time.Duration
+	Skipped  bool
+	Err      error
+}
+
+// SelfTestResult is the outcome of a self-test: the probe object and every step.
+type SelfTestResult struct {
+	Key   string
+	Size  int64
+	Steps []SelfTestStep
+}
+
+// OK reports whether every step succeeded.
+func (r SelfTestResult) OK() bool {
+	for _, step := range r.Steps {
+		if step.Skipped || step.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// SelfTestKey returns the storage key of a self-test probe object: v2/selftest/<id>. Probes are deleted
+// by the self-test; one left behind by a failed delete can be removed by hand.
+func SelfTestKey(id string) string {
+	return path.Join("v2", "selftest", id)
+}
+
+// SelfTest writes a probe object of size random bytes to p, checks that it exists, reads it back and
+// compares its content, then deletes it, timing each step. It exercises the permissions publishing and
+// fetching need (put, head, get, delete). Once the write succeeded, the delete runs even if the stat or
+// read failed, so the probe isn't left behind.
+func SelfTest(ctx context.Context, p StorageProvider, size int64) SelfTestResult {
+	result := SelfTestResult{Key: SelfTestKey(uuid.NewString()), Size: size}
+	content := make([]byte, size)
+	_, _ = rand.Read(content)
+
+	run := func(name string, step func() error) bool {
+		start := time.Now()
+		err := step()
+		result.Steps = append(result.Steps, SelfTestStep{Name: name, Duration: time.Since(start), Err: err})
+		return err == nil
+	}
+
+	if !run(SelfTestWrite, func() error {
+		return p.UploadFile(ctx, result.Key, bytes.NewReader(content), size, "application/octet-stream")
+	}) {
+		for _, name := range []string{SelfTestStat, SelfTestRead, SelfTestDelete} {
+			result.Steps = append(result.Steps, SelfTestStep{Name: name, Skipped: true})
+		}
+		return result
+	}
+	run(SelfTestStat, func() error {
+		exists, err := p.FileExists(ctx, result.Key)
+		if err == nil && !exists {
+			err = errors.New("probe object not found after writing it")
+		}
+		return err
+	})
+	run(SelfTestRead, func() error {
+		reader, err := p.DownloadFile(ctx, result.Key)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		read, err := io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read probe object: %w", err)
+		}
+		if !bytes.Equal(read, content) {
+			return fmt.Errorf("probe object read back differs from what was written (%d of %d bytes)", len(read), size)
+		}
+		return nil
+	})
+	run(SelfTestDelete, func() error {
+		return p.DeleteFile(ctx, result.Key)
+	})
+	return result
+}
+
+// ProviderName returns the name of an initialized provider ("minio" or "local"), or "" if unknown.
+func ProviderName(p StorageProvider) string {
+	if instrumented, ok := p.(*instrumentedProvider); ok {
+		return instrumented.name
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingProvider fails uploads or deletes, like a bucket policy lacking s3:PutObject or s3:DeleteObject.
+type failingProvider struct {
+	StorageProvider
+	failUpload, failDelete bool
+}
+
+var errAccessDenied = errors.New("access denied")
+
+func (f *failingProvider) UploadFile(ctx context.Context, objectName string, reader io.Reader, size int64, contentType string) error {
+	if f.failUpload {
+		return errAccessDenied
+	}
+	return f.StorageProvider.UploadFile(ctx, objectName, reader, size, contentType)
+}
+
+func (f *failingProvider) DeleteFile(ctx context.Context, objectName string) error {
+	if f.failDelete {
+		return errAccessDenied
+	}
+	return f.StorageProvider.DeleteFile(ctx, objectName)
+}
+
+func TestSelfTest(t *testing.T) {
+	local, err := NewLocalStorage(config.Config{LocalStoragePath: t.TempDir()})
+	require.NoError(t, err)
+	p := &instrumentedProvider{StorageProvider: local, name: "local"}
+	ctx := context.Background()
+
+	result := SelfTest(ctx, p, 4096)
+	assert.True(t, result.OK())
+	assert.True(t, strings.HasPrefix(result.Key, "v2/selftest/"))
+	assert.Equal(t, int64(4096), result.Size)
+	var names []string
+	for _, step := range result.Steps {
+		names = append(names, step.Name)
+		assert.NoError(t, step.Err)
+	}
+	assert.Equal(t, []string{SelfTestWrite, SelfTestStat, SelfTestRead, SelfTestDelete}, names)
+	exists, err := p.FileExists(ctx, result.Key)
+	require.NoError(t, err)
+	assert.False(t, exists, "the probe is deleted")
+	assert.Equal(t, "local", ProviderName(p))
+	assert.Equal(t, "", ProviderName(local))
+}
+
+func TestSelfTestFailures(t *testing.T) {
+	local, err := NewLocalStorage(config.Config{LocalStoragePath: t.TempDir()})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// A failed write skips the other steps
+	result := SelfTest(ctx, &failingProvider{StorageProvider: local, failUpload: true}, 16)
+	assert.False(t, result.OK())
+	assert.ErrorIs(t, result.Steps[0].Err, errAccessDenied)
+	for _, step := range result.Steps[1:] {
+		assert.True(t, step.Skipped, step.Name)
+	}
+
+	// A failed delete is reported after the steps that worked
+	result = SelfTest(ctx, &failingProvider{StorageProvider: local, failDelete: true}, 16)
+	assert.False(t, result.OK())
+	assert.NoError(t, result.Steps[2].Err)
+	assert.Equal(t, SelfTestDelete, result.Steps[3].Name)
+	assert.ErrorIs(t, result.Steps[3].Err, errAccessDenied)
+}
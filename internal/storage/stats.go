@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// BucketStats is the content of the bucket (or local storage directories) of a provider.
+type BucketStats struct {
+	Objects    int64 // Number of stored objects
+	TotalBytes int64 // Sum of their sizes
+}
+
+// StatsStorage is implemented by providers that can report how much they store. Both providers list every
+// object, so the stats are gathered periodically in the background (see api.RunCapacityCollector).
+type StatsStorage interface {
+	// Stats counts the stored objects and their total size, in every tier.
+	Stats(ctx context.Context) (BucketStats, error)
+}
+
+// ErrStatsUnsupported is returned by Stats for providers that can't report their content.
+var ErrStatsUnsupported = errors.New("storage: provider does not report bucket stats")
+
+// Stats returns the content of p's bucket, if p supports it.
+func Stats(ctx context.Context, p StorageProvider) (BucketStats, error) {
+	stats, ok := p.(StatsStorage)
+	if !ok {
+		return BucketStats{}, ErrStatsUnsupported
+	}
+	return stats.Stats(ctx)
+}
+
+// Stats counts the files in the storage directory and the cold storage directory (if any). Temporary
+// files of uploads and transitions in progress are not counted.
+func (l *LocalStorage) Stats(ctx context.Context) (BucketStats, error) {
+	var stats BucketStats
+	for _, dir := range []string{l.basePath, l.coldPath} {
+		if dir == "" {
+			continue
+		}
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") || strings.HasPrefix(entry.Name(), ".transition-") {
+				return nil
+			}
+			info, err := entry.Info()
+			if os.IsNotExist(err) {
+				return nil // Deleted meanwhile
+			} else if err != nil {
+				return err
+			}
+			stats.Objects++
+			stats.TotalBytes += info.Size()
+			return nil
+		})
+		if err != nil {
+			return BucketStats{}, fmt.Errorf("failed to walk local storage directory %s: %w", dir, mapLocalError(err))
+		}
+	}
+	return stats, nil
+}
+
+// Stats lists every object of the bucket (in all storage classes).
+func (m *MinioStorage) Stats(ctx context.Context) (BucketStats, error) {
+	var stats BucketStats
+	for object := range m.client.ListObjects(ctx, m.bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if object.Err != nil {
+			return BucketStats{}, fmt.Errorf("failed to list objects of bucket %s: %w", m.bucket, mapMinioError(object.Err))
+		}
+		stats.Objects++
+		stats.TotalBytes += object.Size
+	}
+	return stats, nil
+}
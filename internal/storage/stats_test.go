@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorageStats(t *testing.T) {
+	hot, cold := t.TempDir(), t.TempDir()
+	local, err := NewLocalStorage(config.Config{LocalStoragePath: hot, LocalColdStoragePath: cold})
+	require.NoError(t, err)
+	p := &instrumentedProvider{StorageProvider: local, name: "local"}
+	ctx := context.Background()
+
+	stats, err := Stats(ctx, p)
+	require.NoError(t, err)
+	assert.Equal(t, BucketStats{}, stats)
+
+	require.NoError(t, p.UploadFile(ctx, "m/a.zip", strings.NewReader("data"), 4, "application/zip"))
+	require.NoError(t, p.UploadFile(ctx, "m/b.zip", strings.NewReader("more data"), 9, "application/zip"))
+	require.NoError(t, TransitionFile(ctx, p, "m/a.zip"))
+	// Leftovers of interrupted uploads aren't objects
+	require.NoError(t, os.WriteFile(filepath.Join(hot, "m", ".upload-123"), []byte("partial"), 0644))
+
+	stats, err = Stats(ctx, p)
+	require.NoError(t, err)
+	assert.Equal(t, BucketStats{Objects: 2, TotalBytes: 13}, stats, "objects of both tiers are counted")
+}
+
+func TestStatsUnsupported(t *testing.T) {
+	_, err := Stats(context.Background(), struct{ StorageProvider }{})
+	assert.ErrorIs(t, err, ErrStatsUnsupported)
+}
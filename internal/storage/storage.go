@@ -2,18 +2,23 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
-	"log"
 	"strings"
 
 	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"go.uber.org/zap"
 )
 
 // StorageProvider defines the interface for interacting with the storage backend.
 // This allows swapping between Minio, local filesystem, or other providers.
 type StorageProvider interface {
 	// UploadFile uploads data from a reader to the storage backend.
+	// Objects are write-once: if objectName already exists, nothing is written and an error wrapping
+	// ErrObjectExists is returned. Providers enforce this atomically, not just with a FileExists check.
 	// objectName is the full path/key for the object in the storage.
 	// reader is the source of the data.
 	// size is the total size of the data, required by some providers like MinIO.
@@ -39,6 +44,21 @@ type StorageProvider interface {
 	// GetPresignedURL(ctx context.Context, objectName string, expiry time.Duration) (string, error) // Example, not implementing yet
 }
 
+// ObjectDigest downloads an object and returns the hex-encoded SHA256 of its content.
+func ObjectDigest(ctx context.Context, p StorageProvider, objectName string) (string, error) {
+	reader, err := p.DownloadFile(ctx, objectName)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, reader); err != nil {
+		return "", fmt.Errorf("failed to read object %s: %w", objectName, err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
 // Global storage provider instance
 var provider StorageProvider
 
@@ -46,7 +66,7 @@ var provider StorageProvider
 func InitStorage(cfg config.Config) (StorageProvider, error) {
 	var err error
 	storageType := strings.ToLower(cfg.StorageType)
-	log.Printf("Initializing storage provider: %s", storageType)
+	logging.L().Info("Initializing storage provider", zap.String("type", storageType))
 
 	switch storageType {
 	case "minio":
@@ -62,8 +82,9 @@ func InitStorage(cfg config.Config) (StorageProvider, error) {
 	default:
 		return nil, fmt.Errorf("invalid STORAGE_TYPE: %s. Must be 'minio' or 'local'", cfg.StorageType)
 	}
+	provider = &instrumentedProvider{StorageProvider: provider, name: storageType} // Latency metrics
 
-	log.Printf("Storage provider '%s' initialized successfully.", storageType)
+	logging.L().Info("Storage provider initialized successfully", zap.String("type", storageType))
 	return provider, nil
 }
 
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TieredStorage is implemented by providers that can move objects to a colder (cheaper) storage tier:
+// MinIO/S3 by changing the storage class of the object, local storage by moving the file to the cold
+// storage directory. Objects keep their key, and reads of cold objects are transparent.
+type TieredStorage interface {
+	// TransitionFile moves an object to the cold tier. Objects already in the cold tier are left alone.
+	// Returns an error wrapping ErrTieringUnsupported if the provider has no cold tier configured.
+	TransitionFile(ctx context.Context, objectName string) error
+}
+
+// ErrTieringUnsupported is returned by TransitionFile when no cold tier is configured.
+var ErrTieringUnsupported = errors.New("storage: no cold storage tier configured")
+
+// TransitionFile moves an object of p to the cold tier, if p supports tiering.
+func TransitionFile(ctx context.Context, p StorageProvider, objectName string) error {
+	tiered, ok := p.(TieredStorage)
+	if !ok {
+		return ErrTieringUnsupported
+	}
+	return tiered.TransitionFile(ctx, objectName)
+}
+
+// Storage classes objects can be written with. Only classes that are read without a restore request are
+// accepted, so cold artifacts stay fetchable; archive classes (GLACIER, DEEP_ARCHIVE) would make them fail.
+var storageClasses = map[string]bool{
+	"STANDARD":            true,
+	"REDUCED_REDUNDANCY":  true, // MinIO's reduced parity class
+	"STANDARD_IA":         true,
+	"ONEZONE_IA":          true,
+	"INTELLIGENT_TIERING": true,
+	"GLACIER_IR":          true,
+}
+
+// validateStorageClass checks a configured storage class (empty means the bucket's default).
+func validateStorageClass(setting, class string) error {
+	if class == "" || storageClasses[class] {
+		return nil
+	}
+	switch class {
+	case "GLACIER", "DEEP_ARCHIVE", "GLACIER_FLEXIBLE_RETRIEVAL":
+		return fmt.Errorf("invalid %s %q: objects in archive classes must be restored before they can be read; use GLACIER_IR instead", setting, class)
+	}
+	return fmt.Errorf("invalid %s %q: must be one of STANDARD, REDUCED_REDUNDANCY, STANDARD_IA, ONEZONE_IA, INTELLIGENT_TIERING or GLACIER_IR", setting, strings.ToUpper(class))
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateStorageClass(t *testing.T) {
+	for _, class := range []string{"", "STANDARD", "STANDARD_IA", "GLACIER_IR"} {
+		assert.NoError(t, validateStorageClass("MINIO_COLD_STORAGE_CLASS", class), class)
+	}
+	err := validateStorageClass("MINIO_COLD_STORAGE_CLASS", "GLACIER")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "restored")
+	assert.Error(t, validateStorageClass("MINIO_STORAGE_CLASS", "standard-ia"))
+}
+
+func TestLocalStorageTiering(t *testing.T) {
+	hot, cold := t.TempDir(), t.TempDir()
+	local, err := NewLocalStorage(config.Config{LocalStoragePath: hot, LocalColdStoragePath: cold})
+	require.NoError(t, err)
+	p := &instrumentedProvider{StorageProvider: local, name: "local"}
+	ctx := context.Background()
+	require.NoError(t, p.UploadFile(ctx, "m/a.zip", strings.NewReader("data"), 4, "application/zip"))
+
+	// Moved to the cold directory, and still read transparently
+	require.NoError(t, TransitionFile(ctx, p, "m/a.zip"))
+	_, err = os.Stat(filepath.Join(hot, "m/a.zip"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(cold, "m/a.zip"))
+	assert.NoError(t, err)
+	reader, err := p.DownloadFile(ctx, "m/a.zip")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+	exists, err := p.FileExists(ctx, "m/a.zip")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// Still write-once, transitions are idempotent, and deletes remove the cold copy
+	assert.ErrorIs(t, p.UploadFile(ctx, "m/a.zip", strings.NewReader("other"), 5, "application/zip"), ErrObjectExists)
+	assert.NoError(t, TransitionFile(ctx, p, "m/a.zip"))
+	assert.ErrorIs(t, TransitionFile(ctx, p, "m/missing.zip"), ErrObjectNotFound)
+	require.NoError(t, p.DeleteFile(ctx, "m/a.zip"))
+	exists, err = p.FileExists(ctx, "m/a.zip")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Without a cold directory
+	plain, err := NewLocalStorage(config.Config{LocalStoragePath: t.TempDir()})
+	require.NoError(t, err)
+	assert.ErrorIs(t, TransitionFile(ctx, plain, "m/a.zip"), ErrTieringUnsupported)
+	_, err = NewLocalStorage(config.Config{LocalStoragePath: hot, LocalColdStoragePath: hot + "/"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,229 @@
+// Package translog maintains the checksum log: an append-only Merkle tree of every published
+// (module, version, digest), modelled on Go's checksum database. Clients that remember the log's
+// root hash can detect the registry serving different bytes for a version it already published,
+// or rewriting its history.
+package translog
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when a module version isn't in the log.
+	ErrNotFound = errors.New("checksum entry not found")
+	// ErrDigestConflict is returned when appending a version that is already logged with another digest.
+	ErrDigestConflict = errors.New("version is already logged with a different digest")
+	// ErrTreeSize is returned for a proof against a tree size the log doesn't have or that excludes the entry.
+	ErrTreeSize = errors.New("invalid tree size")
+)
+
+// appendAttempts bounds the retries when another server instance appends at the same time
+// (both pick the same index; the primary key lets only one of them through).
+const appendAttempts = 5
+
+// Log is the checksum log stored in the checksum_entries table.
+type Log struct {
+	db *gorm.DB
+	mu sync.Mutex // Serializes appends within this process
+}
+
+// New creates a checksum log backed by db.
+func New(db *gorm.DB) *Log {
+	return &Log{db: db}
+}
+
+// Proof is an inclusion proof of an entry in the tree of the first TreeSize entries.
+type Proof struct {
+	Entry    models.ChecksumEntry
+	TreeSize int64
+	RootHash string   // Hex root hash of the tree of TreeSize entries
+	Hashes   []string // Hex audit path, from the leaf up (see VerifyInclusion)
+}
+
+// Append logs a module version ("namespace/name") with its artifact digest ("sha256:<hex>") and returns
+// its entry. Appending a version that is already logged with the same digest returns the existing entry.
+func (l *Log) Append(ctx context.Context, module, version, digest string) (*models.ChecksumEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < appendAttempts; attempt++ {
+		var entry *models.ChecksumEntry
+		if entry, err = l.Find(ctx, module, version); err == nil {
+			if entry.Digest != digest {
+				return entry, fmt.Errorf("%w: %s@%s is logged as %s", ErrDigestConflict, module, version, entry.Digest)
+			}
+			return entry, nil
+		} else if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+
+		var leaves [][]byte
+		if leaves, err = l.leafHashes(ctx); err != nil {
+			return nil, err
+		}
+		if entry, err = l.insert(ctx, newCompactRange(leaves), int64(len(leaves)), module, version, digest); err == nil {
+			return entry, nil
+		}
+	}
+	return nil, err
+}
+
+// Sync appends the published versions missing from the log, oldest first, and returns how many were
+// appended. Run at startup, it backfills versions published before the log existed and any whose
+// append failed after the publish was committed.
+func (l *Log) Sync(ctx context.Context) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var missing []struct {
+		Namespace      string
+		Name           string
+		Version        string
+		ArtifactDigest string
+	}
+	err := l.db.WithContext(ctx).Table("module_versions").
+		Select("modules.namespace, modules.name, module_versions.version, module_versions.artifact_digest").
+		Joins("JOIN modules ON modules.id = module_versions.module_id").
+		Joins("LEFT JOIN checksum_entries ON checksum_entries.module = modules.namespace || '/' || modules.name AND checksum_entries.version = module_versions.version").
+		Where("checksum_entries.entry_index IS NULL").
+		Order("module_versions.created_at, module_versions.id").
+		Scan(&missing).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to list versions missing from the checksum log: %w", err)
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	// Extend the tree in memory rather than reloading it for every entry
+	leaves, err := l.leafHashes(ctx)
+	if err != nil {
+		return 0, err
+	}
+	tree, size := newCompactRange(leaves), int64(len(leaves))
+	for i, v := range missing {
+		if _, err := l.insert(ctx, tree, size, v.Namespace+"/"+v.Name, v.Version, "sha256:"+v.ArtifactDigest); err != nil {
+			return i, err
+		}
+		size++
+	}
+	return len(missing), nil
+}
+
+// insert appends an entry at index size to the tree (extending tree in place) and stores it.
+func (l *Log) insert(ctx context.Context, tree *compactRange, size int64, module, version, digest string) (*models.ChecksumEntry, error) {
+	leaf := LeafHash(EntryText(module, version, digest))
+	tree.append(leaf)
+	entry := &models.ChecksumEntry{
+		Index:     size,
+		Module:    module,
+		Version:   version,
+		Digest:    digest,
+		LeafHash:  hex.EncodeToString(leaf),
+		RootHash:  hex.EncodeToString(tree.root()),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := l.db.WithContext(ctx).Create(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to append %s@%s to the checksum log: %w", module, version, err)
+	}
+	return entry, nil
+}
+
+// Head returns the current size of the log and its hex root hash.
+func (l *Log) Head(ctx context.Context) (int64, string, error) {
+	var last []models.ChecksumEntry
+	if err := l.db.WithContext(ctx).Order("entry_index DESC").Limit(1).Find(&last).Error; err != nil {
+		return 0, "", fmt.Errorf("failed to read the checksum log head: %w", err)
+	}
+	if len(last) == 0 {
+		return 0, hex.EncodeToString(RootHash(nil)), nil
+	}
+	return last[0].Index + 1, last[0].RootHash, nil
+}
+
+// Entries returns up to limit entries starting at index start.
+func (l *Log) Entries(ctx context.Context, start int64, limit int) ([]models.ChecksumEntry, error) {
+	var entries []models.ChecksumEntry
+	err := l.db.WithContext(ctx).Where("entry_index >= ?", start).Order("entry_index").Limit(limit).Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksum log entries: %w", err)
+	}
+	return entries, nil
+}
+
+// Prove returns an inclusion proof of a module version in the tree of the first treeSize entries
+// (0 for the current size).
+func (l *Log) Prove(ctx context.Context, module, version string, treeSize int64) (*Proof, error) {
+	entry, err := l.Find(ctx, module, version)
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := l.leafHashes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if treeSize == 0 {
+		treeSize = int64(len(leaves))
+	}
+	if treeSize > int64(len(leaves)) || entry.Index >= treeSize {
+		return nil, fmt.Errorf("%w: entry %d is not in a tree of size %d (log size %d)", ErrTreeSize, entry.Index, treeSize, len(leaves))
+	}
+	leaves = leaves[:treeSize]
+
+	path, err := InclusionProof(leaves, int(entry.Index))
+	if err != nil {
+		return nil, err
+	}
+	// The recomputed root must match the one recorded when the tree had this size
+	root := hex.EncodeToString(RootHash(leaves))
+	head, err := l.Entries(ctx, treeSize-1, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(head) != 1 || head[0].RootHash != root {
+		return nil, fmt.Errorf("checksum log is inconsistent: recorded root hash of tree size %d doesn't match its entries", treeSize)
+	}
+	proof := &Proof{Entry: *entry, TreeSize: treeSize, RootHash: root}
+	for _, h := range path {
+		proof.Hashes = append(proof.Hashes, hex.EncodeToString(h))
+	}
+	return proof, nil
+}
+
+// Find returns the entry of a module version, or ErrNotFound.
+func (l *Log) Find(ctx context.Context, module, version string) (*models.ChecksumEntry, error) {
+	var entry models.ChecksumEntry
+	err := l.db.WithContext(ctx).Where("module = ? AND version = ?", module, version).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s@%s", ErrNotFound, module, version)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to look up checksum entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// leafHashes returns the leaf hashes of all entries, in log order.
+func (l *Log) leafHashes(ctx context.Context) ([][]byte, error) {
+	var hexHashes []string
+	if err := l.db.WithContext(ctx).Model(&models.ChecksumEntry{}).Order("entry_index").Pluck("leaf_hash", &hexHashes).Error; err != nil {
+		return nil, fmt.Errorf("failed to read checksum log: %w", err)
+	}
+	leaves := make([][]byte, len(hexHashes))
+	for i, h := range hexHashes {
+		leaf, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, fmt.Errorf("checksum log entry %d has an invalid leaf hash: %w", i, err)
+		}
+		leaves[i] = leaf
+	}
+	return leaves, nil
+}
@@ -0,0 +1,149 @@
+package translog
+
+import (
+	"context"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupLog creates a SQLite database with the registry tables and an empty checksum log.
+func setupLog(t *testing.T) (*Log, *gorm.DB) {
+	gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormDB.AutoMigrate(&models.Module{}, &models.ModuleVersion{}, &models.ChecksumEntry{}))
+	return New(gormDB), gormDB
+}
+
+// verifyProof checks a proof the way a client would, from its hex fields.
+func verifyProof(t *testing.T, p *Proof) error {
+	leaf, err := hex.DecodeString(p.Entry.LeafHash)
+	require.NoError(t, err)
+	root, err := hex.DecodeString(p.RootHash)
+	require.NoError(t, err)
+	var hashes [][]byte
+	for _, h := range p.Hashes {
+		b, err := hex.DecodeString(h)
+		require.NoError(t, err)
+		hashes = append(hashes, b)
+	}
+	return VerifyInclusion(leaf, p.Entry.Index, p.TreeSize, hashes, root)
+}
+
+func TestLog_AppendAndProve(t *testing.T) {
+	log, _ := setupLog(t)
+	ctx := context.Background()
+
+	for i, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0", "v2.0.0", "v2.0.1"} {
+		entry, err := log.Append(ctx, "acme/billing", v, "sha256:"+v)
+		require.NoError(t, err)
+		assert.Equal(t, int64(i), entry.Index)
+	}
+
+	// Appending the same version again is a no-op; with another digest it is a conflict
+	entry, err := log.Append(ctx, "acme/billing", "v1.1.0", "sha256:v1.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), entry.Index)
+	_, err = log.Append(ctx, "acme/billing", "v1.1.0", "sha256:other")
+	assert.ErrorIs(t, err, ErrDigestConflict)
+
+	size, root, err := log.Head(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), size)
+
+	// Proof against the current tree
+	proof, err := log.Prove(ctx, "acme/billing", "v1.1.0", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), proof.TreeSize)
+	assert.Equal(t, root, proof.RootHash)
+	assert.NoError(t, verifyProof(t, proof))
+
+	// Proof against an older tree, whose root was recorded with its last entry
+	proof, err = log.Prove(ctx, "acme/billing", "v1.1.0", 3)
+	require.NoError(t, err)
+	entries, err := log.Entries(ctx, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, entries[0].RootHash, proof.RootHash)
+	assert.NoError(t, verifyProof(t, proof))
+
+	_, err = log.Prove(ctx, "acme/billing", "v2.0.0", 3)
+	assert.ErrorIs(t, err, ErrTreeSize)
+	_, err = log.Prove(ctx, "acme/billing", "v1.0.0", 6)
+	assert.ErrorIs(t, err, ErrTreeSize)
+	_, err = log.Prove(ctx, "acme/billing", "v9.0.0", 0)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLog_DetectsRewrittenEntry(t *testing.T) {
+	log, gormDB := setupLog(t)
+	ctx := context.Background()
+	for _, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		_, err := log.Append(ctx, "acme/billing", v, "sha256:"+v)
+		require.NoError(t, err)
+	}
+	_, root, err := log.Head(ctx)
+	require.NoError(t, err)
+
+	// Swapping an entry's digest changes every root hash after it
+	forged := hex.EncodeToString(LeafHash(EntryText("acme/billing", "v1.1.0", "sha256:forged")))
+	require.NoError(t, gormDB.Model(&models.ChecksumEntry{}).Where("entry_index = ?", 1).Updates(map[string]interface{}{"digest": "sha256:forged", "leaf_hash": forged}).Error)
+	_, err = log.Prove(ctx, "acme/billing", "v1.1.0", 0)
+	assert.ErrorContains(t, err, "inconsistent")
+
+	// Even with the recorded roots rewritten too, the new root differs from one a client remembered
+	require.NoError(t, gormDB.Exec("DELETE FROM checksum_entries WHERE entry_index >= 1").Error)
+	for _, v := range []string{"v1.1.0", "v1.2.0"} {
+		digest := "sha256:" + v
+		if v == "v1.1.0" {
+			digest = "sha256:forged"
+		}
+		_, err := log.Append(ctx, "acme/billing", v, digest)
+		require.NoError(t, err)
+	}
+	_, forgedRoot, err := log.Head(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, root, forgedRoot)
+}
+
+func TestLog_Sync(t *testing.T) {
+	log, gormDB := setupLog(t)
+	ctx := context.Background()
+
+	module := models.Module{Namespace: "acme", Name: "billing"}
+	require.NoError(t, gormDB.Create(&module).Error)
+	base := time.Now().UTC()
+	for i, v := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		require.NoError(t, gormDB.Create(&models.ModuleVersion{
+			ModuleID: module.ID, Version: v, ArtifactDigest: "abc" + v, ArtifactStorageKey: "k" + v,
+			CreatedAt: base.Add(time.Duration(i) * time.Second),
+		}).Error)
+	}
+	// One version was logged at publish; the others are backfilled, oldest first
+	_, err := log.Append(ctx, "acme/billing", "v1.1.0", "sha256:abcv1.1.0")
+	require.NoError(t, err)
+
+	appended, err := log.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, appended)
+	entries, err := log.Entries(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []string{"v1.1.0", "v1.0.0", "v1.2.0"}, []string{entries[0].Version, entries[1].Version, entries[2].Version})
+	assert.Equal(t, "sha256:abcv1.0.0", entries[1].Digest)
+
+	// Backfilled entries chain like appended ones
+	proof, err := log.Prove(ctx, "acme/billing", "v1.2.0", 0)
+	require.NoError(t, err)
+	assert.NoError(t, verifyProof(t, proof))
+
+	appended, err = log.Sync(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, appended)
+}
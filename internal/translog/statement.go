@@ -0,0 +1,123 @@
+package translog
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Statements let clients verify what they fetch without trusting the connection or a CDN in between:
+// the registry signs, with an Ed25519 key whose public half clients configure, that a module version
+// has a digest and is an entry of its checksum log with a given root hash.
+
+// statementHeader is the first line of a statement, identifying its format.
+const statementHeader = "sproto checksum statement v1"
+
+// ErrInvalidSignature is returned by VerifyStatement when the signature doesn't match the statement.
+var ErrInvalidSignature = errors.New("invalid statement signature")
+
+// Statement is the registry's claim that Module@Version has Digest and is entry Index of the checksum
+// log, whose tree of TreeSize entries has root hash RootHash.
+type Statement struct {
+	Module   string
+	Version  string
+	Digest   string // sha256:<hex>
+	Index    int64
+	TreeSize int64
+	RootHash string // Hex
+}
+
+// Marshal returns the signed text form of the statement:
+//
+//	sproto checksum statement v1
+//	acme/billing v1.2.0 sha256:7ca2895a...
+//	index 41
+//	tree 57 5a1e0b0d...
+func (s Statement) Marshal() []byte {
+	return []byte(fmt.Sprintf("%s\n%s %s %s\nindex %d\ntree %d %s\n", statementHeader, s.Module, s.Version, s.Digest, s.Index, s.TreeSize, s.RootHash))
+}
+
+// ParseStatement parses the text form of a statement (see Marshal).
+func ParseStatement(data []byte) (Statement, error) {
+	var s Statement
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 4 || lines[0] != statementHeader {
+		return s, errors.New("malformed checksum statement")
+	}
+	entry := strings.Fields(lines[1])
+	index := strings.Fields(lines[2])
+	tree := strings.Fields(lines[3])
+	if len(entry) != 3 || len(index) != 2 || index[0] != "index" || len(tree) != 3 || tree[0] != "tree" {
+		return s, errors.New("malformed checksum statement")
+	}
+	var err error
+	s.Module, s.Version, s.Digest, s.RootHash = entry[0], entry[1], entry[2], tree[2]
+	if s.Index, err = strconv.ParseInt(index[1], 10, 64); err != nil {
+		return s, fmt.Errorf("malformed checksum statement index: %w", err)
+	}
+	if s.TreeSize, err = strconv.ParseInt(tree[1], 10, 64); err != nil {
+		return s, fmt.Errorf("malformed checksum statement tree size: %w", err)
+	}
+	return s, nil
+}
+
+// GenerateKey returns a new signing key and its public key, both base64-encoded (see ParsePrivateKey
+// and ParsePublicKey).
+func GenerateKey() (privateKey, publicKey string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(priv.Seed()), base64.StdEncoding.EncodeToString(pub), nil
+}
+
+// ParsePrivateKey parses a base64-encoded Ed25519 private key: its 32-byte seed (as generated by
+// GenerateKey) or the 64-byte expanded form.
+func ParsePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key: not base64: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("invalid signing key: expected a %d-byte Ed25519 seed, got %d bytes", ed25519.SeedSize, len(raw))
+}
+
+// ParsePublicKey parses a base64-encoded Ed25519 public key.
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: not base64: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key: expected %d bytes, got %d", ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// EncodePublicKey returns the base64 form of a signing key's public key, as clients configure it.
+func EncodePublicKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+// SignStatement returns the base64 Ed25519 signature of a statement's text form.
+func SignStatement(key ed25519.PrivateKey, statement []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, statement))
+}
+
+// VerifyStatement checks a base64 signature of a statement's text form.
+func VerifyStatement(key ed25519.PublicKey, statement []byte, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || !ed25519.Verify(key, statement, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package translog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementRoundTrip(t *testing.T) {
+	s := Statement{Module: "acme/billing", Version: "v1.2.0", Digest: "sha256:abcd", Index: 41, TreeSize: 57, RootHash: "5a1e"}
+	assert.Equal(t, "sproto checksum statement v1\nacme/billing v1.2.0 sha256:abcd\nindex 41\ntree 57 5a1e\n", string(s.Marshal()))
+
+	parsed, err := ParseStatement(s.Marshal())
+	require.NoError(t, err)
+	assert.Equal(t, s, parsed)
+
+	for _, bad := range []string{
+		"",
+		"sproto checksum statement v2\nacme/billing v1.2.0 sha256:abcd\nindex 41\ntree 57 5a1e\n",
+		"sproto checksum statement v1\nacme/billing v1.2.0\nindex 41\ntree 57 5a1e\n",
+		"sproto checksum statement v1\nacme/billing v1.2.0 sha256:abcd\nindex x\ntree 57 5a1e\n",
+		"sproto checksum statement v1\nacme/billing v1.2.0 sha256:abcd\nindex 41\nsize 57 5a1e\n",
+	} {
+		_, err := ParseStatement([]byte(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestSignAndVerifyStatement(t *testing.T) {
+	privateKey, publicKey, err := GenerateKey()
+	require.NoError(t, err)
+	key, err := ParsePrivateKey(privateKey)
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, EncodePublicKey(key))
+	pub, err := ParsePublicKey(publicKey)
+	require.NoError(t, err)
+
+	statement := Statement{Module: "acme/billing", Version: "v1.2.0", Digest: "sha256:abcd", Index: 0, TreeSize: 1, RootHash: "5a1e"}.Marshal()
+	signature := SignStatement(key, statement)
+	assert.NoError(t, VerifyStatement(pub, statement, signature))
+
+	// A changed statement, a garbled signature or another key don't verify
+	tampered := Statement{Module: "acme/billing", Version: "v1.2.0", Digest: "sha256:ffff", Index: 0, TreeSize: 1, RootHash: "5a1e"}.Marshal()
+	assert.ErrorIs(t, VerifyStatement(pub, tampered, signature), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyStatement(pub, statement, "not base64!"), ErrInvalidSignature)
+	_, otherPublicKey, err := GenerateKey()
+	require.NoError(t, err)
+	other, err := ParsePublicKey(otherPublicKey)
+	require.NoError(t, err)
+	assert.ErrorIs(t, VerifyStatement(other, statement, signature), ErrInvalidSignature)
+
+	// Keys must have the right size
+	_, err = ParsePrivateKey(publicKey[:8])
+	assert.Error(t, err)
+	_, err = ParsePublicKey(privateKey + "AAAA")
+	assert.Error(t, err)
+}
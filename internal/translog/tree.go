@@ -0,0 +1,154 @@
+package translog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// The log is a Merkle tree as in Certificate Transparency (RFC 6962 section 2.1): leaves are hashed with
+// a 0x00 prefix and interior nodes with 0x01, so a leaf can never be passed off as a subtree. The root
+// hash of the first n entries commits to all of them; an inclusion proof shows that an entry is one of
+// them with only log2(n) hashes.
+
+// ErrInvalidProof is returned by VerifyInclusion when a proof doesn't lead to the expected root hash.
+var ErrInvalidProof = errors.New("invalid inclusion proof")
+
+// EntryText returns the logged form of a module version, e.g.
+// "acme/billing v1.2.0 sha256:7ca2895a...\n". Its leaf hash is what the tree commits to.
+func EntryText(module, version, digest string) []byte {
+	return []byte(fmt.Sprintf("%s %s %s\n", module, version, digest))
+}
+
+// LeafHash returns the hash of a leaf: SHA256(0x00 || entry).
+func LeafHash(entry []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(entry)
+	return h.Sum(nil)
+}
+
+// nodeHash returns the hash of an interior node: SHA256(0x01 || left || right).
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// splitPoint returns the largest power of two smaller than n (n > 1).
+func splitPoint(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// RootHash returns the root hash of a tree with the given leaf hashes. The empty tree's root is SHA256("").
+func RootHash(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leaves[0]
+	}
+	k := splitPoint(len(leaves))
+	return nodeHash(RootHash(leaves[:k]), RootHash(leaves[k:]))
+}
+
+// compactRange holds the roots of the perfect subtrees covering the leaves appended so far, largest first.
+// Appending a leaf and computing the root take O(log n), so a log can be extended without rehashing it.
+type compactRange struct {
+	hashes [][]byte
+	sizes  []int64
+}
+
+// newCompactRange returns the compact range of a tree with the given leaf hashes.
+func newCompactRange(leaves [][]byte) *compactRange {
+	c := &compactRange{}
+	for _, leaf := range leaves {
+		c.append(leaf)
+	}
+	return c
+}
+
+// append adds a leaf hash, merging subtrees of equal size like a binary counter.
+func (c *compactRange) append(leaf []byte) {
+	c.hashes = append(c.hashes, leaf)
+	c.sizes = append(c.sizes, 1)
+	for n := len(c.sizes); n >= 2 && c.sizes[n-2] == c.sizes[n-1]; n = len(c.sizes) {
+		merged, size := nodeHash(c.hashes[n-2], c.hashes[n-1]), c.sizes[n-2]*2
+		c.hashes = append(c.hashes[:n-2], merged)
+		c.sizes = append(c.sizes[:n-2], size)
+	}
+}
+
+// root returns the root hash of the tree, the same as RootHash of its leaves.
+func (c *compactRange) root() []byte {
+	if len(c.hashes) == 0 {
+		return RootHash(nil)
+	}
+	r := c.hashes[len(c.hashes)-1]
+	for i := len(c.hashes) - 2; i >= 0; i-- {
+		r = nodeHash(c.hashes[i], r)
+	}
+	return r
+}
+
+// InclusionProof returns the audit path proving that leaves[index] is in the tree of all leaves,
+// ordered from the leaf up.
+func InclusionProof(leaves [][]byte, index int) ([][]byte, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("leaf index %d out of range for tree size %d", index, len(leaves))
+	}
+	return inclusionPath(leaves, index), nil
+}
+
+func inclusionPath(leaves [][]byte, index int) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := splitPoint(len(leaves))
+	if index < k {
+		return append(inclusionPath(leaves[:k], index), RootHash(leaves[k:]))
+	}
+	return append(inclusionPath(leaves[k:], index-k), RootHash(leaves[:k]))
+}
+
+// VerifyInclusion checks that proof shows the leaf with leafHash at index to be in the tree of treeSize
+// leaves with the given root hash (RFC 9162 section 2.1.3.2). Clients use it to check that the version
+// they fetched is the one the registry logged.
+func VerifyInclusion(leafHash []byte, index, treeSize int64, proof [][]byte, root []byte) error {
+	if index < 0 || index >= treeSize {
+		return fmt.Errorf("%w: leaf index %d out of range for tree size %d", ErrInvalidProof, index, treeSize)
+	}
+	fn, sn := index, treeSize-1
+	r := leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return fmt.Errorf("%w: proof is too long", ErrInvalidProof)
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return fmt.Errorf("%w: proof is too short", ErrInvalidProof)
+	}
+	if !bytes.Equal(r, root) {
+		return fmt.Errorf("%w: root hash mismatch", ErrInvalidProof)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package translog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		leaves[i] = LeafHash(EntryText(fmt.Sprintf("acme/m%d", i), "v1.0.0", "sha256:00"))
+	}
+	return leaves
+}
+
+func TestRootHash_RFC6962(t *testing.T) {
+	// Empty tree, and the single-leaf tree of the empty entry (RFC 6962 test vectors)
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", hex.EncodeToString(RootHash(nil)))
+	assert.Equal(t, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d", hex.EncodeToString(LeafHash(nil)))
+
+	// Interior nodes are domain-separated from leaves
+	a, b := LeafHash([]byte("a")), LeafHash([]byte("b"))
+	pair := sha256.Sum256(append(append([]byte{0x01}, a...), b...))
+	assert.Equal(t, pair[:], RootHash([][]byte{a, b}))
+}
+
+func TestCompactRange_MatchesRootHash(t *testing.T) {
+	leaves := testLeaves(40)
+	c := newCompactRange(nil)
+	assert.Equal(t, RootHash(nil), c.root())
+	for n := 1; n <= len(leaves); n++ {
+		c.append(leaves[n-1])
+		assert.Equal(t, RootHash(leaves[:n]), c.root(), "size %d", n)
+	}
+}
+
+func TestInclusionProof_Verifies(t *testing.T) {
+	leaves := testLeaves(21)
+	for size := 1; size <= len(leaves); size++ {
+		root := RootHash(leaves[:size])
+		for i := 0; i < size; i++ {
+			proof, err := InclusionProof(leaves[:size], i)
+			require.NoError(t, err)
+			assert.NoError(t, VerifyInclusion(leaves[i], int64(i), int64(size), proof, root), "leaf %d of %d", i, size)
+		}
+	}
+}
+
+func TestVerifyInclusion_RejectsTampering(t *testing.T) {
+	leaves := testLeaves(7)
+	root := RootHash(leaves)
+	proof, err := InclusionProof(leaves, 3)
+	require.NoError(t, err)
+
+	// Another leaf, index, size, root or a truncated/extended proof
+	assert.ErrorIs(t, VerifyInclusion(leaves[4], 3, 7, proof, root), ErrInvalidProof)
+	assert.ErrorIs(t, VerifyInclusion(leaves[3], 2, 7, proof, root), ErrInvalidProof)
+	assert.ErrorIs(t, VerifyInclusion(leaves[3], 3, 4, proof, root), ErrInvalidProof)
+	assert.ErrorIs(t, VerifyInclusion(leaves[3], 3, 7, proof, RootHash(leaves[:6])), ErrInvalidProof)
+	assert.ErrorIs(t, VerifyInclusion(leaves[3], 3, 7, proof[:len(proof)-1], root), ErrInvalidProof)
+	assert.ErrorIs(t, VerifyInclusion(leaves[3], 3, 7, append(proof, root), root), ErrInvalidProof)
+	assert.ErrorIs(t, VerifyInclusion(leaves[3], 7, 7, proof, root), ErrInvalidProof)
+
+	_, err = InclusionProof(leaves, 7)
+	assert.Error(t, err)
+}
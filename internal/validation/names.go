@@ -0,0 +1,94 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Length limits for namespace and module names.
+const (
+	MaxNamespaceLength  = 64
+	MaxModuleNameLength = 128
+	MaxClassifierLength = 64
+	MaxPluginNameLength = 128
+	MaxDevChannelLength = 64
+)
+
+// DevChannelPrefix starts the name of every dev channel ("dev-alice"). Versions start with 'v', so a dev
+// channel can be used wherever a version is fetched without being mistaken for one.
+const DevChannelPrefix = "dev-"
+
+// namePattern allows lowercase letters, digits, '-' and '.', starting and ending with a letter or digit.
+// Names end up in URLs, storage keys and (after fetch) directory names, so the character set is
+// deliberately small: no uppercase (case-insensitive filesystems), no '/', '\' or ':' and no
+// trailing '.' (silently stripped on Windows).
+var namePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`)
+
+// windowsReservedNames are device names that can't be used as file or directory names on Windows,
+// with or without an extension (e.g. "con" and "con.v1" are both unusable).
+var windowsReservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true, "com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true, "lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// ValidateNamespace checks that a namespace follows the naming rules.
+func ValidateNamespace(namespace string) error {
+	return validateName("namespace", namespace, MaxNamespaceLength)
+}
+
+// ValidateModuleName checks that a module name (without namespace) follows the naming rules.
+func ValidateModuleName(name string) error {
+	return validateName("module name", name, MaxModuleNameLength)
+}
+
+// ValidateClassifier checks that the classifier of a secondary artifact (e.g. "openapi") follows the naming
+// rules. Classifiers end up in URLs, storage keys and file names like module names do.
+func ValidateClassifier(classifier string) error {
+	return validateName("classifier", classifier, MaxClassifierLength)
+}
+
+// ValidatePluginName checks that the name of a registered protoc plugin (e.g. "grpc-go") follows the naming
+// rules. Plugin names end up in URLs and in the CLI's plugin cache directory.
+func ValidatePluginName(name string) error {
+	return validateName("plugin name", name, MaxPluginNameLength)
+}
+
+// IsDevChannel reports whether a version string names a dev channel rather than a version.
+func IsDevChannel(version string) bool {
+	return strings.HasPrefix(version, DevChannelPrefix)
+}
+
+// ValidateDevChannel checks that a dev channel name is "dev-" followed by a name following the naming
+// rules (e.g. "dev-alice"). Channel names end up in URLs and storage keys like versions do.
+func ValidateDevChannel(channel string) error {
+	if !IsDevChannel(channel) {
+		return fmt.Errorf("invalid dev channel %q: must start with %q", channel, DevChannelPrefix)
+	}
+	if len(channel) > MaxDevChannelLength {
+		return fmt.Errorf("dev channel %q is too long (%d characters, max %d)", channel, len(channel), MaxDevChannelLength)
+	}
+	return validateName("dev channel", strings.TrimPrefix(channel, DevChannelPrefix), MaxDevChannelLength)
+}
+
+// validateName applies the shared naming rules. kind is used in error messages.
+func validateName(kind, name string, maxLength int) error {
+	if name == "" {
+		return fmt.Errorf("%s is required", kind)
+	}
+	if len(name) > maxLength {
+		return fmt.Errorf("%s %q is too long (%d characters, max %d)", kind, name, len(name), maxLength)
+	}
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid %s %q: only lowercase letters, digits, '-' and '.' are allowed, and it must start and end with a letter or digit", kind, name)
+	}
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("invalid %s %q: must not contain '..'", kind, name)
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if windowsReservedNames[base] {
+		return fmt.Errorf("invalid %s %q: %q is a reserved device name on Windows", kind, name, base)
+	}
+	return nil
+}
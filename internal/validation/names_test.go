@@ -0,0 +1,66 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateModuleName(t *testing.T) {
+	valid := []string{"user", "user-service", "billing.v2", "a", "x9", strings.Repeat("a", MaxModuleNameLength)}
+	for _, name := range valid {
+		assert.NoError(t, ValidateModuleName(name), name)
+	}
+
+	invalid := []string{
+		"",                                 // empty
+		"User",                             // uppercase
+		"user_service",                     // underscore
+		"-user", "user-", ".user", "user.", // must start/end with letter or digit
+		"a..b",                              // '..'
+		"a/b", `a\b`, "a:b", "a b", "a%2fb", // path/URL characters
+		"con", "nul.proto", "com1", "lpt9.v1", // Windows device names
+		strings.Repeat("a", MaxModuleNameLength+1), // too long
+	}
+	for _, name := range invalid {
+		assert.Error(t, ValidateModuleName(name), name)
+	}
+}
+
+func TestValidateNamespace(t *testing.T) {
+	assert.NoError(t, ValidateNamespace("mycompany"))
+	assert.NoError(t, ValidateNamespace("my-company.io"))
+	assert.Error(t, ValidateNamespace("MyCompany"))
+	assert.Error(t, ValidateNamespace("aux"))
+	assert.Error(t, ValidateNamespace(strings.Repeat("a", MaxNamespaceLength+1)))
+}
+
+func TestValidateClassifier(t *testing.T) {
+	for _, classifier := range []string{"descriptors", "openapi", "docs", "swift.v2"} {
+		assert.NoError(t, ValidateClassifier(classifier), classifier)
+	}
+	for _, classifier := range []string{"", "OpenAPI", "docs/html", "a..b", "nul", strings.Repeat("a", MaxClassifierLength+1)} {
+		assert.Error(t, ValidateClassifier(classifier), classifier)
+	}
+}
+
+func TestValidatePluginName(t *testing.T) {
+	for _, name := range []string{"go", "grpc-go", "connect-es", "validate.v2"} {
+		assert.NoError(t, ValidatePluginName(name), name)
+	}
+	for _, name := range []string{"", "protoc-gen-Go", "grpc/go", "registry://go", strings.Repeat("a", MaxPluginNameLength+1)} {
+		assert.Error(t, ValidatePluginName(name), name)
+	}
+}
+
+func TestValidateDevChannel(t *testing.T) {
+	for _, channel := range []string{"dev-alice", "dev-bob.smith", "dev-ci-42"} {
+		assert.NoError(t, ValidateDevChannel(channel), channel)
+		assert.True(t, IsDevChannel(channel), channel)
+	}
+	for _, channel := range []string{"", "dev-", "alice", "v1.0.0", "dev-Alice", "dev-alice/x", "dev--alice", "dev-" + strings.Repeat("a", MaxDevChannelLength)} {
+		assert.Error(t, ValidateDevChannel(channel), channel)
+	}
+	assert.False(t, IsDevChannel("v1.0.0-dev-1"))
+}
@@ -0,0 +1,36 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// NormalizeVersion parses a semantic version and returns the spelling the registry stores: with a 'v'
+// prefix and the missing minor and patch numbers filled in ("1.2" becomes "v1.2.0"). Prerelease and build
+// metadata are kept as given.
+func NormalizeVersion(version string) (string, error) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return "v" + v.String(), nil
+}
+
+// VersionKey returns the identity of a version within a module: versions with the same key are the same
+// version, and only one of them can be published. Build metadata is dropped (SemVer gives it no meaning
+// for precedence) and letters are lowercased, so "v1.0.0", "1.0.0", "v1.0.0+build1" and, for prereleases,
+// "v1.0.0-RC.1" and "v1.0.0-rc.1" can't become near-duplicate versions. Strings that aren't semantic
+// versions (only found in data published before versions were validated) are lowercased as they are.
+func VersionKey(version string) string {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return strings.ToLower(version)
+	}
+	key := fmt.Sprintf("v%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+	if v.Prerelease() != "" {
+		key += "-" + v.Prerelease()
+	}
+	return strings.ToLower(key)
+}
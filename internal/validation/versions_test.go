@@ -0,0 +1,34 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeVersion(t *testing.T) {
+	for in, want := range map[string]string{
+		"v1.2.3": "v1.2.3", "1.2.3": "v1.2.3", "1.2": "v1.2.0", "v2": "v2.0.0",
+		"v1.0.0-RC.1": "v1.0.0-RC.1", "1.0.0+build.5": "v1.0.0+build.5",
+	} {
+		got, err := NormalizeVersion(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, in := range []string{"", "latest", "v1.2.3.4", "1.0.0-"} {
+		_, err := NormalizeVersion(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestVersionKey(t *testing.T) {
+	same := []string{"v1.0.0", "1.0.0", "v1.0", "v1.0.0+build1", "V1.0.0", "1.0.0+Build.2"}
+	for _, v := range same {
+		assert.Equal(t, "v1.0.0", VersionKey(v), v)
+	}
+	assert.Equal(t, "v1.0.0-rc.1", VersionKey("v1.0.0-RC.1+sha.abc"))
+	assert.Equal(t, VersionKey("v1.0.0-rc.1"), VersionKey("v1.0.0-Rc.1"))
+	assert.NotEqual(t, VersionKey("v1.0.0-rc.1"), VersionKey("v1.0.0-rc.2"))
+	assert.NotEqual(t, VersionKey("v1.0.0"), VersionKey("v1.0.0-rc.1"))
+	assert.Equal(t, "latest", VersionKey("Latest"))
+}
@@ -0,0 +1,98 @@
+package wkt
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/wellknownimports"
+)
+
+// Pinned versions of the built-in modules. Bump them when the bundled sources change.
+const (
+	// ProtobufVersion matches the protobuf release the well-known type sources come from.
+	ProtobufVersion = "v27.0.0"
+	// GoogleAPIVersion is the version of the bundled google/api annotation sources.
+	GoogleAPIVersion = "v1.0.0"
+)
+
+// Module is a built-in module version and its files (including its sproto.yaml).
+type Module struct {
+	Namespace string
+	Name      string
+	Version   string
+	Files     map[string]string
+}
+
+// protobufFiles are the well-known type sources shipped with protoc.
+var protobufFiles = []string{
+	"google/protobuf/any.proto",
+	"google/protobuf/api.proto",
+	"google/protobuf/compiler/plugin.proto",
+	"google/protobuf/cpp_features.proto",
+	"google/protobuf/descriptor.proto",
+	"google/protobuf/duration.proto",
+	"google/protobuf/empty.proto",
+	"google/protobuf/field_mask.proto",
+	"google/protobuf/java_features.proto",
+	"google/protobuf/source_context.proto",
+	"google/protobuf/struct.proto",
+	"google/protobuf/timestamp.proto",
+	"google/protobuf/type.proto",
+	"google/protobuf/wrappers.proto",
+}
+
+//go:embed google/api/*.proto
+var googleAPIFiles embed.FS
+
+// Modules returns the built-in modules: google/protobuf (well-known types) and
+// google/api (HTTP and field behavior annotations, depending on google/protobuf).
+func Modules() ([]Module, error) {
+	protobuf := Module{
+		Namespace: "google",
+		Name:      "protobuf",
+		Version:   ProtobufVersion,
+		Files:     map[string]string{"sproto.yaml": "name: google/protobuf\n"},
+	}
+	resolver := wellknownimports.WithStandardImports(protocompile.CompositeResolver{})
+	for _, p := range protobufFiles {
+		result, err := resolver.FindFileByPath(p)
+		if err != nil {
+			return nil, fmt.Errorf("well-known type %s not available: %w", p, err)
+		}
+		content, err := io.ReadAll(result.Source)
+		if closer, ok := result.Source.(io.Closer); ok {
+			closer.Close()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read well-known type %s: %w", p, err)
+		}
+		protobuf.Files[p] = string(content)
+	}
+
+	googleAPI := Module{
+		Namespace: "google",
+		Name:      "api",
+		Version:   GoogleAPIVersion,
+		Files: map[string]string{
+			"sproto.yaml": fmt.Sprintf("name: google/api\ndependencies:\n  google/protobuf: \"^%s\"\n", ProtobufVersion[1:]),
+		},
+	}
+	paths, err := fs.Glob(googleAPIFiles, "google/api/*.proto")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		content, err := googleAPIFiles.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		googleAPI.Files[p] = string(content)
+	}
+
+	return []Module{protobuf, googleAPI}, nil
+}
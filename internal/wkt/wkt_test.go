@@ -0,0 +1,39 @@
+package wkt
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/manifest"
+	"github.com/bufbuild/protocompile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModules_CompileWithoutStandardImports(t *testing.T) {
+	modules, err := Modules()
+	require.NoError(t, err)
+	require.Len(t, modules, 2)
+
+	// All sources must come from the modules themselves, not from the compiler's built-in copies
+	sources := make(map[string]string)
+	var protoFiles []string
+	for _, m := range modules {
+		mf, err := manifest.ParseManifest([]byte(m.Files[manifest.ManifestFileName]))
+		require.NoError(t, err, m.Name)
+		assert.Equal(t, m.Namespace+"/"+m.Name, mf.Name)
+		for p, content := range m.Files {
+			if strings.HasSuffix(p, ".proto") {
+				sources[p] = content
+				protoFiles = append(protoFiles, p)
+			}
+		}
+	}
+
+	compiler := protocompile.Compiler{Resolver: &protocompile.SourceResolver{Accessor: protocompile.SourceAccessorFromMap(sources)}}
+	_, err = compiler.Compile(context.Background(), protoFiles...)
+	assert.NoError(t, err)
+	assert.Contains(t, sources, "google/protobuf/timestamp.proto")
+	assert.Contains(t, sources, "google/api/annotations.proto")
+}
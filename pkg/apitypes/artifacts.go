@@ -0,0 +1,21 @@
+package apitypes
+
+import "time"
+
+// VersionArtifactResponse describes a secondary artifact of a module version.
+type VersionArtifactResponse struct {
+	Classifier  string    `json:"classifier"`
+	ContentType string    `json:"content_type"`
+	Digest      string    `json:"digest"` // sha256:<hex_digest>
+	Size        int64     `json:"size"`
+	ScanStatus  string    `json:"scan_status"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ListVersionArtifactsResponse lists the secondary artifacts of a module version.
+type ListVersionArtifactsResponse struct {
+	Namespace  string                    `json:"namespace"`
+	ModuleName string                    `json:"module_name"`
+	Version    string                    `json:"version"`
+	Artifacts  []VersionArtifactResponse `json:"artifacts"` // Sorted by classifier
+}
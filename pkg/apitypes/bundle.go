@@ -0,0 +1,11 @@
+package apitypes
+
+// Bundle formats (?format=).
+const (
+	BundleFormatZip           = "zip"            // The module's and its dependencies' .proto files, one import root
+	BundleFormatDescriptorSet = "descriptor_set" // Binary FileDescriptorSet, well-known types included
+)
+
+// BundleDependenciesHeader lists the dependency versions a bundle was assembled from
+// ("namespace/name@version", comma-separated).
+const BundleDependenciesHeader = "X-Bundle-Dependencies"
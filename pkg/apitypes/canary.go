@@ -0,0 +1,19 @@
+package apitypes
+
+// ClientIDHeader identifies the consumer for canary rollouts (or the client_id query parameter).
+// Without either, the consumer named by the caller's token is used (see api.ModuleConsumersHandler).
+const ClientIDHeader = "X-SProto-Client-ID"
+
+// CanaryRequest is the JSON body of PUT .../{version}/canary.
+type CanaryRequest struct {
+	Percent *int `json:"percent"` // Share of consumers resolving latest to the version, 0-100
+}
+
+// CanaryResponse reports the canary status of a module version.
+type CanaryResponse struct {
+	Namespace  string `json:"namespace"`
+	ModuleName string `json:"module_name"`
+	Version    string `json:"version"`
+	Canary     bool   `json:"canary"`
+	Percent    int    `json:"percent"` // Rollout percentage; 0 unless Canary
+}
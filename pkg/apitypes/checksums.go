@@ -0,0 +1,14 @@
+package apitypes
+
+// ChecksumAttestation is a version's checksum log entry in the metadata response: the registry's statement
+// that the version has its digest and is entry Index of the log, whose tree of TreeSize entries has
+// RootHash, with the inclusion proof and (if a signing key is configured) the statement's signature.
+// The tree is the log right after the version was appended, so the attestation never changes.
+type ChecksumAttestation struct {
+	Index     int64    `json:"index"`
+	TreeSize  int64    `json:"tree_size"`
+	RootHash  string   `json:"root_hash"`
+	Proof     []string `json:"proof"`               // Audit path from the leaf up, hex (RFC 6962)
+	Statement string   `json:"statement"`           // Text form of the statement (see translog.Statement)
+	Signature string   `json:"signature,omitempty"` // Base64 Ed25519 signature of statement; omitted when signing isn't configured
+}
@@ -0,0 +1,23 @@
+package apitypes
+
+import "time"
+
+// ClientInventoryEntry reports the requests of one client version by a consumer.
+type ClientInventoryEntry struct {
+	Consumer    string    `json:"consumer"`
+	Client      string    `json:"client"`  // e.g. protoreg-cli, Go-http-client, unknown
+	Version     string    `json:"version"` // Empty if the User-Agent had none
+	Requests    int64     `json:"requests"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+	Outdated    bool      `json:"outdated"` // The client checked against min_version, in an older version
+}
+
+// ClientInventoryResponse is the client inventory.
+type ClientInventoryResponse struct {
+	Client     string                 `json:"client"`                // Client checked against MinVersion
+	MinVersion string                 `json:"min_version,omitempty"` // As requested
+	Clients    []ClientInventoryEntry `json:"clients"`               // By consumer, then most recently seen first
+	// Consumers whose most recently seen version of Client is older than MinVersion
+	OutdatedConsumers []string `json:"outdated_consumers"`
+}
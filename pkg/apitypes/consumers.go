@@ -0,0 +1,26 @@
+package apitypes
+
+import "time"
+
+// ConsumerVersionUsage reports the downloads of one version by a consumer.
+type ConsumerVersionUsage struct {
+	Version       string    `json:"version"`
+	FetchCount    int64     `json:"fetch_count"`
+	LastFetchedAt time.Time `json:"last_fetched_at"`
+}
+
+// ModuleConsumer reports a consumer's downloads of a module, across versions.
+type ModuleConsumer struct {
+	Consumer       string                 `json:"consumer"`
+	FetchCount     int64                  `json:"fetch_count"`
+	FirstFetchedAt time.Time              `json:"first_fetched_at"`
+	LastFetchedAt  time.Time              `json:"last_fetched_at"`
+	Versions       []ConsumerVersionUsage `json:"versions"` // Newest download first
+}
+
+// ModuleConsumersResponse is the consumption report of a module.
+type ModuleConsumersResponse struct {
+	Namespace  string           `json:"namespace"`
+	ModuleName string           `json:"module_name"`
+	Consumers  []ModuleConsumer `json:"consumers"` // By consumer name
+}
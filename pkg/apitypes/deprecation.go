@@ -0,0 +1,21 @@
+package apitypes
+
+import "time"
+
+// DeprecateModuleVersionRequest is the (optional) JSON body of PUT .../{version}/deprecation.
+type DeprecateModuleVersionRequest struct {
+	Message  string `json:"message"`   // E.g. "use v2.0.1, which fixes billing rounding"
+	SunsetAt string `json:"sunset_at"` // Optional date (2006-01-02) or RFC3339 timestamp after which downloads stop
+}
+
+// DeprecationResponse reports the deprecation status of a module version.
+type DeprecationResponse struct {
+	Namespace    string     `json:"namespace"`
+	ModuleName   string     `json:"module_name"`
+	Version      string     `json:"version"`
+	Deprecated   bool       `json:"deprecated"`
+	Message      string     `json:"message,omitempty"`
+	DeprecatedAt *time.Time `json:"deprecated_at,omitempty"`
+	SunsetAt     *time.Time `json:"sunset_at,omitempty"`
+	Sunset       bool       `json:"sunset,omitempty"` // The sunset date passed and downloads are refused (or warned about)
+}
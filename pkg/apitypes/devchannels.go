@@ -0,0 +1,22 @@
+package apitypes
+
+import "time"
+
+// DevRevisionHeader carries the revision of a dev channel's artifact, incremented each time it changes.
+const DevRevisionHeader = "X-Dev-Revision"
+
+// DevChannelResponse describes a dev channel and its current artifact.
+type DevChannelResponse struct {
+	Namespace      string    `json:"namespace"`
+	ModuleName     string    `json:"module_name"`
+	Channel        string    `json:"channel"`
+	ArtifactDigest string    `json:"artifact_digest"` // sha256:<hex_digest>
+	ArtifactSize   int64     `json:"artifact_size"`
+	ScanStatus     string    `json:"scan_status"`
+	Revision       int       `json:"revision"`
+	Publisher      string    `json:"publisher,omitempty"` // Self-reported via X-SProto-Publisher
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// PUT only: the uploaded artifact was already the channel's, nothing changed
+	Unchanged bool `json:"unchanged,omitempty"`
+}
@@ -0,0 +1,3 @@
+// Package apitypes holds the JSON request and response bodies, header names and constants of the
+// registry's HTTP API that clients share with the server (internal/api), without depending on the server.
+package apitypes
@@ -0,0 +1,17 @@
+package apitypes
+
+// VersionFileResponse is a file of a module version's artifact.
+type VersionFileResponse struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`   // Uncompressed size in bytes
+	Digest string `json:"digest"` // sha256:<hex> of the file's content
+}
+
+// ListVersionFilesResponse lists the files of a module version's artifact.
+type ListVersionFilesResponse struct {
+	Namespace      string                `json:"namespace"`
+	ModuleName     string                `json:"module_name"`
+	Version        string                `json:"version"`
+	ArtifactDigest string                `json:"artifact_digest"` // sha256:<hex> of the whole artifact
+	Files          []VersionFileResponse `json:"files"`           // By path
+}
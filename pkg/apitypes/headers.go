@@ -0,0 +1,21 @@
+package apitypes
+
+// RequestIDHeader is the header used to propagate request IDs between clients, proxies and the server.
+const RequestIDHeader = "X-Request-ID"
+
+// Partial fetch headers. A partial artifact isn't the published artifact, so it is sent without
+// X-Artifact-Digest or the checksum statement; these identify what it was cut from instead.
+const (
+	PartialSourceDigestHeader = "X-Artifact-Source-Digest" // sha256:<hex> of the full artifact
+	PartialFilesHeader        = "X-Artifact-Files"         // Number of files in the partial artifact
+)
+
+// ProvenanceHeader carries the promotion chain of a publish: a JSON array of provenance.Source, the
+// registry where the version was originally published first and the one it's promoted from last.
+const ProvenanceHeader = "X-SProto-Provenance"
+
+// ArtifactDigestHeader carries the digest of an uploaded artifact as computed by the client
+// ("sha256:<hex>"), for publishes, dev channel publishes and secondary artifacts. The registry checks the
+// bytes it received against it before doing anything else with them, so corruption introduced by a proxy
+// or a flaky network is rejected (422) instead of being published. Optional: uploads without it aren't checked.
+const ArtifactDigestHeader = "X-Artifact-Digest"
@@ -0,0 +1,6 @@
+package apitypes
+
+// StartJobRequest is the (optional) JSON body of POST /api/v1/admin/jobs/{job}.
+type StartJobRequest struct {
+	Params map[string]string `json:"params,omitempty"`
+}
@@ -0,0 +1,43 @@
+package apitypes
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of managed tokens.
+const (
+	ManagedTokenRead       = "read"       // Reads the private modules matching its patterns (namespace/name)
+	ManagedTokenMaintainer = "maintainer" // Maintains (and reads) the namespaces matching its patterns
+)
+
+// ManagedTokenRequest is the JSON body of POST /api/v1/admin/managed-tokens, and of PUT
+// .../managed-tokens/{id}, which replaces everything but the token itself.
+type ManagedTokenRequest struct {
+	Kind        string     `json:"kind"`                  // ManagedTokenRead or ManagedTokenMaintainer
+	Consumer    string     `json:"consumer,omitempty"`    // Name in consumption reports; token:<fingerprint> if omitted
+	Patterns    []string   `json:"patterns,omitempty"`    // Private modules a read token reads (payments/*), namespaces a maintainer token maintains (payments-*)
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`  // Never expires if omitted
+	Description string     `json:"description,omitempty"` // What the token is for
+}
+
+// ManagedTokenResponse describes a managed token. The token itself is only returned when it is created.
+type ManagedTokenResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	Kind        string     `json:"kind"`
+	Consumer    string     `json:"consumer,omitempty"`
+	Patterns    []string   `json:"patterns"`
+	Description string     `json:"description,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	Expired     bool       `json:"expired"`
+	Fingerprint string     `json:"fingerprint"` // First 12 hex digits of the token's SHA256: its ID in the token report
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Token       string     `json:"token,omitempty"` // Only in the create response; store it, it can't be retrieved again
+}
+
+// ListManagedTokensResponse lists the managed tokens.
+type ListManagedTokensResponse struct {
+	Tokens []ManagedTokenResponse `json:"tokens"` // Oldest first
+}
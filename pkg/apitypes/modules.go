@@ -0,0 +1,84 @@
+package apitypes
+
+import (
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/provenance"
+)
+
+// Sort orders of the module list (?sort=).
+const (
+	ModuleSortName      = "name"       // By namespace and name (default)
+	ModuleSortUpdatedAt = "updated_at" // Most recently published first
+	ModuleSortDownloads = "downloads"  // Most downloaded first
+)
+
+// ModuleVersionResponse defines the metadata returned for a single module version.
+type ModuleVersionResponse struct {
+	Namespace      string    `json:"namespace"`
+	ModuleName     string    `json:"module_name"`
+	Version        string    `json:"version"`
+	ArtifactDigest string    `json:"artifact_digest"` // sha256:<hex_digest>
+	ArtifactSize   int64     `json:"artifact_size"`   // Bytes; 0 if unknown (published before sizes were recorded)
+	ScanStatus     string    `json:"scan_status"`
+	CreatedAt      time.Time `json:"created_at"`
+	// Deprecation status (PUT/DELETE .../{version}/deprecation)
+	Deprecated         bool       `json:"deprecated"`
+	DeprecationMessage string     `json:"deprecation_message,omitempty"`
+	SunsetAt           *time.Time `json:"sunset_at,omitempty"` // Downloads stop after this date
+	Sunset             bool       `json:"sunset"`              // The sunset is enforced
+	// Canary rollout percentage (PUT/DELETE .../{version}/canary); omitted if the version isn't a canary
+	CanaryPercent *int `json:"canary_percent,omitempty"`
+	// Notes attached after publishing, oldest first (GET only)
+	Notes []VersionNoteResponse `json:"notes"`
+	// Schema change detection (DETECT_SCHEMA_CHANGES): true if the version only changed comments or
+	// formatting compared to NoSchemaChangeFrom, so generated code needn't be regenerated
+	NoSchemaChange     bool   `json:"no_schema_change"`
+	NoSchemaChangeFrom string `json:"no_schema_change_from,omitempty"`
+	// Syntaxes and editions declared by the .proto files (proto2, proto3, edition-2023); empty if unknown
+	Syntaxes []string `json:"syntaxes"`
+
+	// Checksum log entry with its inclusion proof and signed statement (GET only; omitted if the log is disabled)
+	Checksum *ChecksumAttestation `json:"checksum,omitempty"`
+	// Registry signature made at publish (VERSION_SIGNING_KEY); omitted if the version is unsigned
+	Signature *VersionSignature `json:"signature,omitempty"`
+	// Registries the version was promoted from, the original first; omitted if it was published here
+	Provenance []provenance.Source `json:"provenance,omitempty"`
+}
+
+// PublishModuleVersionResponse defines the successful response structure.
+type PublishModuleVersionResponse struct {
+	Namespace      string    `json:"namespace"`
+	ModuleName     string    `json:"module_name"`
+	Version        string    `json:"version"`
+	ArtifactDigest string    `json:"artifact_digest"` // sha256:<hex_digest>
+	ScanStatus     string    `json:"scan_status"`     // skipped, clean, infected, error
+	CreatedAt      time.Time `json:"created_at"`
+	// RepublishedFrom is the source version when the version was created with ?from= (no upload)
+	RepublishedFrom string `json:"republished_from,omitempty"`
+	// Schema change detection (DETECT_SCHEMA_CHANGES): true if the compiled schema is identical to that of
+	// the previous version NoSchemaChangeFrom
+	NoSchemaChange     bool   `json:"no_schema_change"`
+	NoSchemaChangeFrom string `json:"no_schema_change_from,omitempty"`
+	// Syntaxes and editions declared by the .proto files (proto2, proto3, edition-2023)
+	Syntaxes []string `json:"syntaxes"`
+
+	// Digest (sha256:<hex>) of the uploaded bytes, if they differ from the stored canonical archive and
+	// are kept for audits (ORIGINAL_UPLOAD_RETENTION)
+	OriginalDigest string `json:"original_digest,omitempty"`
+	// Registry signature of the version (VERSION_SIGNING_KEY); omitted if signing isn't configured
+	Signature *VersionSignature `json:"signature,omitempty"`
+	// Registries the version was promoted from (ProvenanceHeader); omitted if none were sent
+	Provenance []provenance.Source `json:"provenance,omitempty"`
+}
+
+// ValidatePublishResponse defines the response for a validate-only (dry run) publish.
+type ValidatePublishResponse struct {
+	Valid          bool   `json:"valid"`
+	Namespace      string `json:"namespace"`
+	ModuleName     string `json:"module_name"`
+	Version        string `json:"version"`
+	ArtifactDigest string `json:"artifact_digest"` // sha256:<hex_digest>
+	ArtifactSize   int64  `json:"artifact_size"`
+	ScanStatus     string `json:"scan_status"`
+}
@@ -0,0 +1,15 @@
+package apitypes
+
+import "time"
+
+// AddVersionNoteRequest is the JSON body of the add note endpoint.
+type AddVersionNoteRequest struct {
+	Note string `json:"note"`
+}
+
+// VersionNoteResponse is a single note attached to a module version.
+type VersionNoteResponse struct {
+	Note      string    `json:"note"`
+	Author    string    `json:"author,omitempty"` // Self-reported via X-SProto-Publisher
+	CreatedAt time.Time `json:"created_at"`
+}
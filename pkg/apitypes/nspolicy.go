@@ -0,0 +1,36 @@
+package apitypes
+
+import "time"
+
+// NamespacePolicyRequest is the JSON body of PUT /api/v1/admin/namespace-policies/{namespace}. It replaces
+// the whole policy; omitted fields disable their check.
+type NamespacePolicyRequest struct {
+	LintRuleset  string   `json:"lint_ruleset"`  // minimal, basic or standard; empty to skip linting
+	CompatLevel  string   `json:"compat_level"`  // wire or source; empty to allow breaking changes
+	AllowedFiles []string `json:"allowed_files"` // Glob patterns of file names (e.g. "*.proto"); empty allows any file
+	Monotonic    bool     `json:"monotonic"`     // New versions must be newer than every published version
+	// Syntaxes and editions the .proto files may declare (proto2, proto3, edition-2023); empty allows any
+	AllowedSyntaxes []string `json:"allowed_syntaxes"`
+	// google.api.http annotations must be valid for grpc-gateway, without routes bound twice in a module
+	HTTPRules bool `json:"http_rules"`
+	// Makes the namespace ephemeral: versions are deleted this long after they were published (a duration
+	// such as "72h"); empty keeps them
+	EphemeralTTL string `json:"ephemeral_ttl,omitempty"`
+	// Content policy settings replacing the server's (ARTIFACT_CONTENT_TYPES and the like); empty (0) keeps the server's
+	ContentTypes      []string `json:"content_types"`      // Detected from the content, e.g. "application/zip"
+	MaxFileBytes      int64    `json:"max_file_bytes"`     // Largest uncompressed file in an archive
+	AllowedExtensions []string `json:"allowed_extensions"` // File extensions, e.g. ".proto"
+}
+
+// NamespacePolicyResponse describes the policy of a namespace.
+type NamespacePolicyResponse struct {
+	Namespace string `json:"namespace"`
+	NamespacePolicyRequest
+	UpdatedAt time.Time `json:"updated_at"`
+	Revision  int64     `json:"revision"` // Incremented by every update; the ETag is its quoted value
+}
+
+// ListNamespacePoliciesResponse lists the namespace policies.
+type ListNamespacePoliciesResponse struct {
+	Policies []NamespacePolicyResponse `json:"policies"`
+}
@@ -0,0 +1,32 @@
+package apitypes
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OperationResponse describes a background operation.
+type OperationResponse struct {
+	ID         string            `json:"id"`
+	Kind       string            `json:"kind"`                  // publish, or an admin job (gc, sunset, migrate-storage, import-buf)
+	Namespace  string            `json:"namespace,omitempty"`   // Module the operation is about, if any
+	ModuleName string            `json:"module_name,omitempty"` //
+	Version    string            `json:"version,omitempty"`     //
+	Params     map[string]string `json:"params,omitempty"`      // Parameters of admin jobs (secrets omitted)
+	Status     string            `json:"status"`                // queued, running, succeeded, failed or canceled
+	Stage      string            `json:"stage,omitempty"`       // While running, e.g. "scanning" or "migrating 3/120"
+	CreatedAt  time.Time         `json:"created_at"`
+	StartedAt  *time.Time        `json:"started_at,omitempty"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+
+	// Once finished: the status code and body the equivalent synchronous request would have responded with
+	// (e.g. 201 and a PublishModuleVersionResponse, or 403 and the policy violations)
+	HTTPStatus int             `json:"http_status,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"` // Error message if the operation failed or was canceled
+}
+
+// ListOperationsResponse lists background operations.
+type ListOperationsResponse struct {
+	Operations []OperationResponse `json:"operations"`
+}
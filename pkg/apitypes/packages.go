@@ -0,0 +1,13 @@
+package apitypes
+
+// PackageResponse lists the modules declaring a proto package.
+type PackageResponse struct {
+	Package string          `json:"package"`
+	Modules []PackageModule `json:"modules"` // By module name; a single one with PACKAGE_OWNERSHIP=unique
+}
+
+// PackageModule is a module declaring a proto package.
+type PackageModule struct {
+	Module  string `json:"module"`  // namespace/name
+	Version string `json:"version"` // Most recently published version declaring the package
+}
@@ -0,0 +1,32 @@
+package apitypes
+
+import "time"
+
+// PluginBinaryInfo is a downloadable executable of a plugin version.
+type PluginBinaryInfo struct {
+	Platform string `json:"platform"` // GOOS/GOARCH, e.g. "linux/amd64"
+	URL      string `json:"url"`
+	SHA256   string `json:"sha256"` // Hex digest of the executable
+}
+
+// PluginResponse describes a registered plugin version.
+type PluginResponse struct {
+	Name        string             `json:"name"`
+	Version     string             `json:"version"`
+	Image       string             `json:"image,omitempty"`
+	Binaries    []PluginBinaryInfo `json:"binaries"`
+	Description string             `json:"description,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// ListPluginsResponse lists registered plugin versions.
+type ListPluginsResponse struct {
+	Plugins []PluginResponse `json:"plugins"`
+}
+
+// RegisterPluginRequest is the JSON body of POST /api/v1/plugins/{name}/{version}.
+type RegisterPluginRequest struct {
+	Image       string             `json:"image"`
+	Binaries    []PluginBinaryInfo `json:"binaries"`
+	Description string             `json:"description"`
+}
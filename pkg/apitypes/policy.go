@@ -0,0 +1,19 @@
+package apitypes
+
+// Headers carrying client-reported context for publish policies.
+const (
+	PublisherHeader = "X-SProto-Publisher"
+	BranchHeader    = "X-SProto-Branch"
+)
+
+// PolicyDeniedResponse is returned (403) when the content policy denies an artifact.
+type PolicyDeniedResponse struct {
+	Error      string            `json:"error"`
+	Violations []PolicyViolation `json:"violations"`
+}
+
+// PolicyViolation is a publish policy that denied an artifact, and why.
+type PolicyViolation struct {
+	Policy  string `json:"policy"`
+	Message string `json:"message"`
+}
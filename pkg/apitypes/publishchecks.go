@@ -0,0 +1,32 @@
+package apitypes
+
+// Severities of validation issues.
+const (
+	SeverityError   = "error"   // Rejects the publish
+	SeverityWarning = "warning" // Reported alongside errors (e.g. compiler warnings); never rejects a publish
+)
+
+// Rules of validation issues not raised by a policy or lint rule.
+const (
+	RuleCompile = "compile" // The artifact doesn't parse or compile
+	RuleImport  = "import"  // An import resolves to no file of the artifact, its dependencies or the well-known types
+)
+
+// ValidationIssue is a problem found by the checks a publish runs on an artifact. Rules name the check:
+// "compile", "import", "lint:<rule>", "http:<rule>", "compat:<change>", the namespace policy checks
+// ("allowed-files", "syntax", "monotonic") or "policy:<name>" for publish policies.
+type ValidationIssue struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`   // 1-based; 0 if the issue isn't tied to a line
+	Column   int    `json:"column,omitempty"` // 1-based; 0 if unknown
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ValidationFailedResponse is returned (422) when the checks of a publish find errors. Every check runs, so
+// it lists all the issues at once rather than the first.
+type ValidationFailedResponse struct {
+	Error  string            `json:"error"`
+	Issues []ValidationIssue `json:"issues"`
+}
@@ -0,0 +1,21 @@
+package apitypes
+
+// ResolvedDependency is a pinned module version, as in sproto.lock.
+type ResolvedDependency struct {
+	Module  string `json:"module"` // namespace/name
+	Version string `json:"version"`
+	Digest  string `json:"digest,omitempty"` // sha256:<hex> of the artifact; not needed in requests
+}
+
+// ResolveRequest is the JSON body of POST /api/v1/resolve.
+type ResolveRequest struct {
+	Dependencies map[string]string    `json:"dependencies"`     // Root constraints: module -> semver constraint, as in sproto.yaml
+	Locked       []ResolvedDependency `json:"locked,omitempty"` // Versions to keep (e.g. from sproto.lock) while they satisfy all constraints
+	Update       []string             `json:"update,omitempty"` // Modules allowed to move away from their locked version; all if empty
+	Latest       bool                 `json:"latest,omitempty"` // Ignore the constraints for updated modules and pick their newest version
+}
+
+// ResolveResponse is the resolved set of dependency versions, transitive dependencies included.
+type ResolveResponse struct {
+	Dependencies []ResolvedDependency `json:"dependencies"` // By module name
+}
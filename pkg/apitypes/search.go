@@ -0,0 +1,24 @@
+package apitypes
+
+// Snippet highlighting: matched words are wrapped in these markers.
+const (
+	SearchMatchStart = "<mark>"
+	SearchMatchEnd   = "</mark>"
+)
+
+// SearchResponse is the response of GET /api/v1/search.
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"` // Best matches first
+}
+
+// SearchResult is a module, file or declaration matching a search.
+type SearchResult struct {
+	Module  string  `json:"module"`  // namespace/name
+	Version string  `json:"version"` // Version the file or declaration was indexed from (the newest)
+	Kind    string  `json:"kind"`    // module, file, message, enum, service, method or field
+	Name    string  `json:"name"`    // Module name, file path or fully-qualified declaration name
+	File    string  `json:"file,omitempty"`
+	Snippet string  `json:"snippet,omitempty"` // Excerpt of the description or comment, matches wrapped in <mark></mark>
+	Rank    float64 `json:"rank"`              // Relevance; only comparable within one response
+}
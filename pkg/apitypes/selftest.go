@@ -0,0 +1,20 @@
+package apitypes
+
+// StorageSelfTestStep reports one step of a storage self-test.
+type StorageSelfTestStep struct {
+	Step       string  `json:"step"` // write, stat, read or delete
+	OK         bool    `json:"ok"`
+	Skipped    bool    `json:"skipped,omitempty"` // Not run because the write failed
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// StorageSelfTestResponse reports a storage self-test.
+type StorageSelfTestResponse struct {
+	Provider        string                `json:"provider,omitempty"` // minio or local
+	OK              bool                  `json:"ok"`
+	Key             string                `json:"key"` // Probe object
+	SizeBytes       int64                 `json:"size_bytes"`
+	TotalDurationMs float64               `json:"total_duration_ms"`
+	Steps           []StorageSelfTestStep `json:"steps"`
+}
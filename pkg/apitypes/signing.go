@@ -0,0 +1,25 @@
+package apitypes
+
+// SigningKeysPath serves the registry's version signing keys (see api.SigningKeysHandler).
+const SigningKeysPath = "/.well-known/sproto/signing-keys"
+
+// VersionSignature is a version's signature in metadata and publish responses.
+type VersionSignature struct {
+	Algorithm string `json:"algorithm"` // ed25519
+	KeyID     string `json:"key_id"`    // Key among those at /.well-known/sproto/signing-keys
+	Payload   string `json:"payload"`   // Signed text (see provenance.Payload)
+	Signature string `json:"signature"` // Base64
+}
+
+// SigningKey is a public key in the signing keys response.
+type SigningKey struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`  // ed25519
+	PublicKey string `json:"public_key"` // Base64
+	Current   bool   `json:"current"`    // Signs new versions; retired keys only verify older signatures
+}
+
+// SigningKeysResponse lists the registry's version signing keys.
+type SigningKeysResponse struct {
+	Keys []SigningKey `json:"keys"` // Current key first; empty if signing isn't configured
+}
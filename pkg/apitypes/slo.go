@@ -0,0 +1,29 @@
+package apitypes
+
+import "time"
+
+// SLOLatency reports estimated latency percentiles of a window, in milliseconds.
+type SLOLatency struct {
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Mean float64 `json:"mean"`
+}
+
+// SLOWindow reports the downloads of a module over a window ending now.
+type SLOWindow struct {
+	Window       string      `json:"window"`
+	Since        time.Time   `json:"since"` // Start of the oldest hour counted
+	Requests     int64       `json:"requests"`
+	Errors       int64       `json:"errors"`
+	Availability *float64    `json:"availability"` // Share of requests without a server error; null without requests
+	LatencyMs    *SLOLatency `json:"latency_ms,omitempty"`
+}
+
+// ModuleSLOResponse is the SLO report of a module.
+type ModuleSLOResponse struct {
+	Namespace  string      `json:"namespace"`
+	ModuleName string      `json:"module_name"`
+	Kind       string      `json:"kind,omitempty"` // Only downloads of this kind are counted
+	Windows    []SLOWindow `json:"windows"`
+}
@@ -0,0 +1,46 @@
+package apitypes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenExpiryHeader is set on authenticated responses if the token expires, so clients can warn
+// before it lapses.
+const TokenExpiryHeader = "X-SProto-Token-Expires"
+
+// ParseTokenExpiry parses a token expiry: a date ("2027-01-01", expiring at midnight UTC) or an
+// RFC3339 timestamp. An empty string means the token doesn't expire (nil).
+func ParseTokenExpiry(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid token expiry %q: expected a date (2006-01-02) or an RFC3339 timestamp", value)
+}
+
+// TokenReportEntry describes one configured or managed token. The token itself is never returned.
+type TokenReportEntry struct {
+	ID         string     `json:"id"`                 // First 12 hex digits of the token's SHA256
+	Kind       string     `json:"kind"`               // "admin", "read" or "maintainer"
+	Consumer   string     `json:"consumer"`           // Name in consumption reports (see api.ModuleConsumersHandler)
+	Patterns   []string   `json:"patterns,omitempty"` // Modules of a read token, namespaces of a maintainer token
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Expired    bool       `json:"expired"`
+	LastUsedAt *time.Time `json:"last_used_at"`         // null if never used (or not since tracking started)
+	Stale      bool       `json:"stale"`                // Not used within stale_after
+	ManagedID  *uuid.UUID `json:"managed_id,omitempty"` // ID of a token managed through the admin API (see ManagedTokenResponse)
+}
+
+// TokenReportResponse is the response of the token report endpoint.
+type TokenReportResponse struct {
+	StaleAfter string             `json:"stale_after"`
+	Tokens     []TokenReportEntry `json:"tokens"`
+}
@@ -0,0 +1,26 @@
+package apitypes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTokenExpiry(t *testing.T) {
+	expiry, err := ParseTokenExpiry("")
+	assert.NoError(t, err)
+	assert.Nil(t, expiry)
+
+	expiry, err = ParseTokenExpiry("2027-01-01")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), *expiry)
+
+	expiry, err = ParseTokenExpiry("2027-01-01T12:00:00+02:00")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2027, 1, 1, 10, 0, 0, 0, time.UTC), *expiry)
+	assert.Equal(t, time.UTC, expiry.Location())
+
+	_, err = ParseTokenExpiry("next year")
+	assert.ErrorContains(t, err, "invalid token expiry")
+}
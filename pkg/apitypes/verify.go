@@ -0,0 +1,28 @@
+package apitypes
+
+import "time"
+
+// VerifyRequest is the JSON body of POST /api/v1/verify.
+type VerifyRequest struct {
+	Namespace  string `json:"namespace"`
+	ModuleName string `json:"module_name"`
+	Version    string `json:"version"`
+	Digest     string `json:"digest"` // sha256:<hex_digest> (or the bare hex digest)
+}
+
+// VerifyResponse reports whether a digest matches the one recorded for a module version, and the
+// status of the version. A mismatch is a successful verification with Match false.
+type VerifyResponse struct {
+	Namespace      string `json:"namespace"`
+	ModuleName     string `json:"module_name"`
+	Version        string `json:"version"`
+	Digest         string `json:"digest"`          // The digest that was checked, sha256:<hex_digest>
+	Match          bool   `json:"match"`           // Digest is the recorded artifact digest
+	RecordedDigest string `json:"recorded_digest"` // sha256:<hex_digest>
+	ScanStatus     string `json:"scan_status"`
+	// Deprecation status (see PUT .../{version}/deprecation)
+	Deprecated         bool       `json:"deprecated"`
+	DeprecationMessage string     `json:"deprecation_message,omitempty"`
+	SunsetAt           *time.Time `json:"sunset_at,omitempty"`
+	Sunset             bool       `json:"sunset"` // The version is withdrawn: downloads are refused (410)
+}
@@ -0,0 +1,31 @@
+package apitypes
+
+import "fmt"
+
+// Module visibility levels, enforced on read endpoints.
+const (
+	VisibilityPublic   = "public"   // Anyone, no token required
+	VisibilityInternal = "internal" // Any valid token
+	VisibilityPrivate  = "private"  // Only the admin token and read tokens granted the module
+)
+
+// ValidateVisibility checks that visibility is one of the supported levels.
+func ValidateVisibility(visibility string) error {
+	switch visibility {
+	case VisibilityPublic, VisibilityInternal, VisibilityPrivate:
+		return nil
+	}
+	return fmt.Errorf("invalid visibility %q: must be %s, %s or %s", visibility, VisibilityPublic, VisibilityInternal, VisibilityPrivate)
+}
+
+// SetModuleVisibilityRequest is the JSON body of PUT .../visibility.
+type SetModuleVisibilityRequest struct {
+	Visibility string `json:"visibility"`
+}
+
+// ModuleVisibilityResponse reports the visibility of a module.
+type ModuleVisibilityResponse struct {
+	Namespace  string `json:"namespace"`
+	ModuleName string `json:"module_name"`
+	Visibility string `json:"visibility"`
+}
@@ -0,0 +1,15 @@
+package apitypes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateVisibility(t *testing.T) {
+	for _, visibility := range []string{VisibilityPublic, VisibilityInternal, VisibilityPrivate} {
+		assert.NoError(t, ValidateVisibility(visibility))
+	}
+	assert.EqualError(t, ValidateVisibility("secret"), `invalid visibility "secret": must be public, internal or private`)
+	assert.Error(t, ValidateVisibility(""))
+}
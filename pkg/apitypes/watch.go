@@ -0,0 +1,38 @@
+package apitypes
+
+import "time"
+
+// WatchEventVersion is the SSE event type of a new version.
+const WatchEventVersion = "version"
+
+// VersionEvent is the data of a version event: a new version of a watched module.
+type VersionEvent struct {
+	Namespace       string        `json:"namespace"`
+	ModuleName      string        `json:"module_name"`
+	Version         string        `json:"version"`
+	PreviousVersion string        `json:"previous_version,omitempty"` // Newest older version; omitted for the first
+	CreatedAt       time.Time     `json:"created_at"`
+	Changes         []ElementDiff `json:"changes"`              // Elements that differ from PreviousVersion's
+	DiffError       string        `json:"diff_error,omitempty"` // Why Changes is empty although the schema may have changed
+	// Binary FileDescriptorSet of the version and its dependencies, to refresh what the diff doesn't describe
+	DescriptorSetURL string `json:"descriptor_set_url"`
+}
+
+// Changes of an ElementDiff.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// ElementDiff is an element of a module's schema that differs between two versions (see
+// descriptor.SchemaDiff, whose diffs convert to it).
+type ElementDiff struct {
+	Change  string `json:"change"`  // added, removed or changed
+	Kind    string `json:"kind"`    // file, message, field, enum, enum_value, service or method
+	Element string `json:"element"` // Fully-qualified name (the path for files)
+	File    string `json:"file"`    // File defining the element (the base version's if removed)
+	// Signatures of fields ("repeated string = 3"), enum values ("= 2") and methods ("(Req) returns (Resp)")
+	Before string `json:"before,omitempty"` // In the base version, if removed or changed
+	After  string `json:"after,omitempty"`  // In the new version, if added or changed
+}
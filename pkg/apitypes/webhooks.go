@@ -0,0 +1,34 @@
+package apitypes
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookSubscriptionRequest is the JSON body of POST /api/v1/namespaces/{namespace}/webhooks, and of PUT
+// .../webhooks/{id}, which replaces the whole subscription (an omitted secret makes it unsigned).
+type WebhookSubscriptionRequest struct {
+	URL         string   `json:"url"`                   // http or https
+	Secret      string   `json:"secret,omitempty"`      // Signs the requests (X-SProto-Signature); never returned
+	EventTypes  []string `json:"event_types,omitempty"` // Event types or patterns (module_version.*); empty for all
+	Description string   `json:"description,omitempty"`
+}
+
+// WebhookSubscriptionResponse describes a subscription. The secret is never returned.
+type WebhookSubscriptionResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Namespace   string    `json:"namespace"`
+	URL         string    `json:"url"`
+	EventTypes  []string  `json:"event_types"` // Empty for all events
+	Description string    `json:"description,omitempty"`
+	HasSecret   bool      `json:"has_secret"`
+	CreatedBy   string    `json:"created_by"` // Consumer of the token that created it
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ListWebhookSubscriptionsResponse lists the subscriptions of a namespace.
+type ListWebhookSubscriptionsResponse struct {
+	Subscriptions []WebhookSubscriptionResponse `json:"subscriptions"` // Oldest first
+	EventTypes    []string                      `json:"event_types"`   // Event types that can be subscribed to
+}
@@ -0,0 +1,375 @@
+// Package server embeds the SProto registry in another Go program: New initializes the registry from a
+// Config and Handler returns its HTTP handler, to be mounted in the program's own server and wrapped in its
+// own middleware.
+//
+// The registry keeps its database, storage and policies in process-wide state, so a process runs at most
+// one Server: New fails while another is open, until it is closed.
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Suhaibinator/SProto/internal/api"
+	"github.com/Suhaibinator/SProto/internal/cdn"
+	"github.com/Suhaibinator/SProto/internal/config"
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/Suhaibinator/SProto/internal/descriptor"
+	"github.com/Suhaibinator/SProto/internal/grpcserver"
+	"github.com/Suhaibinator/SProto/internal/integrity"
+	"github.com/Suhaibinator/SProto/internal/logging"
+	"github.com/Suhaibinator/SProto/internal/metrics"
+	"github.com/Suhaibinator/SProto/internal/notify"
+	"github.com/Suhaibinator/SProto/internal/policy"
+	"github.com/Suhaibinator/SProto/internal/provenance"
+	"github.com/Suhaibinator/SProto/internal/scan"
+	"github.com/Suhaibinator/SProto/internal/storage"
+	"github.com/Suhaibinator/SProto/internal/translog"
+	"github.com/Suhaibinator/SProto/pkg/apitypes"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// Config is the registry configuration; see the Server Configuration section of the README for the settings.
+type Config = config.Config
+
+// LoadConfig loads the configuration from PROTOREG_* environment variables, with the defaults of sproto-server.
+func LoadConfig() (Config, error) {
+	return config.LoadConfig()
+}
+
+// open is set from New until the Server is closed.
+var open atomic.Bool
+
+// Server is an initialized registry.
+type Server struct {
+	cfg       Config
+	log       *zap.Logger
+	router    *mux.Router
+	cancel    context.CancelFunc // Stops the background jobs
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// New initializes logging, the database, storage, the virus scanner and the other components configured in
+// cfg, and starts the background jobs (stopped by Close). /metrics is served by Handler unless
+// cfg.MetricsListenAddress is set; MetricsHandler serves it separately. It fails if another Server of the
+// process hasn't been closed.
+func New(cfg Config) (*Server, error) {
+	if !open.CompareAndSwap(false, true) {
+		return nil, errors.New("a registry Server is already open in this process; Close it first")
+	}
+	log, err := InitBackends(cfg)
+	if err != nil {
+		_ = db.Close()
+		open.Store(false)
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{cfg: cfg, log: log, cancel: cancel}
+	if err := s.init(ctx); err != nil {
+		cancel()
+		_ = db.Close()
+		open.Store(false)
+		return nil, err
+	}
+	return s, nil
+}
+
+// init configures the api package and starts the background jobs, which run until ctx is canceled.
+func (s *Server) init(ctx context.Context) error {
+	cfg, log := s.cfg, s.log
+
+	// Initialize Virus Scanner (optional, disabled if no ClamAV address is configured)
+	if _, err := scan.InitScanner(cfg); err != nil {
+		return fmt.Errorf("failed to initialize virus scanner: %w", err)
+	}
+
+	// Initialize webhook notifications (optional, disabled if no webhook URL is configured), and deliver
+	// events to the webhook subscriptions of their namespace
+	notify.InitNotifier(cfg)
+	notify.SetSubscriptionFinder(api.FindWebhookSubscriptions)
+
+	// Publish policies (optional, disabled if no policy file is configured)
+	if _, err := policy.InitEngine(cfg); err != nil {
+		return fmt.Errorf("failed to load publish policies: %w", err)
+	}
+
+	// Per-module soft quota (warnings only)
+	api.SetModuleQuota(api.ModuleQuota{SoftBytes: cfg.ModuleSoftQuotaBytes, WarnPercent: cfg.ModuleQuotaWarnPercent})
+
+	// Cache-Control headers (long-lived for immutable artifacts, short for listings)
+	api.SetCachePolicy(api.CachePolicy{ArtifactMaxAge: cfg.ArtifactCacheMaxAge, ListMaxAge: cfg.ListCacheMaxAge})
+
+	// Upload size and per-endpoint concurrency limits
+	api.SetRequestLimits(api.RequestLimits{
+		MaxUploadBytes:               cfg.MaxUploadSizeBytes,
+		MaxConcurrentPublishes:       cfg.MaxConcurrentPublishes,
+		MaxConcurrentArtifactStreams: cfg.MaxConcurrentArtifactStreams,
+		QueueTimeout:                 cfg.LimitQueueTimeout,
+	})
+
+	// Artifact content policy (content types, file sizes and extensions), checked on every publish path
+	if err := api.SetContentPolicy(strings.Split(cfg.ArtifactContentTypes, ","), strings.Split(cfg.ArtifactAllowedExtensions, ","), cfg.ArtifactMaxFileBytes); err != nil {
+		return fmt.Errorf("invalid artifact content policy: %w", err)
+	}
+
+	// Background operations: asynchronous publishes (?async=true) and admin jobs
+	api.SetOperationQueue(api.OperationQueue{Workers: cfg.OperationWorkers, Size: cfg.OperationQueueSize})
+	go api.RunOperationWorkers(ctx)
+
+	// Module visibility (read tokens, anonymous read and the visibility of newly created modules)
+	readTokens, err := api.ParseReadTokens(cfg.ReadTokens)
+	if err != nil {
+		return fmt.Errorf("invalid READ_TOKENS: %w", err)
+	}
+	maintainerTokens, err := api.ParseMaintainerTokens(cfg.MaintainerTokens)
+	if err != nil {
+		return fmt.Errorf("invalid MAINTAINER_TOKENS: %w", err)
+	}
+	for token, mt := range maintainerTokens {
+		if _, dup := readTokens[token]; dup || token == cfg.AuthToken {
+			return fmt.Errorf("invalid MAINTAINER_TOKENS: a maintainer token is also configured as another token")
+		}
+		readTokens[token] = mt
+	}
+	api.SetReadTokens(readTokens)
+	// Tokens managed through the admin API, reloaded to pick up changes made through other replicas
+	if err := api.LoadManagedTokens(ctx); err != nil {
+		return fmt.Errorf("failed to load managed tokens: %w", err)
+	}
+	go api.RunManagedTokenRefresher(ctx, 30*time.Second)
+	if !cfg.PublicRead && cfg.AuthToken == "" {
+		return fmt.Errorf("PUBLIC_READ=false requires AUTH_TOKEN: without it authentication is disabled and everything is readable")
+	}
+	api.SetPublicRead(cfg.PublicRead)
+	if err := api.SetDefaultVisibility(cfg.DefaultModuleVisibility); err != nil {
+		return fmt.Errorf("invalid DEFAULT_MODULE_VISIBILITY: %w", err)
+	}
+
+	// Token expiry and last-used tracking (uses are persisted in the background)
+	adminExpiresAt, err := apitypes.ParseTokenExpiry(cfg.AuthTokenExpiresAt)
+	if err != nil {
+		return fmt.Errorf("invalid AUTH_TOKEN_EXPIRES_AT: %w", err)
+	}
+	api.SetTokenPolicy(api.TokenPolicy{AdminExpiresAt: adminExpiresAt, StaleAfter: cfg.StaleTokenAfter})
+	go api.RunTokenUsageFlusher(ctx, time.Minute)
+	go api.RunConsumptionFlusher(ctx, time.Minute)     // Consumption reports
+	go api.RunSLOFlusher(ctx, time.Minute)             // Fetch SLO reports
+	go api.RunClientInventoryFlusher(ctx, time.Minute) // Client inventory
+	go api.RunVersionWatcher(ctx, 5*time.Second)       // Schema watch streams
+
+	// Brute-force protection (escalating delays and temporary bans after failed authentication)
+	api.SetAuthFailurePolicy(api.AuthFailurePolicy{
+		MaxFailures:    cfg.AuthMaxFailures,
+		Window:         cfg.AuthFailureWindow,
+		BanDuration:    cfg.AuthBanDuration,
+		FailureDelay:   cfg.AuthFailureDelay,
+		ClientIPHeader: cfg.AuthClientIPHeader,
+	})
+
+	// Network restrictions (write allowlist and denylist by client IP, checked before authentication)
+	writeAllowed, err := api.ParseCIDRs(cfg.WriteAllowedCIDRs)
+	if err != nil {
+		return fmt.Errorf("invalid WRITE_ALLOWED_CIDRS: %w", err)
+	}
+	denied, err := api.ParseCIDRs(cfg.DeniedCIDRs)
+	if err != nil {
+		return fmt.Errorf("invalid DENIED_CIDRS: %w", err)
+	}
+	api.SetIPFilterPolicy(api.IPFilterPolicy{WriteAllowed: writeAllowed, Denied: denied})
+
+	// Proto package index: one owning module per package, or shared packages
+	if err := api.SetPackageOwnership(cfg.PackageOwnership); err != nil {
+		return fmt.Errorf("invalid PACKAGE_OWNERSHIP: %w", err)
+	}
+
+	// Sunset dates of deprecated versions (enforced by a background job; disabled if the interval is 0)
+	if err := api.SetSunsetEnforcement(cfg.SunsetEnforcement); err != nil {
+		return fmt.Errorf("invalid SUNSET_ENFORCEMENT: %w", err)
+	}
+	if cfg.SunsetCheckInterval > 0 {
+		go api.RunSunsetEnforcer(ctx, cfg.SunsetCheckInterval)
+	}
+
+	// Original uploads kept for audits (the cleanup job also runs after retention is turned off, until
+	// the originals kept before have expired)
+	api.SetOriginalUploadRetention(cfg.OriginalUploadRetention)
+	if cfg.OriginalUploadCleanupInterval > 0 {
+		go api.RunOriginalUploadCleanup(ctx, cfg.OriginalUploadCleanupInterval)
+	}
+
+	// Dev channels not updated for DEV_CHANNEL_TTL are deleted, checked hourly (disabled if the TTL is 0)
+	api.SetDevChannelTTL(cfg.DevChannelTTL)
+	if cfg.DevChannelTTL > 0 {
+		go api.RunDevChannelCleanup(ctx, time.Hour)
+	}
+
+	// Versions of ephemeral namespaces are deleted once their namespace's TTL has passed
+	if cfg.EphemeralCleanupInterval > 0 {
+		go api.RunEphemeralCleanup(ctx, cfg.EphemeralCleanupInterval)
+	}
+
+	// Storage tiering: aging artifacts are moved to the provider's cold tier (disabled if the age is 0)
+	api.SetStorageTieringAge(cfg.StorageTieringAge)
+	if cfg.StorageTieringAge > 0 {
+		if (strings.EqualFold(cfg.StorageType, "minio") && cfg.MinioColdStorageClass == "") || (strings.EqualFold(cfg.StorageType, "local") && cfg.LocalColdStoragePath == "") {
+			return fmt.Errorf("STORAGE_TIERING_AGE requires a cold storage tier: set MINIO_COLD_STORAGE_CLASS or LOCAL_COLD_STORAGE_PATH")
+		}
+		if cfg.StorageTieringInterval > 0 {
+			go api.RunStorageTiering(ctx, cfg.StorageTieringInterval)
+		}
+	}
+
+	// Storage and database capacity for /metrics and /readyz (collector disabled if the interval is 0)
+	api.SetCapacityPolicy(api.CapacityPolicy{StorageBytes: cfg.StorageCapacityBytes, DatabaseBytes: cfg.DBCapacityBytes, WarnPercent: cfg.CapacityWarnPercent})
+	if cfg.CapacityCollectInterval > 0 {
+		go api.RunCapacityCollector(ctx, cfg.CapacityCollectInterval)
+	}
+
+	// Tag versions without schema changes (compiles the previous version on publish)
+	api.SetSchemaChangeDetection(cfg.DetectSchemaChanges)
+
+	// Go module proxy of generated Go code (optional, disabled if no modules are enabled)
+	if err := api.SetGoProxy(cfg.GoProxyModulePrefix, strings.Split(cfg.GoProxyModules, ",")); err != nil {
+		return fmt.Errorf("invalid Go module proxy configuration: %w", err)
+	}
+
+	// Maven and npm bridges of generated stubs (optional, disabled without a group prefix / scope)
+	if err := api.SetMavenGroupPrefix(cfg.MavenGroupPrefix); err != nil {
+		return fmt.Errorf("invalid Maven bridge configuration: %w", err)
+	}
+	if err := api.SetNPMScope(cfg.NPMScope); err != nil {
+		return fmt.Errorf("invalid npm bridge configuration: %w", err)
+	}
+
+	// CDN signed URL mode (optional, disabled if no CDN base URL is configured)
+	if cfg.CDNBaseURL != "" {
+		signer, err := cdn.NewSigner(cfg.CDNBaseURL, cfg.CDNSigningKey, cfg.CDNURLTTL)
+		if err != nil {
+			return fmt.Errorf("failed to configure CDN signed URLs: %w", err)
+		}
+		api.SetCDNSigner(signer)
+		log.Info("CDN signed URL mode enabled", zap.String("cdn_base_url", cfg.CDNBaseURL), zap.Duration("url_ttl", cfg.CDNURLTTL))
+	}
+
+	// Background integrity verification of stored artifacts (disabled if the interval is 0)
+	if cfg.IntegrityCheckInterval > 0 && cfg.IntegrityCheckSampleSize > 0 {
+		verifier := integrity.NewVerifier(db.GetDB(), storage.GetStorageProvider(), cfg.IntegrityCheckInterval, cfg.IntegrityCheckSampleSize, cfg.IntegrityCheckPause)
+		go verifier.Run(ctx)
+	}
+
+	// Initialize Router
+	s.router = mux.NewRouter()
+
+	// Register API routes
+	api.RegisterRoutes(s.router, cfg.AuthToken) // Pass the router and auth token
+	if cfg.MetricsListenAddress == "" {
+		api.RegisterMetricsRoute(s.router)
+	}
+	return nil
+}
+
+// Handler returns the HTTP handler serving the registry API (/api/v1, /cdn/v1, /health, /readyz and,
+// unless cfg.MetricsListenAddress is set, /metrics). Mount it at the root of a server or behind
+// http.StripPrefix.
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+// MetricsHandler returns the handler serving the Prometheus metrics, for programs serving them separately.
+func (s *Server) MetricsHandler() http.Handler {
+	return metrics.Handler()
+}
+
+// NewGRPCServer returns a gRPC server offering Server Reflection for the schemas of public modules.
+// The caller serves it on a listener of its choosing, and stops it.
+func (s *Server) NewGRPCServer() *grpc.Server {
+	loader := grpcserver.PublicOnly(descriptor.NewLoader(db.GetDB(), storage.GetStorageProvider()), api.IsPublicModule)
+	return grpcserver.NewServer(loader)
+}
+
+// Logger returns the logger the registry logs to (configured by cfg.LogLevel and cfg.LogFormat).
+func (s *Server) Logger() *zap.Logger {
+	return s.log
+}
+
+// Close stops the background jobs and the read replica's health checks, closes the database connections
+// and flushes the logger; New can then open another Server. Requests in flight fail once the connections
+// are closed: shut down the HTTP server serving Handler first.
+func (s *Server) Close() error {
+	s.closeOnce.Do(func() {
+		s.cancel()
+		s.closeErr = db.Close()
+		_ = s.log.Sync() // Fails for stderr on some platforms
+		open.Store(false)
+	})
+	return s.closeErr
+}
+
+// InitBackends initializes logging, the database (and the checksum log kept in it) and storage, without the
+// rest of the registry. New calls it; maintenance commands working on the stored data use it directly.
+func InitBackends(cfg Config) (*zap.Logger, error) {
+	// Initialize structured logging
+	log, err := logging.Init(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	// Initialize Database (Postgres or SQLite)
+	if _, err := db.Init(cfg); err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	// Checksum log of published digests. Versions missing from it (published before it existed, or whose
+	// append failed after the publish) are appended first, so it covers every version.
+	checksums := translog.New(db.GetDB())
+	if appended, err := checksums.Sync(context.Background()); err != nil {
+		log.Error("Failed to append missing versions to the checksum log", zap.Error(err))
+	} else if appended > 0 {
+		log.Info("Appended missing versions to the checksum log", zap.Int("count", appended))
+	}
+	api.SetChecksumLog(checksums)
+	if cfg.ChecksumSigningKey != "" {
+		key, err := translog.ParsePrivateKey(cfg.ChecksumSigningKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CHECKSUM_SIGNING_KEY: %w", err)
+		}
+		api.SetChecksumSigningKey(key)
+		log.Info("Signing checksum statements", zap.String("public_key", translog.EncodePublicKey(key)))
+	}
+	var signingKey ed25519.PrivateKey
+	if cfg.VersionSigningKey != "" {
+		if signingKey, err = translog.ParsePrivateKey(cfg.VersionSigningKey); err != nil {
+			return nil, fmt.Errorf("invalid VERSION_SIGNING_KEY: %w", err)
+		}
+		log.Info("Signing published versions", zap.String("key_id", provenance.KeyID(signingKey.Public().(ed25519.PublicKey))))
+	}
+	var retiredKeys []ed25519.PublicKey
+	for _, encoded := range strings.Split(cfg.VersionSigningRetiredKeys, ",") {
+		if strings.TrimSpace(encoded) == "" {
+			continue
+		}
+		key, err := translog.ParsePublicKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VERSION_SIGNING_RETIRED_KEYS: %w", err)
+		}
+		retiredKeys = append(retiredKeys, key)
+	}
+	api.SetVersionSigningKeys(signingKey, retiredKeys)
+
+	// Initialize Storage (Minio or Local)
+	if _, err := storage.InitStorage(cfg); err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	return log, nil
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/Suhaibinator/SProto/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Embedded(t *testing.T) {
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	cfg.LogLevel = "error"
+	cfg.StorageType = "local"
+	cfg.LocalStoragePath = filepath.Join(dir, "storage")
+
+	// Configuration errors are returned, not fatal
+	cfg.DbType = "oracle"
+	_, err = New(cfg)
+	assert.ErrorContains(t, err, "invalid DB_TYPE")
+
+	cfg.DbType = "sqlite"
+	cfg.SqlitePath = filepath.Join(dir, "sproto.db")
+	srv, err := New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = srv.Close() })
+
+	// One Server per process
+	_, err = New(cfg)
+	assert.ErrorContains(t, err, "already open")
+
+	// Mounted under a prefix of the embedding program, behind its own middleware
+	var seen []string
+	mux := http.NewServeMux()
+	mux.Handle("/registry/", http.StripPrefix("/registry", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.URL.Path)
+		srv.Handler().ServeHTTP(w, r)
+	})))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	for _, path := range []string{"/health", "/readyz", "/api/v1/modules", "/metrics"} {
+		resp, err := http.Get(ts.URL + "/registry" + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, path)
+	}
+	assert.Equal(t, []string{"/health", "/readyz", "/api/v1/modules", "/metrics"}, seen)
+}
+
+func TestClose_AllowsNewServer(t *testing.T) {
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	cfg.LogLevel = "error"
+	cfg.StorageType = "local"
+	cfg.LocalStoragePath = filepath.Join(dir, "storage")
+	cfg.DbType = "sqlite"
+	cfg.SqlitePath = filepath.Join(dir, "sproto.db")
+
+	srv, err := New(cfg)
+	require.NoError(t, err)
+	sqlDB, err := db.GetDB().DB()
+	require.NoError(t, err)
+	require.NoError(t, srv.Close())
+	assert.Error(t, sqlDB.Ping(), "the database connections are closed")
+	assert.NoError(t, srv.Close(), "closing twice is a no-op")
+
+	srv, err = New(cfg)
+	require.NoError(t, err)
+	assert.NoError(t, srv.Close())
+}
@@ -8,6 +8,8 @@ CREATE TABLE modules (
     name VARCHAR(255) NOT NULL,
     created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Who may read the module: 'public', 'internal' (any valid token) or 'private' (granted tokens only)
+    visibility VARCHAR(16) NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'internal', 'private')),
 
     -- Ensure unique combination of namespace and name
     CONSTRAINT uq_module_namespace_name UNIQUE (namespace, name)
@@ -22,10 +24,38 @@ CREATE TABLE module_versions (
     module_id UUID NOT NULL REFERENCES modules(id) ON DELETE CASCADE,
     -- Store version string directly (e.g., "v1.2.3")
     version VARCHAR(100) NOT NULL CHECK (version ~ '^v(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$'), -- Basic SemVer check constraint
+    -- Identity of the version within the module: lowercased, without build metadata (v1.0.0+build1 -> v1.0.0)
+    version_key VARCHAR(100) NOT NULL DEFAULT '',
     -- SHA256 digest of the artifact zip file for integrity
     artifact_digest VARCHAR(64) NOT NULL, -- SHA256 hex string length
     -- The key (path) within the MinIO bucket where the artifact is stored
     artifact_storage_key TEXT NOT NULL,
+    -- Storage key layout version: 1 = modules/<module_id>/<version>/protos.zip, 2 = v2/modules/<ns>/<name>/<version>/<digest>.zip
+    artifact_key_layout SMALLINT NOT NULL DEFAULT 1,
+    -- Size of the artifact zip in bytes (0 if unknown)
+    artifact_size BIGINT NOT NULL DEFAULT 0,
+    -- When the storage tiering job moved the artifact to the cold storage tier (NULL while it is hot)
+    artifact_tiered_at TIMESTAMPTZ,
+    -- Virus scan outcome ('skipped', 'clean', 'infected', 'error') and detected signature/error
+    scan_status VARCHAR(20) NOT NULL DEFAULT 'skipped',
+    scan_result TEXT,
+    -- Section of the artifact's CHANGELOG.md for this version (empty if none)
+    changelog TEXT,
+    -- Syntax labels declared by the .proto files (proto2, proto3, edition-2023), sorted and comma-separated; NULL if unknown
+    syntaxes VARCHAR(255),
+    -- Set when the version is deprecated (NULL if it isn't), with an optional message for consumers
+    deprecated_at TIMESTAMPTZ,
+    deprecation_message TEXT,
+    -- Optional sunset date of a deprecated version, and when the sunset job started enforcing it
+    sunset_at TIMESTAMPTZ,
+    sunset_enforced_at TIMESTAMPTZ,
+    -- Previous version with an identical compiled schema (only comments/formatting changed); NULL if the schema changed or wasn't compared
+    no_schema_change_from VARCHAR(100),
+    -- Registry signature of the version's coordinates and digest (VERSION_SIGNING_KEY) and the ID of the key that made it; NULL if unsigned
+    signature VARCHAR(128),
+    signing_key_id VARCHAR(32),
+    -- Registries the version was promoted from (JSON array of sources, the original first); NULL if published here
+    provenance TEXT,
     created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
 
     -- Ensure unique combination of module and version
@@ -34,9 +64,261 @@ CREATE TABLE module_versions (
 
 -- Index for efficient lookup by module and version
 CREATE INDEX idx_module_version ON module_versions (module_id, version);
+-- Versions differing only in build metadata or letter case can't both be published
+CREATE UNIQUE INDEX uq_module_version_key ON module_versions (module_id, version_key);
 -- Index for finding all versions of a module
 CREATE INDEX idx_module_versions_module_id ON module_versions (module_id);
 
+-- Free-form notes attached to published versions (append-only)
+CREATE TABLE version_notes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    module_version_id UUID NOT NULL REFERENCES module_versions(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    -- Self-reported by the client (X-SProto-Publisher header)
+    author VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index for listing the notes of a version
+CREATE INDEX idx_version_notes_module_version_id ON version_notes (module_version_id);
+
+-- Secondary artifacts attached to published versions under a classifier (e.g. 'descriptors', 'openapi', 'docs')
+CREATE TABLE version_artifacts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    module_version_id UUID NOT NULL REFERENCES module_versions(id) ON DELETE CASCADE,
+    classifier VARCHAR(64) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    -- SHA256 hex digest and size of the stored object
+    digest VARCHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    -- Key in the storage backend: v2/modules/<ns>/<name>/<version>/artifacts/<classifier>/<digest>
+    storage_key TEXT NOT NULL,
+    scan_status VARCHAR(20) NOT NULL DEFAULT 'skipped',
+    scan_result TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- One artifact per classifier and version
+    CONSTRAINT idx_version_artifact_classifier UNIQUE (module_version_id, classifier)
+);
+
+-- Files of each version's artifact with their SHA256, for clients syncing an extracted copy
+CREATE TABLE version_files (
+    module_version_id UUID NOT NULL REFERENCES module_versions(id) ON DELETE CASCADE,
+    path VARCHAR(1024) NOT NULL,
+    size BIGINT NOT NULL, -- Uncompressed
+    digest VARCHAR(64) NOT NULL,
+    PRIMARY KEY (module_version_id, path)
+);
+
+-- Mutable work-in-progress snapshots of modules, published under dev-<name> channels (protoreg-cli dev)
+CREATE TABLE dev_channels (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    module_id UUID NOT NULL REFERENCES modules(id) ON DELETE CASCADE,
+    -- dev-<name>, e.g. 'dev-alice'
+    channel VARCHAR(64) NOT NULL,
+    -- SHA256 hex digest and size of the current artifact
+    artifact_digest VARCHAR(64) NOT NULL,
+    artifact_size BIGINT NOT NULL,
+    -- Key in the storage backend: v2/dev/<ns>/<name>/<channel>/<digest>.zip
+    artifact_storage_key TEXT NOT NULL,
+    scan_status VARCHAR(20) NOT NULL DEFAULT 'skipped',
+    -- Incremented each time the artifact changes
+    revision INTEGER NOT NULL DEFAULT 1,
+    -- Self-reported by the client (X-SProto-Publisher header)
+    publisher VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+
+    -- One channel per name and module
+    CONSTRAINT idx_dev_channel UNIQUE (module_id, channel)
+);
+CREATE INDEX idx_dev_channels_updated_at ON dev_channels (updated_at);
+
+-- Original uploads of versions whose zip was re-packed into canonical form, kept for audits until expires_at
+CREATE TABLE original_uploads (
+    module_version_id UUID PRIMARY KEY REFERENCES module_versions(id) ON DELETE CASCADE,
+    -- SHA256 hex digest and size of the uploaded bytes
+    digest VARCHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    -- Key in the storage backend: v2/originals/<digest>.zip (shared by versions uploaded with the same bytes)
+    storage_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_original_uploads_digest ON original_uploads (digest);
+CREATE INDEX idx_original_uploads_expires_at ON original_uploads (expires_at);
+
+-- Publish configuration of a namespace (admin-managed), checked on every publish in the namespace
+CREATE TABLE namespace_policies (
+    namespace VARCHAR(255) PRIMARY KEY,
+    -- Lint ruleset (minimal, basic or standard); empty to skip linting
+    lint_ruleset VARCHAR(16) NOT NULL DEFAULT '',
+    -- Breaking changes rejected without a major version bump: wire or source; empty to allow them
+    compat_level VARCHAR(16) NOT NULL DEFAULT '',
+    -- Newline-separated glob patterns of the file names artifacts may contain; empty allows any file
+    allowed_files TEXT NOT NULL DEFAULT '',
+    -- New versions must be newer than every published version of the module
+    monotonic BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Newline-separated syntax labels (proto2, proto3, edition-2023) the .proto files may declare; empty allows any
+    allowed_syntaxes TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL,
+    -- Ephemeral namespaces: seconds after which versions (and dev channels not updated since) are deleted; 0 keeps them
+    ephemeral_ttl_seconds BIGINT NOT NULL DEFAULT 0,
+    -- Content policy settings replacing the server's: newline-separated content types and file extensions,
+    -- and the largest uncompressed file in bytes; empty (0) keeps the server's setting
+    content_types TEXT NOT NULL DEFAULT '',
+    max_file_bytes BIGINT NOT NULL DEFAULT 0,
+    allowed_extensions TEXT NOT NULL DEFAULT '',
+    -- Incremented by every update; the policy's ETag for If-Match
+    revision BIGINT NOT NULL DEFAULT 0
+);
+
+-- Webhook subscriptions of a namespace, managed by its maintainers: its events are also sent to url
+CREATE TABLE webhook_subscriptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    namespace VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    -- HMAC-SHA256 key signing the requests (X-SProto-Signature); empty sends them unsigned
+    secret VARCHAR(255) NOT NULL DEFAULT '',
+    -- Newline-separated event type patterns (e.g. module_version.*); empty for all events
+    event_types TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    -- Consumer of the token that created the subscription
+    created_by VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_webhook_subscriptions_namespace ON webhook_subscriptions (namespace);
+
+-- Read and maintainer tokens created through the admin API (in addition to READ_TOKENS and
+-- MAINTAINER_TOKENS), keyed by the SHA256 of the token; the tokens themselves aren't stored
+CREATE TABLE managed_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind VARCHAR(16) NOT NULL, -- read or maintainer
+    fingerprint VARCHAR(64) NOT NULL,
+    consumer VARCHAR(128) NOT NULL DEFAULT '',
+    -- Newline-separated module patterns (read tokens) or namespace patterns (maintainer tokens)
+    patterns TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX idx_managed_tokens_fingerprint ON managed_tokens (fingerprint);
+
+-- Last use of each API token, keyed by the SHA256 of the token (the tokens themselves are configuration)
+CREATE TABLE token_usages (
+    fingerprint VARCHAR(64) PRIMARY KEY,
+    last_used_at TIMESTAMPTZ NOT NULL
+);
+
+-- Downloads of each module version per consumer (the team/service a token identifies, "admin" or
+-- "anonymous"), for consumption reports. Counts are added periodically from memory.
+CREATE TABLE module_consumptions (
+    module_version_id UUID NOT NULL REFERENCES module_versions(id) ON DELETE CASCADE,
+    consumer VARCHAR(128) NOT NULL,
+    fetch_count BIGINT NOT NULL DEFAULT 0,
+    first_fetched_at TIMESTAMPTZ NOT NULL,
+    last_fetched_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (module_version_id, consumer)
+);
+
+-- Requests per client software version (parsed from the User-Agent) and consumer, for the client
+-- inventory. Counts are added periodically from memory; clients not seen for 90 days are deleted.
+CREATE TABLE client_usages (
+    consumer VARCHAR(128) NOT NULL,
+    client VARCHAR(64) NOT NULL,
+    client_version VARCHAR(64) NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (consumer, client, client_version)
+);
+CREATE INDEX idx_client_usages_last_seen_at ON client_usages (last_seen_at);
+
+-- Checksum log: append-only Merkle tree of published versions and their digests. root_hash is the
+-- tree's root after appending the entry, so each entry commits to every entry before it.
+CREATE TABLE checksum_entries (
+    entry_index BIGINT PRIMARY KEY,
+    module VARCHAR(512) NOT NULL,
+    version VARCHAR(100) NOT NULL,
+    digest VARCHAR(71) NOT NULL,
+    leaf_hash VARCHAR(64) NOT NULL,
+    root_hash VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT idx_checksum_module_version UNIQUE (module, version)
+);
+
+-- Registered protoc plugins: each version is a container image and/or per-platform binaries
+CREATE TABLE plugins (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(128) NOT NULL,
+    version VARCHAR(100) NOT NULL,
+    image TEXT,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_plugin_name_version UNIQUE (name, version)
+);
+
+CREATE TABLE plugin_binaries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    plugin_id UUID NOT NULL REFERENCES plugins(id) ON DELETE CASCADE,
+    -- GOOS/GOARCH, e.g. linux/amd64
+    platform VARCHAR(64) NOT NULL,
+    url TEXT NOT NULL,
+    -- SHA256 hex digest of the executable
+    sha256 VARCHAR(64) NOT NULL,
+    CONSTRAINT idx_plugin_binary_platform UNIQUE (plugin_id, platform)
+);
+
+-- Background operations (asynchronous publishes and admin jobs), polled by clients until finished
+CREATE TABLE operations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    -- publish, gc, sunset, migrate-storage, import-buf, tier-storage or reindex-search
+    kind VARCHAR(32) NOT NULL,
+    -- Module the operation is about, if any
+    namespace VARCHAR(255) NOT NULL DEFAULT '',
+    module_name VARCHAR(255) NOT NULL DEFAULT '',
+    version VARCHAR(100) NOT NULL DEFAULT '',
+    -- JSON object of the job's parameters (secrets omitted)
+    params TEXT NOT NULL DEFAULT '',
+    -- queued, running, succeeded, failed or canceled
+    status VARCHAR(16) NOT NULL,
+    -- Step being run while running (e.g. scanning, migrating 3/120)
+    stage VARCHAR(128) NOT NULL DEFAULT '',
+    -- Once finished: the status and JSON body the synchronous request would have responded with
+    http_status INTEGER NOT NULL DEFAULT 0,
+    result TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+CREATE INDEX idx_operations_kind ON operations (kind);
+CREATE INDEX idx_operations_status ON operations (status);
+CREATE INDEX idx_operations_created_at ON operations (created_at);
+
+-- Full-text search index: each module, and the files and declarations of its newest version
+CREATE TABLE search_documents (
+    id BIGSERIAL PRIMARY KEY,
+    module_id UUID NOT NULL REFERENCES modules(id) ON DELETE CASCADE,
+    module_version_id UUID NOT NULL REFERENCES module_versions(id) ON DELETE CASCADE,
+    -- module, file, message, enum, service, method or field
+    kind VARCHAR(16) NOT NULL,
+    -- Module name, file path or fully-qualified declaration name
+    name TEXT NOT NULL,
+    file TEXT NOT NULL DEFAULT '',
+    -- Words of the name (split on dots, underscores and camel case), weighted above the body
+    terms TEXT NOT NULL,
+    -- Module description or leading comment
+    body TEXT NOT NULL DEFAULT '',
+    tsv tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', coalesce(terms, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(body, '')), 'B')
+    ) STORED
+);
+CREATE INDEX idx_search_documents_module_id ON search_documents (module_id);
+CREATE INDEX idx_search_documents_tsv ON search_documents USING GIN (tsv);
+
 -- Trigger function to update 'updated_at' timestamp on module table
 CREATE OR REPLACE FUNCTION update_module_updated_at()
 RETURNS TRIGGER AS $$